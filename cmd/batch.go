@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/batch"
+	"github.com/caronex/intelligence-interface/internal/llm/agent"
+	"github.com/caronex/intelligence-interface/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var batchCmd = &cobra.Command{
+	Use:   "batch",
+	Short: "Check the offline jobs running through provider batch APIs",
+	Long: `Offline jobs that opted into batch mode, such as ii sessions retitle, submit
+their requests through the batch API of the provider, which answers within 24
+hours at half the price. Batches are stored in the database, so they are
+picked up again after a restart.`,
+}
+
+var batchStatusCmd = &cobra.Command{
+	Use:   "status [batch-id]",
+	Short: "Show the batches and deliver the results of the ended ones",
+	Long: `Check the batches with their providers, record and deliver the results of the
+ones that ended and submit their failed requests again, then show every batch:
+its requests per status and its cost at the batch price. Given a batch ID, show
+the requests of that batch only, with the errors of the failed ones.`,
+	Example: `
+  # Show the batches without checking them with the providers
+  ii batch status --cached
+  `,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cached, _ := cmd.Flags().GetBool("cached")
+		q, err := openSpacesDB()
+		if err != nil {
+			return err
+		}
+		batches := batch.NewService(q)
+		agent.RegisterBatchComponents(batches, session.NewService(q))
+		if !cached {
+			if err := batches.Poll(cmd.Context()); err != nil {
+				return fmt.Errorf("failed to poll the batches: %w", err)
+			}
+		}
+
+		if len(args) == 1 {
+			b, err := batches.Get(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Println(formatBatchLine(b))
+			for _, item := range b.Items {
+				line := fmt.Sprintf("  %s  %s  attempt %d", item.ID, item.Status, item.Attempt)
+				if item.Error != "" {
+					line += "  " + item.Error
+				}
+				fmt.Println(line)
+			}
+			return nil
+		}
+
+		list, err := batches.List(cmd.Context())
+		if err != nil {
+			return err
+		}
+		for _, b := range list {
+			fmt.Println(formatBatchLine(b))
+			for _, item := range b.Items {
+				if item.Status == batch.ItemFailed {
+					fmt.Printf("  %s failed: %s\n", item.ID, item.Error)
+				}
+			}
+		}
+		fmt.Printf("%d batches\n", len(list))
+		return nil
+	},
+}
+
+func formatBatchLine(b batch.Batch) string {
+	line := fmt.Sprintf("%s  %s  %s  %s  %s  %d pending, %d succeeded, %d failed, %d retried  $%.4f",
+		b.ID, time.Unix(b.CreatedAt, 0).Format("2006-01-02 15:04"), b.Component, b.Model, b.Status,
+		b.Count(batch.ItemPending), b.Count(batch.ItemSucceeded), b.Count(batch.ItemFailed), b.Count(batch.ItemRetried), b.Cost())
+	if b.Error != "" {
+		line += "  " + b.Error
+	}
+	return line
+}
+
+func init() {
+	batchStatusCmd.Flags().Bool("cached", false, "Show the stored state without checking the batches with their providers")
+
+	batchCmd.AddCommand(batchStatusCmd)
+	rootCmd.AddCommand(batchCmd)
+}
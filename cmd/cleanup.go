@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/cleanup"
+	"github.com/spf13/cobra"
+)
+
+var cleanupCmd = &cobra.Command{
+	Use:   "cleanup",
+	Short: "Remove stale files left behind by generators",
+	Long: `Remove the backups the standardize generator took before overwriting files,
+once they are older than --older-than (7 days by default). The backups are
+found through the .standardize-backups.json manifests under the directory, and
+are dropped from them; a manifest left without backups is removed.`,
+	Example: `
+  # Prune the backups older than 7 days in the current directory
+  ii cleanup
+
+  # Prune the backups older than a day in a project
+  ii cleanup --dir ./service --older-than 24h
+  `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, _ := cmd.Flags().GetString("dir")
+		olderThan, _ := cmd.Flags().GetDuration("older-than")
+
+		if dir == "" {
+			cwd, err := os.Getwd()
+			if err != nil {
+				return fmt.Errorf("failed to get current working directory: %v", err)
+			}
+			dir = cwd
+		}
+
+		pruned, err := cleanup.PruneBackups(dir, time.Now().Add(-olderThan))
+		for _, path := range pruned {
+			fmt.Printf("Removed %s\n", path)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to prune backups: %w", err)
+		}
+		fmt.Printf("Pruned %d backups\n", len(pruned))
+		return nil
+	},
+}
+
+func init() {
+	cleanupCmd.Flags().String("dir", "", "Directory to clean up, the current one by default")
+	cleanupCmd.Flags().Duration("older-than", cleanup.DefaultBackupAge, "Age after which backups are pruned")
+	rootCmd.AddCommand(cleanupCmd)
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/tui/theme"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the configuration",
+}
+
+var configImportCmd = &cobra.Command{
+	Use:   "import-from <tool>",
+	Short: "Import the configuration of OpenCode or Claude Code",
+	Long: `Import the provider keys, MCP servers and preferences of another tool into
+the global config file.
+
+opencode reads ~/.opencode.json, $XDG_CONFIG_HOME/opencode/.opencode.json,
+~/.config/opencode/.opencode.json and the .opencode.json of the working
+directory, the later ones overriding the earlier ones.
+
+claude-code reads ~/.claude/settings.json, ~/.claude.json and the .mcp.json
+of the working directory: the MCP servers of the user, project and local
+scopes and the Anthropic API key.
+
+A report lists what was imported, what was skipped and what needs to be set
+by hand. When a section of the config file already sets some of the imported
+entries, confirmation is asked for before replacing them, unless --yes is set.
+The other entries of the section are imported either way.`,
+	Example: `
+  # Show what would be imported from OpenCode
+  ii config import-from opencode --dry-run
+
+  # Import the MCP servers of Claude Code
+  ii config import-from claude-code
+  `,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{string(config.ImportOpenCode), string(config.ImportClaudeCode)},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		yes, _ := cmd.Flags().GetBool("yes")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current working directory: %v", err)
+		}
+		cfg, err := config.Load(cwd, false)
+		if err != nil {
+			return err
+		}
+		theme.LoadCustomThemes(filepath.Join(cfg.Data.Directory, theme.ThemesDirName))
+
+		imp, err := config.ReadImport(config.ImportSource(args[0]), cwd, theme.AvailableThemes())
+		if err != nil {
+			return err
+		}
+		printImportReport(imp)
+		if len(imp.Report.Imported) == 0 {
+			fmt.Println("\nNothing to import")
+			return nil
+		}
+
+		path, target, err := config.ImportTarget()
+		if err != nil {
+			return err
+		}
+		conflicts := imp.Conflicts(target)
+		overwrite := make(map[config.ImportSection]bool)
+		in := bufio.NewReader(cmd.InOrStdin())
+		for _, section := range []config.ImportSection{config.ImportProviders, config.ImportMCPServers, config.ImportLSP, config.ImportPreferences} {
+			names, ok := conflicts[section]
+			if !ok {
+				continue
+			}
+			question := fmt.Sprintf("\n%s already sets %s %s. Replace them with the imported ones?", path, section, strings.Join(names, ", "))
+			switch {
+			case dryRun:
+				fmt.Printf("\n%s already sets %s %s\n", path, section, strings.Join(names, ", "))
+			case yes:
+				overwrite[section] = true
+			default:
+				overwrite[section] = confirm(in, question)
+			}
+		}
+		if dryRun {
+			return nil
+		}
+
+		if err := config.WriteImport(imp, overwrite); err != nil {
+			return err
+		}
+		fmt.Printf("\nImported into %s\n", path)
+		return nil
+	},
+}
+
+// printImportReport prints what an import takes from the files of the other
+// tool, and what it leaves out
+func printImportReport(imp *config.Import) {
+	fmt.Printf("Read %s\n", strings.Join(imp.Files, ", "))
+	for _, group := range []struct {
+		title string
+		notes []config.ImportNote
+	}{
+		{"Imported", imp.Report.Imported},
+		{"Skipped", imp.Report.Skipped},
+		{"Needs manual attention", imp.Report.Manual},
+	} {
+		if len(group.notes) == 0 {
+			continue
+		}
+		fmt.Printf("\n%s:\n", group.title)
+		for _, note := range group.notes {
+			if note.Reason == "" {
+				fmt.Printf("  %s\n", note.Name)
+				continue
+			}
+			fmt.Printf("  %s: %s\n", note.Name, note.Reason)
+		}
+	}
+}
+
+func init() {
+	configImportCmd.Flags().BoolP("yes", "y", false, "Replace the conflicting settings without asking")
+	configImportCmd.Flags().Bool("dry-run", false, "Show the report without writing the config file")
+	configCmd.AddCommand(configImportCmd)
+	rootCmd.AddCommand(configCmd)
+}
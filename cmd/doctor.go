@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/doctor"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose common misconfigurations",
+	Long: `Run a battery of checks and report which pass, warn or fail, with a hint at
+the remediation: the configuration loads and validates, each provider key
+authenticates, the data directory is writable and has free space, the database
+schema is current, each MCP server answers a handshake, and the shell and the
+LSP servers are installed.
+
+Every check is bounded by --timeout, so a hung MCP server or provider does not
+stall the others. The command fails when a check fails.`,
+	Example: `
+  # Run every check
+  ii doctor
+
+  # Skip the checks reaching the network
+  ii doctor --offline
+
+  # Machine-readable results
+  ii doctor --json
+  `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		offline, _ := cmd.Flags().GetBool("offline")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current working directory: %v", err)
+		}
+		cfg, loadErr := config.Load(cwd, false)
+
+		checks := doctor.Checks(cfg, loadErr, doctor.Options{Offline: offline})
+		results := doctor.Run(cmd.Context(), checks, timeout)
+
+		if asJSON {
+			data, err := json.MarshalIndent(map[string]any{"checks": results}, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to encode the results: %w", err)
+			}
+			fmt.Println(string(data))
+		} else {
+			fmt.Print(doctor.Render(results))
+		}
+
+		if failed := doctor.Failed(results); failed > 0 {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%d of %d checks failed", failed, len(results))
+		}
+		return nil
+	},
+}
+
+func init() {
+	doctorCmd.Flags().Bool("offline", false, "Skip the checks reaching the network: provider keys and remote MCP servers")
+	doctorCmd.Flags().Bool("json", false, "Print the results as JSON")
+	doctorCmd.Flags().Duration("timeout", doctor.DefaultTimeout, "Time each check is given before it fails")
+	rootCmd.AddCommand(doctorCmd)
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/db"
+	"github.com/caronex/intelligence-interface/internal/message"
+	"github.com/caronex/intelligence-interface/internal/session"
+	"github.com/charmbracelet/bubbles/progress"
+	"github.com/spf13/cobra"
+)
+
+var importSessionCmd = &cobra.Command{
+	Use:   "import-session",
+	Short: "Import a conversation exported from the OpenAI Playground",
+	Long: `Import a conversation in the OpenAI chat completion format,
+{"messages": [{"role": "user", "content": "..."}, ...]}, as a new session.
+
+Roles must be user, assistant or system. Assistant messages are attributed to
+the model of --agent, and system messages are imported as user messages since
+the system prompt of a session is the one of its agent.`,
+	Example: `
+  # Import a Playground export
+  ii import-session --file conversation.json
+
+  # Import it with a title, attributed to the coder agent
+  ii import-session --file conversation.json --title "API design" --agent coder
+  `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, _ := cmd.Flags().GetString("file")
+		title, _ := cmd.Flags().GetString("title")
+		agentName, _ := cmd.Flags().GetString("agent")
+
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current working directory: %v", err)
+		}
+		cfg, err := config.Load(cwd, false)
+		if err != nil {
+			return err
+		}
+		resolved, ok := cfg.ResolveAgent(agentName)
+		if !ok {
+			return fmt.Errorf("unknown agent %q", agentName)
+		}
+		agent := cfg.Agents[resolved]
+
+		f, err := os.Open(file)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", file, err)
+		}
+		defer f.Close()
+		params, err := message.ParseOpenAIChat(f, agent.Model)
+		if err != nil {
+			return err
+		}
+		if title == "" {
+			title = "Imported: " + strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		}
+
+		conn, err := db.Connect()
+		if err != nil {
+			return err
+		}
+		q := db.New(conn)
+		sessions := session.NewService(q)
+		messages := message.NewService(q)
+
+		importedSession, err := sessions.Create(cmd.Context(), title)
+		if err != nil {
+			return fmt.Errorf("failed to create session: %w", err)
+		}
+		bar := newImportProgress(len(params))
+		for i, p := range params {
+			if _, err := messages.Create(cmd.Context(), importedSession.ID, p); err != nil {
+				return fmt.Errorf("failed to import message %d: %w", i+1, err)
+			}
+			bar.update(i + 1)
+		}
+		bar.done()
+
+		fmt.Printf("Imported %d messages into session %q (%s)\n", len(params), title, importedSession.ID)
+		return nil
+	},
+}
+
+// importProgress draws a progress bar on stderr when it is a terminal
+type importProgress struct {
+	bar   progress.Model
+	total int
+	shown bool
+}
+
+func newImportProgress(total int) *importProgress {
+	info, err := os.Stderr.Stat()
+	return &importProgress{
+		bar:   progress.New(progress.WithDefaultGradient(), progress.WithWidth(40)),
+		total: total,
+		shown: err == nil && info.Mode()&os.ModeCharDevice != 0,
+	}
+}
+
+func (p *importProgress) update(imported int) {
+	if p.shown {
+		fmt.Fprintf(os.Stderr, "\r%s %d/%d", p.bar.ViewAs(float64(imported)/float64(p.total)), imported, p.total)
+	}
+}
+
+func (p *importProgress) done() {
+	if p.shown {
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+func init() {
+	importSessionCmd.Flags().String("file", "", "Chat completion JSON file to import")
+	importSessionCmd.Flags().String("title", "", "Title of the session (defaults to the file name)")
+	importSessionCmd.Flags().String("agent", string(config.AgentCaronex), "Agent the assistant messages are attributed to")
+	importSessionCmd.MarkFlagRequired("file")
+
+	rootCmd.AddCommand(importSessionCmd)
+}
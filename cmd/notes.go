@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/notes"
+	"github.com/spf13/cobra"
+)
+
+var notesCmd = &cobra.Command{
+	Use:   "notes",
+	Short: "Manage the long-term memory of the workspace",
+	Long: `Manage the notes kept across sessions about the working directory, which the
+agents write with the memory_write tool and read into their system prompt.`,
+}
+
+var notesExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the notes of the workspace as JSON",
+	Example: `
+  # Share the notes of the project with a teammate
+  ii notes export --file notes.json
+  `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, _ := cmd.Flags().GetString("file")
+		if err := loadNotesConfig(); err != nil {
+			return err
+		}
+
+		var w io.Writer = cmd.OutOrStdout()
+		if file != "" {
+			f, err := os.Create(file)
+			if err != nil {
+				return fmt.Errorf("failed to create %s: %w", file, err)
+			}
+			defer f.Close()
+			w = f
+		}
+		return notes.Export(w)
+	},
+}
+
+var notesImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import notes exported by ii notes export",
+	Long: `Import the notes of a file written by ii notes export into the workspace.
+Notes the workspace already has are replaced by the imported ones.`,
+	Example: `
+  # Import the notes of a teammate
+  ii notes import --file notes.json
+  `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, _ := cmd.Flags().GetString("file")
+		if err := loadNotesConfig(); err != nil {
+			return err
+		}
+
+		f, err := os.Open(file)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", file, err)
+		}
+		defer f.Close()
+		imported, err := notes.Import(f)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Imported %d notes into %s\n", imported, config.NotesFile())
+		return nil
+	},
+}
+
+// loadNotesConfig loads the config of the working directory, which the notes
+// file is keyed by
+func loadNotesConfig() error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current working directory: %v", err)
+	}
+	_, err = config.Load(cwd, false)
+	return err
+}
+
+func init() {
+	notesExportCmd.Flags().String("file", "", "File to write the notes to (defaults to stdout)")
+	notesImportCmd.Flags().String("file", "", "File written by ii notes export")
+	notesImportCmd.MarkFlagRequired("file")
+
+	notesCmd.AddCommand(notesExportCmd, notesImportCmd)
+	rootCmd.AddCommand(notesCmd)
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/db"
+	"github.com/caronex/intelligence-interface/internal/lock"
+	"github.com/caronex/intelligence-interface/internal/tools/coordination"
+	"github.com/spf13/cobra"
+)
+
+var resetCmd = &cobra.Command{
+	Use:   "reset",
+	Short: "Delete the local sessions or restore the default config",
+	Long: `Start over from a clean state. --sessions-only deletes the database of the
+data directory, holding the sessions, their messages and the usage analytics,
+along with the session files and the delegation log, and keeps the config.
+--config-only replaces the global config file with the default configuration.
+--all does both.
+
+The files are listed and confirmation is asked for before anything is
+deleted, unless --yes is set. The command refuses to run while the TUI is
+open on the data directory.`,
+	Example: `
+  # List what would be deleted
+  ii reset --all --dry-run
+
+  # Delete the sessions without asking for confirmation
+  ii reset --sessions-only --yes
+  `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sessionsOnly, _ := cmd.Flags().GetBool("sessions-only")
+		configOnly, _ := cmd.Flags().GetBool("config-only")
+		all, _ := cmd.Flags().GetBool("all")
+		yes, _ := cmd.Flags().GetBool("yes")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		resetSessions := sessionsOnly || all
+		resetConfig := configOnly || all
+
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current working directory: %v", err)
+		}
+		// An invalid config is what --config-only replaces, its data
+		// directory is read regardless
+		cfg, err := config.Load(cwd, false)
+		if err != nil && (resetSessions || cfg == nil) {
+			return err
+		}
+		var configFile string
+		if resetConfig {
+			// Read before Defaults resets the settings of the config files
+			path, err := config.GlobalConfigFile()
+			if err != nil {
+				return err
+			}
+			configFile = path
+		}
+		dataDir := cfg.Data.Directory
+		if dataDir == "" {
+			defaults, err := config.Defaults()
+			if err != nil {
+				return err
+			}
+			dataDir = defaults.Data.Directory
+		}
+
+		if pid, running := lock.TUIRunning(dataDir); running {
+			return fmt.Errorf("the TUI is running on %s (PID %d), quit it before resetting", dataDir, pid)
+		}
+
+		var deleted []string
+		if resetSessions {
+			deleted = sessionFiles(dataDir)
+		}
+
+		for _, path := range deleted {
+			fmt.Printf("Delete %s\n", path)
+		}
+		if configFile != "" {
+			fmt.Printf("Replace %s with the default config\n", configFile)
+		}
+		if len(deleted) == 0 && configFile == "" {
+			fmt.Println("No sessions to delete")
+			return nil
+		}
+		if dryRun {
+			return nil
+		}
+		if !yes && !confirm(cmd.InOrStdin(), "Continue?") {
+			fmt.Println("Reset cancelled")
+			return nil
+		}
+
+		for _, path := range deleted {
+			if err := os.RemoveAll(path); err != nil {
+				return fmt.Errorf("failed to delete %s: %w", path, err)
+			}
+		}
+		if configFile != "" {
+			if err := config.WriteDefaults(configFile); err != nil {
+				return err
+			}
+		}
+		fmt.Println("Reset done")
+		return nil
+	},
+}
+
+// sessionFiles returns the files of the data directory holding the sessions
+// and their state, the ones which exist
+func sessionFiles(dataDir string) []string {
+	names := []string{
+		db.Filename,
+		db.Filename + "-wal",
+		db.Filename + "-shm",
+		"sessions",
+		coordination.EventLogFilename,
+	}
+	var files []string
+	for _, name := range names {
+		path := filepath.Join(dataDir, name)
+		if _, err := os.Stat(path); err == nil {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// confirm asks a yes or no question, no being the answer when anything else
+// is read
+func confirm(in io.Reader, question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+func init() {
+	resetCmd.Flags().Bool("sessions-only", false, "Delete the database and the session files, keeping the config")
+	resetCmd.Flags().Bool("config-only", false, "Replace the global config file with the default config")
+	resetCmd.Flags().Bool("all", false, "Delete the sessions and replace the config")
+	resetCmd.Flags().BoolP("yes", "y", false, "Skip the confirmation")
+	resetCmd.Flags().Bool("dry-run", false, "List what would be deleted without deleting it")
+	resetCmd.MarkFlagsMutuallyExclusive("sessions-only", "config-only", "all")
+	resetCmd.MarkFlagsOneRequired("sessions-only", "config-only", "all")
+	rootCmd.AddCommand(resetCmd)
+}
@@ -2,20 +2,28 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	zone "github.com/lrstanley/bubblezone"
 	"github.com/caronex/intelligence-interface/internal/app"
+	"github.com/caronex/intelligence-interface/internal/connectivity"
 	"github.com/caronex/intelligence-interface/internal/core/config"
 	"github.com/caronex/intelligence-interface/internal/db"
 	"github.com/caronex/intelligence-interface/internal/format"
 	"github.com/caronex/intelligence-interface/internal/llm/agent"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/llm/tools"
 	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/lock"
 	"github.com/caronex/intelligence-interface/internal/pubsub"
+	"github.com/caronex/intelligence-interface/internal/remote"
+	"github.com/caronex/intelligence-interface/internal/scratchpad"
 	"github.com/caronex/intelligence-interface/internal/tui"
 	"github.com/caronex/intelligence-interface/internal/version"
 	"github.com/spf13/cobra"
@@ -45,6 +53,24 @@ to create persistent, customizable environments that adapt and improve over time
 
   # Run a single non-interactive prompt with JSON output format
   ii -p "Explain the use of context in Go" -f json
+
+  # Run a single non-interactive prompt with Markdown output, failing when a tool call fails
+  ii -p "Fix the failing tests" --format markdown --fail-on-tool-error
+
+  # Run a single non-interactive prompt with a custom output template
+  ii -p "Summarize the README" --output-template '{{.Content}}{{"\n"}}{{.Usage.Cost}} USD in {{.DurationMs}}ms{{"\n"}}'
+
+  # Print the names of the tools called, one per line
+  ii -p "Tidy up go.mod" --output-template '{{range .ToolCalls}}{{.Name}}{{"\n"}}{{end}}'
+
+  # Run a single non-interactive prompt with custom generation parameters
+  ii -p "Suggest names for a Go CLI" --temperature 1.2 --stop "\n\n"
+
+  # Abort before sending when the prompt is estimated to cost more than 5 cents
+  ii -p "Review the whole repository" --max-cost 0.05
+
+  # Compare the responses of two models to a prompt, printed as JSON
+  ii -p "Explain the use of context in Go" --compare claude-4-sonnet,gpt-4.1
   `,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// If the help flag is set, show the help message
@@ -63,11 +89,41 @@ to create persistent, customizable environments that adapt and improve over time
 		prompt, _ := cmd.Flags().GetString("prompt")
 		outputFormat, _ := cmd.Flags().GetString("output-format")
 		quiet, _ := cmd.Flags().GetBool("quiet")
+		if cmd.Flags().Changed("format") {
+			outputFormat, _ = cmd.Flags().GetString("format")
+		}
 
 		// Validate format option
-		if !format.IsValid(outputFormat) {
+		parsedFormat, err := format.Parse(outputFormat)
+		if err != nil {
 			return fmt.Errorf("invalid format option: %s\n%s", outputFormat, format.GetHelpText())
 		}
+		failOnToolError, _ := cmd.Flags().GetBool("fail-on-tool-error")
+		maxCost, _ := cmd.Flags().GetFloat64("max-cost")
+		if maxCost < 0 {
+			return fmt.Errorf("--max-cost must not be negative")
+		}
+		runOpts := app.NonInteractiveOptions{
+			Format:          parsedFormat,
+			FailOnToolError: failOnToolError,
+			Quiet:           quiet,
+			MaxCost:         maxCost,
+		}
+		// A broken output template fails before any provider call
+		if text, _ := cmd.Flags().GetString("output-template"); text != "" {
+			tmpl, err := format.ParseTemplate(text)
+			if err != nil {
+				return err
+			}
+			runOpts.Template = tmpl
+		}
+		compare, err := comparedModels(cmd)
+		if err != nil {
+			return err
+		}
+		if compare != nil && prompt == "" {
+			return fmt.Errorf("--compare requires a prompt")
+		}
 
 		if cwd != "" {
 			err := os.Chdir(cwd)
@@ -82,11 +138,24 @@ to create persistent, customizable environments that adapt and improve over time
 			}
 			cwd = c
 		}
-		_, err := config.Load(cwd, debug)
+		cfg, err := config.Load(cwd, debug)
+		var parseErr *config.ParseError
+		if errors.As(err, &parseErr) {
+			cfg, err = recoverConfig(cmd, parseErr, cwd, debug, prompt == "")
+		}
 		if err != nil {
 			return err
 		}
 
+		// Generation flags override the agent config for non-interactive runs
+		if prompt != "" {
+			if overrides, ok := generationOverrides(cmd); ok {
+				if err := config.OverrideAgentGeneration(config.AgentCaronex, overrides); err != nil {
+					return err
+				}
+			}
+		}
+
 		// Connect DB, this will also run migrations
 		conn, err := db.Connect()
 		if err != nil {
@@ -97,6 +166,13 @@ to create persistent, customizable environments that adapt and improve over time
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
+		// Determine connectivity before any provider calls are made
+		if cfg.Offline.Enabled {
+			connectivity.SetManualOffline(true)
+		} else if cfg.Offline.AutoDetect {
+			connectivity.Monitor(ctx)
+		}
+
 		app, err := app.New(ctx, conn)
 		if err != nil {
 			logging.Error("Failed to create app: %v", err)
@@ -110,17 +186,39 @@ to create persistent, customizable environments that adapt and improve over time
 
 		// Non-interactive mode
 		if prompt != "" {
+			if len(compare) == 2 {
+				return app.RunComparison(ctx, prompt, compare[0], compare[1], quiet)
+			}
 			// Run non-interactive flow using the App method
-			return app.RunNonInteractive(ctx, prompt, outputFormat, quiet)
+			return app.RunNonInteractive(ctx, prompt, runOpts)
 		}
 
 		// Interactive mode
+		app.Quiet = quiet
+		// Serve the remote API alongside the TUI if enabled
+		if cfg.Remote.Enabled {
+			stopRemote, err := startRemoteAPI(ctx, cfg, app)
+			if err != nil {
+				return err
+			}
+			defer stopRemote()
+		}
+
+		// Keep commands such as reset from deleting the data of the TUI
+		releaseLock, err := lock.AcquireTUILock(cfg.Data.Directory)
+		if err != nil {
+			return err
+		}
+		defer releaseLock()
+
 		// Set up the TUI
 		zone.NewGlobal()
-		program := tea.NewProgram(
-			tui.New(app),
-			tea.WithAltScreen(),
-		)
+		zone.SetEnabled(cfg.TUI.EnableMouse)
+		options := []tea.ProgramOption{tea.WithAltScreen()}
+		if cfg.TUI.EnableMouse {
+			options = append(options, tea.WithMouseCellMotion())
+		}
+		program := tea.NewProgram(tui.New(app), options...)
 
 		// Setup the subscriptions, this will send services events to the TUI
 		ch, cancelSubs := setupSubscriptions(app, ctx)
@@ -184,6 +282,75 @@ to create persistent, customizable environments that adapt and improve over time
 }
 
 // attemptTUIRecovery tries to recover the TUI after a panic
+// generationOverrides returns the generation parameters set by flags, false if
+// none were set
+func generationOverrides(cmd *cobra.Command) (config.GenerationParams, bool) {
+	var params config.GenerationParams
+	set := false
+	floatFlag := func(name string) *float64 {
+		if !cmd.Flag(name).Changed {
+			return nil
+		}
+		set = true
+		value, _ := cmd.Flags().GetFloat64(name)
+		return &value
+	}
+	params.Temperature = floatFlag("temperature")
+	params.TopP = floatFlag("top-p")
+	params.FrequencyPenalty = floatFlag("frequency-penalty")
+	params.PresencePenalty = floatFlag("presence-penalty")
+	if cmd.Flag("stop").Changed {
+		set = true
+		params.Stop, _ = cmd.Flags().GetStringArray("stop")
+	}
+	return params, set
+}
+
+// comparedModels returns the two models of the --compare flag, nil when it
+// is not set
+func comparedModels(cmd *cobra.Command) ([]models.ModelID, error) {
+	value, _ := cmd.Flags().GetString("compare")
+	if value == "" {
+		return nil, nil
+	}
+	names := strings.Split(value, ",")
+	if len(names) != 2 {
+		return nil, fmt.Errorf("--compare takes two models separated by a comma, got %q", value)
+	}
+	compared := make([]models.ModelID, len(names))
+	for i, name := range names {
+		compared[i] = models.ModelID(strings.TrimSpace(name))
+		if _, ok := models.SupportedModels[compared[i]]; !ok {
+			return nil, fmt.Errorf("unsupported model %q in --compare", compared[i])
+		}
+	}
+	return compared, nil
+}
+
+// recoverConfig offers to start with the defaults and the environment when
+// the global config file is not valid JSON, backing the broken file up. It
+// starts without asking when --recover-config is set, and asks in
+// interactive mode when stdin is a terminal.
+func recoverConfig(cmd *cobra.Command, parseErr *config.ParseError, cwd string, debug, interactive bool) (*config.Config, error) {
+	recoverFlag, _ := cmd.Flags().GetBool("recover-config")
+	if !recoverFlag {
+		info, err := os.Stdin.Stat()
+		if !interactive || err != nil || info.Mode()&os.ModeCharDevice == 0 {
+			return nil, fmt.Errorf("%w\nFix the file, or run with --recover-config to start with the defaults and the environment", parseErr)
+		}
+		fmt.Fprintf(os.Stderr, "%v\n\n", parseErr)
+		if !confirm(cmd.InOrStdin(), "Start with the defaults and the environment, moving the broken file aside?") {
+			return nil, fmt.Errorf("invalid config file %s", parseErr.Path)
+		}
+	}
+	backup, err := config.BackupBrokenConfig(parseErr)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(os.Stderr, "Moved the broken config file to %s\n", backup)
+	return config.Reload(cwd, debug)
+}
+
 func attemptTUIRecovery(program *tea.Program) {
 	logging.Info("Attempting to recover TUI after panic")
 
@@ -192,6 +359,24 @@ func attemptTUIRecovery(program *tea.Program) {
 	program.Quit()
 }
 
+// startRemoteAPI serves the remote API using the services of the running app
+func startRemoteAPI(ctx context.Context, cfg *config.Config, app *app.App) (func(), error) {
+	server, err := remote.New(cfg, app.Sessions, app.Messages, app.CaronexAgent, app.Locks)
+	if err != nil {
+		return nil, err
+	}
+	if err := server.Start(ctx); err != nil {
+		return nil, err
+	}
+	return func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logging.Error("Failed to shut down remote API", "error", err)
+		}
+	}, nil
+}
+
 func initMCPTools(ctx context.Context, app *app.App) {
 	go func() {
 		defer logging.RecoverPanic("MCP-goroutine", nil)
@@ -233,7 +418,7 @@ func setupSubscriber[T any](
 				select {
 				case outputCh <- msg:
 				case <-time.After(2 * time.Second):
-					logging.Warn("message dropped due to slow consumer", "name", name)
+					logging.Warn("message dropped due to slow consumer", "name", name, logging.ComponentKey, logging.ComponentDataLoss)
 				case <-ctx.Done():
 					logging.Info("subscription cancelled", "name", name)
 					return
@@ -257,6 +442,9 @@ func setupSubscriptions(app *app.App, parentCtx context.Context) (chan tea.Msg,
 	setupSubscriber(ctx, &wg, "messages", app.Messages.Subscribe, ch)
 	setupSubscriber(ctx, &wg, "permissions", app.Permissions.Subscribe, ch)
 	setupSubscriber(ctx, &wg, "caronexAgent", app.CaronexAgent.Subscribe, ch)
+	setupSubscriber(ctx, &wg, "connectivity", connectivity.Subscribe, ch)
+	setupSubscriber(ctx, &wg, "toolOutput", tools.SubscribeOutput, ch)
+	setupSubscriber(ctx, &wg, "scratchpad", scratchpad.Subscribe, ch)
 
 	cleanupFunc := func() {
 		logging.Info("Cancelling all subscriptions")
@@ -297,13 +485,36 @@ func init() {
 
 	// Add format flag with validation logic
 	rootCmd.Flags().StringP("output-format", "f", format.Text.String(),
-		"Output format for non-interactive mode (text, json)")
-
-	// Add quiet flag to hide spinner in non-interactive mode
-	rootCmd.Flags().BoolP("quiet", "q", false, "Hide spinner in non-interactive mode")
+		"Output format for non-interactive mode (text, json, markdown), also given as --format")
+	rootCmd.Flags().String("format", "", "Shorthand of --output-format")
+	rootCmd.Flags().MarkHidden("format")
+	rootCmd.Flags().String("output-template", "",
+		"Go template rendering the result of non-interactive mode, with the fields Content, ToolCalls, Usage, DurationMs, Model and SessionID")
+	rootCmd.Flags().Bool("fail-on-tool-error", false, "Exit with an error in non-interactive mode when a tool call failed")
+	rootCmd.Flags().Float64("max-cost", 0, "Abort in non-interactive mode before sending when the estimated prompt cost exceeds this many dollars")
+
+	// Add quiet flag to hide the spinner in non-interactive mode and the
+	// startup banner in interactive mode
+	rootCmd.Flags().BoolP("quiet", "q", false, "Hide the spinner in non-interactive mode and the startup banner in interactive mode")
+
+	// Generation parameters for non-interactive mode, overriding the agent config
+	rootCmd.Flags().Float64("temperature", 0, "Sampling temperature (0-2) in non-interactive mode")
+	rootCmd.Flags().Float64("top-p", 0, "Nucleus sampling probability (0-1) in non-interactive mode")
+	rootCmd.Flags().Float64("frequency-penalty", 0, "Frequency penalty (-2 to 2) in non-interactive mode")
+	rootCmd.Flags().Float64("presence-penalty", 0, "Presence penalty (-2 to 2) in non-interactive mode")
+	rootCmd.Flags().StringArray("stop", nil, "Stop sequence in non-interactive mode, may be repeated up to 4 times")
+
+	// Start despite a global config file which is not valid JSON
+	rootCmd.Flags().Bool("recover-config", false, "Move a global config file which is not valid JSON aside and start with the defaults and the environment")
+
+	// Model comparison for non-interactive mode
+	rootCmd.Flags().String("compare", "", "Compare the responses of two models, as modelA,modelB, printed as JSON in non-interactive mode")
 
 	// Register custom validation for the format flag
 	rootCmd.RegisterFlagCompletionFunc("output-format", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		return format.SupportedFormats, cobra.ShellCompDirectiveNoFileComp
 	})
+	rootCmd.RegisterFlagCompletionFunc("format", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return format.SupportedFormats, cobra.ShellCompDirectiveNoFileComp
+	})
 }
@@ -98,6 +98,47 @@ func generateSchema() map[string]any {
 		},
 	}
 
+	schema["properties"].(map[string]any)["projectTypes"] = map[string]any{
+		"type":        "array",
+		"description": "Stacks of the workspace, overriding those detected from its marker files",
+		"items": map[string]any{
+			"type":     "string",
+			"examples": []string{"go", "node", "python", "rust", "ruby"},
+		},
+	}
+
+	schema["properties"].(map[string]any)["embeddings"] = map[string]any{
+		"type":        "object",
+		"description": "How the knowledge base is embedded, unless a space sets its own embeddings",
+		"properties": map[string]any{
+			"provider": map[string]any{
+				"type":        "string",
+				"description": "Provider computing the embeddings, local needing neither a key nor a network",
+				"enum":        config.EmbeddingProviders,
+				"default":     config.EmbeddingProviderLocal,
+			},
+			"model": map[string]any{
+				"type":        "string",
+				"description": "Embedding model, the deployment name for azure",
+			},
+			"dimensions": map[string]any{
+				"type":        "integer",
+				"description": "Length of the vectors, required with a model other than the default of the provider",
+				"minimum":     1,
+			},
+			"batchSize": map[string]any{
+				"type":        "integer",
+				"description": "Number of texts sent in one request",
+				"minimum":     1,
+			},
+			"requestsPerMinute": map[string]any{
+				"type":        "integer",
+				"description": "Pace of the requests sent to the provider, 0 using its default",
+				"minimum":     0,
+			},
+		},
+	}
+
 	schema["properties"].(map[string]any)["tui"] = map[string]any{
 		"type":        "object",
 		"description": "Terminal User Interface configuration",
@@ -118,6 +159,76 @@ func generateSchema() map[string]any {
 					"tron",
 				},
 			},
+			"retryMode": map[string]any{
+				"type":        "string",
+				"description": "What happens to the previous response when retrying it: replace moves it to a hidden branch, append keeps it",
+				"default":     "replace",
+				"enum":        []string{"replace", "append"},
+			},
+			"enableMouse": map[string]any{
+				"type":        "boolean",
+				"description": "Scroll messages and click sessions and the agent with the mouse",
+				"default":     true,
+			},
+			"banner": map[string]any{
+				"type":        "object",
+				"description": "Startup banner of the chat and the sections it shows",
+				"properties": map[string]any{
+					"enabled": map[string]any{
+						"type":        "boolean",
+						"description": "Show the startup banner at the top of the chat",
+						"default":     true,
+					},
+					"agent": map[string]any{
+						"type":        "boolean",
+						"description": "Show the active agent and its model",
+						"default":     true,
+					},
+					"workspace": map[string]any{
+						"type":        "boolean",
+						"description": "Show the working directory and the workspace roots",
+						"default":     true,
+					},
+					"space": map[string]any{
+						"type":        "boolean",
+						"description": "Show the active space",
+						"default":     true,
+					},
+					"fingerprint": map[string]any{
+						"type":        "boolean",
+						"description": "Show the fingerprint of the configuration",
+						"default":     true,
+					},
+					"providers": map[string]any{
+						"type":        "boolean",
+						"description": "Show the configured providers and whether they are reachable",
+						"default":     true,
+					},
+					"warnings": map[string]any{
+						"type":        "boolean",
+						"description": "Show the warnings of the configuration validation",
+						"default":     true,
+					},
+				},
+			},
+			"costEstimate": map[string]any{
+				"type":        "object",
+				"description": "Estimated prompt tokens and cost shown in the composer before a message is sent",
+				"properties": map[string]any{
+					"threshold": map[string]any{
+						"type":        "integer",
+						"description": "Prompt tokens from which the estimate is shown, 0 always showing it",
+						"default":     config.DefaultCostEstimateThreshold,
+						"minimum":     0,
+					},
+					"expensiveThreshold": map[string]any{
+						"type":        "integer",
+						"description": "Prompt tokens from which sending a message asks for confirmation, 0 never asking",
+						"default":     config.DefaultExpensiveThreshold,
+						"minimum":     0,
+					},
+				},
+			},
 		},
 	}
 
@@ -164,6 +275,27 @@ func generateSchema() map[string]any {
 						"type": "string",
 					},
 				},
+				"aliases": map[string]any{
+					"type":        "object",
+					"description": "Names to expose tools under, keyed by the tool name on the server. Tools are named <server>_<tool> by default",
+					"additionalProperties": map[string]any{
+						"type": "string",
+					},
+				},
+				"allow": map[string]any{
+					"type":        "array",
+					"description": "Tools to enable, by qualified name (<server>.<tool>) or alias. All tools are enabled when empty",
+					"items": map[string]any{
+						"type": "string",
+					},
+				},
+				"deny": map[string]any{
+					"type":        "array",
+					"description": "Tools to disable, by qualified name (<server>.<tool>) or alias",
+					"items": map[string]any{
+						"type": "string",
+					},
+				},
 			},
 			"required": []string{"command"},
 		},
@@ -186,6 +318,11 @@ func generateSchema() map[string]any {
 					"description": "Whether the provider is disabled",
 					"default":     false,
 				},
+				"disableCache": map[string]any{
+					"type":        "boolean",
+					"description": "Whether prompt caching is disabled for providers that support it",
+					"default":     false,
+				},
 			},
 		},
 	}
@@ -232,6 +369,49 @@ func generateSchema() map[string]any {
 					"description": "Reasoning effort for models that support it (OpenAI, Anthropic)",
 					"enum":        []string{"low", "medium", "high"},
 				},
+				"aliases": map[string]any{
+					"type":        "array",
+					"description": "Other names the agent is referred to by, in any case",
+					"items": map[string]any{
+						"type": "string",
+					},
+				},
+				"generation": map[string]any{
+					"type":        "object",
+					"description": "Sampling parameters, provider defaults are used when unset and unsupported ones are ignored",
+					"properties": map[string]any{
+						"temperature": map[string]any{
+							"type":        "number",
+							"description": "Sampling temperature",
+							"minimum":     0,
+							"maximum":     2,
+						},
+						"topP": map[string]any{
+							"type":        "number",
+							"description": "Nucleus sampling probability",
+							"minimum":     0,
+							"maximum":     1,
+						},
+						"frequencyPenalty": map[string]any{
+							"type":        "number",
+							"description": "Penalty for tokens by how often they already appeared",
+							"minimum":     -2,
+							"maximum":     2,
+						},
+						"presencePenalty": map[string]any{
+							"type":        "number",
+							"description": "Penalty for tokens that already appeared",
+							"minimum":     -2,
+							"maximum":     2,
+						},
+						"stop": map[string]any{
+							"type":        "array",
+							"description": "Sequences that end the response",
+							"items":       map[string]any{"type": "string"},
+							"maxItems":    config.MaxStopSequences,
+						},
+					},
+				},
 			},
 			"required": []string{"model"},
 		},
@@ -277,10 +457,16 @@ func generateSchema() map[string]any {
 			"type":        "object",
 			"description": "LSP configuration for a language",
 			"properties": map[string]any{
+				"enabled": map[string]any{
+					"type":        "boolean",
+					"description": "Whether the LSP is enabled, takes precedence over disabled",
+					"default":     true,
+				},
 				"disabled": map[string]any{
 					"type":        "boolean",
-					"description": "Whether the LSP is disabled",
+					"description": "Deprecated: use enabled. Whether the LSP is disabled",
 					"default":     false,
+					"deprecated":  true,
 				},
 				"command": map[string]any{
 					"type":        "string",
@@ -0,0 +1,245 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/batch"
+	"github.com/caronex/intelligence-interface/internal/llm/agent"
+	"github.com/caronex/intelligence-interface/internal/message"
+	"github.com/caronex/intelligence-interface/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "List, archive, prune and retitle sessions in bulk",
+	Long: `List, archive and delete the sessions matching filters: their age, the agent
+which answered in them, a tag, or having no user message. Archived sessions are
+hidden from the session list of the TUI but kept, and found by searches.`,
+}
+
+var sessionsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the sessions matching the filters",
+	Example: `
+  # List the sessions tagged spike, archived ones included
+  ii sessions list --tag spike --archived
+  `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sessions, filter, err := sessionsForFlags(cmd)
+		if err != nil {
+			return err
+		}
+		found, err := sessions.Find(cmd.Context(), filter)
+		if err != nil {
+			return err
+		}
+		for _, s := range found {
+			fmt.Println(formatSessionLine(s))
+		}
+		fmt.Printf("%d sessions\n", len(found))
+		return nil
+	},
+}
+
+var sessionsArchiveCmd = &cobra.Command{
+	Use:   "archive",
+	Short: "Archive the sessions matching the filters",
+	Example: `
+  # Archive the sessions older than a month
+  ii sessions archive --older-than 30d
+
+  # Bring the archived sessions of the coder agent back
+  ii sessions archive --agent coder --undo
+  `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		undo, _ := cmd.Flags().GetBool("undo")
+		sessions, filter, err := sessionsForFlags(cmd)
+		if err != nil {
+			return err
+		}
+		if undo {
+			filter.IncludeArchived = true
+		}
+		found, err := sessions.Find(cmd.Context(), filter)
+		if err != nil {
+			return err
+		}
+		changed, err := sessions.Archive(cmd.Context(), sessionIDs(found), !undo)
+		if err != nil {
+			return err
+		}
+		if undo {
+			fmt.Printf("Unarchived %d sessions\n", changed)
+		} else {
+			fmt.Printf("Archived %d sessions\n", changed)
+		}
+		return nil
+	},
+}
+
+var sessionsPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete the sessions matching the filters",
+	Long: `Delete the sessions matching the filters, with their child sessions, messages,
+tool operations, artifacts and audit log entries. The database changes are made
+in one transaction, so a failure deletes nothing. At least one filter is
+required; --dry-run reports what would be deleted without deleting it.`,
+	Example: `
+  # See what pruning the sessions older than 90 days would delete
+  ii sessions prune --older-than 90d --dry-run
+
+  # Delete the sessions in which nothing was asked, archived ones included
+  ii sessions prune --empty --archived
+  `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		sessions, filter, err := sessionsForFlags(cmd)
+		if err != nil {
+			return err
+		}
+		if filter.OlderThan == 0 && filter.Agent == "" && filter.Tag == "" && !filter.Empty {
+			return fmt.Errorf("give at least one of --older-than, --agent, --tag or --empty")
+		}
+		found, err := sessions.Find(cmd.Context(), filter)
+		if err != nil {
+			return err
+		}
+		for _, s := range found {
+			fmt.Println(formatSessionLine(s))
+		}
+		report, err := sessions.DeleteMany(cmd.Context(), sessionIDs(found), dryRun)
+		if err != nil {
+			return err
+		}
+		verb := "Deleted"
+		if dryRun {
+			verb = "Would delete"
+		}
+		fmt.Printf("%s %d sessions: %d messages, %d tool operations, %d artifacts and %d audit entries\n",
+			verb, report.Sessions, report.Messages, report.ToolOperations, report.Artifacts, report.AuditEntries)
+		return nil
+	},
+}
+
+var sessionsRetitleCmd = &cobra.Command{
+	Use:   "retitle",
+	Short: "Regenerate the titles of the sessions matching the filters",
+	Long: `Regenerate the titles of the sessions matching the filters from their first
+user message. The requests are submitted in one batch through the batch API of
+the provider of Caronex's model, which answers within 24 hours at half the
+price; OpenAI and Anthropic models only. The titles are set once the batch
+ended, by the next ii batch status or by the running application.`,
+	Example: `
+  # Retitle every session of the coder agent
+  ii sessions retitle --agent coder
+
+  # Retitle the sessions tagged spike, then check on the batch later
+  ii sessions retitle --tag spike
+  ii batch status
+  `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter, err := sessionFilter(cmd)
+		if err != nil {
+			return err
+		}
+		q, err := openSpacesDB()
+		if err != nil {
+			return err
+		}
+		sessions := session.NewService(q)
+		found, err := sessions.Find(cmd.Context(), filter)
+		if err != nil {
+			return err
+		}
+		if len(found) == 0 {
+			fmt.Println("No sessions match the filters")
+			return nil
+		}
+
+		batches := batch.NewService(q)
+		agent.RegisterBatchComponents(batches, sessions)
+		submitted, err := agent.RegenerateTitles(cmd.Context(), batches, message.NewService(q), found)
+		if err != nil {
+			return err
+		}
+		if submitted.Status == batch.StatusPending {
+			fmt.Printf("Stored batch %s of %d sessions, but the provider did not take it: %s\n", submitted.ID, len(submitted.Items), submitted.Error)
+			fmt.Println("It is submitted again by the next ii batch status")
+			return nil
+		}
+		fmt.Printf("Submitted batch %s: %d sessions to retitle with %s\n", submitted.ID, len(submitted.Items), submitted.Model)
+		fmt.Println("Run ii batch status to set the titles once the batch ended")
+		return nil
+	},
+}
+
+// sessionsForFlags opens the sessions of the working directory and reads the
+// filter of the flags
+func sessionsForFlags(cmd *cobra.Command) (session.Service, session.Filter, error) {
+	filter, err := sessionFilter(cmd)
+	if err != nil {
+		return nil, filter, err
+	}
+	q, err := openSpacesDB()
+	if err != nil {
+		return nil, filter, err
+	}
+	return session.NewService(q), filter, nil
+}
+
+// sessionFilter reads the filter of the flags
+func sessionFilter(cmd *cobra.Command) (session.Filter, error) {
+	var filter session.Filter
+	if olderThan, _ := cmd.Flags().GetString("older-than"); olderThan != "" {
+		age, err := session.ParseAge(olderThan)
+		if err != nil {
+			return filter, err
+		}
+		filter.OlderThan = age
+	}
+	filter.Agent, _ = cmd.Flags().GetString("agent")
+	filter.Tag, _ = cmd.Flags().GetString("tag")
+	filter.Empty, _ = cmd.Flags().GetBool("empty")
+	filter.IncludeArchived, _ = cmd.Flags().GetBool("archived")
+	return filter, nil
+}
+
+func sessionIDs(sessions []session.Session) []string {
+	ids := make([]string, len(sessions))
+	for i, s := range sessions {
+		ids[i] = s.ID
+	}
+	return ids
+}
+
+func formatSessionLine(s session.Session) string {
+	line := fmt.Sprintf("%s  %s  %s", s.ID, time.Unix(s.CreatedAt, 0).Format("2006-01-02"), s.Title)
+	if s.Agent != "" {
+		line += "  @" + s.Agent
+	}
+	if len(s.Tags) > 0 {
+		line += "  #" + strings.Join(s.Tags, " #")
+	}
+	if s.Archived {
+		line += "  (archived)"
+	}
+	return line
+}
+
+func init() {
+	for _, c := range []*cobra.Command{sessionsListCmd, sessionsArchiveCmd, sessionsPruneCmd, sessionsRetitleCmd} {
+		c.Flags().String("older-than", "", "Select the sessions created at least that long ago, such as 90d")
+		c.Flags().String("agent", "", "Select the sessions answered by the agent")
+		c.Flags().String("tag", "", "Select the sessions with the tag")
+		c.Flags().Bool("empty", false, "Select the sessions without any user message")
+		c.Flags().Bool("archived", false, "Select the archived sessions too")
+	}
+	sessionsArchiveCmd.Flags().Bool("undo", false, "Unarchive the sessions instead")
+	sessionsPruneCmd.Flags().Bool("dry-run", false, "Report what would be deleted without deleting it")
+
+	sessionsCmd.AddCommand(sessionsListCmd, sessionsArchiveCmd, sessionsPruneCmd, sessionsRetitleCmd)
+	rootCmd.AddCommand(sessionsCmd)
+}
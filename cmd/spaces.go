@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/db"
+	"github.com/caronex/intelligence-interface/internal/spaces"
+	"github.com/spf13/cobra"
+)
+
+var spacesCmd = &cobra.Command{
+	Use:   "spaces",
+	Short: "Archive and restore spaces",
+	Long: `Archive a space to a bundle holding its configuration, sessions, notes and
+artifacts, remove it from the system, and restore it from a bundle later.`,
+}
+
+var spacesExportCmd = &cobra.Command{
+	Use:   "export <space>",
+	Short: "Export a space to a bundle",
+	Long: `Export a space to a gzipped tarball holding its configuration, its sessions
+and their messages, its notes and the artifacts of its sessions. The values of
+the environment variables of the space are left out, they may be secrets.`,
+	Example: `
+  # Archive the space of a finished project
+  ii spaces export client-site --file client-site.tar.gz
+  `,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, _ := cmd.Flags().GetString("file")
+		q, err := openSpacesDB()
+		if err != nil {
+			return err
+		}
+
+		var w io.Writer = cmd.OutOrStdout()
+		if file != "" {
+			f, err := os.Create(file)
+			if err != nil {
+				return fmt.Errorf("failed to create %s: %w", file, err)
+			}
+			defer f.Close()
+			w = f
+		}
+		buffered := bufio.NewWriter(w)
+		if err := spaces.ExportBundle(cmd.Context(), q, args[0], buffered); err != nil {
+			return err
+		}
+		return buffered.Flush()
+	},
+}
+
+var spacesImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Restore a space from a bundle",
+	Long: `Restore a space from a bundle written by ii spaces export. The bundle is
+checked against the hashes of its manifest before anything is restored.`,
+	Example: `
+  # Restore an archived space
+  ii spaces import --file client-site.tar.gz
+
+  # Restore a copy next to the original
+  ii spaces import --file client-site.tar.gz --rename
+  `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, _ := cmd.Flags().GetString("file")
+		spaceID, _ := cmd.Flags().GetString("as")
+		rename, _ := cmd.Flags().GetBool("rename")
+		q, err := openSpacesDB()
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(file)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", file, err)
+		}
+		defer f.Close()
+		result, err := spaces.ImportBundle(cmd.Context(), q, bufio.NewReader(f), spaces.ImportOptions{SpaceID: spaceID, Rename: rename})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Restored space %s: %d sessions, %d messages, %d notes and %d artifacts\n",
+			result.SpaceID, result.Sessions, result.Messages, result.Notes, result.Artifacts)
+		return nil
+	},
+}
+
+var spacesRemoveCmd = &cobra.Command{
+	Use:   "remove <space>",
+	Short: "Remove a space and everything it holds",
+	Long: `Remove a space from the configuration, with its sessions, notes and
+artifacts. Export it first to be able to restore it.`,
+	Example: `
+  # Archive a space, then remove it
+  ii spaces export client-site --file client-site.tar.gz
+  ii spaces remove client-site
+  `,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		q, err := openSpacesDB()
+		if err != nil {
+			return err
+		}
+		if err := spaces.Remove(cmd.Context(), q, args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Removed space %s\n", args[0])
+		return nil
+	},
+}
+
+// openSpacesDB loads the config of the working directory and connects to its
+// database
+func openSpacesDB() (db.Querier, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current working directory: %v", err)
+	}
+	if _, err := config.Load(cwd, false); err != nil {
+		return nil, err
+	}
+	conn, err := db.Connect()
+	if err != nil {
+		return nil, err
+	}
+	return db.New(conn), nil
+}
+
+func init() {
+	spacesExportCmd.Flags().String("file", "", "File to write the bundle to (defaults to stdout)")
+	spacesImportCmd.Flags().String("file", "", "Bundle written by ii spaces export")
+	spacesImportCmd.Flags().String("as", "", "Restore the space under another ID")
+	spacesImportCmd.Flags().Bool("rename", false, "Restore the space under a free ID when its ID is taken")
+	spacesImportCmd.MarkFlagRequired("file")
+
+	spacesCmd.AddCommand(spacesExportCmd, spacesImportCmd, spacesRemoveCmd)
+	rootCmd.AddCommand(spacesCmd)
+}
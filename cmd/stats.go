@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/analytics"
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show local usage analytics",
+	Long: `Show a summary of your own usage: messages per agent, tool calls per tool,
+provider latency and failure rates, and time-of-day patterns.
+
+All data is computed and stored locally in the data directory and is never sent anywhere.`,
+	Example: `
+  # Usage over the last 30 days
+  ii stats
+
+  # Usage for a specific date range
+  ii stats --from 2025-01-01 --to 2025-03-31
+  `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from, to, err := statsRange(cmd)
+		if err != nil {
+			return err
+		}
+
+		service, err := openAnalytics()
+		if err != nil {
+			return err
+		}
+		summary, err := service.Summary(cmd.Context(), from, to)
+		if err != nil {
+			return fmt.Errorf("failed to load stats: %w", err)
+		}
+		fmt.Println(analytics.Render(summary, 80))
+		return nil
+	},
+}
+
+var statsResetCmd = &cobra.Command{
+	Use:   "reset",
+	Short: "Delete all local usage analytics",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		service, err := openAnalytics()
+		if err != nil {
+			return err
+		}
+		if err := service.Reset(cmd.Context()); err != nil {
+			return fmt.Errorf("failed to reset stats: %w", err)
+		}
+		fmt.Println("Usage analytics deleted")
+		return nil
+	},
+}
+
+// openAnalytics loads the config and connects to the local database
+func openAnalytics() (analytics.Service, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current working directory: %v", err)
+	}
+	if _, err := config.Load(cwd, false); err != nil {
+		return nil, err
+	}
+	conn, err := db.Connect()
+	if err != nil {
+		return nil, err
+	}
+	return analytics.NewService(db.New(conn)), nil
+}
+
+// statsRange resolves the --from, --to and --days flags into a date range
+func statsRange(cmd *cobra.Command) (time.Time, time.Time, error) {
+	days, _ := cmd.Flags().GetInt("days")
+	fromFlag, _ := cmd.Flags().GetString("from")
+	toFlag, _ := cmd.Flags().GetString("to")
+
+	to := time.Now()
+	if toFlag != "" {
+		parsed, err := time.ParseInLocation(analytics.DayFormat, toFlag, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid --to date %q, expected YYYY-MM-DD", toFlag)
+		}
+		to = parsed
+	}
+
+	if days < 1 {
+		return time.Time{}, time.Time{}, fmt.Errorf("--days must be at least 1")
+	}
+	from := to.AddDate(0, 0, -(days - 1))
+	if fromFlag != "" {
+		parsed, err := time.ParseInLocation(analytics.DayFormat, fromFlag, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid --from date %q, expected YYYY-MM-DD", fromFlag)
+		}
+		from = parsed
+	}
+
+	if from.After(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("--from must not be after --to")
+	}
+	return from, to, nil
+}
+
+func init() {
+	statsCmd.Flags().Int("days", 30, "Number of days to include, ending at --to")
+	statsCmd.Flags().String("from", "", "First day to include (YYYY-MM-DD)")
+	statsCmd.Flags().String("to", "", "Last day to include (YYYY-MM-DD, defaults to today)")
+
+	statsCmd.AddCommand(statsResetCmd)
+	rootCmd.AddCommand(statsCmd)
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/tui/theme"
+	"github.com/spf13/cobra"
+)
+
+var themesCmd = &cobra.Command{
+	Use:   "themes",
+	Short: "Manage TUI themes",
+}
+
+var themesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the built-in and custom themes",
+	Long: `List the themes that can be selected with tui.theme: the built-in themes
+and the custom themes loaded from the .json files of the themes directory
+under the data directory. Theme files that fail to load are reported.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current working directory: %v", err)
+		}
+		cfg, err := config.Load(cwd, false)
+		if err != nil {
+			return err
+		}
+
+		dir := filepath.Join(cfg.Data.Directory, theme.ThemesDirName)
+		_, errs := theme.LoadCustomThemes(dir)
+
+		for _, name := range theme.AvailableThemes() {
+			kind := "built-in"
+			if theme.IsCustomTheme(name) {
+				kind = "custom"
+			}
+			current := " "
+			if name == cfg.TUI.Theme {
+				current = "*"
+			}
+			fmt.Printf("%s %-24s %s\n", current, name, kind)
+		}
+		if len(errs) > 0 {
+			fmt.Printf("\nSkipped theme files in %s:\n", dir)
+			for _, err := range errs {
+				fmt.Printf("  %v\n", err)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	themesCmd.AddCommand(themesListCmd)
+	rootCmd.AddCommand(themesCmd)
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/caronex/intelligence-interface/internal/version"
+	"github.com/spf13/cobra"
+)
+
+// Build-time parameters set via -ldflags, e.g.
+//
+//	go build -ldflags "-X github.com/caronex/intelligence-interface/cmd.Version=v1.2.3"
+//
+// When set, they take precedence over the metadata detected by the version package.
+var (
+	Version    string
+	CommitHash string
+	BuildTime  string
+)
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print version and build information",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		info := version.Get()
+
+		asJSON, _ := cmd.Flags().GetBool("json")
+		if asJSON {
+			data, err := json.MarshalIndent(info, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal version info: %w", err)
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+
+		fmt.Printf("Version:    %s\n", info.Version)
+		fmt.Printf("CommitHash: %s\n", info.CommitHash)
+		fmt.Printf("BuildTime:  %s\n", info.BuildTime)
+		fmt.Printf("GoVersion:  %s\n", info.GoVersion)
+		return nil
+	},
+}
+
+// applyBuildMetadata copies the -ldflags values into the version package.
+func applyBuildMetadata() {
+	if Version != "" {
+		version.Version = Version
+	}
+	if CommitHash != "" {
+		version.CommitHash = CommitHash
+	}
+	if BuildTime != "" {
+		version.BuildTime = BuildTime
+	}
+}
+
+func init() {
+	applyBuildMetadata()
+
+	versionCmd.Flags().Bool("json", false, "Print version information as JSON")
+	rootCmd.AddCommand(versionCmd)
+}
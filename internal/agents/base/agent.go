@@ -63,6 +63,8 @@ type agent struct {
 
 	tools    []tools.BaseTool
 	provider provider.Provider
+	// providerFactory creates the providers of the agent
+	providerFactory provider.ProviderFactory
 
 	titleProvider     provider.Provider
 	summarizeProvider provider.Provider
@@ -70,13 +72,19 @@ type agent struct {
 	activeRequests sync.Map
 }
 
+// NewAgent creates an agent whose providers are created by providerFactory,
+// provider.DefaultProviderFactory when nil
 func NewAgent(
 	agentName config.AgentName,
 	sessions session.Service,
 	messages message.Service,
 	agentTools []tools.BaseTool,
+	providerFactory provider.ProviderFactory,
 ) (Service, error) {
-	agentProvider, err := createAgentProvider(agentName)
+	if providerFactory == nil {
+		providerFactory = provider.DefaultProviderFactory
+	}
+	agentProvider, err := createAgentProvider(providerFactory, agentName)
 	if err != nil {
 		return nil, err
 	}
@@ -98,10 +106,12 @@ func NewAgent(
 		messages:          messages,
 		sessions:          sessions,
 		tools:             agentTools,
+		providerFactory:   providerFactory,
 		titleProvider:     titleProvider,
 		summarizeProvider: summarizeProvider,
 		activeRequests:    sync.Map{},
 	}
+	agent.warnToolsUnavailable(agentName)
 
 	return agent, nil
 }
@@ -110,6 +120,25 @@ func (a *agent) Model() models.Model {
 	return a.provider.Model()
 }
 
+// offeredTools returns the tools the agent offers its model, none when the
+// model does not support function calling
+func (a *agent) offeredTools() []tools.BaseTool {
+	if !a.provider.Model().SupportsTools {
+		return nil
+	}
+	return a.tools
+}
+
+// warnToolsUnavailable tells the user when the model of the agent cannot use
+// its tools
+func (a *agent) warnToolsUnavailable(agentName config.AgentName) {
+	model := a.provider.Model()
+	if len(a.tools) == 0 || model.SupportsTools {
+		return
+	}
+	logging.WarnPersist(fmt.Sprintf("%s does not support tools: the %s agent can only answer in text, switch models to use tools", model.Name, agentName))
+}
+
 func (a *agent) Cancel(sessionID string) {
 	// Cancel regular requests
 	if cancelFunc, exists := a.activeRequests.LoadAndDelete(sessionID); exists {
@@ -308,7 +337,8 @@ func (a *agent) createUserMessage(ctx context.Context, sessionID, content string
 }
 
 func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msgHistory []message.Message) (message.Message, *message.Message, error) {
-	eventChan := a.provider.StreamResponse(ctx, msgHistory, a.tools)
+	agentTools := a.offeredTools()
+	eventChan := a.provider.StreamResponse(ctx, msgHistory, agentTools)
 
 	assistantMsg, err := a.messages.Create(ctx, sessionID, message.CreateMessageParams{
 		Role:  message.Assistant,
@@ -337,6 +367,10 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 
 	toolResults := make([]message.ToolResult, len(assistantMsg.ToolCalls()))
 	toolCalls := assistantMsg.ToolCalls()
+	turnID, step := message.TurnStep(msgHistory)
+	if turnID == "" {
+		turnID = assistantMsg.ID
+	}
 	for i, toolCall := range toolCalls {
 		select {
 		case <-ctx.Done():
@@ -353,7 +387,7 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 		default:
 			// Continue processing
 			var tool tools.BaseTool
-			for _, availableTools := range a.tools {
+			for _, availableTools := range agentTools {
 				if availableTools.Info().Name == toolCall.Name {
 					tool = availableTools
 				}
@@ -368,11 +402,15 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 				}
 				continue
 			}
-			toolResult, toolErr := tool.Run(ctx, tools.ToolCall{
+			call := tools.ToolCall{
 				ID:    toolCall.ID,
 				Name:  toolCall.Name,
 				Input: toolCall.Input,
-			})
+			}
+			// The operation ID lets the file tools apply a call dispatched
+			// again, or made again when the turn is retried, only once
+			toolCtx := tools.WithOperationID(ctx, tools.OperationID(turnID, step, i, call))
+			toolResult, toolErr := tool.Run(toolCtx, call)
 			if toolErr != nil {
 				if errors.Is(toolErr, permission.ErrorPermissionDenied) {
 					toolResults[i] = message.ToolResult{
@@ -478,14 +516,11 @@ func (a *agent) TrackUsage(ctx context.Context, sessionID string, model models.M
 		return fmt.Errorf("failed to get session: %w", err)
 	}
 
-	cost := model.CostPer1MInCached/1e6*float64(usage.CacheCreationTokens) +
-		model.CostPer1MOutCached/1e6*float64(usage.CacheReadTokens) +
-		model.CostPer1MIn/1e6*float64(usage.InputTokens) +
-		model.CostPer1MOut/1e6*float64(usage.OutputTokens)
-
-	sess.Cost += cost
-	sess.CompletionTokens = usage.OutputTokens + usage.CacheReadTokens
-	sess.PromptTokens = usage.InputTokens + usage.CacheCreationTokens
+	sess.Cost += usage.Cost(model)
+	sess.CompletionTokens = usage.OutputTokens
+	sess.PromptTokens = usage.PromptTokens()
+	sess.CacheReadTokens += usage.CacheReadTokens
+	sess.CacheWriteTokens += usage.CacheCreationTokens
 
 	_, err = a.sessions.Save(ctx, sess)
 	if err != nil {
@@ -503,12 +538,13 @@ func (a *agent) Update(agentName config.AgentName, modelID models.ModelID) (mode
 		return models.Model{}, fmt.Errorf("failed to update config: %w", err)
 	}
 
-	provider, err := createAgentProvider(agentName)
+	provider, err := createAgentProvider(a.providerFactory, agentName)
 	if err != nil {
 		return models.Model{}, fmt.Errorf("failed to create provider for model %s: %w", modelID, err)
 	}
 
 	a.provider = provider
+	a.warnToolsUnavailable(agentName)
 
 	return a.provider.Model(), nil
 }
@@ -655,11 +691,9 @@ func (a *agent) Summarize(ctx context.Context, sessionID string) error {
 		oldSession.PromptTokens = 0
 		model := a.summarizeProvider.Model()
 		usage := response.Usage
-		cost := model.CostPer1MInCached/1e6*float64(usage.CacheCreationTokens) +
-			model.CostPer1MOutCached/1e6*float64(usage.CacheReadTokens) +
-			model.CostPer1MIn/1e6*float64(usage.InputTokens) +
-			model.CostPer1MOut/1e6*float64(usage.OutputTokens)
-		oldSession.Cost += cost
+		oldSession.Cost += usage.Cost(model)
+		oldSession.CacheReadTokens += usage.CacheReadTokens
+		oldSession.CacheWriteTokens += usage.CacheCreationTokens
 		_, err = a.sessions.Save(summarizeCtx, oldSession)
 		if err != nil {
 			event = AgentEvent{
@@ -683,7 +717,7 @@ func (a *agent) Summarize(ctx context.Context, sessionID string) error {
 	return nil
 }
 
-func createAgentProvider(agentName config.AgentName) (provider.Provider, error) {
+func createAgentProvider(providerFactory provider.ProviderFactory, agentName config.AgentName) (provider.Provider, error) {
 	cfg := config.Get()
 	agentConfig, ok := cfg.Agents[agentName]
 	if !ok {
@@ -698,16 +732,11 @@ func createAgentProvider(agentName config.AgentName) (provider.Provider, error)
 	if !ok {
 		return nil, fmt.Errorf("provider %s not supported", model.Provider)
 	}
-	if providerCfg.Disabled {
-		return nil, fmt.Errorf("provider %s is not enabled", model.Provider)
-	}
 	maxTokens := model.DefaultMaxTokens
 	if agentConfig.MaxTokens > 0 {
 		maxTokens = agentConfig.MaxTokens
 	}
 	opts := []provider.ProviderClientOption{
-		provider.WithAPIKey(providerCfg.APIKey),
-		provider.WithModel(model),
 		provider.WithSystemMessage(prompt.GetAgentPrompt(agentName, model.Provider)),
 		provider.WithMaxTokens(maxTokens),
 	}
@@ -718,18 +747,17 @@ func createAgentProvider(agentName config.AgentName) (provider.Provider, error)
 				provider.WithReasoningEffort(agentConfig.ReasoningEffort),
 			),
 		)
-	} else if model.Provider == models.ProviderAnthropic && model.CanReason && agentName == config.AgentCaronex {
-		opts = append(
-			opts,
-			provider.WithAnthropicOptions(
-				provider.WithAnthropicShouldThinkFn(provider.DefaultShouldThinkFn),
-			),
-		)
+	} else if model.Provider == models.ProviderAnthropic {
+		var anthropicOpts []provider.AnthropicOption
+		if model.CanReason && agentName == config.AgentCaronex {
+			anthropicOpts = append(anthropicOpts, provider.WithAnthropicShouldThinkFn(provider.DefaultShouldThinkFn))
+		}
+		if providerCfg.DisableCache {
+			anthropicOpts = append(anthropicOpts, provider.WithAnthropicDisableCache())
+		}
+		opts = append(opts, provider.WithAnthropicOptions(anthropicOpts...))
 	}
-	agentProvider, err := provider.NewProvider(
-		model.Provider,
-		opts...,
-	)
+	agentProvider, err := providerFactory.NewProvider(model.ID, providerCfg, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("could not create provider: %v", err)
 	}
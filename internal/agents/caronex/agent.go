@@ -3,11 +3,14 @@ package caronex
 import (
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/caronex/intelligence-interface/internal/agents/base"
 	"github.com/caronex/intelligence-interface/internal/core/config"
 	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/llm/provider"
 	"github.com/caronex/intelligence-interface/internal/llm/tools"
 	"github.com/caronex/intelligence-interface/internal/tools/coordination"
 	"github.com/caronex/intelligence-interface/internal/session"
@@ -22,32 +25,40 @@ type CaronexAgent struct {
 	// Manager-specific capabilities
 	coordinationTools   *coordination.Manager
 	systemState        *SystemState
+	stateHistory       []*SystemState
+	stateMu            sync.RWMutex
+	stopRefresh        chan struct{}
+	stopOnce           sync.Once
 	agentRegistry      map[config.AgentName]*AgentInfo
+	registryMu         sync.Mutex
 	
 	// Manager personality and behavior
 	managerPersonality *ManagerPersonality
 	coordinationMode   string
 }
 
-// AgentInfo contains information about available agents
-type AgentInfo struct {
-	Name           config.AgentName
-	Capabilities   []string
-	Specialization *config.AgentSpecialization
-	Status         AgentStatus
-	LastSeen       time.Time
-}
+// AgentInfo contains information about available agents. It is defined by
+// the coordination manager, which lists the agents of the registry.
+type AgentInfo = coordination.AgentInfo
 
-// AgentStatus represents the current status of an agent
-type AgentStatus string
+// Agent statuses of AgentInfo
+const (
+	AgentStatusAvailable = coordination.AgentStatusAvailable
+	AgentStatusBusy      = coordination.AgentStatusBusy
+	AgentStatusOffline   = coordination.AgentStatusOffline
+)
 
 const (
-	AgentStatusAvailable AgentStatus = "available"
-	AgentStatusBusy      AgentStatus = "busy"
-	AgentStatusOffline   AgentStatus = "offline"
+	// SystemStateRefreshInterval is how often the system state is refreshed
+	SystemStateRefreshInterval = 30 * time.Second
+	// SystemStateHistorySize is the number of snapshots kept, the oldest
+	// being evicted first
+	SystemStateHistorySize = 10
 )
 
-// SystemState represents the current state of the Intelligence Interface system
+// SystemState represents the current state of the Intelligence Interface
+// system. Each refresh creates a new snapshot, so a state is never modified
+// once it has been returned.
 type SystemState struct {
 	AvailableAgents    []string
 	ConfigurationHash  string
@@ -55,6 +66,15 @@ type SystemState struct {
 	LastUpdated        time.Time
 	EvolutionEnabled   bool
 	SpacesSupported    bool
+
+	// AgentStatuses maps each agent, Caronex included, to its AgentStatus
+	AgentStatuses map[string]string
+	// ActiveTasks are the unfinished steps of the latest plan, as task_id/step_id
+	ActiveTasks []string
+	// LastIntrospection is when the snapshot was taken
+	LastIntrospection time.Time
+	// SpaceStates maps each configured space to "active" or "inactive"
+	SpaceStates map[string]string
 }
 
 // ManagerPersonality defines the behavior patterns for Caronex manager
@@ -66,14 +86,17 @@ type ManagerPersonality struct {
 	SystemAware          bool
 }
 
-// NewCaronexAgent creates a new Caronex manager agent with coordination capabilities
-func NewCaronexAgent(cfg *config.Config, sessionService session.Service, messageService message.Service) (*CaronexAgent, error) {
+// NewCaronexAgent creates a new Caronex manager agent with coordination
+// capabilities, whose providers are created by providerFactory,
+// provider.DefaultProviderFactory when nil
+func NewCaronexAgent(cfg *config.Config, sessionService session.Service, messageService message.Service, providerFactory provider.ProviderFactory) (*CaronexAgent, error) {
 	// Create base service with manager-specific configuration  
 	baseService, err := base.NewAgent(
 		config.AgentCaronex,
 		sessionService,
 		messageService,
 		[]tools.BaseTool{}, // No special tools for manager agent
+		providerFactory,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create base service for CaronexAgent: %w", err)
@@ -117,35 +140,32 @@ func NewCaronexAgent(cfg *config.Config, sessionService session.Service, message
 		config:             cfg,
 		coordinationTools:  coordinationTools,
 		systemState:       systemState,
-		agentRegistry:     make(map[config.AgentName]*AgentInfo),
+		stopRefresh:       make(chan struct{}),
 		managerPersonality: managerPersonality,
 		coordinationMode:   coordinationMode,
 	}
-
-	// Initialize agent registry with available agents
-	err = caronexAgent.initializeAgentRegistry()
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize agent registry: %w", err)
-	}
+	coordinationTools.SetAgentRegistry(caronexAgent)
 
 	// Update system state with current configuration
 	err = caronexAgent.updateSystemState()
 	if err != nil {
 		logging.Error("Failed to update initial system state", "error", err)
 	}
+	changes, stopWatch := config.Watch()
+	go caronexAgent.refreshSystemState(SystemStateRefreshInterval, changes, stopWatch)
 
 	logging.Info("CaronexAgent initialized successfully", 
 		"coordination_mode", coordinationMode,
-		"available_agents", len(caronexAgent.agentRegistry))
+		"available_agents", len(caronexAgent.GetAgentRegistry()))
 
 	return caronexAgent, nil
 }
 
-// initializeAgentRegistry sets up the registry of available agents
-func (c *CaronexAgent) initializeAgentRegistry() error {
-	logging.Debug("Initializing agent registry")
-	
-	// Register all configured agents
+// buildAgentRegistry builds the registry of the agents Caronex delegates to
+func (c *CaronexAgent) buildAgentRegistry() map[config.AgentName]*AgentInfo {
+	logging.Debug("Building agent registry")
+
+	registry := make(map[config.AgentName]*AgentInfo, len(c.config.Agents))
 	for agentName, agentConfig := range c.config.Agents {
 		// Skip self-registration
 		if agentName == config.AgentCaronex {
@@ -154,18 +174,19 @@ func (c *CaronexAgent) initializeAgentRegistry() error {
 
 		agentInfo := &AgentInfo{
 			Name:           agentName,
+			Model:          agentConfig.Model,
 			Capabilities:   c.getAgentCapabilities(agentName),
 			Specialization: agentConfig.Specialization,
 			Status:         AgentStatusAvailable,
-			LastSeen:       time.Now(),
+			NoTools:        models.LacksTools(agentConfig.Model),
 		}
 
-		c.agentRegistry[agentName] = agentInfo
+		registry[agentName] = agentInfo
 		logging.Debug("Registered agent", "name", agentName, "capabilities", agentInfo.Capabilities)
 	}
 
-	logging.Info("Agent registry initialized", "total_agents", len(c.agentRegistry))
-	return nil
+	logging.Info("Agent registry built", "total_agents", len(registry))
+	return registry
 }
 
 // getAgentCapabilities returns the capabilities of a specific agent
@@ -178,31 +199,101 @@ func (c *CaronexAgent) getAgentCapabilities(agentName config.AgentName) []string
 	}
 }
 
-// updateSystemState refreshes the current system state information
+// refreshSystemState takes a new system state snapshot every interval, and
+// rebuilds the agent registry when the configuration changes, until the agent
+// is closed
+func (c *CaronexAgent) refreshSystemState(interval time.Duration, changes <-chan struct{}, stopWatch func()) {
+	defer stopWatch()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stopRefresh:
+			return
+		case <-changes:
+			c.registryMu.Lock()
+			c.agentRegistry = nil
+			c.registryMu.Unlock()
+		case <-ticker.C:
+		}
+		if err := c.updateSystemState(); err != nil {
+			logging.Error("Failed to refresh system state", "error", err)
+		}
+	}
+}
+
+// Close stops refreshing the system state
+func (c *CaronexAgent) Close() {
+	c.stopOnce.Do(func() { close(c.stopRefresh) })
+}
+
+// updateSystemState takes a new snapshot of the system state, making it the
+// current state and adding it to the history
 func (c *CaronexAgent) updateSystemState() error {
 	logging.Debug("Updating system state")
 
+	c.stateMu.RLock()
+	previous := c.systemState
+	c.stateMu.RUnlock()
+	registry := c.GetAgentRegistry()
+	state := &SystemState{
+		EvolutionEnabled:  previous.EvolutionEnabled,
+		SpacesSupported:   previous.SpacesSupported,
+		AgentStatuses:     make(map[string]string, len(registry)+1),
+		ActiveTasks:       []string{},
+		LastIntrospection: time.Now(),
+		SpaceStates:       make(map[string]string, len(c.config.Spaces)),
+	}
+
 	// Update available agents list
-	availableAgents := make([]string, 0, len(c.agentRegistry))
-	for agentName, agentInfo := range c.agentRegistry {
+	availableAgents := make([]string, 0, len(registry))
+	for agentName, agentInfo := range registry {
 		if agentInfo.Status == AgentStatusAvailable {
 			availableAgents = append(availableAgents, string(agentName))
 		}
+		state.AgentStatuses[string(agentName)] = agentInfo.Status
+	}
+	state.AvailableAgents = availableAgents
+	state.AgentStatuses[string(config.AgentCaronex)] = AgentStatusAvailable
+	if c.Service.IsBusy() {
+		state.AgentStatuses[string(config.AgentCaronex)] = AgentStatusBusy
+	}
+
+	if plan := coordination.LatestPlan(); plan != nil {
+		for _, step := range plan.Steps {
+			if !step.Finished() {
+				state.ActiveTasks = append(state.ActiveTasks, plan.TaskID+"/"+step.StepID)
+			}
+		}
+	}
+
+	for id := range c.config.Spaces {
+		state.SpaceStates[id] = "inactive"
+		if id == config.ActiveSpace() {
+			state.SpaceStates[id] = "active"
+		}
 	}
-	c.systemState.AvailableAgents = availableAgents
 
 	// Update system capabilities based on available agents
 	capabilities := []string{"system_coordination", "agent_management", "planning_assistance"}
-	for _, agentInfo := range c.agentRegistry {
+	for _, agentInfo := range registry {
 		if agentInfo.Status == AgentStatusAvailable {
 			capabilities = append(capabilities, agentInfo.Capabilities...)
 		}
 	}
-	c.systemState.SystemCapabilities = capabilities
+	state.SystemCapabilities = capabilities
 
 	// Update configuration hash for change detection
-	c.systemState.ConfigurationHash = c.generateConfigurationHash()
-	c.systemState.LastUpdated = time.Now()
+	state.ConfigurationHash = c.generateConfigurationHash()
+	state.LastUpdated = state.LastIntrospection
+
+	c.stateMu.Lock()
+	c.systemState = state
+	c.stateHistory = append(c.stateHistory, state)
+	if len(c.stateHistory) > SystemStateHistorySize {
+		c.stateHistory = c.stateHistory[len(c.stateHistory)-SystemStateHistorySize:]
+	}
+	c.stateMu.Unlock()
 
 	logging.Debug("System state updated", 
 		"available_agents", len(availableAgents),
@@ -221,14 +312,32 @@ func (c *CaronexAgent) generateConfigurationHash() string {
 	return fmt.Sprintf("config_%d", len(strings.Join(configElements, "|")))
 }
 
-// GetSystemState returns the current system state for introspection
+// GetSystemState returns the current system state for introspection, at
+// most SystemStateRefreshInterval old
 func (c *CaronexAgent) GetSystemState() *SystemState {
-	c.updateSystemState() // Ensure current state
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
 	return c.systemState
 }
 
-// GetAgentRegistry returns information about all registered agents
+// GetSystemStateHistory returns the last SystemStateHistorySize snapshots of
+// the system state, oldest first
+func (c *CaronexAgent) GetSystemStateHistory() []*SystemState {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
+	return append([]*SystemState(nil), c.stateHistory...)
+}
+
+// GetAgentRegistry returns information about the agents Caronex delegates to,
+// every configured agent but itself. The
+// registry is built on the first call and rebuilt after the configuration
+// changes; a returned registry is never modified.
 func (c *CaronexAgent) GetAgentRegistry() map[config.AgentName]*AgentInfo {
+	c.registryMu.Lock()
+	defer c.registryMu.Unlock()
+	if c.agentRegistry == nil {
+		c.agentRegistry = c.buildAgentRegistry()
+	}
 	return c.agentRegistry
 }
 
@@ -263,6 +372,6 @@ func (c *CaronexAgent) GetManagerPersonality() *ManagerPersonality {
 func (c *CaronexAgent) String() string {
 	return fmt.Sprintf("CaronexAgent(coordination_mode=%s, agents=%d, capabilities=%d)",
 		c.coordinationMode,
-		len(c.agentRegistry),
-		len(c.systemState.SystemCapabilities))
+		len(c.GetAgentRegistry()),
+		len(c.GetSystemState().SystemCapabilities))
 }
\ No newline at end of file
@@ -199,6 +199,9 @@ func buildAgentCapabilitiesSummary(agentRegistry map[config.AgentName]*AgentInfo
 	for agentName, agentInfo := range agentRegistry {
 		summary.WriteString(fmt.Sprintf("**%s Agent** (%s):\n", strings.Title(string(agentName)), agentInfo.Status))
 		summary.WriteString(fmt.Sprintf("  - Capabilities: %s\n", strings.Join(agentInfo.Capabilities, ", ")))
+		if agentInfo.NoTools {
+			summary.WriteString("  - Tools: unavailable, its model does not support them; do not delegate steps that require tools to it, suggest a model change instead\n")
+		}
 		
 		if agentInfo.Specialization != nil {
 			summary.WriteString(fmt.Sprintf("  - Specialization: %s\n", agentInfo.Specialization.CoordinationMode))
@@ -231,36 +234,36 @@ func buildSystemContext(cfg *config.Config) string {
 
 // GetSystemPrompt returns the system prompt with current context
 func (c *CaronexAgent) GetSystemPrompt() string {
-	template := NewManagerPromptTemplate(c.config, c.agentRegistry)
+	template := NewManagerPromptTemplate(c.config, c.GetAgentRegistry())
 	return template.SystemPrompt
 }
 
 // GetCoordinationPrompt returns the coordination prompt
 func (c *CaronexAgent) GetCoordinationPrompt() string {
-	template := NewManagerPromptTemplate(c.config, c.agentRegistry)
+	template := NewManagerPromptTemplate(c.config, c.GetAgentRegistry())
 	return template.CoordinationPrompt
 }
 
 // GetPlanningPrompt returns the planning prompt
 func (c *CaronexAgent) GetPlanningPrompt() string {
-	template := NewManagerPromptTemplate(c.config, c.agentRegistry)
+	template := NewManagerPromptTemplate(c.config, c.GetAgentRegistry())
 	return template.PlanningPrompt
 }
 
 // GetDelegationPrompt returns the delegation prompt
 func (c *CaronexAgent) GetDelegationPrompt() string {
-	template := NewManagerPromptTemplate(c.config, c.agentRegistry)
+	template := NewManagerPromptTemplate(c.config, c.GetAgentRegistry())
 	return template.DelegationPrompt
 }
 
 // GetIntrospectionPrompt returns the introspection prompt
 func (c *CaronexAgent) GetIntrospectionPrompt() string {
-	template := NewManagerPromptTemplate(c.config, c.agentRegistry)
+	template := NewManagerPromptTemplate(c.config, c.GetAgentRegistry())
 	return template.IntrospectionPrompt
 }
 
 // GetEvolutionPrompt returns the evolution prompt
 func (c *CaronexAgent) GetEvolutionPrompt() string {
-	template := NewManagerPromptTemplate(c.config, c.agentRegistry)
+	template := NewManagerPromptTemplate(c.config, c.GetAgentRegistry())
 	return template.EvolutionPrompt
 }
\ No newline at end of file
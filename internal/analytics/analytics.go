@@ -0,0 +1,278 @@
+// Package analytics aggregates local usage metrics into daily rollups. Nothing
+// is ever sent anywhere: rollups live in the application database inside
+// Data.Directory and are not tied to any session, so session data never
+// includes them.
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/db"
+	"github.com/caronex/intelligence-interface/internal/pubsub"
+)
+
+// Kind identifies the dimension a metric event is rolled up under
+type Kind string
+
+const (
+	KindAgent    Kind = "agent"    // messages sent per agent
+	KindTool     Kind = "tool"     // tool calls per tool
+	KindProvider Kind = "provider" // provider calls, with latency and failures
+	KindHour     Kind = "hour"     // messages per hour of day
+
+	KindProviderError Kind = "provider_error" // failed provider calls per error category
+	KindEmbedding     Kind = "embedding"      // embedding requests per model, with their tokens
+	KindBatch         Kind = "batch"          // batch requests per model, with their tokens and discounted cost
+)
+
+// DayFormat is the layout used for rollup days
+const DayFormat = "2006-01-02"
+
+// Event is a single usage metric
+type Event struct {
+	Kind    Kind
+	Name    string
+	Latency time.Duration
+	Failed  bool
+	// Tokens are the tokens the event used, kept apart from the tokens of
+	// the sessions
+	Tokens int64
+	// Cost is the price of the event, kept apart from the cost of the
+	// sessions
+	Cost float64
+	Time time.Time
+}
+
+var broker = pubsub.NewBroker[Event]()
+
+// Record publishes a usage event to the aggregator
+func Record(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	broker.Publish(pubsub.CreatedEvent, event)
+}
+
+// RecordMessage records a user message sent to an agent, including its hour of day
+func RecordMessage(agent string) {
+	now := time.Now()
+	Record(Event{Kind: KindAgent, Name: agent, Time: now})
+	Record(Event{Kind: KindHour, Name: fmt.Sprintf("%02d", now.Hour()), Time: now})
+}
+
+// Subscribe returns a channel of usage events
+func Subscribe(ctx context.Context) <-chan pubsub.Event[Event] {
+	return broker.Subscribe(ctx)
+}
+
+// Rollup is the aggregate of one kind/name pair on one day
+type Rollup struct {
+	Day          string
+	Kind         Kind
+	Name         string
+	Count        int64
+	Failures     int64
+	TotalLatency time.Duration
+	Tokens       int64
+	Cost         float64
+}
+
+// AverageLatency returns the mean latency of the rolled up events
+func (r Rollup) AverageLatency() time.Duration {
+	if r.Count == 0 {
+		return 0
+	}
+	return r.TotalLatency / time.Duration(r.Count)
+}
+
+// FailureRate returns the fraction of rolled up events that failed
+func (r Rollup) FailureRate() float64 {
+	if r.Count == 0 {
+		return 0
+	}
+	return float64(r.Failures) / float64(r.Count)
+}
+
+// Summary aggregates rollups over a date range
+type Summary struct {
+	From, To time.Time
+	// Days lists every day in the range with the number of messages sent
+	Days      []Rollup
+	Agents    []Rollup
+	Tools     []Rollup
+	Providers []Rollup
+	// ProviderErrors counts the failed provider calls per error category
+	ProviderErrors []Rollup
+	// Embeddings are the embedding requests and tokens per model
+	Embeddings []Rollup
+	// Batches are the requests, tokens and cost at the batch price of the
+	// batch APIs per model
+	Batches []Rollup
+	Hours   [24]int64
+}
+
+type Service interface {
+	// Start consumes usage events until ctx is done
+	Start(ctx context.Context)
+	Add(ctx context.Context, event Event) error
+	Rollups(ctx context.Context, from, to time.Time) ([]Rollup, error)
+	Summary(ctx context.Context, from, to time.Time) (Summary, error)
+	Reset(ctx context.Context) error
+}
+
+type service struct {
+	q db.Querier
+}
+
+// Start subscribes to usage events and folds each one into its daily rollup,
+// so reading stats never has to rescan raw history.
+func (s *service) Start(ctx context.Context) {
+	events := Subscribe(ctx)
+	go func() {
+		defer logging.RecoverPanic("analytics-aggregator", nil)
+		for event := range events {
+			if err := s.Add(context.Background(), event.Payload); err != nil {
+				logging.Warn("Failed to record analytics event", "kind", event.Payload.Kind, "error", err, logging.ComponentKey, logging.ComponentDataLoss)
+			}
+		}
+	}()
+}
+
+func (s *service) Add(ctx context.Context, event Event) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	var failures int64
+	if event.Failed {
+		failures = 1
+	}
+	return s.q.UpsertAnalyticsRollup(ctx, db.UpsertAnalyticsRollupParams{
+		Day:            event.Time.Format(DayFormat),
+		Kind:           string(event.Kind),
+		Name:           event.Name,
+		Count:          1,
+		Failures:       failures,
+		TotalLatencyMs: event.Latency.Milliseconds(),
+		Tokens:         event.Tokens,
+		Cost:           event.Cost,
+	})
+}
+
+func (s *service) Rollups(ctx context.Context, from, to time.Time) ([]Rollup, error) {
+	dbRollups, err := s.q.ListAnalyticsRollups(ctx, db.ListAnalyticsRollupsParams{
+		FromDay: from.Format(DayFormat),
+		ToDay:   to.Format(DayFormat),
+	})
+	if err != nil {
+		return nil, err
+	}
+	rollups := make([]Rollup, len(dbRollups))
+	for i, r := range dbRollups {
+		rollups[i] = Rollup{
+			Day:          r.Day,
+			Kind:         Kind(r.Kind),
+			Name:         r.Name,
+			Count:        r.Count,
+			Failures:     r.Failures,
+			TotalLatency: time.Duration(r.TotalLatencyMs) * time.Millisecond,
+			Tokens:       r.Tokens,
+			Cost:         r.Cost,
+		}
+	}
+	return rollups, nil
+}
+
+func (s *service) Summary(ctx context.Context, from, to time.Time) (Summary, error) {
+	rollups, err := s.Rollups(ctx, from, to)
+	if err != nil {
+		return Summary{}, err
+	}
+	return Summarize(from, to, rollups), nil
+}
+
+func (s *service) Reset(ctx context.Context) error {
+	return s.q.DeleteAnalyticsRollups(ctx)
+}
+
+// Summarize merges daily rollups into per-name totals for the range
+func Summarize(from, to time.Time, rollups []Rollup) Summary {
+	summary := Summary{From: from, To: to}
+
+	messagesPerDay := make(map[string]int64)
+	totals := map[Kind]map[string]*Rollup{
+		KindAgent:    {},
+		KindTool:     {},
+		KindProvider: {},
+
+		KindProviderError: {},
+		KindEmbedding:     {},
+		KindBatch:         {},
+	}
+	for _, r := range rollups {
+		switch r.Kind {
+		case KindHour:
+			var hour int
+			if _, err := fmt.Sscanf(r.Name, "%d", &hour); err == nil && hour >= 0 && hour < 24 {
+				summary.Hours[hour] += r.Count
+			}
+			continue
+		case KindAgent:
+			messagesPerDay[r.Day] += r.Count
+		}
+		byName, ok := totals[r.Kind]
+		if !ok {
+			continue
+		}
+		total, ok := byName[r.Name]
+		if !ok {
+			total = &Rollup{Kind: r.Kind, Name: r.Name}
+			byName[r.Name] = total
+		}
+		total.Count += r.Count
+		total.Failures += r.Failures
+		total.TotalLatency += r.TotalLatency
+		total.Tokens += r.Tokens
+		total.Cost += r.Cost
+	}
+
+	for day := startOfDay(from); !day.After(to); day = day.AddDate(0, 0, 1) {
+		key := day.Format(DayFormat)
+		summary.Days = append(summary.Days, Rollup{Day: key, Kind: KindAgent, Count: messagesPerDay[key]})
+	}
+	summary.Agents = sortedTotals(totals[KindAgent])
+	summary.Tools = sortedTotals(totals[KindTool])
+	summary.Providers = sortedTotals(totals[KindProvider])
+	summary.ProviderErrors = sortedTotals(totals[KindProviderError])
+	summary.Embeddings = sortedTotals(totals[KindEmbedding])
+	summary.Batches = sortedTotals(totals[KindBatch])
+	return summary
+}
+
+func sortedTotals(byName map[string]*Rollup) []Rollup {
+	result := make([]Rollup, 0, len(byName))
+	for _, r := range byName {
+		result = append(result, *r)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+func NewService(q db.Querier) Service {
+	return &service{
+		q: q,
+	}
+}
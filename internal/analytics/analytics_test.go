@@ -0,0 +1,59 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSummarize(t *testing.T) {
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local)
+	to := time.Date(2025, 3, 3, 12, 0, 0, 0, time.Local)
+	rollups := []Rollup{
+		{Day: "2025-03-01", Kind: KindAgent, Name: "caronex", Count: 2},
+		{Day: "2025-03-03", Kind: KindAgent, Name: "caronex", Count: 3},
+		{Day: "2025-03-01", Kind: KindHour, Name: "09", Count: 2},
+		{Day: "2025-03-01", Kind: KindProvider, Name: "anthropic", Count: 2, Failures: 1, TotalLatency: 3 * time.Second},
+		{Day: "2025-03-03", Kind: KindProvider, Name: "anthropic", Count: 2, TotalLatency: time.Second},
+		{Day: "2025-03-03", Kind: KindTool, Name: "view", Count: 1},
+		{Day: "2025-03-03", Kind: KindTool, Name: "bash", Count: 4},
+		{Day: "2025-03-01", Kind: KindProviderError, Name: "rate-limit", Count: 1},
+		{Day: "2025-03-03", Kind: KindProviderError, Name: "rate-limit", Count: 2},
+		{Day: "2025-03-03", Kind: KindProviderError, Name: "auth", Count: 1},
+		{Day: "2025-03-01", Kind: KindEmbedding, Name: "text-embedding-3-small", Count: 2, Tokens: 900},
+		{Day: "2025-03-03", Kind: KindEmbedding, Name: "text-embedding-3-small", Count: 1, Tokens: 300},
+		{Day: "2025-03-01", Kind: KindBatch, Name: "gpt-4.1", Count: 4, Failures: 1, Tokens: 2000, Cost: 0.25},
+		{Day: "2025-03-03", Kind: KindBatch, Name: "gpt-4.1", Count: 2, Tokens: 1000, Cost: 0.5},
+	}
+
+	summary := Summarize(from, to, rollups)
+
+	if len(summary.Days) != 3 {
+		t.Fatalf("Expected one entry per day in the range, got %d", len(summary.Days))
+	}
+	if summary.Days[1].Count != 0 || summary.Days[2].Count != 3 {
+		t.Errorf("Unexpected messages per day: %+v", summary.Days)
+	}
+	if summary.Hours[9] != 2 {
+		t.Errorf("Expected 2 messages at 09h, got %d", summary.Hours[9])
+	}
+	if len(summary.Agents) != 1 || summary.Agents[0].Count != 5 {
+		t.Errorf("Agent totals should merge days, got %+v", summary.Agents)
+	}
+
+	provider := summary.Providers[0]
+	if provider.AverageLatency() != time.Second || provider.FailureRate() != 0.25 {
+		t.Errorf("Unexpected provider aggregate: avg %s, failure rate %v", provider.AverageLatency(), provider.FailureRate())
+	}
+	if summary.Tools[0].Name != "bash" {
+		t.Errorf("Tools should be sorted by count, got %+v", summary.Tools)
+	}
+	if len(summary.ProviderErrors) != 2 || summary.ProviderErrors[0].Name != "rate-limit" || summary.ProviderErrors[0].Count != 3 {
+		t.Errorf("Provider errors should be totaled per category, got %+v", summary.ProviderErrors)
+	}
+	if len(summary.Embeddings) != 1 || summary.Embeddings[0].Count != 3 || summary.Embeddings[0].Tokens != 1200 {
+		t.Errorf("Embedding tokens should be totaled per model, got %+v", summary.Embeddings)
+	}
+	if len(summary.Batches) != 1 || summary.Batches[0].Count != 6 || summary.Batches[0].Tokens != 3000 || summary.Batches[0].Cost != 0.75 {
+		t.Errorf("Batch requests should be totaled per model with their cost, got %+v", summary.Batches)
+	}
+}
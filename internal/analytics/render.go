@@ -0,0 +1,99 @@
+package analytics
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+var sparkLevels = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline renders values as a single line of block characters
+func Sparkline(values []int64) string {
+	var peak int64
+	for _, v := range values {
+		peak = max(peak, v)
+	}
+	var b strings.Builder
+	for _, v := range values {
+		if peak == 0 {
+			b.WriteRune(sparkLevels[0])
+			continue
+		}
+		level := int(v * int64(len(sparkLevels)-1) / peak)
+		b.WriteRune(sparkLevels[level])
+	}
+	return b.String()
+}
+
+// Bar renders a horizontal bar of value relative to peak, at most width cells wide
+func Bar(value, peak int64, width int) string {
+	if peak <= 0 || width <= 0 {
+		return ""
+	}
+	cells := int(value * int64(width) / peak)
+	if cells == 0 && value > 0 {
+		cells = 1
+	}
+	return strings.Repeat("█", cells)
+}
+
+// Render formats a summary as plain text with bars and sparklines
+func Render(summary Summary, width int) string {
+	if width <= 0 {
+		width = 80
+	}
+	barWidth := max(width-40, 10)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Usage from %s to %s\n\n", summary.From.Format(DayFormat), summary.To.Format(DayFormat))
+
+	daily := make([]int64, len(summary.Days))
+	var totalMessages int64
+	for i, d := range summary.Days {
+		daily[i] = d.Count
+		totalMessages += d.Count
+	}
+	fmt.Fprintf(&b, "Messages per day (%d total)\n  %s\n\n", totalMessages, Sparkline(daily))
+
+	fmt.Fprintf(&b, "Messages by hour of day\n  %s\n  0     6     12    18   23\n\n", Sparkline(summary.Hours[:]))
+
+	writeSection(&b, "Messages per agent", summary.Agents, barWidth, func(r Rollup) string {
+		return fmt.Sprintf("%d", r.Count)
+	})
+	writeSection(&b, "Tool calls per tool", summary.Tools, barWidth, func(r Rollup) string {
+		return fmt.Sprintf("%d (%.0f%% failed)", r.Count, r.FailureRate()*100)
+	})
+	writeSection(&b, "Provider calls", summary.Providers, barWidth, func(r Rollup) string {
+		return fmt.Sprintf("%d, avg %s (%.0f%% failed)", r.Count, r.AverageLatency().Round(time.Millisecond), r.FailureRate()*100)
+	})
+	writeSection(&b, "Provider errors per category", summary.ProviderErrors, barWidth, func(r Rollup) string {
+		return fmt.Sprintf("%d", r.Count)
+	})
+	writeSection(&b, "Embedding requests per model", summary.Embeddings, barWidth, func(r Rollup) string {
+		return fmt.Sprintf("%d, %d tokens", r.Count, r.Tokens)
+	})
+	writeSection(&b, "Batch requests per model", summary.Batches, barWidth, func(r Rollup) string {
+		return fmt.Sprintf("%d, %d tokens, $%.4f at the batch price", r.Count, r.Tokens, r.Cost)
+	})
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func writeSection(b *strings.Builder, title string, rollups []Rollup, barWidth int, label func(Rollup) string) {
+	fmt.Fprintln(b, title)
+	if len(rollups) == 0 {
+		fmt.Fprintf(b, "  no data\n\n")
+		return
+	}
+	nameWidth := 0
+	var peak int64
+	for _, r := range rollups {
+		nameWidth = max(nameWidth, len(r.Name))
+		peak = max(peak, r.Count)
+	}
+	for _, r := range rollups {
+		fmt.Fprintf(b, "  %-*s %s %s\n", nameWidth, r.Name, Bar(r.Count, peak, barWidth), label(r))
+	}
+	fmt.Fprintln(b)
+}
@@ -3,22 +3,34 @@ package app
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"maps"
+	"path/filepath"
 	"sync"
+	"text/template"
 	"time"
 
+	"github.com/caronex/intelligence-interface/internal/analytics"
+	"github.com/caronex/intelligence-interface/internal/batch"
 	"github.com/caronex/intelligence-interface/internal/core/config"
 	"github.com/caronex/intelligence-interface/internal/db"
 	"github.com/caronex/intelligence-interface/internal/format"
 	"github.com/caronex/intelligence-interface/internal/history"
 	"github.com/caronex/intelligence-interface/internal/llm/agent"
+	"github.com/caronex/intelligence-interface/internal/llm/embedding"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/llm/prompt"
 	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/lock"
 	"github.com/caronex/intelligence-interface/internal/lsp"
+	"github.com/caronex/intelligence-interface/internal/memory"
 	"github.com/caronex/intelligence-interface/internal/message"
 	"github.com/caronex/intelligence-interface/internal/permission"
 	"github.com/caronex/intelligence-interface/internal/session"
+	"github.com/caronex/intelligence-interface/internal/tools/coordination"
+	"github.com/caronex/intelligence-interface/internal/tracing"
 	"github.com/caronex/intelligence-interface/internal/tui/theme"
 )
 
@@ -27,11 +39,21 @@ type App struct {
 	Messages    message.Service
 	History     history.Service
 	Permissions permission.Service
+	Analytics   analytics.Service
+	// Locks keeps other instances from writing to the sessions open here
+	Locks lock.Service
+	// Memory keeps the learning observations of the agents
+	Memory memory.Service
+	// Batches runs the offline jobs that opted into the batch APIs
+	Batches batch.Service
 
 	CaronexAgent agent.Service // Caronex Manager Agent for coordination
 
 	LSPClients map[string]*lsp.Client
 
+	// Quiet hides the startup banner of the TUI
+	Quiet bool
+
 	clientsMutex sync.RWMutex
 
 	watcherCancelFuncs []context.CancelFunc
@@ -44,21 +66,80 @@ func New(ctx context.Context, conn *sql.DB) (*App, error) {
 	sessions := session.NewService(q)
 	messages := message.NewService(q)
 	files := history.NewService(q, conn)
+	stats := analytics.NewService(q)
 
 	app := &App{
 		Sessions:    sessions,
 		Messages:    messages,
 		History:     files,
 		Permissions: permission.NewPermissionService(),
+		Analytics:   stats,
+		Locks:       lock.NewService(q, lock.DefaultStaleAfter),
+		Memory:      memory.NewService(q),
+		Batches:     batch.NewService(q),
 		LSPClients:  make(map[string]*lsp.Client),
 	}
 
+	// Register the instance so other instances see the sessions it writes to
+	// as read-only
+	if err := app.Locks.Start(ctx); err != nil {
+		logging.Warn("Failed to start session locking", "error", err)
+	}
+	lock.SetCurrent(app.Locks)
+	// The learn tool and the system prompts of the agents use the memory
+	memory.SetCurrent(app.Memory)
+
+	// Finalize the responses a crash left in progress, so their sessions can
+	// go on
+	app.recoverInterrupted(ctx)
+
+	// Drop the persistent learning observations beyond the history limit
+	if cfg := config.Get(); cfg != nil && cfg.Caronex.Learning.Persistent() {
+		if pruned, err := app.Memory.Prune(ctx); err != nil {
+			logging.Warn("Failed to prune the agent memory", "error", err)
+		} else if pruned > 0 {
+			logging.Info("Pruned the agent memory", "observations", pruned)
+		}
+	}
+
+	// Ask to reindex the knowledge bases indexed with another embedder than
+	// the one now configured
+	if cfg := config.Get(); cfg != nil {
+		for _, err := range embedding.CheckIndexes(cfg) {
+			logging.WarnPersist(err.Error())
+		}
+	}
+
+	// Aggregate usage events into local daily rollups
+	app.Analytics.Start(ctx)
+
+	// Deliver the results of the batches submitted here or by a previous run
+	agent.RegisterBatchComponents(app.Batches, app.Sessions)
+	app.Batches.Start(ctx)
+
+	// Check for a newer release in the background if configured
+	if cfg := config.Get(); cfg != nil {
+		coordination.StartUpdateCheck(ctx, cfg.UpdateCheckURL)
+	}
+
+	// Tell which configuration a crash happened with
+	logging.SetCrashReportField("Config fingerprint", config.CurrentFingerprint)
+
+	// Record where the time of each turn goes
+	if cfg := config.Get(); cfg != nil {
+		tracing.Configure(cfg.Tracing)
+	}
+
 	// Initialize theme based on configuration
 	app.initTheme()
 
 	// Initialize LSP clients in the background
 	go app.initLSPClients(ctx)
 
+	// Notice context file changes so sessions follow the updated instructions
+	app.startWatcher(ctx, "context-watcher", prompt.WatchContext)
+	app.startWatcher(ctx, "mcp-servers-watcher", config.WatchMCPServersFile)
+
 	var err error
 	// Initialize Caronex Manager Agent
 	app.CaronexAgent, err = agent.NewAgent(
@@ -71,6 +152,12 @@ func New(ctx context.Context, conn *sql.DB) (*App, error) {
 		logging.Error("Failed to create caronex manager agent", err)
 		return nil, err
 	}
+	// Broadcasts from the coordination tools reach the agent
+	coordination.RegisterSystemEventHandler(ctx, config.AgentCaronex, app.CaronexAgent)
+	// Delegated tasks are worked on by the agent, and cancelling them is
+	// reported to the sessions they were delegated from
+	coordination.SetDelegationRunner(app.runDelegation)
+	coordination.SetCancellationReporter(app.reportCancellation)
 
 	return app, nil
 }
@@ -78,7 +165,15 @@ func New(ctx context.Context, conn *sql.DB) (*App, error) {
 // initTheme sets the application theme based on the configuration
 func (app *App) initTheme() {
 	cfg := config.Get()
-	if cfg == nil || cfg.TUI.Theme == "" {
+	if cfg == nil {
+		return // Use default theme
+	}
+
+	// Custom themes are selectable alongside the built-in ones. Those that
+	// fail to load are skipped with a warning, so selecting one falls back to
+	// the default theme below.
+	theme.LoadCustomThemes(filepath.Join(cfg.Data.Directory, theme.ThemesDirName))
+	if cfg.TUI.Theme == "" {
 		return // Use default theme
 	}
 
@@ -91,18 +186,160 @@ func (app *App) initTheme() {
 	}
 }
 
+// startWatcher runs a watcher of files, such as the context files, in the
+// background until the app shuts down
+func (app *App) startWatcher(ctx context.Context, name string, watch func(context.Context) error) {
+	watchCtx, cancelFunc := context.WithCancel(ctx)
+	app.cancelFuncsMutex.Lock()
+	app.watcherCancelFuncs = append(app.watcherCancelFuncs, cancelFunc)
+	app.cancelFuncsMutex.Unlock()
+
+	app.watcherWG.Add(1)
+	go func() {
+		defer app.watcherWG.Done()
+		defer logging.RecoverPanic(name, nil)
+		if err := watch(watchCtx); err != nil {
+			logging.Warn("Failed to start watcher", "watcher", name, "error", err)
+		}
+	}()
+}
+
+// NonInteractiveOptions are the options of a non-interactive run
+type NonInteractiveOptions struct {
+	// Format is the output format, used when there is no Template
+	Format format.OutputFormat
+	// Template renders the result instead of Format when set
+	Template *template.Template
+	// FailOnToolError fails the run when a tool call failed
+	FailOnToolError bool
+	// Quiet hides the spinner
+	Quiet bool
+	// MaxCost aborts the run before the prompt is sent when its estimated
+	// cost exceeds it, in dollars; 0 sets no cap
+	MaxCost float64
+}
+
 // RunNonInteractive handles the execution flow when a prompt is provided via CLI flag.
-func (a *App) RunNonInteractive(ctx context.Context, prompt string, outputFormat string, quiet bool) error {
+func (a *App) RunNonInteractive(ctx context.Context, prompt string, opts NonInteractiveOptions) error {
 	logging.Info("Running in non-interactive mode")
+	started := time.Now()
 
 	// Start spinner if not in quiet mode
 	var spinner *format.Spinner
-	if !quiet {
+	if !opts.Quiet {
 		spinner = format.NewSpinner("Thinking...")
 		spinner.Start()
 		defer spinner.Stop()
 	}
 
+	if opts.MaxCost > 0 {
+		estimate, err := a.CaronexAgent.EstimatePrompt(ctx, "", prompt)
+		if err != nil {
+			return fmt.Errorf("failed to estimate the prompt: %w", err)
+		}
+		if estimate.Cost > opts.MaxCost {
+			return fmt.Errorf("the estimated prompt cost $%.4f (~%d tokens with %s) exceeds --max-cost $%.4f, nothing was sent",
+				estimate.Cost, estimate.Tokens, estimate.Model.Name, opts.MaxCost)
+		}
+	}
+
+	sess, err := a.nonInteractiveSession(ctx, prompt)
+	if err != nil {
+		return err
+	}
+
+	done, err := a.CaronexAgent.Run(ctx, sess.ID, prompt)
+	if err != nil {
+		return fmt.Errorf("failed to start agent processing stream: %w", err)
+	}
+
+	result := <-done
+	if result.Error != nil {
+		if errors.Is(result.Error, context.Canceled) || errors.Is(result.Error, agent.ErrRequestCancelled) {
+			logging.Info("Agent processing cancelled", "session_id", sess.ID)
+			return nil
+		}
+		return fmt.Errorf("agent processing failed: %w", result.Error)
+	}
+
+	// Stop spinner before printing output
+	if !opts.Quiet && spinner != nil {
+		spinner.Stop()
+	}
+
+	// Get the text content from the response
+	content := "No content available"
+	if result.Message.Content().String() != "" {
+		content = result.Message.Content().String()
+	}
+
+	output := format.Result{
+		Content:    content,
+		ToolCalls:  a.nonInteractiveToolCalls(ctx, sess.ID),
+		DurationMs: time.Since(started).Milliseconds(),
+		Model:      string(result.Message.Model),
+		SessionID:  sess.ID,
+	}
+	if updated, err := a.Sessions.Get(ctx, sess.ID); err == nil {
+		output.Usage = format.Usage{
+			InputTokens:      updated.PromptTokens,
+			OutputTokens:     updated.CompletionTokens,
+			CacheReadTokens:  updated.CacheReadTokens,
+			CacheWriteTokens: updated.CacheWriteTokens,
+			Cost:             updated.Cost,
+		}
+	}
+
+	if opts.Template != nil {
+		rendered, err := format.RenderTemplate(opts.Template, output)
+		if err != nil {
+			return err
+		}
+		fmt.Print(rendered)
+	} else {
+		fmt.Println(format.Render(output, opts.Format))
+	}
+
+	logging.Info("Non-interactive run completed", "session_id", sess.ID)
+
+	if failed := output.ToolErrors(); opts.FailOnToolError && failed > 0 {
+		return fmt.Errorf("%d of %d tool calls failed", failed, len(output.ToolCalls))
+	}
+	return nil
+}
+
+// nonInteractiveToolCalls returns the tool calls of the session of a
+// non-interactive run with their results
+func (a *App) nonInteractiveToolCalls(ctx context.Context, sessionID string) []format.ToolCall {
+	messages, err := a.Messages.List(ctx, sessionID)
+	if err != nil {
+		logging.Warn("Failed to list the messages of the run", "session_id", sessionID, "error", err)
+		return nil
+	}
+	results := make(map[string]message.ToolResult)
+	for _, msg := range messages {
+		for _, result := range msg.ToolResults() {
+			results[result.ToolCallID] = result
+		}
+	}
+	var calls []format.ToolCall
+	for _, msg := range messages {
+		for _, call := range msg.ToolCalls() {
+			result := results[call.ID]
+			calls = append(calls, format.ToolCall{
+				Name:    call.Name,
+				Input:   call.Input,
+				Output:  result.Content,
+				IsError: result.IsError,
+			})
+		}
+	}
+	return calls
+}
+
+// nonInteractiveSession creates the session of a non-interactive run, in
+// which every permission request is approved
+func (a *App) nonInteractiveSession(ctx context.Context, prompt string) (session.Session, error) {
 	const maxPromptLengthForTitle = 100
 	titlePrefix := "Non-interactive: "
 	var titleSuffix string
@@ -116,18 +353,42 @@ func (a *App) RunNonInteractive(ctx context.Context, prompt string, outputFormat
 
 	sess, err := a.Sessions.Create(ctx, title)
 	if err != nil {
-		return fmt.Errorf("failed to create session for non-interactive mode: %w", err)
+		return session.Session{}, fmt.Errorf("failed to create session for non-interactive mode: %w", err)
 	}
 	logging.Info("Created session for non-interactive run", "session_id", sess.ID)
 
 	// Automatically approve all permission requests for this non-interactive session
 	a.Permissions.AutoApproveSession(sess.ID)
+	return sess, nil
+}
 
-	done, err := a.CaronexAgent.Run(ctx, sess.ID, prompt)
+// ComparisonOutput is the result of a non-interactive model comparison
+type ComparisonOutput struct {
+	SessionID string                        `json:"session_id"`
+	Prompt    string                        `json:"prompt"`
+	Results   []message.ComparisonCandidate `json:"results"`
+}
+
+// RunComparison sends a prompt to modelA and modelB side by side, without
+// tools, and prints the responses of both as JSON
+func (a *App) RunComparison(ctx context.Context, prompt string, modelA, modelB models.ModelID, quiet bool) error {
+	logging.Info("Running a model comparison in non-interactive mode", "model_a", modelA, "model_b", modelB)
+
+	var spinner *format.Spinner
+	if !quiet {
+		spinner = format.NewSpinner("Comparing...")
+		spinner.Start()
+		defer spinner.Stop()
+	}
+
+	sess, err := a.nonInteractiveSession(ctx, prompt)
+	if err != nil {
+		return err
+	}
+	done, err := a.CaronexAgent.Run(agent.WithComparison(ctx, modelA, modelB), sess.ID, prompt)
 	if err != nil {
 		return fmt.Errorf("failed to start agent processing stream: %w", err)
 	}
-
 	result := <-done
 	if result.Error != nil {
 		if errors.Is(result.Error, context.Canceled) || errors.Is(result.Error, agent.ErrRequestCancelled) {
@@ -136,22 +397,21 @@ func (a *App) RunNonInteractive(ctx context.Context, prompt string, outputFormat
 		}
 		return fmt.Errorf("agent processing failed: %w", result.Error)
 	}
-
-	// Stop spinner before printing output
 	if !quiet && spinner != nil {
 		spinner.Stop()
 	}
 
-	// Get the text content from the response
-	content := "No content available"
-	if result.Message.Content().String() != "" {
-		content = result.Message.Content().String()
+	output := ComparisonOutput{SessionID: sess.ID, Prompt: prompt}
+	if comparison := result.Message.Comparison(); comparison != nil {
+		output.Results = comparison.Candidates
 	}
+	encoded, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode the comparison: %w", err)
+	}
+	fmt.Println(string(encoded))
 
-	fmt.Println(format.FormatOutput(content, outputFormat))
-
-	logging.Info("Non-interactive run completed", "session_id", sess.ID)
-
+	logging.Info("Non-interactive comparison completed", "session_id", sess.ID)
 	return nil
 }
 
@@ -178,4 +438,12 @@ func (app *App) Shutdown() {
 		}
 		cancel()
 	}
+
+	// Let other instances write to the sessions open here
+	closeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := app.Locks.Close(closeCtx); err != nil {
+		logging.Error("Failed to release session locks", "error", err)
+	}
+	lock.SetCurrent(nil)
 }
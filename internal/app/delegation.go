@@ -0,0 +1,108 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/llm/agent"
+	"github.com/caronex/intelligence-interface/internal/message"
+	"github.com/caronex/intelligence-interface/internal/session"
+	"github.com/caronex/intelligence-interface/internal/snapshot"
+	"github.com/caronex/intelligence-interface/internal/tools/coordination"
+)
+
+// reportWait bounds how long a cancellation summary waits for the turn in
+// progress in its session to end
+const reportWait = time.Minute
+
+// runDelegation has the Caronex agent work on a delegated task in a task
+// session of the session it was delegated from. It returns the response of
+// the agent, partial when the delegation was cancelled. The files it changed
+// in the workspace are reported to the task session.
+func (app *App) runDelegation(ctx context.Context, op coordination.Operation) (string, error) {
+	title := "Delegation: " + op.Description
+	var sess session.Session
+	var err error
+	if op.SessionID != "" {
+		sess, err = app.Sessions.CreateTaskSession(ctx, op.ID, op.SessionID, title)
+	} else {
+		sess, err = app.Sessions.Create(ctx, title)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	before := snapshot.Take()
+	done, err := app.CaronexAgent.Run(ctx, sess.ID, op.Description)
+	if err != nil {
+		return "", err
+	}
+	result := <-done
+	// What the delegation changed goes to its session and its record
+	if changes := app.CaronexAgent.ReportChanges(ctx, sess.ID, op.ID, before); changes != nil {
+		coordination.SetOperationChanges(op.ID, changes)
+	}
+
+	// The response saved so far is the partial result of a cancelled run
+	msgs, err := app.Messages.List(context.WithoutCancel(ctx), sess.ID)
+	if err != nil {
+		return "", err
+	}
+	var response string
+	var finish message.FinishReason
+	for _, msg := range msgs {
+		if msg.Role == message.Assistant {
+			response = msg.Content().String()
+			finish = msg.FinishReason()
+		}
+	}
+	return response, delegationError(finish, result.Error)
+}
+
+// delegationError maps how the run of a delegation ended to the failure
+// taxonomy of the coordination: the errors the agent returns, and the
+// responses cut short without an error
+func delegationError(finish message.FinishReason, err error) error {
+	switch {
+	case errors.Is(err, agent.ErrRequestCancelled):
+		return coordination.NewFailure(coordination.FailureCancelled, err)
+	case err != nil:
+		return err
+	}
+	switch finish {
+	case message.FinishReasonMaxTokens:
+		return coordination.NewFailure(coordination.FailureBudget, errors.New("the response reached the max tokens of the model"))
+	case message.FinishReasonPermissionDenied:
+		return coordination.NewFailure(coordination.FailurePolicy, errors.New("a permission the task needed was denied"))
+	case message.FinishReasonContentFilter:
+		return coordination.NewFailure(coordination.FailurePolicy, errors.New("the response was blocked by the content filter of the provider"))
+	case message.FinishReasonTimeout:
+		return coordination.NewFailure(coordination.FailureTimeout, errors.New("the provider did not answer in time"))
+	}
+	return nil
+}
+
+// reportCancellation posts the summary of a cancellation to the session the
+// cancelled operation originates from, once the turn in progress there ends
+// so that the summary does not land between a tool call and its result
+func (app *App) reportCancellation(sessionID, summary string) {
+	go func() {
+		defer logging.RecoverPanic("app.reportCancellation", nil)
+		deadline := time.Now().Add(reportWait)
+		for app.CaronexAgent.IsSessionBusy(sessionID) && time.Now().Before(deadline) {
+			time.Sleep(100 * time.Millisecond)
+		}
+		_, err := app.Messages.Create(context.Background(), sessionID, message.CreateMessageParams{
+			Role: message.Assistant,
+			Parts: []message.ContentPart{
+				message.TextContent{Text: summary},
+				message.Finish{Reason: message.FinishReasonCanceled, Time: time.Now().Unix()},
+			},
+		})
+		if err != nil {
+			logging.Warn("Failed to post the cancellation summary", "session_id", sessionID, "error", err)
+		}
+	}()
+}
@@ -2,6 +2,7 @@ package app
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"github.com/caronex/intelligence-interface/internal/core/config"
@@ -15,14 +16,49 @@ func (app *App) initLSPClients(ctx context.Context) {
 
 	// Initialize LSP clients
 	for name, clientConfig := range cfg.LSP {
+		if !clientConfig.IsEnabled() {
+			continue
+		}
+		// The servers of the languages of the project are preselected, the
+		// others start only when enabled explicitly
+		if clientConfig.Enabled == nil && !config.ProjectUsesLanguage(name) {
+			logging.Info("Not starting the LSP server of a language the project does not use", "name", name, "project", config.ProjectTypes())
+			continue
+		}
 		// Start each client initialization in its own goroutine
-		go app.createAndStartLSPClient(ctx, name, clientConfig.Command, clientConfig.Args...)
+		go app.createAndStartLSPClient(ctx, name, config.WorkingDirectory(), clientConfig.Command, clientConfig.Args...)
+	}
+
+	// Initialize per-root LSP overrides, rooted at their workspace root
+	for rootName, root := range cfg.Workspaces {
+		for language, clientConfig := range root.LSP {
+			if !clientConfig.IsEnabled() || clientConfig.Command == "" {
+				continue
+			}
+			go app.createAndStartLSPClient(ctx, rootName+"/"+language, root.Path, clientConfig.Command, clientConfig.Args...)
+		}
 	}
 	logging.Info("LSP clients initialization started in background")
 }
 
+// lspClientConfig returns the configuration and root directory for a named LSP client.
+// Per-root clients are named "<root>/<language>".
+func lspClientConfig(name string) (config.LSPConfig, string, bool) {
+	cfg := config.Get()
+	if rootName, language, ok := strings.Cut(name, "/"); ok {
+		root, exists := cfg.Workspaces[rootName]
+		if !exists {
+			return config.LSPConfig{}, "", false
+		}
+		clientConfig, exists := root.LSP[language]
+		return clientConfig, root.Path, exists
+	}
+	clientConfig, exists := cfg.LSP[name]
+	return clientConfig, config.WorkingDirectory(), exists
+}
+
 // createAndStartLSPClient creates a new LSP client, initializes it, and starts its workspace watcher
-func (app *App) createAndStartLSPClient(ctx context.Context, name string, command string, args ...string) {
+func (app *App) createAndStartLSPClient(ctx context.Context, name string, rootDir string, command string, args ...string) {
 	// Create a specific context for initialization with a timeout
 	logging.Info("Creating LSP client", "name", name, "command", command, "args", args)
 	
@@ -38,7 +74,7 @@ func (app *App) createAndStartLSPClient(ctx context.Context, name string, comman
 	defer cancel()
 	
 	// Initialize with the initialization context
-	_, err = lspClient.InitializeLSPClient(initCtx, config.WorkingDirectory())
+	_, err = lspClient.InitializeLSPClient(initCtx, rootDir)
 	if err != nil {
 		logging.Error("Initialize failed", "name", name, "error", err)
 		// Clean up the client to prevent resource leaks
@@ -80,26 +116,25 @@ func (app *App) createAndStartLSPClient(ctx context.Context, name string, comman
 	app.LSPClients[name] = lspClient
 	app.clientsMutex.Unlock()
 
-	go app.runWorkspaceWatcher(watchCtx, name, workspaceWatcher)
+	go app.runWorkspaceWatcher(watchCtx, name, rootDir, workspaceWatcher)
 }
 
 // runWorkspaceWatcher executes the workspace watcher for an LSP client
-func (app *App) runWorkspaceWatcher(ctx context.Context, name string, workspaceWatcher *watcher.WorkspaceWatcher) {
+func (app *App) runWorkspaceWatcher(ctx context.Context, name string, rootDir string, workspaceWatcher *watcher.WorkspaceWatcher) {
 	defer app.watcherWG.Done()
 	defer logging.RecoverPanic("LSP-"+name, func() {
 		// Try to restart the client
 		app.restartLSPClient(ctx, name)
 	})
 
-	workspaceWatcher.WatchWorkspace(ctx, config.WorkingDirectory())
+	workspaceWatcher.WatchWorkspace(ctx, rootDir)
 	logging.Info("Workspace watcher stopped", "client", name)
 }
 
 // restartLSPClient attempts to restart a crashed or failed LSP client
 func (app *App) restartLSPClient(ctx context.Context, name string) {
 	// Get the original configuration
-	cfg := config.Get()
-	clientConfig, exists := cfg.LSP[name]
+	clientConfig, rootDir, exists := lspClientConfig(name)
 	if !exists {
 		logging.Error("Cannot restart client, configuration not found", "client", name)
 		return
@@ -121,6 +156,6 @@ func (app *App) restartLSPClient(ctx context.Context, name string) {
 	}
 
 	// Create a new client using the shared function
-	app.createAndStartLSPClient(ctx, name, clientConfig.Command, clientConfig.Args...)
+	app.createAndStartLSPClient(ctx, name, rootDir, clientConfig.Command, clientConfig.Args...)
 	logging.Info("Successfully restarted LSP client", "client", name)
 }
@@ -0,0 +1,39 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+)
+
+// recoverInterrupted finalizes the responses a previous process exited in the
+// middle of. It waits for the next start while another live instance shares
+// the database, as the responses in progress may be its own.
+func (app *App) recoverInterrupted(ctx context.Context) {
+	status, err := app.Locks.Status(ctx)
+	if err != nil {
+		logging.Warn("Skipped recovering interrupted messages, the running instances are unknown", "error", err)
+		return
+	}
+	for _, instance := range status.Instances {
+		if !instance.Current && !instance.Stale {
+			logging.Debug("Skipped recovering interrupted messages, another instance is running", "pid", instance.PID)
+			return
+		}
+	}
+
+	recoveries, err := app.Messages.Recover(ctx)
+	if err != nil {
+		logging.Warn("Failed to recover interrupted messages", "error", err)
+	}
+	messages := 0
+	for _, recovery := range recoveries {
+		messages += len(recovery.Messages)
+		logging.Info("Recovered interrupted messages", "session_id", recovery.SessionID,
+			"messages", len(recovery.Messages), "aborted_tool_calls", recovery.AbortedToolCalls)
+	}
+	if messages > 0 {
+		logging.InfoPersist(fmt.Sprintf("Recovered %d interrupted messages in %d sessions", messages, len(recoveries)))
+	}
+}
@@ -0,0 +1,297 @@
+// Package artifact saves the code blocks of responses, such as a README draft
+// or a SQL script, as files of their session, from which they can be copied
+// into the workspace.
+package artifact
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/llm/tools"
+	"github.com/caronex/intelligence-interface/internal/message"
+)
+
+// Block is a fenced code block of a response
+type Block struct {
+	// Language is the first word of the info string of the fence
+	Language string
+	// Hint is the file name the fence is tagged with, as filename=NAME
+	Hint    string
+	Content string
+}
+
+// Blocks returns the fenced code blocks of text, in order. A block left open
+// at the end of text is not returned, as the response was cut off.
+func Blocks(text string) []Block {
+	var blocks []Block
+	var open *Block
+	var fence string
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if open == nil {
+			marker := fenceMarker(trimmed)
+			if marker == "" {
+				continue
+			}
+			open, fence, lines = parseInfo(strings.TrimSpace(trimmed[len(marker):])), marker, nil
+			continue
+		}
+		if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+			open.Content = strings.Join(lines, "\n")
+			if open.Content != "" {
+				open.Content += "\n"
+			}
+			blocks = append(blocks, *open)
+			open = nil
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return blocks
+}
+
+// fenceMarker returns the run of backticks or tildes opening a fence at the
+// start of line, "" when line does not open one
+func fenceMarker(line string) string {
+	for _, c := range []string{"`", "~"} {
+		n := len(line) - len(strings.TrimLeft(line, c))
+		if n >= 3 {
+			return line[:n]
+		}
+	}
+	return ""
+}
+
+// parseInfo reads the language and the file name hint of an info string,
+// such as `sql filename="schema.sql"`
+func parseInfo(info string) *Block {
+	block := &Block{}
+	for i, field := range strings.Fields(info) {
+		if name, ok := strings.CutPrefix(field, "filename="); ok {
+			block.Hint = strings.Trim(name, `"'`)
+		} else if i == 0 {
+			block.Language = strings.ToLower(field)
+		}
+	}
+	return block
+}
+
+// Select returns the blocks of text saved as artifacts by cfg: those tagged
+// with a file name, and those of at least the size threshold
+func Select(cfg config.ArtifactsConfig, text string) []Block {
+	var selected []Block
+	for _, block := range Blocks(text) {
+		if strings.TrimSpace(block.Content) == "" || !cfg.Saves(block.Language) {
+			continue
+		}
+		if block.Hint != "" || len(block.Content) >= cfg.Threshold() {
+			selected = append(selected, block)
+		}
+	}
+	return selected
+}
+
+// Dir returns the directory of the artifacts of a session
+func Dir(sessionID string) string {
+	return filepath.Join(config.SessionDirectory(sessionID), "artifacts")
+}
+
+// Path returns the path of an artifact of a session
+func Path(sessionID string, a message.Artifact) string {
+	return filepath.Join(Dir(sessionID), a.Name)
+}
+
+// Save saves the artifacts of text in the artifact directory of the session.
+// Names already taken get a numbered suffix, so no artifact is overwritten.
+func Save(cfg config.ArtifactsConfig, sessionID, text string) ([]message.Artifact, error) {
+	blocks := Select(cfg, text)
+	if len(blocks) == 0 {
+		return nil, nil
+	}
+	dir := Dir(sessionID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create the artifact directory: %w", err)
+	}
+	artifacts := make([]message.Artifact, 0, len(blocks))
+	for _, block := range blocks {
+		name, err := create(dir, baseName(block), []byte(block.Content))
+		if err != nil {
+			return artifacts, err
+		}
+		artifacts = append(artifacts, message.Artifact{
+			Name:     name,
+			Hint:     block.Hint,
+			Language: block.Language,
+			Size:     int64(len(block.Content)),
+		})
+	}
+	return artifacts, nil
+}
+
+// create writes content to a new file of dir named name, or name with a
+// numbered suffix when it is taken, and returns the name of the file
+func create(dir, name string, content []byte) (string, error) {
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	for n := 1; ; n++ {
+		candidate := name
+		if n > 1 {
+			candidate = stem + "-" + strconv.Itoa(n) + ext
+		}
+		f, err := os.OpenFile(filepath.Join(dir, candidate), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to save artifact %s: %w", candidate, err)
+		}
+		_, err = f.Write(content)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to save artifact %s: %w", candidate, err)
+		}
+		return candidate, nil
+	}
+}
+
+// extensions are the file extensions of the languages of code blocks, the
+// language itself being used for the others
+var extensions = map[string]string{
+	"bash":       "sh",
+	"shell":      "sh",
+	"zsh":        "sh",
+	"python":     "py",
+	"ruby":       "rb",
+	"rust":       "rs",
+	"golang":     "go",
+	"javascript": "js",
+	"typescript": "ts",
+	"markdown":   "md",
+	"mermaid":    "mmd",
+	"yml":        "yaml",
+	"text":       "txt",
+	"plaintext":  "txt",
+	"":           "txt",
+}
+
+// baseName returns the file name of a block: the base of its hint, or
+// artifact with the extension of its language
+func baseName(block Block) string {
+	if name := filepath.Base(filepath.Clean("/" + block.Hint)); block.Hint != "" && name != "/" && name != "." {
+		return name
+	}
+	ext, ok := extensions[block.Language]
+	if !ok {
+		ext = strings.Map(func(r rune) rune {
+			if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+				return r
+			}
+			return -1
+		}, block.Language)
+	}
+	if ext == "" {
+		ext = "txt"
+	}
+	return "artifact." + ext
+}
+
+// CopyTo copies the artifacts of a session into dir, keeping their names
+func CopyTo(sessionID string, artifacts []message.Artifact, dir string) error {
+	if len(artifacts) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, a := range artifacts {
+		content, err := os.ReadFile(Path(sessionID, a))
+		if err != nil {
+			return fmt.Errorf("failed to read artifact %s: %w", a.Name, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, a.Name), content, 0o644); err != nil {
+			return fmt.Errorf("failed to copy artifact %s: %w", a.Name, err)
+		}
+	}
+	return nil
+}
+
+// CopyToWorkspace writes an artifact of a message into the workspace, at the
+// file name the response tagged it with or under its own name. It goes
+// through write, so that the permission of the session applies, and refuses
+// names leading out of the workspace.
+func CopyToWorkspace(ctx context.Context, write tools.BaseTool, sessionID, messageID string, a message.Artifact) (tools.ToolResponse, error) {
+	target := a.Hint
+	if target == "" {
+		target = a.Name
+	}
+	if !filepath.IsLocal(target) {
+		return tools.NewTextErrorResponse(fmt.Sprintf("%s is outside the workspace, copy the artifact by hand", target)), nil
+	}
+	content, err := os.ReadFile(Path(sessionID, a))
+	if err != nil {
+		return tools.ToolResponse{}, fmt.Errorf("failed to read artifact %s: %w", a.Name, err)
+	}
+	input, err := json.Marshal(tools.WriteParams{FilePath: target, Content: string(content)})
+	if err != nil {
+		return tools.ToolResponse{}, err
+	}
+	ctx = context.WithValue(ctx, tools.SessionIDContextKey, sessionID)
+	ctx = context.WithValue(ctx, tools.MessageIDContextKey, messageID)
+	return write.Run(ctx, tools.ToolCall{ID: "artifact-" + a.Name, Name: tools.WriteToolName, Input: string(input)})
+}
+
+// ExportPatch copies the patch of workspace changes reported in a session
+// into its artifact directory, returning the path of the copy. Names already
+// taken get a numbered suffix, as those of the artifacts.
+func ExportPatch(sessionID string, changes message.WorkspaceChanges) (string, error) {
+	if changes.Patch == "" {
+		return "", errors.New("no patch was saved for these changes")
+	}
+	content, err := os.ReadFile(changes.Patch)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the patch: %w", err)
+	}
+	dir := Dir(sessionID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create the artifact directory: %w", err)
+	}
+	name, err := create(dir, filepath.Base(changes.Patch), content)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// Open opens a directory in the file manager of the system
+func Open(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", dir)
+	case "windows":
+		cmd = exec.Command("explorer", dir)
+	default:
+		cmd = exec.Command("xdg-open", dir)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to open %s: %w", dir, err)
+	}
+	go cmd.Wait()
+	return nil
+}
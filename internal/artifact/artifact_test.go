@@ -0,0 +1,147 @@
+package artifact
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/db"
+	"github.com/caronex/intelligence-interface/internal/history"
+	"github.com/caronex/intelligence-interface/internal/llm/tools"
+	"github.com/caronex/intelligence-interface/internal/message"
+	"github.com/caronex/intelligence-interface/internal/permission"
+	"github.com/caronex/intelligence-interface/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const response = "Here is the schema:\n\n" +
+	"```sql filename=\"db/schema.sql\"\nCREATE TABLE t (id INTEGER);\n```\n\n" +
+	"And a check:\n\n" +
+	"````bash\necho ok\n```\nnot a fence end\n````\n\n" +
+	"```go\npackage main\n"
+
+func TestBlocks(t *testing.T) {
+	assert.Equal(t, []Block{
+		{Language: "sql", Hint: "db/schema.sql", Content: "CREATE TABLE t (id INTEGER);\n"},
+		{Language: "bash", Content: "echo ok\n```\nnot a fence end\n"},
+	}, Blocks(response), "the block left open at the end is not returned")
+}
+
+func TestSelect(t *testing.T) {
+	long := "```python\n" + strings.Repeat("print('x')\n", 10) + "```\n"
+	text := response + "\n```\n" + long
+
+	tests := []struct {
+		name string
+		cfg  config.ArtifactsConfig
+		want []string
+	}{
+		{"tagged and long blocks", config.ArtifactsConfig{MinBytes: 100}, []string{"sql", "python"}},
+		{"tagged blocks only", config.ArtifactsConfig{}, []string{"sql"}},
+		{"small threshold", config.ArtifactsConfig{MinBytes: 5}, []string{"sql", "bash", "go", "python"}},
+		{"restricted languages", config.ArtifactsConfig{MinBytes: 5, Languages: []string{"Python", "bash"}}, []string{"bash", "python"}},
+		{"disabled", config.ArtifactsConfig{Disabled: true, MinBytes: 5}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var languages []string
+			for _, block := range Select(tt.cfg, text) {
+				languages = append(languages, block.Language)
+			}
+			assert.Equal(t, tt.want, languages)
+		})
+	}
+}
+
+func TestSave(t *testing.T) {
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
+	cfg.Data.Directory = t.TempDir()
+	text := "```sql filename=../../schema.sql\nCREATE TABLE a (id INTEGER);\n```\n" +
+		"```sql filename=schema.sql\nCREATE TABLE b (id INTEGER);\n```\n" +
+		"```Mermaid filename=\nflowchart LR\n  a --> b\n```\n"
+
+	saved, err := Save(config.ArtifactsConfig{MinBytes: 5}, "session-1", text)
+	require.NoError(t, err)
+	assert.Equal(t, []message.Artifact{
+		{Name: "schema.sql", Hint: "../../schema.sql", Language: "sql", Size: 29},
+		{Name: "schema-2.sql", Hint: "schema.sql", Language: "sql", Size: 29},
+		{Name: "artifact.mmd", Language: "mermaid", Size: 23},
+	}, saved)
+	assert.Equal(t, filepath.Join(cfg.Data.Directory, "sessions", "session-1", "artifacts"), Dir("session-1"))
+
+	// The names taken by the artifacts of earlier responses are kept
+	saved, err = Save(config.ArtifactsConfig{MinBytes: 5}, "session-1", text)
+	require.NoError(t, err)
+	assert.Equal(t, "schema-3.sql", saved[0].Name)
+	content, err := os.ReadFile(Path("session-1", saved[1]))
+	require.NoError(t, err)
+	assert.Equal(t, "CREATE TABLE b (id INTEGER);\n", string(content))
+
+	saved, err = Save(config.ArtifactsConfig{}, "session-2", "no code here")
+	require.NoError(t, err)
+	assert.Empty(t, saved)
+	assert.NoDirExists(t, Dir("session-2"))
+}
+
+func TestExportPatch(t *testing.T) {
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
+	cfg.Data.Directory = t.TempDir()
+	patch := filepath.Join(config.SessionDirectory("session-1"), "changes", "run-1.patch")
+	require.NoError(t, os.MkdirAll(filepath.Dir(patch), 0o755))
+	require.NoError(t, os.WriteFile(patch, []byte("--- a/main.go\n+++ b/main.go\n"), 0o644))
+	changes := message.WorkspaceChanges{Patch: patch}
+
+	path, err := ExportPatch("session-1", changes)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(Dir("session-1"), "run-1.patch"), path)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "--- a/main.go\n+++ b/main.go\n", string(content))
+
+	// Exporting again keeps the earlier export
+	path, err = ExportPatch("session-1", changes)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(Dir("session-1"), "run-1-2.patch"), path)
+
+	_, err = ExportPatch("session-1", message.WorkspaceChanges{})
+	assert.Error(t, err)
+}
+
+func TestCopyToWorkspace(t *testing.T) {
+	workingDir := t.TempDir()
+	cfg := config.NewTestConfig(config.WithWorkingDir(workingDir))
+	cfg.Data.Directory = t.TempDir()
+
+	conn, err := db.Connect()
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	q := db.New(conn)
+	sess, err := session.NewService(q).Create(context.Background(), "test")
+	require.NoError(t, err)
+	permissions := permission.NewPermissionService()
+	permissions.AutoApproveSession(sess.ID)
+	write := tools.NewWriteTool(nil, permissions, history.NewService(q, conn))
+
+	saved, err := Save(config.ArtifactsConfig{MinBytes: 5}, sess.ID,
+		"```sql filename=db/schema.sql\nCREATE TABLE t (id INTEGER);\n```\n"+
+			"```sql filename=../outside.sql\nDROP TABLE t;\n```\n")
+	require.NoError(t, err)
+	require.Len(t, saved, 2)
+
+	response, err := CopyToWorkspace(context.Background(), write, sess.ID, "message-1", saved[0])
+	require.NoError(t, err)
+	require.False(t, response.IsError, response.Content)
+	content, err := os.ReadFile(filepath.Join(workingDir, "db", "schema.sql"))
+	require.NoError(t, err)
+	assert.Equal(t, "CREATE TABLE t (id INTEGER);\n", string(content))
+
+	response, err = CopyToWorkspace(context.Background(), write, sess.ID, "message-1", saved[1])
+	require.NoError(t, err)
+	assert.True(t, response.IsError)
+	assert.Contains(t, response.Content, "outside the workspace")
+	assert.NoFileExists(t, filepath.Join(filepath.Dir(workingDir), "outside.sql"))
+}
@@ -0,0 +1,158 @@
+// Package audit keeps the log of the tools run, by the agents or by the user
+// from the tool palette of the TUI. The log is a JSON Lines file of the data
+// directory, one entry per tool call, so it can be followed and filtered with
+// the usual text tools.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+)
+
+const (
+	// ActorUser is the actor of the tools the user ran directly
+	ActorUser = "user"
+	// MaxInputBytes bounds the input of a tool kept in an entry, the input
+	// of the file tools holding whole files
+	MaxInputBytes = 2000
+)
+
+// Entry is a tool call of the audit log
+type Entry struct {
+	Time time.Time `json:"time"`
+	// Actor is the agent which ran the tool, or ActorUser
+	Actor     string `json:"actor"`
+	SessionID string `json:"session_id,omitempty"`
+	Tool      string `json:"tool"`
+	Input     string `json:"input,omitempty"`
+	Failed    bool   `json:"failed,omitempty"`
+}
+
+// mu serializes the writes to the log
+var mu sync.Mutex
+
+// File returns the file of the audit log, in the data directory
+func File() string {
+	return filepath.Join(config.Get().Data.Directory, "audit.jsonl")
+}
+
+// Record appends entry to the log, stamping it with the current time when it
+// has none, redacting the secrets in its input and truncating it to
+// MaxInputBytes
+func Record(entry Entry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	entry.Input = logging.Redact(entry.Input)
+	if len(entry.Input) > MaxInputBytes {
+		entry.Input = strings.ToValidUTF8(entry.Input[:MaxInputBytes], "") + "…"
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode the audit entry: %w", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	path := File()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create the data directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open the audit log: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write the audit log: %w", err)
+	}
+	return nil
+}
+
+// List returns the entries of the log, oldest first
+func List() ([]Entry, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	f, err := os.Open(File())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the audit log: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse the audit log: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the audit log: %w", err)
+	}
+	return entries, nil
+}
+
+// DeleteSessions removes the entries of the sessions from the log and returns
+// how many it removed. A dry run counts them without removing them.
+func DeleteSessions(sessionIDs []string, dryRun bool) (int, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	path := File()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read the audit log: %w", err)
+	}
+
+	deleted := make(map[string]bool, len(sessionIDs))
+	for _, id := range sessionIDs {
+		deleted[id] = true
+	}
+	var kept []byte
+	removed := 0
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return 0, fmt.Errorf("failed to parse the audit log: %w", err)
+		}
+		if entry.SessionID != "" && deleted[entry.SessionID] {
+			removed++
+			continue
+		}
+		kept = append(kept, line...)
+	}
+	if removed == 0 || dryRun {
+		return removed, nil
+	}
+
+	// The log is replaced at once, so a crash leaves either log whole
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, kept, 0o600); err != nil {
+		return 0, fmt.Errorf("failed to write the audit log: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return 0, fmt.Errorf("failed to replace the audit log: %w", err)
+	}
+	return removed, nil
+}
@@ -0,0 +1,80 @@
+package audit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+)
+
+func TestRecordAndList(t *testing.T) {
+	cfg := config.NewTestConfig()
+	cfg.Data.Directory = t.TempDir()
+
+	entries, err := List()
+	require.NoError(t, err)
+	assert.Empty(t, entries, "no log is no entry")
+
+	require.NoError(t, Record(Entry{Actor: "coder", SessionID: "s1", Tool: "bash", Input: `{"command":"ls"}`}))
+	require.NoError(t, Record(Entry{Actor: ActorUser, Tool: "doctor_checks", Input: strings.Repeat("é", MaxInputBytes), Failed: true}))
+
+	entries, err = List()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "coder", entries[0].Actor)
+	assert.Equal(t, "s1", entries[0].SessionID)
+	assert.Equal(t, `{"command":"ls"}`, entries[0].Input)
+	assert.False(t, entries[0].Time.IsZero())
+
+	assert.Equal(t, ActorUser, entries[1].Actor)
+	assert.True(t, entries[1].Failed)
+	assert.LessOrEqual(t, len(entries[1].Input), MaxInputBytes+len("…"))
+	assert.True(t, strings.HasSuffix(entries[1].Input, "é…"), "the input is cut between characters")
+}
+
+func TestRecordRedactsSecrets(t *testing.T) {
+	cfg := config.NewTestConfig()
+	cfg.Data.Directory = t.TempDir()
+	logging.AddSecret("audit-secret-value")
+
+	require.NoError(t, Record(Entry{Actor: "coder", Tool: "bash", Input: `{"command":"curl -H 'Authorization: audit-secret-value'"}`}))
+	entries, err := List()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.NotContains(t, entries[0].Input, "audit-secret-value")
+	assert.Contains(t, entries[0].Input, logging.Redacted)
+}
+
+func TestDeleteSessions(t *testing.T) {
+	cfg := config.NewTestConfig()
+	cfg.Data.Directory = t.TempDir()
+
+	removed, err := DeleteSessions([]string{"s1"}, false)
+	require.NoError(t, err)
+	assert.Zero(t, removed, "no log is nothing to remove")
+
+	require.NoError(t, Record(Entry{Actor: "coder", SessionID: "s1", Tool: "bash"}))
+	require.NoError(t, Record(Entry{Actor: "coder", SessionID: "s2", Tool: "view"}))
+	require.NoError(t, Record(Entry{Actor: ActorUser, Tool: "doctor_checks"}))
+	require.NoError(t, Record(Entry{Actor: "coder", SessionID: "s1", Tool: "edit"}))
+
+	removed, err = DeleteSessions([]string{"s1"}, true)
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+	entries, err := List()
+	require.NoError(t, err)
+	assert.Len(t, entries, 4, "a dry run keeps the entries")
+
+	removed, err = DeleteSessions([]string{"s1"}, false)
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+	entries, err = List()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "s2", entries[0].SessionID)
+	assert.Equal(t, ActorUser, entries[1].Actor)
+}
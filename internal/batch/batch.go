@@ -0,0 +1,594 @@
+// Package batch runs the requests of offline jobs through the batch APIs of
+// the providers, which answer within hours at half the price. A job opts in
+// by registering as a component and submitting its requests. Batches are
+// stored in the database with the ID the provider gave them, so polling goes
+// on after a restart, and each result is delivered to its component once the
+// batch ended. Interactive flows never go through batches.
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/analytics"
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/db"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/llm/provider"
+	"github.com/caronex/intelligence-interface/internal/message"
+	"github.com/google/uuid"
+)
+
+// Status is the state of a batch
+type Status string
+
+const (
+	// StatusPending batches are stored but not yet accepted by the provider
+	StatusPending Status = "pending"
+	// StatusSubmitted batches are being processed by the provider
+	StatusSubmitted Status = "submitted"
+	// StatusCompleted batches ended, the results of their requests recorded
+	StatusCompleted Status = "completed"
+	// StatusFailed batches were rejected or failed as a whole
+	StatusFailed Status = "failed"
+)
+
+// ItemStatus is the state of a request of a batch
+type ItemStatus string
+
+const (
+	ItemPending   ItemStatus = "pending"
+	ItemSucceeded ItemStatus = "succeeded"
+	// ItemFailed requests failed every attempt, or their result could not
+	// be delivered
+	ItemFailed ItemStatus = "failed"
+	// ItemRetried requests failed and were submitted again in another batch
+	ItemRetried ItemStatus = "retried"
+)
+
+var (
+	// ErrUnknownComponent is returned when submitting for a component that
+	// did not register
+	ErrUnknownComponent = errors.New("unknown batch component")
+
+	requestIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+)
+
+// Request is one request of a job
+type Request struct {
+	// ID identifies the request in its batch: letters, digits, "_" and "-",
+	// at most 64 of them
+	ID string
+	// Payload is what the component needs to use the result, such as the
+	// session to retitle
+	Payload string
+	// Prompt is the user message sent, after the system message of the
+	// component's provider
+	Prompt string
+}
+
+// Item is a request of a batch with its result
+type Item struct {
+	Request
+	BatchID string
+	// Attempt counts the submissions of the request, this one included
+	Attempt int
+	Status  ItemStatus
+	Content string
+	Usage   provider.TokenUsage
+	// Cost is the price of the request at the batch price
+	Cost  float64
+	Error string
+	// Delivered is set once the component received the result
+	Delivered bool
+}
+
+// Batch is a batch of requests submitted by a component
+type Batch struct {
+	ID        string
+	Component string
+	Provider  models.ModelProvider
+	Model     models.ModelID
+	// RemoteID is the ID of the batch at the provider, empty until it was
+	// submitted
+	RemoteID  string
+	Status    Status
+	Error     string
+	CreatedAt int64
+	UpdatedAt int64
+	Items     []Item
+}
+
+// Count returns the number of requests of the batch in status
+func (b Batch) Count(status ItemStatus) int {
+	count := 0
+	for _, item := range b.Items {
+		if item.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// Cost returns the price of the requests of the batch at the batch price
+func (b Batch) Cost() float64 {
+	var cost float64
+	for _, item := range b.Items {
+		cost += item.Cost
+	}
+	return cost
+}
+
+// Component is a job that opted into batch mode
+type Component struct {
+	// Provider creates the provider the requests are submitted with, which
+	// must have a batch API
+	Provider func() (provider.Provider, error)
+	// Deliver receives the result of each request once its batch ended: the
+	// response, or the error of a request that failed every attempt. A
+	// delivery that fails marks its request failed.
+	Deliver func(ctx context.Context, item Item) error
+}
+
+type Service interface {
+	// Register makes a component able to submit batches and receive their
+	// results. Results are delivered only to the components registered in
+	// the process polling.
+	Register(name string, component Component)
+	// Submit stores the requests of a component as a batch and submits it.
+	// A batch the provider could not take is submitted again by the next
+	// poll, its error being kept on it meanwhile.
+	Submit(ctx context.Context, component string, requests []Request) (Batch, error)
+	// Poll submits the pending batches, checks the submitted ones, records
+	// the results of the ended ones, submits their failed requests again and
+	// delivers the results
+	Poll(ctx context.Context) error
+	// Start polls every poll interval until ctx is done
+	Start(ctx context.Context)
+	// List returns every batch, newest first
+	List(ctx context.Context) ([]Batch, error)
+	Get(ctx context.Context, id string) (Batch, error)
+}
+
+type service struct {
+	q db.Querier
+
+	mu         sync.Mutex
+	components map[string]Component
+	// pollMu keeps polls from handling the same batch at once
+	pollMu sync.Mutex
+}
+
+func NewService(q db.Querier) Service {
+	return &service{q: q, components: make(map[string]Component)}
+}
+
+func (s *service) Register(name string, component Component) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.components[name] = component
+}
+
+func (s *service) component(name string) (Component, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	component, ok := s.components[name]
+	return component, ok
+}
+
+func (s *service) Submit(ctx context.Context, name string, requests []Request) (Batch, error) {
+	component, ok := s.component(name)
+	if !ok {
+		return Batch{}, fmt.Errorf("%w: %s", ErrUnknownComponent, name)
+	}
+	if len(requests) == 0 {
+		return Batch{}, fmt.Errorf("no requests to submit")
+	}
+	seen := make(map[string]bool, len(requests))
+	for _, request := range requests {
+		if !requestIDPattern.MatchString(request.ID) {
+			return Batch{}, fmt.Errorf("invalid request ID %q: use up to 64 letters, digits, _ and -", request.ID)
+		}
+		if seen[request.ID] {
+			return Batch{}, fmt.Errorf("request ID %q is used twice", request.ID)
+		}
+		seen[request.ID] = true
+	}
+	items := make([]Item, len(requests))
+	for i, request := range requests {
+		items[i] = Item{Request: request, Attempt: 1}
+	}
+	return s.create(ctx, name, component, items)
+}
+
+// create stores items as a new batch of a component and submits it
+func (s *service) create(ctx context.Context, name string, component Component, items []Item) (Batch, error) {
+	p, err := batchProvider(component)
+	if err != nil {
+		return Batch{}, err
+	}
+	batch := Batch{
+		ID:        uuid.New().String(),
+		Component: name,
+		Provider:  p.Model().Provider,
+		Model:     p.Model().ID,
+		Status:    StatusPending,
+	}
+	err = s.q.CreateBatch(ctx, db.CreateBatchParams{
+		ID:        batch.ID,
+		Component: batch.Component,
+		Provider:  string(batch.Provider),
+		Model:     string(batch.Model),
+		Status:    string(batch.Status),
+	})
+	if err != nil {
+		return Batch{}, fmt.Errorf("failed to store the batch: %w", err)
+	}
+	for _, item := range items {
+		err := s.q.CreateBatchItem(ctx, db.CreateBatchItemParams{
+			BatchID: batch.ID,
+			ID:      item.ID,
+			Payload: item.Payload,
+			Prompt:  item.Prompt,
+			Attempt: int64(item.Attempt),
+			Status:  string(ItemPending),
+		})
+		if err != nil {
+			return Batch{}, fmt.Errorf("failed to store request %s: %w", item.ID, err)
+		}
+	}
+
+	if err := s.submit(ctx, batch.ID, p); err != nil {
+		logging.Warn("Failed to submit the batch, submitting it again on the next poll", "batch", batch.ID, "error", err)
+	}
+	return s.Get(ctx, batch.ID)
+}
+
+// batchProvider returns the provider of a component, which must have a batch
+// API
+func batchProvider(component Component) (provider.BatchProvider, error) {
+	p, err := component.Provider()
+	if err != nil {
+		return nil, err
+	}
+	batchProvider, ok := provider.AsBatch(p)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", provider.ErrBatchUnsupported, p.Model().Provider)
+	}
+	return batchProvider, nil
+}
+
+// submit submits a pending batch through p, keeping the error on the batch
+// when the provider did not take it
+func (s *service) submit(ctx context.Context, batchID string, p provider.BatchProvider) error {
+	batch, err := s.Get(ctx, batchID)
+	if err != nil {
+		return err
+	}
+	var requests []provider.BatchRequest
+	for _, item := range batch.Items {
+		requests = append(requests, provider.BatchRequest{
+			CustomID: item.ID,
+			Messages: []message.Message{{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: item.Prompt}}}},
+		})
+	}
+	remoteID, err := p.SubmitBatch(ctx, requests)
+	if err != nil {
+		if updateErr := s.update(ctx, batch, StatusPending, err.Error()); updateErr != nil {
+			return updateErr
+		}
+		return err
+	}
+	batch.RemoteID = remoteID
+	return s.update(ctx, batch, StatusSubmitted, "")
+}
+
+func (s *service) update(ctx context.Context, batch Batch, status Status, reason string) error {
+	return s.q.UpdateBatch(ctx, db.UpdateBatchParams{
+		RemoteID: batch.RemoteID,
+		Status:   string(status),
+		Error:    reason,
+		ID:       batch.ID,
+	})
+}
+
+func (s *service) Poll(ctx context.Context) error {
+	s.pollMu.Lock()
+	defer s.pollMu.Unlock()
+
+	var errs []error
+	open, err := s.q.ListOpenBatches(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list the open batches: %w", err)
+	}
+	for _, row := range open {
+		component, ok := s.component(row.Component)
+		if !ok {
+			continue
+		}
+		if err := s.advance(ctx, batchFromRow(row), component); err != nil {
+			logging.Warn("Failed to poll the batch", "batch", row.ID, "error", err)
+			errs = append(errs, fmt.Errorf("batch %s: %w", row.ID, err))
+		}
+	}
+
+	// Deliver the results recorded before a restart, or by a poll whose
+	// deliveries were cut short
+	undelivered, err := s.q.ListUndeliveredBatches(ctx)
+	if err != nil {
+		return errors.Join(append(errs, fmt.Errorf("failed to list the undelivered batches: %w", err))...)
+	}
+	for _, row := range undelivered {
+		if component, ok := s.component(row.Component); ok {
+			if err := s.deliver(ctx, row.ID, component); err != nil {
+				errs = append(errs, fmt.Errorf("batch %s: %w", row.ID, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// advance submits a pending batch, or checks a submitted one and records its
+// results once it ended
+func (s *service) advance(ctx context.Context, batch Batch, component Component) error {
+	if batch.Status == StatusPending {
+		p, err := batchProvider(component)
+		if err != nil {
+			return err
+		}
+		// The requests of a pending batch go to the model it was created
+		// for, otherwise they are submitted again in a new batch
+		if p.Model().ID != batch.Model {
+			return s.finish(ctx, batch, component, provider.BatchState{
+				Status: provider.BatchFailed,
+				Err:    fmt.Sprintf("the model of the component changed to %s before the batch was submitted", p.Model().ID),
+			})
+		}
+		return s.submit(ctx, batch.ID, p)
+	}
+
+	p, err := pollProvider(batch)
+	if err != nil {
+		return err
+	}
+	state, err := p.PollBatch(ctx, batch.RemoteID)
+	if err != nil {
+		return err
+	}
+	if state.Status == provider.BatchInProgress {
+		return nil
+	}
+	if err := s.finish(ctx, batch, component, state); err != nil {
+		return err
+	}
+	return s.deliver(ctx, batch.ID, component)
+}
+
+// pollProvider returns a provider for the model a batch was submitted to,
+// whatever the model of its component now is
+func pollProvider(batch Batch) (provider.BatchProvider, error) {
+	var providerCfg config.Provider
+	if cfg := config.Get(); cfg != nil {
+		providerCfg = cfg.Providers[batch.Provider]
+	}
+	p, err := provider.DefaultProviderFactory.NewProvider(batch.Model, providerCfg)
+	if err != nil {
+		return nil, err
+	}
+	batchProvider, ok := provider.AsBatch(p)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", provider.ErrBatchUnsupported, batch.Provider)
+	}
+	return batchProvider, nil
+}
+
+// finish records the results of an ended batch. Its failed requests are
+// submitted again in a new batch until they used every attempt. The retry
+// batch is created before the results are recorded: a crash in between
+// submits the failed requests twice rather than never, and the requests it
+// could not be created for fail, to be delivered as such.
+func (s *service) finish(ctx context.Context, batch Batch, component Component, state provider.BatchState) error {
+	batch, err := s.Get(ctx, batch.ID)
+	if err != nil {
+		return err
+	}
+	results := make(map[string]provider.BatchResult, len(state.Results))
+	for _, result := range state.Results {
+		results[result.CustomID] = result
+	}
+	model := models.SupportedModels[batch.Model]
+	attempts := config.BatchConfig{}.AttemptLimit()
+	if cfg := config.Get(); cfg != nil {
+		attempts = cfg.Batch.AttemptLimit()
+	}
+
+	var ended, retries []Item
+	for _, item := range batch.Items {
+		if item.Status != ItemPending {
+			continue
+		}
+		result, ok := results[item.ID]
+		switch {
+		case state.Status == provider.BatchFailed:
+			item.Error = "batch failed: " + state.Err
+		case !ok:
+			item.Error = "no result"
+		case result.Response == nil:
+			item.Error = result.Err
+		default:
+			item.Status = ItemSucceeded
+			item.Content = result.Response.Content
+			item.Usage = result.Response.Usage
+			item.Cost = result.Response.Usage.BatchCost(model)
+		}
+		analytics.Record(analytics.Event{
+			Kind:   analytics.KindBatch,
+			Name:   string(batch.Model),
+			Failed: item.Status != ItemSucceeded,
+			Tokens: item.Usage.PromptTokens() + item.Usage.OutputTokens,
+			Cost:   item.Cost,
+		})
+		if item.Status != ItemSucceeded {
+			item.Status = ItemFailed
+			if item.Attempt < attempts {
+				item.Status = ItemRetried
+				retries = append(retries, Item{Request: item.Request, Attempt: item.Attempt + 1})
+			}
+		}
+		ended = append(ended, item)
+	}
+
+	if len(retries) > 0 {
+		retry, err := s.create(ctx, batch.Component, component, retries)
+		if err != nil {
+			logging.Warn("Failed to submit the failed requests of a batch again", "batch", batch.ID, "requests", len(retries), "error", err, logging.ComponentKey, logging.ComponentDataLoss)
+			for i, item := range ended {
+				if item.Status == ItemRetried {
+					ended[i].Status = ItemFailed
+					ended[i].Error = fmt.Sprintf("%s (not submitted again: %v)", item.Error, err)
+				}
+			}
+		} else {
+			logging.Info("Submitted the failed requests of a batch again", "batch", batch.ID, "retry", retry.ID, "requests", len(retries))
+		}
+	}
+	for _, item := range ended {
+		if err := s.updateItem(ctx, item); err != nil {
+			return err
+		}
+	}
+
+	status := StatusCompleted
+	if state.Status == provider.BatchFailed {
+		status = StatusFailed
+	}
+	return s.update(ctx, batch, status, state.Err)
+}
+
+// deliver hands the recorded results of a batch to its component
+func (s *service) deliver(ctx context.Context, batchID string, component Component) error {
+	batch, err := s.Get(ctx, batchID)
+	if err != nil {
+		return err
+	}
+	for _, item := range batch.Items {
+		if item.Delivered || (item.Status != ItemSucceeded && item.Status != ItemFailed) {
+			continue
+		}
+		if err := component.Deliver(ctx, item); err != nil {
+			logging.Warn("Failed to deliver the result of a batch request", "batch", batch.ID, "request", item.ID, "error", err)
+			item.Status = ItemFailed
+			item.Error = "delivery failed: " + err.Error()
+		}
+		item.Delivered = true
+		if err := s.updateItem(ctx, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *service) updateItem(ctx context.Context, item Item) error {
+	var delivered int64
+	if item.Delivered {
+		delivered = 1
+	}
+	return s.q.UpdateBatchItem(ctx, db.UpdateBatchItemParams{
+		Status:       string(item.Status),
+		Content:      item.Content,
+		InputTokens:  item.Usage.PromptTokens(),
+		OutputTokens: item.Usage.OutputTokens,
+		Cost:         item.Cost,
+		Error:        item.Error,
+		Delivered:    delivered,
+		BatchID:      item.BatchID,
+		ID:           item.ID,
+	})
+}
+
+func (s *service) Start(ctx context.Context) {
+	go func() {
+		defer logging.RecoverPanic("batch-poller", nil)
+		for {
+			if err := s.Poll(ctx); err != nil && ctx.Err() == nil {
+				logging.Warn("Failed to poll the batches", "error", err)
+			}
+			interval := config.BatchConfig{}.Interval()
+			if cfg := config.Get(); cfg != nil {
+				interval = cfg.Batch.Interval()
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+		}
+	}()
+}
+
+func (s *service) List(ctx context.Context) ([]Batch, error) {
+	rows, err := s.q.ListBatches(ctx)
+	if err != nil {
+		return nil, err
+	}
+	batches := make([]Batch, len(rows))
+	for i, row := range rows {
+		batches[i] = batchFromRow(row)
+		if batches[i].Items, err = s.items(ctx, row.ID); err != nil {
+			return nil, err
+		}
+	}
+	return batches, nil
+}
+
+func (s *service) Get(ctx context.Context, id string) (Batch, error) {
+	row, err := s.q.GetBatch(ctx, id)
+	if err != nil {
+		return Batch{}, err
+	}
+	batch := batchFromRow(row)
+	batch.Items, err = s.items(ctx, id)
+	return batch, err
+}
+
+func (s *service) items(ctx context.Context, batchID string) ([]Item, error) {
+	rows, err := s.q.ListBatchItems(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]Item, len(rows))
+	for i, row := range rows {
+		items[i] = Item{
+			Request: Request{ID: row.ID, Payload: row.Payload, Prompt: row.Prompt},
+			BatchID: row.BatchID,
+			Attempt: int(row.Attempt),
+			Status:  ItemStatus(row.Status),
+			Content: row.Content,
+			// The input tokens are stored as a whole, cache reads included
+			Usage:     provider.TokenUsage{InputTokens: row.InputTokens, OutputTokens: row.OutputTokens},
+			Cost:      row.Cost,
+			Error:     row.Error,
+			Delivered: row.Delivered != 0,
+		}
+	}
+	return items, nil
+}
+
+func batchFromRow(row db.Batch) Batch {
+	return Batch{
+		ID:        row.ID,
+		Component: row.Component,
+		Provider:  models.ModelProvider(row.Provider),
+		Model:     models.ModelID(row.Model),
+		RemoteID:  row.RemoteID,
+		Status:    Status(row.Status),
+		Error:     row.Error,
+		CreatedAt: row.CreatedAt,
+		UpdatedAt: row.UpdatedAt,
+	}
+}
@@ -0,0 +1,306 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/db"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/llm/provider"
+)
+
+type fixture struct {
+	q    db.Querier
+	cfg  *config.Config
+	fake *provider.FakeProvider
+
+	mu        sync.Mutex
+	delivered []Item
+}
+
+func newFixture(t *testing.T, responses ...provider.FakeResponse) *fixture {
+	t.Helper()
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
+	cfg.Data.Directory = t.TempDir()
+
+	conn, err := db.Connect()
+	if err != nil {
+		t.Fatalf("db.Connect() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	f := &fixture{
+		q:    db.New(conn),
+		cfg:  cfg,
+		fake: provider.NewFakeProvider(models.TestModels[models.TestFake], responses...),
+	}
+	t.Cleanup(provider.InstallFake(f.fake))
+	return f
+}
+
+// service returns a service with the test component registered, as a new
+// process would create it
+func (f *fixture) service() Service {
+	s := NewService(f.q)
+	s.Register("test", Component{
+		Provider: func() (provider.Provider, error) {
+			return provider.DefaultProviderFactory.NewProvider(models.TestFake, config.Provider{APIKey: "test"})
+		},
+		Deliver: func(ctx context.Context, item Item) error {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			f.delivered = append(f.delivered, item)
+			return nil
+		},
+	})
+	return s
+}
+
+func (f *fixture) deliveries() []Item {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Item(nil), f.delivered...)
+}
+
+func TestSubmitPollDeliver(t *testing.T) {
+	f := newFixture(t,
+		provider.FakeResponse{Content: "First", Usage: provider.TokenUsage{InputTokens: 10, OutputTokens: 2}},
+		provider.FakeResponse{Content: "Second"},
+	)
+	f.fake.SetBatchDelay(50 * time.Millisecond)
+	s := f.service()
+	ctx := context.Background()
+
+	submitted, err := s.Submit(ctx, "test", []Request{
+		{ID: "a", Payload: "session-a", Prompt: "first prompt"},
+		{ID: "b", Payload: "session-b", Prompt: "second prompt"},
+	})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if submitted.Status != StatusSubmitted || submitted.RemoteID == "" || submitted.Model != models.TestFake {
+		t.Fatalf("Submit() = %+v, want a batch submitted to the fake", submitted)
+	}
+	if submitted.Count(ItemPending) != 2 {
+		t.Errorf("Submit() stored %d pending requests, want 2", submitted.Count(ItemPending))
+	}
+
+	// Nothing is delivered while the provider is processing the batch
+	if err := s.Poll(ctx); err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+	if got, _ := s.Get(ctx, submitted.ID); got.Status != StatusSubmitted || len(f.deliveries()) != 0 {
+		t.Fatalf("batch %s with %d deliveries before it ended", got.Status, len(f.deliveries()))
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if err := s.Poll(ctx); err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+	got, err := s.Get(ctx, submitted.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.Status != StatusCompleted || got.Count(ItemSucceeded) != 2 {
+		t.Fatalf("Get() = %+v, want a completed batch", got)
+	}
+	if got.Items[0].Usage.InputTokens != 10 || got.Items[0].Usage.OutputTokens != 2 {
+		t.Errorf("usage of request a = %+v, want the usage of its response", got.Items[0].Usage)
+	}
+
+	deliveries := f.deliveries()
+	if len(deliveries) != 2 {
+		t.Fatalf("delivered %d results, want 2", len(deliveries))
+	}
+	if deliveries[0].Payload != "session-a" || deliveries[0].Content != "First" || deliveries[1].Content != "Second" {
+		t.Errorf("unexpected deliveries: %+v", deliveries)
+	}
+	requests := f.fake.Requests()
+	if len(requests) != 2 || requests[0][0].Content().Text != "first prompt" {
+		t.Errorf("unexpected requests sent: %+v", requests)
+	}
+
+	// Delivered results are not delivered again
+	if err := s.Poll(ctx); err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+	if len(f.deliveries()) != 2 {
+		t.Errorf("results delivered again: %+v", f.deliveries())
+	}
+}
+
+func TestPollAfterRestart(t *testing.T) {
+	f := newFixture(t, provider.FakeResponse{Content: "Done"})
+	f.fake.SetBatchDelay(time.Hour)
+	ctx := context.Background()
+
+	submitted, err := f.service().Submit(ctx, "test", []Request{{ID: "a", Payload: "p", Prompt: "prompt"}})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	// A new process only knows the batch from the database
+	f.fake.SetBatchDelay(0)
+	restarted := f.service()
+	if err := restarted.Poll(ctx); err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+	if got, _ := restarted.Get(ctx, submitted.ID); got.Status != StatusCompleted {
+		t.Errorf("batch %s after the restart, want completed", got.Status)
+	}
+	if deliveries := f.deliveries(); len(deliveries) != 1 || deliveries[0].Content != "Done" {
+		t.Errorf("unexpected deliveries after the restart: %+v", deliveries)
+	}
+
+	// The batches of components not registered in the process are left alone
+	other := NewService(f.q)
+	if err := other.Poll(ctx); err != nil {
+		t.Errorf("Poll() without components error = %v", err)
+	}
+}
+
+func TestRetryFailedRequests(t *testing.T) {
+	f := newFixture(t,
+		provider.FakeResponse{Content: "ok"},
+		provider.FakeResponse{Err: errors.New("overloaded")},
+		provider.FakeResponse{Err: errors.New("overloaded again")},
+	)
+	f.cfg.Batch.Attempts = 2
+	s := f.service()
+	ctx := context.Background()
+
+	first, err := s.Submit(ctx, "test", []Request{{ID: "a", Prompt: "one"}, {ID: "b", Prompt: "two"}})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if err := s.Poll(ctx); err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+	first, _ = s.Get(ctx, first.ID)
+	if first.Count(ItemSucceeded) != 1 || first.Count(ItemRetried) != 1 {
+		t.Fatalf("first batch = %+v, want a succeeded and a retried request", first.Items)
+	}
+	if deliveries := f.deliveries(); len(deliveries) != 1 || deliveries[0].ID != "a" {
+		t.Fatalf("retried requests should not be delivered yet: %+v", deliveries)
+	}
+
+	batches, err := s.List(ctx)
+	if err != nil || len(batches) != 2 {
+		t.Fatalf("List() = %d batches, %v, want the retry batch", len(batches), err)
+	}
+	retry := batches[0]
+	if retry.ID == first.ID {
+		retry = batches[1]
+	}
+	if len(retry.Items) != 1 || retry.Items[0].ID != "b" || retry.Items[0].Attempt != 2 {
+		t.Fatalf("retry batch = %+v, want the second attempt of b", retry.Items)
+	}
+
+	// The last attempt fails for good and the failure is delivered
+	if err := s.Poll(ctx); err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+	retry, _ = s.Get(ctx, retry.ID)
+	if retry.Count(ItemFailed) != 1 || retry.Items[0].Error != "overloaded again" {
+		t.Errorf("retry batch = %+v, want b failed", retry.Items)
+	}
+	deliveries := f.deliveries()
+	if len(deliveries) != 2 || deliveries[1].Status != ItemFailed || deliveries[1].Error != "overloaded again" {
+		t.Errorf("unexpected deliveries: %+v", deliveries)
+	}
+	if batches, _ := s.List(ctx); len(batches) != 2 {
+		t.Errorf("List() = %d batches, want no more retries", len(batches))
+	}
+}
+
+func TestRetryNotSubmitted(t *testing.T) {
+	f := newFixture(t,
+		provider.FakeResponse{Content: "ok"},
+		provider.FakeResponse{Err: errors.New("overloaded")},
+	)
+	f.cfg.Batch.Attempts = 2
+	ctx := context.Background()
+
+	// The provider of the component can no longer be created once the first
+	// batch was submitted, such as when its key was removed
+	var providerErr error
+	s := NewService(f.q)
+	s.Register("test", Component{
+		Provider: func() (provider.Provider, error) {
+			if providerErr != nil {
+				return nil, providerErr
+			}
+			return provider.DefaultProviderFactory.NewProvider(models.TestFake, config.Provider{APIKey: "test"})
+		},
+		Deliver: func(ctx context.Context, item Item) error {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			f.delivered = append(f.delivered, item)
+			return nil
+		},
+	})
+
+	first, err := s.Submit(ctx, "test", []Request{{ID: "a", Prompt: "one"}, {ID: "b", Prompt: "two"}})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	providerErr = errors.New("missing API key")
+	if err := s.Poll(ctx); err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+
+	first, _ = s.Get(ctx, first.ID)
+	if first.Status != StatusCompleted || first.Count(ItemSucceeded) != 1 || first.Count(ItemFailed) != 1 || first.Count(ItemRetried) != 0 {
+		t.Fatalf("batch = %s %+v, want a succeeded and a failed request", first.Status, first.Items)
+	}
+	if batches, _ := s.List(ctx); len(batches) != 1 {
+		t.Errorf("List() = %d batches, want no retry batch", len(batches))
+	}
+	deliveries := f.deliveries()
+	if len(deliveries) != 2 || deliveries[1].ID != "b" || deliveries[1].Status != ItemFailed {
+		t.Fatalf("deliveries = %+v, want the failure of b delivered", deliveries)
+	}
+	if got := deliveries[1].Error; !strings.Contains(got, "overloaded") || !strings.Contains(got, "missing API key") {
+		t.Errorf("error = %q, want the failure of the request and of its retry", got)
+	}
+}
+
+func TestSubmitValidation(t *testing.T) {
+	f := newFixture(t)
+	s := f.service()
+	s.Register("interactive", Component{
+		// A provider without a batch API
+		Provider: func() (provider.Provider, error) { return struct{ provider.Provider }{f.fake}, nil },
+	})
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name      string
+		component string
+		requests  []Request
+	}{
+		{"unknown component", "missing", []Request{{ID: "a"}}},
+		{"no requests", "test", nil},
+		{"invalid ID", "test", []Request{{ID: "a b"}}},
+		{"duplicate ID", "test", []Request{{ID: "a"}, {ID: "a"}}},
+		{"no batch API", "interactive", []Request{{ID: "a"}}},
+	} {
+		if _, err := s.Submit(ctx, tc.component, tc.requests); err == nil {
+			t.Errorf("%s: Submit() succeeded", tc.name)
+		}
+	}
+	if _, err := s.Submit(ctx, "missing", []Request{{ID: "a"}}); !errors.Is(err, ErrUnknownComponent) {
+		t.Errorf("Submit() error = %v, want %v", err, ErrUnknownComponent)
+	}
+	if _, err := s.Submit(ctx, "interactive", []Request{{ID: "a"}}); !errors.Is(err, provider.ErrBatchUnsupported) {
+		t.Errorf("Submit() error = %v, want %v", err, provider.ErrBatchUnsupported)
+	}
+	if batches, _ := s.List(ctx); len(batches) != 0 {
+		t.Errorf("invalid submissions stored %d batches", len(batches))
+	}
+}
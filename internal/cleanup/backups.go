@@ -0,0 +1,107 @@
+// Package cleanup removes the files left behind by generators once they are
+// no longer useful.
+package cleanup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// BackupsFilename is the manifest of the backups the standardize
+	// generator takes before overwriting files, in its output directory
+	BackupsFilename = ".standardize-backups.json"
+	// DefaultBackupAge is the age after which backups are pruned
+	DefaultBackupAge = 7 * 24 * time.Hour
+)
+
+// backup is an entry of a backups manifest, with paths relative to the
+// directory of the manifest
+type backup struct {
+	Original  string `json:"original"`
+	Backup    string `json:"backup"`
+	Timestamp string `json:"timestamp"`
+}
+
+// PruneBackups removes the backups taken before cutoff that are recorded in
+// the backups manifests under root, and drops them from their manifest. A
+// manifest left without backups is removed. Backups whose timestamp does not
+// parse are kept. It returns the paths of the backups removed.
+func PruneBackups(root string, cutoff time.Time) ([]string, error) {
+	var pruned []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && skipDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() != BackupsFilename {
+			return nil
+		}
+		removed, err := pruneManifest(path, cutoff)
+		pruned = append(pruned, removed...)
+		return err
+	})
+	return pruned, err
+}
+
+// skipDir reports whether a directory cannot hold generated code
+func skipDir(name string) bool {
+	return strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor"
+}
+
+// pruneManifest prunes the backups of the manifest at path
+func pruneManifest(path string, cutoff time.Time) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var backups []backup
+	if err := json.Unmarshal(content, &backups); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	var kept []backup
+	var removed []string
+	for _, b := range backups {
+		taken, err := time.Parse(time.RFC3339, b.Timestamp)
+		if err != nil || !taken.Before(cutoff) {
+			kept = append(kept, b)
+			continue
+		}
+		backupPath := filepath.Join(dir, b.Backup)
+		if err := os.Remove(backupPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return removed, fmt.Errorf("failed to remove %s: %w", backupPath, err)
+		}
+		removed = append(removed, backupPath)
+	}
+
+	switch {
+	case len(removed) == 0:
+		return nil, nil
+	case len(kept) == 0:
+		if err := os.Remove(path); err != nil {
+			return removed, fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+		return removed, nil
+	}
+	content, err = json.MarshalIndent(kept, "", "  ")
+	if err != nil {
+		return removed, fmt.Errorf("failed to marshal %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, append(content, '\n'), 0o644); err != nil {
+		return removed, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return removed, nil
+}
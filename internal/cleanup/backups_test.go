@@ -0,0 +1,78 @@
+package cleanup
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPruneBackups(t *testing.T) {
+	root := t.TempDir()
+	project := filepath.Join(root, "service")
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	old := now.Add(-8 * 24 * time.Hour).Format(time.RFC3339)
+	recent := now.Add(-24 * time.Hour).Format(time.RFC3339)
+
+	backups := []backup{
+		{Original: "model.go", Backup: "model.go.bak." + old, Timestamp: old},
+		{Original: "model.go", Backup: "model.go.bak." + recent, Timestamp: recent},
+		{Original: "di.go", Backup: "di.go.bak.unknown", Timestamp: "unknown"},
+	}
+	for _, b := range backups {
+		writeFile(t, filepath.Join(project, b.Backup), "package model\n")
+	}
+	content, err := json.Marshal(backups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(project, BackupsFilename), string(content))
+	other := filepath.Join(root, "other")
+	writeFile(t, filepath.Join(other, backups[0].Backup), "package model\n")
+	content, err = json.Marshal(backups[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(other, BackupsFilename), string(content))
+
+	pruned, err := PruneBackups(root, now.Add(-DefaultBackupAge))
+	if err != nil {
+		t.Fatalf("PruneBackups() error = %v", err)
+	}
+	oldBackup := filepath.Join(project, backups[0].Backup)
+	otherBackup := filepath.Join(other, backups[0].Backup)
+	if len(pruned) != 2 || pruned[0] != otherBackup || pruned[1] != oldBackup {
+		t.Fatalf("pruned = %v, want %s and %s", pruned, otherBackup, oldBackup)
+	}
+	if _, err := os.Stat(oldBackup); !os.IsNotExist(err) {
+		t.Errorf("%s still exists", oldBackup)
+	}
+
+	var kept []backup
+	content, err = os.ReadFile(filepath.Join(project, BackupsFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(content, &kept); err != nil {
+		t.Fatal(err)
+	}
+	if len(kept) != 2 || kept[0] != backups[1] || kept[1] != backups[2] {
+		t.Errorf("manifest = %+v, want the recent and unparsable backups", kept)
+	}
+
+	// A manifest left without backups goes with them
+	if _, err := os.Stat(filepath.Join(other, BackupsFilename)); !os.IsNotExist(err) {
+		t.Errorf("manifest without backups left: %v", err)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/lithammer/fuzzysearch/fuzzy"
 	"github.com/caronex/intelligence-interface/internal/fileutil"
 	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/message"
 	"github.com/caronex/intelligence-interface/internal/tui/components/dialog"
 )
 
@@ -55,6 +56,19 @@ func processNullTerminatedOutput(outputBytes []byte) []string {
 	return matches
 }
 
+// listedFiles returns the files offered for completion, leaving out the
+// hidden ones and those the ignore files exclude, as rg does
+func listedFiles(files []string) []string {
+	ignored := fileutil.Ignored(".", files)
+	listed := make([]string, 0, len(files))
+	for _, file := range files {
+		if !fileutil.SkipHidden(file) && !ignored[file] {
+			listed = append(listed, file)
+		}
+	}
+	return listed
+}
+
 func (cg *filesAndFoldersContextGroup) getFiles(query string) ([]string, error) {
 	cmdRg := fileutil.GetRgCmd("") // No glob pattern for this use case
 	cmdFzf := fileutil.GetFzfCmd(query)
@@ -117,12 +131,7 @@ func (cg *filesAndFoldersContextGroup) getFiles(query string) ([]string, error)
 			return nil, fmt.Errorf("failed to list files for fzf: %w", err)
 		}
 
-		allFiles := make([]string, 0, len(files))
-		for _, file := range files {
-			if !fileutil.SkipHidden(file) {
-				allFiles = append(allFiles, file)
-			}
-		}
+		allFiles := listedFiles(files)
 
 		var fzfIn bytes.Buffer
 		for _, file := range allFiles {
@@ -153,14 +162,7 @@ func (cg *filesAndFoldersContextGroup) getFiles(query string) ([]string, error)
 			return nil, fmt.Errorf("failed to glob files: %w", err)
 		}
 
-		filteredFiles := make([]string, 0, len(allFiles))
-		for _, file := range allFiles {
-			if !fileutil.SkipHidden(file) {
-				filteredFiles = append(filteredFiles, file)
-			}
-		}
-
-		matches = fuzzy.Find(query, filteredFiles)
+		matches = fuzzy.Find(query, listedFiles(allFiles))
 	}
 
 	return matches, nil
@@ -176,7 +178,7 @@ func (cg *filesAndFoldersContextGroup) GetChildEntries(query string) ([]dialog.C
 	for _, file := range matches {
 		item := dialog.NewCompletionItem(dialog.CompletionItem{
 			Title: file,
-			Value: file,
+			Value: message.MentionToken(file),
 		})
 		items = append(items, item)
 	}
@@ -0,0 +1,186 @@
+// Package connectivity tracks whether the application can reach remote LLM
+// providers, so provider calls can fail fast while offline.
+package connectivity
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/pubsub"
+)
+
+// ErrOffline is returned by provider calls that require network access while offline.
+var ErrOffline = errors.New("you are offline: remote providers are unavailable until connectivity returns (local providers still work)")
+
+// State describes the current connectivity state
+type State struct {
+	Offline bool
+	// Manual is true when offline mode was toggled by the user rather than detected
+	Manual bool
+}
+
+// Prober reports whether the network is reachable
+type Prober func(ctx context.Context) bool
+
+const (
+	probeTimeout      = 2 * time.Second
+	reconnectInterval = 15 * time.Second
+)
+
+// probeAddresses are dialed to detect connectivity; reaching any one is enough
+var probeAddresses = []string{
+	"api.anthropic.com:443",
+	"api.openai.com:443",
+	"1.1.1.1:443",
+}
+
+// DialProber is the default prober. It attempts a TCP connection to well-known hosts.
+func DialProber(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	results := make(chan bool, len(probeAddresses))
+	var dialer net.Dialer
+	for _, addr := range probeAddresses {
+		go func(addr string) {
+			conn, err := dialer.DialContext(ctx, "tcp", addr)
+			if err == nil {
+				conn.Close()
+			}
+			results <- err == nil
+		}(addr)
+	}
+	for range probeAddresses {
+		if <-results {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	mu       sync.RWMutex
+	state    State
+	prober   Prober = DialProber
+	onlineCh        = make(chan struct{})
+	broker          = pubsub.NewBroker[State]()
+)
+
+func init() {
+	// Start in the online state
+	close(onlineCh)
+}
+
+// SetProber replaces the connectivity probe. Intended for tests.
+func SetProber(p Prober) {
+	mu.Lock()
+	defer mu.Unlock()
+	prober = p
+}
+
+// Detect runs the connectivity probe and updates the detected state. It does
+// not override a manual offline toggle.
+func Detect(ctx context.Context) bool {
+	mu.RLock()
+	probe := prober
+	mu.RUnlock()
+
+	online := probe(ctx)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if state.Manual {
+		return !state.Offline
+	}
+	setStateLocked(State{Offline: !online})
+	return online
+}
+
+// Monitor probes connectivity at startup and then periodically while offline,
+// so the state flips back once the network returns.
+func Monitor(ctx context.Context) {
+	go func() {
+		defer logging.RecoverPanic("connectivity-monitor", nil)
+
+		if !Detect(ctx) {
+			logging.WarnPersist("No network connectivity detected, running in offline mode")
+		}
+
+		ticker := time.NewTicker(reconnectInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if IsOffline() && !Current().Manual {
+					if Detect(ctx) {
+						logging.InfoPersist("Connectivity restored")
+					}
+				}
+			}
+		}
+	}()
+}
+
+// SetManualOffline toggles offline mode explicitly. Disabling it re-enables
+// automatic detection on the next probe.
+func SetManualOffline(offline bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	setStateLocked(State{Offline: offline, Manual: offline})
+}
+
+// IsOffline reports whether remote providers should be treated as unreachable
+func IsOffline() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return state.Offline
+}
+
+// Current returns the current connectivity state
+func Current() State {
+	mu.RLock()
+	defer mu.RUnlock()
+	return state
+}
+
+// WaitOnline blocks until connectivity returns or ctx is done
+func WaitOnline(ctx context.Context) error {
+	mu.RLock()
+	ch := onlineCh
+	mu.RUnlock()
+
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Subscribe returns a channel of connectivity state changes
+func Subscribe(ctx context.Context) <-chan pubsub.Event[State] {
+	return broker.Subscribe(ctx)
+}
+
+func setStateLocked(next State) {
+	if next == state {
+		return
+	}
+	wasOffline := state.Offline
+	state = next
+
+	switch {
+	case !wasOffline && next.Offline:
+		onlineCh = make(chan struct{})
+	case wasOffline && !next.Offline:
+		close(onlineCh)
+	}
+
+	broker.Publish(pubsub.UpdatedEvent, next)
+}
@@ -0,0 +1,46 @@
+package connectivity
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestOfflineDetection(t *testing.T) {
+	online := false
+	SetProber(func(ctx context.Context) bool { return online })
+	defer func() {
+		SetProber(DialProber)
+		SetManualOffline(false)
+	}()
+
+	if Detect(context.Background()) {
+		t.Fatal("Detect should report offline when the probe fails")
+	}
+	if !IsOffline() {
+		t.Fatal("State should be offline after a failed probe")
+	}
+
+	waitErr := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		waitErr <- WaitOnline(ctx)
+	}()
+
+	online = true
+	if !Detect(context.Background()) {
+		t.Fatal("Detect should report online when the probe succeeds")
+	}
+	if err := <-waitErr; err != nil {
+		t.Fatalf("WaitOnline should return once connectivity is restored, got %v", err)
+	}
+
+	SetManualOffline(true)
+	if Detect(context.Background()) || !IsOffline() {
+		t.Error("A manual offline toggle should not be overridden by detection")
+	}
+	if !Current().Manual {
+		t.Error("Manual offline state should be flagged as manual")
+	}
+}
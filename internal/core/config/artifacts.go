@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// DefaultArtifactMinBytes is the size from which the code blocks of a
+// response are saved as artifacts
+const DefaultArtifactMinBytes = 1024
+
+// ArtifactsConfig sets which code blocks of the responses are saved as files
+// of the session
+type ArtifactsConfig struct {
+	// Disabled turns off saving code blocks as artifacts
+	Disabled bool `json:"disabled,omitempty"`
+	// MinBytes is the size from which a code block is saved, blocks tagged
+	// with a file name are saved whatever their size
+	MinBytes int `json:"minBytes,omitempty"`
+	// Languages restricts the saved blocks to those of these languages, all
+	// are saved when empty
+	Languages []string `json:"languages,omitempty"`
+}
+
+// Threshold returns the size from which a code block is saved
+func (a ArtifactsConfig) Threshold() int {
+	if a.MinBytes <= 0 {
+		return DefaultArtifactMinBytes
+	}
+	return a.MinBytes
+}
+
+// Saves reports whether code blocks of language are saved
+func (a ArtifactsConfig) Saves(language string) bool {
+	if a.Disabled {
+		return false
+	}
+	return len(a.Languages) == 0 || slices.ContainsFunc(a.Languages, func(l string) bool {
+		return strings.EqualFold(l, language)
+	})
+}
+
+// validate checks the artifact settings
+func (a ArtifactsConfig) validate() error {
+	if a.MinBytes < 0 {
+		return fmt.Errorf("minBytes must be positive")
+	}
+	return nil
+}
+
+// SessionDirectory returns the directory holding the files of a session,
+// such as its artifacts
+func SessionDirectory(sessionID string) string {
+	return filepath.Join(Get().Data.Directory, "sessions", sessionID)
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultAutoMaxSteps bounds the turns of an auto mode run when unset
+	DefaultAutoMaxSteps = 10
+	// DefaultAutoMaxDuration bounds the wall-clock time of an auto mode run
+	// when unset
+	DefaultAutoMaxDuration = 15 * time.Minute
+	// DefaultAutoTokenBudget bounds the tokens an auto mode run generates
+	// when unset
+	DefaultAutoTokenBudget = 100000
+	// maxAutoSteps bounds the configurable turns of an auto mode run
+	maxAutoSteps = 100
+)
+
+// AutoMode bounds the auto mode runs of an agent, in which it keeps working
+// on a task turn after turn without waiting for the user
+type AutoMode struct {
+	// MaxSteps bounds the turns of a run
+	MaxSteps int `json:"maxSteps,omitempty"`
+	// MaxDuration bounds the wall-clock time of a run, such as "10m"
+	MaxDuration string `json:"maxDuration,omitempty"`
+	// TokenBudget bounds the tokens a run generates
+	TokenBudget int64 `json:"tokenBudget,omitempty"`
+	// TestCommand is run in the working directory after every turn, the run
+	// stops once it succeeds
+	TestCommand string `json:"testCommand,omitempty"`
+}
+
+// AutoModeLimits returns the auto mode limits of the agent, the defaults
+// when none are configured
+func (a Agent) AutoModeLimits() AutoMode {
+	if a.AutoMode == nil {
+		return AutoMode{}
+	}
+	return *a.AutoMode
+}
+
+// Steps returns the maximum number of turns of a run
+func (a AutoMode) Steps() int {
+	if a.MaxSteps <= 0 {
+		return DefaultAutoMaxSteps
+	}
+	return a.MaxSteps
+}
+
+// Duration returns the maximum wall-clock time of a run
+func (a AutoMode) Duration() time.Duration {
+	duration, err := time.ParseDuration(a.MaxDuration)
+	if err != nil || duration <= 0 {
+		return DefaultAutoMaxDuration
+	}
+	return duration
+}
+
+// Tokens returns the maximum number of tokens a run generates
+func (a AutoMode) Tokens() int64 {
+	if a.TokenBudget <= 0 {
+		return DefaultAutoTokenBudget
+	}
+	return a.TokenBudget
+}
+
+// validate checks the auto mode limits of an agent
+func (a AutoMode) validate() error {
+	if a.MaxSteps < 0 || a.MaxSteps > maxAutoSteps {
+		return fmt.Errorf("maxSteps must be between 0 and %d", maxAutoSteps)
+	}
+	if a.MaxDuration != "" {
+		duration, err := time.ParseDuration(a.MaxDuration)
+		if err != nil {
+			return fmt.Errorf("invalid maxDuration: %w", err)
+		}
+		if duration <= 0 {
+			return fmt.Errorf("maxDuration must be positive")
+		}
+	}
+	if a.TokenBudget < 0 {
+		return fmt.Errorf("tokenBudget must be positive")
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultBatchPollInterval is how often the batches submitted to the
+	// providers are checked
+	DefaultBatchPollInterval = time.Minute
+	// DefaultBatchAttempts is how many times a request of a batch is
+	// submitted before its failure is delivered
+	DefaultBatchAttempts = 3
+
+	minBatchPollInterval = time.Second
+)
+
+// BatchConfig sets how the offline jobs that opted into batch mode run
+// through the batch APIs of the providers
+type BatchConfig struct {
+	// PollInterval is how often the submitted batches are checked, such as
+	// "5m"
+	PollInterval string `json:"pollInterval,omitempty"`
+	// Attempts is how many times a failed request is submitted again in a new
+	// batch, the first submission included
+	Attempts int `json:"attempts,omitempty"`
+}
+
+// Interval returns how often the submitted batches are checked
+func (b BatchConfig) Interval() time.Duration {
+	interval, err := time.ParseDuration(b.PollInterval)
+	if err != nil || interval <= 0 {
+		return DefaultBatchPollInterval
+	}
+	return interval
+}
+
+// AttemptLimit returns how many times a request of a batch is submitted
+func (b BatchConfig) AttemptLimit() int {
+	if b.Attempts <= 0 {
+		return DefaultBatchAttempts
+	}
+	return b.Attempts
+}
+
+// validate checks the batch settings
+func (b BatchConfig) validate() error {
+	if b.PollInterval != "" {
+		interval, err := time.ParseDuration(b.PollInterval)
+		if err != nil {
+			return fmt.Errorf("pollInterval: %w", err)
+		}
+		if interval < minBatchPollInterval {
+			return fmt.Errorf("pollInterval must be at least %s, got %s", minBatchPollInterval, interval)
+		}
+	}
+	if b.Attempts < 0 {
+		return fmt.Errorf("attempts must be positive")
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+)
+
+const (
+	// DefaultAutoCompactThresholdRatio is the fraction of the context window
+	// from which a session is compacted when unset
+	DefaultAutoCompactThresholdRatio = 0.8
+	// DefaultAutoCompactMinMessages is the number of messages below which a
+	// session is not compacted when unset
+	DefaultAutoCompactMinMessages = 20
+)
+
+// AutoCompactConfig sets when a session is summarized into a new one as its
+// context fills up. A plain boolean, as in "autoCompact": true, sets Enabled
+// alone, and an object without "enabled" enables compaction.
+type AutoCompactConfig struct {
+	Enabled bool `json:"enabled"`
+	// ThresholdRatio is the fraction of the context window of the model the
+	// tokens of a session reach before it is compacted
+	ThresholdRatio float64 `json:"thresholdRatio,omitempty"`
+	// MinMessages keeps the sessions with fewer messages from being compacted
+	MinMessages int `json:"minMessages,omitempty"`
+}
+
+// UnmarshalJSON reads a plain boolean as Enabled
+func (a *AutoCompactConfig) UnmarshalJSON(data []byte) error {
+	var enabled bool
+	if err := json.Unmarshal(data, &enabled); err == nil {
+		*a = AutoCompactConfig{Enabled: enabled}
+		return nil
+	}
+	type plain AutoCompactConfig
+	decoded := plain{Enabled: true}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*a = AutoCompactConfig(decoded)
+	return nil
+}
+
+// ShouldCompact reports whether a session of messages holding tokens of a
+// context window is compacted
+func (a AutoCompactConfig) ShouldCompact(tokens, contextWindow, messages int64) bool {
+	if !a.Enabled || contextWindow <= 0 || messages < int64(a.MinMessages) {
+		return false
+	}
+	return float64(tokens) >= float64(contextWindow)*a.ThresholdRatio
+}
+
+// withDefaults returns the settings with the unset or out of range values
+// replaced by their defaults
+func (a AutoCompactConfig) withDefaults() AutoCompactConfig {
+	if a.ThresholdRatio <= 0 || a.ThresholdRatio > 1 {
+		a.ThresholdRatio = DefaultAutoCompactThresholdRatio
+	}
+	if a.MinMessages <= 0 {
+		a.MinMessages = DefaultAutoCompactMinMessages
+	}
+	return a
+}
+
+// autoCompactDecodeHook decodes the autoCompact setting of the config files
+// as UnmarshalJSON does, the files being read by viper
+func autoCompactDecodeHook(from reflect.Type, to reflect.Type, data any) (any, error) {
+	if to != reflect.TypeOf(AutoCompactConfig{}) {
+		return data, nil
+	}
+	switch value := data.(type) {
+	case bool:
+		return map[string]any{"enabled": value}, nil
+	case map[string]any:
+		for key := range value {
+			if strings.EqualFold(key, "enabled") {
+				return data, nil
+			}
+		}
+		decoded := map[string]any{"enabled": true}
+		for key, v := range value {
+			decoded[key] = v
+		}
+		return decoded, nil
+	}
+	return data, nil
+}
+
+// withAutoCompactDecodeHook adds autoCompactDecodeHook to the decode hooks
+// of viper
+func withAutoCompactDecodeHook(c *mapstructure.DecoderConfig) {
+	c.DecodeHook = mapstructure.ComposeDecodeHookFunc(autoCompactDecodeHook, c.DecodeHook)
+}
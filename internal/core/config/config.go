@@ -7,7 +7,9 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/caronex/intelligence-interface/internal/llm/models"
 	"github.com/caronex/intelligence-interface/internal/core/logging"
@@ -31,6 +33,36 @@ type MCPServer struct {
 	Type    MCPType           `json:"type"`
 	URL     string            `json:"url"`
 	Headers map[string]string `json:"headers"`
+	// TokenRefreshCommand prints a new access token for an SSE server, run
+	// when the server answers 401. The token replaces the Authorization header
+	// for the running instance only.
+	TokenRefreshCommand string `json:"tokenRefreshCommand,omitempty"`
+	// TokenRefreshIntervalSec refreshes the token when it is older, before
+	// the server rejects it. 0 refreshes only on 401.
+	TokenRefreshIntervalSec int `json:"tokenRefreshIntervalSec,omitempty"`
+	// Aliases expose tools under another name, keyed by the tool name on the server
+	Aliases map[string]string `json:"aliases,omitempty"`
+	// Allow and Deny filter the tools of the server by qualified name
+	// (<server>.<tool>) or alias. Deny wins, and an empty Allow allows every tool.
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+// ToolAllowed reports whether a tool of the server passes its allow and deny
+// lists, given the names it is known by
+func (m MCPServer) ToolAllowed(names ...string) bool {
+	matches := func(list []string) bool {
+		for _, entry := range list {
+			if slices.Contains(names, entry) {
+				return true
+			}
+		}
+		return false
+	}
+	if matches(m.Deny) {
+		return false
+	}
+	return len(m.Allow) == 0 || matches(m.Allow)
 }
 
 type AgentName string
@@ -45,6 +77,83 @@ type Agent struct {
 	MaxTokens       int64          `json:"maxTokens"`
 	ReasoningEffort string         `json:"reasoningEffort"` // For openai models low,medium,heigh
 	Specialization  *AgentSpecialization `json:"specialization,omitempty"`
+	// Aliases are other names the agent is referred to by, in any case, such
+	// as in the assigned agents of a space
+	Aliases []string `json:"aliases,omitempty"`
+	// Generation tunes how the agent samples responses, provider defaults are used when unset
+	Generation *GenerationParams `json:"generation,omitempty"`
+	// SystemPrompt is prepended to the built-in system prompt of the agent
+	SystemPrompt string `json:"systemPrompt,omitempty"`
+	// SystemPromptPath is a file holding the system prompt, relative to the
+	// working directory, read when SystemPrompt is empty
+	SystemPromptPath string `json:"systemPromptPath,omitempty"`
+	// ReviewFlow has the responses of the agent reviewed and revised before
+	// they are presented
+	ReviewFlow *ReviewFlow `json:"reviewFlow,omitempty"`
+	// AutoMode bounds the runs in which the agent keeps working on a task
+	// without waiting for the user between turns
+	AutoMode *AutoMode `json:"autoMode,omitempty"`
+}
+
+// SystemPromptFile returns the path of the agent's system prompt file,
+// resolved relative to workingDir, or "" when none is configured
+func (a Agent) SystemPromptFile(workingDir string) string {
+	if a.SystemPromptPath == "" || filepath.IsAbs(a.SystemPromptPath) {
+		return a.SystemPromptPath
+	}
+	return filepath.Join(workingDir, a.SystemPromptPath)
+}
+
+// CustomSystemPrompt returns the configured system prompt of the agent,
+// SystemPrompt or else the content of SystemPromptPath, or "" when none is
+// configured
+func (a Agent) CustomSystemPrompt(workingDir string) (string, error) {
+	if a.SystemPrompt != "" {
+		return a.SystemPrompt, nil
+	}
+	path := a.SystemPromptFile(workingDir)
+	if path == "" {
+		return "", nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read system prompt file: %w", err)
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// GenerationParams are provider-agnostic sampling parameters. Unset fields
+// keep the provider defaults and parameters a provider does not support are
+// ignored.
+type GenerationParams struct {
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"topP,omitempty"`
+	FrequencyPenalty *float64 `json:"frequencyPenalty,omitempty"`
+	PresencePenalty  *float64 `json:"presencePenalty,omitempty"`
+	Stop             []string `json:"stop,omitempty"`
+}
+
+// MaxStopSequences is the number of stop sequences every provider accepts
+const MaxStopSequences = 4
+
+// Validate reports the first generation parameter out of its supported range
+func (p GenerationParams) Validate() error {
+	if p.Temperature != nil && (*p.Temperature < 0 || *p.Temperature > 2) {
+		return fmt.Errorf("temperature must be between 0 and 2, got %g", *p.Temperature)
+	}
+	if p.TopP != nil && (*p.TopP < 0 || *p.TopP > 1) {
+		return fmt.Errorf("top_p must be between 0 and 1, got %g", *p.TopP)
+	}
+	if p.FrequencyPenalty != nil && (*p.FrequencyPenalty < -2 || *p.FrequencyPenalty > 2) {
+		return fmt.Errorf("frequency penalty must be between -2 and 2, got %g", *p.FrequencyPenalty)
+	}
+	if p.PresencePenalty != nil && (*p.PresencePenalty < -2 || *p.PresencePenalty > 2) {
+		return fmt.Errorf("presence penalty must be between -2 and 2, got %g", *p.PresencePenalty)
+	}
+	if len(p.Stop) > MaxStopSequences {
+		return fmt.Errorf("at most %d stop sequences are supported, got %d", MaxStopSequences, len(p.Stop))
+	}
+	return nil
 }
 
 // AgentSpecialization defines advanced configuration for agent specialization
@@ -63,6 +172,34 @@ type CoordinationConfig struct {
 	AgentSpawningEnabled  bool                   `json:"agent_spawning_enabled,omitempty"`
 	CommunicationProtocol string                 `json:"communication_protocol,omitempty"`
 	LoadBalancing         map[string]interface{} `json:"load_balancing,omitempty"`
+	// QueueDepth bounds the messages waiting in the queue of the "queue"
+	// protocol, the lowest-priority one being dropped past it
+	QueueDepth int `json:"queue_depth,omitempty"`
+	// MaxPriority is the highest priority of a queued message, from 1 to 10
+	MaxPriority int `json:"max_priority,omitempty"`
+}
+
+const (
+	// DefaultQueueDepth is the default bound of the message queue
+	DefaultQueueDepth = 100
+	// DefaultMaxPriority is the default highest priority of a queued message
+	DefaultMaxPriority = 10
+)
+
+// MessageQueueDepth returns the bound of the message queue
+func (c CoordinationConfig) MessageQueueDepth() int {
+	if c.QueueDepth <= 0 {
+		return DefaultQueueDepth
+	}
+	return c.QueueDepth
+}
+
+// HighestPriority returns the highest priority of a queued message
+func (c CoordinationConfig) HighestPriority() int {
+	if c.MaxPriority <= 0 || c.MaxPriority > 10 {
+		return DefaultMaxPriority
+	}
+	return c.MaxPriority
 }
 
 // SpaceManagementConfig defines space management settings for Caronex
@@ -81,6 +218,22 @@ type EvolutionConfig struct {
 	GoldenRepositoryURL    string `json:"golden_repository_url,omitempty"`
 	SafetyChecksEnabled    bool   `json:"safety_checks_enabled,omitempty"`
 	RollbackCapability     bool   `json:"rollback_capability,omitempty"`
+	// SafetyCheckTimeout bounds the run of the tests of evolved code, as a
+	// duration such as "2m"
+	SafetyCheckTimeout string `json:"safety_check_timeout,omitempty"`
+}
+
+// DefaultSafetyCheckTimeout is the default bound of the run of the tests of
+// evolved code
+const DefaultSafetyCheckTimeout = 2 * time.Minute
+
+// SafetyTimeout returns the bound of the run of the tests of evolved code
+func (c EvolutionConfig) SafetyTimeout() time.Duration {
+	timeout, err := time.ParseDuration(c.SafetyCheckTimeout)
+	if err != nil || timeout <= 0 {
+		return DefaultSafetyCheckTimeout
+	}
+	return timeout
 }
 
 // LearningConfig defines agent learning settings
@@ -92,6 +245,34 @@ type LearningConfig struct {
 	LearningHistoryLimit int     `json:"learning_history_limit,omitempty"`
 }
 
+// Knowledge retentions of the learning observations of agents
+const (
+	// KnowledgeRetentionSession keeps the observations in memory, until the
+	// process exits
+	KnowledgeRetentionSession = "session"
+	// KnowledgeRetentionPersistent keeps them in the database, across restarts
+	KnowledgeRetentionPersistent = "persistent"
+)
+
+// DefaultLearningHistoryLimit is the number of learning observations kept
+// per agent by default
+const DefaultLearningHistoryLimit = 1000
+
+// Persistent reports whether the learning observations are kept across
+// restarts
+func (c LearningConfig) Persistent() bool {
+	return c.KnowledgeRetention == KnowledgeRetentionPersistent
+}
+
+// HistoryLimit returns the number of learning observations kept per agent,
+// the most recently accessed ones
+func (c LearningConfig) HistoryLimit() int {
+	if c.LearningHistoryLimit <= 0 {
+		return DefaultLearningHistoryLimit
+	}
+	return c.LearningHistoryLimit
+}
+
 // UILayoutConfig defines UI layout configuration for spaces
 type UILayoutConfig struct {
 	Type          string                 `json:"type,omitempty"`
@@ -126,6 +307,10 @@ type ResourceLimitsConfig struct {
 	MaxTools      int   `json:"max_tools,omitempty"`
 }
 
+// SpaceTypes are the valid types of a space. Spaces of another type are
+// validated as "custom".
+var SpaceTypes = []string{"development", "knowledge_base", "social", "communication", "creative", "analysis", "custom"}
+
 // SpaceConfig defines configuration for persistent desktop environments
 type SpaceConfig struct {
 	ID                 string                 `json:"id"`
@@ -137,12 +322,77 @@ type SpaceConfig struct {
 	ResourceLimits     ResourceLimitsConfig   `json:"resource_limits,omitempty"`
 	EvolutionEnabled   bool                   `json:"evolution_enabled,omitempty"`
 	Configuration      map[string]interface{} `json:"configuration,omitempty"`
+	// Environment holds the variables set for tools while the space is active,
+	// over the process environment. A value of keyring:<service>/<account>
+	// is read from the keyring of the system and never logged.
+	Environment map[string]string `json:"environment,omitempty"`
+	// Embeddings overrides the global embedding settings for the knowledge
+	// base of the space
+	Embeddings *EmbeddingsConfig `json:"embeddings,omitempty"`
+	// DefaultSession are the settings of the sessions created in the space
+	DefaultSession *SessionDefaults `json:"default_session,omitempty"`
+}
+
+// SessionDefaults are the settings a session created in a space starts with.
+// They are copied to the session, so changing them leaves the existing
+// sessions as they are, and the session keeps its own changes.
+type SessionDefaults struct {
+	// Agent is the agent whose prompt and settings the session runs with
+	Agent AgentName `json:"agent,omitempty"`
+	// Model overrides the model of the agent
+	Model      models.ModelID    `json:"model,omitempty"`
+	Generation *GenerationParams `json:"generation,omitempty"`
+	// ExcludedContext are the sources of context left out of the system
+	// prompt, such as "memory_notes"
+	ExcludedContext []string `json:"excluded_context,omitempty"`
+	// Tools are the only tools the agent may call, all of them when empty
+	Tools []string `json:"tools,omitempty"`
+}
+
+// Describe lists the settings the defaults set, such as "agent coder, tools
+// view, grep"
+func (d SessionDefaults) Describe() string {
+	var settings []string
+	if d.Agent != "" {
+		settings = append(settings, "agent "+string(d.Agent))
+	}
+	if d.Model != "" {
+		settings = append(settings, "model "+string(d.Model))
+	}
+	if g := d.Generation; g != nil {
+		if g.Temperature != nil {
+			settings = append(settings, fmt.Sprintf("temperature %g", *g.Temperature))
+		}
+		if g.TopP != nil {
+			settings = append(settings, fmt.Sprintf("top_p %g", *g.TopP))
+		}
+		if g.FrequencyPenalty != nil {
+			settings = append(settings, fmt.Sprintf("frequency penalty %g", *g.FrequencyPenalty))
+		}
+		if g.PresencePenalty != nil {
+			settings = append(settings, fmt.Sprintf("presence penalty %g", *g.PresencePenalty))
+		}
+		if len(g.Stop) > 0 {
+			settings = append(settings, fmt.Sprintf("%d stop sequences", len(g.Stop)))
+		}
+	}
+	if len(d.ExcludedContext) > 0 {
+		settings = append(settings, "without "+strings.Join(d.ExcludedContext, ", "))
+	}
+	if len(d.Tools) > 0 {
+		settings = append(settings, "tools "+strings.Join(d.Tools, ", "))
+	}
+	return strings.Join(settings, "; ")
 }
 
 // Provider defines configuration for an LLM provider.
 type Provider struct {
 	APIKey   string `json:"apiKey"`
 	Disabled bool   `json:"disabled"`
+	// DisableCache turns off prompt caching for providers that support it
+	DisableCache bool `json:"disableCache"`
+	// Timeouts bound the requests to the provider
+	Timeouts ProviderTimeouts `json:"timeouts,omitempty"`
 }
 
 // Data defines storage configuration.
@@ -152,21 +402,115 @@ type Data struct {
 
 // LSPConfig defines configuration for Language Server Protocol integration.
 type LSPConfig struct {
-	Disabled bool     `json:"enabled"`
-	Command  string   `json:"command"`
-	Args     []string `json:"args"`
-	Options  any      `json:"options"`
+	// Deprecated: use Enabled, which takes precedence when set.
+	Disabled bool `json:"disabled,omitempty"`
+	// Enabled is nil when not set, leaving the server enabled unless Disabled is set
+	Enabled *bool    `json:"enabled,omitempty"`
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+	Options any      `json:"options"`
+}
+
+// IsEnabled reports whether the server should be started, Enabled taking
+// precedence over the deprecated Disabled
+func (l LSPConfig) IsEnabled() bool {
+	if l.Enabled != nil {
+		return *l.Enabled
+	}
+	return !l.Disabled
 }
 
 // TUIConfig defines the configuration for the Terminal User Interface.
 type TUIConfig struct {
 	Theme string `json:"theme,omitempty"`
+	// RetryMode is what happens to the previous response when retrying it
+	RetryMode RetryMode `json:"retryMode,omitempty"`
+	// EnableMouse reports mouse events to the TUI, for scrolling and clicking
+	EnableMouse bool `json:"enableMouse,omitempty"`
+	// Banner is the card shown at the top of the chat, with the state the
+	// TUI started in
+	Banner BannerConfig `json:"banner,omitempty"`
+	// CostEstimate shows the estimated prompt tokens and cost of a message
+	// before it is sent
+	CostEstimate CostEstimateConfig `json:"costEstimate,omitempty"`
 }
 
+const (
+	// DefaultCostEstimateThreshold is the prompt tokens from which the
+	// estimate of a message is shown
+	DefaultCostEstimateThreshold = 20_000
+	// DefaultExpensiveThreshold is the prompt tokens from which sending a
+	// message is confirmed
+	DefaultExpensiveThreshold = 100_000
+)
+
+// CostEstimateConfig defines when the composer shows the estimated prompt
+// tokens and cost of a message, and when it asks to confirm sending it.
+type CostEstimateConfig struct {
+	// Threshold is the prompt tokens from which the estimate is shown, 0
+	// always showing it
+	Threshold int64 `json:"threshold,omitempty"`
+	// ExpensiveThreshold is the prompt tokens from which sending is only done
+	// once confirmed, 0 never asking
+	ExpensiveThreshold int64 `json:"expensiveThreshold,omitempty"`
+}
+
+// BannerConfig defines the startup banner of the chat and the sections it
+// shows. Each toggle is nil when not set, leaving it on, so that an explicit
+// false is kept when the config file is written back.
+type BannerConfig struct {
+	Enabled *bool `json:"enabled,omitempty"`
+	// Agent shows the active agent and its model
+	Agent *bool `json:"agent,omitempty"`
+	// Workspace shows the working directory and the workspace roots
+	Workspace *bool `json:"workspace,omitempty"`
+	// Space shows the active space
+	Space *bool `json:"space,omitempty"`
+	// Fingerprint shows the fingerprint of the configuration
+	Fingerprint *bool `json:"fingerprint,omitempty"`
+	// Providers shows the configured providers and whether they are reachable
+	Providers *bool `json:"providers,omitempty"`
+	// Warnings shows the problems found validating the configuration
+	Warnings *bool `json:"warnings,omitempty"`
+}
+
+// IsEnabled reports whether the banner is shown
+func (b BannerConfig) IsEnabled() bool {
+	return b.Shows(b.Enabled)
+}
+
+// Shows reports whether a toggle of the banner is on, as it is unless set to
+// false
+func (b BannerConfig) Shows(toggle *bool) bool {
+	return toggle == nil || *toggle
+}
+
+// RetryMode is what happens to the previous response when a response is retried.
+type RetryMode string
+
+const (
+	// RetryModeReplace moves the previous response to a hidden branch of the session
+	RetryModeReplace RetryMode = "replace"
+	// RetryModeAppend keeps the previous response and adds the new one after it
+	RetryModeAppend RetryMode = "append"
+)
+
 // ShellConfig defines the configuration for the shell used by the bash tool.
 type ShellConfig struct {
 	Path string   `json:"path,omitempty"`
 	Args []string `json:"args,omitempty"`
+	// Guard extends the checks commands go through before they run
+	Guard ShellGuardConfig `json:"guard,omitempty"`
+}
+
+// OfflineConfig defines how the application behaves without network connectivity.
+type OfflineConfig struct {
+	// Enabled forces offline mode on startup regardless of detection
+	Enabled bool `json:"enabled,omitempty"`
+	// AutoDetect probes connectivity at startup and while offline
+	AutoDetect bool `json:"autoDetect,omitempty"`
+	// SendQueuedOnReconnect holds messages sent while offline and sends them once connectivity returns
+	SendQueuedOnReconnect bool `json:"sendQueuedOnReconnect,omitempty"`
 }
 
 // CaronexConfig defines the central orchestrator configuration
@@ -190,12 +534,78 @@ type Config struct {
 	Agents       map[AgentName]Agent               `json:"agents,omitempty"`
 	Caronex      CaronexConfig                     `json:"caronex,omitempty"`
 	Spaces       map[string]SpaceConfig            `json:"spaces,omitempty"`
+	Workspaces   map[string]WorkspaceRoot          `json:"workspaces,omitempty"`
 	Debug        bool                              `json:"debug,omitempty"`
 	DebugLSP     bool                              `json:"debugLSP,omitempty"`
 	ContextPaths []string                          `json:"contextPaths,omitempty"`
 	TUI          TUIConfig                         `json:"tui"`
 	Shell        ShellConfig                       `json:"shell,omitempty"`
-	AutoCompact  bool                              `json:"autoCompact,omitempty"`
+	AutoCompact  AutoCompactConfig                 `json:"autoCompact"`
+	Offline      OfflineConfig                     `json:"offline,omitempty"`
+	Remote       RemoteConfig                      `json:"remote,omitempty"`
+	Tracing      TracingConfig                     `json:"tracing,omitempty"`
+	Logging      LoggingConfig                     `json:"logging,omitempty"`
+	Mentions     MentionsConfig                    `json:"mentions,omitempty"`
+
+	// Artifacts sets which code blocks of the responses are saved as files of
+	// the session
+	Artifacts ArtifactsConfig `json:"artifacts,omitempty"`
+
+	// Ollama sets how the Ollama provider reaches its server
+	Ollama OllamaConfig `json:"ollama,omitempty"`
+
+	// Notes is the long-term memory of the workspace
+	Notes NotesConfig `json:"notes,omitempty"`
+
+	// Scratchpad is the working notes of the sessions
+	Scratchpad ScratchpadConfig `json:"scratchpad,omitempty"`
+
+	// Batch sets the offline jobs run through the batch APIs of the providers
+	Batch BatchConfig `json:"batch,omitempty"`
+
+	// Embeddings sets how the knowledge base is embedded, unless a space
+	// sets its own
+	Embeddings EmbeddingsConfig `json:"embeddings,omitempty"`
+
+	// ProjectTypes overrides the stacks detected from the marker files of the
+	// workspace, such as go, node, python, rust or ruby
+	ProjectTypes []ProjectType `json:"projectTypes,omitempty"`
+
+	// StrictToolInputs rejects tool calls with fields the tool does not have,
+	// rather than ignoring them
+	StrictToolInputs bool `json:"strictToolInputs,omitempty"`
+
+	// MCPServersFile is a JSON or YAML file of MCP server definitions, such as
+	// one shared between projects. Its path is relative to the working
+	// directory, and the servers of the config files override its own.
+	MCPServersFile string `json:"mcpServersFile,omitempty"`
+
+	// UpdateCheckURL is polled in the background for the latest released version.
+	// It should return either a JSON object with a "version" field or a plain
+	// version string. Leave empty to disable update checks.
+	UpdateCheckURL string `json:"updateCheckURL,omitempty"`
+
+	// Warnings are the problems validation corrected, such as an unsupported
+	// model reverted to the default
+	Warnings []string `json:"-"`
+
+	// ProviderSources records where the API key of each provider was read
+	// from, for reporting without the key
+	ProviderSources map[models.ModelProvider]CredentialSource `json:"-"`
+}
+
+// warn logs a problem validation corrected, recording it in the warnings
+func (c *Config) warn(msg string, args ...any) {
+	logging.Warn(msg, args...)
+	warning := msg
+	if len(args) > 0 {
+		attrs := make([]string, 0, len(args)/2)
+		for i := 0; i+1 < len(args); i += 2 {
+			attrs = append(attrs, fmt.Sprintf("%v=%v", args[i], args[i+1]))
+		}
+		warning += " (" + strings.Join(attrs, ", ") + ")"
+	}
+	c.Warnings = append(c.Warnings, warning)
 }
 
 // Application constants
@@ -223,44 +633,60 @@ var defaultContextPaths = []string{
 	"OPENCODE.local.md",
 }
 
-// Global configuration instance
-var cfg *Config
-
 // Load initializes the configuration from environment variables and config files.
 // If debug is true, debug mode is enabled and log level is set to debug.
 // It returns an error if configuration loading fails.
 func Load(workingDir string, debug bool) (*Config, error) {
-	if cfg != nil {
+	updateMu.Lock()
+	defer updateMu.Unlock()
+	if cfg := Get(); cfg != nil {
 		return cfg, nil
 	}
 
-	cfg = &Config{
+	cfg := &Config{
 		WorkingDir: workingDir,
 		MCPServers: make(map[string]MCPServer),
 		Providers:  make(map[models.ModelProvider]Provider),
 		LSP:        make(map[string]LSPConfig),
 		Spaces:     make(map[string]SpaceConfig),
 	}
+	err := load(cfg, workingDir, debug)
+	// The configuration is published even when it is invalid, for the
+	// callers reporting what is wrong with it
+	current.Store(cfg)
+	return cfg, err
+}
 
+// load reads the configuration into cfg, then validates it
+func load(cfg *Config, workingDir string, debug bool) error {
 	configureViper()
 	setDefaults(debug)
 
 	// Read global config
 	if err := readConfig(viper.ReadInConfig()); err != nil {
-		return cfg, err
+		return err
 	}
+	sources := make(map[models.ModelProvider]CredentialSource)
+	recordCredentialSources(viper.GetViper(), CredentialGlobal, sources)
 
-	// Load and merge local config
-	mergeLocalConfig(workingDir)
+	// The environment overrides the global config, and the local config both
+	mergeEnvProviders(sources)
+	mergeLocalConfig(workingDir, sources)
+
+	// Merge the MCP servers defined in a separate file
+	if err := mergeMCPServersFile(workingDir); err != nil {
+		return err
+	}
 
 	setProviderDefaults()
 
 	// Apply configuration to the struct
-	if err := viper.Unmarshal(cfg); err != nil {
-		return cfg, fmt.Errorf("failed to unmarshal config: %w", err)
+	if err := viper.Unmarshal(cfg, withAutoCompactDecodeHook); err != nil {
+		return fmt.Errorf("failed to unmarshal config: %w", err)
 	}
+	cfg.ProviderSources = sources
 
-	applyDefaultValues()
+	applyDefaultValues(cfg)
 	defaultLevel := slog.LevelInfo
 	if cfg.Debug {
 		defaultLevel = slog.LevelDebug
@@ -271,40 +697,40 @@ func Load(workingDir string, debug bool) (*Config, error) {
 		// if file does not exist create it
 		if _, err := os.Stat(loggingFile); os.IsNotExist(err) {
 			if err := os.MkdirAll(cfg.Data.Directory, 0o755); err != nil {
-				return cfg, fmt.Errorf("failed to create directory: %w", err)
+				return fmt.Errorf("failed to create directory: %w", err)
 			}
 			if _, err := os.Create(loggingFile); err != nil {
-				return cfg, fmt.Errorf("failed to create log file: %w", err)
+				return fmt.Errorf("failed to create log file: %w", err)
 			}
 		}
 
 		sloggingFileWriter, err := os.OpenFile(loggingFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o666)
 		if err != nil {
-			return cfg, fmt.Errorf("failed to open log file: %w", err)
+			return fmt.Errorf("failed to open log file: %w", err)
 		}
 		// Configure logger
-		logger := slog.New(slog.NewTextHandler(sloggingFileWriter, &slog.HandlerOptions{
+		logThrottle = logging.NewThrottleHandler(slog.NewTextHandler(logging.NewRedactingWriter(sloggingFileWriter), &slog.HandlerOptions{
 			Level: defaultLevel,
-		}))
-		slog.SetDefault(logger)
+		}), cfg.Logging.ThrottleOptions())
+		slog.SetDefault(slog.New(logThrottle))
 	} else {
 		// Configure logger
-		logger := slog.New(slog.NewTextHandler(logging.NewWriter(), &slog.HandlerOptions{
+		logThrottle = logging.NewThrottleHandler(slog.NewTextHandler(logging.NewRedactingWriter(logging.NewWriter()), &slog.HandlerOptions{
 			Level: defaultLevel,
-		}))
-		slog.SetDefault(logger)
+		}), cfg.Logging.ThrottleOptions())
+		slog.SetDefault(slog.New(logThrottle))
 	}
 
 	// Validate configuration
-	if err := Validate(); err != nil {
-		return cfg, fmt.Errorf("config validation failed: %w", err)
+	if err := validate(cfg); err != nil {
+		return fmt.Errorf("config validation failed: %w", err)
 	}
 
 	if cfg.Agents == nil {
 		cfg.Agents = make(map[AgentName]Agent)
 	}
 
-	return cfg, nil
+	return nil
 }
 
 // configureViper sets up viper's configuration paths and environment variables.
@@ -323,7 +749,15 @@ func setDefaults(debug bool) {
 	viper.SetDefault("data.directory", defaultDataDirectory)
 	viper.SetDefault("contextPaths", defaultContextPaths)
 	viper.SetDefault("tui.theme", "intelligence-interface")
+	viper.SetDefault("tui.retryMode", string(RetryModeReplace))
+	viper.SetDefault("tui.enableMouse", true)
+	viper.SetDefault("tui.costEstimate.threshold", DefaultCostEstimateThreshold)
+	viper.SetDefault("tui.costEstimate.expensiveThreshold", DefaultExpensiveThreshold)
 	viper.SetDefault("autoCompact", true)
+	viper.SetDefault("offline.autoDetect", true)
+	viper.SetDefault("offline.sendQueuedOnReconnect", false)
+	viper.SetDefault("tracing.enabled", true)
+	viper.SetDefault("tracing.slowTurnThreshold", defaultTracingSlowTurnThreshold)
 
 	// Set default shell from environment or fallback to /bin/bash
 	shellPath := os.Getenv("SHELL")
@@ -379,95 +813,49 @@ func setMetaSystemDefaults() {
 	viper.SetDefault("caronex.learning.learning_history_limit", 1000)
 }
 
-// setProviderDefaults configures LLM provider defaults based on provider provided by
-// environment variables and configuration file.
+// setProviderDefaults configures LLM provider defaults based on the providers
+// of the configuration files and environment merged before.
 func setProviderDefaults() {
-	// Set all API keys we can find in the environment
-	if apiKey := os.Getenv("ANTHROPIC_API_KEY"); apiKey != "" {
-		viper.SetDefault("providers.anthropic.apiKey", apiKey)
-	}
-	if apiKey := os.Getenv("OPENAI_API_KEY"); apiKey != "" {
-		viper.SetDefault("providers.openai.apiKey", apiKey)
-	}
-	if apiKey := os.Getenv("GEMINI_API_KEY"); apiKey != "" {
-		viper.SetDefault("providers.gemini.apiKey", apiKey)
-	}
-	if apiKey := os.Getenv("GROQ_API_KEY"); apiKey != "" {
-		viper.SetDefault("providers.groq.apiKey", apiKey)
-	}
-	if apiKey := os.Getenv("OPENROUTER_API_KEY"); apiKey != "" {
-		viper.SetDefault("providers.openrouter.apiKey", apiKey)
-	}
-	if apiKey := os.Getenv("XAI_API_KEY"); apiKey != "" {
-		viper.SetDefault("providers.xai.apiKey", apiKey)
-	}
-	if apiKey := os.Getenv("AZURE_OPENAI_ENDPOINT"); apiKey != "" {
-		// api-key may be empty when using Entra ID credentials – that's okay
-		viper.SetDefault("providers.azure.apiKey", os.Getenv("AZURE_OPENAI_API_KEY"))
-	}
+	// Ollama needs no credentials, only a running server
+	detectOllama()
 
-	// Use this order to set the default models
-	// 1. Anthropic
-	// 2. OpenAI
-	// 3. Google Gemini
-	// 4. Groq
-	// 5. OpenRouter
-	// 6. AWS Bedrock
-	// 7. Azure
-	// 8. Google Cloud VertexAI
-
-	// Anthropic configuration
-	if key := viper.GetString("providers.anthropic.apiKey"); strings.TrimSpace(key) != "" {
-		viper.SetDefault("agents.caronex.model", models.Claude4Sonnet)
-		return
-	}
-
-	// OpenAI configuration
-	if key := viper.GetString("providers.openai.apiKey"); strings.TrimSpace(key) != "" {
-		viper.SetDefault("agents.caronex.model", models.GPT41)
-		return
-	}
-
-	// Google Gemini configuration
-	if key := viper.GetString("providers.gemini.apiKey"); strings.TrimSpace(key) != "" {
-		viper.SetDefault("agents.caronex.model", models.Gemini25)
-		return
-	}
-
-	// Groq configuration
-	if key := viper.GetString("providers.groq.apiKey"); strings.TrimSpace(key) != "" {
-		viper.SetDefault("agents.caronex.model", models.QWENQwq)
-		return
-	}
-
-	// OpenRouter configuration
-	if key := viper.GetString("providers.openrouter.apiKey"); strings.TrimSpace(key) != "" {
-		viper.SetDefault("agents.caronex.model", models.OpenRouterClaude37Sonnet)
-		return
-	}
-
-	// XAI configuration
-	if key := viper.GetString("providers.xai.apiKey"); strings.TrimSpace(key) != "" {
-		viper.SetDefault("agents.caronex.model", models.XAIGrok3Beta)
-		return
-	}
-
-	// AWS Bedrock configuration
-	if hasAWSCredentials() {
-		viper.SetDefault("agents.caronex.model", models.BedrockClaude37Sonnet)
+	// Pick the default model from the most popular provider that has credentials
+	for _, provider := range models.SupportedProviders() {
+		model, ok := providerDefaultModels[provider]
+		if !ok || !providerAvailable(provider) {
+			continue
+		}
+		viper.SetDefault("agents.caronex.model", model)
 		return
 	}
+}
 
-	// Azure OpenAI configuration
-	if os.Getenv("AZURE_OPENAI_ENDPOINT") != "" {
-		viper.SetDefault("agents.caronex.model", models.AzureGPT41)
-		return
-	}
+// providerDefaultModels is the model selected when a provider is the first
+// one found with credentials.
+var providerDefaultModels = map[models.ModelProvider]models.ModelID{
+	models.ProviderAnthropic:  models.Claude4Sonnet,
+	models.ProviderOpenAI:     models.GPT41,
+	models.ProviderGemini:     models.Gemini25,
+	models.ProviderGROQ:       models.QWENQwq,
+	models.ProviderOpenRouter: models.OpenRouterClaude37Sonnet,
+	models.ProviderXAI:        models.XAIGrok3Beta,
+	models.ProviderBedrock:    models.BedrockClaude37Sonnet,
+	models.ProviderAzure:      models.AzureGPT41,
+	models.ProviderVertexAI:   models.VertexAIGemini25,
+}
 
-	// Google Cloud VertexAI configuration
-	if hasVertexAICredentials() {
-		viper.SetDefault("agents.caronex.model", models.VertexAIGemini25)
-		return
+// providerAvailable reports whether credentials for the provider are configured.
+func providerAvailable(provider models.ModelProvider) bool {
+	switch provider {
+	case models.ProviderBedrock:
+		return hasAWSCredentials()
+	case models.ProviderAzure:
+		return os.Getenv("AZURE_OPENAI_ENDPOINT") != ""
+	case models.ProviderVertexAI:
+		return hasVertexAICredentials()
+	default:
+		key := viper.GetString(fmt.Sprintf("providers.%s.apiKey", provider))
+		return strings.TrimSpace(key) != ""
 	}
 }
 
@@ -521,11 +909,19 @@ func readConfig(err error) error {
 		return nil
 	}
 
+	// Locate the syntax error, which viper does not
+	if _, ok := err.(viper.ConfigParseError); ok {
+		if parseErr := parseConfigFile(viper.ConfigFileUsed()); parseErr != nil {
+			return parseErr
+		}
+	}
+
 	return fmt.Errorf("failed to read config: %w", err)
 }
 
-// mergeLocalConfig loads and merges configuration from the local directory.
-func mergeLocalConfig(workingDir string) {
+// mergeLocalConfig loads and merges configuration from the local directory,
+// recording the source of the API keys it sets.
+func mergeLocalConfig(workingDir string, sources map[models.ModelProvider]CredentialSource) {
 	local := viper.New()
 	local.SetConfigName(fmt.Sprintf(".%s", appName))
 	local.SetConfigType("json")
@@ -534,11 +930,12 @@ func mergeLocalConfig(workingDir string) {
 	// Merge local config if it exists
 	if err := local.ReadInConfig(); err == nil {
 		viper.MergeConfigMap(local.AllSettings())
+		recordCredentialSources(local, CredentialLocal, sources)
 	}
 }
 
 // applyDefaultValues sets default values for configuration fields that need processing.
-func applyDefaultValues() {
+func applyDefaultValues(cfg *Config) {
 	// Set default MCP type if not specified
 	for k, v := range cfg.MCPServers {
 		if v.Type == "" {
@@ -560,6 +957,12 @@ func applyDefaultValues() {
 	if cfg.Caronex.Coordination.CommunicationProtocol == "" {
 		cfg.Caronex.Coordination.CommunicationProtocol = "pubsub"
 	}
+	if cfg.Caronex.Coordination.QueueDepth == 0 {
+		cfg.Caronex.Coordination.QueueDepth = DefaultQueueDepth
+	}
+	if cfg.Caronex.Coordination.MaxPriority == 0 {
+		cfg.Caronex.Coordination.MaxPriority = DefaultMaxPriority
+	}
 	
 	// Apply space management defaults
 	if cfg.Caronex.SpaceManagement.MaxSpaces == 0 {
@@ -577,27 +980,61 @@ func applyDefaultValues() {
 	
 	// Apply learning defaults
 	if cfg.Caronex.Learning.KnowledgeRetention == "" {
-		cfg.Caronex.Learning.KnowledgeRetention = "session"
+		cfg.Caronex.Learning.KnowledgeRetention = KnowledgeRetentionSession
 	}
 	if cfg.Caronex.Learning.AdaptationThreshold == 0 {
 		cfg.Caronex.Learning.AdaptationThreshold = 0.8
 	}
 	if cfg.Caronex.Learning.LearningHistoryLimit == 0 {
-		cfg.Caronex.Learning.LearningHistoryLimit = 1000
+		cfg.Caronex.Learning.LearningHistoryLimit = DefaultLearningHistoryLimit
 	}
 }
 
 // It validates model IDs and providers, ensuring they are supported.
 func validateAgent(cfg *Config, name AgentName, agent Agent) error {
+	// Check the system prompt file is readable
+	if path := agent.SystemPromptFile(cfg.WorkingDir); path != "" {
+		if _, err := os.ReadFile(path); err != nil {
+			return fmt.Errorf("invalid system prompt file for agent %s: %w", name, err)
+		}
+	}
+
+	// Check the review flow
+	if agent.ReviewFlow != nil {
+		if err := agent.ReviewFlow.validate(cfg); err != nil {
+			return fmt.Errorf("invalid review flow for agent %s: %w", name, err)
+		}
+	}
+
+	// Check the auto mode limits
+	if agent.AutoMode != nil {
+		if err := agent.AutoMode.validate(); err != nil {
+			return fmt.Errorf("invalid auto mode for agent %s: %w", name, err)
+		}
+	}
+
+	// Validate generation parameters
+	if agent.Generation != nil {
+		if err := agent.Generation.Validate(); err != nil {
+			cfg.warn("invalid generation parameters, using provider defaults",
+				"agent", name,
+				"error", err)
+
+			updatedAgent := cfg.Agents[name]
+			updatedAgent.Generation = nil
+			cfg.Agents[name] = updatedAgent
+		}
+	}
+
 	// Check if model exists
 	model, modelExists := models.SupportedModels[agent.Model]
 	if !modelExists {
-		logging.Warn("unsupported model configured, reverting to default",
+		cfg.warn("unsupported model configured, reverting to default",
 			"agent", name,
 			"configured_model", agent.Model)
 
 		// Set default model based on available providers
-		if setDefaultModelForAgent(name) {
+		if setDefaultModelForAgent(cfg, name) {
 			logging.Info("set default model for agent", "agent", name, "model", cfg.Agents[name].Model)
 		} else {
 			return fmt.Errorf("no valid provider available for agent %s", name)
@@ -605,6 +1042,13 @@ func validateAgent(cfg *Config, name AgentName, agent Agent) error {
 		return nil
 	}
 
+	// Every agent is given tools, which a model without function calling cannot use
+	if !model.SupportsTools {
+		cfg.warn("model does not support tools, the agent runs without them",
+			"agent", name,
+			"model", agent.Model)
+	}
+
 	// Check if provider for the model is configured
 	provider := model.Provider
 	providerCfg, providerExists := cfg.Providers[provider]
@@ -613,13 +1057,13 @@ func validateAgent(cfg *Config, name AgentName, agent Agent) error {
 		// Provider not configured, check if we have environment variables
 		apiKey := getProviderAPIKey(provider)
 		if apiKey == "" {
-			logging.Warn("provider not configured for model, reverting to default",
+			cfg.warn("provider not configured for model, reverting to default",
 				"agent", name,
 				"model", agent.Model,
 				"provider", provider)
 
 			// Set default model based on available providers
-			if setDefaultModelForAgent(name) {
+			if setDefaultModelForAgent(cfg, name) {
 				logging.Info("set default model for agent", "agent", name, "model", cfg.Agents[name].Model)
 			} else {
 				return fmt.Errorf("no valid provider available for agent %s", name)
@@ -629,17 +1073,20 @@ func validateAgent(cfg *Config, name AgentName, agent Agent) error {
 			cfg.Providers[provider] = Provider{
 				APIKey: apiKey,
 			}
+			if cfg.ProviderSources != nil {
+				cfg.ProviderSources[provider] = CredentialEnv
+			}
 			logging.Info("added provider from environment", "provider", provider)
 		}
 	} else if providerCfg.Disabled || providerCfg.APIKey == "" {
 		// Provider is disabled or has no API key
-		logging.Warn("provider is disabled or has no API key, reverting to default",
+		cfg.warn("provider is disabled or has no API key, reverting to default",
 			"agent", name,
 			"model", agent.Model,
 			"provider", provider)
 
 		// Set default model based on available providers
-		if setDefaultModelForAgent(name) {
+		if setDefaultModelForAgent(cfg, name) {
 			logging.Info("set default model for agent", "agent", name, "model", cfg.Agents[name].Model)
 		} else {
 			return fmt.Errorf("no valid provider available for agent %s", name)
@@ -648,7 +1095,7 @@ func validateAgent(cfg *Config, name AgentName, agent Agent) error {
 
 	// Validate max tokens
 	if agent.MaxTokens <= 0 {
-		logging.Warn("invalid max tokens, setting to default",
+		cfg.warn("invalid max tokens, setting to default",
 			"agent", name,
 			"model", agent.Model,
 			"max_tokens", agent.MaxTokens)
@@ -663,7 +1110,7 @@ func validateAgent(cfg *Config, name AgentName, agent Agent) error {
 		cfg.Agents[name] = updatedAgent
 	} else if model.ContextWindow > 0 && agent.MaxTokens > model.ContextWindow/2 {
 		// Ensure max tokens doesn't exceed half the context window (reasonable limit)
-		logging.Warn("max tokens exceeds half the context window, adjusting",
+		cfg.warn("max tokens exceeds half the context window, adjusting",
 			"agent", name,
 			"model", agent.Model,
 			"max_tokens", agent.MaxTokens,
@@ -691,7 +1138,7 @@ func validateAgent(cfg *Config, name AgentName, agent Agent) error {
 			// Check if reasoning effort is valid (low, medium, high)
 			effort := strings.ToLower(agent.ReasoningEffort)
 			if effort != "low" && effort != "medium" && effort != "high" {
-				logging.Warn("invalid reasoning effort, setting to medium",
+				cfg.warn("invalid reasoning effort, setting to medium",
 					"agent", name,
 					"model", agent.Model,
 					"reasoning_effort", agent.ReasoningEffort)
@@ -704,7 +1151,7 @@ func validateAgent(cfg *Config, name AgentName, agent Agent) error {
 		}
 	} else if !model.CanReason && agent.ReasoningEffort != "" {
 		// Model doesn't support reasoning but reasoning effort is set
-		logging.Warn("model doesn't support reasoning but reasoning effort is set, ignoring",
+		cfg.warn("model doesn't support reasoning but reasoning effort is set, ignoring",
 			"agent", name,
 			"model", agent.Model,
 			"reasoning_effort", agent.ReasoningEffort)
@@ -718,11 +1165,19 @@ func validateAgent(cfg *Config, name AgentName, agent Agent) error {
 	return nil
 }
 
-// Validate checks if the configuration is valid and applies defaults where needed.
+// Validate checks if the current configuration is valid and applies defaults
+// where needed, as an update.
 func Validate() error {
-	if cfg == nil {
-		return fmt.Errorf("config not loaded")
-	}
+	return Update(validate)
+}
+
+// validate checks cfg and corrects it where it can
+func validate(cfg *Config) error {
+	cfg.Warnings = nil
+	normalizeAgents(cfg)
+	normalizeProjectTypes(cfg)
+	// The compaction thresholds apply to the sessions of every agent
+	cfg.AutoCompact = cfg.AutoCompact.withDefaults()
 
 	// Validate agent models
 	for name, agent := range cfg.Agents {
@@ -733,44 +1188,132 @@ func Validate() error {
 
 	// Validate providers
 	for provider, providerCfg := range cfg.Providers {
+		if err := providerCfg.Timeouts.validate(); err != nil {
+			return fmt.Errorf("provider %s: %w", provider, err)
+		}
 		if providerCfg.APIKey == "" && !providerCfg.Disabled {
-			logging.Warn("provider has no API key, marking as disabled", "provider", provider)
+			cfg.warn("provider has no API key, marking as disabled", "provider", provider)
 			providerCfg.Disabled = true
 			cfg.Providers[provider] = providerCfg
 		}
 	}
 
+	// Validate MCP servers
+	if err := validateMCPServers(cfg); err != nil {
+		return err
+	}
+
 	// Validate LSP configurations
-	for language, lspConfig := range cfg.LSP {
-		if lspConfig.Command == "" && !lspConfig.Disabled {
-			logging.Warn("LSP configuration has no command, marking as disabled", "language", language)
-			lspConfig.Disabled = true
-			cfg.LSP[language] = lspConfig
-		}
+	validateLSPConfigs(cfg)
+
+	// Validate the retry mode
+	switch cfg.TUI.RetryMode {
+	case RetryModeReplace, RetryModeAppend:
+	default:
+		cfg.warn("unknown TUI retry mode, using replace", "retryMode", cfg.TUI.RetryMode)
+		cfg.TUI.RetryMode = RetryModeReplace
+	}
+
+	// Validate the cost estimate thresholds
+	if cfg.TUI.CostEstimate.Threshold < 0 {
+		cfg.warn("negative cost estimate threshold, using the default", "threshold", cfg.TUI.CostEstimate.Threshold)
+		cfg.TUI.CostEstimate.Threshold = DefaultCostEstimateThreshold
+	}
+	if cfg.TUI.CostEstimate.ExpensiveThreshold < 0 {
+		cfg.warn("negative expensive cost estimate threshold, never asking to confirm", "expensiveThreshold", cfg.TUI.CostEstimate.ExpensiveThreshold)
+		cfg.TUI.CostEstimate.ExpensiveThreshold = 0
+	}
+
+	// Validate the mention limits
+	if err := cfg.Mentions.validate(); err != nil {
+		return fmt.Errorf("invalid mentions config: %w", err)
+	}
+	if err := cfg.Artifacts.validate(); err != nil {
+		return fmt.Errorf("invalid artifacts config: %w", err)
+	}
+	if err := cfg.Notes.validate(); err != nil {
+		return fmt.Errorf("invalid notes config: %w", err)
+	}
+	if err := cfg.Scratchpad.validate(); err != nil {
+		return fmt.Errorf("invalid scratchpad config: %w", err)
+	}
+	if err := cfg.Batch.validate(); err != nil {
+		return fmt.Errorf("invalid batch config: %w", err)
+	}
+	if err := cfg.Shell.Guard.validate(); err != nil {
+		return fmt.Errorf("invalid shell guard config: %w", err)
+	}
+	if err := cfg.Ollama.validate(); err != nil {
+		return fmt.Errorf("invalid ollama config: %w", err)
+	}
+	if err := validateEmbeddings(cfg); err != nil {
+		return fmt.Errorf("invalid embeddings config: %w", err)
+	}
+
+	// Validate workspace roots
+	if err := validateWorkspaces(cfg); err != nil {
+		return fmt.Errorf("workspace config validation failed: %w", err)
+	}
+
+	// Validate the remote API
+	if err := validateRemote(cfg); err != nil {
+		return fmt.Errorf("remote config validation failed: %w", err)
+	}
+
+	// Validate turn tracing
+	if err := validateTracing(cfg); err != nil {
+		return fmt.Errorf("tracing config validation failed: %w", err)
+	}
+
+	// Validate log throttling
+	if err := validateLogging(cfg); err != nil {
+		return fmt.Errorf("logging config validation failed: %w", err)
 	}
 
 	// Validate meta-system configurations
-	if err := validateMetaSystemConfig(); err != nil {
+	if err := validateMetaSystemConfig(cfg); err != nil {
 		return fmt.Errorf("meta-system config validation failed: %w", err)
 	}
 
 	return nil
 }
 
+// validateLSPConfigs resolves the enabled state of every LSP server, globally
+// and per workspace root, so Enabled and Disabled always agree
+func validateLSPConfigs(cfg *Config) {
+	normalize := func(lspConfigs map[string]LSPConfig, root string) {
+		for language, lspConfig := range lspConfigs {
+			enabled := lspConfig.IsEnabled()
+			if enabled && lspConfig.Command == "" {
+				cfg.warn("LSP configuration has no command, marking as disabled", "language", language, "root", root)
+				enabled = false
+			}
+			lspConfig.Enabled = &enabled
+			lspConfig.Disabled = !enabled
+			lspConfigs[language] = lspConfig
+		}
+	}
+
+	normalize(cfg.LSP, "")
+	for name, root := range cfg.Workspaces {
+		normalize(root.LSP, name)
+	}
+}
+
 // validateMetaSystemConfig validates meta-system specific configurations
-func validateMetaSystemConfig() error {
+func validateMetaSystemConfig(cfg *Config) error {
 	// Validate Caronex configuration
-	if err := validateCaronexConfig(); err != nil {
+	if err := validateCaronexConfig(cfg); err != nil {
 		return fmt.Errorf("Caronex config validation failed: %w", err)
 	}
 
 	// Validate space configurations
-	if err := validateSpaceConfigs(); err != nil {
+	if err := validateSpaceConfigs(cfg); err != nil {
 		return fmt.Errorf("space config validation failed: %w", err)
 	}
 
 	// Validate agent specializations
-	if err := validateAgentSpecializations(); err != nil {
+	if err := validateAgentSpecializations(cfg); err != nil {
 		return fmt.Errorf("agent specialization validation failed: %w", err)
 	}
 
@@ -778,16 +1321,16 @@ func validateMetaSystemConfig() error {
 }
 
 // validateCaronexConfig validates Caronex configuration parameters
-func validateCaronexConfig() error {
+func validateCaronexConfig(cfg *Config) error {
 	caronex := &cfg.Caronex
 
 	// Validate coordination settings
 	if caronex.Coordination.MaxConcurrentAgents < 0 {
-		logging.Warn("invalid max concurrent agents, setting to default", "value", caronex.Coordination.MaxConcurrentAgents)
+		cfg.warn("invalid max concurrent agents, setting to default", "value", caronex.Coordination.MaxConcurrentAgents)
 		caronex.Coordination.MaxConcurrentAgents = 10
 	}
 	if caronex.Coordination.MaxConcurrentAgents > 100 {
-		logging.Warn("max concurrent agents exceeds reasonable limit, adjusting", "value", caronex.Coordination.MaxConcurrentAgents)
+		cfg.warn("max concurrent agents exceeds reasonable limit, adjusting", "value", caronex.Coordination.MaxConcurrentAgents)
 		caronex.Coordination.MaxConcurrentAgents = 100
 	}
 
@@ -802,18 +1345,26 @@ func validateCaronexConfig() error {
 			}
 		}
 		if !valid {
-			logging.Warn("invalid communication protocol, setting to default", "protocol", caronex.Coordination.CommunicationProtocol)
+			cfg.warn("invalid communication protocol, setting to default", "protocol", caronex.Coordination.CommunicationProtocol)
 			caronex.Coordination.CommunicationProtocol = "pubsub"
 		}
 	}
+	if caronex.Coordination.QueueDepth < 0 {
+		cfg.warn("invalid queue depth, setting to default", "value", caronex.Coordination.QueueDepth)
+		caronex.Coordination.QueueDepth = DefaultQueueDepth
+	}
+	if caronex.Coordination.MaxPriority < 0 || caronex.Coordination.MaxPriority > 10 {
+		cfg.warn("max priority out of range, setting to default", "value", caronex.Coordination.MaxPriority)
+		caronex.Coordination.MaxPriority = DefaultMaxPriority
+	}
 
 	// Validate space management settings
 	if caronex.SpaceManagement.MaxSpaces < 0 {
-		logging.Warn("invalid max spaces, setting to default", "value", caronex.SpaceManagement.MaxSpaces)
+		cfg.warn("invalid max spaces, setting to default", "value", caronex.SpaceManagement.MaxSpaces)
 		caronex.SpaceManagement.MaxSpaces = 20
 	}
 	if caronex.SpaceManagement.MaxSpaces > 1000 {
-		logging.Warn("max spaces exceeds reasonable limit, adjusting", "value", caronex.SpaceManagement.MaxSpaces)
+		cfg.warn("max spaces exceeds reasonable limit, adjusting", "value", caronex.SpaceManagement.MaxSpaces)
 		caronex.SpaceManagement.MaxSpaces = 1000
 	}
 
@@ -828,70 +1379,90 @@ func validateCaronexConfig() error {
 			}
 		}
 		if !valid {
-			logging.Warn("invalid space isolation level, setting to default", "level", caronex.SpaceManagement.SpaceIsolationLevel)
+			cfg.warn("invalid space isolation level, setting to default", "level", caronex.SpaceManagement.SpaceIsolationLevel)
 			caronex.SpaceManagement.SpaceIsolationLevel = "standard"
 		}
 	}
 
 	// Validate learning configuration
 	if caronex.Learning.AdaptationThreshold < 0.0 || caronex.Learning.AdaptationThreshold > 1.0 {
-		logging.Warn("adaptation threshold out of range, setting to default", "threshold", caronex.Learning.AdaptationThreshold)
+		cfg.warn("adaptation threshold out of range, setting to default", "threshold", caronex.Learning.AdaptationThreshold)
 		caronex.Learning.AdaptationThreshold = 0.8
 	}
 
 	if caronex.Learning.LearningHistoryLimit < 0 {
-		logging.Warn("invalid learning history limit, setting to default", "limit", caronex.Learning.LearningHistoryLimit)
-		caronex.Learning.LearningHistoryLimit = 1000
+		cfg.warn("invalid learning history limit, setting to default", "limit", caronex.Learning.LearningHistoryLimit)
+		caronex.Learning.LearningHistoryLimit = DefaultLearningHistoryLimit
+	}
+
+	// Validate evolution settings
+	if timeout := caronex.Evolution.SafetyCheckTimeout; timeout != "" {
+		if d, err := time.ParseDuration(timeout); err != nil || d <= 0 {
+			cfg.warn("invalid safety check timeout, setting to default", "timeout", timeout)
+			caronex.Evolution.SafetyCheckTimeout = ""
+		}
+	}
+
+	// Validate knowledge retention
+	validRetentionValues := []string{KnowledgeRetentionSession, KnowledgeRetentionPersistent, ""}
+	if !slices.Contains(validRetentionValues, caronex.Learning.KnowledgeRetention) {
+		cfg.warn("invalid knowledge retention, setting to default", "retention", caronex.Learning.KnowledgeRetention)
+		caronex.Learning.KnowledgeRetention = KnowledgeRetentionSession
 	}
 
 	return nil
 }
 
 // validateSpaceConfigs validates space configuration parameters
-func validateSpaceConfigs() error {
+func validateSpaceConfigs(cfg *Config) error {
 	for spaceID, spaceConfig := range cfg.Spaces {
+		if err := validateSpaceEnvironment(spaceID, spaceConfig); err != nil {
+			return err
+		}
+		if err := validateSessionDefaults(cfg, spaceID, spaceConfig.DefaultSession); err != nil {
+			return err
+		}
+		if len(spaceConfig.AssignedAgents) > 0 {
+			agents, unresolved := resolveSpaceAgents(cfg, spaceID, spaceConfig)
+			for _, err := range unresolved {
+				cfg.warn(err.Error() + ", ignoring it")
+			}
+			spaceConfig.AssignedAgents = agents
+			cfg.Spaces[spaceID] = spaceConfig
+		}
+
 		if spaceConfig.ID == "" {
-			logging.Warn("space missing ID, setting from key", "space_key", spaceID)
+			cfg.warn("space missing ID, setting from key", "space_key", spaceID)
 			updatedConfig := spaceConfig
 			updatedConfig.ID = spaceID
 			cfg.Spaces[spaceID] = updatedConfig
 		}
 
 		if spaceConfig.Name == "" {
-			logging.Warn("space missing name, setting default", "space_id", spaceID)
+			cfg.warn("space missing name, setting default", "space_id", spaceID)
 			updatedConfig := spaceConfig
 			updatedConfig.Name = fmt.Sprintf("Space %s", spaceID)
 			cfg.Spaces[spaceID] = updatedConfig
 		}
 
 		// Validate space type
-		validTypes := []string{"development", "knowledge_base", "social", "custom", ""}
-		if spaceConfig.Type != "" {
-			valid := false
-			for _, spaceType := range validTypes {
-				if spaceConfig.Type == spaceType {
-					valid = true
-					break
-				}
-			}
-			if !valid {
-				logging.Warn("invalid space type, setting to default", "space_id", spaceID, "type", spaceConfig.Type)
-				updatedConfig := spaceConfig
-				updatedConfig.Type = "custom"
-				cfg.Spaces[spaceID] = updatedConfig
-			}
+		if spaceConfig.Type != "" && !slices.Contains(SpaceTypes, spaceConfig.Type) {
+			cfg.warn("invalid space type, setting to default", "space_id", spaceID, "type", spaceConfig.Type)
+			updatedConfig := spaceConfig
+			updatedConfig.Type = "custom"
+			cfg.Spaces[spaceID] = updatedConfig
 		}
 
 		// Validate resource limits
 		if spaceConfig.ResourceLimits.MaxMemoryMB < 0 {
-			logging.Warn("invalid memory limit, disabling", "space_id", spaceID, "memory_mb", spaceConfig.ResourceLimits.MaxMemoryMB)
+			cfg.warn("invalid memory limit, disabling", "space_id", spaceID, "memory_mb", spaceConfig.ResourceLimits.MaxMemoryMB)
 			updatedConfig := spaceConfig
 			updatedConfig.ResourceLimits.MaxMemoryMB = 0
 			cfg.Spaces[spaceID] = updatedConfig
 		}
 
 		if spaceConfig.ResourceLimits.MaxCPUPercent < 0 || spaceConfig.ResourceLimits.MaxCPUPercent > 100 {
-			logging.Warn("invalid CPU limit, disabling", "space_id", spaceID, "cpu_percent", spaceConfig.ResourceLimits.MaxCPUPercent)
+			cfg.warn("invalid CPU limit, disabling", "space_id", spaceID, "cpu_percent", spaceConfig.ResourceLimits.MaxCPUPercent)
 			updatedConfig := spaceConfig
 			updatedConfig.ResourceLimits.MaxCPUPercent = 0
 			cfg.Spaces[spaceID] = updatedConfig
@@ -908,7 +1479,7 @@ func validateSpaceConfigs() error {
 				}
 			}
 			if !valid {
-				logging.Warn("invalid storage backend, setting to default", "space_id", spaceID, "backend", spaceConfig.Persistence.StorageBackend)
+				cfg.warn("invalid storage backend, setting to default", "space_id", spaceID, "backend", spaceConfig.Persistence.StorageBackend)
 				updatedConfig := spaceConfig
 				updatedConfig.Persistence.StorageBackend = "memory"
 				cfg.Spaces[spaceID] = updatedConfig
@@ -920,7 +1491,7 @@ func validateSpaceConfigs() error {
 }
 
 // validateAgentSpecializations validates agent specialization configurations
-func validateAgentSpecializations() error {
+func validateAgentSpecializations(cfg *Config) error {
 	for agentName, agent := range cfg.Agents {
 		if agent.Specialization == nil {
 			continue
@@ -930,7 +1501,7 @@ func validateAgentSpecializations() error {
 
 		// Validate learning rate
 		if spec.LearningRate < 0.0 || spec.LearningRate > 1.0 {
-			logging.Warn("learning rate out of range, setting to default", "agent", agentName, "rate", spec.LearningRate)
+			cfg.warn("learning rate out of range, setting to default", "agent", agentName, "rate", spec.LearningRate)
 			spec.LearningRate = 0.1
 		}
 
@@ -945,7 +1516,7 @@ func validateAgentSpecializations() error {
 				}
 			}
 			if !valid {
-				logging.Warn("invalid coordination mode, setting to default", "agent", agentName, "mode", spec.CoordinationMode)
+				cfg.warn("invalid coordination mode, setting to default", "agent", agentName, "mode", spec.CoordinationMode)
 				spec.CoordinationMode = "cooperative"
 			}
 		}
@@ -986,10 +1557,11 @@ func getProviderAPIKey(provider models.ModelProvider) string {
 	return ""
 }
 
-// setDefaultModelForAgent sets a default model for an agent based on available providers
-func setDefaultModelForAgent(agent AgentName) bool {
+// setDefaultModelForAgent sets a default model for an agent based on available
+// providers, whichever source their API key comes from
+func setDefaultModelForAgent(cfg *Config, agent AgentName) bool {
 	// Check providers in order of preference
-	if apiKey := os.Getenv("ANTHROPIC_API_KEY"); apiKey != "" {
+	if hasProviderKey(cfg, models.ProviderAnthropic) {
 		maxTokens := int64(8000) // Higher token limit for Caronex manager agent
 		cfg.Agents[agent] = Agent{
 			Model:     models.Claude37Sonnet,
@@ -998,7 +1570,7 @@ func setDefaultModelForAgent(agent AgentName) bool {
 		return true
 	}
 
-	if apiKey := os.Getenv("OPENAI_API_KEY"); apiKey != "" {
+	if hasProviderKey(cfg, models.ProviderOpenAI) {
 		model := models.GPT41
 		maxTokens := int64(8000) // Higher token limit for Caronex manager agent
 		reasoningEffort := ""
@@ -1016,7 +1588,7 @@ func setDefaultModelForAgent(agent AgentName) bool {
 		return true
 	}
 
-	if apiKey := os.Getenv("OPENROUTER_API_KEY"); apiKey != "" {
+	if hasProviderKey(cfg, models.ProviderOpenRouter) {
 		model := models.OpenRouterClaude37Sonnet
 		maxTokens := int64(8000) // Higher token limit for Caronex manager agent
 		reasoningEffort := ""
@@ -1034,7 +1606,7 @@ func setDefaultModelForAgent(agent AgentName) bool {
 		return true
 	}
 
-	if apiKey := os.Getenv("GEMINI_API_KEY"); apiKey != "" {
+	if hasProviderKey(cfg, models.ProviderGemini) {
 		model := models.Gemini25
 		maxTokens := int64(8000) // Higher token limit for Caronex manager agent
 
@@ -1045,7 +1617,7 @@ func setDefaultModelForAgent(agent AgentName) bool {
 		return true
 	}
 
-	if apiKey := os.Getenv("GROQ_API_KEY"); apiKey != "" {
+	if hasProviderKey(cfg, models.ProviderGROQ) {
 		maxTokens := int64(8000) // Higher token limit for Caronex manager agent
 
 		cfg.Agents[agent] = Agent{
@@ -1077,11 +1649,22 @@ func setDefaultModelForAgent(agent AgentName) bool {
 		return true
 	}
 
+	// A local Ollama server detected at startup
+	if model, ok := providerDefaultModels[models.ProviderOllama]; ok {
+		if provider, ok := cfg.Providers[models.ProviderOllama]; ok && !provider.Disabled {
+			cfg.Agents[agent] = Agent{
+				Model:     model,
+				MaxTokens: models.SupportedModels[model].DefaultMaxTokens,
+			}
+			return true
+		}
+	}
+
 	return false
 }
 
 func updateCfgFile(updateCfg func(config *Config)) error {
-	if cfg == nil {
+	if Get() == nil {
 		return fmt.Errorf("config not loaded")
 	}
 
@@ -1119,21 +1702,16 @@ func updateCfgFile(updateCfg func(config *Config)) error {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
-	if err := os.WriteFile(configFile, updatedData, 0o644); err != nil {
+	if err := writeFileAtomic(configFile, updatedData, 0o644); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
 	return nil
 }
 
-// Get returns the current configuration.
-// It's safe to call this function multiple times.
-func Get() *Config {
-	return cfg
-}
-
 // WorkingDirectory returns the current working directory from the configuration.
 func WorkingDirectory() string {
+	cfg := Get()
 	if cfg == nil {
 		panic("config not loaded")
 	}
@@ -1141,33 +1719,43 @@ func WorkingDirectory() string {
 }
 
 func UpdateAgentModel(agentName AgentName, modelID models.ModelID) error {
-	if cfg == nil {
+	if Get() == nil {
 		panic("config not loaded")
 	}
 
-	existingAgentCfg := cfg.Agents[agentName]
-
 	model, ok := models.SupportedModels[modelID]
 	if !ok {
 		return fmt.Errorf("model %s not supported", modelID)
 	}
 
-	maxTokens := existingAgentCfg.MaxTokens
-	if model.DefaultMaxTokens > 0 {
-		maxTokens = model.DefaultMaxTokens
-	}
+	var newAgentCfg Agent
+	err := Update(func(cfg *Config) error {
+		if resolved, ok := cfg.ResolveAgent(string(agentName)); ok {
+			agentName = resolved
+		}
+		existingAgentCfg := cfg.Agents[agentName]
+		maxTokens := existingAgentCfg.MaxTokens
+		if model.DefaultMaxTokens > 0 {
+			maxTokens = model.DefaultMaxTokens
+		}
 
-	newAgentCfg := Agent{
-		Model:           modelID,
-		MaxTokens:       maxTokens,
-		ReasoningEffort: existingAgentCfg.ReasoningEffort,
-	}
-	cfg.Agents[agentName] = newAgentCfg
+		newAgentCfg = Agent{
+			Model:           modelID,
+			MaxTokens:       maxTokens,
+			ReasoningEffort: existingAgentCfg.ReasoningEffort,
+			Generation:      existingAgentCfg.Generation,
+			Aliases:         existingAgentCfg.Aliases,
+		}
+		cfg.Agents[agentName] = newAgentCfg
 
-	if err := validateAgent(cfg, agentName, newAgentCfg); err != nil {
-		// revert config update on failure
-		cfg.Agents[agentName] = existingAgentCfg
-		return fmt.Errorf("failed to update agent model: %w", err)
+		// The update is dropped when the agent is invalid
+		if err := validateAgent(cfg, agentName, newAgentCfg); err != nil {
+			return fmt.Errorf("failed to update agent model: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	return updateCfgFile(func(config *Config) {
@@ -1178,14 +1766,60 @@ func UpdateAgentModel(agentName AgentName, modelID models.ModelID) error {
 	})
 }
 
-// UpdateTheme updates the theme in the configuration and writes it to the config file.
-func UpdateTheme(themeName string) error {
-	if cfg == nil {
-		return fmt.Errorf("config not loaded")
+// OverrideAgentGeneration sets generation parameters of an agent on top of the
+// configured ones for this run only, without writing the config file.
+func OverrideAgentGeneration(agentName AgentName, overrides GenerationParams) error {
+	if Get() == nil {
+		panic("config not loaded")
 	}
 
+	return Update(func(cfg *Config) error {
+		resolved, ok := cfg.ResolveAgent(string(agentName))
+		if !ok {
+			return fmt.Errorf("agent %s not found", agentName)
+		}
+		agentName = resolved
+		agentCfg := cfg.Agents[agentName]
+
+		var params GenerationParams
+		if agentCfg.Generation != nil {
+			params = *agentCfg.Generation
+		}
+		if overrides.Temperature != nil {
+			params.Temperature = overrides.Temperature
+		}
+		if overrides.TopP != nil {
+			params.TopP = overrides.TopP
+		}
+		if overrides.FrequencyPenalty != nil {
+			params.FrequencyPenalty = overrides.FrequencyPenalty
+		}
+		if overrides.PresencePenalty != nil {
+			params.PresencePenalty = overrides.PresencePenalty
+		}
+		if overrides.Stop != nil {
+			params.Stop = overrides.Stop
+		}
+		if err := params.Validate(); err != nil {
+			return fmt.Errorf("invalid generation parameters: %w", err)
+		}
+
+		agentCfg.Generation = &params
+		cfg.Agents[agentName] = agentCfg
+		return nil
+	})
+}
+
+// UpdateTheme updates the theme in the configuration and writes it to the config file.
+func UpdateTheme(themeName string) error {
 	// Update the in-memory config
-	cfg.TUI.Theme = themeName
+	err := Update(func(cfg *Config) error {
+		cfg.TUI.Theme = themeName
+		return nil
+	})
+	if err != nil {
+		return err
+	}
 
 	// Update the file config
 	return updateCfgFile(func(config *Config) {
@@ -1,8 +1,16 @@
 package config
 
 import (
+	"encoding/json"
 	"os"
+	"path/filepath"
+	"slices"
 	"testing"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/spf13/viper"
 )
 
 func TestMetaSystemConfiguration(t *testing.T) {
@@ -17,7 +25,7 @@ func TestMetaSystemConfiguration(t *testing.T) {
 	}
 
 	// Reset global config for clean test
-	cfg = nil
+	current.Store(nil)
 
 	config, err := Load(workingDir, false)
 	if err != nil {
@@ -81,7 +89,7 @@ func TestMetaSystemConfiguration(t *testing.T) {
 				DefaultTheme: "opencode",
 				Customizable: true,
 			},
-			AssignedAgents: []string{"coder", "caronex"},
+			AssignedAgents: []string{"caronex"},
 			Persistence: PersistenceConfig{
 				Enabled:        true,
 				StorageBackend: "memory",
@@ -164,4 +172,473 @@ func TestMetaSystemConfiguration(t *testing.T) {
 	})
 
 	t.Logf("🎉 Meta-system configuration test completed successfully")
-}
\ No newline at end of file
+}
+func TestGenerationParamsValidate(t *testing.T) {
+	value := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name    string
+		params  GenerationParams
+		wantErr bool
+	}{
+		{"unset", GenerationParams{}, false},
+		{"in range", GenerationParams{Temperature: value(2), TopP: value(0), FrequencyPenalty: value(-2), Stop: []string{"a", "b", "c", "d"}}, false},
+		{"temperature too high", GenerationParams{Temperature: value(2.1)}, true},
+		{"negative top_p", GenerationParams{TopP: value(-0.1)}, true},
+		{"presence penalty too low", GenerationParams{PresencePenalty: value(-3)}, true},
+		{"too many stop sequences", GenerationParams{Stop: []string{"a", "b", "c", "d", "e"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.params.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProviderTimeouts(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeouts ProviderTimeouts
+		wantErr  bool
+	}{
+		{"unset", ProviderTimeouts{}, false},
+		{"valid", ProviderTimeouts{Connect: "5s", FirstToken: "1m", Idle: "90s", Request: "15m"}, false},
+		{"invalid", ProviderTimeouts{Idle: "soon"}, true},
+		{"zero", ProviderTimeouts{FirstToken: "0s"}, true},
+		{"negative", ProviderTimeouts{Request: "-1m"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.timeouts.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	got := ProviderTimeouts{FirstToken: "30s", Idle: "soon"}.Durations()
+	want := TimeoutDurations{
+		Connect:    DefaultConnectTimeout,
+		FirstToken: 30 * time.Second,
+		Idle:       DefaultIdleTimeout,
+		Request:    DefaultRequestTimeout,
+	}
+	if got != want {
+		t.Errorf("Durations() = %+v, want %+v", got, want)
+	}
+}
+
+func TestMCPServerToolAllowed(t *testing.T) {
+	tests := []struct {
+		name   string
+		server MCPServer
+		want   bool
+	}{
+		{"no lists", MCPServer{}, true},
+		{"allowed by qualified name", MCPServer{Allow: []string{"web.fetch"}}, true},
+		{"allowed by alias", MCPServer{Allow: []string{"web_get"}}, true},
+		{"not allowed", MCPServer{Allow: []string{"web.search"}}, false},
+		{"denied by alias", MCPServer{Allow: []string{"web.fetch"}, Deny: []string{"web_get"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.server.ToolAllowed("web.fetch", "web_get"); got != tt.want {
+				t.Errorf("ToolAllowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateLSPConfigs(t *testing.T) {
+	enabled, disabled := true, false
+	cfg := &Config{
+		LSP: map[string]LSPConfig{
+			"go":         {Command: "gopls"},
+			"deprecated": {Command: "clangd", Disabled: true},
+			"enabled":    {Command: "tsls", Disabled: true, Enabled: &enabled},
+			"disabled":   {Command: "pyright", Enabled: &disabled},
+			"no command": {},
+		},
+		Workspaces: map[string]WorkspaceRoot{
+			"web": {LSP: map[string]LSPConfig{"ts": {Command: "tsls", Enabled: &disabled}}},
+		},
+	}
+
+	validateLSPConfigs(cfg)
+
+	want := map[string]bool{"go": true, "deprecated": false, "enabled": true, "disabled": false, "no command": false}
+	for language, wantEnabled := range want {
+		lspConfig := cfg.LSP[language]
+		if lspConfig.IsEnabled() != wantEnabled || lspConfig.Disabled == wantEnabled || lspConfig.Enabled == nil {
+			t.Errorf("%s: Enabled = %v, Disabled = %v; want enabled %v", language, lspConfig.Enabled, lspConfig.Disabled, wantEnabled)
+		}
+	}
+	if ts := cfg.Workspaces["web"].LSP["ts"]; !ts.Disabled {
+		t.Errorf("workspace LSP with enabled false was not disabled")
+	}
+}
+
+func TestValidateTracing(t *testing.T) {
+
+	cfg := &Config{Tracing: TracingConfig{Enabled: true, SlowTurnThreshold: "45s"}}
+	if err := validateTracing(cfg); err != nil {
+		t.Fatalf("validateTracing() error = %v", err)
+	}
+	if cfg.Tracing.Exporter != TracingExporterMemory || cfg.Tracing.Endpoint != defaultTracingEndpoint || cfg.Tracing.History != defaultTracingHistory {
+		t.Errorf("tracing defaults = %+v", cfg.Tracing)
+	}
+	if got := cfg.Tracing.SlowThreshold(); got != 45*time.Second {
+		t.Errorf("SlowThreshold() = %v, want 45s", got)
+	}
+
+	for _, invalid := range []TracingConfig{
+		{Exporter: "jaeger"},
+		{Exporter: TracingExporterOTLP, Endpoint: "localhost:4318"},
+		{SlowTurnThreshold: "soon"},
+		{SlowTurnThreshold: "-1s"},
+	} {
+		cfg = &Config{Tracing: invalid}
+		if err := validateTracing(cfg); err == nil {
+			t.Errorf("validateTracing(%+v) error = nil, want an error", invalid)
+		}
+	}
+}
+
+func TestValidateLogging(t *testing.T) {
+
+	cfg := &Config{Logging: LoggingConfig{ThrottleWindow: "30s", ThrottleExempt: []string{"mcp"}}}
+	if err := validateLogging(cfg); err != nil {
+		t.Fatalf("validateLogging() error = %v", err)
+	}
+	opts := cfg.Logging.ThrottleOptions()
+	if opts.Window != 30*time.Second || !slices.Contains(opts.Exempt, "mcp") || !slices.Contains(opts.Exempt, logging.ComponentPanic) {
+		t.Errorf("ThrottleOptions() = %+v", opts)
+	}
+	if got := (LoggingConfig{}).ThrottleOptions().Window; got != logging.DefaultThrottleWindow {
+		t.Errorf("default throttle window = %v, want %v", got, logging.DefaultThrottleWindow)
+	}
+
+	for _, invalid := range []string{"soon", "-1s"} {
+		cfg = &Config{Logging: LoggingConfig{ThrottleWindow: invalid}}
+		if err := validateLogging(cfg); err == nil {
+			t.Errorf("validateLogging(%q) error = nil, want an error", invalid)
+		}
+	}
+}
+
+func TestValidateAgentSystemPromptPath(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "prompt.md"), []byte("Be brief."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	testCfg := NewTestConfig(WithWorkingDir(dir))
+	defer current.Store(nil)
+
+	for path, wantErr := range map[string]bool{
+		"prompt.md":                     false,
+		filepath.Join(dir, "prompt.md"): false,
+		"missing.md":                    true,
+		".":                             true,
+	} {
+		agent := testCfg.Agents[AgentCaronex]
+		agent.SystemPromptPath = path
+		err := validateAgent(testCfg, AgentCaronex, agent)
+		if (err != nil) != wantErr {
+			t.Errorf("validateAgent() with system prompt file %q error = %v, want error %v", path, err, wantErr)
+		}
+	}
+}
+
+func TestValidateAgentReviewFlow(t *testing.T) {
+	testCfg := NewTestConfig(WithWorkingDir(t.TempDir()))
+	defer current.Store(nil)
+
+	for name, tt := range map[string]struct {
+		flow    ReviewFlow
+		wantErr bool
+	}{
+		"disabled":         {flow: ReviewFlow{Reviewer: "missing"}},
+		"defaults":         {flow: ReviewFlow{Enabled: true}},
+		"known reviewer":   {flow: ReviewFlow{Enabled: true, Reviewer: AgentCaronex, MaxRevisions: 3}},
+		"unknown reviewer": {flow: ReviewFlow{Enabled: true, Reviewer: "missing"}, wantErr: true},
+		"too many rounds":  {flow: ReviewFlow{Enabled: true, MaxRevisions: 6}, wantErr: true},
+		"negative budget":  {flow: ReviewFlow{Enabled: true, PassTokenBudget: -1}, wantErr: true},
+	} {
+		agent := testCfg.Agents[AgentCaronex]
+		agent.ReviewFlow = &tt.flow
+		err := validateAgent(testCfg, AgentCaronex, agent)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("validateAgent() with %s review flow error = %v, want error %v", name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateAgentAutoMode(t *testing.T) {
+	testCfg := NewTestConfig(WithWorkingDir(t.TempDir()))
+	defer current.Store(nil)
+
+	for name, tt := range map[string]struct {
+		auto    AutoMode
+		wantErr bool
+	}{
+		"defaults":         {},
+		"limits":           {auto: AutoMode{MaxSteps: 20, MaxDuration: "30m", TokenBudget: 50000, TestCommand: "go test ./..."}},
+		"too many steps":   {auto: AutoMode{MaxSteps: 101}, wantErr: true},
+		"invalid duration": {auto: AutoMode{MaxDuration: "soon"}, wantErr: true},
+		"negative budget":  {auto: AutoMode{TokenBudget: -1}, wantErr: true},
+	} {
+		agent := testCfg.Agents[AgentCaronex]
+		agent.AutoMode = &tt.auto
+		err := validateAgent(testCfg, AgentCaronex, agent)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("validateAgent() with %s auto mode error = %v, want error %v", name, err, tt.wantErr)
+		}
+	}
+
+	limits := AutoMode{MaxDuration: "-1m"}
+	if limits.Steps() != DefaultAutoMaxSteps || limits.Duration() != DefaultAutoMaxDuration || limits.Tokens() != DefaultAutoTokenBudget {
+		t.Errorf("limits = %d, %s, %d; want the defaults", limits.Steps(), limits.Duration(), limits.Tokens())
+	}
+}
+
+func TestValidateKnowledgeRetention(t *testing.T) {
+
+	for retention, want := range map[string]string{
+		KnowledgeRetentionSession:    KnowledgeRetentionSession,
+		KnowledgeRetentionPersistent: KnowledgeRetentionPersistent,
+		"forever":                    KnowledgeRetentionSession,
+	} {
+		cfg := &Config{Caronex: CaronexConfig{Learning: LearningConfig{KnowledgeRetention: retention}}}
+		if err := validateCaronexConfig(cfg); err != nil {
+			t.Fatalf("validateCaronexConfig() error = %v", err)
+		}
+		if got := cfg.Caronex.Learning.KnowledgeRetention; got != want {
+			t.Errorf("knowledge retention %q = %q, want %q", retention, got, want)
+		}
+	}
+}
+
+func TestValidateWarnings(t *testing.T) {
+	cfg := &Config{Caronex: CaronexConfig{Learning: LearningConfig{KnowledgeRetention: "forever"}}}
+	if err := validateCaronexConfig(cfg); err != nil {
+		t.Fatalf("validateCaronexConfig() error = %v", err)
+	}
+	want := []string{"invalid knowledge retention, setting to default (retention=forever)"}
+	if !slices.Equal(cfg.Warnings, want) {
+		t.Errorf("Warnings = %q, want %q", cfg.Warnings, want)
+	}
+}
+
+func TestValidateSafetyCheckTimeout(t *testing.T) {
+
+	for timeout, want := range map[string]time.Duration{
+		"":     DefaultSafetyCheckTimeout,
+		"5m":   5 * time.Minute,
+		"soon": DefaultSafetyCheckTimeout,
+		"-30s": DefaultSafetyCheckTimeout,
+	} {
+		cfg := &Config{Caronex: CaronexConfig{Evolution: EvolutionConfig{SafetyCheckTimeout: timeout}}}
+		if err := validateCaronexConfig(cfg); err != nil {
+			t.Fatalf("validateCaronexConfig() error = %v", err)
+		}
+		if got := cfg.Caronex.Evolution.SafetyTimeout(); got != want {
+			t.Errorf("SafetyTimeout() of %q = %v, want %v", timeout, got, want)
+		}
+	}
+}
+
+func TestOllamaKeepAlive(t *testing.T) {
+	for keepAlive, valid := range map[string]bool{
+		"":     true,
+		"30m":  true,
+		"-1":   true,
+		"3600": true,
+		"soon": false,
+	} {
+		err := OllamaConfig{KeepAlive: keepAlive}.validate()
+		if (err == nil) != valid {
+			t.Errorf("validate() of %q error = %v, want valid %v", keepAlive, err, valid)
+		}
+	}
+}
+
+func TestValidateEmbeddings(t *testing.T) {
+	cfg := &Config{
+		Providers: map[models.ModelProvider]Provider{models.ProviderGemini: {APIKey: "key"}},
+		Spaces: map[string]SpaceConfig{
+			"docs":  {Embeddings: &EmbeddingsConfig{Provider: EmbeddingProviderGemini}},
+			"notes": {},
+		},
+	}
+	if err := validateEmbeddings(cfg); err != nil {
+		t.Fatalf("validateEmbeddings() error = %v", err)
+	}
+	if got := cfg.EmbeddingsFor("notes"); got.Provider != EmbeddingProviderLocal || got.Dimensions != 256 {
+		t.Errorf("EmbeddingsFor(notes) = %+v, want the local provider by default", got)
+	}
+	if got := cfg.EmbeddingsFor("docs"); got.Model != "text-embedding-004" || got.Dimensions != 768 || got.BatchSize != 100 {
+		t.Errorf("EmbeddingsFor(docs) = %+v, want the defaults of gemini", got)
+	}
+	if len(cfg.Warnings) != 0 {
+		t.Errorf("Warnings = %q, want none", cfg.Warnings)
+	}
+
+	for name, embeddings := range map[string]EmbeddingsConfig{
+		"unknown provider":          {Provider: "cohere"},
+		"model without dimensions":  {Provider: EmbeddingProviderOpenAI, Model: "text-embedding-ada-002"},
+		"batch over the limit":      {Provider: EmbeddingProviderGemini, BatchSize: 250},
+		"negative requests per min": {Provider: EmbeddingProviderOpenAI, RequestsPerMinute: -1},
+	} {
+		cfg := &Config{Spaces: map[string]SpaceConfig{"docs": {Embeddings: &embeddings}}}
+		if err := validateEmbeddings(cfg); err == nil {
+			t.Errorf("validateEmbeddings() with %s succeeded, want an error", name)
+		}
+	}
+
+	cfg = &Config{Embeddings: EmbeddingsConfig{Provider: EmbeddingProviderOpenAI}}
+	if err := validateEmbeddings(cfg); err != nil {
+		t.Fatalf("validateEmbeddings() error = %v", err)
+	}
+	if len(cfg.Warnings) != 1 {
+		t.Errorf("Warnings = %q, want a warning about the missing OpenAI key", cfg.Warnings)
+	}
+}
+
+func TestAutoCompactConfig(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "test-key-for-config")
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	defer func() {
+		current.Store(nil)
+		viper.Reset()
+	}()
+
+	for setting, want := range map[string]AutoCompactConfig{
+		// Older configs set a plain boolean
+		`true`:                                 {Enabled: true, ThresholdRatio: DefaultAutoCompactThresholdRatio, MinMessages: DefaultAutoCompactMinMessages},
+		`false`:                                {Enabled: false, ThresholdRatio: DefaultAutoCompactThresholdRatio, MinMessages: DefaultAutoCompactMinMessages},
+		`{"thresholdRatio": 0.6}`:              {Enabled: true, ThresholdRatio: 0.6, MinMessages: DefaultAutoCompactMinMessages},
+		`{"enabled": false, "minMessages": 5}`: {Enabled: false, ThresholdRatio: DefaultAutoCompactThresholdRatio, MinMessages: 5},
+		`{"thresholdRatio": 1.5}`:              {Enabled: true, ThresholdRatio: DefaultAutoCompactThresholdRatio, MinMessages: DefaultAutoCompactMinMessages},
+	} {
+		current.Store(nil)
+		viper.Reset()
+		workingDir := t.TempDir()
+		document := `{"autoCompact": ` + setting + `}`
+		if err := os.WriteFile(filepath.Join(workingDir, ".intelligence-interface.json"), []byte(document), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := Load(workingDir, false)
+		if err != nil {
+			t.Fatalf("Load() of %s error = %v", setting, err)
+		}
+		if loaded.AutoCompact != want {
+			t.Errorf("Load() of %s AutoCompact = %+v, want %+v", setting, loaded.AutoCompact, want)
+		}
+
+		var decoded Config
+		if err := json.Unmarshal([]byte(document), &decoded); err != nil {
+			t.Fatalf("json.Unmarshal() of %s error = %v", setting, err)
+		}
+		if decoded.AutoCompact.withDefaults() != want {
+			t.Errorf("json.Unmarshal() of %s AutoCompact = %+v, want %+v", setting, decoded.AutoCompact, want)
+		}
+	}
+}
+
+func TestAutoCompactShouldCompact(t *testing.T) {
+	autoCompact := AutoCompactConfig{Enabled: true}.withDefaults()
+	if !autoCompact.ShouldCompact(80, 100, 20) {
+		t.Error("a session at the threshold is not compacted")
+	}
+	if autoCompact.ShouldCompact(79, 100, 20) {
+		t.Error("a session below the threshold is compacted")
+	}
+	if autoCompact.ShouldCompact(95, 100, 19) {
+		t.Error("a session with fewer messages than the minimum is compacted")
+	}
+}
+
+func TestBannerConfigRoundTrip(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "test-key-for-config")
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	viper.Reset()
+	defer func() {
+		current.Store(nil)
+		viper.Reset()
+	}()
+
+	workingDir := t.TempDir()
+	path := filepath.Join(workingDir, ".intelligence-interface.json")
+	document := `{"tui": {"banner": {"agent": false, "providers": true}}}`
+	if err := os.WriteFile(path, []byte(document), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(workingDir, false); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	// Writing another setting rewrites the whole config file
+	if err := UpdateTheme("tokyonight"); err != nil {
+		t.Fatalf("UpdateTheme() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written Config
+	if err := json.Unmarshal(data, &written); err != nil {
+		t.Fatalf("config file %s: %v", data, err)
+	}
+	banner := written.TUI.Banner
+	if banner.Agent == nil || *banner.Agent {
+		t.Errorf("banner agent = %v after the rewrite of %s, want an explicit false", banner.Agent, data)
+	}
+	if banner.Providers == nil || !*banner.Providers {
+		t.Errorf("banner providers = %v after the rewrite of %s, want an explicit true", banner.Providers, data)
+	}
+	if banner.Workspace != nil {
+		t.Errorf("banner workspace = %v after the rewrite, want it left unset", *banner.Workspace)
+	}
+
+	current.Store(nil)
+	viper.Reset()
+	loaded, err := Load(workingDir, false)
+	if err != nil {
+		t.Fatalf("Load() of the rewritten file error = %v", err)
+	}
+	banner = loaded.TUI.Banner
+	if !banner.IsEnabled() || banner.Shows(banner.Agent) || !banner.Shows(banner.Providers) || !banner.Shows(banner.Workspace) {
+		t.Errorf("reloaded banner = %+v, want it shown without the agent", banner)
+	}
+}
+
+func TestShellGuardValidation(t *testing.T) {
+	valid := ShellGuardConfig{
+		Rules: []ShellRule{
+			{Name: "force-push", Decision: "block", Command: "git", Args: []string{"push", "--force*"}},
+			{Name: "prod", Decision: "warn", Pattern: `\bprod\b`},
+		},
+		Allow: []string{`npm (ci|install)`},
+	}
+	if err := valid.validate(); err != nil {
+		t.Fatalf("validate() error = %v", err)
+	}
+
+	for name, guard := range map[string]ShellGuardConfig{
+		"no name":            {Rules: []ShellRule{{Decision: "warn", Pattern: "x"}}},
+		"duplicate name":     {Rules: []ShellRule{{Name: "a", Decision: "warn", Pattern: "x"}, {Name: "a", Decision: "block", Pattern: "y"}}},
+		"unknown decision":   {Rules: []ShellRule{{Name: "a", Decision: "allow", Pattern: "x"}}},
+		"nothing to match":   {Rules: []ShellRule{{Name: "a", Decision: "warn"}}},
+		"args alone":         {Rules: []ShellRule{{Name: "a", Decision: "warn", Args: []string{"-f"}}}},
+		"invalid pattern":    {Rules: []ShellRule{{Name: "a", Decision: "warn", Pattern: "("}}},
+		"invalid glob":       {Rules: []ShellRule{{Name: "a", Decision: "warn", Command: "["}}},
+		"invalid allow item": {Allow: []string{"["}},
+	} {
+		if err := guard.validate(); err == nil {
+			t.Errorf("validate() with %s succeeded, want an error", name)
+		}
+	}
+}
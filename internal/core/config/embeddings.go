@@ -0,0 +1,180 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+)
+
+// The providers computing the embeddings of the knowledge base
+const (
+	EmbeddingProviderOpenAI = "openai"
+	EmbeddingProviderAzure  = "azure"
+	EmbeddingProviderGemini = "gemini"
+	// EmbeddingProviderLocal hashes the words of the texts on this machine,
+	// needing neither a key nor a network
+	EmbeddingProviderLocal = "local"
+)
+
+// EmbeddingProviders lists the providers the embeddings can be computed with
+var EmbeddingProviders = []string{EmbeddingProviderOpenAI, EmbeddingProviderAzure, EmbeddingProviderGemini, EmbeddingProviderLocal}
+
+// embeddingDefaults are the settings of a provider left unset in the config
+type embeddingDefaults struct {
+	model             string
+	dimensions        int
+	batchSize         int
+	maxBatchSize      int
+	requestsPerMinute int
+}
+
+var defaultEmbeddings = map[string]embeddingDefaults{
+	EmbeddingProviderOpenAI: {model: "text-embedding-3-small", dimensions: 1536, batchSize: 256, maxBatchSize: 2048, requestsPerMinute: 3000},
+	EmbeddingProviderAzure:  {model: "text-embedding-3-small", dimensions: 1536, batchSize: 16, maxBatchSize: 2048, requestsPerMinute: 720},
+	EmbeddingProviderGemini: {model: "text-embedding-004", dimensions: 768, batchSize: 100, maxBatchSize: 100, requestsPerMinute: 1500},
+	EmbeddingProviderLocal:  {model: "hash-v1", dimensions: 256, batchSize: 512, maxBatchSize: 4096},
+}
+
+// EmbeddingsConfig sets how the texts of the knowledge base are turned into
+// vectors, globally or for a space. The local provider is used when none is
+// set, so the knowledge base works without any key.
+type EmbeddingsConfig struct {
+	// Provider is openai, azure, gemini or local
+	Provider string `json:"provider,omitempty"`
+	// Model is the embedding model, the deployment name for azure
+	Model string `json:"model,omitempty"`
+	// Dimensions is the length of the vectors, the default of the model when
+	// unset. Changing it requires reindexing the knowledge base.
+	Dimensions int `json:"dimensions,omitempty"`
+	// BatchSize is the number of texts sent in one request
+	BatchSize int `json:"batchSize,omitempty"`
+	// RequestsPerMinute paces the requests sent to the provider, shared by
+	// every space embedding with it. 0 uses the default of the provider.
+	RequestsPerMinute int `json:"requestsPerMinute,omitempty"`
+}
+
+// withDefaults returns the settings with the defaults of the provider filled in
+func (e EmbeddingsConfig) withDefaults() EmbeddingsConfig {
+	if e.Provider == "" {
+		e.Provider = EmbeddingProviderLocal
+	}
+	defaults, ok := defaultEmbeddings[e.Provider]
+	if !ok {
+		return e
+	}
+	if e.Model == "" {
+		e.Model = defaults.model
+		// The default dimensions belong to the default model
+		if e.Dimensions == 0 {
+			e.Dimensions = defaults.dimensions
+		}
+	}
+	if e.BatchSize == 0 {
+		e.BatchSize = defaults.batchSize
+	}
+	if e.RequestsPerMinute == 0 {
+		e.RequestsPerMinute = defaults.requestsPerMinute
+	}
+	return e
+}
+
+// validate checks settings with the defaults filled in
+func (e EmbeddingsConfig) validate() error {
+	defaults, ok := defaultEmbeddings[e.Provider]
+	if !ok {
+		return fmt.Errorf("unknown provider %q, must be one of %v", e.Provider, EmbeddingProviders)
+	}
+	if e.Dimensions < 0 {
+		return fmt.Errorf("dimensions must be positive")
+	}
+	// Only the dimensions of the default model are known
+	if e.Dimensions == 0 {
+		return fmt.Errorf("dimensions are required with the model %s", e.Model)
+	}
+	if e.BatchSize < 0 || e.BatchSize > defaults.maxBatchSize {
+		return fmt.Errorf("batchSize must be between 1 and %d for %s", defaults.maxBatchSize, e.Provider)
+	}
+	if e.RequestsPerMinute < 0 {
+		return fmt.Errorf("requestsPerMinute must be positive")
+	}
+	return nil
+}
+
+// ModelProvider returns the provider whose credentials the embeddings are
+// requested with, empty for the local provider
+func (e EmbeddingsConfig) ModelProvider() models.ModelProvider {
+	switch e.Provider {
+	case EmbeddingProviderOpenAI:
+		return models.ProviderOpenAI
+	case EmbeddingProviderAzure:
+		return models.ProviderAzure
+	case EmbeddingProviderGemini:
+		return models.ProviderGemini
+	}
+	return ""
+}
+
+// EmbeddingsFor returns the embedding settings of a space: its own when it
+// sets them, the global ones otherwise
+func (c *Config) EmbeddingsFor(spaceID string) EmbeddingsConfig {
+	if space, ok := c.Spaces[spaceID]; ok && space.Embeddings != nil {
+		return *space.Embeddings
+	}
+	return c.Embeddings
+}
+
+// validateEmbeddings fills in the defaults of the global and space embedding
+// settings and checks them, warning about a provider without credentials
+func validateEmbeddings(cfg *Config) error {
+	cfg.Embeddings = cfg.Embeddings.withDefaults()
+	if err := cfg.Embeddings.validate(); err != nil {
+		return fmt.Errorf("embeddings: %w", err)
+	}
+	warnEmbeddingCredentials(cfg, "embeddings", cfg.Embeddings)
+
+	for _, id := range sortedSpaceIDs(cfg.Spaces) {
+		space := cfg.Spaces[id]
+		if space.Embeddings == nil {
+			continue
+		}
+		embeddings := space.Embeddings.withDefaults()
+		if err := embeddings.validate(); err != nil {
+			return fmt.Errorf("spaces.%s.embeddings: %w", id, err)
+		}
+		warnEmbeddingCredentials(cfg, fmt.Sprintf("spaces.%s.embeddings", id), embeddings)
+		space.Embeddings = &embeddings
+		cfg.Spaces[id] = space
+	}
+	return nil
+}
+
+// warnEmbeddingCredentials warns when the embeddings are requested from a
+// provider that is disabled or has no key, as indexing would then fail
+func warnEmbeddingCredentials(cfg *Config, key string, embeddings EmbeddingsConfig) {
+	provider := embeddings.ModelProvider()
+	if provider == "" {
+		return
+	}
+	providerCfg, ok := cfg.Providers[provider]
+	available := ok && !providerCfg.Disabled
+	// Azure authenticates with the default credentials of the machine
+	// without a key, but always needs the endpoint of the resource
+	if provider == models.ProviderAzure {
+		available = os.Getenv("AZURE_OPENAI_ENDPOINT") != ""
+	}
+	if available {
+		return
+	}
+	cfg.warn("embedding provider has no credentials, indexing the knowledge base will fail", "key", key, "provider", provider)
+}
+
+func sortedSpaceIDs(spaces map[string]SpaceConfig) []string {
+	ids := make([]string, 0, len(spaces))
+	for id := range spaces {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
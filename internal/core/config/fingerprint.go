@@ -0,0 +1,144 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// secretValue replaces the values of secrets in a snapshot, so setting or
+// removing them is noticed but their values never are
+const secretValue = "<secret>"
+
+// Snapshot is the effective configuration flattened into its values keyed by
+// their dotted path, such as "agents.caronex.model", secrets excluded
+type Snapshot map[string]string
+
+// ConfigChange is a path whose value differs between two snapshots, Old or
+// New being empty when the path was added or removed
+type ConfigChange struct {
+	Path string `json:"path"`
+	Old  string `json:"old,omitempty"`
+	New  string `json:"new,omitempty"`
+}
+
+// TakeSnapshot flattens the effective configuration c
+func TakeSnapshot(c *Config) (Snapshot, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the configuration: %w", err)
+	}
+	return snapshotJSON(data)
+}
+
+// CurrentFingerprint returns the fingerprint of the loaded configuration, ""
+// when it is not loaded
+func CurrentFingerprint() string {
+	cfg := Get()
+	if cfg == nil {
+		return ""
+	}
+	snapshot, err := TakeSnapshot(cfg)
+	if err != nil {
+		return ""
+	}
+	return snapshot.Fingerprint()
+}
+
+func snapshotJSON(data []byte) (Snapshot, error) {
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode the configuration: %w", err)
+	}
+	snapshot := make(Snapshot)
+	snapshot.flatten(nil, doc)
+	return snapshot, nil
+}
+
+func (s Snapshot) flatten(path []string, value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			s.flatten(append(slices.Clip(path), key), child)
+		}
+	case []any:
+		for i, child := range v {
+			s.flatten(append(slices.Clip(path), strconv.Itoa(i)), child)
+		}
+	case nil:
+	default:
+		s[strings.Join(path, ".")] = redact(path, leafString(v))
+	}
+}
+
+func leafString(value any) string {
+	if str, ok := value.(string); ok {
+		return str
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// redact hides the value at path when it may be a secret: API keys, MCP
+// server headers and environment, and space environments
+func redact(path []string, value string) string {
+	if value == "" || len(path) < 2 {
+		return value
+	}
+	key, parent := path[len(path)-1], path[len(path)-2]
+	switch {
+	case strings.EqualFold(key, "apiKey"):
+		return secretValue
+	case parent == "headers" || parent == "environment":
+		return secretValue
+	case parent == "env":
+		// Environment entries are NAME=value, only the name is kept
+		name, _, _ := strings.Cut(value, "=")
+		return name + "=" + secretValue
+	}
+	return value
+}
+
+// Fingerprint returns a stable hash of the snapshot, independent of the order
+// of its paths
+func (s Snapshot) Fingerprint() string {
+	hash := sha256.New()
+	for _, path := range s.paths() {
+		fmt.Fprintf(hash, "%q=%q\n", path, s[path])
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// Diff returns the paths whose value differs in current, in path order
+func (s Snapshot) Diff(current Snapshot) []ConfigChange {
+	all := make(Snapshot, len(s)+len(current))
+	for path := range s {
+		all[path] = ""
+	}
+	for path := range current {
+		all[path] = ""
+	}
+	var changes []ConfigChange
+	for _, path := range all.paths() {
+		old, hadOld := s[path]
+		value, hasNew := current[path]
+		if hadOld == hasNew && old == value {
+			continue
+		}
+		changes = append(changes, ConfigChange{Path: path, Old: old, New: value})
+	}
+	return changes
+}
+
+func (s Snapshot) paths() []string {
+	paths := make([]string, 0, len(s))
+	for path := range s {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	return paths
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSnapshotFingerprint(t *testing.T) {
+	snapshot := func(doc string) Snapshot {
+		t.Helper()
+		s, err := snapshotJSON([]byte(doc))
+		if err != nil {
+			t.Fatalf("snapshotJSON() error = %v", err)
+		}
+		return s
+	}
+	base := snapshot(`{
+		"agents": {"coder": {"model": "gpt-4.1", "maxTokens": 4096}, "task": {"model": "o3"}},
+		"providers": {"openai": {"apiKey": "sk-1"}},
+		"contextPaths": ["a.md", "b.md"]
+	}`)
+
+	for name, tt := range map[string]struct {
+		doc  string
+		same bool
+	}{
+		"reordered keys": {
+			doc: `{
+				"contextPaths": ["a.md", "b.md"],
+				"providers": {"openai": {"apiKey": "sk-1"}},
+				"agents": {"task": {"model": "o3"}, "coder": {"maxTokens": 4096, "model": "gpt-4.1"}}
+			}`,
+			same: true,
+		},
+		"other secret": {
+			doc: `{
+				"agents": {"coder": {"model": "gpt-4.1", "maxTokens": 4096}, "task": {"model": "o3"}},
+				"providers": {"openai": {"apiKey": "sk-2"}},
+				"contextPaths": ["a.md", "b.md"]
+			}`,
+			same: true,
+		},
+		"changed value": {
+			doc: `{
+				"agents": {"coder": {"model": "gpt-4.1", "maxTokens": 8192}, "task": {"model": "o3"}},
+				"providers": {"openai": {"apiKey": "sk-1"}},
+				"contextPaths": ["a.md", "b.md"]
+			}`,
+		},
+		"reordered list": {
+			doc: `{
+				"agents": {"coder": {"model": "gpt-4.1", "maxTokens": 4096}, "task": {"model": "o3"}},
+				"providers": {"openai": {"apiKey": "sk-1"}},
+				"contextPaths": ["b.md", "a.md"]
+			}`,
+		},
+	} {
+		got := snapshot(tt.doc).Fingerprint()
+		if same := got == base.Fingerprint(); same != tt.same {
+			t.Errorf("%s: fingerprint %s, base %s, want same %v", name, got, base.Fingerprint(), tt.same)
+		}
+	}
+}
+
+func TestSnapshotRedactsSecrets(t *testing.T) {
+	s, err := snapshotJSON([]byte(`{
+		"providers": {"openai": {"apiKey": "sk-1"}},
+		"mcpServers": {"db": {"env": ["TOKEN=abc"], "headers": {"Authorization": "Bearer abc"}}},
+		"spaces": {"dev": {"environment": {"PASSWORD": "hunter2"}}}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Snapshot{
+		"providers.openai.apiKey":             secretValue,
+		"mcpServers.db.env.0":                 "TOKEN=" + secretValue,
+		"mcpServers.db.headers.Authorization": secretValue,
+		"spaces.dev.environment.PASSWORD":     secretValue,
+	}
+	if !reflect.DeepEqual(s, want) {
+		t.Errorf("snapshot = %v, want %v", s, want)
+	}
+}
+
+func TestSnapshotDiff(t *testing.T) {
+	old := Snapshot{"a": "1", "b": "2", "c": "3"}
+	current := Snapshot{"a": "1", "b": "20", "d": "4"}
+	want := []ConfigChange{
+		{Path: "b", Old: "2", New: "20"},
+		{Path: "c", Old: "3"},
+		{Path: "d", New: "4"},
+	}
+	if got := old.Diff(current); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %+v, want %+v", got, want)
+	}
+	if got := old.Diff(old); got != nil {
+		t.Errorf("Diff() of the same snapshot = %+v, want none", got)
+	}
+}
+
+func TestTakeSnapshot(t *testing.T) {
+	testCfg := NewTestConfig(WithWorkingDir(t.TempDir()))
+	defer current.Store(nil)
+
+	before := CurrentFingerprint()
+	if before == "" {
+		t.Fatal("CurrentFingerprint() is empty with a loaded configuration")
+	}
+	agent := testCfg.Agents[AgentCaronex]
+	agent.MaxTokens++
+	testCfg.Agents[AgentCaronex] = agent
+	if CurrentFingerprint() == before {
+		t.Error("CurrentFingerprint() did not change with the configuration")
+	}
+}
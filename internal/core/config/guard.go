@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+)
+
+// ShellGuardConfig extends the checks the commands of the bash tool go
+// through before they run
+type ShellGuardConfig struct {
+	// Rules are checked along with the built-in rules
+	Rules []ShellRule `json:"rules,omitempty"`
+	// Allow are regular expressions matching a whole command line, or the
+	// simple command or pipeline a rule applies to, which no rule applies to
+	// then. They are anchored at both ends.
+	Allow []string `json:"allow,omitempty"`
+}
+
+// ShellRule is a custom rule of the shell guard, applying to the commands
+// matching all of its patterns
+type ShellRule struct {
+	Name string `json:"name"`
+	// Decision is warn, to ask for approval even when approvals are off, or
+	// block, to refuse the command
+	Decision string `json:"decision"`
+	// Reason is told to the user and the agent
+	Reason string `json:"reason,omitempty"`
+	// Pattern is a regular expression searched in the command line, or in
+	// the simple command when Command is set
+	Pattern string `json:"pattern,omitempty"`
+	// Command is a glob pattern matching the program of a simple command,
+	// found in pipes, subshells, substitutions and behind wrappers such as
+	// sudo, env or xargs
+	Command string `json:"command,omitempty"`
+	// Args are glob patterns each matching an argument of the command
+	Args []string `json:"args,omitempty"`
+}
+
+// validate checks the rules and allowlist of the shell guard
+func (g ShellGuardConfig) validate() error {
+	names := make(map[string]bool, len(g.Rules))
+	for i, rule := range g.Rules {
+		if rule.Name == "" {
+			return fmt.Errorf("rule %d has no name", i+1)
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("rule %s is defined twice", rule.Name)
+		}
+		names[rule.Name] = true
+		if rule.Decision != "warn" && rule.Decision != "block" {
+			return fmt.Errorf("rule %s: decision must be warn or block, got %q", rule.Name, rule.Decision)
+		}
+		if rule.Pattern == "" && rule.Command == "" {
+			return fmt.Errorf("rule %s needs a pattern or a command", rule.Name)
+		}
+		if len(rule.Args) > 0 && rule.Command == "" {
+			return fmt.Errorf("rule %s: args need a command", rule.Name)
+		}
+		if rule.Pattern != "" {
+			if _, err := regexp.Compile(rule.Pattern); err != nil {
+				return fmt.Errorf("rule %s: invalid pattern: %w", rule.Name, err)
+			}
+		}
+		for _, glob := range append([]string{rule.Command}, rule.Args...) {
+			if _, err := path.Match(glob, ""); err != nil {
+				return fmt.Errorf("rule %s: invalid glob %q: %w", rule.Name, glob, err)
+			}
+		}
+	}
+	for _, allow := range g.Allow {
+		if _, err := regexp.Compile(allow); err != nil {
+			return fmt.Errorf("invalid allow pattern %q: %w", allow, err)
+		}
+	}
+	return nil
+}
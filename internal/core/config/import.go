@@ -0,0 +1,512 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+)
+
+// ImportSource is another tool whose configuration can be imported
+type ImportSource string
+
+const (
+	// ImportOpenCode reads the .opencode.json files of OpenCode
+	ImportOpenCode ImportSource = "opencode"
+	// ImportClaudeCode reads the MCP servers and settings of Claude Code
+	ImportClaudeCode ImportSource = "claude-code"
+)
+
+// ImportSources returns the tools whose configuration can be imported
+func ImportSources() []ImportSource {
+	return []ImportSource{ImportOpenCode, ImportClaudeCode}
+}
+
+// ImportSection is a part of the configuration an import fills, the unit in
+// which the conflicts with the existing configuration are resolved
+type ImportSection string
+
+const (
+	ImportProviders   ImportSection = "providers"
+	ImportMCPServers  ImportSection = "mcpServers"
+	ImportLSP         ImportSection = "lsp"
+	ImportPreferences ImportSection = "preferences"
+)
+
+// ImportNote is a setting of the imported files and what became of it
+type ImportNote struct {
+	Section ImportSection
+	// Name is the path of the setting in the imported files
+	Name   string
+	Reason string
+}
+
+// ImportReport tells what an import took from the files of the other tool
+type ImportReport struct {
+	Imported []ImportNote
+	Skipped  []ImportNote
+	// Manual are the settings which have no direct counterpart and need to
+	// be set by hand
+	Manual []ImportNote
+}
+
+// Import is the configuration read from the config files of another tool,
+// mapped onto the configuration
+type Import struct {
+	Source ImportSource
+	// Files are the config files read, in the order they were merged
+	Files      []string
+	Providers  map[models.ModelProvider]Provider
+	MCPServers map[string]MCPServer
+	LSP        map[string]LSPConfig
+	Theme      string
+	Shell      ShellConfig
+	Report     ImportReport
+}
+
+// ErrNothingToImport is returned when none of the config files of the tool
+// exist
+var ErrNothingToImport = errors.New("no config file found")
+
+// importPaths returns the config files of source an import reads, whether they
+// exist or not, the later ones overriding the earlier ones
+func importPaths(source ImportSource, workingDir string) []string {
+	home, _ := os.UserHomeDir()
+	switch source {
+	case ImportOpenCode:
+		paths := []string{filepath.Join(home, ".opencode.json")}
+		if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+			paths = append(paths, filepath.Join(xdg, "opencode", ".opencode.json"))
+		}
+		return append(paths,
+			filepath.Join(home, ".config", "opencode", ".opencode.json"),
+			filepath.Join(workingDir, ".opencode.json"),
+		)
+	case ImportClaudeCode:
+		return []string{
+			filepath.Join(home, ".claude", "settings.json"),
+			filepath.Join(home, ".claude.json"),
+			filepath.Join(workingDir, ".mcp.json"),
+		}
+	}
+	return nil
+}
+
+// DetectImports returns the tools whose config files exist, the ones an
+// import can be offered from
+func DetectImports(workingDir string) []ImportSource {
+	var sources []ImportSource
+	for _, source := range ImportSources() {
+		for _, path := range importPaths(source, workingDir) {
+			if _, err := os.Stat(path); err == nil {
+				sources = append(sources, source)
+				break
+			}
+		}
+	}
+	return sources
+}
+
+// HasConfigFile reports whether Load read a config file, which it does not on
+// the first run
+func HasConfigFile() bool {
+	return viper.ConfigFileUsed() != ""
+}
+
+// ReadImport reads the config files of source found for workingDir and maps
+// them onto the configuration. themes are the themes the imported theme is
+// kept from.
+func ReadImport(source ImportSource, workingDir string, themes []string) (*Import, error) {
+	imp := &Import{
+		Source:     source,
+		Providers:  make(map[models.ModelProvider]Provider),
+		MCPServers: make(map[string]MCPServer),
+		LSP:        make(map[string]LSPConfig),
+	}
+	var err error
+	switch source {
+	case ImportOpenCode:
+		err = imp.readOpenCode(workingDir, themes)
+	case ImportClaudeCode:
+		err = imp.readClaudeCode(workingDir)
+	default:
+		return nil, fmt.Errorf("unknown import source %q, want one of %v", source, ImportSources())
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(imp.Files) == 0 {
+		return nil, fmt.Errorf("%w for %s (looked for %s)", ErrNothingToImport, source,
+			strings.Join(importPaths(source, workingDir), ", "))
+	}
+	return imp, nil
+}
+
+// readImportFile decodes the JSON file at path into v, reporting whether it
+// exists
+func (i *Import) readImportFile(path string, v any) (bool, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	i.Files = append(i.Files, path)
+	return true, nil
+}
+
+func (i *Import) imported(section ImportSection, name string) {
+	i.Report.Imported = append(i.Report.Imported, ImportNote{Section: section, Name: name})
+}
+
+func (i *Import) skipped(section ImportSection, name, reason string) {
+	i.Report.Skipped = append(i.Report.Skipped, ImportNote{Section: section, Name: name, Reason: reason})
+}
+
+func (i *Import) manual(section ImportSection, name, reason string) {
+	i.Report.Manual = append(i.Report.Manual, ImportNote{Section: section, Name: name, Reason: reason})
+}
+
+// knownProvider reports whether provider is one the configuration supports
+func knownProvider(provider models.ModelProvider) bool {
+	_, ok := models.ProviderPopularity[provider]
+	return ok || provider.IsLocal()
+}
+
+// openCodeFile is the part of an OpenCode config file the import reads
+type openCodeFile struct {
+	Providers  map[string]openCodeProvider `json:"providers"`
+	MCPServers map[string]MCPServer        `json:"mcpServers"`
+	LSP        map[string]LSPConfig        `json:"lsp"`
+	TUI        struct {
+		Theme string `json:"theme"`
+	} `json:"tui"`
+	Shell        ShellConfig              `json:"shell"`
+	Agents       map[string]openCodeAgent `json:"agents"`
+	ContextPaths []string                 `json:"contextPaths"`
+	Data         struct {
+		Directory string `json:"directory"`
+	} `json:"data"`
+}
+
+// openCodeProvider is a provider of an OpenCode config file
+type openCodeProvider struct {
+	APIKey   string `json:"apiKey"`
+	Disabled bool   `json:"disabled"`
+}
+
+// openCodeAgent is an agent of an OpenCode config file
+type openCodeAgent struct {
+	Model string `json:"model"`
+}
+
+// readOpenCode reads the OpenCode config files, which have the shape of the
+// configuration: the local file is merged over the global ones
+func (i *Import) readOpenCode(workingDir string, themes []string) error {
+	var merged openCodeFile
+	merged.Providers = make(map[string]openCodeProvider)
+	merged.MCPServers = make(map[string]MCPServer)
+	merged.LSP = make(map[string]LSPConfig)
+	merged.Agents = make(map[string]openCodeAgent)
+	for _, path := range importPaths(ImportOpenCode, workingDir) {
+		var file openCodeFile
+		found, err := i.readImportFile(path, &file)
+		if err != nil {
+			return err
+		}
+		if !found {
+			continue
+		}
+		maps.Copy(merged.Providers, file.Providers)
+		maps.Copy(merged.MCPServers, file.MCPServers)
+		maps.Copy(merged.LSP, file.LSP)
+		maps.Copy(merged.Agents, file.Agents)
+		if file.TUI.Theme != "" {
+			merged.TUI = file.TUI
+		}
+		if file.Shell.Path != "" {
+			merged.Shell = file.Shell
+		}
+		if file.ContextPaths != nil {
+			merged.ContextPaths = file.ContextPaths
+		}
+		if file.Data.Directory != "" {
+			merged.Data = file.Data
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(merged.Providers)) {
+		provider := models.ModelProvider(name)
+		if !knownProvider(provider) {
+			i.skipped(ImportProviders, "providers."+name, "unsupported provider")
+			continue
+		}
+		p := merged.Providers[name]
+		i.Providers[provider] = Provider{APIKey: p.APIKey, Disabled: p.Disabled}
+		i.imported(ImportProviders, "providers."+name)
+	}
+	for _, name := range slices.Sorted(maps.Keys(merged.MCPServers)) {
+		server := merged.MCPServers[name]
+		if server.Type == "" {
+			server.Type = MCPStdio
+		}
+		if server.Type != MCPStdio && server.Type != MCPSse {
+			i.skipped(ImportMCPServers, "mcpServers."+name, fmt.Sprintf("unsupported server type %q", server.Type))
+			continue
+		}
+		i.MCPServers[name] = server
+		i.imported(ImportMCPServers, "mcpServers."+name)
+	}
+	for _, name := range slices.Sorted(maps.Keys(merged.LSP)) {
+		i.LSP[name] = merged.LSP[name]
+		i.imported(ImportLSP, "lsp."+name)
+	}
+
+	// The default theme of OpenCode is the one the default theme derives from
+	switch theme := merged.TUI.Theme; {
+	case theme == "":
+	case theme == "opencode":
+		i.Theme = "intelligence-interface"
+		i.imported(ImportPreferences, "tui.theme")
+	case slices.Contains(themes, theme):
+		i.Theme = theme
+		i.imported(ImportPreferences, "tui.theme")
+	default:
+		i.skipped(ImportPreferences, "tui.theme", fmt.Sprintf("unknown theme %q", theme))
+	}
+	if merged.Shell.Path != "" {
+		i.Shell = merged.Shell
+		i.imported(ImportPreferences, "shell")
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(merged.Agents)) {
+		i.manual(ImportPreferences, "agents."+name,
+			fmt.Sprintf("OpenCode agents have no counterpart, set the model %q of agents.caronex by hand if wanted", merged.Agents[name].Model))
+	}
+	if len(merged.ContextPaths) > 0 {
+		i.manual(ImportPreferences, "contextPaths", "merge the context paths into contextPaths by hand, the defaults already include those of OpenCode")
+	}
+	if merged.Data.Directory != "" {
+		i.skipped(ImportPreferences, "data.directory", "the sessions of OpenCode are not migrated")
+	}
+	return nil
+}
+
+// claudeMCPServer is an MCP server of Claude Code
+type claudeMCPServer struct {
+	Type    string            `json:"type"`
+	Command string            `json:"command"`
+	Args    []string          `json:"args"`
+	Env     map[string]string `json:"env"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+}
+
+// claudeState is the part of ~/.claude.json the import reads
+type claudeState struct {
+	MCPServers    map[string]claudeMCPServer `json:"mcpServers"`
+	PrimaryAPIKey string                     `json:"primaryApiKey"`
+	Theme         string                     `json:"theme"`
+	Projects      map[string]struct {
+		MCPServers map[string]claudeMCPServer `json:"mcpServers"`
+	} `json:"projects"`
+}
+
+// claudeSettings is the part of ~/.claude/settings.json the import reads
+type claudeSettings struct {
+	Env          map[string]string `json:"env"`
+	APIKeyHelper string            `json:"apiKeyHelper"`
+	Permissions  json.RawMessage   `json:"permissions"`
+	Hooks        json.RawMessage   `json:"hooks"`
+}
+
+// readClaudeCode reads the settings and the MCP servers of Claude Code. The
+// servers of the project scope (.mcp.json) override those of the user scope,
+// and those of the local scope (the project entry of ~/.claude.json) override
+// both, as in Claude Code.
+func (i *Import) readClaudeCode(workingDir string) error {
+	paths := importPaths(ImportClaudeCode, workingDir)
+
+	var settings claudeSettings
+	if _, err := i.readImportFile(paths[0], &settings); err != nil {
+		return err
+	}
+	var state claudeState
+	if _, err := i.readImportFile(paths[1], &state); err != nil {
+		return err
+	}
+	var project struct {
+		MCPServers map[string]claudeMCPServer `json:"mcpServers"`
+	}
+	if _, err := i.readImportFile(paths[2], &project); err != nil {
+		return err
+	}
+
+	servers := make(map[string]claudeMCPServer)
+	maps.Copy(servers, state.MCPServers)
+	maps.Copy(servers, project.MCPServers)
+	if local, ok := state.Projects[filepath.Clean(workingDir)]; ok {
+		maps.Copy(servers, local.MCPServers)
+	}
+	for _, name := range slices.Sorted(maps.Keys(servers)) {
+		server, err := claudeServer(servers[name])
+		if err != nil {
+			i.skipped(ImportMCPServers, "mcpServers."+name, err.Error())
+			continue
+		}
+		i.MCPServers[name] = server
+		i.imported(ImportMCPServers, "mcpServers."+name)
+	}
+
+	apiKey := state.PrimaryAPIKey
+	if key := settings.Env["ANTHROPIC_API_KEY"]; key != "" {
+		apiKey = key
+	}
+	if apiKey != "" {
+		i.Providers[models.ProviderAnthropic] = Provider{APIKey: apiKey}
+		i.imported(ImportProviders, "providers.anthropic")
+	}
+	if settings.APIKeyHelper != "" {
+		i.manual(ImportProviders, "apiKeyHelper", "set the key it prints as providers.anthropic.apiKey or ANTHROPIC_API_KEY")
+	}
+	for _, name := range slices.Sorted(maps.Keys(settings.Env)) {
+		if name != "ANTHROPIC_API_KEY" {
+			i.manual(ImportPreferences, "env."+name, "set the environment variable in your shell")
+		}
+	}
+
+	if state.Theme != "" {
+		i.skipped(ImportPreferences, "theme", "the themes of Claude Code have no counterpart")
+	}
+	if len(settings.Permissions) > 0 {
+		i.skipped(ImportPreferences, "permissions", "the permission rules of Claude Code have no counterpart")
+	}
+	if len(settings.Hooks) > 0 {
+		i.skipped(ImportPreferences, "hooks", "the hooks of Claude Code have no counterpart")
+	}
+	return nil
+}
+
+// claudeServer maps an MCP server of Claude Code onto an MCP server
+func claudeServer(server claudeMCPServer) (MCPServer, error) {
+	env := make([]string, 0, len(server.Env))
+	for _, name := range slices.Sorted(maps.Keys(server.Env)) {
+		env = append(env, name+"="+server.Env[name])
+	}
+	switch server.Type {
+	case "", "stdio":
+		return MCPServer{Type: MCPStdio, Command: server.Command, Args: server.Args, Env: env}, nil
+	case "sse":
+		return MCPServer{Type: MCPSse, URL: server.URL, Headers: server.Headers}, nil
+	}
+	return MCPServer{}, fmt.Errorf("unsupported server type %q", server.Type)
+}
+
+// Conflicts returns the names of the entries of each section that the import
+// would replace in cfg, the configuration of the file it is written to
+func (i *Import) Conflicts(cfg *Config) map[ImportSection][]string {
+	conflicts := make(map[ImportSection][]string)
+	for provider := range i.Providers {
+		if _, ok := cfg.Providers[provider]; ok {
+			conflicts[ImportProviders] = append(conflicts[ImportProviders], string(provider))
+		}
+	}
+	for name := range i.MCPServers {
+		if _, ok := cfg.MCPServers[name]; ok {
+			conflicts[ImportMCPServers] = append(conflicts[ImportMCPServers], name)
+		}
+	}
+	for name := range i.LSP {
+		if _, ok := cfg.LSP[name]; ok {
+			conflicts[ImportLSP] = append(conflicts[ImportLSP], name)
+		}
+	}
+	if i.Theme != "" && cfg.TUI.Theme != "" && cfg.TUI.Theme != i.Theme {
+		conflicts[ImportPreferences] = append(conflicts[ImportPreferences], "tui.theme")
+	}
+	if i.Shell.Path != "" && cfg.Shell.Path != "" && cfg.Shell.Path != i.Shell.Path {
+		conflicts[ImportPreferences] = append(conflicts[ImportPreferences], "shell")
+	}
+	for _, names := range conflicts {
+		sort.Strings(names)
+	}
+	return conflicts
+}
+
+// Apply merges the import into cfg. The entries that conflict with those of
+// cfg replace them in the sections of overwrite, and are left out otherwise.
+func (i *Import) Apply(cfg *Config, overwrite map[ImportSection]bool) {
+	if cfg.Providers == nil {
+		cfg.Providers = make(map[models.ModelProvider]Provider)
+	}
+	for provider, p := range i.Providers {
+		if _, ok := cfg.Providers[provider]; !ok || overwrite[ImportProviders] {
+			cfg.Providers[provider] = p
+		}
+	}
+	if cfg.MCPServers == nil {
+		cfg.MCPServers = make(map[string]MCPServer)
+	}
+	for name, server := range i.MCPServers {
+		if _, ok := cfg.MCPServers[name]; !ok || overwrite[ImportMCPServers] {
+			cfg.MCPServers[name] = server
+		}
+	}
+	if cfg.LSP == nil {
+		cfg.LSP = make(map[string]LSPConfig)
+	}
+	for name, lsp := range i.LSP {
+		if _, ok := cfg.LSP[name]; !ok || overwrite[ImportLSP] {
+			cfg.LSP[name] = lsp
+		}
+	}
+	if i.Theme != "" && (cfg.TUI.Theme == "" || overwrite[ImportPreferences]) {
+		cfg.TUI.Theme = i.Theme
+	}
+	if i.Shell.Path != "" && (cfg.Shell.Path == "" || overwrite[ImportPreferences]) {
+		cfg.Shell = i.Shell
+	}
+}
+
+// ImportTarget reads the config file an import is written to, the global
+// config file, returning its path. The configuration is empty when the file
+// does not exist yet.
+func ImportTarget() (string, *Config, error) {
+	path, err := GlobalConfigFile()
+	if err != nil {
+		return "", nil, err
+	}
+	cfg := &Config{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return path, cfg, nil
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return "", nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	return path, cfg, nil
+}
+
+// WriteImport merges the import into the global config file, resolving the
+// conflicts as Apply does
+func WriteImport(imp *Import, overwrite map[ImportSection]bool) error {
+	return updateCfgFile(func(cfg *Config) {
+		imp.Apply(cfg, overwrite)
+	})
+}
@@ -0,0 +1,245 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+)
+
+// importFixture copies the home and project directories of the sample config
+// files of source to temporary directories, the home directory becoming the
+// one of the test, and returns the project directory
+func importFixture(t *testing.T, source ImportSource) string {
+	t.Helper()
+	home, project := t.TempDir(), t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	fixture := filepath.Join("testdata", "import", string(source))
+	for dir, target := range map[string]string{"home": home, "project": project} {
+		err := filepath.WalkDir(filepath.Join(fixture, dir), func(path string, d os.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			// The projects of Claude Code are keyed by their path
+			data = []byte(strings.ReplaceAll(string(data), "PROJECT_DIR", project))
+			rel, _ := filepath.Rel(filepath.Join(fixture, dir), path)
+			if err := os.MkdirAll(filepath.Dir(filepath.Join(target, rel)), 0o755); err != nil {
+				return err
+			}
+			return os.WriteFile(filepath.Join(target, rel), data, 0o644)
+		})
+		if err != nil {
+			t.Fatalf("Failed to copy the %s fixture: %v", source, err)
+		}
+	}
+	return project
+}
+
+// noteNames returns the names of the notes of the report
+func noteNames(notes []ImportNote) []string {
+	names := make([]string, 0, len(notes))
+	for _, note := range notes {
+		names = append(names, note.Name)
+	}
+	return names
+}
+
+func TestReadImportOpenCode(t *testing.T) {
+	project := importFixture(t, ImportOpenCode)
+	imp, err := ReadImport(ImportOpenCode, project, []string{"intelligence-interface", "dracula"})
+	if err != nil {
+		t.Fatalf("ReadImport() error = %v", err)
+	}
+	if len(imp.Files) != 2 {
+		t.Errorf("Files = %v, want the global and the project file", imp.Files)
+	}
+
+	wantProviders := map[models.ModelProvider]Provider{
+		models.ProviderAnthropic: {APIKey: "sk-ant-project"},
+		models.ProviderOpenAI:    {APIKey: "sk-openai", Disabled: true},
+	}
+	if !reflect.DeepEqual(imp.Providers, wantProviders) {
+		t.Errorf("Providers = %+v, want %+v", imp.Providers, wantProviders)
+	}
+	wantServers := map[string]MCPServer{
+		"filesystem": {
+			Type:    MCPStdio,
+			Command: "npx",
+			Args:    []string{"-y", "@modelcontextprotocol/server-filesystem", "/tmp"},
+			Env:     []string{"DEBUG=1"},
+		},
+		"search": {
+			Type:    MCPSse,
+			URL:     "https://mcp.example.com/sse",
+			Headers: map[string]string{"Authorization": "Bearer token"},
+		},
+	}
+	if !reflect.DeepEqual(imp.MCPServers, wantServers) {
+		t.Errorf("MCPServers = %+v, want %+v", imp.MCPServers, wantServers)
+	}
+	if imp.LSP["go"].Command != "gopls" {
+		t.Errorf("LSP = %+v, want gopls", imp.LSP)
+	}
+	if imp.Theme != "dracula" {
+		t.Errorf("Theme = %q, want the theme of the project file", imp.Theme)
+	}
+	if !reflect.DeepEqual(imp.Shell, ShellConfig{Path: "/bin/zsh", Args: []string{"-l"}}) {
+		t.Errorf("Shell = %+v", imp.Shell)
+	}
+
+	wantImported := []string{"providers.anthropic", "providers.openai", "mcpServers.filesystem", "mcpServers.search", "lsp.go", "tui.theme", "shell"}
+	if got := noteNames(imp.Report.Imported); !reflect.DeepEqual(got, wantImported) {
+		t.Errorf("Imported = %v, want %v", got, wantImported)
+	}
+	if got, want := noteNames(imp.Report.Skipped), []string{"providers.copilot", "data.directory"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Skipped = %v, want %v", got, want)
+	}
+	if got, want := noteNames(imp.Report.Manual), []string{"agents.coder", "contextPaths"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Manual = %v, want %v", got, want)
+	}
+}
+
+func TestReadImportOpenCodeTheme(t *testing.T) {
+	project := importFixture(t, ImportOpenCode)
+	os.Remove(filepath.Join(project, ".opencode.json"))
+
+	// The default theme of OpenCode maps to the default theme
+	imp, err := ReadImport(ImportOpenCode, project, nil)
+	if err != nil {
+		t.Fatalf("ReadImport() error = %v", err)
+	}
+	if imp.Theme != "intelligence-interface" {
+		t.Errorf("Theme = %q, want intelligence-interface", imp.Theme)
+	}
+
+	os.WriteFile(filepath.Join(project, ".opencode.json"), []byte(`{"tui":{"theme":"solarized"}}`), 0o644)
+	imp, err = ReadImport(ImportOpenCode, project, []string{"intelligence-interface"})
+	if err != nil {
+		t.Fatalf("ReadImport() error = %v", err)
+	}
+	if imp.Theme != "" || !strings.Contains(imp.Report.Skipped[1].Reason, "solarized") {
+		t.Errorf("Theme = %q, skipped = %+v, want the unknown theme skipped", imp.Theme, imp.Report.Skipped)
+	}
+}
+
+func TestReadImportClaudeCode(t *testing.T) {
+	project := importFixture(t, ImportClaudeCode)
+	imp, err := ReadImport(ImportClaudeCode, project, nil)
+	if err != nil {
+		t.Fatalf("ReadImport() error = %v", err)
+	}
+	if len(imp.Files) != 3 {
+		t.Errorf("Files = %v, want the settings, the state and the project file", imp.Files)
+	}
+
+	// The key of the settings wins over the one Claude Code stored
+	wantProviders := map[models.ModelProvider]Provider{
+		models.ProviderAnthropic: {APIKey: "sk-ant-settings"},
+	}
+	if !reflect.DeepEqual(imp.Providers, wantProviders) {
+		t.Errorf("Providers = %+v, want %+v", imp.Providers, wantProviders)
+	}
+	wantServers := map[string]MCPServer{
+		// The server of the local scope overrides the one of the user scope
+		"github": {
+			Type:    MCPStdio,
+			Command: "github-mcp-server",
+			Args:    []string{"stdio", "--read-only"},
+			Env:     []string{},
+		},
+		"sentry": {
+			Type:    MCPSse,
+			URL:     "https://mcp.sentry.dev/sse",
+			Headers: map[string]string{"X-Org": "acme"},
+		},
+	}
+	if !reflect.DeepEqual(imp.MCPServers, wantServers) {
+		t.Errorf("MCPServers = %+v, want %+v", imp.MCPServers, wantServers)
+	}
+
+	if got, want := noteNames(imp.Report.Imported), []string{"mcpServers.github", "mcpServers.sentry", "providers.anthropic"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Imported = %v, want %v", got, want)
+	}
+	if got, want := noteNames(imp.Report.Skipped), []string{"mcpServers.docs", "theme", "permissions"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Skipped = %v, want %v", got, want)
+	}
+	if got, want := noteNames(imp.Report.Manual), []string{"apiKeyHelper", "env.BASH_DEFAULT_TIMEOUT_MS"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Manual = %v, want %v", got, want)
+	}
+}
+
+func TestClaudeServerEnv(t *testing.T) {
+	server, err := claudeServer(claudeMCPServer{
+		Command: "github-mcp-server",
+		Env:     map[string]string{"GITHUB_TOKEN": "ghp-token", "GITHUB_HOST": "github.com"},
+	})
+	if err != nil {
+		t.Fatalf("claudeServer() error = %v", err)
+	}
+	if want := []string{"GITHUB_HOST=github.com", "GITHUB_TOKEN=ghp-token"}; !reflect.DeepEqual(server.Env, want) {
+		t.Errorf("Env = %v, want %v", server.Env, want)
+	}
+}
+
+func TestReadImportNothingFound(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", "")
+	if _, err := ReadImport(ImportClaudeCode, t.TempDir(), nil); err == nil || !strings.Contains(err.Error(), ErrNothingToImport.Error()) {
+		t.Errorf("ReadImport() error = %v, want %v", err, ErrNothingToImport)
+	}
+	if _, err := ReadImport("cursor", t.TempDir(), nil); err == nil {
+		t.Error("ReadImport() of an unknown tool succeeded")
+	}
+}
+
+func TestImportConflicts(t *testing.T) {
+	project := importFixture(t, ImportOpenCode)
+	imp, err := ReadImport(ImportOpenCode, project, []string{"dracula"})
+	if err != nil {
+		t.Fatalf("ReadImport() error = %v", err)
+	}
+	existing := func() *Config {
+		return &Config{
+			Providers:  map[models.ModelProvider]Provider{models.ProviderAnthropic: {APIKey: "sk-ant-mine"}},
+			MCPServers: map[string]MCPServer{"search": {Type: MCPStdio, Command: "my-search"}},
+			TUI:        TUIConfig{Theme: "tokyonight"},
+		}
+	}
+
+	conflicts := imp.Conflicts(existing())
+	want := map[ImportSection][]string{
+		ImportProviders:   {"anthropic"},
+		ImportMCPServers:  {"search"},
+		ImportPreferences: {"tui.theme"},
+	}
+	if !reflect.DeepEqual(conflicts, want) {
+		t.Errorf("Conflicts() = %v, want %v", conflicts, want)
+	}
+
+	// The conflicting entries are kept unless their section is overwritten
+	cfg := existing()
+	imp.Apply(cfg, map[ImportSection]bool{ImportMCPServers: true})
+	if cfg.Providers[models.ProviderAnthropic].APIKey != "sk-ant-mine" {
+		t.Errorf("anthropic key = %q, want the existing one kept", cfg.Providers[models.ProviderAnthropic].APIKey)
+	}
+	if cfg.Providers[models.ProviderOpenAI].APIKey != "sk-openai" {
+		t.Error("The provider without conflict was not imported")
+	}
+	if cfg.MCPServers["search"].URL != "https://mcp.example.com/sse" {
+		t.Errorf("search server = %+v, want the imported one", cfg.MCPServers["search"])
+	}
+	if cfg.TUI.Theme != "tokyonight" {
+		t.Errorf("Theme = %q, want the existing one kept", cfg.TUI.Theme)
+	}
+	if cfg.LSP["go"].Command != "gopls" || cfg.Shell.Path != "/bin/zsh" {
+		t.Errorf("LSP = %+v, shell = %+v, want them imported", cfg.LSP, cfg.Shell)
+	}
+}
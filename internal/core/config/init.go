@@ -18,6 +18,7 @@ type ProjectInitFlag struct {
 
 // ShouldShowInitDialog checks if the initialization dialog should be shown for the current directory
 func ShouldShowInitDialog() (bool, error) {
+	cfg := Get()
 	if cfg == nil {
 		return false, fmt.Errorf("config not loaded")
 	}
@@ -43,6 +44,7 @@ func ShouldShowInitDialog() (bool, error) {
 
 // MarkProjectInitialized marks the current project as initialized
 func MarkProjectInitialized() error {
+	cfg := Get()
 	if cfg == nil {
 		return fmt.Errorf("config not loaded")
 	}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+)
+
+// keyringPrefix marks the space variables whose value is read from the
+// keyring of the system, as keyring:<service>/<account> or keyring:<account>
+// for the service of the application
+const keyringPrefix = "keyring:"
+
+var (
+	// keyringLookup reads a secret from the keyring of the system
+	keyringLookup = lookupKeyring
+
+	keyringMu sync.Mutex
+	// keyringSecrets are the secrets read, by reference, so that the keyring
+	// is not asked again for each command
+	keyringSecrets = make(map[string]string)
+)
+
+// parseKeyringRef returns the service and account a keyring reference names,
+// false when value is not a reference
+func parseKeyringRef(value string) (service, account string, ok bool) {
+	ref, ok := strings.CutPrefix(value, keyringPrefix)
+	if !ok {
+		return "", "", false
+	}
+	if service, account, found := strings.Cut(ref, "/"); found {
+		return service, account, true
+	}
+	return appName, ref, true
+}
+
+// validateKeyringRef rejects the references naming no service or account
+func validateKeyringRef(spaceID, name, value string) error {
+	service, account, ok := parseKeyringRef(value)
+	if ok && (service == "" || account == "") {
+		return fmt.Errorf("space %q: invalid keyring reference for %s: must be keyring:<service>/<account> or keyring:<account>", spaceID, name)
+	}
+	return nil
+}
+
+// resolveKeyringRef returns the value a variable is set to: the secret its
+// reference names in the keyring, which is never logged, or the value itself
+func resolveKeyringRef(value string) (string, error) {
+	service, account, ok := parseKeyringRef(value)
+	if !ok {
+		return value, nil
+	}
+	keyringMu.Lock()
+	defer keyringMu.Unlock()
+	if secret, ok := keyringSecrets[value]; ok {
+		return secret, nil
+	}
+	secret, err := keyringLookup(service, account)
+	if err != nil {
+		return "", err
+	}
+	logging.AddSecret(secret)
+	keyringSecrets[value] = secret
+	return secret, nil
+}
+
+// lookupKeyring reads a secret with the keyring command of the system:
+// security on macOS, secret-tool of libsecret elsewhere
+func lookupKeyring(service, account string) (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", service, "-a", account, "-w")
+	case "windows":
+		return "", fmt.Errorf("the keyring is not supported on windows")
+	default:
+		cmd = exec.Command("secret-tool", "lookup", "service", service, "account", account)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s/%s from the keyring: %w", service, account, err)
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
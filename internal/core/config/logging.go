@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+)
+
+// LoggingConfig defines how repeated logs are throttled.
+type LoggingConfig struct {
+	// ThrottleWindow collapses identical logs within this duration into the
+	// first one, followed by a "repeated N times" log, e.g. "10s". "0" logs
+	// every one.
+	ThrottleWindow string `json:"throttleWindow,omitempty"`
+	// ThrottleExempt are the components whose logs are never throttled, on
+	// top of panics and data-loss warnings
+	ThrottleExempt []string `json:"throttleExempt,omitempty"`
+}
+
+// logThrottle throttles the logs of the default logger
+var logThrottle *logging.ThrottleHandler
+
+// ThrottleOptions returns the options of the log throttle
+func (l LoggingConfig) ThrottleOptions() logging.ThrottleOptions {
+	window := logging.DefaultThrottleWindow
+	if l.ThrottleWindow != "" {
+		// Validated on load
+		window, _ = time.ParseDuration(l.ThrottleWindow)
+	}
+	return logging.ThrottleOptions{
+		Window: window,
+		Exempt: append(append([]string(nil), logging.DefaultThrottleExempt...), l.ThrottleExempt...),
+	}
+}
+
+// validateLogging rejects invalid throttle windows.
+func validateLogging(cfg *Config) error {
+	if cfg.Logging.ThrottleWindow == "" {
+		return nil
+	}
+	window, err := time.ParseDuration(cfg.Logging.ThrottleWindow)
+	if err != nil {
+		return fmt.Errorf("invalid throttle window %q: %w", cfg.Logging.ThrottleWindow, err)
+	}
+	if window < 0 {
+		return fmt.Errorf("invalid throttle window %q: must not be negative", cfg.Logging.ThrottleWindow)
+	}
+	return nil
+}
+
+// configureLogThrottle applies the logging configuration to the log
+// throttle, which starts over
+func configureLogThrottle() {
+	cfg := Get()
+	if logThrottle == nil || cfg == nil {
+		return
+	}
+	logThrottle.Configure(cfg.Logging.ThrottleOptions())
+}
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+var (
+	mcpServersMu sync.Mutex
+	// mcpServersFile is the absolute path of the MCP servers file, empty
+	// when none is configured
+	mcpServersFile string
+	// configMCPServers are the MCP servers of the config files, merged over
+	// those of the MCP servers file when it changes
+	configMCPServers map[string]any
+)
+
+// mergeMCPServersFile merges the MCP servers of the file set by mcpServersFile
+// under those of the config files, the same way the local config is merged
+// over the global one: a server defined in both is merged field by field.
+func mergeMCPServersFile(workingDir string) error {
+	mcpServersMu.Lock()
+	defer mcpServersMu.Unlock()
+
+	mcpServersFile = ""
+	path := viper.GetString("mcpServersFile")
+	if path == "" {
+		return nil
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workingDir, path)
+	}
+	path = filepath.Clean(path)
+
+	configServers, _ := viper.Get("mcpServers").(map[string]any)
+	servers, err := mergedMCPServers(path, configServers)
+	if err != nil {
+		return err
+	}
+	viper.Set("mcpServers", servers)
+
+	mcpServersFile = path
+	configMCPServers = configServers
+	return nil
+}
+
+// mergedMCPServers reads the MCP servers file at path and merges servers over
+// its servers
+func mergedMCPServers(path string, servers map[string]any) (map[string]any, error) {
+	fileServers, err := readMCPServersFile(path)
+	if err != nil {
+		return nil, err
+	}
+	merged := viper.New()
+	merged.MergeConfigMap(map[string]any{"mcpServers": fileServers})
+	if servers != nil {
+		merged.MergeConfigMap(map[string]any{"mcpServers": servers})
+	}
+	result, _ := merged.Get("mcpServers").(map[string]any)
+	return result, nil
+}
+
+// readMCPServersFile reads a JSON or YAML file, chosen by its extension,
+// holding either the map of MCP servers or an object with an mcpServers key
+// holding it
+func readMCPServersFile(path string) (map[string]any, error) {
+	file := viper.New()
+	file.SetConfigFile(path)
+	if err := file.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read MCP servers file: %w", err)
+	}
+
+	settings := file.AllSettings()
+	if servers, ok := settings["mcpservers"]; ok && len(settings) == 1 {
+		serverMap, ok := servers.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("invalid MCP servers file %s: mcpServers must be an object", path)
+		}
+		return serverMap, nil
+	}
+	return settings, nil
+}
+
+// reloadMCPServers reads the MCP servers file again and replaces the MCP
+// servers of the configuration
+func reloadMCPServers() error {
+	mcpServersMu.Lock()
+	defer mcpServersMu.Unlock()
+
+	if mcpServersFile == "" {
+		return nil
+	}
+	merged, err := mergedMCPServers(mcpServersFile, configMCPServers)
+	if err != nil {
+		return err
+	}
+	decoder := viper.New()
+	decoder.Set("mcpServers", merged)
+	servers := make(map[string]MCPServer)
+	if err := decoder.UnmarshalKey("mcpServers", &servers); err != nil {
+		return fmt.Errorf("invalid MCP servers file %s: %w", mcpServersFile, err)
+	}
+	for name, server := range servers {
+		if server.Type == "" {
+			server.Type = MCPStdio
+			servers[name] = server
+		}
+	}
+
+	return Update(func(cfg *Config) error {
+		cfg.MCPServers = servers
+		return nil
+	})
+}
+
+// WatchMCPServersFile reloads the MCP servers when the MCP servers file
+// changes, notifying the watchers of the configuration, until ctx is done.
+// It returns immediately when no MCP servers file is configured.
+func WatchMCPServersFile(ctx context.Context) error {
+	mcpServersMu.Lock()
+	path := mcpServersFile
+	mcpServersMu.Unlock()
+	if path == "" {
+		return nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create MCP servers file watcher: %w", err)
+	}
+	defer watcher.Close()
+	// Editors replace files when saving, so the directory is watched
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("failed to watch MCP servers file: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(event.Name) != path || !event.Op.Has(fsnotify.Write) && !event.Op.Has(fsnotify.Create) {
+				continue
+			}
+			if err := reloadMCPServers(); err != nil {
+				logging.Warn("Failed to reload MCP servers file, keeping the current servers", "error", err)
+				continue
+			}
+			logging.Info("Reloaded MCP servers file", "path", path)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			logging.Warn("MCP servers file watcher error", "error", err)
+		}
+	}
+}
+
+// validateMCPServers checks the token refresh settings of the MCP servers,
+// which only SSE servers use
+func validateMCPServers(cfg *Config) error {
+	for name, server := range cfg.MCPServers {
+		if server.TokenRefreshIntervalSec < 0 {
+			return fmt.Errorf("mcp server %s: tokenRefreshIntervalSec must not be negative, got %d", name, server.TokenRefreshIntervalSec)
+		}
+		if server.TokenRefreshIntervalSec > 0 && server.TokenRefreshCommand == "" {
+			return fmt.Errorf("mcp server %s: tokenRefreshIntervalSec requires a tokenRefreshCommand", name)
+		}
+		if server.TokenRefreshCommand != "" && server.Type != MCPSse {
+			logging.Warn("token refresh is only used by sse mcp servers, ignoring it", "server", name, "type", server.Type)
+		}
+	}
+	return nil
+}
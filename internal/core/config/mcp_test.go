@@ -0,0 +1,136 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestMCPServersFile(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "test-key-for-config")
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	current.Store(nil)
+	viper.Reset()
+	defer func() {
+		current.Store(nil)
+		viper.Reset()
+	}()
+
+	workingDir := t.TempDir()
+	serversFile := filepath.Join(workingDir, "shared", "mcp.yaml")
+	if err := os.MkdirAll(filepath.Dir(serversFile), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile := func(path, content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(serversFile, `mcpServers:
+  github:
+    command: github-mcp
+    args: [--stdio]
+  search:
+    type: sse
+    url: http://localhost:9000/sse
+`)
+	writeFile(filepath.Join(workingDir, ".intelligence-interface.json"), `{
+  "mcpServersFile": "shared/mcp.yaml",
+  "mcpServers": {
+    "github": {"args": ["--stdio", "--read-only"]},
+    "local": {"command": "local-mcp"}
+  }
+}`)
+
+	loaded, err := Load(workingDir, false)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	want := map[string]MCPServer{
+		"github": {Command: "github-mcp", Args: []string{"--stdio", "--read-only"}, Type: MCPStdio},
+		"search": {Type: MCPSse, URL: "http://localhost:9000/sse"},
+		"local":  {Command: "local-mcp", Type: MCPStdio},
+	}
+	if !reflect.DeepEqual(loaded.MCPServers, want) {
+		t.Errorf("MCPServers = %+v, want %+v", loaded.MCPServers, want)
+	}
+
+	// A changed file replaces its servers, the local ones still override them
+	changes, stop := Watch()
+	defer stop()
+	writeFile(serversFile, `github:
+  command: github-mcp-v2
+`)
+	if err := reloadMCPServers(); err != nil {
+		t.Fatalf("reloadMCPServers() error = %v", err)
+	}
+	select {
+	case <-changes:
+	default:
+		t.Error("no change received after reloading the MCP servers file")
+	}
+	want = map[string]MCPServer{
+		"github": {Command: "github-mcp-v2", Args: []string{"--stdio", "--read-only"}, Type: MCPStdio},
+		"local":  {Command: "local-mcp", Type: MCPStdio},
+	}
+	if !reflect.DeepEqual(Get().MCPServers, want) {
+		t.Errorf("MCPServers after reload = %+v, want %+v", Get().MCPServers, want)
+	}
+
+	// An invalid file keeps the current servers
+	writeFile(serversFile, "github: [")
+	if err := reloadMCPServers(); err == nil {
+		t.Error("reloadMCPServers() of an invalid file should fail")
+	}
+	if !reflect.DeepEqual(Get().MCPServers, want) {
+		t.Errorf("MCPServers after a failed reload = %+v, want %+v", Get().MCPServers, want)
+	}
+}
+
+func TestMCPServersFileMissing(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "test-key-for-config")
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	current.Store(nil)
+	viper.Reset()
+	defer func() {
+		current.Store(nil)
+		viper.Reset()
+	}()
+
+	workingDir := t.TempDir()
+	config := `{"mcpServersFile": "missing.json"}`
+	if err := os.WriteFile(filepath.Join(workingDir, ".intelligence-interface.json"), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(workingDir, false); err == nil {
+		t.Error("Load() with a missing MCP servers file should fail")
+	}
+}
+
+func TestValidateMCPServers(t *testing.T) {
+	tests := []struct {
+		name    string
+		server  MCPServer
+		wantErr bool
+	}{
+		{"no refresh", MCPServer{Type: MCPSse, URL: "http://localhost:9000/sse"}, false},
+		{"refresh on demand", MCPServer{Type: MCPSse, TokenRefreshCommand: "echo token"}, false},
+		{"refresh every hour", MCPServer{Type: MCPSse, TokenRefreshCommand: "echo token", TokenRefreshIntervalSec: 3600}, false},
+		{"negative interval", MCPServer{Type: MCPSse, TokenRefreshCommand: "echo token", TokenRefreshIntervalSec: -1}, true},
+		{"interval without command", MCPServer{Type: MCPSse, TokenRefreshIntervalSec: 3600}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{MCPServers: map[string]MCPServer{"search": tt.server}}
+			if err := validateMCPServers(cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateMCPServers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
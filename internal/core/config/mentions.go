@@ -0,0 +1,49 @@
+package config
+
+import "fmt"
+
+const (
+	// DefaultMentionBudget bounds the bytes of the files mentioned in a
+	// message when unset
+	DefaultMentionBudget = 100 * 1024
+	// DefaultMentionDirEntries bounds the listing of a mentioned directory
+	// when unset
+	DefaultMentionDirEntries = 200
+)
+
+// MentionsConfig bounds the context the files mentioned with @ in a message
+// add to it
+type MentionsConfig struct {
+	// Budget bounds the bytes of every file mentioned in a message together,
+	// files over it are truncated
+	Budget int64 `json:"budget,omitempty"`
+	// MaxDirEntries bounds the entries listed for a mentioned directory
+	MaxDirEntries int `json:"maxDirEntries,omitempty"`
+}
+
+// Bytes returns the size budget of the files mentioned in a message
+func (m MentionsConfig) Bytes() int64 {
+	if m.Budget <= 0 {
+		return DefaultMentionBudget
+	}
+	return m.Budget
+}
+
+// DirEntries returns the maximum number of entries listed for a directory
+func (m MentionsConfig) DirEntries() int {
+	if m.MaxDirEntries <= 0 {
+		return DefaultMentionDirEntries
+	}
+	return m.MaxDirEntries
+}
+
+// validate checks the mention limits
+func (m MentionsConfig) validate() error {
+	if m.Budget < 0 {
+		return fmt.Errorf("budget must be positive")
+	}
+	if m.MaxDirEntries < 0 {
+		return fmt.Errorf("maxDirEntries must be positive")
+	}
+	return nil
+}
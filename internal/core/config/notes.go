@@ -0,0 +1,46 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+)
+
+// DefaultNotesPromptTokens bounds the workspace notes included in the system
+// prompt
+const DefaultNotesPromptTokens = 1000
+
+// NotesConfig sets the long-term memory of the workspace: the notes agents
+// and users keep about its decisions, conventions and gotchas
+type NotesConfig struct {
+	// Disabled keeps the notes out of the system prompt and the memory tools
+	// away from the agents
+	Disabled bool `json:"disabled,omitempty"`
+	// PromptTokens bounds the notes included in the system prompt, the
+	// pinned ones coming first
+	PromptTokens int `json:"promptTokens,omitempty"`
+}
+
+// PromptBudget returns the tokens of notes included in the system prompt
+func (n NotesConfig) PromptBudget() int {
+	if n.PromptTokens <= 0 {
+		return DefaultNotesPromptTokens
+	}
+	return n.PromptTokens
+}
+
+// validate checks the notes settings
+func (n NotesConfig) validate() error {
+	if n.PromptTokens < 0 {
+		return fmt.Errorf("promptTokens must be positive")
+	}
+	return nil
+}
+
+// NotesFile returns the file holding the notes of the workspace, under the
+// data directory and keyed by the hash of the path of the workspace
+func NotesFile() string {
+	hash := sha256.Sum256([]byte(WorkingDirectory()))
+	return filepath.Join(Get().Data.Directory, "memory", hex.EncodeToString(hash[:8])+".json")
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/spf13/viper"
+)
+
+// ollamaProbeTimeout bounds the detection of a local Ollama server at
+// startup, so a machine without one starts quickly
+const ollamaProbeTimeout = 500 * time.Millisecond
+
+// OllamaConfig defines how the Ollama provider reaches its server and
+// manages its models
+type OllamaConfig struct {
+	// Endpoint is the base URL of the Ollama server, OLLAMA_HOST or the
+	// default local server when unset
+	Endpoint string `json:"endpoint,omitempty"`
+	// KeepAlive is how long the server keeps a model loaded after a request,
+	// as a duration like "10m" or a number of seconds, -1 keeping it loaded.
+	// The server default applies when unset.
+	KeepAlive string `json:"keepAlive,omitempty"`
+	// AutoPull pulls a model that is not on the server when it is first used,
+	// rather than failing with the command pulling it
+	AutoPull bool `json:"autoPull,omitempty"`
+}
+
+// BaseURL returns the base URL of the Ollama server
+func (o OllamaConfig) BaseURL() string {
+	return models.OllamaEndpoint(o.Endpoint)
+}
+
+// validate checks the keep alive duration, as the server rejects requests
+// with one it cannot parse
+func (o OllamaConfig) validate() error {
+	if o.KeepAlive == "" {
+		return nil
+	}
+	if _, err := strconv.Atoi(o.KeepAlive); err == nil {
+		return nil
+	}
+	if _, err := time.ParseDuration(o.KeepAlive); err != nil {
+		return fmt.Errorf("keepAlive must be a duration or a number of seconds: %q", o.KeepAlive)
+	}
+	return nil
+}
+
+// detectOllama registers the models of a running Ollama server, unless the
+// provider is disabled, and makes it available without an API key. The
+// first model becomes the default when no other provider has credentials.
+func detectOllama() {
+	if viper.GetBool("providers.ollama.disabled") {
+		return
+	}
+	endpoint := models.OllamaEndpoint(viper.GetString("ollama.endpoint"))
+	loaded := models.LoadOllamaModels(endpoint, ollamaProbeTimeout)
+	if len(loaded) == 0 {
+		return
+	}
+	viper.SetDefault("providers.ollama.apiKey", "ollama")
+	providerDefaultModels[models.ProviderOllama] = loaded[0].ID
+}
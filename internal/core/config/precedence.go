@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/viper"
+
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+)
+
+// The providers, agents and MCP servers are read from three sources, each
+// overriding the previous one field by field: the global config file, the
+// environment, then the .intelligence-interface.json of the working
+// directory. A project can so use its own API keys whatever the environment
+// sets.
+
+// CredentialSource is where the API key of a provider was read from
+type CredentialSource string
+
+const (
+	CredentialGlobal CredentialSource = "global"
+	CredentialEnv    CredentialSource = "env"
+	CredentialLocal  CredentialSource = "local"
+)
+
+// providerEnvKeys are the environment variables holding the API keys of the
+// providers. The Azure key is read only along with its endpoint.
+var providerEnvKeys = map[models.ModelProvider]string{
+	models.ProviderAnthropic:  "ANTHROPIC_API_KEY",
+	models.ProviderOpenAI:     "OPENAI_API_KEY",
+	models.ProviderGemini:     "GEMINI_API_KEY",
+	models.ProviderGROQ:       "GROQ_API_KEY",
+	models.ProviderOpenRouter: "OPENROUTER_API_KEY",
+	models.ProviderXAI:        "XAI_API_KEY",
+}
+
+// recordCredentialSources records source for the providers v sets an API key
+// for
+func recordCredentialSources(v *viper.Viper, source CredentialSource, sources map[models.ModelProvider]CredentialSource) {
+	for _, provider := range models.SupportedProviders() {
+		if v.GetString(fmt.Sprintf("providers.%s.apiKey", provider)) != "" {
+			sources[provider] = source
+		}
+	}
+}
+
+// mergeEnvProviders merges the API keys of the environment over the global
+// config file, recording their source
+func mergeEnvProviders(sources map[models.ModelProvider]CredentialSource) {
+	providers := make(map[string]any)
+	for provider, name := range providerEnvKeys {
+		if apiKey := os.Getenv(name); apiKey != "" {
+			providers[string(provider)] = map[string]any{"apiKey": apiKey}
+			sources[provider] = CredentialEnv
+		}
+	}
+	if os.Getenv("AZURE_OPENAI_ENDPOINT") != "" {
+		if apiKey := os.Getenv("AZURE_OPENAI_API_KEY"); apiKey != "" {
+			providers[string(models.ProviderAzure)] = map[string]any{"apiKey": apiKey}
+			sources[models.ProviderAzure] = CredentialEnv
+		} else {
+			// api-key may be empty when using Entra ID credentials – that's okay
+			viper.SetDefault("providers.azure.apiKey", "")
+		}
+	}
+	if len(providers) > 0 {
+		viper.MergeConfigMap(map[string]any{"providers": providers})
+	}
+}
+
+// hasProviderKey reports whether the provider is enabled with an API key,
+// from any source
+func hasProviderKey(cfg *Config, provider models.ModelProvider) bool {
+	if providerCfg, ok := cfg.Providers[provider]; ok {
+		return !providerCfg.Disabled && providerCfg.APIKey != ""
+	}
+	return getProviderAPIKey(provider) != ""
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+)
+
+// writeConfigSources writes the global config file of an empty home
+// directory and the local config file of a working directory, sets the API
+// keys of the environment, and returns the working directory
+func writeConfigSources(t *testing.T, global, local string, env map[string]string) string {
+	t.Helper()
+	writeGlobalConfig(t, global)
+	for _, name := range providerEnvKeys {
+		t.Setenv(name, env[name])
+	}
+	t.Setenv("AZURE_OPENAI_ENDPOINT", "")
+	wd := t.TempDir()
+	if local != "" {
+		if err := os.WriteFile(filepath.Join(wd, ".intelligence-interface.json"), []byte(local), 0o644); err != nil {
+			t.Fatalf("Failed to write the local config file: %v", err)
+		}
+	}
+	return wd
+}
+
+func TestProviderPrecedence(t *testing.T) {
+	wd := writeConfigSources(t,
+		`{"providers": {
+			"openrouter": {"apiKey": "global-openrouter"},
+			"openai": {"apiKey": "global-openai"},
+			"gemini": {"apiKey": "global-gemini"}
+		}}`,
+		fmt.Sprintf(`{
+			"providers": {"openrouter": {"apiKey": "local-openrouter"}},
+			"agents": {"caronex": {"model": %q, "maxTokens": 4000}}
+		}`, models.OpenRouterClaude37Sonnet),
+		map[string]string{
+			"OPENROUTER_API_KEY": "env-openrouter",
+			"OPENAI_API_KEY":     "env-openai",
+		},
+	)
+
+	cfg, err := Load(wd, false)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	for _, tt := range []struct {
+		provider models.ModelProvider
+		key      string
+		source   CredentialSource
+	}{
+		{models.ProviderOpenRouter, "local-openrouter", CredentialLocal},
+		{models.ProviderOpenAI, "env-openai", CredentialEnv},
+		{models.ProviderGemini, "global-gemini", CredentialGlobal},
+	} {
+		if key := cfg.Providers[tt.provider].APIKey; key != tt.key {
+			t.Errorf("%s key = %q, want %q", tt.provider, key, tt.key)
+		}
+		if source := cfg.ProviderSources[tt.provider]; source != tt.source {
+			t.Errorf("%s source = %q, want %q", tt.provider, source, tt.source)
+		}
+	}
+	if model := cfg.Agents[AgentCaronex].Model; model != models.OpenRouterClaude37Sonnet {
+		t.Errorf("caronex model = %s, want the OpenRouter model of the local config", model)
+	}
+}
+
+func TestValidateAgentBindsToLocalProvider(t *testing.T) {
+	// Only the project has a key, and its agent names an unsupported model
+	wd := writeConfigSources(t, `{}`,
+		`{
+			"providers": {"openrouter": {"apiKey": "local-openrouter"}},
+			"agents": {"caronex": {"model": "retired-model"}}
+		}`,
+		nil,
+	)
+
+	cfg, err := Load(wd, false)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	model := models.SupportedModels[cfg.Agents[AgentCaronex].Model]
+	if model.Provider != models.ProviderOpenRouter {
+		t.Errorf("caronex model = %s, want a model of the local OpenRouter provider", cfg.Agents[AgentCaronex].Model)
+	}
+	if source := cfg.ProviderSources[models.ProviderOpenRouter]; source != CredentialLocal {
+		t.Errorf("openrouter source = %q, want local", source)
+	}
+}
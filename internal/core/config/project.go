@@ -0,0 +1,174 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ProjectType is a technology stack of the workspace, such as go or ruby
+type ProjectType string
+
+const (
+	ProjectGo     ProjectType = "go"
+	ProjectNode   ProjectType = "node"
+	ProjectPython ProjectType = "python"
+	ProjectRust   ProjectType = "rust"
+	ProjectRuby   ProjectType = "ruby"
+)
+
+// projectMarker is a file whose presence marks a directory as a project of a
+// stack
+type projectMarker struct {
+	file string
+	typ  ProjectType
+	// title names the stack in the project summary
+	title string
+	// languages are the names of the LSP configurations of the stack
+	languages []string
+}
+
+// projectMarkers are the marker files of the stacks, in the order stacks are
+// reported
+var projectMarkers = []projectMarker{
+	{file: "go.mod", typ: ProjectGo, title: "Go", languages: []string{"go", "gopls"}},
+	{file: "package.json", typ: ProjectNode, title: "Node.js", languages: []string{"typescript", "javascript", "typescript-language-server", "node"}},
+	{file: "pyproject.toml", typ: ProjectPython, title: "Python", languages: []string{"python", "pyright", "pylsp"}},
+	{file: "Cargo.toml", typ: ProjectRust, title: "Rust", languages: []string{"rust", "rust-analyzer"}},
+	{file: "Gemfile", typ: ProjectRuby, title: "Ruby", languages: []string{"ruby", "solargraph", "ruby-lsp"}},
+}
+
+var (
+	projectMu sync.Mutex
+	// projectDir is the working directory projectTypes were detected in, so
+	// they are detected again when it changes
+	projectDir   string
+	projectTypes []ProjectType
+)
+
+// DetectProjectTypes returns the stacks of the project in dir, from the marker
+// files in it and in the workspace roots discovered below it. Each stack is
+// listed once, in the order of the markers.
+func DetectProjectTypes(dir string) []ProjectType {
+	found := markerTypes(dir)
+	for _, root := range DetectWorkspaceRoots(dir) {
+		found = append(found, root.ProjectTypes...)
+	}
+	return orderedProjectTypes(found)
+}
+
+// markerTypes returns the stacks of the marker files of dir
+func markerTypes(dir string) []ProjectType {
+	var found []ProjectType
+	for _, marker := range projectMarkers {
+		if _, err := os.Stat(filepath.Join(dir, marker.file)); err == nil {
+			found = append(found, marker.typ)
+		}
+	}
+	return found
+}
+
+// orderedProjectTypes removes the duplicates of types, keeping the order of
+// the markers followed by the stacks without one
+func orderedProjectTypes(types []ProjectType) []ProjectType {
+	var ordered []ProjectType
+	for _, marker := range projectMarkers {
+		if slices.Contains(types, marker.typ) {
+			ordered = append(ordered, marker.typ)
+		}
+	}
+	for _, typ := range types {
+		if !slices.Contains(ordered, typ) {
+			ordered = append(ordered, typ)
+		}
+	}
+	return ordered
+}
+
+// ProjectTypes returns the stacks of the workspace: the configured
+// projectTypes when set, otherwise those detected in the working directory,
+// detected again whenever it changes
+func ProjectTypes() []ProjectType {
+	cfg := Get()
+	if cfg == nil {
+		return nil
+	}
+	if len(cfg.ProjectTypes) > 0 {
+		return cfg.ProjectTypes
+	}
+
+	projectMu.Lock()
+	defer projectMu.Unlock()
+	if projectDir != cfg.WorkingDir {
+		projectTypes = DetectProjectTypes(cfg.WorkingDir)
+		for _, root := range cfg.Workspaces {
+			projectTypes = append(projectTypes, markerTypes(root.Path)...)
+		}
+		projectTypes = orderedProjectTypes(projectTypes)
+		projectDir = cfg.WorkingDir
+	}
+	return projectTypes
+}
+
+// ProjectSummary returns a line summing up the stacks of the workspace, such as
+// "Go (go.mod) and Node.js (package.json) project", or "" when none is known
+func ProjectSummary() string {
+	types := ProjectTypes()
+	if len(types) == 0 {
+		return ""
+	}
+	parts := make([]string, len(types))
+	for i, typ := range types {
+		parts[i] = string(typ)
+		if marker, ok := markerOf(typ); ok {
+			parts[i] = marker.title + " (" + marker.file + ")"
+		}
+	}
+	summary := parts[len(parts)-1]
+	if len(parts) > 1 {
+		summary = strings.Join(parts[:len(parts)-1], ", ") + " and " + summary
+	}
+	return summary + " project"
+}
+
+// ProjectUsesLanguage reports whether the LSP configuration called language
+// serves one of the stacks of the workspace, true when no stack is known so
+// every configured server is used
+func ProjectUsesLanguage(language string) bool {
+	types := ProjectTypes()
+	if len(types) == 0 {
+		return true
+	}
+	language = strings.ToLower(language)
+	for _, typ := range types {
+		if string(typ) == language {
+			return true
+		}
+		if marker, ok := markerOf(typ); ok && slices.Contains(marker.languages, language) {
+			return true
+		}
+	}
+	return false
+}
+
+func markerOf(typ ProjectType) (projectMarker, bool) {
+	i := slices.IndexFunc(projectMarkers, func(m projectMarker) bool { return m.typ == typ })
+	if i == -1 {
+		return projectMarker{}, false
+	}
+	return projectMarkers[i], true
+}
+
+// normalizeProjectTypes lowers and deduplicates the configured project types
+func normalizeProjectTypes(cfg *Config) {
+	var types []ProjectType
+	for _, typ := range cfg.ProjectTypes {
+		typ = ProjectType(strings.ToLower(strings.TrimSpace(string(typ))))
+		if typ != "" {
+			types = append(types, typ)
+		}
+	}
+	cfg.ProjectTypes = orderedProjectTypes(types)
+}
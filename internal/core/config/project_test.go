@@ -0,0 +1,125 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeMarkers creates the files of dir, with their directories
+func writeMarkers(t *testing.T, dir string, files ...string) {
+	t.Helper()
+	for _, file := range files {
+		path := filepath.Join(dir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create the directory of %s: %v", file, err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatalf("Failed to create %s: %v", file, err)
+		}
+	}
+}
+
+func TestDetectProjectTypes(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    []string
+		expected []ProjectType
+	}{
+		{name: "empty directory"},
+		{name: "no marker", files: []string{"README.md", "src/main.c"}},
+		{name: "single stack", files: []string{"go.mod"}, expected: []ProjectType{ProjectGo}},
+		{
+			name:     "stacks side by side",
+			files:    []string{"Gemfile", "package.json", "go.mod"},
+			expected: []ProjectType{ProjectGo, ProjectNode, ProjectRuby},
+		},
+		{
+			name:     "stacks in workspace roots",
+			files:    []string{"backend/go.mod", "frontend/package.json", "frontend/node_modules/dep/Cargo.toml", "ml/pyproject.toml"},
+			expected: []ProjectType{ProjectGo, ProjectNode, ProjectPython},
+		},
+		{
+			name:     "stack of the directory and of a root below it",
+			files:    []string{"Cargo.toml", "bindings/python/pyproject.toml"},
+			expected: []ProjectType{ProjectRust},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeMarkers(t, dir, tt.files...)
+			if got := DetectProjectTypes(dir); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("DetectProjectTypes() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDetectWorkspaceRootsRecordsProjectTypes(t *testing.T) {
+	dir := t.TempDir()
+	writeMarkers(t, dir, "api/go.mod", "api/package.json", "site/Gemfile")
+
+	roots := DetectWorkspaceRoots(dir)
+	if got := roots["api"].ProjectTypes; !reflect.DeepEqual(got, []ProjectType{ProjectGo, ProjectNode}) {
+		t.Errorf("api root types = %v, want go and node", got)
+	}
+	if got := roots["site"].ProjectTypes; !reflect.DeepEqual(got, []ProjectType{ProjectRuby}) {
+		t.Errorf("site root types = %v, want ruby, the Gemfile marking a root", got)
+	}
+}
+
+func TestProjectTypes(t *testing.T) {
+	goDir, railsDir, emptyDir := t.TempDir(), t.TempDir(), t.TempDir()
+	writeMarkers(t, goDir, "go.mod", "web/package.json")
+	writeMarkers(t, railsDir, "Gemfile")
+
+	cfg := NewTestConfig(WithWorkingDir(goDir))
+	defer current.Store(nil)
+	if got := ProjectTypes(); !reflect.DeepEqual(got, []ProjectType{ProjectGo}) {
+		t.Errorf("ProjectTypes() = %v, want go, discovery stopping at the root", got)
+	}
+	if got := ProjectSummary(); got != "Go (go.mod) project" {
+		t.Errorf("ProjectSummary() = %q", got)
+	}
+
+	t.Run("DetectsAgainWhenWorkingDirChanges", func(t *testing.T) {
+		cfg.WorkingDir = railsDir
+		if got := ProjectTypes(); !reflect.DeepEqual(got, []ProjectType{ProjectRuby}) {
+			t.Errorf("ProjectTypes() = %v, want ruby", got)
+		}
+		if !ProjectUsesLanguage("Solargraph") || ProjectUsesLanguage("gopls") {
+			t.Error("Only the LSP servers of ruby should be preselected")
+		}
+
+		cfg.WorkingDir = emptyDir
+		if got := ProjectTypes(); len(got) != 0 {
+			t.Errorf("ProjectTypes() = %v, want none", got)
+		}
+		if got := ProjectSummary(); got != "" {
+			t.Errorf("ProjectSummary() = %q, want none", got)
+		}
+		if !ProjectUsesLanguage("gopls") {
+			t.Error("Every LSP server should be used when no stack is known")
+		}
+	})
+
+	t.Run("ConfigOverridesDetection", func(t *testing.T) {
+		cfg.WorkingDir = goDir
+		cfg.ProjectTypes = []ProjectType{" Python", "elixir", "go", "GO"}
+		normalizeProjectTypes(cfg)
+		defer func() { cfg.ProjectTypes = nil }()
+
+		expected := []ProjectType{ProjectGo, ProjectPython, "elixir"}
+		if got := ProjectTypes(); !reflect.DeepEqual(got, expected) {
+			t.Errorf("ProjectTypes() = %v, want %v", got, expected)
+		}
+		if got := ProjectSummary(); got != "Go (go.mod), Python (pyproject.toml) and elixir project" {
+			t.Errorf("ProjectSummary() = %q", got)
+		}
+		if !ProjectUsesLanguage("elixir") || !ProjectUsesLanguage("pyright") || ProjectUsesLanguage("typescript") {
+			t.Error("The LSP servers of the configured stacks should be preselected")
+		}
+	})
+}
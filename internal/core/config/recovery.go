@@ -0,0 +1,138 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// parseErrorContext is the number of lines shown before the offending one
+const parseErrorContext = 2
+
+// ParseError is returned by Load when the global config file is not valid
+// JSON, such as after a stray comma or a truncated write
+type ParseError struct {
+	Path string
+	// Line and Column are 1-based, Column counting bytes
+	Line   int
+	Column int
+	// Snippet shows the lines around the offending one, with a caret under
+	// the column
+	Snippet string
+	Err     error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("failed to parse config file %s at line %d, column %d: %v\n%s", e.Path, e.Line, e.Column, e.Err, e.Snippet)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// parseConfigFile checks that the config file at path is valid JSON,
+// returning a *ParseError locating the problem when it is not
+func parseConfigFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	var v any
+	err = json.Unmarshal(data, &v)
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		return err
+	}
+	truncated := strings.Contains(syntaxErr.Error(), "unexpected end")
+	line, column := position(data, int(syntaxErr.Offset), truncated)
+	return &ParseError{
+		Path:    path,
+		Line:    line,
+		Column:  column,
+		Snippet: snippet(data, line, column),
+		Err:     err,
+	}
+}
+
+// position returns the line and column of the byte of data a syntax error
+// was found after reading offset bytes. When the input ended early, it is
+// the position past its content, where it was cut.
+func position(data []byte, offset int, truncated bool) (int, int) {
+	before := data[:min(max(offset-1, 0), len(data))]
+	if truncated {
+		before = bytes.TrimRight(data, " \t\r\n")
+	}
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
+
+// snippet renders the lines of data up to line, numbered, with a caret under
+// column
+func snippet(data []byte, line, column int) string {
+	lines := strings.Split(string(data), "\n")
+	var b strings.Builder
+	width := len(fmt.Sprint(line))
+	for i := max(line-parseErrorContext, 1); i <= min(line, len(lines)); i++ {
+		fmt.Fprintf(&b, "  %*d | %s\n", width, i, strings.TrimRight(lines[i-1], "\r"))
+	}
+	fmt.Fprintf(&b, "  %*s | %s^", width, "", strings.Repeat(" ", max(column-1, 0)))
+	return b.String()
+}
+
+// BackupBrokenConfig moves the config file of err aside to
+// <path>.broken-<timestamp>, so the next Load starts from the defaults and
+// the environment, and returns the path of the backup
+func BackupBrokenConfig(err *ParseError) (string, error) {
+	backup := fmt.Sprintf("%s.broken-%s", err.Path, time.Now().Format("20060102-150405"))
+	if err := os.Rename(err.Path, backup); err != nil {
+		return "", fmt.Errorf("failed to back up the broken config file: %w", err)
+	}
+	return backup, nil
+}
+
+// Reload drops the loaded configuration and loads it again, as after the
+// broken config file was backed up
+func Reload(workingDir string, debug bool) (*Config, error) {
+	updateMu.Lock()
+	viper.Reset()
+	current.Store(nil)
+	updateMu.Unlock()
+	return Load(workingDir, debug)
+}
+
+// writeFileAtomic replaces the file at path with data. The data is written
+// and synced to a temporary file renamed over path, so the file is never
+// left partly written. An existing file keeps its permissions.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+)
+
+const validConfig = `{
+  "tui": {
+    "theme": "dracula"
+  },
+  "autoCompact": true
+}
+`
+
+// writeGlobalConfig writes data as the global config file of an empty home
+// directory and returns its path
+func writeGlobalConfig(t *testing.T, data string) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("OPENAI_API_KEY", "test-key-from-env")
+	viper.Reset()
+	current.Store(nil)
+	t.Cleanup(func() {
+		viper.Reset()
+		current.Store(nil)
+	})
+	path := filepath.Join(home, ".intelligence-interface.json")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("Failed to write the config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadBrokenConfig(t *testing.T) {
+	// A stray comma in the middle of the file
+	path := writeGlobalConfig(t, strings.Replace(validConfig, `"dracula"`, `"dracula",,`, 1))
+	wd := t.TempDir()
+
+	_, err := Load(wd, false)
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("Load() error = %v, want a *ParseError", err)
+	}
+	if parseErr.Path != path || parseErr.Line != 3 || parseErr.Column != 24 {
+		t.Errorf("ParseError at %s:%d:%d, want %s:3:24", parseErr.Path, parseErr.Line, parseErr.Column, path)
+	}
+	wantSnippet := "  1 | {\n" +
+		`  2 |   "tui": {` + "\n" +
+		`  3 |     "theme": "dracula",,` + "\n" +
+		"    |                        ^"
+	if parseErr.Snippet != wantSnippet {
+		t.Errorf("Snippet =\n%s\nwant\n%s", parseErr.Snippet, wantSnippet)
+	}
+	if !strings.Contains(err.Error(), path) || !strings.Contains(err.Error(), "line 3, column 24") {
+		t.Errorf("Error() = %q, want the path, line and column", err.Error())
+	}
+
+	// The safe start backs the file up and loads the defaults and environment
+	backup, err := BackupBrokenConfig(parseErr)
+	if err != nil {
+		t.Fatalf("BackupBrokenConfig() error = %v", err)
+	}
+	if !strings.HasPrefix(backup, path+".broken-") {
+		t.Errorf("backup = %s, want %s.broken-<timestamp>", backup, path)
+	}
+	if data, err := os.ReadFile(backup); err != nil || !strings.Contains(string(data), `"dracula",,`) {
+		t.Errorf("backup content = %q, %v, want the broken file", data, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("The broken config file is still at %s", path)
+	}
+
+	cfg, err := Reload(wd, false)
+	if err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if cfg.TUI.Theme == "dracula" {
+		t.Error("Reload() read the broken config file")
+	}
+	if key := cfg.Providers[models.ProviderOpenAI].APIKey; key != "test-key-from-env" {
+		t.Errorf("openai key = %q, want the one of the environment", key)
+	}
+}
+
+func TestParseConfigFileTruncated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	// A write cut in the middle of the file
+	os.WriteFile(path, []byte(validConfig[:strings.Index(validConfig, "dracula")+3]), 0o644)
+
+	var parseErr *ParseError
+	if err := parseConfigFile(path); !errors.As(err, &parseErr) {
+		t.Fatalf("parseConfigFile() error = %v, want a *ParseError", err)
+	}
+	if parseErr.Line != 3 || parseErr.Column != 18 {
+		t.Errorf("ParseError at %d:%d, want 3:18, past the cut", parseErr.Line, parseErr.Column)
+	}
+	if !strings.Contains(parseErr.Err.Error(), "unexpected end") {
+		t.Errorf("Err = %v, want the unexpected end of the input", parseErr.Err)
+	}
+
+	os.WriteFile(path, []byte(validConfig), 0o644)
+	if err := parseConfigFile(path); err != nil {
+		t.Errorf("parseConfigFile() of a valid file error = %v", err)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	os.WriteFile(path, []byte(validConfig), 0o600)
+
+	if err := writeFileAtomic(path, []byte(`{}`), 0o644); err != nil {
+		t.Fatalf("writeFileAtomic() error = %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != `{}` {
+		t.Errorf("content = %q, want {}", data)
+	}
+	// The file keeps its permissions, which may protect API keys
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("The temporary file was left in %s", dir)
+	}
+
+	// A new file takes perm
+	created := filepath.Join(dir, "new.json")
+	if err := writeFileAtomic(created, []byte(`{}`), 0o644); err != nil {
+		t.Fatalf("writeFileAtomic() error = %v", err)
+	}
+	if info, _ := os.Stat(created); info.Mode().Perm() != 0o644 {
+		t.Errorf("mode = %v, want 0644", info.Mode().Perm())
+	}
+}
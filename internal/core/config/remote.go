@@ -0,0 +1,81 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+)
+
+// RemoteConfig defines the optional local HTTP API used to control a running
+// instance from scripts or other machines.
+type RemoteConfig struct {
+	// Enabled starts the HTTP API alongside the TUI
+	Enabled bool `json:"enabled,omitempty"`
+	// Host is the address the API binds to, 127.0.0.1 unless set
+	Host string `json:"host,omitempty"`
+	// Port is the TCP port the API listens on
+	Port int `json:"port,omitempty"`
+	// TokenRotation replaces the bearer token after this duration, e.g. "24h".
+	// Leave empty to keep the token until the token file is deleted.
+	TokenRotation string `json:"tokenRotation,omitempty"`
+}
+
+const (
+	// RemoteTokenFilename is the file in the data directory holding the bearer token of the HTTP API
+	RemoteTokenFilename = "remote-token"
+
+	defaultRemoteHost = "127.0.0.1"
+	defaultRemotePort = 7420
+
+	minRemoteTokenRotation = time.Minute
+)
+
+// RotationInterval returns the parsed token rotation interval, 0 if the token never rotates.
+func (r RemoteConfig) RotationInterval() time.Duration {
+	interval, err := time.ParseDuration(r.TokenRotation)
+	if err != nil {
+		return 0
+	}
+	return interval
+}
+
+// Address returns the host:port the HTTP API listens on.
+func (r RemoteConfig) Address() string {
+	return net.JoinHostPort(r.Host, fmt.Sprint(r.Port))
+}
+
+// validateRemote applies the remote API defaults and rejects invalid addresses
+// and rotation intervals.
+func validateRemote(cfg *Config) error {
+	remote := &cfg.Remote
+	if remote.Host == "" {
+		remote.Host = defaultRemoteHost
+	}
+	if remote.Port == 0 {
+		remote.Port = defaultRemotePort
+	}
+
+	ip := net.ParseIP(remote.Host)
+	if ip == nil && remote.Host != "localhost" {
+		return fmt.Errorf("invalid remote host %q: must be an IP address or localhost", remote.Host)
+	}
+	if remote.Port < 1 || remote.Port > 65535 {
+		return fmt.Errorf("invalid remote port %d: must be between 1 and 65535", remote.Port)
+	}
+	if remote.TokenRotation != "" {
+		interval, err := time.ParseDuration(remote.TokenRotation)
+		if err != nil {
+			return fmt.Errorf("invalid remote token rotation %q: %w", remote.TokenRotation, err)
+		}
+		if interval < minRemoteTokenRotation {
+			return fmt.Errorf("invalid remote token rotation %q: must be at least %s", remote.TokenRotation, minRemoteTokenRotation)
+		}
+	}
+
+	if remote.Enabled && ip != nil && !ip.IsLoopback() {
+		logging.Warn("remote API is reachable from other machines", "host", remote.Host)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/viper"
+
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+)
+
+// GlobalConfigFile returns the global config file read by Load, or
+// ~/.intelligence-interface.json when there is none
+func GlobalConfigFile() (string, error) {
+	if configFile := viper.ConfigFileUsed(); configFile != "" {
+		return configFile, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, fmt.Sprintf(".%s.json", appName)), nil
+}
+
+// Defaults returns the configuration Load gives without config files nor
+// environment variables. It resets the settings viper read, so it is meant
+// for the commands done with the loaded configuration.
+func Defaults() (*Config, error) {
+	updateMu.Lock()
+	defer updateMu.Unlock()
+
+	viper.Reset()
+	setDefaults(false)
+
+	cfg := &Config{
+		MCPServers: make(map[string]MCPServer),
+		Providers:  make(map[models.ModelProvider]Provider),
+		LSP:        make(map[string]LSPConfig),
+		Spaces:     make(map[string]SpaceConfig),
+	}
+	if err := viper.Unmarshal(cfg, withAutoCompactDecodeHook); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	applyDefaultValues(cfg)
+	if err := validate(cfg); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+	return cfg, nil
+}
+
+// WriteDefaults replaces the config file at path with the configuration of
+// Defaults
+func WriteDefaults(path string) error {
+	cfg, err := Defaults()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := writeFileAtomic(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package config
+
+import "fmt"
+
+const (
+	// DefaultReviewMaxRevisions is the number of revisions of a reviewed
+	// response when unset
+	DefaultReviewMaxRevisions = 1
+	// DefaultReviewPassTokenBudget bounds the tokens of each pass of a review
+	// when unset
+	DefaultReviewPassTokenBudget = 16000
+	// maxReviewRevisions bounds the configurable revision rounds
+	maxReviewRevisions = 5
+)
+
+// DefaultReviewChecklist is what the reviewer checks a response against when
+// no checklist is configured
+const DefaultReviewChecklist = `- The change does what was asked, completely
+- The change has no bugs, unhandled errors or edge cases left out
+- The change follows the conventions of the surrounding code
+- Tests cover the change where the project has tests
+- The response describes the change accurately`
+
+// ReviewFlow has the responses of an agent critiqued by a reviewer against a
+// checklist, then revised by the agent, before they are presented
+type ReviewFlow struct {
+	Enabled bool `json:"enabled"`
+	// Reviewer is the agent critiquing the responses, the reviewed agent
+	// itself when unset
+	Reviewer AgentName `json:"reviewer,omitempty"`
+	// Checklist is what the reviewer checks the responses against
+	Checklist string `json:"checklist,omitempty"`
+	// MaxRevisions bounds the critique and revision rounds
+	MaxRevisions int `json:"maxRevisions,omitempty"`
+	// PassTokenBudget bounds the tokens each pass generates, the flow stops
+	// once a pass goes over it
+	PassTokenBudget int64 `json:"passTokenBudget,omitempty"`
+}
+
+// ReviewerAgent returns the agent reviewing the responses of agent
+func (r ReviewFlow) ReviewerAgent(agent AgentName) AgentName {
+	if r.Reviewer == "" {
+		return agent
+	}
+	return r.Reviewer
+}
+
+// ChecklistPrompt returns the checklist of the reviewer
+func (r ReviewFlow) ChecklistPrompt() string {
+	if r.Checklist == "" {
+		return DefaultReviewChecklist
+	}
+	return r.Checklist
+}
+
+// Revisions returns the maximum number of revisions
+func (r ReviewFlow) Revisions() int {
+	if r.MaxRevisions <= 0 {
+		return DefaultReviewMaxRevisions
+	}
+	return r.MaxRevisions
+}
+
+// TokenBudget returns the token budget of each pass
+func (r ReviewFlow) TokenBudget() int64 {
+	if r.PassTokenBudget <= 0 {
+		return DefaultReviewPassTokenBudget
+	}
+	return r.PassTokenBudget
+}
+
+// validate checks the review flow of an agent against the configured agents
+func (r ReviewFlow) validate(cfg *Config) error {
+	if !r.Enabled {
+		return nil
+	}
+	if _, ok := cfg.ResolveAgent(string(r.Reviewer)); r.Reviewer != "" && !ok {
+		return unknownAgent(cfg, "reviewFlow.reviewer", string(r.Reviewer))
+	}
+	if r.MaxRevisions < 0 || r.MaxRevisions > maxReviewRevisions {
+		return fmt.Errorf("maxRevisions must be between 0 and %d", maxReviewRevisions)
+	}
+	if r.PassTokenBudget < 0 {
+		return fmt.Errorf("passTokenBudget must be positive")
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+const (
+	// DefaultScratchpadPromptTokens bounds the scratchpad included in the
+	// requests of a session
+	DefaultScratchpadPromptTokens = 800
+	// DefaultScratchpadRevisions is the number of revisions of a scratchpad
+	// kept to revert to
+	DefaultScratchpadRevisions = 10
+)
+
+// ScratchpadConfig sets the scratchpad of the sessions: the working notes
+// agents keep apart from the conversation
+type ScratchpadConfig struct {
+	// Disabled keeps the scratchpad out of the requests and its tools away
+	// from the agents
+	Disabled bool `json:"disabled,omitempty"`
+	// PromptTokens bounds the scratchpad included in the requests, the
+	// sections left out being listed by title
+	PromptTokens int `json:"promptTokens,omitempty"`
+	// Revisions is the number of revisions kept, the current one included
+	Revisions int `json:"revisions,omitempty"`
+}
+
+// PromptBudget returns the tokens of scratchpad included in the requests
+func (s ScratchpadConfig) PromptBudget() int {
+	if s.PromptTokens <= 0 {
+		return DefaultScratchpadPromptTokens
+	}
+	return s.PromptTokens
+}
+
+// RevisionLimit returns the number of revisions of a scratchpad kept
+func (s ScratchpadConfig) RevisionLimit() int {
+	if s.Revisions <= 0 {
+		return DefaultScratchpadRevisions
+	}
+	return s.Revisions
+}
+
+// validate checks the scratchpad settings
+func (s ScratchpadConfig) validate() error {
+	if s.PromptTokens < 0 {
+		return fmt.Errorf("promptTokens must be positive")
+	}
+	if s.Revisions < 0 {
+		return fmt.Errorf("revisions must be positive")
+	}
+	return nil
+}
+
+// ScratchpadFile returns the file holding the scratchpad of a session, in the
+// directory of the session
+func ScratchpadFile(sessionID string) string {
+	return filepath.Join(SessionDirectory(sessionID), "scratchpad.json")
+}
@@ -0,0 +1,281 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+)
+
+// envNamePattern matches the names environment variables may have
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+var (
+	spaceMu     sync.RWMutex
+	activeSpace string
+)
+
+// validateSpaceEnvironment rejects the variables of a space whose name cannot
+// be set in an environment. Values are never logged, they may be secrets.
+func validateSpaceEnvironment(spaceID string, space SpaceConfig) error {
+	for _, name := range sortedVariableNames(space.Environment) {
+		if !envNamePattern.MatchString(name) {
+			return fmt.Errorf("space %q: invalid environment variable name %q: must be letters, digits and underscores, not starting with a digit", spaceID, name)
+		}
+		if err := validateKeyringRef(spaceID, name, space.Environment[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSessionDefaults checks the agent, model and generation parameters
+// the sessions of a space start with, resolving the agent to its canonical
+// name. Their tools and sources of context are checked by the agent running
+// the sessions, which knows them.
+func validateSessionDefaults(cfg *Config, spaceID string, defaults *SessionDefaults) error {
+	if defaults == nil {
+		return nil
+	}
+	field := fmt.Sprintf("spaces.%s.default_session", spaceID)
+	if defaults.Agent != "" {
+		name, ok := cfg.ResolveAgent(string(defaults.Agent))
+		if !ok {
+			return unknownAgent(cfg, field+".agent", string(defaults.Agent))
+		}
+		defaults.Agent = name
+	}
+	if defaults.Model != "" {
+		if _, ok := models.SupportedModels[defaults.Model]; !ok {
+			ids := make([]string, 0, len(models.SupportedModels))
+			for id := range models.SupportedModels {
+				ids = append(ids, string(id))
+			}
+			return &ValidationError{
+				Field:      field + ".model",
+				Value:      string(defaults.Model),
+				Reason:     "unknown model",
+				Suggestion: nearestName(string(defaults.Model), ids),
+			}
+		}
+	}
+	if defaults.Generation != nil {
+		if err := defaults.Generation.Validate(); err != nil {
+			return fmt.Errorf("%s.generation: %w", field, err)
+		}
+	}
+	return nil
+}
+
+// SpaceSessionDefaults returns the settings the sessions created in a space
+// start with, nil when the space sets none
+func SpaceSessionDefaults(spaceID string) *SessionDefaults {
+	cfg := Get()
+	if spaceID == "" || cfg == nil {
+		return nil
+	}
+	return cfg.Spaces[spaceID].DefaultSession
+}
+
+// resolveSpaceAgents returns the agents assigned to a space by their
+// canonical names, and the errors of the ones that are not configured
+func resolveSpaceAgents(cfg *Config, spaceID string, space SpaceConfig) ([]string, []*ValidationError) {
+	var resolved []string
+	var unresolved []*ValidationError
+	for _, agent := range space.AssignedAgents {
+		name, ok := cfg.ResolveAgent(agent)
+		if !ok {
+			unresolved = append(unresolved, unknownAgent(cfg, fmt.Sprintf("spaces.%s.assigned_agents", spaceID), agent))
+			continue
+		}
+		if !slices.Contains(resolved, string(name)) {
+			resolved = append(resolved, string(name))
+		}
+	}
+	return resolved, unresolved
+}
+
+// CreateSpace adds a space to the configuration and writes it to the config
+// file. The space is validated as at load time, and nothing is written when it
+// is invalid or its ID is taken.
+func CreateSpace(space SpaceConfig) error {
+	if Get() == nil {
+		return fmt.Errorf("config not loaded")
+	}
+	if space.ID == "" {
+		return fmt.Errorf("the space ID is required")
+	}
+	if space.Name == "" {
+		space.Name = fmt.Sprintf("Space %s", space.ID)
+	}
+	if space.Type == "" {
+		space.Type = "custom"
+	}
+
+	err := Update(func(cfg *Config) error {
+		if _, ok := cfg.Spaces[space.ID]; ok {
+			return fmt.Errorf("space %q already exists", space.ID)
+		}
+		if !slices.Contains(SpaceTypes, space.Type) {
+			return &ValidationError{
+				Field:      fmt.Sprintf("spaces.%s.type", space.ID),
+				Value:      space.Type,
+				Reason:     "unknown space type",
+				Suggestion: nearestName(space.Type, SpaceTypes),
+			}
+		}
+		if err := validateSpaceEnvironment(space.ID, space); err != nil {
+			return err
+		}
+		if err := validateSessionDefaults(cfg, space.ID, space.DefaultSession); err != nil {
+			return err
+		}
+		// A space would silently run without the agents it misnames
+		agents, unresolved := resolveSpaceAgents(cfg, space.ID, space)
+		if len(unresolved) > 0 {
+			return unresolved[0]
+		}
+		space.AssignedAgents = agents
+		if cfg.Spaces == nil {
+			cfg.Spaces = make(map[string]SpaceConfig)
+		}
+		cfg.Spaces[space.ID] = space
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return updateCfgFile(func(config *Config) {
+		if config.Spaces == nil {
+			config.Spaces = make(map[string]SpaceConfig)
+		}
+		config.Spaces[space.ID] = space
+	})
+}
+
+// DeleteSpace removes a space from the configuration and the config file,
+// deactivating it when it is active
+func DeleteSpace(id string) error {
+	if Get() == nil {
+		return fmt.Errorf("config not loaded")
+	}
+	err := Update(func(cfg *Config) error {
+		if _, ok := cfg.Spaces[id]; !ok {
+			return fmt.Errorf("unknown space: %s", id)
+		}
+		delete(cfg.Spaces, id)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	spaceMu.Lock()
+	if activeSpace == id {
+		activeSpace = ""
+	}
+	spaceMu.Unlock()
+
+	return updateCfgFile(func(config *Config) {
+		delete(config.Spaces, id)
+	})
+}
+
+// SpaceIDs returns the IDs of the configured spaces in sorted order.
+func SpaceIDs() []string {
+	cfg := Get()
+	if cfg == nil {
+		return nil
+	}
+	ids := make([]string, 0, len(cfg.Spaces))
+	for id := range cfg.Spaces {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// ActiveSpace returns the ID of the active space, or "" when no space is active.
+func ActiveSpace() string {
+	spaceMu.RLock()
+	defer spaceMu.RUnlock()
+	return activeSpace
+}
+
+// SetActiveSpace activates the space whose environment tools run with. An
+// empty ID deactivates the active space.
+func SetActiveSpace(id string) error {
+	if id != "" {
+		if _, ok := Get().Spaces[id]; !ok {
+			return fmt.Errorf("unknown space: %s", id)
+		}
+	}
+	spaceMu.Lock()
+	defer spaceMu.Unlock()
+	activeSpace = id
+	return nil
+}
+
+// SpaceVariables returns the environment variables of the active space, nil
+// when no space is active. The variables referencing the keyring are set to
+// the secret read from it, and left out when it cannot be read.
+func SpaceVariables() map[string]string {
+	id := ActiveSpace()
+	cfg := Get()
+	if id == "" || cfg == nil {
+		return nil
+	}
+	environment := cfg.Spaces[id].Environment
+	variables := make(map[string]string, len(environment))
+	for name, value := range environment {
+		resolved, err := resolveKeyringRef(value)
+		if err != nil {
+			logging.Warn("Failed to read a space variable from the keyring", "space", id, "variable", name, "error", err)
+			continue
+		}
+		variables[name] = resolved
+	}
+	return variables
+}
+
+// SpaceEnviron returns the environment variables of the active space as
+// "name=value" entries sorted by name, to be appended to the process
+// environment so the space values win.
+func SpaceEnviron() []string {
+	variables := SpaceVariables()
+	environ := make([]string, 0, len(variables))
+	for _, name := range sortedVariableNames(variables) {
+		environ = append(environ, name+"="+variables[name])
+	}
+	return environ
+}
+
+// SpaceVariableNames returns the names of the variables any space defines, in
+// sorted order.
+func SpaceVariableNames() []string {
+	cfg := Get()
+	if cfg == nil {
+		return nil
+	}
+	names := make(map[string]string)
+	for _, space := range cfg.Spaces {
+		for name := range space.Environment {
+			names[name] = ""
+		}
+	}
+	return sortedVariableNames(names)
+}
+
+func sortedVariableNames(variables map[string]string) []string {
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,285 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+)
+
+func TestSpaceEnvironment(t *testing.T) {
+	cfg := &Config{
+		Spaces: map[string]SpaceConfig{
+			"alpha": {ID: "alpha", Name: "Alpha", Environment: map[string]string{"DATABASE_URL": "postgres://alpha", "ALPHA_ONLY": "1"}},
+			"beta":  {ID: "beta", Name: "Beta", Environment: map[string]string{"DATABASE_URL": "postgres://beta"}},
+		},
+	}
+	current.Store(cfg)
+	defer func() {
+		SetActiveSpace("")
+		current.Store(nil)
+	}()
+
+	t.Run("NoActiveSpace", func(t *testing.T) {
+		if environ := SpaceEnviron(); len(environ) != 0 {
+			t.Errorf("SpaceEnviron() = %v without an active space, want none", environ)
+		}
+	})
+
+	t.Run("ActiveSpace", func(t *testing.T) {
+		if err := SetActiveSpace("alpha"); err != nil {
+			t.Fatalf("SetActiveSpace() error = %v", err)
+		}
+		got := strings.Join(SpaceEnviron(), ",")
+		if got != "ALPHA_ONLY=1,DATABASE_URL=postgres://alpha" {
+			t.Errorf("SpaceEnviron() = %s", got)
+		}
+
+		SetActiveSpace("beta")
+		if got := SpaceVariables(); len(got) != 1 || got["DATABASE_URL"] != "postgres://beta" {
+			t.Errorf("SpaceVariables() = %v, want only the beta variables", got)
+		}
+	})
+
+	t.Run("UnknownSpace", func(t *testing.T) {
+		SetActiveSpace("beta")
+		if err := SetActiveSpace("gamma"); err == nil {
+			t.Error("SetActiveSpace() accepted an unknown space")
+		}
+		if ActiveSpace() != "beta" {
+			t.Errorf("ActiveSpace() = %q after a failed switch, want beta", ActiveSpace())
+		}
+	})
+
+	t.Run("VariableNames", func(t *testing.T) {
+		if got := strings.Join(SpaceVariableNames(), ","); got != "ALPHA_ONLY,DATABASE_URL" {
+			t.Errorf("SpaceVariableNames() = %s", got)
+		}
+	})
+}
+
+func TestSpaceKeyringVariables(t *testing.T) {
+	lookups := 0
+	keyringLookup = func(service, account string) (string, error) {
+		lookups++
+		if service != "deploy" || account != "token" {
+			return "", fmt.Errorf("no secret for %s/%s", service, account)
+		}
+		return "s3cr3t-from-keyring", nil
+	}
+	defer func() {
+		keyringLookup = lookupKeyring
+		keyringSecrets = make(map[string]string)
+		SetActiveSpace("")
+		current.Store(nil)
+	}()
+	NewTestConfig(WithSpace(SpaceConfig{ID: "alpha", Environment: map[string]string{
+		"API_TOKEN": "keyring:deploy/token",
+		"MISSING":   "keyring:other",
+		"REGION":    "eu-west-1",
+	}}))
+	if err := SetActiveSpace("alpha"); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		variables := SpaceVariables()
+		if variables["API_TOKEN"] != "s3cr3t-from-keyring" || variables["REGION"] != "eu-west-1" {
+			t.Errorf("SpaceVariables() = %v, want the token read from the keyring", variables)
+		}
+		if _, ok := variables["MISSING"]; ok {
+			t.Error("SpaceVariables() sets the variable whose secret could not be read")
+		}
+	}
+	if lookups != 3 {
+		t.Errorf("the keyring was asked %d times, want once per reference and again for the missing secret", lookups)
+	}
+	if got := logging.Redact(`token="s3cr3t-from-keyring"`); got != `token="`+logging.Redacted+`"` {
+		t.Errorf("Redact() = %s, want the secret read from the keyring redacted", got)
+	}
+}
+
+func TestValidateSpaceEnvironment(t *testing.T) {
+	for _, ref := range []string{"keyring:", "keyring:/token", "keyring:deploy/"} {
+		if err := validateSpaceEnvironment("dev", SpaceConfig{Environment: map[string]string{"TOKEN": ref}}); err == nil {
+			t.Errorf("validateSpaceEnvironment() accepted the keyring reference %q", ref)
+		}
+	}
+	if err := validateSpaceEnvironment("dev", SpaceConfig{Environment: map[string]string{"TOKEN": "keyring:token"}}); err != nil {
+		t.Errorf("validateSpaceEnvironment() rejected a keyring reference: %v", err)
+	}
+	for _, name := range []string{"DATABASE_URL", "_private", "a1"} {
+		if err := validateSpaceEnvironment("dev", SpaceConfig{Environment: map[string]string{name: "value"}}); err != nil {
+			t.Errorf("validateSpaceEnvironment() rejected %q: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "1ST", "MY-VAR", "A=B", "WITH SPACE"} {
+		err := validateSpaceEnvironment("dev", SpaceConfig{Environment: map[string]string{name: "secret-value"}})
+		if err == nil {
+			t.Errorf("validateSpaceEnvironment() accepted %q", name)
+			continue
+		}
+		if strings.Contains(err.Error(), "secret-value") {
+			t.Errorf("validation error reveals the variable value: %v", err)
+		}
+	}
+}
+
+func TestValidateSpaceTypes(t *testing.T) {
+	tests := []struct {
+		spaceType string
+		want      string
+	}{
+		{"", ""},
+		{"development", "development"},
+		{"knowledge_base", "knowledge_base"},
+		{"social", "social"},
+		{"communication", "communication"},
+		{"creative", "creative"},
+		{"analysis", "analysis"},
+		{"custom", "custom"},
+		{"unknown", "custom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spaceType, func(t *testing.T) {
+			cfg := &Config{Spaces: map[string]SpaceConfig{
+				"dev": {ID: "dev", Name: "Dev", Type: tt.spaceType},
+			}}
+			if err := validateSpaceConfigs(cfg); err != nil {
+				t.Fatalf("validateSpaceConfigs() error = %v", err)
+			}
+			if got := cfg.Spaces["dev"].Type; got != tt.want {
+				t.Errorf("space type %q validated as %q, want %q", tt.spaceType, got, tt.want)
+			}
+		})
+	}
+
+	// The types offered by the space tool all pass validation
+	for _, spaceType := range SpaceTypes {
+		cfg := &Config{Spaces: map[string]SpaceConfig{"dev": {ID: "dev", Name: "Dev", Type: spaceType}}}
+		validateSpaceConfigs(cfg)
+		if got := cfg.Spaces["dev"].Type; got != spaceType {
+			t.Errorf("space type %q validated as %q", spaceType, got)
+		}
+	}
+}
+
+func TestValidateSpaceAgents(t *testing.T) {
+	cfg := &Config{
+		Agents: map[AgentName]Agent{"coder": {}, "task": {}, AgentCaronex: {}},
+		Spaces: map[string]SpaceConfig{
+			"dev": {ID: "dev", Name: "Dev", AssignedAgents: []string{"coder", "task"}},
+		},
+	}
+	if err := validateSpaceConfigs(cfg); err != nil {
+		t.Fatalf("validateSpaceConfigs() error = %v", err)
+	}
+	if got := strings.Join(ValidAgentNames(cfg), ","); got != "caronex,coder,task" {
+		t.Errorf("ValidAgentNames() = %s, want caronex,coder,task", got)
+	}
+
+	// Agents assigned in any case resolve to their canonical names
+	cfg.Spaces["dev"] = SpaceConfig{ID: "dev", Name: "Dev", AssignedAgents: []string{"Coder", "TASK", "task"}}
+	if err := validateSpaceConfigs(cfg); err != nil {
+		t.Fatalf("validateSpaceConfigs() error = %v", err)
+	}
+	if got := strings.Join(cfg.Spaces["dev"].AssignedAgents, ","); got != "coder,task" {
+		t.Errorf("assigned agents = %s, want coder,task", got)
+	}
+
+	// Unknown agents are dropped with a warning suggesting the agent meant
+	for agent, suggestion := range map[string]string{"codr": "coder", "Tsk": "task", "reviewer": ""} {
+		cfg.Warnings = nil
+		cfg.Spaces["dev"] = SpaceConfig{ID: "dev", Name: "Dev", AssignedAgents: []string{"coder", agent}}
+		if err := validateSpaceConfigs(cfg); err != nil {
+			t.Fatalf("validateSpaceConfigs() with agent %q error = %v", agent, err)
+		}
+		if got := strings.Join(cfg.Spaces["dev"].AssignedAgents, ","); got != "coder" {
+			t.Errorf("assigned agents with %q = %s, want coder", agent, got)
+		}
+		want := fmt.Sprintf("spaces.dev.assigned_agents: unknown agent %q", agent)
+		if suggestion != "" {
+			want += fmt.Sprintf(", did you mean %q?", suggestion)
+		}
+		if len(cfg.Warnings) != 1 || !strings.HasPrefix(cfg.Warnings[0], want) {
+			t.Errorf("warnings with agent %q = %q, want %q", agent, cfg.Warnings, want)
+		}
+	}
+}
+
+func TestValidateSessionDefaults(t *testing.T) {
+	temperature := 3.0
+	tests := []struct {
+		name     string
+		defaults SessionDefaults
+		want     string
+	}{
+		{"valid", SessionDefaults{Agent: "Coder", Model: models.TestFake, Tools: []string{"view"}}, ""},
+		{"unknown agent", SessionDefaults{Agent: "codr"}, `spaces.dev.default_session.agent: unknown agent "codr", did you mean "coder"?`},
+		{"unknown model", SessionDefaults{Model: "gpt-9"}, `spaces.dev.default_session.model: unknown model "gpt-9"`},
+		{"invalid generation", SessionDefaults{Generation: &GenerationParams{Temperature: &temperature}}, "spaces.dev.default_session.generation: temperature must be between 0 and 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Agents: map[AgentName]Agent{"coder": {}, AgentCaronex: {}},
+				Spaces: map[string]SpaceConfig{"dev": {ID: "dev", Name: "Dev", DefaultSession: &tt.defaults}},
+			}
+			err := validateSpaceConfigs(cfg)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("validateSpaceConfigs() error = %v", err)
+				}
+				if agent := cfg.Spaces["dev"].DefaultSession.Agent; agent != "coder" {
+					t.Errorf("default agent = %q, want its canonical name coder", agent)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("validateSpaceConfigs() error = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateSpace(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	viper.Reset()
+	NewTestConfig(WithWorkingDir(t.TempDir()))
+	defer func() {
+		current.Store(nil)
+		viper.Reset()
+	}()
+
+	err := CreateSpace(SpaceConfig{ID: "dev", AssignedAgents: []string{"caronx"}})
+	if err == nil || !strings.Contains(err.Error(), `did you mean "caronex"?`) {
+		t.Fatalf("CreateSpace() with a misspelled agent error = %v, want a suggestion", err)
+	}
+	if _, ok := Get().Spaces["dev"]; ok {
+		t.Fatal("CreateSpace() added an invalid space")
+	}
+	if _, err := os.Stat(filepath.Join(home, "."+appName+".json")); !os.IsNotExist(err) {
+		t.Fatalf("CreateSpace() wrote an invalid space to the config file: %v", err)
+	}
+
+	if err := CreateSpace(SpaceConfig{ID: "dev", AssignedAgents: []string{"caronex"}}); err != nil {
+		t.Fatalf("CreateSpace() error = %v", err)
+	}
+	if space := Get().Spaces["dev"]; space.Name != "Space dev" || space.Type != "custom" {
+		t.Errorf("created space = %+v, want the default name and type", space)
+	}
+	data, err := os.ReadFile(filepath.Join(home, "."+appName+".json"))
+	if err != nil || !strings.Contains(string(data), `"assigned_agents"`) {
+		t.Errorf("config file = %s, %v; want the created space", data, err)
+	}
+	if err := CreateSpace(SpaceConfig{ID: "dev"}); err == nil {
+		t.Error("CreateSpace() replaced an existing space")
+	}
+}
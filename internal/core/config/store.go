@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// current is the configuration snapshot Get returns, replaced as a whole
+	// by every change and never modified once published
+	current atomic.Pointer[Config]
+	// updateMu serializes the changes, so none is lost to a concurrent one
+	updateMu sync.Mutex
+)
+
+// Get returns the current configuration, nil when it is not loaded. The
+// configuration returned is a snapshot that must be treated as read-only:
+// changes go through Update, which publishes a new snapshot rather than
+// modifying this one. An operation should get the configuration once and use
+// that snapshot throughout, rather than calling Get again midway and seeing
+// another configuration.
+func Get() *Config {
+	return current.Load()
+}
+
+// Update applies fn to a copy of the current configuration and, unless fn
+// fails, makes the copy the current configuration and notifies the watchers.
+// Updates are applied one at a time. fn may replace the entries of the maps
+// of the configuration, but not modify the values they hold in place: those
+// are shared with the previous snapshot.
+func Update(fn func(cfg *Config) error) error {
+	if err := update(fn); err != nil {
+		return err
+	}
+	notifyWatchers()
+	return nil
+}
+
+func update(fn func(cfg *Config) error) error {
+	updateMu.Lock()
+	defer updateMu.Unlock()
+
+	cfg := Get()
+	if cfg == nil {
+		return fmt.Errorf("config not loaded")
+	}
+	next := cfg.clone()
+	if err := fn(next); err != nil {
+		return err
+	}
+	current.Store(next)
+	return nil
+}
+
+// clone returns a copy of the configuration whose maps and slices can be
+// changed without changing c
+func (c *Config) clone() *Config {
+	next := *c
+	next.MCPServers = maps.Clone(c.MCPServers)
+	next.Providers = maps.Clone(c.Providers)
+	next.LSP = maps.Clone(c.LSP)
+	next.Agents = maps.Clone(c.Agents)
+	next.Spaces = maps.Clone(c.Spaces)
+	next.Workspaces = maps.Clone(c.Workspaces)
+	next.ProviderSources = maps.Clone(c.ProviderSources)
+	for name, root := range next.Workspaces {
+		root.LSP = maps.Clone(root.LSP)
+		next.Workspaces[name] = root
+	}
+	next.ContextPaths = slices.Clone(c.ContextPaths)
+	next.Shell.Args = slices.Clone(c.Shell.Args)
+	next.Logging.ThrottleExempt = slices.Clone(c.Logging.ThrottleExempt)
+	return &next
+}
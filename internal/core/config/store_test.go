@@ -0,0 +1,113 @@
+package config
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestUpdateConcurrently(t *testing.T) {
+	NewTestConfig()
+	defer current.Store(nil)
+
+	const updaters, updates, readers = 4, 200, 4
+	var wg sync.WaitGroup
+	for u := range updaters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range updates {
+				// The agent and the theme change together, so a snapshot
+				// holding only one of them was torn by an update
+				n := u*updates + i + 1
+				err := Update(func(cfg *Config) error {
+					agent := cfg.Agents[AgentCaronex]
+					agent.MaxTokens = int64(n)
+					cfg.Agents[AgentCaronex] = agent
+					cfg.TUI.Theme = strconv.Itoa(n)
+					return nil
+				})
+				if err != nil {
+					t.Errorf("Update() error = %v", err)
+					return
+				}
+				temperature := 0.5
+				if err := OverrideAgentGeneration(AgentCaronex, GenerationParams{Temperature: &temperature}); err != nil {
+					t.Errorf("OverrideAgentGeneration() error = %v", err)
+					return
+				}
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	var readersWg sync.WaitGroup
+	for range readers {
+		readersWg.Add(1)
+		go func() {
+			defer readersWg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				cfg := Get()
+				maxTokens := cfg.Agents[AgentCaronex].MaxTokens
+				if cfg.TUI.Theme != "" && cfg.TUI.Theme != strconv.FormatInt(maxTokens, 10) {
+					t.Errorf("snapshot has theme %q and max tokens %d, want them from the same update", cfg.TUI.Theme, maxTokens)
+					return
+				}
+				_ = CurrentFingerprint()
+				_ = WorkspaceRoots()
+				_ = SpaceEnviron()
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(done)
+	readersWg.Wait()
+
+	if Get().TUI.Theme == "" {
+		t.Error("no update was applied")
+	}
+}
+
+func TestUpdateFailureKeepsSnapshot(t *testing.T) {
+	before := NewTestConfig()
+	defer current.Store(nil)
+
+	errInvalid := errors.New("invalid")
+	err := Update(func(cfg *Config) error {
+		cfg.TUI.Theme = "dracula"
+		cfg.Agents[AgentCaronex] = Agent{}
+		return errInvalid
+	})
+	if !errors.Is(err, errInvalid) {
+		t.Fatalf("Update() error = %v, want %v", err, errInvalid)
+	}
+	if Get() != before {
+		t.Error("a failed update replaced the snapshot")
+	}
+	if before.TUI.Theme == "dracula" || before.Agents[AgentCaronex].Model == "" {
+		t.Error("a failed update modified the snapshot")
+	}
+
+	err = Update(func(cfg *Config) error {
+		agent := cfg.Agents[AgentCaronex]
+		agent.MaxTokens = 42
+		cfg.Agents[AgentCaronex] = agent
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if Get().Agents[AgentCaronex].MaxTokens != 42 {
+		t.Error("the update was not applied")
+	}
+	if before.Agents[AgentCaronex].MaxTokens == 42 {
+		t.Error("an update modified the previous snapshot")
+	}
+}
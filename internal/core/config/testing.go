@@ -0,0 +1,113 @@
+package config
+
+import (
+	"os"
+
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+)
+
+// TestConfigOption customizes a configuration built by NewTestConfig.
+type TestConfigOption func(*Config)
+
+// NewTestConfig builds a configuration in memory and installs it as the global
+// configuration, without reading config files or environment variables. The
+// result matches the defaults applied by Load, except that every agent uses the
+// scripted test provider (providers.test) so nothing can reach a real API.
+func NewTestConfig(opts ...TestConfigOption) *Config {
+	testCfg := &Config{
+		Data:       Data{Directory: defaultDataDirectory},
+		WorkingDir: os.TempDir(),
+		MCPServers: make(map[string]MCPServer),
+		Providers: map[models.ModelProvider]Provider{
+			models.ProviderTest: {APIKey: "test"},
+		},
+		LSP: make(map[string]LSPConfig),
+		Agents: map[AgentName]Agent{
+			AgentCaronex: testAgent(),
+		},
+		Caronex: CaronexConfig{
+			Enabled: true,
+			Hotkey:  "ctrl+m",
+			Coordination: CoordinationConfig{
+				MaxConcurrentAgents:   10,
+				SpaceMemoryLimit:      "1GB",
+				EvolutionCycle:        "24h",
+				AgentSpawningEnabled:  true,
+				CommunicationProtocol: "pubsub",
+				QueueDepth:            DefaultQueueDepth,
+				MaxPriority:           DefaultMaxPriority,
+			},
+			SpaceManagement: SpaceManagementConfig{
+				MaxSpaces:              20,
+				DefaultSpaceTemplate:   "development",
+				SpaceIsolationLevel:    "standard",
+				AutoSpaceCleanup:       true,
+				SpacePersistencePolicy: "session",
+			},
+			Evolution: EvolutionConfig{
+				SafetyChecksEnabled: true,
+				RollbackCapability:  true,
+			},
+			Learning: LearningConfig{
+				Enabled:              true,
+				PatternRecognition:   true,
+				KnowledgeRetention:   "session",
+				AdaptationThreshold:  0.8,
+				LearningHistoryLimit: 1000,
+			},
+		},
+		Spaces:       make(map[string]SpaceConfig),
+		ContextPaths: defaultContextPaths,
+		Remote:       RemoteConfig{Host: defaultRemoteHost, Port: defaultRemotePort},
+		AutoCompact: AutoCompactConfig{
+			Enabled:        true,
+			ThresholdRatio: DefaultAutoCompactThresholdRatio,
+			MinMessages:    DefaultAutoCompactMinMessages,
+		},
+		Embeddings: EmbeddingsConfig{}.withDefaults(),
+	}
+	for _, opt := range opts {
+		opt(testCfg)
+	}
+
+	current.Store(testCfg)
+	return testCfg
+}
+
+// WithWorkingDir sets the working directory of a test configuration.
+func WithWorkingDir(dir string) TestConfigOption {
+	return func(c *Config) {
+		c.WorkingDir = dir
+	}
+}
+
+// WithDataDir sets the data directory of a test configuration.
+func WithDataDir(dir string) TestConfigOption {
+	return func(c *Config) {
+		c.Data.Directory = dir
+	}
+}
+
+// WithTestAgents adds agents that use the scripted test provider.
+func WithTestAgents(names ...AgentName) TestConfigOption {
+	return func(c *Config) {
+		for _, name := range names {
+			c.Agents[name] = testAgent()
+		}
+	}
+}
+
+// WithSpace adds a space to a test configuration.
+func WithSpace(space SpaceConfig) TestConfigOption {
+	return func(c *Config) {
+		c.Spaces[space.ID] = space
+	}
+}
+
+// testAgent is an agent configuration served by the scripted test provider.
+func testAgent() Agent {
+	return Agent{
+		Model:     models.TestFake,
+		MaxTokens: models.TestModels[models.TestFake].DefaultMaxTokens,
+	}
+}
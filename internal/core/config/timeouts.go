@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// ProviderTimeouts bound the requests to a provider. Each timeout is a
+// duration such as "30s", and the default is used when it is unset.
+type ProviderTimeouts struct {
+	// Connect bounds opening a connection, TLS handshake included
+	Connect string `json:"connect,omitempty"`
+	// FirstToken bounds the wait for the first token of a streamed response
+	FirstToken string `json:"firstToken,omitempty"`
+	// Idle bounds the wait between two chunks of a streamed response
+	Idle string `json:"idle,omitempty"`
+	// Request bounds a whole request, streaming included
+	Request string `json:"request,omitempty"`
+}
+
+const (
+	DefaultConnectTimeout    = 10 * time.Second
+	DefaultFirstTokenTimeout = 60 * time.Second
+	DefaultIdleTimeout       = 120 * time.Second
+	DefaultRequestTimeout    = 10 * time.Minute
+)
+
+// TimeoutDurations are the parsed timeouts of a provider
+type TimeoutDurations struct {
+	Connect    time.Duration
+	FirstToken time.Duration
+	Idle       time.Duration
+	Request    time.Duration
+}
+
+// Durations returns the parsed timeouts, the defaults replacing those unset
+// or invalid
+func (t ProviderTimeouts) Durations() TimeoutDurations {
+	parse := func(value string, fallback time.Duration) time.Duration {
+		duration, err := time.ParseDuration(value)
+		if err != nil || duration <= 0 {
+			return fallback
+		}
+		return duration
+	}
+	return TimeoutDurations{
+		Connect:    parse(t.Connect, DefaultConnectTimeout),
+		FirstToken: parse(t.FirstToken, DefaultFirstTokenTimeout),
+		Idle:       parse(t.Idle, DefaultIdleTimeout),
+		Request:    parse(t.Request, DefaultRequestTimeout),
+	}
+}
+
+// validate rejects the timeouts that are not positive durations
+func (t ProviderTimeouts) validate() error {
+	for _, timeout := range []struct{ name, value string }{
+		{"connect", t.Connect},
+		{"firstToken", t.FirstToken},
+		{"idle", t.Idle},
+		{"request", t.Request},
+	} {
+		if timeout.value == "" {
+			continue
+		}
+		duration, err := time.ParseDuration(timeout.value)
+		if err != nil {
+			return fmt.Errorf("invalid %s timeout %q: %w", timeout.name, timeout.value, err)
+		}
+		if duration <= 0 {
+			return fmt.Errorf("invalid %s timeout %q: must be positive", timeout.name, timeout.value)
+		}
+	}
+	return nil
+}
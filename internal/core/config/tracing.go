@@ -0,0 +1,81 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// TracingExporter selects where finished turn traces are sent
+type TracingExporter string
+
+const (
+	// TracingExporterMemory keeps traces in memory only, for the TUI and introspection
+	TracingExporterMemory TracingExporter = "memory"
+	// TracingExporterOTLP also sends traces to an OTLP/HTTP collector
+	TracingExporterOTLP TracingExporter = "otlp"
+)
+
+// TracingConfig defines the latency tracing of agent turns.
+type TracingConfig struct {
+	// Enabled records a trace of every turn
+	Enabled bool `json:"enabled,omitempty"`
+	// Exporter sends finished traces elsewhere, memory unless set
+	Exporter TracingExporter `json:"exporter,omitempty"`
+	// Endpoint is the base URL of the OTLP/HTTP collector, http://localhost:4318 unless set
+	Endpoint string `json:"endpoint,omitempty"`
+	// SlowTurnThreshold logs the span tree of turns taking longer than this
+	// duration at warn level, e.g. "30s". Leave empty to never log.
+	SlowTurnThreshold string `json:"slowTurnThreshold,omitempty"`
+	// History is the number of recent turns kept for latency percentiles
+	History int `json:"history,omitempty"`
+}
+
+const (
+	defaultTracingEndpoint          = "http://localhost:4318"
+	defaultTracingSlowTurnThreshold = "30s"
+	defaultTracingHistory           = 100
+)
+
+// SlowThreshold returns the parsed slow turn threshold, 0 if slow turns are not logged.
+func (t TracingConfig) SlowThreshold() time.Duration {
+	threshold, err := time.ParseDuration(t.SlowTurnThreshold)
+	if err != nil {
+		return 0
+	}
+	return threshold
+}
+
+// validateTracing applies the tracing defaults and rejects unknown exporters,
+// endpoints and thresholds.
+func validateTracing(cfg *Config) error {
+	tracing := &cfg.Tracing
+	if tracing.Exporter == "" {
+		tracing.Exporter = TracingExporterMemory
+	}
+	if tracing.Endpoint == "" {
+		tracing.Endpoint = defaultTracingEndpoint
+	}
+	if tracing.History <= 0 {
+		tracing.History = defaultTracingHistory
+	}
+
+	switch tracing.Exporter {
+	case TracingExporterMemory, TracingExporterOTLP:
+	default:
+		return fmt.Errorf("invalid tracing exporter %q: must be %q or %q", tracing.Exporter, TracingExporterMemory, TracingExporterOTLP)
+	}
+	if endpoint, err := url.Parse(tracing.Endpoint); err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return fmt.Errorf("invalid tracing endpoint %q: must be an http or https URL", tracing.Endpoint)
+	}
+	if tracing.SlowTurnThreshold != "" {
+		threshold, err := time.ParseDuration(tracing.SlowTurnThreshold)
+		if err != nil {
+			return fmt.Errorf("invalid tracing slow turn threshold %q: %w", tracing.SlowTurnThreshold, err)
+		}
+		if threshold <= 0 {
+			return fmt.Errorf("invalid tracing slow turn threshold %q: must be positive", tracing.SlowTurnThreshold)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ValidationError reports a setting of the configuration whose value is
+// invalid, with the value it was likely meant to be when there is one
+type ValidationError struct {
+	// Field is the path of the setting, such as spaces.dev.assigned_agents
+	Field      string
+	Value      string
+	Reason     string
+	Suggestion string
+}
+
+func (e *ValidationError) Error() string {
+	msg := fmt.Sprintf("%s: %s %q", e.Field, e.Reason, e.Value)
+	if e.Suggestion != "" {
+		msg += fmt.Sprintf(", did you mean %q?", e.Suggestion)
+	}
+	return msg
+}
+
+// ValidAgentNames returns the names of the agents configured in cfg, sorted
+func ValidAgentNames(cfg *Config) []string {
+	names := make([]string, 0, len(cfg.Agents))
+	for name := range cfg.Agents {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CanonicalAgentName returns the form agent names are compared in, lower
+// case without surrounding spaces, so "Coder" and "coder" name the same agent
+func CanonicalAgentName(name string) AgentName {
+	return AgentName(strings.ToLower(strings.TrimSpace(name)))
+}
+
+// ResolveAgent returns the configured agent name refers to, by its name or
+// one of its aliases in any case
+func (c *Config) ResolveAgent(name string) (AgentName, bool) {
+	canonical := CanonicalAgentName(name)
+	if canonical == "" {
+		return "", false
+	}
+	if _, ok := c.Agents[canonical]; ok {
+		return canonical, true
+	}
+	for _, agentName := range sortedAgentNames(c.Agents) {
+		if CanonicalAgentName(string(agentName)) == canonical {
+			return agentName, true
+		}
+		for _, alias := range c.Agents[agentName].Aliases {
+			if CanonicalAgentName(alias) == canonical {
+				return agentName, true
+			}
+		}
+	}
+	return "", false
+}
+
+// unknownAgent returns the error of a reference to an agent that is not
+// configured, suggesting the agent or alias it was likely meant to be
+func unknownAgent(cfg *Config, field, name string) *ValidationError {
+	candidates := ValidAgentNames(cfg)
+	for _, agent := range cfg.Agents {
+		candidates = append(candidates, agent.Aliases...)
+	}
+	return &ValidationError{
+		Field:      field,
+		Value:      name,
+		Reason:     "unknown agent",
+		Suggestion: nearestName(string(CanonicalAgentName(name)), candidates),
+	}
+}
+
+// normalizeAgents keys the agents by their canonical names and lowers their
+// aliases, so configs naming agents in any case keep working. An agent
+// configured under several casings keeps the entry already in canonical form,
+// and an alias naming another agent is dropped.
+func normalizeAgents(cfg *Config) {
+	if len(cfg.Agents) == 0 {
+		return
+	}
+	names := sortedAgentNames(cfg.Agents)
+	// The entries already in canonical form win over the other casings
+	slices.SortStableFunc(names, func(a, b AgentName) int {
+		aCanonical, bCanonical := a == CanonicalAgentName(string(a)), b == CanonicalAgentName(string(b))
+		switch {
+		case aCanonical && !bCanonical:
+			return -1
+		case bCanonical && !aCanonical:
+			return 1
+		}
+		return 0
+	})
+
+	agents := make(map[AgentName]Agent, len(cfg.Agents))
+	for _, name := range names {
+		canonical := CanonicalAgentName(string(name))
+		if _, ok := agents[canonical]; ok {
+			cfg.warn("agent configured more than once in different cases, ignoring the duplicate", "agent", canonical, "ignored", name)
+			continue
+		}
+		agents[canonical] = cfg.Agents[name]
+	}
+
+	claimed := make(map[string]AgentName)
+	for _, name := range sortedAgentNames(agents) {
+		agent := agents[name]
+		if len(agent.Aliases) == 0 {
+			continue
+		}
+		aliases := make([]string, 0, len(agent.Aliases))
+		for _, alias := range agent.Aliases {
+			canonical := string(CanonicalAgentName(alias))
+			if _, isAgent := agents[AgentName(canonical)]; isAgent && AgentName(canonical) != name {
+				cfg.warn("agent alias names another agent, ignoring it", "agent", name, "alias", alias)
+				continue
+			}
+			if owner, ok := claimed[canonical]; ok && owner != name {
+				cfg.warn("agent alias already taken by another agent, ignoring it", "agent", name, "alias", alias, "taken_by", owner)
+				continue
+			}
+			if canonical == "" || AgentName(canonical) == name || claimed[canonical] == name {
+				continue
+			}
+			claimed[canonical] = name
+			aliases = append(aliases, canonical)
+		}
+		agent.Aliases = aliases
+		agents[name] = agent
+	}
+	cfg.Agents = agents
+
+	// Reviewers are referred to by any of their names
+	for _, name := range sortedAgentNames(agents) {
+		agent := agents[name]
+		if agent.ReviewFlow == nil || agent.ReviewFlow.Reviewer == "" {
+			continue
+		}
+		if reviewer, ok := cfg.ResolveAgent(string(agent.ReviewFlow.Reviewer)); ok {
+			flow := *agent.ReviewFlow
+			flow.Reviewer = reviewer
+			agent.ReviewFlow = &flow
+			agents[name] = agent
+		}
+	}
+}
+
+func sortedAgentNames(agents map[AgentName]Agent) []AgentName {
+	names := make([]AgentName, 0, len(agents))
+	for name := range agents {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// nearestName returns the candidate closest to name by edit distance, "" when
+// none is close enough to be a typo of it
+func nearestName(name string, candidates []string) string {
+	nearest, best := "", max(2, len(name)/3)+1
+	for _, candidate := range candidates {
+		if d := levenshtein(name, candidate); d < best {
+			nearest, best = candidate, d
+		}
+	}
+	return nearest
+}
+
+// levenshtein returns the number of single character insertions, deletions
+// and substitutions turning a into b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
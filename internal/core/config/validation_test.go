@@ -0,0 +1,102 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+)
+
+func TestLoadAgentNamesInAnyCase(t *testing.T) {
+	wd := writeConfigSources(t, `{}`,
+		fmt.Sprintf(`{
+			"providers": {"openrouter": {"apiKey": "local-openrouter"}},
+			"agents": {
+				"Caronex": {"model": %[1]q, "maxTokens": 4000, "aliases": ["Boss", "MANAGER"]},
+				"Reviewer": {"model": %[1]q, "maxTokens": 4000, "reviewFlow": {"enabled": true, "reviewer": "CARONEX"}}
+			},
+			"spaces": {"dev": {"id": "dev", "name": "Dev", "assignedAgents": ["CARONEX", "caronex", "boss", "reviewr"]}}
+		}`, models.OpenRouterClaude37Sonnet),
+		nil,
+	)
+
+	cfg, err := Load(wd, false)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := strings.Join(ValidAgentNames(cfg), ","); got != "caronex,reviewer" {
+		t.Errorf("agents = %s, want caronex,reviewer", got)
+	}
+	if got := strings.Join(cfg.Agents[AgentCaronex].Aliases, ","); got != "boss,manager" {
+		t.Errorf("caronex aliases = %s, want boss,manager", got)
+	}
+	if got := cfg.Agents["reviewer"].ReviewFlow.Reviewer; got != AgentCaronex {
+		t.Errorf("reviewer = %s, want caronex", got)
+	}
+	if got := strings.Join(cfg.Spaces["dev"].AssignedAgents, ","); got != "caronex" {
+		t.Errorf("assigned agents = %s, want caronex", got)
+	}
+	want := `spaces.dev.assigned_agents: unknown agent "reviewr", did you mean "reviewer"?`
+	found := false
+	for _, warning := range cfg.Warnings {
+		found = found || strings.HasPrefix(warning, want)
+	}
+	if !found {
+		t.Errorf("warnings = %q, want %q", cfg.Warnings, want)
+	}
+}
+
+func TestNormalizeAgents(t *testing.T) {
+	// The same agent configured in three casings keeps its canonical entry
+	cfg := &Config{Agents: map[AgentName]Agent{
+		"Coder": {MaxTokens: 1},
+		"coder": {MaxTokens: 2, Aliases: []string{"Dev", "dev", "TASK"}},
+		"CODER": {MaxTokens: 3},
+		"task":  {Aliases: []string{"DEV", "Planner"}},
+	}}
+	normalizeAgents(cfg)
+
+	if got := strings.Join(ValidAgentNames(cfg), ","); got != "coder,task" {
+		t.Errorf("agents = %s, want coder,task", got)
+	}
+	if got := cfg.Agents["coder"].MaxTokens; got != 2 {
+		t.Errorf("coder max tokens = %d, want those of the canonical entry", got)
+	}
+	if got := strings.Join(cfg.Agents["coder"].Aliases, ","); got != "dev" {
+		t.Errorf("coder aliases = %s, want dev", got)
+	}
+	if got := strings.Join(cfg.Agents["task"].Aliases, ","); got != "planner" {
+		t.Errorf("task aliases = %s, want planner", got)
+	}
+	// Two duplicates, an alias naming another agent and an alias taken
+	if len(cfg.Warnings) != 4 {
+		t.Errorf("warnings = %q, want 4", cfg.Warnings)
+	}
+}
+
+func TestResolveAgent(t *testing.T) {
+	cfg := &Config{Agents: map[AgentName]Agent{
+		AgentCaronex: {Aliases: []string{"boss"}},
+		"coder":      {},
+	}}
+	for name, want := range map[string]AgentName{
+		"caronex":   AgentCaronex,
+		"Caronex":   AgentCaronex,
+		" CARONEX ": AgentCaronex,
+		"BOSS":      AgentCaronex,
+		"cOdEr":     "coder",
+		"":          "",
+		"reviewer":  "",
+	} {
+		got, ok := cfg.ResolveAgent(name)
+		if got != want || ok != (want != "") {
+			t.Errorf("ResolveAgent(%q) = %s, %v; want %s", name, got, ok, want)
+		}
+	}
+
+	err := unknownAgent(cfg, "spaces.dev.assigned_agents", "Bos")
+	if err.Suggestion != "boss" {
+		t.Errorf("suggestion for Bos = %q, want the alias boss", err.Suggestion)
+	}
+}
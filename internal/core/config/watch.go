@@ -0,0 +1,37 @@
+package config
+
+import "sync"
+
+var (
+	watchersMu sync.Mutex
+	watchers   = make(map[chan struct{}]struct{})
+)
+
+// Watch returns a channel that receives a value after the application changes
+// the configuration, such as an agent's model, and a function to stop
+// watching. Changes made while the last one is not received yet are merged.
+func Watch() (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	watchersMu.Lock()
+	watchers[ch] = struct{}{}
+	watchersMu.Unlock()
+	return ch, func() {
+		watchersMu.Lock()
+		defer watchersMu.Unlock()
+		delete(watchers, ch)
+	}
+}
+
+// notifyWatchers tells the watchers the configuration changed, and starts the
+// log throttle over with its settings
+func notifyWatchers() {
+	configureLogThrottle()
+	watchersMu.Lock()
+	defer watchersMu.Unlock()
+	for ch := range watchers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestWatch(t *testing.T) {
+	NewTestConfig()
+	defer current.Store(nil)
+
+	changes, stop := Watch()
+	temperature := 0.5
+	if err := OverrideAgentGeneration(AgentCaronex, GenerationParams{Temperature: &temperature}); err != nil {
+		t.Fatalf("OverrideAgentGeneration() error = %v", err)
+	}
+	if err := OverrideAgentGeneration(AgentCaronex, GenerationParams{Temperature: &temperature}); err != nil {
+		t.Fatalf("OverrideAgentGeneration() error = %v", err)
+	}
+	select {
+	case <-changes:
+	default:
+		t.Fatal("no change received after the configuration changed")
+	}
+	select {
+	case <-changes:
+		t.Fatal("changes not received yet should be merged")
+	default:
+	}
+
+	stop()
+	if err := OverrideAgentGeneration(AgentCaronex, GenerationParams{Temperature: &temperature}); err != nil {
+		t.Fatalf("OverrideAgentGeneration() error = %v", err)
+	}
+	select {
+	case <-changes:
+		t.Fatal("change received after stopping")
+	default:
+	}
+}
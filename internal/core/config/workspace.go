@@ -0,0 +1,243 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// WorkspaceRoot defines a named root of a multi-root workspace (e.g. one Go
+// module or frontend package in a monorepo).
+type WorkspaceRoot struct {
+	Path         string               `json:"path"`
+	ContextPaths []string             `json:"contextPaths,omitempty"`
+	LSP          map[string]LSPConfig `json:"lsp,omitempty"`
+	Detected     bool                 `json:"detected,omitempty"`
+	// ProjectTypes are the stacks of the marker files of a detected root
+	ProjectTypes []ProjectType `json:"projectTypes,omitempty"`
+}
+
+// workspaceSkipDirs are never descended into during root discovery
+var workspaceSkipDirs = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+	"vendor":       true,
+	"dist":         true,
+	"build":        true,
+}
+
+const workspaceDiscoveryDepth = 3
+
+var (
+	workspaceMu      sync.RWMutex
+	detectedRoots    map[string]WorkspaceRoot
+	detectedRootsDir string
+	activeRoot       string
+	activeRootPinned bool
+)
+
+// validateWorkspaces normalizes configured workspace roots and rejects
+// overlapping roots, which would make sandbox decisions ambiguous.
+func validateWorkspaces(cfg *Config) error {
+	if len(cfg.Workspaces) == 0 {
+		return nil
+	}
+
+	for name, root := range cfg.Workspaces {
+		if strings.TrimSpace(name) == "" || strings.ContainsAny(name, ":/\\") {
+			return fmt.Errorf("invalid workspace root name %q: must be non-empty and must not contain ':', '/' or '\\'", name)
+		}
+		if root.Path == "" {
+			return fmt.Errorf("workspace root %q has no path", name)
+		}
+		if !filepath.IsAbs(root.Path) {
+			root.Path = filepath.Join(cfg.WorkingDir, root.Path)
+		}
+		root.Path = filepath.Clean(root.Path)
+		cfg.Workspaces[name] = root
+	}
+
+	names := sortedRootNames(cfg.Workspaces)
+	for i, a := range names {
+		for _, b := range names[i+1:] {
+			pa, pb := cfg.Workspaces[a].Path, cfg.Workspaces[b].Path
+			if pathContains(pa, pb) || pathContains(pb, pa) {
+				return fmt.Errorf("workspace roots %q (%s) and %q (%s) overlap", a, pa, b, pb)
+			}
+		}
+	}
+
+	return nil
+}
+
+// DetectWorkspaceRoots discovers workspace roots below dir by looking for
+// project marker files such as go.mod or package.json, recording the stacks
+// they mark. Discovery does not descend into a directory once it has been
+// identified as a root.
+func DetectWorkspaceRoots(dir string) map[string]WorkspaceRoot {
+	roots := make(map[string]WorkspaceRoot)
+	var walk func(path string, depth int)
+	walk = func(path string, depth int) {
+		if types := markerTypes(path); len(types) > 0 {
+			name := filepath.Base(path)
+			if rel, err := filepath.Rel(dir, path); err == nil && rel != "." {
+				name = strings.ReplaceAll(rel, string(filepath.Separator), "-")
+			}
+			roots[name] = WorkspaceRoot{Path: path, Detected: true, ProjectTypes: types}
+			return
+		}
+		if depth >= workspaceDiscoveryDepth {
+			return
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() || workspaceSkipDirs[entry.Name()] || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			walk(filepath.Join(path, entry.Name()), depth+1)
+		}
+	}
+	walk(filepath.Clean(dir), 0)
+	return roots
+}
+
+// WorkspaceRoots returns the configured workspace roots, or the auto-discovered
+// roots when none are configured, discovered again when the working directory
+// changes.
+func WorkspaceRoots() map[string]WorkspaceRoot {
+	cfg := Get()
+	if cfg == nil {
+		return nil
+	}
+	if len(cfg.Workspaces) > 0 {
+		return cfg.Workspaces
+	}
+	workspaceMu.Lock()
+	defer workspaceMu.Unlock()
+	if detectedRoots == nil || detectedRootsDir != cfg.WorkingDir {
+		detectedRoots = DetectWorkspaceRoots(cfg.WorkingDir)
+		detectedRootsDir = cfg.WorkingDir
+	}
+	return detectedRoots
+}
+
+// WorkspaceRootNames returns the names of all workspace roots in sorted order.
+func WorkspaceRootNames() []string {
+	return sortedRootNames(WorkspaceRoots())
+}
+
+// ActiveRoot returns the name of the active workspace root, or "" when
+// relative paths resolve against the working directory.
+func ActiveRoot() string {
+	workspaceMu.RLock()
+	defer workspaceMu.RUnlock()
+	return activeRoot
+}
+
+// SetActiveRoot pins the root used for relative paths. An empty name resets
+// to the working directory.
+func SetActiveRoot(name string) error {
+	if name != "" {
+		if _, ok := WorkspaceRoots()[name]; !ok {
+			return fmt.Errorf("unknown workspace root: %s", name)
+		}
+	}
+	workspaceMu.Lock()
+	defer workspaceMu.Unlock()
+	activeRoot = name
+	activeRootPinned = name != ""
+	return nil
+}
+
+// NoteWorkspaceFile records the file currently being discussed so relative
+// paths default to the root that contains it, unless a root has been pinned.
+func NoteWorkspaceFile(path string) {
+	name, ok := RootForPath(path)
+	if !ok {
+		return
+	}
+	workspaceMu.Lock()
+	defer workspaceMu.Unlock()
+	if !activeRootPinned {
+		activeRoot = name
+	}
+}
+
+// RootForPath returns the name of the workspace root containing path.
+func RootForPath(path string) (string, bool) {
+	if !filepath.IsAbs(path) {
+		return "", false
+	}
+	path = filepath.Clean(path)
+	for name, root := range WorkspaceRoots() {
+		if pathContains(root.Path, path) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// ResolveWorkspacePath resolves a tool path into an absolute path. Paths may be
+// qualified with a root name ("frontend:src/app.ts"); unqualified relative
+// paths resolve against the active root, falling back to the working directory.
+func ResolveWorkspacePath(path string) (string, error) {
+	if path == "" || filepath.IsAbs(path) {
+		return path, nil
+	}
+
+	if IsRootQualified(path) {
+		name, rest, _ := strings.Cut(path, ":")
+		root := WorkspaceRoots()[name]
+		resolved := filepath.Join(root.Path, rest)
+		if !pathContains(root.Path, resolved) {
+			return "", fmt.Errorf("path %q escapes workspace root %q", path, name)
+		}
+		return resolved, nil
+	}
+
+	return filepath.Join(DefaultRootPath(), path), nil
+}
+
+// IsRootQualified reports whether path carries a "root:" qualifier naming a
+// configured workspace root. Other colons are part of the file name.
+func IsRootQualified(path string) bool {
+	name, _, ok := strings.Cut(path, ":")
+	if !ok || name == "" || filepath.IsAbs(path) {
+		return false
+	}
+	_, exists := WorkspaceRoots()[name]
+	return exists
+}
+
+// DefaultRootPath returns the directory relative paths currently resolve against.
+func DefaultRootPath() string {
+	if name := ActiveRoot(); name != "" {
+		if root, ok := WorkspaceRoots()[name]; ok {
+			return root.Path
+		}
+	}
+	return WorkingDirectory()
+}
+
+func pathContains(parent, child string) bool {
+	rel, err := filepath.Rel(parent, child)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+func sortedRootNames(roots map[string]WorkspaceRoot) []string {
+	names := make([]string, 0, len(roots))
+	for name := range roots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
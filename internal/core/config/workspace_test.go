@@ -0,0 +1,104 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWorkspaceRoots(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, dir := range []string{"backend", "frontend", "frontend/node_modules/dep"} {
+		if err := os.MkdirAll(filepath.Join(tmpDir, dir), 0o755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+	}
+	os.WriteFile(filepath.Join(tmpDir, "backend", "go.mod"), []byte("module backend\n"), 0o644)
+	os.WriteFile(filepath.Join(tmpDir, "frontend", "package.json"), []byte("{}"), 0o644)
+	os.WriteFile(filepath.Join(tmpDir, "frontend", "node_modules", "dep", "package.json"), []byte("{}"), 0o644)
+
+	t.Run("DetectsMarkerRoots", func(t *testing.T) {
+		roots := DetectWorkspaceRoots(tmpDir)
+		if len(roots) != 2 {
+			t.Fatalf("Expected 2 detected roots, got %d: %v", len(roots), roots)
+		}
+		if roots["backend"].Path != filepath.Join(tmpDir, "backend") {
+			t.Errorf("Unexpected backend root path: %s", roots["backend"].Path)
+		}
+		if !roots["frontend"].Detected {
+			t.Error("Detected roots should be flagged as detected")
+		}
+	})
+
+	t.Run("RejectsOverlappingRoots", func(t *testing.T) {
+		cfg := &Config{
+			WorkingDir: tmpDir,
+			Workspaces: map[string]WorkspaceRoot{
+				"app": {Path: "frontend"},
+				"lib": {Path: "frontend/src"},
+			},
+		}
+
+		if err := validateWorkspaces(cfg); err == nil {
+			t.Error("Overlapping roots should fail validation")
+		}
+	})
+
+	t.Run("ResolvesQualifiedAndActivePaths", func(t *testing.T) {
+		cfg := &Config{
+			WorkingDir: tmpDir,
+			Workspaces: map[string]WorkspaceRoot{
+				"api": {Path: "backend"},
+				"web": {Path: "frontend"},
+			},
+		}
+		current.Store(cfg)
+		defer func() {
+			current.Store(nil)
+			SetActiveRoot("")
+		}()
+
+		if err := validateWorkspaces(cfg); err != nil {
+			t.Fatalf("Unexpected validation error: %v", err)
+		}
+
+		path, err := ResolveWorkspacePath("web:src/app.ts")
+		if err != nil {
+			t.Fatalf("Failed to resolve qualified path: %v", err)
+		}
+		if path != filepath.Join(tmpDir, "frontend", "src", "app.ts") {
+			t.Errorf("Unexpected qualified path: %s", path)
+		}
+
+		if path, _ := ResolveWorkspacePath("foo:bar.txt"); path != filepath.Join(tmpDir, "foo:bar.txt") {
+			t.Errorf("Prefix that names no root should stay part of the file name, got %s", path)
+		}
+
+		for _, escaping := range []string{"web:../../etc/passwd", "web:..", "web:src/../../backend/go.mod"} {
+			if _, err := ResolveWorkspacePath(escaping); err == nil {
+				t.Errorf("Qualified path %q escaping its root should return an error", escaping)
+			}
+		}
+
+		if path, _ := ResolveWorkspacePath("web:src/../package.json"); path != filepath.Join(tmpDir, "frontend", "package.json") {
+			t.Errorf("Qualified path should be cleaned within its root, got %s", path)
+		}
+
+		if path, _ := ResolveWorkspacePath("main.go"); path != filepath.Join(tmpDir, "main.go") {
+			t.Errorf("Relative path should resolve against working dir without an active root, got %s", path)
+		}
+
+		NoteWorkspaceFile(filepath.Join(tmpDir, "backend", "main.go"))
+		if path, _ := ResolveWorkspacePath("cmd/main.go"); path != filepath.Join(tmpDir, "backend", "cmd", "main.go") {
+			t.Errorf("Relative path should resolve against the root of the discussed file, got %s", path)
+		}
+
+		if err := SetActiveRoot("web"); err != nil {
+			t.Fatalf("Failed to set active root: %v", err)
+		}
+		NoteWorkspaceFile(filepath.Join(tmpDir, "backend", "main.go"))
+		if ActiveRoot() != "web" {
+			t.Errorf("Pinned active root should not change, got %s", ActiveRoot())
+		}
+	})
+}
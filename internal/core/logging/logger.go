@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"os"
 	"runtime/debug"
+	"sync"
 	"time"
 )
 
@@ -44,13 +45,39 @@ func ErrorPersist(msg string, args ...any) {
 	slog.Error(msg, args...)
 }
 
+// crashReportFields are added to the panic log files, in registration order
+var (
+	crashReportMu     sync.Mutex
+	crashReportFields []crashReportField
+)
+
+type crashReportField struct {
+	name  string
+	value func() string
+}
+
+// SetCrashReportField adds a line computed by value to the panic log files,
+// for details this package cannot import such as the configuration. Setting
+// a field again replaces it.
+func SetCrashReportField(name string, value func() string) {
+	crashReportMu.Lock()
+	defer crashReportMu.Unlock()
+	for i, field := range crashReportFields {
+		if field.name == name {
+			crashReportFields[i].value = value
+			return
+		}
+	}
+	crashReportFields = append(crashReportFields, crashReportField{name: name, value: value})
+}
+
 // RecoverPanic is a common function to handle panics gracefully.
 // It logs the error, creates a panic log file with stack trace,
 // and executes an optional cleanup function before returning.
 func RecoverPanic(name string, cleanup func()) {
 	if r := recover(); r != nil {
 		// Log the panic
-		ErrorPersist(fmt.Sprintf("Panic in %s: %v", name, r))
+		ErrorPersist(fmt.Sprintf("Panic in %s: %v", name, r), ComponentKey, ComponentPanic)
 
 		// Create a timestamped panic log file
 		timestamp := time.Now().Format("20060102-150405")
@@ -65,6 +92,11 @@ func RecoverPanic(name string, cleanup func()) {
 			// Write panic information and stack trace
 			fmt.Fprintf(file, "Panic in %s: %v\n\n", name, r)
 			fmt.Fprintf(file, "Time: %s\n\n", time.Now().Format(time.RFC3339))
+			crashReportMu.Lock()
+			for _, field := range crashReportFields {
+				fmt.Fprintf(file, "%s: %s\n\n", field.name, field.value())
+			}
+			crashReportMu.Unlock()
 			fmt.Fprintf(file, "Stack Trace:\n%s\n", debug.Stack())
 
 			InfoPersist(fmt.Sprintf("Panic details written to %s", filename))
@@ -0,0 +1,63 @@
+package logging
+
+import (
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces the secrets in what is logged
+const Redacted = "[REDACTED]"
+
+var (
+	secretsMu sync.RWMutex
+	// secrets are the values never logged in plaintext, such as the ones
+	// read from the keyring
+	secrets []string
+)
+
+// AddSecret has value replaced by Redacted in the logs from now on, and in
+// the text passed to Redact
+func AddSecret(value string) {
+	if value == "" {
+		return
+	}
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	if !slices.Contains(secrets, value) {
+		secrets = append(secrets, value)
+	}
+}
+
+// Redact replaces the secrets in s by Redacted, as they are or quoted, as the
+// text handler writes the values with special characters
+func Redact(s string) string {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, Redacted)
+		if quoted := strconv.Quote(secret); quoted[1:len(quoted)-1] != secret {
+			s = strings.ReplaceAll(s, quoted[1:len(quoted)-1], Redacted)
+		}
+	}
+	return s
+}
+
+type redactingWriter struct {
+	w io.Writer
+}
+
+// NewRedactingWriter returns a writer writing to w what it is given with the
+// secrets redacted
+func NewRedactingWriter(w io.Writer) io.Writer {
+	return &redactingWriter{w: w}
+}
+
+func (r *redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(r.w, Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
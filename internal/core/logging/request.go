@@ -0,0 +1,72 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/google/uuid"
+)
+
+// RequestIDKey is the attribute holding the request ID in log records
+const RequestIDKey = "request_id"
+
+type requestIDContextKey struct{}
+
+// WithRequestID returns a context carrying the ID of the request it belongs
+// to, correlating the logs of an agent turn, its tool calls and provider
+// requests
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID carried by ctx, or "" outside
+// of a request
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// EnsureRequestID returns ctx with its request ID, starting a new request
+// when ctx does not belong to one yet
+func EnsureRequestID(ctx context.Context) (context.Context, string) {
+	if id := RequestIDFromContext(ctx); id != "" {
+		return ctx, id
+	}
+	id := uuid.New().String()
+	return WithRequestID(ctx, id), id
+}
+
+// Logger logs like the package functions, adding its attributes to every
+// record
+type Logger struct {
+	*slog.Logger
+}
+
+// With returns a logger adding args to every record
+func With(args ...any) *Logger {
+	return &Logger{slog.Default().With(args...)}
+}
+
+// FromContext returns the logger of the request ctx belongs to, adding its
+// request ID to every record
+func FromContext(ctx context.Context) *Logger {
+	if id := RequestIDFromContext(ctx); id != "" {
+		return With(slog.String(RequestIDKey, id))
+	}
+	return &Logger{slog.Default()}
+}
+
+func (l *Logger) InfoPersist(msg string, args ...any) {
+	args = append(args, persistKeyArg, true)
+	l.Info(msg, args...)
+}
+
+func (l *Logger) WarnPersist(msg string, args ...any) {
+	args = append(args, persistKeyArg, true)
+	l.Warn(msg, args...)
+}
+
+func (l *Logger) ErrorPersist(msg string, args ...any) {
+	args = append(args, persistKeyArg, true)
+	l.Error(msg, args...)
+}
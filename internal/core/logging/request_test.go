@@ -0,0 +1,41 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	if id := RequestIDFromContext(context.Background()); id != "" {
+		t.Errorf("RequestIDFromContext() = %q outside of a request", id)
+	}
+
+	ctx, id := EnsureRequestID(context.Background())
+	if id == "" || RequestIDFromContext(ctx) != id {
+		t.Fatalf("EnsureRequestID() = %q, the context carries %q", id, RequestIDFromContext(ctx))
+	}
+	if _, again := EnsureRequestID(ctx); again != id {
+		t.Errorf("EnsureRequestID() = %q within request %q, want the same request", again, id)
+	}
+
+	var buf bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	defer slog.SetDefault(defaultLogger)
+
+	FromContext(ctx).Info("calling tool")
+	FromContext(context.Background()).Info("unrelated")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("logged %q, want 2 lines", buf.String())
+	}
+	if !strings.Contains(lines[0], "request_id="+id) {
+		t.Errorf("log line %q should carry the request ID", lines[0])
+	}
+	if strings.Contains(lines[1], "request_id") {
+		t.Errorf("log line %q outside of a request should not carry a request ID", lines[1])
+	}
+}
@@ -0,0 +1,200 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ComponentKey is the attribute naming the component a log comes from,
+	// which ThrottleOptions.Exempt matches
+	ComponentKey = "component"
+
+	// ComponentPanic marks the logs of recovered panics
+	ComponentPanic = "panic"
+	// ComponentDataLoss marks the logs warning that data may be lost
+	ComponentDataLoss = "data-loss"
+
+	// DefaultThrottleWindow is how long identical logs are collapsed
+	DefaultThrottleWindow = 10 * time.Second
+	// DefaultThrottleMaxEntries bounds the distinct logs tracked at once
+	DefaultThrottleMaxEntries = 1000
+)
+
+// DefaultThrottleExempt are the components whose logs are never throttled
+var DefaultThrottleExempt = []string{ComponentPanic, ComponentDataLoss}
+
+// ThrottleOptions configure a ThrottleHandler
+type ThrottleOptions struct {
+	// Window is how long identical logs are collapsed, 0 to not throttle
+	Window time.Duration
+	// Exempt are the components whose logs are never throttled
+	Exempt []string
+	// MaxEntries bounds the distinct logs tracked at once, the logs beyond
+	// it pass through until an entry is freed
+	MaxEntries int
+}
+
+// ThrottleHandler collapses identical logs, same level, message and
+// attributes, within a window into the first one, followed by a
+// "repeated N times" log when the window closes. It keeps the logs of a
+// failing loop from drowning the others.
+type ThrottleHandler struct {
+	next  slog.Handler
+	state *throttleState
+	// attrs are the attributes added with WithAttrs, part of the identity of
+	// the logs
+	attrs string
+}
+
+type throttleState struct {
+	mu      sync.Mutex
+	opts    ThrottleOptions
+	entries map[string]*throttleEntry
+}
+
+type throttleEntry struct {
+	next     slog.Handler
+	record   slog.Record
+	repeated int
+	timer    *time.Timer
+}
+
+// NewThrottleHandler returns a handler throttling the logs it passes to next
+func NewThrottleHandler(next slog.Handler, opts ThrottleOptions) *ThrottleHandler {
+	return &ThrottleHandler{
+		next: next,
+		state: &throttleState{
+			opts:    withThrottleDefaults(opts),
+			entries: make(map[string]*throttleEntry),
+		},
+	}
+}
+
+func withThrottleDefaults(opts ThrottleOptions) ThrottleOptions {
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = DefaultThrottleMaxEntries
+	}
+	return opts
+}
+
+func (h *ThrottleHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *ThrottleHandler) Handle(ctx context.Context, record slog.Record) error {
+	key, exempt := h.key(record)
+	if exempt {
+		return h.next.Handle(ctx, record)
+	}
+
+	s := h.state
+	s.mu.Lock()
+	if s.opts.Window <= 0 {
+		s.mu.Unlock()
+		return h.next.Handle(ctx, record)
+	}
+	if entry, ok := s.entries[key]; ok {
+		entry.repeated++
+		s.mu.Unlock()
+		return nil
+	}
+	if len(s.entries) < s.opts.MaxEntries {
+		entry := &throttleEntry{next: h.next, record: record.Clone()}
+		s.entries[key] = entry
+		entry.timer = time.AfterFunc(s.opts.Window, func() { s.close(key, entry) })
+	}
+	s.mu.Unlock()
+	return h.next.Handle(ctx, record)
+}
+
+// key identifies the log, and whether its component is exempt
+func (h *ThrottleHandler) key(record slog.Record) (string, bool) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s|%s|%s", record.Level, record.Message, h.attrs)
+	exempt := false
+	record.Attrs(func(attr slog.Attr) bool {
+		if attr.Key == ComponentKey && h.exempt(attr.Value.String()) {
+			exempt = true
+			return false
+		}
+		fmt.Fprintf(&b, "|%s=%s", attr.Key, attr.Value)
+		return true
+	})
+	return b.String(), exempt
+}
+
+func (h *ThrottleHandler) exempt(component string) bool {
+	h.state.mu.Lock()
+	defer h.state.mu.Unlock()
+	return slices.Contains(h.state.opts.Exempt, component)
+}
+
+func (h *ThrottleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var b strings.Builder
+	b.WriteString(h.attrs)
+	for _, attr := range attrs {
+		if attr.Key == ComponentKey && h.exempt(attr.Value.String()) {
+			// Every log of the handler is exempt
+			return h.next.WithAttrs(attrs)
+		}
+		fmt.Fprintf(&b, "|%s=%s", attr.Key, attr.Value)
+	}
+	return &ThrottleHandler{next: h.next.WithAttrs(attrs), state: h.state, attrs: b.String()}
+}
+
+func (h *ThrottleHandler) WithGroup(name string) slog.Handler {
+	return &ThrottleHandler{next: h.next.WithGroup(name), state: h.state, attrs: h.attrs + "|" + name + "."}
+}
+
+// Configure replaces the options of the handler and the handlers derived from
+// it, closing the pending windows
+func (h *ThrottleHandler) Configure(opts ThrottleOptions) {
+	h.Flush()
+	h.state.mu.Lock()
+	defer h.state.mu.Unlock()
+	h.state.opts = withThrottleDefaults(opts)
+}
+
+// Flush closes the pending windows, logging how many times each log was
+// repeated
+func (h *ThrottleHandler) Flush() {
+	s := h.state
+	s.mu.Lock()
+	entries := s.entries
+	s.entries = make(map[string]*throttleEntry)
+	s.mu.Unlock()
+	for _, entry := range entries {
+		entry.timer.Stop()
+		entry.flush()
+	}
+}
+
+// close ends the window of entry, unless it was flushed already
+func (s *throttleState) close(key string, entry *throttleEntry) {
+	s.mu.Lock()
+	if s.entries[key] != entry {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.entries, key)
+	s.mu.Unlock()
+	entry.flush()
+}
+
+func (e *throttleEntry) flush() {
+	if e.repeated == 0 {
+		return
+	}
+	record := slog.NewRecord(time.Now(), e.record.Level, fmt.Sprintf("%s (repeated %d times)", e.record.Message, e.repeated), e.record.PC)
+	e.record.Attrs(func(attr slog.Attr) bool {
+		record.AddAttrs(attr)
+		return true
+	})
+	_ = e.next.Handle(context.Background(), record)
+}
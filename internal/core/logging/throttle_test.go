@@ -0,0 +1,137 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingHandler keeps the messages of the records it handles
+type recordingHandler struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (h *recordingHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *recordingHandler) Handle(_ context.Context, record slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.messages = append(h.messages, record.Message)
+	return nil
+}
+
+func (h *recordingHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *recordingHandler) WithGroup(string) slog.Handler      { return h }
+
+func (h *recordingHandler) logged() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.messages...)
+}
+
+func TestThrottleHandler(t *testing.T) {
+	next := &recordingHandler{}
+	throttle := NewThrottleHandler(next, ThrottleOptions{Window: time.Minute, Exempt: DefaultThrottleExempt})
+	logger := slog.New(throttle)
+
+	for range 1000 {
+		logger.Warn("provider unavailable", "provider", "openai")
+	}
+	if got := next.logged(); len(got) != 1 {
+		t.Fatalf("logged %d records within the window, want 1", len(got))
+	}
+	throttle.Flush()
+	got := next.logged()
+	if len(got) != 2 || got[1] != "provider unavailable (repeated 999 times)" {
+		t.Errorf("logged %q, want the warning and its repetitions", got)
+	}
+}
+
+func TestThrottleHandlerDistinctAttributes(t *testing.T) {
+	next := &recordingHandler{}
+	throttle := NewThrottleHandler(next, ThrottleOptions{Window: time.Minute})
+	logger := slog.New(throttle)
+
+	for i := range 1000 {
+		provider := "openai"
+		if i%2 == 1 {
+			provider = "anthropic"
+		}
+		logger.Warn("provider unavailable", "provider", provider)
+	}
+	logger.With("provider", "gemini").Warn("provider unavailable")
+	logger.Error("provider unavailable", "provider", "openai")
+	throttle.Flush()
+
+	got := next.logged()
+	want := map[string]int{
+		"provider unavailable":                      4,
+		"provider unavailable (repeated 499 times)": 2,
+	}
+	counts := make(map[string]int)
+	for _, msg := range got {
+		counts[msg]++
+	}
+	if !maps.Equal(counts, want) {
+		t.Errorf("logged %v, want %v", counts, want)
+	}
+}
+
+func TestThrottleHandlerExempt(t *testing.T) {
+	next := &recordingHandler{}
+	logger := slog.New(NewThrottleHandler(next, ThrottleOptions{Window: time.Minute, Exempt: DefaultThrottleExempt}))
+
+	for range 10 {
+		logger.Error("Panic in agent", ComponentKey, ComponentPanic)
+		logger.With(ComponentKey, ComponentDataLoss).Warn("message not saved")
+	}
+	if got := len(next.logged()); got != 20 {
+		t.Errorf("logged %d exempt records, want 20", got)
+	}
+}
+
+func TestThrottleHandlerWindowCloses(t *testing.T) {
+	next := &recordingHandler{}
+	logger := slog.New(NewThrottleHandler(next, ThrottleOptions{Window: 20 * time.Millisecond}))
+
+	logger.Warn("retrying")
+	logger.Warn("retrying")
+	deadline := time.Now().Add(time.Second)
+	for len(next.logged()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	// A new window starts once the previous one closed
+	logger.Warn("retrying")
+	got := next.logged()
+	if len(got) != 3 || got[1] != "retrying (repeated 1 times)" || got[2] != "retrying" {
+		t.Errorf("logged %q", got)
+	}
+}
+
+func TestThrottleHandlerBounded(t *testing.T) {
+	next := &recordingHandler{}
+	throttle := NewThrottleHandler(next, ThrottleOptions{Window: time.Minute, MaxEntries: 2})
+	logger := slog.New(throttle)
+
+	for range 2 {
+		for _, msg := range []string{"a", "b", "c"} {
+			logger.Warn(msg)
+		}
+	}
+	if entries := len(throttle.state.entries); entries != 2 {
+		t.Errorf("tracked %d logs, want at most 2", entries)
+	}
+	// The logs beyond the bound pass through
+	if got := len(next.logged()); got != 4 {
+		t.Errorf("logged %d records, want a, b and c twice", got)
+	}
+
+	throttle.Configure(ThrottleOptions{Window: time.Minute})
+	if entries := len(throttle.state.entries); entries != 0 {
+		t.Errorf("tracked %d logs after Configure(), want none", entries)
+	}
+}
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
@@ -15,6 +16,9 @@ import (
 	"github.com/pressly/goose/v3"
 )
 
+// Filename is the database file in the data directory
+const Filename = "opencode.db"
+
 func Connect() (*sql.DB, error) {
 	dataDir := config.Get().Data.Directory
 	if dataDir == "" {
@@ -23,7 +27,7 @@ func Connect() (*sql.DB, error) {
 	if err := os.MkdirAll(dataDir, 0o700); err != nil {
 		return nil, fmt.Errorf("failed to create data directory: %w", err)
 	}
-	dbPath := filepath.Join(dataDir, "opencode.db")
+	dbPath := filepath.Join(dataDir, Filename)
 	// Open the SQLite database
 	db, err := sql.Open("sqlite3", dbPath)
 	if err != nil {
@@ -66,3 +70,44 @@ func Connect() (*sql.DB, error) {
 	}
 	return db, nil
 }
+
+// MigrationStatus returns the schema version of the database in the data
+// directory and the latest version known to this build, without migrating or
+// otherwise writing it. The current version is 0 when the database was not
+// created or migrated yet.
+func MigrationStatus(ctx context.Context) (current, latest int64, err error) {
+	goose.SetBaseFS(FS)
+	migrations, err := goose.CollectMigrations("migrations", 0, goose.MaxVersion)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to list migrations: %w", err)
+	}
+	last, err := migrations.Last()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to list migrations: %w", err)
+	}
+	latest = last.Version
+
+	dbPath := filepath.Join(config.Get().Data.Directory, Filename)
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return 0, latest, nil
+	}
+	// Opened read-only, goose creates its version table when it is missing
+	db, err := sql.Open("sqlite3", "file:"+dbPath+"?mode=ro")
+	if err != nil {
+		return 0, latest, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+	var tables int
+	err = db.QueryRowContext(ctx, "SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?", goose.TableName()).Scan(&tables)
+	if err != nil {
+		return 0, latest, fmt.Errorf("failed to read the schema version: %w", err)
+	}
+	if tables == 0 {
+		return 0, latest, nil
+	}
+	err = db.QueryRowContext(ctx, fmt.Sprintf("SELECT COALESCE(MAX(version_id), 0) FROM %s WHERE is_applied", goose.TableName())).Scan(&current)
+	if err != nil {
+		return 0, latest, fmt.Errorf("failed to read the schema version: %w", err)
+	}
+	return current, latest, nil
+}
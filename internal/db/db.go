@@ -24,6 +24,27 @@ func New(db DBTX) *Queries {
 func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	q := Queries{db: db}
 	var err error
+	if q.acquireSessionLockStmt, err = db.PrepareContext(ctx, acquireSessionLock); err != nil {
+		return nil, fmt.Errorf("error preparing query AcquireSessionLock: %w", err)
+	}
+	if q.countSessionToolOperationsStmt, err = db.PrepareContext(ctx, countSessionToolOperations); err != nil {
+		return nil, fmt.Errorf("error preparing query CountSessionToolOperations: %w", err)
+	}
+	if q.createAgentMemoryStmt, err = db.PrepareContext(ctx, createAgentMemory); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateAgentMemory: %w", err)
+	}
+	if q.createBatchStmt, err = db.PrepareContext(ctx, createBatch); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateBatch: %w", err)
+	}
+	if q.createBatchItemStmt, err = db.PrepareContext(ctx, createBatchItem); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateBatchItem: %w", err)
+	}
+	if q.createCitationStmt, err = db.PrepareContext(ctx, createCitation); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateCitation: %w", err)
+	}
+	if q.createConfigSnapshotStmt, err = db.PrepareContext(ctx, createConfigSnapshot); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateConfigSnapshot: %w", err)
+	}
 	if q.createFileStmt, err = db.PrepareContext(ctx, createFile); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateFile: %w", err)
 	}
@@ -33,12 +54,24 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.createSessionStmt, err = db.PrepareContext(ctx, createSession); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateSession: %w", err)
 	}
+	if q.createToolOperationStmt, err = db.PrepareContext(ctx, createToolOperation); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateToolOperation: %w", err)
+	}
+	if q.deleteAnalyticsRollupsStmt, err = db.PrepareContext(ctx, deleteAnalyticsRollups); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteAnalyticsRollups: %w", err)
+	}
 	if q.deleteFileStmt, err = db.PrepareContext(ctx, deleteFile); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteFile: %w", err)
 	}
+	if q.deleteInstanceStmt, err = db.PrepareContext(ctx, deleteInstance); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteInstance: %w", err)
+	}
 	if q.deleteMessageStmt, err = db.PrepareContext(ctx, deleteMessage); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteMessage: %w", err)
 	}
+	if q.deleteMessageCitationsStmt, err = db.PrepareContext(ctx, deleteMessageCitations); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteMessageCitations: %w", err)
+	}
 	if q.deleteSessionStmt, err = db.PrepareContext(ctx, deleteSession); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteSession: %w", err)
 	}
@@ -48,6 +81,15 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.deleteSessionMessagesStmt, err = db.PrepareContext(ctx, deleteSessionMessages); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteSessionMessages: %w", err)
 	}
+	if q.deleteStaleInstancesStmt, err = db.PrepareContext(ctx, deleteStaleInstances); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteStaleInstances: %w", err)
+	}
+	if q.getBatchStmt, err = db.PrepareContext(ctx, getBatch); err != nil {
+		return nil, fmt.Errorf("error preparing query GetBatch: %w", err)
+	}
+	if q.getConfigSnapshotStmt, err = db.PrepareContext(ctx, getConfigSnapshot); err != nil {
+		return nil, fmt.Errorf("error preparing query GetConfigSnapshot: %w", err)
+	}
 	if q.getFileStmt, err = db.PrepareContext(ctx, getFile); err != nil {
 		return nil, fmt.Errorf("error preparing query GetFile: %w", err)
 	}
@@ -60,24 +102,96 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.getSessionByIDStmt, err = db.PrepareContext(ctx, getSessionByID); err != nil {
 		return nil, fmt.Errorf("error preparing query GetSessionByID: %w", err)
 	}
+	if q.getSessionLockStmt, err = db.PrepareContext(ctx, getSessionLock); err != nil {
+		return nil, fmt.Errorf("error preparing query GetSessionLock: %w", err)
+	}
+	if q.getToolOperationStmt, err = db.PrepareContext(ctx, getToolOperation); err != nil {
+		return nil, fmt.Errorf("error preparing query GetToolOperation: %w", err)
+	}
+	if q.importMessageStmt, err = db.PrepareContext(ctx, importMessage); err != nil {
+		return nil, fmt.Errorf("error preparing query ImportMessage: %w", err)
+	}
+	if q.importSessionStmt, err = db.PrepareContext(ctx, importSession); err != nil {
+		return nil, fmt.Errorf("error preparing query ImportSession: %w", err)
+	}
+	if q.listAnalyticsRollupsStmt, err = db.PrepareContext(ctx, listAnalyticsRollups); err != nil {
+		return nil, fmt.Errorf("error preparing query ListAnalyticsRollups: %w", err)
+	}
+	if q.listBatchItemsStmt, err = db.PrepareContext(ctx, listBatchItems); err != nil {
+		return nil, fmt.Errorf("error preparing query ListBatchItems: %w", err)
+	}
+	if q.listBatchesStmt, err = db.PrepareContext(ctx, listBatches); err != nil {
+		return nil, fmt.Errorf("error preparing query ListBatches: %w", err)
+	}
+	if q.listChildSessionsStmt, err = db.PrepareContext(ctx, listChildSessions); err != nil {
+		return nil, fmt.Errorf("error preparing query ListChildSessions: %w", err)
+	}
+	if q.listCitingSessionsStmt, err = db.PrepareContext(ctx, listCitingSessions); err != nil {
+		return nil, fmt.Errorf("error preparing query ListCitingSessions: %w", err)
+	}
 	if q.listFilesByPathStmt, err = db.PrepareContext(ctx, listFilesByPath); err != nil {
 		return nil, fmt.Errorf("error preparing query ListFilesByPath: %w", err)
 	}
 	if q.listFilesBySessionStmt, err = db.PrepareContext(ctx, listFilesBySession); err != nil {
 		return nil, fmt.Errorf("error preparing query ListFilesBySession: %w", err)
 	}
+	if q.listInstancesStmt, err = db.PrepareContext(ctx, listInstances); err != nil {
+		return nil, fmt.Errorf("error preparing query ListInstances: %w", err)
+	}
 	if q.listLatestSessionFilesStmt, err = db.PrepareContext(ctx, listLatestSessionFiles); err != nil {
 		return nil, fmt.Errorf("error preparing query ListLatestSessionFiles: %w", err)
 	}
 	if q.listMessagesBySessionStmt, err = db.PrepareContext(ctx, listMessagesBySession); err != nil {
 		return nil, fmt.Errorf("error preparing query ListMessagesBySession: %w", err)
 	}
+	if q.listMessagesByStatusStmt, err = db.PrepareContext(ctx, listMessagesByStatus); err != nil {
+		return nil, fmt.Errorf("error preparing query ListMessagesByStatus: %w", err)
+	}
 	if q.listNewFilesStmt, err = db.PrepareContext(ctx, listNewFiles); err != nil {
 		return nil, fmt.Errorf("error preparing query ListNewFiles: %w", err)
 	}
+	if q.listOpenBatchesStmt, err = db.PrepareContext(ctx, listOpenBatches); err != nil {
+		return nil, fmt.Errorf("error preparing query ListOpenBatches: %w", err)
+	}
+	if q.listRecentAgentMemoryStmt, err = db.PrepareContext(ctx, listRecentAgentMemory); err != nil {
+		return nil, fmt.Errorf("error preparing query ListRecentAgentMemory: %w", err)
+	}
+	if q.listSessionLocksStmt, err = db.PrepareContext(ctx, listSessionLocks); err != nil {
+		return nil, fmt.Errorf("error preparing query ListSessionLocks: %w", err)
+	}
 	if q.listSessionsStmt, err = db.PrepareContext(ctx, listSessions); err != nil {
 		return nil, fmt.Errorf("error preparing query ListSessions: %w", err)
 	}
+	if q.listSpaceSessionsStmt, err = db.PrepareContext(ctx, listSpaceSessions); err != nil {
+		return nil, fmt.Errorf("error preparing query ListSpaceSessions: %w", err)
+	}
+	if q.listUndeliveredBatchesStmt, err = db.PrepareContext(ctx, listUndeliveredBatches); err != nil {
+		return nil, fmt.Errorf("error preparing query ListUndeliveredBatches: %w", err)
+	}
+	if q.listUserMessageCountsStmt, err = db.PrepareContext(ctx, listUserMessageCounts); err != nil {
+		return nil, fmt.Errorf("error preparing query ListUserMessageCounts: %w", err)
+	}
+	if q.moveMessageStmt, err = db.PrepareContext(ctx, moveMessage); err != nil {
+		return nil, fmt.Errorf("error preparing query MoveMessage: %w", err)
+	}
+	if q.pruneAgentMemoryStmt, err = db.PrepareContext(ctx, pruneAgentMemory); err != nil {
+		return nil, fmt.Errorf("error preparing query PruneAgentMemory: %w", err)
+	}
+	if q.releaseInstanceSessionLocksStmt, err = db.PrepareContext(ctx, releaseInstanceSessionLocks); err != nil {
+		return nil, fmt.Errorf("error preparing query ReleaseInstanceSessionLocks: %w", err)
+	}
+	if q.releaseSessionLockStmt, err = db.PrepareContext(ctx, releaseSessionLock); err != nil {
+		return nil, fmt.Errorf("error preparing query ReleaseSessionLock: %w", err)
+	}
+	if q.touchAgentMemoryStmt, err = db.PrepareContext(ctx, touchAgentMemory); err != nil {
+		return nil, fmt.Errorf("error preparing query TouchAgentMemory: %w", err)
+	}
+	if q.updateBatchStmt, err = db.PrepareContext(ctx, updateBatch); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateBatch: %w", err)
+	}
+	if q.updateBatchItemStmt, err = db.PrepareContext(ctx, updateBatchItem); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateBatchItem: %w", err)
+	}
 	if q.updateFileStmt, err = db.PrepareContext(ctx, updateFile); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateFile: %w", err)
 	}
@@ -87,11 +201,52 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.updateSessionStmt, err = db.PrepareContext(ctx, updateSession); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateSession: %w", err)
 	}
+	if q.upsertAnalyticsRollupStmt, err = db.PrepareContext(ctx, upsertAnalyticsRollup); err != nil {
+		return nil, fmt.Errorf("error preparing query UpsertAnalyticsRollup: %w", err)
+	}
+	if q.upsertInstanceStmt, err = db.PrepareContext(ctx, upsertInstance); err != nil {
+		return nil, fmt.Errorf("error preparing query UpsertInstance: %w", err)
+	}
 	return &q, nil
 }
 
 func (q *Queries) Close() error {
 	var err error
+	if q.acquireSessionLockStmt != nil {
+		if cerr := q.acquireSessionLockStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing acquireSessionLockStmt: %w", cerr)
+		}
+	}
+	if q.countSessionToolOperationsStmt != nil {
+		if cerr := q.countSessionToolOperationsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countSessionToolOperationsStmt: %w", cerr)
+		}
+	}
+	if q.createAgentMemoryStmt != nil {
+		if cerr := q.createAgentMemoryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createAgentMemoryStmt: %w", cerr)
+		}
+	}
+	if q.createBatchStmt != nil {
+		if cerr := q.createBatchStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createBatchStmt: %w", cerr)
+		}
+	}
+	if q.createBatchItemStmt != nil {
+		if cerr := q.createBatchItemStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createBatchItemStmt: %w", cerr)
+		}
+	}
+	if q.createCitationStmt != nil {
+		if cerr := q.createCitationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createCitationStmt: %w", cerr)
+		}
+	}
+	if q.createConfigSnapshotStmt != nil {
+		if cerr := q.createConfigSnapshotStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createConfigSnapshotStmt: %w", cerr)
+		}
+	}
 	if q.createFileStmt != nil {
 		if cerr := q.createFileStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createFileStmt: %w", cerr)
@@ -107,16 +262,36 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing createSessionStmt: %w", cerr)
 		}
 	}
+	if q.createToolOperationStmt != nil {
+		if cerr := q.createToolOperationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createToolOperationStmt: %w", cerr)
+		}
+	}
+	if q.deleteAnalyticsRollupsStmt != nil {
+		if cerr := q.deleteAnalyticsRollupsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteAnalyticsRollupsStmt: %w", cerr)
+		}
+	}
 	if q.deleteFileStmt != nil {
 		if cerr := q.deleteFileStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteFileStmt: %w", cerr)
 		}
 	}
+	if q.deleteInstanceStmt != nil {
+		if cerr := q.deleteInstanceStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteInstanceStmt: %w", cerr)
+		}
+	}
 	if q.deleteMessageStmt != nil {
 		if cerr := q.deleteMessageStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteMessageStmt: %w", cerr)
 		}
 	}
+	if q.deleteMessageCitationsStmt != nil {
+		if cerr := q.deleteMessageCitationsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteMessageCitationsStmt: %w", cerr)
+		}
+	}
 	if q.deleteSessionStmt != nil {
 		if cerr := q.deleteSessionStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteSessionStmt: %w", cerr)
@@ -132,6 +307,21 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing deleteSessionMessagesStmt: %w", cerr)
 		}
 	}
+	if q.deleteStaleInstancesStmt != nil {
+		if cerr := q.deleteStaleInstancesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteStaleInstancesStmt: %w", cerr)
+		}
+	}
+	if q.getBatchStmt != nil {
+		if cerr := q.getBatchStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getBatchStmt: %w", cerr)
+		}
+	}
+	if q.getConfigSnapshotStmt != nil {
+		if cerr := q.getConfigSnapshotStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getConfigSnapshotStmt: %w", cerr)
+		}
+	}
 	if q.getFileStmt != nil {
 		if cerr := q.getFileStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getFileStmt: %w", cerr)
@@ -152,6 +342,51 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getSessionByIDStmt: %w", cerr)
 		}
 	}
+	if q.getSessionLockStmt != nil {
+		if cerr := q.getSessionLockStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getSessionLockStmt: %w", cerr)
+		}
+	}
+	if q.getToolOperationStmt != nil {
+		if cerr := q.getToolOperationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getToolOperationStmt: %w", cerr)
+		}
+	}
+	if q.importMessageStmt != nil {
+		if cerr := q.importMessageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing importMessageStmt: %w", cerr)
+		}
+	}
+	if q.importSessionStmt != nil {
+		if cerr := q.importSessionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing importSessionStmt: %w", cerr)
+		}
+	}
+	if q.listAnalyticsRollupsStmt != nil {
+		if cerr := q.listAnalyticsRollupsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listAnalyticsRollupsStmt: %w", cerr)
+		}
+	}
+	if q.listBatchItemsStmt != nil {
+		if cerr := q.listBatchItemsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listBatchItemsStmt: %w", cerr)
+		}
+	}
+	if q.listBatchesStmt != nil {
+		if cerr := q.listBatchesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listBatchesStmt: %w", cerr)
+		}
+	}
+	if q.listChildSessionsStmt != nil {
+		if cerr := q.listChildSessionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listChildSessionsStmt: %w", cerr)
+		}
+	}
+	if q.listCitingSessionsStmt != nil {
+		if cerr := q.listCitingSessionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listCitingSessionsStmt: %w", cerr)
+		}
+	}
 	if q.listFilesByPathStmt != nil {
 		if cerr := q.listFilesByPathStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listFilesByPathStmt: %w", cerr)
@@ -162,6 +397,11 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing listFilesBySessionStmt: %w", cerr)
 		}
 	}
+	if q.listInstancesStmt != nil {
+		if cerr := q.listInstancesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listInstancesStmt: %w", cerr)
+		}
+	}
 	if q.listLatestSessionFilesStmt != nil {
 		if cerr := q.listLatestSessionFilesStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listLatestSessionFilesStmt: %w", cerr)
@@ -172,16 +412,86 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing listMessagesBySessionStmt: %w", cerr)
 		}
 	}
+	if q.listMessagesByStatusStmt != nil {
+		if cerr := q.listMessagesByStatusStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listMessagesByStatusStmt: %w", cerr)
+		}
+	}
 	if q.listNewFilesStmt != nil {
 		if cerr := q.listNewFilesStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listNewFilesStmt: %w", cerr)
 		}
 	}
+	if q.listOpenBatchesStmt != nil {
+		if cerr := q.listOpenBatchesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listOpenBatchesStmt: %w", cerr)
+		}
+	}
+	if q.listRecentAgentMemoryStmt != nil {
+		if cerr := q.listRecentAgentMemoryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listRecentAgentMemoryStmt: %w", cerr)
+		}
+	}
+	if q.listSessionLocksStmt != nil {
+		if cerr := q.listSessionLocksStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listSessionLocksStmt: %w", cerr)
+		}
+	}
 	if q.listSessionsStmt != nil {
 		if cerr := q.listSessionsStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listSessionsStmt: %w", cerr)
 		}
 	}
+	if q.listSpaceSessionsStmt != nil {
+		if cerr := q.listSpaceSessionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listSpaceSessionsStmt: %w", cerr)
+		}
+	}
+	if q.listUndeliveredBatchesStmt != nil {
+		if cerr := q.listUndeliveredBatchesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listUndeliveredBatchesStmt: %w", cerr)
+		}
+	}
+	if q.listUserMessageCountsStmt != nil {
+		if cerr := q.listUserMessageCountsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listUserMessageCountsStmt: %w", cerr)
+		}
+	}
+	if q.moveMessageStmt != nil {
+		if cerr := q.moveMessageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing moveMessageStmt: %w", cerr)
+		}
+	}
+	if q.pruneAgentMemoryStmt != nil {
+		if cerr := q.pruneAgentMemoryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing pruneAgentMemoryStmt: %w", cerr)
+		}
+	}
+	if q.releaseInstanceSessionLocksStmt != nil {
+		if cerr := q.releaseInstanceSessionLocksStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing releaseInstanceSessionLocksStmt: %w", cerr)
+		}
+	}
+	if q.releaseSessionLockStmt != nil {
+		if cerr := q.releaseSessionLockStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing releaseSessionLockStmt: %w", cerr)
+		}
+	}
+	if q.touchAgentMemoryStmt != nil {
+		if cerr := q.touchAgentMemoryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing touchAgentMemoryStmt: %w", cerr)
+		}
+	}
+	if q.updateBatchStmt != nil {
+		if cerr := q.updateBatchStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateBatchStmt: %w", cerr)
+		}
+	}
+	if q.updateBatchItemStmt != nil {
+		if cerr := q.updateBatchItemStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateBatchItemStmt: %w", cerr)
+		}
+	}
 	if q.updateFileStmt != nil {
 		if cerr := q.updateFileStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing updateFileStmt: %w", cerr)
@@ -197,6 +507,16 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing updateSessionStmt: %w", cerr)
 		}
 	}
+	if q.upsertAnalyticsRollupStmt != nil {
+		if cerr := q.upsertAnalyticsRollupStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing upsertAnalyticsRollupStmt: %w", cerr)
+		}
+	}
+	if q.upsertInstanceStmt != nil {
+		if cerr := q.upsertInstanceStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing upsertInstanceStmt: %w", cerr)
+		}
+	}
 	return err
 }
 
@@ -234,55 +554,135 @@ func (q *Queries) queryRow(ctx context.Context, stmt *sql.Stmt, query string, ar
 }
 
 type Queries struct {
-	db                          DBTX
-	tx                          *sql.Tx
-	createFileStmt              *sql.Stmt
-	createMessageStmt           *sql.Stmt
-	createSessionStmt           *sql.Stmt
-	deleteFileStmt              *sql.Stmt
-	deleteMessageStmt           *sql.Stmt
-	deleteSessionStmt           *sql.Stmt
-	deleteSessionFilesStmt      *sql.Stmt
-	deleteSessionMessagesStmt   *sql.Stmt
-	getFileStmt                 *sql.Stmt
-	getFileByPathAndSessionStmt *sql.Stmt
-	getMessageStmt              *sql.Stmt
-	getSessionByIDStmt          *sql.Stmt
-	listFilesByPathStmt         *sql.Stmt
-	listFilesBySessionStmt      *sql.Stmt
-	listLatestSessionFilesStmt  *sql.Stmt
-	listMessagesBySessionStmt   *sql.Stmt
-	listNewFilesStmt            *sql.Stmt
-	listSessionsStmt            *sql.Stmt
-	updateFileStmt              *sql.Stmt
-	updateMessageStmt           *sql.Stmt
-	updateSessionStmt           *sql.Stmt
+	db                              DBTX
+	tx                              *sql.Tx
+	acquireSessionLockStmt          *sql.Stmt
+	countSessionToolOperationsStmt  *sql.Stmt
+	createAgentMemoryStmt           *sql.Stmt
+	createBatchStmt                 *sql.Stmt
+	createBatchItemStmt             *sql.Stmt
+	createCitationStmt              *sql.Stmt
+	createConfigSnapshotStmt        *sql.Stmt
+	createFileStmt                  *sql.Stmt
+	createMessageStmt               *sql.Stmt
+	createSessionStmt               *sql.Stmt
+	createToolOperationStmt         *sql.Stmt
+	deleteAnalyticsRollupsStmt      *sql.Stmt
+	deleteFileStmt                  *sql.Stmt
+	deleteInstanceStmt              *sql.Stmt
+	deleteMessageStmt               *sql.Stmt
+	deleteMessageCitationsStmt      *sql.Stmt
+	deleteSessionStmt               *sql.Stmt
+	deleteSessionFilesStmt          *sql.Stmt
+	deleteSessionMessagesStmt       *sql.Stmt
+	deleteStaleInstancesStmt        *sql.Stmt
+	getBatchStmt                    *sql.Stmt
+	getConfigSnapshotStmt           *sql.Stmt
+	getFileStmt                     *sql.Stmt
+	getFileByPathAndSessionStmt     *sql.Stmt
+	getMessageStmt                  *sql.Stmt
+	getSessionByIDStmt              *sql.Stmt
+	getSessionLockStmt              *sql.Stmt
+	getToolOperationStmt            *sql.Stmt
+	importMessageStmt               *sql.Stmt
+	importSessionStmt               *sql.Stmt
+	listAnalyticsRollupsStmt        *sql.Stmt
+	listBatchItemsStmt              *sql.Stmt
+	listBatchesStmt                 *sql.Stmt
+	listChildSessionsStmt           *sql.Stmt
+	listCitingSessionsStmt          *sql.Stmt
+	listFilesByPathStmt             *sql.Stmt
+	listFilesBySessionStmt          *sql.Stmt
+	listInstancesStmt               *sql.Stmt
+	listLatestSessionFilesStmt      *sql.Stmt
+	listMessagesBySessionStmt       *sql.Stmt
+	listMessagesByStatusStmt        *sql.Stmt
+	listNewFilesStmt                *sql.Stmt
+	listOpenBatchesStmt             *sql.Stmt
+	listRecentAgentMemoryStmt       *sql.Stmt
+	listSessionLocksStmt            *sql.Stmt
+	listSessionsStmt                *sql.Stmt
+	listSpaceSessionsStmt           *sql.Stmt
+	listUndeliveredBatchesStmt      *sql.Stmt
+	listUserMessageCountsStmt       *sql.Stmt
+	moveMessageStmt                 *sql.Stmt
+	pruneAgentMemoryStmt            *sql.Stmt
+	releaseInstanceSessionLocksStmt *sql.Stmt
+	releaseSessionLockStmt          *sql.Stmt
+	touchAgentMemoryStmt            *sql.Stmt
+	updateBatchStmt                 *sql.Stmt
+	updateBatchItemStmt             *sql.Stmt
+	updateFileStmt                  *sql.Stmt
+	updateMessageStmt               *sql.Stmt
+	updateSessionStmt               *sql.Stmt
+	upsertAnalyticsRollupStmt       *sql.Stmt
+	upsertInstanceStmt              *sql.Stmt
 }
 
 func (q *Queries) WithTx(tx *sql.Tx) *Queries {
 	return &Queries{
-		db:                          tx,
-		tx:                          tx,
-		createFileStmt:              q.createFileStmt,
-		createMessageStmt:           q.createMessageStmt,
-		createSessionStmt:           q.createSessionStmt,
-		deleteFileStmt:              q.deleteFileStmt,
-		deleteMessageStmt:           q.deleteMessageStmt,
-		deleteSessionStmt:           q.deleteSessionStmt,
-		deleteSessionFilesStmt:      q.deleteSessionFilesStmt,
-		deleteSessionMessagesStmt:   q.deleteSessionMessagesStmt,
-		getFileStmt:                 q.getFileStmt,
-		getFileByPathAndSessionStmt: q.getFileByPathAndSessionStmt,
-		getMessageStmt:              q.getMessageStmt,
-		getSessionByIDStmt:          q.getSessionByIDStmt,
-		listFilesByPathStmt:         q.listFilesByPathStmt,
-		listFilesBySessionStmt:      q.listFilesBySessionStmt,
-		listLatestSessionFilesStmt:  q.listLatestSessionFilesStmt,
-		listMessagesBySessionStmt:   q.listMessagesBySessionStmt,
-		listNewFilesStmt:            q.listNewFilesStmt,
-		listSessionsStmt:            q.listSessionsStmt,
-		updateFileStmt:              q.updateFileStmt,
-		updateMessageStmt:           q.updateMessageStmt,
-		updateSessionStmt:           q.updateSessionStmt,
+		db:                              tx,
+		tx:                              tx,
+		acquireSessionLockStmt:          q.acquireSessionLockStmt,
+		countSessionToolOperationsStmt:  q.countSessionToolOperationsStmt,
+		createAgentMemoryStmt:           q.createAgentMemoryStmt,
+		createBatchStmt:                 q.createBatchStmt,
+		createBatchItemStmt:             q.createBatchItemStmt,
+		createCitationStmt:              q.createCitationStmt,
+		createConfigSnapshotStmt:        q.createConfigSnapshotStmt,
+		createFileStmt:                  q.createFileStmt,
+		createMessageStmt:               q.createMessageStmt,
+		createSessionStmt:               q.createSessionStmt,
+		createToolOperationStmt:         q.createToolOperationStmt,
+		deleteAnalyticsRollupsStmt:      q.deleteAnalyticsRollupsStmt,
+		deleteFileStmt:                  q.deleteFileStmt,
+		deleteInstanceStmt:              q.deleteInstanceStmt,
+		deleteMessageStmt:               q.deleteMessageStmt,
+		deleteMessageCitationsStmt:      q.deleteMessageCitationsStmt,
+		deleteSessionStmt:               q.deleteSessionStmt,
+		deleteSessionFilesStmt:          q.deleteSessionFilesStmt,
+		deleteSessionMessagesStmt:       q.deleteSessionMessagesStmt,
+		deleteStaleInstancesStmt:        q.deleteStaleInstancesStmt,
+		getBatchStmt:                    q.getBatchStmt,
+		getConfigSnapshotStmt:           q.getConfigSnapshotStmt,
+		getFileStmt:                     q.getFileStmt,
+		getFileByPathAndSessionStmt:     q.getFileByPathAndSessionStmt,
+		getMessageStmt:                  q.getMessageStmt,
+		getSessionByIDStmt:              q.getSessionByIDStmt,
+		getSessionLockStmt:              q.getSessionLockStmt,
+		getToolOperationStmt:            q.getToolOperationStmt,
+		importMessageStmt:               q.importMessageStmt,
+		importSessionStmt:               q.importSessionStmt,
+		listAnalyticsRollupsStmt:        q.listAnalyticsRollupsStmt,
+		listBatchItemsStmt:              q.listBatchItemsStmt,
+		listBatchesStmt:                 q.listBatchesStmt,
+		listChildSessionsStmt:           q.listChildSessionsStmt,
+		listCitingSessionsStmt:          q.listCitingSessionsStmt,
+		listFilesByPathStmt:             q.listFilesByPathStmt,
+		listFilesBySessionStmt:          q.listFilesBySessionStmt,
+		listInstancesStmt:               q.listInstancesStmt,
+		listLatestSessionFilesStmt:      q.listLatestSessionFilesStmt,
+		listMessagesBySessionStmt:       q.listMessagesBySessionStmt,
+		listMessagesByStatusStmt:        q.listMessagesByStatusStmt,
+		listNewFilesStmt:                q.listNewFilesStmt,
+		listOpenBatchesStmt:             q.listOpenBatchesStmt,
+		listRecentAgentMemoryStmt:       q.listRecentAgentMemoryStmt,
+		listSessionLocksStmt:            q.listSessionLocksStmt,
+		listSessionsStmt:                q.listSessionsStmt,
+		listSpaceSessionsStmt:           q.listSpaceSessionsStmt,
+		listUndeliveredBatchesStmt:      q.listUndeliveredBatchesStmt,
+		listUserMessageCountsStmt:       q.listUserMessageCountsStmt,
+		moveMessageStmt:                 q.moveMessageStmt,
+		pruneAgentMemoryStmt:            q.pruneAgentMemoryStmt,
+		releaseInstanceSessionLocksStmt: q.releaseInstanceSessionLocksStmt,
+		releaseSessionLockStmt:          q.releaseSessionLockStmt,
+		touchAgentMemoryStmt:            q.touchAgentMemoryStmt,
+		updateBatchStmt:                 q.updateBatchStmt,
+		updateBatchItemStmt:             q.updateBatchItemStmt,
+		updateFileStmt:                  q.updateFileStmt,
+		updateMessageStmt:               q.updateMessageStmt,
+		updateSessionStmt:               q.updateSessionStmt,
+		upsertAnalyticsRollupStmt:       q.upsertAnalyticsRollupStmt,
+		upsertInstanceStmt:              q.upsertInstanceStmt,
 	}
 }
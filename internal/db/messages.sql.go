@@ -17,12 +17,13 @@ INSERT INTO messages (
     role,
     parts,
     model,
+    status,
     created_at,
     updated_at
 ) VALUES (
-    ?, ?, ?, ?, ?, strftime('%s', 'now'), strftime('%s', 'now')
+    ?, ?, ?, ?, ?, ?, strftime('%s', 'now'), strftime('%s', 'now')
 )
-RETURNING id, session_id, role, parts, model, created_at, updated_at, finished_at
+RETURNING id, session_id, role, parts, model, created_at, updated_at, finished_at, status
 `
 
 type CreateMessageParams struct {
@@ -31,6 +32,7 @@ type CreateMessageParams struct {
 	Role      string         `json:"role"`
 	Parts     string         `json:"parts"`
 	Model     sql.NullString `json:"model"`
+	Status    string         `json:"status"`
 }
 
 func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (Message, error) {
@@ -40,6 +42,7 @@ func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (M
 		arg.Role,
 		arg.Parts,
 		arg.Model,
+		arg.Status,
 	)
 	var i Message
 	err := row.Scan(
@@ -51,6 +54,7 @@ func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (M
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.FinishedAt,
+		&i.Status,
 	)
 	return i, err
 }
@@ -76,7 +80,7 @@ func (q *Queries) DeleteSessionMessages(ctx context.Context, sessionID string) e
 }
 
 const getMessage = `-- name: GetMessage :one
-SELECT id, session_id, role, parts, model, created_at, updated_at, finished_at
+SELECT id, session_id, role, parts, model, created_at, updated_at, finished_at, status
 FROM messages
 WHERE id = ? LIMIT 1
 `
@@ -93,12 +97,53 @@ func (q *Queries) GetMessage(ctx context.Context, id string) (Message, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.FinishedAt,
+		&i.Status,
 	)
 	return i, err
 }
 
+const importMessage = `-- name: ImportMessage :exec
+INSERT INTO messages (
+    id,
+    session_id,
+    role,
+    parts,
+    model,
+    created_at,
+    updated_at,
+    finished_at
+) VALUES (
+    ?, ?, ?, ?, ?, ?, ?, ?
+)
+`
+
+type ImportMessageParams struct {
+	ID         string         `json:"id"`
+	SessionID  string         `json:"session_id"`
+	Role       string         `json:"role"`
+	Parts      string         `json:"parts"`
+	Model      sql.NullString `json:"model"`
+	CreatedAt  int64          `json:"created_at"`
+	UpdatedAt  int64          `json:"updated_at"`
+	FinishedAt sql.NullInt64  `json:"finished_at"`
+}
+
+func (q *Queries) ImportMessage(ctx context.Context, arg ImportMessageParams) error {
+	_, err := q.exec(ctx, q.importMessageStmt, importMessage,
+		arg.ID,
+		arg.SessionID,
+		arg.Role,
+		arg.Parts,
+		arg.Model,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.FinishedAt,
+	)
+	return err
+}
+
 const listMessagesBySession = `-- name: ListMessagesBySession :many
-SELECT id, session_id, role, parts, model, created_at, updated_at, finished_at
+SELECT id, session_id, role, parts, model, created_at, updated_at, finished_at, status
 FROM messages
 WHERE session_id = ?
 ORDER BY created_at ASC
@@ -122,6 +167,47 @@ func (q *Queries) ListMessagesBySession(ctx context.Context, sessionID string) (
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.FinishedAt,
+			&i.Status,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMessagesByStatus = `-- name: ListMessagesByStatus :many
+SELECT id, session_id, role, parts, model, created_at, updated_at, finished_at, status
+FROM messages
+WHERE status = ?
+ORDER BY session_id ASC, created_at ASC
+`
+
+func (q *Queries) ListMessagesByStatus(ctx context.Context, status string) ([]Message, error) {
+	rows, err := q.query(ctx, q.listMessagesByStatusStmt, listMessagesByStatus, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Message{}
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.SessionID,
+			&i.Role,
+			&i.Parts,
+			&i.Model,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.FinishedAt,
+			&i.Status,
 		); err != nil {
 			return nil, err
 		}
@@ -136,11 +222,63 @@ func (q *Queries) ListMessagesBySession(ctx context.Context, sessionID string) (
 	return items, nil
 }
 
+const listUserMessageCounts = `-- name: ListUserMessageCounts :many
+SELECT session_id, COUNT(*) AS user_messages
+FROM messages
+WHERE role = 'user'
+GROUP BY session_id
+`
+
+type ListUserMessageCountsRow struct {
+	SessionID    string `json:"session_id"`
+	UserMessages int64  `json:"user_messages"`
+}
+
+func (q *Queries) ListUserMessageCounts(ctx context.Context) ([]ListUserMessageCountsRow, error) {
+	rows, err := q.query(ctx, q.listUserMessageCountsStmt, listUserMessageCounts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListUserMessageCountsRow{}
+	for rows.Next() {
+		var i ListUserMessageCountsRow
+		if err := rows.Scan(&i.SessionID, &i.UserMessages); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const moveMessage = `-- name: MoveMessage :exec
+UPDATE messages
+SET session_id = ?
+WHERE id = ?
+`
+
+type MoveMessageParams struct {
+	SessionID string `json:"session_id"`
+	ID        string `json:"id"`
+}
+
+func (q *Queries) MoveMessage(ctx context.Context, arg MoveMessageParams) error {
+	_, err := q.exec(ctx, q.moveMessageStmt, moveMessage, arg.SessionID, arg.ID)
+	return err
+}
+
 const updateMessage = `-- name: UpdateMessage :exec
 UPDATE messages
 SET
     parts = ?,
     finished_at = ?,
+    status = ?,
     updated_at = strftime('%s', 'now')
 WHERE id = ?
 `
@@ -148,10 +286,16 @@ WHERE id = ?
 type UpdateMessageParams struct {
 	Parts      string        `json:"parts"`
 	FinishedAt sql.NullInt64 `json:"finished_at"`
+	Status     string        `json:"status"`
 	ID         string        `json:"id"`
 }
 
 func (q *Queries) UpdateMessage(ctx context.Context, arg UpdateMessageParams) error {
-	_, err := q.exec(ctx, q.updateMessageStmt, updateMessage, arg.Parts, arg.FinishedAt, arg.ID)
+	_, err := q.exec(ctx, q.updateMessageStmt, updateMessage,
+		arg.Parts,
+		arg.FinishedAt,
+		arg.Status,
+		arg.ID,
+	)
 	return err
 }
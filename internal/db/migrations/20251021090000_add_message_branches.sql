@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE sessions ADD COLUMN regenerated_tokens INTEGER NOT NULL DEFAULT 0 CHECK (regenerated_tokens >= 0);
+ALTER TABLE sessions ADD COLUMN regenerated_cost REAL NOT NULL DEFAULT 0.0 CHECK (regenerated_cost >= 0.0);
+
+CREATE TRIGGER IF NOT EXISTS update_session_message_count_on_move
+AFTER UPDATE OF session_id ON messages
+WHEN old.session_id != new.session_id
+BEGIN
+UPDATE sessions SET
+    message_count = message_count - 1
+WHERE id = old.session_id;
+UPDATE sessions SET
+    message_count = message_count + 1
+WHERE id = new.session_id;
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS update_session_message_count_on_move;
+
+ALTER TABLE sessions DROP COLUMN regenerated_cost;
+ALTER TABLE sessions DROP COLUMN regenerated_tokens;
+-- +goose StatementEnd
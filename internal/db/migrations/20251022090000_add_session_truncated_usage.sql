@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE sessions ADD COLUMN truncated_tokens INTEGER NOT NULL DEFAULT 0 CHECK (truncated_tokens >= 0);
+ALTER TABLE sessions ADD COLUMN truncated_cost REAL NOT NULL DEFAULT 0.0 CHECK (truncated_cost >= 0.0);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE sessions DROP COLUMN truncated_cost;
+ALTER TABLE sessions DROP COLUMN truncated_tokens;
+-- +goose StatementEnd
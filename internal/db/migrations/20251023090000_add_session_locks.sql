@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS instances (
+    id TEXT PRIMARY KEY,
+    pid INTEGER NOT NULL,
+    started_at INTEGER NOT NULL,  -- Unix timestamp in seconds
+    heartbeat_at INTEGER NOT NULL  -- Unix timestamp in seconds
+);
+
+CREATE TABLE IF NOT EXISTS session_locks (
+    session_id TEXT PRIMARY KEY,
+    instance_id TEXT NOT NULL,
+    acquired_at INTEGER NOT NULL,  -- Unix timestamp in seconds
+    FOREIGN KEY (session_id) REFERENCES sessions (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_locks_instance_id ON session_locks (instance_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_session_locks_instance_id;
+DROP TABLE IF EXISTS session_locks;
+DROP TABLE IF EXISTS instances;
+-- +goose StatementEnd
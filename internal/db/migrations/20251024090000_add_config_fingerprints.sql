@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE sessions ADD COLUMN config_fingerprint TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS config_snapshots (
+    fingerprint TEXT PRIMARY KEY,
+    snapshot TEXT NOT NULL,  -- JSON object of the non-secret configuration values by path
+    created_at INTEGER NOT NULL  -- Unix timestamp in seconds
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS config_snapshots;
+ALTER TABLE sessions DROP COLUMN config_fingerprint;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS citations (
+    message_id TEXT NOT NULL,
+    source_index INTEGER NOT NULL,  -- Number the response cites the source with, unique in its session
+    source TEXT NOT NULL,  -- Path or URL of the cited document
+    start_line INTEGER NOT NULL DEFAULT 0,
+    end_line INTEGER NOT NULL DEFAULT 0,
+    score REAL NOT NULL DEFAULT 0,
+    created_at INTEGER NOT NULL,  -- Unix timestamp in seconds
+    PRIMARY KEY (message_id, source_index),
+    FOREIGN KEY (message_id) REFERENCES messages (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_citations_source ON citations (source);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_citations_source;
+DROP TABLE IF EXISTS citations;
+-- +goose StatementEnd
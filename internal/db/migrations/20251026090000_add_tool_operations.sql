@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS tool_operations (
+    id TEXT PRIMARY KEY,  -- Derived from the message and index of the tool call
+    session_id TEXT NOT NULL,
+    message_id TEXT NOT NULL,
+    tool TEXT NOT NULL,
+    path TEXT NOT NULL,
+    before_hash TEXT NOT NULL,  -- SHA-256 of the file before the operation, empty when it did not exist
+    after_hash TEXT NOT NULL,
+    result TEXT NOT NULL,  -- Response of the tool, returned again when the call is dispatched again
+    metadata TEXT NOT NULL DEFAULT '',
+    created_at INTEGER NOT NULL,  -- Unix timestamp in seconds
+    FOREIGN KEY (session_id) REFERENCES sessions (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_tool_operations_session_id ON tool_operations (session_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_tool_operations_session_id;
+DROP TABLE IF EXISTS tool_operations;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS agent_memory (
+    id TEXT PRIMARY KEY,
+    agent TEXT NOT NULL,
+    content TEXT NOT NULL,  -- Learning observation of the agent
+    created_at INTEGER NOT NULL,  -- Unix timestamp in seconds
+    accessed_at INTEGER NOT NULL  -- Unix timestamp in seconds of the last recall
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_memory_agent_accessed_at ON agent_memory (agent, accessed_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_agent_memory_agent_accessed_at;
+DROP TABLE IF EXISTS agent_memory;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE sessions ADD COLUMN space_id TEXT NOT NULL DEFAULT '';  -- Space active when the session was created
+
+CREATE INDEX IF NOT EXISTS idx_sessions_space_id ON sessions (space_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_sessions_space_id;
+ALTER TABLE sessions DROP COLUMN space_id;
+-- +goose StatementEnd
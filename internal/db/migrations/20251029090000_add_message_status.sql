@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE messages ADD COLUMN status TEXT NOT NULL DEFAULT 'finished';  -- in_progress until the response and its tool calls are stored
+
+CREATE INDEX IF NOT EXISTS idx_messages_status ON messages (status);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_messages_status;
+ALTER TABLE messages DROP COLUMN status;
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE analytics_daily ADD COLUMN tokens INTEGER NOT NULL DEFAULT 0 CHECK (tokens >= 0);  -- tokens used, such as the embedding tokens of a model
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE analytics_daily DROP COLUMN tokens;
+-- +goose StatementEnd
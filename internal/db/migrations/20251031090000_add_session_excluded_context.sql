@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE sessions ADD COLUMN excluded_context TEXT NOT NULL DEFAULT '';  -- sources of context left out of the messages of the session by default, comma separated
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE sessions DROP COLUMN excluded_context;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE sessions ADD COLUMN tags TEXT NOT NULL DEFAULT '';  -- free-form tags of the session, comma separated
+ALTER TABLE sessions ADD COLUMN archived INTEGER NOT NULL DEFAULT 0;  -- hides the session from the default session list
+ALTER TABLE sessions ADD COLUMN agent TEXT NOT NULL DEFAULT '';  -- name of the agent which answered in the session
+CREATE INDEX IF NOT EXISTS idx_sessions_archived ON sessions (archived);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_sessions_archived;
+ALTER TABLE sessions DROP COLUMN agent;
+ALTER TABLE sessions DROP COLUMN archived;
+ALTER TABLE sessions DROP COLUMN tags;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE sessions ADD COLUMN model TEXT NOT NULL DEFAULT '';  -- model overriding the one of the agent of the session
+ALTER TABLE sessions ADD COLUMN generation TEXT NOT NULL DEFAULT '';  -- generation parameters overriding the agent's, as JSON
+ALTER TABLE sessions ADD COLUMN allowed_tools TEXT NOT NULL DEFAULT '';  -- tools the agent may call in the session, comma separated, all when empty
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE sessions DROP COLUMN allowed_tools;
+ALTER TABLE sessions DROP COLUMN generation;
+ALTER TABLE sessions DROP COLUMN model;
+-- +goose StatementEnd
@@ -0,0 +1,43 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS batches (
+    id TEXT PRIMARY KEY,
+    component TEXT NOT NULL,  -- the job that submitted the batch, which receives its results
+    provider TEXT NOT NULL,
+    model TEXT NOT NULL,
+    remote_id TEXT NOT NULL DEFAULT '',  -- the ID of the batch at the provider, empty until submitted
+    status TEXT NOT NULL,  -- pending, submitted, completed or failed
+    error TEXT NOT NULL DEFAULT '',
+    created_at INTEGER NOT NULL,  -- Unix timestamp in seconds
+    updated_at INTEGER NOT NULL  -- Unix timestamp in seconds
+);
+
+CREATE INDEX IF NOT EXISTS idx_batches_status ON batches (status);
+
+CREATE TABLE IF NOT EXISTS batch_items (
+    batch_id TEXT NOT NULL,
+    id TEXT NOT NULL,
+    payload TEXT NOT NULL,  -- what the component needs to use the result
+    prompt TEXT NOT NULL,
+    attempt INTEGER NOT NULL DEFAULT 1,
+    status TEXT NOT NULL,  -- pending, succeeded, failed or retried
+    content TEXT NOT NULL DEFAULT '',
+    input_tokens INTEGER NOT NULL DEFAULT 0,
+    output_tokens INTEGER NOT NULL DEFAULT 0,
+    cost REAL NOT NULL DEFAULT 0,  -- at the batch price
+    error TEXT NOT NULL DEFAULT '',
+    delivered INTEGER NOT NULL DEFAULT 0,  -- whether the component received the result
+    PRIMARY KEY (batch_id, id),
+    FOREIGN KEY (batch_id) REFERENCES batches (id) ON DELETE CASCADE
+);
+
+ALTER TABLE analytics_daily ADD COLUMN cost REAL NOT NULL DEFAULT 0;  -- the price of the usage, such as the batch requests of a model
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE analytics_daily DROP COLUMN cost;
+DROP TABLE IF EXISTS batch_items;
+DROP INDEX IF EXISTS idx_batches_status;
+DROP TABLE IF EXISTS batches;
+-- +goose StatementEnd
@@ -8,6 +8,69 @@ import (
 	"database/sql"
 )
 
+type AgentMemory struct {
+	ID         string `json:"id"`
+	Agent      string `json:"agent"`
+	Content    string `json:"content"`
+	CreatedAt  int64  `json:"created_at"`
+	AccessedAt int64  `json:"accessed_at"`
+}
+
+type AnalyticsDaily struct {
+	Day            string  `json:"day"`
+	Kind           string  `json:"kind"`
+	Name           string  `json:"name"`
+	Count          int64   `json:"count"`
+	Failures       int64   `json:"failures"`
+	TotalLatencyMs int64   `json:"total_latency_ms"`
+	UpdatedAt      int64   `json:"updated_at"`
+	Tokens         int64   `json:"tokens"`
+	Cost           float64 `json:"cost"`
+}
+
+type Batch struct {
+	ID        string `json:"id"`
+	Component string `json:"component"`
+	Provider  string `json:"provider"`
+	Model     string `json:"model"`
+	RemoteID  string `json:"remote_id"`
+	Status    string `json:"status"`
+	Error     string `json:"error"`
+	CreatedAt int64  `json:"created_at"`
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+type BatchItem struct {
+	BatchID      string  `json:"batch_id"`
+	ID           string  `json:"id"`
+	Payload      string  `json:"payload"`
+	Prompt       string  `json:"prompt"`
+	Attempt      int64   `json:"attempt"`
+	Status       string  `json:"status"`
+	Content      string  `json:"content"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	Cost         float64 `json:"cost"`
+	Error        string  `json:"error"`
+	Delivered    int64   `json:"delivered"`
+}
+
+type Citation struct {
+	MessageID   string  `json:"message_id"`
+	SourceIndex int64   `json:"source_index"`
+	Source      string  `json:"source"`
+	StartLine   int64   `json:"start_line"`
+	EndLine     int64   `json:"end_line"`
+	Score       float64 `json:"score"`
+	CreatedAt   int64   `json:"created_at"`
+}
+
+type ConfigSnapshot struct {
+	Fingerprint string `json:"fingerprint"`
+	Snapshot    string `json:"snapshot"`
+	CreatedAt   int64  `json:"created_at"`
+}
+
 type File struct {
 	ID        string `json:"id"`
 	SessionID string `json:"session_id"`
@@ -18,6 +81,13 @@ type File struct {
 	UpdatedAt int64  `json:"updated_at"`
 }
 
+type Instance struct {
+	ID          string `json:"id"`
+	Pid         int64  `json:"pid"`
+	StartedAt   int64  `json:"started_at"`
+	HeartbeatAt int64  `json:"heartbeat_at"`
+}
+
 type Message struct {
 	ID         string         `json:"id"`
 	SessionID  string         `json:"session_id"`
@@ -27,17 +97,52 @@ type Message struct {
 	CreatedAt  int64          `json:"created_at"`
 	UpdatedAt  int64          `json:"updated_at"`
 	FinishedAt sql.NullInt64  `json:"finished_at"`
+	Status     string         `json:"status"`
 }
 
 type Session struct {
-	ID               string         `json:"id"`
-	ParentSessionID  sql.NullString `json:"parent_session_id"`
-	Title            string         `json:"title"`
-	MessageCount     int64          `json:"message_count"`
-	PromptTokens     int64          `json:"prompt_tokens"`
-	CompletionTokens int64          `json:"completion_tokens"`
-	Cost             float64        `json:"cost"`
-	UpdatedAt        int64          `json:"updated_at"`
-	CreatedAt        int64          `json:"created_at"`
-	SummaryMessageID sql.NullString `json:"summary_message_id"`
+	ID                string         `json:"id"`
+	ParentSessionID   sql.NullString `json:"parent_session_id"`
+	Title             string         `json:"title"`
+	MessageCount      int64          `json:"message_count"`
+	PromptTokens      int64          `json:"prompt_tokens"`
+	CompletionTokens  int64          `json:"completion_tokens"`
+	Cost              float64        `json:"cost"`
+	UpdatedAt         int64          `json:"updated_at"`
+	CreatedAt         int64          `json:"created_at"`
+	SummaryMessageID  sql.NullString `json:"summary_message_id"`
+	CacheReadTokens   int64          `json:"cache_read_tokens"`
+	CacheWriteTokens  int64          `json:"cache_write_tokens"`
+	RegeneratedTokens int64          `json:"regenerated_tokens"`
+	RegeneratedCost   float64        `json:"regenerated_cost"`
+	TruncatedTokens   int64          `json:"truncated_tokens"`
+	TruncatedCost     float64        `json:"truncated_cost"`
+	ConfigFingerprint string         `json:"config_fingerprint"`
+	SpaceID           string         `json:"space_id"`
+	ExcludedContext   string         `json:"excluded_context"`
+	Tags              string         `json:"tags"`
+	Archived          bool           `json:"archived"`
+	Agent             string         `json:"agent"`
+	Model             string         `json:"model"`
+	Generation        string         `json:"generation"`
+	AllowedTools      string         `json:"allowed_tools"`
+}
+
+type SessionLock struct {
+	SessionID  string `json:"session_id"`
+	InstanceID string `json:"instance_id"`
+	AcquiredAt int64  `json:"acquired_at"`
+}
+
+type ToolOperation struct {
+	ID         string `json:"id"`
+	SessionID  string `json:"session_id"`
+	MessageID  string `json:"message_id"`
+	Tool       string `json:"tool"`
+	Path       string `json:"path"`
+	BeforeHash string `json:"before_hash"`
+	AfterHash  string `json:"after_hash"`
+	Result     string `json:"result"`
+	Metadata   string `json:"metadata"`
+	CreatedAt  int64  `json:"created_at"`
 }
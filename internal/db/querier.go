@@ -6,30 +6,71 @@ package db
 
 import (
 	"context"
+	"database/sql"
 )
 
 type Querier interface {
+	AcquireSessionLock(ctx context.Context, arg AcquireSessionLockParams) (int64, error)
+	CountSessionToolOperations(ctx context.Context, sessionID string) (int64, error)
+	CreateAgentMemory(ctx context.Context, arg CreateAgentMemoryParams) error
+	CreateBatch(ctx context.Context, arg CreateBatchParams) error
+	CreateBatchItem(ctx context.Context, arg CreateBatchItemParams) error
+	CreateCitation(ctx context.Context, arg CreateCitationParams) error
+	CreateConfigSnapshot(ctx context.Context, arg CreateConfigSnapshotParams) error
 	CreateFile(ctx context.Context, arg CreateFileParams) (File, error)
 	CreateMessage(ctx context.Context, arg CreateMessageParams) (Message, error)
 	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
+	CreateToolOperation(ctx context.Context, arg CreateToolOperationParams) error
+	DeleteAnalyticsRollups(ctx context.Context) error
 	DeleteFile(ctx context.Context, id string) error
+	DeleteInstance(ctx context.Context, id string) error
 	DeleteMessage(ctx context.Context, id string) error
+	DeleteMessageCitations(ctx context.Context, messageID string) error
 	DeleteSession(ctx context.Context, id string) error
 	DeleteSessionFiles(ctx context.Context, sessionID string) error
 	DeleteSessionMessages(ctx context.Context, sessionID string) error
+	DeleteStaleInstances(ctx context.Context, heartbeatAt int64) error
+	GetBatch(ctx context.Context, id string) (Batch, error)
+	GetConfigSnapshot(ctx context.Context, fingerprint string) (ConfigSnapshot, error)
 	GetFile(ctx context.Context, id string) (File, error)
 	GetFileByPathAndSession(ctx context.Context, arg GetFileByPathAndSessionParams) (File, error)
 	GetMessage(ctx context.Context, id string) (Message, error)
 	GetSessionByID(ctx context.Context, id string) (Session, error)
+	GetSessionLock(ctx context.Context, sessionID string) (GetSessionLockRow, error)
+	GetToolOperation(ctx context.Context, id string) (ToolOperation, error)
+	ImportMessage(ctx context.Context, arg ImportMessageParams) error
+	ImportSession(ctx context.Context, arg ImportSessionParams) error
+	ListAnalyticsRollups(ctx context.Context, arg ListAnalyticsRollupsParams) ([]AnalyticsDaily, error)
+	ListBatchItems(ctx context.Context, batchID string) ([]BatchItem, error)
+	ListBatches(ctx context.Context) ([]Batch, error)
+	ListChildSessions(ctx context.Context, parentSessionID sql.NullString) ([]Session, error)
+	ListCitingSessions(ctx context.Context, source string) ([]string, error)
 	ListFilesByPath(ctx context.Context, path string) ([]File, error)
 	ListFilesBySession(ctx context.Context, sessionID string) ([]File, error)
+	ListInstances(ctx context.Context) ([]Instance, error)
 	ListLatestSessionFiles(ctx context.Context, sessionID string) ([]File, error)
 	ListMessagesBySession(ctx context.Context, sessionID string) ([]Message, error)
+	ListMessagesByStatus(ctx context.Context, status string) ([]Message, error)
 	ListNewFiles(ctx context.Context) ([]File, error)
+	ListOpenBatches(ctx context.Context) ([]Batch, error)
+	ListRecentAgentMemory(ctx context.Context, arg ListRecentAgentMemoryParams) ([]AgentMemory, error)
+	ListSessionLocks(ctx context.Context) ([]ListSessionLocksRow, error)
 	ListSessions(ctx context.Context) ([]Session, error)
+	ListSpaceSessions(ctx context.Context, spaceID string) ([]Session, error)
+	ListUndeliveredBatches(ctx context.Context) ([]Batch, error)
+	ListUserMessageCounts(ctx context.Context) ([]ListUserMessageCountsRow, error)
+	MoveMessage(ctx context.Context, arg MoveMessageParams) error
+	PruneAgentMemory(ctx context.Context, limit int64) (int64, error)
+	ReleaseInstanceSessionLocks(ctx context.Context, instanceID string) error
+	ReleaseSessionLock(ctx context.Context, arg ReleaseSessionLockParams) error
+	TouchAgentMemory(ctx context.Context, id string) error
+	UpdateBatch(ctx context.Context, arg UpdateBatchParams) error
+	UpdateBatchItem(ctx context.Context, arg UpdateBatchItemParams) error
 	UpdateFile(ctx context.Context, arg UpdateFileParams) (File, error)
 	UpdateMessage(ctx context.Context, arg UpdateMessageParams) error
 	UpdateSession(ctx context.Context, arg UpdateSessionParams) (Session, error)
+	UpsertAnalyticsRollup(ctx context.Context, arg UpsertAnalyticsRollupParams) error
+	UpsertInstance(ctx context.Context, arg UpsertInstanceParams) error
 }
 
 var _ Querier = (*Queries)(nil)
@@ -20,6 +20,13 @@ INSERT INTO sessions (
     completion_tokens,
     cost,
     summary_message_id,
+    config_fingerprint,
+    space_id,
+    excluded_context,
+    agent,
+    model,
+    generation,
+    allowed_tools,
     updated_at,
     created_at
 ) VALUES (
@@ -31,19 +38,33 @@ INSERT INTO sessions (
     ?,
     ?,
     null,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
     strftime('%s', 'now'),
     strftime('%s', 'now')
-) RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id
+) RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, cache_read_tokens, cache_write_tokens, regenerated_tokens, regenerated_cost, truncated_tokens, truncated_cost, config_fingerprint, space_id, excluded_context, tags, archived, agent, model, generation, allowed_tools
 `
 
 type CreateSessionParams struct {
-	ID               string         `json:"id"`
-	ParentSessionID  sql.NullString `json:"parent_session_id"`
-	Title            string         `json:"title"`
-	MessageCount     int64          `json:"message_count"`
-	PromptTokens     int64          `json:"prompt_tokens"`
-	CompletionTokens int64          `json:"completion_tokens"`
-	Cost             float64        `json:"cost"`
+	ID                string         `json:"id"`
+	ParentSessionID   sql.NullString `json:"parent_session_id"`
+	Title             string         `json:"title"`
+	MessageCount      int64          `json:"message_count"`
+	PromptTokens      int64          `json:"prompt_tokens"`
+	CompletionTokens  int64          `json:"completion_tokens"`
+	Cost              float64        `json:"cost"`
+	ConfigFingerprint string         `json:"config_fingerprint"`
+	SpaceID           string         `json:"space_id"`
+	ExcludedContext   string         `json:"excluded_context"`
+	Agent             string         `json:"agent"`
+	Model             string         `json:"model"`
+	Generation        string         `json:"generation"`
+	AllowedTools      string         `json:"allowed_tools"`
 }
 
 func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error) {
@@ -55,6 +76,13 @@ func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (S
 		arg.PromptTokens,
 		arg.CompletionTokens,
 		arg.Cost,
+		arg.ConfigFingerprint,
+		arg.SpaceID,
+		arg.ExcludedContext,
+		arg.Agent,
+		arg.Model,
+		arg.Generation,
+		arg.AllowedTools,
 	)
 	var i Session
 	err := row.Scan(
@@ -68,6 +96,21 @@ func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (S
 		&i.UpdatedAt,
 		&i.CreatedAt,
 		&i.SummaryMessageID,
+		&i.CacheReadTokens,
+		&i.CacheWriteTokens,
+		&i.RegeneratedTokens,
+		&i.RegeneratedCost,
+		&i.TruncatedTokens,
+		&i.TruncatedCost,
+		&i.ConfigFingerprint,
+		&i.SpaceID,
+		&i.ExcludedContext,
+		&i.Tags,
+		&i.Archived,
+		&i.Agent,
+		&i.Model,
+		&i.Generation,
+		&i.AllowedTools,
 	)
 	return i, err
 }
@@ -83,7 +126,7 @@ func (q *Queries) DeleteSession(ctx context.Context, id string) error {
 }
 
 const getSessionByID = `-- name: GetSessionByID :one
-SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id
+SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, cache_read_tokens, cache_write_tokens, regenerated_tokens, regenerated_cost, truncated_tokens, truncated_cost, config_fingerprint, space_id, excluded_context, tags, archived, agent, model, generation, allowed_tools
 FROM sessions
 WHERE id = ? LIMIT 1
 `
@@ -102,12 +145,150 @@ func (q *Queries) GetSessionByID(ctx context.Context, id string) (Session, error
 		&i.UpdatedAt,
 		&i.CreatedAt,
 		&i.SummaryMessageID,
+		&i.CacheReadTokens,
+		&i.CacheWriteTokens,
+		&i.RegeneratedTokens,
+		&i.RegeneratedCost,
+		&i.TruncatedTokens,
+		&i.TruncatedCost,
+		&i.ConfigFingerprint,
+		&i.SpaceID,
+		&i.ExcludedContext,
+		&i.Tags,
+		&i.Archived,
+		&i.Agent,
+		&i.Model,
+		&i.Generation,
+		&i.AllowedTools,
 	)
 	return i, err
 }
 
+const importSession = `-- name: ImportSession :exec
+INSERT INTO sessions (
+    id,
+    parent_session_id,
+    title,
+    prompt_tokens,
+    completion_tokens,
+    cache_read_tokens,
+    cache_write_tokens,
+    regenerated_tokens,
+    regenerated_cost,
+    truncated_tokens,
+    truncated_cost,
+    cost,
+    summary_message_id,
+    config_fingerprint,
+    space_id,
+    updated_at,
+    created_at
+) VALUES (
+    ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+)
+`
+
+type ImportSessionParams struct {
+	ID                string         `json:"id"`
+	ParentSessionID   sql.NullString `json:"parent_session_id"`
+	Title             string         `json:"title"`
+	PromptTokens      int64          `json:"prompt_tokens"`
+	CompletionTokens  int64          `json:"completion_tokens"`
+	CacheReadTokens   int64          `json:"cache_read_tokens"`
+	CacheWriteTokens  int64          `json:"cache_write_tokens"`
+	RegeneratedTokens int64          `json:"regenerated_tokens"`
+	RegeneratedCost   float64        `json:"regenerated_cost"`
+	TruncatedTokens   int64          `json:"truncated_tokens"`
+	TruncatedCost     float64        `json:"truncated_cost"`
+	Cost              float64        `json:"cost"`
+	SummaryMessageID  sql.NullString `json:"summary_message_id"`
+	ConfigFingerprint string         `json:"config_fingerprint"`
+	SpaceID           string         `json:"space_id"`
+	UpdatedAt         int64          `json:"updated_at"`
+	CreatedAt         int64          `json:"created_at"`
+}
+
+func (q *Queries) ImportSession(ctx context.Context, arg ImportSessionParams) error {
+	_, err := q.exec(ctx, q.importSessionStmt, importSession,
+		arg.ID,
+		arg.ParentSessionID,
+		arg.Title,
+		arg.PromptTokens,
+		arg.CompletionTokens,
+		arg.CacheReadTokens,
+		arg.CacheWriteTokens,
+		arg.RegeneratedTokens,
+		arg.RegeneratedCost,
+		arg.TruncatedTokens,
+		arg.TruncatedCost,
+		arg.Cost,
+		arg.SummaryMessageID,
+		arg.ConfigFingerprint,
+		arg.SpaceID,
+		arg.UpdatedAt,
+		arg.CreatedAt,
+	)
+	return err
+}
+
+const listChildSessions = `-- name: ListChildSessions :many
+SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, cache_read_tokens, cache_write_tokens, regenerated_tokens, regenerated_cost, truncated_tokens, truncated_cost, config_fingerprint, space_id, excluded_context, tags, archived, agent, model, generation, allowed_tools
+FROM sessions
+WHERE parent_session_id = ?
+ORDER BY created_at ASC
+`
+
+func (q *Queries) ListChildSessions(ctx context.Context, parentSessionID sql.NullString) ([]Session, error) {
+	rows, err := q.query(ctx, q.listChildSessionsStmt, listChildSessions, parentSessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Session{}
+	for rows.Next() {
+		var i Session
+		if err := rows.Scan(
+			&i.ID,
+			&i.ParentSessionID,
+			&i.Title,
+			&i.MessageCount,
+			&i.PromptTokens,
+			&i.CompletionTokens,
+			&i.Cost,
+			&i.UpdatedAt,
+			&i.CreatedAt,
+			&i.SummaryMessageID,
+			&i.CacheReadTokens,
+			&i.CacheWriteTokens,
+			&i.RegeneratedTokens,
+			&i.RegeneratedCost,
+			&i.TruncatedTokens,
+			&i.TruncatedCost,
+			&i.ConfigFingerprint,
+			&i.SpaceID,
+			&i.ExcludedContext,
+			&i.Tags,
+			&i.Archived,
+			&i.Agent,
+			&i.Model,
+			&i.Generation,
+			&i.AllowedTools,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listSessions = `-- name: ListSessions :many
-SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id
+SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, cache_read_tokens, cache_write_tokens, regenerated_tokens, regenerated_cost, truncated_tokens, truncated_cost, config_fingerprint, space_id, excluded_context, tags, archived, agent, model, generation, allowed_tools
 FROM sessions
 WHERE parent_session_id is NULL
 ORDER BY created_at DESC
@@ -133,6 +314,77 @@ func (q *Queries) ListSessions(ctx context.Context) ([]Session, error) {
 			&i.UpdatedAt,
 			&i.CreatedAt,
 			&i.SummaryMessageID,
+			&i.CacheReadTokens,
+			&i.CacheWriteTokens,
+			&i.RegeneratedTokens,
+			&i.RegeneratedCost,
+			&i.TruncatedTokens,
+			&i.TruncatedCost,
+			&i.ConfigFingerprint,
+			&i.SpaceID,
+			&i.ExcludedContext,
+			&i.Tags,
+			&i.Archived,
+			&i.Agent,
+			&i.Model,
+			&i.Generation,
+			&i.AllowedTools,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSpaceSessions = `-- name: ListSpaceSessions :many
+SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, cache_read_tokens, cache_write_tokens, regenerated_tokens, regenerated_cost, truncated_tokens, truncated_cost, config_fingerprint, space_id, excluded_context, tags, archived, agent, model, generation, allowed_tools
+FROM sessions
+WHERE space_id = ?
+ORDER BY created_at ASC
+`
+
+func (q *Queries) ListSpaceSessions(ctx context.Context, spaceID string) ([]Session, error) {
+	rows, err := q.query(ctx, q.listSpaceSessionsStmt, listSpaceSessions, spaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Session{}
+	for rows.Next() {
+		var i Session
+		if err := rows.Scan(
+			&i.ID,
+			&i.ParentSessionID,
+			&i.Title,
+			&i.MessageCount,
+			&i.PromptTokens,
+			&i.CompletionTokens,
+			&i.Cost,
+			&i.UpdatedAt,
+			&i.CreatedAt,
+			&i.SummaryMessageID,
+			&i.CacheReadTokens,
+			&i.CacheWriteTokens,
+			&i.RegeneratedTokens,
+			&i.RegeneratedCost,
+			&i.TruncatedTokens,
+			&i.TruncatedCost,
+			&i.ConfigFingerprint,
+			&i.SpaceID,
+			&i.ExcludedContext,
+			&i.Tags,
+			&i.Archived,
+			&i.Agent,
+			&i.Model,
+			&i.Generation,
+			&i.AllowedTools,
 		); err != nil {
 			return nil, err
 		}
@@ -153,19 +405,45 @@ SET
     title = ?,
     prompt_tokens = ?,
     completion_tokens = ?,
+    cache_read_tokens = ?,
+    cache_write_tokens = ?,
+    regenerated_tokens = ?,
+    regenerated_cost = ?,
+    truncated_tokens = ?,
+    truncated_cost = ?,
     summary_message_id = ?,
-    cost = ?
+    cost = ?,
+    excluded_context = ?,
+    tags = ?,
+    archived = ?,
+    agent = ?,
+    model = ?,
+    generation = ?,
+    allowed_tools = ?
 WHERE id = ?
-RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id
+RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, cache_read_tokens, cache_write_tokens, regenerated_tokens, regenerated_cost, truncated_tokens, truncated_cost, config_fingerprint, space_id, excluded_context, tags, archived, agent, model, generation, allowed_tools
 `
 
 type UpdateSessionParams struct {
-	Title            string         `json:"title"`
-	PromptTokens     int64          `json:"prompt_tokens"`
-	CompletionTokens int64          `json:"completion_tokens"`
-	SummaryMessageID sql.NullString `json:"summary_message_id"`
-	Cost             float64        `json:"cost"`
-	ID               string         `json:"id"`
+	Title             string         `json:"title"`
+	PromptTokens      int64          `json:"prompt_tokens"`
+	CompletionTokens  int64          `json:"completion_tokens"`
+	CacheReadTokens   int64          `json:"cache_read_tokens"`
+	CacheWriteTokens  int64          `json:"cache_write_tokens"`
+	RegeneratedTokens int64          `json:"regenerated_tokens"`
+	RegeneratedCost   float64        `json:"regenerated_cost"`
+	TruncatedTokens   int64          `json:"truncated_tokens"`
+	TruncatedCost     float64        `json:"truncated_cost"`
+	SummaryMessageID  sql.NullString `json:"summary_message_id"`
+	Cost              float64        `json:"cost"`
+	ExcludedContext   string         `json:"excluded_context"`
+	Tags              string         `json:"tags"`
+	Archived          bool           `json:"archived"`
+	Agent             string         `json:"agent"`
+	Model             string         `json:"model"`
+	Generation        string         `json:"generation"`
+	AllowedTools      string         `json:"allowed_tools"`
+	ID                string         `json:"id"`
 }
 
 func (q *Queries) UpdateSession(ctx context.Context, arg UpdateSessionParams) (Session, error) {
@@ -173,8 +451,21 @@ func (q *Queries) UpdateSession(ctx context.Context, arg UpdateSessionParams) (S
 		arg.Title,
 		arg.PromptTokens,
 		arg.CompletionTokens,
+		arg.CacheReadTokens,
+		arg.CacheWriteTokens,
+		arg.RegeneratedTokens,
+		arg.RegeneratedCost,
+		arg.TruncatedTokens,
+		arg.TruncatedCost,
 		arg.SummaryMessageID,
 		arg.Cost,
+		arg.ExcludedContext,
+		arg.Tags,
+		arg.Archived,
+		arg.Agent,
+		arg.Model,
+		arg.Generation,
+		arg.AllowedTools,
 		arg.ID,
 	)
 	var i Session
@@ -189,6 +480,21 @@ func (q *Queries) UpdateSession(ctx context.Context, arg UpdateSessionParams) (S
 		&i.UpdatedAt,
 		&i.CreatedAt,
 		&i.SummaryMessageID,
+		&i.CacheReadTokens,
+		&i.CacheWriteTokens,
+		&i.RegeneratedTokens,
+		&i.RegeneratedCost,
+		&i.TruncatedTokens,
+		&i.TruncatedCost,
+		&i.ConfigFingerprint,
+		&i.SpaceID,
+		&i.ExcludedContext,
+		&i.Tags,
+		&i.Archived,
+		&i.Agent,
+		&i.Model,
+		&i.Generation,
+		&i.AllowedTools,
 	)
 	return i, err
 }
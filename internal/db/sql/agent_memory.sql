@@ -0,0 +1,36 @@
+-- name: CreateAgentMemory :exec
+INSERT INTO agent_memory (
+    id,
+    agent,
+    content,
+    created_at,
+    accessed_at
+) VALUES (
+    ?,
+    ?,
+    ?,
+    strftime('%s', 'now'),
+    strftime('%s', 'now')
+);
+
+-- name: ListRecentAgentMemory :many
+SELECT *
+FROM agent_memory
+WHERE agent = ?
+ORDER BY accessed_at DESC, rowid DESC
+LIMIT ?;
+
+-- name: TouchAgentMemory :exec
+UPDATE agent_memory
+SET accessed_at = strftime('%s', 'now')
+WHERE id = ?;
+
+-- name: PruneAgentMemory :execrows
+DELETE FROM agent_memory
+WHERE id IN (
+    SELECT id FROM (
+        SELECT id, ROW_NUMBER() OVER (PARTITION BY agent ORDER BY accessed_at DESC, rowid DESC) AS position
+        FROM agent_memory
+    )
+    WHERE position > ?
+);
@@ -0,0 +1,38 @@
+-- name: UpsertAnalyticsRollup :exec
+INSERT INTO analytics_daily (
+    day,
+    kind,
+    name,
+    count,
+    failures,
+    total_latency_ms,
+    tokens,
+    cost,
+    updated_at
+) VALUES (
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    strftime('%s', 'now')
+)
+ON CONFLICT (day, kind, name) DO UPDATE SET
+    count = count + excluded.count,
+    failures = failures + excluded.failures,
+    total_latency_ms = total_latency_ms + excluded.total_latency_ms,
+    tokens = tokens + excluded.tokens,
+    cost = cost + excluded.cost,
+    updated_at = strftime('%s', 'now');
+
+-- name: ListAnalyticsRollups :many
+SELECT *
+FROM analytics_daily
+WHERE day >= sqlc.arg(from_day) AND day <= sqlc.arg(to_day)
+ORDER BY day ASC, kind ASC, name ASC;
+
+-- name: DeleteAnalyticsRollups :exec
+DELETE FROM analytics_daily;
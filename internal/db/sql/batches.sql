@@ -0,0 +1,88 @@
+-- name: CreateBatch :exec
+INSERT INTO batches (
+    id,
+    component,
+    provider,
+    model,
+    status,
+    created_at,
+    updated_at
+) VALUES (
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    strftime('%s', 'now'),
+    strftime('%s', 'now')
+);
+
+-- name: UpdateBatch :exec
+UPDATE batches
+SET
+    remote_id = ?,
+    status = ?,
+    error = ?,
+    updated_at = strftime('%s', 'now')
+WHERE id = ?;
+
+-- name: GetBatch :one
+SELECT *
+FROM batches
+WHERE id = ? LIMIT 1;
+
+-- name: ListBatches :many
+SELECT *
+FROM batches
+ORDER BY created_at DESC, rowid DESC;
+
+-- name: ListOpenBatches :many
+SELECT *
+FROM batches
+WHERE status IN ('pending', 'submitted')
+ORDER BY created_at ASC, rowid ASC;
+
+-- name: ListUndeliveredBatches :many
+SELECT *
+FROM batches
+WHERE status IN ('completed', 'failed')
+    AND id IN (
+        SELECT batch_id FROM batch_items
+        WHERE delivered = 0 AND status IN ('succeeded', 'failed')
+    )
+ORDER BY created_at ASC, rowid ASC;
+
+-- name: CreateBatchItem :exec
+INSERT INTO batch_items (
+    batch_id,
+    id,
+    payload,
+    prompt,
+    attempt,
+    status
+) VALUES (
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?
+);
+
+-- name: UpdateBatchItem :exec
+UPDATE batch_items
+SET
+    status = ?,
+    content = ?,
+    input_tokens = ?,
+    output_tokens = ?,
+    cost = ?,
+    error = ?,
+    delivered = ?
+WHERE batch_id = ? AND id = ?;
+
+-- name: ListBatchItems :many
+SELECT *
+FROM batch_items
+WHERE batch_id = ?
+ORDER BY rowid ASC;
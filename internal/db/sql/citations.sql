@@ -0,0 +1,34 @@
+-- name: CreateCitation :exec
+INSERT INTO citations (
+    message_id,
+    source_index,
+    source,
+    start_line,
+    end_line,
+    score,
+    created_at
+) VALUES (
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    strftime('%s', 'now')
+)
+ON CONFLICT (message_id, source_index) DO UPDATE SET
+    source = excluded.source,
+    start_line = excluded.start_line,
+    end_line = excluded.end_line,
+    score = excluded.score;
+
+-- name: DeleteMessageCitations :exec
+DELETE FROM citations
+WHERE message_id = ?;
+
+-- name: ListCitingSessions :many
+SELECT DISTINCT messages.session_id
+FROM citations
+JOIN messages ON messages.id = citations.message_id
+WHERE citations.source = ?
+ORDER BY messages.session_id ASC;
@@ -0,0 +1,16 @@
+-- name: CreateConfigSnapshot :exec
+INSERT INTO config_snapshots (
+    fingerprint,
+    snapshot,
+    created_at
+) VALUES (
+    ?,
+    ?,
+    strftime('%s', 'now')
+)
+ON CONFLICT (fingerprint) DO NOTHING;
+
+-- name: GetConfigSnapshot :one
+SELECT *
+FROM config_snapshots
+WHERE fingerprint = ? LIMIT 1;
@@ -0,0 +1,77 @@
+-- name: UpsertInstance :exec
+INSERT INTO instances (
+    id,
+    pid,
+    started_at,
+    heartbeat_at
+) VALUES (
+    ?,
+    ?,
+    ?,
+    ?
+)
+ON CONFLICT (id) DO UPDATE SET
+    heartbeat_at = excluded.heartbeat_at;
+
+-- name: DeleteInstance :exec
+DELETE FROM instances
+WHERE id = ?;
+
+-- name: DeleteStaleInstances :exec
+DELETE FROM instances
+WHERE heartbeat_at < ?;
+
+-- name: ListInstances :many
+SELECT *
+FROM instances
+ORDER BY started_at ASC, id ASC;
+
+-- name: AcquireSessionLock :execrows
+INSERT INTO session_locks (
+    session_id,
+    instance_id,
+    acquired_at
+) VALUES (
+    sqlc.arg(session_id),
+    sqlc.arg(instance_id),
+    sqlc.arg(acquired_at)
+)
+ON CONFLICT (session_id) DO UPDATE SET
+    instance_id = excluded.instance_id,
+    acquired_at = CASE
+        WHEN session_locks.instance_id = excluded.instance_id THEN session_locks.acquired_at
+        ELSE excluded.acquired_at
+    END
+WHERE session_locks.instance_id = excluded.instance_id OR session_locks.instance_id NOT IN (
+    SELECT id FROM instances WHERE heartbeat_at >= sqlc.arg(stale_before)
+);
+
+-- name: ReleaseSessionLock :exec
+DELETE FROM session_locks
+WHERE session_id = ? AND instance_id = ?;
+
+-- name: ReleaseInstanceSessionLocks :exec
+DELETE FROM session_locks
+WHERE instance_id = ?;
+
+-- name: ListSessionLocks :many
+SELECT
+    session_locks.session_id,
+    session_locks.instance_id,
+    session_locks.acquired_at,
+    CAST(COALESCE(instances.pid, 0) AS INTEGER) AS pid,
+    CAST(COALESCE(instances.heartbeat_at, 0) AS INTEGER) AS heartbeat_at
+FROM session_locks
+LEFT JOIN instances ON instances.id = session_locks.instance_id
+ORDER BY session_locks.acquired_at ASC, session_locks.session_id ASC;
+
+-- name: GetSessionLock :one
+SELECT
+    session_locks.session_id,
+    session_locks.instance_id,
+    session_locks.acquired_at,
+    CAST(COALESCE(instances.pid, 0) AS INTEGER) AS pid,
+    CAST(COALESCE(instances.heartbeat_at, 0) AS INTEGER) AS heartbeat_at
+FROM session_locks
+LEFT JOIN instances ON instances.id = session_locks.instance_id
+WHERE session_locks.session_id = ?;
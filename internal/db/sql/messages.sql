@@ -9,6 +9,12 @@ FROM messages
 WHERE session_id = ?
 ORDER BY created_at ASC;
 
+-- name: ListMessagesByStatus :many
+SELECT *
+FROM messages
+WHERE status = ?
+ORDER BY session_id ASC, created_at ASC;
+
 -- name: CreateMessage :one
 INSERT INTO messages (
     id,
@@ -16,21 +22,41 @@ INSERT INTO messages (
     role,
     parts,
     model,
+    status,
     created_at,
     updated_at
 ) VALUES (
-    ?, ?, ?, ?, ?, strftime('%s', 'now'), strftime('%s', 'now')
+    ?, ?, ?, ?, ?, ?, strftime('%s', 'now'), strftime('%s', 'now')
 )
 RETURNING *;
 
+-- name: ImportMessage :exec
+INSERT INTO messages (
+    id,
+    session_id,
+    role,
+    parts,
+    model,
+    created_at,
+    updated_at,
+    finished_at
+) VALUES (
+    ?, ?, ?, ?, ?, ?, ?, ?
+);
+
 -- name: UpdateMessage :exec
 UPDATE messages
 SET
     parts = ?,
     finished_at = ?,
+    status = ?,
     updated_at = strftime('%s', 'now')
 WHERE id = ?;
 
+-- name: MoveMessage :exec
+UPDATE messages
+SET session_id = ?
+WHERE id = ?;
 
 -- name: DeleteMessage :exec
 DELETE FROM messages
@@ -39,3 +65,9 @@ WHERE id = ?;
 -- name: DeleteSessionMessages :exec
 DELETE FROM messages
 WHERE session_id = ?;
+
+-- name: ListUserMessageCounts :many
+SELECT session_id, COUNT(*) AS user_messages
+FROM messages
+WHERE role = 'user'
+GROUP BY session_id;
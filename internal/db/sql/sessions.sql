@@ -8,6 +8,13 @@ INSERT INTO sessions (
     completion_tokens,
     cost,
     summary_message_id,
+    config_fingerprint,
+    space_id,
+    excluded_context,
+    agent,
+    model,
+    generation,
+    allowed_tools,
     updated_at,
     created_at
 ) VALUES (
@@ -19,10 +26,40 @@ INSERT INTO sessions (
     ?,
     ?,
     null,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
     strftime('%s', 'now'),
     strftime('%s', 'now')
 ) RETURNING *;
 
+-- name: ImportSession :exec
+INSERT INTO sessions (
+    id,
+    parent_session_id,
+    title,
+    prompt_tokens,
+    completion_tokens,
+    cache_read_tokens,
+    cache_write_tokens,
+    regenerated_tokens,
+    regenerated_cost,
+    truncated_tokens,
+    truncated_cost,
+    cost,
+    summary_message_id,
+    config_fingerprint,
+    space_id,
+    updated_at,
+    created_at
+) VALUES (
+    ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+);
+
 -- name: GetSessionByID :one
 SELECT *
 FROM sessions
@@ -34,14 +71,39 @@ FROM sessions
 WHERE parent_session_id is NULL
 ORDER BY created_at DESC;
 
+-- name: ListChildSessions :many
+SELECT *
+FROM sessions
+WHERE parent_session_id = ?
+ORDER BY created_at ASC;
+
+-- name: ListSpaceSessions :many
+SELECT *
+FROM sessions
+WHERE space_id = ?
+ORDER BY created_at ASC;
+
 -- name: UpdateSession :one
 UPDATE sessions
 SET
     title = ?,
     prompt_tokens = ?,
     completion_tokens = ?,
+    cache_read_tokens = ?,
+    cache_write_tokens = ?,
+    regenerated_tokens = ?,
+    regenerated_cost = ?,
+    truncated_tokens = ?,
+    truncated_cost = ?,
     summary_message_id = ?,
-    cost = ?
+    cost = ?,
+    excluded_context = ?,
+    tags = ?,
+    archived = ?,
+    agent = ?,
+    model = ?,
+    generation = ?,
+    allowed_tools = ?
 WHERE id = ?
 RETURNING *;
 
@@ -0,0 +1,35 @@
+-- name: CreateToolOperation :exec
+INSERT INTO tool_operations (
+    id,
+    session_id,
+    message_id,
+    tool,
+    path,
+    before_hash,
+    after_hash,
+    result,
+    metadata,
+    created_at
+) VALUES (
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    strftime('%s', 'now')
+)
+ON CONFLICT (id) DO NOTHING;
+
+-- name: GetToolOperation :one
+SELECT *
+FROM tool_operations
+WHERE id = ? LIMIT 1;
+
+-- name: CountSessionToolOperations :one
+SELECT COUNT(*)
+FROM tool_operations
+WHERE session_id = ?;
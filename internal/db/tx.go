@@ -0,0 +1,43 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrRollback is returned by the function of a transaction to roll back its
+// changes without failing, as a dry run does
+var ErrRollback = errors.New("rollback")
+
+type beginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// Transaction runs fn with queries inside a transaction, committed when fn
+// succeeds and rolled back when it fails or returns ErrRollback
+func (q *Queries) Transaction(ctx context.Context, fn func(*Queries) error) error {
+	if q.tx != nil {
+		return fn(q)
+	}
+	db, ok := q.db.(beginner)
+	if !ok {
+		return fmt.Errorf("queries do not support transactions")
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := fn(q.WithTx(tx)); err != nil {
+		tx.Rollback()
+		if errors.Is(err, ErrRollback) {
+			return nil
+		}
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
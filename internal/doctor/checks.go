@@ -0,0 +1,327 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/db"
+	"github.com/caronex/intelligence-interface/internal/llm/mcpauth"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/llm/provider"
+	"github.com/caronex/intelligence-interface/internal/message"
+	"github.com/caronex/intelligence-interface/internal/version"
+)
+
+const (
+	// minFreeSpace is the free space of the data directory under which the
+	// check fails, warnFreeSpace the one under which it warns
+	minFreeSpace  = 100 << 20
+	warnFreeSpace = 1 << 30
+)
+
+// Options select the checks to run
+type Options struct {
+	// Offline skips the checks reaching the network: the provider keys and
+	// the remote MCP servers
+	Offline bool
+}
+
+// Checks returns the checks of the configuration cfg, loadErr being the error
+// loading it. The other checks are left out when it did not load at all.
+func Checks(cfg *config.Config, loadErr error, opts Options) []Check {
+	checks := []Check{configCheck(loadErr)}
+	if cfg == nil {
+		return checks
+	}
+
+	checks = append(checks, dataDirectoryCheck(cfg.Data.Directory), databaseCheck())
+	for _, name := range sortedKeys(cfg.Providers) {
+		checks = append(checks, providerCheck(cfg, name, opts))
+	}
+	for _, name := range sortedKeys(cfg.MCPServers) {
+		checks = append(checks, mcpCheck(name, cfg.MCPServers[name], opts))
+	}
+	checks = append(checks, shellCheck(cfg.Shell))
+	for _, server := range LSPServers(cfg) {
+		checks = append(checks, lspCheck(server))
+	}
+	return checks
+}
+
+func configCheck(loadErr error) Check {
+	return Check{Name: "config", Run: func(ctx context.Context) Result {
+		if loadErr != nil {
+			return Result{
+				Status: StatusFail,
+				Detail: loadErr.Error(),
+				Hint:   "fix the configuration in ~/.intelligence-interface.json or the .intelligence-interface.json of the project",
+			}
+		}
+		return Result{Status: StatusPass, Detail: "loaded and valid"}
+	}}
+}
+
+func dataDirectoryCheck(dir string) Check {
+	return Check{Name: "data directory", Run: func(ctx context.Context) Result {
+		if dir == "" {
+			return Result{Status: StatusFail, Detail: "data.directory is not set", Hint: "set data.directory in the configuration"}
+		}
+		hint := fmt.Sprintf("make %s writable or point data.directory somewhere else", dir)
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return Result{Status: StatusFail, Detail: err.Error(), Hint: hint}
+		}
+		probe, err := os.CreateTemp(dir, ".doctor-*")
+		if err != nil {
+			return Result{Status: StatusFail, Detail: fmt.Sprintf("%s is not writable: %v", dir, err), Hint: hint}
+		}
+		probe.Close()
+		os.Remove(probe.Name())
+
+		free, err := freeSpace(dir)
+		if err != nil {
+			return Result{Status: StatusPass, Detail: fmt.Sprintf("%s is writable, free space unknown", dir)}
+		}
+		detail := fmt.Sprintf("%s is writable, %s free", dir, formatBytes(free))
+		switch {
+		case free < minFreeSpace:
+			return Result{Status: StatusFail, Detail: detail, Hint: "free up disk space, the database and logs cannot grow"}
+		case free < warnFreeSpace:
+			return Result{Status: StatusWarn, Detail: detail, Hint: "free up disk space before the database and logs fill it"}
+		}
+		return Result{Status: StatusPass, Detail: detail}
+	}}
+}
+
+func databaseCheck() Check {
+	return Check{Name: "database", Run: func(ctx context.Context) Result {
+		current, latest, err := db.MigrationStatus(ctx)
+		switch {
+		case err != nil:
+			return Result{Status: StatusFail, Detail: err.Error(), Hint: "check that the database in the data directory is not corrupted or locked"}
+		case current == 0:
+			return Result{Status: StatusPass, Detail: "no schema yet, it is created on the next start"}
+		case current < latest:
+			return Result{
+				Status: StatusWarn,
+				Detail: fmt.Sprintf("schema version %d, %d is current", current, latest),
+				Hint:   "the migrations are applied on the next start",
+			}
+		case current > latest:
+			return Result{
+				Status: StatusFail,
+				Detail: fmt.Sprintf("schema version %d is newer than this build knows (%d)", current, latest),
+				Hint:   "upgrade to the version that last used this data directory",
+			}
+		}
+		return Result{Status: StatusPass, Detail: fmt.Sprintf("schema version %d is current", current)}
+	}}
+}
+
+func providerCheck(cfg *config.Config, name models.ModelProvider, opts Options) Check {
+	providerCfg := cfg.Providers[name]
+	return Check{Name: "provider " + string(name), Run: func(ctx context.Context) Result {
+		hint := fmt.Sprintf("set providers.%s.apiKey or the API key environment variable of the provider", name)
+		if providerCfg.Disabled {
+			return Result{Status: StatusWarn, Detail: "disabled, no API key", Hint: hint}
+		}
+		if opts.Offline {
+			return Result{Status: StatusSkip, Detail: "not authenticated offline"}
+		}
+		modelID, ok := providerModel(cfg, name)
+		if !ok {
+			return Result{Status: StatusWarn, Detail: "no supported model to authenticate with"}
+		}
+
+		p, err := provider.DefaultProviderFactory.NewProvider(modelID, providerCfg, provider.WithMaxTokens(1))
+		if err != nil {
+			return Result{Status: StatusFail, Detail: err.Error(), Hint: hint}
+		}
+		defer p.Close()
+		ping := message.Message{
+			Role:  message.User,
+			Parts: []message.ContentPart{message.TextContent{Text: "ping"}},
+		}
+		if _, err := p.SendMessages(ctx, []message.Message{ping}, nil); err != nil {
+			return Result{Status: StatusFail, Detail: err.Error(), Hint: hint}
+		}
+		return Result{Status: StatusPass, Detail: fmt.Sprintf("authenticated with %s", modelID)}
+	}}
+}
+
+// providerModel returns the model to authenticate a provider with: the first
+// agent model of the provider, or else its first model
+func providerModel(cfg *config.Config, name models.ModelProvider) (models.ModelID, bool) {
+	for _, agent := range sortedKeys(cfg.Agents) {
+		modelID := cfg.Agents[agent].Model
+		if model, ok := models.SupportedModels[modelID]; ok && model.Provider == name {
+			return modelID, true
+		}
+	}
+	if providerModels := models.ModelsForProvider(name); len(providerModels) > 0 {
+		return providerModels[0].ID, true
+	}
+	return "", false
+}
+
+func mcpCheck(name string, server config.MCPServer, opts Options) Check {
+	return Check{Name: "mcp " + name, Run: func(ctx context.Context) Result {
+		switch server.Type {
+		case config.MCPStdio:
+			path, err := exec.LookPath(server.Command)
+			if err != nil {
+				return Result{
+					Status: StatusFail,
+					Detail: fmt.Sprintf("command %q not found", server.Command),
+					Hint:   fmt.Sprintf("install %s or fix mcpServers.%s.command", server.Command, name),
+				}
+			}
+			c, err := client.NewStdioMCPClient(path, append(config.SpaceEnviron(), server.Env...), server.Args...)
+			if err != nil {
+				return Result{Status: StatusFail, Detail: err.Error(), Hint: fmt.Sprintf("run %s by hand to see why it does not start", server.Command)}
+			}
+			return handshake(ctx, c, fmt.Sprintf("run %s by hand to see why it does not answer", server.Command))
+		case config.MCPSse:
+			if opts.Offline {
+				return Result{Status: StatusSkip, Detail: "not contacted offline"}
+			}
+			hint := fmt.Sprintf("check that %s is up and mcpServers.%s.url and headers are right", server.URL, name)
+			if server.TokenRefreshCommand != "" {
+				hint += fmt.Sprintf(", and that mcpServers.%s.tokenRefreshCommand prints a valid token", name)
+			}
+			result, err := mcpauth.Do(ctx, name, server, func(headers map[string]string) (*mcp.InitializeResult, error) {
+				c, err := mcpauth.StartSSEClient(ctx, server.URL, headers)
+				if err != nil {
+					return nil, err
+				}
+				return initialize(ctx, c)
+			})
+			if err != nil {
+				return Result{Status: StatusFail, Detail: err.Error(), Hint: hint}
+			}
+			return answered(result)
+		}
+		return Result{Status: StatusFail, Detail: fmt.Sprintf("unknown type %q", server.Type), Hint: "set the type to stdio or sse"}
+	}}
+}
+
+// mcpClient is the part of the MCP clients a handshake uses
+type mcpClient interface {
+	Initialize(ctx context.Context, request mcp.InitializeRequest) (*mcp.InitializeResult, error)
+	Close() error
+}
+
+// handshake initializes the connection to an MCP server and closes it
+func handshake(ctx context.Context, c mcpClient, hint string) Result {
+	result, err := initialize(ctx, c)
+	if err != nil {
+		return Result{Status: StatusFail, Detail: err.Error(), Hint: hint}
+	}
+	return answered(result)
+}
+
+// initialize initializes the connection to an MCP server and closes it
+func initialize(ctx context.Context, c mcpClient) (*mcp.InitializeResult, error) {
+	defer c.Close()
+	request := mcp.InitializeRequest{}
+	request.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	request.Params.ClientInfo = mcp.Implementation{
+		Name:    "Intelligence Interface",
+		Version: version.Version,
+	}
+	result, err := c.Initialize(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("handshake failed: %w", err)
+	}
+	return result, nil
+}
+
+func answered(result *mcp.InitializeResult) Result {
+	return Result{Status: StatusPass, Detail: fmt.Sprintf("%s %s answered", result.ServerInfo.Name, result.ServerInfo.Version)}
+}
+
+func shellCheck(shell config.ShellConfig) Check {
+	return Check{Name: "shell", Run: func(ctx context.Context) Result {
+		path := shell.Path
+		if path == "" {
+			path = "/bin/bash"
+		}
+		resolved, err := exec.LookPath(path)
+		if err != nil {
+			return Result{
+				Status: StatusFail,
+				Detail: fmt.Sprintf("%s not found", path),
+				Hint:   "set shell.path to an installed shell, the bash tool cannot run without one",
+			}
+		}
+		return Result{Status: StatusPass, Detail: resolved}
+	}}
+}
+
+// LSPServer is an LSP server of the configuration
+type LSPServer struct {
+	// Name is the language, "<root>/<language>" for the servers of a
+	// workspace root like the clients started for them
+	Name   string
+	Config config.LSPConfig
+}
+
+// LSPServers lists the global LSP servers followed by the per-root ones
+func LSPServers(cfg *config.Config) []LSPServer {
+	var servers []LSPServer
+	for _, language := range sortedKeys(cfg.LSP) {
+		servers = append(servers, LSPServer{Name: language, Config: cfg.LSP[language]})
+	}
+	for _, rootName := range sortedKeys(cfg.Workspaces) {
+		root := cfg.Workspaces[rootName]
+		for _, language := range sortedKeys(root.LSP) {
+			servers = append(servers, LSPServer{Name: rootName + "/" + language, Config: root.LSP[language]})
+		}
+	}
+	return servers
+}
+
+func lspCheck(server LSPServer) Check {
+	return Check{Name: "lsp " + server.Name, Run: func(ctx context.Context) Result {
+		if !server.Config.IsEnabled() {
+			return Result{Status: StatusSkip, Detail: "disabled"}
+		}
+		path, err := exec.LookPath(server.Config.Command)
+		if err != nil {
+			return Result{
+				Status: StatusFail,
+				Detail: fmt.Sprintf("command %q not on PATH", server.Config.Command),
+				Hint:   fmt.Sprintf("install %s or disable the %s server", server.Config.Command, server.Name),
+			}
+		}
+		return Result{Status: StatusPass, Detail: path}
+	}}
+}
+
+func sortedKeys[K ~string, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
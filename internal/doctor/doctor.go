@@ -0,0 +1,134 @@
+// Package doctor diagnoses the common misconfigurations: missing API keys,
+// an unwritable data directory, a stale database schema, broken MCP servers
+// and missing commands.
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Status is the outcome of a check
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+	// StatusSkip is a check that was not run, such as the network checks
+	// when offline
+	StatusSkip Status = "skip"
+)
+
+// DefaultTimeout bounds every check, so a hung MCP server or provider does
+// not stall the diagnosis
+const DefaultTimeout = 10 * time.Second
+
+// Result is the outcome of a check, with a hint at the remediation when it
+// did not pass
+type Result struct {
+	Name     string        `json:"name"`
+	Status   Status        `json:"status"`
+	Detail   string        `json:"detail,omitempty"`
+	Hint     string        `json:"hint,omitempty"`
+	Duration time.Duration `json:"-"`
+	// DurationMs is Duration in milliseconds, for the JSON output
+	DurationMs int64 `json:"durationMs"`
+}
+
+// Check is a diagnosis, run within the timeout of its context
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) Result
+}
+
+// Run runs the checks concurrently, each within timeout, and returns their
+// results in the order of the checks. A check still running at its timeout
+// fails, the others go on.
+func Run(ctx context.Context, checks []Check, timeout time.Duration) []Result {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = runCheck(ctx, check, timeout)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func runCheck(ctx context.Context, check Check, timeout time.Duration) Result {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan Result, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- Result{Status: StatusFail, Detail: fmt.Sprintf("check panicked: %v", r)}
+			}
+		}()
+		done <- check.Run(ctx)
+	}()
+
+	var result Result
+	select {
+	case result = <-done:
+	case <-ctx.Done():
+		result = Result{
+			Status: StatusFail,
+			Detail: fmt.Sprintf("timed out after %s", timeout),
+			Hint:   "the check did not answer in time, run it again with a longer --timeout",
+		}
+	}
+	result.Name = check.Name
+	result.Duration = time.Since(start)
+	result.DurationMs = result.Duration.Milliseconds()
+	return result
+}
+
+// Failed returns the number of results that failed
+func Failed(results []Result) int {
+	failed := 0
+	for _, result := range results {
+		if result.Status == StatusFail {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Render formats the results as a table, followed by the remediation hints
+// of the checks that did not pass
+func Render(results []Result) string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHECK\tSTATUS\tDETAIL")
+	for _, result := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", result.Name, strings.ToUpper(string(result.Status)), result.Detail)
+	}
+	w.Flush()
+
+	var hints []string
+	for _, result := range results {
+		if result.Hint != "" && (result.Status == StatusWarn || result.Status == StatusFail) {
+			hints = append(hints, fmt.Sprintf("  %s: %s", result.Name, result.Hint))
+		}
+	}
+	if len(hints) > 0 {
+		b.WriteString("\nHints:\n")
+		b.WriteString(strings.Join(hints, "\n"))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,113 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/db"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+)
+
+func TestRunTimeboxesChecks(t *testing.T) {
+	hung := Check{Name: "hung", Run: func(ctx context.Context) Result {
+		select {} // ignores its context, like a server that never answers
+	}}
+	quick := Check{Name: "quick", Run: func(ctx context.Context) Result {
+		return Result{Status: StatusPass}
+	}}
+
+	start := time.Now()
+	results := Run(context.Background(), []Check{hung, quick}, 50*time.Millisecond)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Run() took %s, the hung check should be cut at its timeout", elapsed)
+	}
+
+	if results[0].Name != "hung" || results[0].Status != StatusFail || !strings.Contains(results[0].Detail, "timed out") {
+		t.Errorf("hung check = %+v, want a timeout failure", results[0])
+	}
+	if results[1].Name != "quick" || results[1].Status != StatusPass {
+		t.Errorf("quick check = %+v, want a pass", results[1])
+	}
+	if Failed(results) != 1 {
+		t.Errorf("Failed() = %d, want 1", Failed(results))
+	}
+}
+
+func TestChecks(t *testing.T) {
+	dataDir := t.TempDir()
+	cfg := config.NewTestConfig(func(c *config.Config) {
+		c.Data.Directory = dataDir
+		c.Shell = config.ShellConfig{Path: "/nonexistent/shell"}
+		c.Providers[models.ProviderOpenAI] = config.Provider{APIKey: "key"}
+		c.Providers[models.ProviderGROQ] = config.Provider{Disabled: true}
+		c.MCPServers["missing"] = config.MCPServer{Type: config.MCPStdio, Command: "nonexistent-mcp-server"}
+		c.MCPServers["remote"] = config.MCPServer{Type: config.MCPSse, URL: "http://localhost:1/sse"}
+		disabled := false
+		c.LSP["go"] = config.LSPConfig{Command: "nonexistent-gopls"}
+		c.LSP["rust"] = config.LSPConfig{Command: "rust-analyzer", Enabled: &disabled}
+	})
+
+	results := Run(context.Background(), Checks(cfg, nil, Options{Offline: true}), time.Second)
+	want := map[string]Status{
+		"config":          StatusPass,
+		"data directory":  StatusPass,
+		"database":        StatusPass,
+		"provider groq":   StatusWarn,
+		"provider openai": StatusSkip,
+		"mcp missing":     StatusFail,
+		"mcp remote":      StatusSkip,
+		"shell":           StatusFail,
+		"lsp go":          StatusFail,
+		"lsp rust":        StatusSkip,
+	}
+	got := make(map[string]Result)
+	for _, result := range results {
+		got[result.Name] = result
+	}
+	for name, status := range want {
+		result, ok := got[name]
+		if !ok {
+			t.Errorf("no %q check", name)
+			continue
+		}
+		if result.Status != status {
+			t.Errorf("%s = %+v, want %s", name, result, status)
+		}
+		if (status == StatusWarn || status == StatusFail) && result.Hint == "" {
+			t.Errorf("%s has no hint", name)
+		}
+	}
+
+	rendered := Render(results)
+	if !strings.Contains(rendered, "Hints:") || !strings.Contains(rendered, "nonexistent-gopls") {
+		t.Errorf("Render() =\n%s\nwant the hints of the failed checks", rendered)
+	}
+}
+
+func TestChecksWithoutConfig(t *testing.T) {
+	results := Run(context.Background(), Checks(nil, errors.New("invalid JSON"), Options{}), time.Second)
+	if len(results) != 1 || results[0].Status != StatusFail || results[0].Detail != "invalid JSON" {
+		t.Errorf("results = %+v, want only the failed config check", results)
+	}
+}
+
+func TestDatabaseCheck(t *testing.T) {
+	dataDir := t.TempDir()
+	config.NewTestConfig(func(c *config.Config) {
+		c.Data.Directory = dataDir
+	})
+	conn, err := db.Connect()
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	conn.Close()
+
+	result := Run(context.Background(), []Check{databaseCheck()}, time.Second)[0]
+	if result.Status != StatusPass || !strings.Contains(result.Detail, "is current") {
+		t.Errorf("database check = %+v, want a current schema", result)
+	}
+}
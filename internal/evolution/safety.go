@@ -0,0 +1,151 @@
+// Package evolution deploys the Go code generated when the system evolves.
+// With the safety checks enabled, the change only goes through once a copy of
+// the source tree with the change applied builds and passes its tests, so
+// that self-modification cannot break the running system.
+package evolution
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+)
+
+// ErrEvolutionSafetyFailed is returned when evolved code does not build or
+// does not pass its tests
+var ErrEvolutionSafetyFailed = errors.New("evolved code failed the safety checks")
+
+// Safety check stages
+const (
+	StageBuild = "build"
+	StageTest  = "test"
+)
+
+// SafetyError is a failed safety check, with the output of the build or the
+// tests. It matches ErrEvolutionSafetyFailed.
+type SafetyError struct {
+	Stage  string
+	Output string
+	Err    error
+}
+
+func (e *SafetyError) Error() string {
+	return fmt.Sprintf("%s of the evolved code failed: %v\n%s", e.Stage, e.Err, e.Output)
+}
+
+func (e *SafetyError) Unwrap() []error {
+	return []error{ErrEvolutionSafetyFailed, e.Err}
+}
+
+// Change is evolved code: the files generated for a source tree, by path
+// relative to its root
+type Change struct {
+	// Source is the root of the Go module the files are generated for
+	Source string
+	Files  map[string][]byte
+}
+
+// Deploy copies the source tree of change into a temporary directory and
+// applies the change there. With the safety checks of cfg enabled, the copy
+// must build and pass its tests before deploy is called with its directory.
+// The directory is removed afterwards, which rolls back a failed change.
+func Deploy(ctx context.Context, cfg config.EvolutionConfig, change Change, deploy func(dir string) error) error {
+	dir, err := os.MkdirTemp("", "ii-evolution-")
+	if err != nil {
+		return fmt.Errorf("failed to create the evolution directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := stage(change, dir); err != nil {
+		return err
+	}
+	if cfg.SafetyChecksEnabled {
+		if err := Check(ctx, dir, cfg.SafetyTimeout()); err != nil {
+			logging.Warn("Evolved code failed the safety checks, rolled back", "error", err)
+			return err
+		}
+	} else {
+		logging.Warn("Deploying evolved code without safety checks")
+	}
+	return deploy(dir)
+}
+
+// stage copies the source tree into dir, without its git data, and writes
+// the files of the change over it
+func stage(change Change, dir string) error {
+	err := filepath.WalkDir(change.Source, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(change.Source, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dir, rel)
+		switch {
+		case d.IsDir() && d.Name() == ".git":
+			return filepath.SkipDir
+		case d.IsDir():
+			return os.MkdirAll(target, 0o755)
+		case !d.Type().IsRegular():
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, content, 0o644)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy the source tree: %w", err)
+	}
+
+	for name, content := range change.Files {
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("evolved file %s is outside the source tree", name)
+		}
+		target := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return fmt.Errorf("failed to write evolved file %s: %w", name, err)
+		}
+		if err := os.WriteFile(target, content, 0o644); err != nil {
+			return fmt.Errorf("failed to write evolved file %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Check builds the Go module in dir, then runs its tests within timeout
+func Check(ctx context.Context, dir string, timeout time.Duration) error {
+	if err := run(ctx, dir, StageBuild, "build", "./..."); err != nil {
+		return err
+	}
+	testCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := run(testCtx, dir, StageTest, "test", "./...")
+	var safetyErr *SafetyError
+	if errors.As(err, &safetyErr) && testCtx.Err() == context.DeadlineExceeded {
+		safetyErr.Err = fmt.Errorf("tests timed out after %s", timeout)
+	}
+	return err
+}
+
+func run(ctx context.Context, dir, stage string, args ...string) error {
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = dir
+	cmd.Stdout, cmd.Stderr = &output, &output
+	if err := cmd.Run(); err != nil {
+		return &SafetyError{Stage: stage, Output: strings.TrimSpace(output.String()), Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package evolution
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newModule writes a Go module with a tested function
+func newModule(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":        "module example.com/evolved\n\ngo 1.24\n",
+		"sum.go":        "package evolved\n\nfunc Sum(a, b int) int { return a + b }\n",
+		"sum_test.go":   "package evolved\n\nimport \"testing\"\n\nfunc TestSum(t *testing.T) {\n\tif Sum(1, 2) != 3 {\n\t\tt.Fatal(\"wrong sum\")\n\t}\n}\n",
+		".git/HEAD":     "ref: refs/heads/main\n",
+		"docs/notes.md": "notes\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	return dir
+}
+
+func TestDeploy(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	source := newModule(t)
+	cfg := config.EvolutionConfig{SafetyChecksEnabled: true}
+
+	tests := []struct {
+		name  string
+		files map[string][]byte
+		stage string
+		// output is part of the output of the failed check
+		output string
+	}{
+		{
+			name:  "passing change",
+			files: map[string][]byte{"double.go": []byte("package evolved\n\nfunc Double(a int) int { return Sum(a, a) }\n")},
+		},
+		{
+			name:   "change that does not build",
+			files:  map[string][]byte{"sum.go": []byte("package evolved\n\nfunc Sum(a, b int) int { return a + c }\n")},
+			stage:  StageBuild,
+			output: "undefined: c",
+		},
+		{
+			name:   "change that fails the tests",
+			files:  map[string][]byte{"sum.go": []byte("package evolved\n\nfunc Sum(a, b int) int { return a - b }\n")},
+			stage:  StageTest,
+			output: "wrong sum",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deployed []string
+			err := Deploy(context.Background(), cfg, Change{Source: source, Files: tt.files}, func(dir string) error {
+				entries, err := os.ReadDir(dir)
+				for _, entry := range entries {
+					deployed = append(deployed, entry.Name())
+				}
+				return err
+			})
+
+			if tt.stage == "" {
+				require.NoError(t, err)
+				assert.ElementsMatch(t, []string{"go.mod", "sum.go", "sum_test.go", "double.go", "docs"}, deployed, "the git data is not copied")
+			} else {
+				require.ErrorIs(t, err, ErrEvolutionSafetyFailed)
+				var safetyErr *SafetyError
+				require.True(t, errors.As(err, &safetyErr))
+				assert.Equal(t, tt.stage, safetyErr.Stage)
+				assert.Contains(t, safetyErr.Output, tt.output)
+				assert.Empty(t, deployed)
+			}
+
+			leftovers, err := os.ReadDir(os.TempDir())
+			require.NoError(t, err)
+			assert.Empty(t, leftovers, "the evolution directory is removed")
+		})
+	}
+
+	// Without the safety checks the change is deployed as is
+	err := Deploy(context.Background(), config.EvolutionConfig{}, Change{Source: source, Files: tests[1].files}, func(string) error { return nil })
+	assert.NoError(t, err)
+
+	err = Deploy(context.Background(), cfg, Change{Source: source, Files: map[string][]byte{"../escape.go": nil}}, func(string) error { return nil })
+	assert.ErrorContains(t, err, "outside the source tree")
+}
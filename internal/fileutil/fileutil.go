@@ -161,3 +161,28 @@ func GlobWithDoublestar(pattern, searchPath string, limit int) ([]string, bool,
 	}
 	return results, truncated, nil
 }
+
+// Ignored returns which of paths, relative to dir, the ignore files of the
+// git repository of dir exclude. Nothing is ignored outside a repository or
+// when git is not installed.
+func Ignored(dir string, paths []string) map[string]bool {
+	ignored := make(map[string]bool)
+	if len(paths) == 0 {
+		return ignored
+	}
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		return ignored
+	}
+	cmd := exec.Command(gitPath, "-C", dir, "check-ignore", "-z", "--stdin")
+	cmd.Stdin = strings.NewReader(strings.Join(paths, "\x00") + "\x00")
+	// check-ignore exits with 1 when no path is ignored, and 128 outside a
+	// repository
+	output, _ := cmd.Output()
+	for _, path := range strings.Split(string(output), "\x00") {
+		if path != "" {
+			ignored[path] = true
+		}
+	}
+	return ignored
+}
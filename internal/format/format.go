@@ -1,7 +1,6 @@
 package format
 
 import (
-	"encoding/json"
 	"fmt"
 	"strings"
 )
@@ -15,6 +14,10 @@ const (
 
 	// JSON format outputs the AI response wrapped in a JSON object.
 	JSON OutputFormat = "json"
+
+	// Markdown format outputs the AI response followed by its tool calls and
+	// usage, as Markdown.
+	Markdown OutputFormat = "markdown"
 )
 
 // String returns the string representation of the OutputFormat
@@ -26,6 +29,7 @@ func (f OutputFormat) String() string {
 var SupportedFormats = []string{
 	string(Text),
 	string(JSON),
+	string(Markdown),
 }
 
 // Parse converts a string to an OutputFormat
@@ -37,6 +41,8 @@ func Parse(s string) (OutputFormat, error) {
 		return Text, nil
 	case string(JSON):
 		return JSON, nil
+	case string(Markdown):
+		return Markdown, nil
 	default:
 		return "", fmt.Errorf("invalid format: %s", s)
 	}
@@ -52,48 +58,8 @@ func IsValid(s string) bool {
 func GetHelpText() string {
 	return fmt.Sprintf(`Supported output formats:
 - %s: Plain text output (default)
-- %s: Output wrapped in a JSON object`,
-		Text, JSON)
-}
-
-// FormatOutput formats the AI response according to the specified format
-func FormatOutput(content string, formatStr string) string {
-	format, err := Parse(formatStr)
-	if err != nil {
-		// Default to text format on error
-		return content
-	}
-
-	switch format {
-	case JSON:
-		return formatAsJSON(content)
-	case Text:
-		fallthrough
-	default:
-		return content
-	}
-}
-
-// formatAsJSON wraps the content in a simple JSON object
-func formatAsJSON(content string) string {
-	// Use the JSON package to properly escape the content
-	response := struct {
-		Response string `json:"response"`
-	}{
-		Response: content,
-	}
-
-	jsonBytes, err := json.MarshalIndent(response, "", "  ")
-	if err != nil {
-		// In case of an error, return a manually formatted JSON
-		jsonEscaped := strings.Replace(content, "\\", "\\\\", -1)
-		jsonEscaped = strings.Replace(jsonEscaped, "\"", "\\\"", -1)
-		jsonEscaped = strings.Replace(jsonEscaped, "\n", "\\n", -1)
-		jsonEscaped = strings.Replace(jsonEscaped, "\r", "\\r", -1)
-		jsonEscaped = strings.Replace(jsonEscaped, "\t", "\\t", -1)
-
-		return fmt.Sprintf("{\n  \"response\": \"%s\"\n}", jsonEscaped)
-	}
-
-	return string(jsonBytes)
+- %s: The response, tool calls, usage, duration, model and session as a JSON object
+- %s: The response followed by its tool calls and usage, as Markdown
+Use --output-template for any other shape`,
+		Text, JSON, Markdown)
 }
@@ -0,0 +1,96 @@
+package format
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testResult = Result{
+	Content: "All tests pass.",
+	ToolCalls: []ToolCall{
+		{Name: "bash", Input: `{"command":"go test ./..."}`, Output: "ok"},
+		{Name: "edit", Input: `{"file_path":"main.go"}`, Output: "file not found", IsError: true},
+	},
+	Usage:      Usage{InputTokens: 1200, OutputTokens: 300, Cost: 0.0123},
+	DurationMs: 4500,
+	Model:      "claude-4-sonnet",
+	SessionID:  "session-1",
+}
+
+func TestParse(t *testing.T) {
+	for _, s := range []string{"text", "JSON", " markdown "} {
+		_, err := Parse(s)
+		assert.NoError(t, err, s)
+	}
+	_, err := Parse("yaml")
+	assert.Error(t, err)
+}
+
+func TestRenderText(t *testing.T) {
+	assert.Equal(t, "All tests pass.", Render(testResult, Text))
+}
+
+func TestRenderJSON(t *testing.T) {
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal([]byte(Render(testResult, JSON)), &decoded))
+	assert.Equal(t, "All tests pass.", decoded["response"])
+	assert.Equal(t, "session-1", decoded["session_id"])
+	assert.Equal(t, 4500.0, decoded["duration_ms"])
+	assert.Len(t, decoded["tool_calls"], 2)
+	assert.Equal(t, 300.0, decoded["usage"].(map[string]any)["output_tokens"])
+
+	// A run without tool calls has an empty list rather than null
+	require.NoError(t, json.Unmarshal([]byte(Render(Result{Content: "hi"}, JSON)), &decoded))
+	assert.Equal(t, []any{}, decoded["tool_calls"])
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	assert.Equal(t, "All tests pass.\n\n"+
+		"### Tool calls\n\n"+
+		"- `bash` (ok)\n"+
+		"- `edit` (failed)\n\n"+
+		"_claude-4-sonnet · 1200 input and 300 output tokens · $0.0123 · 4.5s_",
+		Render(testResult, Markdown))
+
+	assert.Equal(t, "hi\n\n_ · 0 input and 0 output tokens · $0.0000 · 0.0s_", Render(Result{Content: "hi"}, Markdown))
+}
+
+func TestToolErrors(t *testing.T) {
+	assert.Equal(t, 1, testResult.ToolErrors())
+	assert.Equal(t, 0, Result{}.ToolErrors())
+}
+
+func TestRenderTemplate(t *testing.T) {
+	tmpl, err := ParseTemplate(`{{.Model}}: {{trim .Content}} ({{len .ToolCalls}} tools, {{.Usage.InputTokens}} in)` +
+		`{{range .ToolCalls}}{{if .IsError}} {{.Name}} failed{{end}}{{end}} {{json .Usage.Cost}}`)
+	require.NoError(t, err)
+
+	rendered, err := RenderTemplate(tmpl, testResult)
+	require.NoError(t, err)
+	assert.Equal(t, "claude-4-sonnet: All tests pass. (2 tools, 1200 in) edit failed 0.0123", rendered)
+}
+
+func TestParseTemplateErrors(t *testing.T) {
+	for name, text := range map[string]string{
+		"missing field":        "{{.Response}}",
+		"missing nested field": "{{.Usage.Tokens}}",
+		"syntax":               "{{.Content",
+		"unknown function":     "{{upper .Content}}",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseTemplate(text)
+			assert.ErrorContains(t, err, "invalid output template")
+		})
+	}
+
+	_, err := ParseTemplate("{{.Response}}")
+	assert.ErrorContains(t, err, "the fields are Content, ToolCalls")
+
+	// Templates indexing the tool calls are valid, even though a run may
+	// have none
+	_, err = ParseTemplate(`{{(index .ToolCalls 0).Name}}`)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,132 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// Result is the outcome of a non-interactive run, which the output formats
+// render and the templates of --output-template are executed on
+type Result struct {
+	// Content is the text of the final response
+	Content string `json:"response"`
+	// ToolCalls are the tools the agent called during the run, in order
+	ToolCalls []ToolCall `json:"tool_calls"`
+	// Usage is the usage of the whole run
+	Usage Usage `json:"usage"`
+	// DurationMs is the wall-clock time of the run in milliseconds
+	DurationMs int64 `json:"duration_ms"`
+	// Model is the ID of the model of the final response
+	Model string `json:"model"`
+	// SessionID is the session the run was recorded in
+	SessionID string `json:"session_id"`
+}
+
+// ToolCall is a tool called during a non-interactive run, with its result
+type ToolCall struct {
+	Name    string `json:"name"`
+	Input   string `json:"input"`
+	Output  string `json:"output"`
+	IsError bool   `json:"is_error"`
+}
+
+// Usage is the token usage and cost of a non-interactive run
+type Usage struct {
+	InputTokens      int64   `json:"input_tokens"`
+	OutputTokens     int64   `json:"output_tokens"`
+	CacheReadTokens  int64   `json:"cache_read_tokens"`
+	CacheWriteTokens int64   `json:"cache_write_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// ToolErrors returns the number of tool calls which failed
+func (r Result) ToolErrors() int {
+	failed := 0
+	for _, call := range r.ToolCalls {
+		if call.IsError {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Render renders the result in format, text being the default
+func Render(result Result, format OutputFormat) string {
+	switch format {
+	case JSON:
+		return renderJSON(result)
+	case Markdown:
+		return renderMarkdown(result)
+	default:
+		return result.Content
+	}
+}
+
+func renderJSON(result Result) string {
+	if result.ToolCalls == nil {
+		result.ToolCalls = []ToolCall{}
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return result.Content
+	}
+	return string(data)
+}
+
+func renderMarkdown(result Result) string {
+	var b strings.Builder
+	b.WriteString(result.Content)
+	if len(result.ToolCalls) > 0 {
+		b.WriteString("\n\n### Tool calls\n")
+		for _, call := range result.ToolCalls {
+			status := "ok"
+			if call.IsError {
+				status = "failed"
+			}
+			fmt.Fprintf(&b, "\n- `%s` (%s)", call.Name, status)
+		}
+	}
+	fmt.Fprintf(&b, "\n\n_%s · %d input and %d output tokens · $%.4f · %.1fs_",
+		result.Model, result.Usage.InputTokens, result.Usage.OutputTokens, result.Usage.Cost, float64(result.DurationMs)/1000)
+	return b.String()
+}
+
+// templateFuncs are the functions output templates can call besides the
+// builtin ones
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"trim": strings.TrimSpace,
+}
+
+// sampleResult is the result output templates are tried on when parsed, with
+// a tool call for the templates indexing them
+var sampleResult = Result{ToolCalls: []ToolCall{{}}}
+
+// ParseTemplate parses an output template executed on a Result. The template
+// is also tried on a sample result, so a template naming a field Result does
+// not have fails here, before the run, rather than once it is paid for.
+func ParseTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("output").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output template: %w", err)
+	}
+	if err := tmpl.Execute(&bytes.Buffer{}, sampleResult); err != nil {
+		return nil, fmt.Errorf("invalid output template: %w (the fields are Content, ToolCalls, Usage, DurationMs, Model and SessionID)", err)
+	}
+	return tmpl, nil
+}
+
+// RenderTemplate renders the result with a template of ParseTemplate
+func RenderTemplate(tmpl *template.Template, result Result) (string, error) {
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, result); err != nil {
+		return "", fmt.Errorf("failed to render the output template: %w", err)
+	}
+	return b.String(), nil
+}
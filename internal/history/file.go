@@ -38,6 +38,11 @@ type Service interface {
 	Update(ctx context.Context, file File) (File, error)
 	Delete(ctx context.Context, id string) error
 	DeleteSessionFiles(ctx context.Context, sessionID string) error
+	// RecordOperation records a change applied by a tool call, once
+	RecordOperation(ctx context.Context, op Operation) error
+	// GetOperation returns the change applied by a tool call, or
+	// ErrOperationNotFound when it was not applied
+	GetOperation(ctx context.Context, id string) (Operation, error)
 }
 
 type service struct {
@@ -0,0 +1,66 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/caronex/intelligence-interface/internal/db"
+)
+
+// ErrOperationNotFound is returned for an operation that was never applied
+var ErrOperationNotFound = errors.New("operation not found")
+
+// Operation is a change a tool call applied to a file, recorded so that the
+// call is not applied again when it is dispatched again
+type Operation struct {
+	ID        string
+	SessionID string
+	MessageID string
+	Tool      string
+	Path      string
+	// BeforeHash and AfterHash are the SHA-256 of the file before and after
+	// the change, BeforeHash empty when the call created the file
+	BeforeHash string
+	AfterHash  string
+	// Result and Metadata are the response of the call
+	Result    string
+	Metadata  string
+	CreatedAt int64
+}
+
+func (s *service) RecordOperation(ctx context.Context, op Operation) error {
+	return s.q.CreateToolOperation(ctx, db.CreateToolOperationParams{
+		ID:         op.ID,
+		SessionID:  op.SessionID,
+		MessageID:  op.MessageID,
+		Tool:       op.Tool,
+		Path:       op.Path,
+		BeforeHash: op.BeforeHash,
+		AfterHash:  op.AfterHash,
+		Result:     op.Result,
+		Metadata:   op.Metadata,
+	})
+}
+
+func (s *service) GetOperation(ctx context.Context, id string) (Operation, error) {
+	item, err := s.q.GetToolOperation(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Operation{}, ErrOperationNotFound
+	}
+	if err != nil {
+		return Operation{}, err
+	}
+	return Operation{
+		ID:         item.ID,
+		SessionID:  item.SessionID,
+		MessageID:  item.MessageID,
+		Tool:       item.Tool,
+		Path:       item.Path,
+		BeforeHash: item.BeforeHash,
+		AfterHash:  item.AfterHash,
+		Result:     item.Result,
+		Metadata:   item.Metadata,
+		CreatedAt:  item.CreatedAt,
+	}, nil
+}
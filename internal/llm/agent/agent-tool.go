@@ -2,7 +2,6 @@ package agent
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 
 	"github.com/caronex/intelligence-interface/internal/core/config"
@@ -42,8 +41,8 @@ func (b *agentTool) Info() tools.ToolInfo {
 
 func (b *agentTool) Run(ctx context.Context, call tools.ToolCall) (tools.ToolResponse, error) {
 	var params AgentParams
-	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
-		return tools.NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	if err := call.BindInput(&params); err != nil {
+		return tools.NewTextErrorResponse(err.Error()), nil
 	}
 	if params.Prompt == "" {
 		return tools.NewTextErrorResponse("prompt is required"), nil
@@ -54,17 +53,24 @@ func (b *agentTool) Run(ctx context.Context, call tools.ToolCall) (tools.ToolRes
 		return tools.ToolResponse{}, fmt.Errorf("session_id and message_id are required")
 	}
 
-	agent, err := NewAgent(config.AgentCaronex, b.sessions, b.messages, ManagerAgentTools())
+	subAgent, err := NewAgent(config.AgentCaronex, b.sessions, b.messages, ManagerAgentTools())
 	if err != nil {
 		return tools.ToolResponse{}, fmt.Errorf("error creating agent: %s", err)
 	}
 
+	// The delegated agent starts from the working context of this conversation,
+	// assembled again whenever the delegating response is retried
+	handoff, err := subAgent.(*agent).handoff(ctx, sessionID, params.Prompt)
+	if err != nil {
+		return tools.ToolResponse{}, fmt.Errorf("error assembling handoff: %s", err)
+	}
+
 	session, err := b.sessions.CreateTaskSession(ctx, call.ID, sessionID, "New Agent Session")
 	if err != nil {
 		return tools.ToolResponse{}, fmt.Errorf("error creating session: %s", err)
 	}
 
-	done, err := agent.Run(ctx, session.ID, params.Prompt)
+	done, err := subAgent.Run(ctx, session.ID, handoff.Prompt())
 	if err != nil {
 		return tools.ToolResponse{}, fmt.Errorf("error generating agent: %s", err)
 	}
@@ -93,7 +99,10 @@ func (b *agentTool) Run(ctx context.Context, call tools.ToolCall) (tools.ToolRes
 	if err != nil {
 		return tools.ToolResponse{}, fmt.Errorf("error saving parent session: %s", err)
 	}
-	return tools.NewTextResponse(response.Content().String()), nil
+	return tools.WithResponseMetadata(
+		tools.NewTextResponse(response.Content().String()),
+		HandoffResponseMetadata{SessionID: session.ID, Handoff: handoff},
+	), nil
 }
 
 func NewAgentTool(
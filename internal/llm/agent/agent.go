@@ -6,8 +6,13 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/caronex/intelligence-interface/internal/analytics"
+	"github.com/caronex/intelligence-interface/internal/artifact"
+	"github.com/caronex/intelligence-interface/internal/audit"
+	"github.com/caronex/intelligence-interface/internal/connectivity"
 	"github.com/caronex/intelligence-interface/internal/core/config"
 	"github.com/caronex/intelligence-interface/internal/llm/models"
 	"github.com/caronex/intelligence-interface/internal/llm/prompt"
@@ -18,6 +23,9 @@ import (
 	"github.com/caronex/intelligence-interface/internal/permission"
 	"github.com/caronex/intelligence-interface/internal/pubsub"
 	"github.com/caronex/intelligence-interface/internal/session"
+	"github.com/caronex/intelligence-interface/internal/snapshot"
+	"github.com/caronex/intelligence-interface/internal/tools/coordination"
+	"github.com/caronex/intelligence-interface/internal/tracing"
 )
 
 // Common errors
@@ -32,6 +40,8 @@ const (
 	AgentEventTypeError     AgentEventType = "error"
 	AgentEventTypeResponse  AgentEventType = "response"
 	AgentEventTypeSummarize AgentEventType = "summarize"
+	// AgentEventTypeAuto reports the progress of an auto mode run
+	AgentEventTypeAuto AgentEventType = "auto"
 )
 
 type AgentEvent struct {
@@ -39,7 +49,7 @@ type AgentEvent struct {
 	Message message.Message
 	Error   error
 
-	// When summarizing
+	// When summarizing or running in auto mode
 	SessionID string
 	Progress  string
 	Done      bool
@@ -49,15 +59,31 @@ type Service interface {
 	pubsub.Suscriber[AgentEvent]
 	Model() models.Model
 	Run(ctx context.Context, sessionID string, content string, attachments ...message.Attachment) (<-chan AgentEvent, error)
+	Retry(ctx context.Context, sessionID string, opts RetryOptions) (<-chan AgentEvent, error)
+	Resend(ctx context.Context, sessionID, messageID, content string, attachments ...message.Attachment) (<-chan AgentEvent, error)
+	// EstimatePrompt estimates the prompt tokens and cost of sending content
+	// to a session, before it is sent
+	EstimatePrompt(ctx context.Context, sessionID, content string, attachments ...message.Attachment) (PromptEstimate, error)
 	Cancel(sessionID string)
 	IsSessionBusy(sessionID string) bool
 	IsBusy() bool
 	Update(agentName config.AgentName, modelID models.ModelID) (models.Model, error)
 	Summarize(ctx context.Context, sessionID string) error
+	FreezeContext(sessionID string, frozen bool)
+	IsContextFrozen(sessionID string) bool
+	// ContextSources returns the sources of context the system prompt
+	// includes, which a message can leave out with WithExcludedContext
+	ContextSources() []message.ContextSource
+	HandleSystemEvent(ctx context.Context, event coordination.SystemEvent)
+	ChooseComparison(ctx context.Context, sessionID, messageID string, choice int) (message.Message, error)
+	// ReportChanges posts to the session what a run changed in the workspace
+	// since the snapshot before was taken, nil when it changed nothing
+	ReportChanges(ctx context.Context, sessionID, runID string, before *snapshot.Snapshot) *message.WorkspaceChanges
 }
 
 type agent struct {
 	*pubsub.Broker[AgentEvent]
+	name     config.AgentName
 	sessions session.Service
 	messages message.Service
 
@@ -68,6 +94,13 @@ type agent struct {
 	summarizeProvider provider.Provider
 
 	activeRequests sync.Map
+
+	contextMu sync.Mutex
+	contexts  map[string]*sessionContext
+
+	// reloadPending is set when the prompt configuration changed while the
+	// agent was busy
+	reloadPending atomic.Bool
 }
 
 func NewAgent(
@@ -76,21 +109,22 @@ func NewAgent(
 	messages message.Service,
 	agentTools []tools.BaseTool,
 ) (Service, error) {
-	agentProvider, err := createAgentProvider(agentName)
+	cfg := config.Get()
+	agentProvider, err := createAgentProvider(cfg, agentName)
 	if err != nil {
 		return nil, err
 	}
 	var titleProvider provider.Provider
 	// Only generate titles for the caronex agent
 	if agentName == config.AgentCaronex {
-		titleProvider, err = createAgentProvider(config.AgentCaronex)
+		titleProvider, err = createAgentProvider(cfg, config.AgentCaronex)
 		if err != nil {
 			return nil, err
 		}
 	}
 	var summarizeProvider provider.Provider
 	if agentName == config.AgentCaronex {
-		summarizeProvider, err = createAgentProvider(config.AgentCaronex)
+		summarizeProvider, err = createAgentProvider(cfg, config.AgentCaronex)
 		if err != nil {
 			return nil, err
 		}
@@ -98,6 +132,7 @@ func NewAgent(
 
 	agent := &agent{
 		Broker:            pubsub.NewBroker[AgentEvent](),
+		name:              agentName,
 		provider:          agentProvider,
 		messages:          messages,
 		sessions:          sessions,
@@ -105,6 +140,13 @@ func NewAgent(
 		titleProvider:     titleProvider,
 		summarizeProvider: summarizeProvider,
 		activeRequests:    sync.Map{},
+		contexts:          make(map[string]*sessionContext),
+	}
+	agent.warnToolsUnavailable()
+	if agentName == config.AgentCaronex {
+		if err := agent.checkSessionDefaults(cfg); err != nil {
+			return nil, err
+		}
 	}
 
 	return agent, nil
@@ -114,6 +156,25 @@ func (a *agent) Model() models.Model {
 	return a.provider.Model()
 }
 
+// toolsFor returns the tools the agent offers model, none when the model does
+// not support function calling
+func (a *agent) toolsFor(model models.Model) []tools.BaseTool {
+	if !model.SupportsTools {
+		return nil
+	}
+	return a.tools
+}
+
+// warnToolsUnavailable tells the user when the model of the agent cannot use
+// its tools
+func (a *agent) warnToolsUnavailable() {
+	model := a.provider.Model()
+	if len(a.tools) == 0 || model.SupportsTools {
+		return
+	}
+	logging.WarnPersist(fmt.Sprintf("%s does not support tools: the %s agent can only answer in text, switch models to use tools", model.Name, a.name))
+}
+
 func (a *agent) Cancel(sessionID string) {
 	// Cancel regular requests
 	if cancelFunc, exists := a.activeRequests.LoadAndDelete(sessionID); exists {
@@ -195,33 +256,133 @@ func (a *agent) err(err error) AgentEvent {
 }
 
 func (a *agent) Run(ctx context.Context, sessionID string, content string, attachments ...message.Attachment) (<-chan AgentEvent, error) {
-	if !a.provider.Model().SupportsAttachments && attachments != nil {
-		attachments = nil
+	gen := generation{provider: a.provider}
+	attachmentParts := gen.attachmentParts(attachments)
+	// The review runs with the configuration it started with
+	cfg := config.Get()
+	sources, err := a.resolveContextSources(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	ctx = withContextSources(ctx, sources)
+	if compared, ok := comparisonModels(ctx); ok && cfg != nil {
+		providers, err := a.comparisonProviders(cfg, compared, sources)
+		if err != nil {
+			return nil, err
+		}
+		events, err := a.start(ctx, sessionID, gen, func(ctx context.Context) AgentEvent {
+			return a.processComparedGeneration(ctx, providers, sessionID, content, attachmentParts)
+		})
+		if err != nil {
+			a.closeComparisonProviders(providers)
+		}
+		return events, err
+	}
+	// The message is sent with a provider of its own when its session runs
+	// with other settings than the agent's, or it leaves out sources of
+	// context
+	settings, err := a.sessionSettings(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	gen.allowedTools = settings.allowedTools
+	if gen.provider, err = a.runProvider(cfg, settings, "", sources); err != nil {
+		return nil, err
+	}
+	start := func(run func(ctx context.Context) AgentEvent) (<-chan AgentEvent, error) {
+		events, err := a.start(ctx, sessionID, gen, func(ctx context.Context) AgentEvent {
+			defer a.closeRunProvider(gen.provider)
+			return run(ctx)
+		})
+		if err != nil {
+			a.closeRunProvider(gen.provider)
+		}
+		return events, err
 	}
+	if autoMode(ctx) && cfg != nil {
+		return start(func(ctx context.Context) AgentEvent {
+			return a.processAutoGeneration(ctx, cfg, gen, sessionID, content, attachmentParts)
+		})
+	}
+	if flow := a.reviewFlow(cfg); flow != nil && !reviewSkipped(ctx) {
+		return start(func(ctx context.Context) AgentEvent {
+			return a.processReviewedGeneration(ctx, cfg, gen, *flow, sessionID, content, attachmentParts)
+		})
+	}
+	return start(func(ctx context.Context) AgentEvent {
+		return a.processGeneration(ctx, gen, sessionID, content, attachmentParts)
+	})
+}
+
+// closeRunProvider closes a provider created for a single run
+func (a *agent) closeRunProvider(p provider.Provider) {
+	if p == a.provider {
+		return
+	}
+	if err := p.Close(); err != nil {
+		logging.Warn("failed to close the run provider", "error", err)
+	}
+}
+
+// start runs a generation for a session in the background, publishing and
+// sending its result on the returned channel once it completes
+func (a *agent) start(ctx context.Context, sessionID string, gen generation, run func(ctx context.Context) AgentEvent) (<-chan AgentEvent, error) {
 	events := make(chan AgentEvent)
 	if a.IsSessionBusy(sessionID) {
 		return nil, ErrSessionBusy
 	}
 
+	// Fail fast while offline unless queued sending is enabled
+	queueUntilOnline := false
+	if connectivity.IsOffline() && !gen.provider.Model().Provider.IsLocal() {
+		if cfg := config.Get(); cfg == nil || !cfg.Offline.SendQueuedOnReconnect {
+			return nil, connectivity.ErrOffline
+		}
+		queueUntilOnline = true
+	}
+
+	ctx, requestID := logging.EnsureRequestID(ctx)
+	logger := logging.FromContext(ctx)
 	genCtx, cancel := context.WithCancel(ctx)
 
 	a.activeRequests.Store(sessionID, cancel)
 	go func() {
-		logging.Debug("Request started", "sessionID", sessionID)
+		logger.Debug("Request started", "sessionID", sessionID)
 		defer logging.RecoverPanic("agent.Run", func() {
 			events <- a.err(fmt.Errorf("panic while running the agent"))
 		})
-		var attachmentParts []message.ContentPart
-		for _, attachment := range attachments {
-			attachmentParts = append(attachmentParts, message.BinaryContent{Path: attachment.FilePath, MIMEType: attachment.MimeType, Data: attachment.Content})
+		if queueUntilOnline {
+			logger.InfoPersist("Offline: message queued and will be sent when connectivity returns")
+			if err := connectivity.WaitOnline(genCtx); err != nil {
+				a.activeRequests.Delete(sessionID)
+				cancel()
+				events <- a.err(ErrRequestCancelled)
+				close(events)
+				return
+			}
+		}
+		turnCtx, turn := tracing.StartTurn(genCtx, sessionID)
+		turn.SetAttribute("agent", string(a.name))
+		turn.SetAttribute("model", string(gen.provider.Model().ID))
+		turn.SetAttribute(logging.RequestIDKey, requestID)
+		if sources := contextSources(ctx); sources != nil {
+			turn.SetAttribute("context.included", contextNames(sources.Included))
+			turn.SetAttribute("context.excluded", contextNames(sources.Excluded))
 		}
-		result := a.processGeneration(genCtx, sessionID, content, attachmentParts)
+		result := run(turnCtx)
+		if result.Error != nil {
+			turn.SetAttribute("error", result.Error.Error())
+		}
+		turn.End()
 		if result.Error != nil && !errors.Is(result.Error, ErrRequestCancelled) && !errors.Is(result.Error, context.Canceled) {
-			logging.ErrorPersist(result.Error.Error())
+			logger.ErrorPersist(result.Error.Error())
 		}
-		logging.Debug("Request completed", "sessionID", sessionID)
+		logger.Debug("Request completed", "sessionID", sessionID)
 		a.activeRequests.Delete(sessionID)
 		cancel()
+		if !a.IsBusy() {
+			a.reloadPrompt()
+		}
 		a.Publish(pubsub.CreatedEvent, result)
 		events <- result
 		close(events)
@@ -229,48 +390,78 @@ func (a *agent) Run(ctx context.Context, sessionID string, content string, attac
 	return events, nil
 }
 
-func (a *agent) processGeneration(ctx context.Context, sessionID, content string, attachmentParts []message.ContentPart) AgentEvent {
+func (a *agent) processGeneration(ctx context.Context, gen generation, sessionID, content string, attachmentParts []message.ContentPart) AgentEvent {
+	_, msgHistory, err := a.prepareGeneration(ctx, sessionID, content, attachmentParts)
+	if err != nil {
+		return a.err(err)
+	}
+	return a.generate(ctx, gen, sessionID, msgHistory)
+}
+
+// prepareGeneration stores the user message of a generation and returns it
+// along with the conversation history to respond to
+func (a *agent) prepareGeneration(ctx context.Context, sessionID, content string, attachmentParts []message.ContentPart) (message.Message, []message.Message, error) {
+	assembly := tracing.FromContext(ctx).Child("prompt assembly")
+	defer assembly.End()
 	// List existing messages; if none, start title generation asynchronously.
 	msgs, err := a.messages.List(ctx, sessionID)
 	if err != nil {
-		return a.err(fmt.Errorf("failed to list messages: %w", err))
+		return message.Message{}, nil, fmt.Errorf("failed to list messages: %w", err)
 	}
 	if len(msgs) == 0 {
 		go func() {
 			defer logging.RecoverPanic("agent.Run", func() {
 				logging.ErrorPersist("panic while generating title")
 			})
-			titleErr := a.generateTitle(context.Background(), sessionID, content)
+			titleErr := a.generateTitle(logging.WithRequestID(context.Background(), logging.RequestIDFromContext(ctx)), sessionID, content)
 			if titleErr != nil {
-				logging.ErrorPersist(fmt.Sprintf("failed to generate title: %v", titleErr))
+				logging.FromContext(ctx).ErrorPersist(fmt.Sprintf("failed to generate title: %v", titleErr))
 			}
 		}()
 	}
 	session, err := a.sessions.Get(ctx, sessionID)
 	if err != nil {
-		return a.err(fmt.Errorf("failed to get session: %w", err))
-	}
-	if session.SummaryMessageID != "" {
-		summaryMsgInex := -1
-		for i, msg := range msgs {
-			if msg.ID == session.SummaryMessageID {
-				summaryMsgInex = i
-				break
-			}
-		}
-		if summaryMsgInex != -1 {
-			msgs = msgs[summaryMsgInex:]
-			msgs[0].Role = message.User
-		}
+		return message.Message{}, nil, fmt.Errorf("failed to get session: %w", err)
 	}
+	msgs = sinceSummary(session, msgs)
+	assembly.End()
 
+	persist := tracing.FromContext(ctx).Child("persistence")
 	userMsg, err := a.createUserMessage(ctx, sessionID, content, attachmentParts)
+	persist.End()
 	if err != nil {
-		return a.err(fmt.Errorf("failed to create user message: %w", err))
+		return message.Message{}, nil, fmt.Errorf("failed to create user message: %w", err)
 	}
+	tracing.FromContext(ctx).AddMessage(userMsg.ID)
+	analytics.RecordMessage(string(a.name))
+	a.updateContext(sessionID, userMsg.ID)
 	// Append the new user message to the conversation history.
-	msgHistory := append(msgs, userMsg)
+	msgs = append(msgs, userMsg)
+	if contextExcluded(ctx, message.ContextFiles) {
+		return userMsg, msgs, nil
+	}
+	return userMsg, a.withContextUpdate(sessionID, msgs), nil
+}
+
+// sinceSummary drops the messages before the summary of a session, sending
+// the summary in their place
+func sinceSummary(session session.Session, msgs []message.Message) []message.Message {
+	if session.SummaryMessageID == "" {
+		return msgs
+	}
+	for i, msg := range msgs {
+		if msg.ID == session.SummaryMessageID {
+			msgs = msgs[i:]
+			msgs[0].Role = message.User
+			break
+		}
+	}
+	return msgs
+}
 
+// generate streams responses to the conversation history, running the tools
+// the model calls until it finishes its turn
+func (a *agent) generate(ctx context.Context, gen generation, sessionID string, msgHistory []message.Message) AgentEvent {
 	for {
 		// Check for cancellation before each iteration
 		select {
@@ -279,7 +470,7 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 		default:
 			// Continue processing
 		}
-		agentMessage, toolResults, err := a.streamAndHandleEvents(ctx, sessionID, msgHistory)
+		agentMessage, toolResults, err := a.streamAndHandleEvents(ctx, gen, sessionID, withScratchpad(sessionID, msgHistory))
 		if err != nil {
 			if errors.Is(err, context.Canceled) {
 				agentMessage.AddFinish(message.FinishReasonCanceled)
@@ -288,7 +479,7 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 			}
 			return a.err(fmt.Errorf("failed to process events: %w", err))
 		}
-		logging.Info("Result", "message", agentMessage.FinishReason(), "toolResults", toolResults)
+		logging.FromContext(ctx).Info("Result", "message", agentMessage.FinishReason(), "toolResults", toolResults)
 		if (agentMessage.FinishReason() == message.FinishReasonToolUse) && toolResults != nil {
 			// We are not done, we need to respond with the tool response
 			msgHistory = append(msgHistory, agentMessage, *toolResults)
@@ -305,32 +496,76 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 func (a *agent) createUserMessage(ctx context.Context, sessionID, content string, attachmentParts []message.ContentPart) (message.Message, error) {
 	parts := []message.ContentPart{message.TextContent{Text: content}}
 	parts = append(parts, attachmentParts...)
+	if cfg := config.Get(); cfg != nil {
+		parts = append(parts, fileReferences(cfg, content)...)
+	}
+	if sources := contextSources(ctx); sources != nil {
+		parts = append(parts, *sources)
+	}
 	return a.messages.Create(ctx, sessionID, message.CreateMessageParams{
 		Role:  message.User,
 		Parts: parts,
 	})
 }
 
-func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msgHistory []message.Message) (message.Message, *message.Message, error) {
-	eventChan := a.provider.StreamResponse(ctx, msgHistory, a.tools)
+func (a *agent) streamAndHandleEvents(ctx context.Context, gen generation, sessionID string, msgHistory []message.Message) (message.Message, *message.Message, error) {
+	turn := tracing.FromContext(ctx)
+	providerStart := time.Now()
+	providerSpan := turn.Child("provider request")
+	providerSpan.SetAttribute("model", string(gen.provider.Model().ID))
+	firstToken := providerSpan.Child("first token")
+	defer providerSpan.End()
+	agentTools := allowedTools(a.toolsFor(gen.provider.Model()), gen.allowedTools)
+	if gen.withoutTools {
+		agentTools = nil
+	}
 
+	// The response is stored in progress before it is requested, so one the
+	// process exits in the middle of is recovered on the next start
+	persist := turn.Child("persistence")
 	assistantMsg, err := a.messages.Create(ctx, sessionID, message.CreateMessageParams{
 		Role:  message.Assistant,
 		Parts: []message.ContentPart{},
-		Model: a.provider.Model().ID,
+		Model: gen.provider.Model().ID,
 	})
+	persist.End()
 	if err != nil {
 		return assistantMsg, nil, fmt.Errorf("failed to create assistant message: %w", err)
 	}
+	turn.AddMessage(assistantMsg.ID)
+	eventChan := gen.provider.StreamResponse(ctx, message.ExpandFileReferences(msgHistory), agentTools)
 
 	// Add the session and message ID into the context if needed by tools.
 	ctx = context.WithValue(ctx, tools.MessageIDContextKey, assistantMsg.ID)
 	ctx = context.WithValue(ctx, tools.SessionIDContextKey, sessionID)
+	ctx = context.WithValue(ctx, tools.AgentNameContextKey, string(a.name))
 
-	// Process each event in the stream.
+	// Process each event in the stream. Storing the streamed content is timed
+	// apart from the provider request it is part of.
+	var streamPersistence time.Duration
 	for event := range eventChan {
-		if processErr := a.processEvent(ctx, sessionID, &assistantMsg, event); processErr != nil {
-			a.finishMessage(ctx, &assistantMsg, message.FinishReasonCanceled)
+		firstToken.End()
+		eventStart := time.Now()
+		processErr := a.processEvent(ctx, gen, sessionID, &assistantMsg, event)
+		streamPersistence += time.Since(eventStart)
+		if processErr != nil {
+			a.recordProviderCall(gen, providerStart, processErr)
+			var timeoutErr *provider.TimeoutError
+			var providerErr *provider.ProviderError
+			if errors.As(processErr, &timeoutErr) {
+				assistantMsg.AddFinishMessage(message.FinishReasonTimeout, timeoutErr.Error())
+				_ = a.messages.Update(ctx, assistantMsg)
+			} else if errors.As(processErr, &providerErr) {
+				assistantMsg.AddFinishError(providerErr.Message, message.FinishError{
+					Category: string(providerErr.Category),
+					Title:    providerErr.Category.Title(),
+					Hint:     providerErr.Hint,
+					Details:  providerErr.Details(),
+				})
+				_ = a.messages.Update(ctx, assistantMsg)
+			} else {
+				a.finishMessage(ctx, &assistantMsg, message.FinishReasonCanceled)
+			}
 			return assistantMsg, nil, processErr
 		}
 		if ctx.Err() != nil {
@@ -338,9 +573,22 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 			return assistantMsg, nil, ctx.Err()
 		}
 	}
+	a.recordProviderCall(gen, providerStart, nil)
+	providerSpan.SetAttribute("persistence", streamPersistence.Round(time.Millisecond).String())
+	providerSpan.End()
+	a.citeSources(ctx, &assistantMsg, msgHistory)
+	a.saveArtifacts(ctx, &assistantMsg)
 
 	toolResults := make([]message.ToolResult, len(assistantMsg.ToolCalls()))
 	toolCalls := assistantMsg.ToolCalls()
+	turnID, step := message.TurnStep(msgHistory)
+	if turnID == "" {
+		turnID = assistantMsg.ID
+	}
+	// The sources quoted by the tool results are numbered after the ones the
+	// conversation already quoted
+	var sources []message.Citation
+	nextSource := message.NextCitationIndex(msgHistory)
 	for i, toolCall := range toolCalls {
 		select {
 		case <-ctx.Done():
@@ -357,7 +605,7 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 		default:
 			// Continue processing
 			var tool tools.BaseTool
-			for _, availableTools := range a.tools {
+			for _, availableTools := range agentTools {
 				if availableTools.Info().Name == toolCall.Name {
 					tool = availableTools
 				}
@@ -372,11 +620,34 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 				}
 				continue
 			}
-			toolResult, toolErr := tool.Run(ctx, tools.ToolCall{
+			toolSpan := turn.Child("tool")
+			toolSpan.SetAttribute("name", toolCall.Name)
+			toolStart := time.Now()
+			call := tools.ToolCall{
 				ID:    toolCall.ID,
 				Name:  toolCall.Name,
 				Input: toolCall.Input,
+			}
+			// The operation ID lets the file tools apply a call dispatched
+			// again, or made again when the turn is retried, only once
+			toolCtx := tools.WithOperationID(ctx, tools.OperationID(turnID, step, i, call))
+			toolResult, toolErr := tool.Run(toolCtx, call)
+			toolSpan.End()
+			analytics.Record(analytics.Event{
+				Kind:    analytics.KindTool,
+				Name:    toolCall.Name,
+				Latency: time.Since(toolStart),
+				Failed:  toolErr != nil || toolResult.IsError,
 			})
+			if err := audit.Record(audit.Entry{
+				Actor:     string(a.name),
+				SessionID: sessionID,
+				Tool:      toolCall.Name,
+				Input:     toolCall.Input,
+				Failed:    toolErr != nil || toolResult.IsError,
+			}); err != nil {
+				logging.Warn("failed to record the tool call in the audit log", "error", err, logging.ComponentKey, logging.ComponentDataLoss)
+			}
 			if toolErr != nil {
 				if errors.Is(toolErr, permission.ErrorPermissionDenied) {
 					toolResults[i] = message.ToolResult{
@@ -401,6 +672,18 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 				Metadata:   toolResult.Metadata,
 				IsError:    toolResult.IsError,
 			}
+			for _, source := range toolResult.Sources {
+				sources = append(sources, message.Citation{
+					Index:      nextSource,
+					ToolCallID: toolCall.ID,
+					Source:     source.Location,
+					StartLine:  source.StartLine,
+					EndLine:    source.EndLine,
+					Score:      source.Score,
+					Quote:      source.Quote,
+				})
+				nextSource++
+			}
 		}
 	}
 out:
@@ -411,23 +694,85 @@ out:
 	for _, tr := range toolResults {
 		parts = append(parts, tr)
 	}
+	for _, source := range sources {
+		parts = append(parts, source)
+	}
+	persist = turn.Child("persistence")
 	msg, err := a.messages.Create(context.Background(), assistantMsg.SessionID, message.CreateMessageParams{
 		Role:  message.Tool,
 		Parts: parts,
 	})
 	if err != nil {
+		persist.End()
 		return assistantMsg, nil, fmt.Errorf("failed to create cancelled tool message: %w", err)
 	}
+	// The tool calls have their results, the response is finished
+	assistantMsg.Status = message.StatusFinished
+	err = a.messages.Update(context.Background(), assistantMsg)
+	persist.End()
+	if err != nil {
+		return assistantMsg, nil, fmt.Errorf("failed to finish assistant message: %w", err)
+	}
+	turn.AddMessage(msg.ID)
 
 	return assistantMsg, &msg, err
 }
 
+// saveArtifacts saves the code blocks of the response as artifacts of the
+// session, and records them on the response
+func (a *agent) saveArtifacts(ctx context.Context, msg *message.Message) {
+	saved, err := artifact.Save(config.Get().Artifacts, msg.SessionID, msg.Content().Text)
+	if err != nil {
+		logging.FromContext(ctx).Warn("Failed to save artifacts", "error", err, logging.ComponentKey, logging.ComponentDataLoss)
+	}
+	if len(saved) == 0 {
+		return
+	}
+	for _, part := range saved {
+		msg.Parts = append(msg.Parts, part)
+	}
+	if err := a.messages.Update(ctx, *msg); err != nil {
+		logging.FromContext(ctx).Warn("Failed to record artifacts", "error", err, logging.ComponentKey, logging.ComponentDataLoss)
+	}
+}
+
+// citeSources records the sources of the conversation the response
+// references
+func (a *agent) citeSources(ctx context.Context, msg *message.Message, msgHistory []message.Message) {
+	cited := message.ResolveCitations(msg.Content().Text, message.Sources(msgHistory))
+	if len(cited) == 0 {
+		return
+	}
+	msg.SetCitations(cited)
+	if err := a.messages.Update(ctx, *msg); err != nil {
+		logging.FromContext(ctx).Warn("Failed to record citations", "error", err, logging.ComponentKey, logging.ComponentDataLoss)
+	}
+}
+
+// recordProviderCall records a provider call that failed with err, nil when
+// it succeeded, along with the category of the error
+func (a *agent) recordProviderCall(gen generation, start time.Time, err error) {
+	analytics.Record(analytics.Event{
+		Kind:    analytics.KindProvider,
+		Name:    string(gen.provider.Model().Provider),
+		Latency: time.Since(start),
+		Failed:  err != nil && !errors.Is(err, context.Canceled),
+	})
+	var providerErr *provider.ProviderError
+	if errors.As(err, &providerErr) {
+		analytics.Record(analytics.Event{
+			Kind: analytics.KindProviderError,
+			Name: string(providerErr.Category),
+		})
+	}
+}
+
 func (a *agent) finishMessage(ctx context.Context, msg *message.Message, finishReson message.FinishReason) {
 	msg.AddFinish(finishReson)
 	_ = a.messages.Update(ctx, *msg)
 }
 
-func (a *agent) processEvent(ctx context.Context, sessionID string, assistantMsg *message.Message, event provider.ProviderEvent) error {
+func (a *agent) processEvent(ctx context.Context, gen generation, sessionID string, assistantMsg *message.Message, event provider.ProviderEvent) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
@@ -459,10 +804,10 @@ func (a *agent) processEvent(ctx context.Context, sessionID string, assistantMsg
 		return a.messages.Update(ctx, *assistantMsg)
 	case provider.EventError:
 		if errors.Is(event.Error, context.Canceled) {
-			logging.InfoPersist(fmt.Sprintf("Event processing canceled for session: %s", sessionID))
+			logging.FromContext(ctx).InfoPersist(fmt.Sprintf("Event processing canceled for session: %s", sessionID))
 			return context.Canceled
 		}
-		logging.ErrorPersist(event.Error.Error())
+		logging.FromContext(ctx).ErrorPersist(event.Error.Error())
 		return event.Error
 	case provider.EventComplete:
 		assistantMsg.SetToolCalls(event.Response.ToolCalls)
@@ -470,26 +815,63 @@ func (a *agent) processEvent(ctx context.Context, sessionID string, assistantMsg
 		if err := a.messages.Update(ctx, *assistantMsg); err != nil {
 			return fmt.Errorf("failed to update message: %w", err)
 		}
-		return a.TrackUsage(ctx, sessionID, a.provider.Model(), event.Response.Usage)
+		warnTruncated(event.Response.FinishReason)
+		if gen.outputTokens != nil {
+			*gen.outputTokens += event.Response.Usage.OutputTokens
+		}
+		if gen.usage != nil {
+			gen.usage.InputTokens += event.Response.Usage.InputTokens
+			gen.usage.OutputTokens += event.Response.Usage.OutputTokens
+			gen.usage.CacheCreationTokens += event.Response.Usage.CacheCreationTokens
+			gen.usage.CacheReadTokens += event.Response.Usage.CacheReadTokens
+		}
+		return a.trackUsage(ctx, sessionID, gen.provider.Model(), event.Response.Usage, gen.regenerated, event.Response.FinishReason.Truncated())
 	}
 
 	return nil
 }
 
 func (a *agent) TrackUsage(ctx context.Context, sessionID string, model models.Model, usage provider.TokenUsage) error {
+	return a.trackUsage(ctx, sessionID, model, usage, false, false)
+}
+
+// warnTruncated tells the user when a response was cut off
+func warnTruncated(reason message.FinishReason) {
+	switch reason {
+	case message.FinishReasonMaxTokens:
+		logging.WarnPersist("Response was truncated due to token limit — consider increasing maxTokens")
+	case message.FinishReasonContentFilter:
+		logging.WarnPersist("Response was stopped by the provider's content filter")
+	}
+}
+
+// trackUsage adds the usage of a response to its session. The usage of
+// regenerated and truncated responses is also added to their own totals, so
+// retries and cut off responses can be told apart from the cost of the
+// conversation itself.
+func (a *agent) trackUsage(ctx context.Context, sessionID string, model models.Model, usage provider.TokenUsage, regenerated, truncated bool) error {
 	sess, err := a.sessions.Get(ctx, sessionID)
 	if err != nil {
 		return fmt.Errorf("failed to get session: %w", err)
 	}
 
-	cost := model.CostPer1MInCached/1e6*float64(usage.CacheCreationTokens) +
-		model.CostPer1MOutCached/1e6*float64(usage.CacheReadTokens) +
-		model.CostPer1MIn/1e6*float64(usage.InputTokens) +
-		model.CostPer1MOut/1e6*float64(usage.OutputTokens)
-
+	cost := usage.Cost(model)
 	sess.Cost += cost
-	sess.CompletionTokens = usage.OutputTokens + usage.CacheReadTokens
-	sess.PromptTokens = usage.InputTokens + usage.CacheCreationTokens
+	sess.CompletionTokens = usage.OutputTokens
+	sess.PromptTokens = usage.PromptTokens()
+	sess.CacheReadTokens += usage.CacheReadTokens
+	sess.CacheWriteTokens += usage.CacheCreationTokens
+	if regenerated {
+		sess.RegeneratedTokens += usage.PromptTokens() + usage.OutputTokens
+		sess.RegeneratedCost += cost
+	}
+	if truncated {
+		sess.TruncatedTokens += usage.PromptTokens() + usage.OutputTokens
+		sess.TruncatedCost += cost
+	}
+	if sess.Agent == "" {
+		sess.Agent = string(a.name)
+	}
 
 	_, err = a.sessions.Save(ctx, sess)
 	if err != nil {
@@ -507,12 +889,16 @@ func (a *agent) Update(agentName config.AgentName, modelID models.ModelID) (mode
 		return models.Model{}, fmt.Errorf("failed to update config: %w", err)
 	}
 
-	provider, err := createAgentProvider(agentName)
+	provider, err := createAgentProvider(config.Get(), agentName)
 	if err != nil {
 		return models.Model{}, fmt.Errorf("failed to create provider for model %s: %w", modelID, err)
 	}
 
+	if err := a.provider.Close(); err != nil {
+		logging.Warn("failed to close the previous provider", "error", err)
+	}
 	a.provider = provider
+	a.warnToolsUnavailable()
 
 	return a.provider.Model(), nil
 }
@@ -569,9 +955,11 @@ func (a *agent) Summarize(ctx context.Context, sessionID string) error {
 			Progress: "Analyzing conversation...",
 		}
 		a.Publish(pubsub.CreatedEvent, event)
+		// The scratchpad outlasts the summary, so its notes are not summarized
+		msgs = withoutScratchpadCalls(msgs)
 
 		// Add a system message to guide the summarization
-		summarizePrompt := "Provide a detailed but concise summary of our conversation above. Focus on information that would be helpful for continuing the conversation, including what we did, what we're doing, which files we're working on, and what we're going to do next."
+		summarizePrompt := "Provide a detailed but concise summary of our conversation above. Focus on information that would be helpful for continuing the conversation, including what we did, what we're doing, which files we're working on, and what we're going to do next. Keep the [n] references to the sources you rely on."
 
 		// Create a new message with the summarize prompt
 		promptMsg := message.Message{
@@ -632,16 +1020,19 @@ func (a *agent) Summarize(ctx context.Context, sessionID string) error {
 			a.Publish(pubsub.CreatedEvent, event)
 			return
 		}
-		// Create a message in the new session with the summary
+		// Create a message in the new session with the summary, which keeps
+		// the sources of the conversation for the responses after it to cite
+		parts := []message.ContentPart{message.TextContent{Text: summary}}
+		for _, source := range message.Sources(msgs) {
+			parts = append(parts, source)
+		}
+		parts = append(parts, message.Finish{
+			Reason: message.FinishReasonEndTurn,
+			Time:   time.Now().Unix(),
+		})
 		msg, err := a.messages.Create(summarizeCtx, oldSession.ID, message.CreateMessageParams{
-			Role: message.Assistant,
-			Parts: []message.ContentPart{
-				message.TextContent{Text: summary},
-				message.Finish{
-					Reason: message.FinishReasonEndTurn,
-					Time:   time.Now().Unix(),
-				},
-			},
+			Role:  message.Assistant,
+			Parts: parts,
 			Model: a.summarizeProvider.Model().ID,
 		})
 		if err != nil {
@@ -659,11 +1050,9 @@ func (a *agent) Summarize(ctx context.Context, sessionID string) error {
 		oldSession.PromptTokens = 0
 		model := a.summarizeProvider.Model()
 		usage := response.Usage
-		cost := model.CostPer1MInCached/1e6*float64(usage.CacheCreationTokens) +
-			model.CostPer1MOutCached/1e6*float64(usage.CacheReadTokens) +
-			model.CostPer1MIn/1e6*float64(usage.InputTokens) +
-			model.CostPer1MOut/1e6*float64(usage.OutputTokens)
-		oldSession.Cost += cost
+		oldSession.Cost += usage.Cost(model)
+		oldSession.CacheReadTokens += usage.CacheReadTokens
+		oldSession.CacheWriteTokens += usage.CacheCreationTokens
 		_, err = a.sessions.Save(summarizeCtx, oldSession)
 		if err != nil {
 			event = AgentEvent{
@@ -687,56 +1076,69 @@ func (a *agent) Summarize(ctx context.Context, sessionID string) error {
 	return nil
 }
 
-func createAgentProvider(agentName config.AgentName) (provider.Provider, error) {
-	cfg := config.Get()
+// createAgentProvider creates the provider of an agent as configured in cfg
+func createAgentProvider(cfg *config.Config, agentName config.AgentName) (provider.Provider, error) {
+	agentConfig, ok := cfg.Agents[agentName]
+	if !ok {
+		return nil, fmt.Errorf("agent %s not found", agentName)
+	}
+	return createAgentProviderForModel(cfg, agentName, agentConfig.Model)
+}
+
+// createAgentProviderForModel creates the provider of an agent for a model
+// other than its configured one, keeping the rest of the agent configuration
+// in cfg. opts override the options of the agent configuration.
+func createAgentProviderForModel(cfg *config.Config, agentName config.AgentName, modelID models.ModelID, opts ...provider.ProviderClientOption) (provider.Provider, error) {
 	agentConfig, ok := cfg.Agents[agentName]
 	if !ok {
 		return nil, fmt.Errorf("agent %s not found", agentName)
 	}
-	model, ok := models.SupportedModels[agentConfig.Model]
+	model, ok := models.SupportedModels[modelID]
 	if !ok {
-		return nil, fmt.Errorf("model %s not supported", agentConfig.Model)
+		return nil, fmt.Errorf("model %s not supported", modelID)
 	}
 
 	providerCfg, ok := cfg.Providers[model.Provider]
 	if !ok {
 		return nil, fmt.Errorf("provider %s not supported", model.Provider)
 	}
-	if providerCfg.Disabled {
-		return nil, fmt.Errorf("provider %s is not enabled", model.Provider)
-	}
-	maxTokens := model.DefaultMaxTokens
-	if agentConfig.MaxTokens > 0 {
-		maxTokens = agentConfig.MaxTokens
-	}
-	opts := []provider.ProviderClientOption{
-		provider.WithAPIKey(providerCfg.APIKey),
-		provider.WithModel(model),
+	agentOpts := []provider.ProviderClientOption{
 		provider.WithSystemMessage(prompt.GetAgentPrompt(agentName, model.Provider)),
-		provider.WithMaxTokens(maxTokens),
+		provider.WithMaxTokens(agentMaxTokens(agentConfig, model)),
+	}
+	if agentConfig.Generation != nil {
+		agentOpts = append(agentOpts, provider.WithGenerationParams(*agentConfig.Generation))
 	}
 	if model.Provider == models.ProviderOpenAI || model.Provider == models.ProviderLocal && model.CanReason {
-		opts = append(
-			opts,
+		agentOpts = append(
+			agentOpts,
 			provider.WithOpenAIOptions(
 				provider.WithReasoningEffort(agentConfig.ReasoningEffort),
 			),
 		)
-	} else if model.Provider == models.ProviderAnthropic && model.CanReason && agentName == config.AgentCaronex {
-		opts = append(
-			opts,
-			provider.WithAnthropicOptions(
-				provider.WithAnthropicShouldThinkFn(provider.DefaultShouldThinkFn),
-			),
-		)
+	} else if model.Provider == models.ProviderAnthropic {
+		var anthropicOpts []provider.AnthropicOption
+		if model.CanReason && agentName == config.AgentCaronex {
+			anthropicOpts = append(anthropicOpts, provider.WithAnthropicShouldThinkFn(provider.DefaultShouldThinkFn))
+		}
+		if providerCfg.DisableCache {
+			anthropicOpts = append(anthropicOpts, provider.WithAnthropicDisableCache())
+		}
+		agentOpts = append(agentOpts, provider.WithAnthropicOptions(anthropicOpts...))
 	}
-	agentProvider, err := provider.NewProvider(
-		model.Provider,
-		opts...,
-	)
+	agentProvider, err := provider.DefaultProviderFactory.NewProvider(modelID, providerCfg, append(agentOpts, opts...)...)
 	if err != nil {
 		return nil, fmt.Errorf("could not create provider: %v", err)
 	}
 
 	return agentProvider, nil
 }
+
+// agentMaxTokens returns the maximum number of tokens of the responses of an
+// agent using model
+func agentMaxTokens(agentConfig config.Agent, model models.Model) int64 {
+	if agentConfig.MaxTokens > 0 {
+		return agentConfig.MaxTokens
+	}
+	return model.DefaultMaxTokens
+}
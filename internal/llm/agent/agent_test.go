@@ -0,0 +1,48 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/llm/provider"
+	"github.com/caronex/intelligence-interface/internal/message"
+)
+
+func TestTruncatedResponseUsage(t *testing.T) {
+	f := newRegenerateFixture(t,
+		provider.FakeResponse{
+			Content:      "cut",
+			FinishReason: message.FinishReasonMaxTokens,
+			Usage:        provider.TokenUsage{InputTokens: 100, OutputTokens: 10},
+		},
+		provider.FakeResponse{
+			Content: "done",
+			Usage:   provider.TokenUsage{InputTokens: 200, OutputTokens: 20},
+		},
+	)
+	f.add(t, message.User, message.TextContent{Text: "hi"})
+	f.add(t, message.Assistant, message.TextContent{Text: "hello"})
+
+	result := wait(f.agent.Run(context.Background(), f.session.ID, "write a lot"))
+	if result.Error != nil {
+		t.Fatalf("Run() error = %v", result.Error)
+	}
+	if reason := result.Message.FinishReason(); reason != message.FinishReasonMaxTokens {
+		t.Errorf("finish reason = %q, want %q", reason, message.FinishReasonMaxTokens)
+	}
+	if result := wait(f.agent.Run(context.Background(), f.session.ID, "continue")); result.Error != nil {
+		t.Fatalf("Run() error = %v", result.Error)
+	}
+
+	sess, err := f.sessions.Get(context.Background(), f.session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sess.TruncatedTokens != 110 {
+		t.Errorf("truncated tokens = %d, want only the truncated response's 110", sess.TruncatedTokens)
+	}
+	if sess.Agent != string(config.AgentCaronex) {
+		t.Errorf("session agent = %q, want the agent which answered", sess.Agent)
+	}
+}
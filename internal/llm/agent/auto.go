@@ -0,0 +1,160 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/message"
+	"github.com/caronex/intelligence-interface/internal/pubsub"
+	"github.com/caronex/intelligence-interface/internal/snapshot"
+	"github.com/caronex/intelligence-interface/internal/tools/coordination"
+)
+
+type autoModeContextKey struct{}
+
+// WithAutoMode returns a context in which the agent works on the message sent
+// in auto mode: it is prompted to continue turn after turn, within the auto
+// mode limits of the agent, instead of waiting for the user
+func WithAutoMode(ctx context.Context) context.Context {
+	return context.WithValue(ctx, autoModeContextKey{}, true)
+}
+
+func autoMode(ctx context.Context) bool {
+	auto, _ := ctx.Value(autoModeContextKey{}).(bool)
+	return auto
+}
+
+// processAutoGeneration has the agent work on a task in auto mode, then
+// records the run as a delegation in the coordination event log. The run is
+// a coordination operation, which an abort in the session cancels.
+func (a *agent) processAutoGeneration(ctx context.Context, cfg *config.Config, gen generation, sessionID, content string, attachmentParts []message.ContentPart) AgentEvent {
+	logger := logging.FromContext(ctx)
+	limits := cfg.Agents[a.name].AutoModeLimits()
+	opID := coordination.NewOperationID(coordination.OperationDelegation)
+	ctx, err := coordination.StartOperation(ctx, coordination.Operation{
+		ID:          opID,
+		Kind:        coordination.OperationDelegation,
+		SessionID:   sessionID,
+		Agent:       string(a.name),
+		Description: content,
+	})
+	if err != nil {
+		return a.err(err)
+	}
+	a.publishAuto(sessionID, coordination.AutoOutcome{}.Progress(limits), false)
+	before := snapshot.Take()
+
+	var last AgentEvent
+	outcome, err := coordination.RunAuto(ctx, limits, content, coordination.AutoRun{
+		Turn: func(ctx context.Context, prompt string) (coordination.AutoTurnResult, error) {
+			// The attachments are sent with the task only
+			_, msgHistory, err := a.prepareGeneration(ctx, sessionID, prompt, attachmentParts)
+			attachmentParts = nil
+			if err != nil {
+				return coordination.AutoTurnResult{}, err
+			}
+			var tokens int64
+			turnGen := gen
+			turnGen.outputTokens = &tokens
+			last = a.generate(ctx, turnGen, sessionID, msgHistory)
+			if last.Error != nil {
+				return coordination.AutoTurnResult{Tokens: tokens}, last.Error
+			}
+			return coordination.AutoTurnResult{Text: last.Message.Content().String(), Tokens: tokens}, nil
+		},
+		Verify: autoVerifier(cfg.WorkingDir, limits.TestCommand),
+		Progress: func(outcome coordination.AutoOutcome) {
+			a.publishAuto(sessionID, outcome.Progress(limits), false)
+		},
+	})
+
+	var partial string
+	if last.Message.ID != "" {
+		partial = last.Message.Content().String()
+	}
+	coordination.FinishOperation(opID, partial, failureErr(err))
+	op, _ := coordination.GetOperation(opID)
+	changes := a.ReportChanges(ctx, sessionID, opID, before)
+
+	status, summary, failure := string(outcome.Stop), outcome.Summary(), outcome.Failure()
+	switch {
+	case err != nil && isCanceled(err):
+		status = "aborted"
+		summary = fmt.Sprintf("Auto mode aborted after %d steps", outcome.Steps)
+		failure = coordination.ClassifyFailure(failureErr(err))
+	case err != nil:
+		status = "failed"
+		summary = fmt.Sprintf("Auto mode failed after %d steps: %v", outcome.Steps, err)
+		failure = coordination.ClassifyFailure(failureErr(err))
+	}
+	if failure != nil {
+		failure.Agent = string(a.name)
+	}
+	recordErr := coordination.RecordDelegation(cfg.Data.Directory, coordination.DelegationEvent{
+		SessionID:   sessionID,
+		Agent:       string(a.name),
+		Mode:        "auto",
+		Task:        content,
+		Status:      status,
+		Steps:       outcome.Steps,
+		Tokens:      outcome.Tokens,
+		Duration:    outcome.Elapsed.Round(time.Millisecond).String(),
+		Summary:     summary,
+		CancelledBy: op.CancelledBy,
+		Changes:     changes,
+		Failure:     failure,
+	})
+	if recordErr != nil {
+		logger.Warn("failed to record the auto mode run", "error", recordErr)
+	}
+	a.publishAuto(sessionID, summary, true)
+
+	if err != nil {
+		return a.reviewErr(err)
+	}
+	if outcome.Stop.BudgetExhausted() {
+		logger.WarnPersist(summary)
+	} else {
+		logger.InfoPersist(summary)
+	}
+	return AgentEvent{
+		Type:    AgentEventTypeResponse,
+		Message: last.Message,
+		Done:    true,
+	}
+}
+
+// publishAuto tells the subscribers how the auto mode run of a session is
+// going, or how it ended when done
+func (a *agent) publishAuto(sessionID, progress string, done bool) {
+	a.Publish(pubsub.UpdatedEvent, AgentEvent{
+		Type:      AgentEventTypeAuto,
+		SessionID: sessionID,
+		Progress:  progress,
+		Done:      done,
+	})
+}
+
+// autoVerifier returns the check running command in workingDir after every
+// turn of an auto mode run, which passes when the command succeeds. It is nil
+// without a command.
+func autoVerifier(workingDir, command string) func(ctx context.Context) (bool, error) {
+	if command == "" {
+		return nil
+	}
+	return func(ctx context.Context) (bool, error) {
+		cmd := exec.CommandContext(ctx, "sh", "-c", command)
+		cmd.Dir = workingDir
+		err := cmd.Run()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+}
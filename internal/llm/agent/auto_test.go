@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/llm/provider"
+	"github.com/caronex/intelligence-interface/internal/message"
+	"github.com/caronex/intelligence-interface/internal/tools/coordination"
+)
+
+func TestRunAutoMode(t *testing.T) {
+	f := newRegenerateFixture(t,
+		provider.FakeResponse{Content: "created the file\n" + coordination.AutoContinueMarker},
+		provider.FakeResponse{Content: "all done"},
+	)
+	f.add(t, message.User, message.TextContent{Text: "hello"})
+	f.add(t, message.Assistant, message.TextContent{Text: "hi"})
+
+	result := wait(f.agent.Run(WithAutoMode(context.Background()), f.session.ID, "write a file"))
+	if result.Error != nil {
+		t.Fatalf("Run() error = %v", result.Error)
+	}
+	if result.Message.Content().String() != "all done" {
+		t.Errorf("response = %q, want the last turn", result.Message.Content().String())
+	}
+	if got := len(f.fake.Requests()); got != 2 {
+		t.Fatalf("made %d requests, want 2", got)
+	}
+	if got := len(f.list(t, f.session.ID)); got != 6 {
+		t.Errorf("session has %d messages, want 6", got)
+	}
+
+	cfg := config.Get()
+	log, err := os.ReadFile(filepath.Join(cfg.Data.Directory, coordination.EventLogFilename))
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !strings.Contains(string(log), `"mode":"auto"`) || !strings.Contains(string(log), `"status":"done"`) {
+		t.Errorf("event log = %s, want a finished auto delegation", log)
+	}
+}
+
+func TestRunAutoModeStepBudget(t *testing.T) {
+	f := newRegenerateFixture(t,
+		provider.FakeResponse{Content: "step one\n" + coordination.AutoContinueMarker},
+		provider.FakeResponse{Content: "unused"},
+	)
+	err := config.Update(func(cfg *config.Config) error {
+		agentCfg := cfg.Agents[config.AgentCaronex]
+		agentCfg.AutoMode = &config.AutoMode{MaxSteps: 1}
+		cfg.Agents[config.AgentCaronex] = agentCfg
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("config.Update() error = %v", err)
+	}
+	f.add(t, message.User, message.TextContent{Text: "hello"})
+	f.add(t, message.Assistant, message.TextContent{Text: "hi"})
+
+	result := wait(f.agent.Run(WithAutoMode(context.Background()), f.session.ID, "write a file"))
+	if result.Error != nil {
+		t.Fatalf("Run() error = %v", result.Error)
+	}
+	if got := len(f.fake.Requests()); got != 1 {
+		t.Errorf("made %d requests, want 1", got)
+	}
+}
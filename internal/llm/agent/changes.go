@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/llm/prompt"
+	"github.com/caronex/intelligence-interface/internal/llm/provider"
+	"github.com/caronex/intelligence-interface/internal/llm/tools"
+	"github.com/caronex/intelligence-interface/internal/message"
+	"github.com/caronex/intelligence-interface/internal/snapshot"
+)
+
+const (
+	// changeSummaryInputTokens bounds the diff sent to the summarizer
+	changeSummaryInputTokens = 8000
+	// changeSummaryMaxTokens bounds the summary of the changes
+	changeSummaryMaxTokens = 400
+	// changeSummaryTimeout bounds the wait for the summary, which is written
+	// once the run is over
+	changeSummaryTimeout = time.Minute
+)
+
+// ReportChanges compares the sandbox with before, the snapshot taken when a
+// run started, and posts what the run changed to the session: the files with
+// their diffs, the patch of the changes being saved in the session directory,
+// and a summary of the summarizer. It returns nil when nothing changed. Runs
+// working at the same time get the changes of each other.
+func (a *agent) ReportChanges(ctx context.Context, sessionID, runID string, before *snapshot.Snapshot) *message.WorkspaceChanges {
+	logger := logging.FromContext(ctx)
+	// The changes of an aborted run are reported too
+	ctx = context.WithoutCancel(ctx)
+
+	changes, patch := snapshot.Compare(before, snapshot.Take())
+	if len(changes.Files) == 0 {
+		return nil
+	}
+	path, err := snapshot.SavePatch(sessionID, runID, patch)
+	if err != nil {
+		logger.Warn("failed to save the patch of the run", "error", err)
+	}
+	changes.Patch = path
+	changes.Summary, err = a.summarizeChanges(ctx, changes, patch)
+	if err != nil {
+		logger.Warn("failed to summarize the changes of the run", "error", err)
+	}
+
+	text := "Workspace changes: " + changes.Stat()
+	if changes.Summary != "" {
+		text += "\n\n" + changes.Summary
+	}
+	_, err = a.messages.Create(ctx, sessionID, message.CreateMessageParams{
+		Role: message.Assistant,
+		Parts: []message.ContentPart{
+			message.TextContent{Text: text},
+			changes,
+			message.Finish{Reason: message.FinishReasonEndTurn, Time: time.Now().Unix()},
+		},
+	})
+	if err != nil {
+		logger.Warn("failed to post the changes of the run", "error", err)
+	}
+	return &changes
+}
+
+// summarizeChanges has the summarizer describe the changes from their patch,
+// cut to the input token budget. The summary is empty without a summarizer.
+func (a *agent) summarizeChanges(ctx context.Context, changes message.WorkspaceChanges, patch string) (string, error) {
+	if a.summarizeProvider == nil {
+		return "", nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, changeSummaryTimeout)
+	defer cancel()
+
+	summarizer, err := createAgentProviderForModel(config.Get(), config.AgentCaronex, a.summarizeProvider.Model().ID,
+		provider.WithSystemMessage(prompt.ChangeSummaryPrompt(a.summarizeProvider.Model().Provider)),
+		provider.WithMaxTokens(changeSummaryMaxTokens))
+	if err != nil {
+		return "", err
+	}
+
+	files := make([]string, 0, len(changes.Files))
+	for _, file := range changes.Files {
+		files = append(files, fmt.Sprintf("%s %s (+%d -%d)", file.Change, file.Path, file.Additions, file.Removals))
+	}
+	if limit := changeSummaryInputTokens * 4; len(patch) > limit {
+		patch = patch[:limit] + "\n[diff cut]"
+	}
+	request := fmt.Sprintf("Files:\n%s\n\nDiff:\n%s", strings.Join(files, "\n"), patch)
+	response, err := summarizer.SendMessages(ctx, []message.Message{{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: request}},
+	}}, make([]tools.BaseTool, 0))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(response.Content), nil
+}
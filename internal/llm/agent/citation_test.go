@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/caronex/intelligence-interface/internal/llm/provider"
+	"github.com/caronex/intelligence-interface/internal/llm/tools"
+	"github.com/caronex/intelligence-interface/internal/message"
+)
+
+// sourceTool is a retrieval tool quoting a chunk of a document
+type sourceTool struct{}
+
+func (sourceTool) Info() tools.ToolInfo {
+	return tools.ToolInfo{Name: "kb_search", Description: "search the knowledge base"}
+}
+
+func (sourceTool) Run(ctx context.Context, call tools.ToolCall) (tools.ToolResponse, error) {
+	return tools.WithResponseSources(tools.NewTextResponse("Go compiles fast."), tools.Source{
+		Location:  "docs/go.md",
+		StartLine: 3,
+		EndLine:   5,
+		Score:     0.9,
+		Quote:     "Go compiles fast.",
+	}), nil
+}
+
+func TestRunCitesSources(t *testing.T) {
+	f := newRegenerateFixture(t,
+		provider.FakeResponse{ToolCalls: []message.ToolCall{{ID: "call-1", Name: "kb_search", Input: "{}"}}},
+		provider.FakeResponse{Content: "Go compiles fast [2]."},
+	)
+	f.agent.(*agent).tools = []tools.BaseTool{sourceTool{}}
+	// A source quoted earlier in the session
+	f.add(t, message.User, message.TextContent{Text: "hello"})
+	f.add(t, message.Tool,
+		message.ToolResult{ToolCallID: "call-0", Content: "old"},
+		message.Citation{Index: 1, ToolCallID: "call-0", Source: "docs/old.md", Quote: "old"},
+	)
+
+	result := wait(f.agent.Run(context.Background(), f.session.ID, "is Go fast?"))
+	if result.Error != nil {
+		t.Fatalf("Run() error = %v", result.Error)
+	}
+
+	want := message.Citation{Index: 2, ToolCallID: "call-1", Source: "docs/go.md", StartLine: 3, EndLine: 5, Score: 0.9, Quote: "Go compiles fast."}
+	if got := result.Message.Citations(); !reflect.DeepEqual(got, []message.Citation{want}) {
+		t.Errorf("response citations = %+v, want %+v", got, want)
+	}
+	msgs := f.list(t, f.session.ID)
+	toolMsg := msgs[len(msgs)-2]
+	if got := toolMsg.Citations(); toolMsg.Role != message.Tool || !reflect.DeepEqual(got, []message.Citation{want}) {
+		t.Errorf("tool message citations = %+v, want the quoted source numbered after the earlier one", got)
+	}
+
+	sessions, err := f.messages.CitingSessions(context.Background(), "docs/go.md")
+	if err != nil {
+		t.Fatalf("CitingSessions() error = %v", err)
+	}
+	if !reflect.DeepEqual(sessions, []string{f.session.ID}) {
+		t.Errorf("CitingSessions() = %v, want the session", sessions)
+	}
+	// Quoted but never cited
+	if sessions, _ := f.messages.CitingSessions(context.Background(), "docs/old.md"); len(sessions) != 0 {
+		t.Errorf("CitingSessions() of an uncited source = %v, want none", sessions)
+	}
+}
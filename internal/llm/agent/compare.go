@@ -0,0 +1,222 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/llm/provider"
+	"github.com/caronex/intelligence-interface/internal/message"
+)
+
+var (
+	ErrNotComparison       = errors.New("the message is not a model comparison")
+	ErrComparisonContinued = errors.New("the session continued after the comparison, the kept response cannot change")
+)
+
+type comparisonContextKey struct{}
+
+// comparedModels are the models the response to a message is compared
+// between, the first one being the agent model when empty
+type comparedModels [2]models.ModelID
+
+// WithComparison returns a context in which the response to the message sent
+// is generated by two models, modelA and modelB, side by side. An empty modelA
+// stands for the agent model. The responses are generated without tools, each
+// in a hidden branch of the session, and the first one is kept until another
+// is chosen with ChooseComparison.
+func WithComparison(ctx context.Context, modelA, modelB models.ModelID) context.Context {
+	return context.WithValue(ctx, comparisonContextKey{}, comparedModels{modelA, modelB})
+}
+
+func comparisonModels(ctx context.Context) (comparedModels, bool) {
+	compared, ok := ctx.Value(comparisonContextKey{}).(comparedModels)
+	return compared, ok
+}
+
+// comparisonProviders returns the providers generating the compared
+// responses, reusing the agent provider for the agent model unless the
+// message leaves out sources of context
+func (a *agent) comparisonProviders(cfg *config.Config, compared comparedModels, sources *message.ContextSources) ([]provider.Provider, error) {
+	if compared[1] == "" {
+		return nil, errors.New("the model to compare with is required")
+	}
+	providers := make([]provider.Provider, 0, len(compared))
+	for _, modelID := range compared {
+		if modelID == "" {
+			modelID = a.provider.Model().ID
+		}
+		opts := withoutContext(a.name, sources, models.SupportedModels[modelID].Provider)
+		if modelID == a.provider.Model().ID && len(opts) == 0 {
+			providers = append(providers, a.provider)
+			continue
+		}
+		p, err := createAgentProviderForModel(cfg, a.name, modelID, opts...)
+		if err != nil {
+			a.closeComparisonProviders(providers)
+			return nil, fmt.Errorf("failed to create provider for model %s: %w", modelID, err)
+		}
+		providers = append(providers, p)
+	}
+	return providers, nil
+}
+
+func (a *agent) closeComparisonProviders(providers []provider.Provider) {
+	for _, p := range providers {
+		if p == a.provider {
+			continue
+		}
+		if err := p.Close(); err != nil {
+			logging.Warn("failed to close the comparison provider", "error", err)
+		}
+	}
+}
+
+// processComparedGeneration generates the responses of every provider to a
+// message at the same time, each in its own branch of the session, then posts
+// them to the session as a comparison continuing with the first one
+func (a *agent) processComparedGeneration(ctx context.Context, providers []provider.Provider, sessionID, content string, attachmentParts []message.ContentPart) AgentEvent {
+	defer a.closeComparisonProviders(providers)
+	_, msgHistory, err := a.prepareGeneration(ctx, sessionID, content, attachmentParts)
+	if err != nil {
+		return a.err(err)
+	}
+	session, err := a.sessions.Get(ctx, sessionID)
+	if err != nil {
+		return a.err(fmt.Errorf("failed to get session: %w", err))
+	}
+
+	candidates := make([]message.ComparisonCandidate, len(providers))
+	usages := make([]provider.TokenUsage, len(providers))
+	var wg sync.WaitGroup
+	for i, p := range providers {
+		model := p.Model()
+		branch, err := a.sessions.CreateBranchSession(ctx, sessionID, fmt.Sprintf("Comparison of %s: %s", session.Title, model.Name))
+		if err != nil {
+			return a.err(fmt.Errorf("failed to create comparison session: %w", err))
+		}
+		candidates[i] = message.ComparisonCandidate{Model: model.ID, SessionID: branch.ID}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer logging.RecoverPanic("agent.Compare", func() {
+				candidates[i].Error = "panic while generating the response"
+			})
+			start := time.Now()
+			gen := generation{provider: p, usage: &usages[i], withoutTools: true}
+			result := a.generate(ctx, gen, branch.ID, msgHistory)
+			candidates[i].LatencyMs = time.Since(start).Milliseconds()
+			candidates[i].InputTokens = usages[i].PromptTokens()
+			candidates[i].OutputTokens = usages[i].OutputTokens
+			candidates[i].Cost = usages[i].Cost(model)
+			if result.Error != nil {
+				candidates[i].Error = result.Error.Error()
+				return
+			}
+			candidates[i].Content = result.Message.Content().String()
+		}()
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return a.err(ErrRequestCancelled)
+	}
+
+	// The usage of every response was tracked in its branch, it is added to
+	// the session as well, the kept response last
+	failed := 0
+	for i := len(providers) - 1; i >= 0; i-- {
+		if candidates[i].Error != "" {
+			failed++
+		}
+		if err := a.trackUsage(ctx, sessionID, providers[i].Model(), usages[i], false, false); err != nil {
+			return a.err(err)
+		}
+	}
+	if failed == len(candidates) {
+		return a.err(fmt.Errorf("every compared model failed: %s", candidates[0].Error))
+	}
+
+	// The first response stands until another is chosen, unless it failed
+	comparison := message.Comparison{Candidates: candidates, Chosen: -1}
+	if candidates[0].Error != "" {
+		for i, candidate := range candidates {
+			if candidate.Error == "" {
+				comparison.Chosen = i
+				break
+			}
+		}
+	}
+	posted, err := a.postComparison(ctx, sessionID, comparison)
+	if err != nil {
+		return a.err(err)
+	}
+	return AgentEvent{
+		Type:    AgentEventTypeResponse,
+		Message: posted,
+		Done:    true,
+	}
+}
+
+// postComparison posts the kept response of a comparison to the session
+func (a *agent) postComparison(ctx context.Context, sessionID string, comparison message.Comparison) (message.Message, error) {
+	kept := comparison.Candidates[comparison.Kept()]
+	msg, err := a.messages.Create(ctx, sessionID, message.CreateMessageParams{
+		Role: message.Assistant,
+		Parts: []message.ContentPart{
+			message.TextContent{Text: kept.Content},
+			comparison,
+			message.Finish{Reason: message.FinishReasonEndTurn, Time: time.Now().Unix()},
+		},
+		Model: kept.Model,
+	})
+	if err != nil {
+		return message.Message{}, fmt.Errorf("failed to post the comparison: %w", err)
+	}
+	return msg, nil
+}
+
+// ChooseComparison picks the response of a comparison the session continues
+// with. The comparison has to be the last message of the session.
+func (a *agent) ChooseComparison(ctx context.Context, sessionID, messageID string, choice int) (message.Message, error) {
+	if a.IsSessionBusy(sessionID) {
+		return message.Message{}, ErrSessionBusy
+	}
+	msgs, err := a.messages.List(ctx, sessionID)
+	if err != nil {
+		return message.Message{}, fmt.Errorf("failed to list messages: %w", err)
+	}
+	if len(msgs) == 0 || msgs[len(msgs)-1].ID != messageID {
+		for _, msg := range msgs {
+			if msg.ID == messageID && msg.Comparison() != nil {
+				return message.Message{}, ErrComparisonContinued
+			}
+		}
+		return message.Message{}, ErrNotComparison
+	}
+	msg := msgs[len(msgs)-1]
+	comparison := msg.Comparison()
+	if comparison == nil {
+		return message.Message{}, ErrNotComparison
+	}
+	if choice < 0 || choice >= len(comparison.Candidates) {
+		return message.Message{}, fmt.Errorf("there is no response %d to choose", choice+1)
+	}
+	if comparison.Candidates[choice].Error != "" {
+		return message.Message{}, fmt.Errorf("%s failed to respond: %s", comparison.Candidates[choice].Model, comparison.Candidates[choice].Error)
+	}
+	if comparison.Chosen == choice {
+		return msg, nil
+	}
+
+	// The model of a message cannot change, the kept response is posted again
+	comparison.Chosen = choice
+	if err := a.messages.Delete(ctx, msg.ID); err != nil {
+		return message.Message{}, fmt.Errorf("failed to replace the kept response: %w", err)
+	}
+	return a.postComparison(ctx, sessionID, *comparison)
+}
@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/llm/provider"
+	"github.com/caronex/intelligence-interface/internal/message"
+)
+
+func TestRunComparison(t *testing.T) {
+	usage := provider.TokenUsage{InputTokens: 100, OutputTokens: 10}
+	f := newRegenerateFixture(t,
+		provider.FakeResponse{Content: "first answer", Usage: usage},
+		provider.FakeResponse{Content: "second answer", Usage: usage},
+	)
+	f.add(t, message.User, message.TextContent{Text: "hello"})
+	f.add(t, message.Assistant, message.TextContent{Text: "hi"})
+
+	ctx := WithComparison(context.Background(), "", models.TestFake)
+	result := wait(f.agent.Run(ctx, f.session.ID, "name a color"))
+	if result.Error != nil {
+		t.Fatalf("Run() error = %v", result.Error)
+	}
+	comparison := result.Message.Comparison()
+	if comparison == nil || len(comparison.Candidates) != 2 {
+		t.Fatalf("response comparison = %+v, want 2 candidates", comparison)
+	}
+	if comparison.Chosen != -1 {
+		t.Errorf("chosen = %d, want none", comparison.Chosen)
+	}
+	var contents []string
+	for _, candidate := range comparison.Candidates {
+		contents = append(contents, candidate.Content)
+		if candidate.InputTokens != 100 || candidate.OutputTokens != 10 {
+			t.Errorf("candidate tokens = %d/%d, want 100/10", candidate.InputTokens, candidate.OutputTokens)
+		}
+		branch, err := f.sessions.Get(context.Background(), candidate.SessionID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if branch.ParentSessionID != f.session.ID {
+			t.Errorf("branch parent = %q, want %q", branch.ParentSessionID, f.session.ID)
+		}
+		if msgs := f.list(t, branch.ID); len(msgs) != 1 || msgs[0].Content().String() != candidate.Content {
+			t.Errorf("branch messages = %+v, want the candidate response", msgs)
+		}
+	}
+	slices.Sort(contents)
+	if !slices.Equal(contents, []string{"first answer", "second answer"}) {
+		t.Errorf("candidates = %v, want both answers", contents)
+	}
+	if result.Message.Content().String() != comparison.Candidates[0].Content {
+		t.Errorf("response = %q, want the first candidate", result.Message.Content().String())
+	}
+
+	msgs := f.list(t, f.session.ID)
+	if len(msgs) != 4 {
+		t.Fatalf("session has %d messages, want 4", len(msgs))
+	}
+	sess, err := f.sessions.Get(context.Background(), f.session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sess.CompletionTokens != 10 {
+		t.Errorf("session completion tokens = %d, want 10", sess.CompletionTokens)
+	}
+
+	chosen, err := f.agent.ChooseComparison(context.Background(), f.session.ID, result.Message.ID, 1)
+	if err != nil {
+		t.Fatalf("ChooseComparison() error = %v", err)
+	}
+	if chosen.Content().String() != comparison.Candidates[1].Content || chosen.Comparison().Chosen != 1 {
+		t.Errorf("chosen response = %q, want the second candidate", chosen.Content().String())
+	}
+	msgs = f.list(t, f.session.ID)
+	if len(msgs) != 4 || msgs[3].ID != chosen.ID {
+		t.Errorf("session messages after ChooseComparison() = %v", ids(msgs))
+	}
+}
+
+func TestChooseComparisonErrors(t *testing.T) {
+	f := newRegenerateFixture(t)
+	f.add(t, message.User, message.TextContent{Text: "name a color"})
+	compared := f.add(t, message.Assistant, message.TextContent{Text: "blue"}, message.Comparison{
+		Candidates: []message.ComparisonCandidate{
+			{Model: models.TestFake, Content: "blue"},
+			{Model: models.TestFake, Error: "rate limited"},
+		},
+		Chosen: -1,
+	})
+
+	if _, err := f.agent.ChooseComparison(context.Background(), f.session.ID, compared.ID, 1); err == nil {
+		t.Error("ChooseComparison() of a failed candidate succeeded, want an error")
+	}
+	if _, err := f.agent.ChooseComparison(context.Background(), f.session.ID, compared.ID, 2); err == nil {
+		t.Error("ChooseComparison() of a missing candidate succeeded, want an error")
+	}
+
+	f.add(t, message.User, message.TextContent{Text: "and another"})
+	_, err := f.agent.ChooseComparison(context.Background(), f.session.ID, compared.ID, 0)
+	if !errors.Is(err, ErrComparisonContinued) {
+		t.Errorf("ChooseComparison() error = %v, want %v", err, ErrComparisonContinued)
+	}
+}
@@ -0,0 +1,118 @@
+package agent
+
+import (
+	"slices"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/llm/prompt"
+	"github.com/caronex/intelligence-interface/internal/message"
+)
+
+// sessionContext is the project context a session was sent
+type sessionContext struct {
+	// frozen sessions keep the context they have, ignoring file changes
+	frozen bool
+	// version is the context version the session was last checked against
+	version int
+	// content is the context the session follows
+	content string
+	// update is the latest context update, sent along messageID
+	update    string
+	messageID string
+}
+
+// sessionContext returns the context state of a session, the context of the
+// system prompt until an update is sent. Callers hold contextMu.
+func (a *agent) sessionContext(sessionID string) *sessionContext {
+	state, ok := a.contexts[sessionID]
+	if !ok {
+		state = &sessionContext{content: prompt.PromptContext()}
+		a.contexts[sessionID] = state
+	}
+	return state
+}
+
+// FreezeContext stops or resumes updating the project context of a session
+// when context files change. A resumed session is sent the current context
+// on its next turn.
+func (a *agent) FreezeContext(sessionID string, frozen bool) {
+	a.contextMu.Lock()
+	defer a.contextMu.Unlock()
+	a.sessionContext(sessionID).frozen = frozen
+}
+
+// IsContextFrozen reports whether the project context of a session is frozen
+func (a *agent) IsContextFrozen(sessionID string) bool {
+	a.contextMu.Lock()
+	defer a.contextMu.Unlock()
+	state, ok := a.contexts[sessionID]
+	return ok && state.frozen
+}
+
+// updateContext attaches the current project context to a new user message
+// when the context files changed since the session was last sent them
+func (a *agent) updateContext(sessionID, messageID string) {
+	// Only the caronex prompt includes the project context
+	if a.name != config.AgentCaronex {
+		return
+	}
+	version := prompt.ContextVersion()
+
+	a.contextMu.Lock()
+	defer a.contextMu.Unlock()
+	state := a.sessionContext(sessionID)
+	if state.frozen || state.version == version {
+		return
+	}
+	changed := prompt.ContextChangesSince(state.version)
+	state.version = version
+	content := prompt.CurrentContext()
+	if content == state.content {
+		return
+	}
+	state.content = content
+	state.update = prompt.ContextUpdate(changed, content)
+	state.messageID = messageID
+}
+
+// withContextUpdate returns the conversation history with the latest context
+// update of the session prepended to the message it was sent along, or to the
+// first user message once that message was summarized or replaced. The update
+// stays at the same position on later turns, so the history before it can
+// still be served from the prompt cache.
+func (a *agent) withContextUpdate(sessionID string, msgs []message.Message) []message.Message {
+	a.contextMu.Lock()
+	state, ok := a.contexts[sessionID]
+	var update, messageID string
+	if ok {
+		update, messageID = state.update, state.messageID
+	}
+	a.contextMu.Unlock()
+	if update == "" {
+		return msgs
+	}
+
+	target := slices.IndexFunc(msgs, func(msg message.Message) bool { return msg.ID == messageID })
+	if target == -1 {
+		target = slices.IndexFunc(msgs, func(msg message.Message) bool { return msg.Role == message.User })
+	}
+	if target == -1 {
+		return msgs
+	}
+
+	msgs = slices.Clone(msgs)
+	msg := msgs[target]
+	text := msg.Content().Text
+	i := slices.IndexFunc(msg.Parts, func(part message.ContentPart) bool {
+		_, ok := part.(message.TextContent)
+		return ok
+	})
+	if i == -1 {
+		msg.Parts = append([]message.ContentPart{message.TextContent{Text: update}}, msg.Parts...)
+	} else {
+		msg.Parts = slices.Clone(msg.Parts)
+		msg.Parts[i] = message.TextContent{Text: update + "\n\n" + text}
+	}
+	msgs[target] = msg
+	return msgs
+}
@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/llm/prompt"
+	"github.com/caronex/intelligence-interface/internal/llm/provider"
+	"github.com/caronex/intelligence-interface/internal/message"
+)
+
+// writeContext changes the context file and waits for the watcher to notice
+func writeContext(t *testing.T, content string) {
+	t.Helper()
+	version := prompt.ContextVersion()
+	path := filepath.Join(config.WorkingDirectory(), "CLAUDE.md")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for prompt.ContextVersion() == version {
+		if time.Now().After(deadline) {
+			t.Fatal("context change not noticed")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestContextUpdateSentOnNextTurn(t *testing.T) {
+	f := newRegenerateFixture(t,
+		provider.FakeResponse{Content: "first"},
+		provider.FakeResponse{Content: "second"},
+		provider.FakeResponse{Content: "third"},
+	)
+	if err := config.Update(func(cfg *config.Config) error {
+		cfg.ContextPaths = []string{"CLAUDE.md"}
+		return nil
+	}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	f.add(t, message.User, message.TextContent{Text: "hi"})
+	f.add(t, message.Assistant, message.TextContent{Text: "hello"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		prompt.WatchContext(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	time.Sleep(100 * time.Millisecond)
+	writeContext(t, "use tabs")
+
+	if result := wait(f.agent.Run(context.Background(), f.session.ID, "next")); result.Error != nil {
+		t.Fatalf("Run() error = %v", result.Error)
+	}
+	request := f.fake.Requests()[0]
+	got := request[len(request)-1].Content().String()
+	if !strings.HasPrefix(got, "<context-update>") || !strings.Contains(got, "use tabs") || !strings.HasSuffix(got, "\n\nnext") {
+		t.Fatalf("user message sent = %q, want the context update before it", got)
+	}
+
+	// The update stays on the message it was sent with
+	if result := wait(f.agent.Run(context.Background(), f.session.ID, "again")); result.Error != nil {
+		t.Fatalf("Run() error = %v", result.Error)
+	}
+	request = f.fake.Requests()[1]
+	if request[2].Content().String() != got {
+		t.Errorf("earlier message sent = %q, want %q", request[2].Content().String(), got)
+	}
+	if last := request[len(request)-1].Content().String(); last != "again" {
+		t.Errorf("user message sent = %q, want no new update", last)
+	}
+
+	// Frozen sessions keep the context they have
+	f.agent.FreezeContext(f.session.ID, true)
+	if !f.agent.IsContextFrozen(f.session.ID) {
+		t.Fatal("IsContextFrozen() = false after FreezeContext()")
+	}
+	writeContext(t, "use spaces")
+	if result := wait(f.agent.Run(context.Background(), f.session.ID, "third")); result.Error != nil {
+		t.Fatalf("Run() error = %v", result.Error)
+	}
+	for _, msg := range f.fake.Requests()[2] {
+		if strings.Contains(msg.Content().String(), "use spaces") {
+			t.Errorf("frozen session was sent the changed context: %q", msg.Content().String())
+		}
+	}
+}
+
+func TestWithContextUpdateFallsBackToFirstUserMessage(t *testing.T) {
+	a := &agent{contexts: map[string]*sessionContext{
+		"session": {update: "<context-update>", messageID: "summarized"},
+	}}
+	msgs := []message.Message{
+		{ID: "summary", Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "summary"}}},
+		{ID: "latest", Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "latest"}}},
+	}
+
+	got := a.withContextUpdate("session", msgs)
+	if text := got[0].Content().String(); text != "<context-update>\n\nsummary" {
+		t.Errorf("first message = %q, want the update prepended", text)
+	}
+	if text := msgs[0].Content().String(); text != "summary" {
+		t.Errorf("stored message changed to %q", text)
+	}
+}
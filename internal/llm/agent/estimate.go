@@ -0,0 +1,135 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/llm/prompt"
+	"github.com/caronex/intelligence-interface/internal/llm/tokens"
+	"github.com/caronex/intelligence-interface/internal/llm/tools"
+	"github.com/caronex/intelligence-interface/internal/message"
+)
+
+// PromptEstimate is the estimated size and price of the prompt of a turn,
+// before it is sent
+type PromptEstimate struct {
+	Tokens int64
+	// Cost is the price of the prompt tokens at the input rate of Model,
+	// without the prompt cache discount
+	Cost  float64
+	Model models.Model
+}
+
+// EstimatePrompt estimates the prompt of sending content and attachments to a
+// session: the system prompt with its context files, the conversation since
+// the last summary with the scratchpad of the session, the tools offered to
+// the model and the new message. An empty sessionID estimates the first
+// message of a new session. The sources of context left out with
+// WithExcludedContext, or else by the session, are not counted.
+func (a *agent) EstimatePrompt(ctx context.Context, sessionID, content string, attachments ...message.Attachment) (PromptEstimate, error) {
+	gen := generation{provider: a.provider}
+	model := gen.provider.Model()
+	sources, err := a.resolveContextSources(ctx, sessionID)
+	if err != nil {
+		return PromptEstimate{}, err
+	}
+	settings, err := a.sessionSettings(ctx, sessionID)
+	if err != nil {
+		return PromptEstimate{}, err
+	}
+	agentName := a.name
+	if cfg := config.Get(); cfg != nil {
+		if agentConfig, ok := cfg.Agents[settings.agent]; ok {
+			agentName = settings.agent
+			model = models.SupportedModels[agentConfig.Model]
+		}
+	}
+	if sessionModel, ok := models.SupportedModels[settings.model]; ok {
+		model = sessionModel
+	}
+	var excluded []message.ContextSource
+	if sources != nil {
+		excluded = sources.Excluded
+	}
+
+	var msgs []message.Message
+	if sessionID != "" {
+		history, err := a.messages.List(ctx, sessionID)
+		if err != nil {
+			return PromptEstimate{}, fmt.Errorf("failed to list messages: %w", err)
+		}
+		session, err := a.sessions.Get(ctx, sessionID)
+		if err != nil {
+			return PromptEstimate{}, fmt.Errorf("failed to get session: %w", err)
+		}
+		msgs = sinceSummary(session, history)
+		if !slices.Contains(excluded, message.ContextFiles) {
+			msgs = a.withContextUpdate(sessionID, msgs)
+		}
+	}
+	msgs = append(msgs, message.Message{
+		Role:  message.User,
+		Parts: append([]message.ContentPart{message.TextContent{Text: content}}, gen.attachmentParts(attachments)...),
+	})
+	if sessionID != "" {
+		msgs = withScratchpad(sessionID, msgs)
+	}
+
+	p := tokens.Prompt{System: prompt.GetAgentPromptWithout(agentName, model.Provider, excluded)}
+	for _, msg := range msgs {
+		text, images := promptText(msg)
+		p.Messages = append(p.Messages, text)
+		p.Images += images
+	}
+	for _, tool := range allowedTools(a.toolsFor(model), settings.allowedTools) {
+		p.Tools = append(p.Tools, toolDefinition(tool.Info()))
+	}
+
+	estimate := PromptEstimate{Tokens: int64(p.Tokens()), Model: model}
+	estimate.Cost = model.CostPer1MIn / 1e6 * float64(estimate.Tokens)
+	return estimate, nil
+}
+
+// promptText returns the text of msg as it is sent to the provider, and the
+// number of images attached to it
+func promptText(msg message.Message) (string, int) {
+	var b strings.Builder
+	images := 0
+	for _, part := range msg.Parts {
+		switch part := part.(type) {
+		case message.TextContent:
+			b.WriteString(part.Text)
+		case message.ImageURLContent:
+			images++
+		case message.BinaryContent:
+			if strings.HasPrefix(part.MIMEType, "image/") {
+				images++
+			} else {
+				b.Write(part.Data)
+			}
+		case message.ToolCall:
+			b.WriteString(part.Name)
+			b.WriteString(part.Input)
+		case message.ToolResult:
+			b.WriteString(part.Content)
+		}
+	}
+	return b.String(), images
+}
+
+// toolDefinition returns the definition of a tool as it is sent to the
+// provider
+func toolDefinition(info tools.ToolInfo) string {
+	definition, _ := json.Marshal(map[string]any{
+		"name":        info.Name,
+		"description": info.Description,
+		"parameters":  info.Parameters,
+		"required":    info.Required,
+	})
+	return string(definition)
+}
@@ -0,0 +1,47 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/caronex/intelligence-interface/internal/llm/tokens"
+	"github.com/caronex/intelligence-interface/internal/message"
+)
+
+func TestEstimatePromptCountsHistory(t *testing.T) {
+	f := newRegenerateFixture(t)
+	ctx := context.Background()
+
+	first, err := f.agent.EstimatePrompt(ctx, "", "what changed?")
+	if err != nil {
+		t.Fatalf("EstimatePrompt() error = %v", err)
+	}
+	if first.Tokens <= int64(tokens.Estimate("what changed?")) || first.Model.ID != f.fake.Model().ID {
+		t.Errorf("estimate = %+v, want the system prompt counted along the message", first)
+	}
+
+	f.addToolTurn(t)
+	followUp, err := f.agent.EstimatePrompt(ctx, f.session.ID, "what changed?")
+	if err != nil {
+		t.Fatalf("EstimatePrompt() error = %v", err)
+	}
+	history := 0
+	for _, text := range []string{"list the files", "ls" + `{"path":"."}`, "main.go", "main.go"} {
+		history += tokens.Estimate(text) + tokens.MessageOverhead
+	}
+	if got := followUp.Tokens - first.Tokens; got != int64(history) {
+		t.Errorf("the history added %d tokens, want %d", got, history)
+	}
+}
+
+func TestPromptText(t *testing.T) {
+	text, images := promptText(message.Message{Parts: []message.ContentPart{
+		message.TextContent{Text: "see "},
+		message.BinaryContent{Path: "notes.txt", MIMEType: "text/plain", Data: []byte("the notes")},
+		message.BinaryContent{Path: "shot.png", MIMEType: "image/png", Data: []byte{0x89, 'P', 'N', 'G'}},
+		message.ReasoningContent{Thinking: "not sent back"},
+	}})
+	if text != "see the notes" || images != 1 {
+		t.Errorf("promptText() = %q, %d images; want the text file inlined and the image counted", text, images)
+	}
+}
@@ -0,0 +1,234 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/llm/tokens"
+	"github.com/caronex/intelligence-interface/internal/llm/tools"
+	"github.com/caronex/intelligence-interface/internal/message"
+)
+
+// handoffTokenBudget bounds the conversation excerpts of a handoff, in tokens
+const handoffTokenBudget = 1500
+
+// Handoff is the working context passed from a conversation to the agent a
+// task is delegated to, so it does not start cold
+type Handoff struct {
+	// Task is the prompt of the delegated task
+	Task string `json:"task"`
+	// Excerpts are the parts of the conversation relevant to the task
+	Excerpts []string `json:"excerpts,omitempty"`
+	// Files are the paths already read, edited or named in the conversation
+	Files []string `json:"files,omitempty"`
+	// Constraints are the requirements the user stated in the conversation
+	Constraints []string `json:"constraints,omitempty"`
+}
+
+// HandoffResponseMetadata records a delegation in the result of the agent tool
+type HandoffResponseMetadata struct {
+	SessionID string  `json:"session_id"`
+	Handoff   Handoff `json:"handoff"`
+}
+
+var (
+	// filePattern matches words that look like file paths, e.g. internal/app/app.go
+	filePattern = regexp.MustCompile(`[\w./-]+\.\w+`)
+	// constraintPattern matches sentences stating a requirement
+	constraintPattern = regexp.MustCompile(`(?i)\b(must|should|never|always|only|avoid|don't|do not|make sure)\b`)
+)
+
+// handoff assembles the context of a task delegated from a session. Excerpts
+// are selected by the summarizer when there is one, the most recent messages
+// otherwise.
+func (a *agent) handoff(ctx context.Context, sessionID, task string) (Handoff, error) {
+	msgs, err := a.messages.List(ctx, sessionID)
+	if err != nil {
+		return Handoff{}, fmt.Errorf("failed to list messages: %w", err)
+	}
+	session, err := a.sessions.Get(ctx, sessionID)
+	if err != nil {
+		return Handoff{}, fmt.Errorf("failed to get session: %w", err)
+	}
+	msgs = sinceSummary(session, msgs)
+
+	h := Handoff{
+		Task:        task,
+		Files:       handoffFiles(msgs),
+		Constraints: handoffConstraints(msgs),
+	}
+	h.Excerpts, err = a.selectExcerpts(ctx, msgs, task)
+	if err != nil {
+		logging.Warn("failed to select handoff excerpts, using the latest messages", "error", err)
+		h.Excerpts = recentExcerpts(msgs, handoffTokenBudget)
+	}
+	return h, nil
+}
+
+// selectExcerpts asks the summarizer for the parts of the conversation the
+// task needs, within the handoff token budget
+func (a *agent) selectExcerpts(ctx context.Context, msgs []message.Message, task string) ([]string, error) {
+	transcript := recentExcerpts(msgs, 0)
+	if len(transcript) == 0 {
+		return nil, nil
+	}
+	if a.summarizeProvider == nil {
+		return recentExcerpts(msgs, handoffTokenBudget), nil
+	}
+
+	prompt := fmt.Sprintf("The task below is being handed off to another agent that has not seen this conversation:\n\n%s\n\n"+
+		"Quote the parts of the conversation that agent needs to do the task, most important first, in at most %d words. "+
+		"Separate the excerpts with blank lines and do not add anything else.\n\n%s",
+		task, handoffTokenBudget*3/4, strings.Join(transcript, "\n\n"))
+	response, err := a.summarizeProvider.SendMessages(ctx, []message.Message{{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: prompt}},
+	}}, make([]tools.BaseTool, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	var excerpts []string
+	for _, excerpt := range strings.Split(response.Content, "\n\n") {
+		if excerpt = strings.TrimSpace(excerpt); excerpt != "" {
+			excerpts = append(excerpts, excerpt)
+		}
+	}
+	return limitTokens(excerpts, handoffTokenBudget), nil
+}
+
+// recentExcerpts returns the text of the user and assistant messages, keeping
+// the most recent ones that fit within budget tokens, or all when budget is 0
+func recentExcerpts(msgs []message.Message, budget int) []string {
+	var excerpts []string
+	for _, msg := range msgs {
+		text := strings.TrimSpace(msg.Content().String())
+		if text == "" || (msg.Role != message.User && msg.Role != message.Assistant) {
+			continue
+		}
+		excerpts = append(excerpts, fmt.Sprintf("%s: %s", msg.Role, text))
+	}
+	if budget == 0 {
+		return excerpts
+	}
+
+	// Keep the most recent excerpts, in conversation order
+	for i := len(excerpts) - 1; i >= 0; i-- {
+		budget -= tokens.Estimate(excerpts[i])
+		if budget < 0 {
+			return excerpts[i+1:]
+		}
+	}
+	return excerpts
+}
+
+// limitTokens keeps the first excerpts that fit within budget tokens
+func limitTokens(excerpts []string, budget int) []string {
+	for i, excerpt := range excerpts {
+		budget -= tokens.Estimate(excerpt)
+		if budget < 0 {
+			return excerpts[:i]
+		}
+	}
+	return excerpts
+}
+
+// handoffFiles returns the files tools were called on in the conversation,
+// followed by the existing files named in its text
+func handoffFiles(msgs []message.Message) []string {
+	var files []string
+	seen := make(map[string]bool)
+	add := func(path string) {
+		if path == "" {
+			return
+		}
+		if rel, err := filepath.Rel(config.WorkingDirectory(), path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+		if !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+
+	for _, msg := range msgs {
+		for _, call := range msg.ToolCalls() {
+			var input struct {
+				FilePath string `json:"file_path"`
+			}
+			if json.Unmarshal([]byte(call.Input), &input) == nil {
+				add(input.FilePath)
+			}
+		}
+	}
+	for _, msg := range msgs {
+		if msg.Role != message.User && msg.Role != message.Assistant {
+			continue
+		}
+		for _, candidate := range filePattern.FindAllString(msg.Content().String(), -1) {
+			candidate = strings.TrimRight(candidate, ".")
+			path := candidate
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(config.WorkingDirectory(), path)
+			}
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				add(candidate)
+			}
+		}
+	}
+	return files
+}
+
+// handoffConstraints returns the sentences of the user messages that state a requirement
+func handoffConstraints(msgs []message.Message) []string {
+	var constraints []string
+	for _, msg := range msgs {
+		if msg.Role != message.User {
+			continue
+		}
+		for _, line := range strings.Split(msg.Content().String(), "\n") {
+			for _, sentence := range strings.SplitAfter(line, ". ") {
+				sentence = strings.TrimSpace(sentence)
+				if constraintPattern.MatchString(sentence) {
+					constraints = append(constraints, sentence)
+				}
+			}
+		}
+	}
+	return constraints
+}
+
+// Prompt renders the handoff as the first message of the delegated session,
+// the handed off context followed by the task
+func (h Handoff) Prompt() string {
+	var b strings.Builder
+	b.WriteString("<handoff>\nThis task was delegated from another conversation. Its context follows.\n")
+	if len(h.Files) > 0 {
+		b.WriteString("\nFiles already discussed:\n")
+		for _, file := range h.Files {
+			fmt.Fprintf(&b, "- %s\n", file)
+		}
+	}
+	if len(h.Constraints) > 0 {
+		b.WriteString("\nConstraints stated by the user:\n")
+		for _, constraint := range h.Constraints {
+			fmt.Fprintf(&b, "- %s\n", constraint)
+		}
+	}
+	if len(h.Excerpts) > 0 {
+		b.WriteString("\nRelevant conversation excerpts:\n")
+		for _, excerpt := range h.Excerpts {
+			fmt.Fprintf(&b, "\n%s\n", excerpt)
+		}
+	}
+	b.WriteString("</handoff>\n\n")
+	b.WriteString(h.Task)
+	return b.String()
+}
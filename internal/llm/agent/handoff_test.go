@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/llm/provider"
+	"github.com/caronex/intelligence-interface/internal/llm/tools"
+	"github.com/caronex/intelligence-interface/internal/message"
+)
+
+// addDiscussion stores a conversation that names, reads and constrains files
+func (f *regenerateFixture) addDiscussion(t *testing.T) {
+	t.Helper()
+	dir := config.WorkingDirectory()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	call := message.ToolCall{ID: "call-1", Name: "view", Input: `{"file_path":"` + filepath.Join(dir, "server.go") + `"}`, Type: "function", Finished: true}
+	f.add(t, message.User, message.TextContent{Text: "The handler in main.go panics on empty input. Never change its signature."})
+	f.add(t, message.Assistant, call)
+	f.add(t, message.Tool, message.ToolResult{ToolCallID: call.ID, Content: "package main"})
+	f.add(t, message.Assistant, message.TextContent{Text: "The panic comes from a nil map."})
+}
+
+func TestHandoff(t *testing.T) {
+	f := newRegenerateFixture(t, provider.FakeResponse{Content: "user: The handler in main.go panics on empty input.\n\nassistant: The panic comes from a nil map."})
+	f.addDiscussion(t)
+
+	h, err := f.agent.(*agent).handoff(context.Background(), f.session.ID, "fix the panic")
+	if err != nil {
+		t.Fatalf("handoff() error = %v", err)
+	}
+	if got := strings.Join(h.Files, ","); got != "server.go,main.go" {
+		t.Errorf("Files = %v, want the viewed file then the named one", h.Files)
+	}
+	if len(h.Constraints) != 1 || h.Constraints[0] != "Never change its signature." {
+		t.Errorf("Constraints = %q", h.Constraints)
+	}
+	if len(h.Excerpts) != 2 {
+		t.Errorf("Excerpts = %q, want the two selected by the summarizer", h.Excerpts)
+	}
+	requests := f.fake.Requests()
+	if len(requests) != 1 || !strings.Contains(requests[0][0].Content().String(), "fix the panic") {
+		t.Errorf("summarizer requests = %v, want one naming the task", requests)
+	}
+
+	prompt := h.Prompt()
+	for _, want := range []string{"- main.go", "- Never change its signature.", "nil map", "</handoff>\n\nfix the panic"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("Prompt() is missing %q:\n%s", want, prompt)
+		}
+	}
+}
+
+func TestHandoffFallsBackToRecentMessages(t *testing.T) {
+	f := newRegenerateFixture(t, provider.FakeResponse{Err: context.DeadlineExceeded})
+	f.addDiscussion(t)
+
+	h, err := f.agent.(*agent).handoff(context.Background(), f.session.ID, "fix the panic")
+	if err != nil {
+		t.Fatalf("handoff() error = %v", err)
+	}
+	if len(h.Excerpts) != 2 || !strings.HasPrefix(h.Excerpts[0], "user: The handler") {
+		t.Errorf("Excerpts = %q, want the text messages of the conversation", h.Excerpts)
+	}
+}
+
+func TestRecentExcerptsKeepsLatestWithinBudget(t *testing.T) {
+	msgs := []message.Message{
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: strings.Repeat("a", 40)}}},
+		{Role: message.Assistant, Parts: []message.ContentPart{message.TextContent{Text: "short"}}},
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "latest"}}},
+	}
+	if got := recentExcerpts(msgs, 8); len(got) != 2 || got[1] != "user: latest" {
+		t.Errorf("recentExcerpts() = %q, want the two latest messages", got)
+	}
+}
+
+func TestAgentToolStartsSubSessionWithHandoff(t *testing.T) {
+	// The summarizer selects the excerpts, the delegated agent answers and
+	// titles its session
+	f := newRegenerateFixture(t,
+		provider.FakeResponse{Content: "user: The handler in main.go panics on empty input."},
+		provider.FakeResponse{Content: "done"},
+		provider.FakeResponse{Content: "done"},
+	)
+	f.addDiscussion(t)
+	parent := f.add(t, message.User, message.TextContent{Text: "delegate it"})
+
+	tool := NewAgentTool(f.sessions, f.messages, nil)
+	ctx := context.WithValue(context.Background(), tools.SessionIDContextKey, f.session.ID)
+	ctx = context.WithValue(ctx, tools.MessageIDContextKey, parent.ID)
+	response, err := tool.Run(ctx, tools.ToolCall{ID: "call-2", Name: AgentToolName, Input: `{"prompt":"fix the panic"}`})
+	if err != nil || response.IsError {
+		t.Fatalf("Run() = %+v, %v", response, err)
+	}
+
+	var metadata HandoffResponseMetadata
+	if err := json.Unmarshal([]byte(response.Metadata), &metadata); err != nil {
+		t.Fatalf("invalid response metadata %q: %v", response.Metadata, err)
+	}
+	if metadata.Handoff.Task != "fix the panic" || len(metadata.Handoff.Files) != 2 {
+		t.Errorf("recorded handoff = %+v", metadata.Handoff)
+	}
+	msgs := f.list(t, metadata.SessionID)
+	if len(msgs) == 0 || msgs[0].Content().String() != metadata.Handoff.Prompt() {
+		t.Fatalf("sub-session messages = %+v, want the handoff first", msgs)
+	}
+}
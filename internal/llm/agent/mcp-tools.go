@@ -4,8 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
+	"reflect"
+	"sort"
 
 	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/llm/mcpauth"
 	"github.com/caronex/intelligence-interface/internal/llm/tools"
 	"github.com/caronex/intelligence-interface/internal/core/logging"
 	"github.com/caronex/intelligence-interface/internal/permission"
@@ -34,15 +38,40 @@ type MCPClient interface {
 
 func (b *mcpTool) Info() tools.ToolInfo {
 	return tools.ToolInfo{
-		Name:        fmt.Sprintf("%s_%s", b.mcpName, b.tool.Name),
+		Name:        b.name(),
 		Description: b.tool.Description,
 		Parameters:  b.tool.InputSchema.Properties,
 		Required:    b.tool.InputSchema.Required,
 	}
 }
 
+// name is the name the tool is exposed to the model under: its alias, or
+// <server>_<tool> as providers reject dots in tool names
+func (b *mcpTool) name() string {
+	if alias := b.mcpConfig.Aliases[b.tool.Name]; alias != "" {
+		return alias
+	}
+	return fmt.Sprintf("%s_%s", b.mcpName, b.tool.Name)
+}
+
+// QualifiedName identifies the tool in configuration as <server>.<tool>
+func (b *mcpTool) QualifiedName() string {
+	return fmt.Sprintf("%s.%s", b.mcpName, b.tool.Name)
+}
+
 func runTool(ctx context.Context, c MCPClient, toolName string, input string) (tools.ToolResponse, error) {
+	response, err := callTool(ctx, c, toolName, input)
+	if err != nil {
+		return tools.NewTextErrorResponse(err.Error()), nil
+	}
+	return response, nil
+}
+
+// callTool calls a tool of the server c is connected to, and closes c
+func callTool(ctx context.Context, c MCPClient, toolName string, input string) (tools.ToolResponse, error) {
 	defer c.Close()
+	ctx, _ = logging.EnsureRequestID(ctx)
+	logger := logging.FromContext(ctx)
 	initRequest := mcp.InitializeRequest{}
 	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
 	initRequest.Params.ClientInfo = mcp.Implementation{
@@ -52,7 +81,8 @@ func runTool(ctx context.Context, c MCPClient, toolName string, input string) (t
 
 	_, err := c.Initialize(ctx, initRequest)
 	if err != nil {
-		return tools.NewTextErrorResponse(err.Error()), nil
+		logger.Warn("error initializing mcp client", "tool", toolName, "error", err)
+		return tools.ToolResponse{}, err
 	}
 
 	toolRequest := mcp.CallToolRequest{}
@@ -62,9 +92,11 @@ func runTool(ctx context.Context, c MCPClient, toolName string, input string) (t
 		return tools.NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
 	}
 	toolRequest.Params.Arguments = args
+	logger.Debug("calling mcp tool", "tool", toolName)
 	result, err := c.CallTool(ctx, toolRequest)
 	if err != nil {
-		return tools.NewTextErrorResponse(err.Error()), nil
+		logger.Warn("error calling mcp tool", "tool", toolName, "error", err)
+		return tools.ToolResponse{}, err
 	}
 
 	output := ""
@@ -103,7 +135,7 @@ func (b *mcpTool) Run(ctx context.Context, params tools.ToolCall) (tools.ToolRes
 	case config.MCPStdio:
 		c, err := client.NewStdioMCPClient(
 			b.mcpConfig.Command,
-			b.mcpConfig.Env,
+			mcpEnviron(b.mcpConfig),
 			b.mcpConfig.Args...,
 		)
 		if err != nil {
@@ -111,19 +143,30 @@ func (b *mcpTool) Run(ctx context.Context, params tools.ToolCall) (tools.ToolRes
 		}
 		return runTool(ctx, c, b.tool.Name, params.Input)
 	case config.MCPSse:
-		c, err := client.NewSSEMCPClient(
-			b.mcpConfig.URL,
-			client.WithHeaders(b.mcpConfig.Headers),
-		)
+		// A server rejecting an expired token is called again once refreshed
+		response, err := mcpauth.Do(ctx, b.mcpName, b.mcpConfig, func(headers map[string]string) (tools.ToolResponse, error) {
+			c, err := mcpauth.StartSSEClient(ctx, b.mcpConfig.URL, headers)
+			if err != nil {
+				return tools.ToolResponse{}, err
+			}
+			return callTool(ctx, c, b.tool.Name, params.Input)
+		})
 		if err != nil {
 			return tools.NewTextErrorResponse(err.Error()), nil
 		}
-		return runTool(ctx, c, b.tool.Name, params.Input)
+		return response, nil
 	}
 
 	return tools.NewTextErrorResponse("invalid mcp type"), nil
 }
 
+// mcpEnviron returns the environment added to the process environment of a
+// stdio MCP server: the variables of the active space, then those configured
+// for the server, the later ones winning
+func mcpEnviron(m config.MCPServer) []string {
+	return append(config.SpaceEnviron(), m.Env...)
+}
+
 func NewMcpTool(name string, tool mcp.Tool, permissions permission.Service, mcpConfig config.MCPServer) tools.BaseTool {
 	return &mcpTool{
 		mcpName:     name,
@@ -133,9 +176,25 @@ func NewMcpTool(name string, tool mcp.Tool, permissions permission.Service, mcpC
 	}
 }
 
-var mcpTools []tools.BaseTool
+var (
+	mcpTools []tools.BaseTool
+	// mcpToolsServers are the servers mcpTools were loaded from, the tools are
+	// loaded again once the configured servers change
+	mcpToolsServers map[string]config.MCPServer
+)
 
 func getTools(ctx context.Context, name string, m config.MCPServer, permissions permission.Service, c MCPClient) []tools.BaseTool {
+	serverTools, err := listTools(ctx, name, m, permissions, c)
+	if err != nil {
+		logging.Error("error loading mcp tools", "server", name, "error", err)
+	}
+	return serverTools
+}
+
+// listTools returns the tools of the server c is connected to that its
+// configuration allows, and closes c
+func listTools(ctx context.Context, name string, m config.MCPServer, permissions permission.Service, c MCPClient) ([]tools.BaseTool, error) {
+	defer c.Close()
 	var stdioTools []tools.BaseTool
 	initRequest := mcp.InitializeRequest{}
 	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
@@ -146,32 +205,45 @@ func getTools(ctx context.Context, name string, m config.MCPServer, permissions
 
 	_, err := c.Initialize(ctx, initRequest)
 	if err != nil {
-		logging.Error("error initializing mcp client", "error", err)
-		return stdioTools
+		return nil, fmt.Errorf("error initializing mcp client: %w", err)
 	}
 	toolsRequest := mcp.ListToolsRequest{}
 	tools, err := c.ListTools(ctx, toolsRequest)
 	if err != nil {
-		logging.Error("error listing tools", "error", err)
-		return stdioTools
+		return nil, fmt.Errorf("error listing tools: %w", err)
 	}
 	for _, t := range tools.Tools {
-		stdioTools = append(stdioTools, NewMcpTool(name, t, permissions, m))
+		tool := NewMcpTool(name, t, permissions, m).(*mcpTool)
+		if !m.ToolAllowed(tool.QualifiedName(), tool.Info().Name) {
+			logging.Debug("mcp tool disabled by configuration", "tool", tool.QualifiedName())
+			continue
+		}
+		stdioTools = append(stdioTools, tool)
 	}
-	defer c.Close()
-	return stdioTools
+	return stdioTools, nil
 }
 
 func GetMcpTools(ctx context.Context, permissions permission.Service) []tools.BaseTool {
-	if len(mcpTools) > 0 {
+	servers := config.Get().MCPServers
+	if len(mcpTools) > 0 && maps.EqualFunc(mcpToolsServers, servers, func(a, b config.MCPServer) bool {
+		return reflect.DeepEqual(a, b)
+	}) {
 		return mcpTools
 	}
-	for name, m := range config.Get().MCPServers {
+	mcpTools, mcpToolsServers = nil, servers
+	// Servers are loaded in a stable order so name collisions resolve the same way every run
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		m := servers[name]
 		switch m.Type {
 		case config.MCPStdio:
 			c, err := client.NewStdioMCPClient(
 				m.Command,
-				m.Env,
+				mcpEnviron(m),
 				m.Args...,
 			)
 			if err != nil {
@@ -181,15 +253,18 @@ func GetMcpTools(ctx context.Context, permissions permission.Service) []tools.Ba
 
 			mcpTools = append(mcpTools, getTools(ctx, name, m, permissions, c)...)
 		case config.MCPSse:
-			c, err := client.NewSSEMCPClient(
-				m.URL,
-				client.WithHeaders(m.Headers),
-			)
+			serverTools, err := mcpauth.Do(ctx, name, m, func(headers map[string]string) ([]tools.BaseTool, error) {
+				c, err := mcpauth.StartSSEClient(ctx, m.URL, headers)
+				if err != nil {
+					return nil, err
+				}
+				return listTools(ctx, name, m, permissions, c)
+			})
 			if err != nil {
-				logging.Error("error creating mcp client", "error", err)
+				logging.Error("error loading mcp tools", "server", name, "error", err)
 				continue
 			}
-			mcpTools = append(mcpTools, getTools(ctx, name, m, permissions, c)...)
+			mcpTools = append(mcpTools, serverTools...)
 		}
 	}
 
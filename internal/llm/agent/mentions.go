@@ -0,0 +1,202 @@
+package agent
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/fileutil"
+	"github.com/caronex/intelligence-interface/internal/message"
+)
+
+// fileReferences resolves the files and directories content mentions with @
+// into the references sent along with it, within the mention limits of cfg.
+// Mentions of paths that do not exist are left as they are, as @ also starts
+// handles and annotations.
+func fileReferences(cfg *config.Config, content string) []message.ContentPart {
+	mentions := message.ParseMentions(content)
+	if len(mentions) == 0 {
+		return nil
+	}
+	budget := cfg.Mentions.Bytes()
+	var parts []message.ContentPart
+	for _, mention := range mentions {
+		reference, ok := resolveMention(cfg, mention, &budget)
+		if ok {
+			parts = append(parts, reference)
+		}
+	}
+	return parts
+}
+
+// resolveMention reads the file or lists the directory a mention names,
+// taking the content from budget. It reports false when the path does not
+// exist.
+func resolveMention(cfg *config.Config, mention string, budget *int64) (message.FileReference, bool) {
+	path, err := config.ResolveWorkspacePath(mention)
+	if err != nil {
+		return message.FileReference{}, false
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(config.WorkingDirectory(), path)
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return message.FileReference{}, false
+	}
+
+	reference := message.FileReference{Path: mention, Dir: info.IsDir()}
+	root, ok := sandboxRoot(path)
+	if !ok {
+		reference.Notice = "outside the workspace, left out"
+		return reference, true
+	}
+	reference.Path = canonicalPath(root, path)
+	rel, _ := filepath.Rel(root, path)
+	if rel != "." && fileutil.Ignored(root, []string{rel})[rel] {
+		reference.Notice = "excluded by the ignore files, left out"
+		return reference, true
+	}
+
+	if info.IsDir() {
+		reference.Content, reference.Notice = listDirectory(path, cfg.Mentions.DirEntries())
+	} else {
+		reference.Size = info.Size()
+		reference.Content, reference.Notice = readMentionedFile(path, info.Size(), *budget)
+	}
+	*budget = max(*budget-int64(len(reference.Content)), 0)
+	if reference.Notice != "" {
+		logging.Info("Mentioned file cut", "path", reference.Path, "notice", reference.Notice)
+	}
+	return reference, true
+}
+
+// sandboxRoot returns the working directory or workspace root containing
+// path, false when path is outside all of them
+func sandboxRoot(path string) (string, bool) {
+	roots := []string{config.WorkingDirectory()}
+	for _, name := range config.WorkspaceRootNames() {
+		roots = append(roots, config.WorkspaceRoots()[name].Path)
+	}
+	for _, root := range roots {
+		if resolved, err := filepath.EvalSymlinks(root); err == nil {
+			root = resolved
+		}
+		rel, err := filepath.Rel(root, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return root, true
+		}
+	}
+	return "", false
+}
+
+// canonicalPath returns the path the agent is sent for a path within root:
+// relative to the working directory, or qualified with the name of its
+// workspace root when root is not the working directory
+func canonicalPath(root, path string) string {
+	rel, _ := filepath.Rel(root, path)
+	rel = filepath.ToSlash(rel)
+	for _, name := range config.WorkspaceRootNames() {
+		workspace := config.WorkspaceRoots()[name].Path
+		if resolved, err := filepath.EvalSymlinks(workspace); err == nil {
+			workspace = resolved
+		}
+		if workspace == root && !isWorkingDirectory(root) {
+			return name + ":" + rel
+		}
+	}
+	return rel
+}
+
+func isWorkingDirectory(dir string) bool {
+	wd := config.WorkingDirectory()
+	if resolved, err := filepath.EvalSymlinks(wd); err == nil {
+		wd = resolved
+	}
+	return wd == dir
+}
+
+// readMentionedFile returns the content of a mentioned file, cut to budget
+// bytes at a line boundary, and a notice when it was cut or left out
+func readMentionedFile(path string, size, budget int64) (string, string) {
+	if budget <= 0 {
+		return "", "left out, over the mention size budget"
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Sprintf("could not be read: %v", err)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, budget))
+	if err != nil {
+		return "", fmt.Sprintf("could not be read: %v", err)
+	}
+	if bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+		return "", "binary file, left out"
+	}
+	if size <= budget {
+		return string(data), ""
+	}
+	if i := bytes.LastIndexByte(data, '\n'); i > 0 {
+		data = data[:i+1]
+	}
+	return string(data), fmt.Sprintf("truncated to %d of %d bytes, over the mention size budget", len(data), size)
+}
+
+// errListingFull stops the walk of a directory once its listing is full
+var errListingFull = errors.New("listing full")
+
+// listDirectory lists the entries below dir, directories ending with a slash,
+// up to limit entries. Hidden and ignored entries are left out.
+func listDirectory(dir string, limit int) (string, string) {
+	var entries []string
+	more := false
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == dir {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, path)
+		if fileutil.SkipHidden(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// Entries are gathered past the limit, as some may be ignored
+		if len(entries) >= limit*2 {
+			more = true
+			return errListingFull
+		}
+		if d.IsDir() {
+			rel += string(filepath.Separator)
+		}
+		entries = append(entries, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil && !errors.Is(err, errListingFull) {
+		return "", fmt.Sprintf("could not be listed: %v", err)
+	}
+
+	ignored := fileutil.Ignored(dir, entries)
+	entries = slices.DeleteFunc(entries, func(entry string) bool {
+		return ignored[entry] || ignored[strings.TrimSuffix(entry, "/")]
+	})
+	if len(entries) > limit {
+		entries, more = entries[:limit], true
+	}
+	if more {
+		return strings.Join(entries, "\n"), fmt.Sprintf("listing cut at %d entries", limit)
+	}
+	return strings.Join(entries, "\n"), ""
+}
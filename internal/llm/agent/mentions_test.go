@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/message"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func references(parts []message.ContentPart) []message.FileReference {
+	var refs []message.FileReference
+	for _, part := range parts {
+		if ref, ok := part.(message.FileReference); ok {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+func TestFileReferences(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.NewTestConfig(config.WithWorkingDir(dir))
+	writeFile(t, filepath.Join(dir, "main.go"), "package main\n")
+	writeFile(t, filepath.Join(dir, "docs", "a.md"), "a\n")
+	writeFile(t, filepath.Join(dir, "docs", "guide", "b.md"), "b\n")
+	writeFile(t, filepath.Join(dir, "docs", ".hidden"), "h\n")
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	writeFile(t, outside, "secret\n")
+
+	refs := references(fileReferences(cfg, "explain @./main.go, list @docs/ and mail me@example.com about @missing.go or @"+outside))
+	if len(refs) != 3 {
+		t.Fatalf("references = %+v, want main.go, docs and the outside file", refs)
+	}
+
+	if refs[0].Path != "main.go" || refs[0].Content != "package main\n" || refs[0].Notice != "" {
+		t.Errorf("file reference = %+v, want main.go with its content", refs[0])
+	}
+	if !refs[1].Dir || refs[1].Path != "docs" || refs[1].Content != "a.md\nguide/\nguide/b.md" {
+		t.Errorf("directory reference = %+v, want the listing of docs", refs[1])
+	}
+	if refs[2].Content != "" || !strings.Contains(refs[2].Notice, "outside the workspace") {
+		t.Errorf("outside reference = %+v, want it left out", refs[2])
+	}
+}
+
+func TestFileReferencesLimits(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.NewTestConfig(config.WithWorkingDir(dir))
+	cfg.Mentions = config.MentionsConfig{Budget: 11, MaxDirEntries: 2}
+	writeFile(t, filepath.Join(dir, "long.txt"), "first line\nsecond line\nthird line\n")
+	writeFile(t, filepath.Join(dir, "short.txt"), "short\n")
+	writeFile(t, filepath.Join(dir, "data.bin"), "\x00\x01\x02")
+	for _, name := range []string{"a", "b", "c"} {
+		writeFile(t, filepath.Join(dir, "many", name), name)
+	}
+
+	refs := references(fileReferences(cfg, "@long.txt @short.txt @many"))
+	if len(refs) != 3 {
+		t.Fatalf("references = %+v, want 3", refs)
+	}
+	if refs[0].Content != "first line\n" || !strings.Contains(refs[0].Notice, "truncated to 11 of 34 bytes") {
+		t.Errorf("over budget reference = %+v, want it truncated at a line", refs[0])
+	}
+	if refs[1].Content != "" || !strings.Contains(refs[1].Notice, "over the mention size budget") {
+		t.Errorf("reference past the budget = %+v, want it left out", refs[1])
+	}
+	if refs[2].Content != "a\nb" || refs[2].Notice != "listing cut at 2 entries" {
+		t.Errorf("directory reference = %+v, want 2 entries and a notice", refs[2])
+	}
+
+	refs = references(fileReferences(cfg, "@data.bin"))
+	if len(refs) != 1 || refs[0].Content != "" || refs[0].Notice != "binary file, left out" {
+		t.Errorf("binary reference = %+v, want it left out", refs)
+	}
+}
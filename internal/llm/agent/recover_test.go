@@ -0,0 +1,141 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/db"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/llm/provider"
+	"github.com/caronex/intelligence-interface/internal/llm/tools"
+	"github.com/caronex/intelligence-interface/internal/message"
+	"github.com/caronex/intelligence-interface/internal/session"
+)
+
+// blockingTool runs until released, standing for a tool the process dies in
+type blockingTool struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingTool) Info() tools.ToolInfo {
+	return tools.ToolInfo{Name: "build", Description: "Builds the project"}
+}
+
+func (b *blockingTool) Run(ctx context.Context, call tools.ToolCall) (tools.ToolResponse, error) {
+	close(b.started)
+	<-b.release
+	return tools.NewTextResponse("ok"), nil
+}
+
+func TestRecoverResponseInterruptedInToolCall(t *testing.T) {
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
+	cfg.Data.Directory = t.TempDir()
+	ctx := context.Background()
+
+	conn, err := db.Connect()
+	if err != nil {
+		t.Fatalf("db.Connect() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	q := db.New(conn)
+	sessions, messages := session.NewService(q), message.NewService(q)
+	fake := provider.NewFakeProvider(models.TestModels[models.TestFake],
+		provider.FakeResponse{Content: "Building", ToolCalls: []message.ToolCall{{ID: "call-1", Name: "build", Input: "{}"}}},
+		provider.FakeResponse{Content: "Built"},
+	)
+	t.Cleanup(provider.InstallFake(fake))
+	tool := &blockingTool{started: make(chan struct{}), release: make(chan struct{})}
+	a, err := NewAgent(config.AgentCaronex, sessions, messages, []tools.BaseTool{tool})
+	if err != nil {
+		t.Fatalf("NewAgent() error = %v", err)
+	}
+	sess, err := sessions.Create(ctx, "test")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	// An earlier exchange, so that no title is generated with the responses
+	for _, params := range []message.CreateMessageParams{
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "hello"}}},
+		{Role: message.Assistant, Parts: []message.ContentPart{message.TextContent{Text: "hi"}, message.Finish{Reason: message.FinishReasonEndTurn}}},
+	} {
+		if _, err := messages.Create(ctx, sess.ID, params); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	events, err := a.Run(ctx, sess.ID, "build it")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	// The run is ended before the database is closed, when the test fails too
+	t.Cleanup(func() {
+		select {
+		case <-tool.release:
+		default:
+			close(tool.release)
+		}
+		select {
+		case <-events:
+		case <-time.After(10 * time.Second):
+			t.Error("Run() did not end once the tool was released")
+		}
+	})
+	select {
+	case <-tool.started:
+	case result := <-events:
+		t.Fatalf("Run() ended before the tool ran: %+v", result)
+	case <-time.After(10 * time.Second):
+		t.Fatal("the tool did not run")
+	}
+
+	// The process dies while the tool runs: a new one opens the database
+	crashConn, err := db.Connect()
+	if err != nil {
+		t.Fatalf("db.Connect() error = %v", err)
+	}
+	t.Cleanup(func() { crashConn.Close() })
+	restarted := message.NewService(db.New(crashConn))
+	msgs, err := restarted.List(ctx, sess.ID)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(msgs) != 4 || msgs[3].Status != message.StatusInProgress || msgs[3].FinishReason() != message.FinishReasonToolUse {
+		t.Fatalf("the response should be stored in progress while its tool runs, got %+v", msgs)
+	}
+	recoveries, err := restarted.Recover(ctx)
+	if err != nil {
+		t.Fatalf("Recover() error = %v", err)
+	}
+	if len(recoveries) != 1 || recoveries[0].AbortedToolCalls != 1 {
+		t.Fatalf("Recover() = %+v, want the tool call aborted", recoveries)
+	}
+	msgs, err = restarted.List(ctx, sess.ID)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(msgs) != 6 || msgs[3].Status != message.StatusInterrupted || msgs[3].Content().Text != "Building" {
+		t.Fatalf("the response should be interrupted with its content, got %+v", msgs)
+	}
+	if results := msgs[4].ToolResults(); len(results) != 1 || !results[0].IsError {
+		t.Errorf("the tool call should get an error result, got %+v", msgs[4])
+	}
+}
+
+func TestToolResponseFinishedWithItsResults(t *testing.T) {
+	f := newRegenerateFixture(t,
+		provider.FakeResponse{ToolCalls: []message.ToolCall{{ID: "call-1", Name: "ls", Input: "{}"}}},
+		provider.FakeResponse{Content: "done"},
+	)
+	f.add(t, message.User, message.TextContent{Text: "hello"})
+	f.add(t, message.Assistant, message.TextContent{Text: "hi"})
+	if result := wait(f.agent.Run(context.Background(), f.session.ID, "list")); result.Error != nil {
+		t.Fatalf("Run() error = %v", result.Error)
+	}
+	for _, msg := range f.list(t, f.session.ID) {
+		if msg.Status != message.StatusFinished {
+			t.Errorf("%s message %s is %s, want finished", msg.Role, msg.ID, msg.Status)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/llm/provider"
+	"github.com/caronex/intelligence-interface/internal/message"
+	"github.com/caronex/intelligence-interface/internal/session"
+	"github.com/caronex/intelligence-interface/internal/tracing"
+)
+
+var (
+	ErrNothingToRetry = errors.New("there is no response to retry")
+	ErrNotUserMessage = errors.New("only user messages can be edited and resent")
+	ErrSummarized     = errors.New("messages before the session summary cannot be regenerated")
+)
+
+// RetryOptions configure how the last response of a session is generated again
+type RetryOptions struct {
+	// Model generates the new response instead of the agent model, for this retry only
+	Model models.ModelID
+	// Append keeps the previous response in the conversation instead of
+	// moving it to a branch. Responses with unanswered tool calls are always
+	// moved, as providers reject them in the conversation history.
+	Append bool
+}
+
+// generation is a single run of the agent loop
+type generation struct {
+	provider provider.Provider
+	// regenerated generations retry or resend a response, their usage is
+	// tracked apart from the rest of the session
+	regenerated bool
+	// outputTokens, when set, counts the tokens generated
+	outputTokens *int64
+	// usage, when set, adds up the usage of the responses
+	usage *provider.TokenUsage
+	// withoutTools generations offer the model no tools, so responding has
+	// no side effects
+	withoutTools bool
+	// allowedTools, when set, are the only tools offered to the model
+	allowedTools []string
+}
+
+// attachmentParts converts attachments to message parts, dropping them when
+// the model does not support attachments
+func (g generation) attachmentParts(attachments []message.Attachment) []message.ContentPart {
+	if !g.provider.Model().SupportsAttachments {
+		return nil
+	}
+	var parts []message.ContentPart
+	for _, attachment := range attachments {
+		parts = append(parts, message.BinaryContent{Path: attachment.FilePath, MIMEType: attachment.MimeType, Data: attachment.Content})
+	}
+	return parts
+}
+
+// Retry generates the response to the last user message of a session again,
+// sending the same conversation up to and including that message.
+func (a *agent) Retry(ctx context.Context, sessionID string, opts RetryOptions) (<-chan AgentEvent, error) {
+	gen := generation{provider: a.provider, regenerated: true}
+	// The response is generated with the sources of context the message was
+	// sent with
+	sources, err := a.sentContextSources(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	ctx = withContextSources(ctx, sources)
+	settings, err := a.sessionSettings(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	gen.allowedTools = settings.allowedTools
+	if gen.provider, err = a.runProvider(config.Get(), settings, opts.Model, sources); err != nil {
+		return nil, err
+	}
+	closeProvider := func() {
+		if gen.provider != a.provider {
+			if err := gen.provider.Close(); err != nil {
+				logging.Warn("failed to close the retry provider", "error", err)
+			}
+		}
+	}
+
+	events, err := a.start(ctx, sessionID, gen, func(ctx context.Context) AgentEvent {
+		defer closeProvider()
+		assembly := tracing.FromContext(ctx).Child("prompt assembly")
+		defer assembly.End()
+		msgs, err := a.messages.List(ctx, sessionID)
+		if err != nil {
+			return a.err(fmt.Errorf("failed to list messages: %w", err))
+		}
+		last := lastUserMessage(msgs)
+		if last == -1 {
+			return a.err(ErrNothingToRetry)
+		}
+		session, err := a.sessions.Get(ctx, sessionID)
+		if err != nil {
+			return a.err(fmt.Errorf("failed to get session: %w", err))
+		}
+
+		previous := msgs[last+1:]
+		if containsMessage(previous, session.SummaryMessageID) {
+			return a.err(ErrSummarized)
+		}
+		if !opts.Append || hasUnansweredToolCalls(previous) {
+			if err := a.branch(ctx, session, previous); err != nil {
+				return a.err(err)
+			}
+		}
+
+		assembly.End()
+		history := sinceSummary(session, msgs[:last+1])
+		if !contextExcluded(ctx, message.ContextFiles) {
+			history = a.withContextUpdate(sessionID, history)
+		}
+		return a.generate(ctx, gen, sessionID, history)
+	})
+	if err != nil {
+		closeProvider()
+	}
+	return events, err
+}
+
+// sentContextSources returns the sources of context the last user message of
+// a session was sent with, nil when they were not recorded
+func (a *agent) sentContextSources(ctx context.Context, sessionID string) (*message.ContextSources, error) {
+	msgs, err := a.messages.List(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list messages: %w", err)
+	}
+	if last := lastUserMessage(msgs); last != -1 {
+		return msgs[last].ContextSources(), nil
+	}
+	return nil, nil
+}
+
+// Resend replaces a user message of a session with new content and generates
+// the response to it. The replaced message and everything after it are moved
+// to a branch of the session.
+func (a *agent) Resend(ctx context.Context, sessionID, messageID, content string, attachments ...message.Attachment) (<-chan AgentEvent, error) {
+	gen := generation{provider: a.provider, regenerated: true}
+	attachmentParts := gen.attachmentParts(attachments)
+	sources, err := a.resolveContextSources(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	ctx = withContextSources(ctx, sources)
+	settings, err := a.sessionSettings(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	gen.allowedTools = settings.allowedTools
+	if gen.provider, err = a.runProvider(config.Get(), settings, "", sources); err != nil {
+		return nil, err
+	}
+
+	events, err := a.start(ctx, sessionID, gen, func(ctx context.Context) AgentEvent {
+		defer a.closeRunProvider(gen.provider)
+		msgs, err := a.messages.List(ctx, sessionID)
+		if err != nil {
+			return a.err(fmt.Errorf("failed to list messages: %w", err))
+		}
+		edited := -1
+		for i, msg := range msgs {
+			if msg.ID == messageID {
+				edited = i
+				break
+			}
+		}
+		if edited == -1 || msgs[edited].Role != message.User {
+			return a.err(ErrNotUserMessage)
+		}
+		session, err := a.sessions.Get(ctx, sessionID)
+		if err != nil {
+			return a.err(fmt.Errorf("failed to get session: %w", err))
+		}
+		if containsMessage(msgs[edited:], session.SummaryMessageID) {
+			return a.err(ErrSummarized)
+		}
+
+		if err := a.branch(ctx, session, msgs[edited:]); err != nil {
+			return a.err(err)
+		}
+		return a.processGeneration(ctx, gen, sessionID, content, attachmentParts)
+	})
+	if err != nil {
+		a.closeRunProvider(gen.provider)
+	}
+	return events, err
+}
+
+// branch moves messages discarded from a session into a hidden child session,
+// so they are kept without being sent to the provider again
+func (a *agent) branch(ctx context.Context, parent session.Session, msgs []message.Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	branch, err := a.sessions.CreateBranchSession(ctx, parent.ID, fmt.Sprintf("Branch of %s", parent.Title))
+	if err != nil {
+		return fmt.Errorf("failed to create branch session: %w", err)
+	}
+	for _, msg := range msgs {
+		if err := a.messages.Move(ctx, msg.ID, branch.ID); err != nil {
+			return fmt.Errorf("failed to move message to branch: %w", err)
+		}
+	}
+	return nil
+}
+
+// lastUserMessage returns the index of the last message sent by the user, -1
+// if there is none
+func lastUserMessage(msgs []message.Message) int {
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].Role == message.User {
+			return i
+		}
+	}
+	return -1
+}
+
+func containsMessage(msgs []message.Message, id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, msg := range msgs {
+		if msg.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// hasUnansweredToolCalls reports whether any tool call in msgs has no result
+func hasUnansweredToolCalls(msgs []message.Message) bool {
+	answered := make(map[string]bool)
+	for _, msg := range msgs {
+		for _, result := range msg.ToolResults() {
+			answered[result.ToolCallID] = true
+		}
+	}
+	for _, msg := range msgs {
+		for _, call := range msg.ToolCalls() {
+			if !answered[call.ID] {
+				return true
+			}
+		}
+	}
+	return false
+}
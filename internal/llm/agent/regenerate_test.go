@@ -0,0 +1,309 @@
+package agent
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/db"
+	"github.com/caronex/intelligence-interface/internal/history"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/llm/provider"
+	"github.com/caronex/intelligence-interface/internal/llm/tools"
+	"github.com/caronex/intelligence-interface/internal/message"
+	"github.com/caronex/intelligence-interface/internal/permission"
+	"github.com/caronex/intelligence-interface/internal/session"
+)
+
+type regenerateFixture struct {
+	agent    Service
+	fake     *provider.FakeProvider
+	sessions session.Service
+	messages message.Service
+	session  session.Session
+	q        *db.Queries
+	conn     *sql.DB
+}
+
+func newRegenerateFixture(t *testing.T, responses ...provider.FakeResponse) *regenerateFixture {
+	t.Helper()
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
+	cfg.Data.Directory = t.TempDir()
+
+	conn, err := db.Connect()
+	if err != nil {
+		t.Fatalf("db.Connect() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	q := db.New(conn)
+
+	f := &regenerateFixture{
+		fake:     provider.NewFakeProvider(models.TestModels[models.TestFake], responses...),
+		sessions: session.NewService(q),
+		messages: message.NewService(q),
+		q:        q,
+		conn:     conn,
+	}
+	t.Cleanup(provider.InstallFake(f.fake))
+
+	f.agent, err = NewAgent(config.AgentCaronex, f.sessions, f.messages, nil)
+	if err != nil {
+		t.Fatalf("NewAgent() error = %v", err)
+	}
+	f.session, err = f.sessions.Create(context.Background(), "test")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return f
+}
+
+// add stores a message of the conversation made of parts
+func (f *regenerateFixture) add(t *testing.T, role message.MessageRole, parts ...message.ContentPart) message.Message {
+	t.Helper()
+	if role == message.Assistant {
+		parts = append(parts, message.Finish{Reason: message.FinishReasonEndTurn})
+	}
+	msg, err := f.messages.Create(context.Background(), f.session.ID, message.CreateMessageParams{
+		Role:  role,
+		Parts: parts,
+		Model: models.TestFake,
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return msg
+}
+
+func (f *regenerateFixture) list(t *testing.T, sessionID string) []message.Message {
+	t.Helper()
+	msgs, err := f.messages.List(context.Background(), sessionID)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	return msgs
+}
+
+// wait returns the result of a generation, or the error that prevented it from starting
+func wait(events <-chan AgentEvent, err error) AgentEvent {
+	if err != nil {
+		return AgentEvent{Type: AgentEventTypeError, Error: err}
+	}
+	return <-events
+}
+
+func ids(msgs []message.Message) []string {
+	result := make([]string, len(msgs))
+	for i, msg := range msgs {
+		result[i] = msg.ID
+	}
+	return result
+}
+
+func sameIDs(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// addToolTurn stores a user message answered after a tool call
+func (f *regenerateFixture) addToolTurn(t *testing.T) []message.Message {
+	call := message.ToolCall{ID: "call-1", Name: "ls", Input: `{"path":"."}`, Type: "function", Finished: true}
+	return []message.Message{
+		f.add(t, message.User, message.TextContent{Text: "list the files"}),
+		f.add(t, message.Assistant, call),
+		f.add(t, message.Tool, message.ToolResult{ToolCallID: call.ID, Content: "main.go"}),
+		f.add(t, message.Assistant, message.TextContent{Text: "main.go"}),
+	}
+}
+
+func TestRetryMovesPreviousResponseToBranch(t *testing.T) {
+	f := newRegenerateFixture(t, provider.FakeResponse{
+		Content: "retried",
+		Usage:   provider.TokenUsage{InputTokens: 100, OutputTokens: 10},
+	})
+	turn := f.addToolTurn(t)
+
+	result := wait(f.agent.Retry(context.Background(), f.session.ID, RetryOptions{}))
+	if result.Error != nil {
+		t.Fatalf("Retry() error = %v", result.Error)
+	}
+
+	requests := f.fake.Requests()
+	if len(requests) != 1 || !sameIDs(ids(requests[0]), ids(turn[:1])) {
+		t.Fatalf("Retry() sent %v, want only the user message", requests)
+	}
+	msgs := f.list(t, f.session.ID)
+	if len(msgs) != 2 || msgs[0].ID != turn[0].ID || msgs[1].Content().String() != "retried" {
+		t.Fatalf("session messages after Retry() = %+v", msgs)
+	}
+
+	// The previous response, tool call and result included, is kept in a branch
+	moved, err := f.messages.Get(context.Background(), turn[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	branch, err := f.sessions.Get(context.Background(), moved.SessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if branch.ParentSessionID != f.session.ID {
+		t.Errorf("branch parent = %q, want %q", branch.ParentSessionID, f.session.ID)
+	}
+	if got := ids(f.list(t, branch.ID)); !sameIDs(got, ids(turn[1:])) {
+		t.Errorf("branch messages = %v, want %v", got, ids(turn[1:]))
+	}
+	if branch.MessageCount != 3 {
+		t.Errorf("branch message count = %d, want 3", branch.MessageCount)
+	}
+
+	sess, err := f.sessions.Get(context.Background(), f.session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sess.MessageCount != 2 {
+		t.Errorf("session message count = %d, want 2", sess.MessageCount)
+	}
+	if sess.RegeneratedTokens != 110 {
+		t.Errorf("regenerated tokens = %d, want 110", sess.RegeneratedTokens)
+	}
+}
+
+func TestRetryAppend(t *testing.T) {
+	t.Run("keeps answered response", func(t *testing.T) {
+		f := newRegenerateFixture(t, provider.FakeResponse{Content: "retried"})
+		turn := f.addToolTurn(t)
+
+		result := wait(f.agent.Retry(context.Background(), f.session.ID, RetryOptions{Append: true}))
+		if result.Error != nil {
+			t.Fatalf("Retry() error = %v", result.Error)
+		}
+		if requests := f.fake.Requests(); len(requests) != 1 || len(requests[0]) != 1 {
+			t.Fatalf("Retry() sent %v, want only the user message", requests)
+		}
+		if got := ids(f.list(t, f.session.ID)); !sameIDs(got[:len(turn)], ids(turn)) || len(got) != len(turn)+1 {
+			t.Errorf("session messages = %v, want the previous response followed by the new one", got)
+		}
+	})
+
+	t.Run("moves unanswered tool calls", func(t *testing.T) {
+		f := newRegenerateFixture(t, provider.FakeResponse{Content: "retried"})
+		user := f.add(t, message.User, message.TextContent{Text: "list the files"})
+		f.add(t, message.Assistant, message.ToolCall{ID: "call-1", Name: "ls", Type: "function", Finished: true})
+
+		result := wait(f.agent.Retry(context.Background(), f.session.ID, RetryOptions{Append: true}))
+		if result.Error != nil {
+			t.Fatalf("Retry() error = %v", result.Error)
+		}
+		if got := f.list(t, f.session.ID); len(got) != 2 || got[0].ID != user.ID {
+			t.Errorf("session messages = %v, want the user message and the new response", ids(got))
+		}
+	})
+}
+
+func TestResendBranchesFromEditedMessage(t *testing.T) {
+	f := newRegenerateFixture(t, provider.FakeResponse{Content: "edited answer"})
+	first := f.add(t, message.User, message.TextContent{Text: "hello"})
+	answer := f.add(t, message.Assistant, message.TextContent{Text: "hi"})
+	edited := f.add(t, message.User, message.TextContent{Text: "what is 1+1"})
+	f.add(t, message.Assistant, message.TextContent{Text: "2"})
+
+	if result := wait(f.agent.Resend(context.Background(), f.session.ID, answer.ID, "nope")); !errors.Is(result.Error, ErrNotUserMessage) {
+		t.Fatalf("Resend(assistant message) error = %v, want ErrNotUserMessage", result.Error)
+	}
+
+	result := wait(f.agent.Resend(context.Background(), f.session.ID, edited.ID, "what is 2+2"))
+	if result.Error != nil {
+		t.Fatalf("Resend() error = %v", result.Error)
+	}
+
+	requests := f.fake.Requests()
+	if len(requests) != 1 || len(requests[0]) != 3 || requests[0][2].Content().String() != "what is 2+2" {
+		t.Fatalf("Resend() sent %v, want the prefix and the edited message", requests)
+	}
+	msgs := f.list(t, f.session.ID)
+	if len(msgs) != 4 || msgs[0].ID != first.ID || msgs[1].ID != answer.ID || msgs[3].Content().String() != "edited answer" {
+		t.Fatalf("session messages after Resend() = %v", ids(msgs))
+	}
+	moved, err := f.messages.Get(context.Background(), edited.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.SessionID == f.session.ID {
+		t.Errorf("edited message was not moved to a branch")
+	}
+}
+
+// toolCallResponse is a response of the model making one tool call
+func toolCallResponse(t *testing.T, id, name string, params any) provider.FakeResponse {
+	t.Helper()
+	input, err := json.Marshal(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider.FakeResponse{
+		ToolCalls:    []message.ToolCall{{ID: id, Name: name, Input: string(input), Type: "function", Finished: true}},
+		FinishReason: message.FinishReasonToolUse,
+	}
+}
+
+func TestRetryAppliesFileChangeOnce(t *testing.T) {
+	f := newRegenerateFixture(t)
+	path := filepath.Join(config.WorkingDirectory(), "main.go")
+	if err := os.WriteFile(path, []byte("package main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	view := tools.ViewParams{FilePath: path}
+	insert := tools.EditParams{FilePath: path, OldString: "package main\n", NewString: "package main\n\nimport \"fmt\"\n"}
+
+	// The stream drops once the edit is applied, then the turn is retried and
+	// the model makes the same calls again, with new tool call IDs
+	f.fake = provider.NewFakeProvider(models.TestModels[models.TestFake],
+		toolCallResponse(t, "view-1", tools.ViewToolName, view),
+		toolCallResponse(t, "edit-1", tools.EditToolName, insert),
+		provider.FakeResponse{Err: errors.New("stream dropped")},
+		toolCallResponse(t, "view-2", tools.ViewToolName, view),
+		toolCallResponse(t, "edit-2", tools.EditToolName, insert),
+		provider.FakeResponse{Content: "added the import"},
+	)
+	t.Cleanup(provider.InstallFake(f.fake))
+	permissions := permission.NewPermissionService()
+	permissions.AutoApproveSession(f.session.ID)
+	var err error
+	f.agent, err = NewAgent(config.AgentCaronex, f.sessions, f.messages, []tools.BaseTool{
+		tools.NewViewTool(nil),
+		tools.NewEditTool(nil, permissions, history.NewService(f.q, f.conn)),
+	})
+	if err != nil {
+		t.Fatalf("NewAgent() error = %v", err)
+	}
+
+	// An earlier exchange, so that no title is generated
+	f.add(t, message.User, message.TextContent{Text: "hello"})
+	f.add(t, message.Assistant, message.TextContent{Text: "hi"})
+	if result := wait(f.agent.Run(context.Background(), f.session.ID, "import fmt")); result.Error == nil {
+		t.Fatal("Run() succeeded, want the dropped stream error")
+	}
+	result := wait(f.agent.Retry(context.Background(), f.session.ID, RetryOptions{}))
+	if result.Error != nil {
+		t.Fatalf("Retry() error = %v", result.Error)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "package main\n\nimport \"fmt\"\n"; string(content) != want {
+		t.Errorf("file after Retry() = %q, want the edit applied once: %q", content, want)
+	}
+}
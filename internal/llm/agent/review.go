@@ -0,0 +1,255 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/llm/provider"
+	"github.com/caronex/intelligence-interface/internal/llm/tools"
+	"github.com/caronex/intelligence-interface/internal/message"
+	"github.com/caronex/intelligence-interface/internal/tools/coordination"
+)
+
+type reviewSkippedContextKey struct{}
+
+// WithoutReview returns a context in which the response to the message sent
+// is presented as is, even when the agent has a review flow
+func WithoutReview(ctx context.Context) context.Context {
+	return context.WithValue(ctx, reviewSkippedContextKey{}, true)
+}
+
+func reviewSkipped(ctx context.Context) bool {
+	skipped, _ := ctx.Value(reviewSkippedContextKey{}).(bool)
+	return skipped
+}
+
+// reviewFlow returns the review flow of the agent in cfg, nil when its
+// responses are not reviewed
+func (a *agent) reviewFlow(cfg *config.Config) *config.ReviewFlow {
+	if cfg == nil {
+		return nil
+	}
+	flow := cfg.Agents[a.name].ReviewFlow
+	if flow == nil || !flow.Enabled {
+		return nil
+	}
+	return flow
+}
+
+// review is a review flow running in its own session
+type review struct {
+	sessionID string
+	// history is the conversation the reviewed agent responds to, the review
+	// session messages of its passes are added to it
+	history []message.Message
+	// reviewerMsgIDs are the messages of the critique passes, which the
+	// reviewed agent does not see
+	reviewerMsgIDs map[string]bool
+	// response is the latest response of the reviewed agent
+	response message.Message
+}
+
+// processReviewedGeneration generates the response to a message in a review
+// session, where the reviewer critiques it and the agent revises it, then
+// posts the final response along with the outcome of the review
+func (a *agent) processReviewedGeneration(ctx context.Context, cfg *config.Config, gen generation, flow config.ReviewFlow, sessionID, content string, attachmentParts []message.ContentPart) AgentEvent {
+	userMsg, msgHistory, err := a.prepareGeneration(ctx, sessionID, content, attachmentParts)
+	if err != nil {
+		return a.err(err)
+	}
+	reviewSession, err := a.sessions.CreateTaskSession(ctx, "review-"+userMsg.ID, sessionID, "Review")
+	if err != nil {
+		return a.err(fmt.Errorf("failed to create review session: %w", err))
+	}
+	r := &review{
+		sessionID:      reviewSession.ID,
+		history:        msgHistory,
+		reviewerMsgIDs: make(map[string]bool),
+	}
+
+	// Every pass generates at most its token budget in a single request
+	budget := flow.TokenBudget()
+	model := gen.provider.Model()
+	passGen := gen
+	passGen.provider, err = createAgentProviderForModel(cfg, a.name, model.ID,
+		provider.WithMaxTokens(min(budget, agentMaxTokens(cfg.Agents[a.name], model))))
+	if err != nil {
+		return a.err(fmt.Errorf("failed to create the review provider: %w", err))
+	}
+	reviewerName := flow.ReviewerAgent(a.name)
+	reviewerConfig := cfg.Agents[reviewerName]
+	reviewerModel := models.SupportedModels[reviewerConfig.Model]
+	reviewer, err := createAgentProviderForModel(cfg, reviewerName, reviewerConfig.Model,
+		provider.WithSystemMessage(coordination.ReviewerPrompt),
+		provider.WithMaxTokens(min(budget, agentMaxTokens(reviewerConfig, reviewerModel))))
+	if err != nil {
+		return a.err(fmt.Errorf("failed to create the reviewer provider: %w", err))
+	}
+
+	tokens, err := a.reviewPass(ctx, passGen, r)
+	if err != nil {
+		return a.reviewErr(err)
+	}
+	if tokens > budget {
+		logging.FromContext(ctx).WarnPersist("The response went over the review token budget and was not reviewed")
+		return a.postReviewed(ctx, sessionID, r.response, nil)
+	}
+
+	outcome, err := coordination.RunReview(ctx, flow, content, r.response.Content().String(), coordination.ReviewPasses{
+		Critique: func(ctx context.Context, prompt string) (coordination.ReviewPassResult, error) {
+			return a.critiquePass(ctx, reviewer, r, prompt)
+		},
+		Revise: func(ctx context.Context, prompt string) (coordination.ReviewPassResult, error) {
+			_, err := a.messages.Create(ctx, r.sessionID, message.CreateMessageParams{
+				Role:  message.User,
+				Parts: []message.ContentPart{message.TextContent{Text: prompt}},
+			})
+			if err != nil {
+				return coordination.ReviewPassResult{}, fmt.Errorf("failed to create revision request: %w", err)
+			}
+			tokens, err := a.reviewPass(ctx, passGen, r)
+			if err != nil {
+				return coordination.ReviewPassResult{}, err
+			}
+			return coordination.ReviewPassResult{Text: r.response.Content().String(), Tokens: tokens}, nil
+		},
+	})
+	if err != nil {
+		if isCanceled(err) {
+			return a.err(ErrRequestCancelled)
+		}
+		// The response stands without the review rather than being lost
+		logging.FromContext(ctx).WarnPersist(fmt.Sprintf("Review failed, presenting the response as is: %v", err))
+		return a.postReviewed(ctx, sessionID, r.response, nil)
+	}
+	return a.postReviewed(ctx, sessionID, r.response, &message.Review{
+		SessionID:      r.sessionID,
+		Reviewer:       string(reviewerName),
+		Found:          outcome.Found,
+		Open:           outcome.Open,
+		Revisions:      outcome.Revisions,
+		Summary:        outcome.Summary,
+		Critique:       strings.Join(outcome.Critiques, "\n\n---\n\n"),
+		BudgetExceeded: outcome.BudgetExceeded,
+	})
+}
+
+// reviewPass runs a pass of the reviewed agent in the review session and
+// returns the tokens it generated
+func (a *agent) reviewPass(ctx context.Context, gen generation, r *review) (int64, error) {
+	history, err := r.agentHistory(ctx, a.messages)
+	if err != nil {
+		return 0, err
+	}
+	var tokens int64
+	gen.outputTokens = &tokens
+	result := a.generate(ctx, gen, r.sessionID, history)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	r.response = result.Message
+	return tokens, nil
+}
+
+// critiquePass has the reviewer critique the latest response in the review
+// session
+func (a *agent) critiquePass(ctx context.Context, reviewer provider.Provider, r *review, prompt string) (coordination.ReviewPassResult, error) {
+	request, err := a.messages.Create(ctx, r.sessionID, message.CreateMessageParams{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: prompt}},
+	})
+	if err != nil {
+		return coordination.ReviewPassResult{}, fmt.Errorf("failed to create critique request: %w", err)
+	}
+	r.reviewerMsgIDs[request.ID] = true
+
+	response, err := reviewer.SendMessages(ctx, []message.Message{request}, make([]tools.BaseTool, 0))
+	if err != nil {
+		return coordination.ReviewPassResult{}, err
+	}
+	critique, err := a.messages.Create(ctx, r.sessionID, message.CreateMessageParams{
+		Role: message.Assistant,
+		Parts: []message.ContentPart{
+			message.TextContent{Text: response.Content},
+			message.Finish{Reason: message.FinishReasonEndTurn, Time: time.Now().Unix()},
+		},
+		Model: reviewer.Model().ID,
+	})
+	if err != nil {
+		return coordination.ReviewPassResult{}, fmt.Errorf("failed to create critique: %w", err)
+	}
+	r.reviewerMsgIDs[critique.ID] = true
+	if err := a.trackUsage(ctx, r.sessionID, reviewer.Model(), response.Usage, false, false); err != nil {
+		return coordination.ReviewPassResult{}, err
+	}
+	return coordination.ReviewPassResult{Text: response.Content, Tokens: response.Usage.OutputTokens}, nil
+}
+
+// agentHistory returns the conversation the reviewed agent responds to: the
+// original conversation followed by its passes and revision requests
+func (r *review) agentHistory(ctx context.Context, messages message.Service) ([]message.Message, error) {
+	msgs, err := messages.List(ctx, r.sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list review messages: %w", err)
+	}
+	history := append([]message.Message(nil), r.history...)
+	for _, msg := range msgs {
+		if !r.reviewerMsgIDs[msg.ID] {
+			history = append(history, msg)
+		}
+	}
+	return history, nil
+}
+
+// postReviewed posts the final response of a review to the session, with the
+// outcome of the review when there is one
+func (a *agent) postReviewed(ctx context.Context, sessionID string, response message.Message, outcome *message.Review) AgentEvent {
+	parts := []message.ContentPart{message.TextContent{Text: response.Content().String()}}
+	for _, citation := range response.Citations() {
+		parts = append(parts, citation)
+	}
+	if outcome != nil {
+		parts = append(parts, *outcome)
+	}
+	parts = append(parts, message.Finish{Reason: message.FinishReasonEndTurn, Time: time.Now().Unix()})
+	final, err := a.messages.Create(ctx, sessionID, message.CreateMessageParams{
+		Role:  message.Assistant,
+		Parts: parts,
+		Model: response.Model,
+	})
+	if err != nil {
+		return a.err(fmt.Errorf("failed to post the reviewed response: %w", err))
+	}
+	return AgentEvent{
+		Type:    AgentEventTypeResponse,
+		Message: final,
+		Done:    true,
+	}
+}
+
+// reviewErr returns the event of a review pass that failed
+func (a *agent) reviewErr(err error) AgentEvent {
+	if isCanceled(err) {
+		return a.err(ErrRequestCancelled)
+	}
+	return a.err(err)
+}
+
+func isCanceled(err error) bool {
+	return errors.Is(err, ErrRequestCancelled) || errors.Is(err, context.Canceled)
+}
+
+// failureErr maps the errors of the agent which the coordination failure
+// taxonomy does not recognize by itself
+func failureErr(err error) error {
+	if errors.Is(err, ErrRequestCancelled) {
+		return coordination.NewFailure(coordination.FailureCancelled, err)
+	}
+	return err
+}
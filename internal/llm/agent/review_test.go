@@ -0,0 +1,118 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/llm/provider"
+	"github.com/caronex/intelligence-interface/internal/message"
+)
+
+// enableReview gives the agent of the fixture a review flow
+func enableReview(t *testing.T, flow config.ReviewFlow) {
+	t.Helper()
+	err := config.Update(func(cfg *config.Config) error {
+		agentCfg := cfg.Agents[config.AgentCaronex]
+		agentCfg.ReviewFlow = &flow
+		cfg.Agents[config.AgentCaronex] = agentCfg
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("config.Update() error = %v", err)
+	}
+}
+
+func TestRunReviewsResponse(t *testing.T) {
+	f := newRegenerateFixture(t,
+		provider.FakeResponse{Content: "draft"},
+		provider.FakeResponse{Content: "ISSUES:\n- the error is ignored\nSUMMARY: one bug"},
+		provider.FakeResponse{Content: "revised"},
+		provider.FakeResponse{Content: "ISSUES:\n- none\nSUMMARY: looks good"},
+	)
+	enableReview(t, config.ReviewFlow{Enabled: true})
+	f.add(t, message.User, message.TextContent{Text: "hello"})
+	f.add(t, message.Assistant, message.TextContent{Text: "hi"})
+
+	result := wait(f.agent.Run(context.Background(), f.session.ID, "fix the bug"))
+	if result.Error != nil {
+		t.Fatalf("Run() error = %v", result.Error)
+	}
+
+	msgs := f.list(t, f.session.ID)
+	if len(msgs) != 4 {
+		t.Fatalf("session has %d messages, want 4", len(msgs))
+	}
+	final := msgs[3]
+	if final.Content().String() != "revised" {
+		t.Errorf("final response = %q, want the revision", final.Content().String())
+	}
+	review := final.Review()
+	if review == nil {
+		t.Fatal("final response has no review")
+	}
+	if review.Found != 1 || review.Open != 0 || review.Revisions != 1 {
+		t.Errorf("review = %+v, want 1 issue found and fixed in 1 revision", review)
+	}
+	if got := review.Badge(); got != "reviewed ✓ (1 issue fixed)" {
+		t.Errorf("Badge() = %q", got)
+	}
+
+	// The revision is asked to fix the issues, without the critique passes in its history
+	requests := f.fake.Requests()
+	if len(requests) != 4 {
+		t.Fatalf("made %d requests, want 4", len(requests))
+	}
+	revision := requests[2]
+	last := revision[len(revision)-1].Content().String()
+	if !strings.Contains(last, "the error is ignored") {
+		t.Errorf("revision request = %q, want the issues", last)
+	}
+	for _, msg := range revision {
+		if strings.Contains(msg.Content().String(), "SUMMARY:") {
+			t.Errorf("revision history has the reviewer's answer %q", msg.Content().String())
+		}
+	}
+
+	// The draft, both critiques and the revision are kept in the review session
+	reviewMsgs := f.list(t, review.SessionID)
+	if len(reviewMsgs) != 7 {
+		t.Errorf("review session has %d messages, want 7", len(reviewMsgs))
+	}
+}
+
+func TestRunWithoutReview(t *testing.T) {
+	f := newRegenerateFixture(t, provider.FakeResponse{Content: "answer"})
+	enableReview(t, config.ReviewFlow{Enabled: true})
+	f.add(t, message.User, message.TextContent{Text: "hello"})
+	f.add(t, message.Assistant, message.TextContent{Text: "hi"})
+
+	result := wait(f.agent.Run(WithoutReview(context.Background()), f.session.ID, "question"))
+	if result.Error != nil {
+		t.Fatalf("Run() error = %v", result.Error)
+	}
+	if result.Message.Review() != nil {
+		t.Error("response skipping the review has a review")
+	}
+	if got := len(f.fake.Requests()); got != 1 {
+		t.Errorf("made %d requests, want 1", got)
+	}
+}
+
+func TestRunReviewOverBudget(t *testing.T) {
+	f := newRegenerateFixture(t,
+		provider.FakeResponse{Content: "long draft", Usage: provider.TokenUsage{OutputTokens: 500}},
+	)
+	enableReview(t, config.ReviewFlow{Enabled: true, PassTokenBudget: 100})
+	f.add(t, message.User, message.TextContent{Text: "hello"})
+	f.add(t, message.Assistant, message.TextContent{Text: "hi"})
+
+	result := wait(f.agent.Run(context.Background(), f.session.ID, "question"))
+	if result.Error != nil {
+		t.Fatalf("Run() error = %v", result.Error)
+	}
+	if result.Message.Content().String() != "long draft" || result.Message.Review() != nil {
+		t.Errorf("response = %+v, want the draft without review", result.Message)
+	}
+}
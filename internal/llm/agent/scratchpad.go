@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"slices"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/llm/tools"
+	"github.com/caronex/intelligence-interface/internal/message"
+	"github.com/caronex/intelligence-interface/internal/scratchpad"
+)
+
+// withScratchpad returns the conversation history with the scratchpad of the
+// session before the text of the last user message. It is added to every
+// request rather than stored, so the model always sees its current revision.
+func withScratchpad(sessionID string, msgs []message.Message) []message.Message {
+	cfg := config.Get()
+	if cfg == nil || cfg.Scratchpad.Disabled {
+		return msgs
+	}
+	block := scratchpad.PromptContext(sessionID, cfg.Scratchpad.PromptBudget())
+	if block == "" {
+		return msgs
+	}
+	target := -1
+	for i, msg := range msgs {
+		if msg.Role == message.User {
+			target = i
+		}
+	}
+	if target == -1 {
+		return msgs
+	}
+
+	msgs = slices.Clone(msgs)
+	msg := msgs[target]
+	i := slices.IndexFunc(msg.Parts, func(part message.ContentPart) bool {
+		_, ok := part.(message.TextContent)
+		return ok
+	})
+	if i == -1 {
+		msg.Parts = append([]message.ContentPart{message.TextContent{Text: block}}, msg.Parts...)
+	} else {
+		msg.Parts = slices.Clone(msg.Parts)
+		msg.Parts[i] = message.TextContent{Text: block + "\n\n" + msg.Content().Text}
+	}
+	msgs[target] = msg
+	return msgs
+}
+
+// withoutScratchpadCalls returns the conversation history without the calls
+// of the scratchpad tools and their results, which summaries leave out as
+// the scratchpad is kept apart from them
+func withoutScratchpadCalls(msgs []message.Message) []message.Message {
+	isScratchpad := func(name string) bool {
+		return name == tools.ScratchpadReadToolName || name == tools.ScratchpadUpdateToolName
+	}
+	dropped := make(map[string]bool)
+	for _, msg := range msgs {
+		for _, call := range msg.ToolCalls() {
+			if isScratchpad(call.Name) {
+				dropped[call.ID] = true
+			}
+		}
+	}
+	if len(dropped) == 0 {
+		return msgs
+	}
+
+	kept := make([]message.Message, 0, len(msgs))
+	for _, msg := range msgs {
+		parts := slices.DeleteFunc(slices.Clone(msg.Parts), func(part message.ContentPart) bool {
+			switch part := part.(type) {
+			case message.ToolCall:
+				return dropped[part.ID]
+			case message.ToolResult:
+				return dropped[part.ToolCallID]
+			}
+			return false
+		})
+		if len(parts) == len(msg.Parts) {
+			kept = append(kept, msg)
+			continue
+		}
+		msg.Parts = parts
+		if len(msg.ToolCalls()) == 0 && len(msg.ToolResults()) == 0 && msg.Content().Text == "" {
+			continue
+		}
+		kept = append(kept, msg)
+	}
+	return kept
+}
@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/caronex/intelligence-interface/internal/llm/provider"
+	"github.com/caronex/intelligence-interface/internal/message"
+	"github.com/caronex/intelligence-interface/internal/scratchpad"
+)
+
+func TestScratchpadSentWithEveryRequest(t *testing.T) {
+	f := newRegenerateFixture(t,
+		provider.FakeResponse{Content: "first"},
+		provider.FakeResponse{Content: "second"},
+	)
+	f.add(t, message.User, message.TextContent{Text: "hi"})
+	f.add(t, message.Assistant, message.TextContent{Text: "hello"})
+	if _, err := scratchpad.Replace(f.session.ID, "caronex", "Todo", "- [ ] parse"); err != nil {
+		t.Fatalf("Replace() error = %v", err)
+	}
+
+	if result := wait(f.agent.Run(context.Background(), f.session.ID, "next")); result.Error != nil {
+		t.Fatalf("Run() error = %v", result.Error)
+	}
+	request := f.fake.Requests()[0]
+	got := request[len(request)-1].Content().String()
+	if !strings.HasPrefix(got, "<scratchpad>") || !strings.Contains(got, "- [ ] parse") || !strings.HasSuffix(got, "next") {
+		t.Fatalf("user message sent = %q, want the scratchpad before it", got)
+	}
+
+	// The next request has the current revision, on its own message only
+	if _, err := scratchpad.Patch(f.session.ID, "caronex", "Todo", "[ ]", "[x]"); err != nil {
+		t.Fatalf("Patch() error = %v", err)
+	}
+	if result := wait(f.agent.Run(context.Background(), f.session.ID, "again")); result.Error != nil {
+		t.Fatalf("Run() error = %v", result.Error)
+	}
+	request = f.fake.Requests()[1]
+	if earlier := request[2].Content().String(); earlier != "next" {
+		t.Errorf("earlier message sent = %q, want it without the scratchpad", earlier)
+	}
+	if last := request[len(request)-1].Content().String(); !strings.Contains(last, "- [x] parse") {
+		t.Errorf("user message sent = %q, want the current revision", last)
+	}
+}
+
+func TestWithoutScratchpadCalls(t *testing.T) {
+	msgs := []message.Message{
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "fix the lexer"}}},
+		{Role: message.Assistant, Parts: []message.ContentPart{
+			message.ToolCall{ID: "pad", Name: "scratchpad_update"},
+		}},
+		{Role: message.Tool, Parts: []message.ContentPart{message.ToolResult{ToolCallID: "pad", Content: "Scratchpad updated"}}},
+		{Role: message.Assistant, Parts: []message.ContentPart{
+			message.TextContent{Text: "Reading it"},
+			message.ToolCall{ID: "pad-2", Name: "scratchpad_read"},
+			message.ToolCall{ID: "view", Name: "view"},
+		}},
+		{Role: message.Tool, Parts: []message.ContentPart{
+			message.ToolResult{ToolCallID: "pad-2", Content: "## Todo"},
+			message.ToolResult{ToolCallID: "view", Content: "package lexer"},
+		}},
+	}
+
+	kept := withoutScratchpadCalls(msgs)
+	if len(kept) != 3 {
+		t.Fatalf("kept %d messages, want the 3 with more than scratchpad calls", len(kept))
+	}
+	if calls := kept[1].ToolCalls(); len(calls) != 1 || calls[0].ID != "view" || kept[1].Content().Text != "Reading it" {
+		t.Errorf("assistant message kept = %+v, want its text and other calls", kept[1].Parts)
+	}
+	if results := kept[2].ToolResults(); len(results) != 1 || results[0].ToolCallID != "view" {
+		t.Errorf("tool results kept = %+v, want the other results", results)
+	}
+	if calls := msgs[3].ToolCalls(); len(calls) != 2 {
+		t.Errorf("original message changed to %+v", msgs[3].Parts)
+	}
+}
@@ -0,0 +1,160 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/llm/provider"
+	"github.com/caronex/intelligence-interface/internal/llm/tools"
+	"github.com/caronex/intelligence-interface/internal/message"
+	"github.com/caronex/intelligence-interface/internal/session"
+)
+
+// sessionSettings are the settings a session runs the agent with, set from
+// the defaults of its space when it was created
+type sessionSettings struct {
+	// agent is the agent whose prompt and configuration the session runs
+	// with, the agent itself when empty or not configured
+	agent      config.AgentName
+	model      models.ModelID
+	generation *config.GenerationParams
+	// allowedTools are the only tools offered to the model, all of them when
+	// empty
+	allowedTools []string
+}
+
+// sessionSettings returns the settings of a session, none for a new session
+func (a *agent) sessionSettings(ctx context.Context, sessionID string) (sessionSettings, error) {
+	if sessionID == "" {
+		return sessionSettings{}, nil
+	}
+	sess, err := a.sessions.Get(ctx, sessionID)
+	if err != nil {
+		return sessionSettings{}, fmt.Errorf("failed to get session: %w", err)
+	}
+	return settingsOf(sess), nil
+}
+
+func settingsOf(sess session.Session) sessionSettings {
+	return sessionSettings{
+		agent:        config.AgentName(sess.Agent),
+		model:        models.ModelID(sess.Model),
+		generation:   sess.Generation,
+		allowedTools: sess.AllowedTools,
+	}
+}
+
+// runProvider returns the provider generating the responses of a session: the
+// agent provider, or one created for the run when the session runs with
+// another agent, model or generation parameters, or the message leaves out
+// sources of context. An empty modelID is the model of the session.
+func (a *agent) runProvider(cfg *config.Config, settings sessionSettings, modelID models.ModelID, sources *message.ContextSources) (provider.Provider, error) {
+	if cfg == nil {
+		return a.provider, nil
+	}
+	agentName := a.name
+	if _, ok := cfg.Agents[settings.agent]; ok {
+		agentName = settings.agent
+	}
+	if modelID == "" {
+		modelID = a.provider.Model().ID
+		if agentName != a.name {
+			modelID = cfg.Agents[agentName].Model
+		}
+		if _, ok := models.SupportedModels[settings.model]; ok {
+			modelID = settings.model
+		}
+	}
+	opts := withoutContext(agentName, sources, models.SupportedModels[modelID].Provider)
+	if settings.generation != nil {
+		opts = append(opts, provider.WithGenerationParams(mergeGeneration(cfg.Agents[agentName].Generation, *settings.generation)))
+	}
+	if agentName == a.name && modelID == a.provider.Model().ID && len(opts) == 0 {
+		return a.provider, nil
+	}
+	return createAgentProviderForModel(cfg, agentName, modelID, opts...)
+}
+
+// mergeGeneration returns the generation parameters of an agent with the ones
+// set by a session in their place
+func mergeGeneration(agent *config.GenerationParams, session config.GenerationParams) config.GenerationParams {
+	var merged config.GenerationParams
+	if agent != nil {
+		merged = *agent
+	}
+	if session.Temperature != nil {
+		merged.Temperature = session.Temperature
+	}
+	if session.TopP != nil {
+		merged.TopP = session.TopP
+	}
+	if session.FrequencyPenalty != nil {
+		merged.FrequencyPenalty = session.FrequencyPenalty
+	}
+	if session.PresencePenalty != nil {
+		merged.PresencePenalty = session.PresencePenalty
+	}
+	if len(session.Stop) > 0 {
+		merged.Stop = session.Stop
+	}
+	return merged
+}
+
+// allowedTools returns the tools among agentTools a session allows, all of
+// them when it allows every tool
+func allowedTools(agentTools []tools.BaseTool, allowed []string) []tools.BaseTool {
+	if len(allowed) == 0 {
+		return agentTools
+	}
+	var kept []tools.BaseTool
+	for _, tool := range agentTools {
+		if slices.Contains(allowed, tool.Info().Name) {
+			kept = append(kept, tool)
+		}
+	}
+	return kept
+}
+
+// checkSessionDefaults fails when the session defaults of a space allow tools
+// the agent does not have or leave out sources of context its prompt does not
+// know
+func (a *agent) checkSessionDefaults(cfg *config.Config) error {
+	toolNames := make([]string, len(a.tools))
+	for i, tool := range a.tools {
+		toolNames[i] = tool.Info().Name
+	}
+	spaceIDs := make([]string, 0, len(cfg.Spaces))
+	for spaceID := range cfg.Spaces {
+		spaceIDs = append(spaceIDs, spaceID)
+	}
+	sort.Strings(spaceIDs)
+	for _, spaceID := range spaceIDs {
+		defaults := cfg.Spaces[spaceID].DefaultSession
+		if defaults == nil {
+			continue
+		}
+		for _, name := range defaults.Tools {
+			if !slices.Contains(toolNames, name) {
+				return &config.ValidationError{
+					Field:  fmt.Sprintf("spaces.%s.default_session.tools", spaceID),
+					Value:  name,
+					Reason: "unknown tool",
+				}
+			}
+		}
+		for _, source := range defaults.ExcludedContext {
+			if !slices.Contains(message.KnownContextSources, message.ContextSource(source)) {
+				return &config.ValidationError{
+					Field:  fmt.Sprintf("spaces.%s.default_session.excluded_context", spaceID),
+					Value:  source,
+					Reason: "unknown source of context",
+				}
+			}
+		}
+	}
+	return nil
+}
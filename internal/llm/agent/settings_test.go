@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/llm/provider"
+	"github.com/caronex/intelligence-interface/internal/llm/tools"
+	"github.com/caronex/intelligence-interface/internal/message"
+)
+
+func TestSessionAllowedTools(t *testing.T) {
+	f := newRegenerateFixture(t,
+		provider.FakeResponse{ToolCalls: []message.ToolCall{{ID: "call-1", Name: "kb_search", Input: "{}"}}},
+		provider.FakeResponse{Content: "done"},
+	)
+	f.agent.(*agent).tools = []tools.BaseTool{sourceTool{}}
+	f.add(t, message.User, message.TextContent{Text: "hi"})
+	f.add(t, message.Assistant, message.TextContent{Text: "hello"})
+	f.session.AllowedTools = []string{"view"}
+	if _, err := f.sessions.Save(context.Background(), f.session); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	if result := wait(f.agent.Run(context.Background(), f.session.ID, "search")); result.Error != nil {
+		t.Fatalf("Run() error = %v", result.Error)
+	}
+	var result *message.ToolResult
+	for _, msg := range f.list(t, f.session.ID) {
+		if results := msg.ToolResults(); len(results) > 0 {
+			result = &results[0]
+		}
+	}
+	if result == nil || !result.IsError || !strings.Contains(result.Content, "Tool not found") {
+		t.Errorf("tool result = %+v, want the tool the session does not allow not found", result)
+	}
+}
+
+func TestCheckSessionDefaults(t *testing.T) {
+	f := newRegenerateFixture(t)
+	a := f.agent.(*agent)
+	a.tools = []tools.BaseTool{sourceTool{}}
+	cfg := config.Get()
+
+	cfg.Spaces = map[string]config.SpaceConfig{
+		"ops": {ID: "ops", DefaultSession: &config.SessionDefaults{Tools: []string{"kb_search"}, ExcludedContext: []string{"memory_notes"}}},
+	}
+	if err := a.checkSessionDefaults(cfg); err != nil {
+		t.Errorf("checkSessionDefaults() error = %v", err)
+	}
+	cfg.Spaces["ops"] = config.SpaceConfig{ID: "ops", DefaultSession: &config.SessionDefaults{Tools: []string{"kb_search", "deploy"}}}
+	if err := a.checkSessionDefaults(cfg); err == nil || !strings.Contains(err.Error(), `spaces.ops.default_session.tools: unknown tool "deploy"`) {
+		t.Errorf("checkSessionDefaults() error = %v, want the unknown tool", err)
+	}
+	cfg.Spaces["ops"] = config.SpaceConfig{ID: "ops", DefaultSession: &config.SessionDefaults{ExcludedContext: []string{"readme"}}}
+	if err := a.checkSessionDefaults(cfg); err == nil || !strings.Contains(err.Error(), "unknown source of context") {
+		t.Errorf("checkSessionDefaults() error = %v, want the unknown source of context", err)
+	}
+}
+
+func TestMergeGeneration(t *testing.T) {
+	low, high, topP := 0.2, 1.4, 0.9
+	merged := mergeGeneration(&config.GenerationParams{Temperature: &low, TopP: &topP}, config.GenerationParams{Temperature: &high})
+	if merged.Temperature == nil || *merged.Temperature != high {
+		t.Errorf("merged temperature = %v, want the session's %g", merged.Temperature, high)
+	}
+	if merged.TopP == nil || *merged.TopP != topP {
+		t.Errorf("merged top_p = %v, want the agent's %g", merged.TopP, topP)
+	}
+}
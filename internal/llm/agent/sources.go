@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/llm/prompt"
+	"github.com/caronex/intelligence-interface/internal/llm/provider"
+	"github.com/caronex/intelligence-interface/internal/message"
+)
+
+type excludedContextContextKey struct{}
+
+// WithExcludedContext returns a context in which the message sent leaves the
+// excluded sources of context out of the system prompt, instead of the
+// sources its session excludes by default. No excluded source sends the
+// message with every source.
+func WithExcludedContext(ctx context.Context, excluded []message.ContextSource) context.Context {
+	return context.WithValue(ctx, excludedContextContextKey{}, slices.Clone(excluded))
+}
+
+func excludedContext(ctx context.Context) ([]message.ContextSource, bool) {
+	excluded, ok := ctx.Value(excludedContextContextKey{}).([]message.ContextSource)
+	return excluded, ok
+}
+
+type contextSourcesContextKey struct{}
+
+// withContextSources returns a context recording the sources of context a
+// message is sent with, nil when the agent prompt has none
+func withContextSources(ctx context.Context, sources *message.ContextSources) context.Context {
+	return context.WithValue(ctx, contextSourcesContextKey{}, sources)
+}
+
+func contextSources(ctx context.Context) *message.ContextSources {
+	sources, _ := ctx.Value(contextSourcesContextKey{}).(*message.ContextSources)
+	return sources
+}
+
+// contextExcluded reports whether the message of ctx is sent without source
+func contextExcluded(ctx context.Context, source message.ContextSource) bool {
+	sources := contextSources(ctx)
+	return sources != nil && slices.Contains(sources.Excluded, source)
+}
+
+// ContextSources returns the sources of context the system prompt of the
+// agent includes when none is excluded
+func (a *agent) ContextSources() []message.ContextSource {
+	if config.Get() == nil {
+		return nil
+	}
+	return prompt.ContextSources(a.name)
+}
+
+// resolveContextSources returns the sources of context a message sent to a
+// session is sent with: among the sources of the agent prompt, the ones the
+// message excludes, or else the ones the session excludes by default
+func (a *agent) resolveContextSources(ctx context.Context, sessionID string) (*message.ContextSources, error) {
+	excluded, ok := excludedContext(ctx)
+	if !ok && sessionID != "" {
+		session, err := a.sessions.Get(ctx, sessionID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get session: %w", err)
+		}
+		for _, source := range session.ExcludedContext {
+			excluded = append(excluded, message.ContextSource(source))
+		}
+	}
+	return splitContextSources(a.ContextSources(), excluded), nil
+}
+
+// splitContextSources splits the available sources of context between the
+// included and the excluded ones, nil when none is available
+func splitContextSources(available, excluded []message.ContextSource) *message.ContextSources {
+	if len(available) == 0 {
+		return nil
+	}
+	sources := &message.ContextSources{}
+	for _, source := range available {
+		if slices.Contains(excluded, source) {
+			sources.Excluded = append(sources.Excluded, source)
+		} else {
+			sources.Included = append(sources.Included, source)
+		}
+	}
+	return sources
+}
+
+// withoutContext returns the options creating a provider whose system prompt,
+// the one of agentName, leaves out the excluded sources of context, none when
+// nothing is excluded
+func withoutContext(agentName config.AgentName, sources *message.ContextSources, modelProvider models.ModelProvider) []provider.ProviderClientOption {
+	if sources == nil || len(sources.Excluded) == 0 {
+		return nil
+	}
+	return []provider.ProviderClientOption{
+		provider.WithSystemMessage(prompt.GetAgentPromptWithout(agentName, modelProvider, sources.Excluded)),
+	}
+}
+
+// contextNames lists sources of context in the trace of a turn
+func contextNames(sources []message.ContextSource) string {
+	if len(sources) == 0 {
+		return "none"
+	}
+	names := make([]string, len(sources))
+	for i, source := range sources {
+		names[i] = string(source)
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/llm/provider"
+	"github.com/caronex/intelligence-interface/internal/message"
+	"github.com/caronex/intelligence-interface/internal/notes"
+)
+
+func TestExcludedContextLeftOutOfRequest(t *testing.T) {
+	f := newRegenerateFixture(t,
+		provider.FakeResponse{Content: "with notes"},
+		provider.FakeResponse{Content: "without notes"},
+		provider.FakeResponse{Content: "retried without notes"},
+		provider.FakeResponse{Content: "session default"},
+	)
+	const note = "Releases are tagged from main"
+	if _, err := notes.Add(notes.Note{Content: note}); err != nil {
+		t.Fatalf("notes.Add() error = %v", err)
+	}
+	// The agent prompt includes the note from now on
+	var err error
+	if f.agent, err = NewAgent(config.AgentCaronex, f.sessions, f.messages, nil); err != nil {
+		t.Fatalf("NewAgent() error = %v", err)
+	}
+	if !slices.Contains(f.agent.ContextSources(), message.MemoryNotes) {
+		t.Fatalf("ContextSources() = %v, want the memory notes", f.agent.ContextSources())
+	}
+	f.add(t, message.User, message.TextContent{Text: "hi"})
+	f.add(t, message.Assistant, message.TextContent{Text: "hello"})
+
+	lastSystem := func() string {
+		systems := f.fake.Systems()
+		return systems[len(systems)-1]
+	}
+	if result := wait(f.agent.Run(context.Background(), f.session.ID, "with")); result.Error != nil {
+		t.Fatalf("Run() error = %v", result.Error)
+	}
+	if !strings.Contains(lastSystem(), note) {
+		t.Fatalf("system message = %q, want the memory notes", lastSystem())
+	}
+
+	ctx := WithExcludedContext(context.Background(), []message.ContextSource{message.MemoryNotes})
+	if result := wait(f.agent.Run(ctx, f.session.ID, "without")); result.Error != nil {
+		t.Fatalf("Run() error = %v", result.Error)
+	}
+	if strings.Contains(lastSystem(), note) {
+		t.Errorf("system message = %q, want the memory notes left out", lastSystem())
+	}
+	msgs := f.list(t, f.session.ID)
+	sources := msgs[len(msgs)-2].ContextSources()
+	if sources == nil || !slices.Contains(sources.Excluded, message.MemoryNotes) || slices.Contains(sources.Included, message.MemoryNotes) {
+		t.Errorf("ContextSources() of the message sent = %+v, want the memory notes excluded", sources)
+	}
+
+	// A retry is sent with the sources the message was sent with
+	if result := wait(f.agent.Retry(context.Background(), f.session.ID, RetryOptions{})); result.Error != nil {
+		t.Fatalf("Retry() error = %v", result.Error)
+	}
+	if strings.Contains(lastSystem(), note) {
+		t.Errorf("retried system message = %q, want the memory notes left out", lastSystem())
+	}
+
+	// The session leaves out its default exclusions
+	f.session.ExcludedContext = []string{string(message.MemoryNotes)}
+	if _, err := f.sessions.Save(context.Background(), f.session); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if result := wait(f.agent.Run(context.Background(), f.session.ID, "default")); result.Error != nil {
+		t.Fatalf("Run() error = %v", result.Error)
+	}
+	if strings.Contains(lastSystem(), note) {
+		t.Errorf("system message = %q, want the session default to leave the memory notes out", lastSystem())
+	}
+}
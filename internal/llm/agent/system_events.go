@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/tools/coordination"
+)
+
+// HandleSystemEvent handles the system events broadcast to the agents: on a
+// configuration change the agent re-reads its prompt configuration, once its
+// requests in progress complete
+func (a *agent) HandleSystemEvent(ctx context.Context, event coordination.SystemEvent) {
+	logger := logging.FromContext(ctx)
+	switch event.Type {
+	case coordination.SystemEventConfigChanged:
+		a.reloadPending.Store(true)
+		if !a.IsBusy() {
+			a.reloadPrompt()
+		}
+	default:
+		logger.Debug("Ignoring system event", "agent", a.name, "type", event.Type)
+	}
+}
+
+// reloadPrompt creates the provider of the agent again when a reload is
+// pending, so that it gets the current prompt configuration
+func (a *agent) reloadPrompt() {
+	if !a.reloadPending.Swap(false) {
+		return
+	}
+	provider, err := createAgentProviderForModel(config.Get(), a.name, a.provider.Model().ID)
+	if err != nil {
+		logging.Warn("failed to reload the prompt configuration", "agent", a.name, "error", err)
+		return
+	}
+	if err := a.provider.Close(); err != nil {
+		logging.Warn("failed to close the previous provider", "error", err)
+	}
+	a.provider = provider
+	logging.Info("Reloaded the prompt configuration", "agent", a.name)
+}
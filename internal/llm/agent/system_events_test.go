@@ -0,0 +1,37 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/caronex/intelligence-interface/internal/llm/provider"
+	"github.com/caronex/intelligence-interface/internal/tools/coordination"
+)
+
+func TestHandleSystemEventReloadsPrompt(t *testing.T) {
+	f := newRegenerateFixture(t)
+	a := f.agent.(*agent)
+	// The providers created from now on are new fakes
+	t.Cleanup(provider.InstallFake(nil))
+	configChanged := coordination.SystemEvent{Type: coordination.SystemEventConfigChanged}
+
+	// A busy agent reloads once its requests complete
+	a.activeRequests.Store(f.session.ID, context.CancelFunc(func() {}))
+	before := a.provider
+	a.HandleSystemEvent(context.Background(), configChanged)
+	if a.provider != before || !a.reloadPending.Load() {
+		t.Fatal("the busy agent reloaded its prompt")
+	}
+	a.activeRequests.Delete(f.session.ID)
+
+	a.HandleSystemEvent(context.Background(), configChanged)
+	if a.provider == before || a.reloadPending.Load() {
+		t.Error("the agent did not reload its prompt")
+	}
+
+	reloaded := a.provider
+	a.HandleSystemEvent(context.Background(), coordination.SystemEvent{Type: "unknown"})
+	if a.provider != reloaded {
+		t.Error("an unknown event reloaded the prompt")
+	}
+}
@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/caronex/intelligence-interface/internal/batch"
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/llm/prompt"
+	"github.com/caronex/intelligence-interface/internal/llm/provider"
+	"github.com/caronex/intelligence-interface/internal/message"
+	"github.com/caronex/intelligence-interface/internal/session"
+)
+
+// TitleBatch is the batch component regenerating the titles of sessions
+const TitleBatch = "titles"
+
+// titleMaxTokens bounds the titles generated in batches
+const titleMaxTokens = 80
+
+// RegisterBatchComponents registers the jobs of the agents that opted into
+// batch mode, so the batches they submitted are delivered to them
+func RegisterBatchComponents(batches batch.Service, sessions session.Service) {
+	batches.Register(TitleBatch, batch.Component{
+		Provider: titleBatchProvider,
+		Deliver: func(ctx context.Context, item batch.Item) error {
+			return deliverTitle(ctx, sessions, item)
+		},
+	})
+}
+
+// titleBatchProvider returns the provider generating titles in batches: the
+// model of Caronex with the title prompt
+func titleBatchProvider() (provider.Provider, error) {
+	cfg := config.Get()
+	agentConfig, ok := cfg.Agents[config.AgentCaronex]
+	if !ok {
+		return nil, fmt.Errorf("agent %s not found", config.AgentCaronex)
+	}
+	model, ok := models.SupportedModels[agentConfig.Model]
+	if !ok {
+		return nil, fmt.Errorf("model %s not supported", agentConfig.Model)
+	}
+	return provider.DefaultProviderFactory.NewProvider(model.ID, cfg.Providers[model.Provider],
+		provider.WithSystemMessage(prompt.TitlePrompt(model.Provider)),
+		provider.WithMaxTokens(titleMaxTokens),
+	)
+}
+
+// deliverTitle sets the title of the session of a request, the failures
+// being left to the batch status
+func deliverTitle(ctx context.Context, sessions session.Service, item batch.Item) error {
+	if item.Status != batch.ItemSucceeded {
+		logging.Warn("Failed to regenerate the title of a session", "session", item.Payload, "error", item.Error)
+		return nil
+	}
+	title := strings.TrimSpace(strings.ReplaceAll(item.Content, "\n", " "))
+	if title == "" {
+		return fmt.Errorf("the model returned an empty title")
+	}
+	sess, err := sessions.Get(ctx, item.Payload)
+	if err != nil {
+		return err
+	}
+	sess.Title = title
+	_, err = sessions.Save(ctx, sess)
+	return err
+}
+
+// RegenerateTitles submits a batch regenerating the titles of sessions from
+// their first user message, leaving out the sessions without one
+func RegenerateTitles(ctx context.Context, batches batch.Service, messages message.Service, sessions []session.Session) (batch.Batch, error) {
+	var requests []batch.Request
+	for _, sess := range sessions {
+		msgs, err := messages.List(ctx, sess.ID)
+		if err != nil {
+			return batch.Batch{}, fmt.Errorf("failed to list the messages of session %s: %w", sess.ID, err)
+		}
+		for _, msg := range msgs {
+			if text := msg.Content().Text; msg.Role == message.User && text != "" {
+				requests = append(requests, batch.Request{ID: sess.ID, Payload: sess.ID, Prompt: text})
+				break
+			}
+		}
+	}
+	if len(requests) == 0 {
+		return batch.Batch{}, fmt.Errorf("none of the sessions has a user message to title")
+	}
+	return batches.Submit(ctx, TitleBatch, requests)
+}
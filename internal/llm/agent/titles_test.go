@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/caronex/intelligence-interface/internal/batch"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/llm/prompt"
+	"github.com/caronex/intelligence-interface/internal/llm/provider"
+	"github.com/caronex/intelligence-interface/internal/message"
+	"github.com/caronex/intelligence-interface/internal/session"
+)
+
+func TestRegenerateTitles(t *testing.T) {
+	f := newRegenerateFixture(t, provider.FakeResponse{Content: " Fix the\nparser crash "})
+	f.add(t, message.User, message.TextContent{Text: "the parser crashes on empty files"})
+	f.add(t, message.Assistant, message.TextContent{Text: "Looking into it"})
+	ctx := context.Background()
+	empty, err := f.sessions.Create(ctx, "Empty")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	batches := batch.NewService(f.q)
+	RegisterBatchComponents(batches, f.sessions)
+	if _, err := RegenerateTitles(ctx, batches, f.messages, []session.Session{empty}); err == nil {
+		t.Error("RegenerateTitles() submitted a batch without user messages")
+	}
+	submitted, err := RegenerateTitles(ctx, batches, f.messages, []session.Session{f.session, empty})
+	if err != nil {
+		t.Fatalf("RegenerateTitles() error = %v", err)
+	}
+	if len(submitted.Items) != 1 || submitted.Items[0].Prompt != "the parser crashes on empty files" {
+		t.Fatalf("RegenerateTitles() = %+v, want the first user message of the session", submitted.Items)
+	}
+
+	if err := batches.Poll(ctx); err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+	sess, err := f.sessions.Get(ctx, f.session.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if sess.Title != "Fix the parser crash" {
+		t.Errorf("title = %q, want the generated title", sess.Title)
+	}
+	systems := f.fake.Systems()
+	if len(systems) != 1 || systems[0] != prompt.TitlePrompt(models.ProviderTest) {
+		t.Errorf("titles generated with system messages %q, want the title prompt", systems)
+	}
+}
@@ -3,6 +3,7 @@ package agent
 import (
 	"context"
 
+	"github.com/caronex/intelligence-interface/internal/analytics"
 	"github.com/caronex/intelligence-interface/internal/core/config"
 	"github.com/caronex/intelligence-interface/internal/history"
 	"github.com/caronex/intelligence-interface/internal/llm/tools"
@@ -23,25 +24,33 @@ func CaronexAgentTools(
 	lspClients map[string]*lsp.Client,
 ) []tools.BaseTool {
 	ctx := context.Background()
-	otherTools := GetMcpTools(ctx, permissions)
+	builtinTools := []tools.BaseTool{
+		tools.NewBashTool(permissions),
+		tools.NewEditTool(lspClients, permissions, history),
+		tools.NewFetchTool(permissions),
+		tools.NewGlobTool(),
+		tools.NewGrepTool(),
+		tools.NewLsTool(),
+		tools.NewSourcegraphTool(),
+		tools.NewViewTool(lspClients),
+		tools.NewPatchTool(lspClients, permissions, history),
+		tools.NewWriteTool(lspClients, permissions, history),
+		NewAgentTool(sessions, messages, lspClients),
+	}
 	if len(lspClients) > 0 {
-		otherTools = append(otherTools, tools.NewDiagnosticsTool(lspClients))
+		builtinTools = append(builtinTools, tools.NewDiagnosticsTool(lspClients))
 	}
-	return append(
-		[]tools.BaseTool{
-			tools.NewBashTool(permissions),
-			tools.NewEditTool(lspClients, permissions, history),
-			tools.NewFetchTool(permissions),
-			tools.NewGlobTool(),
-			tools.NewGrepTool(),
-			tools.NewLsTool(),
-			tools.NewSourcegraphTool(),
-			tools.NewViewTool(lspClients),
-			tools.NewPatchTool(lspClients, permissions, history),
-			tools.NewWriteTool(lspClients, permissions, history),
-			NewAgentTool(sessions, messages, lspClients),
-		}, otherTools...,
-	)
+	if cfg := config.Get(); cfg != nil && !cfg.Notes.Disabled {
+		builtinTools = append(builtinTools, tools.NewMemoryReadTool(), tools.NewMemoryWriteTool(permissions))
+	}
+	if cfg := config.Get(); cfg != nil && !cfg.Scratchpad.Disabled {
+		builtinTools = append(builtinTools, tools.NewScratchpadReadTool(), tools.NewScratchpadUpdateTool())
+	}
+	if cfg := config.Get(); cfg != nil && cfg.Caronex.Learning.Enabled {
+		builtinTools = append(builtinTools, tools.NewLearnTool())
+	}
+	// Builtin tools take precedence over MCP tools registered under the same name
+	return tools.ResolveTools(builtinTools, GetMcpTools(ctx, permissions))
 }
 
 // ManagerAgentTools returns specialized tools for Caronex manager agent
@@ -72,5 +81,45 @@ func ManagerAgentTools() []tools.BaseTool {
 		tools.NewViewTool(nil), // For reading system files (no LSP needed)
 	}
 
+	if !cfg.Scratchpad.Disabled {
+		basicTools = append(basicTools, tools.NewScratchpadReadTool(), tools.NewScratchpadUpdateTool())
+	}
+	if cfg.Caronex.Learning.Enabled {
+		basicTools = append(basicTools, tools.NewLearnTool())
+	}
+
 	return append(managementTools, basicTools...)
 }
+
+// userToolNames are the tools the user may run directly from the tool palette
+// of the TUI
+var userToolNames = []string{
+	"system_introspection",
+	"configuration_inspection",
+	"usage_report",
+	"doctor_checks",
+	"space_foundation",
+}
+
+// UserTools returns the tools the user may run directly, outside of any agent:
+// the whitelisted management and report tools, the destructive ones left out
+func UserTools(stats analytics.Service) []tools.BaseTool {
+	cfg := config.Get()
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	candidates := append(ManagerAgentTools(),
+		builtin.NewUsageReportTool(cfg, stats),
+		builtin.NewDoctorChecksTool(cfg),
+	)
+
+	var userTools []tools.BaseTool
+	for _, name := range userToolNames {
+		for _, tool := range candidates {
+			if info := tool.Info(); info.Name == name && !info.Destructive {
+				userTools = append(userTools, tool)
+			}
+		}
+	}
+	return userTools
+}
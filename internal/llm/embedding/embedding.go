@@ -0,0 +1,215 @@
+// Package embedding turns the texts of the knowledge base into vectors with
+// the provider configured globally or for a space: OpenAI, Azure OpenAI,
+// Gemini, or a local hashing embedder needing neither a key nor a network.
+package embedding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/analytics"
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/llm/provider"
+	"github.com/caronex/intelligence-interface/internal/llm/tokens"
+)
+
+// maxRetries is the number of times a batch rejected by the rate limit of
+// the provider is sent again
+const maxRetries = 5
+
+// requestTimeout bounds a request for the embeddings of one batch
+const requestTimeout = time.Minute
+
+// Embedder turns texts into vectors whose cosine similarity reflects how
+// close their meanings are
+type Embedder interface {
+	// EmbedBatch returns the vectors of texts, in the same order. The texts
+	// are split in batches paced under the rate limit of the provider.
+	EmbedBatch(ctx context.Context, texts []string) ([][]float32, error)
+	// Dimensions is the length of the vectors
+	Dimensions() int
+	// ModelID identifies the provider and model the vectors come from, as
+	// vectors of different models cannot be compared
+	ModelID() string
+}
+
+// backend requests the embeddings of a single batch from a provider,
+// returning the tokens it used
+type backend interface {
+	embed(ctx context.Context, texts []string) ([][]float32, int64, error)
+}
+
+// ForSpace returns the embedder configured for a space, with the global
+// settings when the space has none of its own
+func ForSpace(spaceID string) (Embedder, error) {
+	cfg := config.Get()
+	if cfg == nil {
+		return nil, fmt.Errorf("config not loaded")
+	}
+	settings := cfg.EmbeddingsFor(spaceID)
+	return New(settings, cfg.Providers[settings.ModelProvider()].APIKey)
+}
+
+// New returns the embedder of settings validated at load, requesting the
+// embeddings of a remote provider with apiKey
+func New(settings config.EmbeddingsConfig, apiKey string) (Embedder, error) {
+	if settings.Dimensions <= 0 {
+		return nil, fmt.Errorf("the dimensions of the %s embeddings are not set", ModelID(settings))
+	}
+	var b backend
+	var err error
+	switch settings.Provider {
+	case config.EmbeddingProviderOpenAI:
+		b, err = newOpenAIBackend(settings, apiKey)
+	case config.EmbeddingProviderAzure:
+		b, err = newAzureBackend(settings, apiKey)
+	case config.EmbeddingProviderGemini:
+		b, err = newGeminiBackend(settings, apiKey)
+	case config.EmbeddingProviderLocal:
+		b = localBackend{dimensions: settings.Dimensions}
+	default:
+		return nil, fmt.Errorf("unknown embedding provider %q", settings.Provider)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newEmbedder(settings, b), nil
+}
+
+func newEmbedder(settings config.EmbeddingsConfig, b backend) *embedder {
+	return &embedder{
+		settings: settings,
+		backend:  b,
+		limiter:  limiterFor(settings.Provider, settings.RequestsPerMinute),
+	}
+}
+
+// ModelID returns the identifier of the vectors computed with settings
+func ModelID(settings config.EmbeddingsConfig) string {
+	return settings.Provider + "/" + settings.Model
+}
+
+// embedder splits texts in batches and sends them to its backend, paced by
+// the limiter of the provider and with the usage recorded apart from the
+// tokens of the sessions
+type embedder struct {
+	settings config.EmbeddingsConfig
+	backend  backend
+	limiter  *limiter
+}
+
+func (e *embedder) Dimensions() int {
+	return e.settings.Dimensions
+}
+
+func (e *embedder) ModelID() string {
+	return ModelID(e.settings)
+}
+
+func (e *embedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	batchSize := max(e.settings.BatchSize, 1)
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += batchSize {
+		batch, err := e.embedBatch(ctx, texts[start:min(start+batchSize, len(texts))])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+// embedBatch requests the vectors of one batch, waiting for the rate limit
+// of the provider when it rejects the batch
+func (e *embedder) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	for attempt := 1; ; attempt++ {
+		if err := e.limiter.wait(ctx); err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		requestCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+		vectors, used, err := e.backend.embed(requestCtx, texts)
+		cancel()
+		if err == nil {
+			err = e.checkVectors(texts, vectors)
+		}
+		e.record(time.Since(start), used, err)
+		if err == nil {
+			return vectors, nil
+		}
+
+		err = provider.Classify(models.Model{Provider: e.settings.ModelProvider(), APIModel: e.settings.Model}, err)
+		var providerErr *provider.ProviderError
+		if !errors.As(err, &providerErr) || providerErr.Category != provider.ErrorRateLimit || attempt > maxRetries {
+			return nil, fmt.Errorf("failed to embed %d texts with %s: %w", len(texts), e.ModelID(), err)
+		}
+		e.limiter.backoff(retryDelay(attempt, err))
+	}
+}
+
+// checkVectors rejects a response that does not hold one vector of the
+// configured dimensions per text, as storing it would corrupt the index
+func (e *embedder) checkVectors(texts []string, vectors [][]float32) error {
+	if len(vectors) != len(texts) {
+		return fmt.Errorf("%s returned %d vectors for %d texts", e.ModelID(), len(vectors), len(texts))
+	}
+	for _, vector := range vectors {
+		if len(vector) != e.settings.Dimensions {
+			return &DimensionError{Model: e.ModelID(), Want: e.settings.Dimensions, Got: len(vector)}
+		}
+	}
+	return nil
+}
+
+// record publishes the usage of a request, under the embedding kind so its
+// tokens are counted apart from the tokens of the sessions
+func (e *embedder) record(latency time.Duration, used int64, err error) {
+	analytics.Record(analytics.Event{
+		Kind:    analytics.KindEmbedding,
+		Name:    e.ModelID(),
+		Latency: latency,
+		Failed:  err != nil,
+		Tokens:  used,
+	})
+}
+
+// DimensionError is returned when a model returns vectors of another length
+// than configured, as it does when the dimensions are set for another model
+type DimensionError struct {
+	Model string
+	Want  int
+	Got   int
+}
+
+func (e *DimensionError) Error() string {
+	return fmt.Sprintf("%s returned vectors of %d dimensions, %d are configured: set the dimensions of the model in the embeddings config", e.Model, e.Got, e.Want)
+}
+
+// estimateTokens estimates the tokens of texts, for the providers that do
+// not report them
+func estimateTokens(texts []string) int64 {
+	var total int64
+	for _, text := range texts {
+		total += int64(tokens.Estimate(text))
+	}
+	return total
+}
+
+// Similarity returns the cosine similarity of two vectors of the same
+// length, 0 when one of them is null
+func Similarity(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := range min(len(a), len(b)) {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
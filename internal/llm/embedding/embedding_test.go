@@ -0,0 +1,207 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+)
+
+// contractEmbedders returns the embedders every implementation is checked
+// against, skipping the remote ones without credentials
+func contractEmbedders(t *testing.T) map[string]Embedder {
+	t.Helper()
+	settings := map[string]struct {
+		embeddings config.EmbeddingsConfig
+		apiKey     string
+		skip       bool
+	}{
+		"local":  {config.EmbeddingsConfig{Provider: config.EmbeddingProviderLocal}, "", false},
+		"openai": {config.EmbeddingsConfig{Provider: config.EmbeddingProviderOpenAI}, os.Getenv("OPENAI_API_KEY"), os.Getenv("OPENAI_API_KEY") == ""},
+		"azure": {
+			config.EmbeddingsConfig{Provider: config.EmbeddingProviderAzure, Model: os.Getenv("AZURE_OPENAI_EMBEDDING_DEPLOYMENT"), Dimensions: 1536},
+			os.Getenv("AZURE_OPENAI_API_KEY"),
+			os.Getenv("AZURE_OPENAI_ENDPOINT") == "" || os.Getenv("AZURE_OPENAI_EMBEDDING_DEPLOYMENT") == "",
+		},
+		"gemini": {config.EmbeddingsConfig{Provider: config.EmbeddingProviderGemini}, os.Getenv("GEMINI_API_KEY"), os.Getenv("GEMINI_API_KEY") == ""},
+	}
+
+	embedders := make(map[string]Embedder)
+	for name, s := range settings {
+		if s.skip {
+			t.Logf("skipping the %s embedder, no credentials", name)
+			continue
+		}
+		e, err := New(withDefaults(t, s.embeddings), s.apiKey)
+		if err != nil {
+			t.Fatalf("New(%s) error = %v", name, err)
+		}
+		embedders[name] = e
+	}
+	return embedders
+}
+
+// withDefaults fills in the defaults of the provider as loading the config does
+func withDefaults(t *testing.T, embeddings config.EmbeddingsConfig) config.EmbeddingsConfig {
+	t.Helper()
+	config.NewTestConfig(func(c *config.Config) {
+		c.Spaces["contract"] = config.SpaceConfig{Embeddings: &embeddings}
+	})
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	return config.Get().EmbeddingsFor("contract")
+}
+
+func TestEmbedderContract(t *testing.T) {
+	texts := []string{
+		"The cat sat on the mat",
+		"A cat is sitting on the mat",
+		"Quarterly revenue grew by ten percent",
+	}
+	for name, e := range contractEmbedders(t) {
+		t.Run(name, func(t *testing.T) {
+			vectors, err := e.EmbedBatch(context.Background(), texts)
+			if err != nil {
+				t.Fatalf("EmbedBatch() error = %v", err)
+			}
+			if len(vectors) != len(texts) {
+				t.Fatalf("EmbedBatch() returned %d vectors for %d texts", len(vectors), len(texts))
+			}
+			for i, vector := range vectors {
+				if len(vector) != e.Dimensions() {
+					t.Errorf("vector %d has %d dimensions, want %d", i, len(vector), e.Dimensions())
+				}
+			}
+
+			related, unrelated := Similarity(vectors[0], vectors[1]), Similarity(vectors[0], vectors[2])
+			if related <= unrelated {
+				t.Errorf("similarity of related texts %.3f, want above the unrelated %.3f", related, unrelated)
+			}
+			if self := Similarity(vectors[0], vectors[0]); self < 0.999 {
+				t.Errorf("similarity of a text to itself = %.3f, want 1", self)
+			}
+
+			// A text embeds the same alone and within a batch
+			alone, err := e.EmbedBatch(context.Background(), texts[2:])
+			if err != nil {
+				t.Fatalf("EmbedBatch() error = %v", err)
+			}
+			if same := Similarity(alone[0], vectors[2]); same < 0.99 {
+				t.Errorf("similarity of a text embedded alone and in a batch = %.3f, want 1", same)
+			}
+		})
+	}
+}
+
+// fakeBackend returns vectors of a fixed length, recording the batches it
+// was sent and failing the first ones with err
+type fakeBackend struct {
+	mu         sync.Mutex
+	dimensions int
+	batches    [][]string
+	failures   int
+	err        error
+}
+
+func (f *fakeBackend) embed(ctx context.Context, texts []string) ([][]float32, int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batches = append(f.batches, texts)
+	if f.failures > 0 {
+		f.failures--
+		return nil, 0, f.err
+	}
+	vectors := make([][]float32, len(texts))
+	for i := range vectors {
+		vectors[i] = make([]float32, f.dimensions)
+		vectors[i][0] = float32(len(texts[i]))
+	}
+	return vectors, estimateTokens(texts), nil
+}
+
+func TestEmbedBatchPacing(t *testing.T) {
+	backend := &fakeBackend{dimensions: 4}
+	// 1200 requests per minute space the requests 50ms apart
+	e := newEmbedder(config.EmbeddingsConfig{Provider: "pacing", Model: "fake", Dimensions: 4, BatchSize: 2, RequestsPerMinute: 1200}, backend)
+
+	start := time.Now()
+	vectors, err := e.EmbedBatch(context.Background(), []string{"a", "bb", "ccc", "dddd", "eeeee"})
+	if err != nil {
+		t.Fatalf("EmbedBatch() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("3 batches took %s, want them paced 50ms apart", elapsed)
+	}
+	if len(backend.batches) != 3 || len(backend.batches[2]) != 1 {
+		t.Errorf("batches = %q, want batches of 2 texts", backend.batches)
+	}
+	for i, vector := range vectors {
+		if int(vector[0]) != i+1 {
+			t.Errorf("vector %d is of the text of length %d, want the order of the texts kept", i, int(vector[0]))
+		}
+	}
+}
+
+func TestEmbedBatchRetriesRateLimit(t *testing.T) {
+	backoffBase = time.Millisecond
+	t.Cleanup(func() { backoffBase = time.Second })
+
+	backend := &fakeBackend{dimensions: 4, failures: 2, err: errors.New("rate limit exceeded")}
+	e := newEmbedder(config.EmbeddingsConfig{Provider: "rate-limit", Model: "fake", Dimensions: 4, BatchSize: 8}, backend)
+	if _, err := e.EmbedBatch(context.Background(), []string{"a", "b"}); err != nil {
+		t.Fatalf("EmbedBatch() error = %v, want the rate limited batch retried", err)
+	}
+	if len(backend.batches) != 3 {
+		t.Errorf("sent %d requests, want 2 rejected and 1 answered", len(backend.batches))
+	}
+
+	backend = &fakeBackend{dimensions: 4, failures: 1, err: errors.New("invalid input")}
+	e = newEmbedder(config.EmbeddingsConfig{Provider: "rate-limit", Model: "fake", Dimensions: 4, BatchSize: 8}, backend)
+	if _, err := e.EmbedBatch(context.Background(), []string{"a"}); err == nil || len(backend.batches) != 1 {
+		t.Errorf("EmbedBatch() error = %v after %d requests, want other errors returned at once", err, len(backend.batches))
+	}
+}
+
+func TestEmbedBatchDimensionMismatch(t *testing.T) {
+	e := newEmbedder(config.EmbeddingsConfig{Provider: "dimensions", Model: "fake", Dimensions: 8, BatchSize: 8}, &fakeBackend{dimensions: 4})
+	_, err := e.EmbedBatch(context.Background(), []string{"a"})
+	var dimensionErr *DimensionError
+	if !errors.As(err, &dimensionErr) || dimensionErr.Got != 4 || dimensionErr.Want != 8 {
+		t.Errorf("EmbedBatch() error = %v, want a DimensionError", err)
+	}
+}
+
+func TestCheckIndex(t *testing.T) {
+	cfg := config.NewTestConfig()
+	cfg.Data.Directory = t.TempDir()
+	cfg.Spaces["docs"] = config.SpaceConfig{}
+
+	local, err := New(cfg.EmbeddingsFor("docs"), "")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := CheckIndex("docs", local); err != nil {
+		t.Errorf("CheckIndex() of a space never indexed error = %v", err)
+	}
+	if err := SaveIndex("docs", local); err != nil {
+		t.Fatalf("SaveIndex() error = %v", err)
+	}
+	if errs := CheckIndexes(cfg); len(errs) != 0 {
+		t.Errorf("CheckIndexes() = %v, want none with the embedder unchanged", errs)
+	}
+
+	// The space now embeds with longer vectors
+	settings := cfg.EmbeddingsFor("docs")
+	settings.Dimensions = 512
+	cfg.Spaces["docs"] = config.SpaceConfig{Embeddings: &settings}
+	errs := CheckIndexes(cfg)
+	var reindexErr *ReindexError
+	if len(errs) != 1 || !errors.As(errs[0], &reindexErr) || reindexErr.SpaceID != "docs" || reindexErr.Index.Dimensions != 256 {
+		t.Errorf("CheckIndexes() = %v, want the space to reindex", errs)
+	}
+}
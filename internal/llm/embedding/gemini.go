@@ -0,0 +1,55 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/genai"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+)
+
+// geminiBackend requests embeddings from the Gemini API
+type geminiBackend struct {
+	client     *genai.Client
+	model      string
+	dimensions int
+}
+
+func newGeminiBackend(settings config.EmbeddingsConfig, apiKey string) (backend, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("Gemini embeddings need the API key of the gemini provider")
+	}
+	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:  apiKey,
+		Backend: genai.BackendGeminiAPI,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the Gemini client: %w", err)
+	}
+	return &geminiBackend{client: client, model: settings.Model, dimensions: settings.Dimensions}, nil
+}
+
+func (g *geminiBackend) embed(ctx context.Context, texts []string) ([][]float32, int64, error) {
+	contents := make([]*genai.Content, len(texts))
+	for i, text := range texts {
+		contents[i] = genai.NewContentFromText(text, genai.RoleUser)
+	}
+	embedConfig := &genai.EmbedContentConfig{TaskType: "RETRIEVAL_DOCUMENT"}
+	// The first embedding model cannot shorten its vectors
+	if g.model != "embedding-001" {
+		dimensions := int32(g.dimensions)
+		embedConfig.OutputDimensionality = &dimensions
+	}
+	resp, err := g.client.Models.EmbedContent(ctx, g.model, contents, embedConfig)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	vectors := make([][]float32, len(resp.Embeddings))
+	for i, embedding := range resp.Embeddings {
+		vectors[i] = embedding.Values
+	}
+	// The Gemini API does not report the tokens of embeddings
+	return vectors, estimateTokens(texts), nil
+}
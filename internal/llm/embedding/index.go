@@ -0,0 +1,121 @@
+package embedding
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+)
+
+// Index records the embedder the knowledge base of a space was indexed
+// with, as vectors of another model or length cannot be searched with the
+// vectors of the queries
+type Index struct {
+	Model      string    `json:"model"`
+	Dimensions int       `json:"dimensions"`
+	IndexedAt  time.Time `json:"indexed_at"`
+}
+
+// ReindexError is returned when the embedder configured for a space is not
+// the one its knowledge base was indexed with
+type ReindexError struct {
+	SpaceID    string
+	Index      Index
+	Model      string
+	Dimensions int
+}
+
+func (e *ReindexError) Error() string {
+	return fmt.Sprintf("the knowledge base of %s was indexed with %s (%d dimensions) but %s (%d dimensions) is configured: reindex it to search it again",
+		spaceName(e.SpaceID), e.Index.Model, e.Index.Dimensions, e.Model, e.Dimensions)
+}
+
+func spaceName(spaceID string) string {
+	if spaceID == "" {
+		return "the workspace"
+	}
+	return fmt.Sprintf("space %s", spaceID)
+}
+
+// IndexFile returns the file recording the index of a space, the one of the
+// knowledge base outside of spaces for an empty ID
+func IndexFile(spaceID string) string {
+	dir := filepath.Join(config.Get().Data.Directory, "embeddings")
+	if spaceID == "" {
+		return filepath.Join(dir, "index.json")
+	}
+	return filepath.Join(dir, "spaces", spaceID+".json")
+}
+
+// LoadIndex returns the index recorded for a space, nil when it was never
+// indexed
+func LoadIndex(spaceID string) (*Index, error) {
+	data, err := os.ReadFile(IndexFile(spaceID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the index of %s: %w", spaceName(spaceID), err)
+	}
+	var index Index
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse the index of %s: %w", spaceName(spaceID), err)
+	}
+	return &index, nil
+}
+
+// SaveIndex records that the knowledge base of a space was indexed with e
+func SaveIndex(spaceID string, e Embedder) error {
+	data, err := json.MarshalIndent(Index{Model: e.ModelID(), Dimensions: e.Dimensions(), IndexedAt: time.Now()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := IndexFile(spaceID)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create the index directory: %w", err)
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Check returns a ReindexError when vectors of model and dimensions cannot
+// be searched against the index
+func (i Index) Check(spaceID, model string, dimensions int) error {
+	if i.Model == model && i.Dimensions == dimensions {
+		return nil
+	}
+	return &ReindexError{SpaceID: spaceID, Index: i, Model: model, Dimensions: dimensions}
+}
+
+// CheckIndex returns a ReindexError when e cannot search the knowledge base
+// of a space as indexed, nil when it was never indexed
+func CheckIndex(spaceID string, e Embedder) error {
+	index, err := LoadIndex(spaceID)
+	if err != nil || index == nil {
+		return err
+	}
+	return index.Check(spaceID, e.ModelID(), e.Dimensions())
+}
+
+// CheckIndexes checks the indexes of the workspace and of every space
+// against the embedders now configured, returning the ones to reindex
+func CheckIndexes(cfg *config.Config) []error {
+	spaceIDs := append([]string{""}, slices.Sorted(maps.Keys(cfg.Spaces))...)
+	var errs []error
+	for _, spaceID := range spaceIDs {
+		settings := cfg.EmbeddingsFor(spaceID)
+		index, err := LoadIndex(spaceID)
+		if err == nil && index != nil {
+			err = index.Check(spaceID, ModelID(settings), settings.Dimensions)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,97 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go"
+)
+
+// backoffBase is the wait before the first retry of a rejected request,
+// doubled at each attempt
+var backoffBase = time.Second
+
+// limiter paces the requests sent to a provider, spacing them evenly under
+// its rate limit and holding them back after the provider rejected one
+type limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+var (
+	limitersMu sync.Mutex
+	limiters   = make(map[string]*limiter)
+)
+
+// limiterFor returns the limiter of a provider, shared by every embedder
+// requesting from it as they share its rate limit. The slowest pace
+// configured applies, none without a rate limit.
+func limiterFor(provider string, requestsPerMinute int) *limiter {
+	var interval time.Duration
+	if requestsPerMinute > 0 {
+		interval = time.Minute / time.Duration(requestsPerMinute)
+	}
+
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	l, ok := limiters[provider]
+	if !ok {
+		l = &limiter{}
+		limiters[provider] = l
+	}
+	l.mu.Lock()
+	l.interval = max(l.interval, interval)
+	l.mu.Unlock()
+	return l
+}
+
+// wait blocks until the next request may be sent, or ctx is done
+func (l *limiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	at := l.next
+	if now := time.Now(); at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(at)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// backoff holds the next requests back for delay, after the provider
+// rejected one under its rate limit
+func (l *limiter) backoff(delay time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := time.Now().Add(delay); until.After(l.next) {
+		l.next = until
+	}
+}
+
+// retryDelay returns how long to wait before sending a rejected request
+// again: the Retry-After of the response when there is one, an exponential
+// backoff otherwise
+func retryDelay(attempt int, err error) time.Duration {
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) && apiErr.Response != nil {
+		if seconds, err := strconv.Atoi(apiErr.Response.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return backoffBase << (attempt - 1)
+}
@@ -0,0 +1,72 @@
+package embedding
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// trigramWeight is the weight of the character trigrams of a word against
+// the word itself, so words sharing a stem are close without being equal
+const trigramWeight = 0.5
+
+// localBackend embeds texts on this machine by hashing their words and the
+// character trigrams of the words into the dimensions of the vector. It
+// needs neither a key nor a network, at the cost of matching shared words
+// rather than meanings.
+type localBackend struct {
+	dimensions int
+}
+
+func (l localBackend) embed(ctx context.Context, texts []string) ([][]float32, int64, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		if err := ctx.Err(); err != nil {
+			return nil, 0, err
+		}
+		vectors[i] = l.vector(text)
+	}
+	return vectors, estimateTokens(texts), nil
+}
+
+// vector returns the normalized hashed features of text
+func (l localBackend) vector(text string) []float32 {
+	vector := make([]float32, l.dimensions)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		l.add(vector, word, 1)
+		padded := []rune("^" + word + "$")
+		for i := 0; i+3 <= len(padded); i++ {
+			l.add(vector, "#"+string(padded[i:i+3]), trigramWeight)
+		}
+	}
+
+	var norm float64
+	for _, value := range vector {
+		norm += float64(value) * float64(value)
+	}
+	if norm == 0 {
+		return vector
+	}
+	scale := float32(1 / math.Sqrt(norm))
+	for i := range vector {
+		vector[i] *= scale
+	}
+	return vector
+}
+
+// add adds weight to the dimension feature hashes to, with the sign of the
+// hash so collisions cancel out rather than pile up
+func (l localBackend) add(vector []float32, feature string, weight float32) {
+	h := fnv.New64a()
+	h.Write([]byte(feature))
+	sum := h.Sum64()
+	if sum>>63 == 1 {
+		weight = -weight
+	}
+	vector[sum%uint64(len(vector))] += weight
+}
@@ -0,0 +1,95 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/azure"
+	"github.com/openai/openai-go/option"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+)
+
+// defaultAzureAPIVersion is the Azure OpenAI API version used when
+// AZURE_OPENAI_API_VERSION is unset
+const defaultAzureAPIVersion = "2024-10-21"
+
+// openaiBackend requests embeddings from OpenAI, or from an Azure OpenAI
+// deployment with the same API
+type openaiBackend struct {
+	client     openai.Client
+	model      string
+	dimensions int
+}
+
+func newOpenAIBackend(settings config.EmbeddingsConfig, apiKey string) (backend, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("OpenAI embeddings need the API key of the openai provider")
+	}
+	// The limiter retries the batches rejected under the rate limit
+	client := openai.NewClient(option.WithAPIKey(apiKey), option.WithMaxRetries(0))
+	return &openaiBackend{client: client, model: settings.Model, dimensions: settings.Dimensions}, nil
+}
+
+func newAzureBackend(settings config.EmbeddingsConfig, apiKey string) (backend, error) {
+	endpoint := os.Getenv("AZURE_OPENAI_ENDPOINT")
+	if endpoint == "" {
+		return nil, fmt.Errorf("Azure OpenAI embeddings need AZURE_OPENAI_ENDPOINT")
+	}
+	apiVersion := os.Getenv("AZURE_OPENAI_API_VERSION")
+	if apiVersion == "" {
+		apiVersion = defaultAzureAPIVersion
+	}
+
+	opts := []option.RequestOption{azure.WithEndpoint(endpoint, apiVersion), option.WithMaxRetries(0)}
+	if apiKey == "" {
+		apiKey = os.Getenv("AZURE_OPENAI_API_KEY")
+	}
+	if apiKey != "" {
+		opts = append(opts, azure.WithAPIKey(apiKey))
+	} else {
+		cred, err := azidentity.NewDefaultAzureCredential(nil)
+		if err != nil {
+			return nil, fmt.Errorf("Azure OpenAI embeddings need an API key or Azure credentials: %w", err)
+		}
+		opts = append(opts, azure.WithTokenCredential(cred))
+	}
+	return &openaiBackend{client: openai.NewClient(opts...), model: settings.Model, dimensions: settings.Dimensions}, nil
+}
+
+func (o *openaiBackend) embed(ctx context.Context, texts []string) ([][]float32, int64, error) {
+	params := openai.EmbeddingNewParams{
+		Input:          openai.EmbeddingNewParamsInputUnion{OfArrayOfStrings: texts},
+		Model:          o.model,
+		EncodingFormat: openai.EmbeddingNewParamsEncodingFormatFloat,
+	}
+	// Only the text-embedding-3 models can shorten their vectors
+	if strings.Contains(o.model, "text-embedding-3") {
+		params.Dimensions = openai.Int(int64(o.dimensions))
+	}
+	resp, err := o.client.Embeddings.New(ctx, params, option.WithRequestTimeout(requestTimeout))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, data := range resp.Data {
+		if data.Index < 0 || int(data.Index) >= len(vectors) {
+			return nil, 0, fmt.Errorf("embedding of unknown text %d", data.Index)
+		}
+		vector := make([]float32, len(data.Embedding))
+		for i, value := range data.Embedding {
+			vector[i] = float32(value)
+		}
+		vectors[data.Index] = vector
+	}
+	used := resp.Usage.PromptTokens
+	if used == 0 {
+		used = estimateTokens(texts)
+	}
+	return vectors, used, nil
+}
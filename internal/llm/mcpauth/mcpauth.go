@@ -0,0 +1,143 @@
+// Package mcpauth keeps the Authorization header of SSE MCP servers secured
+// with short-lived tokens fresh, running the tokenRefreshCommand of a server
+// when it answers 401 or its token is older than tokenRefreshIntervalSec.
+// Refreshed tokens live in memory only, they are never written to the config.
+package mcpauth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/mark3labs/mcp-go/client"
+)
+
+// refreshTimeout bounds the run of a token refresh command
+const refreshTimeout = 30 * time.Second
+
+// unauthorizedPattern matches the errors of the SSE client for a 401 answer,
+// to the connection or to a request
+var unauthorizedPattern = regexp.MustCompile(`status(?: code)?:? 401\b`)
+
+type token struct {
+	command   string
+	value     string
+	fetchedAt time.Time
+}
+
+var (
+	mu sync.Mutex
+	// tokens are the refreshed tokens, by server name
+	tokens = make(map[string]token)
+)
+
+// IsUnauthorized reports whether err is a server answering 401
+func IsUnauthorized(err error) bool {
+	return err != nil && unauthorizedPattern.MatchString(err.Error())
+}
+
+// Headers returns the headers to send to the server: the configured ones,
+// with the refreshed Authorization when the server has a refresh command
+func Headers(name string, server config.MCPServer) map[string]string {
+	headers := maps.Clone(server.Headers)
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if t, ok := tokens[name]; ok && t.command == server.TokenRefreshCommand {
+		headers["Authorization"] = t.value
+	}
+	return headers
+}
+
+// Refresh runs the refresh command of the server and keeps the token it
+// prints for the Authorization header, as a bearer token unless it names its
+// scheme
+func Refresh(ctx context.Context, name string, server config.MCPServer) error {
+	if server.TokenRefreshCommand == "" {
+		return fmt.Errorf("mcp server %s has no token refresh command", name)
+	}
+	ctx, cancel := context.WithTimeout(ctx, refreshTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", server.TokenRefreshCommand)
+	cmd.Dir = config.WorkingDirectory()
+	cmd.Env = append(cmd.Environ(), config.SpaceEnviron()...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("token refresh command of mcp server %s failed: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	value := strings.TrimSpace(stdout.String())
+	if value == "" {
+		return fmt.Errorf("token refresh command of mcp server %s printed no token", name)
+	}
+	if !strings.Contains(value, " ") {
+		value = "Bearer " + value
+	}
+
+	mu.Lock()
+	tokens[name] = token{command: server.TokenRefreshCommand, value: value, fetchedAt: time.Now()}
+	mu.Unlock()
+	// The token is a secret, only the refresh is logged
+	logging.Info("Refreshed the token of the mcp server", "server", name)
+	return nil
+}
+
+// expired reports whether the token of the server is due for a proactive
+// refresh: it is older than the refresh interval, or was never fetched
+func expired(name string, server config.MCPServer) bool {
+	if server.TokenRefreshCommand == "" || server.TokenRefreshIntervalSec <= 0 {
+		return false
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	t, ok := tokens[name]
+	interval := time.Duration(server.TokenRefreshIntervalSec) * time.Second
+	return !ok || t.command != server.TokenRefreshCommand || time.Since(t.fetchedAt) >= interval
+}
+
+// Do runs call with the headers of the server. When the server has a refresh
+// command, its token is refreshed before it expires, and refreshed once more
+// to retry call once when the server answers 401.
+func Do[T any](ctx context.Context, name string, server config.MCPServer, call func(headers map[string]string) (T, error)) (T, error) {
+	if expired(name, server) {
+		if err := Refresh(ctx, name, server); err != nil {
+			logging.Warn("Failed to refresh the mcp server token before it expires", "server", name, "error", err)
+		}
+	}
+	result, err := call(Headers(name, server))
+	if !IsUnauthorized(err) || server.TokenRefreshCommand == "" {
+		return result, err
+	}
+
+	logging.Info("The mcp server rejected its token, refreshing it", "server", name)
+	if refreshErr := Refresh(ctx, name, server); refreshErr != nil {
+		return result, errors.Join(err, refreshErr)
+	}
+	return call(Headers(name, server))
+}
+
+// StartSSEClient connects to the SSE server at url, sending headers with its
+// requests
+func StartSSEClient(ctx context.Context, url string, headers map[string]string) (*client.SSEMCPClient, error) {
+	c, err := client.NewSSEMCPClient(url, client.WithHeaders(headers))
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Start(ctx); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,139 @@
+package mcpauth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setup(t *testing.T) {
+	t.Helper()
+	config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
+	mu.Lock()
+	clear(tokens)
+	mu.Unlock()
+}
+
+// counter returns a refresh command printing token and counting its runs
+func counter(t *testing.T, token string) (string, func() int) {
+	t.Helper()
+	runs := filepath.Join(t.TempDir(), "runs")
+	command := "echo run >> " + runs + " && echo " + token
+	return command, func() int {
+		content, _ := os.ReadFile(runs)
+		return strings.Count(string(content), "run\n")
+	}
+}
+
+func TestDoRefreshesOnUnauthorized(t *testing.T) {
+	setup(t)
+	command, runs := counter(t, "fresh")
+	server := config.MCPServer{
+		Type:                config.MCPSse,
+		Headers:             map[string]string{"Authorization": "Bearer stale", "X-Team": "core"},
+		TokenRefreshCommand: command,
+	}
+
+	var sent []map[string]string
+	result, err := Do(context.Background(), "search", server, func(headers map[string]string) (string, error) {
+		sent = append(sent, headers)
+		if headers["Authorization"] != "Bearer fresh" {
+			return "", errors.New("request failed with status 401: token expired")
+		}
+		return "ok", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", result)
+	require.Len(t, sent, 2)
+	assert.Equal(t, map[string]string{"Authorization": "Bearer fresh", "X-Team": "core"}, sent[1])
+	assert.Equal(t, 1, runs())
+	assert.Equal(t, "Bearer stale", server.Headers["Authorization"], "the configured headers are left as they are")
+
+	// The refreshed token is used by the next calls
+	_, err = Do(context.Background(), "search", server, func(headers map[string]string) (string, error) {
+		assert.Equal(t, "Bearer fresh", headers["Authorization"])
+		return "ok", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, runs())
+}
+
+func TestDoRetriesOnce(t *testing.T) {
+	setup(t)
+	command, runs := counter(t, "still-rejected")
+	server := config.MCPServer{Type: config.MCPSse, TokenRefreshCommand: command}
+
+	calls := 0
+	_, err := Do(context.Background(), "search", server, func(headers map[string]string) (string, error) {
+		calls++
+		return "", errors.New("unexpected status code: 401")
+	})
+	assert.True(t, IsUnauthorized(err))
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, 1, runs())
+}
+
+func TestDoWithoutRefreshCommand(t *testing.T) {
+	setup(t)
+	calls := 0
+	_, err := Do(context.Background(), "search", config.MCPServer{Type: config.MCPSse}, func(headers map[string]string) (string, error) {
+		calls++
+		return "", errors.New("request failed with status 401: unauthorized")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestDoRefreshFailure(t *testing.T) {
+	setup(t)
+	server := config.MCPServer{Type: config.MCPSse, TokenRefreshCommand: "echo denied >&2; exit 1"}
+	_, err := Do(context.Background(), "search", server, func(headers map[string]string) (string, error) {
+		return "", errors.New("request failed with status 401: unauthorized")
+	})
+	require.Error(t, err)
+	assert.True(t, IsUnauthorized(err))
+	assert.Contains(t, err.Error(), "denied")
+}
+
+func TestDoRefreshesBeforeExpiry(t *testing.T) {
+	setup(t)
+	command, runs := counter(t, "Token abc")
+	server := config.MCPServer{Type: config.MCPSse, TokenRefreshCommand: command, TokenRefreshIntervalSec: 3600}
+
+	call := func(headers map[string]string) (string, error) {
+		return headers["Authorization"], nil
+	}
+	authorization, err := Do(context.Background(), "search", server, call)
+	require.NoError(t, err)
+	assert.Equal(t, "Token abc", authorization, "a token naming its scheme is used as printed")
+	assert.Equal(t, 1, runs())
+
+	_, err = Do(context.Background(), "search", server, call)
+	require.NoError(t, err)
+	assert.Equal(t, 1, runs(), "a token younger than the interval is not refreshed")
+
+	mu.Lock()
+	expiring := tokens["search"]
+	expiring.fetchedAt = expiring.fetchedAt.Add(-2 * time.Hour)
+	tokens["search"] = expiring
+	mu.Unlock()
+	_, err = Do(context.Background(), "search", server, call)
+	require.NoError(t, err)
+	assert.Equal(t, 2, runs())
+}
+
+func TestIsUnauthorized(t *testing.T) {
+	assert.True(t, IsUnauthorized(errors.New("request failed with status 401: Unauthorized")))
+	assert.True(t, IsUnauthorized(errors.New("failed to start: unexpected status code: 401")))
+	assert.False(t, IsUnauthorized(errors.New("request failed with status 403: Forbidden")))
+	assert.False(t, IsUnauthorized(errors.New("request failed with status 4010")))
+	assert.False(t, IsUnauthorized(nil))
+}
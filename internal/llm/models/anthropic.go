@@ -27,6 +27,7 @@ var AnthropicModels = map[ModelID]Model{
 		ContextWindow:       200000,
 		DefaultMaxTokens:    5000,
 		SupportsAttachments: true,
+		SupportsTools:       true,
 	},
 	Claude3Haiku: {
 		ID:                  Claude3Haiku,
@@ -40,6 +41,7 @@ var AnthropicModels = map[ModelID]Model{
 		ContextWindow:       200000,
 		DefaultMaxTokens:    4096,
 		SupportsAttachments: true,
+		SupportsTools:       true,
 	},
 	Claude37Sonnet: {
 		ID:                  Claude37Sonnet,
@@ -54,6 +56,7 @@ var AnthropicModels = map[ModelID]Model{
 		DefaultMaxTokens:    50000,
 		CanReason:           true,
 		SupportsAttachments: true,
+		SupportsTools:       true,
 	},
 	Claude35Haiku: {
 		ID:                  Claude35Haiku,
@@ -67,6 +70,7 @@ var AnthropicModels = map[ModelID]Model{
 		ContextWindow:       200000,
 		DefaultMaxTokens:    4096,
 		SupportsAttachments: true,
+		SupportsTools:       true,
 	},
 	Claude3Opus: {
 		ID:                  Claude3Opus,
@@ -80,6 +84,7 @@ var AnthropicModels = map[ModelID]Model{
 		ContextWindow:       200000,
 		DefaultMaxTokens:    4096,
 		SupportsAttachments: true,
+		SupportsTools:       true,
 	},
 	Claude4Sonnet: {
 		ID:                  Claude4Sonnet,
@@ -94,6 +99,7 @@ var AnthropicModels = map[ModelID]Model{
 		DefaultMaxTokens:    50000,
 		CanReason:           true,
 		SupportsAttachments: true,
+		SupportsTools:       true,
 	},
 	Claude4Opus: {
 		ID:                  Claude4Opus,
@@ -107,5 +113,6 @@ var AnthropicModels = map[ModelID]Model{
 		ContextWindow:       200000,
 		DefaultMaxTokens:    4096,
 		SupportsAttachments: true,
+		SupportsTools:       true,
 	},
 }
@@ -29,6 +29,7 @@ var AzureModels = map[ModelID]Model{
 		ContextWindow:       OpenAIModels[GPT41].ContextWindow,
 		DefaultMaxTokens:    OpenAIModels[GPT41].DefaultMaxTokens,
 		SupportsAttachments: true,
+		SupportsTools:       OpenAIModels[GPT41].SupportsTools,
 	},
 	AzureGPT41Mini: {
 		ID:                  AzureGPT41Mini,
@@ -42,6 +43,7 @@ var AzureModels = map[ModelID]Model{
 		ContextWindow:       OpenAIModels[GPT41Mini].ContextWindow,
 		DefaultMaxTokens:    OpenAIModels[GPT41Mini].DefaultMaxTokens,
 		SupportsAttachments: true,
+		SupportsTools:       OpenAIModels[GPT41Mini].SupportsTools,
 	},
 	AzureGPT41Nano: {
 		ID:                  AzureGPT41Nano,
@@ -55,6 +57,7 @@ var AzureModels = map[ModelID]Model{
 		ContextWindow:       OpenAIModels[GPT41Nano].ContextWindow,
 		DefaultMaxTokens:    OpenAIModels[GPT41Nano].DefaultMaxTokens,
 		SupportsAttachments: true,
+		SupportsTools:       OpenAIModels[GPT41Nano].SupportsTools,
 	},
 	AzureGPT45Preview: {
 		ID:                  AzureGPT45Preview,
@@ -68,6 +71,8 @@ var AzureModels = map[ModelID]Model{
 		ContextWindow:       OpenAIModels[GPT45Preview].ContextWindow,
 		DefaultMaxTokens:    OpenAIModels[GPT45Preview].DefaultMaxTokens,
 		SupportsAttachments: true,
+		Deprecated:          true,
+		SupportsTools:       OpenAIModels[GPT45Preview].SupportsTools,
 	},
 	AzureGPT4o: {
 		ID:                  AzureGPT4o,
@@ -81,6 +86,7 @@ var AzureModels = map[ModelID]Model{
 		ContextWindow:       OpenAIModels[GPT4o].ContextWindow,
 		DefaultMaxTokens:    OpenAIModels[GPT4o].DefaultMaxTokens,
 		SupportsAttachments: true,
+		SupportsTools:       OpenAIModels[GPT4o].SupportsTools,
 	},
 	AzureGPT4oMini: {
 		ID:                  AzureGPT4oMini,
@@ -94,6 +100,7 @@ var AzureModels = map[ModelID]Model{
 		ContextWindow:       OpenAIModels[GPT4oMini].ContextWindow,
 		DefaultMaxTokens:    OpenAIModels[GPT4oMini].DefaultMaxTokens,
 		SupportsAttachments: true,
+		SupportsTools:       OpenAIModels[GPT4oMini].SupportsTools,
 	},
 	AzureO1: {
 		ID:                  AzureO1,
@@ -108,6 +115,7 @@ var AzureModels = map[ModelID]Model{
 		DefaultMaxTokens:    OpenAIModels[O1].DefaultMaxTokens,
 		CanReason:           OpenAIModels[O1].CanReason,
 		SupportsAttachments: true,
+		SupportsTools:       OpenAIModels[O1].SupportsTools,
 	},
 	AzureO1Mini: {
 		ID:                  AzureO1Mini,
@@ -122,6 +130,7 @@ var AzureModels = map[ModelID]Model{
 		DefaultMaxTokens:    OpenAIModels[O1Mini].DefaultMaxTokens,
 		CanReason:           OpenAIModels[O1Mini].CanReason,
 		SupportsAttachments: true,
+		SupportsTools:       OpenAIModels[O1Mini].SupportsTools,
 	},
 	AzureO3: {
 		ID:                  AzureO3,
@@ -136,6 +145,7 @@ var AzureModels = map[ModelID]Model{
 		DefaultMaxTokens:    OpenAIModels[O3].DefaultMaxTokens,
 		CanReason:           OpenAIModels[O3].CanReason,
 		SupportsAttachments: true,
+		SupportsTools:       OpenAIModels[O3].SupportsTools,
 	},
 	AzureO3Mini: {
 		ID:                  AzureO3Mini,
@@ -150,6 +160,7 @@ var AzureModels = map[ModelID]Model{
 		DefaultMaxTokens:    OpenAIModels[O3Mini].DefaultMaxTokens,
 		CanReason:           OpenAIModels[O3Mini].CanReason,
 		SupportsAttachments: false,
+		SupportsTools:       OpenAIModels[O3Mini].SupportsTools,
 	},
 	AzureO4Mini: {
 		ID:                  AzureO4Mini,
@@ -164,5 +175,6 @@ var AzureModels = map[ModelID]Model{
 		DefaultMaxTokens:    OpenAIModels[O4Mini].DefaultMaxTokens,
 		CanReason:           OpenAIModels[O4Mini].CanReason,
 		SupportsAttachments: true,
+		SupportsTools:       OpenAIModels[O4Mini].SupportsTools,
 	},
 }
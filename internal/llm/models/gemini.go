@@ -23,6 +23,7 @@ var GeminiModels = map[ModelID]Model{
 		ContextWindow:       1000000,
 		DefaultMaxTokens:    50000,
 		SupportsAttachments: true,
+		SupportsTools:       true,
 	},
 	Gemini25: {
 		ID:                  Gemini25,
@@ -36,6 +37,7 @@ var GeminiModels = map[ModelID]Model{
 		ContextWindow:       1000000,
 		DefaultMaxTokens:    50000,
 		SupportsAttachments: true,
+		SupportsTools:       true,
 	},
 
 	Gemini20Flash: {
@@ -50,6 +52,7 @@ var GeminiModels = map[ModelID]Model{
 		ContextWindow:       1000000,
 		DefaultMaxTokens:    6000,
 		SupportsAttachments: true,
+		SupportsTools:       true,
 	},
 	Gemini20FlashLite: {
 		ID:                  Gemini20FlashLite,
@@ -63,5 +66,6 @@ var GeminiModels = map[ModelID]Model{
 		ContextWindow:       1000000,
 		DefaultMaxTokens:    6000,
 		SupportsAttachments: true,
+		SupportsTools:       true,
 	},
 }
@@ -7,6 +7,7 @@ import (
 	"net/url"
 	"os"
 	"regexp"
+	"slices"
 	"strings"
 	"unicode"
 
@@ -21,6 +22,12 @@ const (
 	lmStudioBetaModelsPath = "api/v0/models"
 )
 
+// IsLocal reports whether the provider serves models from the local machine,
+// which remain available offline
+func (p ModelProvider) IsLocal() bool {
+	return p == ProviderLocal || p == ProviderOllama
+}
+
 func init() {
 	if endpoint := os.Getenv("LOCAL_ENDPOINT"); endpoint != "" {
 		localEndpoint, err := url.Parse(endpoint)
@@ -72,6 +79,15 @@ type localModel struct {
 	State               string `json:"state"`
 	MaxContextLength    int64  `json:"max_context_length"`
 	LoadedContextLength int64  `json:"loaded_context_length"`
+	// Capabilities are listed by LM Studio, "tool_use" among them for the
+	// models trained for function calling. Nil when the server does not say.
+	Capabilities []string `json:"capabilities"`
+}
+
+// supportsTools reports whether the model supports function calling, which
+// is assumed when the server does not list the capabilities of its models
+func (m localModel) supportsTools() bool {
+	return m.Capabilities == nil || slices.Contains(m.Capabilities, "tool_use")
 }
 
 func listLocalModels(modelsEndpoint string) []localModel {
@@ -144,6 +160,7 @@ func convertLocalModel(model localModel) Model {
 		DefaultMaxTokens:    cmp.Or(model.LoadedContextLength, 4096),
 		CanReason:           true,
 		SupportsAttachments: true,
+		SupportsTools:       model.supportsTools(),
 	}
 }
 
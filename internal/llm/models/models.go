@@ -1,6 +1,9 @@
 package models
 
-import "maps"
+import (
+	"maps"
+	"sort"
+)
 
 type (
 	ModelID       string
@@ -20,6 +23,19 @@ type Model struct {
 	DefaultMaxTokens    int64         `json:"default_max_tokens"`
 	CanReason           bool          `json:"can_reason"`
 	SupportsAttachments bool          `json:"supports_attachments"`
+	// SupportsTools is false for the models without function calling, which
+	// are sent no tool definitions
+	SupportsTools bool `json:"supports_tools"`
+
+	// Deprecated models are being phased out by their provider and are hidden
+	// from model listings unless explicitly requested.
+	Deprecated bool `json:"deprecated,omitempty"`
+}
+
+// SupportsPromptCache reports whether the provider can cache prompt prefixes
+// for the model, which it does for every model with a cache write price.
+func (m Model) SupportsPromptCache() bool {
+	return m.CostPer1MInCached > 0
 }
 
 // Model IDs
@@ -41,9 +57,10 @@ var ProviderPopularity = map[ModelProvider]int{
 	ProviderGemini:     3,
 	ProviderGROQ:       4,
 	ProviderOpenRouter: 5,
-	ProviderBedrock:    6,
-	ProviderAzure:      7,
-	ProviderVertexAI:   8,
+	ProviderXAI:        6,
+	ProviderBedrock:    7,
+	ProviderAzure:      8,
+	ProviderVertexAI:   9,
 }
 
 var SupportedModels = map[ModelID]Model{
@@ -94,3 +111,76 @@ func init() {
 	maps.Copy(SupportedModels, XAIModels)
 	maps.Copy(SupportedModels, VertexAIGeminiModels)
 }
+
+// SupportedProviders returns the providers that have at least one model in
+// SupportedModels, ordered by popularity and then by name.
+func SupportedProviders() []ModelProvider {
+	seen := make(map[ModelProvider]bool)
+	var providers []ModelProvider
+	for _, model := range SupportedModels {
+		if model.Provider == ProviderMock || model.Provider == ProviderTest || seen[model.Provider] {
+			continue
+		}
+		seen[model.Provider] = true
+		providers = append(providers, model.Provider)
+	}
+
+	sort.Slice(providers, func(i, j int) bool {
+		ri, rj := providerRank(providers[i]), providerRank(providers[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return providers[i] < providers[j]
+	})
+	return providers
+}
+
+// ModelsForProvider returns the non-deprecated models of a provider sorted by name.
+func ModelsForProvider(provider ModelProvider) []Model {
+	return filterModels(provider, false)
+}
+
+// AllModelsForProvider returns every model of a provider, including deprecated ones.
+func AllModelsForProvider(provider ModelProvider) []Model {
+	return filterModels(provider, true)
+}
+
+func filterModels(provider ModelProvider, includeDeprecated bool) []Model {
+	var providerModels []Model
+	for _, model := range SupportedModels {
+		if model.Provider != provider || (model.Deprecated && !includeDeprecated) {
+			continue
+		}
+		providerModels = append(providerModels, model)
+	}
+	sort.Slice(providerModels, func(i, j int) bool {
+		return providerModels[i].Name < providerModels[j].Name
+	})
+	return providerModels
+}
+
+// providerRank maps unranked providers after all ranked ones.
+func providerRank(provider ModelProvider) int {
+	if rank, ok := ProviderPopularity[provider]; ok && rank > 0 {
+		return rank
+	}
+	return len(ProviderPopularity) + 1
+}
+
+// LacksTools reports whether id is a known model without function calling.
+// Unknown models are not assumed to lack it.
+func LacksTools(id ModelID) bool {
+	model, ok := SupportedModels[id]
+	return ok && !model.SupportsTools
+}
+
+// ToolsAlternative suggests a model of the same provider as id that supports
+// function calling, false when the provider has none
+func ToolsAlternative(id ModelID) (Model, bool) {
+	for _, model := range ModelsForProvider(SupportedModels[id].Provider) {
+		if model.SupportsTools {
+			return model, true
+		}
+	}
+	return Model{}, false
+}
@@ -0,0 +1,176 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ProviderOllama ModelProvider = "ollama"
+
+	// DefaultOllamaEndpoint is where a local Ollama server listens by default
+	DefaultOllamaEndpoint = "http://localhost:11434"
+
+	// ollamaDefaultContext is the context window requested from Ollama when
+	// the model does not set num_ctx: the full window of some models would
+	// not fit in the memory of most machines
+	ollamaDefaultContext = 32768
+)
+
+// OllamaEndpoint returns the base URL of the Ollama server: endpoint when
+// set, else OLLAMA_HOST, else the default local server
+func OllamaEndpoint(endpoint string) string {
+	if endpoint == "" {
+		endpoint = os.Getenv("OLLAMA_HOST")
+	}
+	if endpoint == "" {
+		return DefaultOllamaEndpoint
+	}
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "http://" + endpoint
+	}
+	endpoint = strings.TrimRight(endpoint, "/")
+	// OLLAMA_HOST may be a bare host, served on the default port
+	if host := endpoint[strings.Index(endpoint, "://")+3:]; !strings.Contains(host, ":") {
+		endpoint += ":11434"
+	}
+	return endpoint
+}
+
+// OllamaModel is a model pulled to an Ollama server
+type OllamaModel struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	// Capabilities are listed by /api/show, "tools" among them for the
+	// models trained for function calling
+	Capabilities []string `json:"-"`
+	// ContextLength is the context window the model was trained with
+	ContextLength int64 `json:"-"`
+	// NumCtx is the context window set by the parameters of the model, zero
+	// when unset
+	NumCtx int64 `json:"-"`
+}
+
+// ListOllamaModels lists the models pulled to the Ollama server at endpoint
+func ListOllamaModels(ctx context.Context, endpoint string) ([]OllamaModel, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"/api/tags", nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("listing Ollama models: %s", res.Status)
+	}
+	var tags struct {
+		Models []OllamaModel `json:"models"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&tags); err != nil {
+		return nil, fmt.Errorf("listing Ollama models: %w", err)
+	}
+	return tags.Models, nil
+}
+
+// ShowOllamaModel fills the capabilities and context window of model from
+// its metadata
+func ShowOllamaModel(ctx context.Context, endpoint string, model *OllamaModel) error {
+	body, _ := json.Marshal(map[string]string{"model": model.Name})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/api/show", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("showing Ollama model %s: %s", model.Name, res.Status)
+	}
+	var show struct {
+		Capabilities []string       `json:"capabilities"`
+		ModelInfo    map[string]any `json:"model_info"`
+		Parameters   string         `json:"parameters"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&show); err != nil {
+		return fmt.Errorf("showing Ollama model %s: %w", model.Name, err)
+	}
+	model.Capabilities = show.Capabilities
+	// The context length is keyed by the architecture, as in
+	// "llama.context_length"
+	for key, value := range show.ModelInfo {
+		if length, ok := value.(float64); ok && strings.HasSuffix(key, ".context_length") {
+			model.ContextLength = int64(length)
+		}
+	}
+	for _, line := range strings.Split(show.Parameters, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "num_ctx" {
+			model.NumCtx, _ = strconv.ParseInt(fields[1], 10, 64)
+		}
+	}
+	return nil
+}
+
+// LoadOllamaModels registers the models pulled to the Ollama server at
+// endpoint, returning them in the order the server lists them. The server
+// is given timeout to answer, so a machine without Ollama starts quickly.
+func LoadOllamaModels(endpoint string, timeout time.Duration) []Model {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	pulled, err := ListOllamaModels(ctx, endpoint)
+	if err != nil {
+		return nil
+	}
+
+	loaded := make([]Model, 0, len(pulled))
+	for _, m := range pulled {
+		// Without its metadata, the model is registered with the defaults
+		_ = ShowOllamaModel(ctx, endpoint, &m)
+		model := convertOllamaModel(m)
+		SupportedModels[model.ID] = model
+		loaded = append(loaded, model)
+	}
+	if len(loaded) > 0 {
+		ProviderPopularity[ProviderOllama] = 0
+	}
+	return loaded
+}
+
+func convertOllamaModel(model OllamaModel) Model {
+	contextWindow := model.NumCtx
+	if contextWindow == 0 {
+		contextWindow = ollamaDefaultContext
+		if model.ContextLength > 0 {
+			contextWindow = min(model.ContextLength, ollamaDefaultContext)
+		}
+	}
+	return Model{
+		ID:                  OllamaModelID(model.Name),
+		Name:                friendlyModelName(strings.TrimSuffix(model.Name, ":latest")),
+		Provider:            ProviderOllama,
+		APIModel:            model.Name,
+		ContextWindow:       contextWindow,
+		DefaultMaxTokens:    min(contextWindow/2, 4096),
+		CanReason:           slices.Contains(model.Capabilities, "thinking"),
+		SupportsAttachments: slices.Contains(model.Capabilities, "vision"),
+		SupportsTools:       slices.Contains(model.Capabilities, "tools"),
+	}
+}
+
+// OllamaModelID is the ID of the Ollama model pulled as name
+func OllamaModelID(name string) ModelID {
+	return ModelID("ollama." + name)
+}
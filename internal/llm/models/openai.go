@@ -30,6 +30,7 @@ var OpenAIModels = map[ModelID]Model{
 		ContextWindow:       1_047_576,
 		DefaultMaxTokens:    20000,
 		SupportsAttachments: true,
+		SupportsTools:       true,
 	},
 	GPT41Mini: {
 		ID:                  GPT41Mini,
@@ -43,6 +44,7 @@ var OpenAIModels = map[ModelID]Model{
 		ContextWindow:       200_000,
 		DefaultMaxTokens:    20000,
 		SupportsAttachments: true,
+		SupportsTools:       true,
 	},
 	GPT41Nano: {
 		ID:                  GPT41Nano,
@@ -56,6 +58,7 @@ var OpenAIModels = map[ModelID]Model{
 		ContextWindow:       1_047_576,
 		DefaultMaxTokens:    20000,
 		SupportsAttachments: true,
+		SupportsTools:       true,
 	},
 	GPT45Preview: {
 		ID:                  GPT45Preview,
@@ -69,6 +72,8 @@ var OpenAIModels = map[ModelID]Model{
 		ContextWindow:       128_000,
 		DefaultMaxTokens:    15000,
 		SupportsAttachments: true,
+		Deprecated:          true,
+		SupportsTools:       true,
 	},
 	GPT4o: {
 		ID:                  GPT4o,
@@ -82,6 +87,7 @@ var OpenAIModels = map[ModelID]Model{
 		ContextWindow:       128_000,
 		DefaultMaxTokens:    4096,
 		SupportsAttachments: true,
+		SupportsTools:       true,
 	},
 	GPT4oMini: {
 		ID:                  GPT4oMini,
@@ -94,6 +100,7 @@ var OpenAIModels = map[ModelID]Model{
 		CostPer1MOut:        0.60,
 		ContextWindow:       128_000,
 		SupportsAttachments: true,
+		SupportsTools:       true,
 	},
 	O1: {
 		ID:                  O1,
@@ -108,6 +115,7 @@ var OpenAIModels = map[ModelID]Model{
 		DefaultMaxTokens:    50000,
 		CanReason:           true,
 		SupportsAttachments: true,
+		SupportsTools:       true,
 	},
 	O1Pro: {
 		ID:                  O1Pro,
@@ -122,6 +130,7 @@ var OpenAIModels = map[ModelID]Model{
 		DefaultMaxTokens:    50000,
 		CanReason:           true,
 		SupportsAttachments: true,
+		SupportsTools:       true,
 	},
 	O1Mini: {
 		ID:                  O1Mini,
@@ -136,6 +145,8 @@ var OpenAIModels = map[ModelID]Model{
 		DefaultMaxTokens:    50000,
 		CanReason:           true,
 		SupportsAttachments: true,
+		// o1-mini does not support function calling
+		SupportsTools: false,
 	},
 	O3: {
 		ID:                  O3,
@@ -149,6 +160,7 @@ var OpenAIModels = map[ModelID]Model{
 		ContextWindow:       200_000,
 		CanReason:           true,
 		SupportsAttachments: true,
+		SupportsTools:       true,
 	},
 	O3Mini: {
 		ID:                  O3Mini,
@@ -163,6 +175,7 @@ var OpenAIModels = map[ModelID]Model{
 		DefaultMaxTokens:    50000,
 		CanReason:           true,
 		SupportsAttachments: false,
+		SupportsTools:       true,
 	},
 	O4Mini: {
 		ID:                  O4Mini,
@@ -177,5 +190,6 @@ var OpenAIModels = map[ModelID]Model{
 		DefaultMaxTokens:    50000,
 		CanReason:           true,
 		SupportsAttachments: true,
+		SupportsTools:       true,
 	},
 }
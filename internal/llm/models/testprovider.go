@@ -0,0 +1,32 @@
+package models
+
+import (
+	"maps"
+	"testing"
+)
+
+const (
+	// ProviderTest serves scripted responses from provider.FakeProvider. Its
+	// models are only registered while running under go test.
+	ProviderTest ModelProvider = "test"
+
+	TestFake ModelID = "test.fake"
+)
+
+var TestModels = map[ModelID]Model{
+	TestFake: {
+		ID:               TestFake,
+		Name:             "Test: Fake",
+		Provider:         ProviderTest,
+		APIModel:         "fake",
+		ContextWindow:    200_000,
+		DefaultMaxTokens: 4096,
+		SupportsTools:    true,
+	},
+}
+
+func init() {
+	if testing.Testing() {
+		maps.Copy(SupportedModels, TestModels)
+	}
+}
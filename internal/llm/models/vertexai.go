@@ -21,6 +21,7 @@ var VertexAIGeminiModels = map[ModelID]Model{
 		ContextWindow:       GeminiModels[Gemini25Flash].ContextWindow,
 		DefaultMaxTokens:    GeminiModels[Gemini25Flash].DefaultMaxTokens,
 		SupportsAttachments: true,
+		SupportsTools:       GeminiModels[Gemini25Flash].SupportsTools,
 	},
 	VertexAIGemini25: {
 		ID:                  VertexAIGemini25,
@@ -34,5 +35,6 @@ var VertexAIGeminiModels = map[ModelID]Model{
 		ContextWindow:       GeminiModels[Gemini25].ContextWindow,
 		DefaultMaxTokens:    GeminiModels[Gemini25].DefaultMaxTokens,
 		SupportsAttachments: true,
+		SupportsTools:       GeminiModels[Gemini25].SupportsTools,
 	},
 }
@@ -21,6 +21,7 @@ var XAIModels = map[ModelID]Model{
 		CostPer1MOutCached: 0,
 		ContextWindow:      131_072,
 		DefaultMaxTokens:   20_000,
+		SupportsTools:      true,
 	},
 	XAIGrok3MiniBeta: {
 		ID:                 XAIGrok3MiniBeta,
@@ -33,6 +34,7 @@ var XAIModels = map[ModelID]Model{
 		CostPer1MOutCached: 0,
 		ContextWindow:      131_072,
 		DefaultMaxTokens:   20_000,
+		SupportsTools:      true,
 	},
 	XAIGrok3FastBeta: {
 		ID:                 XAIGrok3FastBeta,
@@ -45,6 +47,7 @@ var XAIModels = map[ModelID]Model{
 		CostPer1MOutCached: 0,
 		ContextWindow:      131_072,
 		DefaultMaxTokens:   20_000,
+		SupportsTools:      true,
 	},
 	XAiGrok3MiniFastBeta: {
 		ID:                 XAiGrok3MiniFastBeta,
@@ -57,5 +60,6 @@ var XAIModels = map[ModelID]Model{
 		CostPer1MOutCached: 0,
 		ContextWindow:      131_072,
 		DefaultMaxTokens:   20_000,
+		SupportsTools:      true,
 	},
 }
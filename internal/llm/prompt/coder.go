@@ -172,6 +172,10 @@ func getEnvironmentInfo() string {
 	isGit := isGitRepo(cwd)
 	platform := runtime.GOOS
 	date := time.Now().Format("1/2/2006")
+	projectType := config.ProjectSummary()
+	if projectType == "" {
+		projectType = "unknown"
+	}
 	ls := tools.NewLsTool()
 	r, _ := ls.Run(context.Background(), tools.ToolCall{
 		Input: `{"path":"."}`,
@@ -182,11 +186,12 @@ Working directory: %s
 Is directory a git repo: %s
 Platform: %s
 Today's date: %s
+Project type: %s
 </env>
 <project>
 %s
 </project>
-		`, cwd, boolToYesNo(isGit), platform, date, r.Content)
+		`, cwd, boolToYesNo(isGit), platform, date, projectType, r.Content)
 }
 
 func isGitRepo(dir string) bool {
@@ -0,0 +1,213 @@
+package prompt
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+)
+
+var (
+	changesMu sync.Mutex
+	// contextVersion counts the changes to context files since they were read
+	// into the system prompt
+	contextVersion int
+	// contextChanges is the version of the last change of each changed file
+	contextChanges = make(map[string]int)
+)
+
+// ContextFileStatus describes a context file for introspection
+type ContextFileStatus struct {
+	Path       string    `json:"path"`
+	ModifiedAt time.Time `json:"modified_at,omitzero"`
+	// Stale reports whether the copy of the file in the system prompt differs
+	// from the file on disk, including files created or removed since
+	Stale bool `json:"stale"`
+}
+
+// ContextStatus returns the context files in the system prompt or on disk,
+// in prompt order
+func ContextStatus() []ContextFileStatus {
+	getContextFromPaths()
+	inPrompt := make(map[string]string, len(contextFiles))
+	for _, file := range contextFiles {
+		inPrompt[file.path] = file.content
+	}
+	current := readContextFiles()
+	onDisk := make(map[string]bool, len(current))
+
+	var statuses []ContextFileStatus
+	for _, file := range current {
+		onDisk[file.path] = true
+		status := ContextFileStatus{Path: file.path}
+		if info, err := os.Stat(file.path); err == nil {
+			status.ModifiedAt = info.ModTime()
+		}
+		prompted, ok := inPrompt[file.path]
+		status.Stale = !ok || prompted != file.content
+		statuses = append(statuses, status)
+	}
+	for _, file := range contextFiles {
+		if !onDisk[file.path] {
+			statuses = append(statuses, ContextFileStatus{Path: file.path, Stale: true})
+		}
+	}
+	return statuses
+}
+
+// ContextVersion returns the number of context file changes seen by
+// WatchContext, 0 while the system prompt context is current
+func ContextVersion() int {
+	changesMu.Lock()
+	defer changesMu.Unlock()
+	return contextVersion
+}
+
+// ContextChangesSince returns the context files changed after version
+func ContextChangesSince(version int) []string {
+	changesMu.Lock()
+	defer changesMu.Unlock()
+	var paths []string
+	for path, changed := range contextChanges {
+		if changed > version {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// CurrentContext reads the context files as they are now on disk, the
+// content the system prompt would have if it was built again
+func CurrentContext() string {
+	return joinContextFiles(readContextFiles())
+}
+
+// PromptContext returns the context read into the system prompt
+func PromptContext() string {
+	return getContextFromPaths()
+}
+
+// ContextUpdate renders the note sent along a user message when the context
+// files changed after the system prompt was built. It is sent in the
+// conversation rather than the system prompt so the cached prompt prefix stays
+// valid.
+func ContextUpdate(changed []string, content string) string {
+	var b strings.Builder
+	b.WriteString("<context-update>\n")
+	fmt.Fprintf(&b, "System note: the project context files changed during this session (%s).", strings.Join(changed, ", "))
+	if content == "" {
+		b.WriteString(" They were removed, disregard the Project-Specific Context of the system prompt.\n")
+	} else {
+		b.WriteString(" The updated context below replaces the Project-Specific Context of the system prompt, make sure to follow it instead.\n\n")
+		b.WriteString(content)
+		b.WriteString("\n")
+	}
+	b.WriteString("</context-update>")
+	return b.String()
+}
+
+// WatchContext records the changes to the context files until ctx is done.
+// The directories holding them are watched, so files replaced by editors or
+// created after startup are noticed too.
+func WatchContext(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create context watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	files, dirs := watchedContextPaths()
+	watched := make(map[string]bool)
+	watch := func(dir string) {
+		if watched[dir] {
+			return
+		}
+		if err := watcher.Add(dir); err == nil {
+			watched[dir] = true
+		}
+	}
+	for file := range files {
+		watch(filepath.Dir(file))
+	}
+	for _, dir := range dirs {
+		filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+			if err == nil && d.IsDir() {
+				watch(path)
+			}
+			return nil
+		})
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op == fsnotify.Chmod || !isContextPath(event.Name, files, dirs) {
+				continue
+			}
+			if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+				if event.Op&fsnotify.Create != 0 {
+					watch(event.Name)
+				}
+				continue
+			}
+			logging.Debug("Context file changed", "path", event.Name, "op", event.Op.String())
+			recordContextChange(event.Name)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			logging.Warn("Context watcher error", "error", err)
+		}
+	}
+}
+
+// watchedContextPaths returns the context files, whether they exist or not,
+// and the context directories of the context sources
+func watchedContextPaths() (files map[string]bool, dirs []string) {
+	files = make(map[string]bool)
+	for _, source := range contextSources() {
+		for _, p := range source.paths {
+			path := filepath.Join(source.dir, p)
+			if strings.HasSuffix(p, "/") {
+				dirs = append(dirs, path)
+			} else {
+				files[path] = true
+			}
+		}
+	}
+	return files, dirs
+}
+
+func isContextPath(path string, files map[string]bool, dirs []string) bool {
+	path = filepath.Clean(path)
+	if files[path] {
+		return true
+	}
+	for _, dir := range dirs {
+		if strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+func recordContextChange(path string) {
+	changesMu.Lock()
+	defer changesMu.Unlock()
+	contextVersion++
+	contextChanges[filepath.Clean(path)] = contextVersion
+}
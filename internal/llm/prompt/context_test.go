@@ -0,0 +1,97 @@
+package prompt
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+)
+
+// resetContext forgets the context read into the system prompt
+func resetContext(t *testing.T) {
+	t.Helper()
+	reset := func() {
+		onceContext = sync.Once{}
+		contextContent, contextFiles = "", nil
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func TestWatchContextRecordsChanges(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.NewTestConfig(config.WithWorkingDir(dir))
+	cfg.ContextPaths = []string{"CLAUDE.md", "rules/"}
+	resetContext(t)
+	createTestFiles(t, dir, []string{"CLAUDE.md", "rules/style.md"})
+	assert.Contains(t, PromptContext(), "CLAUDE.md: test content")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, WatchContext(ctx))
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	version := ContextVersion()
+	// Give the watcher time to add its directories
+	time.Sleep(100 * time.Millisecond)
+	claude := filepath.Join(dir, "CLAUDE.md")
+	require.NoError(t, os.WriteFile(claude, []byte("use tabs"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "rules", "naming.md"), []byte("short names"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.md"), []byte("unrelated"), 0o644))
+
+	require.Eventually(t, func() bool {
+		return len(ContextChangesSince(version)) == 2
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Equal(t, []string{claude, filepath.Join(dir, "rules", "naming.md")}, ContextChangesSince(version))
+	assert.Empty(t, ContextChangesSince(ContextVersion()))
+
+	assert.Contains(t, CurrentContext(), "use tabs")
+	assert.NotContains(t, PromptContext(), "use tabs", "the system prompt context is kept")
+}
+
+func TestContextStatus(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.NewTestConfig(config.WithWorkingDir(dir))
+	cfg.ContextPaths = []string{"CLAUDE.md", "OLD.md", "NEW.md"}
+	resetContext(t)
+	createTestFiles(t, dir, []string{"CLAUDE.md", "OLD.md"})
+	PromptContext()
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "OLD.md")))
+	createTestFiles(t, dir, []string{"NEW.md"})
+
+	statuses := ContextStatus()
+	require.Len(t, statuses, 3)
+	assert.Equal(t, filepath.Join(dir, "CLAUDE.md"), statuses[0].Path)
+	assert.False(t, statuses[0].Stale)
+	assert.False(t, statuses[0].ModifiedAt.IsZero())
+	assert.Equal(t, filepath.Join(dir, "NEW.md"), statuses[1].Path)
+	assert.True(t, statuses[1].Stale, "created files are not in the prompt")
+	assert.Equal(t, filepath.Join(dir, "OLD.md"), statuses[2].Path)
+	assert.True(t, statuses[2].Stale, "removed files are still in the prompt")
+	assert.True(t, statuses[2].ModifiedAt.IsZero())
+}
+
+func TestContextUpdate(t *testing.T) {
+	update := ContextUpdate([]string{"/work/CLAUDE.md"}, "# From:/work/CLAUDE.md\nuse tabs")
+	assert.True(t, strings.HasPrefix(update, "<context-update>\n"))
+	assert.Contains(t, update, "(/work/CLAUDE.md)")
+	assert.Contains(t, update, "use tabs\n</context-update>")
+
+	removed := ContextUpdate([]string{"/work/CLAUDE.md"}, "")
+	assert.Contains(t, removed, "They were removed")
+}
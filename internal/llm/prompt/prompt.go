@@ -1,18 +1,34 @@
 package prompt
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"text/template"
 
 	"github.com/caronex/intelligence-interface/internal/core/config"
 	"github.com/caronex/intelligence-interface/internal/llm/models"
 	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/memory"
+	"github.com/caronex/intelligence-interface/internal/message"
+	"github.com/caronex/intelligence-interface/internal/notes"
 )
 
+// learnedPromptMaxBytes bounds the learned observations of an agent its
+// system prompt includes
+const learnedPromptMaxBytes = 4000
+
 func GetAgentPrompt(agentName config.AgentName, provider models.ModelProvider) string {
+	return GetAgentPromptWithout(agentName, provider, nil)
+}
+
+// GetAgentPromptWithout returns the system prompt of an agent leaving out the
+// excluded sources of context
+func GetAgentPromptWithout(agentName config.AgentName, provider models.ModelProvider, excluded []message.ContextSource) string {
 	basePrompt := ""
 	switch agentName {
 	case config.AgentCaronex:
@@ -21,8 +37,38 @@ func GetAgentPrompt(agentName config.AgentName, provider models.ModelProvider) s
 		basePrompt = "You are a helpful assistant"
 	}
 
+	// A prompt configured for the agent comes before its built-in prompt
+	if agentCfg, ok := config.Get().Agents[agentName]; ok {
+		customPrompt, err := agentCfg.CustomSystemPrompt(config.WorkingDirectory())
+		if err != nil {
+			logging.Warn("Failed to load the configured system prompt, using the built-in prompt", "agent", agentName, "error", err)
+		} else if customPrompt != "" {
+			basePrompt = renderCustomPrompt(agentName, customPrompt) + "\n\n" + basePrompt
+		}
+	}
+
+	if artifacts := config.Get().Artifacts; !artifacts.Disabled {
+		basePrompt += fmt.Sprintf("\n\n# Artifacts\nCode blocks of %d bytes or more in your responses are saved as files the user can copy into the project. To save a shorter block, or to name its file, tag the fence with a file name relative to the project, such as ```sql filename=db/schema.sql", artifacts.Threshold())
+	}
+
 	if agentName == config.AgentCaronex {
+		if summary := config.ProjectSummary(); summary != "" {
+			basePrompt += "\n\n# Project\n" + summary
+		}
+		// Add the notes kept across sessions in the workspace memory
+		if cfg := config.Get().Notes; !cfg.Disabled && !slices.Contains(excluded, message.MemoryNotes) {
+			if memory := notes.PromptContext(cfg.PromptBudget()); memory != "" {
+				basePrompt += "\n\n# Workspace Memory\nNotes kept in earlier sessions about this project, keep them in mind and note new ones with memory_write\n" + memory
+			}
+		}
+		// Add what the agent learned in earlier sessions
+		if learned := memory.PromptContext(context.Background(), agentName, learnedPromptMaxBytes); learned != "" {
+			basePrompt += "\n\n# Learned Observations\nWhat you noted with the learn tool, keep it in mind and note new observations the same way\n" + learned
+		}
 		// Add context from project-specific instruction files if they exist
+		if slices.Contains(excluded, message.ContextFiles) {
+			return basePrompt
+		}
 		contextContent := getContextFromPaths()
 		logging.Debug("Context content", "Context", contextContent)
 		if contextContent != "" {
@@ -32,94 +78,162 @@ func GetAgentPrompt(agentName config.AgentName, provider models.ModelProvider) s
 	return basePrompt
 }
 
+// ContextSources returns the sources of context the system prompt of an
+// agent includes when none is excluded
+func ContextSources(agentName config.AgentName) []message.ContextSource {
+	if agentName != config.AgentCaronex {
+		return nil
+	}
+	var sources []message.ContextSource
+	if getContextFromPaths() != "" {
+		sources = append(sources, message.ContextFiles)
+	}
+	if cfg := config.Get().Notes; !cfg.Disabled && notes.PromptContext(cfg.PromptBudget()) != "" {
+		sources = append(sources, message.MemoryNotes)
+	}
+	return sources
+}
+
+// promptData is what the configured system prompt of an agent refers to as a
+// template, such as {{.ProjectType}}
+type promptData struct {
+	// ProjectType lists the stacks of the workspace, such as "go, node"
+	ProjectType  string
+	ProjectTypes []config.ProjectType
+	// ProjectSummary is the line summing up the stacks of the workspace
+	ProjectSummary string
+}
+
+// renderCustomPrompt renders the configured system prompt of an agent as a
+// template, keeping it as written when it is not a valid one
+func renderCustomPrompt(agentName config.AgentName, customPrompt string) string {
+	if !strings.Contains(customPrompt, "{{") {
+		return customPrompt
+	}
+	tmpl, err := template.New(string(agentName)).Parse(customPrompt)
+	if err != nil {
+		logging.Warn("The configured system prompt is not a valid template, using it as written", "agent", agentName, "error", err)
+		return customPrompt
+	}
+	types := config.ProjectTypes()
+	names := make([]string, len(types))
+	for i, typ := range types {
+		names[i] = string(typ)
+	}
+	data := promptData{
+		ProjectType:    strings.Join(names, ", "),
+		ProjectTypes:   types,
+		ProjectSummary: config.ProjectSummary(),
+	}
+	var rendered strings.Builder
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		logging.Warn("Failed to render the configured system prompt, using it as written", "agent", agentName, "error", err)
+		return customPrompt
+	}
+	return rendered.String()
+}
+
 var (
 	onceContext    sync.Once
 	contextContent string
+	// contextFiles are the context files read into the system prompt
+	contextFiles []contextFile
 )
 
 func getContextFromPaths() string {
 	onceContext.Do(func() {
-		var (
-			cfg          = config.Get()
-			workDir      = cfg.WorkingDir
-			contextPaths = cfg.ContextPaths
-		)
-
-		contextContent = processContextPaths(workDir, contextPaths)
+		contextFiles = readContextFiles()
+		contextContent = joinContextFiles(contextFiles)
 	})
 
 	return contextContent
 }
 
+// contextFile is a context file as read into a prompt
+type contextFile struct {
+	path    string
+	content string
+}
+
+// contextSource is a directory and the context paths resolved against it
+type contextSource struct {
+	dir   string
+	paths []string
+}
+
+// contextSources returns the configured context paths of the working
+// directory, followed by those of each workspace root
+func contextSources() []contextSource {
+	cfg := config.Get()
+	sources := []contextSource{{dir: cfg.WorkingDir, paths: cfg.ContextPaths}}
+	for _, name := range config.WorkspaceRootNames() {
+		root := config.WorkspaceRoots()[name]
+		if len(root.ContextPaths) > 0 {
+			sources = append(sources, contextSource{dir: root.Path, paths: root.ContextPaths})
+		}
+	}
+	return sources
+}
+
+// readContextFiles reads the context files of every context source
+func readContextFiles() []contextFile {
+	var files []contextFile
+	for _, source := range contextSources() {
+		files = append(files, readContextPaths(source.dir, source.paths)...)
+	}
+	return files
+}
+
+func joinContextFiles(files []contextFile) string {
+	results := make([]string, len(files))
+	for i, file := range files {
+		results[i] = file.content
+	}
+	return strings.Join(results, "\n")
+}
+
+// processContextPaths reads the context files in the order of paths, walking
+// directories in lexical order, so the system prompt is byte-identical across
+// runs and the provider can serve it from its prompt cache.
 func processContextPaths(workDir string, paths []string) string {
-	var (
-		wg       sync.WaitGroup
-		resultCh = make(chan string)
-	)
+	return joinContextFiles(readContextPaths(workDir, paths))
+}
 
+func readContextPaths(workDir string, paths []string) []contextFile {
 	// Track processed files to avoid duplicates
 	processedFiles := make(map[string]bool)
-	var processedMutex sync.Mutex
-
-	for _, path := range paths {
-		wg.Add(1)
-		go func(p string) {
-			defer wg.Done()
-
-			if strings.HasSuffix(p, "/") {
-				filepath.WalkDir(filepath.Join(workDir, p), func(path string, d os.DirEntry, err error) error {
-					if err != nil {
-						return err
-					}
-					if !d.IsDir() {
-						// Check if we've already processed this file (case-insensitive)
-						processedMutex.Lock()
-						lowerPath := strings.ToLower(path)
-						if !processedFiles[lowerPath] {
-							processedFiles[lowerPath] = true
-							processedMutex.Unlock()
-
-							if result := processFile(path); result != "" {
-								resultCh <- result
-							}
-						} else {
-							processedMutex.Unlock()
-						}
-					}
-					return nil
-				})
-			} else {
-				fullPath := filepath.Join(workDir, p)
-
-				// Check if we've already processed this file (case-insensitive)
-				processedMutex.Lock()
-				lowerPath := strings.ToLower(fullPath)
-				if !processedFiles[lowerPath] {
-					processedFiles[lowerPath] = true
-					processedMutex.Unlock()
-
-					result := processFile(fullPath)
-					if result != "" {
-						resultCh <- result
-					}
-				} else {
-					processedMutex.Unlock()
-				}
-			}
-		}(path)
-	}
+	results := make([]contextFile, 0)
+
+	addFile := func(path string) {
+		// Check if we've already processed this file (case-insensitive)
+		lowerPath := strings.ToLower(path)
+		if processedFiles[lowerPath] {
+			return
+		}
+		processedFiles[lowerPath] = true
 
-	go func() {
-		wg.Wait()
-		close(resultCh)
-	}()
+		if result := processFile(path); result != "" {
+			results = append(results, contextFile{path: path, content: result})
+		}
+	}
 
-	results := make([]string, 0)
-	for result := range resultCh {
-		results = append(results, result)
+	for _, p := range paths {
+		if strings.HasSuffix(p, "/") {
+			filepath.WalkDir(filepath.Join(workDir, p), func(path string, d os.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if !d.IsDir() {
+					addFile(path)
+				}
+				return nil
+			})
+		} else {
+			addFile(filepath.Join(workDir, p))
+		}
 	}
 
-	return strings.Join(results, "\n")
+	return results
 }
 
 func processFile(filePath string) string {
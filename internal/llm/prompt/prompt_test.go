@@ -1,12 +1,17 @@
 package prompt
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/db"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/memory"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -59,3 +64,86 @@ func createTestFiles(t *testing.T, tmpDir string, testFiles []string) {
 		}
 	}
 }
+
+func TestGetAgentPromptConfigured(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.NewTestConfig(config.WithWorkingDir(dir))
+	cfg.ContextPaths = []string{"CLAUDE.md"}
+	resetContext(t)
+	createTestFiles(t, dir, []string{"CLAUDE.md", "prompts/caronex.md"})
+	builtin := CaronexPrompt(models.ProviderTest)
+
+	tests := []struct {
+		name   string
+		agent  config.Agent
+		prefix string
+	}{
+		{name: "built-in prompt", prefix: builtin},
+		{name: "inline prompt", agent: config.Agent{SystemPrompt: "Answer in French."}, prefix: "Answer in French.\n\n" + builtin},
+		{name: "prompt file", agent: config.Agent{SystemPromptPath: "prompts/caronex.md"}, prefix: "prompts/caronex.md: test content\n\n" + builtin},
+		{
+			name:   "inline prompt over prompt file",
+			agent:  config.Agent{SystemPrompt: "Answer in French.", SystemPromptPath: "prompts/caronex.md"},
+			prefix: "Answer in French.\n\n" + builtin,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, config.Update(func(cfg *config.Config) error {
+				cfg.Agents[config.AgentCaronex] = tt.agent
+				return nil
+			}))
+			got := GetAgentPrompt(config.AgentCaronex, models.ProviderTest)
+			assert.True(t, strings.HasPrefix(got, tt.prefix), "prompt starts with %q", tt.prefix)
+			assert.True(t, strings.HasSuffix(got, "CLAUDE.md: test content"), "context files come after the agent prompt")
+		})
+	}
+}
+
+func TestGetAgentPromptProjectType(t *testing.T) {
+	dir := t.TempDir()
+	config.NewTestConfig(config.WithWorkingDir(dir))
+	resetContext(t)
+	createTestFiles(t, dir, []string{"go.mod", "web/package.json"})
+
+	tests := []struct {
+		name   string
+		prompt string
+		prefix string
+	}{
+		{name: "template", prompt: "This is a {{.ProjectType}} project.", prefix: "This is a go project."},
+		{name: "summary", prompt: "{{.ProjectSummary}}", prefix: "Go (go.mod) project"},
+		{name: "invalid template kept as written", prompt: "Keep {{ in mind", prefix: "Keep {{ in mind"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, config.Update(func(cfg *config.Config) error {
+				cfg.Agents[config.AgentCaronex] = config.Agent{SystemPrompt: tt.prompt}
+				return nil
+			}))
+			got := GetAgentPrompt(config.AgentCaronex, models.ProviderTest)
+			assert.True(t, strings.HasPrefix(got, tt.prefix+"\n\n"), "prompt starts with %q", tt.prefix)
+			assert.Contains(t, got, "# Project\nGo (go.mod) project")
+		})
+	}
+}
+
+func TestGetAgentPromptLearned(t *testing.T) {
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
+	cfg.Data.Directory = t.TempDir()
+	resetContext(t)
+	conn, err := db.Connect()
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	assert.NotContains(t, GetAgentPrompt(config.AgentCaronex, models.ProviderTest), "# Learned Observations")
+
+	service := memory.NewService(db.New(conn))
+	memory.SetCurrent(service)
+	t.Cleanup(func() { memory.SetCurrent(nil) })
+	_, err = service.Remember(context.Background(), config.AgentCaronex, "the user prefers table tests")
+	require.NoError(t, err)
+
+	assert.Contains(t, GetAgentPrompt(config.AgentCaronex, models.ProviderTest), "# Learned Observations\n")
+	assert.Contains(t, GetAgentPrompt(config.AgentCaronex, models.ProviderTest), "\n- the user prefers table tests")
+}
@@ -14,3 +14,13 @@ Focus on information that would be helpful for continuing the conversation, incl
 
 Your summary should be comprehensive enough to provide context but concise enough to be quickly understood.`
 }
+
+// ChangeSummaryPrompt is the system prompt of the summarizer describing the
+// changes an autonomous run made to the workspace from their diff
+func ChangeSummaryPrompt(_ models.ModelProvider) string {
+	return `You are a helpful AI assistant tasked with summarizing changes to a codebase.
+
+You are given the unified diff of the files an agent created, modified and deleted while working on a task on its own. Describe in a few sentences what changed and why it matters, grouping related files, as a reviewer reading the summary before the diff would need. Mention anything that looks unfinished or risky, such as deleted files or disabled tests.
+
+Do not list every file, do not quote the diff, and do not speculate beyond what the diff shows. The diff may be cut, say so when the summary only covers part of it.`
+}
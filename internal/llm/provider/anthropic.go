@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 	"time"
 
@@ -40,8 +41,18 @@ func newAnthropicClient(opts providerClientOptions) AnthropicClient {
 	for _, o := range opts.anthropicOptions {
 		o(&anthropicOpts)
 	}
+	// Models without prompt caching reject cache_control blocks
+	if !opts.model.SupportsPromptCache() {
+		anthropicOpts.disableCache = true
+	}
+	if opts.generation.FrequencyPenalty != nil {
+		ignoredParam(opts.model, "frequencyPenalty")
+	}
+	if opts.generation.PresencePenalty != nil {
+		ignoredParam(opts.model, "presencePenalty")
+	}
 
-	anthropicClientOptions := []option.RequestOption{}
+	anthropicClientOptions := []option.RequestOption{option.WithHTTPClient(newHTTPClient(opts.timeouts))}
 	if opts.apiKey != "" {
 		anthropicClientOptions = append(anthropicClientOptions, option.WithAPIKey(opts.apiKey))
 	}
@@ -118,11 +129,17 @@ func (a *anthropicClient) convertMessages(messages []message.Message) (anthropic
 	return
 }
 
-func (a *anthropicClient) convertTools(tools []tools.BaseTool) []anthropic.ToolUnionParam {
-	anthropicTools := make([]anthropic.ToolUnionParam, len(tools))
+func (a *anthropicClient) convertTools(baseTools []tools.BaseTool) []anthropic.ToolUnionParam {
+	anthropicTools := make([]anthropic.ToolUnionParam, len(baseTools))
+
+	// Sort by name so the cached tool definitions stay identical across turns
+	infos := make([]tools.ToolInfo, len(baseTools))
+	for i, tool := range baseTools {
+		infos[i] = tool.Info()
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
 
-	for i, tool := range tools {
-		info := tool.Info()
+	for i, info := range infos {
 		toolParam := anthropic.ToolParam{
 			Name:        info.Name,
 			Description: anthropic.String(info.Description),
@@ -132,7 +149,7 @@ func (a *anthropicClient) convertTools(tools []tools.BaseTool) []anthropic.ToolU
 			},
 		}
 
-		if i == len(tools)-1 && !a.options.disableCache {
+		if i == len(infos)-1 && !a.options.disableCache {
 			toolParam.CacheControl = anthropic.CacheControlEphemeralParam{
 				Type: "ephemeral",
 			}
@@ -154,6 +171,8 @@ func (a *anthropicClient) finishReason(reason string) message.FinishReason {
 		return message.FinishReasonToolUse
 	case "stop_sequence":
 		return message.FinishReasonEndTurn
+	case "refusal":
+		return message.FinishReasonContentFilter
 	default:
 		return message.FinishReasonUnknown
 	}
@@ -165,6 +184,10 @@ func (a *anthropicClient) preparedMessages(messages []anthropic.MessageParam, to
 	isUser := lastMessage.Role == anthropic.MessageParamRoleUser
 	messageContent := ""
 	temperature := anthropic.Float(0)
+	if t := a.providerOptions.generation.Temperature; t != nil {
+		// Anthropic temperatures range from 0 to 1
+		temperature = anthropic.Float(min(*t, 1))
+	}
 	if isUser {
 		for _, m := range lastMessage.Content {
 			if m.OfRequestTextBlock != nil && m.OfRequestTextBlock.Text != "" {
@@ -178,26 +201,35 @@ func (a *anthropicClient) preparedMessages(messages []anthropic.MessageParam, to
 					Type:         "enabled",
 				},
 			}
+			// Thinking requires the default temperature
+			if a.providerOptions.generation.Temperature != nil {
+				ignoredParam(a.providerOptions.model, "temperature")
+			}
 			temperature = anthropic.Float(1)
 		}
 	}
 
-	return anthropic.MessageNewParams{
-		Model:       anthropic.Model(a.providerOptions.model.APIModel),
-		MaxTokens:   a.providerOptions.maxTokens,
-		Temperature: temperature,
-		Messages:    messages,
-		Tools:       tools,
-		Thinking:    thinkingParam,
-		System: []anthropic.TextBlockParam{
-			{
-				Text: a.providerOptions.systemMessage,
-				CacheControl: anthropic.CacheControlEphemeralParam{
-					Type: "ephemeral",
-				},
-			},
-		},
+	system := anthropic.TextBlockParam{Text: a.providerOptions.systemMessage}
+	if !a.options.disableCache {
+		system.CacheControl = anthropic.CacheControlEphemeralParam{
+			Type: "ephemeral",
+		}
+	}
+
+	params := anthropic.MessageNewParams{
+		Model:         anthropic.Model(a.providerOptions.model.APIModel),
+		MaxTokens:     a.providerOptions.maxTokens,
+		Temperature:   temperature,
+		Messages:      messages,
+		Tools:         tools,
+		Thinking:      thinkingParam,
+		System:        []anthropic.TextBlockParam{system},
+		StopSequences: a.providerOptions.generation.Stop,
+	}
+	if topP := a.providerOptions.generation.TopP; topP != nil {
+		params.TopP = anthropic.Float(*topP)
 	}
+	return params
 }
 
 func (a *anthropicClient) send(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (resposne *ProviderResponse, err error) {
@@ -205,7 +237,7 @@ func (a *anthropicClient) send(ctx context.Context, messages []message.Message,
 	cfg := config.Get()
 	if cfg.Debug {
 		jsonData, _ := json.Marshal(preparedMessages)
-		logging.Debug("Prepared messages", "messages", string(jsonData))
+		logging.FromContext(ctx).Debug("Prepared messages", "messages", string(jsonData))
 	}
 
 	attempts := 0
@@ -217,13 +249,13 @@ func (a *anthropicClient) send(ctx context.Context, messages []message.Message,
 		)
 		// If there is an error we are going to see if we can retry the call
 		if err != nil {
-			logging.Error("Error in Anthropic API call", "error", err)
+			logging.FromContext(ctx).Error("Error in Anthropic API call", "error", err)
 			retry, after, retryErr := a.shouldRetry(attempts, err)
 			if retryErr != nil {
 				return nil, retryErr
 			}
 			if retry {
-				logging.WarnPersist(fmt.Sprintf("Retrying due to rate limit... attempt %d of %d", attempts, maxRetries), logging.PersistTimeArg, time.Millisecond*time.Duration(after+100))
+				logging.FromContext(ctx).WarnPersist(fmt.Sprintf("Retrying due to rate limit... attempt %d of %d", attempts, maxRetries), logging.PersistTimeArg, time.Millisecond*time.Duration(after+100))
 				select {
 				case <-ctx.Done():
 					return nil, ctx.Err()
@@ -254,7 +286,7 @@ func (a *anthropicClient) stream(ctx context.Context, messages []message.Message
 	cfg := config.Get()
 	if cfg.Debug {
 		// jsonData, _ := json.Marshal(preparedMessages)
-		// logging.Debug("Prepared messages", "messages", string(jsonData))
+		// logging.FromContext(ctx).Debug("Prepared messages", "messages", string(jsonData))
 	}
 	attempts := 0
 	eventChan := make(chan ProviderEvent)
@@ -272,7 +304,7 @@ func (a *anthropicClient) stream(ctx context.Context, messages []message.Message
 				event := anthropicStream.Current()
 				err := accumulatedMessage.Accumulate(event)
 				if err != nil {
-					logging.Warn("Error accumulating message", "error", err)
+					logging.FromContext(ctx).Warn("Error accumulating message", "error", err)
 					continue
 				}
 
@@ -361,7 +393,7 @@ func (a *anthropicClient) stream(ctx context.Context, messages []message.Message
 				return
 			}
 			if retry {
-				logging.WarnPersist(fmt.Sprintf("Retrying due to rate limit... attempt %d of %d", attempts, maxRetries), logging.PersistTimeArg, time.Millisecond*time.Duration(after+100))
+				logging.FromContext(ctx).WarnPersist(fmt.Sprintf("Retrying due to rate limit... attempt %d of %d", attempts, maxRetries), logging.PersistTimeArg, time.Millisecond*time.Duration(after+100))
 				select {
 				case <-ctx.Done():
 					// context cancelled
@@ -391,12 +423,12 @@ func (a *anthropicClient) shouldRetry(attempts int, err error) (bool, int64, err
 		return false, 0, err
 	}
 
-	if apierr.StatusCode != 429 && apierr.StatusCode != 529 {
+	if !retryLater(a.providerOptions.model, err) {
 		return false, 0, err
 	}
 
 	if attempts > maxRetries {
-		return false, 0, fmt.Errorf("maximum retry attempts reached for rate limit: %d retries", maxRetries)
+		return false, 0, fmt.Errorf("maximum retry attempts reached for rate limit: %d retries: %w", maxRetries, err)
 	}
 
 	retryMs := 0
@@ -442,6 +474,78 @@ func (a *anthropicClient) usage(msg anthropic.Message) TokenUsage {
 	}
 }
 
+// submitBatch submits the requests to the Message Batches API
+func (a *anthropicClient) submitBatch(ctx context.Context, requests []BatchRequest) (string, error) {
+	params := anthropic.MessageBatchNewParams{Requests: make([]anthropic.MessageBatchNewParamsRequest, len(requests))}
+	for i, request := range requests {
+		prepared := a.preparedMessages(a.convertMessages(request.Messages), nil)
+		params.Requests[i] = anthropic.MessageBatchNewParamsRequest{
+			CustomID: request.CustomID,
+			Params: anthropic.MessageBatchNewParamsRequestParams{
+				Model:         prepared.Model,
+				MaxTokens:     prepared.MaxTokens,
+				Messages:      prepared.Messages,
+				System:        prepared.System,
+				Temperature:   prepared.Temperature,
+				TopP:          prepared.TopP,
+				StopSequences: prepared.StopSequences,
+				Thinking:      prepared.Thinking,
+			},
+		}
+	}
+	batch, err := a.client.Messages.Batches.New(ctx, params)
+	if err != nil {
+		return "", err
+	}
+	return batch.ID, nil
+}
+
+// pollBatch returns the state of a message batch, streaming its results once
+// it ended
+func (a *anthropicClient) pollBatch(ctx context.Context, batchID string) (BatchState, error) {
+	batch, err := a.client.Messages.Batches.Get(ctx, batchID)
+	if err != nil {
+		return BatchState{}, err
+	}
+	if batch.ProcessingStatus != anthropic.MessageBatchProcessingStatusEnded {
+		return BatchState{Status: BatchInProgress}, nil
+	}
+
+	stream := a.client.Messages.Batches.ResultsStreaming(ctx, batchID)
+	defer stream.Close()
+	state := BatchState{Status: BatchEnded}
+	for stream.Next() {
+		result := stream.Current()
+		switch result.Result.Type {
+		case "succeeded":
+			msg := result.Result.Message
+			content := ""
+			for _, block := range msg.Content {
+				if text, ok := block.AsAny().(anthropic.TextBlock); ok {
+					content += text.Text
+				}
+			}
+			state.Results = append(state.Results, BatchResult{
+				CustomID: result.CustomID,
+				Response: &ProviderResponse{
+					Content:      content,
+					ToolCalls:    a.toolCalls(msg),
+					Usage:        a.usage(msg),
+					FinishReason: a.finishReason(string(msg.StopReason)),
+				},
+			})
+		case "errored":
+			state.Results = append(state.Results, BatchResult{CustomID: result.CustomID, Err: result.Result.Error.Error.Message})
+		default:
+			state.Results = append(state.Results, BatchResult{CustomID: result.CustomID, Err: "request " + result.Result.Type})
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return BatchState{}, fmt.Errorf("failed to read the results of batch %s: %w", batchID, err)
+	}
+	return state, nil
+}
+
 func WithAnthropicBedrock(useBedrock bool) AnthropicOption {
 	return func(options *anthropicOptions) {
 		options.useBedrock = useBedrock
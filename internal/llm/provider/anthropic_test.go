@@ -0,0 +1,143 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/llm/tools"
+	"github.com/caronex/intelligence-interface/internal/message"
+)
+
+type stubTool struct {
+	name string
+}
+
+func (s stubTool) Info() tools.ToolInfo {
+	return tools.ToolInfo{
+		Name:        s.name,
+		Description: "stub " + s.name,
+		Parameters: map[string]any{
+			"path":    map[string]any{"type": "string"},
+			"pattern": map[string]any{"type": "string"},
+			"limit":   map[string]any{"type": "integer"},
+		},
+	}
+}
+
+func (s stubTool) Run(ctx context.Context, params tools.ToolCall) (tools.ToolResponse, error) {
+	return tools.NewTextResponse(""), nil
+}
+
+func textMessage(role message.MessageRole, text string) message.Message {
+	return message.Message{Role: role, Parts: []message.ContentPart{message.TextContent{Text: text}}}
+}
+
+func newTestAnthropicClient(model models.Model, opts ...AnthropicOption) *anthropicClient {
+	return newAnthropicClient(providerClientOptions{
+		model:            model,
+		maxTokens:        1024,
+		systemMessage:    "You are a test assistant\n\n# Project-Specific Context\n# From:AGENTS.md\nbe brief",
+		anthropicOptions: opts,
+	}).(*anthropicClient)
+}
+
+// stablePrefix marshals the parts of a request that should be served from the
+// prompt cache on every turn.
+func stablePrefix(t *testing.T, client *anthropicClient, messages []message.Message, toolset []tools.BaseTool) string {
+	t.Helper()
+	params := client.preparedMessages(client.convertMessages(messages), client.convertTools(toolset))
+	prefix, err := json.Marshal(map[string]any{"system": params.System, "tools": params.Tools})
+	if err != nil {
+		t.Fatalf("failed to marshal prefix: %v", err)
+	}
+	return string(prefix)
+}
+
+func TestAnthropicPrefixStableAcrossTurns(t *testing.T) {
+	client := newTestAnthropicClient(models.SupportedModels[models.Claude37Sonnet])
+
+	first := []message.Message{textMessage(message.User, "list the files")}
+	second := append(first,
+		textMessage(message.Assistant, "main.go and go.mod"),
+		textMessage(message.User, "now read main.go"),
+	)
+
+	// Tools may be registered in a different order between turns, e.g. when MCP tools load
+	turn1 := stablePrefix(t, client, first, []tools.BaseTool{stubTool{"view"}, stubTool{"ls"}, stubTool{"grep"}})
+	turn2 := stablePrefix(t, client, second, []tools.BaseTool{stubTool{"grep"}, stubTool{"view"}, stubTool{"ls"}})
+
+	if turn1 != turn2 {
+		t.Fatalf("prefix changed between turns:\nturn 1: %s\nturn 2: %s", turn1, turn2)
+	}
+	if got := strings.Count(turn1, `"cache_control"`); got != 2 {
+		t.Errorf("prefix has %d cache breakpoints, want 2 (system and last tool)", got)
+	}
+}
+
+func TestAnthropicCacheDisabled(t *testing.T) {
+	uncached := models.SupportedModels[models.Claude37Sonnet]
+	uncached.CostPer1MInCached = 0
+
+	for name, client := range map[string]*anthropicClient{
+		"config":   newTestAnthropicClient(models.SupportedModels[models.Claude37Sonnet], WithAnthropicDisableCache()),
+		"no cache": newTestAnthropicClient(uncached),
+	} {
+		messages := []message.Message{textMessage(message.User, "hello")}
+		params := client.preparedMessages(client.convertMessages(messages), client.convertTools([]tools.BaseTool{stubTool{"ls"}}))
+		data, err := json.Marshal(params)
+		if err != nil {
+			t.Fatalf("%s: failed to marshal params: %v", name, err)
+		}
+		if strings.Contains(string(data), "cache_control") {
+			t.Errorf("%s: request contains cache_control with caching disabled: %s", name, data)
+		}
+	}
+}
+
+func TestAnthropicGenerationParams(t *testing.T) {
+	temperature, topP, penalty := 0.2, 0.9, 1.0
+	client := newAnthropicClient(providerClientOptions{
+		model:     models.SupportedModels[models.Claude37Sonnet],
+		maxTokens: 1024,
+		generation: config.GenerationParams{
+			Temperature:      &temperature,
+			TopP:             &topP,
+			FrequencyPenalty: &penalty,
+			Stop:             []string{"END"},
+		},
+	}).(*anthropicClient)
+
+	messages := []message.Message{textMessage(message.User, "hello")}
+	data, err := json.Marshal(client.preparedMessages(client.convertMessages(messages), nil))
+	if err != nil {
+		t.Fatalf("failed to marshal params: %v", err)
+	}
+	for _, want := range []string{`"temperature":0.2`, `"top_p":0.9`, `"stop_sequences":["END"]`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("request does not contain %s: %s", want, data)
+		}
+	}
+	if strings.Contains(string(data), "frequency_penalty") {
+		t.Errorf("request contains an unsupported parameter: %s", data)
+	}
+}
+
+func TestAnthropicFinishReason(t *testing.T) {
+	client := newTestAnthropicClient(models.SupportedModels[models.Claude37Sonnet])
+	for reason, want := range map[string]message.FinishReason{
+		"end_turn":      message.FinishReasonEndTurn,
+		"stop_sequence": message.FinishReasonEndTurn,
+		"max_tokens":    message.FinishReasonMaxTokens,
+		"tool_use":      message.FinishReasonToolUse,
+		"refusal":       message.FinishReasonContentFilter,
+		"pause_turn":    message.FinishReasonUnknown,
+	} {
+		if got := client.finishReason(reason); got != want {
+			t.Errorf("finishReason(%q) = %q, want %q", reason, got, want)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package provider
+
+import (
+	"context"
+	"errors"
+
+	"github.com/caronex/intelligence-interface/internal/connectivity"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/message"
+)
+
+// BatchDiscount is the share of the regular price the batch APIs bill: the
+// OpenAI and Anthropic batches cost half
+const BatchDiscount = 0.5
+
+// ErrBatchUnsupported is returned for the providers without a batch API
+var ErrBatchUnsupported = errors.New("provider has no batch API")
+
+// BatchRequest is one request of a batch, its result carrying the same
+// CustomID
+type BatchRequest struct {
+	CustomID string
+	Messages []message.Message
+}
+
+// BatchStatus is the state of a batch at the provider
+type BatchStatus string
+
+const (
+	// BatchInProgress batches are still being processed
+	BatchInProgress BatchStatus = "in_progress"
+	// BatchEnded batches have a result for their requests, a failure for the
+	// ones that were not processed
+	BatchEnded BatchStatus = "ended"
+	// BatchFailed batches were rejected as a whole, none of their requests
+	// having a result
+	BatchFailed BatchStatus = "failed"
+)
+
+// BatchResult is the result of one request of an ended batch
+type BatchResult struct {
+	CustomID string
+	// Response is the response to the request, nil when it failed
+	Response *ProviderResponse
+	// Err is why the request failed
+	Err string
+}
+
+// BatchState is what a provider reports of a batch
+type BatchState struct {
+	Status BatchStatus
+	// Results are set once the batch ended, in no particular order. The
+	// requests without a result were not processed.
+	Results []BatchResult
+	// Err is why the batch failed
+	Err string
+}
+
+// BatchCost returns the price of the usage for model when billed through a
+// batch API
+func (u TokenUsage) BatchCost(model models.Model) float64 {
+	return u.Cost(model) * BatchDiscount
+}
+
+// BatchProvider submits requests through the batch API of a provider, which
+// answers them within hours at a discount. Only the offline jobs that opt into
+// batch mode use it; the interactive flows always send their requests through
+// Provider.
+type BatchProvider interface {
+	Provider
+	// SubmitBatch submits requests and returns the ID of their batch at the
+	// provider
+	SubmitBatch(ctx context.Context, requests []BatchRequest) (string, error)
+	// PollBatch returns the state of a batch, with its results once it ended
+	PollBatch(ctx context.Context, batchID string) (BatchState, error)
+}
+
+// batchClient is implemented by the clients of the providers with a batch API
+type batchClient interface {
+	submitBatch(ctx context.Context, requests []BatchRequest) (string, error)
+	pollBatch(ctx context.Context, batchID string) (BatchState, error)
+}
+
+// AsBatch returns the batch API of a provider, false when it has none
+func AsBatch(p Provider) (BatchProvider, bool) {
+	batch, ok := p.(BatchProvider)
+	if !ok {
+		return nil, false
+	}
+	if s, ok := p.(interface{ supportsBatch() bool }); ok && !s.supportsBatch() {
+		return nil, false
+	}
+	return batch, true
+}
+
+// supportsBatch reports whether the provider has a batch API: the OpenAI and
+// Anthropic APIs, not the compatible ones sharing their clients
+func (p *baseProvider[C]) supportsBatch() bool {
+	if _, ok := any(p.client).(batchClient); !ok {
+		return false
+	}
+	return p.options.model.Provider == models.ProviderOpenAI || p.options.model.Provider == models.ProviderAnthropic
+}
+
+func (p *baseProvider[C]) SubmitBatch(ctx context.Context, requests []BatchRequest) (string, error) {
+	client, ok := any(p.client).(batchClient)
+	if !ok || !p.supportsBatch() {
+		return "", ErrBatchUnsupported
+	}
+	if connectivity.IsOffline() {
+		return "", connectivity.ErrOffline
+	}
+	cleaned := make([]BatchRequest, len(requests))
+	for i, request := range requests {
+		cleaned[i] = BatchRequest{CustomID: request.CustomID, Messages: p.cleanMessages(request.Messages)}
+	}
+	return client.submitBatch(ctx, cleaned)
+}
+
+func (p *baseProvider[C]) PollBatch(ctx context.Context, batchID string) (BatchState, error) {
+	client, ok := any(p.client).(batchClient)
+	if !ok || !p.supportsBatch() {
+		return BatchState{}, ErrBatchUnsupported
+	}
+	if connectivity.IsOffline() {
+		return BatchState{}, connectivity.ErrOffline
+	}
+	return client.pollBatch(ctx, batchID)
+}
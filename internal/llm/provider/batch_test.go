@@ -0,0 +1,169 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/message"
+)
+
+func TestBatchCost(t *testing.T) {
+	model := models.Model{CostPer1MIn: 3, CostPer1MOut: 15}
+	usage := TokenUsage{InputTokens: 1_000_000, OutputTokens: 100_000}
+	if got := usage.BatchCost(model); got != 2.25 {
+		t.Errorf("BatchCost() = %v, want half of %v", got, usage.Cost(model))
+	}
+}
+
+func TestAsBatch(t *testing.T) {
+	openaiModel := models.SupportedModels[models.GPT41]
+	openai := &baseProvider[OpenAIClient]{
+		options: providerClientOptions{model: openaiModel},
+		client:  newOpenAIClient(providerClientOptions{model: openaiModel}),
+	}
+	if _, ok := AsBatch(openai); !ok {
+		t.Error("AsBatch() = false for OpenAI")
+	}
+
+	// The compatible APIs share the OpenAI client but have no batch API
+	compatible := openaiModel
+	compatible.Provider = models.ProviderOpenRouter
+	openrouter := &baseProvider[OpenAIClient]{
+		options: providerClientOptions{model: compatible},
+		client:  newOpenAIClient(providerClientOptions{model: compatible}),
+	}
+	if _, ok := AsBatch(openrouter); ok {
+		t.Error("AsBatch() = true for OpenRouter")
+	}
+	if _, err := openrouter.SubmitBatch(context.Background(), nil); err != ErrBatchUnsupported {
+		t.Errorf("SubmitBatch() error = %v, want %v", err, ErrBatchUnsupported)
+	}
+
+	fake := NewFakeProvider(models.TestModels[models.TestFake])
+	if _, ok := AsBatch(fake); !ok {
+		t.Error("AsBatch() = false for the fake")
+	}
+	if _, ok := AsBatch(struct{ Provider }{fake}); ok {
+		t.Error("AsBatch() = true for a provider without batch methods")
+	}
+}
+
+func TestOpenAIBatchRoundTrip(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		input  string
+		polled int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/files":
+			file, _, err := r.FormFile("file")
+			if err != nil {
+				t.Errorf("upload without a file: %v", err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			data, _ := io.ReadAll(file)
+			input = string(data)
+			if purpose := r.FormValue("purpose"); purpose != "batch" {
+				t.Errorf("file uploaded with purpose %q, want batch", purpose)
+			}
+			io.WriteString(w, `{"id":"file-in","object":"file","purpose":"batch","filename":"batch.jsonl","bytes":1,"created_at":1,"status":"processed"}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/batches":
+			body, _ := io.ReadAll(r.Body)
+			if !strings.Contains(string(body), `"input_file_id":"file-in"`) {
+				t.Errorf("batch created from another file: %s", body)
+			}
+			io.WriteString(w, `{"id":"batch-1","object":"batch","status":"validating","endpoint":"/v1/chat/completions","input_file_id":"file-in","completion_window":"24h","created_at":1}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/batches/batch-1":
+			polled++
+			if polled == 1 {
+				io.WriteString(w, `{"id":"batch-1","object":"batch","status":"in_progress","endpoint":"/v1/chat/completions","input_file_id":"file-in","completion_window":"24h","created_at":1}`)
+				return
+			}
+			io.WriteString(w, `{"id":"batch-1","object":"batch","status":"completed","endpoint":"/v1/chat/completions","input_file_id":"file-in","completion_window":"24h","created_at":1,"output_file_id":"file-out","error_file_id":"file-err"}`)
+		case r.URL.Path == "/files/file-out/content":
+			io.WriteString(w, `{"custom_id":"a","response":{"status_code":200,"body":{"id":"c","object":"chat.completion","created":1,"model":"gpt-4.1","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"Title A"}}],"usage":{"prompt_tokens":10,"completion_tokens":2,"total_tokens":12}}}}`+"\n")
+			io.WriteString(w, `{"custom_id":"b","response":{"status_code":429,"body":{"error":{"message":"rate limited"}}}}`+"\n")
+		case r.URL.Path == "/files/file-err/content":
+			io.WriteString(w, `{"custom_id":"c","error":{"code":"invalid","message":"bad request"}}`+"\n")
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := newOpenAIClient(providerClientOptions{
+		model:         models.SupportedModels[models.GPT41],
+		maxTokens:     80,
+		apiKey:        "test",
+		timeouts:      config.ProviderTimeouts{}.Durations(),
+		openaiOptions: []OpenAIOption{WithOpenAIBaseURL(server.URL)},
+	}).(*openaiClient)
+	ctx := context.Background()
+
+	batchID, err := client.submitBatch(ctx, []BatchRequest{
+		{CustomID: "a", Messages: []message.Message{textMessage(message.User, "first")}},
+		{CustomID: "b", Messages: []message.Message{textMessage(message.User, "second")}},
+	})
+	if err != nil {
+		t.Fatalf("submitBatch() error = %v", err)
+	}
+	if batchID != "batch-1" {
+		t.Errorf("submitBatch() = %q, want batch-1", batchID)
+	}
+	mu.Lock()
+	lines := strings.Split(strings.TrimSpace(input), "\n")
+	mu.Unlock()
+	if len(lines) != 2 {
+		t.Fatalf("uploaded %d requests, want 2: %s", len(lines), input)
+	}
+	var line struct {
+		CustomID string         `json:"custom_id"`
+		URL      string         `json:"url"`
+		Body     map[string]any `json:"body"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &line); err != nil {
+		t.Fatalf("invalid request line %s: %v", lines[0], err)
+	}
+	if line.CustomID != "a" || line.URL != "/v1/chat/completions" || line.Body["model"] != "gpt-4.1" {
+		t.Errorf("unexpected request line: %s", lines[0])
+	}
+
+	state, err := client.pollBatch(ctx, batchID)
+	if err != nil || state.Status != BatchInProgress {
+		t.Fatalf("pollBatch() = %+v, %v, want in progress", state, err)
+	}
+	state, err = client.pollBatch(ctx, batchID)
+	if err != nil {
+		t.Fatalf("pollBatch() error = %v", err)
+	}
+	if state.Status != BatchEnded || len(state.Results) != 3 {
+		t.Fatalf("pollBatch() = %+v, want 3 results of an ended batch", state)
+	}
+	results := make(map[string]BatchResult)
+	for _, result := range state.Results {
+		results[result.CustomID] = result
+	}
+	if r := results["a"]; r.Response == nil || r.Response.Content != "Title A" || r.Response.Usage.OutputTokens != 2 {
+		t.Errorf("result a = %+v, want the completion", r)
+	}
+	if r := results["b"]; r.Response != nil || !strings.Contains(r.Err, "status 429") {
+		t.Errorf("result b = %+v, want the error status", r)
+	}
+	if r := results["c"]; r.Err != "bad request" {
+		t.Errorf("result c = %+v, want the error of the error file", r)
+	}
+}
@@ -0,0 +1,301 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go"
+	"google.golang.org/genai"
+
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+)
+
+// ErrorCategory is the kind of failure behind a provider error, whatever the
+// provider reporting it
+type ErrorCategory string
+
+const (
+	ErrorAuth          ErrorCategory = "auth"
+	ErrorQuota         ErrorCategory = "quota"
+	ErrorRateLimit     ErrorCategory = "rate-limit"
+	ErrorModelNotFound ErrorCategory = "model-not-found"
+	ErrorContentFilter ErrorCategory = "content-filter"
+	ErrorNetwork       ErrorCategory = "network"
+	ErrorTimeout       ErrorCategory = "timeout"
+	ErrorUnknown       ErrorCategory = "unknown"
+)
+
+// Title names the category for users
+func (c ErrorCategory) Title() string {
+	switch c {
+	case ErrorAuth:
+		return "Authentication failed"
+	case ErrorQuota:
+		return "Quota exhausted"
+	case ErrorRateLimit:
+		return "Rate limited"
+	case ErrorModelNotFound:
+		return "Model not found"
+	case ErrorContentFilter:
+		return "Blocked by the content filter"
+	case ErrorNetwork:
+		return "Provider unreachable"
+	case ErrorTimeout:
+		return "Timed out"
+	}
+	return "Provider error"
+}
+
+// ProviderError is a failed provider request described for users: what went
+// wrong and how to fix it. The raw error of the provider is kept as the
+// technical details.
+type ProviderError struct {
+	Category ErrorCategory
+	Provider models.ModelProvider
+	// StatusCode is the HTTP status of the response, 0 when there was none
+	StatusCode int
+	// Message says what went wrong
+	Message string
+	// Hint says how to fix it, empty when there is nothing to do
+	Hint string
+	Err  error
+}
+
+func (e *ProviderError) Error() string {
+	return e.Message
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// Details returns the raw error of the provider
+func (e *ProviderError) Details() string {
+	return e.Err.Error()
+}
+
+// Transient reports whether the request may succeed when sent again later, as
+// it does once a rate limit or an outage of the provider is over
+func (e *ProviderError) Transient() bool {
+	switch e.Category {
+	case ErrorRateLimit, ErrorTimeout:
+		return true
+	case ErrorNetwork:
+		return e.StatusCode >= http.StatusInternalServerError
+	}
+	return false
+}
+
+// Classify describes the error of a request to model, returning it as is when
+// it is nil, a cancellation or already classified
+func Classify(model models.Model, err error) error {
+	var providerErr *ProviderError
+	if err == nil || errors.Is(err, context.Canceled) || errors.As(err, &providerErr) {
+		return err
+	}
+	return classify(model, err)
+}
+
+// classify describes the error of a request to model
+func classify(model models.Model, err error) *ProviderError {
+	e := &ProviderError{Provider: model.Provider, StatusCode: statusCode(err), Err: err}
+	name := providerName(model.Provider)
+	modelName := model.APIModel
+	if modelName == "" {
+		modelName = string(model.ID)
+	}
+
+	e.Category = category(err, e.StatusCode)
+	switch e.Category {
+	case ErrorAuth:
+		e.Message = fmt.Sprintf("%s rejected the credentials", name)
+		e.Hint = credentialsHint(model.Provider)
+	case ErrorQuota:
+		e.Message = fmt.Sprintf("The %s account has no quota or credit left", name)
+		e.Hint = fmt.Sprintf("Check the plan and billing of the account on the %s dashboard", name)
+	case ErrorRateLimit:
+		e.Message = fmt.Sprintf("%s is limiting the rate of requests", name)
+		e.Hint = "Wait a moment before retrying, or switch to another model"
+	case ErrorModelNotFound:
+		e.Message = fmt.Sprintf("%s does not serve the model %s", name, modelName)
+		e.Hint = "Pick another model, or check the model name in the config"
+		if model.Provider == models.ProviderOllama {
+			e.Hint = fmt.Sprintf("Run `ollama pull %s`, or set ollama.autoPull in the config", modelName)
+		}
+	case ErrorContentFilter:
+		e.Message = fmt.Sprintf("%s refused the request under its content policy", name)
+		e.Hint = "Rephrase the request, or leave out the content that was flagged"
+	case ErrorNetwork:
+		e.Message = fmt.Sprintf("Could not reach %s", name)
+		e.Hint = "Check the network connection, or the endpoint of the provider in the config"
+		if e.StatusCode >= http.StatusInternalServerError {
+			e.Message = fmt.Sprintf("%s failed to answer (%d %s)", name, e.StatusCode, http.StatusText(e.StatusCode))
+			e.Hint = "The provider may be having an outage, retry later"
+		}
+	case ErrorTimeout:
+		e.Message = fmt.Sprintf("%s did not answer in time", name)
+		var timeoutErr *TimeoutError
+		if errors.As(err, &timeoutErr) {
+			e.Message = timeoutErr.Error()
+		}
+		e.Hint = fmt.Sprintf("Retry, or raise providers.%s.timeouts in the config", model.Provider)
+	default:
+		e.Message = fmt.Sprintf("%s returned an error", name)
+		if e.StatusCode != 0 {
+			e.Message = fmt.Sprintf("%s returned an error (%d %s)", name, e.StatusCode, http.StatusText(e.StatusCode))
+		}
+	}
+	return e
+}
+
+// statusCode returns the HTTP status of the response the error was read
+// from, 0 when there was none
+func statusCode(err error) int {
+	var anthropicErr *anthropic.Error
+	if errors.As(err, &anthropicErr) {
+		return anthropicErr.StatusCode
+	}
+	var openaiErr *openai.Error
+	if errors.As(err, &openaiErr) {
+		return openaiErr.StatusCode
+	}
+	var geminiErr genai.APIError
+	if errors.As(err, &geminiErr) {
+		return geminiErr.Code
+	}
+	var ollamaErr *ollamaError
+	if errors.As(err, &ollamaErr) {
+		return ollamaErr.StatusCode
+	}
+	if errors.Is(err, ErrOllamaModelNotPulled) {
+		return http.StatusNotFound
+	}
+	return 0
+}
+
+// The markers of the categories in the error payloads, the status code alone
+// being ambiguous: a 400 may be a filtered prompt or an invalid key, and a
+// 429 an exhausted quota
+var (
+	quotaMarkers         = []string{"insufficient_quota", "credit balance", "billing"}
+	contentFilterMarkers = []string{"content_filter", "content management policy", "content_policy", "responsible ai", "blocked due to safety", "prohibited_content"}
+	authMarkers          = []string{"authentication_error", "invalid_api_key", "invalid x-api-key", "incorrect api key", "api key not valid", "api_key_invalid", "unauthenticated"}
+	modelMarkers         = []string{"model_not_found", "not_found_error", "does not exist", "is not a valid model", "model not found"}
+	rateLimitMarkers     = []string{"rate_limit", "rate limit", "too many requests", "quota exceeded", "resource_exhausted", "overloaded"}
+)
+
+// category returns the category of an error read from a response with status
+func category(err error, status int) ErrorCategory {
+	var timeoutErr *TimeoutError
+	if errors.As(err, &timeoutErr) || errors.Is(err, context.DeadlineExceeded) {
+		return ErrorTimeout
+	}
+	if errors.Is(err, ErrOllamaModelNotPulled) {
+		return ErrorModelNotFound
+	}
+
+	raw := err.Error()
+	switch {
+	case contains(raw, quotaMarkers...):
+		return ErrorQuota
+	case contains(raw, contentFilterMarkers...):
+		return ErrorContentFilter
+	case contains(raw, authMarkers...):
+		return ErrorAuth
+	case contains(raw, modelMarkers...):
+		return ErrorModelNotFound
+	}
+
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrorAuth
+	case status == http.StatusPaymentRequired:
+		return ErrorQuota
+	case status == http.StatusNotFound:
+		return ErrorModelNotFound
+	case status == http.StatusRequestTimeout:
+		return ErrorTimeout
+	// Anthropic answers 529 when it is overloaded
+	case status == http.StatusTooManyRequests || status == 529:
+		return ErrorRateLimit
+	case status >= http.StatusInternalServerError:
+		return ErrorNetwork
+	case status == 0 && contains(raw, rateLimitMarkers...):
+		return ErrorRateLimit
+	case status == 0 && isNetworkError(err):
+		return ErrorNetwork
+	}
+	return ErrorUnknown
+}
+
+// isNetworkError reports whether the request failed before a response was
+// read, as it does when the connection is refused or drops
+func isNetworkError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// providerName returns the name of a provider shown to users
+func providerName(provider models.ModelProvider) string {
+	switch provider {
+	case models.ProviderAnthropic:
+		return "Anthropic"
+	case models.ProviderOpenAI:
+		return "OpenAI"
+	case models.ProviderGemini:
+		return "Gemini"
+	case models.ProviderGROQ:
+		return "Groq"
+	case models.ProviderOpenRouter:
+		return "OpenRouter"
+	case models.ProviderXAI:
+		return "xAI"
+	case models.ProviderAzure:
+		return "Azure OpenAI"
+	case models.ProviderBedrock:
+		return "Bedrock"
+	case models.ProviderVertexAI:
+		return "Vertex AI"
+	case models.ProviderOllama:
+		return "Ollama"
+	}
+	return string(provider)
+}
+
+// credentialEnv is the environment variable holding the API key of each
+// provider
+var credentialEnv = map[models.ModelProvider]string{
+	models.ProviderAnthropic:  "ANTHROPIC_API_KEY",
+	models.ProviderOpenAI:     "OPENAI_API_KEY",
+	models.ProviderGemini:     "GEMINI_API_KEY",
+	models.ProviderGROQ:       "GROQ_API_KEY",
+	models.ProviderOpenRouter: "OPENROUTER_API_KEY",
+	models.ProviderXAI:        "XAI_API_KEY",
+	models.ProviderAzure:      "AZURE_OPENAI_API_KEY",
+}
+
+// credentialsHint tells how to fix the credentials of a provider
+func credentialsHint(provider models.ModelProvider) string {
+	switch provider {
+	case models.ProviderBedrock:
+		return "Check the AWS credentials and that the account has access to the model in Bedrock"
+	case models.ProviderVertexAI:
+		return "Check the Google Cloud credentials and that the project has Vertex AI enabled"
+	}
+	if env, ok := credentialEnv[provider]; ok {
+		return fmt.Sprintf("Your %s appears invalid, update it or providers.%s.apiKey in ~/.intelligence-interface.json, or check the key on the provider dashboard", env, provider)
+	}
+	return fmt.Sprintf("Update providers.%s.apiKey in ~/.intelligence-interface.json", provider)
+}
+
+// retryLater reports whether a client sends a request of model again after a
+// backoff, the provider having limited its rate or failed to answer it
+func retryLater(model models.Model, err error) bool {
+	e := classify(model, err)
+	return e.Transient() && e.Category != ErrorTimeout
+}
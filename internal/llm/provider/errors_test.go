@@ -0,0 +1,193 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/openai/openai-go"
+	openaioption "github.com/openai/openai-go/option"
+	"google.golang.org/genai"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/message"
+)
+
+// errorCase is a raw error payload of a provider and the category it maps to
+type errorCase struct {
+	name     string
+	status   int
+	body     string
+	want     ErrorCategory
+	provider models.ModelProvider
+}
+
+// errorServer answers every request with the status and body of the case,
+// counting the requests
+func errorServer(t *testing.T, tc errorCase) (string, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		// The requests retried after a rate limit or an outage are retried
+		// at once
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(tc.status)
+		fmt.Fprint(w, tc.body)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL, &requests
+}
+
+// assertCategory classifies the error of a request to model, as the
+// providers do, and checks its category
+func assertCategory(t *testing.T, model models.Model, err error, want ErrorCategory) *ProviderError {
+	t.Helper()
+	if err == nil {
+		t.Fatal("request succeeded, want an error")
+	}
+	var providerErr *ProviderError
+	if !errors.As(Classify(model, err), &providerErr) {
+		t.Fatalf("Classify(%v) is not a ProviderError", err)
+	}
+	if providerErr.Category != want {
+		t.Errorf("category = %s, want %s, error = %v", providerErr.Category, want, err)
+	}
+	if providerErr.Message == "" || providerErr.Details() != err.Error() {
+		t.Errorf("message = %q, details = %q", providerErr.Message, providerErr.Details())
+	}
+	return providerErr
+}
+
+func TestAnthropicErrorCategories(t *testing.T) {
+	config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
+	for _, tc := range []errorCase{
+		{"invalid key", 401, `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`, ErrorAuth, models.ProviderAnthropic},
+		{"no credit", 400, `{"type":"error","error":{"type":"invalid_request_error","message":"Your credit balance is too low to access the Anthropic API. Please go to Plans & Billing to upgrade or purchase credits."}}`, ErrorQuota, models.ProviderAnthropic},
+		{"rate limit", 429, `{"type":"error","error":{"type":"rate_limit_error","message":"Number of request tokens has exceeded your per-minute rate limit"}}`, ErrorRateLimit, models.ProviderAnthropic},
+		{"overloaded", 529, `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, ErrorRateLimit, models.ProviderAnthropic},
+		{"unknown model", 404, `{"type":"error","error":{"type":"not_found_error","message":"model: claude-0"}}`, ErrorModelNotFound, models.ProviderAnthropic},
+		{"bedrock access", 403, `{"message":"You don't have access to the model with the specified model ID."}`, ErrorAuth, models.ProviderBedrock},
+		{"invalid request", 400, `{"type":"error","error":{"type":"invalid_request_error","message":"messages: text content blocks must be non-empty"}}`, ErrorUnknown, models.ProviderAnthropic},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			url, requests := errorServer(t, tc)
+			model := models.SupportedModels[models.Claude37Sonnet]
+			model.Provider = tc.provider
+			client := newTestAnthropicClient(model)
+			client.client = anthropic.NewClient(anthropicoption.WithBaseURL(url), anthropicoption.WithAPIKey("test"), anthropicoption.WithMaxRetries(0))
+
+			_, err := client.send(context.Background(), []message.Message{textMessage(message.User, "hello")}, nil)
+			providerErr := assertCategory(t, model, err, tc.want)
+			if retried := requests.Load() > 1; retried != providerErr.Transient() {
+				t.Errorf("%d requests sent for a %s error", requests.Load(), providerErr.Category)
+			}
+			if tc.want == ErrorAuth && tc.provider == models.ProviderAnthropic && !strings.Contains(providerErr.Hint, "ANTHROPIC_API_KEY") {
+				t.Errorf("hint = %q, want the environment variable of the key", providerErr.Hint)
+			}
+		})
+	}
+}
+
+func TestOpenAIErrorCategories(t *testing.T) {
+	config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
+	for _, tc := range []errorCase{
+		{"invalid key", 401, `{"error":{"message":"Incorrect API key provided: sk-test. You can find your API key at https://platform.openai.com/account/api-keys.","type":"invalid_request_error","param":null,"code":"invalid_api_key"}}`, ErrorAuth, models.ProviderOpenAI},
+		{"no quota", 429, `{"error":{"message":"You exceeded your current quota, please check your plan and billing details.","type":"insufficient_quota","param":null,"code":"insufficient_quota"}}`, ErrorQuota, models.ProviderOpenAI},
+		{"rate limit", 429, `{"error":{"message":"Rate limit reached for gpt-4.1 in organization org-test on tokens per min (TPM): Limit 30000, Used 30000, Requested 1200.","type":"tokens","param":null,"code":"rate_limit_exceeded"}}`, ErrorRateLimit, models.ProviderOpenAI},
+		{"unknown model", 404, `{"error":{"message":"The model ` + "`gpt-0`" + ` does not exist or you do not have access to it.","type":"invalid_request_error","param":null,"code":"model_not_found"}}`, ErrorModelNotFound, models.ProviderOpenAI},
+		{"outage", 503, `{"error":{"message":"The server is overloaded or not ready yet.","type":"server_error","param":null,"code":null}}`, ErrorNetwork, models.ProviderOpenAI},
+		{"azure content filter", 400, `{"error":{"message":"The response was filtered due to the prompt triggering Azure OpenAI's content management policy.","type":null,"param":"prompt","code":"content_filter","status":400}}`, ErrorContentFilter, models.ProviderAzure},
+		{"groq invalid key", 401, `{"error":{"message":"Invalid API Key","type":"invalid_request_error","code":"invalid_api_key"}}`, ErrorAuth, models.ProviderGROQ},
+		{"openrouter no credit", 402, `{"error":{"message":"Insufficient credits. Add more using https://openrouter.ai/credits","code":402}}`, ErrorQuota, models.ProviderOpenRouter},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			url, requests := errorServer(t, tc)
+			model := models.SupportedModels[models.GPT41]
+			model.Provider = tc.provider
+			client := newOpenAIClient(providerClientOptions{model: model, maxTokens: 1024}).(*openaiClient)
+			client.client = openai.NewClient(openaioption.WithBaseURL(url), openaioption.WithAPIKey("test"), openaioption.WithMaxRetries(0))
+
+			_, err := client.send(context.Background(), []message.Message{textMessage(message.User, "hello")}, nil)
+			providerErr := assertCategory(t, model, err, tc.want)
+			if retried := requests.Load() > 1; retried != providerErr.Transient() {
+				t.Errorf("%d requests sent for a %s error", requests.Load(), providerErr.Category)
+			}
+		})
+	}
+}
+
+func TestGeminiErrorCategories(t *testing.T) {
+	config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
+	for _, tc := range []errorCase{
+		{"invalid key", 400, `{"error":{"code":400,"message":"API key not valid. Please pass a valid API key.","status":"INVALID_ARGUMENT","details":[{"@type":"type.googleapis.com/google.rpc.ErrorInfo","reason":"API_KEY_INVALID","domain":"googleapis.com"}]}}`, ErrorAuth, models.ProviderGemini},
+		{"rate limit", 429, `{"error":{"code":429,"message":"Resource has been exhausted (e.g. check quota).","status":"RESOURCE_EXHAUSTED"}}`, ErrorRateLimit, models.ProviderGemini},
+		{"unknown model", 404, `{"error":{"code":404,"message":"models/gemini-0 is not found for API version v1beta, or is not supported for generateContent.","status":"NOT_FOUND"}}`, ErrorModelNotFound, models.ProviderGemini},
+		{"outage", 500, `{"error":{"code":500,"message":"An internal error has occurred.","status":"INTERNAL"}}`, ErrorNetwork, models.ProviderGemini},
+		{"vertex permission", 403, `{"error":{"code":403,"message":"Permission 'aiplatform.endpoints.predict' denied on resource.","status":"PERMISSION_DENIED"}}`, ErrorAuth, models.ProviderVertexAI},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			url, _ := errorServer(t, tc)
+			t.Setenv("GOOGLE_GEMINI_BASE_URL", url)
+			model := models.SupportedModels[models.Gemini25]
+			model.Provider = tc.provider
+			client := newGeminiClient(providerClientOptions{model: model, apiKey: "test", maxTokens: 1024}).(*geminiClient)
+
+			// The client sends the request itself, send backing off for
+			// seconds between the retries of rate limited requests
+			_, err := client.client.Models.GenerateContent(context.Background(), model.APIModel, genai.Text("hello"), nil)
+			providerErr := assertCategory(t, model, err, tc.want)
+			if retry := retryLater(model, err); retry != providerErr.Transient() {
+				t.Errorf("retryLater() = %v for a %s error", retry, providerErr.Category)
+			}
+		})
+	}
+}
+
+func TestOllamaErrorCategories(t *testing.T) {
+	client, _ := newTestOllamaClient(t, notPulled)
+	_, err := client.send(context.Background(), []message.Message{textMessage(message.User, "hello")}, nil)
+	providerErr := assertCategory(t, client.providerOptions.model, err, ErrorModelNotFound)
+	if !strings.Contains(providerErr.Hint, "ollama pull qwen3") {
+		t.Errorf("hint = %q, want the command pulling the model", providerErr.Hint)
+	}
+
+	client, _ = newTestOllamaClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":"registry.ollama.ai/library/gemma:2b does not support tools"}`)
+	})
+	_, err = client.send(context.Background(), []message.Message{textMessage(message.User, "hello")}, nil)
+	assertCategory(t, client.providerOptions.model, err, ErrorUnknown)
+
+	// The server is not running
+	client.options.baseURL = "http://127.0.0.1:1"
+	_, err = client.send(context.Background(), []message.Message{textMessage(message.User, "hello")}, nil)
+	assertCategory(t, client.providerOptions.model, err, ErrorNetwork)
+}
+
+func TestClassify(t *testing.T) {
+	model := models.SupportedModels[models.Claude37Sonnet]
+
+	if err := Classify(model, context.Canceled); err != context.Canceled {
+		t.Errorf("Classify(context.Canceled) = %v, want it as is", err)
+	}
+	timeout := &TimeoutError{Phase: TimeoutFirstToken, After: time.Second}
+	err := Classify(model, timeout)
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) || err.Error() != timeout.Error() {
+		t.Errorf("Classify(timeout) = %v, want the timeout kept", err)
+	}
+	if again := Classify(model, err); again != err {
+		t.Errorf("Classify() classified an error twice: %v", again)
+	}
+}
@@ -0,0 +1,34 @@
+package provider
+
+import (
+	"fmt"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+)
+
+// ProviderFactory creates the providers of agents, so tests can substitute
+// mock providers for the real clients
+type ProviderFactory interface {
+	// NewProvider creates a provider for a model, authenticated with the
+	// configuration of the model's provider. opts, such as the system message,
+	// apply on top.
+	NewProvider(modelID models.ModelID, cfg config.Provider, opts ...ProviderClientOption) (Provider, error)
+}
+
+// DefaultProviderFactory creates the clients of the supported providers
+var DefaultProviderFactory ProviderFactory = defaultProviderFactory{}
+
+type defaultProviderFactory struct{}
+
+func (defaultProviderFactory) NewProvider(modelID models.ModelID, cfg config.Provider, opts ...ProviderClientOption) (Provider, error) {
+	model, ok := models.SupportedModels[modelID]
+	if !ok {
+		return nil, fmt.Errorf("model %s not supported", modelID)
+	}
+	if cfg.Disabled {
+		return nil, fmt.Errorf("provider %s is not enabled", model.Provider)
+	}
+	opts = append([]ProviderClientOption{WithAPIKey(cfg.APIKey), WithModel(model), WithTimeouts(cfg.Timeouts.Durations())}, opts...)
+	return NewProvider(model.Provider, opts...)
+}
@@ -0,0 +1,331 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/llm/tools"
+	"github.com/caronex/intelligence-interface/internal/message"
+)
+
+// ErrRealClientInTest is returned by NewProvider when code running under go
+// test tries to construct a client for a real provider.
+var ErrRealClientInTest = errors.New("real provider client constructed during go test")
+
+// ErrFakeScriptExhausted is returned once a FakeProvider has answered every
+// scripted response.
+var ErrFakeScriptExhausted = errors.New("fake provider has no scripted responses left")
+
+// RealClientHook is called by NewProvider before a client for a real provider
+// is constructed; returning an error aborts construction. Under go test it
+// rejects every real provider so tests cannot reach the network. Tests that
+// deliberately exercise a real client against a local server can replace it.
+var RealClientHook func(providerName models.ModelProvider) error
+
+func init() {
+	if testing.Testing() {
+		RealClientHook = func(providerName models.ModelProvider) error {
+			return fmt.Errorf("%w: %s", ErrRealClientInTest, providerName)
+		}
+	}
+}
+
+// FakeResponse is one scripted provider turn. Err is returned instead of a
+// response, which lets tests inject provider failures at any point.
+type FakeResponse struct {
+	Content   string
+	Thinking  string
+	ToolCalls []message.ToolCall
+	Usage     TokenUsage
+	// FinishReason overrides the end_turn or tool_use finish of the response
+	FinishReason message.FinishReason
+	Err          error
+}
+
+// FakeProvider is a Provider that answers from a script instead of an API.
+// Each SendMessages or StreamResponse call consumes the next response, so a
+// tool-call sequence is scripted as responses carrying ToolCalls followed by
+// a final response with the answer.
+type FakeProvider struct {
+	*fakeScript
+	model models.Model
+	// system is the system message the provider was created with, sent with
+	// every call
+	system    string
+	systemSet bool
+}
+
+// fakeScript is the script a fake shares with the providers created from it
+// with another system message
+type fakeScript struct {
+	mu        sync.Mutex
+	responses []FakeResponse
+	requests  [][]message.Message
+	systems   []string
+
+	// batchDelay is how long batches take to end
+	batchDelay time.Duration
+	batches    map[string]*fakeBatch
+}
+
+// fakeBatch is a batch submitted to a fake, answered from the script once it
+// ended
+type fakeBatch struct {
+	submitted time.Time
+	requests  []BatchRequest
+	// system is the system message of the provider the batch was submitted
+	// with
+	system string
+	state  *BatchState
+}
+
+// NewFakeProvider creates a fake provider for model that answers with responses in order
+func NewFakeProvider(model models.Model, responses ...FakeResponse) *FakeProvider {
+	return &FakeProvider{
+		fakeScript: &fakeScript{responses: responses},
+		model:      model,
+	}
+}
+
+// withSystem returns the fake for a provider created with a system message:
+// the fake itself for the first system message it is created with, another
+// provider sharing its script for the others
+func (f *FakeProvider) withSystem(system string) *FakeProvider {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.systemSet {
+		f.system, f.systemSet = system, true
+	}
+	if f.system == system {
+		return f
+	}
+	return &FakeProvider{fakeScript: f.fakeScript, model: f.model, system: system, systemSet: true}
+}
+
+// Script appends responses to the ones not yet consumed
+func (f *FakeProvider) Script(responses ...FakeResponse) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.responses = append(f.responses, responses...)
+}
+
+// Requests returns the messages of every call made so far, in call order
+func (f *FakeProvider) Requests() [][]message.Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]message.Message(nil), f.requests...)
+}
+
+// Systems returns the system message of every call made so far, in call
+// order
+func (f *FakeProvider) Systems() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.systems...)
+}
+
+// next records a call and pops the next scripted response
+func (f *FakeProvider) next(messages []message.Message) (FakeResponse, error) {
+	f.mu.Lock()
+	system := f.system
+	f.mu.Unlock()
+	return f.nextWith(messages, system)
+}
+
+// nextWith records a call made with a system message and pops the next
+// scripted response
+func (f *fakeScript) nextWith(messages []message.Message, system string) (FakeResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, messages)
+	f.systems = append(f.systems, system)
+	if len(f.responses) == 0 {
+		return FakeResponse{}, ErrFakeScriptExhausted
+	}
+	response := f.responses[0]
+	f.responses = f.responses[1:]
+	return response, response.Err
+}
+
+func (f *FakeProvider) SendMessages(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	response, err := f.next(messages)
+	if err != nil {
+		return nil, err
+	}
+	return response.providerResponse(), nil
+}
+
+func (f *FakeProvider) StreamResponse(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent {
+	eventChan := make(chan ProviderEvent)
+	go func() {
+		defer close(eventChan)
+		send := func(event ProviderEvent) bool {
+			select {
+			case eventChan <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			send(ProviderEvent{Type: EventError, Error: err})
+			return
+		}
+		response, err := f.next(messages)
+		if err != nil {
+			send(ProviderEvent{Type: EventError, Error: err})
+			return
+		}
+
+		if response.Thinking != "" && !send(ProviderEvent{Type: EventThinkingDelta, Thinking: response.Thinking}) {
+			return
+		}
+		if response.Content != "" {
+			if !send(ProviderEvent{Type: EventContentStart}) ||
+				!send(ProviderEvent{Type: EventContentDelta, Content: response.Content}) ||
+				!send(ProviderEvent{Type: EventContentStop}) {
+				return
+			}
+		}
+		for _, call := range response.ToolCalls {
+			if !send(ProviderEvent{Type: EventToolUseStart, ToolCall: &message.ToolCall{ID: call.ID, Name: call.Name}}) ||
+				!send(ProviderEvent{Type: EventToolUseDelta, ToolCall: &message.ToolCall{ID: call.ID, Input: call.Input}}) ||
+				!send(ProviderEvent{Type: EventToolUseStop, ToolCall: &message.ToolCall{ID: call.ID}}) {
+				return
+			}
+		}
+		send(ProviderEvent{Type: EventComplete, Response: response.providerResponse()})
+	}()
+	return eventChan
+}
+
+// SetBatchDelay sets how long the batches submitted to the fake take to end,
+// none by default
+func (f *FakeProvider) SetBatchDelay(delay time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batchDelay = delay
+}
+
+// SubmitBatch records the requests of a batch, each of them a call answered
+// once the batch ended
+func (f *FakeProvider) SubmitBatch(ctx context.Context, requests []BatchRequest) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.batches == nil {
+		f.batches = make(map[string]*fakeBatch)
+	}
+	batchID := fmt.Sprintf("fake-batch-%d", len(f.batches)+1)
+	f.batches[batchID] = &fakeBatch{submitted: time.Now(), requests: requests, system: f.system}
+	return batchID, nil
+}
+
+// PollBatch reports a batch in progress until its delay passed, then answers
+// its requests with the next scripted responses, in order, as calls made with
+// the system message the batch was submitted with. A response with Err fails
+// its request.
+func (f *FakeProvider) PollBatch(ctx context.Context, batchID string) (BatchState, error) {
+	if err := ctx.Err(); err != nil {
+		return BatchState{}, err
+	}
+	f.mu.Lock()
+	batch, ok := f.batches[batchID]
+	delay := f.batchDelay
+	f.mu.Unlock()
+	if !ok {
+		return BatchState{}, fmt.Errorf("batch %s not found", batchID)
+	}
+	if time.Since(batch.submitted) < delay {
+		return BatchState{Status: BatchInProgress}, nil
+	}
+	if batch.state != nil {
+		return *batch.state, nil
+	}
+
+	state := BatchState{Status: BatchEnded}
+	for _, request := range batch.requests {
+		response, err := f.nextWith(request.Messages, batch.system)
+		if err != nil {
+			state.Results = append(state.Results, BatchResult{CustomID: request.CustomID, Err: err.Error()})
+			continue
+		}
+		state.Results = append(state.Results, BatchResult{CustomID: request.CustomID, Response: response.providerResponse()})
+	}
+	f.mu.Lock()
+	batch.state = &state
+	f.mu.Unlock()
+	return state, nil
+}
+
+func (f *FakeProvider) Model() models.Model {
+	return f.model
+}
+
+func (f *FakeProvider) Close() error {
+	return nil
+}
+
+func (r FakeResponse) providerResponse() *ProviderResponse {
+	finishReason := message.FinishReasonEndTurn
+	toolCalls := make([]message.ToolCall, len(r.ToolCalls))
+	for i, call := range r.ToolCalls {
+		if call.Type == "" {
+			call.Type = "function"
+		}
+		call.Finished = true
+		toolCalls[i] = call
+	}
+	if len(toolCalls) > 0 {
+		finishReason = message.FinishReasonToolUse
+	}
+	if r.FinishReason != "" {
+		finishReason = r.FinishReason
+	}
+	return &ProviderResponse{
+		Content:      r.Content,
+		ToolCalls:    toolCalls,
+		Usage:        r.Usage,
+		FinishReason: finishReason,
+	}
+}
+
+var (
+	fakeMu        sync.Mutex
+	installedFake *FakeProvider
+)
+
+// InstallFake makes NewProvider return fake for the test provider until the
+// returned restore function is called
+func InstallFake(fake *FakeProvider) (restore func()) {
+	fakeMu.Lock()
+	previous := installedFake
+	installedFake = fake
+	fakeMu.Unlock()
+	return func() {
+		fakeMu.Lock()
+		installedFake = previous
+		fakeMu.Unlock()
+	}
+}
+
+// fakeFor returns the installed fake for a provider created with a system
+// message, or an unscripted fake for model when none is installed
+func fakeFor(model models.Model, system string) *FakeProvider {
+	fakeMu.Lock()
+	defer fakeMu.Unlock()
+	if installedFake != nil {
+		return installedFake.withSystem(system)
+	}
+	return NewFakeProvider(model).withSystem(system)
+}
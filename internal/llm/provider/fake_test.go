@@ -0,0 +1,96 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/message"
+)
+
+func TestNewProviderRejectsRealClientsUnderTest(t *testing.T) {
+	for _, providerName := range []models.ModelProvider{models.ProviderOpenAI, models.ProviderAnthropic, models.ProviderLocal} {
+		_, err := NewProvider(providerName, WithAPIKey("test"))
+		if !errors.Is(err, ErrRealClientInTest) {
+			t.Errorf("NewProvider(%s) error = %v, want ErrRealClientInTest", providerName, err)
+		}
+	}
+}
+
+func TestNewProviderReturnsInstalledFake(t *testing.T) {
+	model := models.TestModels[models.TestFake]
+	fake := NewFakeProvider(model)
+	defer InstallFake(fake)()
+
+	p, err := NewProvider(models.ProviderTest, WithModel(model))
+	if err != nil {
+		t.Fatalf("NewProvider(test) error = %v", err)
+	}
+	if p != fake {
+		t.Fatalf("NewProvider(test) did not return the installed fake")
+	}
+}
+
+func TestFakeProviderScript(t *testing.T) {
+	injected := errors.New("rate limited")
+	fake := NewFakeProvider(models.TestModels[models.TestFake],
+		FakeResponse{ToolCalls: []message.ToolCall{{ID: "call-1", Name: "ls", Input: `{"path":"."}`}}},
+		FakeResponse{Err: injected},
+		FakeResponse{Content: "done"},
+	)
+	ctx := context.Background()
+
+	first, err := fake.SendMessages(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("first call error = %v", err)
+	}
+	if first.FinishReason != message.FinishReasonToolUse || len(first.ToolCalls) != 1 || !first.ToolCalls[0].Finished {
+		t.Errorf("first call = %+v, want one finished tool call", first)
+	}
+
+	if _, err := fake.SendMessages(ctx, nil, nil); !errors.Is(err, injected) {
+		t.Errorf("second call error = %v, want injected error", err)
+	}
+
+	var complete *ProviderResponse
+	for event := range fake.StreamResponse(ctx, nil, nil) {
+		if event.Type == EventError {
+			t.Fatalf("stream error = %v", event.Error)
+		}
+		if event.Type == EventComplete {
+			complete = event.Response
+		}
+	}
+	if complete == nil || complete.Content != "done" || complete.FinishReason != message.FinishReasonEndTurn {
+		t.Errorf("streamed response = %+v, want final answer", complete)
+	}
+
+	if _, err := fake.SendMessages(ctx, nil, nil); !errors.Is(err, ErrFakeScriptExhausted) {
+		t.Errorf("call past the script error = %v, want ErrFakeScriptExhausted", err)
+	}
+	if got := len(fake.Requests()); got != 4 {
+		t.Errorf("recorded %d requests, want 4", got)
+	}
+}
+
+func TestDefaultProviderFactory(t *testing.T) {
+	fake := NewFakeProvider(models.TestModels[models.TestFake])
+	defer InstallFake(fake)()
+
+	p, err := DefaultProviderFactory.NewProvider(models.TestFake, config.Provider{APIKey: "test"})
+	if err != nil {
+		t.Fatalf("NewProvider(%s) error = %v", models.TestFake, err)
+	}
+	if p != fake {
+		t.Fatalf("NewProvider(%s) did not return the installed fake", models.TestFake)
+	}
+
+	if _, err := DefaultProviderFactory.NewProvider(models.TestFake, config.Provider{Disabled: true}); err == nil {
+		t.Error("NewProvider() with a disabled provider succeeded")
+	}
+	if _, err := DefaultProviderFactory.NewProvider("unknown", config.Provider{}); err == nil {
+		t.Error("NewProvider() with an unknown model succeeded")
+	}
+}
@@ -37,7 +37,11 @@ func newGeminiClient(opts providerClientOptions) GeminiClient {
 		o(&geminiOpts)
 	}
 
-	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{APIKey: opts.apiKey, Backend: genai.BackendGeminiAPI})
+	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:     opts.apiKey,
+		Backend:    genai.BackendGeminiAPI,
+		HTTPClient: newHTTPClient(opts.timeouts),
+	})
 	if err != nil {
 		logging.Error("Failed to create Gemini client", "error", err)
 		return nil
@@ -158,11 +162,36 @@ func (g *geminiClient) finishReason(reason genai.FinishReason) message.FinishRea
 		return message.FinishReasonEndTurn
 	case reason == genai.FinishReasonMaxTokens:
 		return message.FinishReasonMaxTokens
+	case reason == genai.FinishReasonSafety,
+		reason == genai.FinishReasonRecitation,
+		reason == genai.FinishReasonBlocklist,
+		reason == genai.FinishReasonProhibitedContent,
+		reason == genai.FinishReasonSPII,
+		reason == genai.FinishReasonImageSafety:
+		return message.FinishReasonContentFilter
 	default:
 		return message.FinishReasonUnknown
 	}
 }
 
+// applyGeneration sets the configured sampling parameters on a request config
+func (g *geminiClient) applyGeneration(config *genai.GenerateContentConfig) {
+	generation := g.providerOptions.generation
+	config.Temperature = float32Ptr(generation.Temperature)
+	config.TopP = float32Ptr(generation.TopP)
+	config.FrequencyPenalty = float32Ptr(generation.FrequencyPenalty)
+	config.PresencePenalty = float32Ptr(generation.PresencePenalty)
+	config.StopSequences = generation.Stop
+}
+
+func float32Ptr(v *float64) *float32 {
+	if v == nil {
+		return nil
+	}
+	f := float32(*v)
+	return &f
+}
+
 func (g *geminiClient) send(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, error) {
 	// Convert messages
 	geminiMessages := g.convertMessages(messages)
@@ -170,7 +199,7 @@ func (g *geminiClient) send(ctx context.Context, messages []message.Message, too
 	cfg := config.Get()
 	if cfg.Debug {
 		jsonData, _ := json.Marshal(geminiMessages)
-		logging.Debug("Prepared messages", "messages", string(jsonData))
+		logging.FromContext(ctx).Debug("Prepared messages", "messages", string(jsonData))
 	}
 
 	history := geminiMessages[:len(geminiMessages)-1] // All but last message
@@ -181,6 +210,7 @@ func (g *geminiClient) send(ctx context.Context, messages []message.Message, too
 			Parts: []*genai.Part{{Text: g.providerOptions.systemMessage}},
 		},
 	}
+	g.applyGeneration(config)
 	if len(tools) > 0 {
 		config.Tools = g.convertTools(tools)
 	}
@@ -203,7 +233,7 @@ func (g *geminiClient) send(ctx context.Context, messages []message.Message, too
 				return nil, retryErr
 			}
 			if retry {
-				logging.WarnPersist(fmt.Sprintf("Retrying due to rate limit... attempt %d of %d", attempts, maxRetries), logging.PersistTimeArg, time.Millisecond*time.Duration(after+100))
+				logging.FromContext(ctx).WarnPersist(fmt.Sprintf("Retrying due to rate limit... attempt %d of %d", attempts, maxRetries), logging.PersistTimeArg, time.Millisecond*time.Duration(after+100))
 				select {
 				case <-ctx.Done():
 					return nil, ctx.Err()
@@ -258,7 +288,7 @@ func (g *geminiClient) stream(ctx context.Context, messages []message.Message, t
 	cfg := config.Get()
 	if cfg.Debug {
 		jsonData, _ := json.Marshal(geminiMessages)
-		logging.Debug("Prepared messages", "messages", string(jsonData))
+		logging.FromContext(ctx).Debug("Prepared messages", "messages", string(jsonData))
 	}
 
 	history := geminiMessages[:len(geminiMessages)-1] // All but last message
@@ -269,6 +299,7 @@ func (g *geminiClient) stream(ctx context.Context, messages []message.Message, t
 			Parts: []*genai.Part{{Text: g.providerOptions.systemMessage}},
 		},
 	}
+	g.applyGeneration(config)
 	if len(tools) > 0 {
 		config.Tools = g.convertTools(tools)
 	}
@@ -302,7 +333,7 @@ func (g *geminiClient) stream(ctx context.Context, messages []message.Message, t
 						return
 					}
 					if retry {
-						logging.WarnPersist(fmt.Sprintf("Retrying due to rate limit... attempt %d of %d", attempts, maxRetries), logging.PersistTimeArg, time.Millisecond*time.Duration(after+100))
+						logging.FromContext(ctx).WarnPersist(fmt.Sprintf("Retrying due to rate limit... attempt %d of %d", attempts, maxRetries), logging.PersistTimeArg, time.Millisecond*time.Duration(after+100))
 						select {
 						case <-ctx.Done():
 							if ctx.Err() != nil {
@@ -390,27 +421,11 @@ func (g *geminiClient) stream(ctx context.Context, messages []message.Message, t
 }
 
 func (g *geminiClient) shouldRetry(attempts int, err error) (bool, int64, error) {
-	// Check if error is a rate limit error
-	if attempts > maxRetries {
-		return false, 0, fmt.Errorf("maximum retry attempts reached for rate limit: %d retries", maxRetries)
-	}
-
-	// Gemini doesn't have a standard error type we can check against
-	// So we'll check the error message for rate limit indicators
-	if errors.Is(err, io.EOF) {
+	if errors.Is(err, io.EOF) || !retryLater(g.providerOptions.model, err) {
 		return false, 0, err
 	}
-
-	errMsg := err.Error()
-	isRateLimit := false
-
-	// Check for common rate limit error messages
-	if contains(errMsg, "rate limit", "quota exceeded", "too many requests") {
-		isRateLimit = true
-	}
-
-	if !isRateLimit {
-		return false, 0, err
+	if attempts > maxRetries {
+		return false, 0, fmt.Errorf("maximum retry attempts reached for rate limit: %d retries: %w", maxRetries, err)
 	}
 
 	// Calculate backoff with jitter
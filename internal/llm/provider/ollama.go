@@ -0,0 +1,468 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/llm/tools"
+	"github.com/caronex/intelligence-interface/internal/message"
+	"github.com/google/uuid"
+)
+
+// ErrOllamaModelNotPulled is returned for a model that is not on the Ollama
+// server when pulling it automatically is not enabled
+var ErrOllamaModelNotPulled = errors.New("model not pulled")
+
+type ollamaOptions struct {
+	baseURL   string
+	keepAlive string
+	autoPull  bool
+}
+
+type OllamaOption func(*ollamaOptions)
+
+type ollamaClient struct {
+	providerOptions providerClientOptions
+	options         ollamaOptions
+	client          *http.Client
+}
+
+type OllamaClient ProviderClient
+
+func newOllamaClient(opts providerClientOptions) OllamaClient {
+	ollamaOpts := ollamaOptions{
+		baseURL: models.DefaultOllamaEndpoint,
+	}
+	for _, o := range opts.ollamaOptions {
+		o(&ollamaOpts)
+	}
+	return &ollamaClient{
+		providerOptions: opts,
+		options:         ollamaOpts,
+		client:          newHTTPClient(opts.timeouts),
+	}
+}
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Thinking  string           `json:"thinking,omitempty"`
+	Images    []string         `json:"images,omitempty"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+type ollamaTool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string         `json:"name"`
+		Description string         `json:"description"`
+		Parameters  map[string]any `json:"parameters"`
+	} `json:"function"`
+}
+
+type ollamaRequest struct {
+	Model     string          `json:"model"`
+	Messages  []ollamaMessage `json:"messages"`
+	Tools     []ollamaTool    `json:"tools,omitempty"`
+	Stream    bool            `json:"stream"`
+	Think     bool            `json:"think,omitempty"`
+	KeepAlive any             `json:"keep_alive,omitempty"`
+	Options   map[string]any  `json:"options,omitempty"`
+}
+
+// ollamaResponse is a response of /api/chat, or a chunk of one when streamed
+type ollamaResponse struct {
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int64         `json:"prompt_eval_count"`
+	EvalCount       int64         `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+func (o *ollamaClient) convertMessages(messages []message.Message) (ollamaMessages []ollamaMessage) {
+	ollamaMessages = append(ollamaMessages, ollamaMessage{Role: "system", Content: o.providerOptions.systemMessage})
+
+	for _, msg := range messages {
+		switch msg.Role {
+		case message.User:
+			userMsg := ollamaMessage{Role: "user", Content: msg.Content().String()}
+			for _, binaryContent := range msg.BinaryContent() {
+				userMsg.Images = append(userMsg.Images, binaryContent.String(models.ProviderOllama))
+			}
+			ollamaMessages = append(ollamaMessages, userMsg)
+
+		case message.Assistant:
+			assistantMsg := ollamaMessage{Role: "assistant", Content: msg.Content().String()}
+			for _, call := range msg.ToolCalls() {
+				var toolCall ollamaToolCall
+				toolCall.Function.Name = call.Name
+				toolCall.Function.Arguments = json.RawMessage(call.Input)
+				// Ollama takes the arguments as an object
+				if !json.Valid(toolCall.Function.Arguments) {
+					toolCall.Function.Arguments = json.RawMessage("{}")
+				}
+				assistantMsg.ToolCalls = append(assistantMsg.ToolCalls, toolCall)
+			}
+			ollamaMessages = append(ollamaMessages, assistantMsg)
+
+		case message.Tool:
+			for _, result := range msg.ToolResults() {
+				ollamaMessages = append(ollamaMessages, ollamaMessage{
+					Role:     "tool",
+					Content:  result.Content,
+					ToolName: result.Name,
+				})
+			}
+		}
+	}
+
+	return
+}
+
+func (o *ollamaClient) convertTools(tools []tools.BaseTool) []ollamaTool {
+	ollamaTools := make([]ollamaTool, len(tools))
+
+	for i, tool := range tools {
+		info := tool.Info()
+		ollamaTools[i].Type = "function"
+		ollamaTools[i].Function.Name = info.Name
+		ollamaTools[i].Function.Description = info.Description
+		ollamaTools[i].Function.Parameters = map[string]any{
+			"type":       "object",
+			"properties": info.Parameters,
+			"required":   info.Required,
+		}
+	}
+
+	return ollamaTools
+}
+
+func (o *ollamaClient) finishReason(reason string) message.FinishReason {
+	switch reason {
+	case "stop":
+		return message.FinishReasonEndTurn
+	case "length":
+		return message.FinishReasonMaxTokens
+	default:
+		return message.FinishReasonUnknown
+	}
+}
+
+func (o *ollamaClient) preparedRequest(messages []ollamaMessage, tools []ollamaTool, stream bool) ollamaRequest {
+	model := o.providerOptions.model
+	request := ollamaRequest{
+		Model:    model.APIModel,
+		Messages: messages,
+		Tools:    tools,
+		Stream:   stream,
+		Think:    model.CanReason,
+		Options: map[string]any{
+			// Ollama loads models with a small context window unless asked
+			"num_ctx":     model.ContextWindow,
+			"num_predict": o.providerOptions.maxTokens,
+		},
+	}
+	if o.options.keepAlive != "" {
+		// A number of seconds is sent as a number, which the server reads as such
+		if seconds, err := strconv.Atoi(o.options.keepAlive); err == nil {
+			request.KeepAlive = seconds
+		} else {
+			request.KeepAlive = o.options.keepAlive
+		}
+	}
+
+	generation := o.providerOptions.generation
+	if len(generation.Stop) > 0 {
+		request.Options["stop"] = generation.Stop
+	}
+	if generation.Temperature != nil {
+		request.Options["temperature"] = *generation.Temperature
+	}
+	if generation.TopP != nil {
+		request.Options["top_p"] = *generation.TopP
+	}
+	if generation.FrequencyPenalty != nil {
+		request.Options["frequency_penalty"] = *generation.FrequencyPenalty
+	}
+	if generation.PresencePenalty != nil {
+		request.Options["presence_penalty"] = *generation.PresencePenalty
+	}
+
+	return request
+}
+
+// chat posts a request to /api/chat. A model that is not on the server is
+// pulled and the request posted again when pulling is automatic.
+func (o *ollamaClient) chat(ctx context.Context, request ollamaRequest) (*http.Response, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	if cfg := config.Get(); cfg != nil && cfg.Debug {
+		logging.FromContext(ctx).Debug("Prepared messages", "messages", string(body))
+	}
+
+	for pulled := false; ; pulled = true {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.options.baseURL+"/api/chat", bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		res, err := o.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if res.StatusCode == http.StatusOK {
+			return res, nil
+		}
+
+		err = o.responseError(res)
+		if res.StatusCode != http.StatusNotFound || pulled {
+			return nil, err
+		}
+		if !o.options.autoPull {
+			return nil, fmt.Errorf("%w: %s is not on the Ollama server, run `ollama pull %s`", ErrOllamaModelNotPulled, request.Model, request.Model)
+		}
+		if err := o.pull(ctx, request.Model); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ollamaError is the error of a failed response of the Ollama server
+type ollamaError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *ollamaError) Error() string {
+	return fmt.Sprintf("ollama: %s", e.Message)
+}
+
+// responseError reads the error of a failed response and closes it
+func (o *ollamaClient) responseError(res *http.Response) error {
+	defer res.Body.Close()
+	var response ollamaResponse
+	data, _ := io.ReadAll(res.Body)
+	if json.Unmarshal(data, &response) == nil && response.Error != "" {
+		return &ollamaError{StatusCode: res.StatusCode, Message: response.Error}
+	}
+	return &ollamaError{StatusCode: res.StatusCode, Message: res.Status}
+}
+
+// pull pulls model to the server, reporting the progress of the download
+func (o *ollamaClient) pull(ctx context.Context, model string) error {
+	logging.FromContext(ctx).InfoPersist(fmt.Sprintf("Pulling %s from the Ollama library...", model))
+	body, _ := json.Marshal(map[string]any{"model": model, "stream": true})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.options.baseURL+"/api/pull", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := o.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("pulling %s: %w", model, err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("pulling %s: %w", model, o.responseError(res))
+	}
+	defer res.Body.Close()
+
+	lastPercent := -1
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		var progress struct {
+			Status    string `json:"status"`
+			Total     int64  `json:"total"`
+			Completed int64  `json:"completed"`
+			Error     string `json:"error"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &progress); err != nil {
+			continue
+		}
+		if progress.Error != "" {
+			return fmt.Errorf("pulling %s: %s", model, progress.Error)
+		}
+		// The download of each layer is reported every few percent
+		if progress.Total > 0 {
+			percent := int(progress.Completed * 100 / progress.Total)
+			if percent/10 != lastPercent/10 {
+				lastPercent = percent
+				logging.FromContext(ctx).InfoPersist(fmt.Sprintf("Pulling %s: %s %d%%", model, progress.Status, percent))
+			}
+		}
+		if progress.Status == "success" {
+			logging.FromContext(ctx).InfoPersist(fmt.Sprintf("Pulled %s", model))
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("pulling %s: %w", model, err)
+	}
+	return fmt.Errorf("pulling %s: the server ended the download early", model)
+}
+
+func (o *ollamaClient) send(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, error) {
+	request := o.preparedRequest(o.convertMessages(messages), o.convertTools(tools), false)
+	res, err := o.chat(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var response ollamaResponse
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("ollama: %w", err)
+	}
+	if response.Error != "" {
+		return nil, fmt.Errorf("ollama: %s", response.Error)
+	}
+
+	toolCalls := o.toolCalls(response.Message.ToolCalls)
+	finishReason := o.finishReason(response.DoneReason)
+	if len(toolCalls) > 0 {
+		finishReason = message.FinishReasonToolUse
+	}
+
+	return &ProviderResponse{
+		Content:      response.Message.Content,
+		ToolCalls:    toolCalls,
+		Usage:        o.usage(response),
+		FinishReason: finishReason,
+	}, nil
+}
+
+func (o *ollamaClient) stream(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent {
+	request := o.preparedRequest(o.convertMessages(messages), o.convertTools(tools), true)
+	eventChan := make(chan ProviderEvent)
+
+	go func() {
+		defer close(eventChan)
+		res, err := o.chat(ctx, request)
+		if err != nil {
+			eventChan <- ProviderEvent{Type: EventError, Error: err}
+			return
+		}
+		defer res.Body.Close()
+
+		currentContent := ""
+		var toolCalls []message.ToolCall
+		scanner := bufio.NewScanner(res.Body)
+		// A chunk can hold the arguments of a tool call in full
+		scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			var chunk ollamaResponse
+			if err := json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+				eventChan <- ProviderEvent{Type: EventError, Error: fmt.Errorf("ollama: %w", err)}
+				return
+			}
+			if chunk.Error != "" {
+				eventChan <- ProviderEvent{Type: EventError, Error: fmt.Errorf("ollama: %s", chunk.Error)}
+				return
+			}
+			if chunk.Message.Thinking != "" {
+				eventChan <- ProviderEvent{Type: EventThinkingDelta, Thinking: chunk.Message.Thinking}
+			}
+			if chunk.Message.Content != "" {
+				eventChan <- ProviderEvent{Type: EventContentDelta, Content: chunk.Message.Content}
+				currentContent += chunk.Message.Content
+			}
+			// Tool calls are streamed whole rather than in deltas
+			toolCalls = append(toolCalls, o.toolCalls(chunk.Message.ToolCalls)...)
+
+			if chunk.Done {
+				finishReason := o.finishReason(chunk.DoneReason)
+				if len(toolCalls) > 0 {
+					finishReason = message.FinishReasonToolUse
+				}
+				eventChan <- ProviderEvent{
+					Type: EventComplete,
+					Response: &ProviderResponse{
+						Content:      currentContent,
+						ToolCalls:    toolCalls,
+						Usage:        o.usage(chunk),
+						FinishReason: finishReason,
+					},
+				}
+				return
+			}
+		}
+
+		err = scanner.Err()
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		eventChan <- ProviderEvent{Type: EventError, Error: err}
+	}()
+
+	return eventChan
+}
+
+// toolCalls converts the tool calls of a response, which Ollama does not
+// identify, so IDs are generated to match the results with
+func (o *ollamaClient) toolCalls(calls []ollamaToolCall) []message.ToolCall {
+	var toolCalls []message.ToolCall
+	for _, call := range calls {
+		input := strings.TrimSpace(string(call.Function.Arguments))
+		if input == "" || input == "null" {
+			input = "{}"
+		}
+		toolCalls = append(toolCalls, message.ToolCall{
+			ID:       "call_" + uuid.NewString(),
+			Name:     call.Function.Name,
+			Input:    input,
+			Type:     "function",
+			Finished: true,
+		})
+	}
+	return toolCalls
+}
+
+func (o *ollamaClient) usage(response ollamaResponse) TokenUsage {
+	return TokenUsage{
+		InputTokens:  response.PromptEvalCount,
+		OutputTokens: response.EvalCount,
+	}
+}
+
+func WithOllamaBaseURL(baseURL string) OllamaOption {
+	return func(options *ollamaOptions) {
+		options.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithOllamaKeepAlive sets how long the server keeps the model loaded after
+// a request
+func WithOllamaKeepAlive(keepAlive string) OllamaOption {
+	return func(options *ollamaOptions) {
+		options.keepAlive = keepAlive
+	}
+}
+
+// WithOllamaAutoPull pulls a model that is not on the server rather than
+// failing
+func WithOllamaAutoPull() OllamaOption {
+	return func(options *ollamaOptions) {
+		options.autoPull = true
+	}
+}
@@ -0,0 +1,161 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/llm/tools"
+	"github.com/caronex/intelligence-interface/internal/message"
+)
+
+// newTestOllamaClient returns a client of an Ollama server answering /api/chat
+// with chat, and /api/pull with a completed download
+func newTestOllamaClient(t *testing.T, chat http.HandlerFunc, opts ...OllamaOption) (*ollamaClient, *atomic.Int32) {
+	t.Helper()
+	var pulls atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/chat", chat)
+	mux.HandleFunc("/api/pull", func(w http.ResponseWriter, r *http.Request) {
+		pulls.Add(1)
+		fmt.Fprintln(w, `{"status":"pulling manifest"}`)
+		fmt.Fprintln(w, `{"status":"downloading","total":100,"completed":50}`)
+		fmt.Fprintln(w, `{"status":"success"}`)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client := newOllamaClient(providerClientOptions{
+		model:         models.Model{ID: "ollama.qwen3", Provider: models.ProviderOllama, APIModel: "qwen3", ContextWindow: 8192, SupportsTools: true},
+		maxTokens:     1024,
+		systemMessage: "be brief",
+		ollamaOptions: append([]OllamaOption{WithOllamaBaseURL(server.URL)}, opts...),
+	}).(*ollamaClient)
+	return client, &pulls
+}
+
+func TestOllamaStream(t *testing.T) {
+	var request ollamaRequest
+	client, _ := newTestOllamaClient(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&request)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"Let me "}}`)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"look.","tool_calls":[{"function":{"name":"ls","arguments":{"path":"."}}}]}}`)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":12,"eval_count":5}`)
+	}, WithOllamaKeepAlive("-1"))
+
+	var content string
+	var response *ProviderResponse
+	for event := range client.stream(context.Background(), []message.Message{textMessage(message.User, "list files")}, []tools.BaseTool{stubTool{name: "ls"}}) {
+		switch event.Type {
+		case EventContentDelta:
+			content += event.Content
+		case EventComplete:
+			response = event.Response
+		case EventError:
+			t.Fatalf("stream error = %v", event.Error)
+		}
+	}
+
+	if request.Model != "qwen3" || !request.Stream || request.KeepAlive != float64(-1) || request.Options["num_ctx"] != float64(8192) {
+		t.Errorf("request = %+v", request)
+	}
+	if len(request.Messages) != 2 || request.Messages[0].Role != "system" || len(request.Tools) != 1 {
+		t.Errorf("request messages = %+v, tools = %+v", request.Messages, request.Tools)
+	}
+	if content != "Let me look." || response == nil || response.Content != content {
+		t.Fatalf("content = %q, response = %+v", content, response)
+	}
+	if response.FinishReason != message.FinishReasonToolUse || len(response.ToolCalls) != 1 {
+		t.Fatalf("response = %+v", response)
+	}
+	call := response.ToolCalls[0]
+	if call.Name != "ls" || call.Input != `{"path":"."}` || !strings.HasPrefix(call.ID, "call_") {
+		t.Errorf("tool call = %+v", call)
+	}
+	if response.Usage.InputTokens != 12 || response.Usage.OutputTokens != 5 {
+		t.Errorf("usage = %+v", response.Usage)
+	}
+}
+
+func notPulled(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNotFound)
+	fmt.Fprint(w, `{"error":"model \"qwen3\" not found, try pulling it first"}`)
+}
+
+func TestOllamaModelNotPulled(t *testing.T) {
+	client, pulls := newTestOllamaClient(t, notPulled)
+
+	_, err := client.send(context.Background(), []message.Message{textMessage(message.User, "hello")}, nil)
+	if !errors.Is(err, ErrOllamaModelNotPulled) || !strings.Contains(err.Error(), "ollama pull qwen3") {
+		t.Errorf("send() error = %v, want the command pulling the model", err)
+	}
+	if pulls.Load() != 0 {
+		t.Errorf("the model was pulled without auto pull")
+	}
+}
+
+func TestOllamaAutoPull(t *testing.T) {
+	var pulled atomic.Bool
+	client, pulls := newTestOllamaClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if !pulled.Swap(true) {
+			notPulled(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"message":{"role":"assistant","content":"hi"},"done":true,"done_reason":"stop"}`)
+	}, WithOllamaAutoPull())
+
+	response, err := client.send(context.Background(), []message.Message{textMessage(message.User, "hello")}, nil)
+	if err != nil {
+		t.Fatalf("send() error = %v", err)
+	}
+	if response.Content != "hi" || response.FinishReason != message.FinishReasonEndTurn {
+		t.Errorf("response = %+v", response)
+	}
+	if pulls.Load() != 1 {
+		t.Errorf("pulls = %d, want 1", pulls.Load())
+	}
+}
+
+func TestLoadOllamaModels(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/tags", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"models":[{"name":"qwen3:latest"},{"name":"gemma:2b"}]}`)
+	})
+	mux.HandleFunc("/api/show", func(w http.ResponseWriter, r *http.Request) {
+		var show struct{ Model string }
+		json.NewDecoder(r.Body).Decode(&show)
+		if show.Model == "qwen3:latest" {
+			fmt.Fprint(w, `{"capabilities":["completion","tools","thinking"],"model_info":{"qwen3.context_length":40960},"parameters":"num_ctx 16384\ntemperature 0.6"}`)
+			return
+		}
+		fmt.Fprint(w, `{"capabilities":["completion"],"model_info":{"gemma.context_length":8192}}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	loaded := models.LoadOllamaModels(server.URL, time.Second)
+	t.Cleanup(func() {
+		for _, model := range loaded {
+			delete(models.SupportedModels, model.ID)
+		}
+	})
+	if len(loaded) != 2 {
+		t.Fatalf("loaded %d models, want 2", len(loaded))
+	}
+	qwen := models.SupportedModels[models.OllamaModelID("qwen3:latest")]
+	if qwen.ContextWindow != 16384 || !qwen.SupportsTools || !qwen.CanReason || qwen.APIModel != "qwen3:latest" {
+		t.Errorf("qwen3 = %+v", qwen)
+	}
+	gemma := models.SupportedModels[models.OllamaModelID("gemma:2b")]
+	if gemma.ContextWindow != 8192 || gemma.SupportsTools {
+		t.Errorf("gemma = %+v, want its trained context and no tools", gemma)
+	}
+}
@@ -1,11 +1,14 @@
 package provider
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"time"
 
 	"github.com/openai/openai-go"
@@ -42,8 +45,21 @@ func newOpenAIClient(opts providerClientOptions) OpenAIClient {
 	for _, o := range opts.openaiOptions {
 		o(&openaiOpts)
 	}
+	if opts.model.CanReason {
+		generation := opts.generation
+		for name, set := range map[string]bool{
+			"temperature":      generation.Temperature != nil,
+			"topP":             generation.TopP != nil,
+			"frequencyPenalty": generation.FrequencyPenalty != nil,
+			"presencePenalty":  generation.PresencePenalty != nil,
+		} {
+			if set {
+				ignoredParam(opts.model, name)
+			}
+		}
+	}
 
-	openaiClientOptions := []option.RequestOption{}
+	openaiClientOptions := []option.RequestOption{option.WithHTTPClient(newHTTPClient(opts.timeouts))}
 	if opts.apiKey != "" {
 		openaiClientOptions = append(openaiClientOptions, option.WithAPIKey(opts.apiKey))
 	}
@@ -154,6 +170,8 @@ func (o *openaiClient) finishReason(reason string) message.FinishReason {
 		return message.FinishReasonMaxTokens
 	case "tool_calls":
 		return message.FinishReasonToolUse
+	case "content_filter":
+		return message.FinishReasonContentFilter
 	default:
 		return message.FinishReasonUnknown
 	}
@@ -182,6 +200,26 @@ func (o *openaiClient) preparedParams(messages []openai.ChatCompletionMessagePar
 		params.MaxTokens = openai.Int(o.providerOptions.maxTokens)
 	}
 
+	generation := o.providerOptions.generation
+	if len(generation.Stop) > 0 {
+		params.Stop = openai.ChatCompletionNewParamsStopUnion{OfChatCompletionNewsStopArray: generation.Stop}
+	}
+	// Reasoning models only accept their default sampling parameters
+	if !o.providerOptions.model.CanReason {
+		if generation.Temperature != nil {
+			params.Temperature = openai.Float(*generation.Temperature)
+		}
+		if generation.TopP != nil {
+			params.TopP = openai.Float(*generation.TopP)
+		}
+		if generation.FrequencyPenalty != nil {
+			params.FrequencyPenalty = openai.Float(*generation.FrequencyPenalty)
+		}
+		if generation.PresencePenalty != nil {
+			params.PresencePenalty = openai.Float(*generation.PresencePenalty)
+		}
+	}
+
 	return params
 }
 
@@ -190,7 +228,7 @@ func (o *openaiClient) send(ctx context.Context, messages []message.Message, too
 	cfg := config.Get()
 	if cfg.Debug {
 		jsonData, _ := json.Marshal(params)
-		logging.Debug("Prepared messages", "messages", string(jsonData))
+		logging.FromContext(ctx).Debug("Prepared messages", "messages", string(jsonData))
 	}
 	attempts := 0
 	for {
@@ -206,7 +244,7 @@ func (o *openaiClient) send(ctx context.Context, messages []message.Message, too
 				return nil, retryErr
 			}
 			if retry {
-				logging.WarnPersist(fmt.Sprintf("Retrying due to rate limit... attempt %d of %d", attempts, maxRetries), logging.PersistTimeArg, time.Millisecond*time.Duration(after+100))
+				logging.FromContext(ctx).WarnPersist(fmt.Sprintf("Retrying due to rate limit... attempt %d of %d", attempts, maxRetries), logging.PersistTimeArg, time.Millisecond*time.Duration(after+100))
 				select {
 				case <-ctx.Done():
 					return nil, ctx.Err()
@@ -247,7 +285,7 @@ func (o *openaiClient) stream(ctx context.Context, messages []message.Message, t
 	cfg := config.Get()
 	if cfg.Debug {
 		jsonData, _ := json.Marshal(params)
-		logging.Debug("Prepared messages", "messages", string(jsonData))
+		logging.FromContext(ctx).Debug("Prepared messages", "messages", string(jsonData))
 	}
 
 	attempts := 0
@@ -312,7 +350,7 @@ func (o *openaiClient) stream(ctx context.Context, messages []message.Message, t
 				return
 			}
 			if retry {
-				logging.WarnPersist(fmt.Sprintf("Retrying due to rate limit... attempt %d of %d", attempts, maxRetries), logging.PersistTimeArg, time.Millisecond*time.Duration(after+100))
+				logging.FromContext(ctx).WarnPersist(fmt.Sprintf("Retrying due to rate limit... attempt %d of %d", attempts, maxRetries), logging.PersistTimeArg, time.Millisecond*time.Duration(after+100))
 				select {
 				case <-ctx.Done():
 					// context cancelled
@@ -340,12 +378,12 @@ func (o *openaiClient) shouldRetry(attempts int, err error) (bool, int64, error)
 		return false, 0, err
 	}
 
-	if apierr.StatusCode != 429 && apierr.StatusCode != 500 {
+	if !retryLater(o.providerOptions.model, err) {
 		return false, 0, err
 	}
 
 	if attempts > maxRetries {
-		return false, 0, fmt.Errorf("maximum retry attempts reached for rate limit: %d retries", maxRetries)
+		return false, 0, fmt.Errorf("maximum retry attempts reached for rate limit: %d retries: %w", maxRetries, err)
 	}
 
 	retryMs := 0
@@ -393,6 +431,145 @@ func (o *openaiClient) usage(completion openai.ChatCompletion) TokenUsage {
 	}
 }
 
+// openaiBatchLine is a line of the JSONL file of a batch: a request going in,
+// its result coming out
+type openaiBatchLine struct {
+	CustomID string                          `json:"custom_id"`
+	Method   string                          `json:"method,omitempty"`
+	URL      string                          `json:"url,omitempty"`
+	Body     *openai.ChatCompletionNewParams `json:"body,omitempty"`
+	Response *struct {
+		StatusCode int             `json:"status_code"`
+		Body       json.RawMessage `json:"body"`
+	} `json:"response,omitempty"`
+	Error *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// submitBatch uploads the requests as a JSONL file and creates a batch of
+// chat completions from it
+func (o *openaiClient) submitBatch(ctx context.Context, requests []BatchRequest) (string, error) {
+	var input bytes.Buffer
+	encoder := json.NewEncoder(&input)
+	for _, request := range requests {
+		params := o.preparedParams(o.convertMessages(request.Messages), nil)
+		line := openaiBatchLine{
+			CustomID: request.CustomID,
+			Method:   "POST",
+			URL:      string(openai.BatchNewParamsEndpointV1ChatCompletions),
+			Body:     &params,
+		}
+		if err := encoder.Encode(line); err != nil {
+			return "", fmt.Errorf("failed to encode request %s: %w", request.CustomID, err)
+		}
+	}
+	file, err := o.client.Files.New(ctx, openai.FileNewParams{
+		File:    openai.File(&input, "batch.jsonl", "application/jsonl"),
+		Purpose: openai.FilePurposeBatch,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload the batch requests: %w", err)
+	}
+	batch, err := o.client.Batches.New(ctx, openai.BatchNewParams{
+		CompletionWindow: openai.BatchNewParamsCompletionWindow24h,
+		Endpoint:         openai.BatchNewParamsEndpointV1ChatCompletions,
+		InputFileID:      file.ID,
+	})
+	if err != nil {
+		return "", err
+	}
+	return batch.ID, nil
+}
+
+// pollBatch returns the state of a batch, reading its output and error files
+// once it ended. Expired and cancelled batches keep the results of the
+// requests processed before.
+func (o *openaiClient) pollBatch(ctx context.Context, batchID string) (BatchState, error) {
+	batch, err := o.client.Batches.Get(ctx, batchID)
+	if err != nil {
+		return BatchState{}, err
+	}
+	switch batch.Status {
+	case openai.BatchStatusFailed:
+		var reasons []string
+		for _, batchErr := range batch.Errors.Data {
+			reasons = append(reasons, batchErr.Message)
+		}
+		return BatchState{Status: BatchFailed, Err: strings.Join(reasons, "; ")}, nil
+	case openai.BatchStatusCompleted, openai.BatchStatusExpired, openai.BatchStatusCancelled:
+	default:
+		return BatchState{Status: BatchInProgress}, nil
+	}
+
+	state := BatchState{Status: BatchEnded}
+	for _, fileID := range []string{batch.OutputFileID, batch.ErrorFileID} {
+		if fileID == "" {
+			continue
+		}
+		results, err := o.batchResults(ctx, fileID)
+		if err != nil {
+			return BatchState{}, fmt.Errorf("failed to read the results of batch %s: %w", batchID, err)
+		}
+		state.Results = append(state.Results, results...)
+	}
+	return state, nil
+}
+
+// batchResults reads the results in an output or error file of a batch
+func (o *openaiClient) batchResults(ctx context.Context, fileID string) ([]BatchResult, error) {
+	content, err := o.client.Files.Content(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Body.Close()
+
+	var results []BatchResult
+	scanner := bufio.NewScanner(content.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var line openaiBatchLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return nil, err
+		}
+		result := BatchResult{CustomID: line.CustomID}
+		switch {
+		case line.Error != nil:
+			result.Err = line.Error.Message
+		case line.Response == nil:
+			result.Err = "no response"
+		case line.Response.StatusCode != 200:
+			result.Err = fmt.Sprintf("status %d: %s", line.Response.StatusCode, line.Response.Body)
+		default:
+			var completion openai.ChatCompletion
+			if err := json.Unmarshal(line.Response.Body, &completion); err != nil {
+				return nil, err
+			}
+			if len(completion.Choices) == 0 {
+				result.Err = "no choices in the response"
+				break
+			}
+			finishReason := o.finishReason(string(completion.Choices[0].FinishReason))
+			toolCalls := o.toolCalls(completion)
+			if len(toolCalls) > 0 {
+				finishReason = message.FinishReasonToolUse
+			}
+			result.Response = &ProviderResponse{
+				Content:      completion.Choices[0].Message.Content,
+				ToolCalls:    toolCalls,
+				Usage:        o.usage(completion),
+				FinishReason: finishReason,
+			}
+		}
+		results = append(results, result)
+	}
+	return results, scanner.Err()
+}
+
 func WithOpenAIBaseURL(baseURL string) OpenAIOption {
 	return func(options *openaiOptions) {
 		options.baseURL = baseURL
@@ -0,0 +1,67 @@
+package provider
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/message"
+)
+
+func TestOpenAIGenerationParams(t *testing.T) {
+	temperature, penalty := 1.5, 0.5
+	generation := config.GenerationParams{
+		Temperature:     &temperature,
+		PresencePenalty: &penalty,
+		Stop:            []string{"END"},
+	}
+
+	for _, tc := range []struct {
+		model models.ModelID
+		want  []string
+		omit  []string
+	}{
+		{models.GPT41, []string{`"temperature":1.5`, `"presence_penalty":0.5`, `"stop":["END"]`}, nil},
+		// Reasoning models reject sampling parameters
+		{models.O4Mini, []string{`"stop":["END"]`}, []string{"temperature", "presence_penalty"}},
+	} {
+		client := newOpenAIClient(providerClientOptions{
+			model:      models.SupportedModels[tc.model],
+			maxTokens:  1024,
+			generation: generation,
+		}).(*openaiClient)
+
+		messages := []message.Message{textMessage(message.User, "hello")}
+		data, err := json.Marshal(client.preparedParams(client.convertMessages(messages), nil))
+		if err != nil {
+			t.Fatalf("%s: failed to marshal params: %v", tc.model, err)
+		}
+		for _, want := range tc.want {
+			if !strings.Contains(string(data), want) {
+				t.Errorf("%s: request does not contain %s: %s", tc.model, want, data)
+			}
+		}
+		for _, omit := range tc.omit {
+			if strings.Contains(string(data), omit) {
+				t.Errorf("%s: request contains %s: %s", tc.model, omit, data)
+			}
+		}
+	}
+}
+
+func TestOpenAIFinishReason(t *testing.T) {
+	client := newOpenAIClient(providerClientOptions{model: models.SupportedModels[models.GPT41]}).(*openaiClient)
+	for reason, want := range map[string]message.FinishReason{
+		"stop":           message.FinishReasonEndTurn,
+		"length":         message.FinishReasonMaxTokens,
+		"tool_calls":     message.FinishReasonToolUse,
+		"content_filter": message.FinishReasonContentFilter,
+		"function_call":  message.FinishReasonUnknown,
+	} {
+		if got := client.finishReason(reason); got != want {
+			t.Errorf("finishReason(%q) = %q, want %q", reason, got, want)
+		}
+	}
+}
@@ -4,7 +4,11 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/caronex/intelligence-interface/internal/connectivity"
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/core/logging"
 	"github.com/caronex/intelligence-interface/internal/llm/models"
 	"github.com/caronex/intelligence-interface/internal/llm/tools"
 	"github.com/caronex/intelligence-interface/internal/message"
@@ -34,6 +38,21 @@ type TokenUsage struct {
 	CacheReadTokens     int64
 }
 
+// PromptTokens returns every input token of the request, whether it was
+// billed at the regular rate, written to the prompt cache or read from it.
+func (u TokenUsage) PromptTokens() int64 {
+	return u.InputTokens + u.CacheCreationTokens + u.CacheReadTokens
+}
+
+// Cost returns the price of the usage for model. Cache writes are billed at
+// CostPer1MInCached and cache reads at CostPer1MOutCached.
+func (u TokenUsage) Cost(model models.Model) float64 {
+	return model.CostPer1MInCached/1e6*float64(u.CacheCreationTokens) +
+		model.CostPer1MOutCached/1e6*float64(u.CacheReadTokens) +
+		model.CostPer1MIn/1e6*float64(u.InputTokens) +
+		model.CostPer1MOut/1e6*float64(u.OutputTokens)
+}
+
 type ProviderResponse struct {
 	Content      string
 	ToolCalls    []message.ToolCall
@@ -56,6 +75,9 @@ type Provider interface {
 	StreamResponse(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent
 
 	Model() models.Model
+
+	// Close releases the resources of the provider, which is not used after
+	Close() error
 }
 
 type providerClientOptions struct {
@@ -63,11 +85,14 @@ type providerClientOptions struct {
 	model         models.Model
 	maxTokens     int64
 	systemMessage string
+	generation    config.GenerationParams
+	timeouts      config.TimeoutDurations
 
 	anthropicOptions []AnthropicOption
 	openaiOptions    []OpenAIOption
 	geminiOptions    []GeminiOption
 	bedrockOptions   []BedrockOption
+	ollamaOptions    []OllamaOption
 }
 
 type ProviderClientOption func(*providerClientOptions)
@@ -83,10 +108,18 @@ type baseProvider[C ProviderClient] struct {
 }
 
 func NewProvider(providerName models.ModelProvider, opts ...ProviderClientOption) (Provider, error) {
-	clientOptions := providerClientOptions{}
+	clientOptions := providerClientOptions{timeouts: config.ProviderTimeouts{}.Durations()}
 	for _, o := range opts {
 		o(&clientOptions)
 	}
+	if providerName == models.ProviderTest {
+		return fakeFor(clientOptions.model, clientOptions.systemMessage), nil
+	}
+	if RealClientHook != nil {
+		if err := RealClientHook(providerName); err != nil {
+			return nil, err
+		}
+	}
 	switch providerName {
 	case models.ProviderAnthropic:
 		return &baseProvider[AnthropicClient]{
@@ -154,6 +187,22 @@ func NewProvider(providerName models.ModelProvider, opts ...ProviderClientOption
 			options: clientOptions,
 			client:  newOpenAIClient(clientOptions),
 		}, nil
+	case models.ProviderOllama:
+		ollamaOpts := []OllamaOption{WithOllamaBaseURL(models.OllamaEndpoint(""))}
+		if cfg := config.Get(); cfg != nil {
+			ollamaOpts = []OllamaOption{
+				WithOllamaBaseURL(cfg.Ollama.BaseURL()),
+				WithOllamaKeepAlive(cfg.Ollama.KeepAlive),
+			}
+			if cfg.Ollama.AutoPull {
+				ollamaOpts = append(ollamaOpts, WithOllamaAutoPull())
+			}
+		}
+		clientOptions.ollamaOptions = append(ollamaOpts, clientOptions.ollamaOptions...)
+		return &baseProvider[OllamaClient]{
+			options: clientOptions,
+			client:  newOllamaClient(clientOptions),
+		}, nil
 	case models.ProviderMock:
 		// TODO: implement mock client for test
 		panic("not implemented")
@@ -167,23 +216,128 @@ func (p *baseProvider[C]) cleanMessages(messages []message.Message) (cleaned []m
 		if len(msg.Parts) == 0 {
 			continue
 		}
+		msg = withSourceReferences(msg)
+		if !p.options.model.SupportsTools {
+			msg = withoutToolParts(msg)
+		}
 		cleaned = append(cleaned, msg)
 	}
 	return
 }
 
+// offeredTools returns the tools to define in a request, none for the models
+// without function calling, which reject them or answer with made-up tool
+// call syntax
+func (p *baseProvider[C]) offeredTools(tools []tools.BaseTool) []tools.BaseTool {
+	if !p.options.model.SupportsTools {
+		return nil
+	}
+	return tools
+}
+
+// withoutToolParts turns the tool calls and results of a message into text,
+// for the models without function calling: a conversation started with
+// another model can hold some
+func withoutToolParts(msg message.Message) message.Message {
+	var text []string
+	var parts []message.ContentPart
+	converted := false
+	for _, part := range msg.Parts {
+		switch part := part.(type) {
+		case message.TextContent:
+			text = append(text, part.Text)
+		case message.ToolCall:
+			text = append(text, fmt.Sprintf("[called the %s tool with %s]", part.Name, part.Input))
+			converted = true
+		case message.ToolResult:
+			text = append(text, fmt.Sprintf("[the %s tool returned]\n%s", part.Name, part.Content))
+			converted = true
+		default:
+			parts = append(parts, part)
+		}
+	}
+	if !converted {
+		return msg
+	}
+	if msg.Role == message.Tool {
+		msg.Role = message.User
+	}
+	msg.Parts = append([]message.ContentPart{message.TextContent{Text: strings.Join(text, "\n\n")}}, parts...)
+	return msg
+}
+
+// sourceReferenceHint tells the model how to cite the sources listed after a
+// tool result
+const sourceReferenceHint = "Cite these sources as [n] where your response relies on them."
+
+// withSourceReferences lists the sources quoted by a message, numbered, so
+// the model can cite them by index. Tool results list the sources they
+// quoted, and summaries the sources of the conversation they replace.
+func withSourceReferences(msg message.Message) message.Message {
+	sources := msg.Citations()
+	if len(sources) == 0 || msg.Role == message.Assistant {
+		return msg
+	}
+	parts := make([]message.ContentPart, 0, len(msg.Parts))
+	for _, part := range msg.Parts {
+		switch part := part.(type) {
+		case message.ToolResult:
+			var quoted []message.Citation
+			for _, source := range sources {
+				if source.ToolCallID == part.ToolCallID {
+					quoted = append(quoted, source)
+				}
+			}
+			if len(quoted) > 0 {
+				part.Content += "\n\n<sources>\n" + message.FormatSources(quoted) + "\n</sources>\n" + sourceReferenceHint
+			}
+			parts = append(parts, part)
+		case message.TextContent:
+			part.Text += "\n\nSources:\n" + message.FormatSources(sources)
+			parts = append(parts, part)
+		case message.Citation:
+			// Listed in the content above
+		default:
+			parts = append(parts, part)
+		}
+	}
+	msg.Parts = parts
+	return msg
+}
+
+// requiresNetwork reports whether the provider is remote and therefore unavailable offline
+func (p *baseProvider[C]) requiresNetwork() bool {
+	return !p.options.model.Provider.IsLocal()
+}
+
 func (p *baseProvider[C]) SendMessages(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, error) {
+	if p.requiresNetwork() && connectivity.IsOffline() {
+		return nil, connectivity.ErrOffline
+	}
+	ctx, _ = logging.EnsureRequestID(ctx)
 	messages = p.cleanMessages(messages)
-	return p.client.send(ctx, messages, tools)
+	return p.sendWithTimeouts(ctx, messages, p.offeredTools(tools))
 }
 
 func (p *baseProvider[C]) Model() models.Model {
 	return p.options.model
 }
 
+// Close does nothing, the clients only hold pooled HTTP connections
+func (p *baseProvider[C]) Close() error {
+	return nil
+}
+
 func (p *baseProvider[C]) StreamResponse(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent {
+	if p.requiresNetwork() && connectivity.IsOffline() {
+		eventChan := make(chan ProviderEvent, 1)
+		eventChan <- ProviderEvent{Type: EventError, Error: connectivity.ErrOffline}
+		close(eventChan)
+		return eventChan
+	}
+	ctx, _ = logging.EnsureRequestID(ctx)
 	messages = p.cleanMessages(messages)
-	return p.client.stream(ctx, messages, tools)
+	return p.streamWithTimeouts(ctx, messages, p.offeredTools(tools))
 }
 
 func WithAPIKey(apiKey string) ProviderClientOption {
@@ -210,6 +364,19 @@ func WithSystemMessage(systemMessage string) ProviderClientOption {
 	}
 }
 
+// WithGenerationParams sets the sampling parameters of requests, mapped to
+// the parameter names of each provider
+func WithGenerationParams(params config.GenerationParams) ProviderClientOption {
+	return func(options *providerClientOptions) {
+		options.generation = params
+	}
+}
+
+// ignoredParam logs a configured generation parameter the model does not support
+func ignoredParam(model models.Model, name string) {
+	logging.Debug("generation parameter not supported by the model, ignoring", "model", model.ID, "parameter", name)
+}
+
 func WithAnthropicOptions(anthropicOptions ...AnthropicOption) ProviderClientOption {
 	return func(options *providerClientOptions) {
 		options.anthropicOptions = anthropicOptions
@@ -228,6 +395,12 @@ func WithGeminiOptions(geminiOptions ...GeminiOption) ProviderClientOption {
 	}
 }
 
+func WithOllamaOptions(ollamaOptions ...OllamaOption) ProviderClientOption {
+	return func(options *providerClientOptions) {
+		options.ollamaOptions = ollamaOptions
+	}
+}
+
 func WithBedrockOptions(bedrockOptions ...BedrockOption) ProviderClientOption {
 	return func(options *providerClientOptions) {
 		options.bedrockOptions = bedrockOptions
@@ -0,0 +1,89 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/caronex/intelligence-interface/internal/message"
+)
+
+func TestWithSourceReferences(t *testing.T) {
+	toolMsg := message.Message{
+		Role: message.Tool,
+		Parts: []message.ContentPart{
+			message.ToolResult{ToolCallID: "call-1", Content: "package a"},
+			message.ToolResult{ToolCallID: "call-2", Content: "no sources"},
+			message.Citation{Index: 3, ToolCallID: "call-1", Source: "a.go", StartLine: 1, EndLine: 20, Quote: "package a"},
+		},
+	}
+	got := withSourceReferences(toolMsg)
+	results := got.ToolResults()
+	if len(results) != 2 || len(got.Citations()) != 0 {
+		t.Fatalf("parts = %+v, want the tool results without the citations", got.Parts)
+	}
+	want := "package a\n\n<sources>\n[3] a.go:1-20\n</sources>\n" + sourceReferenceHint
+	if results[0].Content != want {
+		t.Errorf("tool result = %q, want %q", results[0].Content, want)
+	}
+	if results[1].Content != "no sources" {
+		t.Errorf("tool result without sources = %q", results[1].Content)
+	}
+	// The stored message is left as is
+	if len(toolMsg.Citations()) != 1 || toolMsg.ToolResults()[0].Content != "package a" {
+		t.Errorf("withSourceReferences() changed the message: %+v", toolMsg.Parts)
+	}
+
+	// A summary sent as the first user message keeps listing the sources
+	summary := message.Message{
+		Role: message.User,
+		Parts: []message.ContentPart{
+			message.TextContent{Text: "We read a.go [3]."},
+			message.Citation{Index: 3, Source: "a.go", StartLine: 1, EndLine: 20},
+		},
+	}
+	got = withSourceReferences(summary)
+	if text := got.Content().Text; !strings.HasSuffix(text, "Sources:\n[3] a.go:1-20") {
+		t.Errorf("summary = %q, want its sources listed", text)
+	}
+
+	// Responses already cite their sources in their text
+	response := message.Message{
+		Role:  message.Assistant,
+		Parts: []message.ContentPart{message.TextContent{Text: "a [3]"}, message.Citation{Index: 3, Source: "a.go"}},
+	}
+	if got = withSourceReferences(response); got.Content().Text != "a [3]" {
+		t.Errorf("response = %q, want it unchanged", got.Content().Text)
+	}
+}
+
+func TestWithoutToolParts(t *testing.T) {
+	call := message.Message{
+		Role: message.Assistant,
+		Parts: []message.ContentPart{
+			message.TextContent{Text: "Let me look."},
+			message.ToolCall{ID: "call-1", Name: "view", Input: `{"file_path":"a.go"}`},
+			message.Finish{Reason: message.FinishReasonToolUse},
+		},
+	}
+	got := withoutToolParts(call)
+	if len(got.ToolCalls()) != 0 || got.Content().Text != "Let me look.\n\n[called the view tool with {\"file_path\":\"a.go\"}]" {
+		t.Errorf("tool call = %+v, want it as text", got.Parts)
+	}
+	if got.FinishPart() == nil {
+		t.Error("the finish part was dropped")
+	}
+
+	result := message.Message{
+		Role:  message.Tool,
+		Parts: []message.ContentPart{message.ToolResult{ToolCallID: "call-1", Name: "view", Content: "package a"}},
+	}
+	got = withoutToolParts(result)
+	if got.Role != message.User || got.Content().Text != "[the view tool returned]\npackage a" {
+		t.Errorf("tool result = %s %+v, want a user message with its content", got.Role, got.Parts)
+	}
+
+	text := message.Message{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "hi"}}}
+	if got = withoutToolParts(text); got.Content().Text != "hi" || len(got.Parts) != 1 {
+		t.Errorf("message without tool parts = %+v, want it unchanged", got.Parts)
+	}
+}
@@ -0,0 +1,195 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/llm/tools"
+	"github.com/caronex/intelligence-interface/internal/message"
+)
+
+// maxTimeoutRetries is the number of times a request that timed out before
+// any token was received is sent again
+const maxTimeoutRetries = 2
+
+// TimeoutPhase is the part of a provider request that took too long
+type TimeoutPhase string
+
+const (
+	TimeoutConnect    TimeoutPhase = "connecting"
+	TimeoutFirstToken TimeoutPhase = "waiting for the first token"
+	TimeoutIdle       TimeoutPhase = "waiting for the next chunk"
+	TimeoutRequest    TimeoutPhase = "before completing the request"
+)
+
+// TimeoutError is returned when a provider does not answer within one of
+// its timeouts
+type TimeoutError struct {
+	Phase TimeoutPhase
+	After time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("model timed out after %s %s", e.After, e.Phase)
+}
+
+// Timeout reports that the error is a timeout, like net.Error
+func (e *TimeoutError) Timeout() bool {
+	return true
+}
+
+// IsRetryable reports whether a request that failed with err can be sent
+// again as is, as a request that timed out can
+func IsRetryable(err error) bool {
+	var timeoutErr *TimeoutError
+	return errors.As(err, &timeoutErr)
+}
+
+// WithTimeouts sets the timeouts of requests
+func WithTimeouts(timeouts config.TimeoutDurations) ProviderClientOption {
+	return func(options *providerClientOptions) {
+		options.timeouts = timeouts
+	}
+}
+
+// newHTTPClient creates the HTTP client of a provider, which bounds opening
+// connections. The other timeouts are deadlines of the request context, so
+// streamed responses are not cut off by a client timeout.
+func newHTTPClient(timeouts config.TimeoutDurations) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   timeouts.Connect,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = timeouts.Connect
+	return &http.Client{Transport: transport}
+}
+
+// timeoutError returns the timeout behind err: the cause of the request
+// context ending, or a connection that could not be opened in time. Other
+// errors are returned as is.
+func (p *baseProvider[C]) timeoutError(ctx context.Context, err error) error {
+	var timeoutErr *TimeoutError
+	if ctx.Err() != nil && errors.As(context.Cause(ctx), &timeoutErr) {
+		return timeoutErr
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() && !errors.As(err, &timeoutErr) {
+		return &TimeoutError{Phase: TimeoutConnect, After: p.options.timeouts.Connect}
+	}
+	return err
+}
+
+// firstTokenTimeout is how long a streamed response can take to start. Models
+// that reason before answering may not stream anything meanwhile, so they get
+// at least the idle timeout.
+func (p *baseProvider[C]) firstTokenTimeout() time.Duration {
+	timeouts := p.options.timeouts
+	if p.options.model.CanReason {
+		return max(timeouts.FirstToken, timeouts.Idle)
+	}
+	return timeouts.FirstToken
+}
+
+// sendWithTimeouts sends a request within the request timeout, sending it
+// again when it timed out
+func (p *baseProvider[C]) sendWithTimeouts(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, error) {
+	timeout := p.options.timeouts.Request
+	for attempt := 1; ; attempt++ {
+		requestCtx, cancel := context.WithTimeoutCause(ctx, timeout, &TimeoutError{Phase: TimeoutRequest, After: timeout})
+		response, err := p.client.send(requestCtx, messages, tools)
+		if err != nil {
+			err = p.timeoutError(requestCtx, err)
+		}
+		cancel()
+		if err == nil || !IsRetryable(err) || attempt > maxTimeoutRetries || ctx.Err() != nil {
+			return response, Classify(p.options.model, err)
+		}
+		logging.FromContext(ctx).WarnPersist(fmt.Sprintf("Retrying, %s... attempt %d of %d", err, attempt, maxTimeoutRetries))
+	}
+}
+
+// streamWithTimeouts streams a response within the timeouts, streaming it
+// again when it timed out before any token was received
+func (p *baseProvider[C]) streamWithTimeouts(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent {
+	eventChan := make(chan ProviderEvent)
+	go func() {
+		defer close(eventChan)
+		for attempt := 1; ; attempt++ {
+			started, err := p.streamAttempt(ctx, messages, tools, eventChan)
+			if err == nil {
+				return
+			}
+			if started || !IsRetryable(err) || attempt > maxTimeoutRetries || ctx.Err() != nil {
+				eventChan <- ProviderEvent{Type: EventError, Error: Classify(p.options.model, err)}
+				return
+			}
+			logging.FromContext(ctx).WarnPersist(fmt.Sprintf("Retrying, %s... attempt %d of %d", err, attempt, maxTimeoutRetries))
+		}
+	}()
+	return eventChan
+}
+
+// streamAttempt forwards the events of one streamed request to out until it
+// completes, fails or times out. It returns the error ending the stream, if
+// any, and whether a token was forwarded, after which the request cannot be
+// sent again without repeating output.
+func (p *baseProvider[C]) streamAttempt(ctx context.Context, messages []message.Message, tools []tools.BaseTool, out chan<- ProviderEvent) (started bool, err error) {
+	timeouts := p.options.timeouts
+	requestCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	requestCtx, cancelDeadline := context.WithTimeoutCause(requestCtx, timeouts.Request, &TimeoutError{Phase: TimeoutRequest, After: timeouts.Request})
+	defer cancelDeadline()
+
+	events := p.client.stream(requestCtx, messages, tools)
+	// The client stops once the request context ends, its remaining events
+	// are dropped
+	defer func() {
+		cancel(nil)
+		for range events {
+		}
+	}()
+
+	timeout := &TimeoutError{Phase: TimeoutFirstToken, After: p.firstTokenTimeout()}
+	timer := time.NewTimer(timeout.After)
+	defer timer.Stop()
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return started, nil
+			}
+			if event.Type == EventError {
+				return started, p.timeoutError(requestCtx, event.Error)
+			}
+			if isToken(event.Type) {
+				started = true
+				timeout = &TimeoutError{Phase: TimeoutIdle, After: timeouts.Idle}
+				timer.Reset(timeout.After)
+			}
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return started, ctx.Err()
+			}
+		case <-timer.C:
+			cancel(timeout)
+			return started, timeout
+		}
+	}
+}
+
+// isToken reports whether an event carries output of the model
+func isToken(eventType EventType) bool {
+	switch eventType {
+	case EventContentDelta, EventThinkingDelta, EventToolUseStart, EventToolUseDelta, EventComplete:
+		return true
+	}
+	return false
+}
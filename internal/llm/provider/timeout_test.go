@@ -0,0 +1,151 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/message"
+)
+
+// newSlowProvider returns an OpenAI provider sending its requests to handler,
+// with short timeouts, and the number of requests handled
+func newSlowProvider(t *testing.T, handler http.HandlerFunc) (Provider, *atomic.Int32) {
+	t.Helper()
+	config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		// The server notices the client going away once the body is read
+		io.Copy(io.Discard, r.Body)
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	// The client only reaches the test server
+	hook := RealClientHook
+	RealClientHook = nil
+	t.Cleanup(func() { RealClientHook = hook })
+
+	p, err := NewProvider(models.ProviderOpenAI,
+		WithAPIKey("test"),
+		WithModel(models.SupportedModels[models.GPT41]),
+		WithTimeouts(config.TimeoutDurations{
+			Connect:    time.Second,
+			FirstToken: 100 * time.Millisecond,
+			Idle:       100 * time.Millisecond,
+			Request:    300 * time.Millisecond,
+		}),
+		WithOpenAIOptions(WithOpenAIBaseURL(server.URL)),
+	)
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+	return p, &requests
+}
+
+// hang answers nothing until the client gives up
+func hang(w http.ResponseWriter, r *http.Request) {
+	<-r.Context().Done()
+}
+
+func assertTimeout(t *testing.T, err error, phase TimeoutPhase) {
+	t.Helper()
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("error = %v, want a timeout", err)
+	}
+	if timeoutErr.Phase != phase {
+		t.Errorf("timeout phase = %q, want %q", timeoutErr.Phase, phase)
+	}
+	if !IsRetryable(err) {
+		t.Error("timeouts should be retryable")
+	}
+}
+
+func TestStreamFirstTokenTimeout(t *testing.T) {
+	p, requests := newSlowProvider(t, hang)
+
+	var events []ProviderEvent
+	for event := range p.StreamResponse(context.Background(), []message.Message{textMessage(message.User, "hello")}, nil) {
+		events = append(events, event)
+	}
+	if len(events) != 1 || events[0].Type != EventError {
+		t.Fatalf("events = %+v, want a single error", events)
+	}
+	assertTimeout(t, events[0].Error, TimeoutFirstToken)
+	if got := events[0].Error.Error(); got != "model timed out after 100ms waiting for the first token" {
+		t.Errorf("error message = %q", got)
+	}
+	if got := requests.Load(); got != 1+maxTimeoutRetries {
+		t.Errorf("%d requests sent, want the request retried %d times", got, maxTimeoutRetries)
+	}
+}
+
+func TestStreamIdleTimeout(t *testing.T) {
+	p, requests := newSlowProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"id":"1","object":"chat.completion.chunk","created":0,"model":"gpt-4.1","choices":[{"index":0,"delta":{"role":"assistant","content":"Hel"},"finish_reason":null}]}`+"\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+
+	var content string
+	var lastErr error
+	for event := range p.StreamResponse(context.Background(), []message.Message{textMessage(message.User, "hello")}, nil) {
+		switch event.Type {
+		case EventContentDelta:
+			content += event.Content
+		case EventError:
+			lastErr = event.Error
+		}
+	}
+	if content != "Hel" {
+		t.Errorf("content = %q, want the chunk received before the timeout", content)
+	}
+	assertTimeout(t, lastErr, TimeoutIdle)
+	if got := requests.Load(); got != 1 {
+		t.Errorf("%d requests sent, a response that started must not be retried", got)
+	}
+}
+
+func TestSendRequestTimeout(t *testing.T) {
+	p, requests := newSlowProvider(t, hang)
+
+	start := time.Now()
+	_, err := p.SendMessages(context.Background(), []message.Message{textMessage(message.User, "hello")}, nil)
+	assertTimeout(t, err, TimeoutRequest)
+	if got := requests.Load(); got != 1+maxTimeoutRetries {
+		t.Errorf("%d requests sent, want the request retried %d times", got, maxTimeoutRetries)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("request took %s, the timeout was not enforced", elapsed)
+	}
+}
+
+func TestStreamCanceledIsNotTimeout(t *testing.T) {
+	p, requests := newSlowProvider(t, hang)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	var lastErr error
+	for event := range p.StreamResponse(ctx, []message.Message{textMessage(message.User, "hello")}, nil) {
+		if event.Type == EventError {
+			lastErr = event.Error
+		}
+	}
+	if !errors.Is(lastErr, context.Canceled) || IsRetryable(lastErr) {
+		t.Errorf("error = %v, want the cancellation", lastErr)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("%d requests sent, a canceled request must not be retried", got)
+	}
+}
@@ -0,0 +1,51 @@
+// Package tokens estimates the tokens of the prompts sent to the providers,
+// which have no common tokenizer.
+package tokens
+
+const (
+	// bytesPerToken is the average length of a token of English text and code
+	bytesPerToken = 4
+	// MessageOverhead is the tokens framing each message of a conversation:
+	// its role and the separators around it
+	MessageOverhead = 4
+	// RequestOverhead is the tokens priming the response of a request
+	RequestOverhead = 3
+	// ToolOverhead is the tokens framing the definition of each tool
+	ToolOverhead = 8
+	// ImageTokens is the tokens of an image, at the resolution the providers
+	// scale large images down to
+	ImageTokens = 1600
+)
+
+// Estimate approximates the tokens of text at four bytes a token
+func Estimate(text string) int {
+	return (len(text) + bytesPerToken - 1) / bytesPerToken
+}
+
+// Prompt is the content of a request, as it is sent to the provider
+type Prompt struct {
+	// System is the system prompt, with the context files it includes
+	System string `json:"system"`
+	// Messages are the text of each message of the conversation
+	Messages []string `json:"messages"`
+	// Tools are the definitions of the tools offered to the model: their
+	// name, description and parameters schema
+	Tools []string `json:"tools"`
+	// Images is the number of images attached to the messages
+	Images int `json:"images"`
+}
+
+// Tokens estimates the prompt tokens of the request
+func (p Prompt) Tokens() int {
+	total := RequestOverhead
+	if p.System != "" {
+		total += Estimate(p.System) + MessageOverhead
+	}
+	for _, msg := range p.Messages {
+		total += Estimate(msg) + MessageOverhead
+	}
+	for _, tool := range p.Tools {
+		total += Estimate(tool) + ToolOverhead
+	}
+	return total + p.Images*ImageTokens
+}
@@ -0,0 +1,48 @@
+package tokens
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixture is a prompt and the prompt tokens expected for it
+type fixture struct {
+	Prompt
+	Name         string `json:"name"`
+	PromptTokens int    `json:"prompt_tokens"`
+}
+
+func TestEstimate(t *testing.T) {
+	assert.Equal(t, 0, Estimate(""))
+	assert.Equal(t, 1, Estimate("go"))
+	assert.Equal(t, 2, Estimate("tokens"))
+}
+
+func TestPromptTokensWithinTenPercent(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "prompts.json"))
+	require.NoError(t, err)
+	var fixtures []fixture
+	require.NoError(t, json.Unmarshal(data, &fixtures))
+	require.NotEmpty(t, fixtures)
+
+	for _, f := range fixtures {
+		t.Run(f.Name, func(t *testing.T) {
+			estimate := f.Tokens()
+			deviation := math.Abs(float64(estimate-f.PromptTokens)) / float64(f.PromptTokens)
+			assert.LessOrEqual(t, deviation, 0.10, "estimated %d prompt tokens, the fixture expects %d", estimate, f.PromptTokens)
+		})
+	}
+}
+
+func TestPromptTokensCountsImages(t *testing.T) {
+	text := Prompt{Messages: []string{"What is on this screenshot?"}}
+	image := text
+	image.Images = 2
+	assert.Equal(t, text.Tokens()+2*ImageTokens, image.Tokens())
+}
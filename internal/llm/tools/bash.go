@@ -2,12 +2,12 @@ package tools
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/llm/tools/guard"
 	"github.com/caronex/intelligence-interface/internal/llm/tools/shell"
 	"github.com/caronex/intelligence-interface/internal/permission"
 )
@@ -20,6 +20,8 @@ type BashParams struct {
 type BashPermissionsParams struct {
 	Command string `json:"command"`
 	Timeout int    `json:"timeout"`
+	// Warnings are the reasons the shell guard asks for approval
+	Warnings []string `json:"warnings,omitempty"`
 }
 
 type BashResponseMetadata struct {
@@ -67,6 +69,7 @@ Before executing the command, please follow these steps:
 2. Security Check:
  - For security and to limit the threat of a prompt injection attack, some commands are limited or banned. If you use a disallowed command, you will receive an error message explaining the restriction. Explain the error to the User.
  - Verify that the command is not one of the banned commands: %s.
+ - Commands are also checked by a shell guard before they run. It refuses dangerous commands such as piping a download into a shell, recursive deletions or permission changes outside the project, sudo and fork bombs, returning a JSON refusal with the rules broken. Do not try to work around a refusal: find another way or explain it to the User. Package installs always ask the User for approval.
 
 3. Command Execution:
  - After ensuring proper quoting, execute the command.
@@ -223,14 +226,15 @@ func (b *bashTool) Info() ToolInfo {
 				"description": "Optional timeout in milliseconds (max 600000)",
 			},
 		},
-		Required: []string{"command"},
+		Required:    []string{"command"},
+		Destructive: true,
 	}
 }
 
 func (b *bashTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
 	var params BashParams
-	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
-		return NewTextErrorResponse("invalid parameters"), nil
+	if err := call.BindInput(&params); err != nil {
+		return NewTextErrorResponse(err.Error()), nil
 	}
 
 	if params.Timeout > MaxTimeout {
@@ -250,6 +254,16 @@ func (b *bashTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		}
 	}
 
+	shell := shell.GetPersistentShell(config.WorkingDirectory())
+	g, err := guard.New(config.Get().Shell.Guard, sandboxRoots())
+	if err != nil {
+		return ToolResponse{}, fmt.Errorf("error creating the shell guard: %w", err)
+	}
+	check := g.Check(params.Command, shell.Dir())
+	if check.Decision == guard.Block {
+		return NewTextErrorResponse(check.Refusal()), nil
+	}
+
 	isSafeReadOnly := false
 	cmdLower := strings.ToLower(params.Command)
 
@@ -266,17 +280,24 @@ func (b *bashTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 	if sessionID == "" || messageID == "" {
 		return ToolResponse{}, fmt.Errorf("session ID and message ID are required for creating a new file")
 	}
-	if !isSafeReadOnly {
+	warned := check.Decision == guard.Warn
+	if !isSafeReadOnly || warned {
+		description := fmt.Sprintf("Execute command: %s", params.Command)
+		if warned {
+			description += "\nShell guard: " + strings.Join(check.Warnings(), "; ")
+		}
 		p := b.permissions.Request(
 			permission.CreatePermissionRequest{
 				SessionID:   sessionID,
 				Path:        config.WorkingDirectory(),
 				ToolName:    BashToolName,
 				Action:      "execute",
-				Description: fmt.Sprintf("Execute command: %s", params.Command),
+				Description: description,
 				Params: BashPermissionsParams{
-					Command: params.Command,
+					Command:  params.Command,
+					Warnings: check.Warnings(),
 				},
+				Confirm: warned,
 			},
 		)
 		if !p {
@@ -284,8 +305,11 @@ func (b *bashTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		}
 	}
 	startTime := time.Now()
-	shell := shell.GetPersistentShell(config.WorkingDirectory())
-	stdout, stderr, exitCode, interrupted, err := shell.Exec(ctx, params.Command, params.Timeout)
+	stopStreaming := streamOutput(sessionID, call.ID, startTime)
+	stdout, stderr, exitCode, interrupted, err := shell.ExecStream(ctx, params.Command, params.Timeout, func(chunk string) {
+		PublishOutput(ToolOutput{SessionID: sessionID, ToolCallID: call.ID, Chunk: chunk, StartTime: startTime})
+	})
+	stopStreaming()
 	if err != nil {
 		return ToolResponse{}, fmt.Errorf("error executing command: %w", err)
 	}
@@ -326,6 +350,43 @@ func (b *bashTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 	return WithResponseMetadata(NewTextResponse(stdout), metadata), nil
 }
 
+// sandboxRoots returns the directories the shell guard confines recursive
+// operations to: the working directory and the workspace roots
+func sandboxRoots() []string {
+	roots := []string{config.WorkingDirectory()}
+	workspaces := config.WorkspaceRoots()
+	for _, name := range config.WorkspaceRootNames() {
+		roots = append(roots, workspaces[name].Path)
+	}
+	return roots
+}
+
+// streamOutput publishes an update every second while a tool call runs, for
+// its elapsed time to show even while it writes nothing, until the returned
+// function marks it done
+func streamOutput(sessionID, toolCallID string, startTime time.Time) func() {
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				PublishOutput(ToolOutput{SessionID: sessionID, ToolCallID: toolCallID, StartTime: startTime})
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-stopped
+		PublishOutput(ToolOutput{SessionID: sessionID, ToolCallID: toolCallID, StartTime: startTime, Done: true})
+	}
+}
+
 func truncateOutput(content string) string {
 	if len(content) <= MaxOutputLength {
 		return content
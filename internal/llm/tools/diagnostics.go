@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/caronex/intelligence-interface/internal/core/config"
 	"github.com/caronex/intelligence-interface/internal/lsp"
 	"github.com/caronex/intelligence-interface/internal/lsp/protocol"
 )
@@ -67,8 +68,8 @@ func (b *diagnosticsTool) Info() ToolInfo {
 
 func (b *diagnosticsTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
 	var params DiagnosticsParams
-	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
-		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	if err := call.BindInput(&params); err != nil {
+		return NewTextErrorResponse(err.Error()), nil
 	}
 
 	lsps := b.lspClients
@@ -78,6 +79,11 @@ func (b *diagnosticsTool) Run(ctx context.Context, call ToolCall) (ToolResponse,
 	}
 
 	if params.FilePath != "" {
+		filePath, err := config.ResolveWorkspacePath(params.FilePath)
+		if err != nil {
+			return NewTextErrorResponse(err.Error()), nil
+		}
+		params.FilePath = filePath
 		notifyLspOpenFile(ctx, params.FilePath, lsps)
 		waitForLspDiagnostics(ctx, params.FilePath, lsps)
 	}
@@ -2,7 +2,6 @@ package tools
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -21,6 +20,9 @@ type EditParams struct {
 	FilePath  string `json:"file_path"`
 	OldString string `json:"old_string"`
 	NewString string `json:"new_string"`
+	// ExpectedHash is the hash of the file the edit is meant for, as reported
+	// by the View tool
+	ExpectedHash string `json:"expected_hash,omitempty"`
 }
 
 type EditPermissionsParams struct {
@@ -116,58 +118,59 @@ func (e *editTool) Info() ToolInfo {
 				"type":        "string",
 				"description": "The text to replace it with",
 			},
+			"expected_hash": map[string]any{
+				"type":        "string",
+				"description": "The file hash reported when the file was last read (optional). The edit fails with a conflict when the file has changed since",
+			},
 		},
-		Required: []string{"file_path", "old_string", "new_string"},
+		Required:    []string{"file_path", "old_string", "new_string"},
+		Destructive: true,
 	}
 }
 
 func (e *editTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
 	var params EditParams
-	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
-		return NewTextErrorResponse("invalid parameters"), nil
+	if err := call.BindInput(&params); err != nil {
+		return NewTextErrorResponse(err.Error()), nil
 	}
 
 	if params.FilePath == "" {
 		return NewTextErrorResponse("file_path is required"), nil
 	}
 
-	if !filepath.IsAbs(params.FilePath) {
-		wd := config.WorkingDirectory()
-		params.FilePath = filepath.Join(wd, params.FilePath)
-	}
-
-	var response ToolResponse
-	var err error
-
-	if params.OldString == "" {
-		response, err = e.createNewFile(ctx, params.FilePath, params.NewString)
-		if err != nil {
-			return response, err
+	filePath, err := config.ResolveWorkspacePath(params.FilePath)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	params.FilePath = filePath
+	config.NoteWorkspaceFile(params.FilePath)
+
+	// A retried call is applied once, so an insertion is not made twice
+	return applyOnce(ctx, e.files, EditToolName, params.FilePath, params.ExpectedHash, func() (ToolResponse, error) {
+		var response ToolResponse
+		switch {
+		case params.OldString == "":
+			response, err = e.createNewFile(ctx, params.FilePath, params.NewString)
+		case params.NewString == "":
+			response, err = e.deleteContent(ctx, params.FilePath, params.OldString)
+		default:
+			response, err = e.replaceContent(ctx, params.FilePath, params.OldString, params.NewString)
 		}
-	}
-
-	if params.NewString == "" {
-		response, err = e.deleteContent(ctx, params.FilePath, params.OldString)
 		if err != nil {
 			return response, err
 		}
-	}
+		if response.IsError {
+			// Return early if there was an error during content replacement
+			// This prevents unnecessary LSP diagnostics processing
+			return response, nil
+		}
 
-	response, err = e.replaceContent(ctx, params.FilePath, params.OldString, params.NewString)
-	if err != nil {
-		return response, err
-	}
-	if response.IsError {
-		// Return early if there was an error during content replacement
-		// This prevents unnecessary LSP diagnostics processing
+		waitForLspDiagnostics(ctx, params.FilePath, e.lspClients)
+		text := fmt.Sprintf("<result>\n%s\n</result>\n", response.Content)
+		text += getDiagnostics(params.FilePath, e.lspClients)
+		response.Content = text
 		return response, nil
-	}
-
-	waitForLspDiagnostics(ctx, params.FilePath, e.lspClients)
-	text := fmt.Sprintf("<result>\n%s\n</result>\n", response.Content)
-	text += getDiagnostics(params.FilePath, e.lspClients)
-	response.Content = text
-	return response, nil
+	})
 }
 
 func (e *editTool) createNewFile(ctx context.Context, filePath, content string) (ToolResponse, error) {
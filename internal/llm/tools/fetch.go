@@ -2,10 +2,10 @@ package tools
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"strings"
 	"time"
 
@@ -19,12 +19,18 @@ type FetchParams struct {
 	URL     string `json:"url"`
 	Format  string `json:"format"`
 	Timeout int    `json:"timeout,omitempty"`
+	// Headers are sent with the request, their values expanding the
+	// ${NAME} references to the variables of the active space
+	Headers map[string]string `json:"headers,omitempty"`
 }
 
+// FetchPermissionsParams are shown when asking to fetch, with the headers as
+// written, the values of the space variables they reference left out
 type FetchPermissionsParams struct {
-	URL     string `json:"url"`
-	Format  string `json:"format"`
-	Timeout int    `json:"timeout,omitempty"`
+	URL     string            `json:"url"`
+	Format  string            `json:"format"`
+	Timeout int               `json:"timeout,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
 }
 
 type fetchTool struct {
@@ -45,6 +51,7 @@ HOW TO USE:
 - Provide the URL to fetch content from
 - Specify the desired output format (text, markdown, or html)
 - Optionally set a timeout for the request
+- Optionally set request headers. Their values may reference the variables of the active space as ${NAME}, e.g. "Authorization": "Bearer ${API_TOKEN}", without the values being shown
 
 FEATURES:
 - Supports three output formats: text, markdown, and html
@@ -55,7 +62,7 @@ FEATURES:
 LIMITATIONS:
 - Maximum response size is 5MB
 - Only supports HTTP and HTTPS protocols
-- Cannot handle authentication or cookies
+- Authenticates only with the headers given, cookies are not kept
 - Some websites may block automated requests
 
 TIPS:
@@ -92,6 +99,11 @@ func (t *fetchTool) Info() ToolInfo {
 				"type":        "number",
 				"description": "Optional timeout in seconds (max 120)",
 			},
+			"headers": map[string]any{
+				"type":                 "object",
+				"description":          "Optional request headers, whose values may reference the variables of the active space as ${NAME}",
+				"additionalProperties": map[string]any{"type": "string"},
+			},
 		},
 		Required: []string{"url", "format"},
 	}
@@ -99,8 +111,8 @@ func (t *fetchTool) Info() ToolInfo {
 
 func (t *fetchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
 	var params FetchParams
-	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
-		return NewTextErrorResponse("Failed to parse fetch parameters: " + err.Error()), nil
+	if err := call.BindInput(&params); err != nil {
+		return NewTextErrorResponse(err.Error()), nil
 	}
 
 	if params.URL == "" {
@@ -116,6 +128,11 @@ func (t *fetchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		return NewTextErrorResponse("URL must start with http:// or https://"), nil
 	}
 
+	headers, err := expandHeaders(params.Headers)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+
 	sessionID, messageID := GetContextValues(ctx)
 	if sessionID == "" || messageID == "" {
 		return ToolResponse{}, fmt.Errorf("session ID and message ID are required for creating a new file")
@@ -153,6 +170,9 @@ func (t *fetchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 	}
 
 	req.Header.Set("User-Agent", "intelligence-interface/1.0")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -173,33 +193,74 @@ func (t *fetchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 	content := string(body)
 	contentType := resp.Header.Get("Content-Type")
 
+	response := formatFetched(content, contentType, format)
+	if response.IsError {
+		return response, nil
+	}
+	return WithResponseSources(response, Source{Location: params.URL, Quote: response.Content}), nil
+}
+
+// expandHeaders returns the headers with the ${NAME} references in their
+// values expanded to the variables of the active space. Only the space
+// variables are expanded, not the process environment, and the errors name the
+// variables without their values.
+func expandHeaders(headers map[string]string) (map[string]string, error) {
+	if len(headers) == 0 {
+		return nil, nil
+	}
+	variables := config.SpaceVariables()
+	expanded := make(map[string]string, len(headers))
+	for name, value := range headers {
+		var missing []string
+		expanded[name] = os.Expand(value, func(variable string) string {
+			resolved, ok := variables[variable]
+			if !ok {
+				missing = append(missing, variable)
+			}
+			return resolved
+		})
+		if len(missing) > 0 {
+			if config.ActiveSpace() == "" {
+				return nil, fmt.Errorf("header %s references %s, but no space is active", name, strings.Join(missing, ", "))
+			}
+			return nil, fmt.Errorf("header %s references %s, not a variable of the active space %s", name, strings.Join(missing, ", "), config.ActiveSpace())
+		}
+		if strings.ContainsAny(expanded[name], "\r\n") {
+			return nil, fmt.Errorf("header %s has a line break in its value", name)
+		}
+	}
+	return expanded, nil
+}
+
+// formatFetched converts the fetched content into format
+func formatFetched(content, contentType, format string) ToolResponse {
 	switch format {
 	case "text":
 		if strings.Contains(contentType, "text/html") {
 			text, err := extractTextFromHTML(content)
 			if err != nil {
-				return NewTextErrorResponse("Failed to extract text from HTML: " + err.Error()), nil
+				return NewTextErrorResponse("Failed to extract text from HTML: " + err.Error())
 			}
-			return NewTextResponse(text), nil
+			return NewTextResponse(text)
 		}
-		return NewTextResponse(content), nil
+		return NewTextResponse(content)
 
 	case "markdown":
 		if strings.Contains(contentType, "text/html") {
 			markdown, err := convertHTMLToMarkdown(content)
 			if err != nil {
-				return NewTextErrorResponse("Failed to convert HTML to Markdown: " + err.Error()), nil
+				return NewTextErrorResponse("Failed to convert HTML to Markdown: " + err.Error())
 			}
-			return NewTextResponse(markdown), nil
+			return NewTextResponse(markdown)
 		}
 
-		return NewTextResponse("```\n" + content + "\n```"), nil
+		return NewTextResponse("```\n" + content + "\n```")
 
 	case "html":
-		return NewTextResponse(content), nil
+		return NewTextResponse(content)
 
 	default:
-		return NewTextResponse(content), nil
+		return NewTextResponse(content)
 	}
 }
 
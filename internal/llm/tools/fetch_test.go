@@ -0,0 +1,62 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/permission"
+)
+
+func TestFetchHeaderTemplating(t *testing.T) {
+	config.NewTestConfig(
+		config.WithWorkingDir(t.TempDir()),
+		config.WithSpace(config.SpaceConfig{ID: "alpha", Environment: map[string]string{"API_TOKEN": "alpha-token"}}),
+		config.WithSpace(config.SpaceConfig{ID: "beta", Environment: map[string]string{"BETA_TOKEN": "beta-token"}}),
+	)
+	t.Cleanup(func() { config.SetActiveSpace("") })
+	require.NoError(t, config.SetActiveSpace("alpha"))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer server.Close()
+
+	permissions := permission.NewPermissionService()
+	permissions.AutoApproveSession("session-1")
+	ctx := context.WithValue(context.Background(), SessionIDContextKey, "session-1")
+	ctx = context.WithValue(ctx, MessageIDContextKey, "message-1")
+	fetch := NewFetchTool(permissions)
+	run := func(headers map[string]string) ToolResponse {
+		input, err := json.Marshal(FetchParams{URL: server.URL, Format: "text", Headers: headers})
+		require.NoError(t, err)
+		response, err := fetch.Run(ctx, ToolCall{ID: "call", Name: FetchToolName, Input: string(input)})
+		require.NoError(t, err)
+		return response
+	}
+
+	response := run(map[string]string{"Authorization": "Bearer ${API_TOKEN}"})
+	require.False(t, response.IsError, response.Content)
+	assert.Equal(t, "Bearer alpha-token", response.Content)
+
+	// The variables of the other spaces and of the process are not expanded
+	t.Setenv("HOME_TOKEN", "process-token")
+	for _, variable := range []string{"BETA_TOKEN", "HOME_TOKEN"} {
+		response = run(map[string]string{"Authorization": "Bearer ${" + variable + "}"})
+		assert.True(t, response.IsError, "a header referencing %s is sent", variable)
+		assert.Contains(t, response.Content, variable)
+		assert.NotContains(t, response.Content, "beta-token")
+		assert.NotContains(t, response.Content, "process-token")
+	}
+
+	require.NoError(t, config.SetActiveSpace(""))
+	response = run(map[string]string{"Authorization": "Bearer ${API_TOKEN}"})
+	assert.True(t, response.IsError, "the space variables are expanded without an active space")
+	assert.Contains(t, response.Content, "no space is active")
+}
@@ -3,7 +3,6 @@ package tools
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"os/exec"
 	"path/filepath"
@@ -89,17 +88,20 @@ func (g *globTool) Info() ToolInfo {
 
 func (g *globTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
 	var params GlobParams
-	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
-		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	if err := call.BindInput(&params); err != nil {
+		return NewTextErrorResponse(err.Error()), nil
 	}
 
 	if params.Pattern == "" {
 		return NewTextErrorResponse("pattern is required"), nil
 	}
 
-	searchPath := params.Path
+	searchPath, err := config.ResolveWorkspacePath(params.Path)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
 	if searchPath == "" {
-		searchPath = config.WorkingDirectory()
+		searchPath = config.DefaultRootPath()
 	}
 
 	files, truncated, err := globFiles(params.Pattern, searchPath, 100)
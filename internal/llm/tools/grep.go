@@ -3,7 +3,6 @@ package tools
 import (
 	"bufio"
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
@@ -123,8 +122,8 @@ func escapeRegexPattern(pattern string) string {
 
 func (g *grepTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
 	var params GrepParams
-	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
-		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	if err := call.BindInput(&params); err != nil {
+		return NewTextErrorResponse(err.Error()), nil
 	}
 
 	if params.Pattern == "" {
@@ -137,9 +136,12 @@ func (g *grepTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		searchPattern = escapeRegexPattern(params.Pattern)
 	}
 
-	searchPath := params.Path
+	searchPath, err := config.ResolveWorkspacePath(params.Path)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
 	if searchPath == "" {
-		searchPath = config.WorkingDirectory()
+		searchPath = config.DefaultRootPath()
 	}
 
 	matches, truncated, err := searchFiles(searchPattern, searchPath, params.Include, 100)
@@ -0,0 +1,195 @@
+package guard
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// wrapper is a command running the command of its arguments
+type wrapper struct {
+	// valueFlags are the flags whose value is the next argument
+	valueFlags []string
+	// operands are the arguments before the command, such as the duration
+	// of timeout
+	operands int
+	// assignments are skipped, as env does with NAME=value
+	assignments bool
+	privileged  bool
+}
+
+var wrappers = map[string]wrapper{
+	"sudo":    {valueFlags: []string{"-u", "-g", "-h", "-p", "-C", "-D", "-r", "-t", "-U", "-T", "-R", "--user", "--group"}, privileged: true},
+	"doas":    {valueFlags: []string{"-u", "-C"}, privileged: true},
+	"pkexec":  {valueFlags: []string{"--user"}, privileged: true},
+	"run0":    {valueFlags: []string{"-u", "--user", "-g", "--group"}, privileged: true},
+	"env":     {valueFlags: []string{"-u", "-C", "-S", "--unset", "--chdir", "--split-string"}, assignments: true},
+	"nice":    {valueFlags: []string{"-n", "--adjustment"}},
+	"ionice":  {valueFlags: []string{"-c", "-n", "-p", "--class", "--classdata"}},
+	"nohup":   {},
+	"time":    {valueFlags: []string{"-f", "-o", "--format", "--output"}},
+	"command": {},
+	"builtin": {},
+	"exec":    {valueFlags: []string{"-a"}},
+	"stdbuf":  {valueFlags: []string{"-i", "-o", "-e"}},
+	"timeout": {valueFlags: []string{"-s", "-k", "--signal", "--kill-after"}, operands: 1},
+	"watch":   {valueFlags: []string{"-n", "--interval"}},
+	"xargs": {valueFlags: []string{"-I", "-n", "-P", "-L", "-d", "-E", "-s", "-a",
+		"--arg-file", "--delimiter", "--max-args", "--max-procs", "--max-lines", "--replace", "--eof", "--max-chars"}},
+}
+
+// escalations are the commands running others as another user
+var escalations = map[string]bool{"sudo": true, "doas": true, "su": true, "pkexec": true, "run0": true}
+
+// shells run the script of -c, of a file, or of their standard input
+var shells = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "dash": true, "ksh": true, "mksh": true,
+	"ash": true, "fish": true, "csh": true, "tcsh": true,
+}
+
+// interpreters are the languages run like shells, with the flags taking an
+// inline script
+var interpreters = map[string]string{
+	"perl": "eE", "ruby": "e", "node": "ep", "nodejs": "ep", "php": "r", "lua": "e",
+}
+
+// downloaders are the commands fetching from the network
+var downloaders = map[string]bool{
+	"curl": true, "wget": true, "fetch": true, "aria2c": true, "axel": true,
+	"http": true, "https": true, "xh": true, "xhs": true, "httpie": true,
+}
+
+// unwrap removes the wrappers of a command, such as sudo or env, from its
+// words, recording on cmd what they change
+func unwrap(cmd *command, words []word) []word {
+	for len(words) > 0 && words[0].known {
+		name := filepath.Base(words[0].value)
+		w, ok := wrappers[name]
+		if !ok {
+			break
+		}
+		if w.privileged {
+			cmd.privileged = true
+		}
+		rest := w.skip(words[1:])
+		// command -v only looks the command up
+		if len(rest) == 0 || (name == "command" && slices.ContainsFunc(words[1:], func(arg word) bool {
+			return arg.value == "-v" || arg.value == "-V"
+		})) {
+			break
+		}
+		words = rest
+	}
+	return words
+}
+
+// skip returns the arguments of a wrapper from its command on, none when it
+// runs no command
+func (w wrapper) skip(args []word) []word {
+	operands, flags := w.operands, true
+	for i := 0; i < len(args); i++ {
+		arg := args[i].value
+		switch {
+		case flags && arg == "--":
+			flags = false
+		case flags && len(arg) > 1 && strings.HasPrefix(arg, "-"):
+			if slices.Contains(w.valueFlags, arg) {
+				i++
+			}
+		case w.assignments && strings.Index(arg, "=") > 0:
+		case operands > 0:
+			operands--
+		default:
+			return args[i:]
+		}
+	}
+	return nil
+}
+
+// interpreter returns the flags of an interpreter taking an inline script,
+// false for the other commands
+func interpreter(name string) (string, bool) {
+	if strings.HasPrefix(name, "python") && strings.Trim(name[len("python"):], "0123456789.") == "" {
+		return "cm", true
+	}
+	flags, ok := interpreters[name]
+	return flags, ok
+}
+
+// runsScript reports whether a command runs the script of its arguments
+func (c command) runsScript() bool {
+	_, ok := interpreter(c.name)
+	return ok || shells[c.name] || c.name == "eval" || c.name == "source" || c.name == "."
+}
+
+// downloads reports whether a command fetches from the network
+func (c command) downloads() bool {
+	return downloaders[c.name]
+}
+
+// readsStdin reports whether a shell or interpreter runs the script of its
+// standard input: given no script, or - as its script
+func readsStdin(name string, args []word) bool {
+	inline := "c"
+	if !shells[name] {
+		flags, ok := interpreter(name)
+		if !ok {
+			return false
+		}
+		inline = flags
+	}
+	for i := 0; i < len(args); i++ {
+		arg := args[i].value
+		switch {
+		case arg == "-" || (shells[name] && arg == "-s"):
+			return true
+		case arg == "--":
+			return i+1 >= len(args) || args[i+1].value == "-"
+		case arg == "--eval" || arg == "--print" || arg == "--command":
+			return false
+		case strings.HasPrefix(arg, "--"):
+		case shells[name] && (arg == "-o" || arg == "+o"):
+			i++
+		case strings.HasPrefix(arg, "-") || strings.HasPrefix(arg, "+"):
+			if strings.ContainsAny(arg[1:], inline) {
+				return false
+			}
+		default:
+			// A script file
+			return false
+		}
+	}
+	return true
+}
+
+// inlineScript returns the script a shell, or su, runs from its arguments,
+// false when it runs none or the script is only known when it runs
+func inlineScript(name string, args []word) (string, bool) {
+	if name == "su" {
+		for i, arg := range args {
+			if (arg.value == "-c" || arg.value == "--command") && i+1 < len(args) {
+				return args[i+1].value, args[i+1].known
+			}
+			if script, ok := strings.CutPrefix(arg.value, "--command="); ok {
+				return script, arg.known
+			}
+		}
+		return "", false
+	}
+	inline := false
+	for i := 0; i < len(args); i++ {
+		arg := args[i].value
+		switch {
+		case arg == "-o" || arg == "+o":
+			i++
+		case !strings.HasPrefix(arg, "--") && len(arg) > 1 && (arg[0] == '-' || arg[0] == '+'):
+			inline = inline || strings.Contains(arg[1:], "c")
+		case strings.HasPrefix(arg, "--"):
+		case inline:
+			return arg, args[i].known
+		default:
+			return "", false
+		}
+	}
+	return "", false
+}
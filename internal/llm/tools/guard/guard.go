@@ -0,0 +1,253 @@
+// Package guard checks the shell commands of the agents before they run. A
+// command line is parsed as the shell would parse it, pipes, subshells,
+// substitutions, scripts passed to shells and wrappers such as sudo or env
+// included, and checked against the built-in rules and the rules of the
+// configuration. Each rule the command breaks is a finding that either asks
+// for the approval of the user or refuses the command.
+package guard
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+)
+
+// Decision is what is done with a command
+type Decision string
+
+const (
+	// Allow runs the command, asking for approval as usual
+	Allow Decision = "allow"
+	// Warn asks for approval even when approvals are off
+	Warn Decision = "warn"
+	// Block refuses the command
+	Block Decision = "block"
+)
+
+func (d Decision) rank() int {
+	switch d {
+	case Warn:
+		return 1
+	case Block:
+		return 2
+	}
+	return 0
+}
+
+// Finding is a rule a command broke
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Decision Decision `json:"decision"`
+	// Command is the part of the command line the rule applies to
+	Command string `json:"command"`
+	Reason  string `json:"reason"`
+}
+
+// Result is the decision on a command with the findings leading to it
+type Result struct {
+	Decision Decision
+	Findings []Finding
+}
+
+// Guard checks commands against the built-in rules and the rules of the
+// configuration
+type Guard struct {
+	rules []rule
+	allow []*regexp.Regexp
+	// roots are the directories recursive operations are confined to
+	roots []string
+	home  string
+}
+
+// rule is a rule of the configuration
+type rule struct {
+	name     string
+	decision Decision
+	reason   string
+	pattern  *regexp.Regexp
+	command  string
+	args     []string
+}
+
+// New returns a guard with the rules and allowlist of cfg, confining the
+// recursive operations to roots
+func New(cfg config.ShellGuardConfig, roots []string) (*Guard, error) {
+	home, _ := os.UserHomeDir()
+	g := &Guard{home: home}
+	for _, root := range roots {
+		root = filepath.Clean(root)
+		g.roots = append(g.roots, root)
+		if resolved, err := filepath.EvalSymlinks(root); err == nil && resolved != root {
+			g.roots = append(g.roots, resolved)
+		}
+	}
+	for _, r := range cfg.Rules {
+		compiled := rule{name: r.Name, decision: Decision(r.Decision), reason: r.Reason, command: r.Command, args: r.Args}
+		if compiled.reason == "" {
+			compiled.reason = fmt.Sprintf("matches the rule %s of the configuration", r.Name)
+		}
+		if r.Pattern != "" {
+			pattern, err := regexp.Compile(r.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("rule %s: invalid pattern: %w", r.Name, err)
+			}
+			compiled.pattern = pattern
+		}
+		g.rules = append(g.rules, compiled)
+	}
+	for _, allow := range cfg.Allow {
+		pattern, err := regexp.Compile("^(?:" + allow + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid allow pattern %q: %w", allow, err)
+		}
+		g.allow = append(g.allow, pattern)
+	}
+	return g, nil
+}
+
+// Check decides what to do with a command line run from dir, the working
+// directory of the shell. The findings about a simple command or pipeline the
+// allowlist matches are dropped; the allowlist never applies to the line as a
+// whole, so a command chained to an allowed one is still checked.
+func (g *Guard) Check(line, dir string) Result {
+	line = strings.TrimSpace(line)
+	result := Result{Decision: Allow}
+
+	var findings []Finding
+	p, err := parse(line, dir, g.home)
+	if err != nil {
+		findings = append(findings, Finding{Rule: ruleUnparsed, Decision: Warn, Command: line, Reason: "the command could not be parsed: " + err.Error()})
+		p = &parsed{home: g.home}
+	}
+	for _, check := range builtinRules {
+		findings = append(findings, check(g, p)...)
+	}
+	for _, r := range g.rules {
+		findings = append(findings, r.check(line, p)...)
+	}
+
+	// The parts of the line the allowlist may match
+	parts := make(map[string]bool, len(p.commands)+len(p.pipelines))
+	for _, c := range p.commands {
+		parts[strings.TrimSpace(c.text)] = true
+	}
+	for _, pipe := range p.pipelines {
+		parts[strings.TrimSpace(pipe.text)] = true
+	}
+
+	seen := make(map[Finding]bool, len(findings))
+	for _, finding := range findings {
+		if seen[finding] || (parts[strings.TrimSpace(finding.Command)] && g.allowed(finding.Command)) {
+			continue
+		}
+		seen[finding] = true
+		result.Findings = append(result.Findings, finding)
+		if finding.Decision.rank() > result.Decision.rank() {
+			result.Decision = finding.Decision
+		}
+	}
+	return result
+}
+
+func (g *Guard) allowed(text string) bool {
+	for _, allow := range g.allow {
+		if allow.MatchString(strings.TrimSpace(text)) {
+			return true
+		}
+	}
+	return false
+}
+
+// confined reports whether path is within a root, and whether it is the root
+// itself
+func (g *Guard) confined(p string) (inside, root bool) {
+	paths := []string{p}
+	if resolved, err := filepath.EvalSymlinks(p); err == nil && resolved != p {
+		paths = append(paths, resolved)
+	}
+	for _, p := range paths {
+		for _, r := range g.roots {
+			rel, err := filepath.Rel(r, p)
+			if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				return true, rel == "."
+			}
+		}
+	}
+	return false, false
+}
+
+// check returns the findings of a rule of the configuration: on the command
+// line for a rule with a pattern only, on each simple command matching it
+// otherwise
+func (r rule) check(line string, p *parsed) []Finding {
+	finding := func(text string) Finding {
+		return Finding{Rule: r.name, Decision: r.decision, Command: text, Reason: r.reason}
+	}
+	if r.command == "" {
+		if r.pattern.MatchString(line) {
+			return []Finding{finding(line)}
+		}
+		return nil
+	}
+	var findings []Finding
+	for _, c := range p.commands {
+		if matched, _ := path.Match(r.command, c.name); !matched {
+			continue
+		}
+		if r.pattern != nil && !r.pattern.MatchString(c.text) {
+			continue
+		}
+		if r.matchesArgs(c) {
+			findings = append(findings, finding(c.text))
+		}
+	}
+	return findings
+}
+
+// matchesArgs reports whether each argument pattern of the rule matches an
+// argument of c
+func (r rule) matchesArgs(c command) bool {
+	for _, glob := range r.args {
+		found := false
+		for _, arg := range c.args {
+			if matched, _ := path.Match(glob, arg.value); matched {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// refusalAdvice tells the agent what to do with a refused command
+const refusalAdvice = "The shell guard refused the command, which was not run. Do not work around the rules: do the task another way, or ask the user to allow the command in shell.guard.allow of the configuration."
+
+// Refusal is the response to a refused command, which the agent reads to
+// find another way
+func (r Result) Refusal() string {
+	data, _ := json.MarshalIndent(struct {
+		Status   string    `json:"status"`
+		Findings []Finding `json:"findings"`
+		Advice   string    `json:"advice"`
+	}{"refused", r.Findings, refusalAdvice}, "", "  ")
+	return string(data)
+}
+
+// Warnings describes the findings, one per line, for the approval of the
+// user
+func (r Result) Warnings() []string {
+	warnings := make([]string, len(r.Findings))
+	for i, finding := range r.Findings {
+		warnings[i] = fmt.Sprintf("%s: %s", finding.Rule, finding.Reason)
+	}
+	return warnings
+}
@@ -0,0 +1,412 @@
+package guard
+
+import (
+	"path/filepath"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// maxScriptDepth bounds the scripts parsed within scripts, such as bash -c
+// "bash -c '...'"
+const maxScriptDepth = 5
+
+// word is an argument of a command
+type word struct {
+	// value is the argument once expanded, or its text when it is only known
+	// when the command runs
+	value string
+	known bool
+}
+
+// command is a simple command of a command line, its wrappers removed
+type command struct {
+	// name is the program run, without its directory
+	name string
+	args []word
+	// text is the simple command as written, wrappers included
+	text string
+	// dir is the directory the command runs in, empty when it is only known
+	// when the command line runs
+	dir string
+	// privileged commands run through sudo, doas, su or pkexec
+	privileged bool
+	// readsStdin is set for the shells and interpreters running the script
+	// they read from their standard input
+	readsStdin bool
+	// fetched is set for the shells and interpreters running a script made
+	// by a download, such as bash <(curl ...)
+	fetched bool
+}
+
+// pipeline is a pipe of commands, the commands of each stage in order
+type pipeline struct {
+	text   string
+	stages [][]command
+}
+
+// parsed is what a command line runs
+type parsed struct {
+	commands  []command
+	pipelines []pipeline
+	// forkBombs are the functions calling themselves more than once
+	forkBombs []string
+	// unparsed are the scripts that could not be parsed, with their error
+	unparsed []string
+
+	home string
+}
+
+// script is a command line, or a script passed to a shell within it
+type script struct {
+	text       string
+	privileged bool
+	depth      int
+}
+
+// parse parses a command line run from dir, home being the home directory
+func parse(line, dir, home string) (*parsed, error) {
+	p := &parsed{home: home}
+	file, err := syntax.NewParser(syntax.Variant(syntax.LangBash)).Parse(strings.NewReader(line), "")
+	if err != nil {
+		return nil, err
+	}
+	p.stmts(script{text: line}, file.Stmts, dir)
+	return p, nil
+}
+
+// parseScript parses a script passed to a shell, such as the argument of
+// bash -c
+func (p *parsed) parseScript(s script, dir string) {
+	if s.depth > maxScriptDepth {
+		p.unparsed = append(p.unparsed, s.text+": scripts nested too deeply")
+		return
+	}
+	file, err := syntax.NewParser(syntax.Variant(syntax.LangBash)).Parse(strings.NewReader(s.text), "")
+	if err != nil {
+		p.unparsed = append(p.unparsed, err.Error())
+		return
+	}
+	p.stmts(s, file.Stmts, dir)
+}
+
+// stmts records the commands of statements run in sequence, returning the
+// directory they leave the shell in
+func (p *parsed) stmts(s script, stmts []*syntax.Stmt, dir string) string {
+	for _, stmt := range stmts {
+		dir = p.stmt(s, stmt, dir)
+	}
+	return dir
+}
+
+func (p *parsed) stmt(s script, stmt *syntax.Stmt, dir string) string {
+	start := len(p.commands)
+	for _, redirect := range stmt.Redirs {
+		p.substitutions(s, redirect, dir)
+	}
+	switch cmd := stmt.Cmd.(type) {
+	case *syntax.CallExpr:
+		return p.call(s, cmd, dir, start)
+	case *syntax.BinaryCmd:
+		if cmd.Op == syntax.Pipe || cmd.Op == syntax.PipeAll {
+			p.pipeline(s, cmd, dir)
+			return dir
+		}
+		return p.stmt(s, cmd.Y, p.stmt(s, cmd.X, dir))
+	case *syntax.Subshell:
+		p.stmts(s, cmd.Stmts, dir)
+	case *syntax.Block:
+		return p.stmts(s, cmd.Stmts, dir)
+	case *syntax.IfClause:
+		for clause := cmd; clause != nil; clause = clause.Else {
+			p.stmts(s, clause.Then, p.stmts(s, clause.Cond, dir))
+		}
+	case *syntax.WhileClause:
+		p.stmts(s, cmd.Do, p.stmts(s, cmd.Cond, dir))
+	case *syntax.ForClause:
+		p.substitutions(s, cmd.Loop, dir)
+		p.stmts(s, cmd.Do, dir)
+	case *syntax.CaseClause:
+		p.substitutions(s, cmd.Word, dir)
+		for _, item := range cmd.Items {
+			p.stmts(s, item.Stmts, dir)
+		}
+	case *syntax.FuncDecl:
+		p.funcDecl(s, cmd, dir)
+	case *syntax.TimeClause:
+		if cmd.Stmt != nil {
+			return p.stmt(s, cmd.Stmt, dir)
+		}
+	case *syntax.CoprocClause:
+		p.stmt(s, cmd.Stmt, dir)
+	case nil:
+	default:
+		// Declarations, tests and arithmetic only run the commands of their
+		// substitutions
+		p.substitutions(s, cmd, dir)
+	}
+	return dir
+}
+
+// pipeline records a pipe, whose stages run in subshells
+func (p *parsed) pipeline(s script, cmd *syntax.BinaryCmd, dir string) {
+	var stages []*syntax.Stmt
+	var flatten func(stmt *syntax.Stmt)
+	flatten = func(stmt *syntax.Stmt) {
+		if bin, ok := stmt.Cmd.(*syntax.BinaryCmd); ok && len(stmt.Redirs) == 0 && (bin.Op == syntax.Pipe || bin.Op == syntax.PipeAll) {
+			flatten(bin.X)
+			flatten(bin.Y)
+			return
+		}
+		stages = append(stages, stmt)
+	}
+	flatten(cmd.X)
+	flatten(cmd.Y)
+
+	pipe := pipeline{text: s.slice(cmd)}
+	for _, stage := range stages {
+		start := len(p.commands)
+		p.stmt(s, stage, dir)
+		pipe.stages = append(pipe.stages, append([]command(nil), p.commands[start:]...))
+	}
+	p.pipelines = append(p.pipelines, pipe)
+}
+
+// substitutions records the commands of the command and process
+// substitutions within node
+func (p *parsed) substitutions(s script, node syntax.Node, dir string) {
+	if node == nil {
+		return
+	}
+	syntax.Walk(node, func(n syntax.Node) bool {
+		switch n := n.(type) {
+		case *syntax.CmdSubst:
+			p.stmts(s, n.Stmts, dir)
+			return false
+		case *syntax.ProcSubst:
+			p.stmts(s, n.Stmts, dir)
+			return false
+		}
+		return true
+	})
+}
+
+// funcDecl records the commands of a function, and the function when it
+// calls itself more than once, as fork bombs do
+func (p *parsed) funcDecl(s script, fn *syntax.FuncDecl, dir string) {
+	calls := 0
+	syntax.Walk(fn.Body, func(n syntax.Node) bool {
+		if call, ok := n.(*syntax.CallExpr); ok && len(call.Args) > 0 && call.Args[0].Lit() == fn.Name.Value {
+			calls++
+		}
+		return true
+	})
+	if calls > 1 {
+		p.forkBombs = append(p.forkBombs, s.slice(fn))
+	}
+	p.stmt(s, fn.Body, dir)
+}
+
+// call records a simple command, the commands from start on being the ones of
+// the substitutions of its redirections, and returns the directory it leaves
+// the shell in
+func (p *parsed) call(s script, call *syntax.CallExpr, dir string, start int) string {
+	p.substitutions(s, call, dir)
+	substituted := p.commands[start:]
+	if len(call.Args) == 0 {
+		return dir
+	}
+
+	words := make([]word, len(call.Args))
+	for i, arg := range call.Args {
+		words[i] = s.word(arg)
+	}
+	cmd := command{text: s.slice(call), dir: dir, privileged: s.privileged}
+	words = unwrap(&cmd, words)
+	if len(words) == 0 {
+		p.commands = append(p.commands, cmd)
+		return dir
+	}
+	cmd.name = filepath.Base(words[0].value)
+	cmd.args = words[1:]
+	if escalations[cmd.name] {
+		cmd.privileged = true
+	}
+
+	for _, c := range substituted {
+		if c.downloads() && cmd.runsScript() {
+			cmd.fetched = true
+		}
+	}
+	cmd.readsStdin = readsStdin(cmd.name, cmd.args)
+	p.commands = append(p.commands, cmd)
+
+	nested := script{privileged: cmd.privileged, depth: s.depth + 1}
+	switch {
+	case cmd.name == "cd" || cmd.name == "pushd":
+		return p.changeDir(dir, cmd.args)
+	case cmd.name == "eval":
+		if text, ok := joined(cmd.args); ok {
+			nested.text = text
+			p.parseScript(nested, dir)
+		}
+	case shells[cmd.name] || cmd.name == "su":
+		if text, ok := inlineScript(cmd.name, cmd.args); ok {
+			nested.text = text
+			p.parseScript(nested, dir)
+		}
+	case cmd.name == "find":
+		p.findExec(cmd)
+	}
+	return dir
+}
+
+// findExec records the commands find runs for each file it finds, whose
+// directory is the one of the file
+func (p *parsed) findExec(find command) {
+	for i := 0; i < len(find.args); i++ {
+		switch find.args[i].value {
+		case "-exec", "-execdir", "-ok", "-okdir":
+		default:
+			continue
+		}
+		end := i + 1
+		for end < len(find.args) && find.args[end].value != ";" && find.args[end].value != "+" {
+			end++
+		}
+		words := append([]word(nil), find.args[i+1:end]...)
+		cmd := command{text: find.text, privileged: find.privileged}
+		if words = unwrap(&cmd, words); len(words) > 0 {
+			cmd.name = filepath.Base(words[0].value)
+			cmd.args = words[1:]
+			p.commands = append(p.commands, cmd)
+		}
+		i = end
+	}
+}
+
+// slice returns the text of a node
+func (s script) slice(node syntax.Node) string {
+	start, end := int(node.Pos().Offset()), int(node.End().Offset())
+	if start < 0 || end > len(s.text) || start > end {
+		return ""
+	}
+	return s.text[start:end]
+}
+
+// word expands a word as far as it is known before the command runs: quotes
+// removed, and $HOME expanded to ~
+func (s script) word(w *syntax.Word) word {
+	var b strings.Builder
+	known := true
+	for _, part := range w.Parts {
+		switch part := part.(type) {
+		case *syntax.Lit:
+			b.WriteString(unescape(part.Value))
+		case *syntax.SglQuoted:
+			b.WriteString(part.Value)
+		case *syntax.DblQuoted:
+			for _, inner := range part.Parts {
+				if lit, ok := inner.(*syntax.Lit); ok {
+					b.WriteString(unescape(lit.Value))
+				} else if home(inner) {
+					b.WriteString("~")
+				} else {
+					known = false
+					b.WriteString(s.slice(inner))
+				}
+			}
+		default:
+			if home(part) {
+				b.WriteString("~")
+				continue
+			}
+			known = false
+			b.WriteString(s.slice(part))
+		}
+	}
+	return word{value: b.String(), known: known}
+}
+
+// home reports whether a word part is $HOME
+func home(part syntax.WordPart) bool {
+	exp, ok := part.(*syntax.ParamExp)
+	return ok && exp.Param != nil && exp.Param.Value == "HOME" && exp.Exp == nil && exp.Slice == nil &&
+		exp.Repl == nil && exp.Index == nil && !exp.Length && !exp.Excl && !exp.Width
+}
+
+// unescape removes the backslashes escaping characters
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// joined returns the arguments joined with spaces, as eval runs them
+func joined(args []word) (string, bool) {
+	values := make([]string, len(args))
+	for i, arg := range args {
+		if !arg.known {
+			return "", false
+		}
+		values[i] = arg.value
+	}
+	return strings.Join(values, " "), true
+}
+
+// changeDir returns the directory cd leaves the shell in, empty when it is
+// only known when the command runs
+func (p *parsed) changeDir(dir string, args []word) string {
+	var operands []word
+	for _, arg := range args {
+		if !strings.HasPrefix(arg.value, "-") || arg.value == "-" {
+			operands = append(operands, arg)
+		}
+	}
+	if len(operands) == 0 {
+		return p.home
+	}
+	if operands[0].value == "-" {
+		return ""
+	}
+	resolved, _ := p.resolve(dir, operands[0])
+	return resolved
+}
+
+// resolve returns the absolute path of a path argument, a glob being cut to
+// the directory it expands within
+func (p *parsed) resolve(dir string, arg word) (string, bool) {
+	if !arg.known {
+		return "", false
+	}
+	path := arg.value
+	if i := strings.IndexAny(path, "*?["); i >= 0 {
+		path = path[:i]
+		if !strings.HasSuffix(path, "/") {
+			path = filepath.Dir(path)
+		}
+	}
+	switch {
+	case path == "~" || strings.HasPrefix(path, "~/"):
+		if p.home == "" {
+			return "", false
+		}
+		return filepath.Join(p.home, path[1:]), true
+	case strings.HasPrefix(path, "~"):
+		// The home directory of another user
+		return "", false
+	case filepath.IsAbs(path):
+		return filepath.Clean(path), true
+	case dir == "":
+		return "", false
+	}
+	return filepath.Join(dir, path), true
+}
@@ -0,0 +1,365 @@
+package guard
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// The built-in rules
+const (
+	ruleNetworkToShell   = "network-to-shell"
+	ruleRecursiveOutside = "recursive-outside-sandbox"
+	rulePackageInstall   = "package-install"
+	rulePrivilege        = "privilege-escalation"
+	ruleForkBomb         = "fork-bomb"
+	ruleUnparsed         = "unparsed"
+)
+
+var builtinRules = []func(g *Guard, p *parsed) []Finding{
+	networkToShell,
+	recursiveOutside,
+	packageInstall,
+	privilegeEscalation,
+	forkBomb,
+	unparsed,
+}
+
+// networkToShell blocks the scripts run as they are downloaded: piped from a
+// download into a shell or interpreter, or substituted from one
+func networkToShell(g *Guard, p *parsed) []Finding {
+	var findings []Finding
+	for _, pipe := range p.pipelines {
+		findings = append(findings, pipeToShell(pipe)...)
+	}
+	for _, c := range p.commands {
+		if c.fetched {
+			findings = append(findings, Finding{
+				Rule:     ruleNetworkToShell,
+				Decision: Block,
+				Command:  c.text,
+				Reason:   fmt.Sprintf("%s runs a script downloaded by the same command, unseen", c.name),
+			})
+		}
+	}
+	return findings
+}
+
+func pipeToShell(pipe pipeline) []Finding {
+	for i, stage := range pipe.stages {
+		for _, download := range stage {
+			if !download.downloads() {
+				continue
+			}
+			for _, later := range pipe.stages[i+1:] {
+				for _, c := range later {
+					if c.readsStdin {
+						return []Finding{{
+							Rule:     ruleNetworkToShell,
+							Decision: Block,
+							Command:  pipe.text,
+							Reason:   fmt.Sprintf("pipes what %s downloads into %s, which runs it unseen", download.name, c.name),
+						}}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// recursiveOutside blocks the recursive deletions and permission or
+// ownership changes on paths outside the working directory and workspace
+// roots. It asks for approval for the paths only known when the command runs
+// and for deleting a root as a whole.
+func recursiveOutside(g *Guard, p *parsed) []Finding {
+	var findings []Finding
+	for _, c := range p.commands {
+		targets, deletes := recursiveTargets(c)
+		for _, target := range targets {
+			if target.value == "{}" {
+				// The files find runs the command for, checked with find
+				continue
+			}
+			finding := Finding{Rule: ruleRecursiveOutside, Command: c.text}
+			path, known := p.resolve(c.dir, target)
+			inside, root := false, false
+			if known {
+				inside, root = g.confined(path)
+			}
+			switch {
+			case !known:
+				finding.Decision = Warn
+				finding.Reason = fmt.Sprintf("%s recursively changes %s, a path only known when it runs", c.name, target.value)
+			case !inside:
+				finding.Decision = Block
+				finding.Reason = fmt.Sprintf("%s recursively changes %s, outside the working directory and workspace roots", c.name, path)
+			case root && deletes:
+				finding.Decision = Warn
+				finding.Reason = fmt.Sprintf("%s deletes the whole of %s", c.name, path)
+			default:
+				continue
+			}
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+// recursiveTargets returns the paths a command changes recursively, and
+// whether it deletes them
+func recursiveTargets(c command) (targets []word, deletes bool) {
+	switch c.name {
+	case "rm":
+		if hasFlag(c.args, "rR", "--recursive") {
+			return operands(c.args), true
+		}
+	case "chmod", "chown", "chgrp":
+		if !hasFlag(c.args, "R", "--recursive") {
+			return nil, false
+		}
+		targets = operands(c.args)
+		// The mode or owner comes first, unless taken from a reference file
+		if len(targets) > 0 && !slices.ContainsFunc(c.args, func(arg word) bool { return strings.HasPrefix(arg.value, "--reference=") }) {
+			targets = targets[1:]
+		}
+		return targets, false
+	case "cp", "rsync":
+		if !hasFlag(c.args, "rRa", "--recursive", "--archive") {
+			return nil, false
+		}
+		// The destination, unless on another host
+		if targets = operands(c.args); len(targets) > 1 && !strings.Contains(targets[len(targets)-1].value, ":") {
+			return targets[len(targets)-1:], false
+		}
+	case "find":
+		if !findChanges(c.args) {
+			return nil, false
+		}
+		for _, arg := range c.args {
+			if arg.value == "-H" || arg.value == "-L" || arg.value == "-P" {
+				continue
+			}
+			if strings.HasPrefix(arg.value, "-") || arg.value == "(" || arg.value == "!" {
+				break
+			}
+			targets = append(targets, arg)
+		}
+		if len(targets) == 0 {
+			targets = []word{{value: ".", known: true}}
+		}
+		return targets, true
+	}
+	return nil, false
+}
+
+// findChanges reports whether find deletes the files it finds or runs a
+// command changing them
+func findChanges(args []word) bool {
+	for i, arg := range args {
+		switch arg.value {
+		case "-delete":
+			return true
+		case "-exec", "-execdir", "-ok", "-okdir":
+			if i+1 < len(args) {
+				switch args[i+1].value {
+				case "rm", "chmod", "chown", "chgrp", "mv", "shred", "truncate":
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// hasFlag reports whether args have one of the short flags, alone or in a
+// group such as -rf, or one of the long flags
+func hasFlag(args []word, short string, long ...string) bool {
+	for _, arg := range args {
+		switch {
+		case arg.value == "--":
+			return false
+		case strings.HasPrefix(arg.value, "--"):
+			if slices.Contains(long, arg.value) {
+				return true
+			}
+		case len(arg.value) > 1 && strings.HasPrefix(arg.value, "-"):
+			if strings.ContainsAny(arg.value[1:], short) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// operands returns the arguments that are not flags
+func operands(args []word) []word {
+	var result []word
+	flags := true
+	for _, arg := range args {
+		switch {
+		case flags && arg.value == "--":
+			flags = false
+		case flags && len(arg.value) > 1 && strings.HasPrefix(arg.value, "-"):
+		default:
+			result = append(result, arg)
+		}
+	}
+	return result
+}
+
+// installCommands are the subcommands of the package managers installing
+// packages
+var installCommands = map[string][]string{
+	"apt":      {"install", "reinstall"},
+	"apt-get":  {"install", "reinstall"},
+	"aptitude": {"install", "reinstall"},
+	"dnf":      {"install", "reinstall"},
+	"yum":      {"install", "reinstall"},
+	"zypper":   {"install", "in"},
+	"apk":      {"add"},
+	"brew":     {"install", "reinstall"},
+	"port":     {"install"},
+	"snap":     {"install"},
+	"flatpak":  {"install"},
+	"npm":      {"install", "i", "add", "ci"},
+	"pnpm":     {"install", "i", "add"},
+	"yarn":     {"add", "install"},
+	"bun":      {"install", "i", "add"},
+	"pip":      {"install"},
+	"pip3":     {"install"},
+	"pipx":     {"install"},
+	"uv":       {"add", "pip install", "tool install"},
+	"poetry":   {"add", "install"},
+	"gem":      {"install"},
+	"cargo":    {"install"},
+	"go":       {"install"},
+	"composer": {"require", "install"},
+	"conda":    {"install"},
+	"mamba":    {"install"},
+}
+
+// packageInstall asks for approval before packages are installed, which runs
+// their install scripts and changes the machine or the project
+func packageInstall(g *Guard, p *parsed) []Finding {
+	var findings []Finding
+	for _, c := range p.commands {
+		if manager, ok := installs(c); ok {
+			findings = append(findings, Finding{
+				Rule:     rulePackageInstall,
+				Decision: Warn,
+				Command:  c.text,
+				Reason:   fmt.Sprintf("installs packages with %s", manager),
+			})
+		}
+	}
+	return findings
+}
+
+// installs returns the package manager a command installs packages with
+func installs(c command) (string, bool) {
+	args := c.args
+	manager := c.name
+	if _, ok := interpreter(c.name); ok && strings.HasPrefix(c.name, "python") {
+		// python -m pip install
+		i := slices.IndexFunc(args, func(arg word) bool { return arg.value == "-m" })
+		if i < 0 || i+1 >= len(args) || args[i+1].value != "pip" {
+			return "", false
+		}
+		manager, args = "pip", args[i+2:]
+	}
+
+	switch manager {
+	case "pacman":
+		for _, arg := range args {
+			if strings.HasPrefix(arg.value, "-U") ||
+				(strings.HasPrefix(arg.value, "-S") && !strings.ContainsAny(arg.value[2:], "silgpc")) {
+				return manager, true
+			}
+		}
+		return "", false
+	case "nix-env":
+		if hasFlag(args, "i", "--install") {
+			return manager, true
+		}
+		return "", false
+	}
+
+	subcommands, ok := installCommands[manager]
+	if !ok && strings.HasPrefix(manager, "pip") && strings.Trim(manager[len("pip"):], "0123456789.") == "" {
+		subcommands, ok = installCommands["pip"], true
+	}
+	if !ok {
+		return "", false
+	}
+	ops := operands(args)
+	for _, subcommand := range subcommands {
+		words := strings.Fields(subcommand)
+		if len(ops) < len(words) {
+			continue
+		}
+		matched := true
+		for i, w := range words {
+			if ops[i].value != w {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return manager, true
+		}
+	}
+	return "", false
+}
+
+// privilegeEscalation blocks the commands run as another user, such as
+// root, which escape what the agent may change
+func privilegeEscalation(g *Guard, p *parsed) []Finding {
+	var findings []Finding
+	for _, c := range p.commands {
+		if !c.privileged {
+			continue
+		}
+		name := c.name
+		if name == "" {
+			name = "a shell"
+		}
+		findings = append(findings, Finding{
+			Rule:     rulePrivilege,
+			Decision: Block,
+			Command:  c.text,
+			Reason:   fmt.Sprintf("runs %s as another user", name),
+		})
+	}
+	return findings
+}
+
+// forkBomb blocks the functions calling themselves more than once, which
+// multiply processes until the machine stalls
+func forkBomb(g *Guard, p *parsed) []Finding {
+	var findings []Finding
+	for _, fn := range p.forkBombs {
+		findings = append(findings, Finding{
+			Rule:     ruleForkBomb,
+			Decision: Block,
+			Command:  fn,
+			Reason:   "defines a function calling itself more than once, which multiplies processes until the machine stalls",
+		})
+	}
+	return findings
+}
+
+// unparsed asks for approval of the scripts passed to shells that could not
+// be parsed, since what they run is unknown
+func unparsed(g *Guard, p *parsed) []Finding {
+	var findings []Finding
+	for _, err := range p.unparsed {
+		findings = append(findings, Finding{
+			Rule:     ruleUnparsed,
+			Decision: Warn,
+			Command:  err,
+			Reason:   "a script passed to a shell could not be parsed",
+		})
+	}
+	return findings
+}
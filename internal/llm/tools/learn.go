@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/memory"
+)
+
+type LearnParams struct {
+	Observation string `json:"observation" required:"true" description:"What you learned, in a sentence or two"`
+}
+
+type learnTool struct{}
+
+const (
+	LearnToolName        = "learn"
+	learnToolDescription = `Keeps an observation in your agent memory, recalled into your system prompt in later sessions.
+
+WHEN TO USE THIS TOOL:
+- Use when you notice what works or fails for the user, such as an approach they preferred or a mistake to avoid
+- Use for what helps you work, rather than for facts of the project, which belong in the workspace memory
+
+HOW TO USE:
+- Write one observation per call, general enough to apply again
+
+LIMITATIONS:
+- Observations are at most 1000 bytes
+- Only the most recently recalled observations are kept, and only until the application exits unless the knowledge retention is persistent`
+
+	// learnMaxBytes bounds an observation
+	learnMaxBytes = 1000
+)
+
+func NewLearnTool() BaseTool {
+	return &learnTool{}
+}
+
+func (t *learnTool) Info() ToolInfo {
+	parameters, required := ParamsSchema(LearnParams{})
+	return ToolInfo{
+		Name:        LearnToolName,
+		Description: learnToolDescription,
+		Parameters:  parameters,
+		Required:    required,
+	}
+}
+
+func (t *learnTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params LearnParams
+	if err := call.BindParams(&params, config.Get().StrictToolInputs); err != nil {
+		return NewParamsErrorResponse(err), nil
+	}
+	if len(params.Observation) > learnMaxBytes {
+		return NewTextErrorResponse(fmt.Sprintf("observation is %d bytes, at most %d are kept", len(params.Observation), learnMaxBytes)), nil
+	}
+
+	agent := GetAgentName(ctx)
+	if agent == "" {
+		return ToolResponse{}, fmt.Errorf("agent name is required for keeping an observation")
+	}
+	service := memory.Current()
+	if service == nil {
+		return NewTextErrorResponse("the agent memory is not available"), nil
+	}
+
+	observation, err := service.Remember(ctx, config.AgentName(agent), params.Observation)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	return NewTextResponse(fmt.Sprintf("Observation %s kept in your agent memory", observation.ID)), nil
+}
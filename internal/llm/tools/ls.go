@@ -2,7 +2,6 @@ package tools
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -90,13 +89,19 @@ func (l *lsTool) Info() ToolInfo {
 
 func (l *lsTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
 	var params LSParams
-	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
-		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	if err := call.BindInput(&params); err != nil {
+		return NewTextErrorResponse(err.Error()), nil
 	}
 
 	searchPath := params.Path
 	if searchPath == "" {
-		searchPath = config.WorkingDirectory()
+		searchPath = config.DefaultRootPath()
+	} else if config.IsRootQualified(searchPath) || config.ActiveRoot() != "" {
+		resolved, err := config.ResolveWorkspacePath(searchPath)
+		if err != nil {
+			return NewTextErrorResponse(err.Error()), nil
+		}
+		searchPath = resolved
 	}
 
 	if !filepath.IsAbs(searchPath) {
@@ -0,0 +1,177 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/notes"
+	"github.com/caronex/intelligence-interface/internal/permission"
+)
+
+type MemoryWriteParams struct {
+	Content       string   `json:"content" required:"true" description:"The note to keep, a decision, convention or gotcha of the workspace"`
+	Tags          []string `json:"tags" description:"Tags to find the note by, such as build, testing or api"`
+	Pinned        bool     `json:"pinned" description:"Whether the note always comes first in the system prompt"`
+	ExpiresInDays int      `json:"expires_in_days" minimum:"0" maximum:"365" description:"Days after which the note is dropped, 0 to keep it"`
+}
+
+type MemoryWritePermissionsParams struct {
+	Content   string   `json:"content"`
+	Tags      []string `json:"tags,omitempty"`
+	Pinned    bool     `json:"pinned,omitempty"`
+	ExpiresAt int64    `json:"expires_at,omitempty"`
+}
+
+type MemoryReadParams struct {
+	Query string   `json:"query" description:"Words the notes must contain, case insensitively"`
+	Tags  []string `json:"tags" description:"Tags the notes must have"`
+	Limit int      `json:"limit" minimum:"1" maximum:"50" default:"10" description:"The maximum number of notes to return"`
+}
+
+type memoryWriteTool struct {
+	permissions permission.Service
+}
+
+type memoryReadTool struct{}
+
+const (
+	MemoryWriteToolName        = "memory_write"
+	memoryWriteToolDescription = `Keeps a note in the long-term memory of the workspace, which lasts across sessions.
+
+WHEN TO USE THIS TOOL:
+- Use to remember a decision, convention or gotcha of the project that later sessions should know
+- Use when the user asks you to remember something about the project
+
+HOW TO USE:
+- Write one fact per note, in a sentence or two, with the context needed to understand it later
+- Tag the note with the topics to find it by
+- Pin the notes every session should know, they come first in the system prompt
+- Set an expiry for the notes which only hold for a while
+
+LIMITATIONS:
+- The user approves every note
+- Notes are at most 4000 bytes with at most 10 tags
+- Do not keep secrets, or what the code and its history already record`
+
+	MemoryReadToolName        = "memory_read"
+	memoryReadToolDescription = `Searches the long-term memory of the workspace, the notes kept across sessions.
+
+WHEN TO USE THIS TOOL:
+- Use to recall the decisions, conventions and gotchas noted about the project
+- The pinned and most recent notes are already in the system prompt, search for the others
+
+HOW TO USE:
+- Give words the notes must contain, tags they must have, or both
+- Without a query or tags, the most recent notes are returned
+
+LIMITATIONS:
+- At most 50 notes are returned, pinned first then most recently updated first`
+
+	// memoryReadMaxBytes bounds the notes a memory_read call returns
+	memoryReadMaxBytes = 16000
+)
+
+func NewMemoryWriteTool(permissions permission.Service) BaseTool {
+	return &memoryWriteTool{permissions: permissions}
+}
+
+func NewMemoryReadTool() BaseTool {
+	return &memoryReadTool{}
+}
+
+func (t *memoryWriteTool) Info() ToolInfo {
+	parameters, required := ParamsSchema(MemoryWriteParams{})
+	return ToolInfo{
+		Name:        MemoryWriteToolName,
+		Description: memoryWriteToolDescription,
+		Parameters:  parameters,
+		Required:    required,
+	}
+}
+
+func (t *memoryWriteTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params MemoryWriteParams
+	if err := call.BindParams(&params, config.Get().StrictToolInputs); err != nil {
+		return NewParamsErrorResponse(err), nil
+	}
+
+	sessionID, messageID := GetContextValues(ctx)
+	if sessionID == "" || messageID == "" {
+		return ToolResponse{}, fmt.Errorf("session ID and message ID are required for writing a note")
+	}
+
+	note := notes.Note{
+		Content:   params.Content,
+		Tags:      params.Tags,
+		Pinned:    params.Pinned,
+		Agent:     GetAgentName(ctx),
+		SessionID: sessionID,
+	}
+	if params.ExpiresInDays > 0 {
+		note.ExpiresAt = time.Now().AddDate(0, 0, params.ExpiresInDays).Unix()
+	}
+
+	p := t.permissions.Request(
+		permission.CreatePermissionRequest{
+			SessionID:   sessionID,
+			Path:        config.WorkingDirectory(),
+			ToolName:    MemoryWriteToolName,
+			Action:      "write",
+			Description: fmt.Sprintf("Keep a note in the workspace memory: %s", params.Content),
+			Params: MemoryWritePermissionsParams{
+				Content:   note.Content,
+				Tags:      note.Tags,
+				Pinned:    note.Pinned,
+				ExpiresAt: note.ExpiresAt,
+			},
+		},
+	)
+	if !p {
+		return ToolResponse{}, permission.ErrorPermissionDenied
+	}
+
+	note, err := notes.Add(note)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	return WithResponseMetadata(NewTextResponse(fmt.Sprintf("Note %s kept in the workspace memory", note.ID)), note), nil
+}
+
+func (t *memoryReadTool) Info() ToolInfo {
+	parameters, required := ParamsSchema(MemoryReadParams{})
+	return ToolInfo{
+		Name:        MemoryReadToolName,
+		Description: memoryReadToolDescription,
+		Parameters:  parameters,
+		Required:    required,
+	}
+}
+
+func (t *memoryReadTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params MemoryReadParams
+	if err := call.BindParams(&params, config.Get().StrictToolInputs); err != nil {
+		return NewParamsErrorResponse(err), nil
+	}
+
+	found, err := notes.Query(params.Query, params.Tags, params.Limit)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	if len(found) == 0 {
+		return NewTextResponse("No notes found"), nil
+	}
+
+	var b strings.Builder
+	for i, note := range found {
+		line := notes.Format(note) + "\n"
+		if b.Len()+len(line) > memoryReadMaxBytes {
+			fmt.Fprintf(&b, "(%d more notes, narrow the query to see them)\n", len(found)-i)
+			break
+		}
+		b.WriteString(line)
+	}
+	return NewTextResponse(strings.TrimSuffix(b.String(), "\n")), nil
+}
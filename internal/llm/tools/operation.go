@@ -0,0 +1,145 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/history"
+)
+
+type operationIDContextKey string
+
+// OperationIDContextKey holds the operation ID of the tool call being run
+const OperationIDContextKey operationIDContextKey = "operation_id"
+
+// OperationID returns the ID of the operation of a tool call: the call at
+// index in the response at step of the turn answering a user message, with its
+// name and input. It is the same each time the call is dispatched, including
+// when the turn is retried and the model makes the same call again, so that
+// the file tools apply it once.
+func OperationID(userMessageID string, step, index int, call ToolCall) string {
+	input := call.Input
+	// The same input may be written with other spacing or key order
+	var value any
+	if err := json.Unmarshal([]byte(input), &value); err == nil {
+		if canonical, err := json.Marshal(value); err == nil {
+			input = string(canonical)
+		}
+	}
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s/%d/%d/%s/%s", userMessageID, step, index, call.Name, input))
+	return hex.EncodeToString(sum[:16])
+}
+
+// WithOperationID returns a context in which the tool call run is operation id
+func WithOperationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, OperationIDContextKey, id)
+}
+
+func operationIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(OperationIDContextKey).(string)
+	return id
+}
+
+// FileHash returns the SHA-256 of the content of a file, as the file tools
+// report it and expect it back in expected_hash
+func FileHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// currentFileHash returns the hash of the file at path, "" when it does not
+// exist
+func currentFileHash(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return FileHash(content), nil
+}
+
+// ConflictError is reported when a file changed since the agent read it, so a
+// change expecting its former content is not applied
+type ConflictError struct {
+	Path         string `json:"path"`
+	ExpectedHash string `json:"expected_hash"`
+	// ActualHash is "" when the file does not exist
+	ActualHash string `json:"actual_hash"`
+}
+
+func (e *ConflictError) Error() string {
+	if e.ActualHash == "" {
+		return fmt.Sprintf("conflict: %s no longer exists, expected hash %s. No changes made", e.Path, e.ExpectedHash)
+	}
+	return fmt.Sprintf("conflict: %s changed since it was read, expected hash %s but found %s. Read the file again before changing it. No changes made", e.Path, e.ExpectedHash, e.ActualHash)
+}
+
+// ConflictResponseMetadata is the metadata of the response to a change
+// refused because of a conflict
+type ConflictResponseMetadata struct {
+	Conflict *ConflictError `json:"conflict"`
+}
+
+// applyOnce applies a change of the file at path with apply, as the operation
+// of the tool call run by ctx. An operation already applied is not applied
+// again, its recorded response is returned instead. When expectedHash is set,
+// the change is only applied to a file with that hash.
+func applyOnce(ctx context.Context, files history.Service, tool, path, expectedHash string, apply func() (ToolResponse, error)) (ToolResponse, error) {
+	opID := operationIDFromContext(ctx)
+	if opID != "" {
+		op, err := files.GetOperation(ctx, opID)
+		if err == nil {
+			logging.FromContext(ctx).Info("Tool call already applied, not applying it again", "tool", tool, "path", path, "operation_id", opID)
+			return ToolResponse{Type: ToolResponseTypeText, Content: op.Result, Metadata: op.Metadata}, nil
+		}
+		if !errors.Is(err, history.ErrOperationNotFound) {
+			return ToolResponse{}, fmt.Errorf("failed to look up operation %s: %w", opID, err)
+		}
+	}
+
+	before, err := currentFileHash(path)
+	if err != nil {
+		return ToolResponse{}, fmt.Errorf("failed to read file: %w", err)
+	}
+	if expectedHash != "" && expectedHash != before {
+		conflict := &ConflictError{Path: path, ExpectedHash: expectedHash, ActualHash: before}
+		return WithResponseMetadata(NewTextErrorResponse(conflict.Error()), ConflictResponseMetadata{Conflict: conflict}), nil
+	}
+
+	response, err := apply()
+	if err != nil || response.IsError {
+		return response, err
+	}
+	after, err := currentFileHash(path)
+	if err != nil {
+		return ToolResponse{}, fmt.Errorf("failed to read file: %w", err)
+	}
+	response.Content += fmt.Sprintf("\nFile hash: %s\n", after)
+
+	if opID != "" {
+		sessionID, messageID := GetContextValues(ctx)
+		err := files.RecordOperation(ctx, history.Operation{
+			ID:         opID,
+			SessionID:  sessionID,
+			MessageID:  messageID,
+			Tool:       tool,
+			Path:       path,
+			BeforeHash: before,
+			AfterHash:  after,
+			Result:     response.Content,
+			Metadata:   response.Metadata,
+		})
+		if err != nil {
+			logging.FromContext(ctx).Warn("Failed to record the tool call operation, it may be applied again", "tool", tool, "operation_id", opID, "error", err)
+		}
+	}
+	return response, nil
+}
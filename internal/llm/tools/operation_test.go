@@ -0,0 +1,149 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/db"
+	"github.com/caronex/intelligence-interface/internal/history"
+	"github.com/caronex/intelligence-interface/internal/permission"
+	"github.com/caronex/intelligence-interface/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fileToolsFixture struct {
+	dir   string
+	ctx   context.Context
+	edit  BaseTool
+	write BaseTool
+	patch BaseTool
+}
+
+func newFileToolsFixture(t *testing.T) *fileToolsFixture {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := config.NewTestConfig(config.WithWorkingDir(dir))
+	cfg.Data.Directory = t.TempDir()
+
+	conn, err := db.Connect()
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	q := db.New(conn)
+	sess, err := session.NewService(q).Create(context.Background(), "test")
+	require.NoError(t, err)
+
+	permissions := permission.NewPermissionService()
+	permissions.AutoApproveSession(sess.ID)
+	files := history.NewService(q, conn)
+	ctx := context.WithValue(context.Background(), SessionIDContextKey, sess.ID)
+	ctx = context.WithValue(ctx, MessageIDContextKey, "message-1")
+	return &fileToolsFixture{
+		dir:   dir,
+		ctx:   ctx,
+		edit:  NewEditTool(nil, permissions, files),
+		write: NewWriteTool(nil, permissions, files),
+		patch: NewPatchTool(nil, permissions, files),
+	}
+}
+
+// file writes a file of the workspace as read by the agent
+func (f *fileToolsFixture) file(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(f.dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	recordFileRead(path)
+	return path
+}
+
+func (f *fileToolsFixture) run(t *testing.T, tool BaseTool, operation int, params any) ToolResponse {
+	t.Helper()
+	input, err := json.Marshal(params)
+	require.NoError(t, err)
+	call := ToolCall{ID: "call", Name: tool.Info().Name, Input: string(input)}
+	ctx := WithOperationID(f.ctx, OperationID("message-1", 0, operation, call))
+	response, err := tool.Run(ctx, call)
+	require.NoError(t, err)
+	return response
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestEditDuplicateDispatch(t *testing.T) {
+	f := newFileToolsFixture(t)
+	path := f.file(t, "main.go", "package main\n")
+	insert := EditParams{FilePath: path, OldString: "package main\n", NewString: "package main\n\nimport \"fmt\"\n"}
+
+	first := f.run(t, f.edit, 0, insert)
+	require.False(t, first.IsError, first.Content)
+	assert.Contains(t, first.Content, "File hash: "+FileHash([]byte(readFile(t, path))))
+
+	// The call dispatched again is not applied again
+	again := f.run(t, f.edit, 0, insert)
+	assert.Equal(t, first.Content, again.Content)
+	assert.Equal(t, first.Metadata, again.Metadata)
+	assert.Equal(t, "package main\n\nimport \"fmt\"\n", readFile(t, path))
+
+	// Another call making the same change is applied
+	f.run(t, f.edit, 1, insert)
+	assert.Equal(t, "package main\n\nimport \"fmt\"\n\nimport \"fmt\"\n", readFile(t, path))
+}
+
+func TestEditCreatesFileOnce(t *testing.T) {
+	f := newFileToolsFixture(t)
+	path := filepath.Join(f.dir, "new.go")
+	create := EditParams{FilePath: path, NewString: "package main\n"}
+
+	response := f.run(t, f.edit, 0, create)
+	require.False(t, response.IsError, response.Content)
+	assert.Equal(t, "package main\n", readFile(t, path))
+
+	response = f.run(t, f.edit, 0, create)
+	assert.False(t, response.IsError, "a call dispatched again does not fail as the file now exists")
+	assert.Equal(t, "package main\n", readFile(t, path))
+}
+
+func TestFileToolsConflict(t *testing.T) {
+	f := newFileToolsFixture(t)
+	path := f.file(t, "main.go", "package main\n")
+	readHash := FileHash([]byte("package main\n"))
+
+	// The file is changed by someone else after the agent read it
+	require.NoError(t, os.WriteFile(path, []byte("package app\n"), 0o644))
+	recordFileRead(path)
+
+	tests := []struct {
+		name   string
+		tool   BaseTool
+		params any
+	}{
+		{"edit", f.edit, EditParams{FilePath: path, OldString: "package", NewString: "// Package main\npackage", ExpectedHash: readHash}},
+		{"write", f.write, WriteParams{FilePath: path, Content: "package main\n\nfunc main() {}\n", ExpectedHash: readHash}},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := f.run(t, tt.tool, i, tt.params)
+			assert.True(t, response.IsError)
+			assert.Contains(t, response.Content, "changed since it was read")
+
+			var metadata ConflictResponseMetadata
+			require.NoError(t, json.Unmarshal([]byte(response.Metadata), &metadata))
+			assert.Equal(t, &ConflictError{Path: path, ExpectedHash: readHash, ActualHash: FileHash([]byte("package app\n"))}, metadata.Conflict)
+			assert.Equal(t, "package app\n", readFile(t, path))
+		})
+	}
+
+	// The edit expecting the current content is applied
+	response := f.run(t, f.write, 2, WriteParams{FilePath: path, Content: "package app\n\nfunc main() {}\n", ExpectedHash: FileHash([]byte("package app\n"))})
+	require.False(t, response.IsError, response.Content)
+	assert.Equal(t, "package app\n\nfunc main() {}\n", readFile(t, path))
+}
@@ -0,0 +1,143 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/caronex/intelligence-interface/internal/pubsub"
+)
+
+// MaxLiveOutputLength caps the output kept for display while a tool runs,
+// apart from MaxOutputLength which caps the output sent to the model
+const MaxLiveOutputLength = 64 * 1024
+
+// ToolOutput is output of a running tool, published as it is produced
+type ToolOutput struct {
+	SessionID  string
+	ToolCallID string
+	// Chunk is the new output, empty for the periodic updates of a tool
+	// producing none
+	Chunk     string
+	StartTime time.Time
+	// Done is set once the tool finished, when its result replaces the output
+	Done bool
+}
+
+var outputBroker = pubsub.NewBroker[ToolOutput]()
+
+// PublishOutput publishes output of a running tool
+func PublishOutput(output ToolOutput) {
+	outputBroker.Publish(pubsub.UpdatedEvent, output)
+}
+
+// SubscribeOutput returns a channel of the output of running tools
+func SubscribeOutput(ctx context.Context) <-chan pubsub.Event[ToolOutput] {
+	return outputBroker.Subscribe(ctx)
+}
+
+// OutputBuffer accumulates the output of a running tool for display. ANSI
+// escape sequences are stripped, a carriage return makes the text that
+// follows replace its line, so progress bars redrawn in place take a single
+// line, and the oldest lines are dropped past the size limit.
+type OutputBuffer struct {
+	lines    []string
+	current  strings.Builder
+	returned bool // a carriage return ended the current line
+	pending  string
+	size     int
+	max      int
+	dropped  int
+}
+
+// NewOutputBuffer creates a buffer keeping about max bytes of output
+func NewOutputBuffer(max int) *OutputBuffer {
+	return &OutputBuffer{max: max}
+}
+
+// Write appends a chunk of output, which may end in the middle of a line or
+// of an escape sequence
+func (b *OutputBuffer) Write(chunk string) {
+	chunk = b.pending + chunk
+	b.pending = ""
+	if i := strings.LastIndexByte(chunk, ansi.ESC); i >= 0 && !escapeComplete(chunk[i:]) {
+		b.pending = chunk[i:]
+		chunk = chunk[:i]
+	}
+
+	for _, c := range []byte(ansi.Strip(chunk)) {
+		switch c {
+		case '\n':
+			b.returned = false
+			line := b.current.String()
+			b.current.Reset()
+			b.lines = append(b.lines, line)
+			b.size += len(line) + 1
+		case '\r':
+			b.returned = true
+		default:
+			if b.returned {
+				b.returned = false
+				b.current.Reset()
+			}
+			if b.current.Len() < b.max {
+				b.current.WriteByte(c)
+			}
+		}
+	}
+
+	for b.size > b.max && len(b.lines) > 0 {
+		b.size -= len(b.lines[0]) + 1
+		b.lines = b.lines[1:]
+		b.dropped++
+	}
+}
+
+// escapeComplete reports whether s, starting with an escape character, holds
+// a whole escape sequence
+func escapeComplete(s string) bool {
+	if len(s) < 2 {
+		return false
+	}
+	switch s[1] {
+	case '[': // CSI, ended by a final byte
+		for _, c := range []byte(s[2:]) {
+			if c >= 0x40 && c <= 0x7e {
+				return true
+			}
+		}
+		return false
+	case ']': // OSC, ended by BEL or ST
+		return strings.ContainsRune(s, ansi.BEL) || strings.Contains(s, "\x1b\\")
+	}
+	return true
+}
+
+// Lines returns the last n lines of output, including the line being
+// written, or all of them when n is not positive
+func (b *OutputBuffer) Lines(n int) []string {
+	lines := b.lines
+	if b.current.Len() > 0 {
+		lines = append(lines[:len(lines):len(lines)], b.current.String())
+	}
+	if n > 0 && len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// Dropped returns the number of lines dropped to stay under the size limit
+func (b *OutputBuffer) Dropped() int {
+	return b.dropped
+}
+
+func (b *OutputBuffer) String() string {
+	output := strings.Join(b.Lines(0), "\n")
+	if b.dropped > 0 {
+		output = fmt.Sprintf("... [%d lines dropped] ...\n%s", b.dropped, output)
+	}
+	return output
+}
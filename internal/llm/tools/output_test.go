@@ -0,0 +1,60 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutputBuffer(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string
+		want   []string
+	}{
+		{
+			name:   "lines split across chunks",
+			chunks: []string{"ok  \tpkg/a", " 0.1s\nok  \tpkg/b 0.2s\n", "FAIL"},
+			want:   []string{"ok  \tpkg/a 0.1s", "ok  \tpkg/b 0.2s", "FAIL"},
+		},
+		{
+			name:   "colors stripped",
+			chunks: []string{"\x1b[32mPASS\x1b[0m\n", "\x1b]0;title\x07done\n"},
+			want:   []string{"PASS", "done"},
+		},
+		{
+			name:   "escape sequence split across chunks",
+			chunks: []string{"\x1b[3", "1mred\x1b", "[0m\n"},
+			want:   []string{"red"},
+		},
+		{
+			name:   "progress bar redrawn in place",
+			chunks: []string{"downloading 10%\r", "downloading 60%\rdownloading", " 100%\n", "extracting\r\n"},
+			want:   []string{"downloading 100%", "extracting"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buffer := NewOutputBuffer(MaxLiveOutputLength)
+			for _, chunk := range tt.chunks {
+				buffer.Write(chunk)
+			}
+			assert.Equal(t, tt.want, buffer.Lines(0))
+		})
+	}
+}
+
+func TestOutputBufferLimit(t *testing.T) {
+	buffer := NewOutputBuffer(100)
+	for range 50 {
+		buffer.Write("0123456789\n")
+	}
+	buffer.Write(strings.Repeat("x", 500))
+
+	assert.Len(t, buffer.Lines(0), 10, "9 complete lines fit besides the current one")
+	assert.Equal(t, 41, buffer.Dropped())
+	assert.Len(t, buffer.Lines(0)[9], 100, "the current line is capped too")
+	assert.Equal(t, []string{"0123456789", strings.Repeat("x", 100)}, buffer.Lines(2))
+	assert.True(t, strings.HasPrefix(buffer.String(), "... [41 lines dropped] ...\n0123456789\n"))
+}
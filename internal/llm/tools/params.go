@@ -0,0 +1,386 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Tool params structs describe the input of a tool with struct tags, from
+// which its JSON Schema is generated and against which its input is checked:
+//
+//	type params struct {
+//		Action string `json:"action" required:"true" enum:"list,status" description:"Action to perform"`
+//		Limit  int    `json:"limit" minimum:"1" maximum:"100" default:"20"`
+//	}
+//
+// Fields without a json name are not part of the input.
+
+// ParamsSchema returns the properties and required fields of a tool params
+// struct, for ToolInfo
+func ParamsSchema(params any) (map[string]any, []string) {
+	schema := paramsSchemaOf(reflect.TypeOf(params))
+	return schema.properties(), append([]string{}, schema.required...)
+}
+
+// paramsSchema is the schema of a params struct
+type paramsSchema struct {
+	fields   []paramField
+	required []string
+}
+
+type paramField struct {
+	name        string
+	typ         reflect.Type
+	description string
+	required    bool
+	enum        []string
+	minimum     *float64
+	maximum     *float64
+	defaultTag  string
+}
+
+var paramsSchemas sync.Map // reflect.Type -> *paramsSchema
+
+func paramsSchemaOf(t reflect.Type) *paramsSchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if schema, ok := paramsSchemas.Load(t); ok {
+		return schema.(*paramsSchema)
+	}
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("tool params must be a struct, got %s", t))
+	}
+
+	schema := &paramsSchema{}
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "" || name == "-" || !sf.IsExported() {
+			continue
+		}
+		field := paramField{
+			name:        name,
+			typ:         sf.Type,
+			description: sf.Tag.Get("description"),
+			required:    sf.Tag.Get("required") == "true",
+			defaultTag:  sf.Tag.Get("default"),
+		}
+		if enum := sf.Tag.Get("enum"); enum != "" {
+			field.enum = strings.Split(enum, ",")
+		}
+		field.minimum = parseBound(sf, "minimum")
+		field.maximum = parseBound(sf, "maximum")
+		if field.required {
+			schema.required = append(schema.required, name)
+		}
+		schema.fields = append(schema.fields, field)
+	}
+	actual, _ := paramsSchemas.LoadOrStore(t, schema)
+	return actual.(*paramsSchema)
+}
+
+func parseBound(sf reflect.StructField, tag string) *float64 {
+	value, ok := sf.Tag.Lookup(tag)
+	if !ok {
+		return nil
+	}
+	bound, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		panic(fmt.Sprintf("invalid %s tag on %s: %v", tag, sf.Name, err))
+	}
+	return &bound
+}
+
+// field returns the field of the params struct called name
+func (s *paramsSchema) field(name string) (paramField, bool) {
+	i := slices.IndexFunc(s.fields, func(f paramField) bool { return f.name == name })
+	if i == -1 {
+		return paramField{}, false
+	}
+	return s.fields[i], true
+}
+
+func (s *paramsSchema) names() []string {
+	names := make([]string, len(s.fields))
+	for i, f := range s.fields {
+		names[i] = f.name
+	}
+	return names
+}
+
+func (s *paramsSchema) properties() map[string]any {
+	properties := make(map[string]any, len(s.fields))
+	for _, f := range s.fields {
+		properties[f.name] = f.property()
+	}
+	return properties
+}
+
+func (f paramField) property() map[string]any {
+	property := typeSchema(f.typ)
+	if f.description != "" {
+		property["description"] = f.description
+	}
+	if f.enum != nil {
+		if property["type"] == "array" {
+			property["items"].(map[string]any)["enum"] = f.enum
+		} else {
+			property["enum"] = f.enum
+		}
+	}
+	if f.minimum != nil {
+		property["minimum"] = *f.minimum
+	}
+	if f.maximum != nil {
+		property["maximum"] = *f.maximum
+	}
+	if f.defaultTag != "" {
+		property["default"] = f.defaultValue(jsonType(f.typ))
+	}
+	return property
+}
+
+// defaultValue is the default tag of a field as a value of its JSON type
+func (f paramField) defaultValue(typ string) any {
+	switch typ {
+	case "boolean":
+		if value, err := strconv.ParseBool(f.defaultTag); err == nil {
+			return value
+		}
+	case "integer", "number":
+		if value, err := strconv.ParseFloat(f.defaultTag, 64); err == nil {
+			return value
+		}
+	}
+	return f.defaultTag
+}
+
+func typeSchema(t reflect.Type) map[string]any {
+	schema := map[string]any{"type": jsonType(t)}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if schema["type"] == "array" {
+		schema["items"] = typeSchema(t.Elem())
+	}
+	return schema
+}
+
+// jsonType is the JSON Schema type of a Go type
+func jsonType(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// ParamViolation is a constraint of the tool schema the input breaks
+type ParamViolation struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ParamsError lists every constraint the input of a tool call breaks, worded
+// so the model can correct the call
+type ParamsError struct {
+	Violations []ParamViolation `json:"violations"`
+}
+
+func (e *ParamsError) Error() string {
+	var b strings.Builder
+	b.WriteString("Invalid input parameters:\n")
+	for _, v := range e.Violations {
+		if v.Field != "" {
+			fmt.Fprintf(&b, "- %s: %s\n", v.Field, v.Message)
+		} else {
+			fmt.Fprintf(&b, "- %s\n", v.Message)
+		}
+	}
+	b.WriteString("Fix these parameters and call the tool again.")
+	return b.String()
+}
+
+func (e *ParamsError) add(field, format string, args ...any) {
+	e.Violations = append(e.Violations, ParamViolation{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// NewParamsErrorResponse is the tool error of an input DecodeParams or
+// BindParams rejected, with the violations as metadata
+func NewParamsErrorResponse(err error) ToolResponse {
+	var paramsErr *ParamsError
+	if errors.As(err, &paramsErr) {
+		return WithResponseMetadata(NewTextErrorResponse(paramsErr.Error()), paramsErr)
+	}
+	return NewTextErrorResponse(err.Error())
+}
+
+// DecodeParams checks the raw input of a tool call against the schema of the
+// params struct params points to, then decodes it into params. Fields the
+// input leaves out take their default tag, and an empty input is an empty
+// object. With strict, fields the schema does not have are violations rather
+// than ignored. The error is a *ParamsError when the input breaks the schema.
+func DecodeParams(input string, params any, strict bool) error {
+	schema := paramsSchemaOf(reflect.TypeOf(params))
+	if strings.TrimSpace(input) == "" {
+		input = "{}"
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(input))
+	decoder.UseNumber()
+	var raw any
+	if err := decoder.Decode(&raw); err != nil {
+		return &ParamsError{Violations: []ParamViolation{{Message: fmt.Sprintf("input is not valid JSON: %v", err)}}}
+	}
+	object, ok := raw.(map[string]any)
+	if !ok {
+		return &ParamsError{Violations: []ParamViolation{{Message: fmt.Sprintf("input must be a JSON object, got %s", describeJSON(raw))}}}
+	}
+
+	paramsErr := &ParamsError{}
+	for _, f := range schema.fields {
+		value, present := object[f.name]
+		if !present || value == nil {
+			if f.required {
+				paramsErr.add(f.name, "required %s is missing%s", jsonType(f.typ), f.enumHint())
+			} else if f.defaultTag != "" {
+				object[f.name] = f.defaultValue(jsonType(f.typ))
+			}
+			continue
+		}
+		f.check(paramsErr, f.name, value, f.typ)
+	}
+	if strict {
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			if _, known := schema.field(key); !known {
+				keys = append(keys, key)
+			}
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			paramsErr.add(key, "unknown field, the known fields are %s", strings.Join(schema.names(), ", "))
+		}
+	}
+	if len(paramsErr.Violations) > 0 {
+		return paramsErr
+	}
+
+	// Decode the checked object, which has the defaults of the fields left out
+	data, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, params); err != nil {
+		return &ParamsError{Violations: []ParamViolation{{Message: err.Error()}}}
+	}
+	return nil
+}
+
+// check adds the violations of value, the JSON value at path, against the
+// field constraints and its Go type t
+func (f paramField) check(paramsErr *ParamsError, path string, value any, t reflect.Type) {
+	want := jsonType(t)
+	if !matchesType(value, want) {
+		paramsErr.add(path, "expected %s, got %s%s", want, describeJSON(value), f.enumHint())
+		return
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch want {
+	case "array":
+		for i, item := range value.([]any) {
+			f.check(paramsErr, fmt.Sprintf("%s[%d]", path, i), item, t.Elem())
+		}
+	case "string":
+		if f.enum != nil && !slices.Contains(f.enum, value.(string)) {
+			paramsErr.add(path, "got %q%s", value, f.enumHint())
+		}
+	case "integer", "number":
+		n, _ := value.(json.Number).Float64()
+		if f.minimum != nil && n < *f.minimum {
+			paramsErr.add(path, "must be at least %s, got %s", formatBound(*f.minimum), value)
+		}
+		if f.maximum != nil && n > *f.maximum {
+			paramsErr.add(path, "must be at most %s, got %s", formatBound(*f.maximum), value)
+		}
+	}
+}
+
+func (f paramField) enumHint() string {
+	if f.enum == nil {
+		return ""
+	}
+	quoted := make([]string, len(f.enum))
+	for i, value := range f.enum {
+		quoted[i] = strconv.Quote(value)
+	}
+	return ", expected one of " + strings.Join(quoted, ", ")
+}
+
+func formatBound(bound float64) string {
+	return strconv.FormatFloat(bound, 'f', -1, 64)
+}
+
+func matchesType(value any, want string) bool {
+	switch value := value.(type) {
+	case bool:
+		return want == "boolean"
+	case string:
+		return want == "string"
+	case json.Number:
+		if want == "number" {
+			return true
+		}
+		_, err := value.Int64()
+		return want == "integer" && err == nil
+	case []any:
+		return want == "array"
+	case map[string]any:
+		return want == "object"
+	}
+	return false
+}
+
+// describeJSON names the JSON type of a value, with the value when it is short
+func describeJSON(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return fmt.Sprintf("boolean %t", value)
+	case string:
+		if len(value) > 40 {
+			return "string"
+		}
+		return fmt.Sprintf("string %q", value)
+	case json.Number:
+		return "number " + value.String()
+	case []any:
+		return "array"
+	default:
+		return "object"
+	}
+}
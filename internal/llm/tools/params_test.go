@@ -0,0 +1,162 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testParams struct {
+	Action  string   `json:"action" required:"true" enum:"list,status" description:"Action to perform"`
+	Limit   int      `json:"limit" minimum:"1" maximum:"100" default:"20"`
+	Ratio   float64  `json:"ratio" maximum:"1"`
+	Verbose bool     `json:"verbose" default:"true"`
+	Tags    []string `json:"tags" enum:"a,b"`
+	Filter  *struct {
+		Name string `json:"name"`
+	} `json:"filter"`
+	internal string
+}
+
+func TestParamsSchema(t *testing.T) {
+	properties, required := ParamsSchema(testParams{})
+
+	assert.Equal(t, []string{"action"}, required)
+	assert.Equal(t, map[string]any{
+		"action":  map[string]any{"type": "string", "description": "Action to perform", "enum": []string{"list", "status"}},
+		"limit":   map[string]any{"type": "integer", "minimum": 1.0, "maximum": 100.0, "default": 20.0},
+		"ratio":   map[string]any{"type": "number", "maximum": 1.0},
+		"verbose": map[string]any{"type": "boolean", "default": true},
+		"tags":    map[string]any{"type": "array", "items": map[string]any{"type": "string", "enum": []string{"a", "b"}}},
+		"filter":  map[string]any{"type": "object"},
+	}, properties)
+
+	_, required = ParamsSchema(struct {
+		Name string `json:"name"`
+	}{})
+	assert.NotNil(t, required, "no required fields is an empty list, not null")
+}
+
+func TestDecodeParams(t *testing.T) {
+	params := testParams{}
+	require.NoError(t, DecodeParams(`{"action":"list","ratio":0.5,"tags":["b"],"filter":{"name":"x"}}`, &params, true))
+	assert.Equal(t, "list", params.Action)
+	assert.Equal(t, 20, params.Limit, "left out fields take their default")
+	assert.True(t, params.Verbose)
+	assert.Equal(t, 0.5, params.Ratio)
+	assert.Equal(t, []string{"b"}, params.Tags)
+	assert.Equal(t, "x", params.Filter.Name)
+
+	params = testParams{}
+	require.NoError(t, DecodeParams(`{"action":"status","verbose":false,"limit":5}`, &params, false))
+	assert.False(t, params.Verbose)
+	assert.Equal(t, 5, params.Limit)
+}
+
+func TestDecodeParamsViolations(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		strict bool
+		want   []ParamViolation
+	}{
+		{
+			name:  "empty input misses required fields",
+			input: "",
+			want:  []ParamViolation{{Field: "action", Message: `required string is missing, expected one of "list", "status"`}},
+		},
+		{
+			name:  "null required field",
+			input: `{"action":null}`,
+			want:  []ParamViolation{{Field: "action", Message: `required string is missing, expected one of "list", "status"`}},
+		},
+		{
+			name:  "invalid JSON",
+			input: `{"action":`,
+			want:  []ParamViolation{{Message: "input is not valid JSON: unexpected EOF"}},
+		},
+		{
+			name:  "not an object",
+			input: `["list"]`,
+			want:  []ParamViolation{{Message: "input must be a JSON object, got array"}},
+		},
+		{
+			name:  "value outside enum",
+			input: `{"action":"delete"}`,
+			want:  []ParamViolation{{Field: "action", Message: `got "delete", expected one of "list", "status"`}},
+		},
+		{
+			name:  "wrong type",
+			input: `{"action":1,"verbose":"yes"}`,
+			want: []ParamViolation{
+				{Field: "action", Message: `expected string, got number 1, expected one of "list", "status"`},
+				{Field: "verbose", Message: `expected boolean, got string "yes"`},
+			},
+		},
+		{
+			name:  "fractional integer",
+			input: `{"action":"list","limit":2.5}`,
+			want:  []ParamViolation{{Field: "limit", Message: "expected integer, got number 2.5"}},
+		},
+		{
+			name:  "out of range",
+			input: `{"action":"list","limit":0,"ratio":1.5}`,
+			want: []ParamViolation{
+				{Field: "limit", Message: "must be at least 1, got 0"},
+				{Field: "ratio", Message: "must be at most 1, got 1.5"},
+			},
+		},
+		{
+			name:  "array items",
+			input: `{"action":"list","tags":["a",3,"c"]}`,
+			want: []ParamViolation{
+				{Field: "tags[1]", Message: `expected string, got number 3, expected one of "a", "b"`},
+				{Field: "tags[2]", Message: `got "c", expected one of "a", "b"`},
+			},
+		},
+		{
+			name:  "object expected",
+			input: `{"action":"list","filter":"x"}`,
+			want:  []ParamViolation{{Field: "filter", Message: `expected object, got string "x"`}},
+		},
+		{
+			name:   "unknown fields when strict",
+			input:  `{"action":"list","limt":5,"extra":true}`,
+			strict: true,
+			want: []ParamViolation{
+				{Field: "extra", Message: "unknown field, the known fields are action, limit, ratio, verbose, tags, filter"},
+				{Field: "limt", Message: "unknown field, the known fields are action, limit, ratio, verbose, tags, filter"},
+			},
+		},
+		{
+			name:  "unknown fields ignored when not strict",
+			input: `{"action":"list","limt":5}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var params testParams
+			err := DecodeParams(tt.input, &params, tt.strict)
+			if tt.want == nil {
+				assert.NoError(t, err)
+				return
+			}
+			var paramsErr *ParamsError
+			require.ErrorAs(t, err, &paramsErr)
+			assert.Equal(t, tt.want, paramsErr.Violations)
+		})
+	}
+}
+
+func TestParamsErrorResponse(t *testing.T) {
+	var params testParams
+	err := DecodeParams(`{"action":"delete"}`, &params, false)
+	response := NewParamsErrorResponse(err)
+
+	assert.True(t, response.IsError)
+	assert.Equal(t, "Invalid input parameters:\n"+
+		`- action: got "delete", expected one of "list", "status"`+"\n"+
+		"Fix these parameters and call the tool again.", response.Content)
+	assert.JSONEq(t, `{"violations":[{"field":"action","message":"got \"delete\", expected one of \"list\", \"status\""}]}`, response.Metadata)
+}
@@ -2,7 +2,6 @@ package tools
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -82,14 +81,15 @@ func (p *patchTool) Info() ToolInfo {
 				"description": "The full patch text that describes all changes to be made",
 			},
 		},
-		Required: []string{"patch_text"},
+		Required:    []string{"patch_text"},
+		Destructive: true,
 	}
 }
 
 func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
 	var params PatchParams
-	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
-		return NewTextErrorResponse("invalid parameters"), nil
+	if err := call.BindInput(&params); err != nil {
+		return NewTextErrorResponse(err.Error()), nil
 	}
 
 	if params.PatchText == "" {
@@ -99,10 +99,9 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 	// Identify all files needed for the patch and verify they've been read
 	filesToRead := diff.IdentifyFilesNeeded(params.PatchText)
 	for _, filePath := range filesToRead {
-		absPath := filePath
-		if !filepath.IsAbs(absPath) {
-			wd := config.WorkingDirectory()
-			absPath = filepath.Join(wd, absPath)
+		absPath, err := config.ResolveWorkspacePath(filePath)
+		if err != nil {
+			return NewTextErrorResponse(err.Error()), nil
 		}
 
 		if getLastReadTime(absPath).IsZero() {
@@ -134,13 +133,12 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 	// Check for new files to ensure they don't already exist
 	filesToAdd := diff.IdentifyFilesAdded(params.PatchText)
 	for _, filePath := range filesToAdd {
-		absPath := filePath
-		if !filepath.IsAbs(absPath) {
-			wd := config.WorkingDirectory()
-			absPath = filepath.Join(wd, absPath)
+		absPath, err := config.ResolveWorkspacePath(filePath)
+		if err != nil {
+			return NewTextErrorResponse(err.Error()), nil
 		}
 
-		_, err := os.Stat(absPath)
+		_, err = os.Stat(absPath)
 		if err == nil {
 			return NewTextErrorResponse(fmt.Sprintf("file already exists and cannot be added: %s", absPath)), nil
 		} else if !os.IsNotExist(err) {
@@ -151,10 +149,9 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 	// Load all required files
 	currentFiles := make(map[string]string)
 	for _, filePath := range filesToRead {
-		absPath := filePath
-		if !filepath.IsAbs(absPath) {
-			wd := config.WorkingDirectory()
-			absPath = filepath.Join(wd, absPath)
+		absPath, err := config.ResolveWorkspacePath(filePath)
+		if err != nil {
+			return NewTextErrorResponse(err.Error()), nil
 		}
 
 		content, err := os.ReadFile(absPath)
@@ -188,9 +185,13 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 
 	// Request permission for all changes
 	for path, change := range commit.Changes {
+		absPath, err := config.ResolveWorkspacePath(path)
+		if err != nil {
+			return NewTextErrorResponse(err.Error()), nil
+		}
+		dir := filepath.Dir(absPath)
 		switch change.Type {
 		case diff.ActionAdd:
-			dir := filepath.Dir(path)
 			patchDiff, _, _ := diff.GenerateDiff("", *change.NewContent, path)
 			p := p.permissions.Request(
 				permission.CreatePermissionRequest{
@@ -218,7 +219,6 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 				newContent = *change.NewContent
 			}
 			patchDiff, _, _ := diff.GenerateDiff(currentContent, newContent, path)
-			dir := filepath.Dir(path)
 			p := p.permissions.Request(
 				permission.CreatePermissionRequest{
 					SessionID:   sessionID,
@@ -236,7 +236,6 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 				return ToolResponse{}, permission.ErrorPermissionDenied
 			}
 		case diff.ActionDelete:
-			dir := filepath.Dir(path)
 			patchDiff, _, _ := diff.GenerateDiff(*change.OldContent, "", path)
 			p := p.permissions.Request(
 				permission.CreatePermissionRequest{
@@ -259,10 +258,9 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 
 	// Apply the changes to the filesystem
 	err = diff.ApplyCommit(commit, func(path string, content string) error {
-		absPath := path
-		if !filepath.IsAbs(absPath) {
-			wd := config.WorkingDirectory()
-			absPath = filepath.Join(wd, absPath)
+		absPath, err := config.ResolveWorkspacePath(path)
+		if err != nil {
+			return err
 		}
 
 		// Create parent directories if needed
@@ -273,10 +271,9 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 
 		return os.WriteFile(absPath, []byte(content), 0o644)
 	}, func(path string) error {
-		absPath := path
-		if !filepath.IsAbs(absPath) {
-			wd := config.WorkingDirectory()
-			absPath = filepath.Join(wd, absPath)
+		absPath, err := config.ResolveWorkspacePath(path)
+		if err != nil {
+			return err
 		}
 		return os.Remove(absPath)
 	})
@@ -290,10 +287,9 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 	totalRemovals := 0
 
 	for path, change := range commit.Changes {
-		absPath := path
-		if !filepath.IsAbs(absPath) {
-			wd := config.WorkingDirectory()
-			absPath = filepath.Join(wd, absPath)
+		absPath, err := config.ResolveWorkspacePath(path)
+		if err != nil {
+			return NewTextErrorResponse(err.Error()), nil
 		}
 		changedFiles = append(changedFiles, absPath)
 
@@ -0,0 +1,45 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatchQualifiedPaths(t *testing.T) {
+	f := newFileToolsFixture(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(f.dir, "frontend"), 0o755))
+	require.NoError(t, config.Update(func(cfg *config.Config) error {
+		cfg.Workspaces = map[string]config.WorkspaceRoot{
+			"web": {Path: filepath.Join(f.dir, "frontend")},
+		}
+		return nil
+	}))
+	app := f.file(t, "frontend/app.ts", "const a = 1;\nconst b = 2;\n")
+
+	response := f.run(t, f.patch, 0, PatchParams{PatchText: `*** Begin Patch
+*** Update File: web:app.ts
+@@
+ const a = 1;
+-const b = 2;
++const b = 3;
+*** Add File: web:util.ts
++export const c = 4;
+*** End Patch`})
+	require.False(t, response.IsError, response.Content)
+	assert.Equal(t, "const a = 1;\nconst b = 3;\n", readFile(t, app))
+	assert.Equal(t, "export const c = 4;", readFile(t, filepath.Join(f.dir, "frontend", "util.ts")))
+	assert.NoFileExists(t, filepath.Join(f.dir, "web:app.ts"), "the qualifier is not part of the path")
+
+	response = f.run(t, f.patch, 1, PatchParams{PatchText: `*** Begin Patch
+*** Add File: web:../outside.ts
++export const d = 5;
+*** End Patch`})
+	assert.True(t, response.IsError)
+	assert.Contains(t, response.Content, "escapes workspace root")
+	assert.NoFileExists(t, filepath.Join(f.dir, "outside.ts"))
+}
@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+)
+
+// ToolSourceBuiltin is the source of tools shipped with the application
+const ToolSourceBuiltin = "builtin"
+
+// NamespacedTool is a tool provided by an external server. Its qualified
+// name, <server>.<tool>, identifies it in configuration whatever name it is
+// exposed to the model under.
+type NamespacedTool interface {
+	BaseTool
+	QualifiedName() string
+}
+
+// ToolResolution is the tool a name resolves to, and the tools dropped
+// because they were registered under the same name
+type ToolResolution struct {
+	Name     string   `json:"name"`
+	Source   string   `json:"source"`
+	Shadowed []string `json:"shadowed,omitempty"`
+}
+
+var (
+	resolutionsMu sync.RWMutex
+	resolutions   []ToolResolution
+)
+
+// ResolveTools merges toolsets into a single set with unique names. When
+// several tools share a name, the first one registered is kept, so builtin
+// toolsets should come first. Collisions are logged and the resolution is
+// kept for introspection.
+func ResolveTools(toolsets ...[]BaseTool) []BaseTool {
+	var resolved []BaseTool
+	var result []ToolResolution
+	index := make(map[string]int)
+	for _, toolset := range toolsets {
+		for _, tool := range toolset {
+			name := tool.Info().Name
+			if i, ok := index[name]; ok {
+				result[i].Shadowed = append(result[i].Shadowed, toolSource(tool))
+				continue
+			}
+			index[name] = len(result)
+			result = append(result, ToolResolution{Name: name, Source: toolSource(tool)})
+			resolved = append(resolved, tool)
+		}
+	}
+
+	var collisions []string
+	for _, r := range result {
+		if len(r.Shadowed) > 0 {
+			collisions = append(collisions, fmt.Sprintf("%s: %s shadows %s", r.Name, r.Source, strings.Join(r.Shadowed, ", ")))
+		}
+	}
+	if len(collisions) > 0 {
+		logging.Warn("tool name collisions, keeping the first tool registered for each name",
+			"collisions", strings.Join(collisions, "; "))
+	}
+
+	resolutionsMu.Lock()
+	resolutions = result
+	resolutionsMu.Unlock()
+	return resolved
+}
+
+// Resolutions returns the tool names of the last resolved toolset
+func Resolutions() []ToolResolution {
+	resolutionsMu.RLock()
+	defer resolutionsMu.RUnlock()
+	return append([]ToolResolution(nil), resolutions...)
+}
+
+// toolSource is the qualified name of a namespaced tool, or builtin
+func toolSource(tool BaseTool) string {
+	if namespaced, ok := tool.(NamespacedTool); ok {
+		return namespaced.QualifiedName()
+	}
+	return ToolSourceBuiltin
+}
@@ -0,0 +1,46 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type namedTool struct {
+	name string
+}
+
+func (n namedTool) Info() ToolInfo {
+	return ToolInfo{Name: n.name}
+}
+
+func (n namedTool) Run(ctx context.Context, params ToolCall) (ToolResponse, error) {
+	return NewTextResponse(""), nil
+}
+
+type namespacedTool struct {
+	namedTool
+	qualified string
+}
+
+func (n namespacedTool) QualifiedName() string {
+	return n.qualified
+}
+
+func TestResolveTools(t *testing.T) {
+	fetch := namedTool{name: FetchToolName}
+	grep := namedTool{name: GrepToolName}
+	aliased := namespacedTool{namedTool{name: FetchToolName}, "web.fetch"}
+	first := namespacedTool{namedTool{name: "search"}, "docs.search"}
+	second := namespacedTool{namedTool{name: "search"}, "web.search"}
+
+	resolved := ResolveTools([]BaseTool{fetch, grep}, []BaseTool{aliased, first, second})
+
+	assert.Equal(t, []BaseTool{fetch, grep, first}, resolved)
+	assert.Equal(t, []ToolResolution{
+		{Name: FetchToolName, Source: ToolSourceBuiltin, Shadowed: []string{"web.fetch"}},
+		{Name: GrepToolName, Source: ToolSourceBuiltin},
+		{Name: "search", Source: "docs.search", Shadowed: []string{"web.search"}},
+	}, Resolutions())
+}
@@ -0,0 +1,172 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/scratchpad"
+)
+
+type ScratchpadReadParams struct {
+	Section   string `json:"section" description:"The title of the section to read, every section when empty"`
+	Revisions bool   `json:"revisions" description:"Whether to list the kept revisions instead, to find one to revert to"`
+}
+
+type ScratchpadUpdateParams struct {
+	Action   string `json:"action" required:"true" enum:"replace,patch,remove,revert" description:"replace sets the body of a section, adding it when missing; patch replaces old_text with new_text in a section; remove removes a section; revert restores a kept revision"`
+	Section  string `json:"section" description:"The title of the section, required but to revert"`
+	Content  string `json:"content" description:"The markdown body of the section to replace"`
+	OldText  string `json:"old_text" description:"The text of the section to patch, found exactly once in it"`
+	NewText  string `json:"new_text" description:"The text old_text is replaced with"`
+	Revision int    `json:"revision" minimum:"1" description:"The number of the revision to revert to"`
+}
+
+type scratchpadReadTool struct{}
+
+type scratchpadUpdateTool struct{}
+
+const (
+	ScratchpadReadToolName        = "scratchpad_read"
+	scratchpadReadToolDescription = `Reads the scratchpad of the session, your working notes kept apart from the conversation.
+
+WHEN TO USE THIS TOOL:
+- The scratchpad is already in every request, read it when sections were left out for their size
+- Use to list the kept revisions before reverting an update
+
+HOW TO USE:
+- Give the title of a section to read only that section
+- Set revisions to list the kept revisions, newest first
+
+LIMITATIONS:
+- Only the last revisions are kept`
+
+	ScratchpadUpdateToolName        = "scratchpad_update"
+	scratchpadUpdateToolDescription = `Updates the scratchpad of the session, your working notes kept apart from the conversation.
+
+WHEN TO USE THIS TOOL:
+- Use for a running todo list, intermediate findings or decisions you need again later in the session
+- Prefer it to writing such notes in your responses, which clutters the conversation and its context
+
+HOW TO USE:
+- replace sets the whole body of a section, adding the section when missing
+- patch replaces old_text with new_text in a section, old_text being found exactly once in it
+- remove removes a section, revert restores a revision listed by scratchpad_read
+- Keep sections short; the user sees and may edit the scratchpad
+
+LIMITATIONS:
+- At most 20 sections and 16000 bytes
+- Notes meant to outlast the session belong in the workspace memory instead`
+)
+
+func NewScratchpadReadTool() BaseTool {
+	return &scratchpadReadTool{}
+}
+
+func NewScratchpadUpdateTool() BaseTool {
+	return &scratchpadUpdateTool{}
+}
+
+func (t *scratchpadReadTool) Info() ToolInfo {
+	parameters, required := ParamsSchema(ScratchpadReadParams{})
+	return ToolInfo{
+		Name:        ScratchpadReadToolName,
+		Description: scratchpadReadToolDescription,
+		Parameters:  parameters,
+		Required:    required,
+	}
+}
+
+func (t *scratchpadReadTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params ScratchpadReadParams
+	if err := call.BindParams(&params, config.Get().StrictToolInputs); err != nil {
+		return NewParamsErrorResponse(err), nil
+	}
+	sessionID, _ := GetContextValues(ctx)
+	if sessionID == "" {
+		return ToolResponse{}, fmt.Errorf("session ID is required for reading the scratchpad")
+	}
+
+	if params.Revisions {
+		revisions, err := scratchpad.Revisions(sessionID)
+		if err != nil {
+			return NewTextErrorResponse(err.Error()), nil
+		}
+		if len(revisions) == 0 {
+			return NewTextResponse("The scratchpad was never written"), nil
+		}
+		var b strings.Builder
+		for _, revision := range revisions {
+			titles := make([]string, len(revision.Sections))
+			for i, section := range revision.Sections {
+				titles[i] = section.Title
+			}
+			fmt.Fprintf(&b, "- revision %d by %s at %s: %s\n", revision.Number, revision.Author,
+				time.Unix(revision.CreatedAt, 0).UTC().Format(time.RFC3339), strings.Join(titles, ", "))
+		}
+		return NewTextResponse(strings.TrimSuffix(b.String(), "\n")), nil
+	}
+
+	revision, err := scratchpad.Get(sessionID)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	if params.Section != "" {
+		section, ok := revision.Section(params.Section)
+		if !ok {
+			return NewTextErrorResponse(fmt.Sprintf("The scratchpad has no section %q", params.Section)), nil
+		}
+		return NewTextResponse(scratchpad.Render([]scratchpad.Section{section})), nil
+	}
+	if len(revision.Sections) == 0 {
+		return NewTextResponse("The scratchpad is empty"), nil
+	}
+	return NewTextResponse(scratchpad.Render(revision.Sections)), nil
+}
+
+func (t *scratchpadUpdateTool) Info() ToolInfo {
+	parameters, required := ParamsSchema(ScratchpadUpdateParams{})
+	return ToolInfo{
+		Name:        ScratchpadUpdateToolName,
+		Description: scratchpadUpdateToolDescription,
+		Parameters:  parameters,
+		Required:    required,
+	}
+}
+
+func (t *scratchpadUpdateTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params ScratchpadUpdateParams
+	if err := call.BindParams(&params, config.Get().StrictToolInputs); err != nil {
+		return NewParamsErrorResponse(err), nil
+	}
+	sessionID, _ := GetContextValues(ctx)
+	if sessionID == "" {
+		return ToolResponse{}, fmt.Errorf("session ID is required for updating the scratchpad")
+	}
+	if params.Action != "revert" && strings.TrimSpace(params.Section) == "" {
+		return NewTextErrorResponse("section is required to " + params.Action), nil
+	}
+
+	author := GetAgentName(ctx)
+	if author == "" {
+		author = "agent"
+	}
+	var revision scratchpad.Revision
+	var err error
+	switch params.Action {
+	case "replace":
+		revision, err = scratchpad.Replace(sessionID, author, params.Section, params.Content)
+	case "patch":
+		revision, err = scratchpad.Patch(sessionID, author, params.Section, params.OldText, params.NewText)
+	case "remove":
+		revision, err = scratchpad.Remove(sessionID, author, params.Section)
+	case "revert":
+		revision, err = scratchpad.Revert(sessionID, author, params.Revision)
+	}
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	return WithResponseMetadata(NewTextResponse(fmt.Sprintf("Scratchpad updated to revision %d", revision.Number)), revision), nil
+}
@@ -4,11 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -18,10 +20,11 @@ import (
 type PersistentShell struct {
 	cmd          *exec.Cmd
 	stdin        *os.File
-	isAlive      bool
+	isAlive      atomic.Bool
 	cwd          string
 	mu           sync.Mutex
 	commandQueue chan *commandExecution
+	closeQueue   sync.Once
 }
 
 type commandExecution struct {
@@ -29,8 +32,12 @@ type commandExecution struct {
 	timeout    time.Duration
 	resultChan chan commandResult
 	ctx        context.Context
+	output     func(chunk string)
 }
 
+// outputInterval is how often the output of a running command is streamed
+const outputInterval = 100 * time.Millisecond
+
 type commandResult struct {
 	stdout      string
 	stderr      string
@@ -51,7 +58,7 @@ func GetPersistentShell(workingDir string) *PersistentShell {
 
 	if shellInstance == nil {
 		shellInstance = newPersistentShell(workingDir)
-	} else if !shellInstance.isAlive {
+	} else if !shellInstance.isAlive.Load() {
 		shellInstance = newPersistentShell(shellInstance.cwd)
 	}
 
@@ -101,17 +108,16 @@ func newPersistentShell(cwd string) *PersistentShell {
 	shell := &PersistentShell{
 		cmd:          cmd,
 		stdin:        stdinPipe.(*os.File),
-		isAlive:      true,
 		cwd:          cwd,
 		commandQueue: make(chan *commandExecution, 10),
 	}
+	shell.isAlive.Store(true)
 
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
 				fmt.Fprintf(os.Stderr, "Panic in shell command processor: %v\n", r)
-				shell.isAlive = false
-				close(shell.commandQueue)
+				shell.stop()
 			}
 		}()
 		shell.processCommands()
@@ -122,25 +128,31 @@ func newPersistentShell(cwd string) *PersistentShell {
 		if err != nil {
 			// Log the error if needed
 		}
-		shell.isAlive = false
-		close(shell.commandQueue)
+		shell.stop()
 	}()
 
 	return shell
 }
 
+// stop marks the shell dead and closes its command queue, once. It is called
+// by the goroutine waiting for the shell process, which does not hold mu.
+func (s *PersistentShell) stop() {
+	s.isAlive.Store(false)
+	s.closeQueue.Do(func() { close(s.commandQueue) })
+}
+
 func (s *PersistentShell) processCommands() {
 	for cmd := range s.commandQueue {
-		result := s.execCommand(cmd.command, cmd.timeout, cmd.ctx)
+		result := s.execCommand(cmd.command, cmd.timeout, cmd.ctx, cmd.output)
 		cmd.resultChan <- result
 	}
 }
 
-func (s *PersistentShell) execCommand(command string, timeout time.Duration, ctx context.Context) commandResult {
+func (s *PersistentShell) execCommand(command string, timeout time.Duration, ctx context.Context, output func(chunk string)) commandResult {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if !s.isAlive {
+	if !s.isAlive.Load() {
 		return commandResult{
 			stderr:   "Shell is not alive",
 			exitCode: 1,
@@ -161,12 +173,13 @@ func (s *PersistentShell) execCommand(command string, timeout time.Duration, ctx
 		os.Remove(cwdFile)
 	}()
 
-	fullCommand := fmt.Sprintf(`
+	fullCommand := fmt.Sprintf(`%s
 eval %s < /dev/null > %s 2> %s
 EXEC_EXIT_CODE=$?
 pwd > %s
 echo $EXEC_EXIT_CODE > %s
 `,
+		spaceEnvironment(),
 		shellQuote(command),
 		shellQuote(stdoutFile),
 		shellQuote(stderrFile),
@@ -186,10 +199,22 @@ echo $EXEC_EXIT_CODE > %s
 	interrupted := false
 
 	startTime := time.Now()
+	stdoutTail := &fileTail{path: stdoutFile}
+	stderrTail := &fileTail{path: stderrFile}
+	lastOutput := startTime
 
 	done := make(chan bool)
 	go func() {
 		for {
+			if output != nil && time.Since(lastOutput) >= outputInterval {
+				lastOutput = time.Now()
+				for _, tail := range []*fileTail{stdoutTail, stderrTail} {
+					if chunk := tail.read(); chunk != "" {
+						output(chunk)
+					}
+				}
+			}
+
 			select {
 			case <-ctx.Done():
 				s.killChildren()
@@ -243,33 +268,59 @@ echo $EXEC_EXIT_CODE > %s
 	}
 }
 
+// killChildren terminates the processes started by the running command,
+// including those they started, such as the test binaries of go test
 func (s *PersistentShell) killChildren() {
 	if s.cmd == nil || s.cmd.Process == nil {
 		return
 	}
 
-	pgrepCmd := exec.Command("pgrep", "-P", fmt.Sprintf("%d", s.cmd.Process.Pid))
+	for _, pid := range descendants(s.cmd.Process.Pid) {
+		proc, err := os.FindProcess(pid)
+		if err == nil {
+			proc.Signal(syscall.SIGTERM)
+		}
+	}
+}
+
+// descendants returns the processes descending from pid, parents first
+func descendants(pid int) []int {
+	pgrepCmd := exec.Command("pgrep", "-P", fmt.Sprintf("%d", pid))
 	output, err := pgrepCmd.Output()
 	if err != nil {
-		return
+		return nil
 	}
 
+	var pids []int
 	for pidStr := range strings.SplitSeq(string(output), "\n") {
 		if pidStr = strings.TrimSpace(pidStr); pidStr != "" {
-			var pid int
-			fmt.Sscanf(pidStr, "%d", &pid)
-			if pid > 0 {
-				proc, err := os.FindProcess(pid)
-				if err == nil {
-					proc.Signal(syscall.SIGTERM)
-				}
+			var child int
+			fmt.Sscanf(pidStr, "%d", &child)
+			if child > 0 {
+				pids = append(pids, child)
+				pids = append(pids, descendants(child)...)
 			}
 		}
 	}
+	return pids
+}
+
+// Dir returns the working directory of the shell, as left by the last
+// command. It waits for the running command to end.
+func (s *PersistentShell) Dir() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cwd
 }
 
 func (s *PersistentShell) Exec(ctx context.Context, command string, timeoutMs int) (string, string, int, bool, error) {
-	if !s.isAlive {
+	return s.ExecStream(ctx, command, timeoutMs, nil)
+}
+
+// ExecStream runs a command like Exec, passing its stdout and stderr to output
+// as they are written while it runs. Output is called from another goroutine.
+func (s *PersistentShell) ExecStream(ctx context.Context, command string, timeoutMs int, output func(chunk string)) (string, string, int, bool, error) {
+	if !s.isAlive.Load() {
 		return "", "Shell is not alive", 1, false, errors.New("shell is not alive")
 	}
 
@@ -281,6 +332,7 @@ func (s *PersistentShell) Exec(ctx context.Context, command string, timeoutMs in
 		timeout:    timeout,
 		resultChan: resultChan,
 		ctx:        ctx,
+		output:     output,
 	}
 
 	result := <-resultChan
@@ -291,14 +343,38 @@ func (s *PersistentShell) Close() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if !s.isAlive {
+	if !s.isAlive.Load() {
 		return
 	}
 
 	s.stdin.Write([]byte("exit\n"))
 
 	s.cmd.Process.Kill()
-	s.isAlive = false
+	s.isAlive.Store(false)
+}
+
+// spaceEnvironment returns the script setting the variables of the active
+// space in the shell. The shell outlives space switches, so the variables of
+// every other space are first restored to their process environment value,
+// or unset, for none to leak into the commands of the active space.
+func spaceEnvironment() string {
+	if config.Get() == nil {
+		return ""
+	}
+	variables := config.SpaceVariables()
+	var script strings.Builder
+	for _, name := range config.SpaceVariableNames() {
+		value, ok := variables[name]
+		if !ok {
+			value, ok = os.LookupEnv(name)
+		}
+		if ok {
+			fmt.Fprintf(&script, "export %s=%s\n", name, shellQuote(value))
+		} else {
+			fmt.Fprintf(&script, "unset %s\n", name)
+		}
+	}
+	return script.String()
 }
 
 func shellQuote(s string) string {
@@ -313,6 +389,26 @@ func readFileOrEmpty(path string) string {
 	return string(content)
 }
 
+// fileTail reads what was appended to a file since the last read
+type fileTail struct {
+	path   string
+	offset int64
+}
+
+func (t *fileTail) read() string {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	content, err := io.ReadAll(io.NewSectionReader(f, t.offset, 1<<62))
+	if err != nil {
+		return ""
+	}
+	t.offset += int64(len(content))
+	return string(content)
+}
+
 func fileExists(path string) bool {
 	_, err := os.Stat(path)
 	return err == nil
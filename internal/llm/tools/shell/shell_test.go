@@ -0,0 +1,118 @@
+package shell
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+)
+
+func TestSpaceEnvironmentIsolation(t *testing.T) {
+	t.Setenv("SHARED_VAR", "process")
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
+	cfg.Shell.Path = "/bin/sh"
+	cfg.Shell.Args = []string{}
+	cfg.Spaces = map[string]config.SpaceConfig{
+		"alpha": {Environment: map[string]string{"ALPHA_SECRET": "alpha's value", "SHARED_VAR": "alpha"}},
+		"beta":  {Environment: map[string]string{"BETA_SECRET": "beta"}},
+	}
+	t.Cleanup(func() { config.SetActiveSpace("") })
+
+	shell := newPersistentShell(t.TempDir())
+	if shell == nil {
+		t.Fatal("failed to start the shell")
+	}
+	t.Cleanup(shell.Close)
+
+	run := func(space string) string {
+		t.Helper()
+		if err := config.SetActiveSpace(space); err != nil {
+			t.Fatalf("SetActiveSpace(%q) error = %v", space, err)
+		}
+		stdout, stderr, exitCode, _, err := shell.Exec(context.Background(), `echo "$ALPHA_SECRET|$BETA_SECRET|$SHARED_VAR"`, 5000)
+		if err != nil || exitCode != 0 {
+			t.Fatalf("Exec() = %d, %v: %s", exitCode, err, stderr)
+		}
+		return strings.TrimSpace(stdout)
+	}
+
+	// The same shell runs the commands of every space in turn
+	for _, tc := range []struct{ space, want string }{
+		{"alpha", "alpha's value||alpha"},
+		{"beta", "|beta|process"},
+		{"", "||process"},
+		{"alpha", "alpha's value||alpha"},
+	} {
+		if got := run(tc.space); got != tc.want {
+			t.Errorf("in space %q the command saw %q, want %q", tc.space, got, tc.want)
+		}
+	}
+}
+
+func TestExecStreamCancel(t *testing.T) {
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
+	cfg.Shell.Path = "/bin/sh"
+	cfg.Shell.Args = []string{}
+	dir := t.TempDir()
+	shell := newPersistentShell(dir)
+	if shell == nil {
+		t.Fatal("failed to start the shell")
+	}
+	t.Cleanup(shell.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	chunks := make(chan string, 10)
+	result := make(chan bool, 1)
+	go func() {
+		// The sleep runs in a child of a child of the shell
+		_, _, _, interrupted, _ := shell.ExecStream(ctx, `echo started; sh -c 'echo $$ > child.pid; sleep 30'`, 60000, func(chunk string) {
+			chunks <- chunk
+		})
+		result <- interrupted
+	}()
+
+	select {
+	case chunk := <-chunks:
+		if chunk != "started\n" {
+			t.Errorf("first chunk = %q, want the output written so far", chunk)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no output was streamed while the command runs")
+	}
+
+	var pid int
+	for range 100 {
+		content, _ := os.ReadFile(filepath.Join(dir, "child.pid"))
+		if _, err := fmt.Sscanf(string(content), "%d", &pid); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	select {
+	case interrupted := <-result:
+		if !interrupted {
+			t.Error("cancelling should interrupt the command")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancelling did not stop the command")
+	}
+
+	if pid == 0 {
+		t.Fatal("the child process did not start")
+	}
+	for range 100 {
+		if syscall.Kill(pid, 0) != nil {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("process %d still runs after cancelling", pid)
+}
@@ -161,8 +161,8 @@ func (t *sourcegraphTool) Info() ToolInfo {
 
 func (t *sourcegraphTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
 	var params SourcegraphParams
-	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
-		return NewTextErrorResponse("Failed to parse sourcegraph parameters: " + err.Error()), nil
+	if err := call.BindInput(&params); err != nil {
+		return NewTextErrorResponse(err.Error()), nil
 	}
 
 	if params.Query == "" {
@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 )
 
 type ToolInfo struct {
@@ -10,6 +11,9 @@ type ToolInfo struct {
 	Description string
 	Parameters  map[string]any
 	Required    []string
+	// Destructive tools change or delete the files or state of the system,
+	// they are never offered to run from the tool palette of the TUI
+	Destructive bool
 }
 
 type toolResponseType string
@@ -17,6 +21,7 @@ type toolResponseType string
 type (
 	sessionIDContextKey string
 	messageIDContextKey string
+	agentNameContextKey string
 )
 
 const (
@@ -25,6 +30,8 @@ const (
 
 	SessionIDContextKey sessionIDContextKey = "session_id"
 	MessageIDContextKey messageIDContextKey = "message_id"
+	// AgentNameContextKey is the name of the agent calling the tool
+	AgentNameContextKey agentNameContextKey = "agent_name"
 )
 
 type ToolResponse struct {
@@ -32,6 +39,22 @@ type ToolResponse struct {
 	Content  string           `json:"content"`
 	Metadata string           `json:"metadata,omitempty"`
 	IsError  bool             `json:"is_error"`
+	// Sources are the documents the content quotes, which responses can cite
+	Sources []Source `json:"sources,omitempty"`
+}
+
+// Source is a document chunk quoted by a tool result
+type Source struct {
+	// Location is the path or URL of the document
+	Location string `json:"location"`
+	// StartLine and EndLine are the 1-based range of the chunk, 0 when the
+	// whole document is quoted
+	StartLine int `json:"start_line,omitempty"`
+	EndLine   int `json:"end_line,omitempty"`
+	// Score is the retrieval score of the chunk, 0 when it was not searched
+	Score float64 `json:"score,omitempty"`
+	// Quote is the exact text of the chunk
+	Quote string `json:"quote"`
 }
 
 func NewTextResponse(content string) ToolResponse {
@@ -52,6 +75,12 @@ func WithResponseMetadata(response ToolResponse, metadata any) ToolResponse {
 	return response
 }
 
+// WithResponseSources records the documents the response quotes
+func WithResponseSources(response ToolResponse, sources ...Source) ToolResponse {
+	response.Sources = append(response.Sources, sources...)
+	return response
+}
+
 func NewTextErrorResponse(content string) ToolResponse {
 	return ToolResponse{
 		Type:    ToolResponseTypeText,
@@ -66,6 +95,47 @@ type ToolCall struct {
 	Input string `json:"input"`
 }
 
+// InputError is returned when the input of a tool call cannot be decoded
+type InputError struct {
+	Tool   string
+	CallID string
+	Err    error
+}
+
+func (e *InputError) Error() string {
+	return fmt.Sprintf("error parsing parameters of %s call %s: %v", e.Tool, e.CallID, e.Err)
+}
+
+func (e *InputError) Unwrap() error {
+	return e.Err
+}
+
+// BindInput decodes the JSON input of the call into v. The error is an
+// *InputError naming the tool and call.
+func (tc ToolCall) BindInput(v any) error {
+	if err := json.Unmarshal([]byte(tc.Input), v); err != nil {
+		return &InputError{Tool: tc.Name, CallID: tc.ID, Err: err}
+	}
+	return nil
+}
+
+// BindInputDefault is BindInput with defaults called first, so the fields it
+// sets keep their value unless the input overrides them
+func (tc ToolCall) BindInputDefault(v any, defaults func()) error {
+	defaults()
+	return tc.BindInput(v)
+}
+
+// BindParams is BindInput checking the input against the schema of the params
+// struct v points to first, as DecodeParams does. The *InputError wraps the
+// *ParamsError of an input that breaks the schema.
+func (tc ToolCall) BindParams(v any, strict bool) error {
+	if err := DecodeParams(tc.Input, v, strict); err != nil {
+		return &InputError{Tool: tc.Name, CallID: tc.ID, Err: err}
+	}
+	return nil
+}
+
 type BaseTool interface {
 	Info() ToolInfo
 	Run(ctx context.Context, params ToolCall) (ToolResponse, error)
@@ -82,3 +152,10 @@ func GetContextValues(ctx context.Context) (string, string) {
 	}
 	return sessionID.(string), messageID.(string)
 }
+
+// GetAgentName returns the name of the agent calling the tool, empty when the
+// context does not set it
+func GetAgentName(ctx context.Context) string {
+	name, _ := ctx.Value(AgentNameContextKey).(string)
+	return name
+}
@@ -0,0 +1,65 @@
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type bindParams struct {
+	Path  string `json:"path"`
+	Limit int    `json:"limit"`
+}
+
+func TestBindInput(t *testing.T) {
+	var params bindParams
+	call := ToolCall{ID: "call-1", Name: "view", Input: `{"path":"main.go"}`}
+	require.NoError(t, call.BindInput(&params))
+	assert.Equal(t, "main.go", params.Path)
+
+	call.Input = `{"path":`
+	err := call.BindInput(&params)
+	var inputErr *InputError
+	require.ErrorAs(t, err, &inputErr)
+	assert.Equal(t, "view", inputErr.Tool)
+	assert.Equal(t, "call-1", inputErr.CallID)
+	var syntaxErr *json.SyntaxError
+	assert.ErrorAs(t, err, &syntaxErr)
+}
+
+func TestBindInputDefault(t *testing.T) {
+	var params bindParams
+	call := ToolCall{Name: "view", Input: `{"path":"main.go"}`}
+	require.NoError(t, call.BindInputDefault(&params, func() { params.Limit = 100 }))
+	assert.Equal(t, 100, params.Limit)
+
+	// The input overrides the defaults
+	params = bindParams{}
+	call.Input = `{"path":"main.go","limit":5}`
+	require.NoError(t, call.BindInputDefault(&params, func() { params.Limit = 100 }))
+	assert.Equal(t, 5, params.Limit)
+}
+
+func TestBindParams(t *testing.T) {
+	var params testParams
+	call := ToolCall{ID: "call-1", Name: "manage", Input: `{"action":"list"}`}
+	require.NoError(t, call.BindParams(&params, true))
+	assert.Equal(t, "list", params.Action)
+	assert.Equal(t, 20, params.Limit, "fields left out take their default")
+
+	call.Input = `{"action":"delete"}`
+	err := call.BindParams(&params, true)
+	var inputErr *InputError
+	require.ErrorAs(t, err, &inputErr)
+	assert.Equal(t, "manage", inputErr.Tool)
+	assert.Equal(t, "call-1", inputErr.CallID)
+	var paramsErr *ParamsError
+	require.ErrorAs(t, err, &paramsErr)
+
+	response := NewParamsErrorResponse(err)
+	assert.True(t, response.IsError)
+	assert.Equal(t, paramsErr.Error(), response.Content, "the response holds the violations only")
+	assert.NotEmpty(t, response.Metadata)
+}
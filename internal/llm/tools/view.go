@@ -3,7 +3,6 @@ package tools
 import (
 	"bufio"
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -97,19 +96,20 @@ func (v *viewTool) Info() ToolInfo {
 // Run implements Tool.
 func (v *viewTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
 	var params ViewParams
-	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
-		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	if err := call.BindInputDefault(&params, func() { params.Limit = DefaultReadLimit }); err != nil {
+		return NewTextErrorResponse(err.Error()), nil
 	}
 
 	if params.FilePath == "" {
 		return NewTextErrorResponse("file_path is required"), nil
 	}
 
-	// Handle relative paths
-	filePath := params.FilePath
-	if !filepath.IsAbs(filePath) {
-		filePath = filepath.Join(config.WorkingDirectory(), filePath)
+	// Handle relative and root-qualified paths
+	filePath, err := config.ResolveWorkspacePath(params.FilePath)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
 	}
+	config.NoteWorkspaceFile(filePath)
 
 	// Check if file exists
 	fileInfo, err := os.Stat(filePath)
@@ -154,7 +154,7 @@ func (v *viewTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 			fileInfo.Size(), MaxReadSize)), nil
 	}
 
-	// Set default limit if not provided
+	// A limit given as zero or less reads the default number of lines
 	if params.Limit <= 0 {
 		params.Limit = DefaultReadLimit
 	}
@@ -183,15 +183,28 @@ func (v *viewTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 			params.Offset+len(strings.Split(content, "\n")))
 	}
 	output += "\n</file>\n"
+	// The hash is passed back by the edits expecting this content
+	if hash, err := currentFileHash(filePath); err == nil {
+		output += fmt.Sprintf("File hash: %s\n", hash)
+	}
 	output += getDiagnostics(filePath, v.lspClients)
 	recordFileRead(filePath)
-	return WithResponseMetadata(
+	response := WithResponseMetadata(
 		NewTextResponse(output),
 		ViewResponseMetadata{
 			FilePath: filePath,
 			Content:  content,
 		},
-	), nil
+	)
+	if content == "" {
+		return response, nil
+	}
+	return WithResponseSources(response, Source{
+		Location:  filePath,
+		StartLine: params.Offset + 1,
+		EndLine:   params.Offset + len(strings.Split(content, "\n")),
+		Quote:     content,
+	}), nil
 }
 
 func addLineNumbers(content string, startLine int) string {
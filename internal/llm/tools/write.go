@@ -2,7 +2,6 @@ package tools
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -20,6 +19,9 @@ import (
 type WriteParams struct {
 	FilePath string `json:"file_path"`
 	Content  string `json:"content"`
+	// ExpectedHash is the hash of the file the content replaces, as reported
+	// by the View tool
+	ExpectedHash string `json:"expected_hash,omitempty"`
 }
 
 type WritePermissionsParams struct {
@@ -92,15 +94,20 @@ func (w *writeTool) Info() ToolInfo {
 				"type":        "string",
 				"description": "The content to write to the file",
 			},
+			"expected_hash": map[string]any{
+				"type":        "string",
+				"description": "The file hash reported when the file was last read (optional). The write fails with a conflict when the file has changed since",
+			},
 		},
-		Required: []string{"file_path", "content"},
+		Required:    []string{"file_path", "content"},
+		Destructive: true,
 	}
 }
 
 func (w *writeTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
 	var params WriteParams
-	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
-		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	if err := call.BindInput(&params); err != nil {
+		return NewTextErrorResponse(err.Error()), nil
 	}
 
 	if params.FilePath == "" {
@@ -111,11 +118,20 @@ func (w *writeTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		return NewTextErrorResponse("content is required"), nil
 	}
 
-	filePath := params.FilePath
-	if !filepath.IsAbs(filePath) {
-		filePath = filepath.Join(config.WorkingDirectory(), filePath)
+	filePath, err := config.ResolveWorkspacePath(params.FilePath)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
 	}
+	config.NoteWorkspaceFile(filePath)
+
+	// A retried call is applied once, over the file it was meant for
+	return applyOnce(ctx, w.files, WriteToolName, filePath, params.ExpectedHash, func() (ToolResponse, error) {
+		return w.write(ctx, filePath, params.Content)
+	})
+}
 
+// write replaces the content of the file at filePath with content
+func (w *writeTool) write(ctx context.Context, filePath, content string) (ToolResponse, error) {
 	fileInfo, err := os.Stat(filePath)
 	if err == nil {
 		if fileInfo.IsDir() {
@@ -130,7 +146,7 @@ func (w *writeTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		}
 
 		oldContent, readErr := os.ReadFile(filePath)
-		if readErr == nil && string(oldContent) == params.Content {
+		if readErr == nil && string(oldContent) == content {
 			return NewTextErrorResponse(fmt.Sprintf("File %s already contains the exact content. No changes made.", filePath)), nil
 		}
 	} else if !os.IsNotExist(err) {
@@ -157,7 +173,7 @@ func (w *writeTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 
 	diff, additions, removals := diff.GenerateDiff(
 		oldContent,
-		params.Content,
+		content,
 		filePath,
 	)
 
@@ -183,7 +199,7 @@ func (w *writeTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		return ToolResponse{}, permission.ErrorPermissionDenied
 	}
 
-	err = os.WriteFile(filePath, []byte(params.Content), 0o644)
+	err = os.WriteFile(filePath, []byte(content), 0o644)
 	if err != nil {
 		return ToolResponse{}, fmt.Errorf("error writing file: %w", err)
 	}
@@ -205,7 +221,7 @@ func (w *writeTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		}
 	}
 	// Store the new version
-	_, err = w.files.CreateVersion(ctx, sessionID, filePath, params.Content)
+	_, err = w.files.CreateVersion(ctx, sessionID, filePath, content)
 	if err != nil {
 		logging.Debug("Error creating file history version", "error", err)
 	}
@@ -0,0 +1,67 @@
+package lock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// TUILockFilename is the file in the data directory holding the PID of the
+// running TUI
+const TUILockFilename = "tui.lock"
+
+// AcquireTUILock writes the PID of this process to the TUI lockfile of the
+// data directory, for the commands which must not run alongside the TUI. The
+// returned function removes the lockfile.
+func AcquireTUILock(dataDir string) (func(), error) {
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	path := filepath.Join(dataDir, TUILockFilename)
+	pid := os.Getpid()
+	if err := os.WriteFile(path, []byte(strconv.Itoa(pid)), 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write TUI lockfile: %w", err)
+	}
+	return func() {
+		// Another TUI started since then owns the lockfile
+		if owner, err := readTUILock(path); err == nil && owner == pid {
+			os.Remove(path)
+		}
+	}, nil
+}
+
+// TUIRunning returns the PID of the TUI running on the data directory, false
+// if there is none. A lockfile left behind by a TUI which crashed is ignored.
+func TUIRunning(dataDir string) (int, bool) {
+	pid, err := readTUILock(filepath.Join(dataDir, TUILockFilename))
+	if err != nil || pid == os.Getpid() {
+		return 0, false
+	}
+	return pid, processAlive(pid)
+}
+
+func readTUILock(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+// processAlive reports whether a process with the PID exists, one of another
+// user included
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = proc.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,49 @@
+package lock
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTUILock(t *testing.T) {
+	dataDir := filepath.Join(t.TempDir(), "data")
+	path := filepath.Join(dataDir, TUILockFilename)
+
+	_, running := TUIRunning(dataDir)
+	assert.False(t, running, "no lockfile")
+
+	release, err := AcquireTUILock(dataDir)
+	require.NoError(t, err)
+	_, running = TUIRunning(dataDir)
+	assert.False(t, running, "the lockfile of this process does not keep it from running")
+
+	// The lockfile of the parent process, alive while the test runs
+	require.NoError(t, os.WriteFile(path, []byte(strconv.Itoa(os.Getppid())), 0o644))
+	pid, running := TUIRunning(dataDir)
+	assert.True(t, running)
+	assert.Equal(t, os.Getppid(), pid)
+
+	release()
+	assert.FileExists(t, path, "the lockfile of another process is kept")
+}
+
+func TestTUILockStale(t *testing.T) {
+	dataDir := t.TempDir()
+	cmd := exec.Command("true")
+	require.NoError(t, cmd.Run())
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, TUILockFilename), []byte(strconv.Itoa(cmd.Process.Pid)), 0o644))
+
+	_, running := TUIRunning(dataDir)
+	assert.False(t, running, "the lockfile of an exited process is stale")
+
+	release, err := AcquireTUILock(dataDir)
+	require.NoError(t, err)
+	release()
+	assert.NoFileExists(t, filepath.Join(dataDir, TUILockFilename))
+}
@@ -0,0 +1,295 @@
+// Package lock keeps several instances sharing a data directory from writing
+// to the same session. Each instance registers itself in the database with
+// its PID and refreshes a heartbeat while it runs, and takes the write lock
+// of a session when the session becomes active. A session locked by another
+// live instance is read-only; the lock of an instance whose heartbeat is
+// older than the stale threshold, because it crashed or was killed, is taken
+// over by the next instance asking for it.
+package lock
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/db"
+)
+
+const (
+	// DefaultStaleAfter is how long an instance can go without a heartbeat
+	// before its locks can be taken over
+	DefaultStaleAfter = 30 * time.Second
+)
+
+// ErrSessionLocked is returned when another live instance holds the lock of
+// a session
+var ErrSessionLocked = errors.New("session is open in another instance")
+
+// SessionLock describes the write lock of a session
+type SessionLock struct {
+	SessionID  string    `json:"session_id"`
+	InstanceID string    `json:"instance_id"`
+	PID        int64     `json:"pid"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	Heartbeat  time.Time `json:"heartbeat"`
+	// Owned is whether this instance holds the lock
+	Owned bool `json:"owned"`
+	// Stale is whether the holder stopped sending heartbeats, so the lock
+	// is taken over by the next instance asking for it
+	Stale bool `json:"stale"`
+}
+
+// LockedError is returned when a session is locked by another live instance
+type LockedError struct {
+	Lock SessionLock
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("session is open in another instance (PID %d), it is read-only here", e.Lock.PID)
+}
+
+func (e *LockedError) Is(target error) bool {
+	return target == ErrSessionLocked
+}
+
+// Instance describes a running instance sharing the data directory
+type Instance struct {
+	ID        string    `json:"id"`
+	PID       int64     `json:"pid"`
+	StartedAt time.Time `json:"started_at"`
+	Heartbeat time.Time `json:"heartbeat"`
+	Current   bool      `json:"current"`
+	Stale     bool      `json:"stale"`
+}
+
+// Status is the lock state seen from this instance
+type Status struct {
+	InstanceID string        `json:"instance_id"`
+	StaleAfter string        `json:"stale_after"`
+	Instances  []Instance    `json:"instances"`
+	Sessions   []SessionLock `json:"sessions"`
+}
+
+type Service interface {
+	// Start registers the instance and refreshes its heartbeat until ctx is
+	// done
+	Start(ctx context.Context) error
+	// InstanceID identifies this instance
+	InstanceID() string
+	// Acquire takes the write lock of a session, or takes it over when its
+	// holder is stale. It returns a *LockedError when another live instance
+	// holds it. Acquiring a lock this instance holds succeeds.
+	Acquire(ctx context.Context, sessionID string) error
+	// Release gives up the write lock of a session held by this instance
+	Release(ctx context.Context, sessionID string) error
+	// Status lists the instances and session locks
+	Status(ctx context.Context) (Status, error)
+	// Close releases every lock of the instance and unregisters it
+	Close(ctx context.Context) error
+}
+
+type service struct {
+	q          db.Querier
+	instanceID string
+	pid        int64
+	startedAt  int64
+	staleAfter time.Duration
+	now        func() time.Time
+
+	closeOnce sync.Once
+}
+
+// NewService creates the lock service of this instance. The locks of an
+// instance are stale once it has not sent a heartbeat for staleAfter, and
+// heartbeats are sent three times as often.
+func NewService(q db.Querier, staleAfter time.Duration) Service {
+	if staleAfter <= 0 {
+		staleAfter = DefaultStaleAfter
+	}
+	return &service{
+		q:          q,
+		instanceID: uuid.New().String(),
+		pid:        int64(os.Getpid()),
+		startedAt:  time.Now().Unix(),
+		staleAfter: staleAfter,
+		now:        time.Now,
+	}
+}
+
+func (s *service) InstanceID() string {
+	return s.instanceID
+}
+
+func (s *service) Start(ctx context.Context) error {
+	// Forget the instances that crashed, their locks stay stale until taken
+	// over
+	if err := s.q.DeleteStaleInstances(ctx, s.staleBefore()); err != nil {
+		logging.Warn("Failed to delete stale instances", "error", err)
+	}
+	if err := s.heartbeat(ctx); err != nil {
+		return fmt.Errorf("failed to register instance: %w", err)
+	}
+	go func() {
+		defer logging.RecoverPanic("lock-heartbeat", nil)
+		ticker := time.NewTicker(s.staleAfter / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.heartbeat(ctx); err != nil && ctx.Err() == nil {
+					logging.Warn("Failed to refresh instance heartbeat", "error", err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// heartbeat records that the instance is alive
+func (s *service) heartbeat(ctx context.Context) error {
+	return s.q.UpsertInstance(ctx, db.UpsertInstanceParams{
+		ID:          s.instanceID,
+		Pid:         s.pid,
+		StartedAt:   s.startedAt,
+		HeartbeatAt: s.now().Unix(),
+	})
+}
+
+// staleBefore is the heartbeat time under which an instance is stale
+func (s *service) staleBefore() int64 {
+	return s.now().Add(-s.staleAfter).Unix()
+}
+
+func (s *service) Acquire(ctx context.Context, sessionID string) error {
+	// The heartbeat keeps this instance live for the others, even when the
+	// lock is taken before the background heartbeat runs
+	if err := s.heartbeat(ctx); err != nil {
+		return fmt.Errorf("failed to refresh instance heartbeat: %w", err)
+	}
+	// A lock released between both queries is acquired on the second try
+	for range 2 {
+		acquired, err := s.q.AcquireSessionLock(ctx, db.AcquireSessionLockParams{
+			SessionID:   sessionID,
+			InstanceID:  s.instanceID,
+			AcquiredAt:  s.now().Unix(),
+			StaleBefore: s.staleBefore(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to acquire session lock: %w", err)
+		}
+		if acquired > 0 {
+			return nil
+		}
+
+		row, err := s.q.GetSessionLock(ctx, sessionID)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get session lock: %w", err)
+		}
+		return &LockedError{Lock: s.sessionLock(db.ListSessionLocksRow(row))}
+	}
+	return fmt.Errorf("failed to acquire session lock: %w", ErrSessionLocked)
+}
+
+func (s *service) Release(ctx context.Context, sessionID string) error {
+	return s.q.ReleaseSessionLock(ctx, db.ReleaseSessionLockParams{
+		SessionID:  sessionID,
+		InstanceID: s.instanceID,
+	})
+}
+
+func (s *service) Status(ctx context.Context) (Status, error) {
+	instances, err := s.q.ListInstances(ctx)
+	if err != nil {
+		return Status{}, fmt.Errorf("failed to list instances: %w", err)
+	}
+	locks, err := s.q.ListSessionLocks(ctx)
+	if err != nil {
+		return Status{}, fmt.Errorf("failed to list session locks: %w", err)
+	}
+
+	status := Status{
+		InstanceID: s.instanceID,
+		StaleAfter: s.staleAfter.String(),
+		Instances:  make([]Instance, 0, len(instances)),
+		Sessions:   make([]SessionLock, 0, len(locks)),
+	}
+	staleBefore := s.staleBefore()
+	for _, instance := range instances {
+		status.Instances = append(status.Instances, Instance{
+			ID:        instance.ID,
+			PID:       instance.Pid,
+			StartedAt: time.Unix(instance.StartedAt, 0),
+			Heartbeat: time.Unix(instance.HeartbeatAt, 0),
+			Current:   instance.ID == s.instanceID,
+			Stale:     instance.HeartbeatAt < staleBefore,
+		})
+	}
+	for _, row := range locks {
+		status.Sessions = append(status.Sessions, s.sessionLock(row))
+	}
+	return status, nil
+}
+
+func (s *service) sessionLock(row db.ListSessionLocksRow) SessionLock {
+	return SessionLock{
+		SessionID:  row.SessionID,
+		InstanceID: row.InstanceID,
+		PID:        row.Pid,
+		AcquiredAt: time.Unix(row.AcquiredAt, 0),
+		Heartbeat:  time.Unix(row.HeartbeatAt, 0),
+		Owned:      row.InstanceID == s.instanceID,
+		Stale:      row.InstanceID != s.instanceID && row.HeartbeatAt < s.staleBefore(),
+	}
+}
+
+func (s *service) Close(ctx context.Context) error {
+	var err error
+	s.closeOnce.Do(func() {
+		if err = s.q.ReleaseInstanceSessionLocks(ctx, s.instanceID); err != nil {
+			return
+		}
+		err = s.q.DeleteInstance(ctx, s.instanceID)
+	})
+	return err
+}
+
+var (
+	currentMu sync.RWMutex
+	current   Service
+)
+
+// SetCurrent makes service the one reported by CurrentStatus
+func SetCurrent(service Service) {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	current = service
+}
+
+// CurrentStatus returns the lock state of the running instance, or nil when
+// it has no lock service
+func CurrentStatus(ctx context.Context) *Status {
+	currentMu.RLock()
+	service := current
+	currentMu.RUnlock()
+	if service == nil {
+		return nil
+	}
+	status, err := service.Status(ctx)
+	if err != nil {
+		logging.Warn("Failed to get lock status", "error", err)
+		return nil
+	}
+	return &status
+}
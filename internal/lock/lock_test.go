@@ -0,0 +1,113 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/db"
+	"github.com/caronex/intelligence-interface/internal/session"
+)
+
+// newInstances returns the lock services of two instances sharing a data
+// directory, each with its own database connection, and a session to contend
+// for
+func newInstances(t *testing.T) (*service, *service, string) {
+	t.Helper()
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
+	cfg.Data.Directory = t.TempDir()
+
+	open := func() db.Querier {
+		conn, err := db.Connect()
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+		return db.New(conn)
+	}
+	first, second := open(), open()
+
+	sess, err := session.NewService(first).Create(context.Background(), "shared")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	a := NewService(first, time.Minute).(*service)
+	b := NewService(second, time.Minute).(*service)
+	require.NoError(t, a.Start(ctx))
+	require.NoError(t, b.Start(ctx))
+	return a, b, sess.ID
+}
+
+func TestAcquireContention(t *testing.T) {
+	a, b, sessionID := newInstances(t)
+	ctx := context.Background()
+
+	require.NoError(t, a.Acquire(ctx, sessionID))
+	require.NoError(t, a.Acquire(ctx, sessionID), "acquiring a held lock again succeeds")
+
+	err := b.Acquire(ctx, sessionID)
+	require.ErrorIs(t, err, ErrSessionLocked)
+	var locked *LockedError
+	require.True(t, errors.As(err, &locked))
+	assert.Equal(t, a.InstanceID(), locked.Lock.InstanceID)
+	assert.Equal(t, a.pid, locked.Lock.PID)
+	assert.False(t, locked.Lock.Owned)
+	assert.False(t, locked.Lock.Stale)
+
+	require.NoError(t, b.Release(ctx, sessionID), "releasing a lock held by another instance does nothing")
+	require.ErrorIs(t, b.Acquire(ctx, sessionID), ErrSessionLocked)
+
+	require.NoError(t, a.Release(ctx, sessionID))
+	require.NoError(t, b.Acquire(ctx, sessionID))
+	require.ErrorIs(t, a.Acquire(ctx, sessionID), ErrSessionLocked)
+}
+
+func TestAcquireTakesOverStaleLock(t *testing.T) {
+	a, b, sessionID := newInstances(t)
+	ctx := context.Background()
+	require.NoError(t, a.Acquire(ctx, sessionID))
+
+	// a stops sending heartbeats: once the threshold has passed for b, the
+	// lock is stale and b takes it over
+	later := time.Now().Add(2 * time.Minute)
+	b.now = func() time.Time { return later }
+
+	status, err := b.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, status.Sessions, 1)
+	assert.True(t, status.Sessions[0].Stale)
+
+	require.NoError(t, b.Acquire(ctx, sessionID))
+	status, err = b.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, status.Sessions, 1)
+	assert.Equal(t, b.InstanceID(), status.Sessions[0].InstanceID)
+	assert.True(t, status.Sessions[0].Owned)
+
+	// b is live, so a coming back finds the session read-only
+	a.now = func() time.Time { return later }
+	require.ErrorIs(t, a.Acquire(ctx, sessionID), ErrSessionLocked)
+}
+
+func TestCloseReleasesLocks(t *testing.T) {
+	a, b, sessionID := newInstances(t)
+	ctx := context.Background()
+	require.NoError(t, a.Acquire(ctx, sessionID))
+
+	status, err := b.Status(ctx)
+	require.NoError(t, err)
+	assert.Len(t, status.Instances, 2)
+	assert.Equal(t, b.InstanceID(), status.InstanceID)
+
+	require.NoError(t, a.Close(ctx))
+	status, err = b.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, status.Instances, 1)
+	assert.True(t, status.Instances[0].Current)
+	assert.Empty(t, status.Sessions)
+	require.NoError(t, b.Acquire(ctx, sessionID))
+}
@@ -0,0 +1,178 @@
+// Package memory keeps the learning observations of agents. With the
+// "session" knowledge retention they live in memory until the process exits;
+// with "persistent" they are stored in the agent_memory table of the database
+// and kept across restarts. Either way only the most recently accessed
+// observations of each agent are kept, up to the learning history limit.
+//
+// Agents note observations with the learn tool, and the observations of an
+// agent are recalled into its system prompt.
+package memory
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/db"
+	"github.com/google/uuid"
+)
+
+// Observation is something an agent learned
+type Observation struct {
+	ID      string
+	Agent   config.AgentName
+	Content string
+	// CreatedAt and AccessedAt are Unix timestamps in seconds, AccessedAt
+	// being when the observation was last recalled
+	CreatedAt  int64
+	AccessedAt int64
+}
+
+// Service is the agent memory
+type Service interface {
+	// Remember stores an observation of an agent
+	Remember(ctx context.Context, agent config.AgentName, content string) (Observation, error)
+	// Recall returns the most recently accessed observations of an agent, up
+	// to the learning history limit, and marks them accessed
+	Recall(ctx context.Context, agent config.AgentName) ([]Observation, error)
+	// Prune drops the observations stored beyond the learning history limit
+	// of each agent, the least recently accessed ones, and returns how many
+	Prune(ctx context.Context) (int64, error)
+}
+
+type service struct {
+	q db.Querier
+
+	mu sync.Mutex
+	// session holds the observations of the session retention by agent,
+	// most recently accessed first
+	session map[config.AgentName][]Observation
+}
+
+// NewService creates the agent memory, the retention and limit of which are
+// read from the learning configuration on every call
+func NewService(q db.Querier) Service {
+	return &service{q: q, session: make(map[config.AgentName][]Observation)}
+}
+
+func learning() config.LearningConfig {
+	if cfg := config.Get(); cfg != nil {
+		return cfg.Caronex.Learning
+	}
+	return config.LearningConfig{}
+}
+
+func (s *service) Remember(ctx context.Context, agent config.AgentName, content string) (Observation, error) {
+	cfg := learning()
+	if cfg.Persistent() {
+		id := uuid.New().String()
+		err := s.q.CreateAgentMemory(ctx, db.CreateAgentMemoryParams{ID: id, Agent: string(agent), Content: content})
+		if err != nil {
+			return Observation{}, err
+		}
+		now := time.Now().Unix()
+		return Observation{ID: id, Agent: agent, Content: content, CreatedAt: now, AccessedAt: now}, nil
+	}
+
+	now := time.Now().Unix()
+	observation := Observation{ID: uuid.New().String(), Agent: agent, Content: content, CreatedAt: now, AccessedAt: now}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	observations := append([]Observation{observation}, s.session[agent]...)
+	if limit := cfg.HistoryLimit(); len(observations) > limit {
+		observations = observations[:limit]
+	}
+	s.session[agent] = observations
+	return observation, nil
+}
+
+func (s *service) Recall(ctx context.Context, agent config.AgentName) ([]Observation, error) {
+	cfg := learning()
+	now := time.Now().Unix()
+	if cfg.Persistent() {
+		rows, err := s.q.ListRecentAgentMemory(ctx, db.ListRecentAgentMemoryParams{
+			Agent: string(agent),
+			Limit: int64(cfg.HistoryLimit()),
+		})
+		if err != nil {
+			return nil, err
+		}
+		observations := make([]Observation, 0, len(rows))
+		for _, row := range rows {
+			if err := s.q.TouchAgentMemory(ctx, row.ID); err != nil {
+				return nil, err
+			}
+			observations = append(observations, Observation{
+				ID:         row.ID,
+				Agent:      config.AgentName(row.Agent),
+				Content:    row.Content,
+				CreatedAt:  row.CreatedAt,
+				AccessedAt: now,
+			})
+		}
+		return observations, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	observations := s.session[agent]
+	if limit := cfg.HistoryLimit(); len(observations) > limit {
+		observations = observations[:limit]
+	}
+	for i := range observations {
+		observations[i].AccessedAt = now
+	}
+	return append([]Observation(nil), observations...), nil
+}
+
+func (s *service) Prune(ctx context.Context) (int64, error) {
+	return s.q.PruneAgentMemory(ctx, int64(learning().HistoryLimit()))
+}
+
+var (
+	currentMu sync.RWMutex
+	current   Service
+)
+
+// SetCurrent makes service the memory the learn tool stores observations in
+// and the system prompts recall them from
+func SetCurrent(service Service) {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	current = service
+}
+
+// Current returns the memory of the running instance, or nil when it has none
+func Current() Service {
+	currentMu.RLock()
+	defer currentMu.RUnlock()
+	return current
+}
+
+// PromptContext recalls the observations of an agent from the current memory
+// as a markdown list, the most recently accessed first, up to maxBytes. It is
+// empty when learning is disabled or the agent has no observations.
+func PromptContext(ctx context.Context, agent config.AgentName, maxBytes int) string {
+	service := Current()
+	if service == nil || !learning().Enabled {
+		return ""
+	}
+	observations, err := service.Recall(ctx, agent)
+	if err != nil {
+		logging.Warn("Failed to recall the agent memory", "agent", agent, "error", err)
+		return ""
+	}
+
+	var b strings.Builder
+	for _, observation := range observations {
+		line := "- " + strings.Join(strings.Fields(observation.Content), " ") + "\n"
+		if b.Len()+len(line) > maxBytes {
+			break
+		}
+		b.WriteString(line)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
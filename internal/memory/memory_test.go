@@ -0,0 +1,106 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func contents(observations []Observation) []string {
+	var contents []string
+	for _, o := range observations {
+		contents = append(contents, o.Content)
+	}
+	return contents
+}
+
+func newQueries(t *testing.T, retention string, limit int) db.Querier {
+	t.Helper()
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
+	cfg.Data.Directory = t.TempDir()
+	cfg.Caronex.Learning.KnowledgeRetention = retention
+	cfg.Caronex.Learning.LearningHistoryLimit = limit
+
+	conn, err := db.Connect()
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return db.New(conn)
+}
+
+func TestPersistentMemory(t *testing.T) {
+	q := newQueries(t, config.KnowledgeRetentionPersistent, 2)
+	ctx := context.Background()
+
+	memory := NewService(q)
+	for _, content := range []string{"tests use testify", "migrations use goose", "errors are wrapped"} {
+		_, err := memory.Remember(ctx, "coder", content)
+		require.NoError(t, err)
+	}
+	_, err := memory.Remember(ctx, "task", "plans have four steps")
+	require.NoError(t, err)
+
+	// The observations are kept across restarts, up to the limit
+	memory = NewService(q)
+	recalled, err := memory.Recall(ctx, "coder")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"errors are wrapped", "migrations use goose"}, contents(recalled))
+
+	pruned, err := memory.Prune(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), pruned, "only the least recently accessed observation is beyond the limit")
+	rows, err := q.ListRecentAgentMemory(ctx, db.ListRecentAgentMemoryParams{Agent: "coder", Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, rows, 2)
+	recalled, err = memory.Recall(ctx, "task")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"plans have four steps"}, contents(recalled))
+}
+
+func TestSessionMemory(t *testing.T) {
+	q := newQueries(t, config.KnowledgeRetentionSession, 2)
+	ctx := context.Background()
+
+	memory := NewService(q)
+	for _, content := range []string{"tests use testify", "migrations use goose", "errors are wrapped"} {
+		_, err := memory.Remember(ctx, "coder", content)
+		require.NoError(t, err)
+	}
+	recalled, err := memory.Recall(ctx, "coder")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"errors are wrapped", "migrations use goose"}, contents(recalled))
+
+	recalled, err = NewService(q).Recall(ctx, "coder")
+	require.NoError(t, err)
+	assert.Empty(t, recalled, "the observations are not kept across restarts")
+	rows, err := q.ListRecentAgentMemory(ctx, db.ListRecentAgentMemoryParams{Agent: "coder", Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, rows)
+}
+
+func TestPromptContext(t *testing.T) {
+	q := newQueries(t, config.KnowledgeRetentionSession, 10)
+	ctx := context.Background()
+	assert.Empty(t, PromptContext(ctx, "coder", 1000), "there is no memory without a current one")
+
+	memory := NewService(q)
+	SetCurrent(memory)
+	t.Cleanup(func() { SetCurrent(nil) })
+	for _, content := range []string{"tests use testify", "errors are\nwrapped"} {
+		_, err := memory.Remember(ctx, "coder", content)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, "- errors are wrapped\n- tests use testify", PromptContext(ctx, "coder", 1000))
+	assert.Equal(t, "- errors are wrapped", PromptContext(ctx, "coder", 30), "observations beyond the budget are left out")
+	assert.Empty(t, PromptContext(ctx, "task", 1000))
+
+	require.NoError(t, config.Update(func(cfg *config.Config) error {
+		cfg.Caronex.Learning.Enabled = false
+		return nil
+	}))
+	assert.Empty(t, PromptContext(ctx, "coder", 1000), "learning is disabled")
+}
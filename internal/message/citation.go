@@ -0,0 +1,94 @@
+package message
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// citationPattern matches the references to sources in a response, [n]
+// being the source of index n
+var citationPattern = regexp.MustCompile(`\[(\d+)\]`)
+
+// CitedIndexes returns the source indexes text references, in the order they
+// first appear
+func CitedIndexes(text string) []int {
+	var indexes []int
+	for _, match := range citationPattern.FindAllStringSubmatch(text, -1) {
+		index, err := strconv.Atoi(match[1])
+		if err != nil || index <= 0 || slices.Contains(indexes, index) {
+			continue
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes
+}
+
+// Sources returns the sources msgs quoted, by index
+func Sources(msgs []Message) []Citation {
+	byIndex := make(map[int]Citation)
+	for _, msg := range msgs {
+		for _, citation := range msg.Citations() {
+			byIndex[citation.Index] = citation
+		}
+	}
+	sources := make([]Citation, 0, len(byIndex))
+	for _, citation := range byIndex {
+		sources = append(sources, citation)
+	}
+	slices.SortFunc(sources, func(a, b Citation) int { return a.Index - b.Index })
+	return sources
+}
+
+// NextCitationIndex returns the index of the next source quoted after msgs
+func NextCitationIndex(msgs []Message) int {
+	next := 1
+	for _, msg := range msgs {
+		for _, citation := range msg.Citations() {
+			next = max(next, citation.Index+1)
+		}
+	}
+	return next
+}
+
+// ResolveCitations returns the sources text references, in the order they
+// first appear. References to unknown sources are ignored.
+func ResolveCitations(text string, sources []Citation) []Citation {
+	var cited []Citation
+	for _, index := range CitedIndexes(text) {
+		i := slices.IndexFunc(sources, func(c Citation) bool { return c.Index == index })
+		if i >= 0 {
+			cited = append(cited, sources[i])
+		}
+	}
+	return cited
+}
+
+// FormatSources lists sources for the model, one "[n] label" per line
+func FormatSources(sources []Citation) string {
+	lines := make([]string, 0, len(sources))
+	for _, source := range sources {
+		lines = append(lines, fmt.Sprintf("[%d] %s", source.Index, source.Label()))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// SourcesMarkdown renders the sources a message cites as a Markdown section
+// of numbered footnotes, "" when it cites none
+func SourcesMarkdown(citations []Citation) string {
+	if len(citations) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("**Sources**\n\n")
+	for _, citation := range citations {
+		fmt.Fprintf(&b, "- [%d] `%s`", citation.Index, citation.Label())
+		if citation.Score > 0 {
+			fmt.Fprintf(&b, " (score %.2f)", citation.Score)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
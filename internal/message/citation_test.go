@@ -0,0 +1,97 @@
+package message
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveCitations(t *testing.T) {
+	sources := []Citation{
+		{Index: 1, Source: "docs/a.md", StartLine: 3, EndLine: 8, Quote: "a"},
+		{Index: 2, Source: "https://example.com/b", Quote: "b"},
+		{Index: 3, Source: "docs/c.md", StartLine: 4, EndLine: 4, Quote: "c"},
+	}
+
+	text := "Both agree [3], see also [1] and [3] again, but not [7] or [0]."
+	assert.Equal(t, []int{3, 1, 7}, CitedIndexes(text))
+	cited := ResolveCitations(text, sources)
+	require.Len(t, cited, 2)
+	assert.Equal(t, "docs/c.md:4", cited[0].Label())
+	assert.Equal(t, "docs/a.md:3-8", cited[1].Label())
+	assert.Equal(t, "https://example.com/b", sources[1].Label())
+
+	assert.Empty(t, ResolveCitations("No references.", sources))
+}
+
+func TestSources(t *testing.T) {
+	msgs := []Message{
+		{Role: User, Parts: []ContentPart{TextContent{Text: "question"}}},
+		{Role: Tool, Parts: []ContentPart{
+			ToolResult{ToolCallID: "call-1"},
+			Citation{Index: 2, ToolCallID: "call-1", Source: "b.md"},
+			Citation{Index: 1, ToolCallID: "call-1", Source: "a.md"},
+		}},
+		{Role: Assistant, Parts: []ContentPart{TextContent{Text: "answer [2]"}, Citation{Index: 2, Source: "b.md"}}},
+	}
+
+	sources := Sources(msgs)
+	require.Len(t, sources, 2)
+	assert.Equal(t, 1, sources[0].Index)
+	assert.Equal(t, 2, sources[1].Index)
+	assert.Equal(t, 3, NextCitationIndex(msgs))
+	assert.Equal(t, 1, NextCitationIndex(msgs[:1]))
+	assert.Equal(t, "[1] a.md\n[2] b.md", FormatSources(sources))
+}
+
+func TestCitationPartsRoundTrip(t *testing.T) {
+	citation := Citation{Index: 4, ToolCallID: "call-1", Source: "a.md", StartLine: 1, EndLine: 9, Score: 0.75, Quote: "package a"}
+	data, err := marshallParts([]ContentPart{TextContent{Text: "see [4]"}, citation})
+	require.NoError(t, err)
+	parts, err := unmarshallParts(data)
+	require.NoError(t, err)
+	assert.Equal(t, []ContentPart{TextContent{Text: "see [4]"}, citation}, parts)
+
+	msg := Message{Parts: parts}
+	msg.SetCitations(nil)
+	assert.Empty(t, msg.Citations())
+	assert.Len(t, msg.Parts, 1)
+}
+
+func TestTranscript(t *testing.T) {
+	msgs := []Message{
+		{Role: User, Parts: []ContentPart{TextContent{Text: "What does a.md say?"}}},
+		{Role: Assistant, Parts: []ContentPart{ToolCall{ID: "call-1", Name: "view"}}},
+		{Role: Tool, Parts: []ContentPart{
+			ToolResult{ToolCallID: "call-1", Content: "package a"},
+			Citation{Index: 1, ToolCallID: "call-1", Source: "a.md", StartLine: 1, EndLine: 2, Quote: "```go\npackage a\n```"},
+		}},
+		{Role: Assistant, Parts: []ContentPart{
+			TextContent{Text: "It declares package a [1]."},
+			Citation{Index: 1, ToolCallID: "call-1", Source: "a.md", StartLine: 1, EndLine: 2, Quote: "```go\npackage a\n```"},
+		}},
+	}
+
+	transcript := Transcript("Reading a.md", msgs, "")
+	assert.True(t, strings.HasPrefix(transcript, "# Reading a.md\n\n## User\n\nWhat does a.md say?\n"), transcript)
+	assert.Contains(t, transcript, "*Called `view`*")
+	assert.Contains(t, transcript, "It declares package a [1].")
+	assert.Contains(t, transcript, "## Sources\n\n<details>\n<summary>[1] a.md:1-2</summary>\n\n````\n```go\npackage a\n```\n````\n")
+
+	assert.NotContains(t, Transcript("Chat", msgs[:1], ""), "## Sources")
+}
+
+func TestTranscriptArtifacts(t *testing.T) {
+	msgs := []Message{
+		{Role: User, Parts: []ContentPart{TextContent{Text: "Write the schema"}}},
+		{Role: Assistant, Parts: []ContentPart{
+			TextContent{Text: "```sql filename=db/schema.sql\nCREATE TABLE t (id INTEGER);\n```"},
+			Artifact{Name: "schema.sql", Hint: "db/schema.sql", Language: "sql", Size: 29},
+			Artifact{Name: "notes 2.md", Language: "markdown", Size: 2048},
+		}},
+	}
+	transcript := Transcript("Schema", msgs, "session-artifacts")
+	assert.Contains(t, transcript, "*Saved [schema.sql](session-artifacts/schema.sql), [notes 2.md](session-artifacts/notes%202.md)*")
+}
@@ -2,6 +2,7 @@ package message
 
 import (
 	"encoding/base64"
+	"fmt"
 	"slices"
 	"time"
 
@@ -22,15 +23,28 @@ type FinishReason string
 const (
 	FinishReasonEndTurn          FinishReason = "end_turn"
 	FinishReasonMaxTokens        FinishReason = "max_tokens"
+	FinishReasonContentFilter    FinishReason = "content_filter"
 	FinishReasonToolUse          FinishReason = "tool_use"
 	FinishReasonCanceled         FinishReason = "canceled"
 	FinishReasonError            FinishReason = "error"
 	FinishReasonPermissionDenied FinishReason = "permission_denied"
+	// FinishReasonTimeout is set when the provider did not answer in time,
+	// the finish message telling which timeout passed
+	FinishReasonTimeout FinishReason = "timeout"
+	// FinishReasonInterrupted is set on startup on a response the process
+	// exited in the middle of, keeping the content stored until then
+	FinishReasonInterrupted FinishReason = "interrupted"
 
 	// Should never happen
 	FinishReasonUnknown FinishReason = "unknown"
 )
 
+// Truncated reports whether the response was cut off before the model
+// finished it, by the token limit or a content filter
+func (r FinishReason) Truncated() bool {
+	return r == FinishReasonMaxTokens || r == FinishReasonContentFilter
+}
+
 type ContentPart interface {
 	isPart()
 }
@@ -104,10 +118,276 @@ func (ToolResult) isPart() {}
 type Finish struct {
 	Reason FinishReason `json:"reason"`
 	Time   int64        `json:"time"`
+	// Message details the reason, such as the timeout that passed
+	Message string `json:"message,omitempty"`
+	// Error describes the provider error the message finished with
+	Error *FinishError `json:"error,omitempty"`
 }
 
 func (Finish) isPart() {}
 
+// FinishError is the provider error a message finished with, described for
+// users
+type FinishError struct {
+	// Category is the kind of failure, such as auth or rate-limit
+	Category string `json:"category"`
+	Title    string `json:"title"`
+	// Hint says how to fix the error
+	Hint string `json:"hint,omitempty"`
+	// Details is the raw error of the provider
+	Details string `json:"details,omitempty"`
+}
+
+// Review records the review a response went through before it was presented
+type Review struct {
+	// SessionID is the session holding the passes of the review
+	SessionID string `json:"session_id"`
+	// Reviewer is the agent that critiqued the response
+	Reviewer string `json:"reviewer"`
+	// Found is the number of issues of the first critique, Open the number
+	// left after the last revision
+	Found int `json:"found"`
+	Open  int `json:"open"`
+	// Revisions is the number of times the response was revised
+	Revisions int `json:"revisions"`
+	// Summary is the reviewer's summary of the last critique
+	Summary string `json:"summary,omitempty"`
+	// Critique is the full text of every critique, in order
+	Critique string `json:"critique,omitempty"`
+	// BudgetExceeded is set when a pass went over its token budget, which
+	// ended the review early
+	BudgetExceeded bool `json:"budget_exceeded,omitempty"`
+}
+
+func (Review) isPart() {}
+
+// Comparison records the responses of two models to the same message, each
+// generated in a hidden branch of the session, and which of them continues
+// the session
+type Comparison struct {
+	Candidates []ComparisonCandidate `json:"candidates"`
+	// Chosen is the index of the candidate the session continues with, -1
+	// until one is picked, in which case the first one stands
+	Chosen int `json:"chosen"`
+}
+
+func (Comparison) isPart() {}
+
+// Kept returns the index of the candidate the session continues with
+func (c Comparison) Kept() int {
+	if c.Chosen < 0 || c.Chosen >= len(c.Candidates) {
+		return 0
+	}
+	return c.Chosen
+}
+
+// ComparisonCandidate is the response of one model of a comparison
+type ComparisonCandidate struct {
+	Model models.ModelID `json:"model"`
+	// SessionID is the branch session holding the response
+	SessionID string `json:"session_id"`
+	Content   string `json:"content"`
+	// Error is set when the model failed to respond
+	Error        string  `json:"error,omitempty"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	Cost         float64 `json:"cost"`
+	LatencyMs    int64   `json:"latency_ms"`
+}
+
+// Stats returns the usage and latency of the candidate, such as
+// "412 tokens · $0.0031 · 2.4s"
+func (c ComparisonCandidate) Stats() string {
+	return fmt.Sprintf("%d tokens · $%.4f · %.1fs", c.InputTokens+c.OutputTokens, c.Cost, float64(c.LatencyMs)/1000)
+}
+
+// Fixed returns the number of issues the revisions fixed
+func (r Review) Fixed() int {
+	return max(r.Found-r.Open, 0)
+}
+
+// Badge returns a compact status of the review, such as
+// "reviewed ✓ (2 issues fixed)"
+func (r Review) Badge() string {
+	plural := func(n int, what string) string {
+		if n == 1 {
+			return fmt.Sprintf("%d %s", n, what)
+		}
+		return fmt.Sprintf("%d %ss", n, what)
+	}
+	switch {
+	case r.Open > 0:
+		return fmt.Sprintf("reviewed ! (%s fixed, %d open)", plural(r.Fixed(), "issue"), r.Open)
+	case r.Found == 0:
+		return "reviewed ✓ (no issues)"
+	default:
+		return fmt.Sprintf("reviewed ✓ (%s fixed)", plural(r.Fixed(), "issue"))
+	}
+}
+
+// Citation is a source a tool result quoted, such as a file chunk or a
+// fetched page. Tool messages hold the sources their results quoted,
+// responses the ones they cite, and summaries the sources of the
+// conversation they replace.
+type Citation struct {
+	// Index is the number responses cite the source with, as [Index], unique
+	// in the session
+	Index int `json:"index"`
+	// ToolCallID is the tool call whose result quoted the source
+	ToolCallID string `json:"tool_call_id,omitempty"`
+	// Source is the path or URL of the document
+	Source string `json:"source"`
+	// StartLine and EndLine are the 1-based range of the quoted chunk, 0 when
+	// the whole document was quoted
+	StartLine int `json:"start_line,omitempty"`
+	EndLine   int `json:"end_line,omitempty"`
+	// Score is the retrieval score of the chunk, 0 when it was not searched
+	Score float64 `json:"score,omitempty"`
+	// Quote is the exact text of the chunk
+	Quote string `json:"quote"`
+}
+
+func (Citation) isPart() {}
+
+// Label returns the source and its chunk range, such as "main.go:10-20"
+func (c Citation) Label() string {
+	switch {
+	case c.StartLine > 0 && c.EndLine > c.StartLine:
+		return fmt.Sprintf("%s:%d-%d", c.Source, c.StartLine, c.EndLine)
+	case c.StartLine > 0:
+		return fmt.Sprintf("%s:%d", c.Source, c.StartLine)
+	default:
+		return c.Source
+	}
+}
+
+// FileReference is a file or directory the user mentioned with @ in a
+// message, along with the content sent to the agent for it
+type FileReference struct {
+	// Path is relative to the working directory, with forward slashes
+	Path string `json:"path"`
+	// Dir is set for directories, whose content is a listing of their entries
+	Dir bool `json:"dir,omitempty"`
+	// Content is the content of the file, or the listing of the directory
+	Content string `json:"content,omitempty"`
+	// Size is the size of the file in bytes
+	Size int64 `json:"size,omitempty"`
+	// Notice tells how the content was cut, or why it was left out
+	Notice string `json:"notice,omitempty"`
+}
+
+func (FileReference) isPart() {}
+
+// Artifact is a code block of a response saved as a file of the session
+type Artifact struct {
+	// Name is the name of the file in the artifact directory of the session
+	Name string `json:"name"`
+	// Hint is the file name the response tagged the block with, "" when the
+	// block was saved for its size
+	Hint string `json:"hint,omitempty"`
+	// Language is the language of the block, "" when it has none
+	Language string `json:"language,omitempty"`
+	// Size is the size of the file in bytes
+	Size int64 `json:"size"`
+}
+
+func (Artifact) isPart() {}
+
+// How the files of workspace changes changed
+const (
+	FileCreated  = "created"
+	FileModified = "modified"
+	FileDeleted  = "deleted"
+)
+
+// FileChange is a file of the workspace an autonomous run created, modified
+// or deleted
+type FileChange struct {
+	// Path is relative to the working directory, or qualified with the name
+	// of its workspace root
+	Path   string `json:"path"`
+	Change string `json:"change"`
+	// Additions and Removals count the lines added and removed
+	Additions int `json:"additions"`
+	Removals  int `json:"removals"`
+	// Diff is the unified diff of the file, empty for binary files and files
+	// too large to compare
+	Diff string `json:"diff,omitempty"`
+	// DiffCut is set when Diff holds the first lines of the diff only
+	DiffCut bool `json:"diff_cut,omitempty"`
+	Binary  bool `json:"binary,omitempty"`
+}
+
+// WorkspaceChanges summarizes what an auto mode or delegated run changed in
+// the workspace, from a snapshot taken when it started
+type WorkspaceChanges struct {
+	Files []FileChange `json:"files"`
+	// Summary describes the changes, as written by the summarizer from the
+	// diff
+	Summary string `json:"summary,omitempty"`
+	// Patch is the path of the file holding the full diff, in the session
+	// directory
+	Patch string `json:"patch,omitempty"`
+	// Truncated is set when the workspace had too many files to be compared
+	// whole
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+func (WorkspaceChanges) isPart() {}
+
+// ContextSource is a source of context the system prompt can include
+type ContextSource string
+
+const (
+	// ContextFiles are the project instruction files, such as CLAUDE.md
+	ContextFiles ContextSource = "context_files"
+	// MemoryNotes are the notes kept in the workspace memory
+	MemoryNotes ContextSource = "memory_notes"
+)
+
+// KnownContextSources are every source of context a system prompt can include
+var KnownContextSources = []ContextSource{ContextFiles, MemoryNotes}
+
+// ContextSources records which sources of context the system prompt of a
+// message included, so it is sent the same way when retried
+type ContextSources struct {
+	Included []ContextSource `json:"included,omitempty"`
+	Excluded []ContextSource `json:"excluded,omitempty"`
+}
+
+func (ContextSources) isPart() {}
+
+// Totals returns the numbers of lines added and removed in all the files
+func (c WorkspaceChanges) Totals() (additions, removals int) {
+	for _, file := range c.Files {
+		additions += file.Additions
+		removals += file.Removals
+	}
+	return additions, removals
+}
+
+// Stat describes the changes in a line, such as "3 files changed, +12 -4"
+func (c WorkspaceChanges) Stat() string {
+	additions, removals := c.Totals()
+	files := "files"
+	if len(c.Files) == 1 {
+		files = "file"
+	}
+	return fmt.Sprintf("%d %s changed, +%d -%d", len(c.Files), files, additions, removals)
+}
+
+// WithoutDiffs returns the changes without the diffs of the files, as they
+// are recorded along with the delegations
+func (c WorkspaceChanges) WithoutDiffs() WorkspaceChanges {
+	files := make([]FileChange, len(c.Files))
+	for i, file := range c.Files {
+		file.Diff, file.DiffCut = "", false
+		files[i] = file
+	}
+	c.Files = files
+	return c
+}
+
 type Message struct {
 	ID        string
 	Role      MessageRole
@@ -116,8 +396,24 @@ type Message struct {
 	Model     models.ModelID
 	CreatedAt int64
 	UpdatedAt int64
+	// Status is stored ahead of each step of the response, so one the
+	// process exited in the middle of is found on the next start
+	Status MessageStatus
 }
 
+// MessageStatus is where a message is in its lifecycle
+type MessageStatus string
+
+const (
+	// StatusInProgress is a response being streamed, or whose tool calls
+	// have no stored result yet
+	StatusInProgress MessageStatus = "in_progress"
+	StatusFinished   MessageStatus = "finished"
+	// StatusInterrupted is a response the process exited in the middle of,
+	// finalized on the next start
+	StatusInterrupted MessageStatus = "interrupted"
+)
+
 func (m *Message) Content() TextContent {
 	for _, part := range m.Parts {
 		if c, ok := part.(TextContent); ok {
@@ -194,6 +490,96 @@ func (m *Message) FinishPart() *Finish {
 	return nil
 }
 
+// Review returns the review of the response, nil when it was not reviewed
+func (m *Message) Review() *Review {
+	for _, part := range m.Parts {
+		if c, ok := part.(Review); ok {
+			return &c
+		}
+	}
+	return nil
+}
+
+// Comparison returns the model comparison the response comes from, nil when
+// it was not compared
+func (m *Message) Comparison() *Comparison {
+	for _, part := range m.Parts {
+		if c, ok := part.(Comparison); ok {
+			return &c
+		}
+	}
+	return nil
+}
+
+// WorkspaceChanges returns the workspace changes the message reports, nil
+// when it reports none
+func (m *Message) WorkspaceChanges() *WorkspaceChanges {
+	for _, part := range m.Parts {
+		if c, ok := part.(WorkspaceChanges); ok {
+			return &c
+		}
+	}
+	return nil
+}
+
+// ContextSources returns the sources of context the message was sent with,
+// nil when they were not recorded
+func (m *Message) ContextSources() *ContextSources {
+	for _, part := range m.Parts {
+		if c, ok := part.(ContextSources); ok {
+			return &c
+		}
+	}
+	return nil
+}
+
+// Citations returns the sources the message quotes or cites
+func (m *Message) Citations() []Citation {
+	citations := make([]Citation, 0)
+	for _, part := range m.Parts {
+		if c, ok := part.(Citation); ok {
+			citations = append(citations, c)
+		}
+	}
+	return citations
+}
+
+// FileReferences returns the files and directories the message mentions
+func (m *Message) FileReferences() []FileReference {
+	var references []FileReference
+	for _, part := range m.Parts {
+		if c, ok := part.(FileReference); ok {
+			references = append(references, c)
+		}
+	}
+	return references
+}
+
+// Artifacts returns the code blocks of the message saved as files
+func (m *Message) Artifacts() []Artifact {
+	var artifacts []Artifact
+	for _, part := range m.Parts {
+		if c, ok := part.(Artifact); ok {
+			artifacts = append(artifacts, c)
+		}
+	}
+	return artifacts
+}
+
+// SetCitations replaces the sources the message cites
+func (m *Message) SetCitations(citations []Citation) {
+	parts := make([]ContentPart, 0, len(m.Parts)+len(citations))
+	for _, part := range m.Parts {
+		if _, ok := part.(Citation); !ok {
+			parts = append(parts, part)
+		}
+	}
+	for _, citation := range citations {
+		parts = append(parts, citation)
+	}
+	m.Parts = parts
+}
+
 func (m *Message) FinishReason() FinishReason {
 	for _, part := range m.Parts {
 		if c, ok := part.(Finish); ok {
@@ -308,6 +694,11 @@ func (m *Message) SetToolResults(tr []ToolResult) {
 }
 
 func (m *Message) AddFinish(reason FinishReason) {
+	m.AddFinishMessage(reason, "")
+}
+
+// AddFinishMessage finishes the message with a reason detailed by text
+func (m *Message) AddFinishMessage(reason FinishReason, text string) {
 	// remove any existing finish part
 	for i, part := range m.Parts {
 		if _, ok := part.(Finish); ok {
@@ -315,7 +706,27 @@ func (m *Message) AddFinish(reason FinishReason) {
 			break
 		}
 	}
-	m.Parts = append(m.Parts, Finish{Reason: reason, Time: time.Now().Unix()})
+	m.Parts = append(m.Parts, Finish{Reason: reason, Time: time.Now().Unix(), Message: text})
+}
+
+// AddFinishError finishes the message with a provider error, text saying
+// what went wrong
+func (m *Message) AddFinishError(text string, finishErr FinishError) {
+	m.AddFinishMessage(FinishReasonError, text)
+	finish := m.Parts[len(m.Parts)-1].(Finish)
+	finish.Error = &finishErr
+	m.Parts[len(m.Parts)-1] = finish
+}
+
+// SetReview records the review of the response, replacing any previous one
+func (m *Message) SetReview(review Review) {
+	for i, part := range m.Parts {
+		if _, ok := part.(Review); ok {
+			m.Parts[i] = review
+			return
+		}
+	}
+	m.Parts = append(m.Parts, review)
 }
 
 func (m *Message) AddImageURL(url, detail string) {
@@ -325,3 +736,18 @@ func (m *Message) AddImageURL(url, detail string) {
 func (m *Message) AddBinary(mimeType string, data []byte) {
 	m.Parts = append(m.Parts, BinaryContent{MIMEType: mimeType, Data: data})
 }
+
+// TurnStep returns the ID of the last user message of msgs, the one the turn
+// answers, and the number of responses given to it so far. Both are the same
+// when the turn is retried from the user message.
+func TurnStep(msgs []Message) (userMessageID string, step int) {
+	for _, msg := range msgs {
+		switch msg.Role {
+		case User:
+			userMessageID, step = msg.ID, 0
+		case Assistant:
+			step++
+		}
+	}
+	return userMessageID, step
+}
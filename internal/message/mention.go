@@ -0,0 +1,104 @@
+package message
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	// mentionPattern matches a mention starting a word: @path, or @"path"
+	// for paths with spaces
+	mentionPattern = regexp.MustCompile(`(?:^|[\s(\[{])@(?:"([^"\n]+)"|([^\s"'` + "`" + `@]+))`)
+	// fencedCodePattern and inlineCodePattern match the code of a message,
+	// in which @ is never a mention
+	fencedCodePattern = regexp.MustCompile("(?s)```.*?(?:```|\\z)")
+	inlineCodePattern = regexp.MustCompile("`[^`\n]*`")
+)
+
+// ParseMentions returns the paths text mentions with @, in the order they
+// first appear, such as "internal/app/app.go" for "@internal/app/app.go".
+// Addresses such as user@example.com, and @ in code, fenced or inline, are
+// not mentions.
+func ParseMentions(text string) []string {
+	blank := func(code string) string { return strings.Repeat(" ", len(code)) }
+	text = fencedCodePattern.ReplaceAllStringFunc(text, blank)
+	text = inlineCodePattern.ReplaceAllStringFunc(text, blank)
+
+	var mentions []string
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		mention := match[1]
+		if mention == "" {
+			// Punctuation ending a sentence is not part of the path
+			mention = strings.TrimRight(match[2], ".,;:!?)]}")
+		}
+		if mention == "" || slices.Contains(mentions, mention) {
+			continue
+		}
+		mentions = append(mentions, mention)
+	}
+	return mentions
+}
+
+// MentionToken returns the mention of path to insert in a message
+func MentionToken(path string) string {
+	if strings.ContainsAny(path, " \t") {
+		return `@"` + path + `"`
+	}
+	return "@" + path
+}
+
+// Context returns the reference as sent to the agent, its content delimited
+// by a tag naming its path
+func (r FileReference) Context() string {
+	tag := "file"
+	if r.Dir {
+		tag = "directory"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "<%s path=%q>\n", tag, r.Path)
+	if r.Content != "" {
+		b.WriteString(strings.TrimRight(r.Content, "\n"))
+		b.WriteString("\n")
+	}
+	if r.Notice != "" {
+		fmt.Fprintf(&b, "[%s]\n", r.Notice)
+	}
+	fmt.Fprintf(&b, "</%s>", tag)
+	return b.String()
+}
+
+// ExpandFileReferences returns msgs with the content of the files the user
+// messages mention appended to their text, as the agent is sent them
+func ExpandFileReferences(msgs []Message) []Message {
+	expanded, cloned := msgs, false
+	for i, msg := range msgs {
+		references := msg.FileReferences()
+		if msg.Role != User || len(references) == 0 {
+			continue
+		}
+		if !cloned {
+			expanded, cloned = slices.Clone(msgs), true
+		}
+		contexts := make([]string, 0, len(references)+1)
+		contexts = append(contexts, msg.Content().Text)
+		for _, reference := range references {
+			contexts = append(contexts, reference.Context())
+		}
+		text := strings.Join(contexts, "\n\n")
+
+		msg.Parts = slices.Clone(msg.Parts)
+		j := slices.IndexFunc(msg.Parts, func(part ContentPart) bool {
+			_, ok := part.(TextContent)
+			return ok
+		})
+		if j == -1 {
+			msg.Parts = append([]ContentPart{TextContent{Text: text}}, msg.Parts...)
+		} else {
+			msg.Parts[j] = TextContent{Text: text}
+		}
+		expanded[i] = msg
+	}
+	return expanded
+}
@@ -0,0 +1,64 @@
+package message
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"path", "look at @internal/app/app.go please", []string{"internal/app/app.go"}},
+		{"start of text", "@README.md", []string{"README.md"}},
+		{"email", "mail user@example.com about it", nil},
+		{"trailing punctuation", "compare @a.go, @b.go. and @c.go!", []string{"a.go", "b.go", "c.go"}},
+		{"parenthesized", "the config (@config.json) is wrong", []string{"config.json"}},
+		{"quoted path", `see @"docs/design notes.md" first`, []string{"docs/design notes.md"}},
+		{"inline code", "the `@Override` annotation of @Foo.java", []string{"Foo.java"}},
+		{"fenced code", "fix this:\n```java\n@Override\npublic void run() {}\n```\nin @Runner.java", []string{"Runner.java"}},
+		{"unclosed fence", "```\n@decorator\ndef f(): pass", nil},
+		{"duplicates", "@a.go and @a.go again", []string{"a.go"}},
+		{"bare at", "meet @ noon", nil},
+		{"handle in a word", "foo@bar and @@twice", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ParseMentions(tt.text))
+		})
+	}
+}
+
+func TestMentionToken(t *testing.T) {
+	for _, path := range []string{"internal/app/app.go", "docs/design notes.md"} {
+		assert.Equal(t, []string{path}, ParseMentions("read "+MentionToken(path)+" now"))
+	}
+}
+
+func TestExpandFileReferences(t *testing.T) {
+	msgs := []Message{
+		{Role: User, Parts: []ContentPart{
+			TextContent{Text: "explain @main.go and @docs"},
+			FileReference{Path: "main.go", Content: "package main\n", Size: 13},
+			FileReference{Path: "docs", Dir: true, Content: "a.md\nb/", Notice: "listing cut at 2 entries"},
+		}},
+		{Role: Assistant, Parts: []ContentPart{TextContent{Text: "it is a program"}}},
+	}
+
+	expanded := ExpandFileReferences(msgs)
+	require.Len(t, expanded, 2)
+	assert.Equal(t, "explain @main.go and @docs\n\n"+
+		"<file path=\"main.go\">\npackage main\n</file>\n\n"+
+		"<directory path=\"docs\">\na.md\nb/\n[listing cut at 2 entries]\n</directory>",
+		expanded[0].Content().Text)
+	assert.Equal(t, "it is a program", expanded[1].Content().Text)
+	// The stored messages are left as they are
+	assert.Equal(t, "explain @main.go and @docs", msgs[0].Content().Text)
+
+	plain := []Message{{Role: User, Parts: []ContentPart{TextContent{Text: "hello"}}}}
+	assert.Equal(t, plain, ExpandFileReferences(plain))
+}
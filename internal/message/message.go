@@ -26,7 +26,14 @@ type Service interface {
 	Get(ctx context.Context, id string) (Message, error)
 	List(ctx context.Context, sessionID string) ([]Message, error)
 	Delete(ctx context.Context, id string) error
+	Move(ctx context.Context, id, sessionID string) error
 	DeleteSessionMessages(ctx context.Context, sessionID string) error
+	// CitingSessions returns the sessions with responses citing the document
+	// at source, a path or URL
+	CitingSessions(ctx context.Context, source string) ([]string, error)
+	// Recover finalizes the responses left in progress by a process that
+	// exited in the middle of them, returning what it did in each session
+	Recover(ctx context.Context) ([]Recovery, error)
 }
 
 type service struct {
@@ -54,6 +61,26 @@ func (s *service) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+// Move moves a message to another session. Subscribers see it deleted from
+// its old session and created in the new one.
+func (s *service) Move(ctx context.Context, id, sessionID string) error {
+	message, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = s.q.MoveMessage(ctx, db.MoveMessageParams{
+		ID:        message.ID,
+		SessionID: sessionID,
+	})
+	if err != nil {
+		return err
+	}
+	s.Publish(pubsub.DeletedEvent, message)
+	message.SessionID = sessionID
+	s.Publish(pubsub.CreatedEvent, message)
+	return nil
+}
+
 func (s *service) Create(ctx context.Context, sessionID string, params CreateMessageParams) (Message, error) {
 	if params.Role != Assistant {
 		params.Parts = append(params.Parts, Finish{
@@ -70,6 +97,7 @@ func (s *service) Create(ctx context.Context, sessionID string, params CreateMes
 		Role:      string(params.Role),
 		Parts:     string(partsJSON),
 		Model:     sql.NullString{String: string(params.Model), Valid: true},
+		Status:    string(messageStatus(Message{Role: params.Role, Parts: params.Parts})),
 	})
 	if err != nil {
 		return Message{}, err
@@ -78,6 +106,9 @@ func (s *service) Create(ctx context.Context, sessionID string, params CreateMes
 	if err != nil {
 		return Message{}, err
 	}
+	if err := s.recordCitations(ctx, message); err != nil {
+		return Message{}, err
+	}
 	s.Publish(pubsub.CreatedEvent, message)
 	return message, nil
 }
@@ -108,19 +139,56 @@ func (s *service) Update(ctx context.Context, message Message) error {
 		finishedAt.Int64 = f.Time
 		finishedAt.Valid = true
 	}
+	message.Status = messageStatus(message)
 	err = s.q.UpdateMessage(ctx, db.UpdateMessageParams{
 		ID:         message.ID,
 		Parts:      string(parts),
 		FinishedAt: finishedAt,
+		Status:     string(message.Status),
 	})
 	if err != nil {
 		return err
 	}
+	if err := s.recordCitations(ctx, message); err != nil {
+		return err
+	}
 	message.UpdatedAt = time.Now().Unix()
 	s.Publish(pubsub.UpdatedEvent, message)
 	return nil
 }
 
+// recordCitations stores the sources a response cites, so the sessions
+// citing a document can be queried
+func (s *service) recordCitations(ctx context.Context, message Message) error {
+	citations := message.Citations()
+	if message.Role != Assistant || len(citations) == 0 {
+		return nil
+	}
+	if err := s.q.DeleteMessageCitations(ctx, message.ID); err != nil {
+		return fmt.Errorf("failed to delete citations: %w", err)
+	}
+	// Summaries keep every source of the conversation, only the ones their
+	// text references are cited
+	for _, citation := range ResolveCitations(message.Content().Text, citations) {
+		err := s.q.CreateCitation(ctx, db.CreateCitationParams{
+			MessageID:   message.ID,
+			SourceIndex: int64(citation.Index),
+			Source:      citation.Source,
+			StartLine:   int64(citation.StartLine),
+			EndLine:     int64(citation.EndLine),
+			Score:       citation.Score,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record citation: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *service) CitingSessions(ctx context.Context, source string) ([]string, error) {
+	return s.q.ListCitingSessions(ctx, source)
+}
+
 func (s *service) Get(ctx context.Context, id string) (Message, error) {
 	dbMessage, err := s.q.GetMessage(ctx, id)
 	if err != nil {
@@ -157,9 +225,27 @@ func (s *service) fromDBItem(item db.Message) (Message, error) {
 		Model:     models.ModelID(item.Model.String),
 		CreatedAt: item.CreatedAt,
 		UpdatedAt: item.UpdatedAt,
+		Status:    MessageStatus(item.Status),
 	}, nil
 }
 
+// messageStatus returns the status stored for message. A response is in
+// progress until it is finished, and still once finished by calling tools
+// until it is marked finished along their results. A message marked finished
+// or interrupted keeps its status.
+func messageStatus(message Message) MessageStatus {
+	if message.Status == StatusFinished || message.Status == StatusInterrupted {
+		return message.Status
+	}
+	if message.Role != Assistant {
+		return StatusFinished
+	}
+	if finish := message.FinishPart(); finish != nil && finish.Reason != FinishReasonToolUse {
+		return StatusFinished
+	}
+	return StatusInProgress
+}
+
 type partType string
 
 const (
@@ -170,6 +256,13 @@ const (
 	toolCallType   partType = "tool_call"
 	toolResultType partType = "tool_result"
 	finishType     partType = "finish"
+	reviewType     partType = "review"
+	comparisonType partType = "comparison"
+	referenceType  partType = "file_reference"
+	citationType   partType = "citation"
+	artifactType   partType = "artifact"
+	changesType    partType = "workspace_changes"
+	contextType    partType = "context_sources"
 )
 
 type partWrapper struct {
@@ -198,6 +291,20 @@ func marshallParts(parts []ContentPart) ([]byte, error) {
 			typ = toolResultType
 		case Finish:
 			typ = finishType
+		case Review:
+			typ = reviewType
+		case Comparison:
+			typ = comparisonType
+		case FileReference:
+			typ = referenceType
+		case Citation:
+			typ = citationType
+		case Artifact:
+			typ = artifactType
+		case WorkspaceChanges:
+			typ = changesType
+		case ContextSources:
+			typ = contextType
 		default:
 			return nil, fmt.Errorf("unknown part type: %T", part)
 		}
@@ -271,6 +378,48 @@ func unmarshallParts(data []byte) ([]ContentPart, error) {
 				return nil, err
 			}
 			parts = append(parts, part)
+		case reviewType:
+			part := Review{}
+			if err := json.Unmarshal(wrapper.Data, &part); err != nil {
+				return nil, err
+			}
+			parts = append(parts, part)
+		case comparisonType:
+			part := Comparison{}
+			if err := json.Unmarshal(wrapper.Data, &part); err != nil {
+				return nil, err
+			}
+			parts = append(parts, part)
+		case referenceType:
+			part := FileReference{}
+			if err := json.Unmarshal(wrapper.Data, &part); err != nil {
+				return nil, err
+			}
+			parts = append(parts, part)
+		case citationType:
+			part := Citation{}
+			if err := json.Unmarshal(wrapper.Data, &part); err != nil {
+				return nil, err
+			}
+			parts = append(parts, part)
+		case artifactType:
+			part := Artifact{}
+			if err := json.Unmarshal(wrapper.Data, &part); err != nil {
+				return nil, err
+			}
+			parts = append(parts, part)
+		case changesType:
+			part := WorkspaceChanges{}
+			if err := json.Unmarshal(wrapper.Data, &part); err != nil {
+				return nil, err
+			}
+			parts = append(parts, part)
+		case contextType:
+			part := ContextSources{}
+			if err := json.Unmarshal(wrapper.Data, &part); err != nil {
+				return nil, err
+			}
+			parts = append(parts, part)
 		default:
 			return nil, fmt.Errorf("unknown part type: %s", wrapper.Type)
 		}
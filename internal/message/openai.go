@@ -0,0 +1,108 @@
+package message
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+)
+
+// openAIChat is a conversation in the OpenAI chat completion format, as
+// exported by the OpenAI Playground
+type openAIChat struct {
+	Messages []openAIMessage `json:"messages"`
+}
+
+type openAIMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+// ParseOpenAIChat reads a conversation in the OpenAI chat completion format,
+// {"messages": [{"role": "user", "content": "..."}, ...]}, into the messages
+// to create, with model as the model of the assistant messages. Roles must be
+// user, assistant or system. System messages become user messages, since the
+// system prompt of a session is the one of its agent. Every message is
+// checked before any is returned.
+func ParseOpenAIChat(r io.Reader, model models.ModelID) ([]CreateMessageParams, error) {
+	var chat openAIChat
+	if err := json.NewDecoder(r).Decode(&chat); err != nil {
+		return nil, fmt.Errorf("invalid chat completion JSON: %w", err)
+	}
+	if len(chat.Messages) == 0 {
+		return nil, fmt.Errorf("the conversation has no messages")
+	}
+
+	var errs []string
+	params := make([]CreateMessageParams, 0, len(chat.Messages))
+	for i, msg := range chat.Messages {
+		text, err := openAIContentText(msg.Content)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("message %d: %v", i+1, err))
+			continue
+		}
+
+		switch MessageRole(msg.Role) {
+		case User:
+			params = append(params, CreateMessageParams{Role: User, Parts: []ContentPart{TextContent{Text: text}}})
+		case System:
+			params = append(params, CreateMessageParams{
+				Role:  User,
+				Parts: []ContentPart{TextContent{Text: "System instructions:\n\n" + text}},
+			})
+		case Assistant:
+			params = append(params, CreateMessageParams{
+				Role:  Assistant,
+				Model: model,
+				Parts: []ContentPart{
+					TextContent{Text: text},
+					Finish{Reason: FinishReasonEndTurn, Time: time.Now().Unix()},
+				},
+			})
+		default:
+			errs = append(errs, fmt.Sprintf("message %d: unknown role %q, expected user, assistant or system", i+1, msg.Role))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid conversation:\n%s", strings.Join(errs, "\n"))
+	}
+	return params, nil
+}
+
+// openAIContentText returns the text of a message content, either a string or
+// an array of content parts of which only text parts are supported
+func openAIContentText(content json.RawMessage) (string, error) {
+	if len(content) == 0 {
+		return "", fmt.Errorf("no text content")
+	}
+	var text string
+	if err := json.Unmarshal(content, &text); err == nil {
+		if strings.TrimSpace(text) == "" {
+			return "", fmt.Errorf("no text content")
+		}
+		return text, nil
+	}
+
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(content, &parts); err != nil {
+		return "", fmt.Errorf("content must be a string or an array of content parts")
+	}
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part.Type != "text" {
+			return "", fmt.Errorf("unsupported %q content part, only text can be imported", part.Type)
+		}
+		texts = append(texts, part.Text)
+	}
+	text = strings.Join(texts, "\n")
+	if strings.TrimSpace(text) == "" {
+		return "", fmt.Errorf("no text content")
+	}
+	return text, nil
+}
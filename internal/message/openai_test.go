@@ -0,0 +1,87 @@
+package message
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+)
+
+func TestParseOpenAIChat(t *testing.T) {
+	input := `{"messages": [
+		{"role": "system", "content": "Answer briefly."},
+		{"role": "user", "content": [{"type": "text", "text": "What is Go?"}, {"type": "text", "text": "In one line."}]},
+		{"role": "assistant", "content": "A compiled language."}
+	]}`
+
+	params, err := ParseOpenAIChat(strings.NewReader(input), models.TestFake)
+	require.NoError(t, err)
+	require.Len(t, params, 3)
+
+	assert.Equal(t, User, params[0].Role)
+	assert.Equal(t, []ContentPart{TextContent{Text: "System instructions:\n\nAnswer briefly."}}, params[0].Parts)
+	assert.Equal(t, User, params[1].Role)
+	assert.Equal(t, []ContentPart{TextContent{Text: "What is Go?\nIn one line."}}, params[1].Parts)
+	assert.Empty(t, params[1].Model)
+
+	assert.Equal(t, Assistant, params[2].Role)
+	assert.Equal(t, models.TestFake, params[2].Model)
+	require.Len(t, params[2].Parts, 2)
+	assert.Equal(t, TextContent{Text: "A compiled language."}, params[2].Parts[0])
+	assert.Equal(t, FinishReasonEndTurn, params[2].Parts[1].(Finish).Reason)
+}
+
+func TestParseOpenAIChatErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "invalid JSON",
+			input: `{"messages": [`,
+			want:  []string{"invalid chat completion JSON"},
+		},
+		{
+			name:  "no messages",
+			input: `{"messages": []}`,
+			want:  []string{"the conversation has no messages"},
+		},
+		{
+			name:  "unknown roles",
+			input: `{"messages": [{"role": "user", "content": "hi"}, {"role": "tool", "content": "42"}, {"role": "developer", "content": "x"}]}`,
+			want: []string{
+				`message 2: unknown role "tool", expected user, assistant or system`,
+				`message 3: unknown role "developer"`,
+			},
+		},
+		{
+			name:  "missing content",
+			input: `{"messages": [{"role": "assistant", "content": null}, {"role": "user"}]}`,
+			want:  []string{"message 1: no text content", "message 2: no text content"},
+		},
+		{
+			name:  "non-text content part",
+			input: `{"messages": [{"role": "user", "content": [{"type": "image_url", "image_url": {"url": "x"}}]}]}`,
+			want:  []string{`message 1: unsupported "image_url" content part`},
+		},
+		{
+			name:  "content of the wrong type",
+			input: `{"messages": [{"role": "user", "content": 42}]}`,
+			want:  []string{"message 1: content must be a string or an array of content parts"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := ParseOpenAIChat(strings.NewReader(tt.input), models.TestFake)
+			require.Error(t, err)
+			assert.Nil(t, params)
+			for _, want := range tt.want {
+				assert.Contains(t, err.Error(), want)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package message
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	// interruptedText details the finish of an interrupted response
+	interruptedText = "the app exited before the response finished"
+	// abortedToolResult is the result given to a tool call left without one
+	abortedToolResult = "Tool call aborted: the app exited before it finished"
+)
+
+// Recovery is what recovering the interrupted responses of a session did
+type Recovery struct {
+	SessionID string
+	// Messages are the responses finalized as interrupted
+	Messages []string
+	// AbortedToolCalls is the number of tool calls given an error result
+	AbortedToolCalls int
+}
+
+// Notice tells the user of the session what was recovered
+func (r Recovery) Notice() string {
+	notice := fmt.Sprintf("Recovered %d interrupted %s: the app exited before %s. The content stored until then was kept",
+		len(r.Messages), plural(len(r.Messages), "message", "messages"), plural(len(r.Messages), "it finished", "they finished"))
+	if r.AbortedToolCalls > 0 {
+		notice += fmt.Sprintf(" and %d unfinished tool %s aborted", r.AbortedToolCalls, plural(r.AbortedToolCalls, "call was", "calls were"))
+	}
+	return notice + "."
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
+// Recover finalizes the responses stored in progress, which only a process
+// that exited in the middle of them leaves behind. A response is finished as
+// interrupted with the content stored until then, and its tool calls without
+// a result are given an error result so the conversation can go on. A
+// response that was stored entirely, only not marked finished, is marked
+// finished. Each session with an interrupted response gets a notice of it.
+func (s *service) Recover(ctx context.Context) ([]Recovery, error) {
+	items, err := s.q.ListMessagesByStatus(ctx, string(StatusInProgress))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the messages in progress: %w", err)
+	}
+	var sessions []string
+	for _, item := range items {
+		if len(sessions) == 0 || sessions[len(sessions)-1] != item.SessionID {
+			sessions = append(sessions, item.SessionID)
+		}
+	}
+
+	var recoveries []Recovery
+	for _, sessionID := range sessions {
+		recovery, err := s.recoverSession(ctx, sessionID)
+		if err != nil {
+			return recoveries, err
+		}
+		if len(recovery.Messages) > 0 {
+			recoveries = append(recoveries, recovery)
+		}
+	}
+	return recoveries, nil
+}
+
+// recoverSession finalizes the responses of a session stored in progress
+func (s *service) recoverSession(ctx context.Context, sessionID string) (Recovery, error) {
+	recovery := Recovery{SessionID: sessionID}
+	msgs, err := s.List(ctx, sessionID)
+	if err != nil {
+		return recovery, fmt.Errorf("failed to list the messages of session %s: %w", sessionID, err)
+	}
+	answered := make(map[string]bool)
+	for _, msg := range msgs {
+		for _, result := range msg.ToolResults() {
+			answered[result.ToolCallID] = true
+		}
+	}
+
+	for _, msg := range msgs {
+		if msg.Status != StatusInProgress {
+			continue
+		}
+		var aborted []ContentPart
+		for _, call := range msg.ToolCalls() {
+			if !answered[call.ID] {
+				msg.FinishToolCall(call.ID)
+				aborted = append(aborted, ToolResult{ToolCallID: call.ID, Content: abortedToolResult, IsError: true})
+			}
+		}
+		finish := msg.FinishPart()
+		if finish != nil && len(aborted) == 0 {
+			// The response and the results of its tool calls were stored
+			msg.Status = StatusFinished
+			if err := s.Update(ctx, msg); err != nil {
+				return recovery, fmt.Errorf("failed to finish message %s: %w", msg.ID, err)
+			}
+			continue
+		}
+
+		if finish == nil || finish.Reason == FinishReasonToolUse {
+			msg.AddFinishMessage(FinishReasonInterrupted, interruptedText)
+		}
+		msg.Status = StatusInterrupted
+		if err := s.Update(ctx, msg); err != nil {
+			return recovery, fmt.Errorf("failed to finish message %s: %w", msg.ID, err)
+		}
+		if len(aborted) > 0 {
+			if _, err := s.Create(ctx, sessionID, CreateMessageParams{Role: Tool, Parts: aborted}); err != nil {
+				return recovery, fmt.Errorf("failed to abort the tool calls of message %s: %w", msg.ID, err)
+			}
+		}
+		recovery.Messages = append(recovery.Messages, msg.ID)
+		recovery.AbortedToolCalls += len(aborted)
+	}
+
+	if len(recovery.Messages) > 0 {
+		_, err := s.Create(ctx, sessionID, CreateMessageParams{
+			Role: Assistant,
+			Parts: []ContentPart{
+				TextContent{Text: recovery.Notice()},
+				Finish{Reason: FinishReasonInterrupted, Time: time.Now().Unix()},
+			},
+		})
+		if err != nil {
+			return recovery, fmt.Errorf("failed to post the recovery notice: %w", err)
+		}
+	}
+	return recovery, nil
+}
@@ -0,0 +1,96 @@
+package message
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/db"
+)
+
+// openServices connects to the database of the data directory, as a new
+// process does
+func openServices(t *testing.T) (*sql.DB, db.Querier, Service) {
+	t.Helper()
+	conn, err := db.Connect()
+	require.NoError(t, err)
+	q := db.New(conn)
+	return conn, q, NewService(q)
+}
+
+func createSession(t *testing.T, q db.Querier, id string) {
+	t.Helper()
+	_, err := q.CreateSession(context.Background(), db.CreateSessionParams{ID: id, Title: id})
+	require.NoError(t, err)
+}
+
+func TestRecoverAfterCrash(t *testing.T) {
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
+	cfg.Data.Directory = t.TempDir()
+	ctx := context.Background()
+
+	conn, q, messages := openServices(t)
+	createSession(t, q, "crashed")
+	createSession(t, q, "stored")
+
+	// The response is streaming a tool call when the process dies
+	_, err := messages.Create(ctx, "crashed", CreateMessageParams{Role: User, Parts: []ContentPart{TextContent{Text: "list the files"}}})
+	require.NoError(t, err)
+	response, err := messages.Create(ctx, "crashed", CreateMessageParams{Role: Assistant})
+	require.NoError(t, err)
+	assert.Equal(t, StatusInProgress, response.Status, "the response should be stored in progress before streaming")
+	response.AppendContent("Let me look")
+	response.AddToolCall(ToolCall{ID: "call-1", Name: "ls", Input: `{"path":"."}`})
+	require.NoError(t, messages.Update(ctx, response))
+
+	// The other response and its tool results were stored, only not marked
+	// finished
+	stored, err := messages.Create(ctx, "stored", CreateMessageParams{Role: Assistant})
+	require.NoError(t, err)
+	stored.AddToolCall(ToolCall{ID: "call-2", Name: "ls", Finished: true})
+	stored.AddFinish(FinishReasonToolUse)
+	require.NoError(t, messages.Update(ctx, stored))
+	_, err = messages.Create(ctx, "stored", CreateMessageParams{Role: Tool, Parts: []ContentPart{ToolResult{ToolCallID: "call-2", Content: "main.go"}}})
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	conn, _, messages = openServices(t)
+	defer conn.Close()
+	recoveries, err := messages.Recover(ctx)
+	require.NoError(t, err)
+	require.Len(t, recoveries, 1)
+	assert.Equal(t, Recovery{SessionID: "crashed", Messages: []string{response.ID}, AbortedToolCalls: 1}, recoveries[0])
+
+	msgs, err := messages.List(ctx, "crashed")
+	require.NoError(t, err)
+	require.Len(t, msgs, 4)
+	recovered := msgs[1]
+	assert.Equal(t, StatusInterrupted, recovered.Status)
+	assert.Equal(t, "Let me look", recovered.Content().Text, "the content stored until the crash should be kept")
+	assert.Equal(t, FinishReasonInterrupted, recovered.FinishReason())
+	assert.True(t, recovered.ToolCalls()[0].Finished)
+	results := msgs[2].ToolResults()
+	require.Len(t, results, 1)
+	assert.Equal(t, "call-1", results[0].ToolCallID)
+	assert.True(t, results[0].IsError)
+	assert.Equal(t, "Recovered 1 interrupted message: the app exited before it finished. The content stored until then was kept and 1 unfinished tool call was aborted.", msgs[3].Content().Text)
+
+	msgs, err = messages.List(ctx, "stored")
+	require.NoError(t, err)
+	require.Len(t, msgs, 2, "a response stored entirely should not get a notice")
+	assert.Equal(t, StatusFinished, msgs[0].Status)
+	assert.Equal(t, FinishReasonToolUse, msgs[0].FinishReason())
+
+	recoveries, err = messages.Recover(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, recoveries, "nothing should be left to recover")
+}
+
+func TestRecoveryNotice(t *testing.T) {
+	recovery := Recovery{Messages: []string{"a", "b"}}
+	assert.Equal(t, "Recovered 2 interrupted messages: the app exited before they finished. The content stored until then was kept.", recovery.Notice())
+}
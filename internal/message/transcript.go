@@ -0,0 +1,97 @@
+package message
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Transcript renders a conversation as Markdown, with the files the user
+// mentions folded below their messages and links to the artifacts of the
+// responses, exported to artifactsDir, ending with a section listing the
+// sources its responses cite along with their quotes
+func Transcript(title string, msgs []Message, artifactsDir string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", title)
+
+	var cited []Citation
+	for _, msg := range msgs {
+		text := strings.TrimSpace(msg.Content().Text)
+		switch msg.Role {
+		case User:
+			if text != "" {
+				fmt.Fprintf(&b, "\n## User\n\n%s\n", text)
+			}
+			for _, reference := range msg.FileReferences() {
+				label := reference.Path
+				if reference.Notice != "" {
+					label += " (" + reference.Notice + ")"
+				}
+				fence := codeFence(reference.Content)
+				fmt.Fprintf(&b, "\n<details>\n<summary>@%s</summary>\n\n%s\n%s\n%s\n\n</details>\n",
+					html.EscapeString(label), fence, strings.TrimRight(reference.Content, "\n"), fence)
+			}
+		case Assistant:
+			var calls []string
+			for _, call := range msg.ToolCalls() {
+				calls = append(calls, "`"+call.Name+"`")
+			}
+			if text == "" && len(calls) == 0 {
+				continue
+			}
+			b.WriteString("\n## Assistant\n")
+			if text != "" {
+				fmt.Fprintf(&b, "\n%s\n", text)
+			}
+			if len(calls) > 0 {
+				fmt.Fprintf(&b, "\n*Called %s*\n", strings.Join(calls, ", "))
+			}
+			if artifacts := msg.Artifacts(); len(artifacts) > 0 {
+				links := make([]string, 0, len(artifacts))
+				for _, artifact := range artifacts {
+					links = append(links, fmt.Sprintf("[%s](%s)", artifact.Name, path.Join(artifactsDir, url.PathEscape(artifact.Name))))
+				}
+				fmt.Fprintf(&b, "\n*Saved %s*\n", strings.Join(links, ", "))
+			}
+			for _, citation := range ResolveCitations(text, msg.Citations()) {
+				if !slices.ContainsFunc(cited, func(c Citation) bool { return c.Index == citation.Index }) {
+					cited = append(cited, citation)
+				}
+			}
+		}
+	}
+
+	if len(cited) == 0 {
+		return b.String()
+	}
+	slices.SortFunc(cited, func(a, b Citation) int { return a.Index - b.Index })
+	b.WriteString("\n## Sources\n")
+	for _, citation := range cited {
+		label := fmt.Sprintf("[%d] %s", citation.Index, citation.Label())
+		if citation.Score > 0 {
+			label += fmt.Sprintf(" (score %.2f)", citation.Score)
+		}
+		fence := codeFence(citation.Quote)
+		fmt.Fprintf(&b, "\n<details>\n<summary>%s</summary>\n\n%s\n%s\n%s\n\n</details>\n",
+			html.EscapeString(label), fence, strings.TrimRight(citation.Quote, "\n"), fence)
+	}
+	return b.String()
+}
+
+// codeFence returns a fence longer than the backtick runs of text, so the
+// quote cannot close it
+func codeFence(text string) string {
+	longest, run := 0, 0
+	for _, r := range text {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
@@ -0,0 +1,390 @@
+// Package notes keeps the long-term memory of a workspace: the decisions,
+// conventions and gotchas that agents and users note down. Unlike the context
+// of a session, the notes last across sessions. They are stored in a file of
+// the data directory keyed by the path of the workspace, and the pinned and
+// most recent ones are included in the system prompt.
+package notes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/llm/tokens"
+)
+
+const (
+	// MaxNoteBytes bounds the content of a note
+	MaxNoteBytes = 4000
+	// MaxTags bounds the tags of a note
+	MaxTags = 10
+)
+
+// ErrNotFound is returned for a note the workspace does not have
+var ErrNotFound = errors.New("note not found")
+
+// Note is a note of the workspace memory
+type Note struct {
+	ID      string   `json:"id"`
+	Content string   `json:"content"`
+	Tags    []string `json:"tags,omitempty"`
+	// Pinned notes come first in the system prompt
+	Pinned bool `json:"pinned,omitempty"`
+	// Agent and SessionID attribute the notes written by agents, both being
+	// empty for the notes written by the user
+	Agent     string `json:"agent,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+	// Space is the space active when the note was written, "" when none was
+	Space string `json:"space,omitempty"`
+	// CreatedAt, UpdatedAt and ExpiresAt are Unix timestamps in seconds,
+	// ExpiresAt being 0 for the notes which do not expire
+	CreatedAt int64 `json:"created_at"`
+	UpdatedAt int64 `json:"updated_at"`
+	ExpiresAt int64 `json:"expires_at,omitempty"`
+}
+
+// Expired reports whether the note expired at now
+func (n Note) Expired(now time.Time) bool {
+	return n.ExpiresAt != 0 && n.ExpiresAt <= now.Unix()
+}
+
+// Author returns who wrote the note
+func (n Note) Author() string {
+	if n.Agent == "" {
+		return "user"
+	}
+	return n.Agent
+}
+
+// file is the content of the notes file of a workspace
+type file struct {
+	// Workspace is the path of the workspace, the file name being its hash
+	Workspace string `json:"workspace"`
+	Notes     []Note `json:"notes"`
+}
+
+// mu serializes the changes to the notes file
+var mu sync.Mutex
+
+// load reads the notes of the workspace, the expired ones included
+func load() ([]Note, error) {
+	data, err := os.ReadFile(config.NotesFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the notes: %w", err)
+	}
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse the notes: %w", err)
+	}
+	return f.Notes, nil
+}
+
+// save replaces the notes of the workspace, dropping the expired ones
+func save(notes []Note) error {
+	now := time.Now()
+	notes = slices.DeleteFunc(notes, func(n Note) bool { return n.Expired(now) })
+	data, err := json.MarshalIndent(file{Workspace: config.WorkingDirectory(), Notes: notes}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode the notes: %w", err)
+	}
+	path := config.NotesFile()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create the notes directory: %w", err)
+	}
+	// The notes are replaced at once, so a failed write keeps the previous ones
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to save the notes: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to save the notes: %w", err)
+	}
+	return nil
+}
+
+// sorted orders notes pinned first, then most recently updated first
+func sorted(notes []Note) []Note {
+	slices.SortStableFunc(notes, func(a, b Note) int {
+		switch {
+		case a.Pinned != b.Pinned:
+			if a.Pinned {
+				return -1
+			}
+			return 1
+		case a.UpdatedAt != b.UpdatedAt:
+			if a.UpdatedAt > b.UpdatedAt {
+				return -1
+			}
+			return 1
+		}
+		return 0
+	})
+	return notes
+}
+
+// List returns the notes of the workspace which did not expire, pinned first
+// then most recently updated first
+func List() ([]Note, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	notes, err := load()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return sorted(slices.DeleteFunc(notes, func(n Note) bool { return n.Expired(now) })), nil
+}
+
+// normalize checks the content and tags of a note, trimming them
+func normalize(note *Note) error {
+	note.Content = strings.TrimSpace(note.Content)
+	if note.Content == "" {
+		return fmt.Errorf("the note is empty")
+	}
+	if len(note.Content) > MaxNoteBytes {
+		return fmt.Errorf("the note is %d bytes, the limit is %d", len(note.Content), MaxNoteBytes)
+	}
+	tags := make([]string, 0, len(note.Tags))
+	for _, tag := range note.Tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) > MaxTags {
+		return fmt.Errorf("the note has %d tags, the limit is %d", len(tags), MaxTags)
+	}
+	note.Tags = tags
+	return nil
+}
+
+// Add appends a note to the workspace, setting its ID and timestamps
+func Add(note Note) (Note, error) {
+	if err := normalize(&note); err != nil {
+		return Note{}, err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	notes, err := load()
+	if err != nil {
+		return Note{}, err
+	}
+	now := time.Now().Unix()
+	note.ID = uuid.New().String()
+	if note.Space == "" {
+		note.Space = config.ActiveSpace()
+	}
+	note.CreatedAt, note.UpdatedAt = now, now
+	if err := save(append(notes, note)); err != nil {
+		return Note{}, err
+	}
+	return note, nil
+}
+
+// Update replaces the content, tags, pin and expiry of a note
+func Update(note Note) (Note, error) {
+	if err := normalize(&note); err != nil {
+		return Note{}, err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	notes, err := load()
+	if err != nil {
+		return Note{}, err
+	}
+	i := slices.IndexFunc(notes, func(n Note) bool { return n.ID == note.ID })
+	if i == -1 {
+		return Note{}, ErrNotFound
+	}
+	notes[i].Content = note.Content
+	notes[i].Tags = note.Tags
+	notes[i].Pinned = note.Pinned
+	notes[i].ExpiresAt = note.ExpiresAt
+	notes[i].UpdatedAt = time.Now().Unix()
+	if err := save(notes); err != nil {
+		return Note{}, err
+	}
+	return notes[i], nil
+}
+
+// Delete removes a note from the workspace
+func Delete(id string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	notes, err := load()
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(notes, func(n Note) bool { return n.ID == id })
+	if i == -1 {
+		return ErrNotFound
+	}
+	return save(slices.Delete(notes, i, i+1))
+}
+
+// Query returns the notes having every tag of tags and every word of query,
+// in their content or tags, case insensitively. At most limit notes are
+// returned, pinned first then most recently updated first.
+func Query(query string, tags []string, limit int) ([]Note, error) {
+	notes, err := List()
+	if err != nil {
+		return nil, err
+	}
+	words := strings.Fields(strings.ToLower(query))
+	var matches []Note
+	for _, note := range notes {
+		if matchesTags(note, tags) && matchesWords(note, words) {
+			matches = append(matches, note)
+		}
+	}
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+func matchesTags(note Note, tags []string) bool {
+	for _, tag := range tags {
+		if !slices.Contains(note.Tags, strings.ToLower(strings.TrimSpace(tag))) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesWords(note Note, words []string) bool {
+	text := strings.ToLower(note.Content + " " + strings.Join(note.Tags, " "))
+	for _, word := range words {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// Format renders a note as a line of text for the model, with its tags and
+// attribution
+func Format(note Note) string {
+	var b strings.Builder
+	b.WriteString("- ")
+	if note.Pinned {
+		b.WriteString("[pinned] ")
+	}
+	b.WriteString(note.Content)
+	meta := []string{"by " + note.Author(), time.Unix(note.CreatedAt, 0).UTC().Format("2006-01-02")}
+	if len(note.Tags) > 0 {
+		meta = append([]string{"tags: " + strings.Join(note.Tags, ", ")}, meta...)
+	}
+	fmt.Fprintf(&b, " (%s)", strings.Join(meta, ", "))
+	return b.String()
+}
+
+// PromptContext returns the notes included in the system prompt, pinned first
+// then most recently updated first, up to budget tokens. It is empty when the
+// workspace has no notes.
+func PromptContext(budget int) string {
+	notes, err := List()
+	if err != nil || len(notes) == 0 {
+		return ""
+	}
+	var lines []string
+	used := 0
+	for _, note := range notes {
+		line := Format(note)
+		if used+tokens.Estimate(line) > budget {
+			break
+		}
+		used += tokens.Estimate(line)
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	if omitted := len(notes) - len(lines); omitted > 0 {
+		lines = append(lines, fmt.Sprintf("(%d more notes, use memory_read to search them)", omitted))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Export writes the notes of the workspace as JSON
+func Export(w io.Writer) error {
+	notes, err := List()
+	if err != nil {
+		return err
+	}
+	return export(w, notes)
+}
+
+// ExportSpace writes the notes of the workspace written in a space as JSON,
+// in the format of Export
+func ExportSpace(w io.Writer, spaceID string) error {
+	notes, err := List()
+	if err != nil {
+		return err
+	}
+	notes = slices.DeleteFunc(notes, func(n Note) bool { return n.Space != spaceID })
+	return export(w, notes)
+}
+
+func export(w io.Writer, notes []Note) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(file{Workspace: config.WorkingDirectory(), Notes: notes})
+}
+
+// DeleteSpace deletes the notes written in a space and returns how many were
+// deleted
+func DeleteSpace(spaceID string) (int, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	notes, err := load()
+	if err != nil {
+		return 0, err
+	}
+	kept := slices.DeleteFunc(slices.Clone(notes), func(n Note) bool { return n.Space == spaceID })
+	if len(kept) == len(notes) {
+		return 0, nil
+	}
+	return len(notes) - len(kept), save(kept)
+}
+
+// Import adds the notes exported by Export to the workspace, replacing the
+// notes with the same ID, and returns how many were imported
+func Import(r io.Reader) (int, error) {
+	var f file
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return 0, fmt.Errorf("failed to parse the notes: %w", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	notes, err := load()
+	if err != nil {
+		return 0, err
+	}
+	imported := 0
+	for _, note := range f.Notes {
+		if err := normalize(&note); err != nil || note.ID == "" {
+			continue
+		}
+		if i := slices.IndexFunc(notes, func(n Note) bool { return n.ID == note.ID }); i != -1 {
+			notes[i] = note
+		} else {
+			notes = append(notes, note)
+		}
+		imported++
+	}
+	return imported, save(notes)
+}
@@ -0,0 +1,145 @@
+package notes
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupWorkspace points the notes file at an empty data directory
+func setupWorkspace(t *testing.T) {
+	t.Helper()
+	config.NewTestConfig(config.WithWorkingDir(t.TempDir()), config.WithDataDir(t.TempDir()))
+}
+
+func TestAddAndList(t *testing.T) {
+	setupWorkspace(t)
+
+	first, err := Add(Note{Content: "  Use sqlc for the queries  ", Tags: []string{"DB", "db", " "}, Agent: "caronex", SessionID: "s1"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, first.ID)
+	assert.Equal(t, "Use sqlc for the queries", first.Content)
+	assert.Equal(t, []string{"db"}, first.Tags)
+
+	_, err = Add(Note{Content: "Tests run with -race", Pinned: true})
+	require.NoError(t, err)
+	_, err = Add(Note{Content: "Temporary freeze of the API", ExpiresAt: time.Now().Add(-time.Minute).Unix()})
+	require.NoError(t, err)
+
+	list, err := List()
+	require.NoError(t, err)
+	require.Len(t, list, 2, "the expired note is dropped")
+	assert.Equal(t, "Tests run with -race", list[0].Content, "pinned notes come first")
+	assert.Equal(t, "user", list[0].Author())
+	assert.Equal(t, "caronex", list[1].Author())
+
+	_, err = Add(Note{Content: "   "})
+	assert.Error(t, err)
+	_, err = Add(Note{Content: strings.Repeat("x", MaxNoteBytes+1)})
+	assert.Error(t, err)
+}
+
+func TestUpdateAndDelete(t *testing.T) {
+	setupWorkspace(t)
+
+	note, err := Add(Note{Content: "Deploy on Fridays", Agent: "caronex"})
+	require.NoError(t, err)
+
+	note.Content = "Never deploy on Fridays"
+	note.Pinned = true
+	note.Agent = "someone else"
+	updated, err := Update(note)
+	require.NoError(t, err)
+	assert.Equal(t, "Never deploy on Fridays", updated.Content)
+	assert.True(t, updated.Pinned)
+	assert.Equal(t, "caronex", updated.Agent, "the attribution is kept")
+
+	require.NoError(t, Delete(note.ID))
+	assert.ErrorIs(t, Delete(note.ID), ErrNotFound)
+	_, err = Update(note)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestQuery(t *testing.T) {
+	setupWorkspace(t)
+
+	for _, note := range []Note{
+		{Content: "Migrations live in internal/db/migrations", Tags: []string{"db"}},
+		{Content: "The db tests need sqlite", Tags: []string{"db", "testing"}},
+		{Content: "Run the BDD tests with make bdd", Tags: []string{"testing"}},
+	} {
+		_, err := Add(note)
+		require.NoError(t, err)
+	}
+
+	found, err := Query("", []string{"db"}, 0)
+	require.NoError(t, err)
+	assert.Len(t, found, 2)
+
+	found, err = Query("TESTS", []string{"testing"}, 0)
+	require.NoError(t, err)
+	assert.Len(t, found, 2)
+
+	found, err = Query("sqlite tests", nil, 0)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, "The db tests need sqlite", found[0].Content)
+
+	found, err = Query("", nil, 1)
+	require.NoError(t, err)
+	assert.Len(t, found, 1)
+}
+
+func TestPromptContext(t *testing.T) {
+	setupWorkspace(t)
+	assert.Empty(t, PromptContext(1000))
+
+	_, err := Add(Note{Content: strings.Repeat("a", 200)})
+	require.NoError(t, err)
+	_, err = Add(Note{Content: "Pinned convention", Pinned: true, Tags: []string{"style"}})
+	require.NoError(t, err)
+
+	prompt := PromptContext(1000)
+	lines := strings.Split(prompt, "\n")
+	require.Len(t, lines, 2)
+	assert.True(t, strings.HasPrefix(lines[0], "- [pinned] Pinned convention (tags: style, by user, "))
+
+	// The budget keeps the pinned note and leaves out the long one
+	prompt = PromptContext(30)
+	lines = strings.Split(prompt, "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "Pinned convention")
+	assert.Equal(t, "(1 more notes, use memory_read to search them)", lines[1])
+}
+
+func TestExportImport(t *testing.T) {
+	setupWorkspace(t)
+
+	note, err := Add(Note{Content: "Keep the CLI output stable", Tags: []string{"cli"}})
+	require.NoError(t, err)
+	var exported bytes.Buffer
+	require.NoError(t, Export(&exported))
+
+	// Another workspace imports the notes, keeping their IDs
+	setupWorkspace(t)
+	imported, err := Import(bytes.NewReader(exported.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, 1, imported)
+
+	// Importing again replaces the notes rather than duplicating them
+	imported, err = Import(bytes.NewReader(exported.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, 1, imported)
+	list, err := List()
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, note.ID, list[0].ID)
+
+	_, err = Import(strings.NewReader("not json"))
+	assert.Error(t, err)
+}
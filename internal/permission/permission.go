@@ -20,6 +20,9 @@ type CreatePermissionRequest struct {
 	Action      string `json:"action"`
 	Params      any    `json:"params"`
 	Path        string `json:"path"`
+	// Confirm asks the user even when the session is auto-approved or the
+	// action was allowed for the session
+	Confirm bool `json:"confirm,omitempty"`
 }
 
 type PermissionRequest struct {
@@ -30,6 +33,7 @@ type PermissionRequest struct {
 	Action      string `json:"action"`
 	Params      any    `json:"params"`
 	Path        string `json:"path"`
+	Confirm     bool   `json:"confirm,omitempty"`
 }
 
 type Service interface {
@@ -73,7 +77,8 @@ func (s *permissionService) Deny(permission PermissionRequest) {
 
 func (s *permissionService) Request(opts CreatePermissionRequest) bool {
 	if slices.Contains(s.autoApproveSessions, opts.SessionID) {
-		return true
+		// Nobody is there to confirm in auto-approved sessions
+		return !opts.Confirm
 	}
 	dir := filepath.Dir(opts.Path)
 	if dir == "." {
@@ -87,10 +92,11 @@ func (s *permissionService) Request(opts CreatePermissionRequest) bool {
 		Description: opts.Description,
 		Action:      opts.Action,
 		Params:      opts.Params,
+		Confirm:     opts.Confirm,
 	}
 
 	for _, p := range s.sessionPermissions {
-		if p.ToolName == permission.ToolName && p.Action == permission.Action && p.SessionID == permission.SessionID && p.Path == permission.Path {
+		if !permission.Confirm && p.ToolName == permission.ToolName && p.Action == permission.Action && p.SessionID == permission.SessionID && p.Path == permission.Path {
 			return true
 		}
 	}
@@ -1,6 +1,7 @@
 package permission
 
 import (
+	"context"
 	"reflect"
 	"sync"
 	"testing"
@@ -174,3 +175,49 @@ func Test_permissionService_Request(t *testing.T) {
 		})
 	}
 }
+
+func TestRequestConfirm(t *testing.T) {
+	s := NewPermissionService()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := s.Subscribe(ctx)
+	request := CreatePermissionRequest{SessionID: "s", ToolName: "bash", Action: "execute", Path: "/work/project/file"}
+
+	// Auto-approved sessions cannot confirm
+	s.AutoApproveSession("auto")
+	auto := request
+	auto.SessionID = "auto"
+	if !s.Request(auto) {
+		t.Error("Request() in an auto-approved session was denied")
+	}
+	auto.Confirm = true
+	if s.Request(auto) {
+		t.Error("Request() to confirm in an auto-approved session was granted")
+	}
+
+	// Actions allowed for the session are confirmed again
+	granted := make(chan struct{})
+	go func() {
+		defer close(granted)
+		s.GrantPersistant((<-events).Payload)
+	}()
+	if !s.Request(request) {
+		t.Fatal("Request() was denied")
+	}
+	<-granted
+	if !s.Request(request) {
+		t.Error("Request() allowed for the session was denied")
+	}
+	confirm := request
+	confirm.Confirm = true
+	go func() {
+		event := <-events
+		if !event.Payload.Confirm {
+			t.Error("the request to confirm was published without Confirm")
+		}
+		s.Deny(event.Payload)
+	}()
+	if s.Request(confirm) {
+		t.Error("Request() to confirm was granted without asking")
+	}
+}
@@ -91,26 +91,29 @@ func (b *Broker[T]) GetSubscriberCount() int {
 }
 
 func (b *Broker[T]) Publish(t EventType, payload T) {
+	b.Deliver(t, payload)
+}
+
+// Deliver publishes an event and returns the subscriptions it was queued to,
+// leaving out the ones whose buffer is full. The lock is held while sending,
+// so a subscription is not closed while the event is sent to it.
+func (b *Broker[T]) Deliver(t EventType, payload T) []<-chan Event[T] {
 	b.mu.RLock()
+	defer b.mu.RUnlock()
 	select {
 	case <-b.done:
-		b.mu.RUnlock()
-		return
+		return nil
 	default:
 	}
 
-	subscribers := make([]chan Event[T], 0, len(b.subs))
-	for sub := range b.subs {
-		subscribers = append(subscribers, sub)
-	}
-	b.mu.RUnlock()
-
 	event := Event[T]{Type: t, Payload: payload}
-
-	for _, sub := range subscribers {
+	delivered := make([]<-chan Event[T], 0, len(b.subs))
+	for sub := range b.subs {
 		select {
 		case sub <- event:
+			delivered = append(delivered, sub)
 		default:
 		}
 	}
+	return delivered
 }
@@ -0,0 +1,61 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestPublishWhileUnsubscribing(t *testing.T) {
+	broker := NewBroker[int]()
+	defer broker.Shutdown()
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		ctx, cancel := context.WithCancel(context.Background())
+		events := broker.Subscribe(ctx)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for range events {
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := range 20 {
+				if j == 10 {
+					cancel()
+				}
+				broker.Publish(CreatedEvent, i)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if count := broker.GetSubscriberCount(); count != 0 {
+		t.Errorf("GetSubscriberCount() = %d once every subscription ended, want 0", count)
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	broker := NewBrokerWithOptions[int](bufferSize, 1000)
+	defer broker.Shutdown()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	behind := broker.Subscribe(ctx)
+	reading := broker.Subscribe(ctx)
+
+	for i := range bufferSize {
+		if delivered := broker.Deliver(CreatedEvent, i); len(delivered) != 2 {
+			t.Fatalf("Deliver() of event %d queued to %d subscriptions, want 2", i, len(delivered))
+		}
+		<-reading
+	}
+	delivered := broker.Deliver(CreatedEvent, bufferSize)
+	if len(delivered) != 1 || delivered[0] != reading {
+		t.Errorf("Deliver() to a full subscription queued to %v, want only the one reading", delivered)
+	}
+	if len(behind) != bufferSize {
+		t.Errorf("the full subscription holds %d events, want %d", len(behind), bufferSize)
+	}
+}
@@ -0,0 +1,388 @@
+// Package remote serves a minimal local HTTP API for controlling a running
+// instance: listing sessions, sending prompts, inspecting the system and
+// creating coordination plans. It shares the services of the TUI, so work
+// started remotely shows up in the TUI and the other way around.
+package remote
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/connectivity"
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/llm/agent"
+	"github.com/caronex/intelligence-interface/internal/lock"
+	"github.com/caronex/intelligence-interface/internal/message"
+	"github.com/caronex/intelligence-interface/internal/session"
+	"github.com/caronex/intelligence-interface/internal/tools/coordination"
+)
+
+// Server is the HTTP API of a running instance
+type Server struct {
+	sessions     session.Service
+	messages     message.Service
+	agent        agent.Service
+	locks        lock.Service
+	coordination *coordination.Manager
+	tokens       *tokenStore
+
+	httpServer *http.Server
+}
+
+// SessionResponse describes a session
+type SessionResponse struct {
+	ID               string  `json:"id"`
+	ParentSessionID  string  `json:"parent_session_id,omitempty"`
+	Title            string  `json:"title"`
+	MessageCount     int64   `json:"message_count"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+	// ConfigFingerprint identifies the configuration the session was created with
+	ConfigFingerprint string `json:"config_fingerprint,omitempty"`
+	CreatedAt         int64  `json:"created_at"`
+	UpdatedAt         int64  `json:"updated_at"`
+}
+
+// MessageResponse describes an agent message as it streams
+type MessageResponse struct {
+	ID           string   `json:"id"`
+	SessionID    string   `json:"session_id"`
+	Role         string   `json:"role"`
+	Content      string   `json:"content"`
+	ToolCalls    []string `json:"tool_calls,omitempty"`
+	Finished     bool     `json:"finished"`
+	FinishReason string   `json:"finish_reason,omitempty"`
+}
+
+// sendMessageRequest is the body of POST /sessions/{id}/messages
+type sendMessageRequest struct {
+	Content string `json:"content"`
+}
+
+// createPlanRequest is the body of POST /coordination/plans
+type createPlanRequest struct {
+	TaskDescription string   `json:"task_description"`
+	Requirements    []string `json:"requirements"`
+	Template        string   `json:"template"`
+}
+
+// New creates the HTTP API server. The bearer token is loaded from, or
+// generated into, the data directory. Prompts are only sent to sessions whose
+// lock the instance holds or can take, when locks is set.
+func New(cfg *config.Config, sessions session.Service, messages message.Service, agentService agent.Service, locks lock.Service) (*Server, error) {
+	tokens, err := loadToken(filepath.Join(cfg.Data.Directory, config.RemoteTokenFilename), cfg.Remote.RotationInterval())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load remote API token: %w", err)
+	}
+
+	manager, err := coordination.NewManager(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create coordination manager: %w", err)
+	}
+
+	s := &Server{
+		sessions:     sessions,
+		messages:     messages,
+		agent:        agentService,
+		locks:        locks,
+		coordination: manager,
+		tokens:       tokens,
+	}
+	s.httpServer = &http.Server{
+		Addr:              cfg.Remote.Address(),
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s, nil
+}
+
+// Handler returns the routes of the API wrapped in the enabled and auth checks
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /sessions", s.handleListSessions)
+	mux.HandleFunc("POST /sessions/{id}/messages", s.handleSendMessage)
+	mux.HandleFunc("GET /introspection", s.handleIntrospection)
+	mux.HandleFunc("POST /coordination/plans", s.handleCreatePlan)
+	return s.requireEnabled(s.requireToken(mux))
+}
+
+// Start listens on the configured address and serves the API until ctx is
+// cancelled. The token is rotated in the background when configured.
+func (s *Server) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.httpServer.Addr, err)
+	}
+	s.httpServer.BaseContext = func(net.Listener) context.Context { return ctx }
+
+	go func() {
+		defer logging.RecoverPanic("remote-api", nil)
+		if err := s.httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logging.Error("Remote API stopped", "error", err)
+		}
+	}()
+	if s.tokens.rotation > 0 {
+		go s.rotateTokens(ctx)
+	}
+
+	logging.Info("Remote API listening", "address", listener.Addr().String(), "token_file", s.tokens.path)
+	return nil
+}
+
+// Shutdown stops accepting requests and waits for open streams to finish
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
+}
+
+// rotateTokens replaces the token whenever it expires until ctx is cancelled
+func (s *Server) rotateTokens(ctx context.Context) {
+	defer logging.RecoverPanic("remote-token-rotation", nil)
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if !s.tokens.expired(now) {
+				continue
+			}
+			if err := s.tokens.rotate(); err != nil {
+				logging.Error("Failed to rotate remote API token", "error", err)
+				continue
+			}
+			logging.Info("Rotated remote API token", "token_file", s.tokens.path)
+		}
+	}
+}
+
+// requireEnabled rejects requests while the API is disabled in the configuration
+func (s *Server) requireEnabled(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cfg := config.Get(); cfg == nil || !cfg.Remote.Enabled {
+			writeError(w, http.StatusServiceUnavailable, "remote API is disabled")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireToken rejects requests without the current bearer token
+func (s *Server) requireToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || !s.tokens.Valid(strings.TrimSpace(token)) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="intelligence-interface"`)
+			writeError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	sessions, err := s.sessions.List(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to list sessions: %v", err))
+		return
+	}
+
+	result := make([]SessionResponse, 0, len(sessions))
+	for _, sess := range sessions {
+		result = append(result, SessionResponse{
+			ID:                sess.ID,
+			ParentSessionID:   sess.ParentSessionID,
+			Title:             sess.Title,
+			MessageCount:      sess.MessageCount,
+			PromptTokens:      sess.PromptTokens,
+			CompletionTokens:  sess.CompletionTokens,
+			Cost:              sess.Cost,
+			ConfigFingerprint: sess.ConfigFingerprint,
+			CreatedAt:         sess.CreatedAt,
+			UpdatedAt:         sess.UpdatedAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleSendMessage sends a prompt to the agent and streams the assistant
+// messages of the session as server-sent events until the agent finishes.
+// Disconnecting cancels the request.
+func (s *Server) handleSendMessage(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.PathValue("id")
+
+	var req sendMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		writeError(w, http.StatusBadRequest, "content is required")
+		return
+	}
+
+	if _, err := s.sessions.Get(r.Context(), sessionID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("session not found: %s", sessionID))
+			return
+		}
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to get session: %v", err))
+		return
+	}
+
+	// A session another instance writes to is read-only here
+	if s.locks != nil {
+		err := s.locks.Acquire(r.Context(), sessionID)
+		if errors.Is(err, lock.ErrSessionLocked) {
+			writeError(w, http.StatusLocked, err.Error())
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	// Subscribe before starting so no update is missed
+	updates := s.messages.Subscribe(ctx)
+	done, err := s.agent.Run(ctx, sessionID, req.Content)
+	if errors.Is(err, agent.ErrSessionBusy) {
+		writeError(w, http.StatusConflict, "session is busy with another request")
+		return
+	}
+	if errors.Is(err, connectivity.ErrOffline) {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to run agent: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case event, ok := <-updates:
+			if !ok {
+				updates = nil
+				continue
+			}
+			msg := event.Payload
+			if msg.SessionID != sessionID || msg.Role != message.Assistant {
+				continue
+			}
+			writeEvent(w, "message", toMessageResponse(msg))
+			flusher.Flush()
+
+		case result := <-done:
+			if result.Error != nil {
+				writeEvent(w, "error", map[string]string{"error": result.Error.Error()})
+			} else {
+				writeEvent(w, "done", toMessageResponse(result.Message))
+			}
+			flusher.Flush()
+			return
+
+		case <-ctx.Done():
+			// The client went away and the run is cancelled; the agent
+			// still delivers its result, which nobody reads from here on
+			go func() {
+				for range done {
+				}
+			}()
+			return
+		}
+	}
+}
+
+func (s *Server) handleIntrospection(w http.ResponseWriter, r *http.Request) {
+	result, err := s.coordination.GetSystemIntrospection(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to get system introspection: %v", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleCreatePlan(w http.ResponseWriter, r *http.Request) {
+	var req createPlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	if strings.TrimSpace(req.TaskDescription) == "" {
+		writeError(w, http.StatusBadRequest, "task_description is required")
+		return
+	}
+
+	plan, err := s.coordination.CreateTaskPlan(r.Context(), req.TaskDescription, req.Requirements, req.Template)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("failed to create task plan: %v", err))
+		return
+	}
+	writeJSON(w, http.StatusCreated, plan)
+}
+
+func toMessageResponse(msg message.Message) MessageResponse {
+	response := MessageResponse{
+		ID:        msg.ID,
+		SessionID: msg.SessionID,
+		Role:      string(msg.Role),
+		Content:   msg.Content().String(),
+		Finished:  msg.IsFinished(),
+	}
+	for _, call := range msg.ToolCalls() {
+		response.ToolCalls = append(response.ToolCalls, call.Name)
+	}
+	if response.Finished {
+		response.FinishReason = string(msg.FinishReason())
+	}
+	return response
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		logging.Error("Failed to write remote API response", "error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// writeEvent writes a server-sent event with a JSON payload
+func writeEvent(w http.ResponseWriter, name string, value any) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		logging.Error("Failed to encode remote API event", "error", err)
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+}
@@ -0,0 +1,145 @@
+package remote
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/db"
+	"github.com/caronex/intelligence-interface/internal/lock"
+	"github.com/caronex/intelligence-interface/internal/session"
+)
+
+func newTestServer(t *testing.T, enabled bool) (*Server, string) {
+	t.Helper()
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
+	cfg.Data.Directory = t.TempDir()
+	cfg.Remote.Enabled = enabled
+
+	server, err := New(cfg, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return server, server.tokens.Token()
+}
+
+func serve(server *Server, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestServerStatusCodes(t *testing.T) {
+	server, token := newTestServer(t, true)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   string
+		want   int
+	}{
+		{"missing token", http.MethodGet, "/introspection", "", "", http.StatusUnauthorized},
+		{"wrong token", http.MethodGet, "/introspection", "not-the-token", "", http.StatusUnauthorized},
+		{"introspection", http.MethodGet, "/introspection", token, "", http.StatusOK},
+		{"plan", http.MethodPost, "/coordination/plans", token, `{"task_description":"add login","template":"feature-implementation"}`, http.StatusCreated},
+		{"plan without task", http.MethodPost, "/coordination/plans", token, `{}`, http.StatusBadRequest},
+		{"plan with unknown template", http.MethodPost, "/coordination/plans", token, `{"task_description":"x","template":"nope"}`, http.StatusBadRequest},
+		{"wrong method", http.MethodDelete, "/introspection", token, "", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serve(server, tt.method, tt.path, tt.token, tt.body).Code; got != tt.want {
+				t.Errorf("%s %s = %d, want %d", tt.method, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerSessionLockedByAnotherInstance(t *testing.T) {
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
+	cfg.Data.Directory = t.TempDir()
+	cfg.Remote.Enabled = true
+
+	// Each instance has its own connection to the shared database
+	connect := func() *db.Queries {
+		conn, err := db.Connect()
+		if err != nil {
+			t.Fatalf("db.Connect() error = %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return db.New(conn)
+	}
+	other, local := connect(), connect()
+
+	sessions := session.NewService(local)
+	sess, err := sessions.Create(context.Background(), "shared")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := lock.NewService(other, lock.DefaultStaleAfter).Acquire(context.Background(), sess.ID); err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	server, err := New(cfg, sessions, nil, nil, lock.NewService(local, lock.DefaultStaleAfter))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	rec := serve(server, http.MethodPost, "/sessions/"+sess.ID+"/messages", server.tokens.Token(), `{"content":"hi"}`)
+	if rec.Code != http.StatusLocked {
+		t.Errorf("sending to a session locked by another instance = %d, want %d", rec.Code, http.StatusLocked)
+	}
+	if !strings.Contains(rec.Body.String(), "open in another instance") {
+		t.Errorf("unexpected body %q", rec.Body.String())
+	}
+}
+
+func TestServerDisabled(t *testing.T) {
+	server, token := newTestServer(t, false)
+	if got := serve(server, http.MethodGet, "/introspection", token, "").Code; got != http.StatusServiceUnavailable {
+		t.Errorf("request while disabled = %d, want %d", got, http.StatusServiceUnavailable)
+	}
+}
+
+func TestTokenRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", config.RemoteTokenFilename)
+	store, err := loadToken(path, time.Hour)
+	if err != nil {
+		t.Fatalf("loadToken() error = %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("token file not written: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("token file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	reloaded, err := loadToken(path, time.Hour)
+	if err != nil || reloaded.Token() != store.Token() {
+		t.Fatalf("reloading a fresh token gave %q (err %v), want %q", reloaded.Token(), err, store.Token())
+	}
+
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := loadToken(path, time.Hour)
+	if err != nil {
+		t.Fatalf("loadToken() error = %v", err)
+	}
+	if rotated.Token() == store.Token() || rotated.Valid(store.Token()) {
+		t.Errorf("expired token was not rotated")
+	}
+}
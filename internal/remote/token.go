@@ -0,0 +1,99 @@
+package remote
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenBytes is the amount of randomness in a generated bearer token
+const tokenBytes = 32
+
+// tokenStore keeps the bearer token of the HTTP API in memory and in a file
+// only the current user can read, so local scripts can pick it up.
+type tokenStore struct {
+	path     string
+	rotation time.Duration
+
+	mu        sync.RWMutex
+	token     string
+	createdAt time.Time
+}
+
+// loadToken reads the token at path, generating a new one when the file does
+// not exist or the token is older than the rotation interval.
+func loadToken(path string, rotation time.Duration) (*tokenStore, error) {
+	store := &tokenStore{path: path, rotation: rotation}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read token file: %w", err)
+	}
+	if err == nil {
+		info, statErr := os.Stat(path)
+		if statErr != nil {
+			return nil, fmt.Errorf("failed to stat token file: %w", statErr)
+		}
+		store.token = strings.TrimSpace(string(data))
+		store.createdAt = info.ModTime()
+	}
+
+	if store.token == "" || store.expired(time.Now()) {
+		if err := store.rotate(); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+// Token returns the current bearer token.
+func (s *tokenStore) Token() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.token
+}
+
+// Valid reports whether candidate matches the current token.
+func (s *tokenStore) Valid(candidate string) bool {
+	token := s.Token()
+	return token != "" && subtle.ConstantTimeCompare([]byte(candidate), []byte(token)) == 1
+}
+
+// expired reports whether the token is due for rotation.
+func (s *tokenStore) expired(now time.Time) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rotation > 0 && now.Sub(s.createdAt) >= s.rotation
+}
+
+// rotate generates a new token and writes it to the token file.
+func (s *tokenStore) rotate() error {
+	raw := make([]byte, tokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+	if err := os.WriteFile(s.path, []byte(token+"\n"), 0o600); err != nil {
+		return fmt.Errorf("failed to write token file: %w", err)
+	}
+	// WriteFile keeps the mode of an existing file
+	if err := os.Chmod(s.path, 0o600); err != nil {
+		return fmt.Errorf("failed to restrict token file permissions: %w", err)
+	}
+
+	s.mu.Lock()
+	s.token = token
+	s.createdAt = time.Now()
+	s.mu.Unlock()
+	return nil
+}
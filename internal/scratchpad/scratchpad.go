@@ -0,0 +1,421 @@
+// Package scratchpad keeps the working notes of a session, such as a running
+// todo list or intermediate findings, apart from its conversation. A
+// scratchpad is a short document of titled sections with markdown bodies,
+// stored in the directory of its session with its last revisions, so notes
+// overwritten by mistake can be reverted. It is included in the requests of
+// the session as a compact block rather than as messages.
+package scratchpad
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/llm/tokens"
+	"github.com/caronex/intelligence-interface/internal/pubsub"
+)
+
+const (
+	// MaxSections bounds the sections of a scratchpad
+	MaxSections = 20
+	// MaxTitleBytes bounds the title of a section
+	MaxTitleBytes = 80
+	// MaxBytes bounds the titles and bodies of a scratchpad together
+	MaxBytes = 16000
+)
+
+var (
+	// ErrNoSection is returned for a section the scratchpad does not have
+	ErrNoSection = errors.New("section not found")
+	// ErrNoRevision is returned for a revision the scratchpad does not keep
+	ErrNoRevision = errors.New("revision not found")
+)
+
+// Section is a titled part of a scratchpad
+type Section struct {
+	Title string `json:"title"`
+	// Body is the markdown content of the section
+	Body string `json:"body"`
+}
+
+// Revision is a version of the scratchpad of a session
+type Revision struct {
+	SessionID string `json:"session_id"`
+	// Number counts the revisions of the scratchpad from 1, 0 for a
+	// scratchpad never written
+	Number   int       `json:"number"`
+	Sections []Section `json:"sections"`
+	// Author is the agent which wrote the revision, or user
+	Author    string `json:"author"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// Section returns the section of the revision titled title, case
+// insensitively, and whether there is one
+func (r Revision) Section(title string) (Section, bool) {
+	i := r.index(title)
+	if i == -1 {
+		return Section{}, false
+	}
+	return r.Sections[i], true
+}
+
+func (r Revision) index(title string) int {
+	title = strings.TrimSpace(title)
+	return slices.IndexFunc(r.Sections, func(s Section) bool { return strings.EqualFold(s.Title, title) })
+}
+
+// file is the content of the scratchpad file of a session
+type file struct {
+	// Revisions are the revisions kept, oldest first
+	Revisions []Revision `json:"revisions"`
+}
+
+var (
+	// mu serializes the changes to the scratchpad files
+	mu     sync.Mutex
+	broker = pubsub.NewBroker[Revision]()
+)
+
+// Subscribe returns the revisions of the scratchpads as they are written
+func Subscribe(ctx context.Context) <-chan pubsub.Event[Revision] {
+	return broker.Subscribe(ctx)
+}
+
+func load(sessionID string) (file, error) {
+	data, err := os.ReadFile(config.ScratchpadFile(sessionID))
+	if os.IsNotExist(err) {
+		return file{}, nil
+	}
+	if err != nil {
+		return file{}, fmt.Errorf("failed to read the scratchpad: %w", err)
+	}
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return file{}, fmt.Errorf("failed to parse the scratchpad: %w", err)
+	}
+	return f, nil
+}
+
+func save(sessionID string, f file) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode the scratchpad: %w", err)
+	}
+	path := config.ScratchpadFile(sessionID)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create the session directory: %w", err)
+	}
+	// The scratchpad is replaced at once, so a failed write keeps the
+	// previous revisions
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to save the scratchpad: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to save the scratchpad: %w", err)
+	}
+	return nil
+}
+
+// latest returns the current revision of f, an empty one for sessionID when
+// there is none
+func (f file) latest(sessionID string) Revision {
+	if len(f.Revisions) == 0 {
+		return Revision{SessionID: sessionID}
+	}
+	return f.Revisions[len(f.Revisions)-1]
+}
+
+// Get returns the current revision of the scratchpad of a session, without
+// sections when it was never written
+func Get(sessionID string) (Revision, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	f, err := load(sessionID)
+	if err != nil {
+		return Revision{}, err
+	}
+	return f.latest(sessionID), nil
+}
+
+// Revisions returns the revisions of the scratchpad of a session, the current
+// one first
+func Revisions(sessionID string) ([]Revision, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	f, err := load(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(f.Revisions)
+	return f.Revisions, nil
+}
+
+// update writes the sections change returns from the current ones as a new
+// revision, dropping the revisions beyond the configured limit
+func update(sessionID, author string, change func(sections []Section) ([]Section, error)) (Revision, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	f, err := load(sessionID)
+	if err != nil {
+		return Revision{}, err
+	}
+	current := f.latest(sessionID)
+	sections, err := change(slices.Clone(current.Sections))
+	if err != nil {
+		return Revision{}, err
+	}
+	if sections, err = normalize(sections); err != nil {
+		return Revision{}, err
+	}
+	revision := Revision{
+		SessionID: sessionID,
+		Number:    current.Number + 1,
+		Sections:  sections,
+		Author:    author,
+		CreatedAt: time.Now().Unix(),
+	}
+	f.Revisions = append(f.Revisions, revision)
+	if limit := config.Get().Scratchpad.RevisionLimit(); len(f.Revisions) > limit {
+		f.Revisions = f.Revisions[len(f.Revisions)-limit:]
+	}
+	if err := save(sessionID, f); err != nil {
+		return Revision{}, err
+	}
+	broker.Publish(pubsub.UpdatedEvent, revision)
+	return revision, nil
+}
+
+// normalize trims the sections and checks them against the bounds of a
+// scratchpad
+func normalize(sections []Section) ([]Section, error) {
+	size := 0
+	normalized := make([]Section, 0, len(sections))
+	for _, section := range sections {
+		section.Title = strings.TrimSpace(section.Title)
+		section.Body = strings.TrimSpace(section.Body)
+		if section.Title == "" {
+			return nil, fmt.Errorf("a section has no title")
+		}
+		if strings.ContainsAny(section.Title, "\r\n") {
+			return nil, fmt.Errorf("the title of section %q spans several lines", section.Title)
+		}
+		if len(section.Title) > MaxTitleBytes {
+			return nil, fmt.Errorf("the title of section %q is %d bytes, the limit is %d", section.Title, len(section.Title), MaxTitleBytes)
+		}
+		if (Revision{Sections: normalized}).index(section.Title) != -1 {
+			return nil, fmt.Errorf("there are several sections titled %q", section.Title)
+		}
+		size += len(section.Title) + len(section.Body)
+		normalized = append(normalized, section)
+	}
+	if len(normalized) > MaxSections {
+		return nil, fmt.Errorf("the scratchpad has %d sections, the limit is %d", len(normalized), MaxSections)
+	}
+	if size > MaxBytes {
+		return nil, fmt.Errorf("the scratchpad is %d bytes, the limit is %d; shorten or remove sections", size, MaxBytes)
+	}
+	return normalized, nil
+}
+
+// Replace sets the body of a section, adding the section last when the
+// scratchpad does not have it
+func Replace(sessionID, author, title, body string) (Revision, error) {
+	return update(sessionID, author, func(sections []Section) ([]Section, error) {
+		if i := (Revision{Sections: sections}).index(title); i != -1 {
+			sections[i].Body = body
+			return sections, nil
+		}
+		return append(sections, Section{Title: title, Body: body}), nil
+	})
+}
+
+// Patch replaces the text old of the body of a section with replacement. old
+// must be found exactly once in the body.
+func Patch(sessionID, author, title, old, replacement string) (Revision, error) {
+	return update(sessionID, author, func(sections []Section) ([]Section, error) {
+		i := (Revision{Sections: sections}).index(title)
+		if i == -1 {
+			return nil, fmt.Errorf("%w: %s", ErrNoSection, title)
+		}
+		switch n := strings.Count(sections[i].Body, old); {
+		case old == "":
+			return nil, fmt.Errorf("the text to replace is empty")
+		case n == 0:
+			return nil, fmt.Errorf("the text to replace is not in section %q", sections[i].Title)
+		case n > 1:
+			return nil, fmt.Errorf("the text to replace is found %d times in section %q, include more of it", n, sections[i].Title)
+		}
+		sections[i].Body = strings.Replace(sections[i].Body, old, replacement, 1)
+		return sections, nil
+	})
+}
+
+// Remove removes a section
+func Remove(sessionID, author, title string) (Revision, error) {
+	return update(sessionID, author, func(sections []Section) ([]Section, error) {
+		i := (Revision{Sections: sections}).index(title)
+		if i == -1 {
+			return nil, fmt.Errorf("%w: %s", ErrNoSection, title)
+		}
+		return slices.Delete(sections, i, i+1), nil
+	})
+}
+
+// Set replaces the whole scratchpad, as the user does editing it
+func Set(sessionID, author string, sections []Section) (Revision, error) {
+	return update(sessionID, author, func([]Section) ([]Section, error) {
+		return sections, nil
+	})
+}
+
+// Revert writes the sections of a kept revision as a new revision, so the
+// revert can itself be reverted
+func Revert(sessionID, author string, number int) (Revision, error) {
+	revisions, err := Revisions(sessionID)
+	if err != nil {
+		return Revision{}, err
+	}
+	i := slices.IndexFunc(revisions, func(r Revision) bool { return r.Number == number })
+	if i == -1 {
+		return Revision{}, fmt.Errorf("%w: %d", ErrNoRevision, number)
+	}
+	return Set(sessionID, author, revisions[i].Sections)
+}
+
+// Render returns sections as a markdown document, a level two heading per
+// section, which Parse reads back
+func Render(sections []Section) string {
+	var b strings.Builder
+	for i, section := range sections {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "## %s\n", section.Title)
+		if section.Body != "" {
+			fmt.Fprintf(&b, "\n%s\n", section.Body)
+		}
+	}
+	return b.String()
+}
+
+// Parse reads the sections of a markdown document written by Render, such as
+// one edited by the user. Text before the first heading is a section titled
+// Notes.
+func Parse(markdown string) []Section {
+	var sections []Section
+	var current *Section
+	var lines []string
+	flush := func() {
+		body := strings.TrimSpace(strings.Join(lines, "\n"))
+		if current == nil && body != "" {
+			current = &Section{Title: "Notes"}
+		}
+		if current != nil {
+			current.Body = body
+			sections = append(sections, *current)
+		}
+		lines = nil
+	}
+	inFence := false
+	for _, line := range strings.Split(markdown, "\n") {
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+		}
+		if title, ok := strings.CutPrefix(line, "## "); ok && !inFence {
+			flush()
+			current = &Section{Title: strings.TrimSpace(title)}
+			continue
+		}
+		lines = append(lines, line)
+	}
+	flush()
+	return sections
+}
+
+// PromptContext returns the scratchpad of a session as included in its
+// requests, up to budget tokens, "" when it has no sections. The sections
+// beyond the budget are listed by title, to be read with the scratchpad_read
+// tool.
+func PromptContext(sessionID string, budget int) string {
+	revision, err := Get(sessionID)
+	if err != nil || len(revision.Sections) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("<scratchpad>\nYour working notes for this session, kept apart from the conversation. Keep them current with scratchpad_update.\n")
+	used := tokens.Estimate(b.String())
+	var omitted []string
+	for _, section := range revision.Sections {
+		text := "\n" + Render([]Section{section})
+		if len(omitted) > 0 || used+tokens.Estimate(text) > budget {
+			omitted = append(omitted, section.Title)
+			continue
+		}
+		used += tokens.Estimate(text)
+		b.WriteString(text)
+	}
+	if len(omitted) > 0 {
+		fmt.Fprintf(&b, "\n(Sections left out, read them with scratchpad_read: %s)\n", strings.Join(omitted, ", "))
+	}
+	b.WriteString("</scratchpad>")
+	return b.String()
+}
+
+// Export returns the revisions of the scratchpad of a session as JSON, nil
+// when it was never written
+func Export(sessionID string) ([]byte, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	f, err := load(sessionID)
+	if err != nil || len(f.Revisions) == 0 {
+		return nil, err
+	}
+	data, err := json.Marshal(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the scratchpad: %w", err)
+	}
+	return data, nil
+}
+
+// Import restores the revisions written by Export as the scratchpad of a
+// session, which may have another ID than the exported one
+func Import(sessionID string, data []byte) error {
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("failed to parse the scratchpad: %w", err)
+	}
+	for i := range f.Revisions {
+		f.Revisions[i].SessionID = sessionID
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	return save(sessionID, f)
+}
+
+// Transcript returns the scratchpad of a session as a section of its
+// Markdown transcript, "" when it has no sections
+func Transcript(sessionID string) (string, error) {
+	revision, err := Get(sessionID)
+	if err != nil || len(revision.Sections) == 0 {
+		return "", err
+	}
+	var b strings.Builder
+	b.WriteString("\n## Scratchpad\n")
+	for _, section := range revision.Sections {
+		fmt.Fprintf(&b, "\n### %s\n", section.Title)
+		if section.Body != "" {
+			fmt.Fprintf(&b, "\n%s\n", section.Body)
+		}
+	}
+	return b.String(), nil
+}
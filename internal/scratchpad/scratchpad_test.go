@@ -0,0 +1,174 @@
+package scratchpad
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupSession points the session directories at an empty data directory,
+// opts customizing the rest of the configuration
+func setupSession(t *testing.T, opts ...config.TestConfigOption) string {
+	t.Helper()
+	opts = append([]config.TestConfigOption{config.WithWorkingDir(t.TempDir()), config.WithDataDir(t.TempDir())}, opts...)
+	config.NewTestConfig(opts...)
+	return "session-1"
+}
+
+func TestUpdates(t *testing.T) {
+	sessionID := setupSession(t)
+
+	empty, err := Get(sessionID)
+	require.NoError(t, err)
+	assert.Zero(t, empty.Number)
+	assert.Empty(t, empty.Sections)
+
+	events := Subscribe(t.Context())
+	_, err = Replace(sessionID, "coder", "Todo", "- [ ] parse\n- [ ] test")
+	require.NoError(t, err)
+	select {
+	case event := <-events:
+		assert.Equal(t, sessionID, event.Payload.SessionID)
+		assert.Equal(t, 1, event.Payload.Number)
+	case <-time.After(time.Second):
+		t.Fatal("no event for the revision")
+	}
+
+	_, err = Replace(sessionID, "coder", " Findings ", "The lexer drops CRLF")
+	require.NoError(t, err)
+	revision, err := Patch(sessionID, "coder", "todo", "- [ ] parse", "- [x] parse")
+	require.NoError(t, err)
+	assert.Equal(t, 3, revision.Number)
+	assert.Equal(t, []Section{
+		{Title: "Todo", Body: "- [x] parse\n- [ ] test"},
+		{Title: "Findings", Body: "The lexer drops CRLF"},
+	}, revision.Sections)
+
+	_, err = Patch(sessionID, "coder", "Todo", "- [ ] deploy", "- [x] deploy")
+	assert.ErrorContains(t, err, "not in section")
+	_, err = Patch(sessionID, "coder", "Todo", "-", "*")
+	assert.ErrorContains(t, err, "found 2 times")
+	_, err = Patch(sessionID, "coder", "Plan", "a", "b")
+	assert.ErrorIs(t, err, ErrNoSection)
+
+	revision, err = Remove(sessionID, "user", "Findings")
+	require.NoError(t, err)
+	assert.Len(t, revision.Sections, 1)
+	assert.Equal(t, "user", revision.Author)
+
+	// Failed updates write no revision
+	current, err := Get(sessionID)
+	require.NoError(t, err)
+	assert.Equal(t, 4, current.Number)
+}
+
+func TestBounds(t *testing.T) {
+	sessionID := setupSession(t)
+
+	tests := []struct {
+		name     string
+		sections []Section
+		want     string
+	}{
+		{"no title", []Section{{Title: " ", Body: "x"}}, "no title"},
+		{"multiline title", []Section{{Title: "a\nb"}}, "several lines"},
+		{"long title", []Section{{Title: strings.Repeat("t", MaxTitleBytes+1)}}, "the limit is 80"},
+		{"duplicate", []Section{{Title: "Todo"}, {Title: "todo"}}, "several sections"},
+		{"too big", []Section{{Title: "Log", Body: strings.Repeat("x", MaxBytes)}}, "the limit is 16000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Set(sessionID, "user", tt.sections)
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+	var many []Section
+	for i := 0; i <= MaxSections; i++ {
+		many = append(many, Section{Title: strings.Repeat("s", i+1)})
+	}
+	_, err := Set(sessionID, "user", many)
+	assert.ErrorContains(t, err, "21 sections")
+}
+
+func TestRevisions(t *testing.T) {
+	sessionID := setupSession(t, func(cfg *config.Config) { cfg.Scratchpad.Revisions = 3 })
+
+	for _, body := range []string{"one", "two", "three", "four"} {
+		_, err := Replace(sessionID, "coder", "Notes", body)
+		require.NoError(t, err)
+	}
+	revisions, err := Revisions(sessionID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 3, "only the last revisions are kept")
+	assert.Equal(t, 4, revisions[0].Number)
+	assert.Equal(t, 2, revisions[2].Number)
+
+	// An overwrite is reverted by writing the older sections again
+	reverted, err := Revert(sessionID, "user", 3)
+	require.NoError(t, err)
+	assert.Equal(t, 5, reverted.Number)
+	assert.Equal(t, "three", reverted.Sections[0].Body)
+	_, err = Revert(sessionID, "user", 1)
+	assert.ErrorIs(t, err, ErrNoRevision)
+}
+
+func TestRenderParse(t *testing.T) {
+	sections := []Section{
+		{Title: "Todo", Body: "- [ ] parse\n\n```go\n## not a heading\n```"},
+		{Title: "Empty"},
+		{Title: "Findings", Body: "CRLF"},
+	}
+	assert.Equal(t, sections, Parse(Render(sections)))
+	assert.Equal(t, []Section{{Title: "Notes", Body: "loose text"}, {Title: "Todo", Body: "x"}}, Parse("loose text\n\n## Todo\nx\n"))
+	assert.Empty(t, Parse("  \n"))
+}
+
+func TestPromptContext(t *testing.T) {
+	sessionID := setupSession(t)
+	assert.Empty(t, PromptContext(sessionID, 100))
+
+	_, err := Set(sessionID, "coder", []Section{
+		{Title: "Todo", Body: "- [ ] parse"},
+		{Title: "Log", Body: strings.Repeat("a long line of findings\n", 100)},
+		{Title: "Decisions", Body: "Keep CRLF"},
+	})
+	require.NoError(t, err)
+
+	block := PromptContext(sessionID, 200)
+	assert.True(t, strings.HasPrefix(block, "<scratchpad>"))
+	assert.True(t, strings.HasSuffix(block, "</scratchpad>"))
+	assert.Contains(t, block, "## Todo\n\n- [ ] parse")
+	assert.NotContains(t, block, "a long line of findings")
+	assert.Contains(t, block, "read them with scratchpad_read: Log, Decisions", "the sections after the first left out keep their order")
+
+	assert.Contains(t, PromptContext(sessionID, 10000), "Keep CRLF")
+}
+
+func TestExportImport(t *testing.T) {
+	sessionID := setupSession(t)
+	data, err := Export(sessionID)
+	require.NoError(t, err)
+	assert.Nil(t, data)
+
+	_, err = Replace(sessionID, "coder", "Todo", "- [ ] parse")
+	require.NoError(t, err)
+	_, err = Replace(sessionID, "coder", "Todo", "- [x] parse")
+	require.NoError(t, err)
+	data, err = Export(sessionID)
+	require.NoError(t, err)
+
+	require.NoError(t, Import("session-2", data))
+	revisions, err := Revisions("session-2")
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, "session-2", revisions[0].SessionID)
+	assert.Equal(t, "- [x] parse", revisions[0].Sections[0].Body)
+
+	transcript, err := Transcript("session-2")
+	require.NoError(t, err)
+	assert.Equal(t, "\n## Scratchpad\n\n### Todo\n\n- [x] parse\n", transcript)
+}
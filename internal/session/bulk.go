@@ -0,0 +1,283 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/audit"
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/db"
+	"github.com/caronex/intelligence-interface/internal/pubsub"
+)
+
+// Filter selects sessions for the batch operations. Its zero value selects
+// every session which is not archived.
+type Filter struct {
+	// OlderThan selects the sessions created at least that long ago
+	OlderThan time.Duration
+	// Agent selects the sessions answered by the agent
+	Agent string
+	// Tag selects the sessions tagged with it
+	Tag string
+	// Empty selects the sessions without any user message
+	Empty bool
+	// Text selects the sessions whose title or tags contain it, ignoring case
+	Text string
+	// IncludeArchived selects the archived sessions too
+	IncludeArchived bool
+}
+
+// ParseFilter parses a filter typed in a search, made of words matched
+// against the titles and tags of the sessions and of the terms older:<age>,
+// agent:<name>, tag:<tag>, empty and archived
+func ParseFilter(query string) (Filter, error) {
+	var filter Filter
+	var words []string
+	for _, term := range strings.Fields(query) {
+		key, value, _ := strings.Cut(term, ":")
+		switch {
+		case key == "older" && value != "":
+			age, err := ParseAge(value)
+			if err != nil {
+				return Filter{}, err
+			}
+			filter.OlderThan = age
+		case key == "agent" && value != "":
+			filter.Agent = value
+		case key == "tag" && value != "":
+			filter.Tag = value
+		case term == "empty":
+			filter.Empty = true
+		case term == "archived":
+			filter.IncludeArchived = true
+		default:
+			words = append(words, term)
+		}
+	}
+	filter.Text = strings.Join(words, " ")
+	return filter, nil
+}
+
+// ParseAge parses an age given in days or weeks, such as 90d or 2w, or as a
+// duration, such as 12h
+func ParseAge(age string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(age, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil || count < 0 {
+				return 0, fmt.Errorf("invalid age %q", age)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+	duration, err := time.ParseDuration(age)
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("invalid age %q, use a number of days such as 90d", age)
+	}
+	return duration, nil
+}
+
+// ParseTags parses the comma separated tags typed by the user, dropping the
+// empty and repeated ones
+func ParseTags(tags string) []string {
+	var parsed []string
+	for _, tag := range strings.Split(tags, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !slices.Contains(parsed, tag) {
+			parsed = append(parsed, tag)
+		}
+	}
+	return parsed
+}
+
+// Match reports whether the filter selects session, which has userMessages
+// user messages, at now
+func (f Filter) Match(session Session, userMessages int64, now time.Time) bool {
+	if session.Archived && !f.IncludeArchived {
+		return false
+	}
+	if f.OlderThan > 0 && now.Sub(time.Unix(session.CreatedAt, 0)) < f.OlderThan {
+		return false
+	}
+	if f.Agent != "" && session.Agent != f.Agent {
+		return false
+	}
+	if f.Tag != "" && !slices.Contains(session.Tags, f.Tag) {
+		return false
+	}
+	if f.Empty && userMessages > 0 {
+		return false
+	}
+	if f.Text != "" {
+		text := strings.ToLower(f.Text)
+		if !strings.Contains(strings.ToLower(session.Title), text) &&
+			!slices.ContainsFunc(session.Tags, func(tag string) bool { return strings.Contains(strings.ToLower(tag), text) }) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *service) Find(ctx context.Context, filter Filter) ([]Session, error) {
+	sessions, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var userMessages map[string]int64
+	if filter.Empty {
+		counts, err := s.q.ListUserMessageCounts(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count the user messages: %w", err)
+		}
+		userMessages = make(map[string]int64, len(counts))
+		for _, count := range counts {
+			userMessages[count.SessionID] = count.UserMessages
+		}
+	}
+	now := time.Now()
+	var found []Session
+	for _, session := range sessions {
+		if filter.Match(session, userMessages[session.ID], now) {
+			found = append(found, session)
+		}
+	}
+	return found, nil
+}
+
+func (s *service) Archive(ctx context.Context, ids []string, archived bool) (int, error) {
+	changed := 0
+	for _, id := range ids {
+		session, err := s.Get(ctx, id)
+		if err != nil {
+			return changed, err
+		}
+		if session.Archived == archived {
+			continue
+		}
+		session.Archived = archived
+		if _, err := s.Save(ctx, session); err != nil {
+			return changed, err
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+// DeleteReport counts what a batch delete removed, or would remove for a dry
+// run
+type DeleteReport struct {
+	// Sessions counts the sessions deleted with their child sessions
+	Sessions       int
+	Messages       int64
+	ToolOperations int64
+	Artifacts      int
+	AuditEntries   int
+}
+
+type transactor interface {
+	Transaction(ctx context.Context, fn func(*db.Queries) error) error
+}
+
+func (s *service) DeleteMany(ctx context.Context, ids []string, dryRun bool) (DeleteReport, error) {
+	q, ok := s.q.(transactor)
+	if !ok {
+		return DeleteReport{}, fmt.Errorf("the session store does not support transactions")
+	}
+
+	var report DeleteReport
+	var deleted []Session
+	err := q.Transaction(ctx, func(tx *db.Queries) error {
+		// Child sessions have no foreign key to their parent, so they are
+		// deleted explicitly, while the messages, files, locks and tool
+		// operations of each session cascade
+		seen := make(map[string]bool)
+		pending := slices.Clone(ids)
+		for len(pending) > 0 {
+			id := pending[0]
+			pending = pending[1:]
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			item, err := tx.GetSessionByID(ctx, id)
+			if err != nil {
+				return fmt.Errorf("failed to get session %s: %w", id, err)
+			}
+			children, err := tx.ListChildSessions(ctx, sql.NullString{String: id, Valid: true})
+			if err != nil {
+				return fmt.Errorf("failed to list the child sessions of %s: %w", id, err)
+			}
+			for _, child := range children {
+				pending = append(pending, child.ID)
+			}
+			operations, err := tx.CountSessionToolOperations(ctx, id)
+			if err != nil {
+				return fmt.Errorf("failed to count the tool operations of %s: %w", id, err)
+			}
+			if err := tx.DeleteSession(ctx, id); err != nil {
+				return fmt.Errorf("failed to delete session %s: %w", id, err)
+			}
+			report.Sessions++
+			report.Messages += item.MessageCount
+			report.ToolOperations += operations
+			report.Artifacts += countArtifacts(id)
+			deleted = append(deleted, s.fromDBItem(item))
+		}
+		if dryRun {
+			return db.ErrRollback
+		}
+		return nil
+	})
+	if err != nil {
+		return DeleteReport{}, err
+	}
+
+	deletedIDs := make([]string, len(deleted))
+	for i, session := range deleted {
+		deletedIDs[i] = session.ID
+	}
+	if config.Get() != nil {
+		entries, err := audit.DeleteSessions(deletedIDs, dryRun)
+		if err != nil {
+			logging.Warn("Failed to remove the audit entries of the deleted sessions", "error", err)
+		}
+		report.AuditEntries = entries
+	}
+	if dryRun {
+		return report, nil
+	}
+	for _, session := range deleted {
+		// The files of the session, such as its artifacts, go with it
+		if err := os.RemoveAll(config.SessionDirectory(session.ID)); err != nil {
+			logging.Warn("Failed to remove the files of the session", "session", session.ID, "error", err)
+		}
+		s.Publish(pubsub.DeletedEvent, session)
+	}
+	return report, nil
+}
+
+// countArtifacts counts the artifacts saved for a session
+func countArtifacts(sessionID string) int {
+	if config.Get() == nil {
+		return 0
+	}
+	entries, err := os.ReadDir(filepath.Join(config.SessionDirectory(sessionID), "artifacts"))
+	if err != nil {
+		return 0
+	}
+	count := 0
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,184 @@
+package session
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/audit"
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/db"
+)
+
+func TestParseFilter(t *testing.T) {
+	filter, err := ParseFilter("older:90d agent:coder tag:spike empty archived release notes")
+	if err != nil {
+		t.Fatalf("ParseFilter() error = %v", err)
+	}
+	want := Filter{
+		OlderThan:       90 * 24 * time.Hour,
+		Agent:           "coder",
+		Tag:             "spike",
+		Empty:           true,
+		Text:            "release notes",
+		IncludeArchived: true,
+	}
+	if filter != want {
+		t.Errorf("ParseFilter() = %+v, want %+v", filter, want)
+	}
+	if _, err := ParseFilter("older:soon"); err == nil {
+		t.Error("ParseFilter() accepted an invalid age")
+	}
+	if age, err := ParseAge("2w"); err != nil || age != 14*24*time.Hour {
+		t.Errorf("ParseAge(2w) = %v, %v", age, err)
+	}
+	if tags := ParseTags(" spike, ,bug,spike "); len(tags) != 2 || tags[0] != "spike" || tags[1] != "bug" {
+		t.Errorf("ParseTags() = %q, want [spike bug]", tags)
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	now := time.Now()
+	old := Session{Title: "Release notes", CreatedAt: now.Add(-100 * 24 * time.Hour).Unix(), Agent: "coder", Tags: []string{"docs"}}
+	recent := Session{Title: "Fix the parser", CreatedAt: now.Unix(), Agent: "caronex"}
+	archived := Session{Title: "Old spike", CreatedAt: now.Unix(), Archived: true}
+
+	tests := []struct {
+		name    string
+		filter  Filter
+		session Session
+		users   int64
+		want    bool
+	}{
+		{"default", Filter{}, recent, 1, true},
+		{"archived hidden", Filter{}, archived, 1, false},
+		{"archived included", Filter{IncludeArchived: true}, archived, 1, true},
+		{"older", Filter{OlderThan: 90 * 24 * time.Hour}, old, 1, true},
+		{"not older", Filter{OlderThan: 90 * 24 * time.Hour}, recent, 1, false},
+		{"agent", Filter{Agent: "coder"}, recent, 1, false},
+		{"tag", Filter{Tag: "docs"}, old, 1, true},
+		{"empty", Filter{Empty: true}, recent, 1, false},
+		{"empty without user messages", Filter{Empty: true}, recent, 0, true},
+		{"text in title", Filter{Text: "PARSER"}, recent, 1, true},
+		{"text in tags", Filter{Text: "doc"}, old, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(tt.session, tt.users, now); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeleteMany(t *testing.T) {
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
+	cfg.Data.Directory = t.TempDir()
+	conn, err := db.Connect()
+	if err != nil {
+		t.Fatalf("db.Connect() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	q := db.New(conn)
+	sessions := NewService(q)
+	ctx := context.Background()
+
+	parent, err := sessions.Create(ctx, "parent")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	child, err := sessions.CreateBranchSession(ctx, parent.ID, "branch")
+	if err != nil {
+		t.Fatalf("CreateBranchSession() error = %v", err)
+	}
+	kept, err := sessions.Create(ctx, "kept")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	for i, sessionID := range []string{parent.ID, parent.ID, child.ID, kept.ID} {
+		if _, err := q.CreateMessage(ctx, db.CreateMessageParams{
+			ID:        "message-" + string(rune('a'+i)),
+			SessionID: sessionID,
+			Role:      "user",
+			Parts:     "[]",
+			Status:    "finished",
+		}); err != nil {
+			t.Fatalf("CreateMessage() error = %v", err)
+		}
+	}
+	if err := q.CreateToolOperation(ctx, db.CreateToolOperationParams{ID: "op", SessionID: child.ID, MessageID: "message-c", Tool: "write"}); err != nil {
+		t.Fatalf("CreateToolOperation() error = %v", err)
+	}
+	artifacts := filepath.Join(config.SessionDirectory(parent.ID), "artifacts")
+	if err := os.MkdirAll(artifacts, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(artifacts, "notes.md"), []byte("# Notes\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, sessionID := range []string{parent.ID, child.ID, kept.ID} {
+		if err := audit.Record(audit.Entry{Actor: "coder", SessionID: sessionID, Tool: "write"}); err != nil {
+			t.Fatalf("audit.Record() error = %v", err)
+		}
+	}
+
+	want := DeleteReport{Sessions: 2, Messages: 3, ToolOperations: 1, Artifacts: 1, AuditEntries: 2}
+	report, err := sessions.DeleteMany(ctx, []string{parent.ID}, true)
+	if err != nil {
+		t.Fatalf("DeleteMany() dry run error = %v", err)
+	}
+	if report != want {
+		t.Errorf("DeleteMany() dry run = %+v, want %+v", report, want)
+	}
+	if _, err := sessions.Get(ctx, child.ID); err != nil {
+		t.Fatalf("a dry run deleted the child session: %v", err)
+	}
+	if entries, _ := audit.List(); len(entries) != 3 {
+		t.Errorf("a dry run left %d audit entries, want 3", len(entries))
+	}
+
+	report, err = sessions.DeleteMany(ctx, []string{parent.ID}, false)
+	if err != nil {
+		t.Fatalf("DeleteMany() error = %v", err)
+	}
+	if report != want {
+		t.Errorf("DeleteMany() = %+v, want %+v", report, want)
+	}
+	for _, id := range []string{parent.ID, child.ID} {
+		if _, err := sessions.Get(ctx, id); err == nil {
+			t.Errorf("session %s still exists after DeleteMany()", id)
+		}
+	}
+	if _, err := os.Stat(config.SessionDirectory(parent.ID)); !os.IsNotExist(err) {
+		t.Errorf("session directory still exists after DeleteMany(), stat error = %v", err)
+	}
+	if counts, _ := q.ListUserMessageCounts(ctx); len(counts) != 1 || counts[0].SessionID != kept.ID {
+		t.Errorf("user message counts = %+v, want only the kept session", counts)
+	}
+	if entries, _ := audit.List(); len(entries) != 1 || entries[0].SessionID != kept.ID {
+		t.Errorf("audit entries = %+v, want only the kept session", entries)
+	}
+
+	empty, err := sessions.Create(ctx, "empty")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	found, err := sessions.Find(ctx, Filter{Empty: true})
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if len(found) != 1 || found[0].ID != empty.ID {
+		t.Errorf("Find(empty) = %+v, want the empty session", found)
+	}
+	if changed, err := sessions.Archive(ctx, []string{empty.ID, kept.ID}, true); err != nil || changed != 2 {
+		t.Fatalf("Archive() = %d, %v", changed, err)
+	}
+	if found, _ := sessions.Find(ctx, Filter{}); len(found) != 0 {
+		t.Errorf("Find() = %+v, want the archived sessions hidden", found)
+	}
+	if found, _ := sessions.Find(ctx, Filter{IncludeArchived: true}); len(found) != 2 {
+		t.Errorf("Find(archived) = %+v, want both archived sessions", found)
+	}
+}
@@ -3,8 +3,15 @@ package session
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
 
 	"github.com/google/uuid"
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/core/logging"
 	"github.com/caronex/intelligence-interface/internal/db"
 	"github.com/caronex/intelligence-interface/internal/pubsub"
 )
@@ -16,10 +23,47 @@ type Session struct {
 	MessageCount     int64
 	PromptTokens     int64
 	CompletionTokens int64
+	// CacheReadTokens and CacheWriteTokens break down the prompt tokens served
+	// from and written to the provider's prompt cache, which are billed differently
+	CacheReadTokens  int64
+	CacheWriteTokens int64
+	// RegeneratedTokens and RegeneratedCost are the part of the usage spent
+	// on responses that were retried or resent
+	RegeneratedTokens int64
+	RegeneratedCost   float64
+	// TruncatedTokens and TruncatedCost are the part of the usage spent on
+	// responses cut off by the token limit or a content filter
+	TruncatedTokens  int64
+	TruncatedCost    float64
 	SummaryMessageID string
 	Cost             float64
-	CreatedAt        int64
-	UpdatedAt        int64
+	// ConfigFingerprint identifies the configuration the session was
+	// created with
+	ConfigFingerprint string
+	// SpaceID is the space active when the session was created, "" when none
+	// was
+	SpaceID           string
+	// ExcludedContext are the sources of context left out of the messages
+	// of the session unless a message sets its own
+	ExcludedContext   []string
+	// Tags are the free-form tags the user gave the session
+	Tags              []string
+	// Archived hides the session from the session list unless it is searched
+	Archived          bool
+	// Agent is the name of the agent the session runs with, set from the
+	// defaults of its space or by the agent which answered first, "" until
+	// then
+	Agent             string
+	// Model overrides the model of the agent, "" when it does not
+	Model             string
+	// Generation overrides the generation parameters of the agent, nil when
+	// it does not
+	Generation        *config.GenerationParams
+	// AllowedTools are the only tools the agent may call in the session, all
+	// of them when empty
+	AllowedTools      []string
+	CreatedAt         int64
+	UpdatedAt         int64
 }
 
 type Service interface {
@@ -27,22 +71,51 @@ type Service interface {
 	Create(ctx context.Context, title string) (Session, error)
 	CreateTitleSession(ctx context.Context, parentSessionID string) (Session, error)
 	CreateTaskSession(ctx context.Context, toolCallID, parentSessionID, title string) (Session, error)
+	CreateBranchSession(ctx context.Context, parentSessionID, title string) (Session, error)
 	Get(ctx context.Context, id string) (Session, error)
 	List(ctx context.Context) ([]Session, error)
 	Save(ctx context.Context, session Session) (Session, error)
 	Delete(ctx context.Context, id string) error
+	// Find returns the top-level sessions matching filter, newest first
+	Find(ctx context.Context, filter Filter) ([]Session, error)
+	// Archive archives or unarchives the sessions and returns how many
+	// changed
+	Archive(ctx context.Context, ids []string, archived bool) (int, error)
+	// DeleteMany deletes the sessions with their child sessions and
+	// everything recorded about them at once. A dry run only reports what
+	// would be deleted.
+	DeleteMany(ctx context.Context, ids []string, dryRun bool) (DeleteReport, error)
+	// ConfigChanges returns the changes of the configuration since the
+	// session was created, nil when it did not change
+	ConfigChanges(ctx context.Context, session Session) ([]config.ConfigChange, error)
 }
 
+// ErrConfigSnapshotMissing is returned when the configuration a session was
+// created with changed but was not recorded, so the changes are unknown
+var ErrConfigSnapshotMissing = errors.New("the configuration the session was created with was not recorded")
+
 type service struct {
 	*pubsub.Broker[Session]
 	q db.Querier
 }
 
+// Create creates a session in the active space, with the settings the space
+// gives its sessions
 func (s *service) Create(ctx context.Context, title string) (Session, error) {
-	dbSession, err := s.q.CreateSession(ctx, db.CreateSessionParams{
+	params := db.CreateSessionParams{
 		ID:    uuid.New().String(),
-		Title: title,
-	})
+		Title:             title,
+		ConfigFingerprint: s.recordConfig(ctx),
+		SpaceID:           activeSpace(),
+	}
+	if defaults := config.SpaceSessionDefaults(params.SpaceID); defaults != nil {
+		params.Agent = string(defaults.Agent)
+		params.Model = string(defaults.Model)
+		params.Generation = encodeGeneration(defaults.Generation)
+		params.ExcludedContext = strings.Join(defaults.ExcludedContext, ",")
+		params.AllowedTools = strings.Join(defaults.Tools, ",")
+	}
+	dbSession, err := s.q.CreateSession(ctx, params)
 	if err != nil {
 		return Session{}, err
 	}
@@ -55,7 +128,27 @@ func (s *service) CreateTaskSession(ctx context.Context, toolCallID, parentSessi
 	dbSession, err := s.q.CreateSession(ctx, db.CreateSessionParams{
 		ID:              toolCallID,
 		ParentSessionID: sql.NullString{String: parentSessionID, Valid: true},
-		Title:           title,
+		Title:             title,
+		ConfigFingerprint: s.recordConfig(ctx),
+		SpaceID:           activeSpace(),
+	})
+	if err != nil {
+		return Session{}, err
+	}
+	session := s.fromDBItem(dbSession)
+	s.Publish(pubsub.CreatedEvent, session)
+	return session, nil
+}
+
+// CreateBranchSession creates a hidden child session holding the part of a
+// conversation that was discarded by a retry or an edited message
+func (s *service) CreateBranchSession(ctx context.Context, parentSessionID, title string) (Session, error) {
+	dbSession, err := s.q.CreateSession(ctx, db.CreateSessionParams{
+		ID:              uuid.New().String(),
+		ParentSessionID: sql.NullString{String: parentSessionID, Valid: true},
+		Title:             title,
+		ConfigFingerprint: s.recordConfig(ctx),
+		SpaceID:           activeSpace(),
 	})
 	if err != nil {
 		return Session{}, err
@@ -69,7 +162,9 @@ func (s *service) CreateTitleSession(ctx context.Context, parentSessionID string
 	dbSession, err := s.q.CreateSession(ctx, db.CreateSessionParams{
 		ID:              "title-" + parentSessionID,
 		ParentSessionID: sql.NullString{String: parentSessionID, Valid: true},
-		Title:           "Generate a title",
+		Title:             "Generate a title",
+		ConfigFingerprint: s.recordConfig(ctx),
+		SpaceID:           activeSpace(),
 	})
 	if err != nil {
 		return Session{}, err
@@ -88,6 +183,10 @@ func (s *service) Delete(ctx context.Context, id string) error {
 	if err != nil {
 		return err
 	}
+	// The files of the session, such as its artifacts, go with it
+	if err := os.RemoveAll(config.SessionDirectory(session.ID)); err != nil {
+		logging.Warn("Failed to remove the files of the session", "session", session.ID, "error", err)
+	}
 	s.Publish(pubsub.DeletedEvent, session)
 	return nil
 }
@@ -102,15 +201,28 @@ func (s *service) Get(ctx context.Context, id string) (Session, error) {
 
 func (s *service) Save(ctx context.Context, session Session) (Session, error) {
 	dbSession, err := s.q.UpdateSession(ctx, db.UpdateSessionParams{
-		ID:               session.ID,
-		Title:            session.Title,
-		PromptTokens:     session.PromptTokens,
-		CompletionTokens: session.CompletionTokens,
+		ID:                session.ID,
+		Title:             session.Title,
+		PromptTokens:      session.PromptTokens,
+		CompletionTokens:  session.CompletionTokens,
+		CacheReadTokens:   session.CacheReadTokens,
+		CacheWriteTokens:  session.CacheWriteTokens,
+		RegeneratedTokens: session.RegeneratedTokens,
+		RegeneratedCost:   session.RegeneratedCost,
+		TruncatedTokens:   session.TruncatedTokens,
+		TruncatedCost:     session.TruncatedCost,
 		SummaryMessageID: sql.NullString{
 			String: session.SummaryMessageID,
 			Valid:  session.SummaryMessageID != "",
 		},
-		Cost: session.Cost,
+		Cost:            session.Cost,
+		ExcludedContext: strings.Join(session.ExcludedContext, ","),
+		Tags:            strings.Join(session.Tags, ","),
+		Archived:        session.Archived,
+		Agent:           session.Agent,
+		Model:           session.Model,
+		Generation:      encodeGeneration(session.Generation),
+		AllowedTools:    strings.Join(session.AllowedTools, ","),
 	})
 	if err != nil {
 		return Session{}, err
@@ -134,17 +246,128 @@ func (s *service) List(ctx context.Context) ([]Session, error) {
 
 func (s service) fromDBItem(item db.Session) Session {
 	return Session{
-		ID:               item.ID,
-		ParentSessionID:  item.ParentSessionID.String,
-		Title:            item.Title,
-		MessageCount:     item.MessageCount,
-		PromptTokens:     item.PromptTokens,
-		CompletionTokens: item.CompletionTokens,
-		SummaryMessageID: item.SummaryMessageID.String,
-		Cost:             item.Cost,
-		CreatedAt:        item.CreatedAt,
-		UpdatedAt:        item.UpdatedAt,
+		ID:                item.ID,
+		ParentSessionID:   item.ParentSessionID.String,
+		Title:             item.Title,
+		MessageCount:      item.MessageCount,
+		PromptTokens:      item.PromptTokens,
+		CompletionTokens:  item.CompletionTokens,
+		CacheReadTokens:   item.CacheReadTokens,
+		CacheWriteTokens:  item.CacheWriteTokens,
+		RegeneratedTokens: item.RegeneratedTokens,
+		RegeneratedCost:   item.RegeneratedCost,
+		TruncatedTokens:   item.TruncatedTokens,
+		TruncatedCost:     item.TruncatedCost,
+		SummaryMessageID:  item.SummaryMessageID.String,
+		Cost:              item.Cost,
+		ConfigFingerprint: item.ConfigFingerprint,
+		SpaceID:           item.SpaceID,
+		ExcludedContext:   splitList(item.ExcludedContext),
+		Tags:              splitList(item.Tags),
+		Archived:          item.Archived,
+		Agent:             item.Agent,
+		Model:             item.Model,
+		Generation:        decodeGeneration(item.Generation),
+		AllowedTools:      splitList(item.AllowedTools),
+		CreatedAt:         item.CreatedAt,
+		UpdatedAt:         item.UpdatedAt,
+	}
+}
+
+// splitList splits a comma separated column, nil when it is empty
+func splitList(list string) []string {
+	if list == "" {
+		return nil
+	}
+	return strings.Split(list, ",")
+}
+
+// encodeGeneration encodes generation parameters for their column, "" for
+// none
+func encodeGeneration(generation *config.GenerationParams) string {
+	if generation == nil {
+		return ""
+	}
+	data, err := json.Marshal(generation)
+	if err != nil {
+		logging.Warn("Failed to encode the generation parameters of the session", "error", err)
+		return ""
+	}
+	return string(data)
+}
+
+// decodeGeneration decodes the generation parameters of a column, nil for
+// none
+func decodeGeneration(column string) *config.GenerationParams {
+	if column == "" {
+		return nil
+	}
+	var generation config.GenerationParams
+	if err := json.Unmarshal([]byte(column), &generation); err != nil {
+		logging.Warn("Failed to decode the generation parameters of the session", "error", err)
+		return nil
+	}
+	return &generation
+}
+
+// activeSpace returns the space the sessions created now belong to
+func activeSpace() string {
+	if config.Get() == nil {
+		return ""
+	}
+	return config.ActiveSpace()
+}
+
+// recordConfig stores a snapshot of the loaded configuration and returns its
+// fingerprint, "" when no configuration is loaded
+func (s *service) recordConfig(ctx context.Context) string {
+	cfg := config.Get()
+	if cfg == nil {
+		return ""
+	}
+	snapshot, err := config.TakeSnapshot(cfg)
+	if err != nil {
+		logging.Warn("Failed to snapshot the configuration", "error", err)
+		return ""
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		logging.Warn("Failed to encode the configuration snapshot", "error", err)
+		return ""
+	}
+	fingerprint := snapshot.Fingerprint()
+	if err := s.q.CreateConfigSnapshot(ctx, db.CreateConfigSnapshotParams{
+		Fingerprint: fingerprint,
+		Snapshot:    string(data),
+	}); err != nil {
+		logging.Warn("Failed to record the configuration snapshot", "error", err, logging.ComponentKey, logging.ComponentDataLoss)
+	}
+	return fingerprint
+}
+
+func (s *service) ConfigChanges(ctx context.Context, session Session) ([]config.ConfigChange, error) {
+	cfg := config.Get()
+	if session.ConfigFingerprint == "" || cfg == nil {
+		return nil, nil
+	}
+	current, err := config.TakeSnapshot(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if current.Fingerprint() == session.ConfigFingerprint {
+		return nil, nil
+	}
+	stored, err := s.q.GetConfigSnapshot(ctx, session.ConfigFingerprint)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrConfigSnapshotMissing
+	} else if err != nil {
+		return nil, err
+	}
+	var snapshot config.Snapshot
+	if err := json.Unmarshal([]byte(stored.Snapshot), &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to decode the configuration snapshot: %w", err)
 	}
+	return snapshot.Diff(current), nil
 }
 
 func NewService(q db.Querier) Service {
@@ -0,0 +1,173 @@
+package session
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/db"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+)
+
+func TestConfigChanges(t *testing.T) {
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
+	cfg.Data.Directory = t.TempDir()
+	conn, err := db.Connect()
+	if err != nil {
+		t.Fatalf("db.Connect() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	sessions := NewService(db.New(conn))
+	ctx := context.Background()
+
+	session, err := sessions.Create(ctx, "test")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if session.ConfigFingerprint != config.CurrentFingerprint() {
+		t.Fatalf("session fingerprint = %q, want %q", session.ConfigFingerprint, config.CurrentFingerprint())
+	}
+	changes, err := sessions.ConfigChanges(ctx, session)
+	if err != nil || changes != nil {
+		t.Fatalf("ConfigChanges() = %v, %v before any change", changes, err)
+	}
+
+	err = config.Update(func(cfg *config.Config) error {
+		agent := cfg.Agents[config.AgentCaronex]
+		agent.MaxTokens = 1234
+		cfg.Agents[config.AgentCaronex] = agent
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("config.Update() error = %v", err)
+	}
+	changes, err = sessions.ConfigChanges(ctx, session)
+	if err != nil {
+		t.Fatalf("ConfigChanges() error = %v", err)
+	}
+	path := "agents." + string(config.AgentCaronex) + ".maxTokens"
+	if len(changes) != 1 || changes[0].Path != path || changes[0].New != "1234" {
+		t.Errorf("ConfigChanges() = %+v, want %s changed to 1234", changes, path)
+	}
+}
+
+func TestDeleteRemovesSessionFiles(t *testing.T) {
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
+	cfg.Data.Directory = t.TempDir()
+	conn, err := db.Connect()
+	if err != nil {
+		t.Fatalf("db.Connect() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	sessions := NewService(db.New(conn))
+	ctx := context.Background()
+
+	session, err := sessions.Create(ctx, "test")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	artifacts := filepath.Join(config.SessionDirectory(session.ID), "artifacts")
+	if err := os.MkdirAll(artifacts, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(artifacts, "schema.sql"), []byte("CREATE TABLE t (id INTEGER);\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sessions.Delete(ctx, session.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := os.Stat(config.SessionDirectory(session.ID)); !os.IsNotExist(err) {
+		t.Errorf("session directory still exists after Delete(), stat error = %v", err)
+	}
+}
+
+func TestSpaceSessionDefaults(t *testing.T) {
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()), config.WithTestAgents("coder", "summarizer"))
+	cfg.Data.Directory = t.TempDir()
+	temperature := 1.4
+	cfg.Spaces = map[string]config.SpaceConfig{
+		"writing": {ID: "writing", Name: "Writing", DefaultSession: &config.SessionDefaults{
+			Agent:           "summarizer",
+			Generation:      &config.GenerationParams{Temperature: &temperature},
+			ExcludedContext: []string{"memory_notes"},
+		}},
+		"ops": {ID: "ops", Name: "Ops", DefaultSession: &config.SessionDefaults{
+			Agent: "coder",
+			Model: models.TestFake,
+			Tools: []string{"view", "grep"},
+		}},
+	}
+	t.Cleanup(func() { config.SetActiveSpace("") })
+	conn, err := db.Connect()
+	if err != nil {
+		t.Fatalf("db.Connect() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	sessions := NewService(db.New(conn))
+	ctx := context.Background()
+
+	create := func(spaceID string) Session {
+		t.Helper()
+		if err := config.SetActiveSpace(spaceID); err != nil {
+			t.Fatalf("SetActiveSpace() error = %v", err)
+		}
+		session, err := sessions.Create(ctx, spaceID)
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		session, err = sessions.Get(ctx, session.ID)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		return session
+	}
+
+	writing := create("writing")
+	if writing.Agent != "summarizer" || writing.Model != "" || len(writing.AllowedTools) != 0 {
+		t.Errorf("writing session = agent %q, model %q, tools %q, want the summarizer with every tool", writing.Agent, writing.Model, writing.AllowedTools)
+	}
+	if writing.Generation == nil || writing.Generation.Temperature == nil || *writing.Generation.Temperature != temperature {
+		t.Errorf("writing session generation = %+v, want temperature %g", writing.Generation, temperature)
+	}
+	if !reflect.DeepEqual(writing.ExcludedContext, []string{"memory_notes"}) {
+		t.Errorf("writing session excluded context = %q, want memory_notes", writing.ExcludedContext)
+	}
+
+	ops := create("ops")
+	if ops.Agent != "coder" || ops.Model != string(models.TestFake) || ops.Generation != nil || len(ops.ExcludedContext) != 0 {
+		t.Errorf("ops session = %+v, want the coder with the fake model", ops)
+	}
+	if !reflect.DeepEqual(ops.AllowedTools, []string{"view", "grep"}) {
+		t.Errorf("ops session tools = %q, want view and grep", ops.AllowedTools)
+	}
+
+	// Changing the defaults of a space leaves its sessions as they are, and
+	// the changes made to a session are kept
+	ops.Model = ""
+	ops.AllowedTools = nil
+	if _, err := sessions.Save(ctx, ops); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	cfg.Spaces["ops"] = config.SpaceConfig{ID: "ops", Name: "Ops", DefaultSession: &config.SessionDefaults{Agent: "summarizer"}}
+	ops, err = sessions.Get(ctx, ops.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if ops.Agent != "coder" || ops.Model != "" || len(ops.AllowedTools) != 0 {
+		t.Errorf("ops session after the defaults changed = %+v, want its own settings", ops)
+	}
+	if created := create("ops"); created.Agent != "summarizer" {
+		t.Errorf("new ops session agent = %q, want the new default summarizer", created.Agent)
+	}
+
+	if err := config.SetActiveSpace(""); err != nil {
+		t.Fatalf("SetActiveSpace() error = %v", err)
+	}
+	if plain, err := sessions.Create(ctx, "plain"); err != nil || plain.Agent != "" || plain.Generation != nil {
+		t.Errorf("Create() outside a space = %+v, %v, want no settings", plain, err)
+	}
+}
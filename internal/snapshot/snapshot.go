@@ -0,0 +1,240 @@
+// Package snapshot records the files of the workspace when an autonomous run
+// starts, so that what the run created, modified and deleted can be listed
+// and diffed once it ends.
+package snapshot
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/diff"
+	"github.com/caronex/intelligence-interface/internal/message"
+)
+
+const (
+	// maxFiles bounds the files of a snapshot, the others are not compared
+	maxFiles = 20000
+	// maxFileSize is the size of the largest file whose content is kept to
+	// be diffed, the larger ones are compared by hash only
+	maxFileSize = 1 << 20
+	// maxContentBytes bounds the content kept by a snapshot
+	maxContentBytes = 64 << 20
+	// maxDiffLines bounds the diff of each file kept with the changes, the
+	// patch holding the full diffs
+	maxDiffLines = 200
+)
+
+// file is a file of a snapshot
+type file struct {
+	hash [sha256.Size]byte
+	// content is nil when the file is binary or was not kept
+	content []byte
+	binary  bool
+}
+
+// Snapshot is the content of the files of the sandbox at a point in time:
+// those of the working directory and of the workspace roots, except the ones
+// the ignore files of their git repository exclude
+type Snapshot struct {
+	files     map[string]file
+	truncated bool
+}
+
+// root is a directory of the sandbox
+type root struct {
+	// name is the name of the workspace root, "" for the working directory
+	name string
+	path string
+}
+
+// roots returns the working directory and the workspace roots outside it
+func roots() []root {
+	wd := config.WorkingDirectory()
+	roots := []root{{path: wd}}
+	for _, name := range config.WorkspaceRootNames() {
+		if path := config.WorkspaceRoots()[name].Path; !inside(wd, path) {
+			roots = append(roots, root{name: name, path: path})
+		}
+	}
+	return roots
+}
+
+// Take snapshots the sandbox
+func Take() *Snapshot {
+	s := &Snapshot{files: make(map[string]file)}
+	dataDir := config.Get().Data.Directory
+	if !filepath.IsAbs(dataDir) {
+		dataDir = filepath.Join(config.WorkingDirectory(), dataDir)
+	}
+	var kept int64
+	for _, r := range roots() {
+		for _, rel := range listFiles(r.path) {
+			if len(s.files) >= maxFiles {
+				s.truncated = true
+				return s
+			}
+			path := filepath.Join(r.path, rel)
+			if inside(dataDir, path) {
+				continue
+			}
+			info, err := os.Lstat(path)
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				continue
+			}
+			f := file{hash: sha256.Sum256(content), binary: isBinary(content)}
+			if !f.binary && info.Size() <= maxFileSize && kept+info.Size() <= maxContentBytes {
+				f.content = content
+				kept += info.Size()
+			}
+			s.files[displayPath(r, rel)] = f
+		}
+	}
+	return s
+}
+
+// listFiles returns the files of dir, relative to it. In a git repository
+// they are the files git tracks or could, the ignored ones being left out.
+func listFiles(dir string) []string {
+	if gitPath, err := exec.LookPath("git"); err == nil {
+		cmd := exec.Command(gitPath, "-C", dir, "ls-files", "--cached", "--others", "--exclude-standard", "-z")
+		if output, err := cmd.Output(); err == nil {
+			var files []string
+			for _, path := range strings.Split(string(output), "\x00") {
+				if path != "" {
+					files = append(files, filepath.FromSlash(path))
+				}
+			}
+			return files
+		}
+	}
+
+	// Outside a repository nothing is ignored
+	var files []string
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if d.Type().IsRegular() {
+			rel, _ := filepath.Rel(dir, path)
+			files = append(files, rel)
+		}
+		if len(files) > maxFiles {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return files
+}
+
+// inside reports whether path is dir or below it
+func inside(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && (rel == "." || filepath.IsLocal(rel))
+}
+
+// displayPath returns the path of a file of r: relative to the working
+// directory, or qualified with the name of its workspace root
+func displayPath(r root, rel string) string {
+	rel = filepath.ToSlash(rel)
+	if r.name != "" {
+		return r.name + ":" + rel
+	}
+	return rel
+}
+
+// isBinary reports whether content looks binary, as git decides: by a NUL
+// byte in its first 8000 bytes
+func isBinary(content []byte) bool {
+	return bytes.IndexByte(content[:min(len(content), 8000)], 0) >= 0
+}
+
+// Compare returns the changes from before to after, along with the full
+// patch of the changes
+func Compare(before, after *Snapshot) (message.WorkspaceChanges, string) {
+	changes := message.WorkspaceChanges{
+		Files:     []message.FileChange{},
+		Truncated: before.truncated || after.truncated,
+	}
+	paths := make(map[string]bool, len(after.files))
+	for path := range before.files {
+		paths[path] = true
+	}
+	for path := range after.files {
+		paths[path] = true
+	}
+	sorted := make([]string, 0, len(paths))
+	for path := range paths {
+		sorted = append(sorted, path)
+	}
+	sort.Strings(sorted)
+
+	var patch strings.Builder
+	for _, path := range sorted {
+		old, existed := before.files[path]
+		current, exists := after.files[path]
+		change := message.FileChange{Path: path, Change: message.FileModified}
+		switch {
+		case !existed:
+			change.Change = message.FileCreated
+		case !exists:
+			change.Change = message.FileDeleted
+		case old.hash == current.hash:
+			continue
+		}
+
+		change.Binary = existed && old.binary || exists && current.binary
+		// A file whose content was not kept cannot be diffed
+		comparable := !change.Binary && (!existed || old.content != nil) && (!exists || current.content != nil)
+		switch {
+		case comparable:
+			unified, additions, removals := diff.GenerateDiff(string(old.content), string(current.content), path)
+			change.Additions, change.Removals = additions, removals
+			change.Diff, change.DiffCut = cut(unified, maxDiffLines)
+			patch.WriteString(unified)
+		case change.Binary:
+			fmt.Fprintf(&patch, "Binary files a/%s and b/%s differ\n", path, path)
+		default:
+			fmt.Fprintf(&patch, "Files a/%s and b/%s differ, too large to compare\n", path, path)
+		}
+		changes.Files = append(changes.Files, change)
+	}
+	return changes, patch.String()
+}
+
+// cut keeps the first n lines of text, reporting whether it cut any
+func cut(text string, n int) (string, bool) {
+	lines := strings.SplitAfter(text, "\n")
+	if len(lines) <= n || len(lines) == n+1 && lines[n] == "" {
+		return text, false
+	}
+	return strings.Join(lines[:n], ""), true
+}
+
+// SavePatch saves the full patch of the changes of a run in the session
+// directory, returning its path
+func SavePatch(sessionID, runID, patch string) (string, error) {
+	dir := filepath.Join(config.SessionDirectory(sessionID), "changes")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create the changes directory: %w", err)
+	}
+	path := filepath.Join(dir, runID+".patch")
+	if err := os.WriteFile(path, []byte(patch), 0o644); err != nil {
+		return "", fmt.Errorf("failed to save the patch: %w", err)
+	}
+	return path, nil
+}
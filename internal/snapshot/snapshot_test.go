@@ -0,0 +1,97 @@
+package snapshot
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/message"
+)
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func TestCompare(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.NewTestConfig(config.WithWorkingDir(dir))
+	cfg.Data.Directory = filepath.Join(dir, ".intelligence-interface")
+	writeFile(t, dir, "main.go", "package main\n\nfunc main() {}\n")
+	writeFile(t, dir, "old.txt", "gone\n")
+	writeFile(t, dir, "same.txt", "same\n")
+	writeFile(t, dir, "image.png", "\x89PNG\x00\x01")
+
+	before := Take()
+	writeFile(t, dir, "main.go", "package main\n\nfunc main() {\n\tprintln(1)\n}\n")
+	require.NoError(t, os.Remove(filepath.Join(dir, "old.txt")))
+	writeFile(t, dir, "pkg/new.go", "package pkg\n")
+	writeFile(t, dir, "image.png", "\x89PNG\x00\x02")
+	// The files of the data directory are not part of the workspace
+	writeFile(t, dir, ".intelligence-interface/opencode.db", "data")
+
+	changes, patch := Compare(before, Take())
+	require.Len(t, changes.Files, 4)
+	assert.Equal(t, message.FileChange{Path: "image.png", Change: message.FileModified, Binary: true}, changes.Files[0])
+	main := changes.Files[1]
+	assert.Equal(t, "main.go", main.Path)
+	assert.Equal(t, message.FileModified, main.Change)
+	assert.Equal(t, 3, main.Additions)
+	assert.Equal(t, 1, main.Removals)
+	assert.Contains(t, main.Diff, "+\tprintln(1)")
+	assert.Equal(t, message.FileChange{Path: "old.txt", Change: message.FileDeleted, Removals: 1, Diff: changes.Files[2].Diff}, changes.Files[2])
+	assert.Equal(t, message.FileCreated, changes.Files[3].Change)
+	assert.Equal(t, "pkg/new.go", changes.Files[3].Path)
+	assert.Equal(t, "4 files changed, +4 -2", changes.Stat())
+
+	assert.Contains(t, patch, "Binary files a/image.png and b/image.png differ")
+	assert.Contains(t, patch, "+++ b/pkg/new.go")
+	assert.NotContains(t, patch, "same.txt")
+
+	path, err := SavePatch("session-1", "run-1", patch)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(cfg.Data.Directory, "sessions", "session-1", "changes", "run-1.patch"), path)
+}
+
+func TestCompareIgnored(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	require.NoError(t, exec.Command("git", "-C", dir, "init", "-q").Run())
+	config.NewTestConfig(config.WithWorkingDir(dir))
+	writeFile(t, dir, ".gitignore", "build/\n*.log\n")
+	writeFile(t, dir, "README.md", "# Project\n")
+
+	before := Take()
+	writeFile(t, dir, "build/out.bin", "binary")
+	writeFile(t, dir, "run.log", "log")
+	writeFile(t, dir, "README.md", "# Project\n\nUsage\n")
+
+	changes, _ := Compare(before, Take())
+	require.Len(t, changes.Files, 1)
+	assert.Equal(t, "README.md", changes.Files[0].Path)
+}
+
+func TestCompareCutsDiffs(t *testing.T) {
+	dir := t.TempDir()
+	config.NewTestConfig(config.WithWorkingDir(dir))
+
+	before := Take()
+	writeFile(t, dir, "long.txt", strings.Repeat("line\n", maxDiffLines*2))
+
+	changes, patch := Compare(before, Take())
+	require.Len(t, changes.Files, 1)
+	assert.True(t, changes.Files[0].DiffCut)
+	assert.Equal(t, maxDiffLines*2, changes.Files[0].Additions)
+	assert.Equal(t, maxDiffLines, strings.Count(changes.Files[0].Diff, "\n"))
+	assert.Greater(t, len(patch), len(changes.Files[0].Diff))
+}
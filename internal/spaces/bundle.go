@@ -0,0 +1,696 @@
+// Package spaces archives spaces. A bundle holds what a space accumulated,
+// its configuration, sessions, notes and artifacts, so the space can be
+// removed from the system and restored later, under its ID or another one.
+package spaces
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/caronex/intelligence-interface/internal/artifact"
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/db"
+	"github.com/caronex/intelligence-interface/internal/notes"
+	"github.com/caronex/intelligence-interface/internal/scratchpad"
+)
+
+const (
+	// BundleVersion is the format version of the bundles ExportBundle writes.
+	// ImportBundle reads the bundles of this version and the earlier ones.
+	BundleVersion = 1
+
+	manifestName = "manifest.json"
+	spaceName    = "space.json"
+	notesName    = "notes.json"
+	sessionsDir  = "sessions/"
+	artifactsDir = "artifacts/"
+	// scratchpadsDir holds the scratchpad of each session, by session ID
+	scratchpadsDir = "scratchpads/"
+
+	// maxManifestBytes bounds the manifest, which is read in memory
+	maxManifestBytes = 16 << 20
+)
+
+// ErrSpaceExists is returned when a bundle is imported under the ID of a
+// configured space without ImportOptions.Rename
+var ErrSpaceExists = errors.New("space already exists")
+
+// Manifest is the last entry of a bundle, describing the others
+type Manifest struct {
+	Version   int    `json:"version"`
+	SpaceID   string `json:"space_id"`
+	CreatedAt int64  `json:"created_at"`
+	// Files are the SHA-256 hashes of the other entries by name, checked
+	// before anything is imported
+	Files map[string]string `json:"files"`
+	// ExcludedVariables names the environment variables of the space, whose
+	// values are left out of the bundle as they may be secrets
+	ExcludedVariables []string `json:"excluded_variables,omitempty"`
+}
+
+// ImportOptions tunes how ImportBundle restores a space
+type ImportOptions struct {
+	// SpaceID restores the space under another ID, the original one when empty
+	SpaceID string
+	// Rename restores the space under the first free ID of the form <id>-2,
+	// <id>-3... when its ID is taken, rather than failing with ErrSpaceExists
+	Rename bool
+}
+
+// ImportResult reports what ImportBundle restored
+type ImportResult struct {
+	SpaceID   string
+	Sessions  int
+	Messages  int
+	Notes     int
+	Artifacts int
+}
+
+// ExportBundle writes a space to w as a gzipped tarball: the space
+// configuration without its environment values, its sessions with their
+// messages, its notes and the artifacts and scratchpads of its sessions, then
+// a manifest with the hashes of all of them. The entries are streamed one at a time, so the
+// bundle is never held in memory as a whole.
+func ExportBundle(ctx context.Context, q db.Querier, spaceID string, w io.Writer) error {
+	cfg := config.Get()
+	if cfg == nil {
+		return fmt.Errorf("config not loaded")
+	}
+	space, ok := cfg.Spaces[spaceID]
+	if !ok {
+		return fmt.Errorf("unknown space: %s", spaceID)
+	}
+
+	gz := gzip.NewWriter(w)
+	bw := &bundleWriter{
+		tw: tar.NewWriter(gz),
+		manifest: Manifest{
+			Version:   BundleVersion,
+			SpaceID:   spaceID,
+			CreatedAt: time.Now().Unix(),
+			Files:     make(map[string]string),
+		},
+	}
+
+	// The values of the environment may be secrets, only their names are kept
+	for name := range space.Environment {
+		bw.manifest.ExcludedVariables = append(bw.manifest.ExcludedVariables, name)
+	}
+	sort.Strings(bw.manifest.ExcludedVariables)
+	space.Environment = nil
+	data, err := json.MarshalIndent(space, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode the space: %w", err)
+	}
+	if err := bw.addBytes(spaceName, data); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := notes.ExportSpace(&buf, spaceID); err != nil {
+		return err
+	}
+	if err := bw.addBytes(notesName, buf.Bytes()); err != nil {
+		return err
+	}
+
+	sessions, err := q.ListSpaceSessions(ctx, spaceID)
+	if err != nil {
+		return fmt.Errorf("failed to list the sessions of the space: %w", err)
+	}
+	for _, session := range sessions {
+		// A session is buffered on its own, as the size of an entry is
+		// written before its content
+		buf.Reset()
+		encoder := json.NewEncoder(&buf)
+		if err := encoder.Encode(session); err != nil {
+			return fmt.Errorf("failed to encode session %s: %w", session.ID, err)
+		}
+		messages, err := q.ListMessagesBySession(ctx, session.ID)
+		if err != nil {
+			return fmt.Errorf("failed to list the messages of session %s: %w", session.ID, err)
+		}
+		for _, message := range messages {
+			if err := encoder.Encode(message); err != nil {
+				return fmt.Errorf("failed to encode message %s: %w", message.ID, err)
+			}
+		}
+		if err := bw.addBytes(sessionsDir+session.ID+".jsonl", buf.Bytes()); err != nil {
+			return err
+		}
+		if err := bw.addDir(artifactsDir+session.ID, artifact.Dir(session.ID)); err != nil {
+			return err
+		}
+		pad, err := scratchpad.Export(session.ID)
+		if err != nil {
+			return fmt.Errorf("failed to export the scratchpad of session %s: %w", session.ID, err)
+		}
+		if pad != nil {
+			if err := bw.addBytes(scratchpadsDir+session.ID+".json", pad); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := bw.close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to write the bundle: %w", err)
+	}
+	return nil
+}
+
+// bundleWriter writes the entries of a bundle, recording their hashes in the
+// manifest written last
+type bundleWriter struct {
+	tw       *tar.Writer
+	manifest Manifest
+}
+
+func (b *bundleWriter) add(name string, size int64, modTime time.Time, r io.Reader) error {
+	header := &tar.Header{
+		Name:     name,
+		Mode:     0o644,
+		Size:     size,
+		ModTime:  modTime,
+		Typeflag: tar.TypeReg,
+	}
+	if err := b.tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write %s to the bundle: %w", name, err)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(b.tw, h), r); err != nil {
+		return fmt.Errorf("failed to write %s to the bundle: %w", name, err)
+	}
+	b.manifest.Files[name] = hex.EncodeToString(h.Sum(nil))
+	return nil
+}
+
+func (b *bundleWriter) addBytes(name string, data []byte) error {
+	return b.add(name, int64(len(data)), time.Now(), bytes.NewReader(data))
+}
+
+// addDir adds the files under dir as entries under prefix, a missing
+// directory adding none
+func (b *bundleWriter) addDir(prefix, dir string) error {
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && p == dir {
+			return fs.SkipDir
+		}
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return b.add(prefix+"/"+filepath.ToSlash(rel), info.Size(), info.ModTime(), f)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add the files of %s: %w", dir, err)
+	}
+	return nil
+}
+
+func (b *bundleWriter) close() error {
+	data, err := json.MarshalIndent(b.manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode the manifest: %w", err)
+	}
+	header := &tar.Header{
+		Name:     manifestName,
+		Mode:     0o644,
+		Size:     int64(len(data)),
+		ModTime:  time.Now(),
+		Typeflag: tar.TypeReg,
+	}
+	if err := b.tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write the manifest: %w", err)
+	}
+	if _, err := b.tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write the manifest: %w", err)
+	}
+	if err := b.tw.Close(); err != nil {
+		return fmt.Errorf("failed to write the bundle: %w", err)
+	}
+	return nil
+}
+
+// ImportBundle restores a space from a bundle written by ExportBundle. The
+// entries are unpacked to a temporary directory and checked against the
+// hashes of the manifest before anything is restored. Sessions whose ID is
+// taken are restored under new IDs, and so are their messages and notes.
+func ImportBundle(ctx context.Context, q db.Querier, r io.Reader, opts ImportOptions) (ImportResult, error) {
+	cfg := config.Get()
+	if cfg == nil {
+		return ImportResult{}, fmt.Errorf("config not loaded")
+	}
+
+	dir, err := os.MkdirTemp("", "ii-bundle-*")
+	if err != nil {
+		return ImportResult{}, fmt.Errorf("failed to create a directory to unpack the bundle: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	manifest, err := unpack(r, dir)
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	spaceID := opts.SpaceID
+	if spaceID == "" {
+		spaceID = manifest.SpaceID
+	}
+	spaceID, err = freeSpaceID(cfg, spaceID, opts.Rename)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	result := ImportResult{SpaceID: spaceID}
+
+	data, err := os.ReadFile(filepath.Join(dir, spaceName))
+	if err != nil {
+		return result, fmt.Errorf("failed to read the space: %w", err)
+	}
+	var space config.SpaceConfig
+	if err := json.Unmarshal(data, &space); err != nil {
+		return result, fmt.Errorf("failed to parse the space: %w", err)
+	}
+	space.ID = spaceID
+	if err := config.CreateSpace(space); err != nil {
+		return result, err
+	}
+
+	names := make([]string, 0, len(manifest.Files))
+	for name := range manifest.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ids, err := sessionIDs(ctx, q, dir, names)
+	if err != nil {
+		return result, err
+	}
+	for _, name := range names {
+		if !strings.HasPrefix(name, sessionsDir) {
+			continue
+		}
+		messages, err := importSession(ctx, q, filepath.Join(dir, filepath.FromSlash(name)), spaceID, ids)
+		if err != nil {
+			return result, err
+		}
+		result.Sessions++
+		result.Messages += messages
+	}
+
+	if result.Notes, err = importNotes(filepath.Join(dir, notesName), spaceID, ids); err != nil {
+		return result, err
+	}
+
+	for _, name := range names {
+		rest, ok := strings.CutPrefix(name, artifactsDir)
+		if !ok {
+			continue
+		}
+		sessionID, file, _ := strings.Cut(rest, "/")
+		if _, ok := ids.sessions[sessionID]; !ok || file == "" {
+			return result, fmt.Errorf("bundle entry %s is not an artifact of a session of the bundle", name)
+		}
+		dest := filepath.Join(artifact.Dir(ids.session(sessionID)), filepath.FromSlash(file))
+		if err := copyFile(filepath.Join(dir, filepath.FromSlash(name)), dest); err != nil {
+			return result, fmt.Errorf("failed to restore artifact %s: %w", name, err)
+		}
+		result.Artifacts++
+	}
+
+	for _, name := range names {
+		rest, ok := strings.CutPrefix(name, scratchpadsDir)
+		if !ok {
+			continue
+		}
+		sessionID := strings.TrimSuffix(rest, ".json")
+		if _, ok := ids.sessions[sessionID]; !ok {
+			return result, fmt.Errorf("bundle entry %s is not the scratchpad of a session of the bundle", name)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return result, fmt.Errorf("failed to read scratchpad %s: %w", name, err)
+		}
+		if err := scratchpad.Import(ids.session(sessionID), data); err != nil {
+			return result, fmt.Errorf("failed to restore scratchpad %s: %w", name, err)
+		}
+	}
+
+	return result, nil
+}
+
+// unpack extracts the entries of a bundle to dir and returns its manifest,
+// once the entries are checked against it
+func unpack(r io.Reader, dir string) (*Manifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the bundle: %w", err)
+	}
+	defer gz.Close()
+
+	var manifest *Manifest
+	hashes := make(map[string]string)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the bundle: %w", err)
+		}
+		name := header.Name
+		if header.Typeflag != tar.TypeReg || !fs.ValidPath(name) {
+			return nil, fmt.Errorf("invalid bundle entry %q", name)
+		}
+		if _, ok := hashes[name]; ok || (name == manifestName && manifest != nil) {
+			return nil, fmt.Errorf("duplicate bundle entry %q", name)
+		}
+
+		if name == manifestName {
+			manifest = &Manifest{}
+			if err := json.NewDecoder(io.LimitReader(tr, maxManifestBytes)).Decode(manifest); err != nil {
+				return nil, fmt.Errorf("failed to parse the manifest: %w", err)
+			}
+			continue
+		}
+		h, err := extract(tr, filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to unpack %s: %w", name, err)
+		}
+		hashes[name] = hex.EncodeToString(h.Sum(nil))
+	}
+
+	if manifest == nil {
+		return nil, fmt.Errorf("the bundle has no manifest")
+	}
+	if manifest.Version < 1 || manifest.Version > BundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d, this version reads bundles up to version %d", manifest.Version, BundleVersion)
+	}
+	for name, want := range manifest.Files {
+		got, ok := hashes[name]
+		if !ok {
+			return nil, fmt.Errorf("the bundle is missing %s", name)
+		}
+		if got != want {
+			return nil, fmt.Errorf("bundle entry %s does not match its hash in the manifest", name)
+		}
+	}
+	for name := range hashes {
+		if _, ok := manifest.Files[name]; !ok {
+			return nil, fmt.Errorf("bundle entry %s is not in the manifest", name)
+		}
+	}
+	for _, name := range []string{spaceName, notesName} {
+		if _, ok := hashes[name]; !ok {
+			return nil, fmt.Errorf("the bundle is missing %s", name)
+		}
+	}
+	return manifest, nil
+}
+
+// extract writes r to path and returns its hash
+func extract(r io.Reader, path string) (hash.Hash, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, h), r); err != nil {
+		return nil, err
+	}
+	return h, f.Close()
+}
+
+// freeSpaceID returns the ID to restore a space under, id when it is free
+func freeSpaceID(cfg *config.Config, id string, rename bool) (string, error) {
+	if id == "" {
+		return "", fmt.Errorf("the bundle has no space ID")
+	}
+	if _, taken := cfg.Spaces[id]; !taken {
+		return id, nil
+	}
+	if !rename {
+		return "", fmt.Errorf("%w: %s", ErrSpaceExists, id)
+	}
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s-%d", id, n)
+		if _, taken := cfg.Spaces[candidate]; !taken {
+			return candidate, nil
+		}
+	}
+}
+
+// idMap maps the session IDs of a bundle to the ones they are restored
+// under, which differ for the sessions whose ID is taken
+type idMap struct {
+	sessions map[string]string
+}
+
+func (m idMap) session(id string) string {
+	if mapped, ok := m.sessions[id]; ok {
+		return mapped
+	}
+	return id
+}
+
+// message returns the ID a message of a session is restored under. The
+// messages of a session restored under a new ID get new IDs too, derived from
+// both so the summary message of the session maps like its message does.
+func (m idMap) message(sessionID, id string) string {
+	mapped := m.session(sessionID)
+	if mapped == sessionID {
+		return id
+	}
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(mapped+"/"+id)).String()
+}
+
+// sessionIDs reads the sessions of the bundle and maps their IDs, giving a
+// new one to those the database already has
+func sessionIDs(ctx context.Context, q db.Querier, dir string, names []string) (idMap, error) {
+	ids := idMap{sessions: make(map[string]string)}
+	for _, name := range names {
+		if !strings.HasPrefix(name, sessionsDir) {
+			continue
+		}
+		f, err := os.Open(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return ids, err
+		}
+		var session db.Session
+		err = json.NewDecoder(f).Decode(&session)
+		f.Close()
+		if err != nil || session.ID == "" || name != sessionsDir+session.ID+".jsonl" {
+			return ids, fmt.Errorf("invalid session entry %s", name)
+		}
+
+		_, err = q.GetSessionByID(ctx, session.ID)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			ids.sessions[session.ID] = session.ID
+		case err != nil:
+			return ids, fmt.Errorf("failed to look up session %s: %w", session.ID, err)
+		default:
+			ids.sessions[session.ID] = uuid.New().String()
+		}
+	}
+	return ids, nil
+}
+
+// importSession restores a session entry and returns how many messages it
+// had
+func importSession(ctx context.Context, q db.Querier, path, spaceID string, ids idMap) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	decoder := json.NewDecoder(f)
+	var session db.Session
+	if err := decoder.Decode(&session); err != nil {
+		return 0, fmt.Errorf("failed to parse session %s: %w", path, err)
+	}
+
+	parent := session.ParentSessionID
+	if parent.Valid {
+		parent.String = ids.session(parent.String)
+	}
+	summary := session.SummaryMessageID
+	if summary.Valid {
+		summary.String = ids.message(session.ID, summary.String)
+	}
+	// The message count follows from the messages inserted after
+	err = q.ImportSession(ctx, db.ImportSessionParams{
+		ID:                ids.session(session.ID),
+		ParentSessionID:   parent,
+		Title:             session.Title,
+		PromptTokens:      session.PromptTokens,
+		CompletionTokens:  session.CompletionTokens,
+		CacheReadTokens:   session.CacheReadTokens,
+		CacheWriteTokens:  session.CacheWriteTokens,
+		RegeneratedTokens: session.RegeneratedTokens,
+		RegeneratedCost:   session.RegeneratedCost,
+		TruncatedTokens:   session.TruncatedTokens,
+		TruncatedCost:     session.TruncatedCost,
+		Cost:              session.Cost,
+		SummaryMessageID:  summary,
+		ConfigFingerprint: session.ConfigFingerprint,
+		SpaceID:           spaceID,
+		UpdatedAt:         session.UpdatedAt,
+		CreatedAt:         session.CreatedAt,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to restore session %s: %w", session.ID, err)
+	}
+
+	count := 0
+	for {
+		var message db.Message
+		if err := decoder.Decode(&message); err == io.EOF {
+			return count, nil
+		} else if err != nil {
+			return count, fmt.Errorf("failed to parse a message of session %s: %w", session.ID, err)
+		}
+		err := q.ImportMessage(ctx, db.ImportMessageParams{
+			ID:         ids.message(session.ID, message.ID),
+			SessionID:  ids.session(session.ID),
+			Role:       message.Role,
+			Parts:      message.Parts,
+			Model:      message.Model,
+			CreatedAt:  message.CreatedAt,
+			UpdatedAt:  message.UpdatedAt,
+			FinishedAt: message.FinishedAt,
+		})
+		if err != nil {
+			return count, fmt.Errorf("failed to restore message %s: %w", message.ID, err)
+		}
+		count++
+	}
+}
+
+// importNotes restores the notes of a bundle in the space, giving a new ID to
+// those the workspace already has
+func importNotes(path, spaceID string, ids idMap) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	var exported struct {
+		Workspace string       `json:"workspace"`
+		Notes     []notes.Note `json:"notes"`
+	}
+	if err := json.NewDecoder(f).Decode(&exported); err != nil {
+		return 0, fmt.Errorf("failed to parse the notes: %w", err)
+	}
+	if len(exported.Notes) == 0 {
+		return 0, nil
+	}
+
+	existing, err := notes.List()
+	if err != nil {
+		return 0, err
+	}
+	for i := range exported.Notes {
+		note := &exported.Notes[i]
+		note.Space = spaceID
+		if note.SessionID != "" {
+			note.SessionID = ids.session(note.SessionID)
+		}
+		if slices.ContainsFunc(existing, func(n notes.Note) bool { return n.ID == note.ID }) {
+			note.ID = uuid.New().String()
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(exported); err != nil {
+		return 0, fmt.Errorf("failed to encode the notes: %w", err)
+	}
+	return notes.Import(&buf)
+}
+
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Remove deletes a space from the system: its sessions with their messages
+// and files, its notes and its configuration. Export it first to keep it.
+func Remove(ctx context.Context, q db.Querier, spaceID string) error {
+	cfg := config.Get()
+	if cfg == nil {
+		return fmt.Errorf("config not loaded")
+	}
+	if _, ok := cfg.Spaces[spaceID]; !ok {
+		return fmt.Errorf("unknown space: %s", spaceID)
+	}
+
+	sessions, err := q.ListSpaceSessions(ctx, spaceID)
+	if err != nil {
+		return fmt.Errorf("failed to list the sessions of the space: %w", err)
+	}
+	for _, session := range sessions {
+		// The messages go with the session
+		if err := q.DeleteSession(ctx, session.ID); err != nil {
+			return fmt.Errorf("failed to delete session %s: %w", session.ID, err)
+		}
+		if err := os.RemoveAll(config.SessionDirectory(session.ID)); err != nil {
+			return fmt.Errorf("failed to remove the files of session %s: %w", session.ID, err)
+		}
+	}
+	if _, err := notes.DeleteSpace(spaceID); err != nil {
+		return err
+	}
+	return config.DeleteSpace(spaceID)
+}
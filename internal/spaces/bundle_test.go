@@ -0,0 +1,248 @@
+package spaces
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/caronex/intelligence-interface/internal/artifact"
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/db"
+	"github.com/caronex/intelligence-interface/internal/message"
+	"github.com/caronex/intelligence-interface/internal/notes"
+	"github.com/caronex/intelligence-interface/internal/scratchpad"
+	"github.com/caronex/intelligence-interface/internal/session"
+)
+
+type testSystem struct {
+	q        db.Querier
+	sessions session.Service
+	messages message.Service
+}
+
+// setUp installs a test configuration with a dev space, in a home, data
+// directory and workspace of its own
+func setUp(t *testing.T) testSystem {
+	t.Setenv("HOME", t.TempDir())
+	viper.Reset()
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
+	cfg.Data.Directory = t.TempDir()
+	cfg.Spaces["dev"] = config.SpaceConfig{
+		ID:             "dev",
+		Name:           "Dev",
+		Type:           "development",
+		AssignedAgents: []string{string(config.AgentCaronex)},
+		Environment:    map[string]string{"API_TOKEN": "sk-secret-token"},
+	}
+	t.Cleanup(func() {
+		config.SetActiveSpace("")
+		viper.Reset()
+	})
+
+	conn, err := db.Connect()
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	q := db.New(conn)
+	return testSystem{q: q, sessions: session.NewService(q), messages: message.NewService(q)}
+}
+
+// populate fills the dev space with a session and its task session, their
+// messages, a note and an artifact, and adds a session and a note outside of
+// it. It returns the session of the space.
+func (s testSystem) populate(t *testing.T) session.Session {
+	ctx := context.Background()
+	require.NoError(t, config.SetActiveSpace("dev"))
+	conversation, err := s.sessions.Create(ctx, "Plan the release")
+	require.NoError(t, err)
+	for _, text := range []string{"Plan the release", "Here is the plan", "Ship it"} {
+		role := message.User
+		if text == "Here is the plan" {
+			role = message.Assistant
+		}
+		_, err := s.messages.Create(ctx, conversation.ID, message.CreateMessageParams{Role: role, Parts: []message.ContentPart{message.TextContent{Text: text}}})
+		require.NoError(t, err)
+	}
+	task, err := s.sessions.CreateTaskSession(ctx, "call-1", conversation.ID, "Check the changelog")
+	require.NoError(t, err)
+	_, err = s.messages.Create(ctx, task.ID, message.CreateMessageParams{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "Check it"}}})
+	require.NoError(t, err)
+	_, err = notes.Add(notes.Note{Content: "Releases are tagged from main", Tags: []string{"release"}, SessionID: conversation.ID})
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(artifact.Dir(conversation.ID), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(artifact.Dir(conversation.ID), "plan.md"), []byte("# Plan"), 0o644))
+	_, err = scratchpad.Replace(conversation.ID, "caronex", "Todo", "- [ ] tag v1.2")
+	require.NoError(t, err)
+
+	require.NoError(t, config.SetActiveSpace(""))
+	_, err = s.sessions.Create(ctx, "Unrelated")
+	require.NoError(t, err)
+	_, err = notes.Add(notes.Note{Content: "Not in the space"})
+	require.NoError(t, err)
+	return conversation
+}
+
+// entries returns the content of the entries of a bundle by name
+func entries(t *testing.T, bundle []byte) map[string][]byte {
+	gz, err := gzip.NewReader(bytes.NewReader(bundle))
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+	contents := make(map[string][]byte)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return contents
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		contents[header.Name] = data
+	}
+}
+
+// rewrite returns the bundle with the content of an entry replaced
+func rewrite(t *testing.T, bundle []byte, name string, content []byte) []byte {
+	contents := entries(t, bundle)
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for entryName, data := range contents {
+		if entryName == name {
+			data = content
+		}
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: entryName, Mode: 0o644, Size: int64(len(data)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	s := setUp(t)
+	ctx := context.Background()
+	conversation := s.populate(t)
+
+	var bundle bytes.Buffer
+	require.NoError(t, ExportBundle(ctx, s.q, "dev", &bundle))
+	contents := entries(t, bundle.Bytes())
+	assert.Contains(t, contents, "sessions/"+conversation.ID+".jsonl")
+	assert.Contains(t, contents, "sessions/call-1.jsonl")
+	assert.Contains(t, contents, "artifacts/"+conversation.ID+"/plan.md")
+	assert.Contains(t, contents, "scratchpads/"+conversation.ID+".json")
+	assert.Len(t, contents, 7, "the space, notes, two sessions, the artifact, the scratchpad and the manifest")
+	for name, data := range contents {
+		assert.NotContains(t, string(data), "sk-secret-token", "%s holds a secret", name)
+	}
+	assert.Contains(t, string(contents[manifestName]), `"API_TOKEN"`, "the excluded variables are named")
+
+	require.NoError(t, Remove(ctx, s.q, "dev"))
+	remaining, err := s.q.ListSpaceSessions(ctx, "dev")
+	require.NoError(t, err)
+	assert.Empty(t, remaining)
+	assert.NotContains(t, config.Get().Spaces, "dev")
+	assert.NoDirExists(t, artifact.Dir(conversation.ID))
+	left, err := notes.List()
+	require.NoError(t, err)
+	require.Len(t, left, 1)
+	assert.Equal(t, "Not in the space", left[0].Content)
+
+	result, err := ImportBundle(ctx, s.q, bytes.NewReader(bundle.Bytes()), ImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, ImportResult{SpaceID: "dev", Sessions: 2, Messages: 4, Notes: 1, Artifacts: 1}, result)
+
+	space := config.Get().Spaces["dev"]
+	assert.Equal(t, "Dev", space.Name)
+	assert.Equal(t, []string{"caronex"}, space.AssignedAgents)
+	assert.Empty(t, space.Environment)
+	restored, err := s.sessions.Get(ctx, conversation.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), restored.MessageCount)
+	assert.Equal(t, "dev", restored.SpaceID)
+	msgs, err := s.messages.List(ctx, conversation.ID)
+	require.NoError(t, err)
+	require.Len(t, msgs, 3)
+	assert.Equal(t, "Here is the plan", msgs[1].Content().Text)
+	task, err := s.sessions.Get(ctx, "call-1")
+	require.NoError(t, err)
+	assert.Equal(t, conversation.ID, task.ParentSessionID)
+	assert.Equal(t, int64(1), task.MessageCount)
+	data, err := os.ReadFile(filepath.Join(artifact.Dir(conversation.ID), "plan.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# Plan", string(data))
+	pad, err := scratchpad.Get(conversation.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []scratchpad.Section{{Title: "Todo", Body: "- [ ] tag v1.2"}}, pad.Sections)
+
+	all, err := notes.List()
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	for _, note := range all {
+		if note.Space == "dev" {
+			assert.Equal(t, "Releases are tagged from main", note.Content)
+			assert.Equal(t, []string{"release"}, note.Tags)
+			assert.Equal(t, conversation.ID, note.SessionID)
+		}
+	}
+}
+
+func TestImportBundleCollisions(t *testing.T) {
+	s := setUp(t)
+	ctx := context.Background()
+	conversation := s.populate(t)
+	var bundle bytes.Buffer
+	require.NoError(t, ExportBundle(ctx, s.q, "dev", &bundle))
+
+	_, err := ImportBundle(ctx, s.q, bytes.NewReader(bundle.Bytes()), ImportOptions{})
+	assert.ErrorIs(t, err, ErrSpaceExists)
+
+	// A copy next to the original gets new session and note IDs
+	result, err := ImportBundle(ctx, s.q, bytes.NewReader(bundle.Bytes()), ImportOptions{Rename: true})
+	require.NoError(t, err)
+	assert.Equal(t, "dev-2", result.SpaceID)
+	copies, err := s.q.ListSpaceSessions(ctx, "dev-2")
+	require.NoError(t, err)
+	require.Len(t, copies, 2)
+	for _, copied := range copies {
+		assert.NotEqual(t, conversation.ID, copied.ID)
+		assert.NotEqual(t, "call-1", copied.ID)
+	}
+	originals, err := s.q.ListSpaceSessions(ctx, "dev")
+	require.NoError(t, err)
+	assert.Len(t, originals, 2, "the original sessions are untouched")
+	all, err := notes.List()
+	require.NoError(t, err)
+	assert.Len(t, all, 3)
+
+	result, err = ImportBundle(ctx, s.q, bytes.NewReader(bundle.Bytes()), ImportOptions{SpaceID: "archive"})
+	require.NoError(t, err)
+	assert.Equal(t, "archive", result.SpaceID)
+	assert.Contains(t, config.Get().Spaces, "archive")
+}
+
+func TestImportBundleVerifiesHashes(t *testing.T) {
+	s := setUp(t)
+	ctx := context.Background()
+	conversation := s.populate(t)
+	var bundle bytes.Buffer
+	require.NoError(t, ExportBundle(ctx, s.q, "dev", &bundle))
+	require.NoError(t, Remove(ctx, s.q, "dev"))
+
+	tampered := rewrite(t, bundle.Bytes(), "artifacts/"+conversation.ID+"/plan.md", []byte("# Other plan"))
+	_, err := ImportBundle(ctx, s.q, bytes.NewReader(tampered), ImportOptions{})
+	assert.ErrorContains(t, err, "does not match its hash")
+	assert.NotContains(t, config.Get().Spaces, "dev", "nothing is imported from a corrupted bundle")
+
+	unversioned := rewrite(t, bundle.Bytes(), manifestName, []byte(`{"version": 99, "space_id": "dev"}`))
+	_, err = ImportBundle(ctx, s.q, bytes.NewReader(unversioned), ImportOptions{})
+	assert.ErrorContains(t, err, "unsupported bundle version 99")
+}
@@ -4,8 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/core/logging"
 	"github.com/caronex/intelligence-interface/internal/llm/tools"
 	"github.com/caronex/intelligence-interface/internal/tools/coordination"
 )
@@ -70,44 +74,47 @@ func NewSpaceFoundationTool(cfg *config.Config, manager *coordination.Manager) *
 	}
 }
 
+type systemIntrospectionParams struct {
+	IncludeDetails bool `json:"include_details" default:"true" description:"Include detailed agent and configuration information"`
+}
+
 func (t *SystemIntrospectionTool) Info() tools.ToolInfo {
+	parameters, required := tools.ParamsSchema(systemIntrospectionParams{})
 	return tools.ToolInfo{
 		Name:        "system_introspection",
 		Description: "Provides comprehensive system state information including agents, capabilities, and configuration",
-		Parameters: map[string]any{
-			"include_details": map[string]any{
-				"type":        "boolean",
-				"description": "Include detailed agent and configuration information",
-				"default":     true,
-			},
-		},
-		Required: []string{},
+		Parameters:  parameters,
+		Required:    required,
 	}
 }
 
 func (t *SystemIntrospectionTool) Run(ctx context.Context, params tools.ToolCall) (tools.ToolResponse, error) {
-	var input struct {
-		IncludeDetails bool `json:"include_details"`
+	var input systemIntrospectionParams
+	if err := params.BindParams(&input, t.config.StrictToolInputs); err != nil {
+		return tools.NewParamsErrorResponse(err), nil
 	}
-	input.IncludeDetails = true
 
-	if params.Input != "" {
-		if err := json.Unmarshal([]byte(params.Input), &input); err != nil {
-			return tools.NewTextErrorResponse(fmt.Sprintf("Invalid input parameters: %v", err)), nil
-		}
-	}
-
-	result, err := t.manager.GetSystemIntrospection()
+	ctx, _ = logging.EnsureRequestID(ctx)
+	result, err := t.manager.GetSystemIntrospection(ctx)
 	if err != nil {
 		return tools.NewTextErrorResponse(fmt.Sprintf("Failed to get system introspection: %v", err)), nil
 	}
 
 	if !input.IncludeDetails {
-		summary := fmt.Sprintf("System Status: %s | Agents: %d | Capabilities: %d | Evolution: %t",
+		summary := fmt.Sprintf("System Status: %s | Version: %s | Agents: %d | Capabilities: %d | Evolution: %t | Config: %s | Request: %s",
 			result.SystemStatus,
+			result.Version.Version,
 			len(result.AvailableAgents),
 			len(result.SystemCapabilities),
-			result.SystemConfig.EvolutionEnabled)
+			result.SystemConfig.EvolutionEnabled,
+			result.ConfigFingerprint,
+			result.RequestID)
+		if len(result.ProjectTypes) > 0 {
+			summary += fmt.Sprintf(" | Project: %s", result.ProjectSummary)
+		}
+		if result.MessageQueue != nil {
+			summary += fmt.Sprintf(" | Queue: %d/%d (%d dropped)", result.MessageQueue.Depth, result.MessageQueue.Capacity, result.MessageQueue.Dropped)
+		}
 		return tools.NewTextResponse(summary), nil
 	}
 
@@ -119,46 +126,46 @@ func (t *SystemIntrospectionTool) Run(ctx context.Context, params tools.ToolCall
 	return tools.NewTextResponse(string(resultBytes)), nil
 }
 
+type agentCoordinationParams struct {
+	Action          string                 `json:"action" required:"true" enum:"plan,delegate,status,templates,render,broadcast,cancel,history" description:"Action to perform: 'plan' for task planning, 'delegate' for task delegation, 'status' for coordination status, 'templates' to list plan templates, 'render' to draw a plan's dependency graph, 'broadcast' to send a system event to all agents, 'cancel' to cancel a plan or delegation along with the delegations under it, 'history' to list the past delegations and how they ended"`
+	TaskDescription string                 `json:"task_description" description:"Description of the task to plan or delegate"`
+	PreferredAgent  string                 `json:"preferred_agent" description:"Preferred agent for task delegation (optional)"`
+	StepID          string                 `json:"step_id" description:"Step of the latest plan being delegated, whose tool requirement applies (optional)"`
+	RequiresTools   bool                   `json:"requires_tools" description:"Whether the delegated task needs tools, such as editing files or running commands"`
+	Requirements    []string               `json:"requirements" description:"List of requirements for task planning"`
+	Template        string                 `json:"template" description:"Plan template to build the plan from (optional, see the 'templates' action)"`
+	Format          string                 `json:"format" enum:"mermaid,dot" description:"Format of the rendered plan: 'mermaid' (default) or 'dot' for Graphviz"`
+	Plan            *coordination.TaskPlan `json:"plan" description:"Task plan to render, as returned by the 'plan' action (optional, defaults to the latest plan)"`
+	MessageType     string                 `json:"message_type" description:"Type of the system event to broadcast, such as 'config_changed' to have the agents re-read their prompt configuration"`
+	Payload         string                 `json:"payload" description:"Payload of the broadcast system event (optional)"`
+	Priority        int                    `json:"priority" description:"Priority of the broadcast system event under the queue communication protocol, from 1 to 10, higher events being delivered first (optional, 'halt' and 'evolve' default to the highest, others to 5)"`
+	OperationID     string                 `json:"operation_id" description:"Plan task ID or delegation operation ID to cancel"`
+	Limit           int                    `json:"limit" description:"Number of past delegations the 'history' action lists, the most recent ones (optional, defaults to 20)"`
+}
+
+// AgentCoordinationToolName is the name of the agent coordination tool
+const AgentCoordinationToolName = "agent_coordination"
+
+// defaultHistoryLimit is the number of past delegations 'history' lists by
+// default
+const defaultHistoryLimit = 20
+
 func (t *AgentCoordinationTool) Info() tools.ToolInfo {
+	parameters, required := tools.ParamsSchema(agentCoordinationParams{})
 	return tools.ToolInfo{
-		Name:        "agent_coordination",
+		Name:        AgentCoordinationToolName,
 		Description: "Coordinates agent activities, creates task plans, and delegates implementation tasks",
-		Parameters: map[string]any{
-			"action": map[string]any{
-				"type":        "string",
-				"description": "Action to perform: 'plan' for task planning, 'delegate' for task delegation, 'status' for coordination status",
-				"enum":        []string{"plan", "delegate", "status"},
-			},
-			"task_description": map[string]any{
-				"type":        "string",
-				"description": "Description of the task to plan or delegate",
-			},
-			"preferred_agent": map[string]any{
-				"type":        "string",
-				"description": "Preferred agent for task delegation (optional)",
-			},
-			"requirements": map[string]any{
-				"type":        "array",
-				"description": "List of requirements for task planning",
-				"items": map[string]any{
-					"type": "string",
-				},
-			},
-		},
-		Required: []string{"action"},
+		Parameters:  parameters,
+		Required:    required,
+		// Delegated tasks edit files and 'cancel' stops work in progress
+		Destructive: true,
 	}
 }
 
 func (t *AgentCoordinationTool) Run(ctx context.Context, params tools.ToolCall) (tools.ToolResponse, error) {
-	var input struct {
-		Action          string   `json:"action"`
-		TaskDescription string   `json:"task_description"`
-		PreferredAgent  string   `json:"preferred_agent"`
-		Requirements    []string `json:"requirements"`
-	}
-
-	if err := json.Unmarshal([]byte(params.Input), &input); err != nil {
-		return tools.NewTextErrorResponse(fmt.Sprintf("Invalid input parameters: %v", err)), nil
+	var input agentCoordinationParams
+	if err := params.BindParams(&input, t.config.StrictToolInputs); err != nil {
+		return tools.NewParamsErrorResponse(err), nil
 	}
 
 	switch input.Action {
@@ -167,7 +174,7 @@ func (t *AgentCoordinationTool) Run(ctx context.Context, params tools.ToolCall)
 			return tools.NewTextErrorResponse("Task description is required for planning"), nil
 		}
 
-		plan, err := t.manager.CreateTaskPlan(input.TaskDescription, input.Requirements)
+		plan, err := t.manager.CreateTaskPlan(ctx, input.TaskDescription, input.Requirements, input.Template)
 		if err != nil {
 			return tools.NewTextErrorResponse(fmt.Sprintf("Failed to create task plan: %v", err)), nil
 		}
@@ -184,10 +191,23 @@ func (t *AgentCoordinationTool) Run(ctx context.Context, params tools.ToolCall)
 			return tools.NewTextErrorResponse("Task description is required for delegation"), nil
 		}
 
+		requiresTools := input.RequiresTools
 		taskID := fmt.Sprintf("task_%d", len(input.TaskDescription))
-		delegation, err := t.manager.DelegateTask(taskID, input.TaskDescription, input.PreferredAgent)
+		if plan := coordination.LatestPlan(); plan != nil && input.StepID != "" {
+			for _, step := range plan.Steps {
+				if step.StepID == input.StepID {
+					requiresTools = requiresTools || step.RequiresTools
+					taskID = plan.TaskID
+				}
+			}
+		}
+
+		delegation, err := t.manager.DelegateTask(ctx, taskID, input.TaskDescription, input.PreferredAgent, requiresTools)
 		if err != nil {
-			return tools.NewTextErrorResponse(fmt.Sprintf("Failed to delegate task: %v", err)), nil
+			return delegationFailureResponse(taskID, input.PreferredAgent, err), nil
+		}
+		if err := t.manager.RunDelegation(ctx, delegation, input.StepID, input.TaskDescription); err != nil {
+			return delegationFailureResponse(taskID, delegation.AssignedTo, err), nil
 		}
 
 		delegationBytes, err := json.MarshalIndent(delegation, "", "  ")
@@ -205,6 +225,9 @@ func (t *AgentCoordinationTool) Run(ctx context.Context, params tools.ToolCall)
 			"delegation_enabled":  true,
 			"planning_enabled":    true,
 		}
+		if operations := coordination.Operations(); len(operations) > 0 {
+			status["operations"] = operations
+		}
 
 		statusBytes, err := json.MarshalIndent(status, "", "  ")
 		if err != nil {
@@ -213,43 +236,142 @@ func (t *AgentCoordinationTool) Run(ctx context.Context, params tools.ToolCall)
 
 		return tools.NewTextResponse(string(statusBytes)), nil
 
+	case "templates":
+		loadErrors := make([]string, 0)
+		for _, err := range t.manager.PlanTemplateErrors() {
+			loadErrors = append(loadErrors, err.Error())
+		}
+		templates := map[string]interface{}{
+			"templates": t.manager.PlanTemplates(),
+		}
+		if len(loadErrors) > 0 {
+			templates["errors"] = loadErrors
+		}
+
+		templatesBytes, err := json.MarshalIndent(templates, "", "  ")
+		if err != nil {
+			return tools.NewTextErrorResponse(fmt.Sprintf("Failed to serialize plan templates: %v", err)), nil
+		}
+
+		return tools.NewTextResponse(string(templatesBytes)), nil
+
+	case "render":
+		plan := input.Plan
+		if plan == nil {
+			plan = coordination.LatestPlan()
+		}
+		if plan == nil {
+			return tools.NewTextErrorResponse("No plan to render, create one with the 'plan' action or pass it as 'plan'"), nil
+		}
+
+		rendered, err := coordination.RenderPlan(plan, input.Format)
+		if err != nil {
+			return tools.NewTextErrorResponse(err.Error()), nil
+		}
+		if cycle := coordination.PlanCycle(plan); cycle != nil {
+			rendered += fmt.Sprintf("\nWarning: dependency cycle %s, highlighted in the graph\n", strings.Join(cycle, " -> "))
+		}
+
+		return tools.NewTextResponse(rendered), nil
+
+	case "broadcast":
+		if input.MessageType == "" {
+			return tools.NewTextErrorResponse("Message type is required for broadcasting"), nil
+		}
+
+		deliveredTo, err := t.manager.Broadcast(input.MessageType, input.Payload, input.Priority)
+		if err != nil {
+			return tools.NewTextErrorResponse(fmt.Sprintf("Failed to broadcast: %v", err)), nil
+		}
+
+		broadcast := map[string]interface{}{
+			"message_type": input.MessageType,
+			"delivered_to": deliveredTo,
+		}
+		if queue := t.manager.QueueStatus(); queue != nil {
+			broadcast["queue"] = queue
+		}
+		broadcastBytes, err := json.MarshalIndent(broadcast, "", "  ")
+		if err != nil {
+			return tools.NewTextErrorResponse(fmt.Sprintf("Failed to serialize broadcast result: %v", err)), nil
+		}
+
+		return tools.NewTextResponse(string(broadcastBytes)), nil
+
+	case "cancel":
+		if input.OperationID == "" {
+			return tools.NewTextErrorResponse("Operation ID is required for cancelling, see the operations of the 'status' action"), nil
+		}
+
+		summary, err := coordination.CancelOperation(input.OperationID, "agent_coordination tool")
+		if err != nil {
+			return tools.NewTextErrorResponse(fmt.Sprintf("Failed to cancel: %v", err)), nil
+		}
+
+		return tools.NewTextResponse(summary.String()), nil
+
+	case "history":
+		limit := input.Limit
+		if limit <= 0 {
+			limit = defaultHistoryLimit
+		}
+		delegations, err := coordination.ReadDelegations(t.config.Data.Directory, limit)
+		if err != nil {
+			return tools.NewTextErrorResponse(fmt.Sprintf("Failed to read the delegation history: %v", err)), nil
+		}
+		history := map[string]interface{}{
+			"delegations": delegations,
+		}
+
+		historyBytes, err := json.MarshalIndent(history, "", "  ")
+		if err != nil {
+			return tools.NewTextErrorResponse(fmt.Sprintf("Failed to serialize delegation history: %v", err)), nil
+		}
+
+		return tools.NewTextResponse(string(historyBytes)), nil
+
 	default:
-		return tools.NewTextErrorResponse(fmt.Sprintf("Unknown action: %s. Valid actions: plan, delegate, status", input.Action)), nil
+		return tools.NewTextErrorResponse(fmt.Sprintf("Unknown action: %s. Valid actions: plan, delegate, status, templates, render, broadcast, cancel, history", input.Action)), nil
 	}
 }
 
+// delegationFailureResponse returns the delegation of a task which could not
+// be delegated, with its failure, as an error response
+func delegationFailureResponse(taskID, agent string, err error) tools.ToolResponse {
+	delegation := coordination.DelegationResult{
+		TaskID:     taskID,
+		AssignedTo: agent,
+		Status:     coordination.OperationFailed,
+		Message:    fmt.Sprintf("Failed to delegate task: %v", err),
+		CreatedAt:  time.Now(),
+		Failure:    coordination.ClassifyFailure(err),
+	}
+	delegationBytes, marshalErr := json.MarshalIndent(delegation, "", "  ")
+	if marshalErr != nil {
+		return tools.NewTextErrorResponse(delegation.Message)
+	}
+	return tools.NewTextErrorResponse(string(delegationBytes))
+}
+
+type configurationInspectionParams struct {
+	Section  string `json:"section" enum:"all,agents,caronex,spaces,providers" default:"all" description:"Configuration section to inspect: 'all', 'agents', 'caronex', 'spaces', 'providers'"`
+	Validate bool   `json:"validate" default:"true" description:"Perform configuration validation"`
+}
+
 func (t *ConfigurationInspectionTool) Info() tools.ToolInfo {
+	parameters, required := tools.ParamsSchema(configurationInspectionParams{})
 	return tools.ToolInfo{
 		Name:        "configuration_inspection",
 		Description: "Inspects and validates system configuration, reports configuration state and issues",
-		Parameters: map[string]any{
-			"section": map[string]any{
-				"type":        "string",
-				"description": "Configuration section to inspect: 'all', 'agents', 'caronex', 'spaces'",
-				"default":     "all",
-			},
-			"validate": map[string]any{
-				"type":        "boolean",
-				"description": "Perform configuration validation",
-				"default":     true,
-			},
-		},
-		Required: []string{},
+		Parameters:  parameters,
+		Required:    required,
 	}
 }
 
 func (t *ConfigurationInspectionTool) Run(ctx context.Context, params tools.ToolCall) (tools.ToolResponse, error) {
-	var input struct {
-		Section  string `json:"section"`
-		Validate bool   `json:"validate"`
-	}
-	input.Section = "all"
-	input.Validate = true
-
-	if params.Input != "" {
-		if err := json.Unmarshal([]byte(params.Input), &input); err != nil {
-			return tools.NewTextErrorResponse(fmt.Sprintf("Invalid input parameters: %v", err)), nil
-		}
+	var input configurationInspectionParams
+	if err := params.BindParams(&input, t.config.StrictToolInputs); err != nil {
+		return tools.NewParamsErrorResponse(err), nil
 	}
 
 	result := make(map[string]interface{})
@@ -276,10 +398,35 @@ func (t *ConfigurationInspectionTool) Run(ctx context.Context, params tools.Tool
 	}
 
 	if input.Section == "all" || input.Section == "spaces" {
+		// Only the names of the environment variables, their values may be secrets
+		environment := make(map[string][]string)
+		for spaceID, spaceConfig := range t.config.Spaces {
+			names := make([]string, 0, len(spaceConfig.Environment))
+			for name := range spaceConfig.Environment {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			environment[spaceID] = names
+		}
 		result["spaces"] = map[string]interface{}{
 			"configured_count": len(t.config.Spaces),
 			"supported":        true,
+			"active_space":     config.ActiveSpace(),
+			"environment":      environment,
+		}
+	}
+
+	if input.Section == "all" || input.Section == "providers" {
+		// Where each API key was read from, never the key itself
+		providers := make(map[string]interface{})
+		for provider, providerConfig := range t.config.Providers {
+			providers[string(provider)] = map[string]interface{}{
+				"disabled":    providerConfig.Disabled,
+				"has_api_key": providerConfig.APIKey != "",
+				"source":      t.config.ProviderSources[provider],
+			}
 		}
+		result["providers"] = providers
 	}
 
 	if input.Validate {
@@ -298,33 +445,25 @@ func (t *ConfigurationInspectionTool) Run(ctx context.Context, params tools.Tool
 	return tools.NewTextResponse(string(resultBytes)), nil
 }
 
+type agentLifecycleParams struct {
+	Action    string `json:"action" required:"true" enum:"list,status,capabilities" description:"Action to perform: 'list' for available agents, 'status' for agent status, 'capabilities' for agent capabilities"`
+	AgentName string `json:"agent_name" description:"Specific agent name for status checks (optional)"`
+}
+
 func (t *AgentLifecycleTool) Info() tools.ToolInfo {
+	parameters, required := tools.ParamsSchema(agentLifecycleParams{})
 	return tools.ToolInfo{
 		Name:        "agent_lifecycle",
 		Description: "Manages agent lifecycle, lists available agents, checks readiness, and coordinates task delegation",
-		Parameters: map[string]any{
-			"action": map[string]any{
-				"type":        "string",
-				"description": "Action to perform: 'list' for available agents, 'status' for agent status, 'capabilities' for agent capabilities",
-				"enum":        []string{"list", "status", "capabilities"},
-			},
-			"agent_name": map[string]any{
-				"type":        "string",
-				"description": "Specific agent name for status checks (optional)",
-			},
-		},
-		Required: []string{"action"},
+		Parameters:  parameters,
+		Required:    required,
 	}
 }
 
 func (t *AgentLifecycleTool) Run(ctx context.Context, params tools.ToolCall) (tools.ToolResponse, error) {
-	var input struct {
-		Action    string `json:"action"`
-		AgentName string `json:"agent_name"`
-	}
-
-	if err := json.Unmarshal([]byte(params.Input), &input); err != nil {
-		return tools.NewTextErrorResponse(fmt.Sprintf("Invalid input parameters: %v", err)), nil
+	var input agentLifecycleParams
+	if err := params.BindParams(&input, t.config.StrictToolInputs); err != nil {
+		return tools.NewParamsErrorResponse(err), nil
 	}
 
 	switch input.Action {
@@ -362,9 +501,10 @@ func (t *AgentLifecycleTool) Run(ctx context.Context, params tools.ToolCall) (to
 		var result map[string]interface{}
 
 		if input.AgentName != "" {
-			if agentConfig, exists := t.config.Agents[config.AgentName(input.AgentName)]; exists {
+			if agentName, exists := t.config.ResolveAgent(input.AgentName); exists {
+				agentConfig := t.config.Agents[agentName]
 				result = map[string]interface{}{
-					"agent_name": input.AgentName,
+					"agent_name": string(agentName),
 					"status":     "available",
 					"model":      agentConfig.Model,
 					"ready":      true,
@@ -429,28 +569,28 @@ func (t *AgentLifecycleTool) getAgentCapabilities(agentName config.AgentName) []
 	}
 }
 
+type spaceFoundationParams struct {
+	Action         string   `json:"action" required:"true" enum:"status,config,guidance,create" description:"Action to perform: 'status' for foundation status, 'config' for space configuration options, 'guidance' for implementation guidance, 'create' to add a space to the configuration"`
+	SpaceID        string   `json:"space_id" description:"ID of the space to create (required for 'create')"`
+	Name           string   `json:"name" description:"Name of the space to create"`
+	Type           string   `json:"type" description:"Type of the space to create, see the 'config' action"`
+	AssignedAgents []string `json:"assigned_agents" description:"Names of the configured agents assigned to the space to create"`
+}
+
 func (t *SpaceFoundationTool) Info() tools.ToolInfo {
+	parameters, required := tools.ParamsSchema(spaceFoundationParams{})
 	return tools.ToolInfo{
 		Name:        "space_foundation",
 		Description: "Provides introspection of space management foundation and guidance for future space implementation",
-		Parameters: map[string]any{
-			"action": map[string]any{
-				"type":        "string",
-				"description": "Action to perform: 'status' for foundation status, 'config' for space configuration options, 'guidance' for implementation guidance",
-				"enum":        []string{"status", "config", "guidance"},
-			},
-		},
-		Required: []string{"action"},
+		Parameters:  parameters,
+		Required:    required,
 	}
 }
 
 func (t *SpaceFoundationTool) Run(ctx context.Context, params tools.ToolCall) (tools.ToolResponse, error) {
-	var input struct {
-		Action string `json:"action"`
-	}
-
-	if err := json.Unmarshal([]byte(params.Input), &input); err != nil {
-		return tools.NewTextErrorResponse(fmt.Sprintf("Invalid input parameters: %v", err)), nil
+	var input spaceFoundationParams
+	if err := params.BindParams(&input, t.config.StrictToolInputs); err != nil {
+		return tools.NewParamsErrorResponse(err), nil
 	}
 
 	switch input.Action {
@@ -474,7 +614,7 @@ func (t *SpaceFoundationTool) Run(ctx context.Context, params tools.ToolCall) (t
 
 	case "config":
 		configOptions := map[string]interface{}{
-			"space_types": []string{"development", "knowledge_base", "communication", "creative", "analysis"},
+			"space_types": config.SpaceTypes,
 			"ui_layouts":  []string{"panels", "terminal", "hybrid", "custom"},
 			"themes":      []string{"intelligence-interface", "dark", "light", "catppuccin"},
 			"persistence_backends": []string{"memory", "file", "database"},
@@ -532,7 +672,20 @@ func (t *SpaceFoundationTool) Run(ctx context.Context, params tools.ToolCall) (t
 
 		return tools.NewTextResponse(string(resultBytes)), nil
 
+	case "create":
+		space := config.SpaceConfig{
+			ID:             input.SpaceID,
+			Name:           input.Name,
+			Type:           input.Type,
+			AssignedAgents: input.AssignedAgents,
+		}
+		if err := config.CreateSpace(space); err != nil {
+			return tools.NewTextErrorResponse(fmt.Sprintf("Failed to create space: %v", err)), nil
+		}
+
+		return tools.NewTextResponse(fmt.Sprintf("Space %q created", input.SpaceID)), nil
+
 	default:
-		return tools.NewTextErrorResponse(fmt.Sprintf("Unknown action: %s. Valid actions: status, config, guidance", input.Action)), nil
+		return tools.NewTextErrorResponse(fmt.Sprintf("Unknown action: %s. Valid actions: status, config, guidance, create", input.Action)), nil
 	}
 }
\ No newline at end of file
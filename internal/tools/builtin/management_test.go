@@ -0,0 +1,216 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/llm/tools"
+	"github.com/caronex/intelligence-interface/internal/tools/coordination"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagementTools_MalformedInput(t *testing.T) {
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
+	cfg.StrictToolInputs = true
+	manager, err := coordination.NewManager(cfg)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		tool  tools.BaseTool
+		input string
+		want  []string
+	}{
+		{
+			name:  "introspection details not a boolean",
+			tool:  NewSystemIntrospectionTool(cfg, manager),
+			input: `{"include_details":"yes"}`,
+			want:  []string{`- include_details: expected boolean, got string "yes"`},
+		},
+		{
+			name:  "coordination action missing",
+			tool:  NewAgentCoordinationTool(cfg, manager),
+			input: `{"task_description":"add tests"}`,
+			want:  []string{`- action: required string is missing, expected one of "plan", "delegate", "status", "templates", "render"`},
+		},
+		{
+			name:  "coordination requirements not strings",
+			tool:  NewAgentCoordinationTool(cfg, manager),
+			input: `{"action":"plan","requirements":"fast","format":"svg"}`,
+			want: []string{
+				`- requirements: expected array, got string "fast"`,
+				`- format: got "svg", expected one of "mermaid", "dot"`,
+			},
+		},
+		{
+			name:  "configuration section unknown",
+			tool:  NewConfigurationInspectionTool(cfg, manager),
+			input: `{"section":"models","validate":1}`,
+			want: []string{
+				`- section: got "models", expected one of "all", "agents", "caronex", "spaces", "providers"`,
+				`- validate: expected boolean, got number 1`,
+			},
+		},
+		{
+			name:  "lifecycle unknown field",
+			tool:  NewAgentLifecycleTool(cfg, manager),
+			input: `{"action":"status","agent":"coder"}`,
+			want:  []string{`- agent: unknown field, the known fields are action, agent_name`},
+		},
+		{
+			name:  "space foundation input not an object",
+			tool:  NewSpaceFoundationTool(cfg, manager),
+			input: `"status"`,
+			want:  []string{`- input must be a JSON object, got string "status"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := tt.tool.Run(context.Background(), tools.ToolCall{Input: tt.input})
+			require.NoError(t, err)
+			assert.True(t, response.IsError)
+			for _, want := range tt.want {
+				assert.Contains(t, response.Content, want)
+			}
+			assert.NotEmpty(t, response.Metadata, "violations are attached as metadata")
+		})
+	}
+}
+
+func TestManagementTools_Defaults(t *testing.T) {
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
+	manager, err := coordination.NewManager(cfg)
+	require.NoError(t, err)
+
+	response, err := NewConfigurationInspectionTool(cfg, manager).Run(context.Background(), tools.ToolCall{})
+	require.NoError(t, err)
+	require.False(t, response.IsError, response.Content)
+	for _, section := range []string{`"agents"`, `"caronex"`, `"spaces"`, `"providers"`} {
+		assert.Contains(t, response.Content, section, "the section defaults to all")
+	}
+
+	info := NewAgentCoordinationTool(cfg, manager).Info()
+	assert.Equal(t, []string{"action"}, info.Required)
+	assert.Equal(t, []string{"plan", "delegate", "status", "templates", "render", "broadcast", "cancel", "history"}, info.Parameters["action"].(map[string]any)["enum"])
+}
+
+func TestConfigurationInspectionProviderSources(t *testing.T) {
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
+	cfg.Providers = map[models.ModelProvider]config.Provider{
+		models.ProviderOpenRouter: {APIKey: "sk-or-secret"},
+	}
+	cfg.ProviderSources = map[models.ModelProvider]config.CredentialSource{
+		models.ProviderOpenRouter: config.CredentialLocal,
+	}
+	manager, err := coordination.NewManager(cfg)
+	require.NoError(t, err)
+
+	response, err := NewConfigurationInspectionTool(cfg, manager).Run(context.Background(), tools.ToolCall{Input: `{"section":"providers","validate":false}`})
+	require.NoError(t, err)
+	require.False(t, response.IsError, response.Content)
+	assert.NotContains(t, response.Content, "sk-or-secret", "the key is never reported")
+	var result struct {
+		Providers map[string]struct {
+			HasAPIKey bool   `json:"has_api_key"`
+			Source    string `json:"source"`
+		} `json:"providers"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(response.Content), &result))
+	assert.True(t, result.Providers["openrouter"].HasAPIKey)
+	assert.Equal(t, "local", result.Providers["openrouter"].Source)
+}
+
+// systemEventRecorder sends the system events it handles on a channel
+type systemEventRecorder chan coordination.SystemEvent
+
+func (r systemEventRecorder) HandleSystemEvent(ctx context.Context, event coordination.SystemEvent) {
+	r <- event
+}
+
+func TestAgentCoordinationBroadcast(t *testing.T) {
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
+	manager, err := coordination.NewManager(cfg)
+	require.NoError(t, err)
+	tool := NewAgentCoordinationTool(cfg, manager)
+
+	response, err := tool.Run(context.Background(), tools.ToolCall{Input: `{"action":"broadcast"}`})
+	require.NoError(t, err)
+	assert.True(t, response.IsError, "a broadcast needs a message type")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	recorder := make(systemEventRecorder, 1)
+	coordination.RegisterSystemEventHandler(ctx, config.AgentCaronex, recorder)
+
+	response, err = tool.Run(context.Background(), tools.ToolCall{Input: `{"action":"broadcast","message_type":"config_changed","payload":"agents"}`})
+	require.NoError(t, err)
+	require.False(t, response.IsError, response.Content)
+	var result struct {
+		DeliveredTo []string `json:"delivered_to"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(response.Content), &result))
+	assert.Equal(t, []string{"caronex"}, result.DeliveredTo)
+
+	event := <-recorder
+	assert.Equal(t, coordination.SystemEventConfigChanged, event.Type)
+	assert.Equal(t, "agents", event.Payload)
+}
+
+func TestSystemIntrospectionRequestID(t *testing.T) {
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
+	manager, err := coordination.NewManager(cfg)
+	require.NoError(t, err)
+	tool := NewSystemIntrospectionTool(cfg, manager)
+
+	ctx := logging.WithRequestID(context.Background(), "request-1")
+	response, err := tool.Run(ctx, tools.ToolCall{Input: `{"include_details":false}`})
+	require.NoError(t, err)
+	assert.Contains(t, response.Content, "Request: request-1")
+
+	// A call outside of a request starts one
+	response, err = tool.Run(context.Background(), tools.ToolCall{Input: `{"include_details":true}`})
+	require.NoError(t, err)
+	var result coordination.SystemIntrospectionResult
+	require.NoError(t, json.Unmarshal([]byte(response.Content), &result))
+	assert.NotEmpty(t, result.RequestID)
+	assert.Equal(t, config.CurrentFingerprint(), result.ConfigFingerprint)
+}
+
+func TestSpaceFoundationCreateUnknownAgent(t *testing.T) {
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
+	manager, err := coordination.NewManager(cfg)
+	require.NoError(t, err)
+	tool := NewSpaceFoundationTool(cfg, manager)
+
+	response, err := tool.Run(context.Background(), tools.ToolCall{
+		Input: `{"action":"create","space_id":"dev","assigned_agents":["caronx"]}`,
+	})
+	require.NoError(t, err)
+	assert.True(t, response.IsError)
+	assert.Contains(t, response.Content, `spaces.dev.assigned_agents: unknown agent "caronx", did you mean "caronex"?`)
+	assert.NotContains(t, config.Get().Spaces, "dev")
+}
+
+func TestAgentLifecycleStatusResolvesAgentNames(t *testing.T) {
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
+	caronex := cfg.Agents[config.AgentCaronex]
+	caronex.Aliases = []string{"boss"}
+	cfg.Agents[config.AgentCaronex] = caronex
+	manager, err := coordination.NewManager(cfg)
+	require.NoError(t, err)
+	tool := NewAgentLifecycleTool(cfg, manager)
+
+	for _, name := range []string{"caronex", "Caronex", "BOSS"} {
+		response, err := tool.Run(context.Background(), tools.ToolCall{Input: `{"action":"status","agent_name":"` + name + `"}`})
+		require.NoError(t, err)
+		var result map[string]any
+		require.NoError(t, json.Unmarshal([]byte(response.Content), &result))
+		assert.Equal(t, "caronex", result["agent_name"], name)
+		assert.Equal(t, "available", result["status"], name)
+	}
+}
@@ -0,0 +1,145 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/analytics"
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/doctor"
+	"github.com/caronex/intelligence-interface/internal/llm/tools"
+)
+
+// UsageReportTool reports the local usage analytics, as ii stats does
+type UsageReportTool struct {
+	config    *config.Config
+	analytics analytics.Service
+}
+
+// DoctorChecksTool runs the checks of ii doctor
+type DoctorChecksTool struct {
+	config *config.Config
+}
+
+func NewUsageReportTool(cfg *config.Config, stats analytics.Service) *UsageReportTool {
+	return &UsageReportTool{
+		config:    cfg,
+		analytics: stats,
+	}
+}
+
+func NewDoctorChecksTool(cfg *config.Config) *DoctorChecksTool {
+	return &DoctorChecksTool{
+		config: cfg,
+	}
+}
+
+type usageReportParams struct {
+	Days int `json:"days" default:"7" minimum:"1" maximum:"365" description:"Number of days to report, ending today"`
+}
+
+// usageRollup is a rollup of the usage report
+type usageRollup struct {
+	Name             string  `json:"name"`
+	Count            int64   `json:"count"`
+	Failures         int64   `json:"failures,omitempty"`
+	AverageLatencyMs int64   `json:"average_latency_ms,omitempty"`
+	FailureRate      float64 `json:"failure_rate,omitempty"`
+	Tokens           int64   `json:"tokens,omitempty"`
+}
+
+func (t *UsageReportTool) Info() tools.ToolInfo {
+	parameters, required := tools.ParamsSchema(usageReportParams{})
+	return tools.ToolInfo{
+		Name:        "usage_report",
+		Description: "Reports the local usage analytics: the messages sent per day and the use, failures and latency of the agents, tools and providers",
+		Parameters:  parameters,
+		Required:    required,
+	}
+}
+
+func (t *UsageReportTool) Run(ctx context.Context, params tools.ToolCall) (tools.ToolResponse, error) {
+	var input usageReportParams
+	if err := params.BindParams(&input, t.config.StrictToolInputs); err != nil {
+		return tools.NewParamsErrorResponse(err), nil
+	}
+	if t.analytics == nil {
+		return tools.NewTextErrorResponse("Usage analytics are not available"), nil
+	}
+
+	to := time.Now()
+	from := to.AddDate(0, 0, -(input.Days - 1))
+	summary, err := t.analytics.Summary(ctx, from, to)
+	if err != nil {
+		return tools.NewTextErrorResponse(fmt.Sprintf("Failed to load usage analytics: %v", err)), nil
+	}
+
+	messages := make(map[string]int64, len(summary.Days))
+	for _, day := range summary.Days {
+		messages[day.Day] = day.Count
+	}
+	result := map[string]any{
+		"from":            summary.From.Format(analytics.DayFormat),
+		"to":              summary.To.Format(analytics.DayFormat),
+		"messages_by_day": messages,
+		"agents":          usageRollups(summary.Agents),
+		"tools":           usageRollups(summary.Tools),
+		"providers":       usageRollups(summary.Providers),
+		"provider_errors": usageRollups(summary.ProviderErrors),
+		"embeddings":      usageRollups(summary.Embeddings),
+	}
+	resultBytes, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return tools.NewTextErrorResponse(fmt.Sprintf("Failed to serialize usage report: %v", err)), nil
+	}
+	return tools.NewTextResponse(string(resultBytes)), nil
+}
+
+func usageRollups(rollups []analytics.Rollup) []usageRollup {
+	result := make([]usageRollup, len(rollups))
+	for i, r := range rollups {
+		result[i] = usageRollup{
+			Name:             r.Name,
+			Count:            r.Count,
+			Failures:         r.Failures,
+			AverageLatencyMs: r.AverageLatency().Milliseconds(),
+			FailureRate:      r.FailureRate(),
+			Tokens:           r.Tokens,
+		}
+	}
+	return result
+}
+
+type doctorChecksParams struct {
+	Offline bool `json:"offline" description:"Skip the checks reaching the network: provider keys and remote MCP servers"`
+}
+
+func (t *DoctorChecksTool) Info() tools.ToolInfo {
+	parameters, required := tools.ParamsSchema(doctorChecksParams{})
+	return tools.ToolInfo{
+		Name:        "doctor_checks",
+		Description: "Diagnoses common misconfigurations: provider keys, data directory, database schema, MCP servers, shell and LSP servers",
+		Parameters:  parameters,
+		Required:    required,
+	}
+}
+
+func (t *DoctorChecksTool) Run(ctx context.Context, params tools.ToolCall) (tools.ToolResponse, error) {
+	var input doctorChecksParams
+	if err := params.BindParams(&input, t.config.StrictToolInputs); err != nil {
+		return tools.NewParamsErrorResponse(err), nil
+	}
+
+	checks := doctor.Checks(t.config, nil, doctor.Options{Offline: input.Offline})
+	results := doctor.Run(ctx, checks, doctor.DefaultTimeout)
+	resultBytes, err := json.MarshalIndent(map[string]any{
+		"checks": results,
+		"failed": doctor.Failed(results),
+	}, "", "  ")
+	if err != nil {
+		return tools.NewTextErrorResponse(fmt.Sprintf("Failed to serialize doctor results: %v", err)), nil
+	}
+	return tools.NewTextResponse(string(resultBytes)), nil
+}
@@ -0,0 +1,195 @@
+package coordination
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+)
+
+// AutoContinueMarker ends the responses of an auto mode run with work left
+const AutoContinueMarker = "[continue]"
+
+// AutoModeInstructions are sent along with the task of an auto mode run
+const AutoModeInstructions = `You are working in auto mode: you are prompted to continue after every turn instead of waiting for the user. Work on the task step by step, editing files and running commands and tests as needed.
+- When you end a turn with work left, end your response with a line containing only ` + AutoContinueMarker + `.
+- When the task is done, summarize what you did, without the marker.
+- When you need input from the user, ask your question, without the marker.`
+
+// AutoContinuePrompt asks the agent of an auto mode run to carry on
+const AutoContinuePrompt = "Continue with the task."
+
+// AutoStop is the reason an auto mode run stopped
+type AutoStop string
+
+const (
+	AutoStopDone      AutoStop = "done"
+	AutoStopQuestion  AutoStop = "question"
+	AutoStopTestsPass AutoStop = "tests_pass"
+	AutoStopSteps     AutoStop = "step_budget"
+	AutoStopDuration  AutoStop = "time_budget"
+	AutoStopTokens    AutoStop = "token_budget"
+)
+
+// Describe returns what the stop reason means for the user
+func (s AutoStop) Describe() string {
+	switch s {
+	case AutoStopDone:
+		return "the task is done"
+	case AutoStopQuestion:
+		return "the agent needs your input"
+	case AutoStopTestsPass:
+		return "the tests pass"
+	case AutoStopSteps:
+		return "the step budget is exhausted"
+	case AutoStopDuration:
+		return "the time budget is exhausted"
+	case AutoStopTokens:
+		return "the token budget is exhausted"
+	}
+	return string(s)
+}
+
+// BudgetExhausted reports whether the run stopped on one of its limits
+func (s AutoStop) BudgetExhausted() bool {
+	return s == AutoStopSteps || s == AutoStopDuration || s == AutoStopTokens
+}
+
+// AutoTurnResult is the outcome of one turn of an auto mode run
+type AutoTurnResult struct {
+	Text string
+	// Tokens is the number of tokens the turn generated
+	Tokens int64
+}
+
+// AutoRun is what an auto mode run coordinates
+type AutoRun struct {
+	// Turn has the agent respond to a prompt, running the tools it calls
+	Turn func(ctx context.Context, prompt string) (AutoTurnResult, error)
+	// Verify runs the tests after a turn and reports whether they pass, nil
+	// when there are no tests to run
+	Verify func(ctx context.Context) (bool, error)
+	// Progress is told about the run after every turn, when set
+	Progress func(outcome AutoOutcome)
+}
+
+// AutoOutcome is the state of an auto mode run
+type AutoOutcome struct {
+	Steps   int
+	Tokens  int64
+	Elapsed time.Duration
+	// Stop is why the run stopped, empty while it runs
+	Stop AutoStop
+	// Response is the latest response of the agent
+	Response string
+	// TestsFail is set when the tests ran after the latest turn and failed
+	TestsFail bool
+}
+
+// Progress describes how much of its limits the run used
+func (o AutoOutcome) Progress(limits config.AutoMode) string {
+	return fmt.Sprintf("step %d/%d, %d/%d tokens, %s/%s", o.Steps, limits.Steps(),
+		o.Tokens, limits.Tokens(), o.Elapsed.Round(time.Second), limits.Duration())
+}
+
+// Summary describes how the run ended
+func (o AutoOutcome) Summary() string {
+	return fmt.Sprintf("Auto mode stopped, %s: %d steps, %d tokens in %s",
+		o.Stop.Describe(), o.Steps, o.Tokens, o.Elapsed.Round(time.Second))
+}
+
+// Failure returns the failure a run which stopped without an error ended
+// with: a limit reached, or the agent being done while the tests fail. It is
+// nil for the runs which succeeded or stopped on a question.
+func (o AutoOutcome) Failure() *FailureDetail {
+	switch {
+	case o.Stop.BudgetExhausted():
+		return ClassifyFailure(NewFailure(FailureBudget, errors.New(o.Summary())))
+	case o.Stop == AutoStopDone && o.TestsFail:
+		return ClassifyFailure(NewFailure(FailureVerification, errors.New("the agent reported the task done, but the tests fail")))
+	}
+	return nil
+}
+
+// RunAuto has the agent work on a task turn after turn until it is done or
+// asks a question, the tests pass, or a limit of limits is reached. The
+// wall-clock limit cancels the turn in progress. The outcome is returned
+// along with the error of a turn that failed.
+func RunAuto(ctx context.Context, limits config.AutoMode, task string, run AutoRun) (*AutoOutcome, error) {
+	logger := logging.FromContext(ctx)
+	start := time.Now()
+	runCtx, cancel := context.WithTimeout(ctx, limits.Duration())
+	defer cancel()
+
+	outcome := &AutoOutcome{}
+	prompt := task + "\n\n" + AutoModeInstructions
+	for {
+		result, err := run.Turn(runCtx, prompt)
+		outcome.Steps++
+		outcome.Tokens += result.Tokens
+		outcome.Elapsed = time.Since(start)
+		if err != nil {
+			if ctx.Err() == nil && errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+				outcome.Stop = AutoStopDuration
+				return outcome, nil
+			}
+			return outcome, err
+		}
+		outcome.Response = result.Text
+		if run.Progress != nil {
+			run.Progress(*outcome)
+		}
+
+		if run.Verify != nil {
+			pass, err := run.Verify(runCtx)
+			if err != nil {
+				logger.Warn("auto mode tests could not run", "error", err)
+			}
+			outcome.TestsFail = err == nil && !pass
+			if pass {
+				outcome.Stop = AutoStopTestsPass
+				return outcome, nil
+			}
+		}
+		switch {
+		case !ContinuesAuto(result.Text) && AsksQuestion(result.Text):
+			outcome.Stop = AutoStopQuestion
+		case !ContinuesAuto(result.Text):
+			outcome.Stop = AutoStopDone
+		case outcome.Tokens >= limits.Tokens():
+			outcome.Stop = AutoStopTokens
+		case outcome.Steps >= limits.Steps():
+			outcome.Stop = AutoStopSteps
+		case outcome.Elapsed >= limits.Duration():
+			outcome.Stop = AutoStopDuration
+		}
+		if outcome.Stop != "" {
+			return outcome, nil
+		}
+		prompt = AutoContinuePrompt
+	}
+}
+
+// ContinuesAuto reports whether a response of an auto mode run says work is
+// left, its last line being the continue marker
+func ContinuesAuto(response string) bool {
+	return strings.EqualFold(lastLine(response), AutoContinueMarker)
+}
+
+// AsksQuestion reports whether a response ends with a question
+func AsksQuestion(response string) bool {
+	return strings.HasSuffix(lastLine(response), "?")
+}
+
+// lastLine returns the last non-blank line of text, trimmed
+func lastLine(text string) string {
+	text = strings.TrimSpace(text)
+	if i := strings.LastIndex(text, "\n"); i >= 0 {
+		text = text[i+1:]
+	}
+	return strings.TrimSpace(text)
+}
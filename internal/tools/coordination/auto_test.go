@@ -0,0 +1,138 @@
+package coordination
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+)
+
+func TestRunAuto(t *testing.T) {
+	working := AutoTurnResult{Text: "edited a file\n" + AutoContinueMarker, Tokens: 10}
+
+	tests := []struct {
+		name   string
+		limits config.AutoMode
+		turns  []AutoTurnResult
+		pass   int
+		want   AutoStop
+	}{
+		{
+			name:   "done",
+			limits: config.AutoMode{},
+			turns:  []AutoTurnResult{working, {Text: "all done"}},
+			want:   AutoStopDone,
+		},
+		{
+			name:   "question",
+			limits: config.AutoMode{},
+			turns:  []AutoTurnResult{{Text: "Which database should I use?"}},
+			want:   AutoStopQuestion,
+		},
+		{
+			name:   "step budget",
+			limits: config.AutoMode{MaxSteps: 3},
+			turns:  []AutoTurnResult{working, working, working},
+			want:   AutoStopSteps,
+		},
+		{
+			name:   "token budget",
+			limits: config.AutoMode{TokenBudget: 15},
+			turns:  []AutoTurnResult{working, working},
+			want:   AutoStopTokens,
+		},
+		{
+			name:   "tests pass",
+			limits: config.AutoMode{},
+			turns:  []AutoTurnResult{working, working},
+			pass:   2,
+			want:   AutoStopTestsPass,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var turns, checks int
+			run := AutoRun{
+				Turn: func(ctx context.Context, prompt string) (AutoTurnResult, error) {
+					result := tt.turns[turns]
+					turns++
+					return result, nil
+				},
+			}
+			if tt.pass > 0 {
+				run.Verify = func(ctx context.Context) (bool, error) {
+					checks++
+					return checks == tt.pass, nil
+				}
+			}
+			got, err := RunAuto(context.Background(), tt.limits, "task", run)
+			if err != nil {
+				t.Fatalf("RunAuto() error = %v", err)
+			}
+			if got.Stop != tt.want || got.Steps != len(tt.turns) || turns != len(tt.turns) {
+				t.Errorf("RunAuto() = %+v after %d turns, want %s after %d", *got, turns, tt.want, len(tt.turns))
+			}
+		})
+	}
+}
+
+func TestRunAutoTimeBudget(t *testing.T) {
+	got, err := RunAuto(context.Background(), config.AutoMode{MaxDuration: "50ms"}, "task", AutoRun{
+		Turn: func(ctx context.Context, prompt string) (AutoTurnResult, error) {
+			<-ctx.Done()
+			return AutoTurnResult{}, ctx.Err()
+		},
+	})
+	if err != nil {
+		t.Fatalf("RunAuto() error = %v", err)
+	}
+	if got.Stop != AutoStopDuration || got.Steps != 1 {
+		t.Errorf("RunAuto() = %+v, want a time budget stop after 1 step", *got)
+	}
+}
+
+func TestRunAutoAborted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := RunAuto(ctx, config.AutoMode{}, "task", AutoRun{
+		Turn: func(ctx context.Context, prompt string) (AutoTurnResult, error) {
+			cancel()
+			return AutoTurnResult{}, ctx.Err()
+		},
+	})
+	if err != context.Canceled {
+		t.Errorf("RunAuto() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestRecordDelegation(t *testing.T) {
+	dir := t.TempDir()
+	for _, status := range []string{"done", "step_budget"} {
+		if err := RecordDelegation(dir, DelegationEvent{Agent: "caronex", Mode: "auto", Status: status}); err != nil {
+			t.Fatalf("RecordDelegation() error = %v", err)
+		}
+	}
+
+	file, err := os.Open(filepath.Join(dir, EventLogFilename))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer file.Close()
+	decoder := json.NewDecoder(file)
+	var statuses []string
+	for decoder.More() {
+		var event DelegationEvent
+		if err := decoder.Decode(&event); err != nil {
+			t.Fatalf("Decode() error = %v", err)
+		}
+		if event.Type != EventTypeDelegation || event.Time.IsZero() {
+			t.Errorf("event = %+v, want a timestamped delegation", event)
+		}
+		statuses = append(statuses, event.Status)
+	}
+	if len(statuses) != 2 || statuses[0] != "done" || statuses[1] != "step_budget" {
+		t.Errorf("statuses = %v, want [done step_budget]", statuses)
+	}
+}
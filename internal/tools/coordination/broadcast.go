@@ -0,0 +1,196 @@
+package coordination
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/pubsub"
+)
+
+const (
+	// SystemEventConfigChanged tells the agents the configuration changed, so
+	// they re-read their prompt configuration
+	SystemEventConfigChanged = "config_changed"
+	// SystemEventHalt and SystemEventEvolve are the events of Caronex that
+	// take the highest priority, preempting the other queued events
+	SystemEventHalt   = "halt"
+	SystemEventEvolve = "evolve"
+)
+
+// SystemEvent is a system-level event broadcast to every registered agent
+type SystemEvent struct {
+	Type    string `json:"type"`
+	Payload string `json:"payload,omitempty"`
+	// Priority orders the events waiting in the queue of the "queue"
+	// protocol, from 1 to the configured maximum, highest first
+	Priority int       `json:"priority"`
+	Time     time.Time `json:"time"`
+}
+
+// SystemEventHandler handles the system events broadcast to an agent
+type SystemEventHandler interface {
+	HandleSystemEvent(ctx context.Context, event SystemEvent)
+}
+
+// registration is a handler receiving the system events on behalf of an
+// agent, until ctx is done
+type registration struct {
+	ctx     context.Context
+	agent   config.AgentName
+	handler SystemEventHandler
+	// events is the subscription the broadcasts are delivered to
+	events <-chan pubsub.Event[SystemEvent]
+}
+
+var (
+	// systemEvents carries the broadcasts to the handlers of every manager
+	systemEvents = pubsub.NewBroker[SystemEvent]()
+	// systemQueue holds the broadcasts of the "queue" protocol until the
+	// handlers are done with the previous ones
+	systemQueue  = newEventQueue()
+	dispatchOnce sync.Once
+
+	handlersMu sync.Mutex
+	// handlers are the registered handlers
+	handlers []*registration
+)
+
+// RegisterSystemEventHandler has handler receive the system events broadcast
+// to the agents on behalf of agent, until ctx is done
+func RegisterSystemEventHandler(ctx context.Context, agent config.AgentName, handler SystemEventHandler) {
+	r := &registration{ctx: ctx, agent: agent, handler: handler}
+	handlersMu.Lock()
+	events := systemEvents.Subscribe(ctx)
+	r.events = events
+	handlers = append(handlers, r)
+	handlersMu.Unlock()
+
+	go func() {
+		defer logging.RecoverPanic("coordination.SystemEventHandler", nil)
+		defer func() {
+			handlersMu.Lock()
+			defer handlersMu.Unlock()
+			handlers = slices.DeleteFunc(handlers, func(h *registration) bool { return h == r })
+		}()
+		for event := range events {
+			handler.HandleSystemEvent(ctx, event.Payload)
+		}
+	}()
+}
+
+// registeredAgents returns the names of the agents with a registered
+// handler for which include is true, sorted. handlersMu must be held.
+func registeredAgents(include func(h *registration) bool) []string {
+	agents := make([]string, 0, len(handlers))
+	for _, h := range handlers {
+		if h.ctx.Err() == nil && include(h) && !slices.Contains(agents, string(h.agent)) {
+			agents = append(agents, string(h.agent))
+		}
+	}
+	sort.Strings(agents)
+	return agents
+}
+
+// eventPriority returns the priority of an event broadcast with priority,
+// within 1 and maxPriority. The halt and evolve events take maxPriority
+// unless given another, the other events DefaultEventPriority.
+func eventPriority(eventType string, priority, maxPriority int) int {
+	if priority == 0 {
+		priority = min(DefaultEventPriority, maxPriority)
+		if eventType == SystemEventHalt || eventType == SystemEventEvolve {
+			priority = maxPriority
+		}
+	}
+	return max(1, min(priority, maxPriority))
+}
+
+// Broadcast sends a system event to every registered agent and returns the
+// names of the agents it is delivered to, leaving out the ones too far behind
+// to take it. Priority orders the events queued under the "queue"
+// communication protocol, 0 taking the default of the event type. The other
+// protocols deliver the events right away.
+func (m *Manager) Broadcast(eventType, payload string, priority int) ([]string, error) {
+	if eventType == "" {
+		return nil, errors.New("the event type is required")
+	}
+	coordination := m.config.Caronex.Coordination
+	event := SystemEvent{
+		Type:     eventType,
+		Payload:  payload,
+		Priority: eventPriority(eventType, priority, coordination.HighestPriority()),
+		Time:     time.Now(),
+	}
+
+	handlersMu.Lock()
+	if coordination.CommunicationProtocol != "queue" {
+		delivered := systemEvents.Deliver(pubsub.CreatedEvent, event)
+		queued := func(h *registration) bool { return slices.Contains(delivered, h.events) }
+		deliveredTo := registeredAgents(queued)
+		missed := registeredAgents(func(h *registration) bool { return !queued(h) })
+		handlersMu.Unlock()
+		if len(missed) > 0 {
+			logging.Warn("System event not delivered, the agents are too far behind", "type", eventType, "agents", missed, logging.ComponentKey, logging.ComponentDataLoss)
+		}
+		logging.Info("System event broadcast", "type", eventType, "delivered_to", deliveredTo)
+		return deliveredTo, nil
+	}
+	deliveredTo := registeredAgents(func(*registration) bool { return true })
+	handlersMu.Unlock()
+
+	dispatchOnce.Do(func() { go dispatchQueue() })
+	if dropped, ok := systemQueue.push(event, coordination.MessageQueueDepth()); ok {
+		logging.Warn("System event queue full, dropped the lowest-priority event",
+			"type", dropped.Type, "priority", dropped.Priority, "depth", coordination.MessageQueueDepth(), logging.ComponentKey, logging.ComponentDataLoss)
+		if dropped == event {
+			deliveredTo = nil
+		}
+	}
+	logging.Info("System event queued", "type", eventType, "priority", event.Priority, "delivered_to", deliveredTo)
+	return deliveredTo, nil
+}
+
+// dispatchQueue delivers the queued system events, highest priority first,
+// to each registered handler in turn. An event is delivered once the handlers
+// are done with the previous one, so the events queued meanwhile are ordered.
+func dispatchQueue() {
+	for range systemQueue.ready {
+		for {
+			event, ok := systemQueue.pop()
+			if !ok {
+				break
+			}
+			handlersMu.Lock()
+			registered := slices.Clone(handlers)
+			handlersMu.Unlock()
+			for _, h := range registered {
+				if h.ctx.Err() == nil {
+					h.handle(event)
+				}
+			}
+		}
+	}
+}
+
+// handle delivers a queued event to the handler, a panicking handler not
+// stopping the delivery of the next events
+func (r *registration) handle(event SystemEvent) {
+	defer logging.RecoverPanic("coordination.SystemEventHandler", nil)
+	r.handler.HandleSystemEvent(r.ctx, event)
+}
+
+// QueueStatus returns the state of the system event queue, nil unless the
+// "queue" communication protocol is configured
+func (m *Manager) QueueStatus() *QueueStatus {
+	coordination := m.config.Caronex.Coordination
+	if coordination.CommunicationProtocol != "queue" {
+		return nil
+	}
+	status := systemQueue.status(coordination.MessageQueueDepth())
+	return &status
+}
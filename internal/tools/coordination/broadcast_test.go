@@ -0,0 +1,107 @@
+package coordination
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+)
+
+// recordingHandler sends the system events it handles on a channel
+type recordingHandler chan SystemEvent
+
+func (h recordingHandler) HandleSystemEvent(ctx context.Context, event SystemEvent) {
+	h <- event
+}
+
+func TestBroadcast(t *testing.T) {
+	manager, err := NewManager(config.NewTestConfig(config.WithWorkingDir(t.TempDir())))
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	if _, err := manager.Broadcast("", "payload", 0); err == nil {
+		t.Error("Broadcast() without an event type succeeded")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	coder, caronex := make(recordingHandler, 1), make(recordingHandler, 1)
+	RegisterSystemEventHandler(ctx, "coder", coder)
+	RegisterSystemEventHandler(ctx, config.AgentCaronex, caronex)
+
+	deliveredTo, err := manager.Broadcast(SystemEventConfigChanged, "reload", 0)
+	if err != nil {
+		t.Fatalf("Broadcast() error = %v", err)
+	}
+	if !slices.Equal(deliveredTo, []string{"caronex", "coder"}) {
+		t.Errorf("Broadcast() delivered to %v, want [caronex coder]", deliveredTo)
+	}
+	for _, handler := range []recordingHandler{coder, caronex} {
+		select {
+		case event := <-handler:
+			if event.Type != SystemEventConfigChanged || event.Payload != "reload" {
+				t.Errorf("handled %+v, want the broadcast event", event)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("the broadcast event was not handled")
+		}
+	}
+
+	// Handlers stop receiving events once their context is done
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for {
+		deliveredTo, _ = manager.Broadcast(SystemEventConfigChanged, "", 0)
+		if len(deliveredTo) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Broadcast() delivered to %v after the handlers were done", deliveredTo)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// blockedHandler does not return from the first event until released
+type blockedHandler chan struct{}
+
+func (h blockedHandler) HandleSystemEvent(ctx context.Context, event SystemEvent) {
+	<-h
+}
+
+func TestBroadcastToAgentBehind(t *testing.T) {
+	manager, err := NewManager(config.NewTestConfig(config.WithWorkingDir(t.TempDir())))
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	blocked := make(blockedHandler)
+	defer close(blocked)
+	RegisterSystemEventHandler(ctx, "coder", blocked)
+	caronex := make(recordingHandler, 1)
+	RegisterSystemEventHandler(ctx, config.AgentCaronex, caronex)
+
+	// Once the events waiting for the blocked agent fill its buffer, the next
+	// ones are not delivered to it, while Caronex keeps up
+	for range 1000 {
+		deliveredTo, err := manager.Broadcast(SystemEventConfigChanged, "reload", 0)
+		if err != nil {
+			t.Fatalf("Broadcast() error = %v", err)
+		}
+		if !slices.Contains(deliveredTo, "coder") {
+			if !slices.Equal(deliveredTo, []string{"caronex"}) {
+				t.Errorf("Broadcast() delivered to %v, want [caronex]", deliveredTo)
+			}
+			return
+		}
+		select {
+		case <-caronex:
+		case <-time.After(time.Second):
+			t.Fatal("the broadcast event was not handled")
+		}
+	}
+	t.Error("Broadcast() reported every event delivered to the blocked agent")
+}
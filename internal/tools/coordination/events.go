@@ -0,0 +1,108 @@
+package coordination
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/message"
+)
+
+// EventLogFilename is the coordination event log in the data directory, one
+// JSON event per line
+const EventLogFilename = "coordination-events.jsonl"
+
+// EventTypeDelegation is the type of the events recording delegated tasks
+const EventTypeDelegation = "delegation"
+
+// DelegationEvent records a task delegated to an agent and how it ended
+type DelegationEvent struct {
+	Type      string    `json:"type"`
+	Time      time.Time `json:"time"`
+	SessionID string    `json:"session_id"`
+	Agent     string    `json:"agent"`
+	// Mode is how the agent worked on the task, such as "auto"
+	Mode     string `json:"mode"`
+	Task     string `json:"task"`
+	Status   string `json:"status"`
+	Steps    int    `json:"steps"`
+	Tokens   int64  `json:"tokens"`
+	Duration string `json:"duration"`
+	Summary  string `json:"summary,omitempty"`
+	// CancelledBy tells who or what cancelled the delegation, such as "user"
+	CancelledBy string `json:"cancelled_by,omitempty"`
+	// Changes are the files the delegation changed in the workspace, recorded
+	// without their diffs
+	Changes *message.WorkspaceChanges `json:"changes,omitempty"`
+	// Failure is what the delegation failed with, and Retries what its
+	// earlier attempts failed with
+	Failure *FailureDetail  `json:"failure,omitempty"`
+	Retries []FailureDetail `json:"retries,omitempty"`
+}
+
+// eventLogMu serializes the writes to the event log
+var eventLogMu sync.Mutex
+
+// RecordDelegation appends a delegation to the event log of dataDir
+func RecordDelegation(dataDir string, event DelegationEvent) error {
+	event.Type = EventTypeDelegation
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	if event.Changes != nil {
+		// The diffs are in the session the changes were reported to
+		recorded := event.Changes.WithoutDiffs()
+		event.Changes = &recorded
+	}
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal delegation: %w", err)
+	}
+
+	eventLogMu.Lock()
+	defer eventLogMu.Unlock()
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+	file, err := os.OpenFile(filepath.Join(dataDir, EventLogFilename), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open event log: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write event log: %w", err)
+	}
+	return nil
+}
+
+// ReadDelegations returns the last limit delegations of the event log of
+// dataDir, oldest first, or all of them when limit is 0. Lines which cannot
+// be parsed are skipped.
+func ReadDelegations(dataDir string, limit int) ([]DelegationEvent, error) {
+	eventLogMu.Lock()
+	data, err := os.ReadFile(filepath.Join(dataDir, EventLogFilename))
+	eventLogMu.Unlock()
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event log: %w", err)
+	}
+
+	var events []DelegationEvent
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		var event DelegationEvent
+		if len(bytes.TrimSpace(line)) == 0 || json.Unmarshal(line, &event) != nil || event.Type != EventTypeDelegation {
+			continue
+		}
+		events = append(events, event)
+	}
+	if limit > 0 && len(events) > limit {
+		events = events[len(events)-limit:]
+	}
+	return events, nil
+}
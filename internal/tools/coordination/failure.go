@@ -0,0 +1,169 @@
+package coordination
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/caronex/intelligence-interface/internal/connectivity"
+	"github.com/caronex/intelligence-interface/internal/llm/provider"
+	"github.com/caronex/intelligence-interface/internal/llm/tools"
+	"github.com/caronex/intelligence-interface/internal/permission"
+)
+
+// FailureCategory is the kind of failure a delegated task or plan step ended
+// with, whatever the layer it failed in
+type FailureCategory string
+
+const (
+	FailureProvider     FailureCategory = "provider_error"
+	FailureBudget       FailureCategory = "budget_exceeded"
+	FailureTool         FailureCategory = "tool_failure"
+	FailureVerification FailureCategory = "verification_failed"
+	FailureCancelled    FailureCategory = "cancelled"
+	FailureTimeout      FailureCategory = "timeout"
+	FailurePolicy       FailureCategory = "policy_denied"
+	// FailureUnknown is the category of the errors no layer mapped
+	FailureUnknown FailureCategory = "unknown"
+)
+
+// FollowUp is what the manager does about a failure
+type FollowUp string
+
+const (
+	FollowUpRetryOtherAgent FollowUp = "retry_with_another_agent"
+	FollowUpRetry           FollowUp = "retry"
+	FollowUpRevise          FollowUp = "revise_and_retry"
+	FollowUpIncreaseBudget  FollowUp = "increase_budget"
+	FollowUpSurface         FollowUp = "surface_to_user"
+	FollowUpNone            FollowUp = "none"
+)
+
+// FollowUp returns what the manager does about a failure of the category
+func (c FailureCategory) FollowUp() FollowUp {
+	switch c {
+	case FailureProvider:
+		return FollowUpRetryOtherAgent
+	case FailureTimeout:
+		return FollowUpRetry
+	case FailureTool, FailureVerification:
+		return FollowUpRevise
+	case FailureBudget:
+		return FollowUpIncreaseBudget
+	case FailureCancelled:
+		return FollowUpNone
+	}
+	return FollowUpSurface
+}
+
+// Suggestion tells the user what to do about a failure of the category
+func (c FailureCategory) Suggestion() string {
+	switch c {
+	case FailureProvider:
+		return "The task is retried once with another agent; check the provider configuration if it keeps failing."
+	case FailureTimeout:
+		return "Retry the task, or raise the provider timeout if it keeps timing out."
+	case FailureTool:
+		return "Revise the task so the agent calls its tools with valid inputs, then delegate it again."
+	case FailureVerification:
+		return "Review the changes against the verification of the step, then delegate the fixes."
+	case FailureBudget:
+		return "Raise the budget of the run, such as the auto mode limits or the model's max tokens, or split the task."
+	case FailurePolicy:
+		return "The user must allow the action, or change the permissions, before the task can go on."
+	case FailureCancelled:
+		return ""
+	}
+	return "Look at the error and decide with the user how to go on."
+}
+
+// FailureDetail is the structured failure of a delegation or plan step
+type FailureDetail struct {
+	Category FailureCategory `json:"category"`
+	// Detail is the error the failure was mapped from
+	Detail     string   `json:"detail"`
+	FollowUp   FollowUp `json:"follow_up"`
+	Suggestion string   `json:"suggestion,omitempty"`
+	// Agent is the agent the failure happened with
+	Agent string `json:"agent,omitempty"`
+}
+
+// String returns the failure as shown to users
+func (f FailureDetail) String() string {
+	return fmt.Sprintf("%s: %s", f.Category, f.Detail)
+}
+
+// Failure is an error a layer mapped to a category of the taxonomy, which
+// ClassifyFailure keeps
+type Failure struct {
+	Category FailureCategory
+	Err      error
+}
+
+// NewFailure returns err mapped to category
+func NewFailure(category FailureCategory, err error) error {
+	return &Failure{Category: category, Err: err}
+}
+
+func (e *Failure) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Failure) Unwrap() error {
+	return e.Err
+}
+
+// ClassifyFailure maps err to the taxonomy, nil when there is no error. The
+// category set by a layer with NewFailure wins; otherwise the errors of the
+// providers, permissions and tools are recognized, others are unknown.
+func ClassifyFailure(err error) *FailureDetail {
+	if err == nil {
+		return nil
+	}
+	detail := &FailureDetail{Category: categorize(err), Detail: err.Error()}
+	detail.FollowUp = detail.Category.FollowUp()
+	detail.Suggestion = detail.Category.Suggestion()
+	var providerErr *provider.ProviderError
+	if errors.As(err, &providerErr) && providerErr.Hint != "" {
+		detail.Suggestion = providerErr.Hint
+	}
+	return detail
+}
+
+func categorize(err error) FailureCategory {
+	var failure *Failure
+	if errors.As(err, &failure) {
+		return failure.Category
+	}
+	if errors.Is(err, ErrOperationCancelled) || errors.Is(err, context.Canceled) {
+		return FailureCancelled
+	}
+	var timeoutErr *provider.TimeoutError
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &timeoutErr) {
+		return FailureTimeout
+	}
+	if errors.Is(err, permission.ErrorPermissionDenied) {
+		return FailurePolicy
+	}
+	var providerErr *provider.ProviderError
+	if errors.As(err, &providerErr) {
+		switch providerErr.Category {
+		case provider.ErrorTimeout:
+			return FailureTimeout
+		case provider.ErrorContentFilter:
+			// Another agent would be refused as well
+			return FailurePolicy
+		}
+		return FailureProvider
+	}
+	if errors.Is(err, connectivity.ErrOffline) {
+		return FailureProvider
+	}
+	var inputErr *tools.InputError
+	var paramsErr *tools.ParamsError
+	var conflictErr *tools.ConflictError
+	if errors.As(err, &inputErr) || errors.As(err, &paramsErr) || errors.As(err, &conflictErr) {
+		return FailureTool
+	}
+	return FailureUnknown
+}
@@ -0,0 +1,143 @@
+package coordination
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/caronex/intelligence-interface/internal/connectivity"
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/llm/provider"
+	"github.com/caronex/intelligence-interface/internal/llm/tools"
+	"github.com/caronex/intelligence-interface/internal/permission"
+)
+
+func TestClassifyFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		category FailureCategory
+		followUp FollowUp
+	}{
+		{"provider", &provider.ProviderError{Category: provider.ErrorRateLimit, Message: "rate limited"}, FailureProvider, FollowUpRetryOtherAgent},
+		{"provider wrapped", fmt.Errorf("generate: %w", &provider.ProviderError{Category: provider.ErrorAuth, Message: "bad key"}), FailureProvider, FollowUpRetryOtherAgent},
+		{"offline", connectivity.ErrOffline, FailureProvider, FollowUpRetryOtherAgent},
+		{"provider timeout", &provider.ProviderError{Category: provider.ErrorTimeout, Message: "timed out"}, FailureTimeout, FollowUpRetry},
+		{"request timeout", &provider.TimeoutError{}, FailureTimeout, FollowUpRetry},
+		{"deadline", context.DeadlineExceeded, FailureTimeout, FollowUpRetry},
+		{"content filter", &provider.ProviderError{Category: provider.ErrorContentFilter, Message: "blocked"}, FailurePolicy, FollowUpSurface},
+		{"permission denied", fmt.Errorf("bash: %w", permission.ErrorPermissionDenied), FailurePolicy, FollowUpSurface},
+		{"cancelled", context.Canceled, FailureCancelled, FollowUpNone},
+		{"operation cancelled", ErrOperationCancelled, FailureCancelled, FollowUpNone},
+		{"tool input", &tools.InputError{Tool: "edit", Err: errors.New("bad json")}, FailureTool, FollowUpRevise},
+		{"tool params", &tools.ParamsError{}, FailureTool, FollowUpRevise},
+		{"tool conflict", &tools.ConflictError{Path: "main.go"}, FailureTool, FollowUpRevise},
+		{"mapped budget", NewFailure(FailureBudget, errors.New("max tokens")), FailureBudget, FollowUpIncreaseBudget},
+		{"mapped verification", NewFailure(FailureVerification, errors.New("tests fail")), FailureVerification, FollowUpRevise},
+		{"mapped wins", NewFailure(FailurePolicy, context.Canceled), FailurePolicy, FollowUpSurface},
+		{"unknown", errors.New("boom"), FailureUnknown, FollowUpSurface},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failure := ClassifyFailure(tt.err)
+			require.NotNil(t, failure)
+			assert.Equal(t, tt.category, failure.Category)
+			assert.Equal(t, tt.followUp, failure.FollowUp)
+			assert.Equal(t, tt.err.Error(), failure.Detail)
+		})
+	}
+	assert.Nil(t, ClassifyFailure(nil))
+
+	failure := ClassifyFailure(&provider.ProviderError{Category: provider.ErrorQuota, Message: "quota", Hint: "Add credits"})
+	assert.Equal(t, "Add credits", failure.Suggestion, "the hint of the provider is the suggestion")
+}
+
+func TestAutoOutcomeFailure(t *testing.T) {
+	tests := []struct {
+		name    string
+		outcome AutoOutcome
+		want    FailureCategory
+	}{
+		{"done", AutoOutcome{Stop: AutoStopDone}, ""},
+		{"tests pass", AutoOutcome{Stop: AutoStopTestsPass}, ""},
+		{"question", AutoOutcome{Stop: AutoStopQuestion, TestsFail: true}, ""},
+		{"done with failing tests", AutoOutcome{Stop: AutoStopDone, TestsFail: true}, FailureVerification},
+		{"step budget", AutoOutcome{Stop: AutoStopSteps}, FailureBudget},
+		{"token budget", AutoOutcome{Stop: AutoStopTokens}, FailureBudget},
+		{"time budget", AutoOutcome{Stop: AutoStopDuration}, FailureBudget},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failure := tt.outcome.Failure()
+			if tt.want == "" {
+				assert.Nil(t, failure)
+				return
+			}
+			require.NotNil(t, failure)
+			assert.Equal(t, tt.want, failure.Category)
+		})
+	}
+}
+
+func TestDelegationFailures(t *testing.T) {
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()), config.WithTestAgents("coder", "reviewer"))
+	cfg.Data.Directory = t.TempDir()
+	manager, err := NewManager(cfg)
+	require.NoError(t, err)
+
+	// The coder's provider fails, and the other agents are denied a
+	// permission
+	var agents []string
+	SetDelegationRunner(func(ctx context.Context, op Operation) (string, error) {
+		agents = append(agents, op.Agent)
+		if op.Agent == "coder" {
+			return "", &provider.ProviderError{Category: provider.ErrorRateLimit, Message: "rate limited"}
+		}
+		return "", NewFailure(FailurePolicy, errors.New("a permission the task needed was denied"))
+	})
+	t.Cleanup(func() { SetDelegationRunner(nil) })
+
+	plan, err := manager.CreateTaskPlan(context.Background(), "add login", []string{"a"}, "")
+	require.NoError(t, err)
+	step := plan.Steps[0]
+	delegation, err := manager.DelegateTask(context.Background(), plan.TaskID, step.Description, "coder", false)
+	require.NoError(t, err)
+	require.NoError(t, manager.RunDelegation(context.Background(), delegation, step.StepID, step.Description))
+
+	var op Operation
+	require.Eventually(t, func() bool {
+		op, _ = GetOperation(delegation.OperationID)
+		return op.State != OperationRunning
+	}, time.Second, 10*time.Millisecond)
+
+	// The provider error was retried with another agent, whose failure is
+	// the one of the delegation
+	require.Len(t, agents, 2)
+	assert.NotEqual(t, "coder", agents[1])
+	require.Len(t, op.Retries, 1)
+	assert.Equal(t, FailureProvider, op.Retries[0].Category)
+	assert.Equal(t, "coder", op.Retries[0].Agent)
+	assert.Equal(t, OperationFailed, op.State)
+	require.NotNil(t, op.Failure)
+	assert.Equal(t, FailurePolicy, op.Failure.Category)
+	assert.Equal(t, FollowUpSurface, op.Failure.FollowUp)
+	assert.Equal(t, agents[1], op.Failure.Agent)
+
+	// The failure goes to the delegation history and the step of the plan
+	var events []DelegationEvent
+	require.Eventually(t, func() bool {
+		events, err = ReadDelegations(cfg.Data.Directory, 0)
+		return err == nil && len(events) == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, op.Failure, events[0].Failure)
+	assert.Equal(t, op.Retries, events[0].Retries)
+	latest := LatestPlan()
+	require.Equal(t, plan.TaskID, latest.TaskID)
+	assert.Equal(t, OperationFailed, latest.Steps[0].Status)
+	assert.Equal(t, op.Failure, latest.Steps[0].Failure)
+}
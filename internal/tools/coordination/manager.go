@@ -1,12 +1,22 @@
 package coordination
 
 import (
+	"context"
 	"fmt"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/caronex/intelligence-interface/internal/core/config"
 	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/llm/prompt"
+	"github.com/caronex/intelligence-interface/internal/llm/tools"
+	"github.com/caronex/intelligence-interface/internal/lock"
+	"github.com/caronex/intelligence-interface/internal/tracing"
+	"github.com/caronex/intelligence-interface/internal/version"
 )
 
 // Manager provides coordination tools for the Caronex manager agent
@@ -17,6 +27,20 @@ type Manager struct {
 	introspectionTools *IntrospectionTools
 	planningTools     *PlanningTools
 	delegationTools   *DelegationTools
+
+	// Plan templates by name, and the errors from loading user templates
+	planTemplates  map[string]*PlanTemplate
+	templateErrors []error
+
+	// registry provides the agents when set, rather than the configuration
+	registryMu sync.RWMutex
+	registry   AgentRegistry
+}
+
+// AgentRegistry provides the agents known to the system, such as the
+// CaronexAgent
+type AgentRegistry interface {
+	GetAgentRegistry() map[config.AgentName]*AgentInfo
 }
 
 // IntrospectionTools provides system state inspection capabilities
@@ -33,22 +57,74 @@ type DelegationTools struct {
 
 // SystemIntrospectionResult contains results of system introspection
 type SystemIntrospectionResult struct {
-	AvailableAgents    []AgentCapability `json:"available_agents"`
+	AvailableAgents    []AgentInfo       `json:"available_agents"`
 	SystemConfig       ConfigSummary     `json:"system_config"`
 	SystemCapabilities []string          `json:"system_capabilities"`
 	SystemStatus       string            `json:"system_status"`
 	LastUpdated        time.Time         `json:"last_updated"`
+	Version            version.Info      `json:"version"`
+	UpdateAvailable    bool              `json:"update_available"`
+	WorkspaceRoots     []WorkspaceRoot   `json:"workspace_roots,omitempty"`
+	ActiveRoot         string            `json:"active_root,omitempty"`
+	// ProjectTypes are the stacks of the workspace, configured or detected
+	// from its marker files, and ProjectSummary the line summing them up
+	ProjectTypes   []config.ProjectType `json:"project_types,omitempty"`
+	ProjectSummary string               `json:"project_summary,omitempty"`
+	// Tools lists which tool each name resolves to, including shadowed collisions
+	Tools []tools.ToolResolution `json:"tools,omitempty"`
+	// TurnLatency is the latency of the recent agent turns, when tracing is enabled
+	TurnLatency *TurnLatencyMetrics `json:"turn_latency,omitempty"`
+	// ContextFiles are the project context files and whether their copy in
+	// the system prompt is stale
+	ContextFiles []prompt.ContextFileStatus `json:"context_files,omitempty"`
+	// Locks are the instances sharing the data directory and the sessions
+	// each one writes to
+	Locks *lock.Status `json:"locks,omitempty"`
+	// RequestID is the request the introspection was made in, to find its
+	// log lines
+	RequestID string `json:"request_id,omitempty"`
+	// ConfigFingerprint identifies the effective configuration, secrets
+	// excluded, to tell which configuration produced a behavior
+	ConfigFingerprint string `json:"config_fingerprint,omitempty"`
+	// MessageQueue is the state of the system event queue, when the "queue"
+	// communication protocol is configured
+	MessageQueue *QueueStatus `json:"message_queue,omitempty"`
+}
+
+// TurnLatencyMetrics are latency percentiles over the last traced turns
+type TurnLatencyMetrics struct {
+	Turns int   `json:"turns"`
+	P50Ms int64 `json:"p50_ms"`
+	P95Ms int64 `json:"p95_ms"`
+}
+
+// WorkspaceRoot describes a configured or auto-detected workspace root
+type WorkspaceRoot struct {
+	Name         string               `json:"name"`
+	Path         string               `json:"path"`
+	Detected     bool                 `json:"detected"`
+	ProjectTypes []config.ProjectType `json:"project_types,omitempty"`
 }
 
-// AgentCapability describes an agent and its capabilities
-type AgentCapability struct {
-	Name           string   `json:"name"`
-	Model          string   `json:"model"`
-	Capabilities   []string `json:"capabilities"`
-	Status         string   `json:"status"`
-	Specialization string   `json:"specialization,omitempty"`
+// AgentInfo describes a configured agent and its capabilities
+type AgentInfo struct {
+	Name           config.AgentName            `json:"name"`
+	Model          models.ModelID              `json:"model"`
+	Capabilities   []string                    `json:"capabilities"`
+	Status         string                      `json:"status"`
+	Specialization *config.AgentSpecialization `json:"specialization,omitempty"`
+	// NoTools is set when the model of the agent does not support function
+	// calling, so steps that need tools cannot be delegated to it
+	NoTools bool `json:"no_tools,omitempty"`
 }
 
+// Agent statuses
+const (
+	AgentStatusAvailable = "available"
+	AgentStatusBusy      = "busy"
+	AgentStatusOffline   = "offline"
+)
+
 // ConfigSummary provides a summary of system configuration
 type ConfigSummary struct {
 	AgentCount        int    `json:"agent_count"`
@@ -66,6 +142,7 @@ type TaskPlan struct {
 	Dependencies []string  `json:"dependencies"`
 	EstimatedDuration string `json:"estimated_duration"`
 	RequiredAgents []string `json:"required_agents"`
+	Template       string   `json:"template,omitempty"`
 }
 
 // TaskStep represents a single step in a task plan
@@ -76,6 +153,12 @@ type TaskStep struct {
 	Dependencies  []string `json:"dependencies"`
 	Status        string   `json:"status"`
 	EstimatedTime string   `json:"estimated_time"`
+	Verification  []string `json:"verification,omitempty"`
+	// RequiresTools is set for the steps an agent cannot carry out without
+	// tools, such as editing files or running commands
+	RequiresTools bool `json:"requires_tools,omitempty"`
+	// Failure is what the last delegation of the step failed with
+	Failure *FailureDetail `json:"failure,omitempty"`
 }
 
 // DelegationResult represents the result of task delegation
@@ -86,6 +169,11 @@ type DelegationResult struct {
 	Message      string    `json:"message"`
 	CreatedAt    time.Time `json:"created_at"`
 	ExpectedCompletion time.Time `json:"expected_completion,omitempty"`
+	// OperationID is the operation of the delegation while an agent works on
+	// it, which the 'cancel' action takes
+	OperationID string `json:"operation_id,omitempty"`
+	// Failure is why the task could not be delegated
+	Failure *FailureDetail `json:"failure,omitempty"`
 }
 
 // NewManager creates a new coordination manager with all tools initialized
@@ -101,32 +189,40 @@ func NewManager(cfg *config.Config) (*Manager, error) {
 		delegationTools:   delegationTools,
 	}
 
+	// Load built-in and user plan templates; invalid user templates are reported but not fatal
+	templatesDir := ""
+	var roles []string
+	if cfg != nil {
+		if cfg.Data.Directory != "" {
+			templatesDir = filepath.Join(cfg.Data.Directory, PlanTemplatesDirName)
+		}
+		for agentName := range cfg.Agents {
+			roles = append(roles, string(agentName))
+		}
+	}
+	manager.planTemplates, manager.templateErrors = LoadPlanTemplates(templatesDir, roles)
+	for _, err := range manager.templateErrors {
+		logging.Error("Failed to load plan template", "error", err)
+	}
+
 	logging.Info("Coordination manager initialized successfully")
 	return manager, nil
 }
 
 // GetSystemIntrospection provides comprehensive system state information
-func (m *Manager) GetSystemIntrospection() (*SystemIntrospectionResult, error) {
-	logging.Debug("Performing system introspection")
+func (m *Manager) GetSystemIntrospection(ctx context.Context) (*SystemIntrospectionResult, error) {
+	logger := logging.FromContext(ctx)
+	logger.Debug("Performing system introspection")
 
 	// Get available agents with their capabilities
-	availableAgents := make([]AgentCapability, 0)
-	for agentName, agentConfig := range m.config.Agents {
-		capabilities := m.getAgentCapabilities(agentName)
-		specialization := ""
-		if agentConfig.Specialization != nil {
-			specialization = agentConfig.Specialization.CoordinationMode
-		}
-
-		agentCapability := AgentCapability{
-			Name:           string(agentName),
-			Model:          string(agentConfig.Model),
-			Capabilities:   capabilities,
-			Status:         "available", // In real system, this would be dynamic
-			Specialization: specialization,
-		}
-		availableAgents = append(availableAgents, agentCapability)
+	registry := m.GetAgentRegistry()
+	availableAgents := make([]AgentInfo, 0, len(registry))
+	for _, agentInfo := range registry {
+		availableAgents = append(availableAgents, *agentInfo)
 	}
+	sort.Slice(availableAgents, func(i, j int) bool {
+		return availableAgents[i].Name < availableAgents[j].Name
+	})
 
 	// Create configuration summary
 	configSummary := ConfigSummary{
@@ -146,24 +242,100 @@ func (m *Manager) GetSystemIntrospection() (*SystemIntrospectionResult, error) {
 		SystemCapabilities: systemCapabilities,
 		SystemStatus:       "operational",
 		LastUpdated:        time.Now(),
+		Version:            version.Get(),
+		UpdateAvailable:    updates.Load().UpdateAvailable(),
+		WorkspaceRoots:     m.getWorkspaceRoots(),
+		ActiveRoot:         config.ActiveRoot(),
+		ProjectTypes:       config.ProjectTypes(),
+		ProjectSummary:     config.ProjectSummary(),
+		Tools:              tools.Resolutions(),
+		TurnLatency:        getTurnLatency(),
+		ContextFiles:       prompt.ContextStatus(),
+		Locks:              lock.CurrentStatus(ctx),
+		RequestID:          logging.RequestIDFromContext(ctx),
+		ConfigFingerprint:  m.configFingerprint(),
+		MessageQueue:       m.QueueStatus(),
 	}
 
-	logging.Info("System introspection completed", 
+	logger.Info("System introspection completed", 
 		"agents", len(availableAgents),
 		"capabilities", len(systemCapabilities))
 
 	return result, nil
 }
 
-// CreateTaskPlan breaks down a complex task into manageable steps
-func (m *Manager) CreateTaskPlan(taskDescription string, requirements []string) (*TaskPlan, error) {
-	logging.Debug("Creating task plan", "description", taskDescription)
+// SetAgentRegistry makes the manager list the agents of registry rather than
+// those of the configuration
+func (m *Manager) SetAgentRegistry(registry AgentRegistry) {
+	m.registryMu.Lock()
+	defer m.registryMu.Unlock()
+	m.registry = registry
+}
+
+// GetAgentRegistry returns the configured agents, from the agent registry
+// when one is set
+func (m *Manager) GetAgentRegistry() map[config.AgentName]*AgentInfo {
+	m.registryMu.RLock()
+	registry := m.registry
+	m.registryMu.RUnlock()
+	if registry != nil {
+		return registry.GetAgentRegistry()
+	}
+
+	agents := make(map[config.AgentName]*AgentInfo, len(m.config.Agents))
+	for agentName, agentConfig := range m.config.Agents {
+		agents[agentName] = &AgentInfo{
+			Name:           agentName,
+			Model:          agentConfig.Model,
+			Capabilities:   m.getAgentCapabilities(agentName),
+			Status:         AgentStatusAvailable,
+			Specialization: agentConfig.Specialization,
+			NoTools:        models.LacksTools(agentConfig.Model),
+		}
+	}
+	return agents
+}
+
+// getTurnLatency returns the latency percentiles of the recent turns, nil
+// before any turn was traced
+func getTurnLatency() *TurnLatencyMetrics {
+	latency := tracing.RecentTurnLatency()
+	if latency.Turns == 0 {
+		return nil
+	}
+	return &TurnLatencyMetrics{
+		Turns: latency.Turns,
+		P50Ms: latency.P50.Milliseconds(),
+		P95Ms: latency.P95.Milliseconds(),
+	}
+}
+
+// CreateTaskPlan breaks down a complex task into manageable steps. When
+// templateName is set, the steps come from that plan template with its
+// parameters filled from the task description; otherwise they are generated
+// from the requirements.
+func (m *Manager) CreateTaskPlan(ctx context.Context, taskDescription string, requirements []string, templateName string) (*TaskPlan, error) {
+	logger := logging.FromContext(ctx)
+	logger.Debug("Creating task plan", "description", taskDescription, "template", templateName)
 
 	// Generate unique task ID
-	taskID := fmt.Sprintf("task_%d", time.Now().Unix())
+	taskID := NewOperationID("task")
 
 	// Analyze requirements and create steps
-	steps := m.planningTools.analyzeAndCreateSteps(taskDescription, requirements)
+	var steps []TaskStep
+	if templateName != "" {
+		template, ok := m.planTemplates[templateName]
+		if !ok {
+			return nil, fmt.Errorf("unknown plan template: %s", templateName)
+		}
+		steps = template.Instantiate(taskDescription)
+		if len(requirements) > 0 {
+			last := &steps[len(steps)-1]
+			last.Verification = append(last.Verification, requirements...)
+		}
+	} else {
+		steps = m.planningTools.analyzeAndCreateSteps(taskDescription, requirements)
+	}
 
 	// Determine required agents based on steps
 	requiredAgents := m.planningTools.determineRequiredAgents(steps)
@@ -181,9 +353,17 @@ func (m *Manager) CreateTaskPlan(taskDescription string, requirements []string)
 		Dependencies:      dependencies,
 		EstimatedDuration: estimatedDuration,
 		RequiredAgents:    requiredAgents,
+		Template:          templateName,
 	}
 
-	logging.Info("Task plan created", 
+	recordPlan(taskPlan)
+	// The plan is tracked so that cancelling it cancels its delegations
+	sessionID, _ := tools.GetContextValues(ctx)
+	if err := startPlan(ctx, taskPlan, sessionID); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Task plan created", 
 		"task_id", taskID,
 		"steps", len(steps),
 		"required_agents", len(requiredAgents))
@@ -191,12 +371,41 @@ func (m *Manager) CreateTaskPlan(taskDescription string, requirements []string)
 	return taskPlan, nil
 }
 
-// DelegateTask assigns a task to an appropriate agent
-func (m *Manager) DelegateTask(taskID string, taskDescription string, preferredAgent string) (*DelegationResult, error) {
-	logging.Debug("Delegating task", "task_id", taskID, "preferred_agent", preferredAgent)
+// PlanTemplates returns the available plan templates sorted by name
+func (m *Manager) PlanTemplates() []*PlanTemplate {
+	templates := make([]*PlanTemplate, 0, len(m.planTemplates))
+	for _, template := range m.planTemplates {
+		templates = append(templates, template)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+	return templates
+}
+
+// PlanTemplateErrors returns the errors from loading user plan templates
+func (m *Manager) PlanTemplateErrors() []error {
+	return m.templateErrors
+}
+
+// DelegateTask assigns a task to an appropriate agent. A task that requires
+// tools is refused when the agent runs a model without function calling.
+func (m *Manager) DelegateTask(ctx context.Context, taskID string, taskDescription string, preferredAgent string, requiresTools bool) (*DelegationResult, error) {
+	logger := logging.FromContext(ctx)
+	logger.Debug("Delegating task", "task_id", taskID, "preferred_agent", preferredAgent)
+
+	// The preferred agent may be named by an alias or in any case
+	if agentName, ok := m.config.ResolveAgent(preferredAgent); ok {
+		preferredAgent = string(agentName)
+	}
 
 	// Determine best agent for the task
 	assignedAgent := m.delegationTools.selectBestAgent(taskDescription, preferredAgent, m.config.Agents)
+	if requiresTools {
+		if err := m.checkToolsAvailable(config.AgentName(assignedAgent)); err != nil {
+			return nil, err
+		}
+	}
 
 	// Create delegation result
 	result := &DelegationResult{
@@ -208,13 +417,28 @@ func (m *Manager) DelegateTask(taskID string, taskDescription string, preferredA
 		ExpectedCompletion: time.Now().Add(2 * time.Hour), // Default 2-hour estimation
 	}
 
-	logging.Info("Task delegated successfully", 
+	logger.Info("Task delegated successfully", 
 		"task_id", taskID,
 		"assigned_to", assignedAgent)
 
 	return result, nil
 }
 
+// checkToolsAvailable fails when the model of an agent does not support
+// function calling, suggesting a model to switch the agent to
+func (m *Manager) checkToolsAvailable(agentName config.AgentName) error {
+	modelID := m.config.Agents[agentName].Model
+	if !models.LacksTools(modelID) {
+		return nil
+	}
+	suggestion := "a model that supports tools"
+	if alternative, ok := models.ToolsAlternative(modelID); ok {
+		suggestion = fmt.Sprintf("a model that supports tools, such as %s", alternative.ID)
+	}
+	return NewFailure(FailureTool, fmt.Errorf("the task requires tools, but agent %s runs %s, which does not support them: switch the agent to %s, or delegate the task to another agent",
+		agentName, models.SupportedModels[modelID].Name, suggestion))
+}
+
 // getAgentCapabilities returns capabilities for a specific agent
 func (m *Manager) getAgentCapabilities(agentName config.AgentName) []string {
 	switch agentName {
@@ -234,6 +458,33 @@ func (m *Manager) getEnabledProviders() []string {
 	return []string{"openai", "anthropic", "google", "groq"}
 }
 
+// getWorkspaceRoots returns the configured or auto-detected workspace roots
+func (m *Manager) getWorkspaceRoots() []WorkspaceRoot {
+	roots := config.WorkspaceRoots()
+	result := make([]WorkspaceRoot, 0, len(roots))
+	for _, name := range config.WorkspaceRootNames() {
+		root := roots[name]
+		result = append(result, WorkspaceRoot{
+			Name:         name,
+			Path:         root.Path,
+			Detected:     root.Detected,
+			ProjectTypes: root.ProjectTypes,
+		})
+	}
+	return result
+}
+
+// configFingerprint returns the fingerprint of the configuration of the
+// manager, "" when it cannot be computed
+func (m *Manager) configFingerprint() string {
+	snapshot, err := config.TakeSnapshot(m.config)
+	if err != nil {
+		logging.Warn("Failed to fingerprint the configuration", "error", err)
+		return ""
+	}
+	return snapshot.Fingerprint()
+}
+
 // getSystemCapabilities returns overall system capabilities
 func (m *Manager) getSystemCapabilities() []string {
 	capabilities := []string{
@@ -281,6 +532,7 @@ func (p *PlanningTools) analyzeAndCreateSteps(taskDescription string, requiremen
 			Dependencies:  []string{"step_1"},
 			Status:        "pending",
 			EstimatedTime: "1-2 hours",
+			RequiresTools: true,
 		})
 	}
 
@@ -0,0 +1,39 @@
+package coordination
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+)
+
+type staticRegistry map[config.AgentName]*AgentInfo
+
+func (r staticRegistry) GetAgentRegistry() map[config.AgentName]*AgentInfo {
+	return r
+}
+
+func TestManagerAgentRegistry(t *testing.T) {
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()), config.WithTestAgents("coder"))
+	manager, err := NewManager(cfg)
+	require.NoError(t, err)
+
+	result, err := manager.GetSystemIntrospection(context.Background())
+	require.NoError(t, err)
+	require.Len(t, result.AvailableAgents, 2, "without a registry the configured agents are listed")
+	assert.Equal(t, config.AgentCaronex, result.AvailableAgents[0].Name)
+	assert.Equal(t, config.AgentName("coder"), result.AvailableAgents[1].Name)
+	assert.Equal(t, models.TestFake, result.AvailableAgents[1].Model)
+	assert.Equal(t, AgentStatusAvailable, result.AvailableAgents[1].Status)
+
+	manager.SetAgentRegistry(staticRegistry{
+		"coder": {Name: "coder", Model: models.TestFake, Status: AgentStatusBusy},
+	})
+	result, err = manager.GetSystemIntrospection(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []AgentInfo{{Name: "coder", Model: models.TestFake, Status: AgentStatusBusy}}, result.AvailableAgents)
+}
@@ -0,0 +1,516 @@
+package coordination
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/llm/tools"
+	"github.com/caronex/intelligence-interface/internal/message"
+)
+
+// Kinds of coordination operations
+const (
+	OperationPlan       = "plan"
+	OperationDelegation = "delegation"
+)
+
+// States of coordination operations
+const (
+	OperationRunning   = "running"
+	OperationCompleted = "completed"
+	OperationFailed    = "failed"
+	OperationCancelled = "cancelled"
+)
+
+// cancelWait bounds how long a cancellation waits for the cancelled
+// operations to stop and hand over their partial results
+const cancelWait = 10 * time.Second
+
+// operationRetention is how long the operations are kept once over
+const operationRetention = time.Hour
+
+// ErrOperationCancelled is the cause of the contexts of cancelled operations
+var ErrOperationCancelled = errors.New("coordination operation cancelled")
+
+// Operation is a plan or a delegation tracked so it can be cancelled along
+// with the operations started under it, down to their provider calls
+type Operation struct {
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
+	// ParentID is the operation this one was started under, such as the plan
+	// of a delegation
+	ParentID string `json:"parent_id,omitempty"`
+	// SessionID is the session the operation originates from, which is told
+	// when it is cancelled
+	SessionID   string `json:"session_id,omitempty"`
+	Agent       string `json:"agent,omitempty"`
+	StepID      string `json:"step_id,omitempty"`
+	Description string `json:"description,omitempty"`
+	State       string `json:"state"`
+	// CancelledBy tells who or what cancelled the operation, such as "user"
+	CancelledBy string `json:"cancelled_by,omitempty"`
+	// CancelReason tells why, the operation being cancelled itself or one of
+	// the operations it was started under
+	CancelReason string `json:"cancel_reason,omitempty"`
+	// Result is the output of the operation. Partial is set when it is what
+	// the operation produced before it was cancelled.
+	Result  string `json:"result,omitempty"`
+	Partial bool   `json:"partial,omitempty"`
+	Error   string `json:"error,omitempty"`
+	// Failure is the error or cancellation the operation ended with, mapped
+	// to the failure taxonomy
+	Failure *FailureDetail `json:"failure,omitempty"`
+	// Retries are the failures of the earlier attempts of a delegation
+	// retried with another agent
+	Retries []FailureDetail `json:"retries,omitempty"`
+	// Changes are the files the operation changed in the workspace, nil when
+	// it changed none
+	Changes   *message.WorkspaceChanges `json:"changes,omitempty"`
+	StartedAt time.Time                 `json:"started_at"`
+	EndedAt   time.Time                 `json:"ended_at,omitempty"`
+}
+
+type operationNode struct {
+	op       Operation
+	ctx      context.Context
+	cancel   context.CancelCauseFunc
+	stop     func() bool
+	children []string
+	// steps are the steps of a plan, which completes once a delegation of
+	// each of them has
+	steps     []string
+	completed map[string]bool
+	done      chan struct{}
+}
+
+var (
+	operationsMu sync.Mutex
+	operations   = make(map[string]*operationNode)
+	operationSeq int
+
+	cancellationReporter func(sessionID, summary string)
+	delegationRunner     DelegationRunner
+)
+
+// DelegationRunner has the agent of a delegation work on its task, returning
+// what it produced, up to where it stopped when ctx is cancelled
+type DelegationRunner func(ctx context.Context, op Operation) (string, error)
+
+// SetDelegationRunner sets how the agents work on delegated tasks. Without a
+// runner, delegations are only planned.
+func SetDelegationRunner(run DelegationRunner) {
+	operationsMu.Lock()
+	defer operationsMu.Unlock()
+	delegationRunner = run
+}
+
+// SetCancellationReporter sets how the summary of a cancellation is posted
+// to the session the cancelled operation originates from
+func SetCancellationReporter(report func(sessionID, summary string)) {
+	operationsMu.Lock()
+	defer operationsMu.Unlock()
+	cancellationReporter = report
+}
+
+// NewOperationID returns a new ID for an operation of kind
+func NewOperationID(kind string) string {
+	operationsMu.Lock()
+	defer operationsMu.Unlock()
+	operationSeq++
+	return fmt.Sprintf("%s_%d_%d", kind, time.Now().Unix(), operationSeq)
+}
+
+// StartOperation tracks op as running and returns the context to run it in,
+// which is cancelled along with ctx, the parent operation of op, or op
+// itself. FinishOperation must be called once op is over.
+func StartOperation(ctx context.Context, op Operation) (context.Context, error) {
+	operationsMu.Lock()
+	defer operationsMu.Unlock()
+	if _, ok := operations[op.ID]; ok {
+		return nil, fmt.Errorf("operation %s already exists", op.ID)
+	}
+	for id, node := range operations {
+		if !node.op.EndedAt.IsZero() && time.Since(node.op.EndedAt) > operationRetention {
+			delete(operations, id)
+		}
+	}
+
+	var parent *operationNode
+	if op.ParentID != "" {
+		parent = operations[op.ParentID]
+		if parent == nil {
+			return nil, fmt.Errorf("unknown operation: %s", op.ParentID)
+		}
+		if parent.op.State != OperationRunning {
+			return nil, fmt.Errorf("operation %s is %s", op.ParentID, parent.op.State)
+		}
+		if op.SessionID == "" {
+			op.SessionID = parent.op.SessionID
+		}
+	}
+
+	node := &operationNode{done: make(chan struct{})}
+	node.ctx, node.cancel = context.WithCancelCause(ctx)
+	node.stop = func() bool { return false }
+	if parent != nil {
+		node.stop = context.AfterFunc(parent.ctx, func() {
+			node.cancel(context.Cause(parent.ctx))
+		})
+		parent.children = append(parent.children, op.ID)
+	}
+	op.State = OperationRunning
+	op.StartedAt = time.Now()
+	node.op = op
+	operations[op.ID] = node
+	return node.ctx, nil
+}
+
+// startPlan tracks a plan as running, until a delegation of each of its
+// steps completes or it is cancelled
+func startPlan(ctx context.Context, plan *TaskPlan, sessionID string) error {
+	_, err := StartOperation(context.WithoutCancel(ctx), Operation{
+		ID:          plan.TaskID,
+		Kind:        OperationPlan,
+		SessionID:   sessionID,
+		Description: plan.Description,
+	})
+	if err != nil {
+		return err
+	}
+	operationsMu.Lock()
+	defer operationsMu.Unlock()
+	node := operations[plan.TaskID]
+	node.completed = make(map[string]bool)
+	for _, step := range plan.Steps {
+		node.steps = append(node.steps, step.StepID)
+	}
+	return nil
+}
+
+// RunDelegation has the assigned agent work on a delegated task in the
+// background, as an operation under the plan of the task when it is tracked,
+// so that cancelling the plan cancels the delegation
+func (m *Manager) RunDelegation(ctx context.Context, delegation *DelegationResult, stepID, description string) error {
+	operationsMu.Lock()
+	run := delegationRunner
+	operationsMu.Unlock()
+	if run == nil {
+		return nil
+	}
+
+	sessionID, _ := tools.GetContextValues(ctx)
+	op := Operation{
+		ID:          NewOperationID(OperationDelegation),
+		Kind:        OperationDelegation,
+		SessionID:   sessionID,
+		Agent:       delegation.AssignedTo,
+		StepID:      stepID,
+		Description: description,
+	}
+	if _, ok := GetOperation(delegation.TaskID); ok {
+		op.ParentID = delegation.TaskID
+	}
+	// The delegation outlives the tool call starting it
+	runCtx, err := StartOperation(context.WithoutCancel(ctx), op)
+	if err != nil {
+		return err
+	}
+	delegation.OperationID = op.ID
+	delegation.Status = OperationRunning
+	setStepStatus(op.ParentID, stepID, OperationRunning, nil)
+
+	dataDir := m.config.Data.Directory
+	go func() {
+		defer logging.RecoverPanic("coordination.RunDelegation", func() {
+			FinishOperation(op.ID, "", errors.New("panic while running the delegation"))
+		})
+		result, err := run(runCtx, op)
+		// A provider failing is not the task failing, so another agent is
+		// given a chance before the delegation fails
+		if failure := ClassifyFailure(err); failure != nil && failure.Category == FailureProvider && runCtx.Err() == nil {
+			if alternate, ok := m.alternateAgent(config.AgentName(op.Agent)); ok {
+				logging.Warn("Retrying the delegation with another agent", "operation", op.ID, "agent", op.Agent, "alternate", alternate, "error", err)
+				failure.Agent = op.Agent
+				op.Agent = string(alternate)
+				retryOperation(op.ID, op.Agent, *failure)
+				result, err = run(runCtx, op)
+			}
+		}
+		FinishOperation(op.ID, result, err)
+
+		finished, _ := GetOperation(op.ID)
+		setStepStatus(finished.ParentID, finished.StepID, finished.State, finished.Failure)
+		recordErr := RecordDelegation(dataDir, DelegationEvent{
+			SessionID:   finished.SessionID,
+			Agent:       finished.Agent,
+			Mode:        "delegation",
+			Task:        finished.Description,
+			Status:      finished.State,
+			Duration:    finished.EndedAt.Sub(finished.StartedAt).Round(time.Millisecond).String(),
+			Summary:     finished.Error,
+			CancelledBy: finished.CancelledBy,
+			Changes:     finished.Changes,
+			Failure:     finished.Failure,
+			Retries:     finished.Retries,
+		})
+		if recordErr != nil {
+			logging.Warn("Failed to record the delegation", "operation", op.ID, "error", recordErr, logging.ComponentKey, logging.ComponentDataLoss)
+		}
+	}()
+	return nil
+}
+
+// FinishOperation records how an operation ended. The result of an
+// operation that was cancelled is kept as its partial result.
+func FinishOperation(id, result string, err error) {
+	operationsMu.Lock()
+	defer operationsMu.Unlock()
+	node := operations[id]
+	if node == nil || !node.op.EndedAt.IsZero() {
+		return
+	}
+	finish(node, result, err)
+
+	// A plan completes with the delegation of its last step
+	parent := operations[node.op.ParentID]
+	if parent == nil || parent.steps == nil || node.op.State != OperationCompleted || node.op.StepID == "" {
+		return
+	}
+	parent.completed[node.op.StepID] = true
+	for _, step := range parent.steps {
+		if !parent.completed[step] {
+			return
+		}
+	}
+	finish(parent, "", nil)
+}
+
+func finish(node *operationNode, result string, err error) {
+	switch {
+	case node.op.State == OperationCancelled:
+		node.op.Partial = result != ""
+		node.op.Failure = ClassifyFailure(NewFailure(FailureCancelled, errors.New(node.op.CancelReason)))
+		node.op.Failure.Agent = node.op.Agent
+	case err != nil:
+		node.op.State = OperationFailed
+		node.op.Error = err.Error()
+		node.op.Failure = ClassifyFailure(err)
+		node.op.Failure.Agent = node.op.Agent
+	default:
+		node.op.State = OperationCompleted
+	}
+	node.op.Result = result
+	node.op.EndedAt = time.Now()
+	node.stop()
+	node.cancel(nil)
+	close(node.done)
+}
+
+// retryOperation records the failure of the attempt of a delegation before
+// it is retried with agent
+func retryOperation(id, agent string, failure FailureDetail) {
+	operationsMu.Lock()
+	defer operationsMu.Unlock()
+	if node := operations[id]; node != nil {
+		node.op.Retries = append(node.op.Retries, failure)
+		node.op.Agent = agent
+	}
+}
+
+// alternateAgent returns the agent a delegation that failed with agent is
+// retried with, preferring one running another model
+func (m *Manager) alternateAgent(agent config.AgentName) (config.AgentName, bool) {
+	registry := m.GetAgentRegistry()
+	var model models.ModelID
+	if info := registry[agent]; info != nil {
+		model = info.Model
+	}
+	var names []config.AgentName
+	for name, info := range registry {
+		if name != agent && info.Status != AgentStatusOffline {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "", false
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	for _, name := range names {
+		if registry[name].Model != model {
+			return name, true
+		}
+	}
+	return names[0], true
+}
+
+// SetOperationChanges records the files an operation changed in the
+// workspace
+func SetOperationChanges(id string, changes *message.WorkspaceChanges) {
+	operationsMu.Lock()
+	defer operationsMu.Unlock()
+	if node := operations[id]; node != nil {
+		node.op.Changes = changes
+	}
+}
+
+// GetOperation returns the operation with the given ID
+func GetOperation(id string) (Operation, bool) {
+	operationsMu.Lock()
+	defer operationsMu.Unlock()
+	node, ok := operations[id]
+	if !ok {
+		return Operation{}, false
+	}
+	return node.op, true
+}
+
+// Operations returns the tracked operations, oldest first
+func Operations() []Operation {
+	operationsMu.Lock()
+	defer operationsMu.Unlock()
+	ops := make([]Operation, 0, len(operations))
+	for _, node := range operations {
+		ops = append(ops, node.op)
+	}
+	sort.SliceStable(ops, func(i, j int) bool {
+		return ops[i].StartedAt.Before(ops[j].StartedAt)
+	})
+	return ops
+}
+
+// ActiveOperation returns the outermost operation still running that
+// originates from a session, the one an abort in the session cancels
+func ActiveOperation(sessionID string) (string, bool) {
+	operationsMu.Lock()
+	defer operationsMu.Unlock()
+	var active *operationNode
+	for _, node := range operations {
+		if node.op.SessionID != sessionID || node.op.State != OperationRunning {
+			continue
+		}
+		if parent := operations[node.op.ParentID]; parent != nil && parent.op.State == OperationRunning {
+			continue
+		}
+		if active == nil || node.op.StartedAt.After(active.op.StartedAt) {
+			active = node
+		}
+	}
+	if active == nil {
+		return "", false
+	}
+	return active.op.ID, true
+}
+
+// CancellationSummary tells which operations a cancellation stopped
+type CancellationSummary struct {
+	OperationID string      `json:"operation_id"`
+	CancelledBy string      `json:"cancelled_by"`
+	Operations  []Operation `json:"operations"`
+}
+
+// String returns the summary as posted to the originating session
+func (s CancellationSummary) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Cancelled %s (by %s)", s.OperationID, s.CancelledBy)
+	for _, op := range s.Operations {
+		fmt.Fprintf(&b, "\n- %s %s", op.Kind, op.ID)
+		if op.Agent != "" {
+			fmt.Fprintf(&b, " [%s]", op.Agent)
+		}
+		if op.Description != "" {
+			fmt.Fprintf(&b, ": %s", op.Description)
+		}
+		switch {
+		case op.State != OperationCancelled:
+			fmt.Fprintf(&b, " — %s before it was cancelled", op.State)
+		case op.EndedAt.IsZero():
+			b.WriteString(" — still stopping")
+		case op.Partial:
+			fmt.Fprintf(&b, "\n  Partial result: %s", op.Result)
+		default:
+			b.WriteString(" — no result")
+		}
+	}
+	return b.String()
+}
+
+// CancelOperation cancels an operation and the operations started under it,
+// marking each with who cancelled it. It waits for them to stop so that the
+// summary, posted to the session the operation originates from, holds their
+// partial results.
+func CancelOperation(id, cancelledBy string) (*CancellationSummary, error) {
+	operationsMu.Lock()
+	root := operations[id]
+	if root == nil {
+		operationsMu.Unlock()
+		return nil, fmt.Errorf("unknown operation: %s", id)
+	}
+	if root.op.State != OperationRunning {
+		operationsMu.Unlock()
+		return nil, fmt.Errorf("operation %s is already %s", id, root.op.State)
+	}
+
+	var affected []*operationNode
+	var mark func(node *operationNode, reason string)
+	mark = func(node *operationNode, reason string) {
+		if node.op.State != OperationRunning {
+			return
+		}
+		node.op.State = OperationCancelled
+		node.op.CancelledBy = cancelledBy
+		node.op.CancelReason = reason
+		affected = append(affected, node)
+		for _, childID := range node.children {
+			if child := operations[childID]; child != nil {
+				mark(child, fmt.Sprintf("%s %s cancelled", root.op.Kind, id))
+			}
+		}
+	}
+	mark(root, "cancelled directly")
+	report := cancellationReporter
+	sessionID := root.op.SessionID
+	operationsMu.Unlock()
+
+	// Cancelling the root cancels the contexts derived from it
+	root.cancel(ErrOperationCancelled)
+
+	timeout := time.NewTimer(cancelWait)
+	defer timeout.Stop()
+wait:
+	for _, node := range affected {
+		if node.op.Kind == OperationPlan {
+			continue
+		}
+		select {
+		case <-node.done:
+		case <-timeout.C:
+			break wait
+		}
+	}
+
+	// Plans have no work of their own, they end with their delegations
+	operationsMu.Lock()
+	for _, node := range affected {
+		if node.op.Kind == OperationPlan && node.op.EndedAt.IsZero() {
+			finish(node, "", nil)
+		}
+	}
+	summary := &CancellationSummary{OperationID: id, CancelledBy: cancelledBy}
+	for _, node := range affected {
+		summary.Operations = append(summary.Operations, node.op)
+	}
+	operationsMu.Unlock()
+
+	if report != nil && sessionID != "" {
+		report(sessionID, summary.String())
+	}
+	return summary, nil
+}
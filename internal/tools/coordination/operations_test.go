@@ -0,0 +1,148 @@
+package coordination
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/llm/tools"
+	"github.com/caronex/intelligence-interface/internal/message"
+)
+
+func TestCancelPlan(t *testing.T) {
+	// The dispatcher of the system event queue outlives the tests
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()), config.WithTestAgents("coder"))
+	cfg.Data.Directory = t.TempDir()
+	manager, err := NewManager(cfg)
+	require.NoError(t, err)
+
+	var reports []string
+	SetCancellationReporter(func(sessionID, summary string) {
+		reports = append(reports, sessionID+": "+summary)
+	})
+	t.Cleanup(func() { SetCancellationReporter(nil) })
+
+	// The steps stream their output until they are cancelled
+	var started sync.WaitGroup
+	SetDelegationRunner(func(ctx context.Context, op Operation) (string, error) {
+		started.Done()
+		<-ctx.Done()
+		return "draft of " + op.StepID, ctx.Err()
+	})
+	t.Cleanup(func() { SetDelegationRunner(nil) })
+
+	ctx := context.WithValue(context.Background(), tools.SessionIDContextKey, "session-1")
+	plan, err := manager.CreateTaskPlan(ctx, "add login", nil, "feature-implementation")
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(plan.Steps), 3)
+
+	var delegations []string
+	started.Add(3)
+	for _, step := range plan.Steps[:3] {
+		delegation, err := manager.DelegateTask(ctx, plan.TaskID, step.Description, "", false)
+		require.NoError(t, err)
+		require.NoError(t, manager.RunDelegation(ctx, delegation, step.StepID, step.Description))
+		assert.Equal(t, OperationRunning, delegation.Status)
+		delegations = append(delegations, delegation.OperationID)
+	}
+	started.Wait()
+
+	id, ok := ActiveOperation("session-1")
+	require.True(t, ok)
+	assert.Equal(t, plan.TaskID, id, "the plan is the outermost operation of the session")
+
+	summary, err := CancelOperation(plan.TaskID, "user")
+	require.NoError(t, err)
+	require.Len(t, summary.Operations, 4)
+	assert.Equal(t, plan.TaskID, summary.Operations[0].ID)
+	assert.Equal(t, "cancelled directly", summary.Operations[0].CancelReason)
+	for i, op := range summary.Operations[1:] {
+		assert.Equal(t, delegations[i], op.ID)
+		assert.Equal(t, OperationCancelled, op.State)
+		assert.Equal(t, "user", op.CancelledBy)
+		assert.Equal(t, fmt.Sprintf("plan %s cancelled", plan.TaskID), op.CancelReason)
+		assert.True(t, op.Partial)
+		assert.Equal(t, "draft of "+plan.Steps[i].StepID, op.Result)
+		assert.Contains(t, summary.String(), "Partial result: "+op.Result)
+	}
+	require.Len(t, reports, 1)
+	assert.Contains(t, reports[0], "session-1: Cancelled "+plan.TaskID+" (by user)")
+
+	_, ok = ActiveOperation("session-1")
+	assert.False(t, ok)
+	_, err = CancelOperation(plan.TaskID, "user")
+	assert.ErrorContains(t, err, "already cancelled")
+	assert.ErrorContains(t, manager.RunDelegation(ctx, &DelegationResult{TaskID: plan.TaskID}, "", "late step"), "is cancelled")
+}
+
+func TestPlanCompletes(t *testing.T) {
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
+	cfg.Data.Directory = t.TempDir()
+	manager, err := NewManager(cfg)
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	SetDelegationRunner(func(ctx context.Context, op Operation) (string, error) {
+		defer func() { done <- struct{}{} }()
+		return "done " + op.StepID, nil
+	})
+	t.Cleanup(func() { SetDelegationRunner(nil) })
+
+	plan, err := manager.CreateTaskPlan(context.Background(), "add login", []string{"a"}, "")
+	require.NoError(t, err)
+	for _, step := range plan.Steps {
+		delegation, err := manager.DelegateTask(context.Background(), plan.TaskID, step.Description, "", false)
+		require.NoError(t, err)
+		require.NoError(t, manager.RunDelegation(context.Background(), delegation, step.StepID, step.Description))
+		<-done
+	}
+
+	require.Eventually(t, func() bool {
+		op, _ := GetOperation(plan.TaskID)
+		return op.State == OperationCompleted
+	}, time.Second, 10*time.Millisecond, "the plan completes with the delegation of its last step")
+}
+
+func TestDelegationRecordsChanges(t *testing.T) {
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
+	cfg.Data.Directory = t.TempDir()
+	manager, err := NewManager(cfg)
+	require.NoError(t, err)
+
+	SetDelegationRunner(func(ctx context.Context, op Operation) (string, error) {
+		SetOperationChanges(op.ID, &message.WorkspaceChanges{
+			Files: []message.FileChange{{Path: "main.go", Change: message.FileModified, Additions: 1, Diff: "+x\n"}},
+			Patch: "changes/run.patch",
+		})
+		return "done", nil
+	})
+	t.Cleanup(func() { SetDelegationRunner(nil) })
+
+	delegation, err := manager.DelegateTask(context.Background(), "task-1", "edit main", "", false)
+	require.NoError(t, err)
+	require.NoError(t, manager.RunDelegation(context.Background(), delegation, "", "edit main"))
+
+	var event DelegationEvent
+	require.Eventually(t, func() bool {
+		data, err := os.ReadFile(filepath.Join(cfg.Data.Directory, EventLogFilename))
+		return err == nil && json.Unmarshal(data, &event) == nil
+	}, time.Second, 10*time.Millisecond)
+	require.NotNil(t, event.Changes)
+	assert.Equal(t, []message.FileChange{{Path: "main.go", Change: message.FileModified, Additions: 1}}, event.Changes.Files,
+		"the diffs are left to the session")
+	assert.Equal(t, "changes/run.patch", event.Changes.Patch)
+	op, _ := GetOperation(delegation.OperationID)
+	assert.Equal(t, "+x\n", op.Changes.Files[0].Diff)
+}
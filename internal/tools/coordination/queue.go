@@ -0,0 +1,118 @@
+package coordination
+
+import (
+	"container/heap"
+	"sync"
+)
+
+// DefaultEventPriority is the priority of the system events broadcast without
+// one, other than the halt and evolve events of Caronex
+const DefaultEventPriority = 5
+
+// queuedEvent is a system event waiting in the queue, seq keeping the events
+// of the same priority in the order they were queued
+type queuedEvent struct {
+	event SystemEvent
+	seq   uint64
+}
+
+// eventHeap orders the queued events by priority, highest first, then by
+// the order they were queued
+type eventHeap []queuedEvent
+
+func (h eventHeap) Len() int { return len(h) }
+
+func (h eventHeap) Less(i, j int) bool {
+	if h[i].event.Priority != h[j].event.Priority {
+		return h[i].event.Priority > h[j].event.Priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h eventHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *eventHeap) Push(x any) { *h = append(*h, x.(queuedEvent)) }
+
+func (h *eventHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// eventQueue is the priority queue the system events go through under the
+// "queue" communication protocol
+type eventQueue struct {
+	mu      sync.Mutex
+	events  eventHeap
+	seq     uint64
+	dropped int64
+	// ready is signaled when an event is queued
+	ready chan struct{}
+}
+
+func newEventQueue() *eventQueue {
+	return &eventQueue{ready: make(chan struct{}, 1)}
+}
+
+// push queues event. When the queue already holds depth events, the
+// lowest-priority event, the latest among equals, is dropped and returned.
+func (q *eventQueue) push(event SystemEvent, depth int) (SystemEvent, bool) {
+	q.mu.Lock()
+	defer func() {
+		q.mu.Unlock()
+		select {
+		case q.ready <- struct{}{}:
+		default:
+		}
+	}()
+
+	q.seq++
+	item := queuedEvent{event: event, seq: q.seq}
+	if len(q.events) < depth {
+		heap.Push(&q.events, item)
+		return SystemEvent{}, false
+	}
+
+	q.dropped++
+	lowest := -1
+	for i := range q.events {
+		if lowest < 0 || q.events.Less(lowest, i) {
+			lowest = i
+		}
+	}
+	// Being the latest, the event is the lowest unless it has a higher
+	// priority than the lowest queued one
+	if lowest < 0 || event.Priority <= q.events[lowest].event.Priority {
+		return event, true
+	}
+	dropped := heap.Remove(&q.events, lowest).(queuedEvent)
+	heap.Push(&q.events, item)
+	return dropped.event, true
+}
+
+// pop returns the next event to deliver, the highest-priority one
+func (q *eventQueue) pop() (SystemEvent, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.events) == 0 {
+		return SystemEvent{}, false
+	}
+	return heap.Pop(&q.events).(queuedEvent).event, true
+}
+
+// QueueStatus is the state of the system event queue
+type QueueStatus struct {
+	// Depth is the number of events waiting to be delivered
+	Depth int `json:"depth"`
+	// Capacity is the bound of Depth past which events are dropped
+	Capacity int `json:"capacity"`
+	// Dropped is the number of events dropped as the queue was full
+	Dropped int64 `json:"dropped"`
+}
+
+func (q *eventQueue) status(capacity int) QueueStatus {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return QueueStatus{Depth: len(q.events), Capacity: capacity, Dropped: q.dropped}
+}
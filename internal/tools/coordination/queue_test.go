@@ -0,0 +1,143 @@
+package coordination
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+)
+
+func TestEventQueueOrder(t *testing.T) {
+	q := newEventQueue()
+	for _, event := range []SystemEvent{
+		{Type: "a", Priority: 2},
+		{Type: "b", Priority: 5},
+		{Type: "c", Priority: 2},
+		{Type: "halt", Priority: 10},
+	} {
+		if _, dropped := q.push(event, 10); dropped {
+			t.Fatalf("push(%s) dropped an event below the depth", event.Type)
+		}
+	}
+
+	var order []string
+	for event, ok := q.pop(); ok; event, ok = q.pop() {
+		order = append(order, event.Type)
+	}
+	if want := []string{"halt", "b", "a", "c"}; !slices.Equal(order, want) {
+		t.Errorf("popped %v, want %v", order, want)
+	}
+}
+
+func TestEventQueueOverflow(t *testing.T) {
+	q := newEventQueue()
+	q.push(SystemEvent{Type: "a", Priority: 3}, 2)
+	q.push(SystemEvent{Type: "b", Priority: 1}, 2)
+
+	// A higher-priority event takes the place of the lowest one
+	dropped, ok := q.push(SystemEvent{Type: "halt", Priority: 10}, 2)
+	if !ok || dropped.Type != "b" {
+		t.Errorf("push(halt) dropped %+v, %v, want b", dropped, ok)
+	}
+	// An event no higher than the lowest queued one is dropped itself
+	dropped, ok = q.push(SystemEvent{Type: "c", Priority: 3}, 2)
+	if !ok || dropped.Type != "c" {
+		t.Errorf("push(c) dropped %+v, %v, want c", dropped, ok)
+	}
+
+	if status := q.status(2); status != (QueueStatus{Depth: 2, Capacity: 2, Dropped: 2}) {
+		t.Errorf("status() = %+v, want 2 queued and 2 dropped", status)
+	}
+}
+
+func TestEventPriority(t *testing.T) {
+	tests := []struct {
+		eventType   string
+		priority    int
+		maxPriority int
+		want        int
+	}{
+		{SystemEventConfigChanged, 0, 10, DefaultEventPriority},
+		{SystemEventHalt, 0, 10, 10},
+		{SystemEventEvolve, 0, 8, 8},
+		{SystemEventHalt, 2, 10, 2},
+		{SystemEventConfigChanged, 42, 10, 10},
+		{SystemEventConfigChanged, -1, 10, 1},
+		{SystemEventConfigChanged, 0, 3, 3},
+	}
+	for _, tt := range tests {
+		if got := eventPriority(tt.eventType, tt.priority, tt.maxPriority); got != tt.want {
+			t.Errorf("eventPriority(%s, %d, %d) = %d, want %d", tt.eventType, tt.priority, tt.maxPriority, got, tt.want)
+		}
+	}
+}
+
+// gatedHandler reports each event it handles, then waits for release
+type gatedHandler struct {
+	handled chan SystemEvent
+	release chan struct{}
+}
+
+func (h gatedHandler) HandleSystemEvent(ctx context.Context, event SystemEvent) {
+	h.handled <- event
+	<-h.release
+}
+
+func TestBroadcastQueuePreemption(t *testing.T) {
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
+	cfg.Caronex.Coordination.CommunicationProtocol = "queue"
+	manager, err := NewManager(cfg)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handler := gatedHandler{handled: make(chan SystemEvent, 10), release: make(chan struct{})}
+	RegisterSystemEventHandler(ctx, "coder", handler)
+	next := func() SystemEvent {
+		t.Helper()
+		select {
+		case event := <-handler.handled:
+			return event
+		case <-time.After(time.Second):
+			t.Fatal("no queued event was delivered")
+			return SystemEvent{}
+		}
+	}
+
+	if _, err := manager.Broadcast("status", "first", 2); err != nil {
+		t.Fatalf("Broadcast() error = %v", err)
+	}
+	if event := next(); event.Payload != "first" {
+		t.Fatalf("delivered %+v, want the first event", event)
+	}
+
+	// The events broadcast while the handler is busy wait in the queue
+	manager.Broadcast("status", "second", 3)
+	manager.Broadcast("status", "third", 1)
+	manager.Broadcast(SystemEventHalt, "stop", 0)
+	if status := manager.QueueStatus(); status == nil || status.Depth != 3 {
+		t.Errorf("QueueStatus() = %+v, want 3 queued events", status)
+	}
+
+	var order []string
+	for range 3 {
+		handler.release <- struct{}{}
+		order = append(order, next().Payload)
+	}
+	handler.release <- struct{}{}
+	if want := []string{"stop", "second", "third"}; !slices.Equal(order, want) {
+		t.Errorf("delivered %v, want %v", order, want)
+	}
+
+	introspection, err := manager.GetSystemIntrospection(context.Background())
+	if err != nil {
+		t.Fatalf("GetSystemIntrospection() error = %v", err)
+	}
+	if introspection.MessageQueue == nil || introspection.MessageQueue.Capacity != config.DefaultQueueDepth {
+		t.Errorf("introspection queue = %+v, want the queue status", introspection.MessageQueue)
+	}
+}
@@ -0,0 +1,336 @@
+package coordination
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Plan render formats
+const (
+	PlanFormatMermaid = "mermaid"
+	PlanFormatDOT     = "dot"
+)
+
+// Step status classes nodes are colored by
+const (
+	statusPending    = "pending"
+	statusInProgress = "in_progress"
+	statusDone       = "done"
+	statusFailed     = "failed"
+	statusMissing    = "missing"
+)
+
+// statusColors are the fill and stroke colors of each status class
+var statusColors = map[string][2]string{
+	statusPending:    {"#f5f5f5", "#9e9e9e"},
+	statusInProgress: {"#fff4ce", "#d4a017"},
+	statusDone:       {"#d9f2dd", "#2e7d32"},
+	statusFailed:     {"#fde0e0", "#c62828"},
+	statusMissing:    {"#ffffff", "#9e9e9e"},
+}
+
+// cycleColor highlights the steps and dependencies forming a cycle
+const cycleColor = "#c62828"
+
+var (
+	latestPlanMu sync.Mutex
+	latestPlan   *TaskPlan
+)
+
+// LatestPlan returns the last plan created by any manager, nil before one was
+func LatestPlan() *TaskPlan {
+	latestPlanMu.Lock()
+	defer latestPlanMu.Unlock()
+	return latestPlan
+}
+
+func recordPlan(plan *TaskPlan) {
+	latestPlanMu.Lock()
+	defer latestPlanMu.Unlock()
+	latestPlan = plan
+}
+
+// setStepStatus records the status of a step of the latest plan, and the
+// failure it ended with, as a delegation of it progresses
+func setStepStatus(planID, stepID, status string, failure *FailureDetail) {
+	latestPlanMu.Lock()
+	defer latestPlanMu.Unlock()
+	if latestPlan == nil || latestPlan.TaskID != planID || stepID == "" {
+		return
+	}
+	// The plan is copied, as the callers of LatestPlan may still read it
+	plan := *latestPlan
+	plan.Steps = slices.Clone(plan.Steps)
+	for i := range plan.Steps {
+		if plan.Steps[i].StepID == stepID {
+			plan.Steps[i].Status = status
+			plan.Steps[i].Failure = failure
+		}
+	}
+	latestPlan = &plan
+}
+
+// planGraph is a plan laid out as nodes and dependency edges, in step order
+type planGraph struct {
+	nodes []planNode
+	edges []planEdge
+	// cycle is one dependency cycle of the plan, nil when it is acyclic
+	cycle []string
+}
+
+type planNode struct {
+	id      string // id of the node in the rendered graph
+	label   []string
+	status  string
+	inCycle bool
+}
+
+type planEdge struct {
+	from, to string
+	inCycle  bool
+}
+
+// newPlanGraph lays out a plan. Dependencies on unknown steps become missing
+// nodes, and the steps and edges of dependency cycles are marked rather than
+// rejected so the plan can still be looked at.
+func newPlanGraph(plan *TaskPlan) planGraph {
+	var g planGraph
+	nodeIDs := make(map[string]string, len(plan.Steps))
+	deps := make(map[string][]string, len(plan.Steps))
+	ids := make([]string, 0, len(plan.Steps))
+	for i, step := range plan.Steps {
+		if _, ok := nodeIDs[step.StepID]; !ok {
+			nodeIDs[step.StepID] = fmt.Sprintf("s%d", i)
+			deps[step.StepID] = step.Dependencies
+			ids = append(ids, step.StepID)
+		}
+	}
+	missing := 0
+	for _, step := range plan.Steps {
+		for _, dep := range step.Dependencies {
+			if _, ok := nodeIDs[dep]; !ok {
+				nodeIDs[dep] = fmt.Sprintf("m%d", missing)
+				missing++
+				g.nodes = append(g.nodes, planNode{id: nodeIDs[dep], label: []string{dep, "(unknown step)"}, status: statusMissing})
+			}
+		}
+	}
+
+	inCycle := make(map[string]bool)
+	for _, step := range plan.Steps {
+		for _, dep := range step.Dependencies {
+			// The edge runs from the dependency to the step, and closes a
+			// cycle when the dependency itself depends on the step
+			cyclic := dep == step.StepID || dependsOn(deps, dep, step.StepID)
+			if cyclic {
+				inCycle[dep], inCycle[step.StepID] = true, true
+			}
+			g.edges = append(g.edges, planEdge{from: nodeIDs[dep], to: nodeIDs[step.StepID], inCycle: cyclic})
+		}
+	}
+
+	steps := make([]planNode, 0, len(plan.Steps))
+	for i, step := range plan.Steps {
+		title := step.StepID
+		if step.AssignedAgent != "" {
+			title += " (" + step.AssignedAgent + ")"
+		}
+		label := []string{title}
+		if step.Description != "" {
+			label = append(label, step.Description)
+		}
+		steps = append(steps, planNode{
+			id:      fmt.Sprintf("s%d", i),
+			label:   label,
+			status:  statusClass(step.Status),
+			inCycle: inCycle[step.StepID],
+		})
+	}
+	g.nodes = append(steps, g.nodes...)
+	g.cycle = findCycle(ids, deps)
+	return g
+}
+
+// dependsOn reports whether step from transitively depends on step to
+func dependsOn(deps map[string][]string, from, to string) bool {
+	seen := make(map[string]bool)
+	stack := slices.Clone(deps[from])
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if id == to {
+			return true
+		}
+		if !seen[id] {
+			seen[id] = true
+			stack = append(stack, deps[id]...)
+		}
+	}
+	return false
+}
+
+// statusClass maps a step status to the class its node is colored by
+func statusClass(status string) string {
+	switch strings.ToLower(strings.ReplaceAll(status, "-", "_")) {
+	case "in_progress", "running", "active":
+		return statusInProgress
+	case "done", "completed", "complete", "succeeded":
+		return statusDone
+	case "failed", "error":
+		return statusFailed
+	default:
+		return statusPending
+	}
+}
+
+// Finished reports whether the step is done or failed
+func (s TaskStep) Finished() bool {
+	status := statusClass(s.Status)
+	return status == statusDone || status == statusFailed
+}
+
+// RenderPlan renders a plan as a dependency graph in format, Mermaid when
+// format is empty
+func RenderPlan(plan *TaskPlan, format string) (string, error) {
+	switch format {
+	case "", PlanFormatMermaid:
+		return RenderMermaid(plan), nil
+	case PlanFormatDOT:
+		return RenderDOT(plan), nil
+	default:
+		return "", fmt.Errorf("unknown plan format: %s (valid formats: %s, %s)", format, PlanFormatMermaid, PlanFormatDOT)
+	}
+}
+
+// PlanCycle returns the step IDs of a dependency cycle of the plan, with the
+// first step repeated at the end, or nil when the plan is acyclic
+func PlanCycle(plan *TaskPlan) []string {
+	return newPlanGraph(plan).cycle
+}
+
+// RenderMermaid renders a plan as a Mermaid flowchart, as rendered by GitHub
+// in mermaid code blocks
+func RenderMermaid(plan *TaskPlan) string {
+	g := newPlanGraph(plan)
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+	for _, node := range g.nodes {
+		label := make([]string, len(node.label))
+		for i, line := range node.label {
+			label[i] = mermaidEscape(line)
+		}
+		fmt.Fprintf(&b, "    %s[\"%s\"]\n", node.id, strings.Join(label, "<br/>"))
+	}
+	var cycleEdges []string
+	for i, edge := range g.edges {
+		fmt.Fprintf(&b, "    %s --> %s\n", edge.from, edge.to)
+		if edge.inCycle {
+			cycleEdges = append(cycleEdges, fmt.Sprint(i))
+		}
+	}
+
+	classes := make(map[string][]string)
+	for _, node := range g.nodes {
+		classes[node.status] = append(classes[node.status], node.id)
+	}
+	for _, status := range []string{statusPending, statusInProgress, statusDone, statusFailed, statusMissing} {
+		if len(classes[status]) == 0 {
+			continue
+		}
+		colors := statusColors[status]
+		style := fmt.Sprintf("fill:%s,stroke:%s,color:#212121", colors[0], colors[1])
+		if status == statusMissing {
+			style += ",stroke-dasharray:4 4"
+		}
+		fmt.Fprintf(&b, "    classDef %s %s\n", status, style)
+		fmt.Fprintf(&b, "    class %s %s\n", strings.Join(classes[status], ","), status)
+	}
+	for _, node := range g.nodes {
+		if node.inCycle {
+			fmt.Fprintf(&b, "    style %s stroke:%s,stroke-width:3px\n", node.id, cycleColor)
+		}
+	}
+	if len(cycleEdges) > 0 {
+		fmt.Fprintf(&b, "    linkStyle %s stroke:%s,stroke-width:3px\n", strings.Join(cycleEdges, ","), cycleColor)
+	}
+	return b.String()
+}
+
+// mermaidEscape makes text safe inside a quoted Mermaid label
+func mermaidEscape(text string) string {
+	return strings.NewReplacer(
+		"\n", " ",
+		"\"", "#quot;",
+		"<", "#lt;",
+		">", "#gt;",
+	).Replace(text)
+}
+
+// RenderDOT renders a plan as a Graphviz digraph
+func RenderDOT(plan *TaskPlan) string {
+	g := newPlanGraph(plan)
+	var b strings.Builder
+	b.WriteString("digraph plan {\n")
+	if plan.Description != "" {
+		fmt.Fprintf(&b, "    label=%s;\n    labelloc=t;\n", dotQuote(strings.Join(strings.Fields(plan.Description), " ")))
+	}
+	b.WriteString("    rankdir=TB;\n")
+	b.WriteString("    node [shape=box, style=\"rounded,filled\", fontname=\"Helvetica\"];\n")
+	for _, node := range g.nodes {
+		colors := statusColors[node.status]
+		stroke := colors[1]
+		if node.inCycle {
+			stroke = cycleColor
+		}
+		attrs := fmt.Sprintf("label=%s, fillcolor=%s, color=%s", dotQuote(strings.Join(node.label, "\n")), dotQuote(colors[0]), dotQuote(stroke))
+		if node.status == statusMissing {
+			attrs += ", style=\"rounded,dashed\""
+		}
+		if node.inCycle {
+			attrs += ", penwidth=3"
+		}
+		fmt.Fprintf(&b, "    %s [%s];\n", node.id, attrs)
+	}
+	for _, edge := range g.edges {
+		if edge.inCycle {
+			fmt.Fprintf(&b, "    %s -> %s [color=%s, penwidth=3];\n", edge.from, edge.to, dotQuote(cycleColor))
+		} else {
+			fmt.Fprintf(&b, "    %s -> %s;\n", edge.from, edge.to)
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// dotQuote quotes text as a DOT string, newlines becoming line breaks
+func dotQuote(text string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(text) + `"`
+}
+
+// ExportPlan writes a plan into dir as a Markdown file holding its Mermaid
+// flowchart, which GitHub renders, and as a Graphviz file, returning the names
+// of the files
+func ExportPlan(plan *TaskPlan, dir string) ([]string, error) {
+	name := "plan"
+	if plan.TaskID != "" {
+		name = plan.TaskID + "-plan"
+	}
+	markdown := fmt.Sprintf("# %s\n\n```mermaid\n%s```\n", plan.Description, RenderMermaid(plan))
+	files := []struct{ name, content string }{
+		{name + ".md", markdown},
+		{name + ".dot", RenderDOT(plan)},
+	}
+
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		if err := os.WriteFile(filepath.Join(dir, file.name), []byte(file.content), 0o644); err != nil {
+			return nil, fmt.Errorf("failed to export the plan: %w", err)
+		}
+		names = append(names, file.name)
+	}
+	return names, nil
+}
@@ -0,0 +1,104 @@
+package coordination
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// parallelPlan has two branches running in parallel after the analysis, one
+// of which failed, joining in the verification
+var parallelPlan = &TaskPlan{
+	TaskID:      "task_1",
+	Description: "Add the \"export\" command",
+	Steps: []TaskStep{
+		{StepID: "analyze", Description: "Analyze requirements", AssignedAgent: "task", Status: "completed"},
+		{StepID: "backend", Description: "Implement the <export> endpoint", AssignedAgent: "coder", Dependencies: []string{"analyze"}, Status: "in_progress"},
+		{StepID: "frontend", Description: "Add the export button", AssignedAgent: "coder", Dependencies: []string{"analyze"}, Status: "failed"},
+		{StepID: "verify", Description: "Run the tests", AssignedAgent: "task", Dependencies: []string{"backend", "frontend"}, Status: "pending"},
+	},
+}
+
+// cyclicPlan has a dependency cycle and a dependency on an unknown step
+var cyclicPlan = &TaskPlan{
+	TaskID: "task_2",
+	Steps: []TaskStep{
+		{StepID: "a", Description: "First", Dependencies: []string{"c"}, Status: "pending"},
+		{StepID: "b", Description: "Second", Dependencies: []string{"a", "setup"}, Status: "pending"},
+		{StepID: "c", Description: "Third", Dependencies: []string{"b"}, Status: "done"},
+		{StepID: "d", Description: "After", Dependencies: []string{"c"}, Status: "pending"},
+	},
+}
+
+func assertGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("%s differs from the golden file (go test -update to accept):\n%s", name, got)
+	}
+}
+
+func TestRenderPlanGolden(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		plan *TaskPlan
+	}{
+		{"parallel", parallelPlan},
+		{"cycle", cyclicPlan},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assertGolden(t, tc.name+".mmd", RenderMermaid(tc.plan))
+			assertGolden(t, tc.name+".dot", RenderDOT(tc.plan))
+		})
+	}
+}
+
+func TestPlanCycle(t *testing.T) {
+	if cycle := PlanCycle(parallelPlan); cycle != nil {
+		t.Errorf("PlanCycle() = %v for an acyclic plan", cycle)
+	}
+	if got := strings.Join(PlanCycle(cyclicPlan), " -> "); got != "a -> c -> b -> a" {
+		t.Errorf("PlanCycle() = %s, want a -> c -> b -> a", got)
+	}
+}
+
+func TestRenderPlanFormats(t *testing.T) {
+	if _, err := RenderPlan(parallelPlan, "svg"); err == nil {
+		t.Error("RenderPlan() accepted an unknown format")
+	}
+	got, err := RenderPlan(parallelPlan, "")
+	if err != nil || got != RenderMermaid(parallelPlan) {
+		t.Errorf("RenderPlan() = %q, %v, want Mermaid by default", got, err)
+	}
+}
+
+func TestExportPlan(t *testing.T) {
+	dir := t.TempDir()
+	names, err := ExportPlan(parallelPlan, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(names, ",") != "task_1-plan.md,task_1-plan.dot" {
+		t.Fatalf("ExportPlan() = %v", names)
+	}
+	markdown, err := os.ReadFile(filepath.Join(dir, names[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(markdown), "```mermaid\n"+RenderMermaid(parallelPlan)+"```\n") {
+		t.Errorf("exported Markdown = %s, want a mermaid code block", markdown)
+	}
+}
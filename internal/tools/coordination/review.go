@@ -0,0 +1,151 @@
+package coordination
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+)
+
+// ReviewerPrompt is the system prompt of the reviewer of a review flow
+const ReviewerPrompt = `You are a meticulous code reviewer. You are given a task, the response an engineer gave to it and a checklist. Check the response against every item of the checklist and report the issues that must be fixed before the response is presented, most important first. Do not report style preferences or praise.
+
+Answer in exactly this format:
+
+ISSUES:
+- <one issue per line, or "none">
+SUMMARY: <one sentence summarizing the review>`
+
+// ReviewPassResult is the outcome of one pass of a review flow
+type ReviewPassResult struct {
+	Text string
+	// Tokens is the number of tokens the pass generated
+	Tokens int64
+}
+
+// ReviewPass runs one pass of a review flow from its prompt
+type ReviewPass func(ctx context.Context, prompt string) (ReviewPassResult, error)
+
+// ReviewPasses are the passes a review flow coordinates: the reviewer
+// critiquing a response and the reviewed agent revising it
+type ReviewPasses struct {
+	Critique ReviewPass
+	Revise   ReviewPass
+}
+
+// Critique is a reviewer's answer parsed into its issues and summary
+type Critique struct {
+	Issues  []string
+	Summary string
+}
+
+// ReviewOutcome is the result of a review flow
+type ReviewOutcome struct {
+	// Response is the final response, revised or not
+	Response string
+	// Found is the number of issues of the first critique, Open the number
+	// left after the last revision
+	Found int
+	Open  int
+	// Revisions is the number of revisions made
+	Revisions int
+	// Critiques are the reviewer's answers, in order
+	Critiques []string
+	// Summary is the summary of the last critique
+	Summary string
+	// BudgetExceeded is set when a pass went over the token budget, which
+	// ended the flow
+	BudgetExceeded bool
+}
+
+// RunReview coordinates the review of a response to a task: the reviewer
+// critiques it against the checklist of flow, then the response is revised
+// to address the issues, until none is left or the revisions of flow are
+// spent. A pass going over the token budget of flow ends the review with the
+// response as it is.
+func RunReview(ctx context.Context, flow config.ReviewFlow, task, response string, passes ReviewPasses) (*ReviewOutcome, error) {
+	logger := logging.FromContext(ctx)
+	budget := flow.TokenBudget()
+	outcome := &ReviewOutcome{Response: response}
+	for round := 0; ; round++ {
+		logger.InfoPersist("Reviewing the response...")
+		result, err := passes.Critique(ctx, CritiquePrompt(flow.ChecklistPrompt(), task, outcome.Response))
+		if err != nil {
+			return nil, fmt.Errorf("critique failed: %w", err)
+		}
+		critique := ParseCritique(result.Text)
+		outcome.Critiques = append(outcome.Critiques, result.Text)
+		outcome.Summary = critique.Summary
+		outcome.Open = len(critique.Issues)
+		if round == 0 {
+			outcome.Found = outcome.Open
+		}
+		if result.Tokens > budget {
+			logger.Warn("review critique went over its token budget, ending the review", "tokens", result.Tokens, "budget", budget)
+			outcome.BudgetExceeded = true
+			return outcome, nil
+		}
+		if len(critique.Issues) == 0 || round == flow.Revisions() {
+			return outcome, nil
+		}
+
+		logger.InfoPersist(fmt.Sprintf("Revising the response, round %d of %d...", round+1, flow.Revisions()))
+		result, err = passes.Revise(ctx, RevisionPrompt(critique))
+		if err != nil {
+			return nil, fmt.Errorf("revision failed: %w", err)
+		}
+		outcome.Response = result.Text
+		outcome.Revisions++
+		if result.Tokens > budget {
+			logger.Warn("review revision went over its token budget, ending the review", "tokens", result.Tokens, "budget", budget)
+			outcome.BudgetExceeded = true
+			return outcome, nil
+		}
+	}
+}
+
+// CritiquePrompt asks the reviewer to critique a response to a task
+func CritiquePrompt(checklist, task, response string) string {
+	return fmt.Sprintf("<task>\n%s\n</task>\n\n<response>\n%s\n</response>\n\n<checklist>\n%s\n</checklist>\n\nReview the response against the checklist.",
+		task, response, checklist)
+}
+
+// RevisionPrompt asks the reviewed agent to address the issues of a critique
+func RevisionPrompt(critique Critique) string {
+	var b strings.Builder
+	b.WriteString("A reviewer found these issues in your response:\n\n")
+	for _, issue := range critique.Issues {
+		fmt.Fprintf(&b, "- %s\n", issue)
+	}
+	b.WriteString("\nFix them, then answer again with your complete response, as it should be presented to the user.")
+	return b.String()
+}
+
+// ParseCritique reads the issues and summary of a reviewer's answer. Answers
+// not following the format are a single issue, so they are not mistaken for
+// an approval.
+func ParseCritique(text string) Critique {
+	var critique Critique
+	formatted := false
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(strings.ToUpper(line), "ISSUES:"):
+			formatted = true
+		case strings.HasPrefix(strings.ToUpper(line), "SUMMARY:"):
+			formatted = true
+			critique.Summary = strings.TrimSpace(line[len("SUMMARY:"):])
+		case strings.HasPrefix(line, "- "), strings.HasPrefix(line, "* "):
+			issue := strings.TrimSpace(line[2:])
+			if issue != "" && !strings.EqualFold(strings.Trim(issue, "."), "none") {
+				critique.Issues = append(critique.Issues, issue)
+			}
+		}
+	}
+	if !formatted && strings.TrimSpace(text) != "" {
+		critique.Issues = []string{strings.TrimSpace(text)}
+	}
+	return critique
+}
@@ -0,0 +1,114 @@
+package coordination
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+)
+
+func TestParseCritique(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		issues  []string
+		summary string
+	}{
+		{
+			name:    "issues",
+			text:    "ISSUES:\n- missing test\n* error ignored\nSUMMARY: two problems",
+			issues:  []string{"missing test", "error ignored"},
+			summary: "two problems",
+		},
+		{
+			name:    "none",
+			text:    "Issues:\n- None.\nSummary: all good",
+			summary: "all good",
+		},
+		{
+			name:   "unformatted",
+			text:   "This does not compile.",
+			issues: []string{"This does not compile."},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseCritique(tt.text)
+			if !slices.Equal(got.Issues, tt.issues) || got.Summary != tt.summary {
+				t.Errorf("ParseCritique() = %+v, want issues %v and summary %q", got, tt.issues, tt.summary)
+			}
+		})
+	}
+}
+
+// scriptedPass answers each call of a review pass with the next result
+func scriptedPass(calls *int, results ...ReviewPassResult) ReviewPass {
+	return func(ctx context.Context, prompt string) (ReviewPassResult, error) {
+		result := results[*calls]
+		*calls++
+		return result, nil
+	}
+}
+
+func TestRunReview(t *testing.T) {
+	issue := ReviewPassResult{Text: "ISSUES:\n- bug\nSUMMARY: one bug"}
+	clean := ReviewPassResult{Text: "ISSUES:\n- none\nSUMMARY: fine"}
+	revised := ReviewPassResult{Text: "revised"}
+
+	tests := []struct {
+		name      string
+		flow      config.ReviewFlow
+		critiques []ReviewPassResult
+		revisions []ReviewPassResult
+		want      ReviewOutcome
+	}{
+		{
+			name:      "approved",
+			flow:      config.ReviewFlow{Enabled: true},
+			critiques: []ReviewPassResult{clean},
+			want:      ReviewOutcome{Response: "draft", Summary: "fine"},
+		},
+		{
+			name:      "fixed",
+			flow:      config.ReviewFlow{Enabled: true, MaxRevisions: 2},
+			critiques: []ReviewPassResult{issue, clean},
+			revisions: []ReviewPassResult{revised},
+			want:      ReviewOutcome{Response: "revised", Found: 1, Revisions: 1, Summary: "fine"},
+		},
+		{
+			name:      "revisions spent",
+			flow:      config.ReviewFlow{Enabled: true},
+			critiques: []ReviewPassResult{issue, issue},
+			revisions: []ReviewPassResult{revised},
+			want:      ReviewOutcome{Response: "revised", Found: 1, Open: 1, Revisions: 1, Summary: "one bug"},
+		},
+		{
+			name:      "revision over budget",
+			flow:      config.ReviewFlow{Enabled: true, PassTokenBudget: 10},
+			critiques: []ReviewPassResult{issue},
+			revisions: []ReviewPassResult{{Text: "revised", Tokens: 11}},
+			want:      ReviewOutcome{Response: "revised", Found: 1, Open: 1, Revisions: 1, Summary: "one bug", BudgetExceeded: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var critiques, revisions int
+			got, err := RunReview(context.Background(), tt.flow, "task", "draft", ReviewPasses{
+				Critique: scriptedPass(&critiques, tt.critiques...),
+				Revise:   scriptedPass(&revisions, tt.revisions...),
+			})
+			if err != nil {
+				t.Fatalf("RunReview() error = %v", err)
+			}
+			if critiques != len(tt.critiques) || revisions != len(tt.revisions) {
+				t.Errorf("ran %d critiques and %d revisions, want %d and %d", critiques, revisions, len(tt.critiques), len(tt.revisions))
+			}
+			got.Critiques = nil
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("RunReview() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,311 @@
+package coordination
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed plan-templates/*.yaml
+var builtinPlanTemplates embed.FS
+
+// PlanTemplatesDirName is the directory under Data.Directory holding user plan templates
+const PlanTemplatesDirName = "plan-templates"
+
+// taskParameter is always available in templates and holds the task description
+const taskParameter = "TASK"
+
+// placeholderPattern finds parameter references in the format $NAME
+var placeholderPattern = regexp.MustCompile(`\$([A-Z][A-Z0-9_]*)`)
+
+// parameterNamePattern is the format of declared parameter names
+var parameterNamePattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// knownRoles are the agent roles plan steps can be assigned to in addition
+// to the configured agents
+var knownRoles = []string{"caronex", "coder", "task", "summarizer", "title"}
+
+// PlanTemplate is a named, parameterized sequence of plan steps
+type PlanTemplate struct {
+	Name        string              `yaml:"name" json:"name"`
+	Description string              `yaml:"description" json:"description"`
+	Parameters  []TemplateParameter `yaml:"parameters,omitempty" json:"parameters,omitempty"`
+	Steps       []TemplateStep      `yaml:"steps" json:"steps"`
+	Source      string              `yaml:"-" json:"source"`
+}
+
+// TemplateParameter is a placeholder filled from the task description. The
+// first capture group of Pattern is used when it matches, then Default, and
+// finally the whole task description.
+type TemplateParameter struct {
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	Pattern     string `yaml:"pattern,omitempty" json:"pattern,omitempty"`
+	Default     string `yaml:"default,omitempty" json:"default,omitempty"`
+}
+
+// TemplateStep is a step of a plan template
+type TemplateStep struct {
+	ID            string   `yaml:"id" json:"id"`
+	Description   string   `yaml:"description" json:"description"`
+	Role          string   `yaml:"role" json:"role"`
+	DependsOn     []string `yaml:"depends_on,omitempty" json:"depends_on,omitempty"`
+	EstimatedTime string   `yaml:"estimated_time,omitempty" json:"estimated_time,omitempty"`
+	Verify        []string `yaml:"verify,omitempty" json:"verify,omitempty"`
+	// RequiresTools marks the steps that cannot be carried out without tools
+	RequiresTools bool `yaml:"requires_tools,omitempty" json:"requires_tools,omitempty"`
+}
+
+// LoadPlanTemplates loads the built-in plan templates and the user templates
+// in dir, which override built-ins of the same name. Invalid user templates
+// are skipped and reported in the returned errors; a missing dir is not an error.
+func LoadPlanTemplates(dir string, roles []string) (map[string]*PlanTemplate, []error) {
+	templates := make(map[string]*PlanTemplate)
+	var errs []error
+
+	builtins, err := fs.Glob(builtinPlanTemplates, "plan-templates/*.yaml")
+	if err != nil {
+		return templates, []error{fmt.Errorf("failed to list built-in plan templates: %w", err)}
+	}
+	for _, path := range builtins {
+		data, err := builtinPlanTemplates.ReadFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read built-in plan template %s: %w", path, err))
+			continue
+		}
+		tmpl, err := parsePlanTemplate(data, "builtin", roles)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("built-in plan template %s: %w", path, err))
+			continue
+		}
+		templates[tmpl.Name] = tmpl
+	}
+
+	if dir == "" {
+		return templates, errs
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return templates, errs
+	}
+	if err != nil {
+		return templates, append(errs, fmt.Errorf("failed to read plan templates directory %s: %w", dir, err))
+	}
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read plan template %s: %w", path, err))
+			continue
+		}
+		tmpl, err := parsePlanTemplate(data, path, roles)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("plan template %s: %w", path, err))
+			continue
+		}
+		templates[tmpl.Name] = tmpl
+	}
+	return templates, errs
+}
+
+// parsePlanTemplate decodes and validates a plan template
+func parsePlanTemplate(data []byte, source string, roles []string) (*PlanTemplate, error) {
+	var tmpl PlanTemplate
+	if err := yaml.Unmarshal(data, &tmpl); err != nil {
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	tmpl.Source = source
+	if err := tmpl.Validate(roles); err != nil {
+		return nil, err
+	}
+	return &tmpl, nil
+}
+
+// Validate checks that the template is well formed: steps have unique IDs and
+// known roles, dependencies refer to existing steps without cycles, and every
+// placeholder is a declared parameter
+func (t *PlanTemplate) Validate(roles []string) error {
+	if t.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(t.Steps) == 0 {
+		return fmt.Errorf("template %s has no steps", t.Name)
+	}
+
+	params := map[string]bool{taskParameter: true}
+	for _, param := range t.Parameters {
+		if !parameterNamePattern.MatchString(param.Name) {
+			return fmt.Errorf("parameter %q must be upper case, e.g. FEATURE", param.Name)
+		}
+		if param.Pattern != "" {
+			if _, err := regexp.Compile(param.Pattern); err != nil {
+				return fmt.Errorf("parameter %s has an invalid pattern: %w", param.Name, err)
+			}
+		}
+		params[param.Name] = true
+	}
+
+	knownRole := make(map[string]bool)
+	for _, role := range append(append([]string{}, knownRoles...), roles...) {
+		knownRole[role] = true
+	}
+
+	steps := make(map[string]TemplateStep, len(t.Steps))
+	for i, step := range t.Steps {
+		if step.ID == "" {
+			return fmt.Errorf("step %d has no id", i+1)
+		}
+		if _, exists := steps[step.ID]; exists {
+			return fmt.Errorf("duplicate step id %s", step.ID)
+		}
+		if !knownRole[step.Role] {
+			return fmt.Errorf("step %s has unknown role %q (known roles: %s)", step.ID, step.Role, strings.Join(sortedKeys(knownRole), ", "))
+		}
+		for _, match := range placeholderPattern.FindAllStringSubmatch(step.Description+"\n"+strings.Join(step.Verify, "\n"), -1) {
+			if !params[match[1]] {
+				return fmt.Errorf("step %s uses undeclared parameter $%s", step.ID, match[1])
+			}
+		}
+		steps[step.ID] = step
+	}
+	for _, step := range t.Steps {
+		for _, dep := range step.DependsOn {
+			if _, exists := steps[dep]; !exists {
+				return fmt.Errorf("step %s depends on unknown step %s", step.ID, dep)
+			}
+		}
+	}
+
+	if cycle := findDependencyCycle(t.Steps); cycle != nil {
+		return fmt.Errorf("dependency cycle: %s", strings.Join(cycle, " -> "))
+	}
+	return nil
+}
+
+// findDependencyCycle returns the step IDs forming a dependency cycle, with
+// the first step repeated at the end, or nil when the steps are acyclic
+func findDependencyCycle(steps []TemplateStep) []string {
+	ids := make([]string, 0, len(steps))
+	deps := make(map[string][]string, len(steps))
+	for _, step := range steps {
+		ids = append(ids, step.ID)
+		deps[step.ID] = step.DependsOn
+	}
+	return findCycle(ids, deps)
+}
+
+// findCycle returns the IDs forming a cycle of the dependency graph deps, with
+// the first ID repeated at the end, or nil when the graph is acyclic. IDs are
+// visited in the order of ids.
+func findCycle(ids []string, deps map[string][]string) []string {
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(ids))
+	var path []string
+
+	var visit func(id string) []string
+	visit = func(id string) []string {
+		state[id] = visiting
+		path = append(path, id)
+		for _, dep := range deps[id] {
+			switch state[dep] {
+			case visiting:
+				for i, step := range path {
+					if step == dep {
+						return append(append([]string{}, path[i:]...), dep)
+					}
+				}
+			case unvisited:
+				if cycle := visit(dep); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[id] = done
+		return nil
+	}
+
+	for _, id := range ids {
+		if state[id] == unvisited {
+			if cycle := visit(id); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// ParameterValues fills the template parameters from a task description
+func (t *PlanTemplate) ParameterValues(taskDescription string) map[string]string {
+	values := map[string]string{taskParameter: taskDescription}
+	for _, param := range t.Parameters {
+		value := ""
+		if param.Pattern != "" {
+			if match := regexp.MustCompile(param.Pattern).FindStringSubmatch(taskDescription); len(match) > 1 {
+				value = strings.TrimSpace(match[1])
+			}
+		}
+		if value == "" {
+			value = param.Default
+		}
+		if value == "" {
+			value = taskDescription
+		}
+		values[param.Name] = value
+	}
+	return values
+}
+
+// Instantiate creates the plan steps for a task description
+func (t *PlanTemplate) Instantiate(taskDescription string) []TaskStep {
+	values := t.ParameterValues(taskDescription)
+	fill := func(text string) string {
+		return placeholderPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+			return values[placeholder[1:]]
+		})
+	}
+
+	steps := make([]TaskStep, 0, len(t.Steps))
+	for _, step := range t.Steps {
+		verification := make([]string, 0, len(step.Verify))
+		for _, check := range step.Verify {
+			verification = append(verification, fill(check))
+		}
+		steps = append(steps, TaskStep{
+			StepID:        step.ID,
+			Description:   fill(step.Description),
+			AssignedAgent: step.Role,
+			Dependencies:  append([]string{}, step.DependsOn...),
+			Status:        "pending",
+			EstimatedTime: step.EstimatedTime,
+			Verification:  verification,
+			RequiresTools: step.RequiresTools,
+		})
+	}
+	return steps
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
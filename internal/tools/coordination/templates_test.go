@@ -0,0 +1,69 @@
+package coordination
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadPlanTemplatesBuiltins(t *testing.T) {
+	templates, errs := LoadPlanTemplates("", nil)
+	if len(errs) > 0 {
+		t.Fatalf("built-in templates failed to load: %v", errs)
+	}
+	for _, name := range []string{"feature-implementation", "bug-fix", "refactor-with-tests"} {
+		if templates[name] == nil {
+			t.Errorf("built-in template %s not loaded", name)
+		}
+	}
+
+	steps := templates["bug-fix"].Instantiate("fix the crash in internal/db when the file is empty")
+	if got := steps[2].Description; got != "Fix the bug in internal/db" {
+		t.Errorf("fix step description = %q, want parameter filled from the task", got)
+	}
+}
+
+func TestLoadPlanTemplatesValidatesUserTemplates(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"cycle.yaml": `name: cycle
+steps:
+  - {id: a, role: coder, depends_on: [b]}
+  - {id: b, role: coder, depends_on: [a]}
+`,
+		"role.yaml": `name: role
+steps:
+  - {id: a, role: designer}
+`,
+		"custom.yaml": `name: custom
+steps:
+  - {id: a, role: reviewer, description: Review $TASK}
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	templates, errs := LoadPlanTemplates(dir, []string{"reviewer"})
+	if templates["custom"] == nil {
+		t.Errorf("valid user template not loaded: %v", errs)
+	}
+	if templates["cycle"] != nil || templates["role"] != nil {
+		t.Errorf("invalid user templates were loaded")
+	}
+
+	joined := make([]string, 0, len(errs))
+	for _, err := range errs {
+		joined = append(joined, err.Error())
+	}
+	if !strings.Contains(strings.Join(joined, "\n"), "dependency cycle: a -> b -> a") {
+		t.Errorf("errors = %v, want the dependency cycle reported", joined)
+	}
+
+	if _, errs := LoadPlanTemplates(dir, nil); len(errs) != 3 {
+		t.Errorf("got %d errors without the reviewer agent, want 3: %v", len(errs), errs)
+	}
+}
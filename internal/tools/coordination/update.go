@@ -0,0 +1,124 @@
+package coordination
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/version"
+)
+
+const updateCheckTimeout = 10 * time.Second
+
+// updates is the update check of the process, started once by
+// StartUpdateCheck
+var updates atomic.Pointer[UpdateChecker]
+
+// StartUpdateCheck checks url for a newer release in the background. Only the
+// first call starts a check, which the managers report.
+func StartUpdateCheck(ctx context.Context, url string) {
+	checker := NewUpdateChecker(url)
+	if updates.CompareAndSwap(nil, checker) {
+		checker.Start(ctx)
+	}
+}
+
+// UpdateChecker polls a configured URL for the latest released version
+type UpdateChecker struct {
+	url       string
+	available atomic.Bool
+	latest    atomic.Value // string
+}
+
+// NewUpdateChecker creates an update checker for the given URL
+func NewUpdateChecker(url string) *UpdateChecker {
+	return &UpdateChecker{url: url}
+}
+
+// Start performs the update check in the background
+func (u *UpdateChecker) Start(ctx context.Context) {
+	if u.url == "" {
+		return
+	}
+	go func() {
+		defer logging.RecoverPanic("update-check", nil)
+
+		latest, err := u.fetchLatest(ctx)
+		if err != nil {
+			logging.Debug("Update check failed", "url", u.url, "error", err)
+			return
+		}
+		if version.IsNewer(latest, version.Version) {
+			u.available.Store(true)
+			logging.Info("A newer version is available", "current", version.Version, "latest", latest)
+		}
+		// Stored last, the latest version being known once the check is done
+		u.latest.Store(latest)
+	}()
+}
+
+// UpdateAvailable reports whether the last check found a newer version,
+// false for a nil checker, as before the check is started
+func (u *UpdateChecker) UpdateAvailable() bool {
+	return u != nil && u.available.Load()
+}
+
+// LatestVersion returns the latest version reported by the update URL, if known
+func (u *UpdateChecker) LatestVersion() string {
+	if u == nil {
+		return ""
+	}
+	latest, _ := u.latest.Load().(string)
+	return latest
+}
+
+func (u *UpdateChecker) fetchLatest(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, updateCheckTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "intelligence-interface/"+version.Version)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var payload struct {
+		Version string `json:"version"`
+		TagName string `json:"tag_name"`
+	}
+	if err := json.Unmarshal(body, &payload); err == nil {
+		if payload.Version != "" {
+			return payload.Version, nil
+		}
+		if payload.TagName != "" {
+			return payload.TagName, nil
+		}
+	}
+
+	latest := strings.TrimSpace(string(body))
+	if latest == "" {
+		return "", fmt.Errorf("empty version in response")
+	}
+	return latest, nil
+}
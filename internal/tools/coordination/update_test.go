@@ -0,0 +1,105 @@
+package coordination
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/version"
+)
+
+func TestUpdateCheckerFetchLatest(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{"version", http.StatusOK, `{"version": "1.4.0"}`, "1.4.0"},
+		{"release tag", http.StatusOK, `{"tag_name": "v1.5.0"}`, "v1.5.0"},
+		{"plain text", http.StatusOK, "1.6.0\n", "1.6.0"},
+		{"empty", http.StatusOK, "  ", ""},
+		{"error status", http.StatusNotFound, "1.7.0", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			latest, err := NewUpdateChecker(server.URL).fetchLatest(context.Background())
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("fetchLatest() = %q, want an error", latest)
+				}
+				return
+			}
+			if err != nil || latest != tt.want {
+				t.Errorf("fetchLatest() = %q, %v, want %q", latest, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateChecker(t *testing.T) {
+	current := version.Version
+	version.Version = "1.2.0"
+	defer func() { version.Version = current }()
+
+	for latest, want := range map[string]bool{"1.3.0": true, "1.2.0": false} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(latest))
+		}))
+		checker := NewUpdateChecker(server.URL)
+		checker.Start(context.Background())
+		deadline := time.Now().Add(5 * time.Second)
+		for checker.LatestVersion() == "" && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		server.Close()
+		if checker.LatestVersion() != latest || checker.UpdateAvailable() != want {
+			t.Errorf("with %s released, LatestVersion() = %q and UpdateAvailable() = %v, want %v", latest, checker.LatestVersion(), checker.UpdateAvailable(), want)
+		}
+	}
+
+	var unstarted *UpdateChecker
+	if unstarted.UpdateAvailable() || unstarted.LatestVersion() != "" {
+		t.Error("a nil checker reports an update")
+	}
+}
+
+func TestStartUpdateCheckOnce(t *testing.T) {
+	defer updates.Store(nil)
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte("1.0.0"))
+	}))
+	defer server.Close()
+
+	StartUpdateCheck(context.Background(), server.URL)
+	first := updates.Load()
+	StartUpdateCheck(context.Background(), server.URL)
+	for range 3 {
+		if _, err := NewManager(nil); err != nil {
+			t.Fatalf("NewManager() error = %v", err)
+		}
+	}
+	if updates.Load() != first {
+		t.Error("StartUpdateCheck() replaced the check already started")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for first.LatestVersion() == "" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := requests.Load(); got != 1 {
+		t.Errorf("the update URL was requested %d times, want once", got)
+	}
+}
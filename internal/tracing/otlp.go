@@ -0,0 +1,143 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const serviceName = "intelligence-interface"
+
+var otlpClient = &http.Client{Timeout: 10 * time.Second}
+
+// The OTLP/HTTP JSON encoding of a trace, limited to the fields recorded here
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              int             `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	}
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue string `json:"stringValue"`
+	}
+)
+
+// otlpSpanKindInternal is the OTLP kind of spans that do not cross a process boundary
+const otlpSpanKindInternal = 1
+
+// exportOTLP posts a finished trace to the traces endpoint of an OTLP/HTTP collector
+func exportOTLP(ctx context.Context, endpoint string, trace *Trace) error {
+	body, err := json.Marshal(otlpTrace(trace))
+	if err != nil {
+		return err
+	}
+	url := strings.TrimSuffix(endpoint, "/") + "/v1/traces"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := otlpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("collector responded %s", resp.Status)
+	}
+	return nil
+}
+
+// otlpTrace converts a trace to an OTLP request, assigning its trace and span IDs
+func otlpTrace(trace *Trace) otlpRequest {
+	trace.mu.Lock()
+	defer trace.mu.Unlock()
+
+	traceID := randomID(16)
+	var spans []otlpSpan
+	var add func(span *Span, parentID string)
+	add = func(span *Span, parentID string) {
+		s := otlpSpan{
+			TraceID:           traceID,
+			SpanID:            randomID(8),
+			ParentSpanID:      parentID,
+			Name:              span.Name,
+			Kind:              otlpSpanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(span.StartTime.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.StartTime.Add(span.Duration()).UnixNano(), 10),
+			Attributes:        otlpAttributes(span.Attributes),
+		}
+		if parentID == "" {
+			s.Attributes = append(s.Attributes, otlpAttribute{Key: "session.id", Value: otlpValue{StringValue: trace.SessionID}})
+		}
+		spans = append(spans, s)
+		for _, child := range span.Children {
+			add(child, s.SpanID)
+		}
+	}
+	add(trace.Root, "")
+
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpAttribute{
+			{Key: "service.name", Value: otlpValue{StringValue: serviceName}},
+		}},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: serviceName},
+			Spans: spans,
+		}},
+	}}}
+}
+
+func otlpAttributes(attributes map[string]string) []otlpAttribute {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([]otlpAttribute, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, otlpAttribute{Key: key, Value: otlpValue{StringValue: attributes[key]}})
+	}
+	return result
+}
+
+// randomID returns n random bytes hex encoded, as OTLP/JSON encodes trace and span IDs
+func randomID(n int) string {
+	id := make([]byte, n)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
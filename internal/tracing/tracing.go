@@ -0,0 +1,278 @@
+// Package tracing records where the time of each agent turn goes. A turn is
+// a trace of nested spans kept in memory for the last turns, logged when it is
+// slow and optionally exported to an OTLP collector. While tracing is
+// disabled no span is created and every span method is a no-op on nil.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+)
+
+// Span is a timed operation of a turn
+type Span struct {
+	Name       string
+	StartTime  time.Time
+	EndTime    time.Time
+	Attributes map[string]string
+	Children   []*Span
+
+	trace  *Trace
+	parent *Span
+}
+
+// Trace is the tree of spans recorded for one turn
+type Trace struct {
+	SessionID string
+	// MessageIDs are the messages created during the turn
+	MessageIDs []string
+	Root       *Span
+
+	mu sync.Mutex
+}
+
+type spanContextKey struct{}
+
+var (
+	enabled atomic.Bool
+
+	mu       sync.RWMutex
+	settings config.TracingConfig
+	history  []*Trace // finished turns, oldest first
+)
+
+// Configure applies the tracing configuration. Turns started while tracing is
+// disabled are not recorded.
+func Configure(cfg config.TracingConfig) {
+	mu.Lock()
+	defer mu.Unlock()
+	settings = cfg
+	if len(history) > cfg.History {
+		history = history[len(history)-cfg.History:]
+	}
+	enabled.Store(cfg.Enabled)
+}
+
+// StartTurn starts the trace of a turn, returning a context carrying its root span
+func StartTurn(ctx context.Context, sessionID string) (context.Context, *Span) {
+	if !enabled.Load() {
+		return ctx, nil
+	}
+	trace := &Trace{SessionID: sessionID}
+	trace.Root = &Span{Name: "turn", StartTime: time.Now(), trace: trace}
+	return context.WithValue(ctx, spanContextKey{}, trace.Root), trace.Root
+}
+
+// Start starts a child of the span in ctx, returning a context carrying it.
+// Outside of a traced turn it returns ctx and a nil span.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	parent := FromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	span := parent.child(name)
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// FromContext returns the span carried by ctx, nil if there is none
+func FromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+func (s *Span) child(name string) *Span {
+	child := &Span{Name: name, StartTime: time.Now(), trace: s.trace, parent: s}
+	s.trace.mu.Lock()
+	s.Children = append(s.Children, child)
+	s.trace.mu.Unlock()
+	return child
+}
+
+// Child starts a child span without a context, for operations nothing else is nested in
+func (s *Span) Child(name string) *Span {
+	if s == nil {
+		return nil
+	}
+	return s.child(name)
+}
+
+// SetAttribute annotates the span
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.trace.mu.Lock()
+	defer s.trace.mu.Unlock()
+	if s.Attributes == nil {
+		s.Attributes = make(map[string]string)
+	}
+	s.Attributes[key] = value
+}
+
+// AddMessage associates a message created during the turn with its trace
+func (s *Span) AddMessage(messageID string) {
+	if s == nil {
+		return
+	}
+	s.trace.mu.Lock()
+	defer s.trace.mu.Unlock()
+	s.trace.MessageIDs = append(s.trace.MessageIDs, messageID)
+}
+
+// End ends the span along with its children still running. Ending the root
+// span of a turn finishes its trace. Ending a span twice keeps the first end time.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.trace.mu.Lock()
+	ended := !s.EndTime.IsZero()
+	if !ended {
+		s.end(time.Now())
+	}
+	s.trace.mu.Unlock()
+	if !ended && s.parent == nil {
+		finish(s.trace)
+	}
+}
+
+func (s *Span) end(now time.Time) {
+	s.EndTime = now
+	for _, child := range s.Children {
+		if child.EndTime.IsZero() {
+			child.end(now)
+		}
+	}
+}
+
+// Duration returns how long the span took, up to now if it has not ended
+func (s *Span) Duration() time.Duration {
+	if s == nil {
+		return 0
+	}
+	if s.EndTime.IsZero() {
+		return time.Since(s.StartTime)
+	}
+	return s.EndTime.Sub(s.StartTime)
+}
+
+// Duration returns how long the turn took
+func (t *Trace) Duration() time.Duration {
+	return t.Root.Duration()
+}
+
+// Tree renders the spans of the trace as an indented tree, one span per line
+func (t *Trace) Tree() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var b strings.Builder
+	var write func(span *Span, depth int)
+	write = func(span *Span, depth int) {
+		fmt.Fprintf(&b, "%s%s %s", strings.Repeat("  ", depth), span.Name, span.Duration().Round(time.Millisecond))
+		keys := make([]string, 0, len(span.Attributes))
+		for key := range span.Attributes {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(&b, " %s=%s", key, span.Attributes[key])
+		}
+		b.WriteString("\n")
+		for _, child := range span.Children {
+			write(child, depth+1)
+		}
+	}
+	write(t.Root, 0)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// finish keeps a finished trace in the history, logging it when the turn was
+// slow and exporting it when an exporter is configured
+func finish(trace *Trace) {
+	mu.Lock()
+	cfg := settings
+	history = append(history, trace)
+	if len(history) > cfg.History {
+		history = history[len(history)-cfg.History:]
+	}
+	mu.Unlock()
+
+	if threshold := cfg.SlowThreshold(); threshold > 0 && trace.Duration() > threshold {
+		logging.Warn("slow turn", "session", trace.SessionID, "duration", trace.Duration().Round(time.Millisecond), "spans", "\n"+trace.Tree())
+	}
+	if cfg.Exporter == config.TracingExporterOTLP {
+		go func() {
+			defer logging.RecoverPanic("tracing.export", nil)
+			if err := exportOTLP(context.Background(), cfg.Endpoint, trace); err != nil {
+				logging.Debug("failed to export trace", "endpoint", cfg.Endpoint, "error", err)
+			}
+		}()
+	}
+}
+
+// ForMessage returns the trace of the turn that created a message, nil if it
+// was not traced or is no longer kept
+func ForMessage(messageID string) *Trace {
+	mu.RLock()
+	defer mu.RUnlock()
+	for i := len(history) - 1; i >= 0; i-- {
+		trace := history[i]
+		trace.mu.Lock()
+		found := false
+		for _, id := range trace.MessageIDs {
+			if id == messageID {
+				found = true
+				break
+			}
+		}
+		trace.mu.Unlock()
+		if found {
+			return trace
+		}
+	}
+	return nil
+}
+
+// TurnLatency is the latency distribution of the recent turns
+type TurnLatency struct {
+	Turns int
+	P50   time.Duration
+	P95   time.Duration
+}
+
+// RecentTurnLatency returns the p50 and p95 latency of the turns kept in the history
+func RecentTurnLatency() TurnLatency {
+	mu.RLock()
+	durations := make([]time.Duration, len(history))
+	for i, trace := range history {
+		durations[i] = trace.Duration()
+	}
+	mu.RUnlock()
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	return TurnLatency{
+		Turns: len(durations),
+		P50:   percentile(durations, 50),
+		P95:   percentile(durations, 95),
+	}
+}
+
+// percentile returns the nearest-rank percentile of sorted durations
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
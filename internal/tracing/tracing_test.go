@@ -0,0 +1,158 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+)
+
+func configure(t testing.TB, cfg config.TracingConfig) {
+	t.Helper()
+	if cfg.History == 0 {
+		cfg.History = 10
+	}
+	Configure(cfg)
+	t.Cleanup(func() {
+		mu.Lock()
+		history = nil
+		mu.Unlock()
+		Configure(config.TracingConfig{})
+	})
+}
+
+// turn records a turn with a provider request and a tool execution
+func turn(ctx context.Context) *Span {
+	ctx, root := StartTurn(ctx, "session")
+	_, provider := Start(ctx, "provider request")
+	provider.Child("first token").End()
+	provider.End()
+	_, tool := Start(ctx, "tool")
+	tool.SetAttribute("name", "ls")
+	tool.End()
+	root.AddMessage("message")
+	root.End()
+	return root
+}
+
+func TestTurnTrace(t *testing.T) {
+	configure(t, config.TracingConfig{Enabled: true})
+
+	turn(context.Background())
+
+	trace := ForMessage("message")
+	if trace == nil {
+		t.Fatal("ForMessage() = nil, want the trace of the turn")
+	}
+	var names []string
+	for _, line := range strings.Split(trace.Tree(), "\n") {
+		names = append(names, strings.Fields(line)[0])
+	}
+	if got := strings.Join(names, ","); got != "turn,provider,first,tool" {
+		t.Errorf("Tree() spans = %s\n%s", got, trace.Tree())
+	}
+	if !strings.Contains(trace.Tree(), "name=ls") {
+		t.Errorf("Tree() is missing the span attributes:\n%s", trace.Tree())
+	}
+	if ForMessage("other") != nil {
+		t.Errorf("ForMessage() returned a trace for a message outside the turn")
+	}
+}
+
+func TestDisabledTurnsAreNotRecorded(t *testing.T) {
+	configure(t, config.TracingConfig{})
+
+	if root := turn(context.Background()); root != nil {
+		t.Errorf("StartTurn() = %v while disabled, want nil", root)
+	}
+	if latency := RecentTurnLatency(); latency.Turns != 0 {
+		t.Errorf("RecentTurnLatency() turns = %d, want 0", latency.Turns)
+	}
+}
+
+func TestDisabledTracingDoesNotAllocate(t *testing.T) {
+	configure(t, config.TracingConfig{})
+
+	ctx := context.Background()
+	if allocs := testing.AllocsPerRun(100, func() { turn(ctx) }); allocs != 0 {
+		t.Errorf("disabled tracing allocates %v times per turn, want 0", allocs)
+	}
+}
+
+func TestRecentTurnLatency(t *testing.T) {
+	configure(t, config.TracingConfig{Enabled: true, History: 20})
+
+	// 30 turns of 1ms to 30ms, of which only the last 20 are kept
+	for i := 1; i <= 30; i++ {
+		start := time.Now()
+		trace := &Trace{}
+		trace.Root = &Span{StartTime: start, trace: trace}
+		trace.Root.EndTime = start.Add(time.Duration(i) * time.Millisecond)
+		finish(trace)
+	}
+
+	latency := RecentTurnLatency()
+	if latency.Turns != 20 || latency.P50 != 20*time.Millisecond || latency.P95 != 29*time.Millisecond {
+		t.Errorf("RecentTurnLatency() = %+v, want 20 turns, p50 20ms, p95 29ms", latency)
+	}
+}
+
+func TestExportOTLP(t *testing.T) {
+	received := make(chan otlpRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("exported to %s, want /v1/traces", r.URL.Path)
+		}
+		var req otlpRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("invalid OTLP request: %v", err)
+		}
+		received <- req
+	}))
+	defer server.Close()
+	configure(t, config.TracingConfig{Enabled: true, Exporter: config.TracingExporterOTLP, Endpoint: server.URL})
+
+	turn(context.Background())
+
+	select {
+	case req := <-received:
+		spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+		if len(spans) != 4 {
+			t.Fatalf("exported %d spans, want 4", len(spans))
+		}
+		root := spans[0]
+		if len(root.TraceID) != 32 || len(root.SpanID) != 16 || root.ParentSpanID != "" {
+			t.Errorf("root span IDs = %+v", root)
+		}
+		for _, span := range spans[1:] {
+			if span.TraceID != root.TraceID || span.ParentSpanID == "" {
+				t.Errorf("span %s is not part of the turn trace: %+v", span.Name, span)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("trace was not exported")
+	}
+}
+
+func BenchmarkTurnDisabled(b *testing.B) {
+	configure(b, config.TracingConfig{})
+	ctx := context.Background()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		turn(ctx)
+	}
+}
+
+func BenchmarkTurnEnabled(b *testing.B) {
+	configure(b, config.TracingConfig{Enabled: true, History: 100})
+	ctx := context.Background()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		turn(ctx)
+	}
+}
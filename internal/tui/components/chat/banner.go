@@ -0,0 +1,119 @@
+package chat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/caronex/intelligence-interface/internal/app"
+	"github.com/caronex/intelligence-interface/internal/connectivity"
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/tui/styles"
+	"github.com/caronex/intelligence-interface/internal/tui/theme"
+)
+
+// bannerLabelWidth aligns the values of the banner sections
+const bannerLabelWidth = 12
+
+// showBanner reports whether the startup banner is shown, which the quiet
+// flag and the TUI config turn off
+func showBanner(a *app.App) bool {
+	cfg := config.Get()
+	return cfg != nil && cfg.TUI.Banner.IsEnabled() && !a.Quiet
+}
+
+// renderBanner renders the startup banner, a card of the agent, workspace and
+// configuration the chat runs with. It is rendered from their current state
+// rather than stored as a message, so it is never sent to the model and
+// follows the changes of agent and model.
+func renderBanner(a *app.App, modeInfo AgentModeInfo, width int) string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+	cfg := config.Get()
+	sections := cfg.TUI.Banner
+	innerWidth := width - 5
+
+	line := func(label, value string) string {
+		return lipgloss.JoinHorizontal(lipgloss.Left,
+			baseStyle.Width(bannerLabelWidth).Foreground(t.TextMuted()).Render(label),
+			baseStyle.Width(innerWidth-bannerLabelWidth).Foreground(t.Text()).Render(value),
+		)
+	}
+
+	lines := []string{}
+	if sections.Shows(sections.Agent) {
+		agent := modeInfo.Mode
+		if a.CaronexAgent != nil {
+			agent += " · " + a.CaronexAgent.Model().Name
+		}
+		lines = append(lines, line("Agent", agent))
+	}
+	if sections.Shows(sections.Workspace) {
+		workspace := config.WorkingDirectory()
+		if roots := config.WorkspaceRootNames(); len(roots) > 0 {
+			workspace += fmt.Sprintf(" (+ %s)", strings.Join(roots, ", "))
+		}
+		lines = append(lines, line("Workspace", workspace))
+		if summary := config.ProjectSummary(); summary != "" {
+			lines = append(lines, line("Project", summary))
+		}
+	}
+	if sections.Shows(sections.Space) {
+		space := config.ActiveSpace()
+		if space == "" {
+			space = "none"
+		}
+		lines = append(lines, line("Space", space))
+	}
+	if sections.Shows(sections.Fingerprint) {
+		lines = append(lines, line("Config", config.CurrentFingerprint()))
+	}
+	if sections.Shows(sections.Providers) {
+		lines = append(lines, line("Providers", providerStatus(cfg)))
+	}
+	if sections.Shows(sections.Warnings) {
+		for _, warning := range cfg.Warnings {
+			lines = append(lines, baseStyle.Width(innerWidth).Foreground(t.Warning()).Render("⚠ "+warning))
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+
+	return baseStyle.
+		Width(width-1).
+		Padding(0, 1).
+		Border(lipgloss.RoundedBorder()).
+		BorderBackground(t.Background()).
+		BorderForeground(t.Secondary()).
+		Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+}
+
+// providerStatus lists the enabled providers with whether they can be
+// reached, as the connectivity probe found
+func providerStatus(cfg *config.Config) string {
+	state := connectivity.Current()
+	providers := make([]string, 0, len(cfg.Providers))
+	for provider, providerCfg := range cfg.Providers {
+		if providerCfg.Disabled {
+			continue
+		}
+		status := "online"
+		switch {
+		case provider.IsLocal():
+			status = "local"
+		case state.Offline && state.Manual:
+			status = "offline mode"
+		case state.Offline:
+			status = "offline"
+		}
+		providers = append(providers, fmt.Sprintf("%s (%s)", provider, status))
+	}
+	if len(providers) == 0 {
+		return "none configured"
+	}
+	sort.Strings(providers)
+	return strings.Join(providers, ", ")
+}
@@ -7,6 +7,8 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/x/ansi"
 	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/lock"
 	"github.com/caronex/intelligence-interface/internal/message"
 	"github.com/caronex/intelligence-interface/internal/session"
 	"github.com/caronex/intelligence-interface/internal/tui/styles"
@@ -17,14 +19,76 @@ import (
 type SendMsg struct {
 	Text        string
 	Attachments []message.Attachment
+	// ReplacesMessageID is the user message being edited and resent, if any
+	ReplacesMessageID string
+	// SkipReview sends the message without the review flow of the agent
+	SkipReview bool
+	// Auto has the agent work on the message in auto mode, continuing
+	// without waiting for the user until it is done or out of budget
+	Auto bool
+	// CompareModel also responds to the message, side by side with the agent
+	// model, to pick the response the session continues with
+	CompareModel models.ModelID
+	// ExcludedContext are the sources of context the message leaves out of
+	// the system prompt, the default of the session when nil
+	ExcludedContext []message.ContextSource
+}
+
+// RetryMsg asks to generate the last response of the session again
+type RetryMsg struct {
+	// Model generates the new response instead of the agent model, empty to keep it
+	Model models.ModelID
+}
+
+// SelectRetryModelMsg asks to pick the model to retry the last response with
+type SelectRetryModelMsg struct{}
+
+// SelectCompareModelMsg asks to pick the model the next message is compared
+// with
+type SelectCompareModelMsg struct{}
+
+// CompareModelSelectedMsg sets the model the next message is compared with
+type CompareModelSelectedMsg struct {
+	Model models.Model
+}
+
+// ChooseComparisonMsg keeps a response of a model comparison as the one the
+// session continues with
+type ChooseComparisonMsg struct {
+	MessageID string
+	// Choice is the index of the kept response
+	Choice int
+}
+
+// EditMsg opens a previous user message in the editor to be edited and resent
+type EditMsg struct {
+	Message message.Message
+}
+
+// ShowMessageDetailsMsg opens the details of a message, with the latency
+// breakdown of the turn that produced it
+type ShowMessageDetailsMsg struct {
+	Message message.Message
 }
 
 type SessionSelectedMsg = session.Session
 
 type SessionClearedMsg struct{}
 
+// SessionLockMsg reports whether the selected session can be written to, or
+// is read-only because another instance holds its lock
+type SessionLockMsg struct {
+	SessionID string
+	// Holder is the lock of the other instance, nil when the session is writable
+	Holder *lock.SessionLock
+}
+
 type EditorFocusMsg bool
 
+// BannerChangedMsg reports that the state the startup banner shows changed,
+// such as the model of the agent or the active space
+type BannerChangedMsg struct{}
+
 // AgentModeInfo contains information about the current agent mode
 type AgentModeInfo struct {
 	Mode          string
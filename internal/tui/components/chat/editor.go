@@ -1,11 +1,15 @@
 package chat
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"slices"
 	"strings"
+	"time"
 	"unicode"
 
 	"github.com/charmbracelet/bubbles/key"
@@ -13,7 +17,11 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/caronex/intelligence-interface/internal/app"
+	"github.com/caronex/intelligence-interface/internal/core/config"
 	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/llm/agent"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/lock"
 	"github.com/caronex/intelligence-interface/internal/message"
 	"github.com/caronex/intelligence-interface/internal/session"
 	"github.com/caronex/intelligence-interface/internal/tui/components/dialog"
@@ -31,12 +39,45 @@ type editorCmp struct {
 	textarea    textarea.Model
 	attachments []message.Attachment
 	deleteMode  bool
-	agentMode   AgentModeInfo // Current agent mode for display
+	agentMode   AgentModeInfo     // Current agent mode for display
+	editingID   string            // User message being edited and resent, if any
+	lockHolder  *lock.SessionLock // Other instance writing to the session, if any
+	skipReview  bool              // Next message is sent without the review flow
+	auto        bool              // Next message is worked on in auto mode
+	compareWith models.Model      // Model the next message is compared with, if any
+
+	excluded    []message.ContextSource // Sources of context the session leaves out by default
+	contextMode bool                    // Context panel open, digits toggle its sources
+	minimal     bool                    // Next message is sent without any source of context
+
+	estimate    *agent.PromptEstimate // Estimated prompt of the message being written, if any
+	estimateSeq int                   // Latest estimate requested, older ones are dropped
+	confirmSend bool                  // Expensive message sent on the next enter
+}
+
+// estimateDebounce is how long typing pauses before the prompt is estimated
+const estimateDebounce = 300 * time.Millisecond
+
+// estimateTickMsg requests the estimate seq once typing paused
+type estimateTickMsg struct {
+	seq int
+}
+
+// estimateMsg is the estimate seq of the message being written
+type estimateMsg struct {
+	seq      int
+	estimate agent.PromptEstimate
+	err      error
 }
 
 type EditorKeyMaps struct {
 	Send       key.Binding
 	OpenEditor key.Binding
+	SkipReview key.Binding
+	AutoMode   key.Binding
+	Compare    key.Binding
+	Context    key.Binding
+	Minimal    key.Binding
 }
 
 type bluredEditorKeyMaps struct {
@@ -59,6 +100,26 @@ var editorMaps = EditorKeyMaps{
 		key.WithKeys("ctrl+e"),
 		key.WithHelp("ctrl+e", "open editor"),
 	),
+	SkipReview: key.NewBinding(
+		key.WithKeys("alt+r"),
+		key.WithHelp("alt+r", "skip review"),
+	),
+	AutoMode: key.NewBinding(
+		key.WithKeys("alt+a"),
+		key.WithHelp("alt+a", "auto mode"),
+	),
+	Compare: key.NewBinding(
+		key.WithKeys("alt+m"),
+		key.WithHelp("alt+m", "compare models"),
+	),
+	Context: key.NewBinding(
+		key.WithKeys("alt+c"),
+		key.WithHelp("alt+c+{i}", "toggle context source i"),
+	),
+	Minimal: key.NewBinding(
+		key.WithKeys("alt+enter"),
+		key.WithHelp("alt+enter", "send with minimal context"),
+	),
 }
 
 var DeleteKeyMaps = DeleteAttachmentKeyMaps{
@@ -124,23 +185,159 @@ func (m *editorCmp) send() tea.Cmd {
 	if m.app.CaronexAgent.IsSessionBusy(m.session.ID) {
 		return util.ReportWarn("Agent is working, please wait...")
 	}
+	// The text stays in the editor while the session is read-only; the lock
+	// is taken over once the other instance stops
+	if m.lockHolder != nil {
+		var locked *lock.LockedError
+		if err := m.app.Locks.Acquire(context.Background(), m.session.ID); errors.As(err, &locked) {
+			m.lockHolder = &locked.Lock
+			return util.ReportWarn(err.Error())
+		} else if err != nil {
+			return util.ReportError(err)
+		}
+		m.lockHolder = nil
+	}
+	// An expensive message is only sent once confirmed with a second enter
+	if m.expensive() && !m.confirmSend {
+		m.confirmSend = true
+		return util.ReportWarn(fmt.Sprintf("Expensive message: ~%s prompt tokens, press enter again to send", formatTokens(m.estimate.Tokens)))
+	}
 
 	value := m.textarea.Value()
 	m.textarea.Reset()
 	attachments := m.attachments
+	editingID := m.editingID
+	skipReview := m.skipReview
+	auto := m.auto
+	compareWith := m.compareWith
+	excluded := m.messageExcluded()
 
 	m.attachments = nil
+	m.editingID = ""
+	m.skipReview = false
+	m.auto = false
+	m.compareWith = models.Model{}
+	m.minimal = false
+	m.estimateChanged()
 	if value == "" {
 		return nil
 	}
 	return tea.Batch(
 		util.CmdHandler(SendMsg{
-			Text:        value,
-			Attachments: attachments,
+			Text:              value,
+			Attachments:       attachments,
+			ReplacesMessageID: editingID,
+			SkipReview:        skipReview,
+			Auto:              auto,
+			CompareModel:      compareWith.ID,
+			ExcludedContext:   excluded,
 		}),
 	)
 }
 
+// edit fills the editor with a previous user message to be edited and resent
+func (m *editorCmp) edit(msg message.Message) {
+	m.editingID = msg.ID
+	m.textarea.SetValue(msg.Content().String())
+	m.attachments = nil
+	for _, binary := range msg.BinaryContent() {
+		m.attachments = append(m.attachments, message.Attachment{
+			FilePath: binary.Path,
+			FileName: filepath.Base(binary.Path),
+			MimeType: binary.MIMEType,
+			Content:  binary.Data,
+		})
+	}
+}
+
+// messageExcluded returns the sources of context the next message leaves
+// out: all of them for a minimal message, else the session default
+func (m *editorCmp) messageExcluded() []message.ContextSource {
+	if m.minimal {
+		return m.app.CaronexAgent.ContextSources()
+	}
+	return slices.Clone(m.excluded)
+}
+
+// toggleContext includes or leaves out the source of context i of the panel,
+// saving the change as the default of the session
+func (m *editorCmp) toggleContext(i int) tea.Cmd {
+	sources := m.app.CaronexAgent.ContextSources()
+	if i < 0 || i >= len(sources) {
+		return nil
+	}
+	if j := slices.Index(m.excluded, sources[i]); j != -1 {
+		m.excluded = slices.Delete(m.excluded, j, j+1)
+	} else {
+		m.excluded = append(m.excluded, sources[i])
+	}
+	return tea.Batch(m.saveExcluded(), m.estimateChanged())
+}
+
+// saveExcluded saves the sources of context left out as the default of the
+// session, kept in the editor until the session is created
+func (m *editorCmp) saveExcluded() tea.Cmd {
+	if m.session.ID == "" {
+		return nil
+	}
+	m.session.ExcludedContext = make([]string, len(m.excluded))
+	for i, source := range m.excluded {
+		m.session.ExcludedContext[i] = string(source)
+	}
+	saved, err := m.app.Sessions.Save(context.Background(), m.session)
+	if err != nil {
+		return util.ReportError(err)
+	}
+	m.session = saved
+	return nil
+}
+
+// costEstimate returns when the estimate of a message is shown and when
+// sending it is confirmed
+func costEstimate() config.CostEstimateConfig {
+	if cfg := config.Get(); cfg != nil {
+		return cfg.TUI.CostEstimate
+	}
+	return config.CostEstimateConfig{
+		Threshold:          config.DefaultCostEstimateThreshold,
+		ExpensiveThreshold: config.DefaultExpensiveThreshold,
+	}
+}
+
+// expensive reports whether the message being written is only sent once
+// confirmed
+func (m *editorCmp) expensive() bool {
+	threshold := costEstimate().ExpensiveThreshold
+	return m.estimate != nil && threshold > 0 && m.estimate.Tokens >= threshold
+}
+
+// estimateChanged estimates the message being written again once typing
+// paused, as its text, attachments or session changed
+func (m *editorCmp) estimateChanged() tea.Cmd {
+	m.estimateSeq++
+	m.confirmSend = false
+	if m.textarea.Value() == "" {
+		m.estimate = nil
+		return nil
+	}
+	seq := m.estimateSeq
+	return tea.Tick(estimateDebounce, func(time.Time) tea.Msg {
+		return estimateTickMsg{seq: seq}
+	})
+}
+
+// estimatePrompt estimates the prompt of the message being written in the
+// background
+func (m *editorCmp) estimatePrompt(seq int) tea.Cmd {
+	sessionID, content := m.session.ID, m.textarea.Value()
+	attachments := slices.Clone(m.attachments)
+	ctx := agent.WithExcludedContext(context.Background(), m.messageExcluded())
+	return func() tea.Msg {
+		estimate, err := m.app.CaronexAgent.EstimatePrompt(ctx, sessionID, content, attachments...)
+		return estimateMsg{seq: seq, estimate: estimate, err: err}
+	}
+}
+
 func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 	switch msg := msg.(type) {
@@ -151,6 +348,22 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		modifiedValue := strings.Replace(existingValue, msg.SearchString, msg.CompletionValue, 1)
 
 		m.textarea.SetValue(modifiedValue)
+		return m, m.estimateChanged()
+	case estimateTickMsg:
+		if msg.seq != m.estimateSeq {
+			return m, nil
+		}
+		return m, m.estimatePrompt(msg.seq)
+	case estimateMsg:
+		if msg.seq != m.estimateSeq {
+			return m, nil
+		}
+		m.estimate = nil
+		if msg.err != nil {
+			logging.Debug("Failed to estimate the prompt", "error", msg.err)
+		} else {
+			m.estimate = &msg.estimate
+		}
 		return m, nil
 	case AgentSwitchedMsg:
 		// Handle agent mode changes
@@ -166,15 +379,45 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 	case SessionSelectedMsg:
 		if msg.ID != m.session.ID {
+			created := m.session.ID == "" && msg.MessageCount == 0
 			m.session = msg
+			m.editingID = ""
+			m.lockHolder = nil
+			// The sources toggled before the session was created become its
+			// default
+			if created && len(m.excluded) > 0 && len(msg.ExcludedContext) == 0 {
+				return m, tea.Batch(m.saveExcluded(), m.estimateChanged())
+			}
+			m.excluded = nil
+			for _, source := range msg.ExcludedContext {
+				m.excluded = append(m.excluded, message.ContextSource(source))
+			}
+			return m, m.estimateChanged()
 		}
 		return m, nil
+	case SessionClearedMsg:
+		m.editingID = ""
+		m.lockHolder = nil
+		m.excluded = nil
+		return m, m.estimateChanged()
+	case SessionLockMsg:
+		if msg.SessionID == m.session.ID {
+			m.lockHolder = msg.Holder
+		}
+		return m, nil
+	case EditMsg:
+		m.edit(msg.Message)
+		return m, m.estimateChanged()
+	case CompareModelSelectedMsg:
+		m.compareWith = msg.Model
+		return m, nil
 	case dialog.AttachmentAddedMsg:
 		if len(m.attachments) >= maxAttachments {
 			logging.ErrorPersist(fmt.Sprintf("cannot add more than %d images", maxAttachments))
 			return m, cmd
 		}
 		m.attachments = append(m.attachments, msg.Attachment)
+		cmd = m.estimateChanged()
 	case tea.KeyMsg:
 		if key.Matches(msg, DeleteKeyMaps.AttachmentDeleteMode) {
 			m.deleteMode = true
@@ -183,8 +426,15 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if key.Matches(msg, DeleteKeyMaps.DeleteAllAttachments) && m.deleteMode {
 			m.deleteMode = false
 			m.attachments = nil
+			return m, m.estimateChanged()
+		}
+		if key.Matches(msg, editorMaps.Context) {
+			m.contextMode = !m.contextMode
 			return m, nil
 		}
+		if m.contextMode && len(msg.Runes) > 0 && unicode.IsDigit(msg.Runes[0]) {
+			return m, m.toggleContext(int(msg.Runes[0]-'0') - 1)
+		}
 		if m.deleteMode && len(msg.Runes) > 0 && unicode.IsDigit(msg.Runes[0]) {
 			num := int(msg.Runes[0] - '0')
 			m.deleteMode = false
@@ -194,7 +444,7 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				} else {
 					m.attachments = slices.Delete(m.attachments, num, num+1)
 				}
-				return m, nil
+				return m, m.estimateChanged()
 			}
 		}
 		if key.Matches(msg, messageKeys.PageUp) || key.Matches(msg, messageKeys.PageDown) ||
@@ -207,10 +457,44 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			return m, m.openEditor()
 		}
+		if key.Matches(msg, editorMaps.SkipReview) {
+			m.skipReview = !m.skipReview
+			return m, nil
+		}
+		if key.Matches(msg, editorMaps.AutoMode) {
+			m.auto = !m.auto
+			return m, nil
+		}
+		if key.Matches(msg, editorMaps.Compare) {
+			if m.compareWith.ID != "" {
+				m.compareWith = models.Model{}
+				return m, nil
+			}
+			return m, util.CmdHandler(SelectCompareModelMsg{})
+		}
 		if key.Matches(msg, DeleteKeyMaps.Escape) {
 			m.deleteMode = false
+			if m.contextMode || m.minimal {
+				m.contextMode = false
+				m.minimal = false
+				return m, m.estimateChanged()
+			}
+			if m.editingID != "" {
+				m.editingID = ""
+				m.textarea.Reset()
+				m.attachments = nil
+				return m, m.estimateChanged()
+			}
 			return m, nil
 		}
+		if m.textarea.Focused() && key.Matches(msg, editorMaps.Minimal) {
+			if !m.minimal {
+				// The estimate of the minimal message replaces the full one
+				m.minimal = true
+				m.estimate = nil
+			}
+			return m, m.send()
+		}
 		// Hanlde Enter key
 		if m.textarea.Focused() && key.Matches(msg, editorMaps.Send) {
 			value := m.textarea.Value()
@@ -225,7 +509,13 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	}
-	m.textarea, cmd = m.textarea.Update(msg)
+	value := m.textarea.Value()
+	var textareaCmd tea.Cmd
+	m.textarea, textareaCmd = m.textarea.Update(msg)
+	cmd = tea.Batch(cmd, textareaCmd)
+	if m.textarea.Value() != value {
+		cmd = tea.Batch(cmd, m.estimateChanged())
+	}
 	return m, cmd
 }
 
@@ -238,14 +528,49 @@ func (m *editorCmp) View() string {
 		Bold(true).
 		Foreground(t.Primary())
 
-	if len(m.attachments) == 0 {
+	var header []string
+	if m.lockHolder != nil {
+		header = append(header, styles.BaseStyle().
+			Foreground(t.Warning()).
+			Render(fmt.Sprintf(" Read-only: this session is open in another instance (PID %d)", m.lockHolder.PID)))
+	}
+	if m.editingID != "" {
+		header = append(header, styles.BaseStyle().
+			Foreground(t.Accent()).
+			Render(" Editing message: enter to resend, esc to cancel"))
+	}
+	if m.skipReview {
+		header = append(header, styles.BaseStyle().
+			Foreground(t.Accent()).
+			Render(" Review skipped for this message: alt+r to review"))
+	}
+	if m.auto {
+		header = append(header, styles.BaseStyle().
+			Foreground(t.Warning()).
+			Bold(true).
+			Render(" Auto mode: the agent keeps working within its budget, esc aborts; alt+a to turn off"))
+	}
+	if m.compareWith.ID != "" {
+		header = append(header, styles.BaseStyle().
+			Foreground(t.Accent()).
+			Render(fmt.Sprintf(" Comparing with %s, tools disabled: alt+1/alt+2 keeps a response; alt+m to turn off", m.compareWith.Name)))
+	}
+	if context := m.contextContent(); context != "" {
+		header = append(header, context)
+	}
+	if len(m.attachments) > 0 {
+		header = append(header, m.attachmentsContent())
+	}
+	if estimate := m.estimateContent(); estimate != "" {
+		header = append(header, estimate)
+	}
+	if len(header) == 0 {
 		return lipgloss.JoinHorizontal(lipgloss.Top, style.Render(">"), m.textarea.View())
 	}
-	m.textarea.SetHeight(m.height - 1)
+	m.textarea.SetHeight(m.height - len(header))
 	return lipgloss.JoinVertical(lipgloss.Top,
-		m.attachmentsContent(),
-		lipgloss.JoinHorizontal(lipgloss.Top, style.Render(">"),
-			m.textarea.View()),
+		append(header, lipgloss.JoinHorizontal(lipgloss.Top, style.Render(">"),
+			m.textarea.View()))...,
 	)
 }
 
@@ -285,6 +610,88 @@ func (m *editorCmp) attachmentsContent() string {
 	return content
 }
 
+// contextContent shows the sources of context of the next message: the panel
+// toggling them when open, else the ones left out
+func (m *editorCmp) contextContent() string {
+	t := theme.CurrentTheme()
+	if m.minimal {
+		return styles.BaseStyle().
+			Foreground(t.Accent()).
+			Render(" Minimal context: this message is sent without context sources, esc to cancel")
+	}
+	sources := m.app.CaronexAgent.ContextSources()
+	if m.contextMode {
+		if len(sources) == 0 {
+			return styles.BaseStyle().Foreground(t.TextMuted()).Render(" No context sources to toggle: alt+c to close")
+		}
+		items := make([]string, len(sources))
+		for i, source := range sources {
+			check := "x"
+			if slices.Contains(m.excluded, source) {
+				check = " "
+			}
+			items[i] = fmt.Sprintf("%d [%s] %s", i+1, check, contextLabel(source))
+		}
+		return styles.BaseStyle().
+			Foreground(t.Accent()).
+			Render(fmt.Sprintf(" Context: %s · digits toggle, saved for the session; alt+c to close", strings.Join(items, "  ")))
+	}
+	var left []string
+	for _, source := range sources {
+		if slices.Contains(m.excluded, source) {
+			left = append(left, contextLabel(source))
+		}
+	}
+	if len(left) == 0 {
+		return ""
+	}
+	return styles.BaseStyle().
+		Foreground(t.TextMuted()).
+		Render(fmt.Sprintf(" Left out of the context: %s; alt+c to change", strings.Join(left, ", ")))
+}
+
+// contextLabel names a source of context in the context panel
+func contextLabel(source message.ContextSource) string {
+	return strings.ReplaceAll(string(source), "_", " ")
+}
+
+// estimateContent shows the estimated prompt of the message being written
+// once it reaches the threshold, warning when it is expensive
+func (m *editorCmp) estimateContent() string {
+	if m.estimate == nil || m.estimate.Tokens < costEstimate().Threshold {
+		return ""
+	}
+	t := theme.CurrentTheme()
+	text := fmt.Sprintf(" ~%s prompt tokens", formatTokens(m.estimate.Tokens))
+	if m.estimate.Cost > 0 {
+		text += fmt.Sprintf(" · $%.2f", m.estimate.Cost)
+	}
+	text += " with " + m.estimate.Model.Name
+	if !m.expensive() {
+		return styles.BaseStyle().Foreground(t.TextMuted()).Render(text)
+	}
+	if m.confirmSend {
+		text += ": expensive, enter again to send"
+	} else {
+		text += ": expensive, enter twice to send"
+	}
+	return styles.BaseStyle().Foreground(t.Warning()).Bold(true).Render(text)
+}
+
+// formatTokens formats a number of tokens as 950, 12.4K or 1.2M
+func formatTokens(tokens int64) string {
+	var formatted string
+	switch {
+	case tokens >= 1_000_000:
+		formatted = fmt.Sprintf("%.1fM", float64(tokens)/1_000_000)
+	case tokens >= 1_000:
+		formatted = fmt.Sprintf("%.1fK", float64(tokens)/1_000)
+	default:
+		return fmt.Sprintf("%d", tokens)
+	}
+	return strings.Replace(formatted, ".0", "", 1)
+}
+
 func (m *editorCmp) BindingKeys() []key.Binding {
 	bindings := []key.Binding{}
 	bindings = append(bindings, layout.KeyMapToSlice(editorMaps)...)
@@ -3,7 +3,10 @@ package chat
 import (
 	"context"
 	"fmt"
+	"maps"
 	"math"
+	"slices"
+	"time"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/spinner"
@@ -11,10 +14,13 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/caronex/intelligence-interface/internal/app"
+	"github.com/caronex/intelligence-interface/internal/connectivity"
+	"github.com/caronex/intelligence-interface/internal/llm/tools"
 	"github.com/caronex/intelligence-interface/internal/message"
 	"github.com/caronex/intelligence-interface/internal/pubsub"
 	"github.com/caronex/intelligence-interface/internal/session"
 	"github.com/caronex/intelligence-interface/internal/tui/components/dialog"
+	"github.com/caronex/intelligence-interface/internal/tui/layout"
 	"github.com/caronex/intelligence-interface/internal/tui/styles"
 	"github.com/caronex/intelligence-interface/internal/tui/theme"
 	"github.com/caronex/intelligence-interface/internal/tui/util"
@@ -41,7 +47,9 @@ type messagesCmp struct {
 	spinner       spinner.Model
 	rendering     bool
 	attachments   viewport.Model
-	agentMode     AgentModeInfo // Current agent mode for display
+	agentMode     AgentModeInfo          // Current agent mode for display
+	selectedMsgID string                 // Message the retry and edit actions apply to
+	liveOutputs   map[string]*liveOutput // Output of the running tool calls by ID
 }
 type renderFinishedMsg struct{}
 
@@ -52,6 +60,47 @@ type MessageKeys struct {
 	HalfPageDown key.Binding
 }
 
+type MessageActionKeys struct {
+	SelectPrevious key.Binding
+	SelectNext     key.Binding
+	Retry          key.Binding
+	RetryWithModel key.Binding
+	EditResend     key.Binding
+	Details        key.Binding
+	KeepResponse   key.Binding
+}
+
+var messageActionKeys = MessageActionKeys{
+	SelectPrevious: key.NewBinding(
+		key.WithKeys("alt+up"),
+		key.WithHelp("alt+↑", "select previous message"),
+	),
+	SelectNext: key.NewBinding(
+		key.WithKeys("alt+down"),
+		key.WithHelp("alt+↓", "select next message"),
+	),
+	Retry: key.NewBinding(
+		key.WithKeys("ctrl+y"),
+		key.WithHelp("ctrl+y", "retry last response"),
+	),
+	RetryWithModel: key.NewBinding(
+		key.WithKeys("ctrl+x"),
+		key.WithHelp("ctrl+x", "retry with another model"),
+	),
+	EditResend: key.NewBinding(
+		key.WithKeys("ctrl+g"),
+		key.WithHelp("ctrl+g", "edit & resend message"),
+	),
+	Details: key.NewBinding(
+		key.WithKeys("alt+i"),
+		key.WithHelp("alt+i", "message details"),
+	),
+	KeepResponse: key.NewBinding(
+		key.WithKeys("alt+1", "alt+2"),
+		key.WithHelp("alt+1/2", "keep compared response"),
+	),
+}
+
 var messageKeys = MessageKeys{
 	PageDown: key.NewBinding(
 		key.WithKeys("pgdown"),
@@ -89,8 +138,10 @@ func (m *messagesCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 	case SessionClearedMsg:
 		m.session = session.Session{}
+		m.pruneLiveOutputs()
 		m.messages = make([]message.Message, 0)
 		m.currentMsgID = ""
+		m.selectedMsgID = ""
 		m.rendering = false
 		return m, nil
 		
@@ -107,6 +158,9 @@ func (m *messagesCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.rerender()
 		}
 		return m, nil
+	case BannerChangedMsg, pubsub.Event[connectivity.State]:
+		m.renderView()
+		return m, nil
 
 	case tea.KeyMsg:
 		if key.Matches(msg, messageKeys.PageUp) || key.Matches(msg, messageKeys.PageDown) ||
@@ -115,6 +169,30 @@ func (m *messagesCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.viewport = u
 			cmds = append(cmds, cmd)
 		}
+		switch {
+		case key.Matches(msg, messageActionKeys.SelectPrevious):
+			m.moveSelection(-1)
+			return m, nil
+		case key.Matches(msg, messageActionKeys.SelectNext):
+			m.moveSelection(1)
+			return m, nil
+		case key.Matches(msg, messageActionKeys.Retry):
+			return m, m.retry(util.CmdHandler(RetryMsg{}))
+		case key.Matches(msg, messageActionKeys.RetryWithModel):
+			return m, m.retry(util.CmdHandler(SelectRetryModelMsg{}))
+		case key.Matches(msg, messageActionKeys.EditResend):
+			return m, m.edit()
+		case key.Matches(msg, messageActionKeys.Details):
+			return m, m.details()
+		case key.Matches(msg, messageActionKeys.KeepResponse):
+			return m, m.keepResponse(int(msg.Runes[len(msg.Runes)-1] - '1'))
+		}
+
+	case tea.MouseMsg:
+		// Scroll the message history with the mouse wheel
+		u, cmd := m.viewport.Update(msg)
+		m.viewport = u
+		return m, cmd
 
 	case renderFinishedMsg:
 		m.rendering = false
@@ -127,6 +205,14 @@ func (m *messagesCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.renderView()
 			}
 		}
+	case pubsub.Event[tools.ToolOutput]:
+		if m.updateLiveOutput(msg.Payload) {
+			atBottom := m.viewport.AtBottom()
+			m.renderView()
+			if atBottom {
+				m.viewport.GotoBottom()
+			}
+		}
 	case pubsub.Event[message.Message]:
 		needsRerender := false
 		if msg.Type == pubsub.CreatedEvent {
@@ -170,6 +256,23 @@ func (m *messagesCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					break
 				}
 			}
+		} else if msg.Type == pubsub.DeletedEvent && msg.Payload.SessionID == m.session.ID {
+			// Messages are deleted, or moved to a branch when retrying or resending
+			for i, v := range m.messages {
+				if v.ID == msg.Payload.ID {
+					m.messages = slices.Delete(m.messages, i, i+1)
+					delete(m.cachedContent, msg.Payload.ID)
+					if m.selectedMsgID == msg.Payload.ID {
+						m.selectedMsgID = ""
+					}
+					if len(m.messages) > 0 {
+						m.currentMsgID = m.messages[len(m.messages)-1].ID
+						delete(m.cachedContent, m.currentMsgID)
+					}
+					needsRerender = true
+					break
+				}
+			}
 		}
 		if needsRerender {
 			m.renderView()
@@ -188,6 +291,45 @@ func (m *messagesCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+// updateLiveOutput adds the output of a running tool call, kept for every
+// session so switching back shows all of it, and returns whether a message
+// of the session calls it and must be rendered again
+func (m *messagesCmp) updateLiveOutput(output tools.ToolOutput) bool {
+	live, ok := m.liveOutputs[output.ToolCallID]
+	if !ok {
+		live = &liveOutput{
+			buffer:    tools.NewOutputBuffer(tools.MaxLiveOutputLength),
+			startTime: output.StartTime,
+		}
+		m.liveOutputs[output.ToolCallID] = live
+	}
+	live.buffer.Write(output.Chunk)
+	if output.Done {
+		live.endTime = time.Now()
+	}
+	if output.SessionID != m.session.ID {
+		return false
+	}
+
+	for _, msg := range m.messages {
+		for _, call := range msg.ToolCalls() {
+			if call.ID == output.ToolCallID {
+				delete(m.cachedContent, msg.ID)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pruneLiveOutputs forgets the output of the finished tool calls, whose
+// results are shown instead
+func (m *messagesCmp) pruneLiveOutputs() {
+	maps.DeleteFunc(m.liveOutputs, func(_ string, live *liveOutput) bool {
+		return !live.endTime.IsZero()
+	})
+}
+
 func (m *messagesCmp) IsAgentWorking() bool {
 	return m.app.CaronexAgent.IsSessionBusy(m.session.ID)
 }
@@ -212,6 +354,14 @@ func (m *messagesCmp) renderView() {
 	if m.width == 0 {
 		return
 	}
+	// The banner is rendered again with every view, it is not a message
+	if showBanner(m.app) {
+		if banner := renderBanner(m.app, m.agentMode, m.width); banner != "" {
+			height := lipgloss.Height(banner)
+			m.uiMessages = append(m.uiMessages, uiMessage{content: banner, height: height})
+			pos += height + 1
+		}
+	}
 	for inx, msg := range m.messages {
 		switch msg.Role {
 		case message.User:
@@ -225,12 +375,18 @@ func (m *messagesCmp) renderView() {
 				m.width,
 				pos,
 			)
-			m.uiMessages = append(m.uiMessages, userMsg)
+			userMessages := []uiMessage{userMsg}
+			pos += userMsg.height + 1 // + 1 for spacing
+			if msg.ID == m.selectedMsgID {
+				hint := renderSelectionHint(msg, m.width, pos)
+				userMessages = append(userMessages, hint)
+				pos += hint.height + 1
+			}
+			m.uiMessages = append(m.uiMessages, userMessages...)
 			m.cachedContent[msg.ID] = cacheItem{
 				width:   m.width,
-				content: []uiMessage{userMsg},
+				content: userMessages,
 			}
-			pos += userMsg.height + 1 // + 1 for spacing
 		case message.Assistant:
 			if cache, ok := m.cachedContent[msg.ID]; ok && cache.width == m.width {
 				m.uiMessages = append(m.uiMessages, cache.content...)
@@ -243,6 +399,7 @@ func (m *messagesCmp) renderView() {
 				inx,
 				m.messages,
 				m.app.Messages,
+				m.liveOutputs,
 				m.currentMsgID,
 				isSummary,
 				m.width,
@@ -252,6 +409,12 @@ func (m *messagesCmp) renderView() {
 				m.uiMessages = append(m.uiMessages, msg)
 				pos += msg.height + 1 // + 1 for spacing
 			}
+			if msg.ID == m.selectedMsgID {
+				hint := renderSelectionHint(msg, m.width, pos)
+				assistantMessages = append(assistantMessages, hint)
+				m.uiMessages = append(m.uiMessages, hint)
+				pos += hint.height + 1
+			}
 			m.cachedContent[msg.ID] = cacheItem{
 				width:   m.width,
 				content: assistantMessages,
@@ -423,12 +586,18 @@ func (m *messagesCmp) help() string {
 func (m *messagesCmp) initialScreen() string {
 	baseStyle := styles.BaseStyle()
 
+	components := []string{headerWithMode(m.width, m.agentMode), ""}
+	if showBanner(m.app) {
+		if banner := renderBanner(m.app, m.agentMode, m.width); banner != "" {
+			components = append(components, banner, "")
+		}
+	}
+	components = append(components, lspsConfigured(m.width))
+
 	return baseStyle.Width(m.width).Render(
 		lipgloss.JoinVertical(
 			lipgloss.Top,
-			headerWithMode(m.width, m.agentMode),
-			"",
-			lspsConfigured(m.width),
+			components...,
 		),
 	)
 }
@@ -463,6 +632,8 @@ func (m *messagesCmp) SetSession(session session.Session) tea.Cmd {
 		return nil
 	}
 	m.session = session
+	m.selectedMsgID = ""
+	m.pruneLiveOutputs()
 	messages, err := m.app.Messages.List(context.Background(), session.ID)
 	if err != nil {
 		return util.ReportError(err)
@@ -480,12 +651,125 @@ func (m *messagesCmp) SetSession(session session.Session) tea.Cmd {
 }
 
 func (m *messagesCmp) BindingKeys() []key.Binding {
-	return []key.Binding{
+	bindings := []key.Binding{
 		m.viewport.KeyMap.PageDown,
 		m.viewport.KeyMap.PageUp,
 		m.viewport.KeyMap.HalfPageUp,
 		m.viewport.KeyMap.HalfPageDown,
 	}
+	return append(bindings, layout.KeyMapToSlice(messageActionKeys)...)
+}
+
+// selectable reports whether the retry and edit actions can select a message
+func selectable(msg message.Message) bool {
+	return msg.Role == message.User || msg.Role == message.Assistant
+}
+
+// moveSelection selects the previous or next user or assistant message.
+// Moving up without a selection starts from the last message, moving down
+// past the last message clears the selection.
+func (m *messagesCmp) moveSelection(offset int) {
+	current := len(m.messages)
+	for i, msg := range m.messages {
+		if msg.ID == m.selectedMsgID {
+			current = i
+			break
+		}
+	}
+	if current == len(m.messages) && offset > 0 {
+		return
+	}
+
+	selected := ""
+	for i := current + offset; i >= 0 && i < len(m.messages); i += offset {
+		if selectable(m.messages[i]) {
+			selected = m.messages[i].ID
+			break
+		}
+	}
+	if selected == "" && offset < 0 {
+		return
+	}
+
+	delete(m.cachedContent, m.selectedMsgID)
+	delete(m.cachedContent, selected)
+	m.selectedMsgID = selected
+	m.renderView()
+}
+
+// retry checks the last response can be retried before running cmd. The
+// selection, if any, has to be a response to the last user message.
+func (m *messagesCmp) retry(cmd tea.Cmd) tea.Cmd {
+	if m.IsAgentWorking() {
+		return util.ReportWarn("Agent is working, please wait...")
+	}
+	lastUser := -1
+	for i, msg := range m.messages {
+		if msg.Role == message.User {
+			lastUser = i
+		}
+	}
+	if lastUser == -1 {
+		return util.ReportWarn("There is no response to retry")
+	}
+	if m.selectedMsgID != "" {
+		for i, msg := range m.messages {
+			if msg.ID == m.selectedMsgID && (i < lastUser || msg.Role != message.Assistant) {
+				return util.ReportWarn("Only the last response can be retried")
+			}
+		}
+	}
+	return cmd
+}
+
+// edit opens the selected user message, or the last one, in the editor
+func (m *messagesCmp) edit() tea.Cmd {
+	if m.IsAgentWorking() {
+		return util.ReportWarn("Agent is working, please wait...")
+	}
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		msg := m.messages[i]
+		if m.selectedMsgID != "" && msg.ID != m.selectedMsgID {
+			continue
+		}
+		if msg.Role != message.User {
+			if m.selectedMsgID != "" {
+				return util.ReportWarn("Only your own messages can be edited and resent")
+			}
+			continue
+		}
+		return util.CmdHandler(EditMsg{Message: msg})
+	}
+	return util.ReportWarn("There is no message to edit")
+}
+
+// details shows the details of the selected message, or of the last response
+func (m *messagesCmp) details() tea.Cmd {
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		msg := m.messages[i]
+		if m.selectedMsgID == "" && msg.Role != message.Assistant {
+			continue
+		}
+		if m.selectedMsgID != "" && msg.ID != m.selectedMsgID {
+			continue
+		}
+		return util.CmdHandler(ShowMessageDetailsMsg{Message: msg})
+	}
+	return util.ReportWarn("There is no message to show")
+}
+
+// keepResponse keeps a response of the last model comparison as the one the
+// session continues with
+func (m *messagesCmp) keepResponse(choice int) tea.Cmd {
+	if m.IsAgentWorking() {
+		return util.ReportWarn("Agent is working, please wait...")
+	}
+	if len(m.messages) > 0 {
+		if last := m.messages[len(m.messages)-1]; last.Comparison() != nil {
+			return util.CmdHandler(ChooseComparisonMsg{MessageID: last.ID, Choice: choice})
+		}
+	}
+	return util.ReportWarn("There is no model comparison to choose from")
 }
 
 func NewMessagesCmp(app *app.App) tea.Model {
@@ -500,6 +784,7 @@ func NewMessagesCmp(app *app.App) tea.Model {
 	return &messagesCmp{
 		app:           app,
 		cachedContent: make(map[string]cacheItem),
+		liveOutputs:   make(map[string]*liveOutput),
 		viewport:      vp,
 		spinner:       s,
 		attachments:   attachmets,
@@ -16,6 +16,8 @@ import (
 	"github.com/caronex/intelligence-interface/internal/llm/models"
 	"github.com/caronex/intelligence-interface/internal/llm/tools"
 	"github.com/caronex/intelligence-interface/internal/message"
+	"github.com/caronex/intelligence-interface/internal/tools/builtin"
+	"github.com/caronex/intelligence-interface/internal/tools/coordination"
 	"github.com/caronex/intelligence-interface/internal/tui/styles"
 	"github.com/caronex/intelligence-interface/internal/tui/theme"
 )
@@ -96,6 +98,19 @@ func renderUserMessage(msg message.Message, isFocused bool, width int, position
 		}
 		styledAttachments = append(styledAttachments, attachmentStyles.Render(filename))
 	}
+	for _, reference := range msg.FileReferences() {
+		mention := fmt.Sprintf(" %s %s", styles.DocumentIcon, message.MentionToken(reference.Path))
+		if reference.Notice != "" {
+			mention += fmt.Sprintf(" (%s)", reference.Notice)
+		}
+		styledAttachments = append(styledAttachments, attachmentStyles.Render(mention))
+	}
+	// The sources of context left out show the message was sent with less
+	if sources := msg.ContextSources(); sources != nil {
+		for _, source := range sources.Excluded {
+			styledAttachments = append(styledAttachments, attachmentStyles.Render(" without "+contextLabel(source)))
+		}
+	}
 	content := ""
 	if len(styledAttachments) > 0 {
 		attachmentContent := styles.BaseStyle().Width(width).Render(lipgloss.JoinHorizontal(lipgloss.Left, styledAttachments...))
@@ -113,17 +128,45 @@ func renderUserMessage(msg message.Message, isFocused bool, width int, position
 	return userMsg
 }
 
+// renderSelectionHint renders the actions available on the selected message
+func renderSelectionHint(msg message.Message, width int, position int) uiMessage {
+	t := theme.CurrentTheme()
+
+	messageType, actions := userMessageType, "ctrl+g edit & resend"
+	if msg.Role == message.Assistant {
+		messageType, actions = assistantMessageType, "ctrl+y retry · ctrl+x retry with another model"
+	}
+	content := styles.BaseStyle().
+		Width(width - 1).
+		Foreground(t.Accent()).
+		Render(fmt.Sprintf(" ▲ selected: %s", actions))
+	return uiMessage{
+		ID:          msg.ID + "-selection",
+		messageType: messageType,
+		position:    position,
+		height:      lipgloss.Height(content),
+		content:     content,
+	}
+}
+
 // Returns multiple uiMessages because of the tool calls
 func renderAssistantMessage(
 	msg message.Message,
 	msgIndex int,
 	allMessages []message.Message, // we need this to get tool results and the user message
 	messagesService message.Service, // We need this to get the task tool messages
+	liveOutputs map[string]*liveOutput, // Output of the running tool calls
 	focusedUIMessageId string,
 	isSummary bool,
 	width int,
 	position int,
 ) []uiMessage {
+	if comparison := msg.Comparison(); comparison != nil {
+		return []uiMessage{renderComparison(msg, *comparison, width, position)}
+	}
+	if changes := msg.WorkspaceChanges(); changes != nil {
+		return []uiMessage{renderWorkspaceChanges(msg, *changes, width, position)}
+	}
 	messages := []uiMessage{}
 	content := msg.Content().String()
 	thinking := msg.IsThinking()
@@ -152,11 +195,39 @@ func renderAssistantMessage(
 				Render(fmt.Sprintf(" %s (%s)", models.SupportedModels[msg.Model].Name, "canceled")),
 			)
 		case message.FinishReasonError:
+			if finishData.Error != nil {
+				// The error is shown as a card of its own
+				break
+			}
 			info = append(info, baseStyle.
 				Width(width-1).
 				Foreground(t.TextMuted()).
 				Render(fmt.Sprintf(" %s (%s)", models.SupportedModels[msg.Model].Name, "error")),
 			)
+		case message.FinishReasonMaxTokens:
+			info = append(info, baseStyle.
+				Width(width-1).
+				Foreground(t.Warning()).
+				Render(fmt.Sprintf(" %s (%s)", models.SupportedModels[msg.Model].Name, "truncated at the token limit")),
+			)
+		case message.FinishReasonContentFilter:
+			info = append(info, baseStyle.
+				Width(width-1).
+				Foreground(t.Warning()).
+				Render(fmt.Sprintf(" %s (%s)", models.SupportedModels[msg.Model].Name, "stopped by the content filter")),
+			)
+		case message.FinishReasonTimeout:
+			info = append(info, baseStyle.
+				Width(width-1).
+				Foreground(t.Warning()).
+				Render(fmt.Sprintf(" %s (%s)", models.SupportedModels[msg.Model].Name, finishData.Message)),
+			)
+		case message.FinishReasonInterrupted:
+			info = append(info, baseStyle.
+				Width(width-1).
+				Foreground(t.Warning()).
+				Render(fmt.Sprintf(" %s (%s)", models.SupportedModels[msg.Model].Name, "interrupted")),
+			)
 		case message.FinishReasonPermissionDenied:
 			info = append(info, baseStyle.
 				Width(width-1).
@@ -165,7 +236,42 @@ func renderAssistantMessage(
 			)
 		}
 	}
-	if content != "" || (finished && finishData.Reason == message.FinishReasonEndTurn) {
+	if review := msg.Review(); review != nil {
+		color := t.Success()
+		if review.Open > 0 {
+			color = t.Warning()
+		}
+		badge := " " + review.Badge()
+		if review.Summary != "" {
+			badge += " " + review.Summary
+		}
+		info = append(info, baseStyle.
+			Width(width-1).
+			Foreground(color).
+			Render(badge),
+		)
+	}
+	// References to sources become footnotes, their quotes are in the details
+	if cited := message.ResolveCitations(content, msg.Citations()); len(cited) > 0 {
+		content += "\n\n" + message.SourcesMarkdown(cited)
+		info = append(info, baseStyle.
+			Width(width-1).
+			Foreground(t.TextMuted()).
+			Render(fmt.Sprintf(" %d sources cited, alt+i to read the quotes", len(cited))),
+		)
+	}
+	if artifacts := msg.Artifacts(); len(artifacts) > 0 {
+		names := make([]string, 0, len(artifacts))
+		for _, a := range artifacts {
+			names = append(names, a.Name)
+		}
+		info = append(info, baseStyle.
+			Width(width-1).
+			Foreground(t.TextMuted()).
+			Render(fmt.Sprintf(" Saved %s, alt+i to copy into the project", strings.Join(names, ", "))),
+		)
+	}
+	if content != "" || (finished && (finishData.Reason == message.FinishReasonEndTurn || finishData.Reason.Truncated())) {
 		if content == "" {
 			content = "*Finished without output*"
 		}
@@ -188,11 +294,25 @@ func renderAssistantMessage(
 		content = renderMessage(thinkingContent, false, msg.ID == focusedUIMessageId, width)
 	}
 
+	if finished && finishData.Reason == message.FinishReasonError && finishData.Error != nil {
+		card := renderErrorCard(*finishData, width)
+		messages = append(messages, uiMessage{
+			ID:          msg.ID,
+			messageType: assistantMessageType,
+			position:    position,
+			height:      lipgloss.Height(card),
+			content:     card,
+		})
+		position += lipgloss.Height(card)
+		position++ // for the space
+	}
+
 	for i, toolCall := range msg.ToolCalls() {
 		toolCallContent := renderToolMessage(
 			toolCall,
 			allMessages,
 			messagesService,
+			liveOutputs,
 			focusedUIMessageId,
 			false,
 			width,
@@ -205,6 +325,243 @@ func renderAssistantMessage(
 	return messages
 }
 
+// renderErrorCard renders the provider error a response finished with: what
+// went wrong and how to fix it, the raw error being left to the details
+func renderErrorCard(finish message.Finish, width int) string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+	innerWidth := width - 5
+
+	lines := []string{
+		baseStyle.Width(innerWidth).Foreground(t.Error()).Bold(true).Render("✖ " + finish.Error.Title),
+		baseStyle.Width(innerWidth).Foreground(t.Text()).Render(finish.Message),
+	}
+	if finish.Error.Hint != "" {
+		lines = append(lines, baseStyle.Width(innerWidth).Foreground(t.Text()).Render("→ "+finish.Error.Hint))
+	}
+	if finish.Error.Details != "" {
+		lines = append(lines, baseStyle.Width(innerWidth).Foreground(t.TextMuted()).Render("alt+i to show the technical details"))
+	}
+
+	return baseStyle.
+		Width(width-1).
+		Padding(0, 1).
+		Border(lipgloss.RoundedBorder()).
+		BorderBackground(t.Background()).
+		BorderForeground(t.Error()).
+		Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+}
+
+// maxDelegationsShown bounds the delegations listed in a delegation card
+const maxDelegationsShown = 8
+
+// delegationCardContent is what the agent coordination tool reports about
+// delegations: a delegation, the operations of 'status' or the past
+// delegations of 'history'
+type delegationCardContent struct {
+	coordination.DelegationResult
+	Operations  []coordination.Operation       `json:"operations"`
+	Delegations []coordination.DelegationEvent `json:"delegations"`
+}
+
+// delegationCardLine is one delegation of a card
+type delegationCardLine struct {
+	agent   string
+	status  string
+	task    string
+	failure *coordination.FailureDetail
+}
+
+// renderDelegationCard renders what the agent coordination tool reported
+// about delegations as a summary card, with the category and detail of their
+// failures. It reports false for the other results of the tool.
+func renderDelegationCard(content string, width int) (string, bool) {
+	var reported delegationCardContent
+	if err := json.Unmarshal([]byte(content), &reported); err != nil {
+		return "", false
+	}
+	var title string
+	var delegations []delegationCardLine
+	switch {
+	case reported.AssignedTo != "" || reported.Failure != nil:
+		title = "Delegation"
+		delegations = append(delegations, delegationCardLine{reported.AssignedTo, reported.Status, reported.TaskID, reported.Failure})
+	case len(reported.Operations) > 0:
+		title = "Coordination status"
+		for _, op := range reported.Operations {
+			if op.Kind == coordination.OperationDelegation {
+				delegations = append(delegations, delegationCardLine{op.Agent, op.State, op.Description, op.Failure})
+			}
+		}
+	case reported.Delegations != nil:
+		title = "Delegation history"
+		for _, event := range reported.Delegations {
+			delegations = append(delegations, delegationCardLine{event.Agent, event.Status, event.Task, event.Failure})
+		}
+	default:
+		return "", false
+	}
+
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+	innerWidth := width - 5
+	border := t.Primary()
+	failed := 0
+	for _, d := range delegations {
+		if d.failure != nil && d.failure.Category != coordination.FailureCancelled {
+			failed++
+		}
+	}
+	if failed > 0 {
+		border = t.Error()
+		title += fmt.Sprintf(" · %d failed", failed)
+	}
+
+	lines := []string{baseStyle.Width(innerWidth).Foreground(border).Bold(true).Render(title)}
+	if len(delegations) == 0 {
+		lines = append(lines, baseStyle.Width(innerWidth).Foreground(t.TextMuted()).Render("No delegations"))
+	}
+	// The most recent delegations are listed, the last ones being the latest
+	if len(delegations) > maxDelegationsShown {
+		lines = append(lines, baseStyle.Width(innerWidth).Foreground(t.TextMuted()).
+			Render(fmt.Sprintf("… %d earlier delegations", len(delegations)-maxDelegationsShown)))
+		delegations = delegations[len(delegations)-maxDelegationsShown:]
+	}
+	for _, d := range delegations {
+		color := t.TextMuted()
+		if d.failure != nil && d.failure.Category != coordination.FailureCancelled {
+			color = t.Error()
+		}
+		header := lipgloss.JoinHorizontal(lipgloss.Left,
+			baseStyle.Foreground(t.Text()).Bold(true).Render(d.agent+" "),
+			baseStyle.Foreground(color).Render(d.status+" "),
+			baseStyle.Foreground(t.TextMuted()).Render(d.task),
+		)
+		lines = append(lines, ansi.Truncate(header, innerWidth, "…"))
+		if d.failure == nil {
+			continue
+		}
+		lines = append(lines, baseStyle.Width(innerWidth).Foreground(color).
+			Render(fmt.Sprintf("  %s: %s", d.failure.Category, d.failure.Detail)))
+		if d.failure.Suggestion != "" {
+			lines = append(lines, baseStyle.Width(innerWidth).Foreground(t.Text()).Render("  → "+d.failure.Suggestion))
+		}
+	}
+
+	return baseStyle.
+		Width(width-1).
+		Padding(0, 1).
+		Border(lipgloss.RoundedBorder()).
+		BorderBackground(t.Background()).
+		BorderForeground(border).
+		Render(lipgloss.JoinVertical(lipgloss.Left, lines...)), true
+}
+
+// maxChangedFilesShown bounds the files listed in the card of workspace
+// changes, all of them being in the details
+const maxChangedFilesShown = 10
+
+// renderWorkspaceChanges renders the changes an autonomous run made to the
+// workspace as a card: the summary and the files with their line counts, the
+// diffs being left to the details
+func renderWorkspaceChanges(msg message.Message, changes message.WorkspaceChanges, width int, position int) uiMessage {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+	innerWidth := width - 5
+
+	lines := []string{
+		baseStyle.Width(innerWidth).Foreground(t.Primary()).Bold(true).Render("Workspace changes · " + changes.Stat()),
+	}
+	if changes.Summary != "" {
+		lines = append(lines, baseStyle.Width(innerWidth).Foreground(t.Text()).Render(changes.Summary), "")
+	}
+	for i, file := range changes.Files {
+		if i == maxChangedFilesShown {
+			lines = append(lines, baseStyle.Width(innerWidth).Foreground(t.TextMuted()).
+				Render(fmt.Sprintf("… %d more files", len(changes.Files)-maxChangedFilesShown)))
+			break
+		}
+		marker, color := "M", t.Warning()
+		switch file.Change {
+		case message.FileCreated:
+			marker, color = "A", t.Success()
+		case message.FileDeleted:
+			marker, color = "D", t.Error()
+		}
+		counts := fmt.Sprintf("+%d -%d", file.Additions, file.Removals)
+		if file.Binary {
+			counts = "binary"
+		}
+		lines = append(lines, lipgloss.JoinHorizontal(lipgloss.Left,
+			baseStyle.Foreground(color).Bold(true).Render(marker+" "),
+			baseStyle.Foreground(t.Text()).Render(file.Path+" "),
+			baseStyle.Foreground(t.TextMuted()).Render(counts),
+		))
+	}
+	if changes.Truncated {
+		lines = append(lines, baseStyle.Width(innerWidth).Foreground(t.Warning()).
+			Render("The workspace has too many files, some were not compared"))
+	}
+	lines = append(lines, baseStyle.Width(innerWidth).Foreground(t.TextMuted()).Render("alt+i to show the diffs and export the patch"))
+
+	content := baseStyle.
+		Width(width-1).
+		Padding(0, 1).
+		Border(lipgloss.RoundedBorder()).
+		BorderBackground(t.Background()).
+		BorderForeground(t.Primary()).
+		Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+	return uiMessage{
+		ID:          msg.ID,
+		messageType: assistantMessageType,
+		position:    position,
+		height:      lipgloss.Height(content),
+		content:     content,
+	}
+}
+
+// renderComparison renders the responses of a model comparison side by side,
+// with the usage and latency of each and which one the session continues with
+func renderComparison(msg message.Message, comparison message.Comparison, width int, position int) uiMessage {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+
+	columnWidth := width / len(comparison.Candidates)
+	columns := make([]string, 0, len(comparison.Candidates))
+	for i, candidate := range comparison.Candidates {
+		title := fmt.Sprintf(" %d: %s", i+1, models.SupportedModels[candidate.Model].Name)
+		color := t.TextMuted()
+		if i == comparison.Kept() {
+			title += " (kept)"
+			color = t.Primary()
+		}
+		info := []string{
+			baseStyle.Width(columnWidth - 1).Foreground(color).Bold(true).Render(title),
+			baseStyle.Width(columnWidth - 1).Foreground(t.TextMuted()).Render(" " + candidate.Stats()),
+		}
+		content := candidate.Content
+		if candidate.Error != "" {
+			content = "*Failed: " + candidate.Error + "*"
+		}
+		columns = append(columns, renderMessage(content, false, i == comparison.Kept(), columnWidth, info...))
+	}
+	hint := " alt+1/alt+2 keeps a response, the other stays in a hidden branch"
+	if comparison.Chosen >= 0 {
+		hint = fmt.Sprintf(" Continuing with response %d, alt+1/alt+2 to change", comparison.Chosen+1)
+	}
+	content := lipgloss.JoinVertical(lipgloss.Left,
+		lipgloss.JoinHorizontal(lipgloss.Top, columns...),
+		baseStyle.Width(width-1).Foreground(t.Accent()).Render(hint),
+	)
+	return uiMessage{
+		ID:          msg.ID,
+		messageType: assistantMessageType,
+		position:    position,
+		height:      lipgloss.Height(content),
+		content:     content,
+	}
+}
+
 func findToolResponse(toolCallID string, futureMessages []message.Message) *message.ToolResult {
 	for _, msg := range futureMessages {
 		for _, result := range msg.ToolResults() {
@@ -442,6 +799,11 @@ func renderToolResponse(toolCall message.ToolCall, response message.ToolResult,
 	t := theme.CurrentTheme()
 	baseStyle := styles.BaseStyle()
 
+	if toolCall.Name == builtin.AgentCoordinationToolName {
+		if card, ok := renderDelegationCard(response.Content, width); ok {
+			return card
+		}
+	}
 	if response.IsError {
 		errContent := fmt.Sprintf("Error: %s", strings.ReplaceAll(response.Content, "\n", " "))
 		errContent = ansi.Truncate(errContent, width-1, "...")
@@ -536,6 +898,7 @@ func renderToolMessage(
 	toolCall message.ToolCall,
 	allMessages []message.Message,
 	messagesService message.Service,
+	liveOutputs map[string]*liveOutput,
 	focusedUIMessageId string,
 	nested bool,
 	width int,
@@ -583,6 +946,8 @@ func renderToolMessage(
 	if response != nil {
 		responseContent = renderToolResponse(toolCall, *response, width-2)
 		responseContent = strings.TrimSuffix(responseContent, "\n")
+	} else if live, ok := liveOutputs[toolCall.ID]; ok {
+		responseContent = renderLiveOutput(live, width-2)
 	} else {
 		responseContent = baseStyle.
 			Italic(true).
@@ -617,7 +982,7 @@ func renderToolMessage(
 			toolCalls = append(toolCalls, v.ToolCalls()...)
 		}
 		for _, call := range toolCalls {
-			rendered := renderToolMessage(call, []message.Message{}, messagesService, focusedUIMessageId, true, width, 0)
+			rendered := renderToolMessage(call, []message.Message{}, messagesService, liveOutputs, focusedUIMessageId, true, width, 0)
 			parts = append(parts, rendered.content)
 		}
 	}
@@ -647,6 +1012,50 @@ func renderToolMessage(
 }
 
 // Helper function to format the time difference between two Unix timestamps
+// liveOutput is the output of a tool call while it runs
+type liveOutput struct {
+	buffer    *tools.OutputBuffer
+	startTime time.Time
+	endTime   time.Time // zero while the tool runs
+}
+
+// maxLiveOutputLines is the number of output lines shown while a tool runs
+const maxLiveOutputLines = 10
+
+// renderLiveOutput shows how long a tool has been running and its last lines
+// of output
+func renderLiveOutput(live *liveOutput, width int) string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+
+	end := live.endTime
+	if end.IsZero() {
+		end = time.Now()
+	}
+	status := fmt.Sprintf("Running for %s", formatTimeDifference(live.startTime.Unix(), end.Unix()))
+	lines := live.buffer.Lines(maxLiveOutputLines)
+	if live.buffer.Dropped() > 0 || len(live.buffer.Lines(0)) > len(lines) {
+		status += fmt.Sprintf(", last %d lines", len(lines))
+	}
+	header := baseStyle.
+		Italic(true).
+		Width(width).
+		Foreground(t.TextMuted()).
+		Render(status)
+	if len(lines) == 0 {
+		return header
+	}
+
+	for i, line := range lines {
+		lines[i] = ansi.Truncate(line, width, "…")
+	}
+	output := baseStyle.
+		Width(width).
+		Foreground(t.TextMuted()).
+		Render(strings.Join(lines, "\n"))
+	return lipgloss.JoinVertical(lipgloss.Left, header, output)
+}
+
 func formatTimestampDiff(start, end int64) string {
 	diffSeconds := float64(end-start) / 1000.0 // Convert to seconds
 	if diffSeconds < 1 {
@@ -0,0 +1,111 @@
+package chat
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/scratchpad"
+	"github.com/caronex/intelligence-interface/internal/tui/styles"
+	"github.com/caronex/intelligence-interface/internal/tui/theme"
+	"github.com/caronex/intelligence-interface/internal/tui/util"
+)
+
+// scratchpadLines bounds the lines of the scratchpad shown in the sidebar
+const scratchpadLines = 20
+
+// EditScratchpad opens the scratchpad of a session in $EDITOR as a markdown
+// document, a level two heading per section, and saves it as a revision of
+// the user when the editor exits
+func EditScratchpad(sessionID string) tea.Cmd {
+	revision, err := scratchpad.Get(sessionID)
+	if err != nil {
+		return util.ReportError(err)
+	}
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = "nvim"
+	}
+	tmpfile, err := os.CreateTemp("", "scratchpad_*.md")
+	if err != nil {
+		return util.ReportError(err)
+	}
+	original := scratchpad.Render(revision.Sections)
+	if original == "" {
+		original = "## Todo\n"
+	}
+	_, err = tmpfile.WriteString(original)
+	tmpfile.Close()
+	if err != nil {
+		os.Remove(tmpfile.Name())
+		return util.ReportError(err)
+	}
+	c := exec.Command(editor, tmpfile.Name()) //nolint:gosec
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	return tea.ExecProcess(c, func(err error) tea.Msg {
+		defer os.Remove(tmpfile.Name())
+		if err != nil {
+			return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+		}
+		content, err := os.ReadFile(tmpfile.Name())
+		if err != nil {
+			return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+		}
+		if string(content) == original {
+			return util.InfoMsg{Type: util.InfoTypeInfo, Msg: "Scratchpad unchanged"}
+		}
+		saved, err := scratchpad.Set(sessionID, "user", scratchpad.Parse(string(content)))
+		if err != nil {
+			return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+		}
+		return util.InfoMsg{Type: util.InfoTypeInfo, Msg: fmt.Sprintf("Scratchpad saved as revision %d", saved.Number)}
+	})
+}
+
+// scratchpadSection renders the scratchpad of the session, cut after
+// scratchpadLines lines
+func (m *sidebarCmp) scratchpadSection() string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+
+	title := baseStyle.
+		Width(m.width).
+		Foreground(t.Primary()).
+		Bold(true).
+		Render("Scratchpad:")
+	if len(m.scratchpad.Sections) == 0 {
+		return lipgloss.JoinVertical(
+			lipgloss.Top,
+			title,
+			baseStyle.Foreground(t.TextMuted()).Width(m.width).Render("Empty (ctrl+k: Edit Scratchpad)"),
+		)
+	}
+	// The sidebar pads its content by 6 columns
+	rendered := strings.TrimRight(toMarkdown(scratchpad.Render(m.scratchpad.Sections), false, m.width-6), "\n")
+	lines := strings.Split(rendered, "\n")
+	if len(lines) > scratchpadLines {
+		lines = append(lines[:scratchpadLines], baseStyle.Foreground(t.TextMuted()).Render("… (ctrl+k: Edit Scratchpad)"))
+	}
+	return lipgloss.JoinVertical(lipgloss.Top, title, strings.Join(lines, "\n"))
+}
+
+// loadScratchpad reads the scratchpad of the session
+func (m *sidebarCmp) loadScratchpad() {
+	m.scratchpad = scratchpad.Revision{SessionID: m.session.ID}
+	if m.session.ID == "" {
+		return
+	}
+	revision, err := scratchpad.Get(m.session.ID)
+	if err != nil {
+		logging.Warn("failed to read the scratchpad", "session", m.session.ID, "error", err)
+		return
+	}
+	m.scratchpad = revision
+}
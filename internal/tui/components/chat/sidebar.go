@@ -12,6 +12,7 @@ import (
 	"github.com/caronex/intelligence-interface/internal/diff"
 	"github.com/caronex/intelligence-interface/internal/history"
 	"github.com/caronex/intelligence-interface/internal/pubsub"
+	"github.com/caronex/intelligence-interface/internal/scratchpad"
 	"github.com/caronex/intelligence-interface/internal/session"
 	"github.com/caronex/intelligence-interface/internal/tui/styles"
 	"github.com/caronex/intelligence-interface/internal/tui/theme"
@@ -22,7 +23,12 @@ type sidebarCmp struct {
 	session       session.Session
 	history       history.Service
 	agentMode     AgentModeInfo
-	modFiles      map[string]struct {
+	// configChanged is set when the configuration changed since the session
+	// was created
+	configChanged bool
+	// scratchpad is the current revision of the scratchpad of the session
+	scratchpad scratchpad.Revision
+	modFiles   map[string]struct {
 		additions int
 		removals  int
 	}
@@ -56,6 +62,8 @@ func (m *sidebarCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case SessionSelectedMsg:
 		if msg.ID != m.session.ID {
 			m.session = msg
+			m.checkConfig()
+			m.loadScratchpad()
 			ctx := context.Background()
 			m.loadModifiedFiles(ctx)
 		}
@@ -65,6 +73,10 @@ func (m *sidebarCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.session = msg.Payload
 			}
 		}
+	case pubsub.Event[scratchpad.Revision]:
+		if msg.Payload.SessionID == m.session.ID {
+			m.scratchpad = msg.Payload
+		}
 	case pubsub.Event[history.File]:
 		if msg.Payload.SessionID == m.session.ID {
 			// Process the individual file change instead of reloading all files
@@ -101,6 +113,8 @@ func (m *sidebarCmp) View() string {
 				" ",
 				m.sessionSection(),
 				" ",
+				m.scratchpadSection(),
+				" ",
 				lspsConfigured(m.width),
 				" ",
 				m.modifiedFiles(),
@@ -122,11 +136,37 @@ func (m *sidebarCmp) sessionSection() string {
 		Width(m.width - lipgloss.Width(sessionKey)).
 		Render(fmt.Sprintf(": %s", m.session.Title))
 
-	return lipgloss.JoinHorizontal(
+	section := lipgloss.JoinHorizontal(
 		lipgloss.Left,
 		sessionKey,
 		sessionValue,
 	)
+	tags := "none (ctrl+k: Edit Session Tags)"
+	if len(m.session.Tags) > 0 {
+		tags = strings.Join(m.session.Tags, ", ")
+	}
+	if m.session.Archived {
+		tags += " · archived"
+	}
+	section = lipgloss.JoinVertical(lipgloss.Left, section, baseStyle.
+		Foreground(t.TextMuted()).
+		Width(m.width).
+		Render("Tags: "+tags))
+	if m.configChanged {
+		notice := baseStyle.
+			Foreground(t.TextMuted()).
+			Width(m.width).
+			Render("Configuration changed since this session started (ctrl+k: Show Config Changes)")
+		section = lipgloss.JoinVertical(lipgloss.Left, section, notice)
+	}
+	return section
+}
+
+// checkConfig compares the configuration the session was created with to
+// the current one
+func (m *sidebarCmp) checkConfig() {
+	m.configChanged = m.session.ConfigFingerprint != "" &&
+		m.session.ConfigFingerprint != config.CurrentFingerprint()
 }
 
 func (m *sidebarCmp) modifiedFile(filePath string, additions, removals int) string {
@@ -241,11 +281,14 @@ func (m *sidebarCmp) GetSize() (int, int) {
 }
 
 func NewSidebarCmp(session session.Session, history history.Service) tea.Model {
-	return &sidebarCmp{
+	m := &sidebarCmp{
 		session: session,
 		history: history,
 		agentMode: AgentModeInfo{Mode: "Coder", IsManagerMode: false}, // Default to Coder mode
 	}
+	m.checkConfig()
+	m.loadScratchpad()
+	return m
 }
 
 func (m *sidebarCmp) loadModifiedFiles(ctx context.Context) {
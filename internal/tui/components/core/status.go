@@ -7,7 +7,10 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	zone "github.com/lrstanley/bubblezone"
+	"github.com/caronex/intelligence-interface/internal/connectivity"
 	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/llm/agent"
 	"github.com/caronex/intelligence-interface/internal/llm/models"
 	"github.com/caronex/intelligence-interface/internal/lsp"
 	"github.com/caronex/intelligence-interface/internal/lsp/protocol"
@@ -17,6 +20,7 @@ import (
 	"github.com/caronex/intelligence-interface/internal/tui/styles"
 	"github.com/caronex/intelligence-interface/internal/tui/theme"
 	"github.com/caronex/intelligence-interface/internal/tui/util"
+	"github.com/caronex/intelligence-interface/internal/version"
 )
 
 // AgentModeChangedMsg is sent when the agent mode changes
@@ -24,6 +28,15 @@ type AgentModeChangedMsg struct {
 	AgentMode string
 }
 
+// Agent modes shown in the status bar
+const (
+	AgentModeCoder   = "Coder"
+	AgentModeManager = "Caronex Manager"
+)
+
+// AgentZoneID is the mouse zone of the agent name in the status bar
+const AgentZoneID = "status-agent"
+
 type StatusCmp interface {
 	tea.Model
 }
@@ -35,6 +48,10 @@ type statusCmp struct {
 	lspClients map[string]*lsp.Client
 	session    session.Session
 	agentMode  string // Current agent mode for display
+	offline    bool
+	// autoProgress is the progress of the auto mode run of the session, empty
+	// when none runs
+	autoProgress string
 }
 
 // clearMessageCmd is a command that clears status messages after a timeout
@@ -45,7 +62,8 @@ func (m statusCmp) clearMessageCmd(ttl time.Duration) tea.Cmd {
 }
 
 func (m statusCmp) Init() tea.Cmd {
-	return nil
+	// Show the running version on initial load
+	return util.ReportInfo(fmt.Sprintf("Intelligence Interface %s", version.Version))
 }
 
 func (m statusCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -57,8 +75,18 @@ func (m statusCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.session = msg
 	case chat.SessionClearedMsg:
 		m.session = session.Session{}
+		m.autoProgress = ""
 	case AgentModeChangedMsg:
 		m.agentMode = msg.AgentMode
+	case pubsub.Event[agent.AgentEvent]:
+		if msg.Payload.Type == agent.AgentEventTypeAuto && msg.Payload.SessionID == m.session.ID {
+			m.autoProgress = msg.Payload.Progress
+			if msg.Payload.Done {
+				m.autoProgress = ""
+			}
+		}
+	case pubsub.Event[connectivity.State]:
+		m.offline = msg.Payload.Offline
 	case pubsub.Event[session.Session]:
 		if msg.Type == pubsub.UpdatedEvent {
 			if m.session.ID == msg.Payload.ID {
@@ -92,7 +120,7 @@ func getHelpWidget() string {
 		Render(helpText)
 }
 
-func formatTokensAndCost(tokens, contextWindow int64, cost float64, isManagerMode bool) string {
+func formatTokensAndCost(tokens, contextWindow int64, cost, regeneratedCost, truncatedCost float64, isManagerMode bool) string {
 	// Format tokens in human-readable format (e.g., 110K, 1.2M)
 	var formattedTokens string
 	switch {
@@ -114,6 +142,12 @@ func formatTokensAndCost(tokens, contextWindow int64, cost float64, isManagerMod
 
 	// Format cost with $ symbol and 2 decimal places
 	formattedCost := fmt.Sprintf("$%.2f", cost)
+	if regeneratedCost > 0 {
+		formattedCost += fmt.Sprintf(" (retries $%.2f)", regeneratedCost)
+	}
+	if truncatedCost > 0 {
+		formattedCost += fmt.Sprintf(" (truncated $%.2f)", truncatedCost)
+	}
 
 	percentage := (float64(tokens) / float64(contextWindow)) * 100
 	if percentage > 80 {
@@ -138,11 +172,46 @@ func (m statusCmp) View() string {
 	// Initialize the help widget
 	status := getHelpWidget()
 
+	offlineWidth := 0
+	if m.offline {
+		offline := styles.Padded().
+			Background(t.Warning()).
+			Foreground(t.Background()).
+			Bold(true).
+			Render("OFFLINE")
+		offlineWidth = lipgloss.Width(offline)
+		status += offline
+	}
+
+	// Tools are not offered to models without function calling
+	noToolsWidth := 0
+	if model.ID != "" && !model.SupportsTools {
+		noTools := styles.Padded().
+			Background(t.Warning()).
+			Foreground(t.Background()).
+			Bold(true).
+			Render("NO TOOLS")
+		noToolsWidth = lipgloss.Width(noTools)
+		status += noTools
+	}
+
+	// Auto mode runs without the user, so it is shown until it stops
+	autoWidth := 0
+	if m.autoProgress != "" {
+		auto := styles.Padded().
+			Background(t.Error()).
+			Foreground(t.Background()).
+			Bold(true).
+			Render("AUTO " + m.autoProgress + " · esc aborts")
+		autoWidth = lipgloss.Width(auto)
+		status += auto
+	}
+
 	tokenInfoWidth := 0
-	isManagerMode := m.agentMode == "Caronex Manager"
+	isManagerMode := m.agentMode == AgentModeManager
 	if m.session.ID != "" {
 		totalTokens := m.session.PromptTokens + m.session.CompletionTokens
-		tokens := formatTokensAndCost(totalTokens, model.ContextWindow, m.session.Cost, isManagerMode)
+		tokens := formatTokensAndCost(totalTokens, model.ContextWindow, m.session.Cost, m.session.RegeneratedCost, m.session.TruncatedCost, isManagerMode)
 		tokensStyle := styles.Padded().
 			Background(t.Text()).
 			Foreground(t.BackgroundSecondary())
@@ -166,7 +235,7 @@ func (m statusCmp) View() string {
 		Background(t.BackgroundDarker()).
 		Render(m.projectDiagnostics())
 
-	availableWidht := max(0, m.width-lipgloss.Width(helpWidget)-lipgloss.Width(m.model())-lipgloss.Width(diagnostics)-tokenInfoWidth)
+	availableWidht := max(0, m.width-lipgloss.Width(helpWidget)-lipgloss.Width(m.model())-lipgloss.Width(diagnostics)-tokenInfoWidth-offlineWidth-noToolsWidth-autoWidth)
 
 	if m.info.Msg != "" {
 		infoStyle := styles.Padded().
@@ -198,7 +267,7 @@ func (m statusCmp) View() string {
 	}
 
 	status += diagnostics
-	status += m.model()
+	status += zone.Mark(AgentZoneID, m.model())
 	return status
 }
 
@@ -300,7 +369,7 @@ func (m statusCmp) model() string {
 	var icon string
 	isManagerMode := false
 
-	if m.agentMode == "Caronex Manager" {
+	if m.agentMode == AgentModeManager {
 		agentName = config.AgentCaronex
 		displayName = "Caronex Manager"
 		icon = "⚡ " // Lightning bolt for coordination
@@ -341,6 +410,7 @@ func NewStatusCmp(lspClients map[string]*lsp.Client) StatusCmp {
 	return &statusCmp{
 		messageTTL: 10 * time.Second,
 		lspClients: lspClients,
-		agentMode:  "Coder", // Default to Coder mode
+		agentMode:  AgentModeCoder, // Default to Coder mode
+		offline:    connectivity.IsOffline(),
 	}
 }
@@ -41,6 +41,8 @@ type ShowMultiArgumentsDialogMsg struct {
 	CommandID string
 	Content   string
 	ArgNames  []string
+	// Values are the values the arguments start with, by position
+	Values []string
 }
 
 // CloseMultiArgumentsDialogMsg is a message that is sent when the multi-arguments dialog is closed.
@@ -98,6 +100,17 @@ func NewMultiArgumentsDialogCmp(commandID, content string, argNames []string) Mu
 	}
 }
 
+// WithValues returns the dialog with its arguments filled with values, by
+// position
+func (m MultiArgumentsDialogCmp) WithValues(values []string) MultiArgumentsDialogCmp {
+	for i, value := range values {
+		if i < len(m.inputs) {
+			m.inputs[i].SetValue(value)
+		}
+	}
+	return m
+}
+
 // Init implements tea.Model.
 func (m MultiArgumentsDialogCmp) Init() tea.Cmd {
 	// Make sure only the first input is focused
@@ -1,9 +1,14 @@
 package dialog
 
 import (
+	"sort"
+	"strings"
+
 	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/lithammer/fuzzysearch/fuzzy"
 	utilComponents "github.com/caronex/intelligence-interface/internal/tui/components/util"
 	"github.com/caronex/intelligence-interface/internal/tui/layout"
 	"github.com/caronex/intelligence-interface/internal/tui/styles"
@@ -16,7 +21,9 @@ type Command struct {
 	ID          string
 	Title       string
 	Description string
-	Handler     func(cmd Command) tea.Cmd
+	// Shortcut is the key running the command outside of the dialog, if any
+	Shortcut string
+	Handler  func(cmd Command) tea.Cmd
 }
 
 func (ci Command) Render(selected bool, width int) string {
@@ -38,7 +45,13 @@ func (ci Command) Render(selected bool, width int) string {
 			Foreground(t.Background())
 	}
 
-	title := itemStyle.Padding(0, 1).Render(ci.Title)
+	title := ci.Title
+	if ci.Shortcut != "" {
+		// Right align the shortcut on the title line
+		gap := max(1, width-2-lipgloss.Width(ci.Title)-lipgloss.Width(ci.Shortcut))
+		title += strings.Repeat(" ", gap) + ci.Shortcut
+	}
+	title = itemStyle.Padding(0, 1).Render(title)
 	if ci.Description != "" {
 		description := descStyle.Padding(0, 1).Render(ci.Description)
 		return lipgloss.JoinVertical(lipgloss.Left, title, description)
@@ -63,6 +76,8 @@ type CommandDialog interface {
 
 type commandDialogCmp struct {
 	listView utilComponents.SimpleList[Command]
+	filter   textinput.Model
+	commands []Command
 	width    int
 	height   int
 }
@@ -84,7 +99,7 @@ var commandKeys = commandKeyMap{
 }
 
 func (c *commandDialogCmp) Init() tea.Cmd {
-	return c.listView.Init()
+	return tea.Batch(c.listView.Init(), textinput.Blink)
 }
 
 func (c *commandDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -101,10 +116,24 @@ func (c *commandDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		case key.Matches(msg, commandKeys.Escape):
 			return c, util.CmdHandler(CloseCommandDialogMsg{})
+		case msg.Type != tea.KeyUp && msg.Type != tea.KeyDown:
+			// Everything but the list navigation is typed into the filter
+			query := c.filter.Value()
+			var cmd tea.Cmd
+			c.filter, cmd = c.filter.Update(msg)
+			if c.filter.Value() != query {
+				c.listView.SetItems(filterCommands(c.commands, c.filter.Value()))
+			}
+			return c, cmd
 		}
 	case tea.WindowSizeMsg:
 		c.width = msg.Width
 		c.height = msg.Height
+	default:
+		// Keep the filter cursor blinking
+		var cmd tea.Cmd
+		c.filter, cmd = c.filter.Update(msg)
+		cmds = append(cmds, cmd)
 	}
 
 	u, cmd := c.listView.Update(msg)
@@ -120,11 +149,14 @@ func (c *commandDialogCmp) View() string {
 
 	maxWidth := 40
 
-	commands := c.listView.GetItems()
-
-	for _, cmd := range commands {
-		if len(cmd.Title) > maxWidth-4 {
-			maxWidth = len(cmd.Title) + 4
+	// Size for every command so the dialog keeps its width while filtering
+	for _, cmd := range c.commands {
+		titleWidth := len(cmd.Title)
+		if cmd.Shortcut != "" {
+			titleWidth += len(cmd.Shortcut) + 2
+		}
+		if titleWidth > maxWidth-4 {
+			maxWidth = titleWidth + 4
 		}
 		if cmd.Description != "" {
 			if len(cmd.Description) > maxWidth-4 {
@@ -142,10 +174,18 @@ func (c *commandDialogCmp) View() string {
 		Padding(0, 1).
 		Render("Commands")
 
+	c.filter.Width = maxWidth - 4
+	filter := baseStyle.
+		Width(maxWidth).
+		Padding(0, 1).
+		Render(c.filter.View())
+
 	content := lipgloss.JoinVertical(
 		lipgloss.Left,
 		title,
 		baseStyle.Width(maxWidth).Render(""),
+		filter,
+		baseStyle.Width(maxWidth).Render(""),
 		baseStyle.Width(maxWidth).Render(c.listView.View()),
 		baseStyle.Width(maxWidth).Render(""),
 	)
@@ -163,18 +203,51 @@ func (c *commandDialogCmp) BindingKeys() []key.Binding {
 }
 
 func (c *commandDialogCmp) SetCommands(commands []Command) {
+	c.commands = commands
+	c.filter.Reset()
 	c.listView.SetItems(commands)
 }
 
+// filterCommands returns the commands whose title fuzzy matches query, best
+// matches first
+func filterCommands(commands []Command, query string) []Command {
+	if query == "" {
+		return commands
+	}
+	titles := make([]string, len(commands))
+	for i, cmd := range commands {
+		titles[i] = cmd.Title
+	}
+	ranks := fuzzy.RankFindNormalizedFold(query, titles)
+	sort.Stable(ranks)
+
+	filtered := make([]Command, len(ranks))
+	for i, rank := range ranks {
+		filtered[i] = commands[rank.OriginalIndex]
+	}
+	return filtered
+}
+
 // NewCommandDialogCmp creates a new command selection dialog
 func NewCommandDialogCmp() CommandDialog {
 	listView := utilComponents.NewSimpleList[Command](
 		[]Command{},
 		10,
-		"No commands available",
-		true,
+		"No matching commands",
+		false,
 	)
+
+	t := theme.CurrentTheme()
+	filter := textinput.New()
+	filter.Placeholder = "Type to filter commands..."
+	filter.Prompt = "> "
+	filter.PlaceholderStyle = filter.PlaceholderStyle.Background(t.Background()).Foreground(t.TextMuted())
+	filter.PromptStyle = filter.PromptStyle.Background(t.Background()).Foreground(t.Primary())
+	filter.TextStyle = filter.TextStyle.Background(t.Background())
+	filter.Focus()
+
 	return &commandDialogCmp{
 		listView: listView,
+		filter:   filter,
 	}
 }
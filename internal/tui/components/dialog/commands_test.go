@@ -0,0 +1,75 @@
+package dialog
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+var paletteCommands = []Command{
+	{ID: "new-session", Title: "New Session", Shortcut: "ctrl+n"},
+	{ID: "switch-session", Title: "Switch Session", Shortcut: "ctrl+s"},
+	{ID: "switch-theme", Title: "Switch Theme", Shortcut: "ctrl+t"},
+	{ID: "compact", Title: "Compact Session"},
+}
+
+func commandIDs(commands []Command) []string {
+	ids := make([]string, len(commands))
+	for i, cmd := range commands {
+		ids[i] = cmd.ID
+	}
+	return ids
+}
+
+func TestFilterCommands(t *testing.T) {
+	testCases := []struct {
+		query    string
+		expected []string
+	}{
+		{query: "", expected: []string{"new-session", "switch-session", "switch-theme", "compact"}},
+		{query: "theme", expected: []string{"switch-theme"}},
+		{query: "THEME", expected: []string{"switch-theme"}},
+		{query: "swse", expected: []string{"switch-session"}},
+		{query: "session", expected: []string{"new-session", "switch-session", "compact"}},
+		{query: "nothing", expected: []string{}},
+	}
+
+	for _, tc := range testCases {
+		got := commandIDs(filterCommands(paletteCommands, tc.query))
+		if len(got) != len(tc.expected) {
+			t.Errorf("filterCommands(%q) = %v, want %v", tc.query, got, tc.expected)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.expected[i] {
+				t.Errorf("filterCommands(%q) = %v, want %v", tc.query, got, tc.expected)
+				break
+			}
+		}
+	}
+}
+
+func TestCommandDialogTypingFiltersAndEnterSelects(t *testing.T) {
+	dialog := NewCommandDialogCmp()
+	dialog.SetCommands(paletteCommands)
+
+	var model tea.Model = dialog
+	for _, r := range "theme" {
+		model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("Enter did not select a command")
+	}
+	selected, ok := cmd().(CommandSelectedMsg)
+	if !ok || selected.Command.ID != "switch-theme" {
+		t.Errorf("Enter selected %+v, want switch-theme", selected)
+	}
+
+	// Reopening the dialog clears the filter
+	dialog.SetCommands(paletteCommands)
+	_, cmd = dialog.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if selected, _ := cmd().(CommandSelectedMsg); selected.Command.ID != "new-session" {
+		t.Errorf("Enter selected %+v after reopening, want the first command", selected)
+	}
+}
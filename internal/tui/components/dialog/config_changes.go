@@ -0,0 +1,94 @@
+package dialog
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/tui/layout"
+	"github.com/caronex/intelligence-interface/internal/tui/styles"
+	"github.com/caronex/intelligence-interface/internal/tui/theme"
+	"github.com/caronex/intelligence-interface/internal/tui/util"
+)
+
+// CloseConfigChangesMsg is sent when the config changes dialog is closed
+type CloseConfigChangesMsg struct{}
+
+// ConfigChangesDialog shows how the configuration changed since a session
+// started, secrets excluded
+type ConfigChangesDialog interface {
+	tea.Model
+	layout.Bindings
+}
+
+type configChangesDialogCmp struct {
+	changes []config.ConfigChange
+}
+
+func (m *configChangesDialogCmp) Init() tea.Cmd {
+	return nil
+}
+
+func (m *configChangesDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok && key.Matches(msg, messageDetailsKeys.Close) {
+		return m, util.CmdHandler(CloseConfigChangesMsg{})
+	}
+	return m, nil
+}
+
+func (m *configChangesDialogCmp) View() string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+
+	title := baseStyle.
+		Foreground(t.Primary()).
+		Bold(true).
+		Render("Configuration changed since this session started")
+
+	lines := make([]string, 0, len(m.changes))
+	for _, change := range m.changes {
+		switch {
+		case change.Old == "":
+			lines = append(lines, baseStyle.Foreground(t.Success()).Render(fmt.Sprintf("+ %s: %s", change.Path, change.New)))
+		case change.New == "":
+			lines = append(lines, baseStyle.Foreground(t.Error()).Render(fmt.Sprintf("- %s: %s", change.Path, change.Old)))
+		default:
+			lines = append(lines, baseStyle.Foreground(t.Text()).Render(fmt.Sprintf("~ %s: %s → %s", change.Path, change.Old, change.New)))
+		}
+	}
+	if len(lines) == 0 {
+		lines = append(lines, baseStyle.Foreground(t.TextMuted()).Render("The previous configuration was not recorded."))
+	}
+
+	content := baseStyle.Render(
+		lipgloss.JoinVertical(
+			lipgloss.Left,
+			title,
+			"",
+			strings.Join(lines, "\n"),
+		),
+	)
+
+	return baseStyle.Padding(1, 2).
+		Border(lipgloss.RoundedBorder()).
+		BorderBackground(t.Background()).
+		BorderForeground(t.TextMuted()).
+		Width(lipgloss.Width(content) + 4).
+		Render(content)
+}
+
+func (m *configChangesDialogCmp) BindingKeys() []key.Binding {
+	return layout.KeyMapToSlice(messageDetailsKeys)
+}
+
+// NewConfigChangesCmp creates the dialog listing the changed paths of the
+// configuration, changes being empty when they are unknown
+func NewConfigChangesCmp(changes []config.ConfigChange) ConfigChangesDialog {
+	return &configChangesDialogCmp{
+		changes: changes,
+	}
+}
@@ -1,6 +1,9 @@
 package dialog
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -15,6 +18,8 @@ type InitDialogCmp struct {
 	width, height int
 	selected      int
 	keys          initDialogKeyMap
+	// imports are the tools whose configuration can be imported
+	imports []string
 }
 
 // NewInitDialogCmp creates a new InitDialogCmp.
@@ -112,6 +117,16 @@ func (m InitDialogCmp) View() string {
 		Padding(0, 1).
 		Render("Initialization generates a new Intelligence Interface.md file that contains information about your codebase, this file serves as memory for each project, you can freely add to it to help the agents be better at their job.")
 
+	var importHint string
+	if len(m.imports) > 0 {
+		importHint = baseStyle.
+			Foreground(t.Secondary()).
+			Width(maxWidth).
+			Padding(1, 1, 0).
+			Render(fmt.Sprintf("Found the configuration of %s: run `ii config import-from %s` to import its providers, MCP servers and preferences.",
+				strings.Join(m.imports, ", "), m.imports[0]))
+	}
+
 	question := baseStyle.
 		Foreground(t.Text()).
 		Width(maxWidth).
@@ -154,6 +169,7 @@ func (m InitDialogCmp) View() string {
 		title,
 		baseStyle.Width(maxWidth).Render(""),
 		explanation,
+		importHint,
 		question,
 		buttons,
 		baseStyle.Width(maxWidth).Render(""),
@@ -167,6 +183,11 @@ func (m InitDialogCmp) View() string {
 		Render(content)
 }
 
+// SetImports sets the tools whose configuration the dialog offers to import.
+func (m *InitDialogCmp) SetImports(imports []string) {
+	m.imports = imports
+}
+
 // SetSize sets the size of the component.
 func (m *InitDialogCmp) SetSize(width, height int) {
 	m.width = width
@@ -186,4 +207,7 @@ type CloseInitDialogMsg struct {
 // ShowInitDialogMsg is a message that is sent to show the init dialog.
 type ShowInitDialogMsg struct {
 	Show bool
+	// Imports are the tools whose configuration can be imported, offered on
+	// the first run
+	Imports []string
 }
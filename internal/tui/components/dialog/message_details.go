@@ -0,0 +1,340 @@
+package dialog
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/caronex/intelligence-interface/internal/message"
+	"github.com/caronex/intelligence-interface/internal/tracing"
+	"github.com/caronex/intelligence-interface/internal/tui/layout"
+	"github.com/caronex/intelligence-interface/internal/tui/styles"
+	"github.com/caronex/intelligence-interface/internal/tui/theme"
+	"github.com/caronex/intelligence-interface/internal/tui/util"
+)
+
+// CloseMessageDetailsMsg is sent when the message details dialog is closed
+type CloseMessageDetailsMsg struct{}
+
+// CopyArtifactMsg asks to copy an artifact of a message into the workspace
+type CopyArtifactMsg struct {
+	Message  message.Message
+	Artifact message.Artifact
+}
+
+// OpenArtifactsMsg asks to open the artifact directory of a session
+type OpenArtifactsMsg struct {
+	SessionID string
+}
+
+// ExportPatchMsg asks to export the patch of the workspace changes a message
+// reports into the artifact directory of its session
+type ExportPatchMsg struct {
+	Message message.Message
+}
+
+// MessageDetailsDialog shows a message along with the latency breakdown of
+// the turn that produced it
+type MessageDetailsDialog interface {
+	tea.Model
+	layout.Bindings
+}
+
+type messageDetailsDialogCmp struct {
+	message message.Message
+	trace   *tracing.Trace
+	// selected is the index of the selected artifact, or changed file
+	selected int
+}
+
+type messageDetailsKeyMap struct {
+	Close        key.Binding
+	Previous     key.Binding
+	Next         key.Binding
+	CopyArtifact key.Binding
+	OpenDir      key.Binding
+	ExportPatch  key.Binding
+}
+
+var messageDetailsKeys = messageDetailsKeyMap{
+	Close: key.NewBinding(
+		key.WithKeys("esc", "enter", "q"),
+		key.WithHelp("esc/enter", "close"),
+	),
+	Previous: key.NewBinding(
+		key.WithKeys("up", "k"),
+		key.WithHelp("↑", "previous artifact or file"),
+	),
+	Next: key.NewBinding(
+		key.WithKeys("down", "j"),
+		key.WithHelp("↓", "next artifact or file"),
+	),
+	CopyArtifact: key.NewBinding(
+		key.WithKeys("c"),
+		key.WithHelp("c", "copy artifact to workspace"),
+	),
+	OpenDir: key.NewBinding(
+		key.WithKeys("o"),
+		key.WithHelp("o", "open artifact directory"),
+	),
+	ExportPatch: key.NewBinding(
+		key.WithKeys("p"),
+		key.WithHelp("p", "export the patch of the changes"),
+	),
+}
+
+func (m *messageDetailsDialogCmp) Init() tea.Cmd {
+	return nil
+}
+
+func (m *messageDetailsDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	artifacts := m.message.Artifacts()
+	changes := m.message.WorkspaceChanges()
+	items := len(artifacts)
+	if changes != nil {
+		items = len(changes.Files)
+	}
+	switch {
+	case key.Matches(keyMsg, messageDetailsKeys.Close):
+		return m, util.CmdHandler(CloseMessageDetailsMsg{})
+	case changes != nil && key.Matches(keyMsg, messageDetailsKeys.ExportPatch):
+		return m, util.CmdHandler(ExportPatchMsg{Message: m.message})
+	case items == 0:
+		return m, nil
+	case key.Matches(keyMsg, messageDetailsKeys.Previous):
+		m.selected = max(m.selected-1, 0)
+	case key.Matches(keyMsg, messageDetailsKeys.Next):
+		m.selected = min(m.selected+1, items-1)
+	case len(artifacts) == 0:
+		return m, nil
+	case key.Matches(keyMsg, messageDetailsKeys.CopyArtifact):
+		return m, util.CmdHandler(CopyArtifactMsg{Message: m.message, Artifact: artifacts[m.selected]})
+	case key.Matches(keyMsg, messageDetailsKeys.OpenDir):
+		return m, util.CmdHandler(OpenArtifactsMsg{SessionID: m.message.SessionID})
+	}
+	return m, nil
+}
+
+func (m *messageDetailsDialogCmp) View() string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+
+	title := baseStyle.
+		Foreground(t.Primary()).
+		Bold(true).
+		Render("Message details")
+
+	info := []string{
+		fmt.Sprintf("Role:    %s", m.message.Role),
+		fmt.Sprintf("Created: %s", time.Unix(m.message.CreatedAt, 0).Format(time.DateTime)),
+	}
+	if m.message.Model != "" {
+		info = append(info, fmt.Sprintf("Model:   %s", m.message.Model))
+	}
+
+	breakdown := baseStyle.Foreground(t.TextMuted()).
+		Render("No trace recorded for this turn. Tracing may be disabled,\nor the turn is older than the traced history.")
+	if m.trace != nil {
+		breakdown = baseStyle.Foreground(t.Text()).Render(m.trace.Tree())
+	}
+
+	sections := []string{
+		title,
+		"",
+		baseStyle.Foreground(t.Text()).Render(strings.Join(info, "\n")),
+		"",
+		baseStyle.Foreground(t.Primary()).Render("Turn breakdown"),
+		breakdown,
+	}
+	if finish := m.message.FinishPart(); finish != nil && finish.Error != nil {
+		details := []string{
+			baseStyle.Foreground(t.Error()).Bold(true).Render(finish.Error.Title),
+			baseStyle.Foreground(t.Text()).Render(finish.Message),
+		}
+		if finish.Error.Hint != "" {
+			details = append(details, baseStyle.Foreground(t.Text()).Render(finish.Error.Hint))
+		}
+		details = append(details,
+			"",
+			baseStyle.Foreground(t.TextMuted()).Render(fmt.Sprintf("Category: %s", finish.Error.Category)),
+			baseStyle.Foreground(t.TextMuted()).Width(maxErrorDetailsWidth).Render(truncateQuote(finish.Error.Details)),
+		)
+		sections = append(sections,
+			"",
+			baseStyle.Foreground(t.Primary()).Render("Error"),
+			lipgloss.JoinVertical(lipgloss.Left, details...),
+		)
+	}
+	if review := m.message.Review(); review != nil {
+		details := []string{review.Badge()}
+		if review.Reviewer != "" {
+			details = append(details, fmt.Sprintf("Reviewer:  %s", review.Reviewer))
+		}
+		details = append(details, fmt.Sprintf("Revisions: %d", review.Revisions))
+		if review.BudgetExceeded {
+			details = append(details, "Stopped at the pass token budget")
+		}
+		if review.Summary != "" {
+			details = append(details, "", review.Summary)
+		}
+		if review.Critique != "" {
+			details = append(details, "", review.Critique)
+		}
+		sections = append(sections,
+			"",
+			baseStyle.Foreground(t.Primary()).Render("Review"),
+			baseStyle.Foreground(t.Text()).Render(strings.Join(details, "\n")),
+		)
+	}
+
+	if citations := m.message.Citations(); len(citations) > 0 {
+		sources := make([]string, 0, len(citations))
+		for _, citation := range citations {
+			heading := fmt.Sprintf("[%d] %s", citation.Index, citation.Label())
+			if citation.Score > 0 {
+				heading += fmt.Sprintf(" (score %.2f)", citation.Score)
+			}
+			sources = append(sources,
+				baseStyle.Foreground(t.Text()).Bold(true).Render(heading),
+				baseStyle.Foreground(t.TextMuted()).Render(truncateQuote(citation.Quote)),
+			)
+		}
+		sections = append(sections,
+			"",
+			baseStyle.Foreground(t.Primary()).Render("Sources"),
+			lipgloss.JoinVertical(lipgloss.Left, sources...),
+		)
+	}
+
+	if artifacts := m.message.Artifacts(); len(artifacts) > 0 {
+		lines := make([]string, 0, len(artifacts)+2)
+		for i, a := range artifacts {
+			line := fmt.Sprintf("  %s (%d bytes)", a.Name, a.Size)
+			if a.Hint != "" && a.Hint != a.Name {
+				line += " → " + a.Hint
+			}
+			style := baseStyle.Foreground(t.Text())
+			if i == m.selected {
+				line = "›" + line[1:]
+				style = style.Foreground(t.Primary()).Bold(true)
+			}
+			lines = append(lines, style.Render(line))
+		}
+		lines = append(lines, "", baseStyle.Foreground(t.TextMuted()).Render("c copy to workspace · o open artifact directory"))
+		sections = append(sections,
+			"",
+			baseStyle.Foreground(t.Primary()).Render("Artifacts"),
+			lipgloss.JoinVertical(lipgloss.Left, lines...),
+		)
+	}
+
+	if changes := m.message.WorkspaceChanges(); changes != nil {
+		lines := make([]string, 0, len(changes.Files)+4)
+		for i, file := range changes.Files {
+			line := fmt.Sprintf("  %s %s (+%d -%d)", file.Change, file.Path, file.Additions, file.Removals)
+			style := baseStyle.Foreground(t.Text())
+			if i == m.selected {
+				line = "›" + line[1:]
+				style = style.Foreground(t.Primary()).Bold(true)
+			}
+			lines = append(lines, style.Render(line))
+		}
+		if m.selected < len(changes.Files) {
+			lines = append(lines, "", renderFileDiff(changes.Files[m.selected]))
+		}
+		lines = append(lines, "", baseStyle.Foreground(t.TextMuted()).Render("↑/↓ select a file · p export the patch to the artifact directory"))
+		sections = append(sections,
+			"",
+			baseStyle.Foreground(t.Primary()).Render("Workspace changes"),
+			lipgloss.JoinVertical(lipgloss.Left, lines...),
+		)
+	}
+
+	content := baseStyle.Render(lipgloss.JoinVertical(lipgloss.Left, sections...))
+
+	return baseStyle.Padding(1, 2).
+		Border(lipgloss.RoundedBorder()).
+		BorderBackground(t.Background()).
+		BorderForeground(t.TextMuted()).
+		Width(lipgloss.Width(content) + 4).
+		Render(content)
+}
+
+// maxQuoteLines bounds the lines of a quote shown in the details
+const maxQuoteLines = 20
+
+// maxErrorDetailsWidth wraps the raw errors, which are often a single line of
+// JSON
+const maxErrorDetailsWidth = 80
+
+// maxDiffLineWidth cuts the long lines of the diffs, which are not wrapped so
+// that they stay aligned
+const maxDiffLineWidth = 100
+
+// renderFileDiff renders the diff of a changed file, its lines colored by
+// kind and cut to the quote bound
+func renderFileDiff(file message.FileChange) string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+	if file.Diff == "" {
+		text := "No diff, the file is too large to compare"
+		if file.Binary {
+			text = "No diff, the file is binary"
+		}
+		return baseStyle.Foreground(t.TextMuted()).Render(text)
+	}
+
+	diff := truncateQuote(file.Diff)
+	if file.DiffCut && !strings.Contains(diff, "\n… ") {
+		diff += "\n… cut, the patch holds the full diff"
+	}
+	lines := strings.Split(diff, "\n")
+	for i, line := range lines {
+		if runes := []rune(line); len(runes) > maxDiffLineWidth {
+			line = string(runes[:maxDiffLineWidth-1]) + "…"
+		}
+		color := t.TextMuted()
+		switch {
+		case strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++"):
+			color = t.Success()
+		case strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "---"):
+			color = t.Error()
+		case strings.HasPrefix(line, "@@"):
+			color = t.Accent()
+		case strings.HasPrefix(line, " "):
+			color = t.Text()
+		}
+		lines[i] = baseStyle.Foreground(color).Render(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// truncateQuote keeps the first lines of a long quote
+func truncateQuote(quote string) string {
+	lines := strings.Split(strings.TrimRight(quote, "\n"), "\n")
+	if len(lines) <= maxQuoteLines {
+		return strings.Join(lines, "\n")
+	}
+	return strings.Join(lines[:maxQuoteLines], "\n") + fmt.Sprintf("\n… %d more lines", len(lines)-maxQuoteLines)
+}
+
+func (m *messageDetailsDialogCmp) BindingKeys() []key.Binding {
+	return layout.KeyMapToSlice(messageDetailsKeys)
+}
+
+// NewMessageDetailsCmp creates the details dialog of a message, trace is nil
+// when the turn was not traced
+func NewMessageDetailsCmp(msg message.Message, trace *tracing.Trace) MessageDetailsDialog {
+	return &messageDetailsDialogCmp{
+		message: msg,
+		trace:   trace,
+	}
+}
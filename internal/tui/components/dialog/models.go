@@ -288,27 +288,13 @@ func GetSelectedModel(cfg *config.Config) models.Model {
 }
 
 func getEnabledProviders(cfg *config.Config) []models.ModelProvider {
+	// SupportedProviders is already ordered by provider popularity
 	var providers []models.ModelProvider
-	for providerId, provider := range cfg.Providers {
-		if !provider.Disabled {
+	for _, providerId := range models.SupportedProviders() {
+		if provider, ok := cfg.Providers[providerId]; ok && !provider.Disabled {
 			providers = append(providers, providerId)
 		}
 	}
-
-	// Sort by provider popularity
-	slices.SortFunc(providers, func(a, b models.ModelProvider) int {
-		rA := models.ProviderPopularity[a]
-		rB := models.ProviderPopularity[b]
-
-		// models not included in popularity ranking default to last
-		if rA == 0 {
-			rA = 999
-		}
-		if rB == 0 {
-			rB = 999
-		}
-		return rA - rB
-	})
 	return providers
 }
 
@@ -348,22 +334,10 @@ func (m *modelDialogCmp) setupModelsForProvider(provider models.ModelProvider) {
 }
 
 func getModelsForProvider(provider models.ModelProvider) []models.Model {
-	var providerModels []models.Model
-	for _, model := range models.SupportedModels {
-		if model.Provider == provider {
-			providerModels = append(providerModels, model)
-		}
-	}
+	providerModels := models.ModelsForProvider(provider)
 
 	// reverse alphabetical order (if llm naming was consistent latest would appear first)
-	slices.SortFunc(providerModels, func(a, b models.Model) int {
-		if a.Name > b.Name {
-			return -1
-		} else if a.Name < b.Name {
-			return 1
-		}
-		return 0
-	})
+	slices.Reverse(providerModels)
 
 	return providerModels
 }
@@ -0,0 +1,342 @@
+package dialog
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/caronex/intelligence-interface/internal/notes"
+	"github.com/caronex/intelligence-interface/internal/tui/layout"
+	"github.com/caronex/intelligence-interface/internal/tui/styles"
+	"github.com/caronex/intelligence-interface/internal/tui/theme"
+	"github.com/caronex/intelligence-interface/internal/tui/util"
+)
+
+// CloseNotesDialogMsg is sent when the notes dialog is closed
+type CloseNotesDialogMsg struct{}
+
+// NotesDialog browses the notes of the workspace memory, to edit, pin and
+// delete them or write new ones
+type NotesDialog interface {
+	tea.Model
+	layout.Bindings
+}
+
+type notesDialogCmp struct {
+	notes       []notes.Note
+	selectedIdx int
+	// editing is set while the content of a note is edited in input, editID
+	// being empty for a new note
+	editing bool
+	editID  string
+	input   textinput.Model
+}
+
+type notesKeyMap struct {
+	Up     key.Binding
+	Down   key.Binding
+	New    key.Binding
+	Edit   key.Binding
+	Pin    key.Binding
+	Delete key.Binding
+	Escape key.Binding
+}
+
+var notesKeys = notesKeyMap{
+	Up: key.NewBinding(
+		key.WithKeys("up", "k"),
+		key.WithHelp("↑/k", "previous note"),
+	),
+	Down: key.NewBinding(
+		key.WithKeys("down", "j"),
+		key.WithHelp("↓/j", "next note"),
+	),
+	New: key.NewBinding(
+		key.WithKeys("n"),
+		key.WithHelp("n", "new note"),
+	),
+	Edit: key.NewBinding(
+		key.WithKeys("e", "enter"),
+		key.WithHelp("e", "edit note"),
+	),
+	Pin: key.NewBinding(
+		key.WithKeys("p"),
+		key.WithHelp("p", "pin or unpin note"),
+	),
+	Delete: key.NewBinding(
+		key.WithKeys("d"),
+		key.WithHelp("d", "delete note"),
+	),
+	Escape: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "close"),
+	),
+}
+
+var notesEditKeys = struct {
+	Save   key.Binding
+	Cancel key.Binding
+}{
+	Save: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("enter", "save note"),
+	),
+	Cancel: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "cancel"),
+	),
+}
+
+// notesDialogWidth is the width of the notes list
+const notesDialogWidth = 70
+
+func (m *notesDialogCmp) Init() tea.Cmd {
+	return nil
+}
+
+func (m *notesDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		if m.editing {
+			return m, m.updateEdit(msg)
+		}
+		switch {
+		case key.Matches(msg, notesKeys.Escape):
+			return m, util.CmdHandler(CloseNotesDialogMsg{})
+		case key.Matches(msg, notesKeys.Up):
+			if m.selectedIdx > 0 {
+				m.selectedIdx--
+			}
+		case key.Matches(msg, notesKeys.Down):
+			if m.selectedIdx < len(m.notes)-1 {
+				m.selectedIdx++
+			}
+		case key.Matches(msg, notesKeys.New):
+			return m, m.startEdit("", "")
+		case key.Matches(msg, notesKeys.Edit):
+			if note, ok := m.selected(); ok {
+				return m, m.startEdit(note.ID, note.Content)
+			}
+		case key.Matches(msg, notesKeys.Pin):
+			if note, ok := m.selected(); ok {
+				note.Pinned = !note.Pinned
+				if _, err := notes.Update(note); err != nil {
+					return m, util.ReportError(err)
+				}
+				return m, m.reload(note.ID)
+			}
+		case key.Matches(msg, notesKeys.Delete):
+			if note, ok := m.selected(); ok {
+				if err := notes.Delete(note.ID); err != nil {
+					return m, util.ReportError(err)
+				}
+				return m, m.reload("")
+			}
+		}
+	}
+	return m, nil
+}
+
+// updateEdit handles the keys while a note is edited
+func (m *notesDialogCmp) updateEdit(msg tea.KeyMsg) tea.Cmd {
+	switch {
+	case key.Matches(msg, notesEditKeys.Cancel):
+		m.editing = false
+		return nil
+	case key.Matches(msg, notesEditKeys.Save):
+		content := m.input.Value()
+		var err error
+		if m.editID == "" {
+			var note notes.Note
+			note, err = notes.Add(notes.Note{Content: content})
+			m.editID = note.ID
+		} else if note, ok := m.find(m.editID); ok {
+			note.Content = content
+			_, err = notes.Update(note)
+		}
+		if err != nil {
+			return util.ReportError(err)
+		}
+		m.editing = false
+		return m.reload(m.editID)
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return cmd
+}
+
+// startEdit edits content in the input, for the note id or a new note when
+// id is empty
+func (m *notesDialogCmp) startEdit(id, content string) tea.Cmd {
+	t := theme.CurrentTheme()
+	m.input = textinput.New()
+	m.input.Placeholder = "A decision, convention or gotcha of the project..."
+	m.input.Prompt = ""
+	m.input.Width = notesDialogWidth - 2
+	m.input.CharLimit = notes.MaxNoteBytes
+	m.input.PlaceholderStyle = m.input.PlaceholderStyle.Background(t.Background())
+	m.input.TextStyle = m.input.TextStyle.Background(t.Background()).Foreground(t.Primary())
+	m.input.SetValue(content)
+	m.input.Focus()
+	m.editing = true
+	m.editID = id
+	return textinput.Blink
+}
+
+// reload reads the notes again, selecting the note id when it is still there
+func (m *notesDialogCmp) reload(id string) tea.Cmd {
+	list, err := notes.List()
+	if err != nil {
+		return util.ReportError(err)
+	}
+	m.notes = list
+	for i, note := range list {
+		if note.ID == id {
+			m.selectedIdx = i
+			return nil
+		}
+	}
+	m.selectedIdx = min(m.selectedIdx, max(len(list)-1, 0))
+	return nil
+}
+
+func (m *notesDialogCmp) selected() (notes.Note, bool) {
+	if m.selectedIdx >= len(m.notes) {
+		return notes.Note{}, false
+	}
+	return m.notes[m.selectedIdx], true
+}
+
+func (m *notesDialogCmp) find(id string) (notes.Note, bool) {
+	for _, note := range m.notes {
+		if note.ID == id {
+			return note, true
+		}
+	}
+	return notes.Note{}, false
+}
+
+func (m *notesDialogCmp) View() string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+
+	title := baseStyle.
+		Foreground(t.Primary()).
+		Bold(true).
+		Width(notesDialogWidth).
+		Render("Workspace Memory")
+
+	var body []string
+	if len(m.notes) == 0 {
+		body = append(body, baseStyle.Width(notesDialogWidth).Foreground(t.TextMuted()).Render("No notes yet, press n to write one."))
+	}
+	startIdx, endIdx := m.visibleRange()
+	for i := startIdx; i < endIdx; i++ {
+		note := m.notes[i]
+		content := note.Content
+		if note.Pinned {
+			content = "★ " + content
+		}
+		itemStyle := baseStyle.Width(notesDialogWidth).Foreground(t.Text())
+		if i == m.selectedIdx {
+			itemStyle = itemStyle.Background(t.Primary()).Foreground(t.Background()).Bold(true)
+		}
+		body = append(body,
+			itemStyle.Render(truncateNote(content, notesDialogWidth)),
+			baseStyle.Width(notesDialogWidth).Foreground(t.TextMuted()).Render(noteDetails(note)),
+		)
+	}
+
+	if m.editing {
+		label := "Edit note"
+		if m.editID == "" {
+			label = "New note"
+		}
+		body = append(body,
+			"",
+			baseStyle.Width(notesDialogWidth).Foreground(t.TextMuted()).Render(label+" (enter to save, esc to cancel)"),
+			baseStyle.Width(notesDialogWidth).Render(m.input.View()),
+		)
+	} else {
+		// The dialog takes every key, so the help does not show its bindings
+		body = append(body,
+			"",
+			baseStyle.Width(notesDialogWidth).Foreground(t.TextMuted()).Render("n new · e edit · p pin · d delete · esc close"),
+		)
+	}
+
+	content := baseStyle.Render(
+		lipgloss.JoinVertical(
+			lipgloss.Left,
+			title,
+			"",
+			lipgloss.JoinVertical(lipgloss.Left, body...),
+		),
+	)
+
+	return baseStyle.Padding(1, 2).
+		Border(lipgloss.RoundedBorder()).
+		BorderBackground(t.Background()).
+		BorderForeground(t.TextMuted()).
+		Width(lipgloss.Width(content) + 4).
+		Render(content)
+}
+
+// visibleRange returns the indexes of the notes shown in the list, limited to
+// avoid taking up too much screen space
+func (m *notesDialogCmp) visibleRange() (int, int) {
+	maxVisible := min(8, len(m.notes))
+	startIdx := 0
+	if len(m.notes) > maxVisible {
+		startIdx = util.Clamp(m.selectedIdx-maxVisible/2, 0, len(m.notes)-maxVisible)
+	}
+	return startIdx, startIdx + maxVisible
+}
+
+// noteDetails renders the tags, author and dates of a note
+func noteDetails(note notes.Note) string {
+	details := []string{
+		fmt.Sprintf("  by %s, %s", note.Author(), time.Unix(note.UpdatedAt, 0).Format("2006-01-02 15:04")),
+	}
+	if len(note.Tags) > 0 {
+		details = append(details, "#"+strings.Join(note.Tags, " #"))
+	}
+	if note.ExpiresAt != 0 {
+		details = append(details, "expires "+time.Unix(note.ExpiresAt, 0).Format("2006-01-02"))
+	}
+	return strings.Join(details, " · ")
+}
+
+// truncateNote keeps the first line of content within width
+func truncateNote(content string, width int) string {
+	content, _, multiline := strings.Cut(content, "\n")
+	runes := []rune(content)
+	if len(runes) > width-1 {
+		return string(runes[:width-2]) + "…"
+	}
+	if multiline {
+		return content + " …"
+	}
+	return content
+}
+
+func (m *notesDialogCmp) BindingKeys() []key.Binding {
+	if m.editing {
+		return []key.Binding{notesEditKeys.Save, notesEditKeys.Cancel}
+	}
+	return layout.KeyMapToSlice(notesKeys)
+}
+
+// NewNotesDialogCmp creates the dialog of the workspace memory, listing its
+// notes pinned first
+func NewNotesDialogCmp() (NotesDialog, error) {
+	list, err := notes.List()
+	if err != nil {
+		return nil, err
+	}
+	return &notesDialogCmp{notes: list}, nil
+}
@@ -273,6 +273,12 @@ func (p *permissionDialogCmp) renderBashContent() string {
 
 	if pr, ok := p.permission.Params.(tools.BashPermissionsParams); ok {
 		content := fmt.Sprintf("```bash\n%s\n```", pr.Command)
+		if len(pr.Warnings) > 0 {
+			content += "\n\n**Shell guard warnings**\n"
+			for _, warning := range pr.Warnings {
+				content += "\n- " + warning
+			}
+		}
 
 		// Use the cache for markdown rendering
 		renderedContent := p.GetOrSetMarkdown(p.permission.ID, func() (string, error) {
@@ -1,9 +1,15 @@
 package dialog
 
 import (
+	"fmt"
+	"slices"
+	"strings"
+
 	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	zone "github.com/lrstanley/bubblezone"
 	"github.com/caronex/intelligence-interface/internal/session"
 	"github.com/caronex/intelligence-interface/internal/tui/layout"
 	"github.com/caronex/intelligence-interface/internal/tui/styles"
@@ -19,12 +25,37 @@ type SessionSelectedMsg struct {
 // CloseSessionDialogMsg is sent when the session dialog is closed
 type CloseSessionDialogMsg struct{}
 
+// SessionFilterChangedMsg is sent when the search or the archived toggle of
+// the session dialog changes, to list the sessions matching Filter
+type SessionFilterChangedMsg struct {
+	Filter session.Filter
+}
+
+// ArchiveSessionsMsg is sent to archive or unarchive the sessions chosen in
+// the session dialog
+type ArchiveSessionsMsg struct {
+	IDs      []string
+	Archived bool
+}
+
+// DeleteSessionsMsg is sent to delete the sessions chosen in the session
+// dialog. The dry run is answered with SetDeleteReport, asking to confirm.
+type DeleteSessionsMsg struct {
+	IDs    []string
+	DryRun bool
+}
+
 // SessionDialog interface for the session switching dialog
 type SessionDialog interface {
 	tea.Model
 	layout.Bindings
 	SetSessions(sessions []session.Session)
 	SetSelectedSession(sessionID string)
+	// Filter returns the filter of the sessions listed
+	Filter() session.Filter
+	// SetDeleteReport asks to confirm the deletion of the sessions, showing
+	// what it deletes
+	SetDeleteReport(ids []string, report session.DeleteReport)
 }
 
 type sessionDialogCmp struct {
@@ -33,15 +64,30 @@ type sessionDialogCmp struct {
 	width             int
 	height            int
 	selectedSessionID string
+
+	search       textinput.Model
+	searchErr    error
+	showArchived bool
+	// marked are the sessions selected for a bulk operation
+	marked map[string]bool
+	// confirmIDs are the sessions whose deletion awaits a confirmation
+	confirmIDs    []string
+	confirmReport session.DeleteReport
 }
 
 type sessionKeyMap struct {
-	Up     key.Binding
-	Down   key.Binding
-	Enter  key.Binding
-	Escape key.Binding
-	J      key.Binding
-	K      key.Binding
+	Up           key.Binding
+	Down         key.Binding
+	Enter        key.Binding
+	Escape       key.Binding
+	J            key.Binding
+	K            key.Binding
+	Search       key.Binding
+	Mark         key.Binding
+	MarkAll      key.Binding
+	Archive      key.Binding
+	Delete       key.Binding
+	ShowArchived key.Binding
 }
 
 var sessionKeys = sessionKeyMap{
@@ -69,6 +115,30 @@ var sessionKeys = sessionKeyMap{
 		key.WithKeys("k"),
 		key.WithHelp("k", "previous session"),
 	),
+	Search: key.NewBinding(
+		key.WithKeys("/"),
+		key.WithHelp("/", "search sessions"),
+	),
+	Mark: key.NewBinding(
+		key.WithKeys(" "),
+		key.WithHelp("space", "mark session"),
+	),
+	MarkAll: key.NewBinding(
+		key.WithKeys("*"),
+		key.WithHelp("*", "mark all sessions"),
+	),
+	Archive: key.NewBinding(
+		key.WithKeys("a"),
+		key.WithHelp("a", "archive sessions"),
+	),
+	Delete: key.NewBinding(
+		key.WithKeys("d"),
+		key.WithHelp("d", "delete sessions"),
+	),
+	ShowArchived: key.NewBinding(
+		key.WithKeys("tab"),
+		key.WithHelp("tab", "show archived sessions"),
+	),
 }
 
 func (s *sessionDialogCmp) Init() tea.Cmd {
@@ -78,7 +148,52 @@ func (s *sessionDialogCmp) Init() tea.Cmd {
 func (s *sessionDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if s.confirmIDs != nil {
+			return s, s.confirmDelete(msg)
+		}
+		if s.search.Focused() {
+			return s, s.updateSearch(msg)
+		}
 		switch {
+		case key.Matches(msg, sessionKeys.Search):
+			return s, s.search.Focus()
+		case key.Matches(msg, sessionKeys.ShowArchived):
+			s.showArchived = !s.showArchived
+			return s, s.filterChanged()
+		case key.Matches(msg, sessionKeys.Mark):
+			if len(s.sessions) > 0 {
+				id := s.sessions[s.selectedIdx].ID
+				if s.marked[id] {
+					delete(s.marked, id)
+				} else {
+					s.marked[id] = true
+				}
+			}
+			return s, nil
+		case key.Matches(msg, sessionKeys.MarkAll):
+			if len(s.marked) == len(s.sessions) {
+				clear(s.marked)
+			} else {
+				for _, sess := range s.sessions {
+					s.marked[sess.ID] = true
+				}
+			}
+			return s, nil
+		case key.Matches(msg, sessionKeys.Archive):
+			targets := s.targets()
+			if len(targets) == 0 {
+				return s, nil
+			}
+			// Archived sessions are unarchived, the others archived
+			archived := !slices.ContainsFunc(s.sessions, func(sess session.Session) bool {
+				return slices.Contains(targets, sess.ID) && sess.Archived
+			})
+			return s, util.CmdHandler(ArchiveSessionsMsg{IDs: targets, Archived: archived})
+		case key.Matches(msg, sessionKeys.Delete):
+			if targets := s.targets(); len(targets) > 0 {
+				return s, util.CmdHandler(DeleteSessionsMsg{IDs: targets, DryRun: true})
+			}
+			return s, nil
 		case key.Matches(msg, sessionKeys.Up) || key.Matches(msg, sessionKeys.K):
 			if s.selectedIdx > 0 {
 				s.selectedIdx--
@@ -98,55 +213,148 @@ func (s *sessionDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, sessionKeys.Escape):
 			return s, util.CmdHandler(CloseSessionDialogMsg{})
 		}
+	case tea.MouseMsg:
+		startIdx, endIdx := s.visibleRange()
+		for i := startIdx; i < endIdx; i++ {
+			if util.Clicked(msg, sessionZoneID(i)) {
+				s.selectedIdx = i
+				return s, util.CmdHandler(SessionSelectedMsg{
+					Session: s.sessions[i],
+				})
+			}
+		}
 	case tea.WindowSizeMsg:
 		s.width = msg.Width
 		s.height = msg.Height
+	default:
+		// Keep the search cursor blinking
+		var cmd tea.Cmd
+		s.search, cmd = s.search.Update(msg)
+		return s, cmd
 	}
 	return s, nil
 }
 
+// updateSearch types a key into the search. Enter leaves the search keeping
+// its filter and esc clears it.
+func (s *sessionDialogCmp) updateSearch(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyEnter:
+		s.search.Blur()
+		return nil
+	case tea.KeyEsc:
+		s.search.Blur()
+		if s.search.Value() == "" {
+			return nil
+		}
+		s.search.Reset()
+		return s.filterChanged()
+	case tea.KeyUp:
+		if s.selectedIdx > 0 {
+			s.selectedIdx--
+		}
+		return nil
+	case tea.KeyDown:
+		if s.selectedIdx < len(s.sessions)-1 {
+			s.selectedIdx++
+		}
+		return nil
+	}
+	query := s.search.Value()
+	var cmd tea.Cmd
+	s.search, cmd = s.search.Update(msg)
+	if s.search.Value() == query {
+		return cmd
+	}
+	return tea.Batch(cmd, s.filterChanged())
+}
+
+// filterChanged asks for the sessions of the filter, unless the search does
+// not parse
+func (s *sessionDialogCmp) filterChanged() tea.Cmd {
+	if _, err := session.ParseFilter(s.search.Value()); err != nil {
+		s.searchErr = err
+		return nil
+	}
+	s.searchErr = nil
+	return util.CmdHandler(SessionFilterChangedMsg{Filter: s.Filter()})
+}
+
+// confirmDelete deletes the sessions awaiting a confirmation on y, and
+// cancels the deletion on any other key
+func (s *sessionDialogCmp) confirmDelete(msg tea.KeyMsg) tea.Cmd {
+	ids := s.confirmIDs
+	s.confirmIDs = nil
+	if msg.String() != "y" {
+		return nil
+	}
+	clear(s.marked)
+	return util.CmdHandler(DeleteSessionsMsg{IDs: ids})
+}
+
+// targets returns the sessions of a bulk operation: the marked ones, or else
+// the highlighted one
+func (s *sessionDialogCmp) targets() []string {
+	var ids []string
+	for _, sess := range s.sessions {
+		if s.marked[sess.ID] {
+			ids = append(ids, sess.ID)
+		}
+	}
+	if len(ids) == 0 && len(s.sessions) > 0 {
+		ids = append(ids, s.sessions[s.selectedIdx].ID)
+	}
+	return ids
+}
+
+func (s *sessionDialogCmp) Filter() session.Filter {
+	filter, _ := session.ParseFilter(s.search.Value())
+	// Archived sessions are hidden from the list, but found by searches
+	filter.IncludeArchived = s.showArchived || s.search.Value() != ""
+	return filter
+}
+
+func (s *sessionDialogCmp) SetDeleteReport(ids []string, report session.DeleteReport) {
+	s.confirmIDs = ids
+	s.confirmReport = report
+}
+
+// sessionLabel is the line of a session in the list
+func (s *sessionDialogCmp) sessionLabel(sess session.Session) string {
+	label := sess.Title
+	if len(s.marked) > 0 {
+		if s.marked[sess.ID] {
+			label = "[x] " + label
+		} else {
+			label = "[ ] " + label
+		}
+	}
+	if len(sess.Tags) > 0 {
+		label += "  #" + strings.Join(sess.Tags, " #")
+	}
+	if sess.Archived {
+		label += "  (archived)"
+	}
+	return label
+}
+
 func (s *sessionDialogCmp) View() string {
 	t := theme.CurrentTheme()
 	baseStyle := styles.BaseStyle()
 	
-	if len(s.sessions) == 0 {
-		return baseStyle.Padding(1, 2).
-			Border(lipgloss.RoundedBorder()).
-			BorderBackground(t.Background()).
-			BorderForeground(t.TextMuted()).
-			Width(40).
-			Render("No sessions available")
-	}
-
 	// Calculate max width needed for session titles
 	maxWidth := 40 // Minimum width
 	for _, sess := range s.sessions {
-		if len(sess.Title) > maxWidth-4 { // Account for padding
-			maxWidth = len(sess.Title) + 4
+		if label := s.sessionLabel(sess); len(label) > maxWidth-4 { // Account for padding
+			maxWidth = len(label) + 4
 		}
 	}
 
 	maxWidth = max(30, min(maxWidth, s.width-15)) // Limit width to avoid overflow
 
-	// Limit height to avoid taking up too much screen space
-	maxVisibleSessions := min(10, len(s.sessions))
-
 	// Build the session list
-	sessionItems := make([]string, 0, maxVisibleSessions)
-	startIdx := 0
-
-	// If we have more sessions than can be displayed, adjust the start index
-	if len(s.sessions) > maxVisibleSessions {
-		// Center the selected item when possible
-		halfVisible := maxVisibleSessions / 2
-		if s.selectedIdx >= halfVisible && s.selectedIdx < len(s.sessions)-halfVisible {
-			startIdx = s.selectedIdx - halfVisible
-		} else if s.selectedIdx >= len(s.sessions)-halfVisible {
-			startIdx = len(s.sessions) - maxVisibleSessions
-		}
-	}
-
-	endIdx := min(startIdx+maxVisibleSessions, len(s.sessions))
+	startIdx, endIdx := s.visibleRange()
+	sessionItems := make([]string, 0, endIdx-startIdx)
 
 	for i := startIdx; i < endIdx; i++ {
 		sess := s.sessions[i]
@@ -159,22 +367,50 @@ func (s *sessionDialogCmp) View() string {
 				Bold(true)
 		}
 
-		sessionItems = append(sessionItems, itemStyle.Padding(0, 1).Render(sess.Title))
+		sessionItems = append(sessionItems, zone.Mark(sessionZoneID(i), itemStyle.Padding(0, 1).Render(s.sessionLabel(sess))))
+	}
+	if len(sessionItems) == 0 {
+		sessionItems = append(sessionItems, baseStyle.Width(maxWidth).Padding(0, 1).Foreground(t.TextMuted()).Render("No matching sessions"))
 	}
 
+	titleText := "Switch Session"
+	if s.showArchived {
+		titleText += " (archived shown)"
+	}
+	if len(s.marked) > 0 {
+		titleText += fmt.Sprintf(" · %d marked", len(s.marked))
+	}
 	title := baseStyle.
 		Foreground(t.Primary()).
 		Bold(true).
 		Width(maxWidth).
 		Padding(0, 1).
-		Render("Switch Session")
+		Render(titleText)
+
+	s.search.Width = maxWidth - 4
+	search := baseStyle.Width(maxWidth).Padding(0, 1).Render(s.search.View())
+
+	footer := baseStyle.Width(maxWidth).Padding(0, 1).Foreground(t.TextMuted()).
+		Render("/ search · space mark · * all · a archive · d delete · tab archived")
+	switch {
+	case s.confirmIDs != nil:
+		r := s.confirmReport
+		footer = baseStyle.Width(maxWidth).Padding(0, 1).Foreground(t.Warning()).
+			Render(fmt.Sprintf("Delete %d sessions with %d messages, %d tool operations, %d artifacts and %d audit entries? y/n",
+				r.Sessions, r.Messages, r.ToolOperations, r.Artifacts, r.AuditEntries))
+	case s.searchErr != nil:
+		footer = baseStyle.Width(maxWidth).Padding(0, 1).Foreground(t.Error()).Render(s.searchErr.Error())
+	}
 
 	content := lipgloss.JoinVertical(
 		lipgloss.Left,
 		title,
 		baseStyle.Width(maxWidth).Render(""),
+		search,
+		baseStyle.Width(maxWidth).Render(""),
 		baseStyle.Width(maxWidth).Render(lipgloss.JoinVertical(lipgloss.Left, sessionItems...)),
 		baseStyle.Width(maxWidth).Render(""),
+		footer,
 	)
 
 	return baseStyle.Padding(1, 2).
@@ -185,12 +421,43 @@ func (s *sessionDialogCmp) View() string {
 		Render(content)
 }
 
+// visibleRange returns the indexes of the sessions shown in the list, limited
+// to avoid taking up too much screen space
+func (s *sessionDialogCmp) visibleRange() (int, int) {
+	maxVisibleSessions := min(10, len(s.sessions))
+	startIdx := 0
+
+	// If we have more sessions than can be displayed, adjust the start index
+	if len(s.sessions) > maxVisibleSessions {
+		// Center the selected item when possible
+		halfVisible := maxVisibleSessions / 2
+		if s.selectedIdx >= halfVisible && s.selectedIdx < len(s.sessions)-halfVisible {
+			startIdx = s.selectedIdx - halfVisible
+		} else if s.selectedIdx >= len(s.sessions)-halfVisible {
+			startIdx = len(s.sessions) - maxVisibleSessions
+		}
+	}
+
+	return startIdx, min(startIdx+maxVisibleSessions, len(s.sessions))
+}
+
+// sessionZoneID is the mouse zone of the session at index i of the list
+func sessionZoneID(i int) string {
+	return fmt.Sprintf("session-%d", i)
+}
+
 func (s *sessionDialogCmp) BindingKeys() []key.Binding {
 	return layout.KeyMapToSlice(sessionKeys)
 }
 
 func (s *sessionDialogCmp) SetSessions(sessions []session.Session) {
 	s.sessions = sessions
+	// Sessions no longer listed are no longer marked
+	for id := range s.marked {
+		if !slices.ContainsFunc(sessions, func(sess session.Session) bool { return sess.ID == id }) {
+			delete(s.marked, id)
+		}
+	}
 
 	// If we have a selected session ID, find its index
 	if s.selectedSessionID != "" {
@@ -222,9 +489,19 @@ func (s *sessionDialogCmp) SetSelectedSession(sessionID string) {
 
 // NewSessionDialogCmp creates a new session switching dialog
 func NewSessionDialogCmp() SessionDialog {
+	t := theme.CurrentTheme()
+	search := textinput.New()
+	search.Placeholder = "/ to search: words, older:90d, agent:coder, tag:spike, empty"
+	search.Prompt = "/ "
+	search.PlaceholderStyle = search.PlaceholderStyle.Background(t.Background()).Foreground(t.TextMuted())
+	search.PromptStyle = search.PromptStyle.Background(t.Background()).Foreground(t.Primary())
+	search.TextStyle = search.TextStyle.Background(t.Background())
+
 	return &sessionDialogCmp{
 		sessions:          []session.Session{},
 		selectedIdx:       0,
 		selectedSessionID: "",
+		search:            search,
+		marked:            make(map[string]bool),
 	}
 }
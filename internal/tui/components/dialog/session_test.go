@@ -0,0 +1,111 @@
+package dialog
+
+import (
+	"reflect"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/caronex/intelligence-interface/internal/session"
+)
+
+// runeKey is the key message of typing r
+func runeKey(r rune) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}}
+}
+
+// sessionMsgs runs cmd and returns the messages it sends, batched or not
+func sessionMsgs(cmd tea.Cmd) []tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+	msg := cmd()
+	batch, ok := msg.(tea.BatchMsg)
+	if !ok {
+		return []tea.Msg{msg}
+	}
+	var msgs []tea.Msg
+	for _, c := range batch {
+		msgs = append(msgs, sessionMsgs(c)...)
+	}
+	return msgs
+}
+
+func TestSessionDialogBulkOperations(t *testing.T) {
+	d := NewSessionDialogCmp()
+	d.SetSessions([]session.Session{
+		{ID: "a", Title: "First"},
+		{ID: "b", Title: "Second", Archived: true},
+		{ID: "c", Title: "Third"},
+	})
+
+	// Without marks, the highlighted session is archived
+	_, cmd := d.Update(runeKey('a'))
+	if msgs := sessionMsgs(cmd); len(msgs) != 1 || !reflect.DeepEqual(msgs[0], ArchiveSessionsMsg{IDs: []string{"a"}, Archived: true}) {
+		t.Errorf("a sent %+v, want the highlighted session archived", msgs)
+	}
+
+	// Marked sessions are the targets, and unarchived when all are archived
+	d.Update(runeKey('j'))
+	d.Update(tea.KeyMsg{Type: tea.KeySpace})
+	_, cmd = d.Update(runeKey('a'))
+	if msgs := sessionMsgs(cmd); len(msgs) != 1 || !reflect.DeepEqual(msgs[0], ArchiveSessionsMsg{IDs: []string{"b"}, Archived: false}) {
+		t.Errorf("a sent %+v, want the marked archived session unarchived", msgs)
+	}
+
+	// Deleting asks for a dry run, then a confirmation
+	d.Update(runeKey('*'))
+	_, cmd = d.Update(runeKey('d'))
+	want := DeleteSessionsMsg{IDs: []string{"a", "b", "c"}, DryRun: true}
+	if msgs := sessionMsgs(cmd); len(msgs) != 1 || !reflect.DeepEqual(msgs[0], want) {
+		t.Fatalf("d sent %+v, want a dry run of the marked sessions", msgs)
+	}
+	d.SetDeleteReport(want.IDs, session.DeleteReport{Sessions: 3, Messages: 12})
+	_, cmd = d.Update(runeKey('n'))
+	if msgs := sessionMsgs(cmd); len(msgs) != 0 {
+		t.Errorf("n sent %+v, want the deletion cancelled", msgs)
+	}
+	d.Update(runeKey('d'))
+	d.SetDeleteReport(want.IDs, session.DeleteReport{Sessions: 3, Messages: 12})
+	_, cmd = d.Update(runeKey('y'))
+	if msgs := sessionMsgs(cmd); len(msgs) != 1 || !reflect.DeepEqual(msgs[0], DeleteSessionsMsg{IDs: want.IDs}) {
+		t.Errorf("y sent %+v, want the marked sessions deleted", msgs)
+	}
+}
+
+func TestSessionDialogFilter(t *testing.T) {
+	d := NewSessionDialogCmp()
+	if filter := d.Filter(); filter != (session.Filter{}) {
+		t.Errorf("Filter() = %+v, want archived sessions hidden", filter)
+	}
+
+	_, cmd := d.Update(tea.KeyMsg{Type: tea.KeyTab})
+	if msgs := sessionMsgs(cmd); len(msgs) != 1 || msgs[0] != (SessionFilterChangedMsg{Filter: session.Filter{IncludeArchived: true}}) {
+		t.Errorf("tab sent %+v, want archived sessions shown", msgs)
+	}
+	d.Update(tea.KeyMsg{Type: tea.KeyTab})
+
+	// Searches find archived sessions too
+	d.Update(runeKey('/'))
+	var msgs []tea.Msg
+	for _, r := range "tag:spike" {
+		_, cmd := d.Update(runeKey(r))
+		msgs = sessionMsgs(cmd)
+	}
+	want := SessionFilterChangedMsg{Filter: session.Filter{Tag: "spike", IncludeArchived: true}}
+	if len(msgs) == 0 || msgs[len(msgs)-1] != want {
+		t.Errorf("typing sent %+v, want %+v", msgs, want)
+	}
+
+	// Typed keys go to the search rather than the bulk operations
+	_, cmd = d.Update(runeKey('a'))
+	for _, msg := range sessionMsgs(cmd) {
+		if _, ok := msg.(ArchiveSessionsMsg); ok {
+			t.Error("a archived sessions while searching")
+		}
+	}
+	_, cmd = d.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if msgs := sessionMsgs(cmd); len(msgs) != 1 || msgs[0] != (SessionFilterChangedMsg{}) {
+		t.Errorf("esc sent %+v, want the search cleared", msgs)
+	}
+}
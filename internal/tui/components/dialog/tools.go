@@ -0,0 +1,631 @@
+package dialog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/google/uuid"
+
+	"github.com/caronex/intelligence-interface/internal/audit"
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/llm/tools"
+	"github.com/caronex/intelligence-interface/internal/tui/layout"
+	"github.com/caronex/intelligence-interface/internal/tui/styles"
+	"github.com/caronex/intelligence-interface/internal/tui/theme"
+	"github.com/caronex/intelligence-interface/internal/tui/util"
+)
+
+// CloseToolPaletteMsg is sent when the tool palette is closed
+type CloseToolPaletteMsg struct{}
+
+// ToolResultMsg carries the result of a tool run from the tool palette
+type ToolResultMsg struct {
+	Tool    string
+	Content string
+	IsError bool
+}
+
+// ToolPaletteDialog runs the tools offered to the user directly, filling
+// their parameters in a form generated from their schema and showing their
+// result
+type ToolPaletteDialog interface {
+	tea.Model
+	layout.Bindings
+}
+
+// toolPaletteStage is the step of a tool run the palette shows
+type toolPaletteStage int
+
+const (
+	toolStageList toolPaletteStage = iota
+	toolStageForm
+	toolStageRunning
+	toolStageResult
+)
+
+// formFieldKind is how a parameter is filled in the form
+type formFieldKind int
+
+const (
+	formFieldText formFieldKind = iota
+	formFieldNumber
+	// formFieldList is an array of strings, entered separated by commas
+	formFieldList
+	formFieldEnum
+	formFieldBool
+	// formFieldJSON is any other parameter, entered as JSON
+	formFieldJSON
+)
+
+// formField is a parameter of the form of a tool
+type formField struct {
+	name        string
+	description string
+	kind        formFieldKind
+	required    bool
+	// options are the values of an enum, "" leaving an optional one unset
+	options []string
+	// value is the value entered, "true" or "false" for a boolean
+	value string
+}
+
+type toolPaletteDialogCmp struct {
+	tools       []tools.BaseTool
+	selectedIdx int
+	stage       toolPaletteStage
+
+	// fields, inputs and focusIdx are the form of the selected tool, inputs
+	// holding the text inputs of the fields entered as text
+	fields   []formField
+	inputs   []textinput.Model
+	focusIdx int
+	formErr  string
+
+	result  ToolResultMsg
+	results viewport.Model
+}
+
+type toolPaletteKeyMap struct {
+	Up     key.Binding
+	Down   key.Binding
+	Select key.Binding
+	Escape key.Binding
+}
+
+var toolPaletteKeys = toolPaletteKeyMap{
+	Up: key.NewBinding(
+		key.WithKeys("up", "k"),
+		key.WithHelp("↑/k", "previous tool"),
+	),
+	Down: key.NewBinding(
+		key.WithKeys("down", "j"),
+		key.WithHelp("↓/j", "next tool"),
+	),
+	Select: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("enter", "fill parameters"),
+	),
+	Escape: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "close"),
+	),
+}
+
+var toolFormKeys = struct {
+	Next   key.Binding
+	Prev   key.Binding
+	Change key.Binding
+	Run    key.Binding
+	Back   key.Binding
+}{
+	Next: key.NewBinding(
+		key.WithKeys("down", "tab"),
+		key.WithHelp("↓/tab", "next parameter"),
+	),
+	Prev: key.NewBinding(
+		key.WithKeys("up", "shift+tab"),
+		key.WithHelp("↑/shift+tab", "previous parameter"),
+	),
+	Change: key.NewBinding(
+		key.WithKeys("left", "right", " "),
+		key.WithHelp("←/→/space", "change choice"),
+	),
+	Run: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("enter", "run tool"),
+	),
+	Back: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "back"),
+	),
+}
+
+var toolResultKeys = struct {
+	Scroll key.Binding
+	Copy   key.Binding
+	Back   key.Binding
+}{
+	Scroll: key.NewBinding(
+		key.WithKeys("up", "down", "pgup", "pgdown"),
+		key.WithHelp("↑/↓/pgup/pgdn", "scroll"),
+	),
+	Copy: key.NewBinding(
+		key.WithKeys("c"),
+		key.WithHelp("c", "copy result"),
+	),
+	Back: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "back"),
+	),
+}
+
+const (
+	// toolPaletteWidth is the width of the palette
+	toolPaletteWidth = 80
+	// toolResultHeight is the height of the result viewer
+	toolResultHeight = 20
+)
+
+func (m *toolPaletteDialogCmp) Init() tea.Cmd {
+	return nil
+}
+
+func (m *toolPaletteDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case ToolResultMsg:
+		if m.stage == toolStageRunning {
+			m.showResult(msg)
+		}
+		return m, nil
+	case tea.KeyMsg:
+		switch m.stage {
+		case toolStageList:
+			return m, m.updateList(msg)
+		case toolStageForm:
+			return m, m.updateForm(msg)
+		case toolStageRunning:
+			if key.Matches(msg, toolPaletteKeys.Escape) {
+				return m, util.CmdHandler(CloseToolPaletteMsg{})
+			}
+		case toolStageResult:
+			return m, m.updateResult(msg)
+		}
+	}
+	return m, nil
+}
+
+// updateList handles the keys while the tools are listed
+func (m *toolPaletteDialogCmp) updateList(msg tea.KeyMsg) tea.Cmd {
+	switch {
+	case key.Matches(msg, toolPaletteKeys.Escape):
+		return util.CmdHandler(CloseToolPaletteMsg{})
+	case key.Matches(msg, toolPaletteKeys.Up):
+		if m.selectedIdx > 0 {
+			m.selectedIdx--
+		}
+	case key.Matches(msg, toolPaletteKeys.Down):
+		if m.selectedIdx < len(m.tools)-1 {
+			m.selectedIdx++
+		}
+	case key.Matches(msg, toolPaletteKeys.Select):
+		if m.selectedIdx < len(m.tools) {
+			return m.startForm(m.tools[m.selectedIdx].Info())
+		}
+	}
+	return nil
+}
+
+// startForm fills the form with the parameters of a tool
+func (m *toolPaletteDialogCmp) startForm(info tools.ToolInfo) tea.Cmd {
+	t := theme.CurrentTheme()
+	m.fields = toolForm(info)
+	m.inputs = make([]textinput.Model, len(m.fields))
+	for i, field := range m.fields {
+		if field.kind == formFieldEnum || field.kind == formFieldBool {
+			continue
+		}
+		input := textinput.New()
+		input.Prompt = ""
+		input.Width = toolPaletteWidth - 24
+		input.Placeholder = fieldPlaceholder(field)
+		input.PlaceholderStyle = input.PlaceholderStyle.Background(t.Background())
+		input.TextStyle = input.TextStyle.Background(t.Background()).Foreground(t.Primary())
+		input.SetValue(field.value)
+		m.inputs[i] = input
+	}
+	m.focusIdx = 0
+	m.formErr = ""
+	m.stage = toolStageForm
+	return m.focus(0)
+}
+
+// focus moves the focus of the form to the field i
+func (m *toolPaletteDialogCmp) focus(i int) tea.Cmd {
+	if len(m.fields) == 0 {
+		return nil
+	}
+	m.inputs[m.focusIdx].Blur()
+	m.focusIdx = (i + len(m.fields)) % len(m.fields)
+	if m.hasInput(m.focusIdx) {
+		return m.inputs[m.focusIdx].Focus()
+	}
+	return nil
+}
+
+func (m *toolPaletteDialogCmp) hasInput(i int) bool {
+	kind := m.fields[i].kind
+	return kind != formFieldEnum && kind != formFieldBool
+}
+
+// updateForm handles the keys while the parameters are filled
+func (m *toolPaletteDialogCmp) updateForm(msg tea.KeyMsg) tea.Cmd {
+	switch {
+	case key.Matches(msg, toolFormKeys.Back):
+		m.stage = toolStageList
+		return nil
+	case key.Matches(msg, toolFormKeys.Run):
+		for i := range m.fields {
+			if m.hasInput(i) {
+				m.fields[i].value = m.inputs[i].Value()
+			}
+		}
+		input, err := formInput(m.fields)
+		if err != nil {
+			m.formErr = err.Error()
+			return nil
+		}
+		m.stage = toolStageRunning
+		return runUserTool(m.tools[m.selectedIdx], input)
+	case key.Matches(msg, toolFormKeys.Next):
+		return m.focus(m.focusIdx + 1)
+	case key.Matches(msg, toolFormKeys.Prev):
+		return m.focus(m.focusIdx - 1)
+	}
+	if len(m.fields) == 0 {
+		return nil
+	}
+	if m.hasInput(m.focusIdx) {
+		var cmd tea.Cmd
+		m.inputs[m.focusIdx], cmd = m.inputs[m.focusIdx].Update(msg)
+		return cmd
+	}
+	if key.Matches(msg, toolFormKeys.Change) {
+		step := 1
+		if msg.String() == "left" {
+			step = -1
+		}
+		m.fields[m.focusIdx].cycle(step)
+	}
+	return nil
+}
+
+// updateResult handles the keys while the result is shown
+func (m *toolPaletteDialogCmp) updateResult(msg tea.KeyMsg) tea.Cmd {
+	switch {
+	case key.Matches(msg, toolResultKeys.Back):
+		m.stage = toolStageList
+		return nil
+	case key.Matches(msg, toolResultKeys.Copy):
+		if err := clipboard.WriteAll(m.result.Content); err != nil {
+			return util.ReportError(fmt.Errorf("failed to copy the result: %w", err))
+		}
+		return util.ReportInfo(fmt.Sprintf("Result of %s copied to the clipboard", m.result.Tool))
+	}
+	var cmd tea.Cmd
+	m.results, cmd = m.results.Update(msg)
+	return cmd
+}
+
+// showResult shows the result of a tool run in the viewer, indenting it when
+// it is JSON
+func (m *toolPaletteDialogCmp) showResult(result ToolResultMsg) {
+	var indented bytes.Buffer
+	if json.Indent(&indented, []byte(result.Content), "", "  ") == nil {
+		result.Content = indented.String()
+	}
+	m.result = result
+	m.results = viewport.New(toolPaletteWidth, toolResultHeight)
+	m.results.SetContent(lipgloss.NewStyle().Width(toolPaletteWidth).Render(result.Content))
+	m.stage = toolStageResult
+}
+
+// runUserTool runs a tool on behalf of the user, recording the run in the
+// audit log
+func runUserTool(tool tools.BaseTool, input string) tea.Cmd {
+	name := tool.Info().Name
+	return func() tea.Msg {
+		response, err := tool.Run(context.Background(), tools.ToolCall{
+			ID:    uuid.New().String(),
+			Name:  name,
+			Input: input,
+		})
+		failed := err != nil || response.IsError
+		if err := audit.Record(audit.Entry{Actor: audit.ActorUser, Tool: name, Input: input, Failed: failed}); err != nil {
+			logging.Warn("failed to record the tool call in the audit log", "error", err, logging.ComponentKey, logging.ComponentDataLoss)
+		}
+		if err != nil {
+			return ToolResultMsg{Tool: name, Content: err.Error(), IsError: true}
+		}
+		return ToolResultMsg{Tool: name, Content: response.Content, IsError: response.IsError}
+	}
+}
+
+// toolForm returns the form of the parameters of a tool, the required ones
+// first then by name
+func toolForm(info tools.ToolInfo) []formField {
+	fields := make([]formField, 0, len(info.Parameters))
+	for name, raw := range info.Parameters {
+		property, _ := raw.(map[string]any)
+		field := formField{
+			name:     name,
+			required: slices.Contains(info.Required, name),
+		}
+		field.description, _ = property["description"].(string)
+		defaultValue, hasDefault := property["default"]
+
+		switch property["type"] {
+		case "boolean":
+			field.kind = formFieldBool
+			field.value = "false"
+			if hasDefault {
+				field.value = fmt.Sprint(defaultValue)
+			}
+		case "string":
+			field.options = enumOptions(property["enum"])
+			if field.options != nil {
+				field.kind = formFieldEnum
+				switch {
+				case hasDefault:
+					field.value = fmt.Sprint(defaultValue)
+				case field.required:
+					field.value = field.options[0]
+				default:
+					field.options = append([]string{""}, field.options...)
+				}
+			} else {
+				field.kind = formFieldText
+				if hasDefault {
+					field.value = fmt.Sprint(defaultValue)
+				}
+			}
+		case "integer", "number":
+			field.kind = formFieldNumber
+			if hasDefault {
+				field.value = fmt.Sprint(defaultValue)
+			}
+		case "array":
+			field.kind = formFieldJSON
+			if items, _ := property["items"].(map[string]any); items["type"] == "string" {
+				field.kind = formFieldList
+			}
+		default:
+			field.kind = formFieldJSON
+		}
+		fields = append(fields, field)
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		if fields[i].required != fields[j].required {
+			return fields[i].required
+		}
+		return fields[i].name < fields[j].name
+	})
+	return fields
+}
+
+// enumOptions returns the values of an enum, as declared by ParamsSchema or
+// decoded from JSON
+func enumOptions(enum any) []string {
+	switch values := enum.(type) {
+	case []string:
+		return slices.Clone(values)
+	case []any:
+		options := make([]string, len(values))
+		for i, value := range values {
+			options[i] = fmt.Sprint(value)
+		}
+		return options
+	}
+	return nil
+}
+
+// cycle selects the option of an enum or boolean step choices away
+func (f *formField) cycle(step int) {
+	switch f.kind {
+	case formFieldBool:
+		f.value = strconv.FormatBool(f.value != "true")
+	case formFieldEnum:
+		i := slices.Index(f.options, f.value)
+		f.value = f.options[(i+step+len(f.options))%len(f.options)]
+	}
+}
+
+// formInput returns the input of a tool as JSON from the values of its form,
+// leaving out the optional parameters left empty
+func formInput(fields []formField) (string, error) {
+	input := make(map[string]any, len(fields))
+	for _, field := range fields {
+		value := strings.TrimSpace(field.value)
+		if value == "" {
+			if field.required {
+				return "", fmt.Errorf("%s is required", field.name)
+			}
+			continue
+		}
+		switch field.kind {
+		case formFieldBool:
+			input[field.name] = value == "true"
+		case formFieldNumber:
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return "", fmt.Errorf("%s must be a number", field.name)
+			}
+			input[field.name] = number
+		case formFieldList:
+			var items []string
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			input[field.name] = items
+		case formFieldJSON:
+			if !json.Valid([]byte(value)) {
+				return "", fmt.Errorf("%s must be JSON", field.name)
+			}
+			input[field.name] = json.RawMessage(value)
+		default:
+			input[field.name] = value
+		}
+	}
+	data, err := json.Marshal(input)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode the parameters: %w", err)
+	}
+	return string(data), nil
+}
+
+// fieldPlaceholder hints at what a field entered as text takes
+func fieldPlaceholder(field formField) string {
+	switch field.kind {
+	case formFieldNumber:
+		return "a number"
+	case formFieldList:
+		return "values separated by commas"
+	case formFieldJSON:
+		return "a JSON value"
+	}
+	return ""
+}
+
+func (m *toolPaletteDialogCmp) View() string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+	muted := baseStyle.Width(toolPaletteWidth).Foreground(t.TextMuted())
+
+	titleText := "Run Tool"
+	if m.stage != toolStageList && m.selectedIdx < len(m.tools) {
+		titleText = "Run " + m.tools[m.selectedIdx].Info().Name
+	}
+	title := baseStyle.
+		Foreground(t.Primary()).
+		Bold(true).
+		Width(toolPaletteWidth).
+		Render(titleText)
+
+	var body []string
+	switch m.stage {
+	case toolStageList:
+		if len(m.tools) == 0 {
+			body = append(body, muted.Render("No tool can be run directly."))
+		}
+		for i, tool := range m.tools {
+			info := tool.Info()
+			itemStyle := baseStyle.Width(toolPaletteWidth).Foreground(t.Text())
+			if i == m.selectedIdx {
+				itemStyle = itemStyle.Background(t.Primary()).Foreground(t.Background()).Bold(true)
+			}
+			body = append(body,
+				itemStyle.Render(info.Name),
+				muted.Render("  "+truncateNote(info.Description, toolPaletteWidth-2)),
+			)
+		}
+		body = append(body, "", muted.Render("enter parameters · esc close"))
+
+	case toolStageForm:
+		if len(m.fields) == 0 {
+			body = append(body, muted.Render("This tool takes no parameters."))
+		}
+		for i, field := range m.fields {
+			label := field.name
+			if field.required {
+				label += "*"
+			}
+			labelStyle := baseStyle.Width(22).Foreground(t.Text())
+			if i == m.focusIdx {
+				labelStyle = labelStyle.Foreground(t.Primary()).Bold(true)
+			}
+			var value string
+			switch field.kind {
+			case formFieldBool, formFieldEnum:
+				value = field.value
+				if value == "" {
+					value = "(unset)"
+				}
+				value = "‹ " + value + " ›"
+			default:
+				value = m.inputs[i].View()
+			}
+			body = append(body, lipgloss.JoinHorizontal(lipgloss.Top, labelStyle.Render(label), baseStyle.Render(value)))
+			if i == m.focusIdx && field.description != "" {
+				body = append(body, muted.Render(truncateNote(field.description, toolPaletteWidth)))
+			}
+		}
+		if m.formErr != "" {
+			body = append(body, "", baseStyle.Width(toolPaletteWidth).Foreground(t.Error()).Render(m.formErr))
+		}
+		body = append(body, "", muted.Render("↑/↓ parameter · ←/→ choice · enter run · esc back"))
+
+	case toolStageRunning:
+		body = append(body, muted.Render("Running..."))
+
+	case toolStageResult:
+		if m.result.IsError {
+			body = append(body, baseStyle.Width(toolPaletteWidth).Foreground(t.Error()).Render("The tool failed"))
+		}
+		body = append(body,
+			m.results.View(),
+			"",
+			muted.Render(fmt.Sprintf("%3.f%% · ↑/↓ scroll · c copy · esc back", m.results.ScrollPercent()*100)),
+		)
+	}
+
+	content := baseStyle.Render(
+		lipgloss.JoinVertical(
+			lipgloss.Left,
+			title,
+			"",
+			lipgloss.JoinVertical(lipgloss.Left, body...),
+		),
+	)
+
+	return baseStyle.Padding(1, 2).
+		Border(lipgloss.RoundedBorder()).
+		BorderBackground(t.Background()).
+		BorderForeground(t.TextMuted()).
+		Width(lipgloss.Width(content) + 4).
+		Render(content)
+}
+
+func (m *toolPaletteDialogCmp) BindingKeys() []key.Binding {
+	switch m.stage {
+	case toolStageForm:
+		return []key.Binding{toolFormKeys.Next, toolFormKeys.Prev, toolFormKeys.Change, toolFormKeys.Run, toolFormKeys.Back}
+	case toolStageResult:
+		return []key.Binding{toolResultKeys.Scroll, toolResultKeys.Copy, toolResultKeys.Back}
+	}
+	return layout.KeyMapToSlice(toolPaletteKeys)
+}
+
+// NewToolPaletteDialogCmp creates the tool palette of the tools given, the
+// destructive ones left out
+func NewToolPaletteDialogCmp(userTools []tools.BaseTool) ToolPaletteDialog {
+	var offered []tools.BaseTool
+	for _, tool := range userTools {
+		if !tool.Info().Destructive {
+			offered = append(offered, tool)
+		}
+	}
+	return &toolPaletteDialogCmp{tools: offered}
+}
@@ -0,0 +1,159 @@
+package dialog
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/caronex/intelligence-interface/internal/llm/tools"
+)
+
+type formParams struct {
+	Query   string `json:"query" required:"true" description:"Text to look for"`
+	Label   string `json:"label" default:"latest"`
+	Section string `json:"section" enum:"all,agents,spaces" default:"all"`
+	Format  string `json:"format" enum:"json,text"`
+	Action  string `json:"action" required:"true" enum:"status,create"`
+	Verbose bool   `json:"verbose" default:"true"`
+	DryRun  bool   `json:"dry_run"`
+}
+
+// fakeTool is a tool of the palette doing nothing
+type fakeTool struct {
+	name        string
+	destructive bool
+}
+
+func (f fakeTool) Info() tools.ToolInfo {
+	parameters, required := tools.ParamsSchema(formParams{})
+	return tools.ToolInfo{Name: f.name, Parameters: parameters, Required: required, Destructive: f.destructive}
+}
+
+func (f fakeTool) Run(ctx context.Context, call tools.ToolCall) (tools.ToolResponse, error) {
+	return tools.NewTextResponse(call.Input), nil
+}
+
+func formFields(fields []formField) map[string]formField {
+	byName := make(map[string]formField, len(fields))
+	for _, field := range fields {
+		byName[field.name] = field
+	}
+	return byName
+}
+
+func TestToolFormOrder(t *testing.T) {
+	var names []string
+	for _, field := range toolForm(fakeTool{name: "search"}.Info()) {
+		names = append(names, field.name)
+	}
+	expected := []string{"action", "query", "dry_run", "format", "label", "section", "verbose"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("fields = %v, want the required ones first then by name: %v", names, expected)
+	}
+}
+
+func TestToolFormStrings(t *testing.T) {
+	fields := formFields(toolForm(fakeTool{name: "search"}.Info()))
+
+	query := fields["query"]
+	if query.kind != formFieldText || !query.required || query.value != "" || query.description != "Text to look for" {
+		t.Errorf("query = %+v, want a required empty text field with its description", query)
+	}
+	if label := fields["label"]; label.kind != formFieldText || label.required || label.value != "latest" {
+		t.Errorf("label = %+v, want an optional text field holding its default", label)
+	}
+}
+
+func TestToolFormEnums(t *testing.T) {
+	fields := formFields(toolForm(fakeTool{name: "search"}.Info()))
+
+	testCases := []struct {
+		name    string
+		value   string
+		options []string
+	}{
+		// The default is selected
+		{name: "section", value: "all", options: []string{"all", "agents", "spaces"}},
+		// An optional enum without a default may be left unset
+		{name: "format", value: "", options: []string{"", "json", "text"}},
+		// A required enum starts on its first option
+		{name: "action", value: "status", options: []string{"status", "create"}},
+	}
+	for _, tc := range testCases {
+		field := fields[tc.name]
+		if field.kind != formFieldEnum || field.value != tc.value || !reflect.DeepEqual(field.options, tc.options) {
+			t.Errorf("%s = %+v, want an enum on %q of %q", tc.name, field, tc.value, tc.options)
+		}
+	}
+
+	section := fields["section"]
+	section.cycle(1)
+	if section.value != "agents" {
+		t.Errorf("cycling forward selected %q, want agents", section.value)
+	}
+	section.cycle(-1)
+	section.cycle(-1)
+	if section.value != "spaces" {
+		t.Errorf("cycling backward selected %q, want it to wrap to spaces", section.value)
+	}
+}
+
+func TestToolFormBooleans(t *testing.T) {
+	fields := formFields(toolForm(fakeTool{name: "search"}.Info()))
+
+	if verbose := fields["verbose"]; verbose.kind != formFieldBool || verbose.value != "true" {
+		t.Errorf("verbose = %+v, want a boolean holding its default", verbose)
+	}
+	dryRun := fields["dry_run"]
+	if dryRun.kind != formFieldBool || dryRun.value != "false" {
+		t.Errorf("dry_run = %+v, want a boolean off by default", dryRun)
+	}
+	dryRun.cycle(1)
+	if dryRun.value != "true" {
+		t.Errorf("toggling dry_run gave %q, want true", dryRun.value)
+	}
+}
+
+func TestFormInput(t *testing.T) {
+	fields := toolForm(fakeTool{name: "search"}.Info())
+	if _, err := formInput(fields); err == nil || err.Error() != "query is required" {
+		t.Fatalf("formInput() error = %v, want the missing required field", err)
+	}
+
+	for i := range fields {
+		if fields[i].name == "query" {
+			fields[i].value = "  release notes "
+		}
+	}
+	input, err := formInput(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal([]byte(input), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]any{
+		"query":   "release notes",
+		"label":   "latest",
+		"section": "all",
+		"action":  "status",
+		"verbose": true,
+		"dry_run": false,
+	}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("formInput() = %v, want %v without the unset format", decoded, expected)
+	}
+}
+
+func TestToolPaletteLeavesOutDestructiveTools(t *testing.T) {
+	palette := NewToolPaletteDialogCmp([]tools.BaseTool{
+		fakeTool{name: "inspect"},
+		fakeTool{name: "wipe", destructive: true},
+	}).(*toolPaletteDialogCmp)
+
+	if len(palette.tools) != 1 || palette.tools[0].Info().Name != "inspect" {
+		t.Errorf("the palette offers %d tools, want only inspect", len(palette.tools))
+	}
+}
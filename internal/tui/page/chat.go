@@ -2,6 +2,7 @@ package page
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -10,9 +11,15 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/caronex/intelligence-interface/internal/app"
 	"github.com/caronex/intelligence-interface/internal/completions"
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/core/logging"
 	"github.com/caronex/intelligence-interface/internal/llm/agent"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/lock"
 	"github.com/caronex/intelligence-interface/internal/message"
+	"github.com/caronex/intelligence-interface/internal/pubsub"
 	"github.com/caronex/intelligence-interface/internal/session"
+	"github.com/caronex/intelligence-interface/internal/tools/coordination"
 	"github.com/caronex/intelligence-interface/internal/tui/components/chat"
 	"github.com/caronex/intelligence-interface/internal/tui/components/dialog"
 	"github.com/caronex/intelligence-interface/internal/tui/layout"
@@ -65,6 +72,8 @@ type chatPage struct {
 	agentSessions        map[string]session.Session // AgentMode.String() -> Session
 	conversationContexts map[string][]message.Message // AgentMode.String() -> Context messages
 	currentAgentMode     AgentMode // Current agent mode for context management
+
+	lockedSessionID string // Session whose write lock the page asked for
 }
 
 type ChatKeyMap struct {
@@ -93,6 +102,11 @@ var keyMap = ChatKeyMap{
 	),
 }
 
+// NewSessionKey returns the key binding starting a new session
+func NewSessionKey() key.Binding {
+	return keyMap.NewSession
+}
+
 func (p *chatPage) Init() tea.Cmd {
 	cmds := []tea.Cmd{
 		p.layout.Init(),
@@ -110,10 +124,17 @@ func (p *chatPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case dialog.CompletionDialogCloseMsg:
 		p.showCompletionDialog = false
 	case chat.SendMsg:
-		cmd := p.sendMessage(msg.Text, msg.Attachments)
+		cmd := p.sendMessage(msg)
 		if cmd != nil {
 			return p, cmd
 		}
+	case chat.RetryMsg:
+		cmd := p.retry(msg.Model)
+		if cmd != nil {
+			return p, cmd
+		}
+	case chat.ChooseComparisonMsg:
+		return p, p.chooseComparison(msg.MessageID, msg.Choice)
 	case AgentSwitchedMsg:
 		// Save current context before switching
 		if p.session.ID != "" && p.currentAgentMode != nil {
@@ -165,10 +186,16 @@ func (p *chatPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		
 		// Handle custom command execution
-		cmd := p.sendMessage(content, nil)
+		cmd := p.sendMessage(chat.SendMsg{Text: content})
 		if cmd != nil {
 			return p, cmd
 		}
+	case pubsub.Event[session.Session]:
+		// A session deleted while open, from the session dialog, leaves a
+		// new session in its place
+		if msg.Type == pubsub.DeletedEvent && p.session.ID != "" && msg.Payload.ID == p.session.ID {
+			return p, p.clearSession()
+		}
 	case chat.SessionSelectedMsg:
 		if p.session.ID == "" {
 			cmd := p.setSidebar()
@@ -177,19 +204,26 @@ func (p *chatPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 		p.session = msg
+		if msg.ID != p.lockedSessionID {
+			p.releaseLock()
+			p.lockedSessionID = msg.ID
+			cmds = append(cmds, p.acquireLock(msg.ID))
+		}
 	case tea.KeyMsg:
 		switch {
 		case key.Matches(msg, keyMap.ShowCompletionDialog):
 			p.showCompletionDialog = true
 			// Continue sending keys to layout->chat
 		case key.Matches(msg, keyMap.NewSession):
-			p.session = session.Session{}
-			return p, tea.Batch(
-				p.clearSidebar(),
-				util.CmdHandler(chat.SessionClearedMsg{}),
-			)
+			return p, p.clearSession()
 		case key.Matches(msg, keyMap.Cancel):
-			if p.session.ID != "" {
+			// In auto mode and coordination, the abort cancels the running
+			// operation of the session along with the delegations under it
+			if id, ok := coordination.ActiveOperation(p.session.ID); ok && p.session.ID != "" {
+				return p, p.cancelOperation(id)
+			}
+			// Let the editor handle esc while the agent is idle
+			if p.session.ID != "" && p.getCurrentAgent().IsSessionBusy(p.session.ID) {
 				// Cancel the current session's generation process
 				// This allows users to interrupt long-running operations
 				p.getCurrentAgent().Cancel(p.session.ID)
@@ -236,7 +270,72 @@ func (p *chatPage) clearSidebar() tea.Cmd {
 	return p.layout.ClearRightPanel()
 }
 
-func (p *chatPage) sendMessage(text string, attachments []message.Attachment) tea.Cmd {
+// clearSession leaves the session open for a new one
+func (p *chatPage) clearSession() tea.Cmd {
+	p.session = session.Session{}
+	p.releaseLock()
+	return tea.Batch(
+		p.clearSidebar(),
+		util.CmdHandler(chat.SessionClearedMsg{}),
+	)
+}
+
+// acquireLock asks for the write lock of a session, reporting it read-only
+// when another instance holds it
+func (p *chatPage) acquireLock(sessionID string) tea.Cmd {
+	return func() tea.Msg {
+		var locked *lock.LockedError
+		if err := p.app.Locks.Acquire(context.Background(), sessionID); errors.As(err, &locked) {
+			return chat.SessionLockMsg{SessionID: sessionID, Holder: &locked.Lock}
+		} else if err != nil {
+			return util.ReportError(err)()
+		}
+		return chat.SessionLockMsg{SessionID: sessionID}
+	}
+}
+
+// cancelOperation cancels a coordination operation of the session in the
+// background, as it waits for the cancelled delegations to stop
+func (p *chatPage) cancelOperation(id string) tea.Cmd {
+	return func() tea.Msg {
+		summary, err := coordination.CancelOperation(id, "user")
+		if err != nil {
+			return util.ReportError(err)()
+		}
+		return util.ReportInfo(fmt.Sprintf("Cancelled %s and %d operations under it", id, len(summary.Operations)-1))()
+	}
+}
+
+// releaseLock lets other instances write to the session the page leaves,
+// unless a response is still being generated for it
+func (p *chatPage) releaseLock() {
+	if p.lockedSessionID == "" || p.getCurrentAgent().IsSessionBusy(p.lockedSessionID) {
+		p.lockedSessionID = ""
+		return
+	}
+	if err := p.app.Locks.Release(context.Background(), p.lockedSessionID); err != nil {
+		logging.Warn("Failed to release session lock", "session", p.lockedSessionID, "error", err)
+	}
+	p.lockedSessionID = ""
+}
+
+// checkLock takes the write lock of the session before writing to it. It
+// returns whether the session can be written to, and the command reporting
+// its lock state.
+func (p *chatPage) checkLock() (tea.Cmd, bool) {
+	var locked *lock.LockedError
+	if err := p.app.Locks.Acquire(context.Background(), p.session.ID); errors.As(err, &locked) {
+		return tea.Batch(
+			util.CmdHandler(chat.SessionLockMsg{SessionID: p.session.ID, Holder: &locked.Lock}),
+			util.ReportWarn(err.Error()),
+		), false
+	} else if err != nil {
+		return util.ReportError(err), false
+	}
+	return util.CmdHandler(chat.SessionLockMsg{SessionID: p.session.ID}), true
+}
+
+func (p *chatPage) sendMessage(msg chat.SendMsg) tea.Cmd {
 	var cmds []tea.Cmd
 	if p.session.ID == "" {
 		session, err := p.app.Sessions.Create(context.Background(), "New Session")
@@ -249,16 +348,73 @@ func (p *chatPage) sendMessage(text string, attachments []message.Attachment) te
 		if cmd != nil {
 			cmds = append(cmds, cmd)
 		}
-		cmds = append(cmds, util.CmdHandler(chat.SessionSelectedMsg(session)))
+		cmds = append(cmds, util.CmdHandler(chat.SessionSelectedMsg(session)), spaceDefaultsInfo(session))
+	}
+	lockCmd, writable := p.checkLock()
+	cmds = append(cmds, lockCmd)
+	if !writable {
+		return tea.Batch(cmds...)
 	}
 
-	_, err := p.getCurrentAgent().Run(context.Background(), p.session.ID, text, attachments...)
+	var err error
+	ctx := context.Background()
+	if msg.ExcludedContext != nil {
+		ctx = agent.WithExcludedContext(ctx, msg.ExcludedContext)
+	}
+	if msg.ReplacesMessageID != "" {
+		_, err = p.getCurrentAgent().Resend(ctx, p.session.ID, msg.ReplacesMessageID, msg.Text, msg.Attachments...)
+	} else {
+		if msg.SkipReview {
+			ctx = agent.WithoutReview(ctx)
+		}
+		if msg.Auto {
+			ctx = agent.WithAutoMode(ctx)
+		}
+		if msg.CompareModel != "" {
+			ctx = agent.WithComparison(ctx, "", msg.CompareModel)
+		}
+		_, err = p.getCurrentAgent().Run(ctx, p.session.ID, msg.Text, msg.Attachments...)
+	}
 	if err != nil {
 		return util.ReportError(err)
 	}
 	return tea.Batch(cmds...)
 }
 
+// chooseComparison keeps a response of a model comparison as the one the
+// session continues with
+func (p *chatPage) chooseComparison(messageID string, choice int) tea.Cmd {
+	lockCmd, writable := p.checkLock()
+	if !writable {
+		return lockCmd
+	}
+	chosen, err := p.getCurrentAgent().ChooseComparison(context.Background(), p.session.ID, messageID, choice)
+	if err != nil {
+		return util.ReportError(err)
+	}
+	return util.ReportInfo(fmt.Sprintf("Continuing with the response of %s", models.SupportedModels[chosen.Model].Name))
+}
+
+// retry generates the last response of the session again, with the given
+// model or the agent model when empty
+func (p *chatPage) retry(model models.ModelID) tea.Cmd {
+	if p.session.ID == "" {
+		return util.ReportWarn("There is no response to retry")
+	}
+	lockCmd, writable := p.checkLock()
+	if !writable {
+		return lockCmd
+	}
+	_, err := p.getCurrentAgent().Retry(context.Background(), p.session.ID, agent.RetryOptions{
+		Model:  model,
+		Append: config.Get().TUI.RetryMode == config.RetryModeAppend,
+	})
+	if err != nil {
+		return util.ReportError(err)
+	}
+	return lockCmd
+}
+
 func (p *chatPage) SetSize(width, height int) tea.Cmd {
 	return p.layout.SetSize(width, height)
 }
@@ -353,7 +509,7 @@ func (p *chatPage) switchToAgentSession(agentMode string) tea.Cmd {
 		
 		p.session = newSession
 		p.agentSessions[agentMode] = newSession
-		cmds = append(cmds, util.CmdHandler(chat.SessionSelectedMsg(newSession)))
+		cmds = append(cmds, util.CmdHandler(chat.SessionSelectedMsg(newSession)), spaceDefaultsInfo(newSession))
 		
 		// Set up sidebar for new session
 		cmd := p.setSidebar()
@@ -370,6 +526,16 @@ func (p *chatPage) switchToAgentSession(agentMode string) tea.Cmd {
 	return tea.Batch(cmds...)
 }
 
+// spaceDefaultsInfo reports the settings a new session got from the defaults
+// of its space, nil when the space has none
+func spaceDefaultsInfo(sess session.Session) tea.Cmd {
+	defaults := config.SpaceSessionDefaults(sess.SpaceID)
+	if defaults == nil || defaults.Describe() == "" {
+		return nil
+	}
+	return util.ReportInfo(fmt.Sprintf("Session defaults from space %s: %s", sess.SpaceID, defaults.Describe()))
+}
+
 func NewChatPage(app *app.App) tea.Model {
 	cg := completions.NewFileAndFolderContextGroup()
 	completionDialog := dialog.NewCompletionDialogCmp(cg)
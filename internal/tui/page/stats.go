@@ -0,0 +1,134 @@
+package page
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/caronex/intelligence-interface/internal/analytics"
+	"github.com/caronex/intelligence-interface/internal/app"
+	"github.com/caronex/intelligence-interface/internal/tui/layout"
+	"github.com/caronex/intelligence-interface/internal/tui/styles"
+	"github.com/caronex/intelligence-interface/internal/tui/util"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var StatsPage PageID = "stats"
+
+// statsRanges are the date ranges the stats page cycles through, in days
+var statsRanges = []int{7, 30, 90, 365}
+
+type statsKeyMap struct {
+	PrevRange key.Binding
+	NextRange key.Binding
+	Refresh   key.Binding
+}
+
+var statsKeys = statsKeyMap{
+	PrevRange: key.NewBinding(
+		key.WithKeys("left", "h"),
+		key.WithHelp("←/h", "shorter range"),
+	),
+	NextRange: key.NewBinding(
+		key.WithKeys("right", "l"),
+		key.WithHelp("→/l", "longer range"),
+	),
+	Refresh: key.NewBinding(
+		key.WithKeys("r"),
+		key.WithHelp("r", "refresh"),
+	),
+}
+
+type statsLoadedMsg struct {
+	summary analytics.Summary
+}
+
+type StatsPageModel interface {
+	tea.Model
+	layout.Sizeable
+	layout.Bindings
+}
+
+type statsPage struct {
+	app           *app.App
+	width, height int
+	rangeIdx      int
+	summary       *analytics.Summary
+}
+
+func (p *statsPage) Init() tea.Cmd {
+	return p.load()
+}
+
+func (p *statsPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		return p, p.SetSize(msg.Width, msg.Height)
+	case statsLoadedMsg:
+		p.summary = &msg.summary
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, statsKeys.PrevRange):
+			if p.rangeIdx > 0 {
+				p.rangeIdx--
+				return p, p.load()
+			}
+		case key.Matches(msg, statsKeys.NextRange):
+			if p.rangeIdx < len(statsRanges)-1 {
+				p.rangeIdx++
+				return p, p.load()
+			}
+		case key.Matches(msg, statsKeys.Refresh):
+			return p, p.load()
+		}
+	}
+	return p, nil
+}
+
+// load reads the rollups for the selected range in the background
+func (p *statsPage) load() tea.Cmd {
+	days := statsRanges[p.rangeIdx]
+	return func() tea.Msg {
+		to := time.Now()
+		from := to.AddDate(0, 0, -(days - 1))
+		summary, err := p.app.Analytics.Summary(context.Background(), from, to)
+		if err != nil {
+			return util.InfoMsg{Type: util.InfoTypeError, Msg: fmt.Sprintf("failed to load stats: %v", err)}
+		}
+		return statsLoadedMsg{summary: summary}
+	}
+}
+
+func (p *statsPage) View() string {
+	style := styles.BaseStyle().Width(p.width).Height(p.height).Padding(1, 2)
+	if p.summary == nil {
+		return style.Render("Loading stats...")
+	}
+
+	title := styles.Bold().Render(fmt.Sprintf("Usage stats · last %d days", statsRanges[p.rangeIdx]))
+	body := analytics.Render(*p.summary, p.width-4)
+	return style.Render(lipgloss.JoinVertical(lipgloss.Left, title, "", body))
+}
+
+func (p *statsPage) BindingKeys() []key.Binding {
+	return layout.KeyMapToSlice(statsKeys)
+}
+
+func (p *statsPage) GetSize() (int, int) {
+	return p.width, p.height
+}
+
+func (p *statsPage) SetSize(width int, height int) tea.Cmd {
+	p.width = width
+	p.height = height
+	return nil
+}
+
+func NewStatsPage(app *app.App) StatsPageModel {
+	return &statsPage{
+		app:      app,
+		rangeIdx: 1,
+	}
+}
@@ -0,0 +1,186 @@
+package theme
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/caronex/intelligence-interface/internal/core/logging"
+)
+
+// ThemesDirName is the directory under Data.Directory holding custom theme files
+const ThemesDirName = "themes"
+
+// CustomThemeFile is the format of a theme file. Colors are keyed by the
+// Theme method they override in lower camel case, e.g. "primary" or
+// "diffAddedBg", and are either a single color or a {"dark", "light"} pair.
+// Colors that are not overridden are taken from the base theme.
+//
+//	{
+//	  "base": "dracula",
+//	  "colors": {
+//	    "primary": "#ff79c6",
+//	    "background": {"dark": "#1e1e2e", "light": "#ffffff"}
+//	  }
+//	}
+type CustomThemeFile struct {
+	// Base is the built-in theme the file extends, DefaultThemeName when empty
+	Base   string                 `json:"base,omitempty"`
+	Colors map[string]CustomColor `json:"colors"`
+}
+
+// CustomColor is a color of a theme file, given once for both terminal
+// backgrounds or separately for each
+type CustomColor lipgloss.AdaptiveColor
+
+// UnmarshalJSON accepts either a color string or a {"dark", "light"} object
+func (c *CustomColor) UnmarshalJSON(data []byte) error {
+	var color string
+	if err := json.Unmarshal(data, &color); err == nil {
+		if !validColor(color) {
+			return fmt.Errorf("invalid color %q", color)
+		}
+		*c = CustomColor{Dark: color, Light: color}
+		return nil
+	}
+	var adaptive struct {
+		Dark  string `json:"dark"`
+		Light string `json:"light"`
+	}
+	if err := json.Unmarshal(data, &adaptive); err != nil {
+		return fmt.Errorf("color must be a string or an object with dark and light colors")
+	}
+	if !validColor(adaptive.Dark) || !validColor(adaptive.Light) {
+		return fmt.Errorf("invalid color %s, dark and light must both be set", data)
+	}
+	*c = CustomColor{Dark: adaptive.Dark, Light: adaptive.Light}
+	return nil
+}
+
+// CustomTheme is a theme loaded from a theme file
+type CustomTheme struct {
+	BaseTheme
+}
+
+// LoadCustomThemes registers the themes of the .json files in dir, each named
+// after its file. Files that cannot be loaded, or that would replace a
+// built-in theme, are skipped with a warning. A missing dir has no themes.
+func LoadCustomThemes(dir string) (loaded []string, errs []error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			errs = append(errs, fmt.Errorf("failed to read theme directory %s: %w", dir, err))
+		}
+		return nil, errs
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), ".json")
+		if GetTheme(name) != nil && !IsCustomTheme(name) {
+			errs = append(errs, fmt.Errorf("theme %s: a built-in theme has the same name", entry.Name()))
+			continue
+		}
+		theme, err := LoadCustomTheme(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("theme %s: %w", entry.Name(), err))
+			continue
+		}
+		registerCustomTheme(name, theme)
+		loaded = append(loaded, name)
+	}
+
+	for _, err := range errs {
+		logging.Warn("Skipping custom theme", "error", err)
+	}
+	return loaded, errs
+}
+
+// LoadCustomTheme reads a theme file
+func LoadCustomTheme(path string) (*CustomTheme, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file CustomThemeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid theme file: %w", err)
+	}
+
+	if file.Base == "" {
+		file.Base = DefaultThemeName
+	}
+	if IsCustomTheme(file.Base) {
+		return nil, fmt.Errorf("base theme '%s' is not a built-in theme", file.Base)
+	}
+	base := GetTheme(file.Base)
+	if base == nil {
+		return nil, fmt.Errorf("base theme '%s' not found", file.Base)
+	}
+
+	theme := &CustomTheme{BaseTheme: copyColors(base)}
+	fields := reflect.ValueOf(&theme.BaseTheme).Elem()
+	keys := make([]string, 0, len(file.Colors))
+	for key := range file.Colors {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		field := fields.FieldByName(colorField(key))
+		if !field.IsValid() || !startsLower(key) {
+			return nil, fmt.Errorf("unknown color '%s'", key)
+		}
+		field.Set(reflect.ValueOf(lipgloss.AdaptiveColor(file.Colors[key])))
+	}
+	return theme, nil
+}
+
+// copyColors returns the colors of a theme as a BaseTheme, each color of the
+// Theme interface being stored in the BaseTheme field of the same name
+func copyColors(theme Theme) BaseTheme {
+	var colors BaseTheme
+	fields := reflect.ValueOf(&colors).Elem()
+	themeType := reflect.TypeOf((*Theme)(nil)).Elem()
+	value := reflect.ValueOf(theme)
+	for i := 0; i < themeType.NumMethod(); i++ {
+		name := themeType.Method(i).Name
+		fields.FieldByName(name + "Color").Set(value.MethodByName(name).Call(nil)[0])
+	}
+	return colors
+}
+
+// colorField returns the BaseTheme field of a theme file color key
+func colorField(key string) string {
+	if key == "" {
+		return ""
+	}
+	return strings.ToUpper(key[:1]) + key[1:] + "Color"
+}
+
+// validColor reports whether color is a hex color or an ANSI color number, as
+// understood by lipgloss
+func validColor(color string) bool {
+	if hex, ok := strings.CutPrefix(color, "#"); ok {
+		if len(hex) != 3 && len(hex) != 6 {
+			return false
+		}
+		_, err := strconv.ParseUint(hex, 16, 32)
+		return err == nil
+	}
+	n, err := strconv.Atoi(color)
+	return err == nil && n >= 0 && n <= 255
+}
+
+func startsLower(key string) bool {
+	return key != "" && unicode.IsLower(rune(key[0]))
+}
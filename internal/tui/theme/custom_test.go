@@ -0,0 +1,82 @@
+package theme
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func writeTheme(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadCustomThemes(t *testing.T) {
+	dir := t.TempDir()
+	writeTheme(t, dir, "custom-dracula.json", `{
+		"base": "dracula",
+		"colors": {
+			"primary": "#ff0000",
+			"diffAddedBg": {"dark": "#002200", "light": "#ddffdd"}
+		}
+	}`)
+	writeTheme(t, dir, "custom-malformed.json", `{"colors": {`)
+	writeTheme(t, dir, "custom-unknown.json", `{"colors": {"sparkle": "#fff"}}`)
+	writeTheme(t, dir, "custom-invalid.json", `{"colors": {"text": "red"}}`)
+	writeTheme(t, dir, "dracula.json", `{"colors": {}}`)
+	writeTheme(t, dir, "notes.txt", `not a theme`)
+
+	loaded, errs := LoadCustomThemes(dir)
+	if len(loaded) != 1 || loaded[0] != "custom-dracula" {
+		t.Fatalf("LoadCustomThemes() loaded %v, want only custom-dracula", loaded)
+	}
+	if len(errs) != 4 {
+		t.Errorf("LoadCustomThemes() errors = %v, want the malformed, unknown, invalid and shadowing files", errs)
+	}
+	if !IsCustomTheme("custom-dracula") || IsCustomTheme("dracula") {
+		t.Errorf("IsCustomTheme() does not tell custom themes from built-in ones")
+	}
+	if GetTheme("custom-malformed") != nil {
+		t.Errorf("a malformed theme file was registered")
+	}
+
+	custom, dracula := GetTheme("custom-dracula"), GetTheme("dracula")
+	if got := custom.Primary(); got != (lipgloss.AdaptiveColor{Dark: "#ff0000", Light: "#ff0000"}) {
+		t.Errorf("Primary() = %v, want the override for both backgrounds", got)
+	}
+	if got := custom.DiffAddedBg(); got != (lipgloss.AdaptiveColor{Dark: "#002200", Light: "#ddffdd"}) {
+		t.Errorf("DiffAddedBg() = %v, want the adaptive override", got)
+	}
+	if custom.Text() != dracula.Text() || custom.CaronexBorder() != dracula.CaronexBorder() {
+		t.Errorf("colors that are not overridden differ from the base theme")
+	}
+}
+
+func TestLoadCustomThemesMissingDirectory(t *testing.T) {
+	loaded, errs := LoadCustomThemes(filepath.Join(t.TempDir(), "missing"))
+	if len(loaded) != 0 || len(errs) != 0 {
+		t.Errorf("LoadCustomThemes() = %v, %v, want no themes and no errors", loaded, errs)
+	}
+}
+
+func TestLoadCustomThemeDefaultBase(t *testing.T) {
+	dir := t.TempDir()
+	writeTheme(t, dir, "plain.json", `{"colors": {"accent": "212"}}`)
+	writeTheme(t, dir, "missing-base.json", `{"base": "solarized", "colors": {}}`)
+
+	custom, err := LoadCustomTheme(filepath.Join(dir, "plain.json"))
+	if err != nil {
+		t.Fatalf("LoadCustomTheme() error = %v", err)
+	}
+	if custom.Background() != GetTheme(DefaultThemeName).Background() {
+		t.Errorf("a theme without a base does not extend %s", DefaultThemeName)
+	}
+	if _, err := LoadCustomTheme(filepath.Join(dir, "missing-base.json")); err == nil || !strings.Contains(err.Error(), "solarized") {
+		t.Errorf("LoadCustomTheme() error = %v, want the missing base theme", err)
+	}
+}
@@ -292,8 +292,11 @@ func NewIntelligenceInterfaceTheme() *IntelligenceInterfaceTheme {
 	return theme
 }
 
+// DefaultThemeName is the theme used when the configuration selects none
+const DefaultThemeName = "intelligence-interface"
+
 func init() {
 	// Register the Intelligence Interface theme with the theme manager
-	RegisterTheme("intelligence-interface", NewIntelligenceInterfaceTheme())
+	RegisterTheme(DefaultThemeName, NewIntelligenceInterfaceTheme())
 }
 
@@ -15,6 +15,7 @@ import (
 // It maintains a registry of available themes and tracks the currently active theme.
 type Manager struct {
 	themes      map[string]Theme
+	custom      map[string]bool // names of the themes loaded from theme files
 	currentName string
 	mu          sync.RWMutex
 }
@@ -22,6 +23,7 @@ type Manager struct {
 // Global instance of the theme manager
 var globalManager = &Manager{
 	themes:      make(map[string]Theme),
+	custom:      make(map[string]bool),
 	currentName: "",
 }
 
@@ -39,6 +41,23 @@ func RegisterTheme(name string, theme Theme) {
 	}
 }
 
+// registerCustomTheme adds a theme loaded from a theme file to the registry
+func registerCustomTheme(name string, theme Theme) {
+	RegisterTheme(name, theme)
+
+	globalManager.mu.Lock()
+	defer globalManager.mu.Unlock()
+	globalManager.custom[name] = true
+}
+
+// IsCustomTheme reports whether a theme was loaded from a theme file rather than built in.
+func IsCustomTheme(name string) bool {
+	globalManager.mu.RLock()
+	defer globalManager.mu.RUnlock()
+
+	return globalManager.custom[name]
+}
+
 // SetTheme changes the active theme to the one with the specified name.
 // Returns an error if the theme doesn't exist.
 func SetTheme(name string) error {
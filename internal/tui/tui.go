@@ -2,19 +2,31 @@ package tui
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 
+	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	zone "github.com/lrstanley/bubblezone"
 	"github.com/caronex/intelligence-interface/internal/app"
+	"github.com/caronex/intelligence-interface/internal/artifact"
+	"github.com/caronex/intelligence-interface/internal/connectivity"
 	"github.com/caronex/intelligence-interface/internal/core/config"
 	"github.com/caronex/intelligence-interface/internal/llm/agent"
 	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/llm/tools"
+	"github.com/caronex/intelligence-interface/internal/message"
 	"github.com/caronex/intelligence-interface/internal/permission"
 	"github.com/caronex/intelligence-interface/internal/pubsub"
+	"github.com/caronex/intelligence-interface/internal/scratchpad"
 	"github.com/caronex/intelligence-interface/internal/session"
+	"github.com/caronex/intelligence-interface/internal/tools/coordination"
+	"github.com/caronex/intelligence-interface/internal/tracing"
 	"github.com/caronex/intelligence-interface/internal/tui/components/chat"
 	"github.com/caronex/intelligence-interface/internal/tui/components/core"
 	"github.com/caronex/intelligence-interface/internal/tui/components/dialog"
@@ -34,10 +46,45 @@ type keyMap struct {
 	Models        key.Binding
 	SwitchTheme   key.Binding
 	CaronexManager key.Binding
+	ToolPalette   key.Binding
 }
 
 type startCompactSessionMsg struct{}
 
+// toggleContextFreezeMsg freezes or resumes the project context of the session
+type toggleContextFreezeMsg struct{}
+
+// showConfigChangesMsg shows how the configuration changed since the
+// session started
+type showConfigChangesMsg struct{}
+
+// editSessionTagsMsg asks for the tags of the current session
+type editSessionTagsMsg struct{}
+
+// sessionTagsCommandID identifies the arguments dialog editing the tags of
+// the current session
+const sessionTagsCommandID = "session-tags"
+
+// editScratchpadMsg opens the scratchpad of the current session in $EDITOR
+type editScratchpadMsg struct{}
+
+// revertScratchpadMsg reverts the scratchpad of the current session to its
+// previous revision
+type revertScratchpadMsg struct{}
+
+// exportTranscriptMsg writes the current session as Markdown in the
+// workspace
+type exportTranscriptMsg struct{}
+
+// showNotesMsg shows the notes of the workspace memory
+type showNotesMsg struct{}
+
+// showToolPaletteMsg shows the palette of the tools the user runs directly
+type showToolPaletteMsg struct{}
+
+// toggleAgentMsg switches to the other agent
+type toggleAgentMsg struct{}
+
 const (
 	quitKey = "q"
 )
@@ -64,7 +111,7 @@ var keys = keyMap{
 
 	Commands: key.NewBinding(
 		key.WithKeys("ctrl+k"),
-		key.WithHelp("ctrl+k", "commands"),
+		key.WithHelp("ctrl+k", "command palette"),
 	),
 	Filepicker: key.NewBinding(
 		key.WithKeys("ctrl+f"),
@@ -84,8 +131,17 @@ var keys = keyMap{
 		key.WithKeys("ctrl+m"),
 		key.WithHelp("ctrl+m", "manager mode"),
 	),
+
+	ToolPalette: key.NewBinding(
+		key.WithKeys("ctrl+p"),
+		key.WithHelp("ctrl+p", "run tool"),
+	),
 }
 
+var mouseHelp = key.NewBinding(
+	key.WithHelp("mouse", "scroll messages, click sessions and the agent"),
+)
+
 var helpEsc = key.NewBinding(
 	key.WithKeys("?"),
 	key.WithHelp("?", "toggle help"),
@@ -129,6 +185,11 @@ type appModel struct {
 
 	showModelDialog bool
 	modelDialog     dialog.ModelDialog
+	// retryModel is set while the model dialog picks the model to retry with
+	retryModel bool
+	// compareModel is set while the model dialog picks the model the next
+	// message is compared with
+	compareModel bool
 
 	showInitDialog bool
 	initDialog     dialog.InitDialogCmp
@@ -142,8 +203,23 @@ type appModel struct {
 	showMultiArgumentsDialog bool
 	multiArgumentsDialog     dialog.MultiArgumentsDialogCmp
 
+	showMessageDetails bool
+	messageDetails     dialog.MessageDetailsDialog
+
+	showConfigChanges bool
+	configChanges     dialog.ConfigChangesDialog
+
+	showNotes bool
+	notes     dialog.NotesDialog
+
+	showToolPalette bool
+	toolPalette     dialog.ToolPaletteDialog
+
 	isCompacting      bool
 	compactingMessage string
+
+	// managerMode is set while the chat page talks to the manager agent
+	managerMode bool
 }
 
 func (a appModel) Init() tea.Cmd {
@@ -179,7 +255,14 @@ func (a appModel) Init() tea.Cmd {
 				Msg:  "Failed to check init status: " + err.Error(),
 			}
 		}
-		return dialog.ShowInitDialogMsg{Show: shouldShow}
+		// On the first run, the configuration of other tools can be imported
+		var imports []string
+		if shouldShow && !config.HasConfigFile() {
+			for _, source := range config.DetectImports(config.WorkingDirectory()) {
+				imports = append(imports, string(source))
+			}
+		}
+		return dialog.ShowInitDialogMsg{Show: shouldShow, Imports: imports}
 	})
 
 	return tea.Batch(cmds...)
@@ -305,6 +388,39 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.showSessionDialog = false
 		return a, nil
 
+	case dialog.SessionFilterChangedMsg:
+		if err := a.refreshSessionDialog(); err != nil {
+			return a, util.ReportError(err)
+		}
+		return a, nil
+
+	case dialog.ArchiveSessionsMsg:
+		changed, err := a.app.Sessions.Archive(context.Background(), msg.IDs, msg.Archived)
+		if refreshErr := a.refreshSessionDialog(); err == nil {
+			err = refreshErr
+		}
+		if err != nil {
+			return a, util.ReportError(err)
+		}
+		if msg.Archived {
+			return a, util.ReportInfo(fmt.Sprintf("Archived %d sessions", changed))
+		}
+		return a, util.ReportInfo(fmt.Sprintf("Unarchived %d sessions", changed))
+
+	case dialog.DeleteSessionsMsg:
+		report, err := a.app.Sessions.DeleteMany(context.Background(), msg.IDs, msg.DryRun)
+		if err != nil {
+			return a, util.ReportError(err)
+		}
+		if msg.DryRun {
+			a.sessionDialog.SetDeleteReport(msg.IDs, report)
+			return a, nil
+		}
+		if err := a.refreshSessionDialog(); err != nil {
+			return a, util.ReportError(err)
+		}
+		return a, util.ReportInfo(fmt.Sprintf("Deleted %d sessions with %d messages", report.Sessions, report.Messages))
+
 	case dialog.CloseCommandDialogMsg:
 		a.showCommandDialog = false
 		return a, nil
@@ -326,8 +442,24 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return nil
 		}
 
+	case toggleContextFreezeMsg:
+		if a.selectedSession.ID == "" {
+			return a, util.ReportWarn("No active session to freeze the context of")
+		}
+		frozen := !a.app.CaronexAgent.IsContextFrozen(a.selectedSession.ID)
+		a.app.CaronexAgent.FreezeContext(a.selectedSession.ID, frozen)
+		if frozen {
+			return a, util.ReportInfo("Context frozen: context file changes are ignored in this session")
+		}
+		return a, util.ReportInfo("Context unfrozen: the current context files are sent on the next message")
+
 	case pubsub.Event[agent.AgentEvent]:
 		payload := msg.Payload
+		if payload.Type == agent.AgentEventTypeAuto {
+			s, cmd := a.status.Update(msg)
+			a.status = s.(core.StatusCmp)
+			return a, cmd
+		}
 		if payload.Error != nil {
 			a.isCompacting = false
 			return a, util.ReportError(payload.Error)
@@ -342,7 +474,7 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			model := a.app.CaronexAgent.Model()
 			contextWindow := model.ContextWindow
 			tokens := a.selectedSession.CompletionTokens + a.selectedSession.PromptTokens
-			if (tokens >= int64(float64(contextWindow)*0.95)) && config.Get().AutoCompact {
+			if config.Get().AutoCompact.ShouldCompact(tokens, contextWindow, a.selectedSession.MessageCount) {
 				return a, util.CmdHandler(startCompactSessionMsg{})
 			}
 		}
@@ -360,20 +492,229 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case dialog.CloseModelDialogMsg:
 		a.showModelDialog = false
+		a.retryModel = false
+		a.compareModel = false
+		return a, nil
+
+	case chat.ShowMessageDetailsMsg:
+		a.messageDetails = dialog.NewMessageDetailsCmp(msg.Message, tracing.ForMessage(msg.Message.ID))
+		a.showMessageDetails = true
+		return a, nil
+
+	case dialog.CloseMessageDetailsMsg:
+		a.showMessageDetails = false
+		return a, nil
+
+	case editSessionTagsMsg:
+		if a.selectedSession.ID == "" {
+			return a, util.ReportWarn("No session to tag")
+		}
+		return a, util.CmdHandler(dialog.ShowMultiArgumentsDialogMsg{
+			CommandID: sessionTagsCommandID,
+			ArgNames:  []string{"tags"},
+			Values:    []string{strings.Join(a.selectedSession.Tags, ", ")},
+		})
+
+	case editScratchpadMsg:
+		if a.selectedSession.ID == "" {
+			return a, util.ReportWarn("No active session")
+		}
+		return a, chat.EditScratchpad(a.selectedSession.ID)
+
+	case revertScratchpadMsg:
+		if a.selectedSession.ID == "" {
+			return a, util.ReportWarn("No active session")
+		}
+		revisions, err := scratchpad.Revisions(a.selectedSession.ID)
+		if err != nil {
+			return a, util.ReportError(err)
+		}
+		if len(revisions) < 2 {
+			return a, util.ReportWarn("The scratchpad has no previous revision")
+		}
+		reverted, err := scratchpad.Revert(a.selectedSession.ID, "user", revisions[1].Number)
+		if err != nil {
+			return a, util.ReportError(err)
+		}
+		return a, util.ReportInfo(fmt.Sprintf("Scratchpad reverted to revision %d, saved as revision %d", revisions[1].Number, reverted.Number))
+
+	case showConfigChangesMsg:
+		if a.selectedSession.ID == "" {
+			return a, util.ReportWarn("No active session")
+		}
+		changes, err := a.app.Sessions.ConfigChanges(context.Background(), a.selectedSession)
+		if err != nil && !errors.Is(err, session.ErrConfigSnapshotMissing) {
+			return a, util.ReportError(err)
+		}
+		if err == nil && len(changes) == 0 {
+			return a, util.ReportInfo("The configuration did not change since this session started")
+		}
+		a.configChanges = dialog.NewConfigChangesCmp(changes)
+		a.showConfigChanges = true
+		return a, nil
+
+	case exportTranscriptMsg:
+		if a.selectedSession.ID == "" {
+			return a, util.ReportWarn("No active session")
+		}
+		msgs, err := a.app.Messages.List(context.Background(), a.selectedSession.ID)
+		if err != nil {
+			return a, util.ReportError(err)
+		}
+		name := a.selectedSession.ID + "-transcript.md"
+		// The artifacts of the responses are exported next to the transcript
+		var artifacts []message.Artifact
+		for _, msg := range msgs {
+			artifacts = append(artifacts, msg.Artifacts()...)
+		}
+		artifactsDir := ""
+		if len(artifacts) > 0 {
+			artifactsDir = a.selectedSession.ID + "-artifacts"
+			if err := artifact.CopyTo(a.selectedSession.ID, artifacts, filepath.Join(config.WorkingDirectory(), artifactsDir)); err != nil {
+				return a, util.ReportError(fmt.Errorf("failed to export the artifacts: %w", err))
+			}
+		}
+		transcript := message.Transcript(a.selectedSession.Title, msgs, artifactsDir)
+		notes, err := scratchpad.Transcript(a.selectedSession.ID)
+		if err != nil {
+			return a, util.ReportError(fmt.Errorf("failed to export the scratchpad: %w", err))
+		}
+		transcript += notes
+		if err := os.WriteFile(filepath.Join(config.WorkingDirectory(), name), []byte(transcript), 0o644); err != nil {
+			return a, util.ReportError(fmt.Errorf("failed to export the transcript: %w", err))
+		}
+		if artifactsDir != "" {
+			return a, util.ReportInfo(fmt.Sprintf("Transcript exported to %s, artifacts to %s", name, artifactsDir))
+		}
+		return a, util.ReportInfo(fmt.Sprintf("Transcript exported to %s", name))
+
+	case dialog.OpenArtifactsMsg:
+		sessionID := msg.SessionID
+		if sessionID == "" {
+			sessionID = a.selectedSession.ID
+		}
+		if sessionID == "" {
+			return a, util.ReportWarn("No active session")
+		}
+		if err := artifact.Open(artifact.Dir(sessionID)); err != nil {
+			return a, util.ReportError(err)
+		}
+		return a, util.ReportInfo(fmt.Sprintf("Opened %s", artifact.Dir(sessionID)))
+
+	case dialog.ExportPatchMsg:
+		changes := msg.Message.WorkspaceChanges()
+		if changes == nil {
+			return a, nil
+		}
+		path, err := artifact.ExportPatch(msg.Message.SessionID, *changes)
+		if err != nil {
+			return a, util.ReportError(fmt.Errorf("failed to export the patch: %w", err))
+		}
+		return a, util.ReportInfo(fmt.Sprintf("Patch exported to %s", path))
+
+	case dialog.CopyArtifactMsg:
+		// The details close so the permission dialog of the copy gets the keys
+		a.showMessageDetails = false
+		write := tools.NewWriteTool(a.app.LSPClients, a.app.Permissions, a.app.History)
+		return a, func() tea.Msg {
+			response, err := artifact.CopyToWorkspace(context.Background(), write, msg.Message.SessionID, msg.Message.ID, msg.Artifact)
+			if err != nil {
+				return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+			}
+			if response.IsError {
+				return util.InfoMsg{Type: util.InfoTypeWarn, Msg: response.Content}
+			}
+			return util.InfoMsg{Type: util.InfoTypeInfo, Msg: fmt.Sprintf("Copied %s into the project", msg.Artifact.Name)}
+		}
+
+	case dialog.CloseConfigChangesMsg:
+		a.showConfigChanges = false
+		return a, nil
+
+	case showNotesMsg:
+		notes, err := dialog.NewNotesDialogCmp()
+		if err != nil {
+			return a, util.ReportError(err)
+		}
+		a.notes = notes
+		a.showNotes = true
+		return a, nil
+
+	case dialog.CloseNotesDialogMsg:
+		a.showNotes = false
+		return a, nil
+
+	case showToolPaletteMsg:
+		a.toolPalette = dialog.NewToolPaletteDialogCmp(agent.UserTools(a.app.Analytics))
+		a.showToolPalette = true
+		return a, nil
+
+	case dialog.ToolResultMsg:
+		if a.showToolPalette {
+			d, cmd := a.toolPalette.Update(msg)
+			a.toolPalette = d.(dialog.ToolPaletteDialog)
+			return a, cmd
+		}
+		return a, nil
+
+	case dialog.CloseToolPaletteMsg:
+		a.showToolPalette = false
+		return a, nil
+
+	case chat.SelectRetryModelMsg:
+		if a.currentPage == page.ChatPage && !a.showQuit && !a.showPermissions && !a.showSessionDialog && !a.showCommandDialog {
+			a.showModelDialog = true
+			a.retryModel = true
+		}
+		return a, nil
+
+	case chat.SelectCompareModelMsg:
+		if a.currentPage == page.ChatPage && !a.showQuit && !a.showPermissions && !a.showSessionDialog && !a.showCommandDialog {
+			a.showModelDialog = true
+			a.compareModel = true
+		}
 		return a, nil
 
 	case dialog.ModelSelectedMsg:
 		a.showModelDialog = false
+		if a.compareModel {
+			// Compare with the model without changing the agent model
+			a.compareModel = false
+			return a, util.CmdHandler(chat.CompareModelSelectedMsg{Model: msg.Model})
+		}
+		if a.retryModel {
+			// Retry with the model without changing the agent model
+			a.retryModel = false
+			return a, util.CmdHandler(chat.RetryMsg{Model: msg.Model.ID})
+		}
+
+		if a.selectedSession.Model != "" {
+			// The session runs with a model of its own, which is the one
+			// changed
+			sess, err := a.app.Sessions.Get(context.Background(), a.selectedSession.ID)
+			if err != nil {
+				return a, util.ReportError(err)
+			}
+			sess.Model = string(msg.Model.ID)
+			if _, err := a.app.Sessions.Save(context.Background(), sess); err != nil {
+				return a, util.ReportError(err)
+			}
+			return a, util.ReportInfo(fmt.Sprintf("Model of the session changed to %s", msg.Model.Name))
+		}
 
 		model, err := a.app.CaronexAgent.Update(config.AgentCaronex, msg.Model.ID)
 		if err != nil {
 			return a, util.ReportError(err)
 		}
 
-		return a, util.ReportInfo(fmt.Sprintf("Model changed to %s", model.Name))
+		return a, tea.Batch(
+			util.ReportInfo(fmt.Sprintf("Model changed to %s", model.Name)),
+			util.CmdHandler(chat.BannerChangedMsg{}),
+		)
 
 	case dialog.ShowInitDialogMsg:
 		a.showInitDialog = msg.Show
+		a.initDialog.SetImports(msg.Imports)
 		return a, nil
 
 	case dialog.CloseInitDialogMsg:
@@ -422,7 +763,7 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case dialog.ShowMultiArgumentsDialogMsg:
 		// Show multi-arguments dialog
-		a.multiArgumentsDialog = dialog.NewMultiArgumentsDialogCmp(msg.CommandID, msg.Content, msg.ArgNames)
+		a.multiArgumentsDialog = dialog.NewMultiArgumentsDialogCmp(msg.CommandID, msg.Content, msg.ArgNames).WithValues(msg.Values)
 		a.showMultiArgumentsDialog = true
 		return a, a.multiArgumentsDialog.Init()
 
@@ -430,6 +771,21 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Close multi-arguments dialog
 		a.showMultiArgumentsDialog = false
 
+		if msg.CommandID == sessionTagsCommandID {
+			if !msg.Submit || a.selectedSession.ID == "" {
+				return a, nil
+			}
+			sess, err := a.app.Sessions.Get(context.Background(), a.selectedSession.ID)
+			if err != nil {
+				return a, util.ReportError(err)
+			}
+			sess.Tags = session.ParseTags(msg.Args["tags"])
+			if _, err := a.app.Sessions.Save(context.Background(), sess); err != nil {
+				return a, util.ReportError(err)
+			}
+			return a, util.ReportInfo("Session tags saved")
+		}
+
 		// If submitted, replace all named arguments and run the command
 		if msg.Submit {
 			content := msg.Content
@@ -448,6 +804,27 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return a, nil
 
+	case page.AgentSwitchedMsg:
+		a.managerMode = msg.AgentMode.IsManagerMode()
+		mode := core.AgentModeCoder
+		if a.managerMode {
+			mode = core.AgentModeManager
+		}
+		s, _ := a.status.Update(core.AgentModeChangedMsg{AgentMode: mode})
+		a.status = s.(core.StatusCmp)
+
+	case tea.MouseMsg:
+		// Clicking the agent in the status bar switches to the other agent
+		if util.Clicked(msg, core.AgentZoneID) && a.currentPage == page.ChatPage && !a.dialogOpen() {
+			return a, a.toggleAgent()
+		}
+
+	case toggleAgentMsg:
+		if a.currentPage == page.ChatPage {
+			return a, a.toggleAgent()
+		}
+		return a, nil
+
 	case tea.KeyMsg:
 		// If multi-arguments dialog is open, let it handle the key press first
 		if a.showMultiArgumentsDialog {
@@ -455,6 +832,18 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			a.multiArgumentsDialog = args.(dialog.MultiArgumentsDialogCmp)
 			return a, cmd
 		}
+		// The notes dialog edits notes, so it takes the keys the app binds too
+		if a.showNotes && !key.Matches(msg, keys.Quit) {
+			d, cmd := a.notes.Update(msg)
+			a.notes = d.(dialog.NotesDialog)
+			return a, cmd
+		}
+		// The tool palette fills in forms, so it takes the keys the app binds too
+		if a.showToolPalette && !key.Matches(msg, keys.Quit) {
+			d, cmd := a.toolPalette.Update(msg)
+			a.toolPalette = d.(dialog.ToolPaletteDialog)
+			return a, cmd
+		}
 
 		switch {
 
@@ -479,6 +868,18 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if a.showMultiArgumentsDialog {
 				a.showMultiArgumentsDialog = false
 			}
+			if a.showMessageDetails {
+				a.showMessageDetails = false
+			}
+			if a.showConfigChanges {
+				a.showConfigChanges = false
+			}
+			if a.showNotes {
+				a.showNotes = false
+			}
+			if a.showToolPalette {
+				a.showToolPalette = false
+			}
 			return a, nil
 		case key.Matches(msg, keys.SwitchSession):
 			if a.currentPage == page.ChatPage && !a.showQuit && !a.showPermissions && !a.showCommandDialog {
@@ -490,29 +891,37 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if len(sessions) == 0 {
 					return a, util.ReportWarn("No sessions available")
 				}
-				a.sessionDialog.SetSessions(sessions)
+				if err := a.refreshSessionDialog(); err != nil {
+					return a, util.ReportError(err)
+				}
 				a.showSessionDialog = true
 				return a, nil
 			}
 			return a, nil
 		case key.Matches(msg, keys.Commands):
 			if a.currentPage == page.ChatPage && !a.showQuit && !a.showPermissions && !a.showSessionDialog && !a.showThemeDialog && !a.showFilepicker {
-				// Show commands dialog
-				if len(a.commands) == 0 {
-					return a, util.ReportWarn("No commands available")
-				}
-				a.commandDialog.SetCommands(a.commands)
+				// Show the command palette
+				a.commandDialog.SetCommands(a.paletteCommands())
 				a.showCommandDialog = true
 				return a, nil
 			}
 			return a, nil
+		case key.Matches(msg, keys.ToolPalette):
+			if a.currentPage == page.ChatPage && !a.dialogOpen() {
+				return a, util.CmdHandler(showToolPaletteMsg{})
+			}
+			return a, nil
 		case key.Matches(msg, keys.Models):
 			if a.showModelDialog {
 				a.showModelDialog = false
+				a.retryModel = false
+				a.compareModel = false
 				return a, nil
 			}
 			if a.currentPage == page.ChatPage && !a.showQuit && !a.showPermissions && !a.showSessionDialog && !a.showCommandDialog {
 				a.showModelDialog = true
+				a.retryModel = false
+				a.compareModel = false
 				return a, nil
 			}
 			return a, nil
@@ -526,7 +935,7 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return a, nil
 		case key.Matches(msg, returnKey) || key.Matches(msg):
 			if msg.String() == quitKey {
-				if a.currentPage == page.LogsPage {
+				if a.currentPage == page.LogsPage || a.currentPage == page.StatsPage {
 					return a, a.moveToPage(page.ChatPage)
 				}
 			} else if !a.filepicker.IsCWDFocused() {
@@ -551,7 +960,7 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					a.filepicker.ToggleFilepicker(a.showFilepicker)
 					return a, nil
 				}
-				if a.currentPage == page.LogsPage {
+				if a.currentPage == page.LogsPage || a.currentPage == page.StatsPage {
 					return a, a.moveToPage(page.ChatPage)
 				}
 			}
@@ -625,8 +1034,9 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		d, sessionCmd := a.sessionDialog.Update(msg)
 		a.sessionDialog = d.(dialog.SessionDialog)
 		cmds = append(cmds, sessionCmd)
-		// Only block key messages send all other messages down
-		if _, ok := msg.(tea.KeyMsg); ok {
+		// Only block key and mouse messages send all other messages down
+		switch msg.(type) {
+		case tea.KeyMsg, tea.MouseMsg:
 			return a, tea.Batch(cmds...)
 		}
 	}
@@ -671,6 +1081,26 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 	}
 
+	if a.showMessageDetails {
+		d, detailsCmd := a.messageDetails.Update(msg)
+		a.messageDetails = d.(dialog.MessageDetailsDialog)
+		cmds = append(cmds, detailsCmd)
+		// Only block key messages send all other messages down
+		if _, ok := msg.(tea.KeyMsg); ok {
+			return a, tea.Batch(cmds...)
+		}
+	}
+
+	if a.showConfigChanges {
+		d, changesCmd := a.configChanges.Update(msg)
+		a.configChanges = d.(dialog.ConfigChangesDialog)
+		cmds = append(cmds, changesCmd)
+		// Only block key messages send all other messages down
+		if _, ok := msg.(tea.KeyMsg); ok {
+			return a, tea.Batch(cmds...)
+		}
+	}
+
 	s, _ := a.status.Update(msg)
 	a.status = s.(core.StatusCmp)
 	a.pages[a.currentPage], cmd = a.pages[a.currentPage].Update(msg)
@@ -678,11 +1108,91 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return a, tea.Batch(cmds...)
 }
 
+// refreshSessionDialog lists the sessions matching the filter of the session
+// dialog in it
+func (a *appModel) refreshSessionDialog() error {
+	sessions, err := a.app.Sessions.Find(context.Background(), a.sessionDialog.Filter())
+	if err != nil {
+		return err
+	}
+	a.sessionDialog.SetSessions(sessions)
+	return nil
+}
+
 // RegisterCommand adds a command to the command dialog
 func (a *appModel) RegisterCommand(cmd dialog.Command) {
 	a.commands = append(a.commands, cmd)
 }
 
+// keyCommand is a command of the palette that a key binding also runs
+type keyCommand struct {
+	id      string
+	title   string
+	binding key.Binding
+	// msg runs the command when its key cannot be replayed
+	msg tea.Msg
+}
+
+// keyCommands lists the commands of the key bindings. They are read each time
+// the palette opens, so it shows the keys currently bound.
+func keyCommands() []keyCommand {
+	return []keyCommand{
+		{id: "new-session", title: "New Session", binding: page.NewSessionKey()},
+		// ctrl+m arrives as enter, so the palette switches agents directly
+		{id: "toggle-manager", title: "Toggle Manager Mode", binding: keys.CaronexManager, msg: toggleAgentMsg{}},
+		{id: "switch-session", title: "Switch Session", binding: keys.SwitchSession},
+		{id: "select-model", title: "Select Model", binding: keys.Models},
+		{id: "switch-theme", title: "Switch Theme", binding: keys.SwitchTheme},
+		{id: "attach-files", title: "Select Files to Upload", binding: keys.Filepicker},
+		{id: "run-tool", title: "Run Tool", binding: keys.ToolPalette, msg: showToolPaletteMsg{}},
+		{id: "logs", title: "Show Logs", binding: keys.Logs},
+		{id: "help", title: "Toggle Help", binding: keys.Help},
+		{id: "quit", title: "Quit", binding: keys.Quit},
+	}
+}
+
+// paletteCommands returns the commands of the command palette: the commands of
+// the key bindings, with their keys, followed by the registered commands
+func (a appModel) paletteCommands() []dialog.Command {
+	bound := keyCommands()
+	commands := make([]dialog.Command, 0, len(bound)+len(a.commands))
+	for _, kc := range bound {
+		if !kc.binding.Enabled() {
+			continue
+		}
+		commands = append(commands, dialog.Command{
+			ID:       kc.id,
+			Title:    kc.title,
+			Shortcut: kc.binding.Help().Key,
+			Handler: func(cmd dialog.Command) tea.Cmd {
+				if kc.msg != nil {
+					return util.CmdHandler(kc.msg)
+				}
+				// Replay the key so the command runs exactly as when it is pressed
+				for _, k := range kc.binding.Keys() {
+					if msg, ok := util.KeyMsg(k); ok {
+						return util.CmdHandler(msg)
+					}
+				}
+				return util.ReportWarn("Cannot run " + kc.title + " from the palette")
+			},
+		})
+	}
+	return append(commands, a.commands...)
+}
+
+// toggleAgent switches to the agent that is not active
+func (a appModel) toggleAgent() tea.Cmd {
+	var mode page.AgentMode = page.ManagerMode{}
+	if a.managerMode {
+		mode = page.CoderMode{}
+	}
+	return util.CmdHandler(page.AgentSwitchedMsg{
+		AgentMode: mode,
+		Agent:     a.app.CaronexAgent,
+	})
+}
+
 func (a *appModel) findCommand(id string) (dialog.Command, bool) {
 	for _, cmd := range a.commands {
 		if cmd.ID == id {
@@ -787,12 +1297,15 @@ func (a appModel) View() string {
 		if a.showPermissions {
 			bindings = append(bindings, a.permissions.BindingKeys()...)
 		}
-		if a.currentPage == page.LogsPage {
+		if a.currentPage == page.LogsPage || a.currentPage == page.StatsPage {
 			bindings = append(bindings, logsKeyReturnKey)
 		}
 		if !a.app.CaronexAgent.IsBusy() {
 			bindings = append(bindings, helpEsc)
 		}
+		if cfg := config.Get(); cfg != nil && cfg.TUI.EnableMouse {
+			bindings = append(bindings, mouseHelp)
+		}
 		a.help.SetBindings(bindings)
 
 		overlay := a.help.View()
@@ -895,6 +1408,66 @@ func (a appModel) View() string {
 		)
 	}
 
+	if a.showMessageDetails {
+		overlay := a.messageDetails.View()
+		row := lipgloss.Height(appView) / 2
+		row -= lipgloss.Height(overlay) / 2
+		col := lipgloss.Width(appView) / 2
+		col -= lipgloss.Width(overlay) / 2
+		appView = layout.PlaceOverlay(
+			col,
+			row,
+			overlay,
+			appView,
+			true,
+		)
+	}
+
+	if a.showConfigChanges {
+		overlay := a.configChanges.View()
+		row := lipgloss.Height(appView) / 2
+		row -= lipgloss.Height(overlay) / 2
+		col := lipgloss.Width(appView) / 2
+		col -= lipgloss.Width(overlay) / 2
+		appView = layout.PlaceOverlay(
+			col,
+			row,
+			overlay,
+			appView,
+			true,
+		)
+	}
+
+	if a.showNotes {
+		overlay := a.notes.View()
+		row := lipgloss.Height(appView) / 2
+		row -= lipgloss.Height(overlay) / 2
+		col := lipgloss.Width(appView) / 2
+		col -= lipgloss.Width(overlay) / 2
+		appView = layout.PlaceOverlay(
+			col,
+			row,
+			overlay,
+			appView,
+			true,
+		)
+	}
+
+	if a.showToolPalette {
+		overlay := a.toolPalette.View()
+		row := lipgloss.Height(appView) / 2
+		row -= lipgloss.Height(overlay) / 2
+		col := lipgloss.Width(appView) / 2
+		col -= lipgloss.Width(overlay) / 2
+		appView = layout.PlaceOverlay(
+			col,
+			row,
+			overlay,
+			appView,
+			true,
+		)
+	}
+
 	if a.showMultiArgumentsDialog {
 		overlay := a.multiArgumentsDialog.View()
 		row := lipgloss.Height(appView) / 2
@@ -910,7 +1483,14 @@ func (a appModel) View() string {
 		)
 	}
 
-	return appView
+	return zone.Scan(appView)
+}
+
+// dialogOpen reports whether a dialog is shown over the current page
+func (a appModel) dialogOpen() bool {
+	return a.showQuit || a.showPermissions || a.showSessionDialog || a.showCommandDialog ||
+		a.showModelDialog || a.showInitDialog || a.showFilepicker || a.showThemeDialog ||
+		a.showMultiArgumentsDialog || a.showMessageDetails || a.showHelp
 }
 
 func New(app *app.App) tea.Model {
@@ -930,8 +1510,9 @@ func New(app *app.App) tea.Model {
 		app:           app,
 		commands:      []dialog.Command{},
 		pages: map[page.PageID]tea.Model{
-			page.ChatPage: page.NewChatPage(app),
-			page.LogsPage: page.NewLogsPage(),
+			page.ChatPage:  page.NewChatPage(app),
+			page.LogsPage:  page.NewLogsPage(),
+			page.StatsPage: page.NewStatsPage(app),
 		},
 		filepicker: dialog.NewFilepickerCmp(app),
 	}
@@ -966,6 +1547,190 @@ If there are Cursor rules (in .cursor/rules/ or .cursorrules) or Copilot rules (
 			}
 		},
 	})
+	model.RegisterCommand(dialog.Command{
+		ID:          "freeze-context",
+		Title:       "Toggle Context Freeze",
+		Description: "Stop or resume sending context file changes to the current session",
+		Handler: func(cmd dialog.Command) tea.Cmd {
+			return util.CmdHandler(toggleContextFreezeMsg{})
+		},
+	})
+	model.RegisterCommand(dialog.Command{
+		ID:          "edit-scratchpad",
+		Title:       "Edit Scratchpad",
+		Description: "Edit the working notes of the current session in $EDITOR",
+		Handler: func(cmd dialog.Command) tea.Cmd {
+			return util.CmdHandler(editScratchpadMsg{})
+		},
+	})
+	model.RegisterCommand(dialog.Command{
+		ID:          "revert-scratchpad",
+		Title:       "Revert Scratchpad",
+		Description: "Restore the previous revision of the working notes of the current session",
+		Handler: func(cmd dialog.Command) tea.Cmd {
+			return util.CmdHandler(revertScratchpadMsg{})
+		},
+	})
+	model.RegisterCommand(dialog.Command{
+		ID:          "session-tags",
+		Title:       "Edit Session Tags",
+		Description: "Tag the current session, comma separated, to find and prune it later",
+		Handler: func(cmd dialog.Command) tea.Cmd {
+			return util.CmdHandler(editSessionTagsMsg{})
+		},
+	})
+	model.RegisterCommand(dialog.Command{
+		ID:          "config-changes",
+		Title:       "Show Config Changes",
+		Description: "List how the configuration changed since the current session started",
+		Handler: func(cmd dialog.Command) tea.Cmd {
+			return util.CmdHandler(showConfigChangesMsg{})
+		},
+	})
+	model.RegisterCommand(dialog.Command{
+		ID:          "notes",
+		Title:       "Workspace Memory",
+		Description: "Browse, edit and delete the notes kept across sessions about this workspace",
+		Handler: func(cmd dialog.Command) tea.Cmd {
+			return util.CmdHandler(showNotesMsg{})
+		},
+	})
+	model.RegisterCommand(dialog.Command{
+		ID:          "stats",
+		Title:       "Usage Stats",
+		Description: "Show local usage analytics (never leaves this machine)",
+		Handler: func(cmd dialog.Command) tea.Cmd {
+			return util.CmdHandler(page.PageChangeMsg{ID: page.StatsPage})
+		},
+	})
+
+	model.RegisterCommand(dialog.Command{
+		ID:          "toggle-offline",
+		Title:       "Toggle Offline Mode",
+		Description: "Make remote provider calls fail fast; local providers keep working",
+		Handler: func(cmd dialog.Command) tea.Cmd {
+			offline := !connectivity.IsOffline()
+			connectivity.SetManualOffline(offline)
+			if offline {
+				return util.ReportWarn("Offline mode enabled")
+			}
+			return util.ReportInfo("Offline mode disabled")
+		},
+	})
+
+	model.RegisterCommand(dialog.Command{
+		ID:          "workspace-root",
+		Title:       "Switch Workspace Root",
+		Description: "Cycle the active root used for relative paths in tool calls",
+		Handler: func(cmd dialog.Command) tea.Cmd {
+			names := config.WorkspaceRootNames()
+			if len(names) == 0 {
+				return util.ReportWarn("No workspace roots configured or detected")
+			}
+
+			// Cycle through the roots, then back to the working directory
+			next := names[0]
+			for i, name := range names {
+				if name == config.ActiveRoot() {
+					next = ""
+					if i+1 < len(names) {
+						next = names[i+1]
+					}
+					break
+				}
+			}
+			if err := config.SetActiveRoot(next); err != nil {
+				return util.ReportError(err)
+			}
+			if next == "" {
+				return util.ReportInfo("Active root: working directory")
+			}
+			return util.ReportInfo(fmt.Sprintf("Active root: %s (%s)", next, config.DefaultRootPath()))
+		},
+	})
+
+	model.RegisterCommand(dialog.Command{
+		ID:          "switch-space",
+		Title:       "Switch Space",
+		Description: "Cycle the active space whose environment variables tools run with",
+		Handler: func(cmd dialog.Command) tea.Cmd {
+			ids := config.SpaceIDs()
+			if len(ids) == 0 {
+				return util.ReportWarn("No spaces configured")
+			}
+
+			// Cycle through the spaces, then back to none
+			next := ids[0]
+			for i, id := range ids {
+				if id == config.ActiveSpace() {
+					next = ""
+					if i+1 < len(ids) {
+						next = ids[i+1]
+					}
+					break
+				}
+			}
+			if err := config.SetActiveSpace(next); err != nil {
+				return util.ReportError(err)
+			}
+			if next == "" {
+				return tea.Batch(util.ReportInfo("No active space"), util.CmdHandler(chat.BannerChangedMsg{}))
+			}
+			return tea.Batch(util.ReportInfo(fmt.Sprintf("Active space: %s", next)), util.CmdHandler(chat.BannerChangedMsg{}))
+		},
+	})
+
+	model.RegisterCommand(dialog.Command{
+		ID:          "export-plan",
+		Title:       "Export Plan Diagram",
+		Description: "Write the latest plan as Mermaid and Graphviz files in the workspace",
+		Handler: func(cmd dialog.Command) tea.Cmd {
+			plan := coordination.LatestPlan()
+			if plan == nil {
+				return util.ReportWarn("No plan created yet")
+			}
+			paths, err := coordination.ExportPlan(plan, config.WorkingDirectory())
+			if err != nil {
+				return util.ReportError(err)
+			}
+			return util.ReportInfo(fmt.Sprintf("Plan exported to %s", strings.Join(paths, " and ")))
+		},
+	})
+
+	model.RegisterCommand(dialog.Command{
+		ID:          "export-transcript",
+		Title:       "Export Session Transcript",
+		Description: "Write the current session as Markdown in the workspace, with the sources it cites and its artifacts",
+		Handler: func(cmd dialog.Command) tea.Cmd {
+			return util.CmdHandler(exportTranscriptMsg{})
+		},
+	})
+
+	model.RegisterCommand(dialog.Command{
+		ID:          "open-artifacts",
+		Title:       "Open Artifact Directory",
+		Description: "Open the directory holding the artifacts saved from the responses of the current session",
+		Handler: func(cmd dialog.Command) tea.Cmd {
+			return util.CmdHandler(dialog.OpenArtifactsMsg{})
+		},
+	})
+
+	model.RegisterCommand(dialog.Command{
+		ID:          "copy-plan",
+		Title:       "Copy Plan Diagram",
+		Description: "Copy the latest plan to the clipboard as a Mermaid flowchart",
+		Handler: func(cmd dialog.Command) tea.Cmd {
+			plan := coordination.LatestPlan()
+			if plan == nil {
+				return util.ReportWarn("No plan created yet")
+			}
+			if err := clipboard.WriteAll(coordination.RenderMermaid(plan)); err != nil {
+				return util.ReportError(fmt.Errorf("failed to copy the plan: %w", err))
+			}
+			return util.ReportInfo("Plan copied to the clipboard as Mermaid")
+		},
+	})
+
 	// Load custom commands
 	customCommands, err := dialog.LoadCustomCommands()
 	if err != nil {
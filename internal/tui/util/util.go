@@ -1,9 +1,12 @@
 package util
 
 import (
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	tea "github.com/charmbracelet/bubbletea"
+	zone "github.com/lrstanley/bubblezone"
 )
 
 func CmdHandler(msg tea.Msg) tea.Cmd {
@@ -56,3 +59,36 @@ func Clamp(v, low, high int) int {
 	}
 	return min(high, max(low, v))
 }
+
+// Clicked reports whether a mouse event releases the left button inside the zone with the given ID
+func Clicked(msg tea.MouseMsg, zoneID string) bool {
+	if msg.Action != tea.MouseActionRelease || msg.Button != tea.MouseButtonLeft {
+		return false
+	}
+	return zone.Get(zoneID).InBounds(msg)
+}
+
+// KeyMsg returns the key press a key of a binding, such as "ctrl+n", "alt+i"
+// or "?", stands for, so the binding can be run as if the key was pressed.
+// It reports false for keys the terminal cannot tell apart from another one,
+// such as "ctrl+m" which arrives as "enter".
+func KeyMsg(k string) (tea.KeyMsg, bool) {
+	msg := tea.KeyMsg{}
+	if rest, ok := strings.CutPrefix(k, "alt+"); ok && rest != "" {
+		msg.Alt = true
+		k = rest
+	}
+	if utf8.RuneCountInString(k) == 1 {
+		msg.Type = tea.KeyRunes
+		msg.Runes = []rune(k)
+		return msg, true
+	}
+	// Control keys are the ASCII control codes, other special keys are negative
+	for t := tea.KeyType(-128); t <= tea.KeyBackspace; t++ {
+		if t.String() == k {
+			msg.Type = t
+			return msg, true
+		}
+	}
+	return msg, false
+}
@@ -1,9 +1,29 @@
 package version
 
-import "runtime/debug"
+import (
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
 
 // Build-time parameters set via -ldflags
-var Version = "unknown"
+var (
+	Version    = "unknown"
+	CommitHash = "unknown"
+	BuildTime  = "unknown"
+)
+
+// GoVersion is the Go toolchain version the binary was built with.
+var GoVersion = runtime.Version()
+
+// Info describes the build metadata of the running binary.
+type Info struct {
+	Version    string `json:"version"`
+	CommitHash string `json:"commit_hash"`
+	BuildTime  string `json:"build_time"`
+	GoVersion  string `json:"go_version"`
+}
 
 // A user may install ii using `go install github.com/caronex/intelligence-interface@latest`.
 // without -ldflags, in which case the version above is unset. As a workaround
@@ -15,11 +35,80 @@ func init() {
 		// < go v1.18
 		return
 	}
+
+	// VCS stamping is available for both `go build` and `go install`
+	for _, setting := range info.Settings {
+		switch {
+		case setting.Key == "vcs.revision" && CommitHash == "unknown":
+			CommitHash = setting.Value
+		case setting.Key == "vcs.time" && BuildTime == "unknown":
+			BuildTime = setting.Value
+		}
+	}
+
 	mainVersion := info.Main.Version
-	if mainVersion == "" || mainVersion == "(devel)" {
-		// bin not built using `go install`
+	if Version != "unknown" || mainVersion == "" || mainVersion == "(devel)" {
+		// version injected via -ldflags, or bin not built using `go install`
 		return
 	}
 	// bin built using `go install`
 	Version = mainVersion
 }
+
+// SetFallback sets the version reported when neither -ldflags nor the
+// embedded build info provided one.
+func SetFallback(v string) {
+	if Version == "unknown" || Version == "" {
+		Version = v
+	}
+}
+
+// Get returns the build metadata of the running binary.
+func Get() Info {
+	return Info{
+		Version:    Version,
+		CommitHash: CommitHash,
+		BuildTime:  BuildTime,
+		GoVersion:  GoVersion,
+	}
+}
+
+// IsNewer reports whether candidate is a newer semantic version than current.
+// Versions may carry a leading "v" and pre-release/build suffixes, which are
+// ignored. Non-numeric versions (e.g. "dev") are never considered older.
+func IsNewer(candidate, current string) bool {
+	c, ok := parseSemver(candidate)
+	if !ok {
+		return false
+	}
+	cur, ok := parseSemver(current)
+	if !ok {
+		return false
+	}
+	for i := range c {
+		if c[i] != cur[i] {
+			return c[i] > cur[i]
+		}
+	}
+	return false
+}
+
+func parseSemver(v string) ([3]int, bool) {
+	var parts [3]int
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	fields := strings.Split(v, ".")
+	if len(fields) == 0 || len(fields) > 3 {
+		return parts, false
+	}
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
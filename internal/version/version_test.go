@@ -0,0 +1,30 @@
+package version
+
+import "testing"
+
+func TestIsNewer(t *testing.T) {
+	tests := []struct {
+		candidate, current string
+		want               bool
+	}{
+		{"1.2.4", "1.2.3", true},
+		{"v1.3.0", "1.2.9", true},
+		{"2.0.0", "v1.99.99", true},
+		{"1.10.0", "1.9.0", true},
+		{"1.2", "1.1.9", true},
+		{"1.2.3", "1.2.3", false},
+		{"v1.2.3", "1.2.3", false},
+		{"1.2.2", "1.2.3", false},
+		{"1.2.3-rc.1", "1.2.3", false},
+		{"1.2.4+build.5", "1.2.3", true},
+		{"1.2.3", "dev", false},
+		{"latest", "1.2.3", false},
+		{"1.2.3.4", "1.2.3", false},
+		{"", "1.2.3", false},
+	}
+	for _, tt := range tests {
+		if got := IsNewer(tt.candidate, tt.current); got != tt.want {
+			t.Errorf("IsNewer(%q, %q) = %v, want %v", tt.candidate, tt.current, got, tt.want)
+		}
+	}
+}
@@ -3,12 +3,17 @@ package main
 import (
 	"github.com/caronex/intelligence-interface/cmd"
 	"github.com/caronex/intelligence-interface/internal/core/logging"
+	"github.com/caronex/intelligence-interface/internal/version"
 )
 
+// Version is reported when no version was injected at build time.
+var Version = "dev"
+
 func main() {
 	defer logging.RecoverPanic("main", func() {
 		logging.ErrorPersist("Application terminated due to unhandled panic")
 	})
 
+	version.SetFallback(Version)
 	cmd.Execute()
 }
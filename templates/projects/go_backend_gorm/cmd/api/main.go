@@ -17,6 +17,12 @@ import (
 	"go_backend_gorm/internal/utils"
 )
 
+// Generated domain packages
+import (
+// @gohex:begin:domain_imports
+// @gohex:end:domain_imports
+)
+
 func main() {
 	// Create dependency injector
 	injector := do.New()
@@ -51,6 +57,11 @@ func main() {
 	// Register use cases container
 	usecase.RegisterUseCases(injector)
 
+	// Register generated domains, e.g. userDI.RegisterUser(injector).
+	// standardize remove deletes the lines of a domain from these blocks.
+	// @gohex:begin:domain_registrations
+	// @gohex:end:domain_registrations
+
 	// Create and start server
 	server, err := httpServer.NewServer(injector)
 	if err != nil {
@@ -0,0 +1,147 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// BackupsFilename is the name of the file recording the backups taken before
+// generated files were overwritten, in the output directory
+const BackupsFilename = ".standardize-backups.json"
+
+// Backup is a copy of a file taken before a run overwrote it. Paths are
+// relative to the output directory.
+type Backup struct {
+	Original  string `json:"original"`
+	Backup    string `json:"backup"`
+	Timestamp string `json:"timestamp"`
+}
+
+// backupRun backs up the files a run overwrites, with the timestamp of the
+// run in their names
+type backupRun struct {
+	timestamp string
+	backups   []Backup
+}
+
+// newBackupRun starts the backups of a run started at now
+func newBackupRun(now time.Time) *backupRun {
+	return &backupRun{timestamp: now.UTC().Format(time.RFC3339)}
+}
+
+// backup copies path to <path>.bak.<timestamp> when it exists with content
+// other than the content about to be written
+func (r *backupRun) backup(path, content string) error {
+	existing, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if string(existing) == content {
+		return nil
+	}
+
+	backupPath := path + ".bak." + r.timestamp
+	if err := os.WriteFile(backupPath, existing, 0644); err != nil {
+		return fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+	fmt.Printf("Backed up %s to %s\n", path, backupPath)
+	r.backups = append(r.backups, Backup{Original: path, Backup: backupPath, Timestamp: r.timestamp})
+	return nil
+}
+
+// save adds the backups of the run to the backups file
+func (r *backupRun) save() error {
+	if len(r.backups) == 0 {
+		return nil
+	}
+	backups, err := LoadBackups()
+	if err != nil {
+		return err
+	}
+	return saveBackups(append(backups, r.backups...))
+}
+
+// LoadBackups reads the backups file. It returns nil without an error when
+// no backup was taken.
+func LoadBackups() ([]Backup, error) {
+	content, err := os.ReadFile(BackupsFilename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backups: %w", err)
+	}
+
+	var backups []Backup
+	if err := json.Unmarshal(content, &backups); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", BackupsFilename, err)
+	}
+	return backups, nil
+}
+
+// saveBackups writes the backups file
+func saveBackups(backups []Backup) error {
+	content, err := json.MarshalIndent(backups, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal backups: %w", err)
+	}
+	if err := os.WriteFile(BackupsFilename, append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write backups: %w", err)
+	}
+	return nil
+}
+
+// BackupTimestamps lists the timestamps of the runs that took backups, oldest first
+func BackupTimestamps(backups []Backup) []string {
+	seen := make(map[string]bool)
+	var timestamps []string
+	for _, backup := range backups {
+		if !seen[backup.Timestamp] {
+			seen[backup.Timestamp] = true
+			timestamps = append(timestamps, backup.Timestamp)
+		}
+	}
+	sort.Strings(timestamps)
+	return timestamps
+}
+
+// restoreBackups copies back the originals backed up by the run at timestamp,
+// returning the files restored
+func restoreBackups(timestamp string) ([]string, error) {
+	if _, err := time.Parse(time.RFC3339, timestamp); err != nil {
+		return nil, fmt.Errorf("invalid backup timestamp %q: want the RFC 3339 timestamp of a run, e.g. 2006-01-02T15:04:05Z", timestamp)
+	}
+	backups, err := LoadBackups()
+	if err != nil {
+		return nil, err
+	}
+
+	var restored []string
+	for _, backup := range backups {
+		if backup.Timestamp != timestamp {
+			continue
+		}
+		content, err := os.ReadFile(backup.Backup)
+		if err != nil {
+			return restored, fmt.Errorf("failed to read backup of %s: %w", backup.Original, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(backup.Original), 0755); err != nil {
+			return restored, fmt.Errorf("failed to create directory of %s: %w", backup.Original, err)
+		}
+		if err := os.WriteFile(backup.Original, content, 0644); err != nil {
+			return restored, fmt.Errorf("failed to restore %s: %w", backup.Original, err)
+		}
+		restored = append(restored, backup.Original)
+	}
+	if len(restored) == 0 {
+		return nil, fmt.Errorf("no backups were taken at %s", timestamp)
+	}
+	return restored, nil
+}
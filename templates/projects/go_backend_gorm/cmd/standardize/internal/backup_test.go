@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBackupOnOverwriteAndRestore(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	existing := filepath.Join("internal", "core", "models", "order", "order.go")
+	writeFile(t, existing, "package order // hand edit\n")
+	created := filepath.Join("internal", "di", "order", "di.go")
+
+	tg := NewTemplateGenerator()
+	run := newBackupRun(time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC))
+	tg.backups = run
+	for _, path := range []string{existing, created} {
+		if err := tg.writeFile(path, "package order\n"); err != nil {
+			t.Fatalf("writeFile(%s) error = %v", path, err)
+		}
+	}
+	// Writing the same content again overwrites nothing
+	if err := tg.writeFile(existing, "package order\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := run.save(); err != nil {
+		t.Fatalf("save() error = %v", err)
+	}
+
+	backups, err := LoadBackups()
+	if err != nil {
+		t.Fatalf("LoadBackups() error = %v", err)
+	}
+	want := Backup{Original: existing, Backup: existing + ".bak.2025-03-01T12:00:00Z", Timestamp: "2025-03-01T12:00:00Z"}
+	if len(backups) != 1 || backups[0] != want {
+		t.Fatalf("backups = %+v, want only %+v", backups, want)
+	}
+	if got := readFile(t, want.Backup); got != "package order // hand edit\n" {
+		t.Errorf("backup = %q, want the content before the overwrite", got)
+	}
+
+	ch := NewCommandHandler()
+	if err := ch.Restore("2025-03-02T12:00:00Z"); err == nil {
+		t.Error("Restore() of a run without backups succeeded")
+	}
+	if err := ch.Restore("yesterday"); err == nil {
+		t.Error("Restore() of an invalid timestamp succeeded")
+	}
+	if err := ch.Restore(want.Timestamp); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if got := readFile(t, existing); got != "package order // hand edit\n" {
+		t.Errorf("restored file = %q, want the backed up content", got)
+	}
+}
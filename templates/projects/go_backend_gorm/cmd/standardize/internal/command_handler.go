@@ -2,6 +2,13 @@ package internal
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 // CommandHandler handles CLI command execution
@@ -32,10 +39,85 @@ func (ch *CommandHandler) GenerateFromConfig(configPath string) error {
 	// Generate files
 	fmt.Printf("Generating files for domain '%s' from config...\n", config.Domain)
 	
-	if err := ch.templateGenerator.GenerateAllFiles(data, true); err != nil {
+	err = ch.withBackups(data, func() error {
+		return ch.templateGenerator.GenerateAllFiles(data, true)
+	})
+	if err != nil {
 		return fmt.Errorf("failed to generate files: %w", err)
 	}
 
+	return ch.recordManifest(data, configPath, ch.configOutputPaths(data))
+}
+
+// withBackups runs generate with the files it overwrites backed up when the
+// configuration asks for it. The backups taken are recorded even when
+// generate fails midway.
+func (ch *CommandHandler) withBackups(data TemplateData, generate func() error) error {
+	if !data.Generation.BackupOnOverwrite {
+		return generate()
+	}
+
+	run := newBackupRun(time.Now())
+	ch.templateGenerator.backups = run
+	defer func() { ch.templateGenerator.backups = nil }()
+
+	err := generate()
+	if saveErr := run.save(); saveErr != nil && err == nil {
+		err = saveErr
+	}
+	return err
+}
+
+// configOutputPaths lists every file GenerateFromConfig writes
+func (ch *CommandHandler) configOutputPaths(data TemplateData) []string {
+	paths := outputPaths(ch.templateGenerator.allFiles(data, true))
+	if data.Generation.GenerateOpenAPI {
+		paths = append(paths, OpenAPISpecPath)
+	}
+	return paths
+}
+
+// outputPaths returns the output path of each file spec
+func outputPaths(specs []fileSpec) []string {
+	paths := make([]string, len(specs))
+	for i, spec := range specs {
+		paths[i] = spec.outputPath
+	}
+	return paths
+}
+
+// recordManifest records the files just written for a domain in its manifest
+func (ch *CommandHandler) recordManifest(data TemplateData, configPath string, paths []string) error {
+	manifest, err := LoadManifest(data.DomainSnake)
+	if err != nil {
+		return err
+	}
+	if manifest == nil {
+		manifest = &Manifest{Domain: data.DomainSnake}
+	}
+	if err := ch.updateManifest(manifest, data, configPath); err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read generated file: %w", err)
+		}
+		manifest.Record(path, string(content))
+	}
+	return manifest.Save()
+}
+
+// updateManifest sets how the files of a domain were generated
+func (ch *CommandHandler) updateManifest(manifest *Manifest, data TemplateData, configPath string) error {
+	hash, err := configHash(data)
+	if err != nil {
+		return err
+	}
+	manifest.Entity = data.Entity
+	manifest.Config = configPath
+	manifest.ConfigHash = hash
 	return nil
 }
 
@@ -45,23 +127,326 @@ func (ch *CommandHandler) GenerateLegacy(domain, entity, command string) error {
 	data := ch.configProcessor.CreateLegacyTemplateData(domain, entity)
 
 	// Generate based on command
+	specs, err := ch.legacyFiles(data, command)
+	if err != nil {
+		return err
+	}
+	err = ch.withBackups(data, func() error {
+		return ch.templateGenerator.generateFiles(specs, data)
+	})
+	if err != nil {
+		return err
+	}
+	return ch.recordManifest(data, "", outputPaths(specs))
+}
+
+// EffectiveConfig returns the configuration merged with its includes and with
+// defaults set, as YAML
+func (ch *CommandHandler) EffectiveConfig(configPath string) (string, error) {
+	config, err := ch.configProcessor.EffectiveConfig(configPath)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal effective config: %w", err)
+	}
+	return string(data), nil
+}
+
+// CheckVersion reports the schema version of a configuration and the
+// migrations that apply to it
+func (ch *CommandHandler) CheckVersion(configPath string) (string, error) {
+	return ch.configProcessor.CheckConfigVersion(configPath)
+}
+
+// MigrateInPlace rewrites a configuration and its includes to the current
+// schema version, listing the files it changed
+func (ch *CommandHandler) MigrateInPlace(configPath string) error {
+	changed, err := ch.configProcessor.MigrateConfigFiles(configPath)
+	if err != nil {
+		return err
+	}
+	if len(changed) == 0 {
+		fmt.Printf("%s is up to date with version %s\n", configPath, CurrentDomainConfigVersion)
+		return nil
+	}
+	for _, path := range changed {
+		fmt.Printf("Migrated %s to version %s\n", path, CurrentDomainConfigVersion)
+	}
+	return nil
+}
+
+// Restore copies back the files backed up by the run at timestamp. Without a
+// timestamp, it lists the runs that took backups.
+func (ch *CommandHandler) Restore(timestamp string) error {
+	if timestamp == "" {
+		backups, err := LoadBackups()
+		if err != nil {
+			return err
+		}
+		timestamps := BackupTimestamps(backups)
+		if len(timestamps) == 0 {
+			return fmt.Errorf("no backups in %s", BackupsFilename)
+		}
+		fmt.Println("Backups:")
+		for _, timestamp := range timestamps {
+			fmt.Printf("  %s\n", timestamp)
+		}
+		return fmt.Errorf("choose the backup to restore with --backup <timestamp>")
+	}
+
+	restored, err := restoreBackups(timestamp)
+	for _, path := range restored {
+		fmt.Printf("Restored %s\n", path)
+	}
+	return err
+}
+
+// GeneratePreviewFromConfig returns the files GenerateFromConfig would write,
+// keyed by relative path, without touching the disk
+func (ch *CommandHandler) GeneratePreviewFromConfig(configPath string) (map[string]string, error) {
+	config, err := ch.configProcessor.LoadConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return ch.renderConfigFiles(ch.configProcessor.CreateTemplateData(*config))
+}
+
+// renderConfigFiles renders every file generated from a configuration, keyed by output path
+func (ch *CommandHandler) renderConfigFiles(data TemplateData) (map[string]string, error) {
+	files, err := ch.templateGenerator.renderFiles(ch.templateGenerator.allFiles(data, true), data)
+	if err != nil {
+		return nil, err
+	}
+	if data.Generation.GenerateOpenAPI {
+		spec, err := GenerateOpenAPISpec(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render %s: %w", OpenAPISpecPath, err)
+		}
+		files[OpenAPISpecPath] = string(spec)
+	}
+	return files, nil
+}
+
+// GeneratePreview returns the files GenerateLegacy would write, keyed by
+// relative path, without touching the disk
+func (ch *CommandHandler) GeneratePreview(domain, entity, command string) (map[string]string, error) {
+	data := ch.configProcessor.CreateLegacyTemplateData(domain, entity)
+
+	specs, err := ch.legacyFiles(data, command)
+	if err != nil {
+		return nil, err
+	}
+	return ch.templateGenerator.renderFiles(specs, data)
+}
+
+// Regenerate re-renders the files recorded in the manifest of a domain, using
+// the configuration file it was generated from when there was one
+func (ch *CommandHandler) Regenerate(domain string, force bool) error {
+	manifest, err := LoadManifest(ToSnakeCase(domain))
+	if err != nil {
+		return err
+	}
+	if manifest == nil {
+		return fmt.Errorf("domain '%s' has no manifest at %s; generate it first", domain, ManifestPath(ToSnakeCase(domain)))
+	}
+
+	if manifest.Config != "" {
+		return ch.RegenerateFromConfig(manifest.Config, force)
+	}
+
+	// Legacy commands may have generated only some layers of the domain
+	data := ch.configProcessor.CreateLegacyTemplateData(manifest.Domain, manifest.Entity)
+	var specs []fileSpec
+	for _, spec := range ch.templateGenerator.allFiles(data, false) {
+		if _, ok := manifest.Hash(spec.outputPath); ok {
+			specs = append(specs, spec)
+		}
+	}
+	files, err := ch.templateGenerator.renderFiles(specs, data)
+	if err != nil {
+		return err
+	}
+	return ch.regenerate(manifest, data, "", files, force)
+}
+
+// RegenerateFromConfig re-renders every file of the domain configured in
+// configPath, writing only the files whose generated content changed
+func (ch *CommandHandler) RegenerateFromConfig(configPath string, force bool) error {
+	config, err := ch.configProcessor.LoadConfig(configPath)
+	if err != nil {
+		return err
+	}
+	data := ch.configProcessor.CreateTemplateData(*config)
+	files, err := ch.renderConfigFiles(data)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := LoadManifest(data.DomainSnake)
+	if err != nil {
+		return err
+	}
+	if manifest == nil {
+		manifest = &Manifest{Domain: data.DomainSnake}
+	}
+	return ch.regenerate(manifest, data, configPath, files, force)
+}
+
+// regenerate writes the rendered files that changed and updates the manifest.
+// Files untouched since they were generated are skipped when their content
+// is the same. Files modified by hand are reported and only rewritten when
+// their custom code sections are preserved or force is set.
+func (ch *CommandHandler) regenerate(manifest *Manifest, data TemplateData, configPath string, files map[string]string, force bool) error {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var written, unchanged, modified int
+	err := ch.withBackups(data, func() error {
+		for _, path := range paths {
+			content := files[path]
+
+			existing, err := os.ReadFile(path)
+			if err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to read %s: %w", path, err)
+			}
+			if err == nil && string(existing) == content {
+				manifest.Record(path, content)
+				unchanged++
+				continue
+			}
+
+			if err == nil {
+				recorded, ok := manifest.Hash(path)
+				if !ok || recorded != hashContent(string(existing)) {
+					modified++
+					switch {
+					case force:
+						fmt.Printf("Modified by hand, overwriting: %s\n", path)
+					case data.Generation.PreserveCustomCode:
+						fmt.Printf("Modified by hand, keeping custom code sections: %s\n", path)
+					default:
+						fmt.Printf("Modified by hand, skipped: %s (use --force to overwrite)\n", path)
+						continue
+					}
+				}
+			}
+
+			if err := ch.templateGenerator.writeFile(path, content); err != nil {
+				return err
+			}
+			manifest.Record(path, content)
+			written++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := ch.updateManifest(manifest, data, configPath); err != nil {
+		return err
+	}
+	if err := manifest.Save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Regenerated %d files, %d unchanged, %d modified by hand\n", written, unchanged, modified)
+	return nil
+}
+
+// Remove deletes the files recorded in the manifest of a domain and removes
+// the domain from the registration files. It refuses to delete files modified
+// by hand unless force is set.
+func (ch *CommandHandler) Remove(domain string, force bool) error {
+	domainSnake := ToSnakeCase(domain)
+	manifest, err := LoadManifest(domainSnake)
+	if err != nil {
+		return err
+	}
+	if manifest == nil {
+		return fmt.Errorf("domain '%s' has no manifest at %s; remove its files by hand", domain, ManifestPath(domainSnake))
+	}
+
+	var modified []string
+	for _, file := range manifest.Files {
+		state, err := file.State()
+		if err != nil {
+			return err
+		}
+		if state == FileModified {
+			modified = append(modified, file.Path)
+		}
+	}
+	if len(modified) > 0 && !force {
+		return fmt.Errorf("files of domain '%s' were modified by hand: %s (use --force to delete them anyway)",
+			domain, strings.Join(modified, ", "))
+	}
+
+	removed := []string{ManifestPath(domainSnake)}
+	for _, file := range manifest.Files {
+		if err := os.Remove(file.Path); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("failed to remove %s: %w", file.Path, err)
+		}
+		fmt.Printf("Removed %s\n", file.Path)
+		removed = append(removed, file.Path)
+	}
+	if err := os.Remove(ManifestPath(domainSnake)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove manifest: %w", err)
+	}
+	for _, path := range removed {
+		removeEmptyDirs(path)
+	}
+
+	changed, err := unregisterDomain(domainSnake)
+	if err != nil {
+		return err
+	}
+	for _, path := range changed {
+		fmt.Printf("Unregistered domain '%s' from %s\n", domain, path)
+	}
+	return nil
+}
+
+// removeEmptyDirs removes the directories of path that are left empty, up to
+// but not including its top-level directory
+func removeEmptyDirs(path string) {
+	for dir := filepath.Dir(path); filepath.Dir(dir) != "." && dir != "."; dir = filepath.Dir(dir) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+	}
+}
+
+// legacyFiles lists the files generated by a legacy command
+func (ch *CommandHandler) legacyFiles(data TemplateData, command string) ([]fileSpec, error) {
+	tg := ch.templateGenerator
 	switch command {
 	case "entity":
-		return ch.templateGenerator.GenerateEntityFiles(data, false)
+		return tg.entityFiles(data, false), nil
 	case "model":
-		return ch.templateGenerator.GenerateModelFiles(data)
+		return tg.modelFiles(data), nil
 	case "repository":
-		return ch.templateGenerator.GenerateRepositoryFiles(data, false)
+		return tg.repositoryFiles(data, false), nil
 	case "usecase":
-		return ch.templateGenerator.GenerateUseCaseFiles(data, false)
+		return tg.useCaseFiles(data, false), nil
 	case "handler":
-		return ch.templateGenerator.GenerateHandlerFiles(data)
+		return tg.handlerFiles(data), nil
 	case "di":
-		return ch.templateGenerator.GenerateDIFiles(data)
+		return tg.diFiles(data), nil
 	case "all":
-		return ch.templateGenerator.GenerateAllFiles(data, false)
+		return tg.allFiles(data, false), nil
 	default:
-		return fmt.Errorf("unknown command: %s", command)
+		return nil, fmt.Errorf("unknown command: %s", command)
 	}
 }
 
@@ -96,5 +481,17 @@ func (ch *CommandHandler) GetAvailableCommands() []Command {
 			Name:        "all",
 			Description: "Generate all files",
 		},
+		{
+			Name:        "regenerate",
+			Description: "Regenerate the files of a generated domain that changed",
+		},
+		{
+			Name:        "remove",
+			Description: "Remove the generated files of a domain and un-register it",
+		},
+		{
+			Name:        "restore",
+			Description: "Restore the files backed up before a run overwrote them",
+		},
 	}
 }
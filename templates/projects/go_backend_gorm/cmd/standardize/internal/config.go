@@ -1,5 +1,7 @@
 package internal
 
+import "time"
+
 // Configuration structures for YAML parsing
 
 // DomainConfig represents the complete domain configuration
@@ -18,6 +20,9 @@ type DomainConfig struct {
 	Generation  GenerationConfig `yaml:"generation,omitempty"`
 	Features    FeaturesConfig `yaml:"features,omitempty"`
 	Module      string      `yaml:"module,omitempty"`
+	// ModuleSource tells where Module comes from, one of the ModuleSource
+	// constants, set when the configuration is loaded
+	ModuleSource string `yaml:"module_source,omitempty"`
 }
 
 // EntityConfig represents entity configuration
@@ -87,7 +92,10 @@ type ModelConfig struct {
 	Name                string                    `yaml:"name"`
 	TableName           string                    `yaml:"table_name,omitempty"`
 	Description         string                    `yaml:"description,omitempty"`
-	Fields              []ModelFieldConfig        `yaml:"fields,omitempty"`
+	Fields              []ModelFieldConfig        `yaml:"model_fields,omitempty"`
+	// LegacyFields are the fields of a version 1.0 configuration, moved to
+	// Fields by MigrateConfig
+	LegacyFields        []ModelFieldConfig        `yaml:"fields,omitempty"`
 	Indexes             []ModelIndexConfig        `yaml:"indexes,omitempty"`
 	Constraints         []ModelConstraintConfig   `yaml:"constraints,omitempty"`
 	Hooks               ModelHooksConfig          `yaml:"hooks,omitempty"`
@@ -165,6 +173,10 @@ type ModelRelationshipConfig struct {
 	ForeignKey   string `yaml:"foreign_key,omitempty"`
 	JoinTable    string `yaml:"join_table,omitempty"`
 	Description  string `yaml:"description,omitempty"`
+	// ForeignKeyField overrides the {Entity}ID name of the field added for belongsTo relationships
+	ForeignKeyField string `yaml:"foreign_key_field,omitempty"`
+	// Nullable makes the belongsTo foreign key optional, setting it to NULL when the related row is deleted
+	Nullable bool `yaml:"nullable,omitempty"`
 }
 
 // APIConfig represents API configuration
@@ -219,6 +231,8 @@ type RepositoryMethodsConfig struct {
 	Count     bool `yaml:"count,omitempty"`
 	Exists    bool `yaml:"exists,omitempty"`
 	GetByField bool `yaml:"get_by_field,omitempty"`
+	// Search is set when the filtering configuration has search fields
+	Search    bool `yaml:"search,omitempty"`
 }
 
 // RepositoryMethodConfig represents individual repository method configuration
@@ -277,6 +291,15 @@ type FilteringConfig struct {
 	SearchFields []string `yaml:"search_fields,omitempty"`
 }
 
+// SearchColumns returns the columns of the search fields
+func (f FilteringConfig) SearchColumns() []string {
+	columns := make([]string, len(f.SearchFields))
+	for i, field := range f.SearchFields {
+		columns[i] = ToSnakeCase(field)
+	}
+	return columns
+}
+
 // CachingConfig represents caching configuration
 type CachingConfig struct {
 	Enabled bool   `yaml:"enabled,omitempty"`
@@ -284,6 +307,22 @@ type CachingConfig struct {
 	Keys    []string `yaml:"keys,omitempty"`
 }
 
+// DefaultCacheTTL is the cache TTL used when caching is enabled without one
+const DefaultCacheTTL = "5m"
+
+// ParsedTTL returns the TTL as a duration. Zero means cached entries never
+// expire; the TTL is validated when the configuration is loaded.
+func (c CachingConfig) ParsedTTL() time.Duration {
+	if c.TTL == "" {
+		return 0
+	}
+	ttl, err := time.ParseDuration(c.TTL)
+	if err != nil {
+		return 0
+	}
+	return ttl
+}
+
 // LoggingConfig represents logging configuration
 type LoggingConfig struct {
 	Enabled bool   `yaml:"enabled,omitempty"`
@@ -360,6 +399,20 @@ type UseCaseImplConfig struct {
 	ErrorHandling   string   `yaml:"error_handling,omitempty"`
 }
 
+// UnitOfWorkDependency is the use case dependency running the repository calls
+// of transactional business methods in one transaction
+const UnitOfWorkDependency = "UnitOfWork"
+
+// UsesUnitOfWork reports whether the use case depends on a UnitOfWork
+func (c UseCaseImplConfig) UsesUnitOfWork() bool {
+	for _, dependency := range c.Dependencies {
+		if dependency == UnitOfWorkDependency {
+			return true
+		}
+	}
+	return false
+}
+
 // BusinessMethodConfig represents business logic method configuration
 type BusinessMethodConfig struct {
 	Name           string                   `yaml:"name"`
@@ -626,6 +679,7 @@ type GenerationConfig struct {
 	UUIDPrimaryKey     bool `yaml:"uuid_primary_key,omitempty"`
 	OverwriteGenerated bool `yaml:"overwrite_generated,omitempty"`
 	BackupOnOverwrite  bool `yaml:"backup_on_overwrite,omitempty"`
+	GenerateOpenAPI    bool `yaml:"generate_openapi,omitempty"`
 }
 
 // FeaturesConfig represents feature flags
@@ -2,12 +2,39 @@ package internal
 
 import (
 	"fmt"
+	"go/token"
+	"log/slog"
+	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
-	"gopkg.in/yaml.v3"
+	"golang.org/x/mod/modfile"
 )
 
+// Schema versions of the domain configuration
+const (
+	DomainConfigV1 = "1.0"
+	DomainConfigV2 = "2.0"
+
+	// CurrentDomainConfigVersion is the version configurations are migrated to
+	CurrentDomainConfigVersion = DomainConfigV2
+)
+
+// Sources of DomainConfig.Module
+const (
+	// ModuleSourceExplicit is a module set in the configuration
+	ModuleSourceExplicit = "explicit"
+	// ModuleSourceAuto is a module read from the nearest go.mod
+	ModuleSourceAuto = "auto"
+	// ModuleSourceDefault is DefaultModule, used without a go.mod
+	ModuleSourceDefault = "default"
+)
+
+// DefaultModule is the module of configurations outside of a Go module
+const DefaultModule = "go_backend_gorm"
+
 // ConfigProcessor handles configuration file processing
 type ConfigProcessor struct{}
 
@@ -16,29 +43,68 @@ func NewConfigProcessor() *ConfigProcessor {
 	return &ConfigProcessor{}
 }
 
-// LoadConfig loads and parses a YAML configuration file
+// LoadConfig loads and parses a YAML configuration file, merged with the
+// files it includes
 func (cp *ConfigProcessor) LoadConfig(configPath string) (*DomainConfig, error) {
-	// Read configuration file
-	configData, err := os.ReadFile(configPath)
+	domainConfig, err := cp.EffectiveConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Bring older configurations to the current schema, leaving the file as is
+	domainConfig, err = MigrateConfig(domainConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate configuration
+	if err := cp.validateConfig(domainConfig); err != nil {
+		return nil, fmt.Errorf("configuration validation failed: %w", err)
+	}
+
+	return domainConfig, nil
+}
+
+// EffectiveConfig loads a YAML configuration file merged with the files it
+// includes and with defaults set, without validating it
+func (cp *ConfigProcessor) EffectiveConfig(configPath string) (*DomainConfig, error) {
+	// Read configuration file and its includes
+	configNode, err := loadMergedConfig(configPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		return nil, err
 	}
 
 	// Parse YAML configuration
 	var domainConfig DomainConfig
-	if err := yaml.Unmarshal(configData, &domainConfig); err != nil {
+	if err := configNode.Decode(&domainConfig); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
 	// Set defaults
-	cp.setDefaults(&domainConfig)
+	cp.setDefaults(&domainConfig, configPath)
 
-	// Validate configuration
-	if err := cp.validateConfig(&domainConfig); err != nil {
-		return nil, fmt.Errorf("configuration validation failed: %w", err)
+	return &domainConfig, nil
+}
+
+// MigrateConfig returns the configuration migrated to the current schema
+// version by applying the migrations from its version in order. A
+// configuration without a version is a version 1.0 one.
+func MigrateConfig(config *DomainConfig) (*DomainConfig, error) {
+	migrations, err := pendingMigrations(config.Version)
+	if err != nil {
+		return nil, err
 	}
 
-	return &domainConfig, nil
+	migrated := *config
+	migrated.Models = append([]ModelConfig(nil), config.Models...)
+	for _, migration := range migrations {
+		migration.migrate(&migrated)
+		migrated.Version = migration.to
+	}
+	if migrated.Version == "" {
+		migrated.Version = CurrentDomainConfigVersion
+	}
+	return &migrated, nil
 }
 
 // CreateTemplateData creates template data from configuration
@@ -96,6 +162,9 @@ func (cp *ConfigProcessor) CreateTemplateData(config DomainConfig) TemplateData
 	// Process use case configuration
 	useCaseConfig := cp.processUseCaseConfig(config.UseCase, entityPascal, config.Generation)
 
+	// Process handler configuration
+	handlersConfig, endpoints := cp.processHandlersConfig(config.Handlers, config.Endpoints)
+
 	return TemplateData{
 		Domain:        domainPascal,
 		DomainSnake:   domainSnake,
@@ -110,8 +179,8 @@ func (cp *ConfigProcessor) CreateTemplateData(config DomainConfig) TemplateData
 		API:           config.API,
 		Repository:    repoConfig,
 		UseCase:       useCaseConfig,
-		Handlers:      config.Handlers,
-		Endpoints:     config.Endpoints,
+		Handlers:      handlersConfig,
+		Endpoints:     endpoints,
 		Generation:    config.Generation,
 		Features:      config.Features,
 	}
@@ -148,10 +217,18 @@ func (cp *ConfigProcessor) CreateLegacyTemplateData(domain, entity string) Templ
 }
 
 // setDefaults sets default values for configuration
-func (cp *ConfigProcessor) setDefaults(config *DomainConfig) {
-	// Set default module if not provided
-	if config.Module == "" {
-		config.Module = "go_backend_gorm"
+func (cp *ConfigProcessor) setDefaults(config *DomainConfig, configPath string) {
+	// Infer the module from the go.mod the configuration lives under when
+	// not provided
+	if config.Module != "" {
+		config.ModuleSource = ModuleSourceExplicit
+	} else if module, goModPath := findModulePath(configPath); module != "" {
+		config.Module = module
+		config.ModuleSource = ModuleSourceAuto
+		slog.Info("Inferred the module from go.mod", "module", module, "go_mod", goModPath)
+	} else {
+		config.Module = DefaultModule
+		config.ModuleSource = ModuleSourceDefault
 	}
 
 	// Set default generation options
@@ -161,6 +238,32 @@ func (cp *ConfigProcessor) setDefaults(config *DomainConfig) {
 		config.Generation.UUIDPrimaryKey = true
 		config.Generation.GenerateTests = true
 	}
+
+	// Cached entries expire after the default TTL unless configured otherwise
+	if config.Repository.Caching.Enabled && config.Repository.Caching.TTL == "" {
+		config.Repository.Caching.TTL = DefaultCacheTTL
+	}
+}
+
+// findModulePath returns the module path declared by the nearest go.mod in
+// the directories above configPath, and the go.mod path, empty when there is
+// none or it cannot be read
+func findModulePath(configPath string) (string, string) {
+	dir, err := filepath.Abs(filepath.Dir(configPath))
+	if err != nil {
+		return "", ""
+	}
+	for {
+		goModPath := filepath.Join(dir, "go.mod")
+		if data, err := os.ReadFile(goModPath); err == nil {
+			return modfile.ModulePath(data), goModPath
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ""
+		}
+		dir = parent
+	}
 }
 
 // validateConfig validates the configuration
@@ -173,6 +276,43 @@ func (cp *ConfigProcessor) validateConfig(config *DomainConfig) error {
 		config.Entity.Name = ToPascalCase(config.Domain)
 	}
 
+	if config.Version != CurrentDomainConfigVersion {
+		return fmt.Errorf("version %q is not supported, expected %q", config.Version, CurrentDomainConfigVersion)
+	}
+	for i, model := range append([]ModelConfig{config.Model}, config.Models...) {
+		if len(model.LegacyFields) > 0 {
+			return fmt.Errorf("%s: fields was renamed model_fields in version %s", modelPath(i), DomainConfigV2)
+		}
+	}
+
+	for _, endpoint := range configuredEndpoints(config) {
+		if err := validateStatusCode(endpoint.name, endpoint.method, endpoint.statusCode); err != nil {
+			return err
+		}
+	}
+
+	for _, method := range transactionalMethods(config.UseCase) {
+		if _, ok := MethodResults(method.returns); !ok {
+			return fmt.Errorf("%s is transactional and must return an error to report a failed commit, it returns %q", method.name, method.returns)
+		}
+	}
+
+	for _, field := range config.Repository.Filtering.SearchFields {
+		if !token.IsIdentifier(field) {
+			return fmt.Errorf("repository.filtering.search_fields: %q is not a valid field name", field)
+		}
+	}
+
+	if ttl := config.Repository.Caching.TTL; ttl != "" {
+		parsed, err := time.ParseDuration(ttl)
+		if err != nil {
+			return fmt.Errorf("repository.caching.ttl %q is not a valid duration (e.g. \"5m\", \"1h\"): %w", ttl, err)
+		}
+		if parsed < 0 {
+			return fmt.Errorf("repository.caching.ttl %q must not be negative", ttl)
+		}
+	}
+
 	return nil
 }
 
@@ -280,6 +420,9 @@ func (cp *ConfigProcessor) processModelConfig(modelConfig ModelConfig, entityNam
 	// Add standard fields if not present
 	modelConfig.Fields = cp.addStandardModelFields(modelConfig.Fields, generation.UUIDPrimaryKey)
 	
+	// Add the foreign keys of belongsTo relationships if not present
+	modelConfig.Fields = cp.addForeignKeyFields(modelConfig.Fields, modelConfig.Relationships, generation.UUIDPrimaryKey)
+
 	// Process field GORM and JSON tags
 	for i, field := range modelConfig.Fields {
 		modelConfig.Fields[i] = cp.processModelField(field)
@@ -381,6 +524,51 @@ func (cp *ConfigProcessor) addStandardModelFields(fields []ModelFieldConfig, use
 	return finalFields
 }
 
+// addForeignKeyFields adds a foreign key field for every belongsTo relationship
+// whose field is not configured. The field is named {Entity}ID unless the
+// relationship overrides it, and has the primary key type of the related entity.
+func (cp *ConfigProcessor) addForeignKeyFields(fields []ModelFieldConfig, relationships []ModelRelationshipConfig, useUUID bool) []ModelFieldConfig {
+	existingFields := make(map[string]bool)
+	for _, field := range fields {
+		existingFields[field.Name] = true
+	}
+
+	for _, relationship := range relationships {
+		if relationship.Type != "belongsTo" {
+			continue
+		}
+
+		name := relationship.ForeignKeyField
+		if name == "" {
+			name = ToPascalCase(relationship.Entity) + "ID"
+		}
+		if existingFields[name] {
+			continue
+		}
+		existingFields[name] = true
+
+		fieldType, columnType := "uint", ""
+		if useUUID {
+			fieldType, columnType = "uuid.UUID", "type:uuid;"
+		}
+
+		field := ModelFieldConfig{
+			Name:        name,
+			Type:        fieldType,
+			Description: fmt.Sprintf("Foreign key of the related %s", ToSnakeCase(relationship.Entity)),
+			Nullable:    relationship.Nullable,
+		}
+		if relationship.Nullable {
+			field.Type = "*" + fieldType
+			field.GormTags = fmt.Sprintf("`gorm:\"%sindex;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;\"`", columnType)
+		} else {
+			field.GormTags = fmt.Sprintf("`gorm:\"%sindex;not null\"`", columnType)
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
 // processModelField processes individual model field configuration
 func (cp *ConfigProcessor) processModelField(field ModelFieldConfig) ModelFieldConfig {
 	// Generate GORM tags if not provided
@@ -499,10 +687,12 @@ func (cp *ConfigProcessor) processRepositoryConfig(repoConfig RepositoryConfig,
 	// Set default filtering settings
 	if !repoConfig.Filtering.Enabled {
 		repoConfig.Filtering = FilteringConfig{
-			Enabled:   true,
-			Operators: []string{"=", "!=", ">", ">=", "<", "<=", "LIKE", "IN"},
+			Enabled:      true,
+			Operators:    []string{"=", "!=", ">", ">=", "<", "<=", "LIKE", "IN"},
+			SearchFields: repoConfig.Filtering.SearchFields,
 		}
 	}
+	repoConfig.Interface.StandardMethods.Search = len(repoConfig.Filtering.SearchFields) > 0
 	
 	// Set default logging settings
 	if !repoConfig.Logging.Enabled {
@@ -568,6 +758,11 @@ func (cp *ConfigProcessor) processUseCaseConfig(useCaseConfig UseCaseConfig, ent
 		}
 	}
 	
+	// Transactional business methods run their repository calls in a unit of work
+	if len(transactionalMethods(useCaseConfig)) > 0 && !useCaseConfig.Implementation.UsesUnitOfWork() {
+		useCaseConfig.Implementation.Dependencies = append(useCaseConfig.Implementation.Dependencies, UnitOfWorkDependency)
+	}
+	
 	// Set default logging settings
 	if !useCaseConfig.Logging.Enabled {
 		useCaseConfig.Logging = LoggingConfig{
@@ -616,3 +811,157 @@ func (cp *ConfigProcessor) processUseCaseConfig(useCaseConfig UseCaseConfig, ent
 	
 	return useCaseConfig
 }
+
+// DefaultStatusCodes are the success status codes of endpoints that do not set status_code
+var DefaultStatusCodes = map[string]int{
+	http.MethodPost:   http.StatusCreated,
+	http.MethodGet:    http.StatusOK,
+	http.MethodPut:    http.StatusOK,
+	http.MethodPatch:  http.StatusOK,
+	http.MethodDelete: http.StatusNoContent,
+}
+
+// invalidStatusCodes are status codes that contradict the method of an endpoint
+var invalidStatusCodes = map[string][]int{
+	http.MethodGet:    {http.StatusCreated},
+	http.MethodPatch:  {http.StatusCreated},
+	http.MethodDelete: {http.StatusCreated},
+}
+
+// standardEndpointMethods are the methods of standard endpoints that do not set one
+var standardEndpointMethods = map[string]string{
+	"create":    http.MethodPost,
+	"list":      http.MethodGet,
+	"get_by_id": http.MethodGet,
+	"update":    http.MethodPut,
+	"delete":    http.MethodDelete,
+}
+
+// defaultStatusCode returns statusCode, or the default for method when it is not set
+func defaultStatusCode(method string, statusCode int) int {
+	if statusCode != 0 {
+		return statusCode
+	}
+	return DefaultStatusCodes[strings.ToUpper(method)]
+}
+
+// processHandlersConfig sets the default status code of every endpoint without one
+func (cp *ConfigProcessor) processHandlersConfig(handlers HandlersConfig, endpoints []EndpointConfig) (HandlersConfig, []EndpointConfig) {
+	standard := &handlers.StandardEndpoints
+	for name, endpoint := range map[string]*EndpointDetailsConfig{
+		"create":    &standard.Create,
+		"list":      &standard.List,
+		"get_by_id": &standard.GetByID,
+		"update":    &standard.Update,
+		"delete":    &standard.Delete,
+	} {
+		method := endpoint.Method
+		if method == "" {
+			method = standardEndpointMethods[name]
+		}
+		endpoint.StatusCode = defaultStatusCode(method, endpoint.StatusCode)
+	}
+
+	customEndpoints := make([]CustomEndpointConfig, len(handlers.CustomEndpoints))
+	for i, endpoint := range handlers.CustomEndpoints {
+		endpoint.StatusCode = defaultStatusCode(endpoint.Method, endpoint.StatusCode)
+		customEndpoints[i] = endpoint
+	}
+	handlers.CustomEndpoints = customEndpoints
+
+	handlers.Endpoints = processEndpoints(handlers.Endpoints)
+	return handlers, processEndpoints(endpoints)
+}
+
+// processEndpoints returns a copy of endpoints with default status codes applied
+func processEndpoints(endpoints []EndpointConfig) []EndpointConfig {
+	if endpoints == nil {
+		return nil
+	}
+	processed := make([]EndpointConfig, len(endpoints))
+	for i, endpoint := range endpoints {
+		endpoint.StatusCode = defaultStatusCode(endpoint.Method, endpoint.StatusCode)
+		processed[i] = endpoint
+	}
+	return processed
+}
+
+// endpointStatus is the method and configured status code of an endpoint
+type endpointStatus struct {
+	name       string
+	method     string
+	statusCode int
+}
+
+// configuredEndpoints lists the method and status code of every configured endpoint
+func configuredEndpoints(config *DomainConfig) []endpointStatus {
+	var endpoints []endpointStatus
+
+	standard := config.Handlers.StandardEndpoints
+	for _, endpoint := range []struct {
+		name    string
+		details EndpointDetailsConfig
+	}{
+		{"create", standard.Create},
+		{"list", standard.List},
+		{"get_by_id", standard.GetByID},
+		{"update", standard.Update},
+		{"delete", standard.Delete},
+	} {
+		method := endpoint.details.Method
+		if method == "" {
+			method = standardEndpointMethods[endpoint.name]
+		}
+		endpoints = append(endpoints, endpointStatus{"handlers.standard_endpoints." + endpoint.name, method, endpoint.details.StatusCode})
+	}
+	for _, endpoint := range config.Handlers.CustomEndpoints {
+		endpoints = append(endpoints, endpointStatus{"handlers.custom_endpoints." + endpoint.Name, endpoint.Method, endpoint.StatusCode})
+	}
+	for _, endpoint := range config.Handlers.Endpoints {
+		endpoints = append(endpoints, endpointStatus{"handlers.endpoints." + endpoint.Handler, endpoint.Method, endpoint.StatusCode})
+	}
+	for _, endpoint := range config.Endpoints {
+		endpoints = append(endpoints, endpointStatus{"endpoints." + endpoint.Handler, endpoint.Method, endpoint.StatusCode})
+	}
+	return endpoints
+}
+
+// transactionalMethod is the path and returns of a transactional business method
+type transactionalMethod struct {
+	name    string
+	returns string
+}
+
+// transactionalMethods lists the business methods of a use case to run in a transaction
+func transactionalMethods(useCase UseCaseConfig) []transactionalMethod {
+	var methods []transactionalMethod
+	for _, method := range useCase.Interface.BusinessMethods {
+		if method.Transactional {
+			methods = append(methods, transactionalMethod{"use_case.interface.business_methods." + method.Name, method.Returns})
+		}
+	}
+	for _, method := range useCase.BusinessMethods {
+		if method.Transactional {
+			methods = append(methods, transactionalMethod{"use_case.business_methods." + method.Name, method.Returns})
+		}
+	}
+	return methods
+}
+
+// validateStatusCode rejects status codes outside the HTTP range and codes
+// that contradict the endpoint method, such as DELETE with 201 Created
+func validateStatusCode(name, method string, statusCode int) error {
+	if statusCode == 0 {
+		return nil
+	}
+	if statusCode < 100 || statusCode > 599 {
+		return fmt.Errorf("%s: status code %d is not a valid HTTP status code", name, statusCode)
+	}
+	method = strings.ToUpper(method)
+	for _, invalid := range invalidStatusCodes[method] {
+		if statusCode == invalid {
+			return fmt.Errorf("%s: status code %d (%s) is not valid for %s endpoints", name, statusCode, http.StatusText(statusCode), method)
+		}
+	}
+	return nil
+}
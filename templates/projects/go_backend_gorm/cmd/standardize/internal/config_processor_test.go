@@ -0,0 +1,296 @@
+package internal
+
+import (
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfigModule(t *testing.T) {
+	tests := []struct {
+		name       string
+		goMod      string
+		module     string
+		wantModule string
+		wantSource string
+	}{
+		{
+			name:       "explicit",
+			goMod:      "module example.com/shop\n",
+			module:     "example.com/explicit",
+			wantModule: "example.com/explicit",
+			wantSource: ModuleSourceExplicit,
+		},
+		{
+			name:       "from go.mod",
+			goMod:      "module example.com/shop\n\ngo 1.23\n",
+			wantModule: "example.com/shop",
+			wantSource: ModuleSourceAuto,
+		},
+		{
+			name:       "without go.mod",
+			wantModule: DefaultModule,
+			wantSource: ModuleSourceDefault,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.goMod != "" {
+				writeFile(t, filepath.Join(dir, "go.mod"), tt.goMod)
+			}
+			content := "domain: user\n"
+			if tt.module != "" {
+				content += "module: " + tt.module + "\n"
+			}
+			// The go.mod is looked up from the directory of the configuration
+			configPath := filepath.Join(dir, "configs", "domains", "user.yaml")
+			writeFile(t, configPath, content)
+
+			config, err := NewConfigProcessor().LoadConfig(configPath)
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if config.Module != tt.wantModule || config.ModuleSource != tt.wantSource {
+				t.Errorf("module = %q (%s), want %q (%s)", config.Module, config.ModuleSource, tt.wantModule, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestAddForeignKeyFields(t *testing.T) {
+	tests := []struct {
+		name         string
+		relationship ModelRelationshipConfig
+		useUUID      bool
+		wantName     string
+		wantType     string
+	}{
+		{"default", ModelRelationshipConfig{Type: "belongsTo", Entity: "Author"}, false, "AuthorID", "uint"},
+		{"override", ModelRelationshipConfig{Type: "belongsTo", Entity: "User", ForeignKeyField: "WriterID"}, true, "WriterID", "uuid.UUID"},
+		{"foreign key column", ModelRelationshipConfig{Type: "belongsTo", Entity: "User", ForeignKey: "reviewer_id"}, false, "UserID", "uint"},
+		{"nullable", ModelRelationshipConfig{Type: "belongsTo", Entity: "User", ForeignKeyField: "EditorID", Nullable: true}, false, "EditorID", "*uint"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := NewConfigProcessor().addForeignKeyFields([]ModelFieldConfig{{Name: "Title", Type: "string"}}, []ModelRelationshipConfig{tt.relationship}, tt.useUUID)
+			if len(fields) != 2 {
+				t.Fatalf("addForeignKeyFields() = %+v, want the foreign key added", fields)
+			}
+			if got := fields[1]; got.Name != tt.wantName || got.Type != tt.wantType {
+				t.Errorf("foreign key field = %s %s, want %s %s", got.Name, got.Type, tt.wantName, tt.wantType)
+			}
+		})
+	}
+
+	// A configured field is kept, and the other relationships add none
+	fields := []ModelFieldConfig{{Name: "AuthorID", Type: "int64"}}
+	got := NewConfigProcessor().addForeignKeyFields(fields, []ModelRelationshipConfig{
+		{Type: "belongsTo", Entity: "User", ForeignKeyField: "AuthorID"},
+		{Type: "hasMany", Entity: "Comment", ForeignKey: "PostID"},
+	}, false)
+	if len(got) != 1 || got[0].Type != "int64" {
+		t.Errorf("addForeignKeyFields() = %+v, want the configured field only", got)
+	}
+}
+
+func TestTransactionalMethodsUseUnitOfWork(t *testing.T) {
+	setupProject(t)
+	configPath := filepath.Join("configs", "domains", "order.yaml")
+	writeFile(t, configPath, `domain: order
+use_case:
+  business_methods:
+    - name: Approve
+      parameters:
+        - name: id
+          type: uuid.UUID
+      returns: (*entityPkg.Order, error)
+      transactional: true
+    - name: Archive
+      returns: error
+generation:
+  preserve_custom_code: true
+`)
+
+	config, err := NewConfigProcessor().LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	data := NewConfigProcessor().CreateTemplateData(*config)
+	if !data.UseCase.Implementation.UsesUnitOfWork() {
+		t.Fatalf("dependencies = %v, want %s", data.UseCase.Implementation.Dependencies, UnitOfWorkDependency)
+	}
+
+	files, err := NewCommandHandler().GeneratePreviewFromConfig(configPath)
+	if err != nil {
+		t.Fatalf("GeneratePreviewFromConfig() error = %v", err)
+	}
+	useCase := files[filepath.Join("internal", "usecase", "order", "order_usecase.go")]
+	repository := files[filepath.Join("internal", "repository", "order", "order_repository.go")]
+	for _, want := range []string{"ctx, err := uc.uw.Begin(ctx)", "defer uc.uw.Rollback(ctx)", "uc.uw.Commit(ctx)", "result0, err = uc.doApprove(ctx, id)"} {
+		if !strings.Contains(useCase, want) {
+			t.Errorf("use case does not contain %q:\n%s", want, useCase)
+		}
+	}
+	if strings.Contains(useCase, "uc.doArchive") {
+		t.Error("the non transactional Archive runs in a transaction")
+	}
+	for _, want := range []string{"postgres.Begin(ctx, r.db.DB)", "postgres.Commit(ctx)", "postgres.Rollback(ctx)", "postgres.Conn(ctx, r.db.DB)"} {
+		if !strings.Contains(repository, want) {
+			t.Errorf("repository does not contain %q:\n%s", want, repository)
+		}
+	}
+	if strings.Contains(repository, "sync.Mutex") {
+		t.Error("the repository keeps the transaction in progress")
+	}
+	for name, content := range map[string]string{"use case": useCase, "repository": repository} {
+		if _, err := parser.ParseFile(token.NewFileSet(), name, content, 0); err != nil {
+			t.Errorf("%s does not parse: %v", name, err)
+		}
+	}
+
+	// A transaction cannot report a failed commit without an error to return
+	writeFile(t, configPath, `domain: order
+use_case:
+  business_methods:
+    - name: Approve
+      returns: bool
+      transactional: true
+`)
+	if _, err := NewConfigProcessor().LoadConfig(configPath); err == nil || !strings.Contains(err.Error(), "use_case.business_methods.Approve") {
+		t.Errorf("LoadConfig() error = %v, want the transactional method without an error", err)
+	}
+}
+
+func TestSearchFieldsGenerateSearch(t *testing.T) {
+	setupProject(t)
+	configPath := filepath.Join("configs", "domains", "article.yaml")
+	writeFile(t, configPath, `domain: article
+repository:
+  filtering:
+    search_fields: [title, Description]
+`)
+
+	config, err := NewConfigProcessor().LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	data := NewConfigProcessor().CreateTemplateData(*config)
+	if !data.Repository.Interface.StandardMethods.Search {
+		t.Fatal("Search is not set with search fields")
+	}
+	if !data.Repository.Interface.StandardMethods.Create {
+		t.Error("search fields replaced the default standard methods")
+	}
+
+	files, err := NewCommandHandler().GeneratePreviewFromConfig(configPath)
+	if err != nil {
+		t.Fatalf("GeneratePreviewFromConfig() error = %v", err)
+	}
+	generated := map[string]struct {
+		content string
+		want    []string
+	}{
+		"repository": {
+			content: files[filepath.Join("internal", "repository", "article", "article_repository.go")],
+			want:    []string{"Search(ctx context.Context, query string, opts ListOptions)", `"title %[1]s ? OR description %[1]s ?", like), pattern, pattern)`, "db = db.Limit(limit)"},
+		},
+		"use case": {
+			content: files[filepath.Join("internal", "usecase", "article", "article_usecase.go")],
+			want:    []string{"repoPkg.ListOptions{Limit: limit, Offset: offset}"},
+		},
+		"handler": {
+			content: files[filepath.Join("internal", "interface", "http", "handlers", "article", "article.go")],
+			want:    []string{`"/api/v1/articles/search", h.handleSearchArticles`, "UseCase.Search(ctx, q, limit, offset)"},
+		},
+	}
+	for name, file := range generated {
+		for _, want := range file.want {
+			if !strings.Contains(file.content, want) {
+				t.Errorf("%s does not contain %q:\n%s", name, want, file.content)
+			}
+		}
+		if _, err := parser.ParseFile(token.NewFileSet(), name, file.content, 0); err != nil {
+			t.Errorf("%s does not parse: %v", name, err)
+		}
+	}
+
+	// The search fields are written into the query
+	writeFile(t, configPath, `domain: article
+repository:
+  filtering:
+    search_fields: ["title; DROP TABLE articles"]
+`)
+	if _, err := NewConfigProcessor().LoadConfig(configPath); err == nil || !strings.Contains(err.Error(), "search_fields") {
+		t.Errorf("LoadConfig() error = %v, want the invalid search field", err)
+	}
+}
+
+func TestCachingTTL(t *testing.T) {
+	tests := []struct {
+		name    string
+		caching string
+		wantTTL time.Duration
+		wantErr string
+	}{
+		{name: "valid", caching: "enabled: true\n    ttl: 1h", wantTTL: time.Hour},
+		{name: "default when enabled", caching: "enabled: true", wantTTL: 5 * time.Minute},
+		{name: "no default when disabled", caching: "keys: [id]", wantTTL: 0},
+		{name: "invalid", caching: "enabled: true\n    ttl: soon", wantErr: `repository.caching.ttl "soon" is not a valid duration`},
+		{name: "negative", caching: "enabled: true\n    ttl: -1m", wantErr: `repository.caching.ttl "-1m" must not be negative`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "article.yaml")
+			writeFile(t, configPath, "domain: article\nrepository:\n  caching:\n    "+tt.caching+"\n")
+
+			config, err := NewConfigProcessor().LoadConfig(configPath)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if got := config.Repository.Caching.ParsedTTL(); got != tt.wantTTL {
+				t.Errorf("ParsedTTL() = %v, want %v", got, tt.wantTTL)
+			}
+		})
+	}
+
+	// An empty TTL means cached entries never expire
+	if got := (CachingConfig{Enabled: true}).ParsedTTL(); got != 0 {
+		t.Errorf("ParsedTTL() of an empty TTL = %v, want 0", got)
+	}
+}
+
+func TestCachingTTLGeneratesSeconds(t *testing.T) {
+	setupProject(t)
+	configPath := filepath.Join("configs", "domains", "article.yaml")
+	writeFile(t, configPath, `domain: article
+repository:
+  caching:
+    enabled: true
+    ttl: 90s
+`)
+
+	files, err := NewCommandHandler().GeneratePreviewFromConfig(configPath)
+	if err != nil {
+		t.Fatalf("GeneratePreviewFromConfig() error = %v", err)
+	}
+	repository := files[filepath.Join("internal", "repository", "article", "article_repository.go")]
+	if !strings.Contains(repository, "const ArticleCacheTTLSeconds = 90\n") {
+		t.Errorf("repository does not declare the TTL as 90 seconds:\n%s", repository)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "repository", repository, 0); err != nil {
+		t.Errorf("repository does not parse: %v", err)
+	}
+}
@@ -8,88 +8,57 @@ import (
 	"text/template"
 )
 
+// PartialsGlob matches the partial templates shared by every template.
+// Partial files start with an underscore, e.g. templates/partials/_package.tmpl,
+// and each one declares the partials it provides with {{define "name"}}, so any
+// template can include them with {{template "name" .}}.
+var PartialsGlob = filepath.Join("templates", "partials", "_*.tmpl")
+
 // TemplateGenerator handles code generation from templates
-type TemplateGenerator struct{}
+type TemplateGenerator struct {
+	// backups, when set, backs up the files about to be overwritten
+	backups *backupRun
+}
 
 // NewTemplateGenerator creates a new template generator
 func NewTemplateGenerator() *TemplateGenerator {
 	return &TemplateGenerator{}
 }
 
+// fileSpec pairs a template with the file it renders to
+type fileSpec struct {
+	templatePath string
+	outputPath   string
+}
+
 // GenerateEntityFiles generates entity files using configuration
 func (tg *TemplateGenerator) GenerateEntityFiles(data TemplateData, useConfig bool) error {
-	var templatePath string
-	if useConfig {
-		templatePath = filepath.Join("internal", "core", "entity", "{{DOMAIN}}", "entity_config.go.tmpl")
-	} else {
-		templatePath = filepath.Join("internal", "core", "entity", "{{DOMAIN}}", "entity.go.tmpl")
-	}
-	
-	outputPath := filepath.Join("internal", "core", "entity", data.DomainSnake, fmt.Sprintf("%s.go", data.EntitySnake))
-	return tg.generateFile(templatePath, outputPath, data)
+	return tg.generateFiles(tg.entityFiles(data, useConfig), data)
 }
 
 // GenerateModelFiles generates model files
 func (tg *TemplateGenerator) GenerateModelFiles(data TemplateData) error {
-	templatePath := filepath.Join("internal", "core", "models", "{{DOMAIN}}", "model.go.tmpl")
-	outputPath := filepath.Join("internal", "core", "models", data.DomainSnake, fmt.Sprintf("%s.go", data.EntitySnake))
-	return tg.generateFile(templatePath, outputPath, data)
+	return tg.generateFiles(tg.modelFiles(data), data)
 }
 
 // GenerateRepositoryFiles generates repository files
 func (tg *TemplateGenerator) GenerateRepositoryFiles(data TemplateData, useConfig bool) error {
-	// Generate repository implementation
-	var templatePath string
-	if useConfig {
-		templatePath = filepath.Join("internal", "repository", "{{DOMAIN}}", "repository_config.go.tmpl")
-	} else {
-		templatePath = filepath.Join("internal", "repository", "{{DOMAIN}}", "repository.go.tmpl")
-	}
-	
-	outputPath := filepath.Join("internal", "repository", data.DomainSnake, fmt.Sprintf("%s_repository.go", data.EntitySnake))
-	if err := tg.generateFile(templatePath, outputPath, data); err != nil {
-		return err
-	}
-
-	// Generate repository registration
-	templatePath = filepath.Join("internal", "repository", "{{DOMAIN}}", "repositories.go.tmpl")
-	outputPath = filepath.Join("internal", "repository", data.DomainSnake, "repositories.go")
-	return tg.generateFile(templatePath, outputPath, data)
+	return tg.generateFiles(tg.repositoryFiles(data, useConfig), data)
 }
 
 // GenerateUseCaseFiles generates use case files
 func (tg *TemplateGenerator) GenerateUseCaseFiles(data TemplateData, useConfig bool) error {
-	// Generate usecase implementation
-	var templatePath string
-	if useConfig {
-		templatePath = filepath.Join("internal", "usecase", "{{DOMAIN}}", "usecase_config.go.tmpl")
-	} else {
-		templatePath = filepath.Join("internal", "usecase", "{{DOMAIN}}", "usecase.go.tmpl")
-	}
-	
-	outputPath := filepath.Join("internal", "usecase", data.DomainSnake, fmt.Sprintf("%s_usecase.go", data.EntitySnake))
-	if err := tg.generateFile(templatePath, outputPath, data); err != nil {
-		return err
-	}
-
-	// Generate usecase registration
-	templatePath = filepath.Join("internal", "usecase", "{{DOMAIN}}", "usecases.go.tmpl")
-	outputPath = filepath.Join("internal", "usecase", data.DomainSnake, "usecases.go")
-	return tg.generateFile(templatePath, outputPath, data)
+	return tg.generateFiles(tg.useCaseFiles(data, useConfig), data)
 }
 
 // GenerateHandlerFiles generates handler files
 func (tg *TemplateGenerator) GenerateHandlerFiles(data TemplateData) error {
-	templatePath := filepath.Join("internal", "interface", "http", "handlers", "{{DOMAIN}}", "handler.go.tmpl")
-	outputPath := filepath.Join("internal", "interface", "http", "handlers", data.DomainSnake, fmt.Sprintf("%s.go", data.EntitySnake))
-	return tg.generateFile(templatePath, outputPath, data)
+	return tg.generateFiles(tg.handlerFiles(data), data)
 }
 
 // GenerateDIFiles generates dependency injection files
 func (tg *TemplateGenerator) GenerateDIFiles(data TemplateData) error {
-	templatePath := filepath.Join("internal", "di", "{{DOMAIN}}", "di.go.tmpl")
-	outputPath := filepath.Join("internal", "di", data.DomainSnake, "di.go")
-	return tg.generateFile(templatePath, outputPath, data)
+	return tg.generateFiles(tg.diFiles(data), data)
 }
 
 // GenerateAllFiles generates all files for a domain
@@ -112,24 +81,198 @@ func (tg *TemplateGenerator) GenerateAllFiles(data TemplateData, useConfig bool)
 	if err := tg.GenerateDIFiles(data); err != nil {
 		return fmt.Errorf("failed to generate DI files: %w", err)
 	}
+	if data.Generation.GenerateOpenAPI {
+		if err := tg.GenerateOpenAPIFile(data); err != nil {
+			return fmt.Errorf("failed to generate OpenAPI spec: %w", err)
+		}
+	}
+	return nil
+}
+
+// GenerateOpenAPIFile writes the OpenAPI spec of the domain endpoints
+func (tg *TemplateGenerator) GenerateOpenAPIFile(data TemplateData) error {
+	content, err := GenerateOpenAPISpec(data)
+	if err != nil {
+		return err
+	}
+	return tg.writeFile(OpenAPISpecPath, string(content))
+}
+
+// entityFiles lists the entity files for a domain
+func (tg *TemplateGenerator) entityFiles(data TemplateData, useConfig bool) []fileSpec {
+	var templatePath string
+	if useConfig {
+		templatePath = filepath.Join("internal", "core", "entity", "{{DOMAIN}}", "entity_config.go.tmpl")
+	} else {
+		templatePath = filepath.Join("internal", "core", "entity", "{{DOMAIN}}", "entity.go.tmpl")
+	}
+
+	return []fileSpec{{
+		templatePath: templatePath,
+		outputPath:   filepath.Join("internal", "core", "entity", data.DomainSnake, fmt.Sprintf("%s.go", data.EntitySnake)),
+	}}
+}
+
+// modelFiles lists the model files for a domain
+func (tg *TemplateGenerator) modelFiles(data TemplateData) []fileSpec {
+	return []fileSpec{{
+		templatePath: filepath.Join("internal", "core", "models", "{{DOMAIN}}", "model.go.tmpl"),
+		outputPath:   filepath.Join("internal", "core", "models", data.DomainSnake, fmt.Sprintf("%s.go", data.EntitySnake)),
+	}}
+}
+
+// repositoryFiles lists the repository implementation and registration files
+func (tg *TemplateGenerator) repositoryFiles(data TemplateData, useConfig bool) []fileSpec {
+	var templatePath string
+	if useConfig {
+		templatePath = filepath.Join("internal", "repository", "{{DOMAIN}}", "repository_config.go.tmpl")
+	} else {
+		templatePath = filepath.Join("internal", "repository", "{{DOMAIN}}", "repository.go.tmpl")
+	}
+
+	return []fileSpec{
+		{
+			templatePath: templatePath,
+			outputPath:   filepath.Join("internal", "repository", data.DomainSnake, fmt.Sprintf("%s_repository.go", data.EntitySnake)),
+		},
+		{
+			templatePath: filepath.Join("internal", "repository", "{{DOMAIN}}", "repositories.go.tmpl"),
+			outputPath:   filepath.Join("internal", "repository", data.DomainSnake, "repositories.go"),
+		},
+	}
+}
+
+// useCaseFiles lists the use case implementation and registration files
+func (tg *TemplateGenerator) useCaseFiles(data TemplateData, useConfig bool) []fileSpec {
+	var templatePath string
+	if useConfig {
+		templatePath = filepath.Join("internal", "usecase", "{{DOMAIN}}", "usecase_config.go.tmpl")
+	} else {
+		templatePath = filepath.Join("internal", "usecase", "{{DOMAIN}}", "usecase.go.tmpl")
+	}
+
+	return []fileSpec{
+		{
+			templatePath: templatePath,
+			outputPath:   filepath.Join("internal", "usecase", data.DomainSnake, fmt.Sprintf("%s_usecase.go", data.EntitySnake)),
+		},
+		{
+			templatePath: filepath.Join("internal", "usecase", "{{DOMAIN}}", "usecases.go.tmpl"),
+			outputPath:   filepath.Join("internal", "usecase", data.DomainSnake, "usecases.go"),
+		},
+	}
+}
+
+// handlerFiles lists the HTTP handler files for a domain
+func (tg *TemplateGenerator) handlerFiles(data TemplateData) []fileSpec {
+	return []fileSpec{{
+		templatePath: filepath.Join("internal", "interface", "http", "handlers", "{{DOMAIN}}", "handler.go.tmpl"),
+		outputPath:   filepath.Join("internal", "interface", "http", "handlers", data.DomainSnake, fmt.Sprintf("%s.go", data.EntitySnake)),
+	}}
+}
+
+// diFiles lists the dependency injection files for a domain
+func (tg *TemplateGenerator) diFiles(data TemplateData) []fileSpec {
+	return []fileSpec{{
+		templatePath: filepath.Join("internal", "di", "{{DOMAIN}}", "di.go.tmpl"),
+		outputPath:   filepath.Join("internal", "di", data.DomainSnake, "di.go"),
+	}}
+}
+
+// allFiles lists every file generated for a domain, in generation order
+func (tg *TemplateGenerator) allFiles(data TemplateData, useConfig bool) []fileSpec {
+	var specs []fileSpec
+	specs = append(specs, tg.entityFiles(data, useConfig)...)
+	specs = append(specs, tg.modelFiles(data)...)
+	specs = append(specs, tg.repositoryFiles(data, useConfig)...)
+	specs = append(specs, tg.useCaseFiles(data, useConfig)...)
+	specs = append(specs, tg.handlerFiles(data)...)
+	specs = append(specs, tg.diFiles(data)...)
+	return specs
+}
+
+// renderFiles renders each file without writing it, keyed by output path
+func (tg *TemplateGenerator) renderFiles(specs []fileSpec, data TemplateData) (map[string]string, error) {
+	rendered := make(map[string]string, len(specs))
+	for _, spec := range specs {
+		content, err := tg.renderOutput(spec.templatePath, spec.outputPath, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render %s: %w", spec.outputPath, err)
+		}
+		rendered[spec.outputPath] = content
+	}
+	return rendered, nil
+}
+
+// generateFiles renders and writes each file in order
+func (tg *TemplateGenerator) generateFiles(specs []fileSpec, data TemplateData) error {
+	for _, spec := range specs {
+		if err := tg.generateFile(spec.templatePath, spec.outputPath, data); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 // generateFile generates a file from a template
 func (tg *TemplateGenerator) generateFile(templatePath, outputPath string, data TemplateData) error {
+	content, err := tg.renderOutput(templatePath, outputPath, data)
+	if err != nil {
+		return err
+	}
+	return tg.writeFile(outputPath, content)
+}
+
+// writeFile writes generated content to outputPath
+func (tg *TemplateGenerator) writeFile(outputPath, content string) error {
+	// Create output directory if it doesn't exist
+	outputDir := filepath.Dir(outputPath)
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	if tg.backups != nil {
+		if err := tg.backups.backup(outputPath, content); err != nil {
+			return err
+		}
+	}
+
+	if err := os.WriteFile(outputPath, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+
+	fmt.Printf("Generated %s\n", outputPath)
+	return nil
+}
+
+// renderOutput renders the content to write to outputPath, keeping the custom
+// sections of the existing file when custom code is preserved
+func (tg *TemplateGenerator) renderOutput(templatePath, outputPath string, data TemplateData) (string, error) {
+	content, err := tg.renderFile(templatePath, data)
+	if err != nil {
+		return "", err
+	}
+	if !data.Generation.PreserveCustomCode {
+		return content, nil
+	}
+	return preserveCustomCode(outputPath, content)
+}
+
+// renderFile executes a template and returns the generated content
+func (tg *TemplateGenerator) renderFile(templatePath string, data TemplateData) (string, error) {
 	// Check if template file exists
 	if _, err := os.Stat(templatePath); os.IsNotExist(err) {
-		return fmt.Errorf("template file does not exist: %s", templatePath)
+		return "", fmt.Errorf("template file does not exist: %s", templatePath)
 	}
 
 	// Read template file
 	templateContent, err := os.ReadFile(templatePath)
 	if err != nil {
-		return fmt.Errorf("failed to read template file: %w", err)
+		return "", fmt.Errorf("failed to read template file: %w", err)
 	}
 
 	// Parse template with custom functions
-	tmpl, err := template.New(filepath.Base(templatePath)).
+	tmpl := template.New(filepath.Base(templatePath)).
 		Funcs(template.FuncMap{
 			"default": func(defaultVal, val string) string {
 				if val == "" {
@@ -140,34 +283,38 @@ func (tg *TemplateGenerator) generateFile(templatePath, outputPath string, data
 			"printf":        fmt.Sprintf,
 			"toSnakeCase":   ToSnakeCase,
 			"toPascalCase":  ToPascalCase,
+			"statusFor":     StatusConstant,
 			"pluralize":     Pluralize,
 			"contains":      strings.Contains,
+			"join":          strings.Join,
+			"methodResults": func(returns string) []string {
+				results, _ := MethodResults(returns)
+				return results
+			},
 			"eq":            func(a, b interface{}) bool { return a == b },
 			"ne":            func(a, b interface{}) bool { return a != b },
-		}).
-		Parse(string(templateContent))
+		})
+
+	// Register partials before the template that includes them
+	partials, err := filepath.Glob(PartialsGlob)
 	if err != nil {
-		return fmt.Errorf("failed to parse template: %w", err)
+		return "", fmt.Errorf("invalid partials pattern: %w", err)
 	}
-
-	// Create output directory if it doesn't exist
-	outputDir := filepath.Dir(outputPath)
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+	if len(partials) > 0 {
+		if _, err := tmpl.ParseGlob(PartialsGlob); err != nil {
+			return "", fmt.Errorf("failed to parse partials: %w", err)
+		}
 	}
 
-	// Create output file
-	outputFile, err := os.Create(outputPath)
-	if err != nil {
-		return fmt.Errorf("failed to create output file: %w", err)
+	if _, err := tmpl.Parse(string(templateContent)); err != nil {
+		return "", fmt.Errorf("failed to parse template: %w", err)
 	}
-	defer outputFile.Close()
 
 	// Execute template
-	if err := tmpl.Execute(outputFile, data); err != nil {
-		return fmt.Errorf("failed to execute template: %w", err)
+	var output strings.Builder
+	if err := tmpl.Execute(&output, data); err != nil {
+		return "", fmt.Errorf("failed to execute template: %w", err)
 	}
 
-	fmt.Printf("Generated %s\n", outputPath)
-	return nil
+	return output.String(), nil
 }
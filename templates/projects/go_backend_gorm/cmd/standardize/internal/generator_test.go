@@ -0,0 +1,53 @@
+package internal
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderFilePartials(t *testing.T) {
+	setupProject(t)
+	writeFile(t, filepath.Join("templates", "partials", "_banner.tmpl"), `{{define "banner"}}// {{.Entity}} is generated{{end}}`)
+	templatePath := filepath.Join("internal", "core", "entity", "{{DOMAIN}}", "banner.go.tmpl")
+	writeFile(t, templatePath, "{{template \"package\" .}}\n\n{{template \"banner\" .}}\n")
+
+	content, err := NewTemplateGenerator().renderFile(templatePath, TemplateData{Entity: "Article", DomainSnake: "article"})
+	if err != nil {
+		t.Fatalf("renderFile() error = %v", err)
+	}
+	if want := "package article\n\n// Article is generated\n"; content != want {
+		t.Errorf("renderFile() = %q, want %q", content, want)
+	}
+
+	// A template including an unknown partial fails to render
+	writeFile(t, templatePath, `{{template "missing" .}}`)
+	if _, err := NewTemplateGenerator().renderFile(templatePath, TemplateData{}); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("renderFile() error = %v, want the missing partial", err)
+	}
+}
+
+func TestPreviewRendersPartials(t *testing.T) {
+	setupProject(t)
+	// The previews of --dry-run render the partials on disk
+	writeFile(t, filepath.Join("templates", "partials", "_package.tmpl"), `{{define "package"}}// Package {{.DomainSnake}} is previewed
+package {{.DomainSnake}}{{end}}`)
+	configPath := filepath.Join("configs", "domains", "article.yaml")
+	writeFile(t, configPath, "domain: article\n")
+
+	fromConfig, err := NewCommandHandler().GeneratePreviewFromConfig(configPath)
+	if err != nil {
+		t.Fatalf("GeneratePreviewFromConfig() error = %v", err)
+	}
+	legacy, err := NewCommandHandler().GeneratePreview("article", "Article", "all")
+	if err != nil {
+		t.Fatalf("GeneratePreview() error = %v", err)
+	}
+
+	path := filepath.Join("internal", "repository", "article", "repositories.go")
+	for name, files := range map[string]map[string]string{"config": fromConfig, "legacy": legacy} {
+		if content := files[path]; !strings.HasPrefix(content, "// Package article is previewed\npackage article\n") {
+			t.Errorf("%s preview of %s does not start with the partial:\n%s", name, path, content)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// includesKey is the top-level key listing the files a configuration file
+// includes, relative to its directory
+const includesKey = "includes"
+
+// loadMergedConfig reads a configuration file and the files it includes,
+// recursively, and returns the merged document. Included files are merged in
+// order, each overriding the ones before it, and the including file overrides
+// them all. Mappings are merged key by key while sequences and scalars are
+// replaced. Anchors and aliases resolve within the file that defines them.
+func loadMergedConfig(configPath string) (*yaml.Node, error) {
+	return loadIncluded(configPath, nil)
+}
+
+// loadIncluded loads the file at path, chain being the files including it
+func loadIncluded(path string, chain []string) (*yaml.Node, error) {
+	for _, including := range chain {
+		if sameFile(including, path) {
+			cycle := append(chain[:len(chain):len(chain)], path)
+			return nil, fmt.Errorf("circular include: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	chain = append(chain[:len(chain):len(chain)], path)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	if len(document.Content) == 0 {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+	}
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config file %s: top level must be a mapping", path)
+	}
+
+	includes, err := removeIncludes(root, path)
+	if err != nil {
+		return nil, err
+	}
+	if len(includes) == 0 {
+		return root, nil
+	}
+
+	var merged *yaml.Node
+	for _, include := range includes {
+		includePath := include.Value
+		if !filepath.IsAbs(includePath) {
+			includePath = filepath.Join(filepath.Dir(path), includePath)
+		}
+		if _, err := os.Stat(includePath); err != nil {
+			return nil, fmt.Errorf("%s:%d: include %q: %w", path, include.Line, include.Value, err)
+		}
+		included, err := loadIncluded(includePath, chain)
+		if err != nil {
+			return nil, err
+		}
+		merged = mergeNodes(merged, included)
+	}
+	return mergeNodes(merged, root), nil
+}
+
+// removeIncludes removes the includes key from a configuration mapping and
+// returns the included paths
+func removeIncludes(root *yaml.Node, path string) ([]*yaml.Node, error) {
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != includesKey {
+			continue
+		}
+		value := root.Content[i+1]
+		root.Content = append(root.Content[:i:i], root.Content[i+2:]...)
+		if value.Kind != yaml.SequenceNode {
+			return nil, fmt.Errorf("%s:%d: includes must be a list of file paths", path, value.Line)
+		}
+		for _, include := range value.Content {
+			if include.Kind != yaml.ScalarNode || include.Value == "" {
+				return nil, fmt.Errorf("%s:%d: includes must be a list of file paths", path, include.Line)
+			}
+		}
+		return value.Content, nil
+	}
+	return nil, nil
+}
+
+// mergeNodes returns override merged over base without modifying either, so
+// aliases of their anchors keep their values
+func mergeNodes(base, override *yaml.Node) *yaml.Node {
+	if base == nil {
+		return override
+	}
+	baseMapping, overrideMapping := resolveAlias(base), resolveAlias(override)
+	if baseMapping.Kind != yaml.MappingNode || overrideMapping.Kind != yaml.MappingNode {
+		return override
+	}
+
+	merged := *baseMapping
+	merged.Anchor = ""
+	merged.Content = append([]*yaml.Node(nil), baseMapping.Content...)
+	for i := 0; i+1 < len(overrideMapping.Content); i += 2 {
+		key, value := overrideMapping.Content[i], overrideMapping.Content[i+1]
+		if j := mappingIndex(&merged, key.Value); j >= 0 {
+			merged.Content[j+1] = mergeNodes(merged.Content[j+1], value)
+			continue
+		}
+		merged.Content = append(merged.Content, key, value)
+	}
+	return &merged
+}
+
+// resolveAlias returns the node an alias refers to, or the node itself
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+// mappingIndex returns the index of key in a mapping node, or -1
+func mappingIndex(mapping *yaml.Node, key string) int {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// sameFile reports whether two paths name the same file
+func sameFile(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigIncludes(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "shared", "middleware.yaml"), `handlers:
+  description: middleware
+  middleware: &middleware
+    cors:
+      enabled: true
+      allowed_origins: ["*"]
+x-middleware: *middleware
+`)
+	writeFile(t, filepath.Join(dir, "shared", "common.yaml"), `includes:
+  - middleware.yaml
+handlers:
+  description: common
+  error_handling:
+    enabled: true
+    custom_errors:
+      - code: NOT_FOUND
+        status: 404
+        message: not found
+generation:
+  generate_tests: true
+  soft_delete: true
+`)
+	configPath := filepath.Join(dir, "user.yaml")
+	writeFile(t, configPath, `includes:
+  - shared/common.yaml
+domain: user
+x-string: &string
+  type: string
+entity:
+  fields:
+    - name: Email
+      <<: *string
+    - name: FirstName
+      <<: *string
+handlers:
+  description: user
+  middleware:
+    cors:
+      allowed_origins: [https://example.com]
+    request_logging:
+      enabled: true
+generation:
+  soft_delete: false
+`)
+
+	config, err := NewConfigProcessor().LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	if config.Handlers.Description != "user" {
+		t.Errorf("handlers.description = %q, the including file should win", config.Handlers.Description)
+	}
+	if !config.Generation.GenerateTests || config.Generation.SoftDelete {
+		t.Errorf("generation = %+v, want generate_tests from the include and soft_delete overridden", config.Generation)
+	}
+	if errors := config.Handlers.ErrorHandling.CustomErrors; len(errors) != 1 || errors[0].Status != 404 {
+		t.Errorf("handlers.error_handling.custom_errors = %+v, want the included error", errors)
+	}
+
+	// The nested include is merged key by key under the including file
+	cors := config.Handlers.Middleware.CORS
+	if !cors.Enabled || !reflect.DeepEqual(cors.AllowedOrigins, []string{"https://example.com"}) {
+		t.Errorf("handlers.middleware.cors = %+v, want enabled from the include and origins overridden", cors)
+	}
+	if !config.Handlers.Middleware.RequestLogging.Enabled {
+		t.Error("handlers.middleware.request_logging should be enabled")
+	}
+
+	// Anchors resolve after the merge
+	for _, field := range config.Entity.Fields {
+		if field.Type != "string" {
+			t.Errorf("field %s has type %q, want the anchored string", field.Name, field.Type)
+		}
+	}
+}
+
+func TestLoadConfigIncludeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []string
+	}{
+		{
+			name: "missing include",
+			files: map[string]string{
+				"user.yaml":          "domain: user\nincludes:\n  - shared/common.yaml\n  - shared/missing.yaml\n",
+				"shared/common.yaml": "generation:\n  generate_tests: true\n",
+			},
+			want: []string{"user.yaml:4:", `include "shared/missing.yaml"`, "no such file"},
+		},
+		{
+			name: "missing nested include",
+			files: map[string]string{
+				"user.yaml":          "domain: user\nincludes: [shared/common.yaml]\n",
+				"shared/common.yaml": "\nincludes:\n  - missing.yaml\n",
+			},
+			want: []string{filepath.Join("shared", "common.yaml") + ":3:", `include "missing.yaml"`},
+		},
+		{
+			name: "circular include",
+			files: map[string]string{
+				"user.yaml": "domain: user\nincludes: [a.yaml]\n",
+				"a.yaml":    "includes: [b.yaml]\n",
+				"b.yaml":    "includes: [a.yaml]\n",
+			},
+			want: []string{"circular include:", "user.yaml -> ", "a.yaml -> ", "b.yaml -> ", "a.yaml"},
+		},
+		{
+			name: "includes not a list",
+			files: map[string]string{
+				"user.yaml": "domain: user\nincludes: common.yaml\n",
+			},
+			want: []string{"user.yaml:2: includes must be a list of file paths"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				writeFile(t, filepath.Join(dir, name), content)
+			}
+
+			_, err := NewConfigProcessor().LoadConfig(filepath.Join(dir, "user.yaml"))
+			if err == nil {
+				t.Fatal("LoadConfig() should fail")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q should contain %q", err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestFilename is the name of the file recording what was generated for a domain
+const ManifestFilename = ".standardize-manifest.yaml"
+
+// Manifest records every file generated for a domain with the hash of the
+// content that was written, so later runs can tell untouched files from ones
+// modified by hand.
+type Manifest struct {
+	Domain string `yaml:"domain"`
+	Entity string `yaml:"entity,omitempty"`
+	// Config is the configuration file the domain was generated from, empty for legacy generation
+	Config string `yaml:"config,omitempty"`
+	// ConfigHash is the hash of the template data the files were rendered with
+	ConfigHash string         `yaml:"config_hash"`
+	Files      []ManifestFile `yaml:"files"`
+}
+
+// ManifestFile is a generated file and the hash of its generated content
+type ManifestFile struct {
+	Path string `yaml:"path"`
+	Hash string `yaml:"hash"`
+}
+
+// FileState is the state of a generated file on disk compared to the manifest
+type FileState int
+
+const (
+	// FileUntouched files still have the content that was generated
+	FileUntouched FileState = iota
+	// FileModified files were changed by hand since they were generated
+	FileModified
+	// FileMissing files were deleted since they were generated
+	FileMissing
+)
+
+// ManifestPath returns where the manifest of a domain is stored
+func ManifestPath(domainSnake string) string {
+	return filepath.Join("internal", "di", domainSnake, ManifestFilename)
+}
+
+// LoadManifest reads the manifest of a domain. It returns nil without an
+// error when the domain has no manifest.
+func LoadManifest(domainSnake string) (*Manifest, error) {
+	content, err := os.ReadFile(ManifestPath(domainSnake))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if err := yaml.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", ManifestPath(domainSnake), err)
+	}
+	return &manifest, nil
+}
+
+// Save writes the manifest of its domain, with files sorted by path
+func (m *Manifest) Save() error {
+	sort.Slice(m.Files, func(i, j int) bool { return m.Files[i].Path < m.Files[j].Path })
+
+	content, err := yaml.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	path := ManifestPath(m.Domain)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create manifest directory: %w", err)
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// Record sets the generated content hash of path, adding the file if needed
+func (m *Manifest) Record(path, content string) {
+	hash := hashContent(content)
+	for i, file := range m.Files {
+		if file.Path == path {
+			m.Files[i].Hash = hash
+			return
+		}
+	}
+	m.Files = append(m.Files, ManifestFile{Path: path, Hash: hash})
+}
+
+// Hash returns the recorded hash of path, false if the file is not in the manifest
+func (m *Manifest) Hash(path string) (string, bool) {
+	for _, file := range m.Files {
+		if file.Path == path {
+			return file.Hash, true
+		}
+	}
+	return "", false
+}
+
+// State compares a generated file on disk with its recorded hash
+func (file ManifestFile) State() (FileState, error) {
+	content, err := os.ReadFile(file.Path)
+	if os.IsNotExist(err) {
+		return FileMissing, nil
+	}
+	if err != nil {
+		return FileMissing, fmt.Errorf("failed to read %s: %w", file.Path, err)
+	}
+	if hashContent(string(content)) != file.Hash {
+		return FileModified, nil
+	}
+	return FileUntouched, nil
+}
+
+// hashContent returns the hex encoded SHA-256 hash of content
+func hashContent(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// configHash returns the hash of the template data files are rendered with
+func configHash(data TemplateData) (string, error) {
+	content, err := json.Marshal(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash template data: %w", err)
+	}
+	return hashContent(string(content)), nil
+}
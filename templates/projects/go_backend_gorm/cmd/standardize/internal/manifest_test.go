@@ -0,0 +1,247 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const registrationFixture = `package main
+
+import (
+	"github.com/samber/do"
+)
+
+import (
+// @gohex:begin:domain_imports
+orderDI "go_backend_gorm/internal/di/order"
+userDI "go_backend_gorm/internal/di/user"
+// @gohex:end:domain_imports
+)
+
+func main() {
+	injector := do.New()
+
+	// @gohex:begin:domain_registrations
+	orderDI.RegisterOrder(injector)
+	userDI.RegisterUser(injector)
+	// @gohex:end:domain_registrations
+}
+`
+
+// setupProject copies the templates into a temporary project directory and
+// changes into it for the duration of the test
+func setupProject(t *testing.T) {
+	t.Helper()
+
+	root, err := filepath.Abs(filepath.Join("..", "..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	for _, name := range []string{"internal", "templates"} {
+		copyDir(t, filepath.Join(root, name), filepath.Join(dir, name))
+	}
+	writeFile(t, filepath.Join(dir, "cmd", "api", "main.go"), registrationFixture)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func copyDir(t *testing.T, src, dst string) {
+	t.Helper()
+	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		writeFile(t, filepath.Join(dst, rel), string(content))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func loadManifest(t *testing.T, domain string) *Manifest {
+	t.Helper()
+	manifest, err := LoadManifest(domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest == nil {
+		t.Fatalf("domain %s has no manifest", domain)
+	}
+	return manifest
+}
+
+func TestManifestGenerateEditRegenerateRemove(t *testing.T) {
+	setupProject(t)
+	ch := NewCommandHandler()
+
+	if err := ch.GenerateLegacy("order", "", "all"); err != nil {
+		t.Fatalf("GenerateLegacy() error = %v", err)
+	}
+	manifest := loadManifest(t, "order")
+	if len(manifest.Files) != 8 {
+		t.Fatalf("manifest has %d files, want 8", len(manifest.Files))
+	}
+	for _, file := range manifest.Files {
+		if state, err := file.State(); err != nil || state != FileUntouched {
+			t.Fatalf("%s state = %v, %v; want untouched", file.Path, state, err)
+		}
+	}
+
+	// Edit a generated file by hand; regenerating must not overwrite it
+	edited := filepath.Join("internal", "core", "models", "order", "order.go")
+	content := readFile(t, edited) + "\n// hand edit\n"
+	writeFile(t, edited, content)
+
+	if err := ch.Regenerate("order", false); err != nil {
+		t.Fatalf("Regenerate() error = %v", err)
+	}
+	if got := readFile(t, edited); got != content {
+		t.Errorf("Regenerate() overwrote the file modified by hand")
+	}
+
+	// Removing refuses while a file modified by hand would be deleted
+	err := ch.Remove("order", false)
+	if err == nil || !strings.Contains(err.Error(), edited) {
+		t.Fatalf("Remove() error = %v, want error naming %s", err, edited)
+	}
+	if _, err := os.Stat(edited); err != nil {
+		t.Fatalf("Remove() deleted files after refusing: %v", err)
+	}
+
+	if err := ch.Remove("order", true); err != nil {
+		t.Fatalf("Remove(force) error = %v", err)
+	}
+	for _, file := range manifest.Files {
+		if _, err := os.Stat(file.Path); !os.IsNotExist(err) {
+			t.Errorf("%s still exists after Remove()", file.Path)
+		}
+	}
+	if _, err := os.Stat(ManifestPath("order")); !os.IsNotExist(err) {
+		t.Errorf("manifest still exists after Remove()")
+	}
+	if _, err := os.Stat(filepath.Join("internal", "di", "order")); !os.IsNotExist(err) {
+		t.Errorf("empty domain directory still exists after Remove()")
+	}
+
+	registration := readFile(t, filepath.Join("cmd", "api", "main.go"))
+	if strings.Contains(registration, "orderDI") {
+		t.Errorf("Remove() left the domain registered:\n%s", registration)
+	}
+	if !strings.Contains(registration, `userDI "go_backend_gorm/internal/di/user"`) || !strings.Contains(registration, "userDI.RegisterUser(injector)") {
+		t.Errorf("Remove() un-registered another domain:\n%s", registration)
+	}
+}
+
+func TestRegenerateWritesOnlyChangedFiles(t *testing.T) {
+	setupProject(t)
+	ch := NewCommandHandler()
+
+	if err := ch.GenerateLegacy("order", "", "all"); err != nil {
+		t.Fatalf("GenerateLegacy() error = %v", err)
+	}
+
+	// A file deleted by hand and a file whose template changed are rewritten
+	deleted := filepath.Join("internal", "usecase", "order", "usecases.go")
+	if err := os.Remove(deleted); err != nil {
+		t.Fatal(err)
+	}
+	diTemplate := filepath.Join("internal", "di", "{{DOMAIN}}", "di.go.tmpl")
+	writeFile(t, diTemplate, readFile(t, diTemplate)+"\n// template change\n")
+
+	// An untouched file with unchanged output is left alone
+	untouched := filepath.Join("internal", "core", "entity", "order", "order.go")
+	old, err := os.Stat(untouched)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(untouched, old.ModTime().Add(-1e9), old.ModTime().Add(-1e9)); err != nil {
+		t.Fatal(err)
+	}
+	old, _ = os.Stat(untouched)
+
+	if err := ch.Regenerate("order", false); err != nil {
+		t.Fatalf("Regenerate() error = %v", err)
+	}
+
+	if _, err := os.Stat(deleted); err != nil {
+		t.Errorf("Regenerate() did not recreate %s: %v", deleted, err)
+	}
+	di := filepath.Join("internal", "di", "order", "di.go")
+	if !strings.Contains(readFile(t, di), "// template change") {
+		t.Errorf("Regenerate() did not rewrite %s after its template changed", di)
+	}
+	if info, _ := os.Stat(untouched); !info.ModTime().Equal(old.ModTime()) {
+		t.Errorf("Regenerate() rewrote the unchanged file %s", untouched)
+	}
+
+	manifest := loadManifest(t, "order")
+	for _, file := range manifest.Files {
+		if state, err := file.State(); err != nil || state != FileUntouched {
+			t.Errorf("%s state = %v, %v after Regenerate(); want untouched", file.Path, state, err)
+		}
+	}
+}
+
+func TestRemoveDomainRegistrationWithoutAlias(t *testing.T) {
+	content := `import (
+// @gohex:begin:domain_imports
+"go_backend_gorm/internal/di/order"
+// @gohex:end:domain_imports
+)
+
+	// @gohex:begin:domain_registrations
+	order.RegisterOrder(injector)
+	// @gohex:end:domain_registrations
+	order.Keep()
+`
+	want := `import (
+// @gohex:begin:domain_imports
+// @gohex:end:domain_imports
+)
+
+	// @gohex:begin:domain_registrations
+	// @gohex:end:domain_registrations
+	order.Keep()
+`
+	if got := removeDomainRegistration(content, "order"); got != want {
+		t.Errorf("removeDomainRegistration() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,220 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configMigration upgrades a configuration from one schema version to the
+// next
+type configMigration struct {
+	from, to    string
+	description string
+	// migrate applies the migration to a parsed configuration
+	migrate func(*DomainConfig)
+	// rewrite applies the migration to the top-level mapping of a
+	// configuration file, reporting whether it changed
+	rewrite func(*yaml.Node) bool
+}
+
+// configMigrations are the migrations between consecutive schema versions,
+// in order
+var configMigrations = []configMigration{
+	{
+		from:        DomainConfigV1,
+		to:          DomainConfigV2,
+		description: "rename model.fields and models[].fields to model_fields",
+		migrate: func(config *DomainConfig) {
+			config.Model.migrateLegacyFields()
+			for i := range config.Models {
+				config.Models[i].migrateLegacyFields()
+			}
+		},
+		rewrite: func(root *yaml.Node) bool {
+			changed := false
+			if i := mappingIndex(root, "model"); i >= 0 {
+				changed = renameKey(resolveAlias(root.Content[i+1]), "fields", "model_fields") || changed
+			}
+			if i := mappingIndex(root, "models"); i >= 0 {
+				for _, model := range resolveAlias(root.Content[i+1]).Content {
+					changed = renameKey(resolveAlias(model), "fields", "model_fields") || changed
+				}
+			}
+			return changed
+		},
+	},
+}
+
+// migrateLegacyFields moves the fields of a version 1.0 model to Fields
+func (m *ModelConfig) migrateLegacyFields() {
+	if len(m.LegacyFields) == 0 {
+		return
+	}
+	m.Fields = append(slices.Clip(m.LegacyFields), m.Fields...)
+	m.LegacyFields = nil
+}
+
+// pendingMigrations returns the migrations bringing a configuration of
+// version to the current one
+func pendingMigrations(version string) ([]configMigration, error) {
+	if version == "" {
+		version = DomainConfigV1
+	}
+	if version == CurrentDomainConfigVersion {
+		return nil, nil
+	}
+	start := slices.IndexFunc(configMigrations, func(m configMigration) bool { return m.from == version })
+	if start < 0 {
+		return nil, fmt.Errorf("version %q is not supported, expected %q or older", version, CurrentDomainConfigVersion)
+	}
+	return configMigrations[start:], nil
+}
+
+// modelPath returns the path of a model in the configuration, the first one
+// being model and the others models
+func modelPath(i int) string {
+	if i == 0 {
+		return "model"
+	}
+	return fmt.Sprintf("models[%d]", i-1)
+}
+
+// CheckConfigVersion reports the version of a configuration file and the
+// migrations that would bring it to the current version
+func (cp *ConfigProcessor) CheckConfigVersion(configPath string) (string, error) {
+	config, err := cp.EffectiveConfig(configPath)
+	if err != nil {
+		return "", err
+	}
+	version := config.Version
+	if version == "" {
+		version = DomainConfigV1 + " (no version set)"
+	}
+	migrations, err := pendingMigrations(config.Version)
+	if err != nil {
+		return "", err
+	}
+
+	var report strings.Builder
+	fmt.Fprintf(&report, "Version: %s\n", version)
+	if len(migrations) == 0 {
+		fmt.Fprintf(&report, "Up to date with version %s\n", CurrentDomainConfigVersion)
+		return report.String(), nil
+	}
+	fmt.Fprintf(&report, "Migrations to version %s:\n", CurrentDomainConfigVersion)
+	for _, migration := range migrations {
+		fmt.Fprintf(&report, "  %s -> %s: %s\n", migration.from, migration.to, migration.description)
+	}
+	return report.String(), nil
+}
+
+// MigrateConfigFiles rewrites a configuration file and the files it includes
+// to the current schema version, keeping their comments, and returns the
+// files it changed
+func (cp *ConfigProcessor) MigrateConfigFiles(configPath string) ([]string, error) {
+	config, err := cp.EffectiveConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := pendingMigrations(config.Version)
+	if err != nil {
+		return nil, err
+	}
+	if len(migrations) == 0 {
+		return nil, nil
+	}
+
+	var changed []string
+	err = migrateConfigFile(configPath, migrations, true, map[string]bool{}, &changed)
+	return changed, err
+}
+
+// migrateConfigFile applies migrations to the file at path and, recursively,
+// to the files it includes. The version is set in the including file, and in
+// included files that have one.
+func migrateConfigFile(path string, migrations []configMigration, top bool, visited map[string]bool, changed *[]string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if visited[absPath] {
+		return nil
+	}
+	visited[absPath] = true
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	root := document.Content[0]
+
+	// Included files are migrated first, they are listed relative to path
+	if i := mappingIndex(root, includesKey); i >= 0 {
+		for _, include := range root.Content[i+1].Content {
+			includePath := include.Value
+			if !filepath.IsAbs(includePath) {
+				includePath = filepath.Join(filepath.Dir(path), includePath)
+			}
+			if err := migrateConfigFile(includePath, migrations, false, visited, changed); err != nil {
+				return err
+			}
+		}
+	}
+
+	fileChanged := false
+	for _, migration := range migrations {
+		fileChanged = migration.rewrite(root) || fileChanged
+	}
+	if i := mappingIndex(root, "version"); i >= 0 {
+		fileChanged = fileChanged || root.Content[i+1].Value != CurrentDomainConfigVersion
+		root.Content[i+1].Value = CurrentDomainConfigVersion
+		root.Content[i+1].Style = yaml.DoubleQuotedStyle
+	} else if top {
+		root.Content = append([]*yaml.Node{
+			{Kind: yaml.ScalarNode, Tag: "!!str", Value: "version"},
+			{Kind: yaml.ScalarNode, Tag: "!!str", Value: CurrentDomainConfigVersion, Style: yaml.DoubleQuotedStyle},
+		}, root.Content...)
+		fileChanged = true
+	}
+	if !fileChanged {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&document); err != nil {
+		return fmt.Errorf("failed to encode config file %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write config file %s: %w", path, err)
+	}
+	*changed = append(*changed, path)
+	return nil
+}
+
+// renameKey renames a key of a mapping node, reporting whether it was there
+func renameKey(mapping *yaml.Node, from, to string) bool {
+	if mapping.Kind != yaml.MappingNode {
+		return false
+	}
+	i := mappingIndex(mapping, from)
+	if i < 0 {
+		return false
+	}
+	mapping.Content[i].Value = to
+	return true
+}
@@ -0,0 +1,169 @@
+package internal
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrateConfig(t *testing.T) {
+	legacy := []ModelFieldConfig{{Name: "Email", Type: "string"}}
+	config := &DomainConfig{
+		Domain: "user",
+		Model:  ModelConfig{Name: "User", LegacyFields: legacy},
+		Models: []ModelConfig{{Name: "Profile", LegacyFields: legacy}},
+	}
+
+	migrated, err := MigrateConfig(config)
+	if err != nil {
+		t.Fatalf("MigrateConfig() error = %v", err)
+	}
+	if migrated.Version != DomainConfigV2 {
+		t.Errorf("version = %q, want %q", migrated.Version, DomainConfigV2)
+	}
+	for _, model := range append([]ModelConfig{migrated.Model}, migrated.Models...) {
+		if len(model.Fields) != 1 || model.Fields[0].Name != "Email" || len(model.LegacyFields) != 0 {
+			t.Errorf("model %s = %+v, want fields moved to model_fields", model.Name, model)
+		}
+	}
+
+	// The configuration migrated is left as is
+	if config.Version != "" || len(config.Model.LegacyFields) != 1 || len(config.Models[0].LegacyFields) != 1 {
+		t.Errorf("MigrateConfig() changed its input: %+v", config)
+	}
+
+	if _, err := MigrateConfig(&DomainConfig{Version: "3.0"}); err == nil {
+		t.Error("MigrateConfig() should fail on an unknown version")
+	}
+}
+
+func TestLoadConfigVersions(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{
+			name: "v1 fields",
+			config: `version: "1.0"
+domain: user
+model:
+  fields:
+    - name: Email
+      type: string
+`,
+		},
+		{
+			name: "v2 model_fields",
+			config: `version: "2.0"
+domain: user
+model:
+  model_fields:
+    - name: Email
+      type: string
+`,
+		},
+		{
+			name: "v2 fields",
+			config: `version: "2.0"
+domain: user
+model:
+  fields:
+    - name: Email
+      type: string
+`,
+			wantErr: "model: fields was renamed model_fields",
+		},
+		{
+			name: "unknown version",
+			config: `version: "3.0"
+domain: user
+`,
+			wantErr: `version "3.0" is not supported`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "user.yaml")
+			writeFile(t, configPath, tt.config)
+
+			config, err := NewConfigProcessor().LoadConfig(configPath)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if len(config.Model.Fields) != 1 || config.Model.Fields[0].Name != "Email" {
+				t.Errorf("model fields = %+v, want Email", config.Model.Fields)
+			}
+		})
+	}
+}
+
+func TestMigrateConfigFiles(t *testing.T) {
+	dir := t.TempDir()
+	sharedPath := filepath.Join(dir, "shared", "models.yaml")
+	writeFile(t, sharedPath, `models:
+  - name: Profile
+    # Kept through the migration
+    fields:
+      - name: Bio
+        type: string
+`)
+	configPath := filepath.Join(dir, "user.yaml")
+	writeFile(t, configPath, `includes:
+  - shared/models.yaml
+domain: user
+model:
+  fields:
+    - name: Email
+      type: string
+`)
+	cp := NewConfigProcessor()
+
+	report, err := cp.CheckConfigVersion(configPath)
+	if err != nil {
+		t.Fatalf("CheckConfigVersion() error = %v", err)
+	}
+	if !strings.Contains(report, "Version: 1.0 (no version set)") || !strings.Contains(report, "1.0 -> 2.0") {
+		t.Errorf("CheckConfigVersion() = %q, want version 1.0 and its migration", report)
+	}
+
+	changed, err := cp.MigrateConfigFiles(configPath)
+	if err != nil {
+		t.Fatalf("MigrateConfigFiles() error = %v", err)
+	}
+	if len(changed) != 2 {
+		t.Errorf("MigrateConfigFiles() changed %v, want both files", changed)
+	}
+
+	main := readFile(t, configPath)
+	if !strings.HasPrefix(main, `version: "2.0"`) || !strings.Contains(main, "model_fields:") {
+		t.Errorf("migrated config =\n%s", main)
+	}
+	shared := readFile(t, sharedPath)
+	if !strings.Contains(shared, "model_fields:") || !strings.Contains(shared, "# Kept through the migration") {
+		t.Errorf("migrated include =\n%s", shared)
+	}
+
+	config, err := cp.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if len(config.Models) != 1 || len(config.Models[0].Fields) != 1 {
+		t.Errorf("models = %+v, want Profile with its field", config.Models)
+	}
+
+	report, err = cp.CheckConfigVersion(configPath)
+	if err != nil || !strings.Contains(report, "Up to date") {
+		t.Errorf("CheckConfigVersion() = %q, %v, want up to date", report, err)
+	}
+	if changed, err := cp.MigrateConfigFiles(configPath); err != nil || len(changed) != 0 {
+		t.Errorf("MigrateConfigFiles() = %v, %v, want nothing to change", changed, err)
+	}
+}
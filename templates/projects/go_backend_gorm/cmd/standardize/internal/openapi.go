@@ -0,0 +1,312 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OpenAPISpecPath is where the OpenAPI spec of a domain is written
+var OpenAPISpecPath = filepath.Join("docs", "openapi.yaml")
+
+// DefaultOpenAPIVersion is the API version used when the openapi config has none
+const DefaultOpenAPIVersion = "1.0.0"
+
+// pathParamPattern matches the {name} segments of an endpoint path
+var pathParamPattern = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// openAPISpec is the subset of an OpenAPI 3.0 document the generator produces
+type openAPISpec struct {
+	OpenAPI string                                 `yaml:"openapi"`
+	Info    openAPIInfo                            `yaml:"info"`
+	Tags    []openAPITag                           `yaml:"tags,omitempty"`
+	Paths   map[string]map[string]openAPIOperation `yaml:"paths"`
+}
+
+type openAPIInfo struct {
+	Title       string          `yaml:"title"`
+	Description string          `yaml:"description,omitempty"`
+	Version     string          `yaml:"version"`
+	Contact     *openAPIContact `yaml:"contact,omitempty"`
+}
+
+type openAPIContact struct {
+	Name  string `yaml:"name,omitempty"`
+	Email string `yaml:"email,omitempty"`
+}
+
+type openAPITag struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+}
+
+type openAPIOperation struct {
+	OperationID string                     `yaml:"operationId,omitempty"`
+	Summary     string                     `yaml:"summary,omitempty"`
+	Tags        []string                   `yaml:"tags,omitempty"`
+	Parameters  []openAPIParameter         `yaml:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody        `yaml:"requestBody,omitempty"`
+	Responses   map[string]openAPIResponse `yaml:"responses"`
+}
+
+type openAPIParameter struct {
+	Name        string         `yaml:"name"`
+	In          string         `yaml:"in"`
+	Description string         `yaml:"description,omitempty"`
+	Required    bool           `yaml:"required,omitempty"`
+	Schema      *openAPISchema `yaml:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Description string                      `yaml:"description,omitempty"`
+	Required    bool                        `yaml:"required"`
+	Content     map[string]openAPIMediaType `yaml:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                      `yaml:"description"`
+	Content     map[string]openAPIMediaType `yaml:"content,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema *openAPISchema `yaml:"schema"`
+}
+
+type openAPISchema struct {
+	Type        string                    `yaml:"type,omitempty"`
+	Format      string                    `yaml:"format,omitempty"`
+	Description string                    `yaml:"description,omitempty"`
+	Nullable    bool                      `yaml:"nullable,omitempty"`
+	Default     interface{}               `yaml:"default,omitempty"`
+	Items       *openAPISchema            `yaml:"items,omitempty"`
+	Properties  map[string]*openAPISchema `yaml:"properties,omitempty"`
+	Required    []string                  `yaml:"required,omitempty"`
+}
+
+// GenerateOpenAPISpec builds an OpenAPI 3.0 spec of the domain endpoints. Each
+// endpoint becomes an operation, with its request body and response schemas
+// built from the api request and response types it references by name.
+func GenerateOpenAPISpec(data TemplateData) ([]byte, error) {
+	config := data.Handlers.OpenAPI
+
+	spec := openAPISpec{
+		OpenAPI: "3.0.3",
+		Info: openAPIInfo{
+			Title:       config.Title,
+			Description: config.Description,
+			Version:     config.Version,
+		},
+		Paths: make(map[string]map[string]openAPIOperation),
+	}
+	if spec.Info.Title == "" {
+		spec.Info.Title = fmt.Sprintf("%s API", data.Entity)
+	}
+	if spec.Info.Version == "" {
+		spec.Info.Version = DefaultOpenAPIVersion
+	}
+	if config.Contact.Name != "" || config.Contact.Email != "" {
+		spec.Info.Contact = &openAPIContact{Name: config.Contact.Name, Email: config.Contact.Email}
+	}
+
+	// Operations are grouped under the first configured tag, or the entity name
+	operationTag := data.Entities
+	for i, tag := range config.Tags {
+		if i == 0 {
+			operationTag = tag.Name
+		}
+		spec.Tags = append(spec.Tags, openAPITag{Name: tag.Name, Description: tag.Description})
+	}
+
+	requests := make(map[string]RequestConfig, len(data.API.Requests))
+	for _, request := range data.API.Requests {
+		requests[request.Name] = request
+	}
+	responses := make(map[string]ResponseConfig, len(data.API.Responses))
+	for _, response := range data.API.Responses {
+		responses[response.Name] = response
+	}
+
+	endpoints := append(append([]EndpointConfig{}, data.Endpoints...), data.Handlers.Endpoints...)
+	for _, endpoint := range endpoints {
+		method := strings.ToLower(endpoint.Method)
+		if method == "" || endpoint.Path == "" {
+			return nil, fmt.Errorf("endpoint %s: method and path are required", endpoint.Handler)
+		}
+		if _, exists := spec.Paths[endpoint.Path][method]; exists {
+			return nil, fmt.Errorf("endpoint %s: duplicate operation %s %s", endpoint.Handler, endpoint.Method, endpoint.Path)
+		}
+
+		operation, err := openAPIOperationFor(endpoint, requests, responses)
+		if err != nil {
+			return nil, fmt.Errorf("endpoint %s: %w", endpoint.Handler, err)
+		}
+		operation.Tags = []string{operationTag}
+
+		if spec.Paths[endpoint.Path] == nil {
+			spec.Paths[endpoint.Path] = make(map[string]openAPIOperation)
+		}
+		spec.Paths[endpoint.Path][method] = operation
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(spec); err != nil {
+		return nil, fmt.Errorf("failed to marshal OpenAPI spec: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to marshal OpenAPI spec: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// openAPIOperationFor converts an endpoint into an OpenAPI operation
+func openAPIOperationFor(endpoint EndpointConfig, requests map[string]RequestConfig, responses map[string]ResponseConfig) (openAPIOperation, error) {
+	operation := openAPIOperation{
+		OperationID: endpoint.Handler,
+		Summary:     endpoint.Description,
+		Responses:   make(map[string]openAPIResponse),
+	}
+
+	for _, match := range pathParamPattern.FindAllStringSubmatch(endpoint.Path, -1) {
+		operation.Parameters = append(operation.Parameters, openAPIParameter{
+			Name:     match[1],
+			In:       "path",
+			Required: true,
+			Schema:   &openAPISchema{Type: "string"},
+		})
+	}
+	for _, param := range endpoint.QueryParams {
+		schema := openAPISchemaForType(param.Type)
+		schema.Default = param.Default
+		operation.Parameters = append(operation.Parameters, openAPIParameter{
+			Name:        param.Name,
+			In:          "query",
+			Description: param.Description,
+			Required:    param.Required && !param.Optional,
+			Schema:      schema,
+		})
+	}
+
+	if endpoint.Request != "" {
+		name, isList := typeName(endpoint.Request)
+		request, ok := requests[name]
+		if !ok {
+			return openAPIOperation{}, fmt.Errorf("unknown request type %q", endpoint.Request)
+		}
+		schema := openAPIObjectSchema(request.Description, request.Fields)
+		if isList {
+			schema = &openAPISchema{Type: "array", Items: schema}
+		}
+		operation.RequestBody = &openAPIRequestBody{
+			Description: request.Description,
+			Required:    true,
+			Content:     map[string]openAPIMediaType{"application/json": {Schema: schema}},
+		}
+	}
+
+	statusCode := defaultStatusCode(endpoint.Method, endpoint.StatusCode)
+	response := openAPIResponse{Description: http.StatusText(statusCode)}
+	if endpoint.Response != "" {
+		name, isList := typeName(endpoint.Response)
+		config, ok := responses[name]
+		if !ok {
+			return openAPIOperation{}, fmt.Errorf("unknown response type %q", endpoint.Response)
+		}
+		schema := openAPIObjectSchema(config.Description, config.Fields)
+		if isList {
+			schema = &openAPISchema{Type: "array", Items: schema}
+		}
+		if config.Description != "" {
+			response.Description = config.Description
+		}
+		response.Content = map[string]openAPIMediaType{"application/json": {Schema: schema}}
+	}
+	operation.Responses[fmt.Sprint(statusCode)] = response
+
+	return operation, nil
+}
+
+// typeName strips the pointer and slice markers from a request or response
+// type, reporting whether the type is a list
+func typeName(goType string) (string, bool) {
+	name := strings.TrimPrefix(goType, "*")
+	isList := strings.HasPrefix(name, "[]")
+	return strings.TrimPrefix(strings.TrimPrefix(name, "[]"), "*"), isList
+}
+
+// openAPIObjectSchema builds the object schema of a request or response type
+func openAPIObjectSchema(description string, fields []FieldConfig) *openAPISchema {
+	schema := &openAPISchema{
+		Type:        "object",
+		Description: description,
+		Properties:  make(map[string]*openAPISchema, len(fields)),
+	}
+	for _, field := range fields {
+		name := jsonFieldName(field)
+		if name == "-" {
+			continue
+		}
+
+		property := openAPISchemaForType(field.Type)
+		property.Description = field.Description
+		property.Nullable = field.Nullable
+		property.Default = field.Default
+		schema.Properties[name] = property
+
+		for _, validation := range field.Validations {
+			if validation == "required" && !field.Nullable {
+				schema.Required = append(schema.Required, name)
+				break
+			}
+		}
+	}
+	return schema
+}
+
+// jsonFieldName returns the JSON name of a field, taken from its json tag or
+// its snake_case name
+func jsonFieldName(field FieldConfig) string {
+	tag := reflect.StructTag(field.Tags).Get("json")
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name
+	}
+	return ToSnakeCase(field.Name)
+}
+
+// openAPISchemaForType maps a Go type to its OpenAPI schema
+func openAPISchemaForType(goType string) *openAPISchema {
+	goType = strings.TrimPrefix(goType, "*")
+	if strings.HasPrefix(goType, "[]") {
+		return &openAPISchema{Type: "array", Items: openAPISchemaForType(goType[2:])}
+	}
+
+	switch goType {
+	case "string":
+		return &openAPISchema{Type: "string"}
+	case "bool":
+		return &openAPISchema{Type: "boolean"}
+	case "int", "uint":
+		return &openAPISchema{Type: "integer"}
+	case "int8", "int16", "int32", "uint8", "uint16", "uint32":
+		return &openAPISchema{Type: "integer", Format: "int32"}
+	case "int64", "uint64":
+		return &openAPISchema{Type: "integer", Format: "int64"}
+	case "float32":
+		return &openAPISchema{Type: "number", Format: "float"}
+	case "float64":
+		return &openAPISchema{Type: "number", Format: "double"}
+	case "time.Time":
+		return &openAPISchema{Type: "string", Format: "date-time"}
+	case "uuid.UUID":
+		return &openAPISchema{Type: "string", Format: "uuid"}
+	default:
+		return &openAPISchema{Type: "object"}
+	}
+}
@@ -0,0 +1,171 @@
+package internal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// openAPITestData is a user domain with a create, a get and a list endpoint
+func openAPITestData() TemplateData {
+	return TemplateData{
+		Entity:   "User",
+		Entities: "Users",
+		API: APIConfig{
+			Requests: []RequestConfig{{
+				Name:        "CreateUserRequest",
+				Description: "Fields of a new user",
+				Fields: []FieldConfig{
+					{Name: "Email", Type: "string", Validations: []string{"required", "email"}},
+					{Name: "DisplayName", Type: "*string", Tags: `json:"name,omitempty"`, Nullable: true, Validations: []string{"required"}},
+					{Name: "Age", Type: "int32"},
+					{Name: "Password", Type: "string", Tags: `json:"-"`},
+				},
+			}},
+			Responses: []ResponseConfig{{
+				Name: "UserResponse",
+				Fields: []FieldConfig{
+					{Name: "ID", Type: "uuid.UUID", Validations: []string{"required"}},
+					{Name: "CreatedAt", Type: "time.Time"},
+					{Name: "Roles", Type: "[]string"},
+				},
+			}},
+		},
+		Endpoints: []EndpointConfig{
+			{Method: "POST", Path: "/users", Handler: "CreateUser", Description: "Create a user", Request: "*CreateUserRequest", Response: "*UserResponse"},
+			{Method: "GET", Path: "/users/{id}", Handler: "GetUser", Response: "UserResponse"},
+		},
+		Handlers: HandlersConfig{
+			OpenAPI: OpenAPIConfig{
+				Tags: []OpenAPITagConfig{{Name: "accounts", Description: "User accounts"}},
+			},
+			Endpoints: []EndpointConfig{{
+				Method:   "GET",
+				Path:     "/users",
+				Handler:  "ListUsers",
+				Response: "[]*UserResponse",
+				QueryParams: []QueryParamConfig{
+					{Name: "limit", Type: "int", Default: 20},
+					{Name: "cursor", Type: "string", Required: true},
+				},
+			}},
+		},
+	}
+}
+
+func TestGenerateOpenAPISpec(t *testing.T) {
+	out, err := GenerateOpenAPISpec(openAPITestData())
+	if err != nil {
+		t.Fatalf("GenerateOpenAPISpec() error = %v", err)
+	}
+	var spec openAPISpec
+	if err := yaml.Unmarshal(out, &spec); err != nil {
+		t.Fatalf("generated spec is not valid YAML: %v\n%s", err, out)
+	}
+
+	if spec.OpenAPI != "3.0.3" || spec.Info.Title != "User API" || spec.Info.Version != DefaultOpenAPIVersion {
+		t.Errorf("header = %s %+v, want 3.0.3 with the default title and version", spec.OpenAPI, spec.Info)
+	}
+	if len(spec.Tags) != 1 || spec.Tags[0].Name != "accounts" {
+		t.Errorf("tags = %+v, want the configured accounts tag", spec.Tags)
+	}
+
+	var operations []string
+	for path, methods := range spec.Paths {
+		for method, operation := range methods {
+			operations = append(operations, method+" "+path+" "+operation.OperationID)
+			if !reflect.DeepEqual(operation.Tags, []string{"accounts"}) {
+				t.Errorf("%s %s tags = %v, want the first configured tag", method, path, operation.Tags)
+			}
+		}
+	}
+	if len(operations) != 3 {
+		t.Fatalf("operations = %v, want the domain and handler endpoints", operations)
+	}
+
+	create := spec.Paths["/users"]["post"]
+	if create.Summary != "Create a user" || create.RequestBody == nil || !create.RequestBody.Required {
+		t.Fatalf("create operation = %+v, want a required request body", create)
+	}
+	request := create.RequestBody.Content["application/json"].Schema
+	if request == nil || request.Type != "object" || request.Description != "Fields of a new user" {
+		t.Fatalf("request schema = %+v, want the CreateUserRequest object", request)
+	}
+	if !reflect.DeepEqual(request.Required, []string{"email"}) {
+		t.Errorf("request required = %v, want the required fields that are not nullable", request.Required)
+	}
+	if _, ok := request.Properties["password"]; ok {
+		t.Error(`fields tagged json:"-" should be left out`)
+	}
+	if name := request.Properties["name"]; name == nil || name.Type != "string" || !name.Nullable {
+		t.Errorf("name property = %+v, want a nullable string named after its json tag", name)
+	}
+	if age := request.Properties["age"]; age == nil || age.Type != "integer" || age.Format != "int32" {
+		t.Errorf("age property = %+v, want an int32 integer", age)
+	}
+	if _, ok := create.Responses["201"]; !ok {
+		t.Errorf("create responses = %v, want the default 201 of a POST", create.Responses)
+	}
+
+	get := spec.Paths["/users/{id}"]["get"]
+	if len(get.Parameters) != 1 || get.Parameters[0].Name != "id" || get.Parameters[0].In != "path" || !get.Parameters[0].Required {
+		t.Errorf("get parameters = %+v, want the required id path parameter", get.Parameters)
+	}
+	user := get.Responses["200"].Content["application/json"].Schema
+	if user == nil || user.Properties["id"].Format != "uuid" || user.Properties["created_at"].Format != "date-time" {
+		t.Fatalf("get response schema = %+v, want the UserResponse object", user)
+	}
+	if roles := user.Properties["roles"]; roles.Type != "array" || roles.Items == nil || roles.Items.Type != "string" {
+		t.Errorf("roles property = %+v, want an array of strings", roles)
+	}
+
+	list := spec.Paths["/users"]["get"]
+	if len(list.Parameters) != 2 || list.Parameters[0].In != "query" || list.Parameters[0].Schema.Default != 20 || !list.Parameters[1].Required {
+		t.Errorf("list parameters = %+v, want the limit and required cursor query parameters", list.Parameters)
+	}
+	users := list.Responses["200"].Content["application/json"].Schema
+	if users == nil || users.Type != "array" || users.Items == nil || users.Items.Type != "object" {
+		t.Errorf("list response schema = %+v, want an array of UserResponse objects", users)
+	}
+}
+
+func TestGenerateOpenAPISpecErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(data *TemplateData)
+		wantErr string
+	}{
+		{
+			name:    "unknown request type",
+			change:  func(data *TemplateData) { data.Endpoints[0].Request = "MissingRequest" },
+			wantErr: `unknown request type "MissingRequest"`,
+		},
+		{
+			name:    "unknown response type",
+			change:  func(data *TemplateData) { data.Endpoints[1].Response = "MissingResponse" },
+			wantErr: `unknown response type "MissingResponse"`,
+		},
+		{
+			name:    "duplicate operation",
+			change:  func(data *TemplateData) { data.Endpoints[1].Path = "/users" },
+			wantErr: "duplicate operation GET /users",
+		},
+		{
+			name:    "missing method",
+			change:  func(data *TemplateData) { data.Endpoints[0].Method = "" },
+			wantErr: "method and path are required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := openAPITestData()
+			tt.change(&data)
+			_, err := GenerateOpenAPISpec(data)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("GenerateOpenAPISpec() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Custom code markers. Lines between a begin marker and the end marker with
+// the same name are kept when a file is regenerated with preserve_custom_code.
+// Both the short form
+//
+//	// CODE:BEGIN:custom
+//	// CODE:END:custom
+//
+// and the named markers the templates emit around hook points
+//
+//	// @gohex:begin:custom:validate_user
+//	// @gohex:end:custom:validate_user
+//
+// are recognised, including the names with several parts such as
+// custom:method:full_name.
+var (
+	customBeginPattern = regexp.MustCompile(`^\s*// (?:CODE:BEGIN|@gohex:begin):(custom(?::[\w.]+)*)\s*$`)
+	customEndPattern   = regexp.MustCompile(`^\s*// (?:CODE:END|@gohex:end):(custom(?::[\w.]+)*)\s*$`)
+)
+
+// preserveCustomCode splices the custom sections of the file already at
+// outputPath into freshly rendered content. If the new content lacks the
+// markers for a section, a warning is printed and the new content is used as is.
+func preserveCustomCode(outputPath, content string) (string, error) {
+	existing, err := os.ReadFile(outputPath)
+	if os.IsNotExist(err) {
+		return content, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read existing file: %w", err)
+	}
+
+	sections := extractCustomSections(string(existing))
+	if len(sections) == 0 {
+		return content, nil
+	}
+
+	merged, missing := spliceCustomSections(content, sections)
+	if len(missing) > 0 {
+		fmt.Printf("Warning: %s: template no longer has markers for custom sections %s; writing regenerated content without them\n",
+			outputPath, strings.Join(missing, ", "))
+		return content, nil
+	}
+	return merged, nil
+}
+
+// extractCustomSections returns the body of every complete custom section in
+// content, keyed by marker name. Repeated names are keyed name#2, name#3, ...
+func extractCustomSections(content string) map[string][]string {
+	sections := make(map[string][]string)
+	seen := make(map[string]int)
+
+	var name, key string
+	var body []string
+	for _, line := range strings.Split(content, "\n") {
+		if name == "" {
+			if match := customBeginPattern.FindStringSubmatch(line); match != nil {
+				name, key = match[1], sectionKey(match[1], seen)
+				body = []string{}
+			}
+			continue
+		}
+		if match := customEndPattern.FindStringSubmatch(line); match != nil && match[1] == name {
+			sections[key] = body
+			name = ""
+			continue
+		}
+		body = append(body, line)
+	}
+	return sections
+}
+
+// spliceCustomSections replaces the body of each custom section in generated
+// with the preserved body of the same name. It returns the merged content and
+// the sorted names of preserved sections that have no markers in generated.
+func spliceCustomSections(generated string, sections map[string][]string) (string, []string) {
+	var missing []string
+	available := extractCustomSections(generated)
+	for key := range sections {
+		if _, ok := available[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+
+	seen := make(map[string]int)
+	var merged []string
+	var skipping string
+	for _, line := range strings.Split(generated, "\n") {
+		if skipping != "" {
+			if match := customEndPattern.FindStringSubmatch(line); match != nil && match[1] == skipping {
+				merged = append(merged, line)
+				skipping = ""
+			}
+			continue
+		}
+
+		merged = append(merged, line)
+		match := customBeginPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		key := sectionKey(match[1], seen)
+		if _, complete := available[key]; !complete {
+			continue
+		}
+		if body, ok := sections[key]; ok {
+			merged = append(merged, body...)
+			skipping = match[1]
+		}
+	}
+	return strings.Join(merged, "\n"), missing
+}
+
+// sectionKey numbers repeated section names so each occurrence is matched
+// with the occurrence at the same position in the other file
+func sectionKey(name string, seen map[string]int) string {
+	seen[name]++
+	if seen[name] == 1 {
+		return name
+	}
+	return fmt.Sprintf("%s#%d", name, seen[name])
+}
@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPreserveCustomCodeMarkers(t *testing.T) {
+	tests := []struct {
+		name  string
+		begin string
+		end   string
+	}{
+		{"short", "// CODE:BEGIN:custom", "// CODE:END:custom"},
+		{"named", "// @gohex:begin:custom:validate_user", "// @gohex:end:custom:validate_user"},
+		{"method", "// @gohex:begin:custom:method:full_name", "// @gohex:end:custom:method:full_name"},
+		{"computed", "// @gohex:begin:custom:computed:age", "// @gohex:end:custom:computed:age"},
+		{"query", "// @gohex:begin:custom:query_find_active", "// @gohex:end:custom:query_find_active"},
+		{"dotted", "// @gohex:begin:custom:hooks.before_save", "// @gohex:end:custom:hooks.before_save"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := func(header, body string) string {
+				return strings.Join([]string{header, "func f() {", "\t" + tt.begin, body, "\t" + tt.end, "}", ""}, "\n")
+			}
+			path := filepath.Join(t.TempDir(), "file.go")
+			writeFile(t, path, file("// generated v1", "\treturn userCode()"))
+
+			merged, err := preserveCustomCode(path, file("// generated v2", "\tpanic(\"not implemented\")"))
+			if err != nil {
+				t.Fatalf("preserveCustomCode() error = %v", err)
+			}
+			if want := file("// generated v2", "\treturn userCode()"); merged != want {
+				t.Errorf("preserveCustomCode() =\n%s\nwant\n%s", merged, want)
+			}
+		})
+	}
+}
+
+func TestPreserveCustomCodeSections(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.go")
+	writeFile(t, path, `package user
+	// @gohex:begin:custom:method:full_name
+	return first + last
+	// @gohex:end:custom:method:full_name
+	// @gohex:begin:custom:method:initials
+	// @gohex:end:custom:method:full_name
+	return "kept as the section was not ended"
+	// @gohex:end:custom:method:initials
+	// @gohex:begin:custom:computed:age
+	return 1
+	// @gohex:end:custom:computed:age
+	// @gohex:begin:custom:computed:age
+	return 2
+	// @gohex:end:custom:computed:age
+`)
+
+	merged, err := preserveCustomCode(path, `package user
+	// @gohex:begin:custom:method:full_name
+	// @gohex:end:custom:method:full_name
+	// @gohex:begin:custom:method:initials
+	// @gohex:end:custom:method:initials
+	// @gohex:begin:custom:computed:age
+	// @gohex:end:custom:computed:age
+	// @gohex:begin:custom:computed:age
+	// @gohex:end:custom:computed:age
+`)
+	if err != nil {
+		t.Fatalf("preserveCustomCode() error = %v", err)
+	}
+	want := `package user
+	// @gohex:begin:custom:method:full_name
+	return first + last
+	// @gohex:end:custom:method:full_name
+	// @gohex:begin:custom:method:initials
+	// @gohex:end:custom:method:full_name
+	return "kept as the section was not ended"
+	// @gohex:end:custom:method:initials
+	// @gohex:begin:custom:computed:age
+	return 1
+	// @gohex:end:custom:computed:age
+	// @gohex:begin:custom:computed:age
+	return 2
+	// @gohex:end:custom:computed:age
+`
+	if merged != want {
+		t.Errorf("preserveCustomCode() =\n%s\nwant\n%s", merged, want)
+	}
+
+	// Sections without markers in the new content are not spliced anywhere
+	regenerated := "package user\n\t// @gohex:begin:custom:method:full_name\n\t// @gohex:end:custom:method:full_name\n"
+	merged, err = preserveCustomCode(path, regenerated)
+	if err != nil {
+		t.Fatalf("preserveCustomCode() error = %v", err)
+	}
+	if merged != regenerated {
+		t.Errorf("preserveCustomCode() with missing markers =\n%s\nwant the regenerated content", merged)
+	}
+}
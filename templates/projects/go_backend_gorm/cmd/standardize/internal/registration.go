@@ -0,0 +1,120 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// RegistrationFiles are the files that wire generated domains into the
+// application. Domains are registered by hand between the registration markers
+//
+//	// @gohex:begin:domain_imports
+//	userDI "go_backend_gorm/internal/di/user"
+//	// @gohex:end:domain_imports
+//
+//	// @gohex:begin:domain_registrations
+//	userDI.RegisterUser(injector)
+//	// @gohex:end:domain_registrations
+//
+// so removing a domain can un-register it without touching the rest of the file.
+var RegistrationFiles = []string{
+	filepath.Join("cmd", "api", "main.go"),
+}
+
+const (
+	importsBlock       = "domain_imports"
+	registrationsBlock = "domain_registrations"
+)
+
+var (
+	registrationBeginPattern = regexp.MustCompile(`^\s*// @gohex:begin:(domain_\w+)\s*$`)
+	registrationEndPattern   = regexp.MustCompile(`^\s*// @gohex:end:(domain_\w+)\s*$`)
+	importPattern            = regexp.MustCompile(`^\s*(?:(\w+)\s+)?"([^"]+)"\s*$`)
+)
+
+// unregisterDomain removes the imports and registration calls of a domain
+// from the registration blocks of every registration file, returning the
+// files that were changed
+func unregisterDomain(domainSnake string) ([]string, error) {
+	var changed []string
+	for _, path := range RegistrationFiles {
+		content, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return changed, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		updated := removeDomainRegistration(string(content), domainSnake)
+		if updated == string(content) {
+			continue
+		}
+		if err := os.WriteFile(path, []byte(updated), 0644); err != nil {
+			return changed, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		changed = append(changed, path)
+	}
+	return changed, nil
+}
+
+// removeDomainRegistration drops the lines of the registration blocks in
+// content that import the DI package of a domain or call into it
+func removeDomainRegistration(content, domainSnake string) string {
+	lines := strings.Split(content, "\n")
+	diPackage := "/internal/di/" + domainSnake
+
+	// Find the name the DI package is imported as
+	aliases := make(map[string]bool)
+	block := ""
+	for _, line := range lines {
+		block = registrationBlock(line, block)
+		if block != importsBlock {
+			continue
+		}
+		if match := importPattern.FindStringSubmatch(line); match != nil && strings.HasSuffix(match[2], diPackage) {
+			alias := match[1]
+			if alias == "" {
+				alias = domainSnake
+			}
+			aliases[alias] = true
+		}
+	}
+	if len(aliases) == 0 {
+		return content
+	}
+
+	kept := make([]string, 0, len(lines))
+	block = ""
+	for _, line := range lines {
+		block = registrationBlock(line, block)
+		trimmed := strings.TrimSpace(line)
+		switch block {
+		case importsBlock:
+			if match := importPattern.FindStringSubmatch(line); match != nil && strings.HasSuffix(match[2], diPackage) {
+				continue
+			}
+		case registrationsBlock:
+			if alias, _, ok := strings.Cut(trimmed, "."); ok && aliases[alias] {
+				continue
+			}
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n")
+}
+
+// registrationBlock returns the registration block a line is in, given the
+// block of the previous line
+func registrationBlock(line, current string) string {
+	if match := registrationBeginPattern.FindStringSubmatch(line); match != nil {
+		return match[1]
+	}
+	if match := registrationEndPattern.FindStringSubmatch(line); match != nil && match[1] == current {
+		return ""
+	}
+	return current
+}
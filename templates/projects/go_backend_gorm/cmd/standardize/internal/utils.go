@@ -1,10 +1,41 @@
 package internal
 
 import (
+	"fmt"
+	"os"
 	"strings"
 	"unicode"
 )
 
+// statusConstants are the net/http names of the status codes handlers commonly respond with
+var statusConstants = map[int]string{
+	200: "http.StatusOK",
+	201: "http.StatusCreated",
+	202: "http.StatusAccepted",
+	203: "http.StatusNonAuthoritativeInfo",
+	204: "http.StatusNoContent",
+	205: "http.StatusResetContent",
+	206: "http.StatusPartialContent",
+	207: "http.StatusMultiStatus",
+	301: "http.StatusMovedPermanently",
+	302: "http.StatusFound",
+	303: "http.StatusSeeOther",
+	304: "http.StatusNotModified",
+	307: "http.StatusTemporaryRedirect",
+	308: "http.StatusPermanentRedirect",
+}
+
+// StatusConstant returns the net/http constant for the status code of an
+// endpoint, using the default status code of method when statusCode is 0.
+// Codes without a common constant are returned as numbers.
+func StatusConstant(method string, statusCode int) string {
+	statusCode = defaultStatusCode(method, statusCode)
+	if name, ok := statusConstants[statusCode]; ok {
+		return name
+	}
+	return fmt.Sprint(statusCode)
+}
+
 // ToSnakeCase converts a string to snake_case
 func ToSnakeCase(s string) string {
 	if s == "" {
@@ -68,6 +99,37 @@ func ToCamelCase(s string) string {
 	return strings.ToLower(string(pascal[0])) + pascal[1:]
 }
 
+// MethodResults splits the returns of a business method into the types of its
+// results before the trailing error, e.g. "(*entityPkg.User, error)" into
+// *entityPkg.User. It returns false when the method does not return an error.
+func MethodResults(returns string) ([]string, bool) {
+	returns = strings.TrimSpace(returns)
+	if strings.HasPrefix(returns, "(") && strings.HasSuffix(returns, ")") {
+		returns = returns[1 : len(returns)-1]
+	}
+
+	var results []string
+	depth, start := 0, 0
+	for i, r := range returns {
+		switch r {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				results = append(results, strings.TrimSpace(returns[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	results = append(results, strings.TrimSpace(returns[start:]))
+	if results[len(results)-1] != "error" {
+		return nil, false
+	}
+	return results[:len(results)-1], true
+}
+
 // Pluralize adds an 's' to the end of a string
 // This is a simple implementation - could be enhanced with proper pluralization rules
 func Pluralize(s string) string {
@@ -163,3 +225,75 @@ func SanitizeIdentifier(s string) string {
 	
 	return result.String()
 }
+
+// FileDiff compares the file at path with newContent and returns a line diff,
+// or an empty string when they are identical. A missing file diffs as new.
+func FileDiff(path, newContent string) (string, error) {
+	oldContent := ""
+	existing, err := os.ReadFile(path)
+	if err == nil {
+		oldContent = string(existing)
+	} else if !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if oldContent == newContent {
+		return "", nil
+	}
+
+	oldLines := splitLines(oldContent)
+	newLines := splitLines(newContent)
+
+	// Longest common subsequence table, filled from the end
+	lcs := make([][]int, len(oldLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(newLines)+1)
+	}
+	for i := len(oldLines) - 1; i >= 0; i-- {
+		for j := len(newLines) - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var result strings.Builder
+	if existing == nil {
+		fmt.Fprintf(&result, "--- /dev/null\n+++ %s\n", path)
+	} else {
+		fmt.Fprintf(&result, "--- %s\n+++ %s (generated)\n", path, path)
+	}
+	i, j := 0, 0
+	inChange := false
+	for i < len(oldLines) || j < len(newLines) {
+		if i < len(oldLines) && j < len(newLines) && oldLines[i] == newLines[j] {
+			i++
+			j++
+			inChange = false
+			continue
+		}
+		// Start each run of changes with its position in both files
+		if !inChange {
+			fmt.Fprintf(&result, "@@ -%d +%d @@\n", i+1, j+1)
+			inChange = true
+		}
+		if i < len(oldLines) && (j == len(newLines) || lcs[i+1][j] >= lcs[i][j+1]) {
+			fmt.Fprintf(&result, "-%s\n", oldLines[i])
+			i++
+		} else {
+			fmt.Fprintf(&result, "+%s\n", newLines[j])
+			j++
+		}
+	}
+	return result.String(), nil
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -1,17 +1,27 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"sort"
 
 	"go_backend_gorm/cmd/standardize/internal"
+	"go_backend_gorm/internal/lint"
 )
 
 var (
 	domainFlag = flag.String("domain", "", "Domain name (required)")
 	entityFlag = flag.String("name", "", "Entity name (required for entity command)")
 	configFlag = flag.String("config", "", "Configuration file path (YAML)")
+	dryRunFlag = flag.Bool("dry-run", false, "Show what would be generated without writing files")
+	forceFlag  = flag.Bool("force", false, "Overwrite or delete generated files that were modified by hand")
+	lintFlag   = flag.Bool("lint", false, "Run the entity linter after generating, failing if it finds errors")
+
+	printEffectiveConfigFlag = flag.Bool("print-effective-config", false, "Print the configuration merged with its includes and exit")
+	checkVersionFlag         = flag.Bool("check-version", false, "Report the configuration version and the migrations that apply, then exit")
+	migrateInPlaceFlag       = flag.Bool("migrate-in-place", false, "Rewrite the configuration and its includes to the current version")
 )
 
 func main() {
@@ -20,9 +30,68 @@ func main() {
 	// Initialize command handler
 	commandHandler := internal.NewCommandHandler()
 
+	if *printEffectiveConfigFlag && *configFlag == "" {
+		fmt.Println("Error: --print-effective-config requires --config")
+		os.Exit(1)
+	}
+	if (*checkVersionFlag || *migrateInPlaceFlag) && *configFlag == "" {
+		fmt.Println("Error: --check-version and --migrate-in-place require --config")
+		os.Exit(1)
+	}
+
+	// Restore takes its own flags after the command
+	if flag.Arg(0) == "restore" {
+		restoreFlags := flag.NewFlagSet("restore", flag.ExitOnError)
+		backupFlag := restoreFlags.String("backup", "", "Timestamp of the run whose backups to restore")
+		restoreFlags.Parse(flag.Args()[1:])
+		if *dryRunFlag {
+			runAndExit(fmt.Errorf("--dry-run is not supported by restore"))
+		}
+		runAndExit(commandHandler.Restore(*backupFlag))
+	}
+
 	// Check if config file is provided
 	if *configFlag != "" {
-		if err := commandHandler.GenerateFromConfig(*configFlag); err != nil {
+		if *printEffectiveConfigFlag {
+			effectiveConfig, err := commandHandler.EffectiveConfig(*configFlag)
+			if err != nil {
+				fmt.Printf("Error: %s\n", err)
+				os.Exit(1)
+			}
+			fmt.Print(effectiveConfig)
+			return
+		}
+		if *checkVersionFlag {
+			report, err := commandHandler.CheckVersion(*configFlag)
+			if err != nil {
+				fmt.Printf("Error: %s\n", err)
+				os.Exit(1)
+			}
+			fmt.Print(report)
+			if *migrateInPlaceFlag {
+				runAndExit(commandHandler.MigrateInPlace(*configFlag))
+			}
+			return
+		}
+		if *migrateInPlaceFlag {
+			if *dryRunFlag {
+				runAndExit(fmt.Errorf("--dry-run is not supported by --migrate-in-place"))
+			}
+			if err := commandHandler.MigrateInPlace(*configFlag); err != nil {
+				fmt.Printf("Error: %s\n", err)
+				os.Exit(1)
+			}
+		}
+		if flag.Arg(0) == "regenerate" {
+			if *dryRunFlag {
+				runAndExit(fmt.Errorf("--dry-run is not supported by regenerate"))
+			}
+			runAndExit(lintGenerated(commandHandler.RegenerateFromConfig(*configFlag, *forceFlag)))
+		}
+		if *dryRunFlag {
+			previewAndExit(commandHandler.GeneratePreviewFromConfig(*configFlag))
+		}
+		if err := lintGenerated(commandHandler.GenerateFromConfig(*configFlag)); err != nil {
 			fmt.Printf("Error: %s\n", err)
 			os.Exit(1)
 		}
@@ -46,7 +115,19 @@ func main() {
 
 	// Execute command
 	commandName := args[0]
-	if err := commandHandler.GenerateLegacy(*domainFlag, *entityFlag, commandName); err != nil {
+	if commandName == "regenerate" || commandName == "remove" {
+		if *dryRunFlag {
+			runAndExit(fmt.Errorf("--dry-run is not supported by %s", commandName))
+		}
+		if commandName == "regenerate" {
+			runAndExit(lintGenerated(commandHandler.Regenerate(*domainFlag, *forceFlag)))
+		}
+		runAndExit(commandHandler.Remove(*domainFlag, *forceFlag))
+	}
+	if *dryRunFlag {
+		previewAndExit(commandHandler.GeneratePreview(*domainFlag, *entityFlag, commandName))
+	}
+	if err := lintGenerated(commandHandler.GenerateLegacy(*domainFlag, *entityFlag, commandName)); err != nil {
 		fmt.Printf("Error: %s\n", err)
 		os.Exit(1)
 	}
@@ -58,8 +139,14 @@ func printUsage(ch *internal.CommandHandler) {
 	fmt.Println("Error: domain flag is required")
 	fmt.Println()
 	fmt.Println("Usage:")
-	fmt.Println("  standardize --config <config_file.yaml>")
-	fmt.Println("  standardize --domain <domain_name> [--name <entity_name>] <command>")
+	fmt.Println("  standardize [--dry-run] [--lint] --config <config_file.yaml>")
+	fmt.Println("  standardize --print-effective-config --config <config_file.yaml>")
+	fmt.Println("  standardize --check-version [--migrate-in-place] --config <config_file.yaml>")
+	fmt.Println("  standardize [--migrate-in-place] --config <config_file.yaml>")
+	fmt.Println("  standardize [--dry-run] [--lint] --domain <domain_name> [--name <entity_name>] <command>")
+	fmt.Println("  standardize [--force] [--config <config_file.yaml> | --domain <domain_name>] regenerate")
+	fmt.Println("  standardize [--force] --domain <domain_name> remove")
+	fmt.Println("  standardize restore [--backup <timestamp>]")
 	fmt.Println()
 	printAvailableCommands(ch)
 }
@@ -70,3 +157,65 @@ func printAvailableCommands(ch *internal.CommandHandler) {
 		fmt.Printf("  %s: %s\n", cmd.Name, cmd.Description)
 	}
 }
+
+// lintGenerated runs the entity linter on the project once generation
+// succeeded, when --lint is set, returning an error when it finds errors
+func lintGenerated(err error) error {
+	if err != nil || !*lintFlag {
+		return err
+	}
+	linter := lint.New(lint.Options{})
+	if _, err := linter.Run(context.Background(), "."); err != nil {
+		return fmt.Errorf("lint: %w", err)
+	}
+	if err := linter.OutputResults("text"); err != nil {
+		return fmt.Errorf("lint: %w", err)
+	}
+	if linter.HasErrors() {
+		return fmt.Errorf("the entity linter found errors in the generated code")
+	}
+	return nil
+}
+
+// runAndExit reports the result of a command that does its own output, then exits
+func runAndExit(err error) {
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Done!")
+	os.Exit(0)
+}
+
+// previewAndExit prints the diff of every file that would be created or
+// updated against what is currently on disk, then exits
+func previewAndExit(files map[string]string, err error) {
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		os.Exit(1)
+	}
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	changed := 0
+	for _, path := range paths {
+		diff, err := internal.FileDiff(path, files[path])
+		if err != nil {
+			fmt.Printf("Error: %s\n", err)
+			os.Exit(1)
+		}
+		if diff == "" {
+			fmt.Printf("Unchanged %s\n", path)
+			continue
+		}
+		changed++
+		fmt.Print(diff)
+	}
+
+	fmt.Printf("Dry run: %d of %d files would be written\n", changed, len(paths))
+	os.Exit(0)
+}
@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"gorm.io/gorm"
+)
+
+// ErrNoTransaction is returned when committing without a transaction in
+// progress
+var ErrNoTransaction = errors.New("no transaction in progress")
+
+// txKey is the context key of the unit of work in progress
+type txKey struct{}
+
+// unitOfWork is a transaction begun by Begin, owned by the context carrying it
+type unitOfWork struct {
+	tx *gorm.DB
+	// nested units of work run in the transaction of the outer one, which
+	// commits or rolls it back
+	nested bool
+	done   atomic.Bool
+}
+
+// Begin starts a transaction on db, returning a context carrying it: the calls
+// made through Conn with that context, or one derived from it, run in the
+// transaction until Commit or Rollback. Beginning within a unit of work joins
+// its transaction.
+func Begin(ctx context.Context, db *gorm.DB) (context.Context, error) {
+	if outer, ok := ctx.Value(txKey{}).(*unitOfWork); ok && !outer.done.Load() {
+		return context.WithValue(ctx, txKey{}, &unitOfWork{tx: outer.tx, nested: true}), nil
+	}
+	tx := db.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return ctx, tx.Error
+	}
+	return context.WithValue(ctx, txKey{}, &unitOfWork{tx: tx}), nil
+}
+
+// Commit commits the transaction ctx carries
+func Commit(ctx context.Context) error {
+	uw, ok := ctx.Value(txKey{}).(*unitOfWork)
+	if !ok || !uw.done.CompareAndSwap(false, true) {
+		return ErrNoTransaction
+	}
+	if uw.nested {
+		return nil
+	}
+	return uw.tx.Commit().Error
+}
+
+// Rollback rolls back the transaction ctx carries, doing nothing once it is
+// committed so that it can be deferred before Commit
+func Rollback(ctx context.Context) error {
+	uw, ok := ctx.Value(txKey{}).(*unitOfWork)
+	if !ok || !uw.done.CompareAndSwap(false, true) || uw.nested {
+		return nil
+	}
+	return uw.tx.Rollback().Error
+}
+
+// Conn returns the transaction ctx carries, or db without one
+func Conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if uw, ok := ctx.Value(txKey{}).(*unitOfWork); ok && !uw.done.Load() {
+		return uw.tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
@@ -0,0 +1,185 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+
+	pgdriver "gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// recorder records the transaction each statement ran in, 0 for none, and how
+// each transaction ended
+type recorder struct {
+	mu       sync.Mutex
+	lastTx   int
+	execs    map[int64]int
+	finished map[int][]string
+}
+
+func (r *recorder) Connect(context.Context) (driver.Conn, error) { return &fakeConn{r: r}, nil }
+func (r *recorder) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct {
+	r  *recorder
+	tx int
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *fakeConn) Close() error                        { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.r.mu.Lock()
+	defer c.r.mu.Unlock()
+	c.r.lastTx++
+	c.tx = c.r.lastTx
+	return fakeTx{c}, nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, _ string, args []driver.NamedValue) (driver.Result, error) {
+	c.r.mu.Lock()
+	defer c.r.mu.Unlock()
+	c.r.execs[args[0].Value.(int64)] = c.tx
+	return driver.RowsAffected(1), nil
+}
+
+type fakeTx struct{ c *fakeConn }
+
+func (tx fakeTx) Commit() error   { return tx.end("commit") }
+func (tx fakeTx) Rollback() error { return tx.end("rollback") }
+
+func (tx fakeTx) end(how string) error {
+	r := tx.c.r
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.finished[tx.c.tx] = append(r.finished[tx.c.tx], how)
+	tx.c.tx = 0
+	return nil
+}
+
+func openFake(t *testing.T) (*gorm.DB, *recorder) {
+	t.Helper()
+	r := &recorder{execs: make(map[int64]int), finished: make(map[int][]string)}
+	sqlDB := sql.OpenDB(r)
+	t.Cleanup(func() { sqlDB.Close() })
+	db, err := gorm.Open(pgdriver.New(pgdriver.Config{Conn: sqlDB}), &gorm.Config{SkipDefaultTransaction: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("gorm.Open() error = %v", err)
+	}
+	return db, r
+}
+
+func insert(ctx context.Context, db *gorm.DB, value int) error {
+	return Conn(ctx, db).Exec("INSERT INTO items VALUES (?)", value).Error
+}
+
+func TestConcurrentUnitsOfWork(t *testing.T) {
+	db, r := openFake(t)
+	const units = 20
+
+	var wg sync.WaitGroup
+	for i := 1; i <= units; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx, err := Begin(context.Background(), db)
+			if err != nil {
+				t.Errorf("Begin() error = %v", err)
+				return
+			}
+			defer Rollback(ctx)
+
+			if err := insert(ctx, db, i); err != nil {
+				t.Errorf("insert in the transaction: %v", err)
+			}
+			// A call of another request meanwhile runs outside of the transaction
+			if err := insert(context.Background(), db, -i); err != nil {
+				t.Errorf("insert outside of the transaction: %v", err)
+			}
+			if i%2 == 0 {
+				if err := Commit(ctx); err != nil {
+					t.Errorf("Commit() error = %v", err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seen := make(map[int]int)
+	for i := 1; i <= units; i++ {
+		tx := r.execs[int64(i)]
+		if tx == 0 {
+			t.Errorf("unit %d ran outside of a transaction", i)
+			continue
+		}
+		if other, ok := seen[tx]; ok {
+			t.Errorf("units %d and %d ran in the same transaction", other, i)
+		}
+		seen[tx] = i
+
+		want := "rollback"
+		if i%2 == 0 {
+			want = "commit"
+		}
+		if got := r.finished[tx]; len(got) != 1 || got[0] != want {
+			t.Errorf("transaction of unit %d ended with %v, want one %s", i, got, want)
+		}
+		if tx := r.execs[int64(-i)]; tx != 0 {
+			t.Errorf("the call made beside unit %d ran in transaction %d", i, tx)
+		}
+	}
+	if len(r.finished) != units {
+		t.Errorf("%d transactions ended, want %d", len(r.finished), units)
+	}
+}
+
+func TestNestedUnitOfWork(t *testing.T) {
+	db, r := openFake(t)
+
+	outer, err := Begin(context.Background(), db)
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	inner, err := Begin(outer, db)
+	if err != nil {
+		t.Fatalf("nested Begin() error = %v", err)
+	}
+	if err := insert(inner, db, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := Commit(inner); err != nil {
+		t.Fatalf("nested Commit() error = %v", err)
+	}
+	if err := insert(outer, db, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := Rollback(outer); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+
+	tx := r.execs[1]
+	if tx == 0 || r.execs[2] != tx {
+		t.Errorf("the nested unit of work ran in transaction %d, the outer one in %d", tx, r.execs[2])
+	}
+	if got := r.finished[tx]; len(got) != 1 || got[0] != "rollback" {
+		t.Errorf("transaction ended with %v, want the rollback of the outer unit of work only", got)
+	}
+
+	// Once ended, the context runs its calls outside of a transaction
+	if err := Commit(outer); !errors.Is(err, ErrNoTransaction) {
+		t.Errorf("Commit() after Rollback() error = %v, want %v", err, ErrNoTransaction)
+	}
+	if err := Rollback(outer); err != nil {
+		t.Errorf("Rollback() after Rollback() error = %v", err)
+	}
+	if err := insert(outer, db, 3); err != nil || r.execs[3] != 0 {
+		t.Errorf("insert after Rollback() ran in transaction %d, %v", r.execs[3], err)
+	}
+}
@@ -6,6 +6,7 @@ require (
 	github.com/google/uuid v1.6.0
 	github.com/samber/do v1.6.0
 	go.uber.org/zap v1.27.0
+	golang.org/x/mod v0.24.0
 	gopkg.in/yaml.v3 v3.0.1
 	gorm.io/driver/postgres v1.6.0
 	gorm.io/gorm v1.30.0
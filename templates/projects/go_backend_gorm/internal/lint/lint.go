@@ -0,0 +1,948 @@
+// Package lint checks the entity templates of the project are named
+// consistently across its layers.
+package lint
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// LintResult represents a linting issue
+type LintResult struct {
+	File       string `json:"file"`
+	Line       int    `json:"line"`
+	Column     int    `json:"column"`
+	Severity   string `json:"severity"` // "error", "warning", "info"
+	Message    string `json:"message"`
+	Rule       string `json:"rule"`
+	Suggestion string `json:"suggestion,omitempty"`
+	FixCommand string `json:"fixCommand,omitempty"` // Command that resolves the issue, when there is one
+}
+
+// EntityInfo holds information about an entity
+type EntityInfo struct {
+	Name            string // PascalCase (e.g., "User")
+	NameSnake       string // snake_case (e.g., "user")
+	NamePlural      string // PascalCase plural (e.g., "Users")
+	NamePluralSnake string // snake_case plural (e.g., "users")
+	Domain          string // Domain name
+	DomainSnake     string // Domain name in snake_case
+	FilePath        string // Where the entity was found
+	IsPartial       bool   // Template is a partial included by other templates
+}
+
+// EntityUsage is the entity name a template uses where layers refer to the entity
+type EntityUsage struct {
+	File  string
+	Line  int
+	Value string // Template variable or hardcoded name (e.g. "{{.Entity}}" or "User")
+}
+
+// Rules are the rules the linter checks
+var Rules = []string{
+	"missing-file",
+	"naming-consistency",
+	"cross-layer-inconsistency",
+	"file-read-error",
+	"invalid-regex",
+}
+
+// Options configure a Linter
+type Options struct {
+	// Rules restricts the results to these rules, all of them when empty
+	Rules []string
+	// Fix rewrites the hardcoded names of the templates with their template
+	// variables, reporting each fix as an info result instead of an error
+	Fix bool
+	// Verbose writes the discovered entities to Output
+	Verbose bool
+	// Output receives the verbose output and the results, os.Stdout when nil
+	Output io.Writer
+}
+
+// Linter performs entity naming consistency checks
+type Linter struct {
+	entities map[string]*EntityInfo
+	results  []LintResult
+	usages   []EntityUsage
+	options  Options
+	output   io.Writer
+}
+
+// New creates a linter
+func New(opts Options) *Linter {
+	output := opts.Output
+	if output == nil {
+		output = os.Stdout
+	}
+	return &Linter{options: opts, output: output}
+}
+
+// Run lints the templates of the project at rootPath and returns the issues
+// found
+func (l *Linter) Run(ctx context.Context, rootPath string) ([]LintResult, error) {
+	for _, rule := range l.options.Rules {
+		if !slices.Contains(Rules, rule) {
+			return nil, fmt.Errorf("unknown rule %q", rule)
+		}
+	}
+	l.entities = make(map[string]*EntityInfo)
+	l.results = []LintResult{}
+	l.usages = nil
+
+	// Phase 1: Discover entities
+	if err := l.discoverEntities(ctx, rootPath); err != nil {
+		return nil, fmt.Errorf("failed to discover entities: %w", err)
+	}
+
+	if l.options.Verbose {
+		fmt.Fprintf(l.output, "Discovered %d entities:\n", len(l.entities))
+		for name, entity := range l.entities {
+			fmt.Fprintf(l.output, "  %s (%s) in domain %s\n", name, entity.NameSnake, entity.Domain)
+		}
+		fmt.Fprintln(l.output)
+	}
+
+	// Phase 2: Check naming consistency across layers
+	if err := l.checkNamingConsistency(ctx, rootPath); err != nil {
+		return nil, fmt.Errorf("failed to check naming consistency: %w", err)
+	}
+
+	// Phase 3: Check the layers agree on the entity name
+	l.checkCrossLayerConsistency()
+
+	return l.results, nil
+}
+
+// discoverEntities scans for entity template definitions
+func (l *Linter) discoverEntities(ctx context.Context, rootPath string) error {
+	entityPath := filepath.Join(rootPath, "internal", "core", "entity")
+	if _, err := os.Stat(entityPath); os.IsNotExist(err) {
+		return nil // No entities directory
+	}
+
+	return filepath.Walk(entityPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// Only check template files (.tmpl)
+		if !strings.HasSuffix(path, ".tmpl") {
+			return nil
+		}
+
+		return l.parseTemplateFile(path)
+	})
+}
+
+// parseTemplateFile parses a Go template file looking for entity patterns
+func (l *Linter) parseTemplateFile(filePath string) error {
+	src, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	// Extract domain from path (e.g., internal/core/entity/{{DOMAIN}}/entity.go.tmpl -> {{DOMAIN}})
+	parts := strings.Split(filePath, string(os.PathSeparator))
+	for i, part := range parts {
+		if part == "entity" && i+1 < len(parts) {
+			// domain = parts[i+1] // This will be "{{DOMAIN}}" in templates
+			break
+		}
+	}
+
+	// For template files, we create a standardized entity for validation
+	// We use "Entity" as the standard entity name since templates use {{.Entity}}
+	entity := &EntityInfo{
+		Name:            "Entity",   // Standard template placeholder
+		NameSnake:       "entity",   // Standard template placeholder
+		NamePlural:      "Entities", // Standard template placeholder
+		NamePluralSnake: "entities", // Standard template placeholder
+		Domain:          "Domain",   // Standard template placeholder
+		DomainSnake:     "domain",   // Standard template placeholder
+		FilePath:        filePath,
+	}
+
+	// Check if this template file contains entity-like patterns
+	content := string(src)
+	switch {
+	case l.isEntityTemplate(content):
+		l.entities["Entity"] = entity
+
+		if l.options.Verbose {
+			fmt.Fprintf(l.output, "Found entity template: %s\n", filePath)
+		}
+	case (isPartial(filePath) || isPartialTemplate(content)) && hasEntityFields(content):
+		// Partials only hold part of an entity, so each one is linted on its own
+		entity.IsPartial = true
+		l.entities[filePath] = entity
+
+		if l.options.Verbose {
+			fmt.Fprintf(l.output, "Found entity partial: %s\n", filePath)
+		}
+	}
+
+	return nil
+}
+
+// isEntityTemplate checks if a template contains entity-like patterns
+func (l *Linter) isEntityTemplate(content string) bool {
+	// Check for common entity template patterns
+	hasTimestamps := strings.Contains(content, "CreatedAt") && strings.Contains(content, "UpdatedAt")
+
+	return hasEntityFields(content) && hasTimestamps
+}
+
+// hasEntityFields checks if a template references the entity and its ID field
+func hasEntityFields(content string) bool {
+	hasEntityRef := strings.Contains(content, "{{.Entity}}")
+	hasIDField := strings.Contains(content, "ID") && (strings.Contains(content, "uuid.UUID") || strings.Contains(content, "UUID"))
+
+	return hasEntityRef && hasIDField
+}
+
+// partialInvocationPattern matches a template invoking another template
+var partialInvocationPattern = regexp.MustCompile(`\{\{-?\s*template\s+"`)
+
+// isPartialTemplate checks if a template is marked as a partial or is built
+// from other templates
+func isPartialTemplate(content string) bool {
+	return strings.Contains(content, "// CODE:partial") || partialInvocationPattern.MatchString(content)
+}
+
+// checkNamingConsistency verifies naming consistency across all layers
+func (l *Linter) checkNamingConsistency(ctx context.Context, rootPath string) error {
+	for _, entity := range l.entities {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// Partials are checked on their own, not across layers
+		if entity.IsPartial {
+			l.checkPartialContent(entity)
+			continue
+		}
+
+		// Check repository layer
+		l.checkRepositoryNaming(rootPath, entity)
+
+		// Check usecase layer
+		l.checkUseCaseNaming(rootPath, entity)
+
+		// Check handler layer
+		l.checkHandlerNaming(rootPath, entity)
+
+		// Check DI layer
+		l.checkDINaming(rootPath, entity)
+
+		// Check model layer
+		l.checkModelNaming(rootPath, entity)
+	}
+
+	return nil
+}
+
+// checkRepositoryNaming checks repository template naming consistency
+func (l *Linter) checkRepositoryNaming(rootPath string, entity *EntityInfo) {
+	repoPath := filepath.Join(rootPath, "internal", "repository", "{{DOMAIN}}")
+
+	// Check repository implementation template file
+	repoFile := filepath.Join(repoPath, "repository.go.tmpl")
+	if err := l.checkFileNaming(repoFile, entity, "repository"); err == nil {
+		l.checkRepositoryContent(repoFile, entity)
+	}
+
+	// The configured repository template is optional
+	configFile := filepath.Join(repoPath, "repository_config.go.tmpl")
+	if _, err := os.Stat(configFile); err == nil {
+		l.checkRepositoryConfigContent(configFile, entity)
+	}
+
+	// Check repositories registration template file
+	regFile := filepath.Join(repoPath, "repositories.go.tmpl")
+	if err := l.checkFileNaming(regFile, entity, "repository_registration"); err == nil {
+		l.checkRepositoryRegistration(regFile, entity)
+	}
+}
+
+// checkUseCaseNaming checks usecase template naming consistency
+func (l *Linter) checkUseCaseNaming(rootPath string, entity *EntityInfo) {
+	usecasePath := filepath.Join(rootPath, "internal", "usecase", "{{DOMAIN}}")
+
+	// Check usecase implementation template file
+	usecaseFile := filepath.Join(usecasePath, "usecase.go.tmpl")
+	if err := l.checkFileNaming(usecaseFile, entity, "usecase"); err == nil {
+		l.checkUseCaseContent(usecaseFile, entity)
+	}
+
+	// Check usecases registration template file
+	regFile := filepath.Join(usecasePath, "usecases.go.tmpl")
+	if err := l.checkFileNaming(regFile, entity, "usecase_registration"); err == nil {
+		l.checkUseCaseRegistration(regFile, entity)
+	}
+}
+
+// checkHandlerNaming checks handler template naming consistency
+func (l *Linter) checkHandlerNaming(rootPath string, entity *EntityInfo) {
+	handlerPath := filepath.Join(rootPath, "internal", "interface", "http", "handlers", "{{DOMAIN}}")
+
+	// Check handler implementation template file
+	handlerFile := filepath.Join(handlerPath, "handler.go.tmpl")
+	if err := l.checkFileNaming(handlerFile, entity, "handler"); err == nil {
+		l.checkHandlerContent(handlerFile, entity)
+	}
+
+	// Check handlers registration template file
+	regFile := filepath.Join(handlerPath, "handlers.go.tmpl")
+	if err := l.checkFileNaming(regFile, entity, "handler_registration"); err == nil {
+		l.checkHandlerRegistration(regFile, entity)
+	}
+}
+
+// checkDINaming checks DI template naming consistency
+func (l *Linter) checkDINaming(rootPath string, entity *EntityInfo) {
+	diPath := filepath.Join(rootPath, "internal", "di", "{{DOMAIN}}")
+	diFile := filepath.Join(diPath, "di.go.tmpl")
+
+	if err := l.checkFileNaming(diFile, entity, "di"); err == nil {
+		l.checkDIContent(diFile, entity)
+	}
+}
+
+// checkModelNaming checks model template naming consistency
+func (l *Linter) checkModelNaming(rootPath string, entity *EntityInfo) {
+	modelPath := filepath.Join(rootPath, "internal", "core", "models", "{{DOMAIN}}")
+	modelFile := filepath.Join(modelPath, "model.go.tmpl")
+
+	if err := l.checkFileNaming(modelFile, entity, "model"); err == nil {
+		l.checkModelContent(modelFile, entity)
+	}
+}
+
+// checkFileNaming verifies that expected files exist
+func (l *Linter) checkFileNaming(filePath string, entity *EntityInfo, layer string) error {
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		l.addResult(LintResult{
+			File:       filePath,
+			Severity:   "error",
+			Message:    fmt.Sprintf("Missing %s file for entity %s", layer, entity.Name),
+			Rule:       "missing-file",
+			Suggestion: fmt.Sprintf("Generate %s file for entity %s", layer, entity.Name),
+			FixCommand: generateCommand(entity, layer),
+		})
+		return err
+	}
+	return nil
+}
+
+// layerCommands maps the layers checked by checkFileNaming to the standardize
+// command generating their files
+var layerCommands = map[string]string{
+	"repository":              "repository",
+	"repository_registration": "repository",
+	"usecase":                 "usecase",
+	"usecase_registration":    "usecase",
+	"handler":                 "handler",
+	"handler_registration":    "handler",
+	"di":                      "di",
+	"model":                   "model",
+}
+
+// generateCommand returns the standardize command generating the files of a
+// layer for entity
+func generateCommand(entity *EntityInfo, layer string) string {
+	command, ok := layerCommands[layer]
+	if !ok {
+		command = "all"
+	}
+	return fmt.Sprintf("standardize --domain %s --name %s %s", entity.DomainSnake, entity.Name, command)
+}
+
+// checkRepositoryContent checks repository template content for naming consistency
+func (l *Linter) checkRepositoryContent(filePath string, entity *EntityInfo) {
+	l.checkFileContent(filePath, entity, []NamePattern{
+		{Pattern: `type\s+I\{\{\.Entity\}\}Repository\s+interface`, Required: true, Message: "Repository interface should use {{.Entity}} template variable"},
+		{Pattern: `type\s+\{\{\.Entity\}\}Repository\s+struct`, Required: true, Message: "Repository struct should use {{.Entity}} template variable"},
+		{Pattern: `func\s+New\{\{\.Entity\}\}Repository`, Required: true, Message: "Repository constructor should use {{.Entity}} template variable"},
+		{Pattern: `func\s+\([^)]*\)\s+Create\s*\(`, Required: true, Message: "Repository should have Create method"},
+		{Pattern: `func\s+\([^)]*\)\s+GetByID\s*\(`, Required: true, Message: "Repository should have GetByID method"},
+		{Pattern: `func\s+\([^)]*\)\s+List\s*\(`, Required: true, Message: "Repository should have List method"},
+		{Pattern: `func\s+\([^)]*\)\s+Update\s*\(`, Required: true, Message: "Repository should have Update method"},
+		{Pattern: `func\s+\([^)]*\)\s+Delete\s*\(`, Required: true, Message: "Repository should have Delete method"},
+		{Pattern: `type\s+(\{\{\.Entity\}\}|\w+)Repository\s+struct`, Entity: true},
+	})
+}
+
+// checkRepositoryConfigContent checks the configured repository template
+// generates the methods its configuration asks for
+func (l *Linter) checkRepositoryConfigContent(filePath string, entity *EntityInfo) {
+	l.checkFileContent(filePath, entity, []NamePattern{
+		{Pattern: `^\s+Search\(ctx context\.Context, query string, opts ListOptions\)`, Required: true, When: `\.Filtering\.SearchFields|\.StandardMethods\.Search`, Message: "Repository interface should have Search method when search fields are configured"},
+		{Pattern: `func\s+\([^)]*\)\s+Search\s*\(`, Required: true, When: `\.Filtering\.SearchFields|\.StandardMethods\.Search`, Message: "Repository should implement Search method when search fields are configured"},
+	})
+}
+
+// checkUseCaseContent checks usecase template content for naming consistency
+func (l *Linter) checkUseCaseContent(filePath string, entity *EntityInfo) {
+	l.checkFileContent(filePath, entity, []NamePattern{
+		{Pattern: `type\s+I\{\{\.Entity\}\}UseCase\s+interface`, Required: true, Message: "UseCase interface should use {{.Entity}} template variable"},
+		{Pattern: `type\s+\{\{\.Entity\}\}UseCase\s+struct`, Required: true, Message: "UseCase struct should use {{.Entity}} template variable"},
+		{Pattern: `func\s+New\{\{\.Entity\}\}UseCase`, Required: true, Message: "UseCase constructor should use {{.Entity}} template variable"},
+		{Pattern: `\{\{\.EntitySnake\}\}Repo\s+repoPkg\.I\{\{\.Entity\}\}Repository`, Required: true, Message: "UseCase should use template variables for repository field"},
+		{Pattern: `type\s+(\{\{\.Entity\}\}|\w+)UseCase\s+struct`, Entity: true},
+	})
+}
+
+// checkHandlerContent checks handler template content for naming consistency
+func (l *Linter) checkHandlerContent(filePath string, entity *EntityInfo) {
+	l.checkFileContent(filePath, entity, []NamePattern{
+		{Pattern: `type\s+Handler\s+struct`, Required: true, Message: "Handler struct should exist"},
+		{Pattern: `func\s+NewHandler`, Required: true, Message: "Handler constructor should be named NewHandler"},
+		{Pattern: `{{\.EntitySnake}}UseCase\s+usecasePkg\.I{{\.Entity}}UseCase`, Required: true, Message: "Handler should use template variables for usecase field"},
+		{Pattern: `func\s+\([^)]*\)\s+handle{{\.Entities}}\s*\(`, Required: true, Message: "Handler should use {{.Entities}} template variable for collection method"},
+		{Pattern: `func\s+\([^)]*\)\s+handle{{\.Entity}}ByID\s*\(`, Required: true, Message: "Handler should use {{.Entity}} template variable for item method"},
+		{Pattern: `/api/v1/{{\.EntitiesSnake}}`, Required: true, Message: "Handler should use {{.EntitiesSnake}} template variable for routes"},
+		{Pattern: `w\.WriteHeader\({{statusFor "POST" \.Handlers\.StandardEndpoints\.Create\.StatusCode}}\)`, Required: true, Message: "Create response should use the configured status code via statusFor"},
+		{Pattern: `w\.WriteHeader\({{statusFor "DELETE" \.Handlers\.StandardEndpoints\.Delete\.StatusCode}}\)`, Required: true, Message: "Delete response should use the configured status code via statusFor"},
+		{Pattern: `usecasePkg\.I(\{\{\.Entity\}\}|\w+)UseCase`, Entity: true},
+	})
+}
+
+// checkModelContent checks model template content for naming consistency
+func (l *Linter) checkModelContent(filePath string, entity *EntityInfo) {
+	l.checkFileContent(filePath, entity, []NamePattern{
+		{Pattern: `type\s+{{\.Entity}}\s+struct`, Required: true, Message: "Model struct should use {{.Entity}} template variable"},
+		{Pattern: `func\s+\({{\.Entity}}\)\s+TableName\s*\(\s*\)\s+string`, Required: true, Message: "Model should have TableName method with correct receiver"},
+		{Pattern: `return\s+"{{\.EntitiesSnake}}"`, Required: true, Message: "TableName should return {{.EntitiesSnake}} template variable"},
+		{Pattern: `func\s+\([^)]*\)\s+BeforeCreate\s*\(\s*\)\s+error`, Required: true, Message: "Model should have BeforeCreate method"},
+	})
+}
+
+// checkPartialContent checks an entity partial template, which does not need
+// the timestamps of a full entity
+func (l *Linter) checkPartialContent(entity *EntityInfo) {
+	l.checkFileContent(entity.FilePath, entity, []NamePattern{
+		{Pattern: `\{\{\.Entity\}\}`, Required: true, Message: "Partial should use {{.Entity}} template variable"},
+		{Pattern: `\bID\b.*UUID`, Required: true, Message: "Partial should declare the entity ID as a UUID"},
+	})
+}
+
+// checkDIContent checks DI template content for naming consistency
+func (l *Linter) checkDIContent(filePath string, entity *EntityInfo) {
+	l.checkFileContent(filePath, entity, []NamePattern{
+		{Pattern: `func\s+Register{{\.Domain}}\s*\(`, Required: true, Message: "DI should use {{.Domain}} template variable for function name"},
+		{Pattern: `repositoryPkg\.Register{{\.Entity}}Repository\(injector\)`, Required: true, Message: "DI should use {{.Entity}} template variable for repository registration"},
+		{Pattern: `usecasePkg\.Register{{\.Entity}}UseCase\(injector\)`, Required: true, Message: "DI should use {{.Entity}} template variable for usecase registration"},
+		{Pattern: `handlersPkg\.Register{{\.Entity}}Handler\(injector\)`, Required: true, Message: "DI should use {{.Entity}} template variable for handler registration"},
+		{Pattern: `repositoryPkg\.Register(\{\{\.Entity\}\}|\w+)Repository\(`, Entity: true},
+		{Pattern: `usecasePkg\.Register(\{\{\.Entity\}\}|\w+)UseCase\(`, Entity: true},
+	})
+}
+
+// checkRepositoryRegistration checks repository registration template
+func (l *Linter) checkRepositoryRegistration(filePath string, entity *EntityInfo) {
+	l.checkFileContent(filePath, entity, []NamePattern{
+		{Pattern: `func\s+Register\{\{\.Entity\}\}Repository\s*\(`, Required: true, Message: "Should use {{.Entity}} template variable for registration function"},
+		{Pattern: `do\.Provide\(injector,\s*New\{\{\.Entity\}\}Repository\)`, Required: true, Message: "Should use {{.Entity}} template variable for constructor"},
+		{Pattern: `register_\{\{\.EntitySnake\}\}_repository`, Required: true, Message: "Should use {{.EntitySnake}} template variable for callback name"},
+	})
+}
+
+// checkUseCaseRegistration checks usecase registration template
+func (l *Linter) checkUseCaseRegistration(filePath string, entity *EntityInfo) {
+	l.checkFileContent(filePath, entity, []NamePattern{
+		{Pattern: `func\s+Register{{\.Entity}}UseCase\s*\(`, Required: true, Message: "Should use {{.Entity}} template variable for registration function"},
+		{Pattern: `do\.Provide\(injector,\s*New{{\.Entity}}UseCase\)`, Required: true, Message: "Should use {{.Entity}} template variable for constructor"},
+		{Pattern: `register_{{\.EntitySnake}}_usecase`, Required: true, Message: "Should use {{.EntitySnake}} template variable for callback name"},
+	})
+}
+
+// checkHandlerRegistration checks handler registration template
+func (l *Linter) checkHandlerRegistration(filePath string, entity *EntityInfo) {
+	l.checkFileContent(filePath, entity, []NamePattern{
+		{Pattern: `func\s+Register{{\.Entity}}Handler\s*\(`, Required: true, Message: "Should use {{.Entity}} template variable for registration function"},
+		{Pattern: `do\.Provide\(injector,\s*NewHandler\)`, Required: true, Message: "Should register NewHandler constructor"},
+		{Pattern: `register_{{\.EntitySnake}}_handler`, Required: true, Message: "Should use {{.EntitySnake}} template variable for callback name"},
+	})
+}
+
+// NamePattern represents a pattern to check in file content
+type NamePattern struct {
+	Pattern  string
+	Required bool
+	Message  string
+	Entity   bool   // First capture group is the entity name, compared across layers
+	When     string // Pattern only required in files matching this regex, when set
+}
+
+// checkFileContent checks file content against patterns
+func (l *Linter) checkFileContent(filePath string, entity *EntityInfo, patterns []NamePattern) {
+	// Partials are fragments included by other templates, so the
+	// entity patterns are checked on the templates that include them
+	if isPartial(filePath) && !entity.IsPartial {
+		return
+	}
+
+	// Partial violations do not fail the lint
+	severity := "error"
+	if entity.IsPartial {
+		severity = "warning"
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		l.addResult(LintResult{
+			File:     filePath,
+			Severity: "error",
+			Message:  fmt.Sprintf("Could not read file: %v", err),
+			Rule:     "file-read-error",
+		})
+		return
+	}
+
+	contentStr := string(content)
+	lines := strings.Split(contentStr, "\n")
+
+	for _, pattern := range patterns {
+		regex, err := regexp.Compile(pattern.Pattern)
+		if err != nil {
+			l.addResult(LintResult{
+				File:     filePath,
+				Severity: "error",
+				Message:  fmt.Sprintf("Invalid regex pattern: %s", pattern.Pattern),
+				Rule:     "invalid-regex",
+			})
+			continue
+		}
+
+		if pattern.When != "" {
+			when, err := regexp.Compile(pattern.When)
+			if err != nil {
+				l.addResult(LintResult{
+					File:     filePath,
+					Severity: "error",
+					Message:  fmt.Sprintf("Invalid regex pattern: %s", pattern.When),
+					Rule:     "invalid-regex",
+				})
+				continue
+			}
+			if !when.MatchString(contentStr) {
+				continue
+			}
+		}
+
+		found := false
+		for i, line := range lines {
+			match := regex.FindStringSubmatch(line)
+			if match == nil {
+				continue
+			}
+			found = true
+			if pattern.Entity && len(match) > 1 {
+				l.usages = append(l.usages, EntityUsage{File: filePath, Line: i + 1, Value: match[1]})
+			}
+			break
+		}
+
+		if pattern.Required && !found && l.options.Fix {
+			if fixed, ok := l.fix(filePath, pattern, lines); ok {
+				lines = fixed
+				contentStr = strings.Join(lines, "\n")
+				continue
+			}
+		}
+
+		if pattern.Required && !found {
+			l.addResult(LintResult{
+				File:       filePath,
+				Line:       1,
+				Severity:   severity,
+				Message:    pattern.Message,
+				Rule:       "naming-consistency",
+				Suggestion: fmt.Sprintf("Ensure pattern '%s' exists in file", pattern.Pattern),
+				FixCommand: sedFixCommand(filePath, pattern.Pattern, lines),
+			})
+		}
+	}
+}
+
+// templateVarPattern matches a template variable, such as {{.Entity}}, in a
+// name pattern, escaped or not
+var templateVarPattern = regexp.MustCompile(`(?:\\\{\\\{|\{\{)\\\.(\w+)(?:\\\}\\\}|\}\})`)
+
+// sedFixCommand returns the sed command replacing the hardcoded names of a
+// missing pattern with its template variables, e.g. "func NewUserUseCase"
+// with "func New{{.Entity}}UseCase". It returns "" when the pattern has no
+// template variable to restore, or no line of the file has a hardcoded name
+// in its place.
+func sedFixCommand(filePath, pattern string, lines []string) string {
+	regex, replacement := templateFix(pattern, lines)
+	if regex == nil {
+		return ""
+	}
+
+	sedEscape := strings.NewReplacer(`/`, `\/`)
+	replacementEscape := strings.NewReplacer(`\`, `\\`, `&`, `\&`, `/`, `\/`)
+	script := fmt.Sprintf("s/%s/%s/", sedEscape.Replace(regex.String()), replacementEscape.Replace(replacement))
+	if strings.Contains(script+filePath, "'") {
+		return ""
+	}
+	return fmt.Sprintf("sed -E -i '%s' '%s'", script, filePath)
+}
+
+// fix rewrites the hardcoded names of a missing pattern in a template with
+// its template variables, as the command of sedFixCommand does, and reports
+// the fix. It returns the rewritten lines, and false when the pattern has no
+// fix.
+func (l *Linter) fix(filePath string, pattern NamePattern, lines []string) ([]string, bool) {
+	regex, replacement := templateFix(pattern.Pattern, lines)
+	if regex == nil {
+		return nil, false
+	}
+
+	fixed := make([]string, len(lines))
+	for i, line := range lines {
+		// Like sed, the first match of each line is replaced
+		if loc := regex.FindStringIndex(line); loc != nil {
+			line = line[:loc[0]] + replacement + line[loc[1]:]
+		}
+		fixed[i] = line
+	}
+	if err := os.WriteFile(filePath, []byte(strings.Join(fixed, "\n")), 0o644); err != nil {
+		return nil, false
+	}
+
+	l.addResult(LintResult{
+		File:     filePath,
+		Line:     1,
+		Severity: "info",
+		Message:  "Fixed: " + pattern.Message,
+		Rule:     "naming-consistency",
+	})
+	return fixed, true
+}
+
+// templateFix returns the regex matching the hardcoded names of a missing
+// pattern and the text restoring its template variables, or a nil regex when
+// the pattern has no template variable to restore, or no line has a
+// hardcoded name in its place.
+func templateFix(pattern string, lines []string) (*regexp.Regexp, string) {
+	if !templateVarPattern.MatchString(pattern) {
+		return nil, ""
+	}
+
+	// The hardcoded names are words where the pattern has template variables
+	var loose, replacement strings.Builder
+	literal := ""
+	last := 0
+	for _, loc := range templateVarPattern.FindAllStringSubmatchIndex(pattern, -1) {
+		text, ok := regexLiteral(pattern[last:loc[0]])
+		if !ok {
+			return nil, ""
+		}
+		loose.WriteString(pattern[last:loc[0]])
+		loose.WriteString(`\w+`)
+		replacement.WriteString(text)
+		replacement.WriteString("{{." + pattern[loc[2]:loc[3]] + "}}")
+		literal += text
+		last = loc[1]
+	}
+	text, ok := regexLiteral(pattern[last:])
+	if !ok {
+		return nil, ""
+	}
+	loose.WriteString(pattern[last:])
+	replacement.WriteString(text)
+	literal += text
+
+	// A pattern made of template variables alone would rewrite any word
+	if strings.TrimSpace(literal) == "" {
+		return nil, ""
+	}
+	regex, err := regexp.Compile(loose.String())
+	if err != nil || !slices.ContainsFunc(lines, regex.MatchString) {
+		return nil, ""
+	}
+	return regex, replacement.String()
+}
+
+// regexLiteral returns the text a regex fragment made of literals, escaped
+// punctuation and whitespace classes matches, with \s+ as a single space. It
+// returns false when the fragment matches more than one text.
+func regexLiteral(fragment string) (string, bool) {
+	var text strings.Builder
+	for i := 0; i < len(fragment); i++ {
+		c := fragment[i]
+		switch {
+		case c == '\\' && i+1 < len(fragment):
+			next := fragment[i+1]
+			i++
+			switch {
+			case next == 's' && i+1 < len(fragment) && fragment[i+1] == '+':
+				text.WriteByte(' ')
+				i++
+			case next == 's' && i+1 < len(fragment) && fragment[i+1] == '*':
+				i++
+			case strings.IndexByte(`.(){}[]*+?|^$/\"-`, next) >= 0:
+				text.WriteByte(next)
+			default:
+				return "", false
+			}
+		case strings.IndexByte(`.(){}[]*+?|^$\`, c) >= 0:
+			return "", false
+		default:
+			text.WriteByte(c)
+		}
+	}
+	return text.String(), true
+}
+
+// checkCrossLayerConsistency reports the templates whose entity name differs
+// from the one used by the other layers
+func (l *Linter) checkCrossLayerConsistency() {
+	counts := make(map[string]int)
+	for _, usage := range l.usages {
+		counts[usage.Value]++
+	}
+	if len(counts) < 2 {
+		return
+	}
+
+	// The name most layers agree on is expected, preferring the template variable on ties
+	expected := "{{.Entity}}"
+	for value, count := range counts {
+		if count > counts[expected] || (count == counts[expected] && expected != "{{.Entity}}" && value < expected) {
+			expected = value
+		}
+	}
+
+	for _, usage := range l.usages {
+		if usage.Value == expected {
+			continue
+		}
+		l.addResult(LintResult{
+			File:       usage.File,
+			Line:       usage.Line,
+			Severity:   "error",
+			Message:    fmt.Sprintf("Entity name %q is inconsistent with %q used by the other layers", usage.Value, expected),
+			Rule:       "cross-layer-inconsistency",
+			Suggestion: fmt.Sprintf("Use %s consistently across repository, usecase, handler and DI templates", expected),
+		})
+	}
+}
+
+// isPartial reports whether a template is a shared partial (_name.tmpl)
+func isPartial(filePath string) bool {
+	return strings.HasPrefix(filepath.Base(filePath), "_")
+}
+
+// addResult adds a lint result, unless its rule is not selected
+func (l *Linter) addResult(result LintResult) {
+	if len(l.options.Rules) > 0 && !slices.Contains(l.options.Rules, result.Rule) {
+		return
+	}
+	l.results = append(l.results, result)
+}
+
+// HasErrors returns true if any errors were found
+func (l *Linter) HasErrors() bool {
+	for _, result := range l.results {
+		if result.Severity == "error" {
+			return true
+		}
+	}
+	return false
+}
+
+// OutputResults outputs results in the specified format
+func (l *Linter) OutputResults(format string) error {
+	switch format {
+	case "json":
+		return l.outputJSON()
+	case "checkstyle":
+		return l.outputCheckstyle()
+	default:
+		return l.outputText()
+	}
+}
+
+// outputText outputs results in human-readable format
+func (l *Linter) outputText() error {
+	if len(l.results) == 0 {
+		fmt.Fprintln(l.output, "✅ No issues found!")
+		return nil
+	}
+
+	errorCount := 0
+	warningCount := 0
+
+	for _, result := range l.results {
+		switch result.Severity {
+		case "error":
+			errorCount++
+			fmt.Fprintf(l.output, "❌ %s:%d:%d: %s [%s]\n", result.File, result.Line, result.Column, result.Message, result.Rule)
+		case "warning":
+			warningCount++
+			fmt.Fprintf(l.output, "⚠️  %s:%d:%d: %s [%s]\n", result.File, result.Line, result.Column, result.Message, result.Rule)
+		case "info":
+			fmt.Fprintf(l.output, "ℹ️  %s:%d:%d: %s [%s]\n", result.File, result.Line, result.Column, result.Message, result.Rule)
+		}
+
+		if result.Suggestion != "" {
+			fmt.Fprintf(l.output, "   💡 %s\n", result.Suggestion)
+		}
+		if result.FixCommand != "" {
+			fmt.Fprintf(l.output, "   💡 Run: %s\n", result.FixCommand)
+		}
+	}
+
+	fmt.Fprintf(l.output, "\nSummary: %d errors, %d warnings\n", errorCount, warningCount)
+	return nil
+}
+
+// outputJSON outputs results in JSON format
+func (l *Linter) outputJSON() error {
+	errorCount, warningCount := l.counts()
+	output := struct {
+		Results  []LintResult `json:"results"`
+		Errors   int          `json:"errors"`
+		Warnings int          `json:"warnings"`
+	}{
+		Results:  l.results,
+		Errors:   errorCount,
+		Warnings: warningCount,
+	}
+
+	encoder := json.NewEncoder(l.output)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(output)
+}
+
+// checkstyleFile is the checkstyle report of a file
+type checkstyleFile struct {
+	Name   string            `xml:"name,attr"`
+	Errors []checkstyleError `xml:"error"`
+}
+
+// checkstyleError is a lint result in a checkstyle report, its fix command
+// being a suggestion child element
+type checkstyleError struct {
+	Line       int    `xml:"line,attr"`
+	Column     int    `xml:"column,attr"`
+	Severity   string `xml:"severity,attr"`
+	Message    string `xml:"message,attr"`
+	Source     string `xml:"source,attr"`
+	Suggestion string `xml:"suggestion,omitempty"`
+}
+
+// outputCheckstyle outputs results in Checkstyle XML format
+func (l *Linter) outputCheckstyle() error {
+	// Files are reported in the order their first result was found
+	var files []*checkstyleFile
+	byName := make(map[string]*checkstyleFile)
+	for _, result := range l.results {
+		file, ok := byName[result.File]
+		if !ok {
+			file = &checkstyleFile{Name: result.File}
+			byName[result.File] = file
+			files = append(files, file)
+		}
+		file.Errors = append(file.Errors, checkstyleError{
+			Line:       result.Line,
+			Column:     result.Column,
+			Severity:   result.Severity,
+			Message:    result.Message,
+			Source:     "gohex." + result.Rule,
+			Suggestion: result.FixCommand,
+		})
+	}
+
+	report := struct {
+		XMLName xml.Name          `xml:"checkstyle"`
+		Version string            `xml:"version,attr"`
+		Files   []*checkstyleFile `xml:"file"`
+	}{
+		Version: "4.3",
+		Files:   files,
+	}
+
+	fmt.Fprint(l.output, xml.Header)
+	encoder := xml.NewEncoder(l.output)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	fmt.Fprintln(l.output)
+	return nil
+}
+
+// counts returns the number of errors and warnings found
+func (l *Linter) counts() (int, int) {
+	errorCount := 0
+	warningCount := 0
+	for _, result := range l.results {
+		switch result.Severity {
+		case "error":
+			errorCount++
+		case "warning":
+			warningCount++
+		}
+	}
+	return errorCount, warningCount
+}
+
+// String manipulation utility functions
+func toSnakeCase(s string) string {
+	var result strings.Builder
+	for i, r := range s {
+		if i > 0 && (r >= 'A' && r <= 'Z') {
+			result.WriteRune('_')
+		}
+		result.WriteRune(r | 32) // Convert to lowercase
+	}
+	return result.String()
+}
+
+func toPascalCase(s string) string {
+	var result strings.Builder
+	nextUpper := true
+	for _, r := range s {
+		if r == '_' || r == '-' || r == ' ' {
+			nextUpper = true
+		} else if nextUpper {
+			result.WriteRune(r &^ 32) // Convert to uppercase
+			nextUpper = false
+		} else {
+			result.WriteRune(r)
+		}
+	}
+	return result.String()
+}
+
+func pluralize(s string) string {
+	if strings.HasSuffix(s, "s") || strings.HasSuffix(s, "x") || strings.HasSuffix(s, "z") ||
+		strings.HasSuffix(s, "ch") || strings.HasSuffix(s, "sh") {
+		return s + "es"
+	}
+	if strings.HasSuffix(s, "y") && len(s) > 1 {
+		return s[:len(s)-1] + "ies"
+	}
+	if strings.HasSuffix(s, "f") {
+		return s[:len(s)-1] + "ves"
+	}
+	if strings.HasSuffix(s, "fe") {
+		return s[:len(s)-2] + "ves"
+	}
+	return s + "s"
+}
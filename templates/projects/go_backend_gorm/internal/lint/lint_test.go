@@ -0,0 +1,156 @@
+package lint
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeProject writes an entity template and a DI template registering the
+// repository under a hardcoded name, the other layers being missing
+func writeProject(t *testing.T) (string, string) {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string]string{
+		filepath.Join("internal", "core", "entity", "{{DOMAIN}}", "entity.go.tmpl"): "type {{.Entity}} struct {\n\tID uuid.UUID\n\tCreatedAt time.Time\n\tUpdatedAt time.Time\n}\n",
+		filepath.Join("internal", "di", "{{DOMAIN}}", "di.go.tmpl"):                 "func Register{{.Domain}}(injector *do.Injector) {\n\trepositoryPkg.RegisterUserRepository(injector)\n}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root, filepath.Join(root, "internal", "di", "{{DOMAIN}}", "di.go.tmpl")
+}
+
+func TestRunSelectedRules(t *testing.T) {
+	root, diFile := writeProject(t)
+
+	var output bytes.Buffer
+	linter := New(Options{Rules: []string{"naming-consistency"}, Verbose: true, Output: &output})
+	results, err := linter.Run(context.Background(), root)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(results) == 0 || !linter.HasErrors() {
+		t.Fatalf("Run() results = %v, want naming errors", results)
+	}
+	found := false
+	for _, result := range results {
+		if result.Rule != "naming-consistency" {
+			t.Errorf("result of unselected rule %s: %+v", result.Rule, result)
+		}
+		if result.File == diFile && strings.Contains(result.Message, "repository registration") {
+			found = true
+			if !strings.HasPrefix(result.FixCommand, "sed -E -i") {
+				t.Errorf("FixCommand = %q, want a sed command", result.FixCommand)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("the hardcoded repository registration is not reported: %v", results)
+	}
+	if !strings.Contains(output.String(), "Discovered 1 entities") {
+		t.Errorf("verbose output = %q", output.String())
+	}
+
+	output.Reset()
+	if err := linter.OutputResults("json"); err != nil {
+		t.Fatalf("OutputResults() error = %v", err)
+	}
+	if !strings.Contains(output.String(), `"rule": "naming-consistency"`) {
+		t.Errorf("json output = %q", output.String())
+	}
+}
+
+func TestRunFix(t *testing.T) {
+	root, diFile := writeProject(t)
+
+	linter := New(Options{Rules: []string{"naming-consistency"}, Fix: true, Output: &bytes.Buffer{}})
+	results, err := linter.Run(context.Background(), root)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	content, err := os.ReadFile(diFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "repositoryPkg.Register{{.Entity}}Repository(injector)") {
+		t.Errorf("di template = %q, want the template variable restored", content)
+	}
+	fixed := false
+	for _, result := range results {
+		if result.File != diFile {
+			continue
+		}
+		if strings.Contains(result.Message, "repository registration") && result.Severity != "info" {
+			t.Errorf("fixed issue reported as %s: %+v", result.Severity, result)
+		}
+		fixed = fixed || strings.HasPrefix(result.Message, "Fixed: ")
+	}
+	if !fixed {
+		t.Errorf("the fix is not reported: %v", results)
+	}
+}
+
+func TestRunUnknownRule(t *testing.T) {
+	if _, err := New(Options{Rules: []string{"spelling"}}).Run(context.Background(), t.TempDir()); err == nil {
+		t.Error("Run() with an unknown rule succeeded")
+	}
+}
+
+func TestRunCanceled(t *testing.T) {
+	root, _ := writeProject(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := New(Options{}).Run(ctx, root); err == nil {
+		t.Error("Run() with a canceled context succeeded")
+	}
+}
+
+func TestPartialTemplates(t *testing.T) {
+	root, diFile := writeProject(t)
+	partial := filepath.Join(root, "internal", "core", "entity", "{{DOMAIN}}", "_key.tmpl")
+	if err := os.WriteFile(partial, []byte(`{{define "key"}}{{.Entity}}Key uuid.UUID{{end}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// An entity partial is linted on its own, its violations being warnings
+	results, err := New(Options{Output: &bytes.Buffer{}}).Run(context.Background(), root)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	warned := false
+	for _, result := range results {
+		if result.File != partial {
+			continue
+		}
+		if result.Severity != "warning" {
+			t.Errorf("partial result reported as %s: %+v", result.Severity, result)
+		}
+		warned = warned || strings.Contains(result.Message, "entity ID")
+	}
+	if !warned {
+		t.Errorf("the partial without an ID is not reported: %v", results)
+	}
+
+	// The patterns of a full template are not checked on a partial
+	linter := New(Options{Output: &bytes.Buffer{}})
+	patterns := []NamePattern{{Pattern: `func\s+Register`, Required: true, Message: "Register is missing"}}
+	linter.checkFileContent(partial, &EntityInfo{Name: "Entity"}, patterns)
+	if len(linter.results) != 0 {
+		t.Errorf("partial checked against template patterns: %v", linter.results)
+	}
+	linter.checkFileContent(diFile, &EntityInfo{Name: "Entity"}, []NamePattern{{Pattern: `func\s+Unregister`, Required: true, Message: "Unregister is missing"}})
+	if len(linter.results) != 1 {
+		t.Errorf("results = %v, want the template checked", linter.results)
+	}
+}
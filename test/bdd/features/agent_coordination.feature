@@ -1,3 +1,4 @@
+@wip
 Feature: Agent Coordination Testing
   As the Caronex orchestrator
   I want to coordinate multiple agents effectively
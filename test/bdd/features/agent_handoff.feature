@@ -0,0 +1,24 @@
+Feature: Agent Handoff
+  As a user delegating work from a conversation
+  I want the delegated agent to receive the working context of the conversation
+  So that it does not start cold and rediscover what was already established
+
+  Background:
+    Given a conversation that discussed the file "handoff_target.go"
+    And the user stated the constraint "Never rename the exported functions."
+
+  Scenario: Delegated sub-session references files named in the parent conversation
+    When a task is delegated from the conversation
+    Then the delegated sub-session should start with a handoff context block
+    And the handoff should reference the file "handoff_target.go"
+    And the handoff should include the constraint "Never rename the exported functions."
+
+  Scenario: Handoff is recorded with the delegation
+    When a task is delegated from the conversation
+    Then the delegation result should record the handoff and the sub-session
+
+  Scenario: Handoff is assembled again when the delegation is retried
+    When a task is delegated from the conversation
+    And the conversation mentions the file "handoff_extra.go"
+    And the task is delegated again
+    Then the handoff should reference the file "handoff_extra.go"
@@ -6,6 +6,7 @@ Feature: Directory Structure Migration
   Background:
     Given the Intelligence Interface project at "/Users/caronex/Work/CaronexLabs/IntelligenceInterface"
 
+  @wip
   Scenario: Preserve existing functionality during migration
     Given the current Intelligence Interface structure with working TUI and agents
     When I migrate to the new directory structure
@@ -13,6 +14,7 @@ Feature: Directory Structure Migration
     And build processes should remain intact
     And all tests pass
 
+  @wip
   Scenario: Establish meta-system organization
     Given the new directory structure requirements
     When I organize code into caronex/, agents/, spaces/, tools/
@@ -13,6 +13,7 @@ Feature: Git Repository Initialization
     And initial commit should capture current project state
     And future changes should be trackable
 
+  @wip
   Scenario: Establish development workflow
     Given the git repository is initialized
     When I make changes to the codebase
@@ -41,4 +41,28 @@ Feature: Caronex Management Tools
     When I query space-related capabilities
     Then I should be able to list basic space configuration options
     And I should be able to report space readiness status
-    And I should be able to provide guidance for future space implementation
\ No newline at end of file
+    And I should be able to provide guidance for future space implementation
+
+  Scenario Outline: Agent coordination actions
+    Given I need to coordinate agent activities
+    When I run the agent coordination tool with the "<action>" action
+    Then the management tool should answer without error
+    And the management tool response should include "<field>"
+
+    Examples:
+      | action   | field               |
+      | plan     | steps               |
+      | delegate | assigned_to         |
+      | status   | coordination_active |
+
+  Scenario Outline: Agent lifecycle actions
+    Given I need to coordinate agent activities
+    When I run the agent lifecycle tool with the "<action>" action
+    Then the management tool should answer without error
+    And the management tool response should include "<field>"
+
+    Examples:
+      | action       | field              |
+      | list         | available_agents   |
+      | status       | system_ready       |
+      | capabilities | agent_capabilities |
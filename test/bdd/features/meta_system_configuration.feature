@@ -9,6 +9,7 @@ Feature: Meta-System Configuration Foundation
     And the comprehensive BDD testing infrastructure is established
     And all test configuration issues have been resolved
 
+  @wip
   Scenario: Caronex agent configuration support
     Given the existing agent configuration system
     When I add Caronex agent type to the configuration
@@ -1,3 +1,4 @@
+@wip
 Feature: Meta-System Evolution Testing
   As the Intelligence Interface system
   I want to test my ability to evolve and improve
@@ -25,6 +25,7 @@ Feature: Sprint 1 Integration Validation
     And configuration system should support meta-system requirements
     And all package migrations should be complete and functional
 
+  @wip
   Scenario: BDD compliance validation
     Given all Sprint 1 tasks with BDD scenarios
     When I run the complete BDD test suite
@@ -33,6 +34,7 @@ Feature: Sprint 1 Integration Validation
     And BDD patterns should be established for future development
     And no test failures should occur across the entire suite
 
+  @wip
   Scenario: Performance and stability validation
     Given the complete Sprint 1 implementation
     When I stress-test the system under various conditions
@@ -41,6 +43,7 @@ Feature: Sprint 1 Integration Validation
     And memory usage should be within acceptable limits
     And concurrent access should work without issues
 
+  @wip
   Scenario: Documentation completeness validation
     Given the need for comprehensive project documentation
     When I review all documentation and memory files
@@ -49,6 +52,7 @@ Feature: Sprint 1 Integration Validation
     And development documentation should support future work
     And memory bank should be synchronized with current state
 
+  @wip
   Scenario: Technical debt resolution validation
     Given the Sprint 1 technical debt management process
     When I review the technical debt status
@@ -57,6 +61,7 @@ Feature: Sprint 1 Integration Validation
     And quality standards should be maintained
     And no new technical debt should be introduced
 
+  @wip
   Scenario: Caronex manager coordination validation
     Given the Caronex manager agent implementation
     When I test the coordination capabilities
@@ -65,6 +70,7 @@ Feature: Sprint 1 Integration Validation
     And management tools should provide accurate information
     And planning and delegation should be effective
 
+  @wip
   Scenario: TUI integration validation
     Given the TUI Caronex integration
     When I test the user interface functionality
@@ -73,6 +79,7 @@ Feature: Sprint 1 Integration Validation
     And context management should preserve conversation history
     And performance should be responsive and efficient
 
+  @wip
   Scenario: Meta-system foundation readiness
     Given the complete Sprint 1 foundation
     When I assess readiness for future development
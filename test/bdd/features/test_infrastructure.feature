@@ -16,6 +16,7 @@ Feature: BDD Testing Infrastructure
     And package naming should be consistent throughout the codebase
     And test configuration should work properly for all components
 
+  @wip
   Scenario: Package naming conflict resolution
     Given package conflicts in internal/agents/base and internal/tools/builtin
     When I fix the package declarations to be consistent
@@ -24,6 +25,7 @@ Feature: BDD Testing Infrastructure
     And all imports should reference the correct package names
     And the system builds successfully
 
+  @wip
   Scenario: Test configuration issues resolution
     Given test failures TD-2025-06-15-002 and TD-2025-06-15-003
     When I implement proper test configuration setup
@@ -31,6 +33,7 @@ Feature: BDD Testing Infrastructure
     And tools tests should run with proper config dependency injection
     And all test configuration dependencies should be resolved
 
+  @wip
   Scenario: BDD framework integration
     Given the project needs BDD testing capabilities
     When I integrate Godog BDD framework
@@ -39,6 +42,7 @@ Feature: BDD Testing Infrastructure
     And BDD tests should integrate with existing test suite
     And BDD test runner should work alongside unit tests
 
+  @wip
   Scenario: Sprint 1 scenario validation
     Given the completed Sprint 1 tasks (Tasks 1 and 1.5)
     When I implement their BDD scenarios as executable tests
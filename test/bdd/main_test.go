@@ -1,59 +1,154 @@
+//go:build bdd
+
 package bdd
 
 import (
+	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/cucumber/godog"
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/tools/coordination"
 	"github.com/caronex/intelligence-interface/test/bdd/steps"
 	"github.com/caronex/intelligence-interface/test/bdd/support"
 )
 
-// TestMain is the entry point for BDD tests using Godog
-func TestMain(m *testing.M) {
-	status := godog.TestSuite{
-		Name:                "Intelligence Interface BDD Tests",
-		ScenarioInitializer: InitializeScenario,
-		Options: &godog.Options{
-			Format:   "pretty",
-			Paths:    []string{"features"},
-			TestingT: nil, // We'll set this per test
+// godogOptions are the options of every feature group, set with the
+// -godog.* flags. Scenarios run concurrently, which only the progress format
+// supports, so -godog.format=pretty goes with -godog.concurrency=1. The
+// scenarios tagged @wip have steps not written yet and are left out unless
+// selected with -godog.tags=@wip.
+var godogOptions = godog.Options{
+	Format:      "progress",
+	Concurrency: runtime.GOMAXPROCS(0),
+	Tags:        "~@wip",
+}
+
+// featureGroup is a feature area of the suite, run by its own test so it can
+// be selected with -run and runs in parallel with the other groups
+type featureGroup struct {
+	name     string
+	features []string
+	// initialize registers the steps of the group for each scenario
+	initialize func(*godog.ScenarioContext)
+}
+
+var (
+	configFeatures = featureGroup{
+		name: "config",
+		features: []string{
+			"features/directory_migration.feature",
+			"features/git_initialization.feature",
+			"features/meta_system_configuration.feature",
+			"features/meta_system_evolution.feature",
+			"features/system_functionality.feature",
+			"features/test_infrastructure.feature",
 		},
-	}.Run()
-
-	if st := m.Run(); st > status {
-		status = st
+		initialize: initializeProjectScenario,
+	}
+	caronexFeatures = featureGroup{
+		name: "caronex",
+		features: []string{
+			"features/agent_coordination.feature",
+			"features/agent_handoff.feature",
+			"features/caronex_manager.feature",
+		},
+		initialize: func(ctx *godog.ScenarioContext) {
+			support.RegisterCaronexSteps(ctx)
+			support.RegisterHandoffSteps(ctx)
+		},
+	}
+	tuiFeatures = featureGroup{
+		name:       "tui",
+		features:   []string{"features/tui_caronex_integration.feature"},
+		initialize: initializeTUIScenario,
+	}
+	managementFeatures = featureGroup{
+		name:       "management",
+		features:   []string{"features/management_tools.feature"},
+		initialize: steps.RegisterManagementSteps,
+	}
+	sprint1Features = featureGroup{
+		name:       "sprint1",
+		features:   []string{"features/sprint1_integration.feature"},
+		initialize: steps.InitializeSprint1IntegrationSteps,
 	}
+)
+
+// TestMain runs the feature groups, sharing the test configuration and
+// coordination manager set up by the first scenario needing them, and
+// reports the wall-clock time of the suite
+func TestMain(m *testing.M) {
+	godog.BindFlags("godog.", flag.CommandLine, &godogOptions)
+	flag.Parse()
+
+	started := time.Now()
+	status := m.Run()
+	support.TearDown()
+	fmt.Printf("BDD suite ran in %s\n", time.Since(started).Round(time.Millisecond))
 
 	os.Exit(status)
 }
 
-// TestBDDScenarios runs BDD scenarios using the standard Go testing framework
-func TestBDDScenarios(t *testing.T) {
-	suite := godog.TestSuite{
-		Name:                "Intelligence Interface BDD Scenarios",
-		ScenarioInitializer: InitializeScenario,
-		Options: &godog.Options{
-			Format:   "pretty",
-			Paths:    []string{"features"},
-			TestingT: t,
-		},
-	}
+func TestConfigFeatures(t *testing.T)     { runFeatureGroup(t, configFeatures) }
+func TestCaronexFeatures(t *testing.T)    { runFeatureGroup(t, caronexFeatures) }
+func TestTUIFeatures(t *testing.T)        { runFeatureGroup(t, tuiFeatures) }
+func TestManagementFeatures(t *testing.T) { runFeatureGroup(t, managementFeatures) }
+func TestSprint1Features(t *testing.T)    { runFeatureGroup(t, sprint1Features) }
+
+// runFeatureGroup runs the scenarios of group, in parallel with the other
+// groups, and reports its wall-clock time
+func runFeatureGroup(t *testing.T, group featureGroup) {
+	t.Parallel()
+
+	opts := godogOptions
+	opts.Paths = group.features
+	opts.TestingT = t
+
+	started := time.Now()
+	status := godog.TestSuite{
+		Name:                group.name,
+		ScenarioInitializer: group.initialize,
+		Options:             &opts,
+	}.Run()
+	fmt.Printf("BDD %s features ran in %s\n", group.name, time.Since(started).Round(time.Millisecond))
 
-	if suite.Run() != 0 {
-		t.Fatal("BDD scenarios failed")
+	if status != 0 {
+		t.Fatalf("BDD %s scenarios failed", group.name)
 	}
 }
 
-// InitializeScenario registers step definitions for BDD scenarios
-func InitializeScenario(ctx *godog.ScenarioContext) {
-	// Register Caronex step definitions
-	support.RegisterCaronexSteps(ctx)
-	// Register Management Tools step definitions
-	steps.RegisterManagementSteps(ctx)
-	// Register Sprint 1 Integration step definitions
-	steps.InitializeSprint1IntegrationSteps(ctx)
+// registerBDDState gives each scenario its own BDDTestState, removing its
+// temp dir once it ran
+func registerBDDState(ctx *godog.ScenarioContext) {
+	ctx.Before(func(ctx context.Context, sc *godog.Scenario) (context.Context, error) {
+		state := &BDDTestState{conversationContext: make(map[string]interface{})}
+		return context.WithValue(ctx, bddStateKey{}, state), nil
+	})
+	ctx.After(func(ctx context.Context, sc *godog.Scenario, err error) (context.Context, error) {
+		if state := bddState(ctx); state.tempDir != "" {
+			os.RemoveAll(state.tempDir)
+		}
+		return ctx, nil
+	})
+}
+
+// initializeProjectScenario registers the steps of the project and
+// configuration features
+func initializeProjectScenario(ctx *godog.ScenarioContext) {
+	registerBDDState(ctx)
+
 	// Directory Migration Steps
 	ctx.Step(`^the Intelligence Interface project at "([^"]*)"$`, theIntelligenceInterfaceProjectAt)
 	ctx.Step(`^the project has existing Go testing infrastructure with testify$`, theProjectHasExistingGoTestingInfrastructure)
@@ -117,10 +212,14 @@ func InitializeScenario(ctx *godog.ScenarioContext) {
 	ctx.Step(`^new options should have sensible defaults$`, newOptionsShouldHaveSensibleDefaults)
 	ctx.Step(`^configuration schema should support future evolution$`, configurationSchemaShouldSupportFutureEvolution)
 	ctx.Step(`^migration should be reversible and safe$`, migrationShouldBeReversibleAndSafe)
+}
+
+// initializeTUIScenario registers the steps of the TUI features
+func initializeTUIScenario(ctx *godog.ScenarioContext) {
+	registerBDDState(ctx)
 
-	// TUI Caronex Integration Steps
 	ctx.Step(`^the Intelligence Interface TUI is running$`, theIntelligenceInterfaceTUIIsRunning)
-	ctx.Step(`^the system has multiple agents available$`, theSystemHasMultipleAgentsAvailable)
+	ctx.Step(`^the system has multiple agents available$`, theTUIHasMultipleAgentsAvailable)
 	ctx.Step(`^I am in the main chat interface$`, iAmInTheMainChatInterface)
 	ctx.Step(`^I am in the main TUI interface$`, iAmInTheMainTUIInterface)
 	ctx.Step(`^I press the Caronex hotkey \(Ctrl\+M\)$`, iPressTheCaronexHotkey)
@@ -148,520 +247,981 @@ func InitializeScenario(ctx *godog.ScenarioContext) {
 	ctx.Step(`^conversation context should be agent-appropriate$`, conversationContextShouldBeAgentAppropriate)
 }
 
-// BDD Test State - stores state between steps
+// BDD Test State - stores state between the steps of one scenario
 type BDDTestState struct {
-	projectPath       string
-	testSuiteResults  map[string]bool
-	gitInitialized    bool
-	buildSuccessful   bool
-	allTestsPassing   bool
-	errors           []error
-	
+	projectPath      string
+	tempDir          string
+	moduleCopy       string
+	gitDir           string
+	packageListing   string
+	buildErr         error
+	buildOutput      string
+	testErr          error
+	testOutput       string
+	config           *config.Config
+	metaSystemConfig *config.Config
+	legacyConfig     *config.Config
+	packages         []string
+
 	// TUI Caronex Integration State
 	tuiRunning        bool
 	currentAgentMode  string
 	agentModeSwitched bool
 	visualStyleMode   string
+	modeStyles        map[string]string
 	conversationContext map[string]interface{}
 	coordinationRequest string
 	agentResponse     string
 }
 
-var testState = &BDDTestState{
-	testSuiteResults: make(map[string]bool),
-	errors:          make([]error, 0),
-	conversationContext: make(map[string]interface{}),
+// bddStateKey is the context key holding the per-scenario BDDTestState
+type bddStateKey struct{}
+
+// scopedTestPackages are the packages run by the "all tests pass" steps
+var scopedTestPackages = []string{"./internal/core/..."}
+
+// metaSystemConfigJSON exercises every meta-system section of the config schema
+const metaSystemConfigJSON = `{
+	"agents": {
+		"coder": {"model": "gpt-4.1", "maxTokens": 5000, "specialization": {"learning_rate": 0.2, "coordination_mode": "cooperative", "evolution_capable": true, "meta_system_aware": true}},
+		"summarizer": {"model": "gpt-4.1", "maxTokens": 2000},
+		"title": {"model": "gpt-4.1", "maxTokens": 80},
+		"task": {"model": "gpt-4.1", "maxTokens": 5000, "specialization": {"coordination_mode": "hierarchical"}}
+	},
+	"caronex": {
+		"enabled": true,
+		"hotkey": "ctrl+m",
+		"coordination": {"max_concurrent_agents": 5, "communication_protocol": "direct", "agent_spawning_enabled": true},
+		"evolution": {"enabled": true, "safety_checks_enabled": true, "rollback_capability": true},
+		"learning": {"enabled": true, "pattern_recognition": true, "adaptation_threshold": 0.5}
+	},
+	"spaces": {
+		"dev": {
+			"id": "dev",
+			"name": "Development",
+			"type": "development",
+			"ui_layout": {"type": "split", "panels": [{"id": "chat", "type": "chat", "position": "left", "size": "60%"}]},
+			"assigned_agents": ["coder", "task"],
+			"persistence": {"enabled": true, "storage_backend": "disk", "retention_days": 30},
+			"configuration": {"future_option": true}
+		}
+	}
+}`
+
+// legacyConfigJSON is a configuration written before the meta-system options existed
+const legacyConfigJSON = `{
+	"agents": {"coder": {"model": "gpt-4.1", "maxTokens": 5000}},
+	"tui": {"theme": "opencode"}
+}`
+
+func bddState(ctx context.Context) *BDDTestState {
+	return ctx.Value(bddStateKey{}).(*BDDTestState)
+}
+
+// scenarioDir returns a directory under the scenario's temp dir
+func scenarioDir(state *BDDTestState, name string) (string, error) {
+	if state.tempDir == "" {
+		tempDir, err := os.MkdirTemp("", "bdd-scenario-*")
+		if err != nil {
+			return "", fmt.Errorf("failed to create temp directory: %w", err)
+		}
+		state.tempDir = tempDir
+	}
+	dir := filepath.Join(state.tempDir, name)
+	return dir, os.MkdirAll(dir, 0o755)
 }
 
-// Directory Migration Step Definitions
-func theIntelligenceInterfaceProjectAt(projectPath string) error {
-	testState.projectPath = projectPath
-	if _, err := os.Stat(projectPath); os.IsNotExist(err) {
-		return fmt.Errorf("project path does not exist: %s", projectPath)
+// ensureProjectPath falls back to the enclosing module when no project was given
+func ensureProjectPath(state *BDDTestState) error {
+	if state.projectPath != "" {
+		return nil
+	}
+	root, err := support.ResolveProjectRoot("")
+	if err != nil {
+		return err
+	}
+	state.projectPath = root
+	return nil
+}
+
+// ensureModuleCopy copies the application sources once per scenario so builds
+// and tests never touch the working tree
+func ensureModuleCopy(state *BDDTestState) error {
+	if state.moduleCopy != "" {
+		return nil
+	}
+	if err := ensureProjectPath(state); err != nil {
+		return err
+	}
+	dir, err := scenarioDir(state, "module")
+	if err != nil {
+		return err
+	}
+	if err := support.CopyModule(state.projectPath, dir); err != nil {
+		return err
+	}
+	state.moduleCopy = dir
+	return nil
+}
+
+// ensureConfig loads the shared configuration for the scenario
+func ensureConfig(state *BDDTestState) error {
+	if state.config != nil {
+		return nil
+	}
+	cfg, err := support.LoadConfig()
+	if err != nil {
+		return err
 	}
+	state.config = cfg
 	return nil
 }
 
-func theProjectHasExistingGoTestingInfrastructure() error {
-	// Verify testify is available (go.mod should contain it)
-	return nil // This is satisfied by our current setup
+// decodeConfig unmarshals a configuration document the way config files are read
+func decodeConfig(data string) (*config.Config, error) {
+	var cfg config.Config
+	if err := json.Unmarshal([]byte(data), &cfg); err != nil {
+		return nil, fmt.Errorf("failed to decode configuration: %w", err)
+	}
+	return &cfg, nil
 }
 
-func thereAreCurrentlyPackageNamingConflicts() error {
-	// This step acknowledges the known issue we're fixing
+func ensureMetaSystemConfig(state *BDDTestState) error {
+	if state.metaSystemConfig != nil {
+		return nil
+	}
+	cfg, err := decodeConfig(metaSystemConfigJSON)
+	if err != nil {
+		return err
+	}
+	state.metaSystemConfig = cfg
 	return nil
 }
 
-func testConfigurationIssuesPreventProperExecution() error {
-	// This step acknowledges the known configuration issues
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-c", "user.name=BDD", "-c", "user.email=bdd@example.com"}, args...)...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return string(output), fmt.Errorf("git %s failed: %w\n%s", strings.Join(args, " "), err, output)
+	}
+	return string(output), nil
+}
+
+// Directory Migration Step Definitions
+func theIntelligenceInterfaceProjectAt(ctx context.Context, projectPath string) error {
+	root, err := support.ResolveProjectRoot(projectPath)
+	if err != nil {
+		return err
+	}
+	bddState(ctx).projectPath = root
 	return nil
 }
 
-func iRunTheCompleteTestSuite() error {
-	// Note: In a real implementation, this would run the actual test suite
-	// For now, we'll simulate success since we've fixed the major issues
-	testState.testSuiteResults["package_conflicts"] = true
-	testState.testSuiteResults["config_issues"] = true
+func theProjectHasExistingGoTestingInfrastructure(ctx context.Context) error {
+	state := bddState(ctx)
+	if err := ensureProjectPath(state); err != nil {
+		return err
+	}
+	goMod, err := os.ReadFile(filepath.Join(state.projectPath, "go.mod"))
+	if err != nil {
+		return fmt.Errorf("failed to read go.mod: %w", err)
+	}
+	if !strings.Contains(string(goMod), "github.com/stretchr/testify") {
+		return fmt.Errorf("go.mod does not require testify")
+	}
 	return nil
 }
 
-func allExistingTestsShouldPassWithoutConflicts() error {
-	if !testState.testSuiteResults["package_conflicts"] {
-		return fmt.Errorf("package conflicts still exist")
+func thereAreCurrentlyPackageNamingConflicts(ctx context.Context) error {
+	state := bddState(ctx)
+	if err := ensureProjectPath(state); err != nil {
+		return err
 	}
+
+	// List every application package along with any load error
+	output, err := support.RunGo(state.projectPath, "list", "-e", "-f", "{{.ImportPath}}: {{if .Error}}{{.Error}}{{end}}", "./cmd/...", "./internal/...")
+	if err != nil {
+		return fmt.Errorf("%w\n%s", err, output)
+	}
+	state.packageListing = output
 	return nil
 }
 
-func packageNamingShouldBeConsistentThroughout() error {
-	// Verify our package naming fixes
+func testConfigurationIssuesPreventProperExecution(ctx context.Context) error {
+	return ensureConfig(bddState(ctx))
+}
+
+func iRunTheCompleteTestSuite(ctx context.Context) error {
+	return allTestsPass(ctx)
+}
+
+func allExistingTestsShouldPassWithoutConflicts(ctx context.Context) error {
+	state := bddState(ctx)
+	if state.testErr != nil {
+		return fmt.Errorf("%w\n%s", state.testErr, state.testOutput)
+	}
 	return nil
 }
 
-func testConfigurationShouldWorkProperlyForAllComponents() error {
-	if !testState.testSuiteResults["config_issues"] {
-		return fmt.Errorf("configuration issues still exist")
+func packageNamingShouldBeConsistentThroughout(ctx context.Context) error {
+	state := bddState(ctx)
+	if state.packageListing == "" {
+		return fmt.Errorf("packages were not listed")
+	}
+	for _, line := range strings.Split(strings.TrimSpace(state.packageListing), "\n") {
+		if strings.Contains(line, "found packages") {
+			return fmt.Errorf("conflicting package names: %s", line)
+		}
 	}
 	return nil
 }
 
+func testConfigurationShouldWorkProperlyForAllComponents(ctx context.Context) error {
+	if err := ensureConfig(bddState(ctx)); err != nil {
+		return err
+	}
+	return config.Validate()
+}
+
 // Git Initialization Step Definitions
-func theProjectDirectoryExistsWithoutGitTracking() error {
-	// This was the state before Task 1.5
+func theProjectDirectoryExistsWithoutGitTracking(ctx context.Context) error {
+	state := bddState(ctx)
+	if err := ensureProjectPath(state); err != nil {
+		return err
+	}
+	dir, err := scenarioDir(state, "repository")
+	if err != nil {
+		return err
+	}
+	for _, name := range []string{"go.mod", "main.go"} {
+		data, err := os.ReadFile(filepath.Join(state.projectPath, name))
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		return fmt.Errorf("project copy is already tracked by git")
+	}
+	state.gitDir = dir
 	return nil
 }
 
-func iInitializeTheGitRepository() error {
-	testState.gitInitialized = true
+func iInitializeTheGitRepository(ctx context.Context) error {
+	state := bddState(ctx)
+	if state.gitDir == "" {
+		return fmt.Errorf("no project directory to initialize")
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "-A"},
+		{"commit", "--quiet", "-m", "Initial commit"},
+	} {
+		if _, err := runGit(state.gitDir, args...); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func gitShouldBeProperlyConfigured() error {
-	if !testState.gitInitialized {
+func gitShouldBeProperlyConfigured(ctx context.Context) error {
+	output, err := runGit(bddState(ctx).gitDir, "rev-parse", "--is-inside-work-tree")
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(output) != "true" {
 		return fmt.Errorf("git repository not initialized")
 	}
 	return nil
 }
 
-func initialCommitShouldCaptureCurrentProjectState() error {
-	// Verify initial commit exists (Task 1.5 completed this)
+func initialCommitShouldCaptureCurrentProjectState(ctx context.Context) error {
+	state := bddState(ctx)
+	tracked, err := runGit(state.gitDir, "ls-files")
+	if err != nil {
+		return err
+	}
+	for _, name := range []string{"go.mod", "main.go"} {
+		if !strings.Contains(tracked, name) {
+			return fmt.Errorf("initial commit does not include %s", name)
+		}
+	}
+
+	status, err := runGit(state.gitDir, "status", "--porcelain")
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(status) != "" {
+		return fmt.Errorf("working tree not clean after initial commit:\n%s", status)
+	}
 	return nil
 }
 
-func futureChangesShouldBeTrackable() error {
-	if !testState.gitInitialized {
+func futureChangesShouldBeTrackable(ctx context.Context) error {
+	state := bddState(ctx)
+	if err := os.WriteFile(filepath.Join(state.gitDir, "notes.txt"), []byte("next steps\n"), 0o644); err != nil {
+		return fmt.Errorf("failed to change the project: %w", err)
+	}
+	status, err := runGit(state.gitDir, "status", "--porcelain")
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(status, "notes.txt") {
 		return fmt.Errorf("git not properly set up for tracking")
 	}
 	return nil
 }
 
-// System Functionality Step Definitions  
-func theIntelligenceInterfacecodebase() error {
-	return nil
+// System Functionality Step Definitions
+func theIntelligenceInterfacecodebase(ctx context.Context) error {
+	return ensureModuleCopy(bddState(ctx))
 }
 
-func theSystemBuildsSuccessfully() error {
-	testState.buildSuccessful = true
+func theSystemBuildsSuccessfully(ctx context.Context) error {
+	state := bddState(ctx)
+	if err := ensureModuleCopy(state); err != nil {
+		return err
+	}
+	state.buildOutput, state.buildErr = support.RunGo(state.moduleCopy, "build", "./...")
+	if state.buildErr != nil {
+		return fmt.Errorf("%w\n%s", state.buildErr, state.buildOutput)
+	}
 	return nil
 }
 
-func allTestsPass() error {
-	testState.allTestsPassing = true
+func allTestsPass(ctx context.Context) error {
+	state := bddState(ctx)
+	if err := ensureModuleCopy(state); err != nil {
+		return err
+	}
+	state.testOutput, state.testErr = support.RunGo(state.moduleCopy, append([]string{"test"}, scopedTestPackages...)...)
+	if state.testErr != nil {
+		return fmt.Errorf("%w\n%s", state.testErr, state.testOutput)
+	}
 	return nil
 }
 
-func theSystemShouldBeReadyForDevelopment() error {
-	if !testState.buildSuccessful {
-		return fmt.Errorf("system build failed")
+func theSystemShouldBeReadyForDevelopment(ctx context.Context) error {
+	state := bddState(ctx)
+	if state.buildOutput == "" && state.buildErr == nil && state.testOutput == "" {
+		return fmt.Errorf("the system was neither built nor tested")
+	}
+	if state.buildErr != nil {
+		return fmt.Errorf("system build failed: %w", state.buildErr)
 	}
-	if !testState.allTestsPassing {
-		return fmt.Errorf("tests are failing")
+	if state.testErr != nil {
+		return fmt.Errorf("tests are failing: %w", state.testErr)
 	}
 	return nil
 }
 
 // Meta-System Evolution Step Definitions
-func theSystemHasMetaSystemArchitectureSupport() error {
-	// Verify the directory structure supports meta-system architecture
+func theSystemHasMetaSystemArchitectureSupport(ctx context.Context) error {
+	state := bddState(ctx)
+	if err := ensureProjectPath(state); err != nil {
+		return err
+	}
+	for _, dir := range []string{"internal/caronex", "internal/agents/base", "internal/agents/caronex", "internal/tools/coordination", "internal/core/config"} {
+		if info, err := os.Stat(filepath.Join(state.projectPath, dir)); err != nil || !info.IsDir() {
+			return fmt.Errorf("required directory missing: %s", dir)
+		}
+	}
 	return nil
 }
 
-func iValidateTheArchitectureFoundation() error {
-	// This validates that our directory migration (Task 1) was successful
+func iValidateTheArchitectureFoundation(ctx context.Context) error {
+	state := bddState(ctx)
+	output, err := support.RunGo(state.projectPath, "list", "./internal/agents/...", "./internal/tools/...", "./internal/core/...")
+	if err != nil {
+		return fmt.Errorf("%w\n%s", err, output)
+	}
+	state.packages = strings.Fields(output)
 	return nil
 }
 
-func theArchitectureShouldSupportFutureEvolution() error {
-	// Directory structure should support future Caronex, spaces, and agent evolution
+// hasPackage reports whether an internal package was listed
+func hasPackage(state *BDDTestState, path string) bool {
+	for _, pkg := range state.packages {
+		if strings.HasSuffix(pkg, "/"+path) {
+			return true
+		}
+	}
+	return false
+}
+
+func theArchitectureShouldSupportFutureEvolution(ctx context.Context) error {
+	state := bddState(ctx)
+	for _, pkg := range []string{"internal/agents/base", "internal/agents/caronex", "internal/core/config"} {
+		if !hasPackage(state, pkg) {
+			return fmt.Errorf("package %s does not load", pkg)
+		}
+	}
 	return nil
 }
 
-func spaceBasedComputingShouldBePossible() error {
-	// Architecture should support space-based computing patterns
+func spaceBasedComputingShouldBePossible(ctx context.Context) error {
+	state := bddState(ctx)
+	if err := ensureMetaSystemConfig(state); err != nil {
+		return err
+	}
+	space, exists := state.metaSystemConfig.Spaces["dev"]
+	if !exists || space.Type != "development" {
+		return fmt.Errorf("development space was not configured")
+	}
 	return nil
 }
 
-func agentCoordinationPatternsShouldBeEstablished() error {
-	// Foundation for agent coordination should be in place
+func agentCoordinationPatternsShouldBeEstablished(ctx context.Context) error {
+	state := bddState(ctx)
+	if !hasPackage(state, "internal/tools/coordination") {
+		return fmt.Errorf("coordination package does not load")
+	}
+	if err := ensureConfig(state); err != nil {
+		return err
+	}
+	manager, err := coordination.NewManager(state.config)
+	if err != nil {
+		return fmt.Errorf("failed to create coordination manager: %w", err)
+	}
+	plan, err := manager.CreateTaskPlan(context.Background(), "implement a feature", []string{"a requirement"}, "")
+	if err != nil {
+		return fmt.Errorf("failed to create task plan: %w", err)
+	}
+	if len(plan.Steps) < 2 || len(plan.Dependencies) == 0 {
+		return fmt.Errorf("task plans should break work into dependent steps")
+	}
 	return nil
 }
 
 // Configuration Step Definitions
 
-func theExistingConfigurationSystemIn(configPath string) error {
-	// Verify that configuration system exists
+func theExistingConfigurationSystemIn(ctx context.Context, configPath string) error {
+	state := bddState(ctx)
+	if err := ensureProjectPath(state); err != nil {
+		return err
+	}
+	if info, err := os.Stat(filepath.Join(state.projectPath, configPath)); err != nil || !info.IsDir() {
+		return fmt.Errorf("configuration system not found in %s", configPath)
+	}
 	return nil
 }
 
-func theComprehensiveBDDTestingInfrastructureIsEstablished() error {
-	// BDD infrastructure was established in Task 2.5
+func theComprehensiveBDDTestingInfrastructureIsEstablished(ctx context.Context) error {
+	state := bddState(ctx)
+	features, err := filepath.Glob(filepath.Join(state.projectPath, "test", "bdd", "features", "*.feature"))
+	if err != nil {
+		return err
+	}
+	if len(features) == 0 {
+		return fmt.Errorf("no feature files found")
+	}
 	return nil
 }
 
-func allTestConfigurationIssuesHaveBeenResolved() error {
-	// All configuration issues from Task 2.5 were resolved
-	return nil
+func allTestConfigurationIssuesHaveBeenResolved(ctx context.Context) error {
+	return ensureConfig(bddState(ctx))
 }
 
-func iAddCaronexAgentTypeToTheConfiguration() error {
-	// This is implemented - AgentCaronex is already defined in config.go
+func iAddCaronexAgentTypeToTheConfiguration(ctx context.Context) error {
+	state := bddState(ctx)
+	if err := ensureConfig(state); err != nil {
+		return err
+	}
+	if _, exists := state.config.Agents[config.AgentCaronex]; !exists {
+		return fmt.Errorf("Caronex agent not found in configuration")
+	}
 	return nil
 }
 
-func caronexShouldBeConfigurableLikeOtherAgents() error {
-	// Verify Caronex is in agent configuration defaults
+func caronexShouldBeConfigurableLikeOtherAgents(ctx context.Context) error {
+	agent := bddState(ctx).config.Agents[config.AgentCaronex]
+	if _, supported := models.SupportedModels[agent.Model]; !supported {
+		return fmt.Errorf("Caronex agent configured with unsupported model %q", agent.Model)
+	}
+	if agent.MaxTokens <= 0 {
+		return fmt.Errorf("Caronex agent has no max tokens")
+	}
 	return nil
 }
 
-func managerSpecificSettingsShouldBeAvailable() error {
-	// CaronexConfig struct provides manager-specific settings
+func managerSpecificSettingsShouldBeAvailable(ctx context.Context) error {
+	caronex := bddState(ctx).config.Caronex
+	if !caronex.Enabled {
+		return fmt.Errorf("Caronex should be enabled by default")
+	}
+	if caronex.Hotkey != "ctrl+m" {
+		return fmt.Errorf("expected Caronex hotkey ctrl+m, got %q", caronex.Hotkey)
+	}
 	return nil
 }
 
-func coordinationCapabilitiesShouldBeConfigurable() error {
-	// CoordinationConfig struct provides coordination settings
+func coordinationCapabilitiesShouldBeConfigurable(ctx context.Context) error {
+	state := bddState(ctx)
+	if err := ensureMetaSystemConfig(state); err != nil {
+		return err
+	}
+	coordination := state.metaSystemConfig.Caronex.Coordination
+	if coordination.MaxConcurrentAgents != 5 || coordination.CommunicationProtocol != "direct" || !coordination.AgentSpawningEnabled {
+		return fmt.Errorf("coordination settings were not read from configuration: %+v", coordination)
+	}
 	return nil
 }
 
-func configurationValidationShouldIncludeCaronexParameters() error {
-	// validateCaronexConfig function handles Caronex validation
+func configurationValidationShouldIncludeCaronexParameters(ctx context.Context) error {
+	state := bddState(ctx)
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	coordination := state.config.Caronex.Coordination
+	if coordination.MaxConcurrentAgents < 1 || coordination.MaxConcurrentAgents > 100 {
+		return fmt.Errorf("max concurrent agents %d outside validated range", coordination.MaxConcurrentAgents)
+	}
+	if coordination.CommunicationProtocol == "" {
+		return fmt.Errorf("communication protocol not validated")
+	}
 	return nil
 }
 
-func theNeedForPersistentDesktopEnvironments() error {
-	// This acknowledges the requirement for persistent spaces
-	return nil
+func theNeedForPersistentDesktopEnvironments(ctx context.Context) error {
+	return ensureProjectPath(bddState(ctx))
 }
 
-func iAddSpaceConfigurationTypes() error {
-	// SpaceConfig and related structs are implemented
-	return nil
+func iAddSpaceConfigurationTypes(ctx context.Context) error {
+	return ensureMetaSystemConfig(bddState(ctx))
 }
 
-func spaceDefinitionsShouldSupportUILayoutConfiguration() error {
-	// UILayoutConfig provides UI layout support
+func spaceDefinitionsShouldSupportUILayoutConfiguration(ctx context.Context) error {
+	layout := bddState(ctx).metaSystemConfig.Spaces["dev"].UILayout
+	if layout.Type != "split" || len(layout.Panels) != 1 || layout.Panels[0].Position != "left" {
+		return fmt.Errorf("UI layout was not read from configuration: %+v", layout)
+	}
 	return nil
 }
 
-func agentAssignmentToSpacesShouldBePossible() error {
-	// SpaceConfig.AssignedAgents field supports agent assignment
+func agentAssignmentToSpacesShouldBePossible(ctx context.Context) error {
+	agents := bddState(ctx).metaSystemConfig.Spaces["dev"].AssignedAgents
+	if len(agents) != 2 {
+		return fmt.Errorf("expected 2 assigned agents, got %v", agents)
+	}
 	return nil
 }
 
-func spacePersistenceShouldBeConfigurable() error {
-	// PersistenceConfig provides persistence configuration
+func spacePersistenceShouldBeConfigurable(ctx context.Context) error {
+	persistence := bddState(ctx).metaSystemConfig.Spaces["dev"].Persistence
+	if !persistence.Enabled || persistence.StorageBackend != "disk" || persistence.RetentionDays != 30 {
+		return fmt.Errorf("persistence was not read from configuration: %+v", persistence)
+	}
 	return nil
 }
 
-func spaceToAgentMappingShouldBeSupported() error {
-	// SpaceConfig supports space-to-agent mapping via AssignedAgents
+func spaceToAgentMappingShouldBeSupported(ctx context.Context) error {
+	cfg := bddState(ctx).metaSystemConfig
+	for spaceID, space := range cfg.Spaces {
+		for _, agent := range space.AssignedAgents {
+			if _, exists := cfg.Agents[config.AgentName(agent)]; !exists {
+				return fmt.Errorf("space %s assigned unknown agent %s", spaceID, agent)
+			}
+		}
+	}
 	return nil
 }
 
-func theExistingAgentTypes() error {
-	// Acknowledges existing agent types (coder, summarizer, title, task)
+func theExistingAgentTypes(ctx context.Context) error {
+	state := bddState(ctx)
+	if err := ensureMetaSystemConfig(state); err != nil {
+		return err
+	}
+	for _, name := range []config.AgentName{"coder", "summarizer", "title", "task"} {
+		if _, exists := state.metaSystemConfig.Agents[name]; !exists {
+			return fmt.Errorf("agent %s not configured", name)
+		}
+	}
 	return nil
 }
 
-func iExtendAgentConfigurationForSpecialization() error {
-	// AgentSpecialization struct extends agent configuration
+func iExtendAgentConfigurationForSpecialization(ctx context.Context) error {
+	if bddState(ctx).metaSystemConfig.Agents["coder"].Specialization == nil {
+		return fmt.Errorf("coder specialization was not read from configuration")
+	}
 	return nil
 }
 
-func specializedAgentParametersShouldBeConfigurable() error {
-	// AgentSpecialization provides specialized parameters
+func specializedAgentParametersShouldBeConfigurable(ctx context.Context) error {
+	spec := bddState(ctx).metaSystemConfig.Agents["coder"].Specialization
+	if spec.LearningRate != 0.2 || !spec.EvolutionCapable || !spec.MetaSystemAware {
+		return fmt.Errorf("specialization parameters were not read from configuration: %+v", spec)
+	}
 	return nil
 }
 
-func agentCoordinationSettingsShouldBeAvailable() error {
-	// CoordinationMode and other coordination settings are available
+func agentCoordinationSettingsShouldBeAvailable(ctx context.Context) error {
+	agents := bddState(ctx).metaSystemConfig.Agents
+	if agents["coder"].Specialization.CoordinationMode != "cooperative" || agents["task"].Specialization.CoordinationMode != "hierarchical" {
+		return fmt.Errorf("coordination modes were not read from configuration")
+	}
+	if agents["summarizer"].Specialization != nil {
+		return fmt.Errorf("agents without specialization should not get one")
+	}
 	return nil
 }
 
-func agentLearningConfigurationShouldBeSupported() error {
-	// LearningConfig provides learning configuration
+func agentLearningConfigurationShouldBeSupported(ctx context.Context) error {
+	learning := bddState(ctx).metaSystemConfig.Caronex.Learning
+	if !learning.Enabled || !learning.PatternRecognition || learning.AdaptationThreshold != 0.5 {
+		return fmt.Errorf("learning configuration was not read: %+v", learning)
+	}
 	return nil
 }
 
-func metaSystemEvolutionSettingsShouldBeConfigurable() error {
-	// EvolutionConfig provides evolution settings
+func metaSystemEvolutionSettingsShouldBeConfigurable(ctx context.Context) error {
+	evolution := bddState(ctx).metaSystemConfig.Caronex.Evolution
+	if !evolution.Enabled || !evolution.SafetyChecksEnabled || !evolution.RollbackCapability {
+		return fmt.Errorf("evolution configuration was not read: %+v", evolution)
+	}
 	return nil
 }
 
-func theExtendedConfigurationSchema() error {
-	// Extended schema with meta-system types is implemented
-	return nil
+func theExtendedConfigurationSchema(ctx context.Context) error {
+	return ensureMetaSystemConfig(bddState(ctx))
 }
 
-func configurationFilesAreLoaded() error {
-	// Configuration loading works with new meta-system options
-	return nil
+func configurationFilesAreLoaded(ctx context.Context) error {
+	return ensureConfig(bddState(ctx))
 }
 
-func allNewConfigurationOptionsShouldValidateCorrectly() error {
-	// validateMetaSystemConfig ensures all new options validate
-	return nil
+func allNewConfigurationOptionsShouldValidateCorrectly(ctx context.Context) error {
+	return config.Validate()
 }
 
-func backwardCompatibilityWithExistingConfigsShouldBeMaintained() error {
-	// All new fields use omitempty tags for backward compatibility
+func backwardCompatibilityWithExistingConfigsShouldBeMaintained(ctx context.Context) error {
+	legacy, err := decodeConfig(legacyConfigJSON)
+	if err != nil {
+		return err
+	}
+	if legacy.Agents["coder"].Model != "gpt-4.1" {
+		return fmt.Errorf("legacy agent configuration was not read")
+	}
+	if legacy.Agents["coder"].Specialization != nil || len(legacy.Spaces) != 0 {
+		return fmt.Errorf("legacy configuration should not gain meta-system settings")
+	}
+	bddState(ctx).legacyConfig = legacy
 	return nil
 }
 
-func configurationErrorsShouldProvideClearGuidance() error {
-	// Validation functions provide clear error messages
+func configurationErrorsShouldProvideClearGuidance(ctx context.Context) error {
+	err := config.UpdateAgentModel(config.AgentCaronex, "not-a-model")
+	if err == nil {
+		return fmt.Errorf("updating to an unknown model should fail")
+	}
+	if !strings.Contains(err.Error(), "not-a-model") {
+		return fmt.Errorf("error should name the offending model: %v", err)
+	}
 	return nil
 }
 
-func defaultValuesShouldSupportMetaSystemFunctionality() error {
-	// setMetaSystemDefaults provides sensible defaults
+func defaultValuesShouldSupportMetaSystemFunctionality(ctx context.Context) error {
+	caronex := bddState(ctx).config.Caronex
+	if caronex.Coordination.MaxConcurrentAgents != 10 {
+		return fmt.Errorf("expected 10 concurrent agents by default, got %d", caronex.Coordination.MaxConcurrentAgents)
+	}
+	if caronex.Coordination.CommunicationProtocol != "pubsub" {
+		return fmt.Errorf("expected pubsub protocol by default, got %q", caronex.Coordination.CommunicationProtocol)
+	}
+	if caronex.SpaceManagement.MaxSpaces != 20 {
+		return fmt.Errorf("expected 20 spaces by default, got %d", caronex.SpaceManagement.MaxSpaces)
+	}
+	if caronex.Evolution.Enabled {
+		return fmt.Errorf("evolution should be disabled by default")
+	}
+	autoCompact := bddState(ctx).config.AutoCompact
+	if !autoCompact.Enabled {
+		return fmt.Errorf("auto compaction should be enabled by default")
+	}
+	if autoCompact.ThresholdRatio <= 0.5 || autoCompact.ThresholdRatio >= 1 {
+		return fmt.Errorf("expected auto compaction to leave room for the summary, got a threshold ratio of %g", autoCompact.ThresholdRatio)
+	}
+	if autoCompact.MinMessages <= 0 {
+		return fmt.Errorf("expected a minimum number of messages before auto compaction, got %d", autoCompact.MinMessages)
+	}
 	return nil
 }
 
-func existingIntelligenceInterfaceConfigurationFiles() error {
-	// Acknowledges existing Intelligence Interface configurations
-	return nil
+func existingIntelligenceInterfaceConfigurationFiles(ctx context.Context) error {
+	return backwardCompatibilityWithExistingConfigsShouldBeMaintained(ctx)
 }
 
-func theSystemLoadsConfigurationsWithNewMetaSystemOptions() error {
-	// Configuration loading supports new meta-system options
-	return nil
+func theSystemLoadsConfigurationsWithNewMetaSystemOptions(ctx context.Context) error {
+	return ensureConfig(bddState(ctx))
 }
 
-func configurationsShouldMigrateSeamlessly() error {
-	// All new fields are optional, ensuring seamless migration
-	return nil
+func configurationsShouldMigrateSeamlessly(ctx context.Context) error {
+	if bddState(ctx).legacyConfig == nil {
+		return fmt.Errorf("legacy configuration was not loaded")
+	}
+	return config.Validate()
 }
 
-func newOptionsShouldHaveSensibleDefaults() error {
-	// setMetaSystemDefaults provides sensible defaults for all new options
-	return nil
+func newOptionsShouldHaveSensibleDefaults(ctx context.Context) error {
+	return defaultValuesShouldSupportMetaSystemFunctionality(ctx)
 }
 
-func configurationSchemaShouldSupportFutureEvolution() error {
-	// Schema design with map[string]interface{} supports future expansion
+func configurationSchemaShouldSupportFutureEvolution(ctx context.Context) error {
+	state := bddState(ctx)
+	if err := ensureMetaSystemConfig(state); err != nil {
+		return err
+	}
+	if state.metaSystemConfig.Spaces["dev"].Configuration["future_option"] != true {
+		return fmt.Errorf("free-form space configuration was not preserved")
+	}
 	return nil
 }
 
-func migrationShouldBeReversibleAndSafe() error {
-	// Using omitempty tags ensures reversible migration
+func migrationShouldBeReversibleAndSafe(ctx context.Context) error {
+	legacy := bddState(ctx).legacyConfig
+	data, err := json.Marshal(legacy)
+	if err != nil {
+		return fmt.Errorf("failed to encode configuration: %w", err)
+	}
+	roundTripped, err := decodeConfig(string(data))
+	if err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(legacy, roundTripped) {
+		return fmt.Errorf("configuration changed after a save and reload")
+	}
 	return nil
 }
 
 // TUI Caronex Integration Step Definitions
 
-func theIntelligenceInterfaceTUIIsRunning() error {
-	testState.tuiRunning = true
+// agentModeStyle returns the visual style the interface uses for an agent mode
+func agentModeStyle(mode string) string {
+	if mode == string(config.AgentCaronex) {
+		return "caronex_manager"
+	}
+	return "implementation"
+}
+
+// setAgentMode switches the simulated interface to mode
+func setAgentMode(state *BDDTestState, mode string) {
+	state.currentAgentMode = mode
+	state.visualStyleMode = agentModeStyle(mode)
+}
+
+func theIntelligenceInterfaceTUIIsRunning(ctx context.Context) error {
+	bddState(ctx).tuiRunning = true
 	return nil
 }
 
-func theSystemHasMultipleAgentsAvailable() error {
-	// Mock system with multiple agents (coder, caronex, etc.)
+func theTUIHasMultipleAgentsAvailable(ctx context.Context) error {
+	state := bddState(ctx)
+	if err := ensureConfig(state); err != nil {
+		return err
+	}
+	if len(state.config.Agents) < 2 {
+		return fmt.Errorf("expected multiple agents to switch between, got %d", len(state.config.Agents))
+	}
 	return nil
 }
 
-func iAmInTheMainChatInterface() error {
+func iAmInTheMainChatInterface(ctx context.Context) error {
 	// User is in main chat interface - default state
-	testState.currentAgentMode = "coder"
+	setAgentMode(bddState(ctx), "coder")
 	return nil
 }
 
-func iAmInTheMainTUIInterface() error {
-	if !testState.tuiRunning {
+func iAmInTheMainTUIInterface(ctx context.Context) error {
+	if !bddState(ctx).tuiRunning {
 		return fmt.Errorf("TUI is not running")
 	}
 	return nil
 }
 
-func iPressTheCaronexHotkey() error {
+func iPressTheCaronexHotkey(ctx context.Context) error {
 	// Simulate Ctrl+M hotkey press
-	testState.agentModeSwitched = true
+	bddState(ctx).agentModeSwitched = true
 	return nil
 }
 
-func iShouldEnterManagerMode() error {
-	if !testState.agentModeSwitched {
+func iShouldEnterManagerMode(ctx context.Context) error {
+	state := bddState(ctx)
+	if !state.agentModeSwitched {
 		return fmt.Errorf("agent mode was not switched")
 	}
-	testState.currentAgentMode = "caronex"
+	setAgentMode(state, "caronex")
 	return nil
 }
 
-func visualIndicatorsShouldShowImTalkingToCaronex() error {
-	if testState.currentAgentMode != "caronex" {
+func visualIndicatorsShouldShowImTalkingToCaronex(ctx context.Context) error {
+	state := bddState(ctx)
+	if state.currentAgentMode != "caronex" {
 		return fmt.Errorf("not in caronex mode")
 	}
-	testState.visualStyleMode = "caronex_manager"
+	if state.visualStyleMode != "caronex_manager" {
+		return fmt.Errorf("caronex mode is not styled as the manager")
+	}
 	return nil
 }
 
-func conversationContextShouldSwitchToManagerAgent() error {
-	if testState.currentAgentMode != "caronex" {
+func conversationContextShouldSwitchToManagerAgent(ctx context.Context) error {
+	state := bddState(ctx)
+	if state.currentAgentMode != "caronex" {
 		return fmt.Errorf("conversation context not switched to manager agent")
 	}
-	if testState.conversationContext == nil {
-		testState.conversationContext = make(map[string]interface{})
-	}
-	testState.conversationContext["agent_type"] = "manager"
+	state.conversationContext["agent_type"] = "manager"
 	return nil
 }
 
-func iAmSwitchingBetweenAgentModes() error {
+func iAmSwitchingBetweenAgentModes(ctx context.Context) error {
 	// Simulate switching between different agent modes
-	testState.agentModeSwitched = true
+	bddState(ctx).agentModeSwitched = true
 	return nil
 }
 
-func iInteractWithDifferentAgentTypes() error {
-	// Mock interaction with different agents
-	if testState.conversationContext == nil {
-		testState.conversationContext = make(map[string]interface{})
+func iInteractWithDifferentAgentTypes(ctx context.Context) error {
+	// Mock interaction with different agents, recording the style of each
+	state := bddState(ctx)
+	modes := []string{"coder", "caronex", "summarizer"}
+	state.modeStyles = make(map[string]string, len(modes))
+	for _, mode := range modes {
+		setAgentMode(state, mode)
+		state.modeStyles[mode] = state.visualStyleMode
 	}
-	testState.conversationContext["interaction_modes"] = []string{"coder", "caronex", "summarizer"}
+	state.conversationContext["interaction_modes"] = modes
 	return nil
 }
 
-func theInterfaceShouldClearlyIndicateCurrentAgent() error {
-	if testState.currentAgentMode == "" {
+func theInterfaceShouldClearlyIndicateCurrentAgent(ctx context.Context) error {
+	if bddState(ctx).currentAgentMode == "" {
 		return fmt.Errorf("current agent mode not clearly indicated")
 	}
 	return nil
 }
 
-func caronexModeShouldHaveDistinctVisualStyling() error {
-	if testState.visualStyleMode != "caronex_manager" {
-		return fmt.Errorf("caronex mode does not have distinct visual styling")
+func caronexModeShouldHaveDistinctVisualStyling(ctx context.Context) error {
+	styles := bddState(ctx).modeStyles
+	for mode, style := range styles {
+		if mode != "caronex" && style == styles["caronex"] {
+			return fmt.Errorf("caronex mode does not have distinct visual styling")
+		}
 	}
 	return nil
 }
 
-func agentCapabilitiesShouldBeClearlyCommunicated() error {
-	// Validate that agent capabilities are displayed
+func agentCapabilitiesShouldBeClearlyCommunicated(ctx context.Context) error {
+	if _, exists := bddState(ctx).conversationContext["interaction_modes"]; !exists {
+		return fmt.Errorf("agent capabilities were not communicated")
+	}
 	return nil
 }
 
-func iAmInAnyAgentMode() error {
-	if testState.currentAgentMode == "" {
-		testState.currentAgentMode = "coder" // Default to coder mode
+func iAmInAnyAgentMode(ctx context.Context) error {
+	state := bddState(ctx)
+	if state.currentAgentMode == "" {
+		setAgentMode(state, "coder") // Default to coder mode
 	}
 	return nil
 }
 
-func iSwitchToADifferentAgentMode() error {
-	previousMode := testState.currentAgentMode
-	if previousMode == "coder" {
-		testState.currentAgentMode = "caronex"
+func iSwitchToADifferentAgentMode(ctx context.Context) error {
+	state := bddState(ctx)
+	if state.currentAgentMode == "coder" {
+		setAgentMode(state, "caronex")
 	} else {
-		testState.currentAgentMode = "coder"
+		setAgentMode(state, "coder")
 	}
-	testState.agentModeSwitched = true
+	state.agentModeSwitched = true
 	return nil
 }
 
-func theTransitionShouldBeSmoothAndResponsive() error {
-	if !testState.agentModeSwitched {
+func theTransitionShouldBeSmoothAndResponsive(ctx context.Context) error {
+	if !bddState(ctx).agentModeSwitched {
 		return fmt.Errorf("agent mode transition was not smooth")
 	}
 	return nil
 }
 
-func previousConversationContextShouldBePreserved() error {
-	if testState.conversationContext == nil {
+func previousConversationContextShouldBePreserved(ctx context.Context) error {
+	if bddState(ctx).conversationContext == nil {
 		return fmt.Errorf("conversation context was not preserved")
 	}
 	return nil
 }
 
-func modeSpecificUIElementsShouldUpdateCorrectly() error {
-	if testState.currentAgentMode == "caronex" && testState.visualStyleMode != "caronex_manager" {
+func modeSpecificUIElementsShouldUpdateCorrectly(ctx context.Context) error {
+	state := bddState(ctx)
+	if state.visualStyleMode != agentModeStyle(state.currentAgentMode) {
 		return fmt.Errorf("mode-specific UI elements did not update correctly")
 	}
 	return nil
 }
 
-func iAmInCaronexManagerMode() error {
-	testState.currentAgentMode = "caronex"
-	testState.visualStyleMode = "caronex_manager"
-	if testState.conversationContext == nil {
-		testState.conversationContext = make(map[string]interface{})
-	}
-	testState.conversationContext["agent_type"] = "manager"
+func iAmInCaronexManagerMode(ctx context.Context) error {
+	state := bddState(ctx)
+	setAgentMode(state, "caronex")
+	state.conversationContext["agent_type"] = "manager"
 	return nil
 }
 
-func iRequestSystemCoordinationOrPlanningAssistance() error {
-	if testState.currentAgentMode != "caronex" {
+func iRequestSystemCoordinationOrPlanningAssistance(ctx context.Context) error {
+	state := bddState(ctx)
+	if state.currentAgentMode != "caronex" {
 		return fmt.Errorf("not in caronex manager mode")
 	}
-	testState.coordinationRequest = "system_coordination_request"
+	state.coordinationRequest = "system_coordination_request"
 	return nil
 }
 
-func caronexShouldProvideCoordinationFocusedResponses() error {
-	if testState.coordinationRequest == "" {
+func caronexShouldProvideCoordinationFocusedResponses(ctx context.Context) error {
+	state := bddState(ctx)
+	if state.coordinationRequest == "" {
 		return fmt.Errorf("no coordination request made")
 	}
-	testState.agentResponse = "coordination_focused_response"
+	state.agentResponse = "coordination_focused_response"
 	return nil
 }
 
-func caronexShouldDelegateImplementationTasksAppropriately() error {
-	if testState.agentResponse != "coordination_focused_response" {
+func caronexShouldDelegateImplementationTasksAppropriately(ctx context.Context) error {
+	if bddState(ctx).agentResponse != "coordination_focused_response" {
 		return fmt.Errorf("caronex did not provide coordination-focused response")
 	}
 	return nil
 }
 
-func theInterfaceShouldSupportCoordinationWorkflows() error {
-	if testState.currentAgentMode != "caronex" {
+func theInterfaceShouldSupportCoordinationWorkflows(ctx context.Context) error {
+	if bddState(ctx).currentAgentMode != "caronex" {
 		return fmt.Errorf("interface does not support coordination workflows")
 	}
 	return nil
 }
 
-func iSwitchToImplementationAgentMode() error {
-	testState.currentAgentMode = "coder"
-	testState.visualStyleMode = "implementation"
-	testState.agentModeSwitched = true
+func iSwitchToImplementationAgentMode(ctx context.Context) error {
+	state := bddState(ctx)
+	setAgentMode(state, "coder")
+	state.agentModeSwitched = true
 	return nil
 }
 
-func theAgentShouldHandleDirectImplementationTasks() error {
-	if testState.currentAgentMode != "coder" {
+func theAgentShouldHandleDirectImplementationTasks(ctx context.Context) error {
+	if bddState(ctx).currentAgentMode != "coder" {
 		return fmt.Errorf("not in implementation agent mode")
 	}
 	return nil
 }
 
-func theInterfaceShouldReflectImplementationCapabilities() error {
-	if testState.visualStyleMode != "implementation" {
+func theInterfaceShouldReflectImplementationCapabilities(ctx context.Context) error {
+	if bddState(ctx).visualStyleMode != "implementation" {
 		return fmt.Errorf("interface does not reflect implementation capabilities")
 	}
 	return nil
 }
 
-func conversationContextShouldBeAgentAppropriate() error {
-	if testState.conversationContext == nil {
+func conversationContextShouldBeAgentAppropriate(ctx context.Context) error {
+	if bddState(ctx).conversationContext["agent_type"] == nil {
 		return fmt.Errorf("conversation context is not agent-appropriate")
 	}
 	return nil
-}
\ No newline at end of file
+}
@@ -4,13 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
 
 	"github.com/cucumber/godog"
 	"github.com/caronex/intelligence-interface/internal/core/config"
 	"github.com/caronex/intelligence-interface/internal/llm/tools"
 	"github.com/caronex/intelligence-interface/internal/tools/builtin"
-	"github.com/caronex/intelligence-interface/internal/tools/coordination"
+	"github.com/caronex/intelligence-interface/test/bdd/support"
 )
 
 type ManagementTestState struct {
@@ -23,6 +22,8 @@ type ManagementTestState struct {
 	agentData         map[string]interface{}
 	spaceData         map[string]interface{}
 	errors           []error
+	toolResponse      tools.ToolResponse
+	config            *config.Config
 	
 	// Tools for testing
 	systemIntrospectionTool    *builtin.SystemIntrospectionTool
@@ -32,16 +33,29 @@ type ManagementTestState struct {
 	spaceFoundationTool        *builtin.SpaceFoundationTool
 }
 
-var managementTestState = &ManagementTestState{
-	introspectionData: make(map[string]interface{}),
-	coordinationData:  make(map[string]interface{}),
-	configData:        make(map[string]interface{}),
-	agentData:         make(map[string]interface{}),
-	spaceData:         make(map[string]interface{}),
-	errors:           make([]error, 0),
+// managementStateKey is the context key holding the per-scenario
+// ManagementTestState
+type managementStateKey struct{}
+
+// managementState returns the state of the running scenario
+func managementState(ctx context.Context) *ManagementTestState {
+	return ctx.Value(managementStateKey{}).(*ManagementTestState)
 }
 
 func RegisterManagementSteps(ctx *godog.ScenarioContext) {
+	// Each scenario gets its own state so scenarios can run in parallel
+	ctx.Before(func(ctx context.Context, sc *godog.Scenario) (context.Context, error) {
+		state := &ManagementTestState{
+			introspectionData: make(map[string]interface{}),
+			coordinationData:  make(map[string]interface{}),
+			configData:        make(map[string]interface{}),
+			agentData:         make(map[string]interface{}),
+			spaceData:         make(map[string]interface{}),
+			errors:            make([]error, 0),
+		}
+		return context.WithValue(ctx, managementStateKey{}, state), nil
+	})
+
 	// Background steps
 	ctx.Step(`^I am Caronex with access to management tools$`, iAmCaronexWithAccessToManagementTools)
 	ctx.Step(`^the Intelligence Interface system is running$`, theIntelligenceInterfaceSystemIsRunning)
@@ -79,70 +93,73 @@ func RegisterManagementSteps(ctx *godog.ScenarioContext) {
 	ctx.Step(`^I should be able to list basic space configuration options$`, iShouldBeAbleToListBasicSpaceConfigurationOptions)
 	ctx.Step(`^I should be able to report space readiness status$`, iShouldBeAbleToReportSpaceReadinessStatus)
 	ctx.Step(`^I should be able to provide guidance for future space implementation$`, iShouldBeAbleToProvideGuidanceForFutureSpaceImplementation)
+
+	// Tool action scenario outlines
+	ctx.Step(`^I run the agent coordination tool with the "([^"]*)" action$`, iRunTheAgentCoordinationToolWithTheAction)
+	ctx.Step(`^I run the agent lifecycle tool with the "([^"]*)" action$`, iRunTheAgentLifecycleToolWithTheAction)
+	ctx.Step(`^the management tool should answer without error$`, theManagementToolShouldAnswerWithoutError)
+	ctx.Step(`^the management tool response should include "([^"]*)"$`, theManagementToolResponseShouldInclude)
 }
 
 // Background step implementations
-func iAmCaronexWithAccessToManagementTools() error {
-	// Set up test environment
-	os.Setenv("OPENAI_API_KEY", "test-key-for-management")
-	
-	// Load test configuration
-	tempDir := "/tmp"
-	_, err := config.Load(tempDir, false)
+func iAmCaronexWithAccessToManagementTools(ctx context.Context) error {
+	state := managementState(ctx)
+	// The test configuration and coordination manager are set up once for
+	// the whole suite
+	cfg, err := support.LoadConfig()
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %v", err)
 	}
-	
-	cfg := config.Get()
-	
-	// Initialize coordination manager
-	coordinationManager, err := coordination.NewManager(cfg)
+	coordinationManager, err := support.SharedManager()
 	if err != nil {
 		return fmt.Errorf("failed to initialize coordination manager: %v", err)
 	}
+	state.config = cfg
 	
 	// Initialize management tools
-	managementTestState.systemIntrospectionTool = builtin.NewSystemIntrospectionTool(cfg, coordinationManager)
-	managementTestState.agentCoordinationTool = builtin.NewAgentCoordinationTool(cfg, coordinationManager)
-	managementTestState.configInspectionTool = builtin.NewConfigurationInspectionTool(cfg, coordinationManager)
-	managementTestState.agentLifecycleTool = builtin.NewAgentLifecycleTool(cfg, coordinationManager)
-	managementTestState.spaceFoundationTool = builtin.NewSpaceFoundationTool(cfg, coordinationManager)
+	state.systemIntrospectionTool = builtin.NewSystemIntrospectionTool(cfg, coordinationManager)
+	state.agentCoordinationTool = builtin.NewAgentCoordinationTool(cfg, coordinationManager)
+	state.configInspectionTool = builtin.NewConfigurationInspectionTool(cfg, coordinationManager)
+	state.agentLifecycleTool = builtin.NewAgentLifecycleTool(cfg, coordinationManager)
+	state.spaceFoundationTool = builtin.NewSpaceFoundationTool(cfg, coordinationManager)
 	
-	managementTestState.caronexAgent = true
+	state.caronexAgent = true
 	return nil
 }
 
-func theIntelligenceInterfaceSystemIsRunning() error {
-	managementTestState.systemRunning = true
+func theIntelligenceInterfaceSystemIsRunning(ctx context.Context) error {
+	state := managementState(ctx)
+	state.systemRunning = true
 	return nil
 }
 
-func theConfigurationIsProperlyLoaded() error {
-	cfg := config.Get()
-	if cfg == nil {
+func theConfigurationIsProperlyLoaded(ctx context.Context) error {
+	state := managementState(ctx)
+	if state.config == nil {
 		return fmt.Errorf("configuration not loaded")
 	}
-	managementTestState.configLoaded = true
+	state.configLoaded = true
 	return nil
 }
 
 // System state introspection scenario
-func iNeedToAssessCurrentSystemCapabilities() error {
-	if !managementTestState.caronexAgent {
+func iNeedToAssessCurrentSystemCapabilities(ctx context.Context) error {
+	state := managementState(ctx)
+	if !state.caronexAgent {
 		return fmt.Errorf("caronex agent not available")
 	}
 	return nil
 }
 
-func iShouldBeAbleToQueryAvailableAgentsAndTheirSpecializations() error {
-	ctx := context.Background()
+func iShouldBeAbleToQueryAvailableAgentsAndTheirSpecializations(ctx context.Context) error {
+	state := managementState(ctx)
 	toolCall := tools.ToolCall{
 		ID:    "test_introspection",
 		Name:  "system_introspection",
 		Input: `{"include_details": true}`,
 	}
 	
-	response, err := managementTestState.systemIntrospectionTool.Run(ctx, toolCall)
+	response, err := state.systemIntrospectionTool.Run(ctx, toolCall)
 	if err != nil {
 		return fmt.Errorf("failed to run system introspection: %v", err)
 	}
@@ -156,7 +173,7 @@ func iShouldBeAbleToQueryAvailableAgentsAndTheirSpecializations() error {
 		return fmt.Errorf("failed to parse introspection result: %v", err)
 	}
 	
-	managementTestState.introspectionData = result
+	state.introspectionData = result
 	
 	// Verify agents are listed
 	if availableAgents, ok := result["available_agents"]; ok {
@@ -168,15 +185,15 @@ func iShouldBeAbleToQueryAvailableAgentsAndTheirSpecializations() error {
 	return fmt.Errorf("no available agents found in introspection result")
 }
 
-func iShouldBeAbleToCheckCurrentConfigurationState() error {
-	ctx := context.Background()
+func iShouldBeAbleToCheckCurrentConfigurationState(ctx context.Context) error {
+	state := managementState(ctx)
 	toolCall := tools.ToolCall{
 		ID:    "test_config",
 		Name:  "configuration_inspection",
 		Input: `{"section": "all", "validate": true}`,
 	}
 	
-	response, err := managementTestState.configInspectionTool.Run(ctx, toolCall)
+	response, err := state.configInspectionTool.Run(ctx, toolCall)
 	if err != nil {
 		return fmt.Errorf("failed to run configuration inspection: %v", err)
 	}
@@ -190,17 +207,18 @@ func iShouldBeAbleToCheckCurrentConfigurationState() error {
 		return fmt.Errorf("failed to parse configuration result: %v", err)
 	}
 	
-	managementTestState.configData = result
+	state.configData = result
 	return nil
 }
 
-func iShouldBeAbleToReportSystemStatusAccurately() error {
-	if len(managementTestState.introspectionData) == 0 {
+func iShouldBeAbleToReportSystemStatusAccurately(ctx context.Context) error {
+	state := managementState(ctx)
+	if len(state.introspectionData) == 0 {
 		return fmt.Errorf("system introspection data not available")
 	}
 	
 	// Verify system status is reported
-	if systemStatus, ok := managementTestState.introspectionData["system_status"]; ok {
+	if systemStatus, ok := state.introspectionData["system_status"]; ok {
 		if status, ok := systemStatus.(string); ok && status != "" {
 			return nil
 		}
@@ -210,8 +228,9 @@ func iShouldBeAbleToReportSystemStatusAccurately() error {
 }
 
 // Basic coordination capabilities scenario
-func iNeedToCoordinateAgentActivities() error {
-	if !managementTestState.caronexAgent {
+func iNeedToCoordinateAgentActivities(ctx context.Context) error {
+	state := managementState(ctx)
+	if !state.caronexAgent {
 		return fmt.Errorf("caronex agent not available for coordination")
 	}
 	return nil
@@ -222,15 +241,15 @@ func iAssessImplementationRequirements() error {
 	return nil
 }
 
-func iShouldBeAbleToIdentifyAppropriateSpecialistAgents() error {
-	ctx := context.Background()
+func iShouldBeAbleToIdentifyAppropriateSpecialistAgents(ctx context.Context) error {
+	state := managementState(ctx)
 	toolCall := tools.ToolCall{
 		ID:    "test_agent_list",
 		Name:  "agent_lifecycle",
 		Input: `{"action": "capabilities"}`,
 	}
 	
-	response, err := managementTestState.agentLifecycleTool.Run(ctx, toolCall)
+	response, err := state.agentLifecycleTool.Run(ctx, toolCall)
 	if err != nil {
 		return fmt.Errorf("failed to get agent capabilities: %v", err)
 	}
@@ -244,7 +263,7 @@ func iShouldBeAbleToIdentifyAppropriateSpecialistAgents() error {
 		return fmt.Errorf("failed to parse agent capabilities: %v", err)
 	}
 	
-	managementTestState.agentData = result
+	state.agentData = result
 	
 	// Verify agent capabilities are available
 	if capabilities, ok := result["agent_capabilities"]; ok {
@@ -256,15 +275,15 @@ func iShouldBeAbleToIdentifyAppropriateSpecialistAgents() error {
 	return fmt.Errorf("agent capabilities not properly identified")
 }
 
-func iShouldBeAbleToProvidePlanningGuidance() error {
-	ctx := context.Background()
+func iShouldBeAbleToProvidePlanningGuidance(ctx context.Context) error {
+	state := managementState(ctx)
 	toolCall := tools.ToolCall{
 		ID:    "test_planning",
 		Name:  "agent_coordination",
 		Input: `{"action": "plan", "task_description": "Implement new feature", "requirements": ["coding", "testing"]}`,
 	}
 	
-	response, err := managementTestState.agentCoordinationTool.Run(ctx, toolCall)
+	response, err := state.agentCoordinationTool.Run(ctx, toolCall)
 	if err != nil {
 		return fmt.Errorf("failed to create task plan: %v", err)
 	}
@@ -278,7 +297,7 @@ func iShouldBeAbleToProvidePlanningGuidance() error {
 		return fmt.Errorf("failed to parse planning result: %v", err)
 	}
 	
-	managementTestState.coordinationData = result
+	state.coordinationData = result
 	
 	// Verify planning guidance is provided
 	if steps, ok := result["steps"]; ok {
@@ -290,15 +309,15 @@ func iShouldBeAbleToProvidePlanningGuidance() error {
 	return fmt.Errorf("planning guidance not properly provided")
 }
 
-func iShouldBeAbleToDelegateImplementationTasksAppropriately() error {
-	ctx := context.Background()
+func iShouldBeAbleToDelegateImplementationTasksAppropriately(ctx context.Context) error {
+	state := managementState(ctx)
 	toolCall := tools.ToolCall{
 		ID:    "test_delegation",
 		Name:  "agent_coordination",
 		Input: `{"action": "delegate", "task_description": "Write unit tests", "preferred_agent": "coder"}`,
 	}
 	
-	response, err := managementTestState.agentCoordinationTool.Run(ctx, toolCall)
+	response, err := state.agentCoordinationTool.Run(ctx, toolCall)
 	if err != nil {
 		return fmt.Errorf("failed to delegate task: %v", err)
 	}
@@ -323,22 +342,23 @@ func iShouldBeAbleToDelegateImplementationTasksAppropriately() error {
 }
 
 // Configuration management scenario
-func iNeedToUnderstandSystemConfiguration() error {
-	if !managementTestState.configLoaded {
+func iNeedToUnderstandSystemConfiguration(ctx context.Context) error {
+	state := managementState(ctx)
+	if !state.configLoaded {
 		return fmt.Errorf("system configuration not loaded")
 	}
 	return nil
 }
 
-func iQueryConfigurationState() error {
-	ctx := context.Background()
+func iQueryConfigurationState(ctx context.Context) error {
+	state := managementState(ctx)
 	toolCall := tools.ToolCall{
 		ID:    "test_config_query",
 		Name:  "configuration_inspection",
 		Input: `{"section": "all", "validate": true}`,
 	}
 	
-	response, err := managementTestState.configInspectionTool.Run(ctx, toolCall)
+	response, err := state.configInspectionTool.Run(ctx, toolCall)
 	if err != nil {
 		return fmt.Errorf("failed to query configuration: %v", err)
 	}
@@ -352,19 +372,20 @@ func iQueryConfigurationState() error {
 		return fmt.Errorf("failed to parse configuration query: %v", err)
 	}
 	
-	managementTestState.configData = result
+	state.configData = result
 	return nil
 }
 
-func iShouldBeAbleToRetrieveCurrentConfigurationValues() error {
-	if len(managementTestState.configData) == 0 {
+func iShouldBeAbleToRetrieveCurrentConfigurationValues(ctx context.Context) error {
+	state := managementState(ctx)
+	if len(state.configData) == 0 {
 		return fmt.Errorf("configuration data not available")
 	}
 	
 	// Verify configuration sections are present
 	expectedSections := []string{"agents", "caronex", "spaces"}
 	for _, section := range expectedSections {
-		if _, ok := managementTestState.configData[section]; !ok {
+		if _, ok := state.configData[section]; !ok {
 			return fmt.Errorf("configuration section '%s' not found", section)
 		}
 	}
@@ -372,20 +393,21 @@ func iShouldBeAbleToRetrieveCurrentConfigurationValues() error {
 	return nil
 }
 
-func iShouldBeAbleToValidateConfigurationConsistency() error {
-	if len(managementTestState.configData) == 0 {
+func iShouldBeAbleToValidateConfigurationConsistency(ctx context.Context) error {
+	state := managementState(ctx)
+	if len(state.configData) == 0 {
 		return fmt.Errorf("configuration data not available")
 	}
 	
 	// Check validation status
-	if validationStatus, ok := managementTestState.configData["validation_status"]; ok {
+	if validationStatus, ok := state.configData["validation_status"]; ok {
 		if status, ok := validationStatus.(string); ok && status == "valid" {
 			return nil
 		}
 	}
 	
 	// Check for validation errors
-	if validationErrors, ok := managementTestState.configData["validation_errors"]; ok {
+	if validationErrors, ok := state.configData["validation_errors"]; ok {
 		if errors, ok := validationErrors.([]interface{}); ok && len(errors) > 0 {
 			return fmt.Errorf("configuration validation failed with errors")
 		}
@@ -394,10 +416,11 @@ func iShouldBeAbleToValidateConfigurationConsistency() error {
 	return nil
 }
 
-func iShouldBeAbleToReportConfigurationIssuesIfAnyExist() error {
+func iShouldBeAbleToReportConfigurationIssuesIfAnyExist(ctx context.Context) error {
+	state := managementState(ctx)
 	// This step verifies that the tool can report issues when they exist
 	// Since we have a valid configuration, we expect no issues
-	if validationErrors, ok := managementTestState.configData["validation_errors"]; ok {
+	if validationErrors, ok := state.configData["validation_errors"]; ok {
 		if errors, ok := validationErrors.([]interface{}); ok && len(errors) > 0 {
 			// Issues were properly reported
 			return nil
@@ -409,22 +432,23 @@ func iShouldBeAbleToReportConfigurationIssuesIfAnyExist() error {
 }
 
 // Agent lifecycle management scenario
-func iManageAgentOperations() error {
-	if !managementTestState.caronexAgent {
+func iManageAgentOperations(ctx context.Context) error {
+	state := managementState(ctx)
+	if !state.caronexAgent {
 		return fmt.Errorf("caronex agent not available for operations management")
 	}
 	return nil
 }
 
-func iShouldBeAbleToListAvailableAgentTypes() error {
-	ctx := context.Background()
+func iShouldBeAbleToListAvailableAgentTypes(ctx context.Context) error {
+	state := managementState(ctx)
 	toolCall := tools.ToolCall{
 		ID:    "test_agent_list",
 		Name:  "agent_lifecycle",
 		Input: `{"action": "list"}`,
 	}
 	
-	response, err := managementTestState.agentLifecycleTool.Run(ctx, toolCall)
+	response, err := state.agentLifecycleTool.Run(ctx, toolCall)
 	if err != nil {
 		return fmt.Errorf("failed to list agents: %v", err)
 	}
@@ -448,15 +472,15 @@ func iShouldBeAbleToListAvailableAgentTypes() error {
 	return fmt.Errorf("available agent types not properly listed")
 }
 
-func iShouldBeAbleToCheckAgentReadinessStatus() error {
-	ctx := context.Background()
+func iShouldBeAbleToCheckAgentReadinessStatus(ctx context.Context) error {
+	state := managementState(ctx)
 	toolCall := tools.ToolCall{
 		ID:    "test_agent_status",
 		Name:  "agent_lifecycle",
 		Input: `{"action": "status"}`,
 	}
 	
-	response, err := managementTestState.agentLifecycleTool.Run(ctx, toolCall)
+	response, err := state.agentLifecycleTool.Run(ctx, toolCall)
 	if err != nil {
 		return fmt.Errorf("failed to check agent status: %v", err)
 	}
@@ -480,15 +504,15 @@ func iShouldBeAbleToCheckAgentReadinessStatus() error {
 	return fmt.Errorf("agent readiness status not properly checked")
 }
 
-func iShouldBeAbleToCoordinateAgentTaskDelegation() error {
-	ctx := context.Background()
+func iShouldBeAbleToCoordinateAgentTaskDelegation(ctx context.Context) error {
+	state := managementState(ctx)
 	toolCall := tools.ToolCall{
 		ID:    "test_coordination_status",
 		Name:  "agent_coordination",
 		Input: `{"action": "status"}`,
 	}
 	
-	response, err := managementTestState.agentCoordinationTool.Run(ctx, toolCall)
+	response, err := state.agentCoordinationTool.Run(ctx, toolCall)
 	if err != nil {
 		return fmt.Errorf("failed to check coordination status: %v", err)
 	}
@@ -513,10 +537,9 @@ func iShouldBeAbleToCoordinateAgentTaskDelegation() error {
 }
 
 // Space foundation introspection scenario
-func theFoundationForSpaceManagementExists() error {
+func theFoundationForSpaceManagementExists(ctx context.Context) error {
 	// Verify space foundation is established
-	cfg := config.Get()
-	if cfg == nil {
+	if managementState(ctx).config == nil {
 		return fmt.Errorf("configuration not available for space foundation check")
 	}
 	
@@ -524,15 +547,15 @@ func theFoundationForSpaceManagementExists() error {
 	return nil
 }
 
-func iQuerySpaceRelatedCapabilities() error {
-	ctx := context.Background()
+func iQuerySpaceRelatedCapabilities(ctx context.Context) error {
+	state := managementState(ctx)
 	toolCall := tools.ToolCall{
 		ID:    "test_space_status",
 		Name:  "space_foundation",
 		Input: `{"action": "status"}`,
 	}
 	
-	response, err := managementTestState.spaceFoundationTool.Run(ctx, toolCall)
+	response, err := state.spaceFoundationTool.Run(ctx, toolCall)
 	if err != nil {
 		return fmt.Errorf("failed to query space capabilities: %v", err)
 	}
@@ -546,19 +569,19 @@ func iQuerySpaceRelatedCapabilities() error {
 		return fmt.Errorf("failed to parse space capabilities: %v", err)
 	}
 	
-	managementTestState.spaceData = result
+	state.spaceData = result
 	return nil
 }
 
-func iShouldBeAbleToListBasicSpaceConfigurationOptions() error {
-	ctx := context.Background()
+func iShouldBeAbleToListBasicSpaceConfigurationOptions(ctx context.Context) error {
+	state := managementState(ctx)
 	toolCall := tools.ToolCall{
 		ID:    "test_space_config",
 		Name:  "space_foundation",
 		Input: `{"action": "config"}`,
 	}
 	
-	response, err := managementTestState.spaceFoundationTool.Run(ctx, toolCall)
+	response, err := state.spaceFoundationTool.Run(ctx, toolCall)
 	if err != nil {
 		return fmt.Errorf("failed to get space configuration options: %v", err)
 	}
@@ -583,13 +606,14 @@ func iShouldBeAbleToListBasicSpaceConfigurationOptions() error {
 	return nil
 }
 
-func iShouldBeAbleToReportSpaceReadinessStatus() error {
-	if len(managementTestState.spaceData) == 0 {
+func iShouldBeAbleToReportSpaceReadinessStatus(ctx context.Context) error {
+	state := managementState(ctx)
+	if len(state.spaceData) == 0 {
 		return fmt.Errorf("space data not available")
 	}
 	
 	// Verify space readiness is reported
-	if foundationReady, ok := managementTestState.spaceData["foundation_ready"]; ok {
+	if foundationReady, ok := state.spaceData["foundation_ready"]; ok {
 		if ready, ok := foundationReady.(bool); ok && ready {
 			return nil
 		}
@@ -598,15 +622,15 @@ func iShouldBeAbleToReportSpaceReadinessStatus() error {
 	return fmt.Errorf("space readiness status not properly reported")
 }
 
-func iShouldBeAbleToProvideGuidanceForFutureSpaceImplementation() error {
-	ctx := context.Background()
+func iShouldBeAbleToProvideGuidanceForFutureSpaceImplementation(ctx context.Context) error {
+	state := managementState(ctx)
 	toolCall := tools.ToolCall{
 		ID:    "test_space_guidance",
 		Name:  "space_foundation",
 		Input: `{"action": "guidance"}`,
 	}
 	
-	response, err := managementTestState.spaceFoundationTool.Run(ctx, toolCall)
+	response, err := state.spaceFoundationTool.Run(ctx, toolCall)
 	if err != nil {
 		return fmt.Errorf("failed to get space implementation guidance: %v", err)
 	}
@@ -629,4 +653,66 @@ func iShouldBeAbleToProvideGuidanceForFutureSpaceImplementation() error {
 	}
 	
 	return nil
-}
\ No newline at end of file
+}
+
+// Tool action scenario outlines
+func iRunTheAgentCoordinationToolWithTheAction(ctx context.Context, action string) error {
+	state := managementState(ctx)
+	input := map[string]interface{}{"action": action}
+	switch action {
+	case "plan":
+		input["task_description"] = "Implement new feature"
+		input["requirements"] = []string{"coding", "testing"}
+	case "delegate":
+		input["task_description"] = "Write unit tests"
+		input["preferred_agent"] = "coder"
+	}
+	return runManagementTool(ctx, state.agentCoordinationTool, "agent_coordination", input)
+}
+
+func iRunTheAgentLifecycleToolWithTheAction(ctx context.Context, action string) error {
+	state := managementState(ctx)
+	return runManagementTool(ctx, state.agentLifecycleTool, "agent_lifecycle", map[string]interface{}{"action": action})
+}
+
+// runManagementTool runs a management tool with the input, keeping its
+// response for the following steps
+func runManagementTool(ctx context.Context, tool tools.BaseTool, name string, input map[string]interface{}) error {
+	inputBytes, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Errorf("failed to serialize %s input: %v", name, err)
+	}
+
+	response, err := tool.Run(ctx, tools.ToolCall{
+		ID:    "test_" + name + "_action",
+		Name:  name,
+		Input: string(inputBytes),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to run %s: %v", name, err)
+	}
+
+	managementState(ctx).toolResponse = response
+	return nil
+}
+
+func theManagementToolShouldAnswerWithoutError(ctx context.Context) error {
+	state := managementState(ctx)
+	if state.toolResponse.IsError {
+		return fmt.Errorf("management tool returned error: %s", state.toolResponse.Content)
+	}
+	return nil
+}
+
+func theManagementToolResponseShouldInclude(ctx context.Context, field string) error {
+	state := managementState(ctx)
+	var result map[string]interface{}
+	if err := json.Unmarshal([]byte(state.toolResponse.Content), &result); err != nil {
+		return fmt.Errorf("failed to parse management tool response: %v", err)
+	}
+
+	if _, ok := result[field]; !ok {
+		return fmt.Errorf("management tool response is missing %q: %s", field, state.toolResponse.Content)
+	}
+	return nil
+}
@@ -3,7 +3,7 @@ package support
 import (
 	"context"
 	"fmt"
-	"os"
+	"strings"
 
 	"github.com/cucumber/godog"
 	"github.com/caronex/intelligence-interface/internal/agents/caronex"
@@ -21,22 +21,36 @@ import (
 type CaronexTestState struct {
 	config          *config.Config
 	caronexAgent    *caronex.CaronexAgent
+	coordinationManager *coordination.Manager
 	systemState     *caronex.SystemState
 	agentRegistry   map[config.AgentName]*caronex.AgentInfo
 	introspectionResult *coordination.SystemIntrospectionResult
 	taskPlan        *coordination.TaskPlan
 	delegationResult *coordination.DelegationResult
-	tempDir         string
-	errors          []error
-	lastResponse    string
+	stepDelegations []*coordination.DelegationResult
+	taskDescription string
+	requirements    []string
 }
 
-var caronexTestState = &CaronexTestState{
-	errors: make([]error, 0),
+// caronexStateKey is the context key holding the per-scenario CaronexTestState
+type caronexStateKey struct{}
+
+// implementationAgents are added to the test configuration so the
+// coordination steps have specialized agents to plan and delegate against
+var implementationAgents = []config.AgentName{"coder", "task", "summarizer"}
+
+// caronexState returns the state of the running scenario
+func caronexState(ctx context.Context) *CaronexTestState {
+	return ctx.Value(caronexStateKey{}).(*CaronexTestState)
 }
 
 // Register Caronex step definitions
 func RegisterCaronexSteps(ctx *godog.ScenarioContext) {
+	// Each scenario gets its own state so scenarios can run in parallel
+	ctx.Before(func(ctx context.Context, sc *godog.Scenario) (context.Context, error) {
+		return context.WithValue(ctx, caronexStateKey{}, &CaronexTestState{}), nil
+	})
+
 	// Background and setup steps
 	ctx.Step(`^the Intelligence Interface has a complete meta-system foundation$`, theIntelligenceInterfaceHasCompleteMetaSystemFoundation)
 	ctx.Step(`^the configuration system supports Caronex agent specialization$`, theConfigurationSystemSupportsCaronexAgentSpecialization)
@@ -91,44 +105,63 @@ func RegisterCaronexSteps(ctx *godog.ScenarioContext) {
 	ctx.Step(`^Caronex should support bootstrap compiler integration for self-improvement$`, caronexShouldSupportBootstrapCompilerIntegrationForSelfImprovement)
 }
 
-// Background and setup step implementations
-func theIntelligenceInterfaceHasCompleteMetaSystemFoundation() error {
-	// Set up test environment
-	os.Setenv("OPENAI_API_KEY", "test-key-caronex")
-
-	tempDir, err := os.MkdirTemp("", "caronex-test-*")
-	if err != nil {
-		return fmt.Errorf("failed to create temp directory: %w", err)
+// setUpFoundation loads the configuration for the scenario once
+func setUpFoundation(state *CaronexTestState) error {
+	if state.config != nil {
+		return nil
 	}
-	caronexTestState.tempDir = tempDir
 
-	// Load configuration
-	cfg, err := config.Load(tempDir, false)
+	// The loaded config is process-wide, so the scenario works on its own
+	// copy and never mutates the shared one
+	cfg, err := LoadConfig()
 	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
+		return err
 	}
-	caronexTestState.config = cfg
+	state.config = scenarioConfig(cfg)
 
 	return nil
 }
 
-func theConfigurationSystemSupportsCaronexAgentSpecialization() error {
-	// Verify Caronex agent is configured
-	_, exists := caronexTestState.config.Agents[config.AgentCaronex]
-	if !exists {
-		return fmt.Errorf("Caronex agent not found in configuration")
+// scenarioConfig returns a copy of cfg whose agents the scenario can change
+func scenarioConfig(cfg *config.Config) *config.Config {
+	scoped := *cfg
+	scoped.Agents = make(map[config.AgentName]config.Agent, len(cfg.Agents))
+	for name, agent := range cfg.Agents {
+		scoped.Agents[name] = agent
 	}
-	return nil
+	return &scoped
 }
 
-func theBaseAgentFrameworkIsAvailableForExtension() error {
-	// This step verifies the base agent framework exists (which it does)
+// ensureAgent creates the Caronex agent and coordination manager for the scenario
+func ensureAgent(state *CaronexTestState) error {
+	if err := setUpFoundation(state); err != nil {
+		return err
+	}
+	if state.caronexAgent != nil {
+		return nil
+	}
+
+	agent, err := NewCaronexAgent(state.config)
+	if err != nil {
+		return err
+	}
+
+	manager, err := coordination.NewManager(state.config)
+	if err != nil {
+		return fmt.Errorf("failed to create coordination manager: %w", err)
+	}
+
+	state.caronexAgent = agent
+	state.coordinationManager = manager
+	state.systemState = agent.GetSystemState()
+	state.agentRegistry = agent.GetAgentRegistry()
+
 	return nil
 }
 
-// Scenario 1 step implementations
-func iCreateTheCaronexManagerAgentExtendingTheBaseAgentFramework() error {
-	// Create mock session service and message service for testing
+// NewCaronexAgent creates a Caronex agent for cfg whose sessions, messages
+// and provider are in-memory stand-ins
+func NewCaronexAgent(cfg *config.Config) (*caronex.CaronexAgent, error) {
 	sessionService := &mockSessionService{
 		Broker: pubsub.NewBroker[session.Session](),
 	}
@@ -136,39 +169,66 @@ func iCreateTheCaronexManagerAgentExtendingTheBaseAgentFramework() error {
 		Broker: pubsub.NewBroker[message.Message](),
 	}
 
-	// Create Caronex agent
-	agent, err := caronex.NewCaronexAgent(caronexTestState.config, sessionService, messageService)
+	agent, err := caronex.NewCaronexAgent(cfg, sessionService, messageService, &mockProviderFactory{})
 	if err != nil {
-		return fmt.Errorf("failed to create CaronexAgent: %w", err)
+		return nil, fmt.Errorf("failed to create CaronexAgent: %w", err)
 	}
+	return agent, nil
+}
 
-	caronexTestState.caronexAgent = agent
-	caronexTestState.systemState = agent.GetSystemState()
-	caronexTestState.agentRegistry = agent.GetAgentRegistry()
+// Background and setup step implementations
+func theIntelligenceInterfaceHasCompleteMetaSystemFoundation(ctx context.Context) error {
+	return setUpFoundation(caronexState(ctx))
+}
 
+func theConfigurationSystemSupportsCaronexAgentSpecialization(ctx context.Context) error {
+	state := caronexState(ctx)
+
+	// Verify Caronex agent is configured
+	agent, exists := state.config.Agents[config.AgentCaronex]
+	if !exists {
+		return fmt.Errorf("Caronex agent not found in configuration")
+	}
+	if _, supported := models.SupportedModels[agent.Model]; !supported {
+		return fmt.Errorf("Caronex agent configured with unsupported model %q", agent.Model)
+	}
+	return nil
+}
+
+func theBaseAgentFrameworkIsAvailableForExtension(ctx context.Context) error {
+	if config.Get() == nil {
+		return fmt.Errorf("base agents require a loaded configuration")
+	}
 	return nil
 }
 
-func caronexShouldBeProperlyConfiguredAsSpecializedManagerAgent() error {
-	if caronexTestState.caronexAgent == nil {
+// Scenario 1 step implementations
+func iCreateTheCaronexManagerAgentExtendingTheBaseAgentFramework(ctx context.Context) error {
+	return ensureAgent(caronexState(ctx))
+}
+
+func caronexShouldBeProperlyConfiguredAsSpecializedManagerAgent(ctx context.Context) error {
+	state := caronexState(ctx)
+	if state.caronexAgent == nil {
 		return fmt.Errorf("CaronexAgent was not created")
 	}
 
 	// Verify it's configured as a manager agent
-	if !caronexTestState.caronexAgent.IsManagerAgent() {
+	if !state.caronexAgent.IsManagerAgent() {
 		return fmt.Errorf("CaronexAgent is not configured as a manager agent")
 	}
 
 	return nil
 }
 
-func caronexShouldHaveManagerSpecificPersonalityAndCapabilities() error {
-	if caronexTestState.caronexAgent == nil {
+func caronexShouldHaveManagerSpecificPersonalityAndCapabilities(ctx context.Context) error {
+	state := caronexState(ctx)
+	if state.caronexAgent == nil {
 		return fmt.Errorf("CaronexAgent was not created")
 	}
 
 	// Check manager personality
-	personality := caronexTestState.caronexAgent.GetManagerPersonality()
+	personality := state.caronexAgent.GetManagerPersonality()
 	if personality == nil {
 		return fmt.Errorf("Manager personality not configured")
 	}
@@ -182,7 +242,7 @@ func caronexShouldHaveManagerSpecificPersonalityAndCapabilities() error {
 	}
 
 	// Check coordination capabilities
-	capabilities := caronexTestState.caronexAgent.GetCoordinationCapabilities()
+	capabilities := state.caronexAgent.GetCoordinationCapabilities()
 	if len(capabilities) == 0 {
 		return fmt.Errorf("No coordination capabilities found")
 	}
@@ -190,37 +250,57 @@ func caronexShouldHaveManagerSpecificPersonalityAndCapabilities() error {
 	return nil
 }
 
-func caronexShouldIntegrateWithExistingAgentInfrastructure() error {
-	if caronexTestState.agentRegistry == nil {
+func caronexShouldIntegrateWithExistingAgentInfrastructure(ctx context.Context) error {
+	state := caronexState(ctx)
+	if state.agentRegistry == nil {
 		return fmt.Errorf("Agent registry not initialized")
 	}
 
-	// Verify other agents are registered
-	if len(caronexTestState.agentRegistry) == 0 {
-		return fmt.Errorf("No agents registered in the system")
+	// The registry should hold every configured agent except Caronex itself
+	if _, exists := state.agentRegistry[config.AgentCaronex]; exists {
+		return fmt.Errorf("Caronex should not register itself as a delegation target")
+	}
+	if len(state.agentRegistry) != len(state.config.Agents)-1 {
+		return fmt.Errorf("expected %d registered agents, found %d", len(state.config.Agents)-1, len(state.agentRegistry))
+	}
+
+	for name, agentConfig := range state.config.Agents {
+		if name == config.AgentCaronex {
+			continue
+		}
+		info, exists := state.agentRegistry[name]
+		if !exists {
+			return fmt.Errorf("configured agent %s missing from registry", name)
+		}
+		if info.Name != name {
+			return fmt.Errorf("registry entry %s has name %s", name, info.Name)
+		}
+		if info.Model != agentConfig.Model {
+			return fmt.Errorf("agent %s registered with model %s, configured with %s", name, info.Model, agentConfig.Model)
+		}
+		if info.Status != caronex.AgentStatusAvailable {
+			return fmt.Errorf("agent %s should be available, got %s", name, info.Status)
+		}
+		if info.Specialization != agentConfig.Specialization {
+			return fmt.Errorf("agent %s specialization does not match configuration", name)
+		}
 	}
 
 	return nil
 }
 
-func caronexShouldHaveCoordinationFocusedConfigurationSettings() error {
-	if caronexTestState.caronexAgent == nil {
+func caronexShouldHaveCoordinationFocusedConfigurationSettings(ctx context.Context) error {
+	state := caronexState(ctx)
+	if state.caronexAgent == nil {
 		return fmt.Errorf("CaronexAgent was not created")
 	}
 
 	// Check that Caronex has coordination capabilities
-	capabilities := caronexTestState.caronexAgent.GetCoordinationCapabilities()
-	
+	capabilities := state.caronexAgent.GetCoordinationCapabilities()
+
 	expectedCapabilities := []string{"system_introspection", "agent_coordination", "task_planning"}
 	for _, expected := range expectedCapabilities {
-		found := false
-		for _, capability := range capabilities {
-			if capability == expected {
-				found = true
-				break
-			}
-		}
-		if !found {
+		if !contains(capabilities, expected) {
 			return fmt.Errorf("missing coordination capability: %s", expected)
 		}
 	}
@@ -228,17 +308,18 @@ func caronexShouldHaveCoordinationFocusedConfigurationSettings() error {
 	return nil
 }
 
-func caronexShouldBeDistinguishableFromImplementationAgents() error {
-	if caronexTestState.caronexAgent == nil {
+func caronexShouldBeDistinguishableFromImplementationAgents(ctx context.Context) error {
+	state := caronexState(ctx)
+	if state.caronexAgent == nil {
 		return fmt.Errorf("CaronexAgent was not created")
 	}
 
 	// Verify manager vs implementer distinction
-	if !caronexTestState.caronexAgent.IsManagerAgent() {
+	if !state.caronexAgent.IsManagerAgent() {
 		return fmt.Errorf("CaronexAgent should be identified as a manager agent")
 	}
 
-	if caronexTestState.caronexAgent.ShouldImplementDirectly() {
+	if state.caronexAgent.ShouldImplementDirectly() {
 		return fmt.Errorf("CaronexAgent should not implement directly")
 	}
 
@@ -246,47 +327,44 @@ func caronexShouldBeDistinguishableFromImplementationAgents() error {
 }
 
 // Scenario 2 step implementations
-func iAmInteractingWithCaronexManager() error {
-	if caronexTestState.caronexAgent == nil {
-		return fmt.Errorf("CaronexAgent not available for interaction")
-	}
-	return nil
+func iAmInteractingWithCaronexManager(ctx context.Context) error {
+	return ensureAgent(caronexState(ctx))
 }
 
-func theSystemHasMultipleAgentsAvailable() error {
-	if len(caronexTestState.agentRegistry) < 2 {
-		return fmt.Errorf("expected multiple agents, found %d", len(caronexTestState.agentRegistry))
+func theSystemHasMultipleAgentsAvailable(ctx context.Context) error {
+	state := caronexState(ctx)
+	if err := ensureAgent(state); err != nil {
+		return err
+	}
+	if len(state.agentRegistry) < 2 {
+		return fmt.Errorf("expected multiple agents, found %d", len(state.agentRegistry))
 	}
 	return nil
 }
 
-func iAskAboutSystemCapabilitiesAndCurrentState() error {
-	if caronexTestState.caronexAgent == nil {
+func iAskAboutSystemCapabilitiesAndCurrentState(ctx context.Context) error {
+	state := caronexState(ctx)
+	if state.coordinationManager == nil {
 		return fmt.Errorf("CaronexAgent not available")
 	}
 
-	// Create coordination manager directly for testing
-	coordinationManager, err := coordination.NewManager(caronexTestState.config)
-	if err != nil {
-		return fmt.Errorf("failed to create coordination manager: %w", err)
-	}
-
 	// Get system introspection
-	result, err := coordinationManager.GetSystemIntrospection()
+	result, err := state.coordinationManager.GetSystemIntrospection(context.Background())
 	if err != nil {
 		return fmt.Errorf("failed to get system introspection: %w", err)
 	}
 
-	caronexTestState.introspectionResult = result
+	state.introspectionResult = result
 	return nil
 }
 
-func caronexShouldProvideAccurateSystemInformation() error {
-	if caronexTestState.introspectionResult == nil {
+func caronexShouldProvideAccurateSystemInformation(ctx context.Context) error {
+	state := caronexState(ctx)
+	if state.introspectionResult == nil {
 		return fmt.Errorf("system introspection result not available")
 	}
 
-	result := caronexTestState.introspectionResult
+	result := state.introspectionResult
 	if result.SystemStatus != "operational" {
 		return fmt.Errorf("expected operational system status, got %s", result.SystemStatus)
 	}
@@ -298,18 +376,26 @@ func caronexShouldProvideAccurateSystemInformation() error {
 	return nil
 }
 
-func caronexShouldListAvailableAgentsAndTheirSpecializations() error {
-	if caronexTestState.introspectionResult == nil {
+func caronexShouldListAvailableAgentsAndTheirSpecializations(ctx context.Context) error {
+	state := caronexState(ctx)
+	if state.introspectionResult == nil {
 		return fmt.Errorf("system introspection result not available")
 	}
 
-	result := caronexTestState.introspectionResult
-	if len(result.AvailableAgents) == 0 {
-		return fmt.Errorf("no available agents listed")
+	result := state.introspectionResult
+	if len(result.AvailableAgents) != len(state.config.Agents) {
+		return fmt.Errorf("expected %d listed agents, got %d", len(state.config.Agents), len(result.AvailableAgents))
 	}
 
-	// Check that agents have capabilities listed
+	// Check that each listed agent matches its configuration
 	for _, agent := range result.AvailableAgents {
+		agentConfig, exists := state.config.Agents[agent.Name]
+		if !exists {
+			return fmt.Errorf("listed agent %s is not configured", agent.Name)
+		}
+		if agent.Model != agentConfig.Model {
+			return fmt.Errorf("agent %s listed with model %s, configured with %s", agent.Name, agent.Model, agentConfig.Model)
+		}
 		if len(agent.Capabilities) == 0 {
 			return fmt.Errorf("agent %s has no capabilities listed", agent.Name)
 		}
@@ -318,62 +404,460 @@ func caronexShouldListAvailableAgentsAndTheirSpecializations() error {
 	return nil
 }
 
-func caronexShouldReportCurrentSystemConfiguration() error {
-	if caronexTestState.introspectionResult == nil {
+func caronexShouldReportCurrentSystemConfiguration(ctx context.Context) error {
+	state := caronexState(ctx)
+	if state.introspectionResult == nil {
 		return fmt.Errorf("system introspection result not available")
 	}
 
-	result := caronexTestState.introspectionResult
-	if result.SystemConfig.AgentCount == 0 {
-		return fmt.Errorf("system configuration shows no agents")
+	summary := state.introspectionResult.SystemConfig
+	if summary.AgentCount != len(state.config.Agents) {
+		return fmt.Errorf("system configuration reports %d agents, expected %d", summary.AgentCount, len(state.config.Agents))
+	}
+	if summary.EvolutionEnabled != state.config.Caronex.Evolution.Enabled {
+		return fmt.Errorf("system configuration reports evolution enabled=%t, expected %t", summary.EvolutionEnabled, state.config.Caronex.Evolution.Enabled)
 	}
 
 	return nil
 }
 
-func caronexShouldHelpPlanImplementationApproaches() error {
-	// Test task planning capability
-	if caronexTestState.caronexAgent == nil {
+func caronexShouldHelpPlanImplementationApproaches(ctx context.Context) error {
+	state := caronexState(ctx)
+	if state.coordinationManager == nil {
 		return fmt.Errorf("CaronexAgent not available")
 	}
 
-	// Create coordination manager directly for testing
-	coordinationManager, err := coordination.NewManager(caronexTestState.config)
+	taskPlan, err := state.coordinationManager.CreateTaskPlan(context.Background(), "implement feature X", []string{"requirement A", "requirement B"}, "")
 	if err != nil {
-		return fmt.Errorf("failed to create coordination manager: %w", err)
+		return fmt.Errorf("failed to create task plan: %w", err)
+	}
+
+	state.taskPlan = taskPlan
+	return checkPlanStructure(taskPlan, state.config)
+}
+
+func caronexShouldCoordinateWithAppropriateSpecializedAgents(ctx context.Context) error {
+	state := caronexState(ctx)
+	if state.taskPlan == nil {
+		return fmt.Errorf("task plan not available")
+	}
+
+	// Verify configured agents are assigned to steps
+	for _, step := range state.taskPlan.Steps {
+		if _, exists := state.config.Agents[config.AgentName(step.AssignedAgent)]; !exists {
+			return fmt.Errorf("step %s assigned to unconfigured agent %q", step.StepID, step.AssignedAgent)
+		}
 	}
 
-	taskPlan, err := coordinationManager.CreateTaskPlan("implement feature X", []string{"requirement A", "requirement B"})
+	return nil
+}
+
+// Scenario 3 step implementations
+func iRequestSpecificImplementationTask(ctx context.Context) error {
+	state := caronexState(ctx)
+	state.taskDescription = "implement a configuration loader"
+	state.requirements = []string{"parse JSON configuration", "validate required fields"}
+	return nil
+}
+
+func caronexManagerIsAvailableForCoordination(ctx context.Context) error {
+	return ensureAgent(caronexState(ctx))
+}
+
+func iCommunicateWithCaronexAboutTheImplementation(ctx context.Context) error {
+	state := caronexState(ctx)
+
+	plan, err := state.coordinationManager.CreateTaskPlan(context.Background(), state.taskDescription, state.requirements, "")
 	if err != nil {
 		return fmt.Errorf("failed to create task plan: %w", err)
 	}
+	state.taskPlan = plan
 
-	caronexTestState.taskPlan = taskPlan
-	
-	if len(taskPlan.Steps) == 0 {
-		return fmt.Errorf("task plan has no steps")
+	delegation, err := state.coordinationManager.DelegateTask(context.Background(), plan.TaskID, state.taskDescription, "", false)
+	if err != nil {
+		return fmt.Errorf("failed to delegate task: %w", err)
 	}
+	state.delegationResult = delegation
 
 	return nil
 }
 
-func caronexShouldCoordinateWithAppropriateSpecializedAgents() error {
-	if caronexTestState.taskPlan == nil {
-		return fmt.Errorf("task plan not available")
+func caronexShouldFocusOnPlanningAndCoordination(ctx context.Context) error {
+	state := caronexState(ctx)
+
+	capabilities := state.caronexAgent.GetCoordinationCapabilities()
+	for _, expected := range []string{"task_planning", "agent_coordination"} {
+		if !contains(capabilities, expected) {
+			return fmt.Errorf("missing coordination capability: %s", expected)
+		}
+	}
+
+	// Planning comes before any implementation
+	if len(state.taskPlan.Steps) == 0 || len(state.taskPlan.Steps[0].Dependencies) != 0 {
+		return fmt.Errorf("task plan should start with an independent planning step")
+	}
+
+	return nil
+}
+
+func caronexShouldNotAttemptDirectImplementation(ctx context.Context) error {
+	state := caronexState(ctx)
+
+	if state.caronexAgent.ShouldImplementDirectly() {
+		return fmt.Errorf("CaronexAgent should not implement directly")
+	}
+	for _, step := range state.taskPlan.Steps {
+		if step.AssignedAgent == string(config.AgentCaronex) {
+			return fmt.Errorf("step %s is assigned to Caronex itself", step.StepID)
+		}
+	}
+	if state.delegationResult.AssignedTo == string(config.AgentCaronex) {
+		return fmt.Errorf("implementation task was delegated to Caronex itself")
+	}
+
+	return nil
+}
+
+func caronexShouldDelegateToAppropriateImplementationAgents(ctx context.Context) error {
+	state := caronexState(ctx)
+	delegation := state.delegationResult
+
+	if delegation.Status != "delegated" {
+		return fmt.Errorf("expected delegated status, got %s", delegation.Status)
+	}
+	if delegation.TaskID != state.taskPlan.TaskID {
+		return fmt.Errorf("delegation task ID %s does not match plan %s", delegation.TaskID, state.taskPlan.TaskID)
+	}
+	if delegation.AssignedTo != "coder" {
+		return fmt.Errorf("implementation task should be delegated to coder, got %s", delegation.AssignedTo)
+	}
+	if _, exists := state.agentRegistry[config.AgentName(delegation.AssignedTo)]; !exists {
+		return fmt.Errorf("task delegated to unregistered agent %s", delegation.AssignedTo)
+	}
+
+	return nil
+}
+
+func caronexShouldProvideClearTaskBreakdownAndCoordinationPlans(ctx context.Context) error {
+	state := caronexState(ctx)
+	plan := state.taskPlan
+
+	if plan.Description != state.taskDescription {
+		return fmt.Errorf("plan description %q does not match request %q", plan.Description, state.taskDescription)
+	}
+	// One planning step, plus an implementation step when there are requirements
+	if len(plan.Steps) != 2 {
+		return fmt.Errorf("expected planning and implementation steps, got %d steps", len(plan.Steps))
+	}
+	if plan.EstimatedDuration == "" {
+		return fmt.Errorf("task plan has no estimated duration")
+	}
+
+	return checkPlanStructure(plan, state.config)
+}
+
+func caronexShouldMaintainClearBoundariesBetweenManagementAndImplementation(ctx context.Context) error {
+	state := caronexState(ctx)
+
+	personality := state.caronexAgent.GetManagerPersonality()
+	if !personality.ImplementationBoundary || !personality.HelpfulButDelegating {
+		return fmt.Errorf("manager personality should delegate implementation")
+	}
+	if !state.caronexAgent.IsManagerAgent() || state.caronexAgent.ShouldImplementDirectly() {
+		return fmt.Errorf("CaronexAgent should manage rather than implement")
+	}
+
+	return nil
+}
+
+// Scenario 4 step implementations
+func caronexNeedsToCoordinateMultipleAgentsForComplexTask(ctx context.Context) error {
+	state := caronexState(ctx)
+	if err := ensureAgent(state); err != nil {
+		return err
+	}
+	state.taskDescription = "implement and document a plugin system"
+	state.requirements = []string{"plugin discovery", "plugin lifecycle hooks", "developer documentation"}
+	return nil
+}
+
+func theSystemHasVariousSpecializedAgentsAvailable(ctx context.Context) error {
+	state := caronexState(ctx)
+	for _, name := range implementationAgents {
+		if _, exists := state.agentRegistry[name]; !exists {
+			return fmt.Errorf("specialized agent %s is not registered", name)
+		}
+	}
+	return nil
+}
+
+func iRequestMultiStepImplementationRequiringAgentCoordination(ctx context.Context) error {
+	state := caronexState(ctx)
+
+	plan, err := state.coordinationManager.CreateTaskPlan(context.Background(), state.taskDescription, state.requirements, "")
+	if err != nil {
+		return fmt.Errorf("failed to create task plan: %w", err)
+	}
+	state.taskPlan = plan
+
+	// Delegate every step to the agent the plan assigned it to
+	for _, step := range plan.Steps {
+		delegation, err := state.coordinationManager.DelegateTask(context.Background(), plan.TaskID+"/"+step.StepID, step.Description, step.AssignedAgent, step.RequiresTools)
+		if err != nil {
+			return fmt.Errorf("failed to delegate %s: %w", step.StepID, err)
+		}
+		state.stepDelegations = append(state.stepDelegations, delegation)
+	}
+
+	return nil
+}
+
+func caronexShouldIdentifyAppropriateAgentsForEachStep(ctx context.Context) error {
+	state := caronexState(ctx)
+	plan := state.taskPlan
+
+	assigned := make(map[string]bool)
+	for _, step := range plan.Steps {
+		if _, exists := state.agentRegistry[config.AgentName(step.AssignedAgent)]; !exists {
+			return fmt.Errorf("step %s assigned to unregistered agent %q", step.StepID, step.AssignedAgent)
+		}
+		assigned[step.AssignedAgent] = true
+	}
+
+	if len(plan.RequiredAgents) != len(assigned) {
+		return fmt.Errorf("plan requires %v but steps are assigned to %d agents", plan.RequiredAgents, len(assigned))
+	}
+	for _, agent := range plan.RequiredAgents {
+		if !assigned[agent] {
+			return fmt.Errorf("required agent %s has no assigned step", agent)
+		}
+	}
+
+	return nil
+}
+
+func caronexShouldCoordinateAgentInteractionsAndDependencies(ctx context.Context) error {
+	state := caronexState(ctx)
+	plan := state.taskPlan
+
+	// Steps may only depend on steps that come before them
+	seen := make(map[string]bool)
+	var dependencies []string
+	for _, step := range plan.Steps {
+		for _, dependency := range step.Dependencies {
+			if !seen[dependency] {
+				return fmt.Errorf("step %s depends on %s, which does not precede it", step.StepID, dependency)
+			}
+		}
+		dependencies = append(dependencies, step.Dependencies...)
+		seen[step.StepID] = true
+	}
+
+	if len(dependencies) == 0 {
+		return fmt.Errorf("multi-step plan should have dependencies between steps")
+	}
+	if len(plan.Dependencies) != len(dependencies) {
+		return fmt.Errorf("plan lists %d dependencies, steps declare %d", len(plan.Dependencies), len(dependencies))
+	}
+
+	return nil
+}
+
+func caronexShouldMonitorProgressAndProvideStatusUpdates(ctx context.Context) error {
+	state := caronexState(ctx)
+
+	for _, step := range state.taskPlan.Steps {
+		if step.Status != "pending" {
+			return fmt.Errorf("new step %s should be pending, got %s", step.StepID, step.Status)
+		}
+	}
+	for _, delegation := range state.stepDelegations {
+		if delegation.Status != "delegated" {
+			return fmt.Errorf("delegation %s should be delegated, got %s", delegation.TaskID, delegation.Status)
+		}
+		if !delegation.ExpectedCompletion.After(delegation.CreatedAt) {
+			return fmt.Errorf("delegation %s has no expected completion", delegation.TaskID)
+		}
+	}
+
+	return nil
+}
+
+func caronexShouldHandleAgentCommunicationProtocols(ctx context.Context) error {
+	state := caronexState(ctx)
+
+	protocol := state.config.Caronex.Coordination.CommunicationProtocol
+	if !contains([]string{"pubsub", "direct", "queue"}, protocol) {
+		return fmt.Errorf("unsupported communication protocol %q", protocol)
 	}
 
-	// Verify agents are assigned to steps
-	for _, step := range caronexTestState.taskPlan.Steps {
-		if step.AssignedAgent == "" {
-			return fmt.Errorf("step %s has no assigned agent", step.StepID)
+	return nil
+}
+
+func caronexShouldEnsureTaskCompletionThroughProperDelegation(ctx context.Context) error {
+	state := caronexState(ctx)
+
+	if len(state.stepDelegations) != len(state.taskPlan.Steps) {
+		return fmt.Errorf("expected %d delegations, got %d", len(state.taskPlan.Steps), len(state.stepDelegations))
+	}
+	for i, step := range state.taskPlan.Steps {
+		if state.stepDelegations[i].AssignedTo != step.AssignedAgent {
+			return fmt.Errorf("step %s was delegated to %s instead of %s", step.StepID, state.stepDelegations[i].AssignedTo, step.AssignedAgent)
 		}
 	}
 
 	return nil
 }
 
-// Additional step implementations would continue here...
-// For brevity, I'll implement the most critical ones and add placeholders for others
+// Scenario 5 step implementations
+func caronexHasAccessToSystemConfigurationAndState(ctx context.Context) error {
+	state := caronexState(ctx)
+	if err := ensureAgent(state); err != nil {
+		return err
+	}
+	if len(state.systemState.AvailableAgents) != len(state.agentRegistry) {
+		return fmt.Errorf("system state lists %d agents, registry has %d", len(state.systemState.AvailableAgents), len(state.agentRegistry))
+	}
+	if len(state.systemState.AgentStatuses) == 0 {
+		return fmt.Errorf("system state should report agent statuses after initialization")
+	}
+	if history := state.caronexAgent.GetSystemStateHistory(); len(history) == 0 || history[len(history)-1] != state.systemState {
+		return fmt.Errorf("system state history should end with the current state")
+	}
+	return nil
+}
+
+func theMetaSystemSupportsEvolutionAndImprovement(ctx context.Context) error {
+	state := caronexState(ctx)
+
+	// Enable evolution on the scenario's own configuration
+	state.config.Caronex.Evolution.Enabled = true
+	if state.caronexAgent != nil {
+		state.caronexAgent.Close()
+	}
+	state.caronexAgent = nil
+	return ensureAgent(state)
+}
+
+func iRequestSystemEvolutionOrImprovementSuggestions(ctx context.Context) error {
+	state := caronexState(ctx)
+
+	result, err := state.coordinationManager.GetSystemIntrospection(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to get system introspection: %w", err)
+	}
+	state.introspectionResult = result
+
+	plan, err := state.coordinationManager.CreateTaskPlan(context.Background(), "implement system improvement", []string{"improve agent coordination"}, "")
+	if err != nil {
+		return fmt.Errorf("failed to create task plan: %w", err)
+	}
+	state.taskPlan = plan
+
+	return nil
+}
+
+func caronexShouldAnalyzeCurrentSystemCapabilities(ctx context.Context) error {
+	state := caronexState(ctx)
+
+	for _, expected := range []string{"multi_agent_coordination", "system_introspection", "system_evolution"} {
+		if !contains(state.introspectionResult.SystemCapabilities, expected) {
+			return fmt.Errorf("missing system capability: %s", expected)
+		}
+	}
+	if !state.introspectionResult.SystemConfig.EvolutionEnabled {
+		return fmt.Errorf("introspection should report evolution as enabled")
+	}
+
+	return nil
+}
+
+func caronexShouldProvideEvolutionRecommendations(ctx context.Context) error {
+	state := caronexState(ctx)
+
+	if !state.systemState.EvolutionEnabled {
+		return fmt.Errorf("system state should report evolution as enabled")
+	}
+	if !contains(state.caronexAgent.GetCoordinationCapabilities(), "system_evolution_planning") {
+		return fmt.Errorf("Caronex should offer system evolution planning")
+	}
+	if !strings.Contains(state.caronexAgent.GetEvolutionPrompt(), "Evolution capabilities: enabled") {
+		return fmt.Errorf("evolution prompt should report evolution as enabled")
+	}
+
+	return nil
+}
+
+func caronexShouldCoordinateSystemImprovementImplementations(ctx context.Context) error {
+	state := caronexState(ctx)
+	if err := checkPlanStructure(state.taskPlan, state.config); err != nil {
+		return err
+	}
+	for _, step := range state.taskPlan.Steps {
+		if step.AssignedAgent == string(config.AgentCaronex) {
+			return fmt.Errorf("improvement step %s is assigned to Caronex itself", step.StepID)
+		}
+	}
+	return nil
+}
+
+func caronexShouldMaintainSystemStabilityDuringEvolution(ctx context.Context) error {
+	state := caronexState(ctx)
+
+	evolution := state.config.Caronex.Evolution
+	if !evolution.SafetyChecksEnabled {
+		return fmt.Errorf("evolution safety checks should be enabled")
+	}
+	if !evolution.RollbackCapability {
+		return fmt.Errorf("evolution rollback capability should be enabled")
+	}
+
+	// Improvements are planned before they are implemented
+	for _, step := range state.taskPlan.Steps[1:] {
+		if len(step.Dependencies) == 0 {
+			return fmt.Errorf("improvement step %s does not wait for planning", step.StepID)
+		}
+	}
+	return nil
+}
+
+func caronexShouldSupportBootstrapCompilerIntegrationForSelfImprovement(ctx context.Context) error {
+	state := caronexState(ctx)
+	if !contains(state.introspectionResult.SystemCapabilities, "bootstrap_compilation") {
+		return fmt.Errorf("bootstrap compilation should be available when evolution is enabled")
+	}
+	return nil
+}
+
+// checkPlanStructure verifies every step of a plan is complete and assigned to a configured agent
+func checkPlanStructure(plan *coordination.TaskPlan, cfg *config.Config) error {
+	if plan.TaskID == "" {
+		return fmt.Errorf("task plan has no ID")
+	}
+	if len(plan.Steps) == 0 {
+		return fmt.Errorf("task plan has no steps")
+	}
+
+	for _, step := range plan.Steps {
+		if step.StepID == "" || step.Description == "" || step.EstimatedTime == "" {
+			return fmt.Errorf("task plan step %+v is incomplete", step)
+		}
+		if _, exists := cfg.Agents[config.AgentName(step.AssignedAgent)]; !exists {
+			return fmt.Errorf("step %s assigned to unconfigured agent %q", step.StepID, step.AssignedAgent)
+		}
+	}
+
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
 
 // Mock implementations for testing
 type mockSessionService struct{
@@ -392,6 +876,10 @@ func (m *mockSessionService) CreateTaskSession(ctx context.Context, toolCallID,
 	return session.Session{ID: "test-session"}, nil
 }
 
+func (m *mockSessionService) CreateBranchSession(ctx context.Context, parentSessionID, title string) (session.Session, error) {
+	return session.Session{ID: "test-session"}, nil
+}
+
 func (m *mockSessionService) Get(ctx context.Context, id string) (session.Session, error) {
 	return session.Session{ID: id}, nil
 }
@@ -408,16 +896,40 @@ func (m *mockSessionService) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+func (m *mockSessionService) Find(ctx context.Context, filter session.Filter) ([]session.Session, error) {
+	return []session.Session{}, nil
+}
+
+func (m *mockSessionService) Archive(ctx context.Context, ids []string, archived bool) (int, error) {
+	return len(ids), nil
+}
+
+func (m *mockSessionService) DeleteMany(ctx context.Context, ids []string, dryRun bool) (session.DeleteReport, error) {
+	return session.DeleteReport{Sessions: len(ids)}, nil
+}
+
+func (m *mockSessionService) ConfigChanges(ctx context.Context, session session.Session) ([]config.ConfigChange, error) {
+	return nil, nil
+}
+
 type mockProviderFactory struct{}
 
-func (m *mockProviderFactory) CreateProvider(modelID string) (provider.Provider, error) {
-	return &mockProvider{}, nil
+var _ provider.ProviderFactory = (*mockProviderFactory)(nil)
+
+func (m *mockProviderFactory) NewProvider(modelID models.ModelID, cfg config.Provider, opts ...provider.ProviderClientOption) (provider.Provider, error) {
+	return &mockProvider{model: models.SupportedModels[modelID]}, nil
 }
 
-type mockProvider struct{}
+type mockProvider struct {
+	model models.Model
+}
 
 func (m *mockProvider) Model() models.Model {
-	return models.Model{}
+	return m.model
+}
+
+func (m *mockProvider) Close() error {
+	return nil
 }
 
 func (m *mockProvider) SendMessages(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*provider.ProviderResponse, error) {
@@ -461,36 +973,18 @@ func (m *mockMessageService) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+func (m *mockMessageService) Move(ctx context.Context, id, sessionID string) error {
+	return nil
+}
+
 func (m *mockMessageService) DeleteSessionMessages(ctx context.Context, sessionID string) error {
 	return nil
 }
 
-type mockCaronexService struct {
-	coordinationTools *coordination.Manager
-}
-
-// Placeholder implementations for remaining steps
-func iRequestSpecificImplementationTask() error { return nil }
-func caronexManagerIsAvailableForCoordination() error { return nil }
-func iCommunicateWithCaronexAboutTheImplementation() error { return nil }
-func caronexShouldFocusOnPlanningAndCoordination() error { return nil }
-func caronexShouldNotAttemptDirectImplementation() error { return nil }
-func caronexShouldDelegateToAppropriateImplementationAgents() error { return nil }
-func caronexShouldProvideClearTaskBreakdownAndCoordinationPlans() error { return nil }
-func caronexShouldMaintainClearBoundariesBetweenManagementAndImplementation() error { return nil }
-func caronexNeedsToCoordinateMultipleAgentsForComplexTask() error { return nil }
-func theSystemHasVariousSpecializedAgentsAvailable() error { return nil }
-func iRequestMultiStepImplementationRequiringAgentCoordination() error { return nil }
-func caronexShouldIdentifyAppropriateAgentsForEachStep() error { return nil }
-func caronexShouldCoordinateAgentInteractionsAndDependencies() error { return nil }
-func caronexShouldMonitorProgressAndProvideStatusUpdates() error { return nil }
-func caronexShouldHandleAgentCommunicationProtocols() error { return nil }
-func caronexShouldEnsureTaskCompletionThroughProperDelegation() error { return nil }
-func caronexHasAccessToSystemConfigurationAndState() error { return nil }
-func theMetaSystemSupportsEvolutionAndImprovement() error { return nil }
-func iRequestSystemEvolutionOrImprovementSuggestions() error { return nil }
-func caronexShouldAnalyzeCurrentSystemCapabilities() error { return nil }
-func caronexShouldProvideEvolutionRecommendations() error { return nil }
-func caronexShouldCoordinateSystemImprovementImplementations() error { return nil }
-func caronexShouldMaintainSystemStabilityDuringEvolution() error { return nil }
-func caronexShouldSupportBootstrapCompilerIntegrationForSelfImprovement() error { return nil }
\ No newline at end of file
+func (m *mockMessageService) CitingSessions(ctx context.Context, source string) ([]string, error) {
+	return nil, nil
+}
+
+func (m *mockMessageService) Recover(ctx context.Context) ([]message.Recovery, error) {
+	return nil, nil
+}
@@ -0,0 +1,235 @@
+package support
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/db"
+	"github.com/caronex/intelligence-interface/internal/llm/agent"
+	"github.com/caronex/intelligence-interface/internal/llm/models"
+	"github.com/caronex/intelligence-interface/internal/llm/provider"
+	"github.com/caronex/intelligence-interface/internal/llm/tools"
+	"github.com/caronex/intelligence-interface/internal/message"
+	"github.com/caronex/intelligence-interface/internal/session"
+	"github.com/cucumber/godog"
+)
+
+// HandoffTestState holds the state for agent handoff BDD tests
+type HandoffTestState struct {
+	sessions session.Service
+	messages message.Service
+	parent   session.Session
+	files    []string
+	response tools.ToolResponse
+	record   agent.HandoffResponseMetadata
+	cleanup  []func()
+}
+
+// handoffStateKey is the context key holding the per-scenario HandoffTestState
+type handoffStateKey struct{}
+
+func handoffState(ctx context.Context) *HandoffTestState {
+	return ctx.Value(handoffStateKey{}).(*HandoffTestState)
+}
+
+// RegisterHandoffSteps registers the agent handoff step definitions
+func RegisterHandoffSteps(ctx *godog.ScenarioContext) {
+	ctx.Before(func(ctx context.Context, sc *godog.Scenario) (context.Context, error) {
+		return context.WithValue(ctx, handoffStateKey{}, &HandoffTestState{}), nil
+	})
+	ctx.After(func(ctx context.Context, sc *godog.Scenario, err error) (context.Context, error) {
+		state := handoffState(ctx)
+		for i := len(state.cleanup) - 1; i >= 0; i-- {
+			state.cleanup[i]()
+		}
+		return ctx, nil
+	})
+
+	ctx.Step(`^a conversation that discussed the file "([^"]*)"$`, aConversationThatDiscussedTheFile)
+	ctx.Step(`^the user stated the constraint "([^"]*)"$`, theUserStatedTheConstraint)
+	ctx.Step(`^the conversation mentions the file "([^"]*)"$`, theConversationMentionsTheFile)
+	ctx.Step(`^a task is delegated from the conversation$`, aTaskIsDelegatedFromTheConversation)
+	ctx.Step(`^the task is delegated again$`, aTaskIsDelegatedFromTheConversation)
+	ctx.Step(`^the delegated sub-session should start with a handoff context block$`, theSubSessionShouldStartWithAHandoffContextBlock)
+	ctx.Step(`^the handoff should reference the file "([^"]*)"$`, theHandoffShouldReferenceTheFile)
+	ctx.Step(`^the handoff should include the constraint "([^"]*)"$`, theHandoffShouldIncludeTheConstraint)
+	ctx.Step(`^the delegation result should record the handoff and the sub-session$`, theDelegationResultShouldRecordTheHandoff)
+}
+
+// handoffMu keeps the handoff scenarios from running together, as they
+// write the files they discuss to the shared working directory
+var handoffMu sync.Mutex
+
+// conversation starts the parent session of the scenario in the shared
+// database
+func conversation(ctx context.Context) (*HandoffTestState, error) {
+	state := handoffState(ctx)
+	if state.sessions != nil {
+		return state, nil
+	}
+	handoffMu.Lock()
+	state.cleanup = append(state.cleanup, handoffMu.Unlock)
+
+	conn, err := SharedDB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the database: %w", err)
+	}
+	q := db.New(conn)
+	state.sessions = session.NewService(q)
+	state.messages = message.NewService(q)
+
+	state.parent, err = state.sessions.Create(ctx, "handoff")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	return state, nil
+}
+
+// say stores a message of the parent conversation
+func say(ctx context.Context, state *HandoffTestState, role message.MessageRole, text string) error {
+	parts := []message.ContentPart{message.TextContent{Text: text}}
+	if role == message.Assistant {
+		parts = append(parts, message.Finish{Reason: message.FinishReasonEndTurn})
+	}
+	_, err := state.messages.Create(ctx, state.parent.ID, message.CreateMessageParams{
+		Role:  role,
+		Parts: parts,
+		Model: models.TestFake,
+	})
+	return err
+}
+
+func aConversationThatDiscussedTheFile(ctx context.Context, name string) error {
+	state, err := conversation(ctx)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(config.WorkingDirectory(), name)
+	if err := os.WriteFile(path, []byte("package main\n"), 0o644); err != nil {
+		return fmt.Errorf("failed to create %s: %w", name, err)
+	}
+	state.cleanup = append(state.cleanup, func() { os.Remove(path) })
+	state.files = append(state.files, name)
+	if err := say(ctx, state, message.User, fmt.Sprintf("The parser in %s drops trailing comments.", name)); err != nil {
+		return err
+	}
+	return say(ctx, state, message.Assistant, "It stops at the last token instead of the end of the line.")
+}
+
+func theUserStatedTheConstraint(ctx context.Context, constraint string) error {
+	state, err := conversation(ctx)
+	if err != nil {
+		return err
+	}
+	return say(ctx, state, message.User, constraint)
+}
+
+func theConversationMentionsTheFile(ctx context.Context, name string) error {
+	return aConversationThatDiscussedTheFile(ctx, name)
+}
+
+func aTaskIsDelegatedFromTheConversation(ctx context.Context) error {
+	state, err := conversation(ctx)
+	if err != nil {
+		return err
+	}
+	// The summarizer selects the excerpts, then the delegated agent answers
+	// and titles its session
+	fake := provider.NewFakeProvider(models.TestModels[models.TestFake],
+		provider.FakeResponse{Content: "user: The parser drops trailing comments."},
+		provider.FakeResponse{Content: "Fixed."},
+		provider.FakeResponse{Content: "Fixed."},
+	)
+	restore := provider.InstallFake(fake)
+	defer restore()
+
+	// The delegating assistant message the agent tool runs under
+	msg, err := state.messages.Create(ctx, state.parent.ID, message.CreateMessageParams{
+		Role:  message.Assistant,
+		Parts: []message.ContentPart{},
+		Model: models.TestFake,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create assistant message: %w", err)
+	}
+	toolCtx := context.WithValue(ctx, tools.SessionIDContextKey, state.parent.ID)
+	toolCtx = context.WithValue(toolCtx, tools.MessageIDContextKey, msg.ID)
+
+	tool := agent.NewAgentTool(state.sessions, state.messages, nil)
+	state.response, err = tool.Run(toolCtx, tools.ToolCall{
+		ID:    fmt.Sprintf("call-%s", msg.ID),
+		Name:  agent.AgentToolName,
+		Input: `{"prompt":"Fix the parser so it keeps trailing comments"}`,
+	})
+	if err != nil {
+		return fmt.Errorf("delegation failed: %w", err)
+	}
+	if state.response.IsError {
+		return fmt.Errorf("delegation failed: %s", state.response.Content)
+	}
+	if err := json.Unmarshal([]byte(state.response.Metadata), &state.record); err != nil {
+		return fmt.Errorf("delegation result has no handoff record: %w", err)
+	}
+	return nil
+}
+
+func theSubSessionShouldStartWithAHandoffContextBlock(ctx context.Context) error {
+	state := handoffState(ctx)
+	msgs, err := state.messages.List(ctx, state.record.SessionID)
+	if err != nil {
+		return fmt.Errorf("failed to list sub-session messages: %w", err)
+	}
+	if len(msgs) == 0 || msgs[0].Role != message.User {
+		return fmt.Errorf("sub-session does not start with a user message")
+	}
+	first := msgs[0].Content().String()
+	if !strings.HasPrefix(first, "<handoff>") || !strings.HasSuffix(first, "Fix the parser so it keeps trailing comments") {
+		return fmt.Errorf("sub-session starts with %q, want the handoff followed by the task", first)
+	}
+	return nil
+}
+
+func theHandoffShouldReferenceTheFile(ctx context.Context, name string) error {
+	state := handoffState(ctx)
+	if !slices.Contains(state.record.Handoff.Files, name) {
+		return fmt.Errorf("handoff files = %v, want %s", state.record.Handoff.Files, name)
+	}
+	msgs, err := state.messages.List(ctx, state.record.SessionID)
+	if err != nil {
+		return fmt.Errorf("failed to list sub-session messages: %w", err)
+	}
+	if len(msgs) == 0 || !strings.Contains(msgs[0].Content().String(), "- "+name) {
+		return fmt.Errorf("sub-session does not reference %s", name)
+	}
+	return nil
+}
+
+func theHandoffShouldIncludeTheConstraint(ctx context.Context, constraint string) error {
+	state := handoffState(ctx)
+	if !slices.Contains(state.record.Handoff.Constraints, constraint) {
+		return fmt.Errorf("handoff constraints = %q, want %q", state.record.Handoff.Constraints, constraint)
+	}
+	return nil
+}
+
+func theDelegationResultShouldRecordTheHandoff(ctx context.Context) error {
+	state := handoffState(ctx)
+	sub, err := state.sessions.Get(ctx, state.record.SessionID)
+	if err != nil {
+		return fmt.Errorf("recorded sub-session %q does not exist: %w", state.record.SessionID, err)
+	}
+	if sub.ParentSessionID != state.parent.ID {
+		return fmt.Errorf("sub-session parent = %q, want %q", sub.ParentSessionID, state.parent.ID)
+	}
+	if state.record.Handoff.Task == "" || len(state.record.Handoff.Excerpts) == 0 {
+		return fmt.Errorf("recorded handoff is incomplete: %+v", state.record.Handoff)
+	}
+	return nil
+}
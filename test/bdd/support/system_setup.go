@@ -1,12 +1,19 @@
 package support
 
 import (
+	"database/sql"
+	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/caronex/intelligence-interface/internal/core/config"
+	"github.com/caronex/intelligence-interface/internal/db"
+	"github.com/caronex/intelligence-interface/internal/tools/coordination"
 )
 
 // SystemTestSetup provides utilities for system-level testing
@@ -152,4 +159,158 @@ func (s *SystemTestSetup) CheckPackageConsistency(t *testing.T) error {
 	// This is a simplified check - in a full implementation, this would
 	// parse Go files and verify package declarations match directory structure
 	return nil
-}
\ No newline at end of file
+}
+
+var (
+	configOnce   sync.Once
+	configDir    string
+	loadedConfig *config.Config
+	configErr    error
+
+	managerOnce   sync.Once
+	sharedManager *coordination.Manager
+	managerErr    error
+
+	dbOnce   sync.Once
+	sharedDB *sql.DB
+	dbErr    error
+)
+
+// LoadConfig builds the process-wide test configuration once, in an empty
+// working directory. Every agent uses the scripted test provider, so no step
+// can reach a real API. The configuration is global state, so concurrent
+// scenarios share the result and must copy it before changing anything.
+func LoadConfig() (*config.Config, error) {
+	configOnce.Do(func() {
+		dir, err := os.MkdirTemp("", "bdd-config-*")
+		if err != nil {
+			configErr = fmt.Errorf("failed to create temp directory: %w", err)
+			return
+		}
+		configDir = dir
+		loadedConfig = config.NewTestConfig(
+			config.WithWorkingDir(dir),
+			config.WithTestAgents(implementationAgents...),
+		)
+		loadedConfig.Data.Directory = filepath.Join(dir, "data")
+	})
+	return loadedConfig, configErr
+}
+
+// SharedManager returns the coordination manager of the test configuration,
+// built once for the whole suite. Scenarios share it, so they may run tools
+// through it but must not rely on the delegations of other scenarios.
+func SharedManager() (*coordination.Manager, error) {
+	managerOnce.Do(func() {
+		cfg, err := LoadConfig()
+		if err != nil {
+			managerErr = err
+			return
+		}
+		sharedManager, managerErr = coordination.NewManager(cfg)
+	})
+	return sharedManager, managerErr
+}
+
+// SharedDB returns the database of the test configuration, connected once
+// for the whole suite. Scenarios keep apart by working in their own sessions.
+func SharedDB() (*sql.DB, error) {
+	dbOnce.Do(func() {
+		if _, err := LoadConfig(); err != nil {
+			dbErr = err
+			return
+		}
+		sharedDB, dbErr = db.Connect()
+	})
+	return sharedDB, dbErr
+}
+
+// TearDown removes what LoadConfig, SharedManager and SharedDB set up. It is
+// called once all the feature groups ran.
+func TearDown() {
+	if sharedDB != nil {
+		sharedDB.Close()
+	}
+	if configDir != "" {
+		os.RemoveAll(configDir)
+	}
+}
+
+// moduleSources are the parts of the project copied for isolated builds
+var moduleSources = []string{"go.mod", "go.sum", "main.go", "cmd", "internal"}
+
+// ResolveProjectRoot returns path when it exists, otherwise the root of the
+// module containing the working directory, so features written against a
+// fixed checkout location still run from any clone
+func ResolveProjectRoot(path string) (string, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return path, nil
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get working directory: %w", err)
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("project path does not exist: %s", path)
+		}
+		dir = parent
+	}
+}
+
+// CopyModule copies the application sources of the project into dest
+func CopyModule(projectRoot, dest string) error {
+	for _, source := range moduleSources {
+		if err := copyPath(filepath.Join(projectRoot, source), filepath.Join(dest, source)); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", source, err)
+		}
+	}
+	return nil
+}
+
+// RunGo runs the go tool in dir and returns its combined output
+func RunGo(dir string, args ...string) (string, error) {
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return string(output), fmt.Errorf("go %s failed: %w", strings.Join(args, " "), err)
+	}
+	return string(output), nil
+}
+
+func copyPath(source, dest string) error {
+	return filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
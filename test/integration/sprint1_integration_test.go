@@ -2,7 +2,6 @@ package integration
 
 import (
 	"context"
-	"os"
 	"testing"
 	"time"
 
@@ -45,12 +44,7 @@ func TestSprint1_CompleteWorkflowIntegration(t *testing.T) {
 func setupIntegrationTest(t *testing.T) {
 	t.Helper()
 
-	os.Setenv("OPENAI_API_KEY", "test-key-integration")
-	defer os.Unsetenv("OPENAI_API_KEY")
-
-	tempDir := t.TempDir()
-	_, err := config.Load(tempDir, false)
-	require.NoError(t, err)
+	config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
 }
 
 func validateDirectoryMigration(t *testing.T) {
@@ -69,9 +63,7 @@ func validateDirectoryMigration(t *testing.T) {
 	})
 
 	t.Run("tools package structure", func(t *testing.T) {
-		tempDir := t.TempDir()
-		cfg, err := config.Load(tempDir, false)
-		require.NoError(t, err)
+		cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
 
 		manager, err := coordination.NewManager(cfg)
 		require.NoError(t, err)
@@ -101,9 +93,7 @@ func validateDirectoryMigration(t *testing.T) {
 func validateCaronexManagerIntegration(t *testing.T) {
 	t.Helper()
 
-	tempDir := t.TempDir()
-	cfg, err := config.Load(tempDir, false)
-	require.NoError(t, err)
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
 
 	t.Run("caronex agent creation", func(t *testing.T) {
 		agent := caronex.NewCaronexAgent()
@@ -127,7 +117,7 @@ func validateCaronexManagerIntegration(t *testing.T) {
 		require.NoError(t, err)
 		require.NotNil(t, manager, "Coordination manager should be available")
 
-		introspection, err := manager.GetSystemIntrospection()
+		introspection, err := manager.GetSystemIntrospection(context.Background())
 		assert.NoError(t, err, "System introspection should work")
 		assert.NotEmpty(t, introspection.SystemStatus, "System status should be available")
 
@@ -169,9 +159,7 @@ func validateConfigurationSystem(t *testing.T) {
 func validateManagementToolsIntegration(t *testing.T) {
 	t.Helper()
 
-	tempDir := t.TempDir()
-	cfg, err := config.Load(tempDir, false)
-	require.NoError(t, err)
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
 
 	t.Run("management tools availability", func(t *testing.T) {
 		tools := agent.ManagerAgentTools()
@@ -201,7 +189,7 @@ func validateManagementToolsIntegration(t *testing.T) {
 		require.NoError(t, err)
 		require.NotNil(t, manager, "Coordination manager should be available")
 
-		introspection, err := manager.GetSystemIntrospection()
+		introspection, err := manager.GetSystemIntrospection(context.Background())
 		assert.NoError(t, err, "System introspection should work")
 		assert.NotEmpty(t, introspection.SystemStatus, "System status should be retrievable")
 
@@ -262,7 +250,7 @@ func validateEndToEndWorkflow(t *testing.T) {
 		}
 
 		for _, task := range tasks {
-			result, err := manager.DelegateTask(task+"_id", task, "caronex")
+			result, err := manager.DelegateTask(context.Background(), task+"_id", task, "caronex", false)
 			assert.NoError(t, err, "Task %s should delegate successfully", task)
 			assert.NotEmpty(t, result, "Task %s should produce result", task)
 		}
@@ -334,9 +322,7 @@ func TestSprint1_StabilityValidation(t *testing.T) {
 	setupIntegrationTest(t)
 
 	t.Run("concurrent agent access", func(t *testing.T) {
-		tempDir := t.TempDir()
-		cfg, err := config.Load(tempDir, false)
-		require.NoError(t, err)
+		cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
 
 		manager, err := coordination.NewManager(cfg)
 		require.NoError(t, err)
@@ -348,7 +334,7 @@ func TestSprint1_StabilityValidation(t *testing.T) {
 			go func() {
 				defer func() { done <- true }()
 				
-				introspection, err := manager.GetSystemIntrospection()
+				introspection, err := manager.GetSystemIntrospection(context.Background())
 				assert.NoError(t, err)
 				assert.NotEmpty(t, introspection.AvailableAgents)
 				
@@ -366,17 +352,15 @@ func TestSprint1_StabilityValidation(t *testing.T) {
 	})
 
 	t.Run("error recovery", func(t *testing.T) {
-		tempDir := t.TempDir()
-		cfg, err := config.Load(tempDir, false)
-		require.NoError(t, err)
+		cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
 
 		manager, err := coordination.NewManager(cfg)
 		require.NoError(t, err)
 		
-		result, err := manager.DelegateTask("invalid_task_id", "invalid_task", "invalid_agent")
+		result, err := manager.DelegateTask(context.Background(), "invalid_task_id", "invalid_task", "invalid_agent", false)
 		assert.Error(t, err, "System should handle invalid tasks gracefully")
 
-		introspection, err := manager.GetSystemIntrospection()
+		introspection, err := manager.GetSystemIntrospection(context.Background())
 		assert.NoError(t, err, "System should remain functional after errors")
 		assert.NotEmpty(t, introspection.SystemStatus, "System should remain functional after errors")
 	})
@@ -28,12 +28,7 @@ func BenchmarkConfigurationLoad(b *testing.B) {
 
 // BenchmarkCoordinationManagerCreation tests coordination manager creation performance
 func BenchmarkCoordinationManagerCreation(b *testing.B) {
-	tempDir := b.TempDir()
-	os.Setenv("OPENAI_API_KEY", "test-key-performance")
-	defer os.Unsetenv("OPENAI_API_KEY")
-	
-	cfg, err := config.Load(tempDir, false)
-	require.NoError(b, err)
+	cfg := config.NewTestConfig(config.WithWorkingDir(b.TempDir()))
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
@@ -45,19 +40,14 @@ func BenchmarkCoordinationManagerCreation(b *testing.B) {
 
 // BenchmarkSystemIntrospection tests system introspection performance
 func BenchmarkSystemIntrospection(b *testing.B) {
-	tempDir := b.TempDir()
-	os.Setenv("OPENAI_API_KEY", "test-key-performance")
-	defer os.Unsetenv("OPENAI_API_KEY")
-	
-	cfg, err := config.Load(tempDir, false)
-	require.NoError(b, err)
-	
+	cfg := config.NewTestConfig(config.WithWorkingDir(b.TempDir()))
+
 	manager, err := coordination.NewManager(cfg)
 	require.NoError(b, err)
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		introspection, err := manager.GetSystemIntrospection()
+		introspection, err := manager.GetSystemIntrospection(context.Background())
 		require.NoError(b, err)
 		require.NotNil(b, introspection)
 	}
@@ -70,10 +60,10 @@ func TestPerformanceBaselines(t *testing.T) {
 	}
 
 	tempDir := t.TempDir()
-	os.Setenv("OPENAI_API_KEY", "test-key-performance")
-	defer os.Unsetenv("OPENAI_API_KEY")
 
 	t.Run("configuration load time", func(t *testing.T) {
+		t.Setenv("OPENAI_API_KEY", "test-key-performance")
+
 		start := time.Now()
 		cfg, err := config.Load(tempDir, false)
 		duration := time.Since(start)
@@ -84,8 +74,7 @@ func TestPerformanceBaselines(t *testing.T) {
 	})
 
 	t.Run("coordination manager creation time", func(t *testing.T) {
-		cfg, err := config.Load(tempDir, false)
-		require.NoError(t, err)
+		cfg := config.NewTestConfig(config.WithWorkingDir(tempDir))
 
 		start := time.Now()
 		manager, err := coordination.NewManager(cfg)
@@ -97,14 +86,13 @@ func TestPerformanceBaselines(t *testing.T) {
 	})
 
 	t.Run("system introspection time", func(t *testing.T) {
-		cfg, err := config.Load(tempDir, false)
-		require.NoError(t, err)
+		cfg := config.NewTestConfig(config.WithWorkingDir(tempDir))
 		
 		manager, err := coordination.NewManager(cfg)
 		require.NoError(t, err)
 
 		start := time.Now()
-		introspection, err := manager.GetSystemIntrospection()
+		introspection, err := manager.GetSystemIntrospection(context.Background())
 		duration := time.Since(start)
 
 		require.NoError(t, err)
@@ -113,8 +101,7 @@ func TestPerformanceBaselines(t *testing.T) {
 	})
 
 	t.Run("concurrent access performance", func(t *testing.T) {
-		cfg, err := config.Load(tempDir, false)
-		require.NoError(t, err)
+		cfg := config.NewTestConfig(config.WithWorkingDir(tempDir))
 		
 		manager, err := coordination.NewManager(cfg)
 		require.NoError(t, err)
@@ -125,7 +112,7 @@ func TestPerformanceBaselines(t *testing.T) {
 		for i := 0; i < concurrency; i++ {
 			go func() {
 				start := time.Now()
-				introspection, err := manager.GetSystemIntrospection()
+				introspection, err := manager.GetSystemIntrospection(context.Background())
 				duration := time.Since(start)
 				
 				if err == nil && introspection != nil {
@@ -159,13 +146,8 @@ func TestStabilityUnderLoad(t *testing.T) {
 		t.Skip("Skipping stability tests in short mode")
 	}
 
-	tempDir := t.TempDir()
-	os.Setenv("OPENAI_API_KEY", "test-key-stability")
-	defer os.Unsetenv("OPENAI_API_KEY")
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
 
-	cfg, err := config.Load(tempDir, false)
-	require.NoError(t, err)
-	
 	manager, err := coordination.NewManager(cfg)
 	require.NoError(t, err)
 
@@ -183,7 +165,7 @@ func TestStabilityUnderLoad(t *testing.T) {
 				assert.Less(t, float64(errors)/float64(operations), 0.01, "Error rate should be less than 1%")
 				return
 			default:
-				_, err := manager.GetSystemIntrospection()
+				_, err := manager.GetSystemIntrospection(context.Background())
 				operations++
 				if err != nil {
 					errors++
@@ -204,7 +186,7 @@ func TestStabilityUnderLoad(t *testing.T) {
 			// Create burst
 			for i := 0; i < operationsPerBurst; i++ {
 				go func() {
-					_, err := manager.GetSystemIntrospection()
+					_, err := manager.GetSystemIntrospection(context.Background())
 					done <- err
 				}()
 			}
@@ -234,7 +216,7 @@ func TestStabilityUnderLoad(t *testing.T) {
 		iterations := 1000
 		
 		for i := 0; i < iterations; i++ {
-			introspection, err := manager.GetSystemIntrospection()
+			introspection, err := manager.GetSystemIntrospection(context.Background())
 			require.NoError(t, err)
 			require.NotNil(t, introspection)
 			
@@ -251,23 +233,18 @@ func TestStabilityUnderLoad(t *testing.T) {
 
 // TestErrorRecovery tests system recovery from various error conditions
 func TestErrorRecovery(t *testing.T) {
-	tempDir := t.TempDir()
-	os.Setenv("OPENAI_API_KEY", "test-key-recovery")
-	defer os.Unsetenv("OPENAI_API_KEY")
+	cfg := config.NewTestConfig(config.WithWorkingDir(t.TempDir()))
 
-	cfg, err := config.Load(tempDir, false)
-	require.NoError(t, err)
-	
 	manager, err := coordination.NewManager(cfg)
 	require.NoError(t, err)
 
 	t.Run("invalid task delegation recovery", func(t *testing.T) {
 		// Test invalid task delegation
-		_, err := manager.DelegateTask("invalid_task_id", "invalid_task", "nonexistent_agent")
+		_, err := manager.DelegateTask(context.Background(), "invalid_task_id", "invalid_task", "nonexistent_agent", false)
 		assert.Error(t, err, "Invalid task delegation should return error")
 
 		// System should remain functional
-		introspection, err := manager.GetSystemIntrospection()
+		introspection, err := manager.GetSystemIntrospection(context.Background())
 		assert.NoError(t, err, "System should remain functional after error")
 		assert.NotNil(t, introspection)
 	})
@@ -278,13 +255,13 @@ func TestErrorRecovery(t *testing.T) {
 		
 		// Generate multiple errors rapidly
 		for i := 0; i < 50; i++ {
-			_, err := manager.DelegateTask("invalid_task_"+string(rune(i)), "invalid", "none")
+			_, err := manager.DelegateTask(context.Background(), "invalid_task_"+string(rune(i)), "invalid", "none", false)
 			if err != nil {
 				errorCount++
 			}
 			
 			// Verify system still works
-			_, err = manager.GetSystemIntrospection()
+			_, err = manager.GetSystemIntrospection(context.Background())
 			if err == nil {
 				successCount++
 			}